@@ -0,0 +1,668 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/agent"
+	"github.com/e9169/kopilot/pkg/debug"
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+// errUsage reports invalid command-line arguments; the flag package has
+// already printed the problem and the usage.
+var errUsage = errors.New("invalid usage")
+
+// Exit codes. The batch commands (check, report) exit with the health of the
+// checked clusters; every command exits with exitError when it fails.
+const (
+	exitHealthy  = 0
+	exitWarnings = 1
+	exitCritical = 2
+	exitError    = 3
+)
+
+// issuesFound is returned by a batch command whose check completed but found
+// warning or critical issues; it carries the exit code.
+type issuesFound struct {
+	critical int
+	warnings int
+}
+
+func (e *issuesFound) Error() string {
+	return fmt.Sprintf("%d critical and %d warning issue(s) found", e.critical, e.warnings)
+}
+
+// exitCode returns exitCritical or exitWarnings.
+func (e *issuesFound) exitCode() int {
+	if e.critical > 0 {
+		return exitCritical
+	}
+	return exitWarnings
+}
+
+// checkOutcome returns nil for a check without warning or critical issues,
+// issuesFound otherwise.
+func checkOutcome(critical, warnings int) error {
+	if critical == 0 && warnings == 0 {
+		return nil
+	}
+	return &issuesFound{critical: critical, warnings: warnings}
+}
+
+// exitCodeOf maps the result of runCommand to the process exit code.
+func exitCodeOf(err error) int {
+	var found *issuesFound
+	switch {
+	case err == nil:
+		return exitHealthy
+	case errors.As(err, &found):
+		return found.exitCode()
+	}
+	return exitError
+}
+
+// command is a kopilot subcommand.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands returns the subcommands in the order "kopilot help" lists them.
+// It is a function because the chat usage lists the commands.
+func commands() []command {
+	return []command{
+		{"chat", "Start an AI session about your clusters (the default)", runChatCommand},
+		{"check", "Check all clusters once without an AI session", runCheckCommand},
+		{"dashboard", "Show a live health grid of all clusters beside a chat", runDashboardCommand},
+		{"report", "Render a Markdown or HTML health report", runReportCommand},
+		{"serve", "Run as a stdio MCP server", runServeCommand},
+		{"version", "Show version information", runVersionCommand},
+	}
+}
+
+// runCommand dispatches the arguments to a subcommand. Arguments that do not
+// start with one run the chat session, so "kopilot [flags]" keeps working.
+func runCommand(args []string) error {
+	if len(args) == 0 {
+		return runChat(nil, true)
+	}
+	if args[0] == "help" {
+		return runHelpCommand(args[1:])
+	}
+	for _, c := range commands() {
+		if c.name == args[0] {
+			return c.run(args[1:])
+		}
+	}
+	if len(args[0]) > 0 && args[0][0] == '-' {
+		return runChat(args, true)
+	}
+	printCommands(os.Stderr)
+	return fmt.Errorf("unknown command %q (see kopilot help)", args[0])
+}
+
+// runHelpCommand implements "kopilot help [command]".
+func runHelpCommand(args []string) error {
+	if len(args) == 0 {
+		return runChat([]string{"--help"}, true)
+	}
+	for _, c := range commands() {
+		if c.name == args[0] {
+			return c.run([]string{"--help"})
+		}
+	}
+	return fmt.Errorf("unknown command %q (see kopilot help)", args[0])
+}
+
+// printCommands lists the subcommands.
+func printCommands(w io.Writer) {
+	fmt.Fprintf(w, "Commands:\n")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-9s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(w, "  %-9s %s\n", "help", "Show help for a command")
+}
+
+// parseFlags parses args into fs. It reports done when help was requested,
+// and errUsage for invalid flags.
+func parseFlags(fs *flag.FlagSet, args []string) (done bool, err error) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return true, nil
+		}
+		return false, errUsage
+	}
+	return false, nil
+}
+
+// clusterFlags select the kubeconfig and context a command works on.
+type clusterFlags struct {
+	kubeconfig string
+	context    string
+	priceTable string
+	api        *apiFlags
+}
+
+// addClusterFlags registers the cluster selection flags, and the API flags,
+// on fs; contextUsage describes --context, which commands interpret
+// differently.
+func addClusterFlags(fs *flag.FlagSet, contextUsage string) *clusterFlags {
+	f := &clusterFlags{}
+	fs.StringVar(&f.kubeconfig, "kubeconfig", defaultKubeconfigPath(), "Path to kubeconfig file (default: $KUBECONFIG or ~/.kube/config)")
+	fs.StringVar(&f.context, "context", "", contextUsage)
+	fs.StringVar(&f.priceTable, "price-table", os.Getenv("KOPILOT_PRICE_TABLE"), "Path to a JSON node price table enabling cost estimates (default: $KOPILOT_PRICE_TABLE or ~/.kopilot/prices.json if present)")
+	f.api = addAPIFlags(fs)
+	return f
+}
+
+// apiFlags tune the requests sent to the Kubernetes API servers.
+type apiFlags struct {
+	timeout          time.Duration
+	discoveryTimeout time.Duration
+	retries          int
+	maxPods          int
+	liveAfter        int
+	reachTimeout     time.Duration
+}
+
+// addAPIFlags registers the API timeout and retry flags on fs; their
+// environment variables set the defaults.
+func addAPIFlags(fs *flag.FlagSet) *apiFlags {
+	f := &apiFlags{}
+	fs.DurationVar(&f.timeout, "api-timeout", envDuration("KOPILOT_API_TIMEOUT", k8s.DefaultAPITimeout), "Timeout of each Kubernetes API call; $KOPILOT_API_TIMEOUT sets the default")
+	fs.DurationVar(&f.discoveryTimeout, "discovery-timeout", envDuration("KOPILOT_DISCOVERY_TIMEOUT", k8s.DiscoveryTimeout), "Timeout of the version check telling whether a cluster is reachable; $KOPILOT_DISCOVERY_TIMEOUT sets the default")
+	fs.IntVar(&f.retries, "api-retries", envInt("KOPILOT_API_RETRIES", k8s.DefaultAPIRetries), "Retries of API requests failing with throttling (429), unavailability (502-504) or timeouts, with exponential backoff; 0 disables them. $KOPILOT_API_RETRIES sets the default")
+	fs.IntVar(&f.maxPods, "max-pods", envInt("KOPILOT_MAX_PODS", k8s.DefaultMaxPods), "Pods counted per cluster by status checks, listed in pages of 500; clusters with more are reported as truncated. 0 removes the cap. $KOPILOT_MAX_PODS sets the default")
+	fs.DurationVar(&f.reachTimeout, "reach-timeout", envDuration("KOPILOT_REACH_TIMEOUT", k8s.DefaultReachTimeout), "How long a status check waits for the API server to accept a TCP connection before reporting the cluster unreachable; 0 skips the probe. $KOPILOT_REACH_TIMEOUT sets the default")
+	fs.IntVar(&f.liveAfter, "live-state-after", envInt("KOPILOT_LIVE_STATE_AFTER", 0), "Start pod, node and event informers for a context once it has been checked this many times, so later checks read from a local cache; 0 (default) never starts them. $KOPILOT_LIVE_STATE_AFTER sets the default")
+	return f
+}
+
+// providerOptions validates the flags and returns them as provider options.
+func (f *apiFlags) providerOptions() ([]k8s.ProviderOption, error) {
+	if f.timeout <= 0 {
+		return nil, fmt.Errorf("--api-timeout must be positive, got %s", f.timeout)
+	}
+	if f.discoveryTimeout <= 0 {
+		return nil, fmt.Errorf("--discovery-timeout must be positive, got %s", f.discoveryTimeout)
+	}
+	if f.retries < 0 || f.retries > 10 {
+		return nil, fmt.Errorf("--api-retries must be between 0 and 10, got %d", f.retries)
+	}
+	if f.maxPods < 0 {
+		return nil, fmt.Errorf("--max-pods must not be negative, got %d", f.maxPods)
+	}
+	if f.liveAfter < 0 {
+		return nil, fmt.Errorf("--live-state-after must not be negative, got %d", f.liveAfter)
+	}
+	if f.reachTimeout < 0 {
+		return nil, fmt.Errorf("--reach-timeout must not be negative, got %s", f.reachTimeout)
+	}
+	return []k8s.ProviderOption{
+		k8s.WithAPITimeout(f.timeout),
+		k8s.WithDiscoveryTimeout(f.discoveryTimeout),
+		k8s.WithAPIRetries(f.retries),
+		k8s.WithMaxPods(f.maxPods),
+		k8s.WithLiveState(f.liveAfter),
+		k8s.WithReachTimeout(f.reachTimeout),
+	}, nil
+}
+
+// envDuration returns the duration in the environment variable name, or def
+// when it is unset or invalid.
+func envDuration(name string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(name)); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+// envInt returns the non-negative integer in the environment variable name,
+// or def when it is unset or invalid.
+func envInt(name string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n >= 0 {
+		return n
+	}
+	return def
+}
+
+// logFlags control logging, debug output and tracing.
+type logFlags struct {
+	verbose      bool
+	debug        string
+	otlpEndpoint string
+}
+
+// addLogFlags registers the logging flags on fs.
+func addLogFlags(fs *flag.FlagSet) *logFlags {
+	f := &logFlags{}
+	fs.BoolVar(&f.verbose, "verbose", false, "Enable verbose logging with all debug categories (same as --debug=all)")
+	fs.BoolVar(&f.verbose, "v", false, "Enable verbose logging (shorthand for --verbose)")
+	fs.StringVar(&f.debug, "debug", os.Getenv("KOPILOT_DEBUG"), "Comma-separated debug categories written to stderr: copilot, k8s, cache, tools, or all (default: $KOPILOT_DEBUG)")
+	fs.StringVar(&f.otlpEndpoint, "otlp-endpoint", "", "Export OpenTelemetry traces to this OTLP/HTTP endpoint, e.g. http://localhost:4318 (default: $OTEL_EXPORTER_OTLP_ENDPOINT; tracing is off when neither is set)")
+	return f
+}
+
+// setup enables the debug categories and tracing and returns the function
+// flushing pending spans before exit.
+func (f *logFlags) setup() (func(), error) {
+	if err := setupDebug(f.debug, f.verbose); err != nil {
+		return nil, fmt.Errorf("invalid --debug value: %w", err)
+	}
+	return setupTracing(f.otlpEndpoint), nil
+}
+
+// runChatCommand implements "kopilot chat".
+func runChatCommand(args []string) error {
+	return runChat(args, false)
+}
+
+// runChat starts the AI session. The legacy form, "kopilot [flags]", also
+// accepts the flags that predate the subcommands: --version, --mcp-server
+// and --report.
+func runChat(args []string, legacy bool) error {
+	name := "chat"
+	if legacy {
+		name = "kopilot"
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	cluster := addClusterFlags(fs, "Override kubeconfig context")
+	logging := addLogFlags(fs)
+	interactive := fs.Bool("interactive", false, "Enable interactive mode (asks before write operations)")
+	outputFormat := fs.String("output", string(agent.OutputText), "Output format: text or json")
+	agentName := fs.String("agent", string(agent.AgentDefault), "Specialist agent persona: default, debugger, security, optimizer, gitops")
+	configPath := fs.String("config", "", "Path to the kopilot config file defining prompt macros, health thresholds and Prometheus and log endpoints (default: ~/.kopilot/config.json)")
+	mcpConfig := fs.String("mcp-config", "", "Path to MCP server config file (default: ~/.kopilot/mcp.json)")
+	language := fs.String("language", os.Getenv("KOPILOT_LANGUAGE"), "Reply language: auto (follow each prompt), a code such as es or a name such as Spanish; commands stay untouched (default: $KOPILOT_LANGUAGE or auto)")
+	aiProvider := fs.String("ai-provider", "copilot", "AI provider to use: copilot, openai, gemini")
+	approvalPolicy := fs.String("approval", agent.ApprovalLocal, "Write approval policy in interactive mode: local, webhook, or both (two-person)")
+	approvalWebhook := fs.String("approval-webhook", os.Getenv("KOPILOT_APPROVAL_WEBHOOK"), "URL of the external approval endpoint (default: $KOPILOT_APPROVAL_WEBHOOK)")
+	theme := fs.String("theme", os.Getenv("KOPILOT_THEME"), "Colour theme: dark, light, minimal or none (default: $KOPILOT_THEME, the config file's theme or dark)")
+	noColor := fs.Bool("no-color", false, "Disable colours (same as --theme none or setting NO_COLOR)")
+	advisoryFeed := fs.String("advisory-feed", os.Getenv("KOPILOT_ADVISORY_FEED"), "Path to a JSON node advisory feed replacing the bundled one in security audits (default: $KOPILOT_ADVISORY_FEED or ~/.kopilot/advisories.json if present)")
+	var showVersion, mcpServer bool
+	var reportPath string
+	if legacy {
+		fs.BoolVar(&showVersion, "version", false, "Show version information (same as kopilot version)")
+		fs.BoolVar(&mcpServer, "mcp-server", false, "Run as a stdio MCP server (same as kopilot serve)")
+		fs.StringVar(&reportPath, "report", "", "Check all clusters, write the status report to this path (.json, .yaml, .md or .html) and exit (same as kopilot check --report)")
+	}
+	fs.Usage = func() { printChatUsage(fs, legacy) }
+	if done, err := parseFlags(fs, args); done || err != nil {
+		return err
+	}
+
+	switch {
+	case showVersion:
+		return runVersionCommand(nil)
+	case mcpServer:
+		return serve(cluster, *advisoryFeed, logging)
+	case reportPath != "":
+		// Like before the check command, --context does not limit the check.
+		return check(cluster, nil, *configPath, reportPath, "", logging)
+	}
+
+	flushTraces, err := logging.setup()
+	if err != nil {
+		return err
+	}
+	defer flushTraces()
+	if !logging.verbose {
+		log.SetFlags(0) // Remove timestamp for cleaner output
+	}
+
+	cfg, err := agent.LoadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("invalid --config file: %w", err)
+	}
+
+	// Determine execution mode
+	mode := agent.ModeReadOnly
+	if *interactive {
+		mode = agent.ModeInteractive
+	}
+
+	format := agent.OutputFormat(*outputFormat)
+	if format != agent.OutputText && format != agent.OutputJSON {
+		return fmt.Errorf("invalid --output value: %s (use 'text' or 'json')", *outputFormat)
+	}
+
+	agentType, err := agent.ParseAgentType(*agentName)
+	if err != nil {
+		return fmt.Errorf("invalid --agent value: %w", err)
+	}
+
+	approver, err := agent.BuildApprover(*approvalPolicy, *approvalWebhook, format == agent.OutputJSON)
+	if err != nil {
+		return fmt.Errorf("invalid --approval value: %w", err)
+	}
+
+	replyLanguage, err := agent.ParseLanguage(*language)
+	if err != nil {
+		return fmt.Errorf("invalid --language value: %w", err)
+	}
+
+	opts := []agent.Option{agent.WithApprover(approver), agent.WithConfig(cfg), agent.WithLanguage(replyLanguage)}
+	themeOpts, err := themeOptions(*theme, *noColor)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, themeOpts...)
+
+	providerOpts, err := cluster.api.providerOptions()
+	if err != nil {
+		return err
+	}
+	return run(mode, cluster.kubeconfig, providerOpts, cluster.context, cluster.priceTable, *advisoryFeed, format, agentType, *mcpConfig, *aiProvider, opts...)
+}
+
+// themeOptions returns the option selecting the --theme or --no-color
+// theme, none when neither is set.
+func themeOptions(theme string, noColor bool) ([]agent.Option, error) {
+	switch {
+	case noColor:
+		return []agent.Option{agent.WithTheme(agent.ThemeNone)}, nil
+	case theme != "":
+		themeName, err := agent.ParseTheme(theme)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --theme value: %w", err)
+		}
+		return []agent.Option{agent.WithTheme(themeName)}, nil
+	}
+	return nil, nil
+}
+
+// runDashboardCommand implements "kopilot dashboard": a terminal UI with a
+// health grid of all clusters, refreshed every --interval, beside a
+// read-only chat with the agent.
+func runDashboardCommand(args []string) error {
+	fs := flag.NewFlagSet("dashboard", flag.ContinueOnError)
+	cluster := addClusterFlags(fs, "Override kubeconfig context")
+	logging := addLogFlags(fs)
+	interval := fs.Duration("interval", agent.DefaultDashboardInterval, "How often to refresh the health grid")
+	configPath := fs.String("config", "", "Path to the kopilot config file defining health thresholds and per-context settings (default: ~/.kopilot/config.json)")
+	language := fs.String("language", os.Getenv("KOPILOT_LANGUAGE"), "Reply language: auto (follow each prompt), a code such as es or a name such as Spanish (default: $KOPILOT_LANGUAGE or auto)")
+	aiProvider := fs.String("ai-provider", "copilot", "AI provider to use: copilot, openai, gemini")
+	theme := fs.String("theme", os.Getenv("KOPILOT_THEME"), "Colour theme; none disables colours, any other theme uses the terminal's colours (default: $KOPILOT_THEME)")
+	noColor := fs.Bool("no-color", false, "Disable colours (same as --theme none or setting NO_COLOR)")
+	advisoryFeed := fs.String("advisory-feed", os.Getenv("KOPILOT_ADVISORY_FEED"), "Path to a JSON node advisory feed replacing the bundled one in security audits (default: $KOPILOT_ADVISORY_FEED or ~/.kopilot/advisories.json if present)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: kopilot dashboard [flags]\n\n")
+		fmt.Fprintf(fs.Output(), "Show a live health grid of all clusters (nodes, pods, issues) beside a chat with\n")
+		fmt.Fprintf(fs.Output(), "the agent. The chat is read-only; use kopilot chat --interactive for changes.\n")
+		fmt.Fprintf(fs.Output(), "Keys: enter asks, pgup/pgdn scroll the chat, ctrl+r refreshes now, esc quits.\n\n")
+		fmt.Fprintf(fs.Output(), "Flags:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nExamples:\n")
+		fmt.Fprintf(fs.Output(), "  kopilot dashboard\n")
+		fmt.Fprintf(fs.Output(), "  kopilot dashboard --interval 10s --ai-provider openai\n")
+	}
+	if done, err := parseFlags(fs, args); done || err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("dashboard takes no arguments, got %q", fs.Args())
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("invalid --interval value: %s (must be positive)", *interval)
+	}
+	flushTraces, err := logging.setup()
+	if err != nil {
+		return err
+	}
+	defer flushTraces()
+	cfg, err := agent.LoadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("invalid --config file: %w", err)
+	}
+	replyLanguage, err := agent.ParseLanguage(*language)
+	if err != nil {
+		return fmt.Errorf("invalid --language value: %w", err)
+	}
+	opts := []agent.Option{agent.WithConfig(cfg), agent.WithLanguage(replyLanguage)}
+	themeOpts, err := themeOptions(*theme, *noColor)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, themeOpts...)
+	providerOpts, err := cluster.api.providerOptions()
+	if err != nil {
+		return err
+	}
+	return runDashboard(cluster.kubeconfig, providerOpts, cluster.context, cluster.priceTable, *advisoryFeed, *aiProvider, *interval, opts...)
+}
+
+// runCheckCommand implements "kopilot check": it checks all clusters, or the
+// --context ones, once without an AI session, prints the result, records it
+// in the health history when KOPILOT_HEALTH_HISTORY enables it and
+// optionally writes a report.
+func runCheckCommand(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	cluster := addClusterFlags(fs, "Check only these contexts, comma-separated (default: all)")
+	logging := addLogFlags(fs)
+	outputFormat := fs.String("output", string(agent.OutputText), "Output format: text or json")
+	configPath := fs.String("config", "", "Path to the kopilot config file defining health thresholds (default: ~/.kopilot/config.json)")
+	reportPath := fs.String("report", "", "Also write the status report to this path (.json, .yaml, .md or .html)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: kopilot check [flags]\n\n")
+		fmt.Fprintf(fs.Output(), "Check all clusters once without an AI session and print what check_all_clusters\n")
+		fmt.Fprintf(fs.Output(), "reports, or get_cluster_status for a single --context. With KOPILOT_HEALTH_HISTORY\n")
+		fmt.Fprintf(fs.Output(), "set, the result is recorded in the health history, so scheduling it (e.g. from\n")
+		fmt.Fprintf(fs.Output(), "cron) tracks cluster health: on keeps 30 days, a window such as 14d keeps that\n")
+		fmt.Fprintf(fs.Output(), "long. Unset or off, nothing is written.\n\n")
+		fmt.Fprintf(fs.Output(), "Flags:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nExamples:\n")
+		fmt.Fprintf(fs.Output(), "  kopilot check\n")
+		fmt.Fprintf(fs.Output(), "  kopilot check --context prod --output json\n")
+		fmt.Fprintf(fs.Output(), "  kopilot check --context prod,staging --report status.html\n")
+		fmt.Fprintf(fs.Output(), "\nExit status: 0 healthy, 1 warnings, 2 critical issues, 3 error.\n")
+	}
+	if done, err := parseFlags(fs, args); done || err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("check takes no arguments, got %q", fs.Args())
+	}
+	format := agent.OutputFormat(*outputFormat)
+	if format != agent.OutputText && format != agent.OutputJSON {
+		return fmt.Errorf("invalid --output value: %s (use 'text' or 'json')", *outputFormat)
+	}
+	return check(cluster, splitContexts(cluster.context), *configPath, *reportPath, format, logging)
+}
+
+// splitContexts splits a comma-separated --context value.
+func splitContexts(value string) []string {
+	var contexts []string
+	for _, contextName := range strings.Split(value, ",") {
+		if contextName = strings.TrimSpace(contextName); contextName != "" {
+			contexts = append(contexts, contextName)
+		}
+	}
+	return contexts
+}
+
+// check runs "kopilot check" once the flags are parsed; an empty format
+// prints nothing.
+func check(cluster *clusterFlags, contexts []string, configPath, reportPath string, format agent.OutputFormat, logging *logFlags) error {
+	flushTraces, err := logging.setup()
+	if err != nil {
+		return err
+	}
+	defer flushTraces()
+	if !logging.verbose {
+		log.SetFlags(0)
+	}
+	cfg, err := agent.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("invalid --config file: %w", err)
+	}
+	thresholds := cfg.Health
+	if acks, err := agent.LoadAcks(""); err != nil {
+		log.Printf("Warning: ignoring acknowledged issues: %v", err)
+	} else {
+		thresholds.Acknowledged = append(thresholds.Acknowledged, acks...)
+	}
+	providerOpts, err := cluster.api.providerOptions()
+	if err != nil {
+		return err
+	}
+	return runCheck(cluster.kubeconfig, providerOpts, contexts, cluster.priceTable, reportPath, format, thresholds)
+}
+
+// runServeCommand implements "kopilot serve": it exposes the kopilot tools
+// as a stdio MCP server.
+func runServeCommand(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	cluster := addClusterFlags(fs, "Override kubeconfig context")
+	logging := addLogFlags(fs)
+	advisoryFeed := fs.String("advisory-feed", os.Getenv("KOPILOT_ADVISORY_FEED"), "Path to a JSON node advisory feed replacing the bundled one in security audits (default: $KOPILOT_ADVISORY_FEED or ~/.kopilot/advisories.json if present)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: kopilot serve [flags]\n\n")
+		fmt.Fprintf(fs.Output(), "Run as a stdio MCP server, compatible with any MCP client.\n\n")
+		fmt.Fprintf(fs.Output(), "Flags:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nExamples:\n")
+		fmt.Fprintf(fs.Output(), "  kopilot serve\n")
+		fmt.Fprintf(fs.Output(), "  kopilot serve --context production\n")
+	}
+	if done, err := parseFlags(fs, args); done || err != nil {
+		return err
+	}
+	return serve(cluster, *advisoryFeed, logging)
+}
+
+// serve runs "kopilot serve" once the flags are parsed.
+func serve(cluster *clusterFlags, advisoryFeed string, logging *logFlags) error {
+	flushTraces, err := logging.setup()
+	if err != nil {
+		return err
+	}
+	defer flushTraces()
+	providerOpts, err := cluster.api.providerOptions()
+	if err != nil {
+		return err
+	}
+	if err := runMCPServer(cluster.kubeconfig, providerOpts, cluster.context, cluster.priceTable, advisoryFeed, logging.verbose || debug.Any()); err != nil {
+		return fmt.Errorf("MCP server error: %w", err)
+	}
+	return nil
+}
+
+// runVersionCommand implements "kopilot version".
+func runVersionCommand(args []string) error {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: kopilot version\n\nShow version information.\n")
+	}
+	if done, err := parseFlags(fs, args); done || err != nil {
+		return err
+	}
+	fmt.Printf("kopilot version %s\n", version)
+	fmt.Printf("  build date: %s\n", buildDate)
+	fmt.Printf("  git commit: %s\n", gitCommit)
+	return nil
+}
+
+// printChatUsage prints the help of the chat session, with the subcommands
+// when invoked as plain "kopilot".
+func printChatUsage(fs *flag.FlagSet, legacy bool) {
+	w := fs.Output()
+	fmt.Fprintf(w, "Kopilot - Kubernetes Cluster Status Agent\n\n")
+	fmt.Fprintf(w, "Usage:\n")
+	if legacy {
+		fmt.Fprintf(w, "  kopilot [flags]                 (same as kopilot chat)\n")
+		fmt.Fprintf(w, "  kopilot <command> [flags]       (see kopilot help <command>)\n\n")
+		printCommands(w)
+		fmt.Fprintf(w, "\n")
+	} else {
+		fmt.Fprintf(w, "  kopilot chat [flags]\n\n")
+	}
+	fmt.Fprintf(w, "Flags:\n")
+	fs.PrintDefaults()
+	fmt.Fprintf(w, "\nExecution Modes:\n")
+	fmt.Fprintf(w, "  Read-only (default): Blocks all write operations for safety\n")
+	fmt.Fprintf(w, "  Interactive (--interactive): Asks for confirmation before write operations\n")
+	fmt.Fprintf(w, "\nWrite Approval (--approval):\n")
+	fmt.Fprintf(w, "  local    Confirm at the terminal (default)\n")
+	fmt.Fprintf(w, "  webhook  POST the request to --approval-webhook and wait for {\"approved\": true|false}\n")
+	fmt.Fprintf(w, "  both     Require the local user AND the webhook to approve (two-person rule)\n")
+	fmt.Fprintf(w, "\nSpecialist Agents:\n")
+	fmt.Fprintf(w, "  default    Standard Kopilot persona\n")
+	fmt.Fprintf(w, "  debugger   Root cause analysis and pod failure diagnosis\n")
+	fmt.Fprintf(w, "  security   RBAC auditing and privilege escalation detection\n")
+	fmt.Fprintf(w, "  optimizer  Resource right-sizing and cost optimization\n")
+	fmt.Fprintf(w, "  gitops     Flux/ArgoCD sync status and drift detection\n")
+	fmt.Fprintf(w, "  sanitizer  Cluster linting, best-practice scoring, and compliance grading\n")
+	fmt.Fprintf(w, "\nRuntime Commands:\n")
+	fmt.Fprintf(w, "  /readonly          Switch to read-only mode\n")
+	fmt.Fprintf(w, "  /interactive       Switch to interactive mode\n")
+	fmt.Fprintf(w, "  /mode              Show current execution mode\n")
+	fmt.Fprintf(w, "  /agent             Show active agent and available agents\n")
+	fmt.Fprintf(w, "  /agent <name>      Switch to a different specialist agent\n")
+	fmt.Fprintf(w, "  /mcp list          List configured MCP servers\n")
+	fmt.Fprintf(w, "  /mcp add <n> <url> Add an MCP server\n")
+	fmt.Fprintf(w, "  /mcp delete <name> Remove an MCP server\n")
+	fmt.Fprintf(w, "\nAI Providers:\n")
+	fmt.Fprintf(w, "  copilot   GitHub Copilot (default)\n")
+	fmt.Fprintf(w, "              Auth: Copilot CLI token (automatic when gh copilot is installed)\n")
+	fmt.Fprintf(w, "              No extra environment variables required.\n")
+	fmt.Fprintf(w, "\n")
+	fmt.Fprintf(w, "  openai    OpenAI or any OpenAI-compatible API (Ollama, Azure OpenAI, Anthropic…)\n")
+	fmt.Fprintf(w, "              OPENAI_API_KEY    API key (required; use 'none' for local models)\n")
+	fmt.Fprintf(w, "              OPENAI_BASE_URL   Custom base URL (optional, for non-OpenAI backends)\n")
+	fmt.Fprintf(w, "              Examples:\n")
+	fmt.Fprintf(w, "                OPENAI_API_KEY=sk-... kopilot --ai-provider=openai\n")
+	fmt.Fprintf(w, "                OPENAI_BASE_URL=http://localhost:11434/v1 kopilot --ai-provider=openai  # Ollama\n")
+	fmt.Fprintf(w, "\n")
+	fmt.Fprintf(w, "  gemini    Google Gemini (Gemini 1.5 / 2.0 / 2.5)\n")
+	fmt.Fprintf(w, "              GEMINI_API_KEY    API key from https://ai.google.dev (recommended)\n")
+	fmt.Fprintf(w, "              Alternatively, authenticate with Application Default Credentials:\n")
+	fmt.Fprintf(w, "                gcloud auth application-default login\n")
+	fmt.Fprintf(w, "              Example:\n")
+	fmt.Fprintf(w, "                GEMINI_API_KEY=AIza... kopilot --ai-provider=gemini\n")
+	fmt.Fprintf(w, "\nEnvironment Variables:\n")
+	fmt.Fprintf(w, "  KUBECONFIG        Path to kubeconfig file (default: ~/.kube/config)\n")
+	fmt.Fprintf(w, "  OPENAI_API_KEY    API key for --ai-provider=openai\n")
+	fmt.Fprintf(w, "  OPENAI_BASE_URL   Custom API base URL for OpenAI-compatible backends\n")
+	fmt.Fprintf(w, "  GEMINI_API_KEY    API key for --ai-provider=gemini\n")
+	fmt.Fprintf(w, "  KOPILOT_APPROVAL_WEBHOOK  Default for --approval-webhook\n")
+	fmt.Fprintf(w, "  KOPILOT_APPROVAL_TOKEN    Bearer token sent to the approval webhook\n")
+	fmt.Fprintf(w, "  KOPILOT_PRICE_TABLE       Default for --price-table\n")
+	fmt.Fprintf(w, "  KOPILOT_ADVISORY_FEED     Default for --advisory-feed\n")
+	fmt.Fprintf(w, "  KOPILOT_LANGUAGE          Default for --language\n")
+	fmt.Fprintf(w, "  KOPILOT_THEME             Default for --theme\n")
+	fmt.Fprintf(w, "  NO_COLOR                  Disables colours when set to any value\n")
+	fmt.Fprintf(w, "  KOPILOT_DEBUG             Default for --debug\n")
+	fmt.Fprintf(w, "  OTEL_EXPORTER_OTLP_ENDPOINT  Enables trace export (standard OTEL_* variables apply)\n")
+	fmt.Fprintf(w, "\nExamples:\n")
+	fmt.Fprintf(w, "  kopilot                                           # GitHub Copilot, read-only\n")
+	fmt.Fprintf(w, "  kopilot --interactive                             # interactive mode\n")
+	fmt.Fprintf(w, "  kopilot --agent debugger                          # debugging specialist\n")
+	fmt.Fprintf(w, "  kopilot --agent security                          # security auditor\n")
+	fmt.Fprintf(w, "  kopilot --agent optimizer                         # optimization specialist\n")
+	fmt.Fprintf(w, "  kopilot --agent gitops                            # GitOps specialist\n")
+	fmt.Fprintf(w, "  kopilot --agent sanitizer                         # cluster sanitizer\n")
+	fmt.Fprintf(w, "  OPENAI_API_KEY=sk-... kopilot --ai-provider=openai\n")
+	fmt.Fprintf(w, "  OPENAI_BASE_URL=http://localhost:11434/v1 kopilot --ai-provider=openai  # Ollama\n")
+	fmt.Fprintf(w, "  GEMINI_API_KEY=AIza... kopilot --ai-provider=gemini\n")
+	fmt.Fprintf(w, "  kopilot --mcp-config ./mcp.json                   # custom MCP server config\n")
+	fmt.Fprintf(w, "  kopilot -v                                        # verbose logging\n")
+	fmt.Fprintf(w, "  kopilot --debug=k8s,tools                         # log API requests and tool calls\n")
+	fmt.Fprintf(w, "\nBatch Commands:\n")
+	fmt.Fprintf(w, "  kopilot check                                     # check all clusters once\n")
+	fmt.Fprintf(w, "  KOPILOT_HEALTH_HISTORY=on kopilot check           # and record the health history (kept 30d)\n")
+	fmt.Fprintf(w, "  kopilot check --report status.html                # and write a report: .json .yaml .md .html\n")
+	fmt.Fprintf(w, "  kopilot report -o status.html prod staging        # render a report of selected clusters\n")
+	fmt.Fprintf(w, "  kopilot serve --context production                # stdio MCP server\n")
+	fmt.Fprintf(w, "  kopilot dashboard                                 # live health grid beside a chat\n")
+}
@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/e9169/kopilot/pkg/agent"
+	"github.com/e9169/kopilot/pkg/debug"
 	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/report"
+	"github.com/e9169/kopilot/pkg/telemetry"
 )
 
 var (
@@ -21,134 +27,49 @@ var (
 )
 
 func main() {
-	// Parse command-line flags
-	showVersion := flag.Bool("version", false, "Show version information")
-	verbose := flag.Bool("verbose", false, "Enable verbose logging")
-	interactive := flag.Bool("interactive", false, "Enable interactive mode (asks before write operations)")
-	defaultKubeconfig := os.Getenv("KUBECONFIG")
-	if defaultKubeconfig == "" {
-		if homeDir, err := os.UserHomeDir(); err == nil {
-			defaultKubeconfig = filepath.Join(homeDir, ".kube", "config")
-		}
-	}
-	kubeconfig := flag.String("kubeconfig", defaultKubeconfig, "Path to kubeconfig file (default: $KUBECONFIG or ~/.kube/config)")
-	contextName := flag.String("context", "", "Override kubeconfig context")
-	outputFormat := flag.String("output", string(agent.OutputText), "Output format: text or json")
-	agentName := flag.String("agent", string(agent.AgentDefault), "Specialist agent persona: default, debugger, security, optimizer, gitops")
-	mcpConfig := flag.String("mcp-config", "", "Path to MCP server config file (default: ~/.kopilot/mcp.json)")
-	aiProvider := flag.String("ai-provider", "copilot", "AI provider to use: copilot, openai, gemini")
-	mcpServer := flag.Bool("mcp-server", false, "Run as a stdio MCP server (compatible with any MCP client)")
-	flag.BoolVar(verbose, "v", false, "Enable verbose logging (shorthand)")
-
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Kopilot - Kubernetes Cluster Status Agent\n\n")
-		fmt.Fprintf(os.Stderr, "Usage:\n")
-		fmt.Fprintf(os.Stderr, "  kopilot [flags]\n\n")
-		fmt.Fprintf(os.Stderr, "Flags:\n")
-		flag.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nExecution Modes:\n")
-		fmt.Fprintf(os.Stderr, "  Read-only (default): Blocks all write operations for safety\n")
-		fmt.Fprintf(os.Stderr, "  Interactive (--interactive): Asks for confirmation before write operations\n")
-		fmt.Fprintf(os.Stderr, "\nSpecialist Agents:\n")
-		fmt.Fprintf(os.Stderr, "  default    Standard Kopilot persona\n")
-		fmt.Fprintf(os.Stderr, "  debugger   Root cause analysis and pod failure diagnosis\n")
-		fmt.Fprintf(os.Stderr, "  security   RBAC auditing and privilege escalation detection\n")
-		fmt.Fprintf(os.Stderr, "  optimizer  Resource right-sizing and cost optimization\n")
-		fmt.Fprintf(os.Stderr, "  gitops     Flux/ArgoCD sync status and drift detection\n")
-		fmt.Fprintf(os.Stderr, "  sanitizer  Cluster linting, best-practice scoring, and compliance grading\n")
-		fmt.Fprintf(os.Stderr, "\nRuntime Commands:\n")
-		fmt.Fprintf(os.Stderr, "  /readonly          Switch to read-only mode\n")
-		fmt.Fprintf(os.Stderr, "  /interactive       Switch to interactive mode\n")
-		fmt.Fprintf(os.Stderr, "  /mode              Show current execution mode\n")
-		fmt.Fprintf(os.Stderr, "  /agent             Show active agent and available agents\n")
-		fmt.Fprintf(os.Stderr, "  /agent <name>      Switch to a different specialist agent\n")
-		fmt.Fprintf(os.Stderr, "  /mcp list          List configured MCP servers\n")
-		fmt.Fprintf(os.Stderr, "  /mcp add <n> <url> Add an MCP server\n")
-		fmt.Fprintf(os.Stderr, "  /mcp delete <name> Remove an MCP server\n")
-		fmt.Fprintf(os.Stderr, "\nAI Providers:\n")
-		fmt.Fprintf(os.Stderr, "  copilot   GitHub Copilot (default)\n")
-		fmt.Fprintf(os.Stderr, "              Auth: Copilot CLI token (automatic when gh copilot is installed)\n")
-		fmt.Fprintf(os.Stderr, "              No extra environment variables required.\n")
-		fmt.Fprintf(os.Stderr, "\n")
-		fmt.Fprintf(os.Stderr, "  openai    OpenAI or any OpenAI-compatible API (Ollama, Azure OpenAI, Anthropic…)\n")
-		fmt.Fprintf(os.Stderr, "              OPENAI_API_KEY    API key (required; use 'none' for local models)\n")
-		fmt.Fprintf(os.Stderr, "              OPENAI_BASE_URL   Custom base URL (optional, for non-OpenAI backends)\n")
-		fmt.Fprintf(os.Stderr, "              Examples:\n")
-		fmt.Fprintf(os.Stderr, "                OPENAI_API_KEY=sk-... kopilot --ai-provider=openai\n")
-		fmt.Fprintf(os.Stderr, "                OPENAI_BASE_URL=http://localhost:11434/v1 kopilot --ai-provider=openai  # Ollama\n")
-		fmt.Fprintf(os.Stderr, "\n")
-		fmt.Fprintf(os.Stderr, "  gemini    Google Gemini (Gemini 1.5 / 2.0 / 2.5)\n")
-		fmt.Fprintf(os.Stderr, "              GEMINI_API_KEY    API key from https://ai.google.dev (recommended)\n")
-		fmt.Fprintf(os.Stderr, "              Alternatively, authenticate with Application Default Credentials:\n")
-		fmt.Fprintf(os.Stderr, "                gcloud auth application-default login\n")
-		fmt.Fprintf(os.Stderr, "              Example:\n")
-		fmt.Fprintf(os.Stderr, "                GEMINI_API_KEY=AIza... kopilot --ai-provider=gemini\n")
-		fmt.Fprintf(os.Stderr, "\nEnvironment Variables:\n")
-		fmt.Fprintf(os.Stderr, "  KUBECONFIG        Path to kubeconfig file (default: ~/.kube/config)\n")
-		fmt.Fprintf(os.Stderr, "  OPENAI_API_KEY    API key for --ai-provider=openai\n")
-		fmt.Fprintf(os.Stderr, "  OPENAI_BASE_URL   Custom API base URL for OpenAI-compatible backends\n")
-		fmt.Fprintf(os.Stderr, "  GEMINI_API_KEY    API key for --ai-provider=gemini\n")
-		fmt.Fprintf(os.Stderr, "\nExamples:\n")
-		fmt.Fprintf(os.Stderr, "  kopilot                                           # GitHub Copilot, read-only\n")
-		fmt.Fprintf(os.Stderr, "  kopilot --interactive                             # interactive mode\n")
-		fmt.Fprintf(os.Stderr, "  kopilot --agent debugger                         # debugging specialist\n")
-		fmt.Fprintf(os.Stderr, "  kopilot --agent security                         # security auditor\n")
-		fmt.Fprintf(os.Stderr, "  kopilot --agent optimizer                        # optimization specialist\n")
-		fmt.Fprintf(os.Stderr, "  kopilot --agent gitops                           # GitOps specialist\n")
-		fmt.Fprintf(os.Stderr, "  kopilot --agent sanitizer                        # cluster sanitizer\n")
-		fmt.Fprintf(os.Stderr, "  OPENAI_API_KEY=sk-... kopilot --ai-provider=openai\n")
-		fmt.Fprintf(os.Stderr, "  OPENAI_BASE_URL=http://localhost:11434/v1 kopilot --ai-provider=openai  # Ollama\n")
-		fmt.Fprintf(os.Stderr, "  GEMINI_API_KEY=AIza... kopilot --ai-provider=gemini\n")
-		fmt.Fprintf(os.Stderr, "  kopilot --mcp-config ./mcp.json                  # custom MCP server config\n")
-		fmt.Fprintf(os.Stderr, "  kopilot -v                                        # verbose logging\n")
-		fmt.Fprintf(os.Stderr, "\nMCP Server Mode:\n")
-		fmt.Fprintf(os.Stderr, "  kopilot --mcp-server                              # stdio MCP server\n")
-		fmt.Fprintf(os.Stderr, "  kopilot --mcp-server --context production         # specific kube context\n")
-	}
-
-	flag.Parse()
-
-	if *mcpServer {
-		if err := runMCPServer(*kubeconfig, *contextName, *verbose); err != nil {
-			log.Fatalf("MCP server error: %v", err)
-		}
-		os.Exit(0)
-	}
-
-	if *showVersion {
-		fmt.Printf("kopilot version %s\n", version)
-		fmt.Printf("  build date: %s\n", buildDate)
-		fmt.Printf("  git commit: %s\n", gitCommit)
-		os.Exit(0)
+	err := runCommand(os.Args[1:])
+	var found *issuesFound
+	if err != nil && !errors.Is(err, errUsage) && !errors.As(err, &found) {
+		log.Printf("Error: %v", err)
 	}
+	os.Exit(exitCodeOf(err))
+}
 
-	// Set log output based on verbose flag
-	if !*verbose {
-		log.SetFlags(0) // Remove timestamp for cleaner output
+// setupDebug enables the --debug categories; --verbose turns them all on.
+func setupDebug(spec string, verbose bool) error {
+	if verbose {
+		spec = "all"
 	}
-
-	// Determine execution mode
-	mode := agent.ModeReadOnly
-	if *interactive {
-		mode = agent.ModeInteractive
+	cats, err := debug.Parse(spec)
+	if err != nil {
+		return err
 	}
+	debug.Enable(cats...)
+	return nil
+}
 
-	format := agent.OutputFormat(*outputFormat)
-	if format != agent.OutputText && format != agent.OutputJSON {
-		log.Fatalf("Invalid --output value: %s (use 'text' or 'json')", *outputFormat)
+// setupTracing enables OpenTelemetry trace export when an OTLP endpoint is
+// configured and returns a function flushing pending spans before exit. A
+// failing exporter only disables tracing.
+func setupTracing(endpoint string) func() {
+	shutdown, err := telemetry.Setup(context.Background(), endpoint, version)
+	if err != nil {
+		log.Printf("Warning: tracing disabled: %v", err)
+		return func() {}
 	}
-
-	agentType, agentErr := agent.ParseAgentType(*agentName)
-	if agentErr != nil {
-		log.Fatalf("Invalid --agent value: %v", agentErr)
+	if telemetry.Enabled(endpoint) {
+		log.Printf("Exporting OpenTelemetry traces over OTLP")
 	}
-
-	if err := run(mode, *kubeconfig, *contextName, format, agentType, *mcpConfig, *aiProvider); err != nil {
-		log.Fatalf("Error: %v", err)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			log.Printf("Warning: failed to flush traces: %v", err)
+		}
 	}
 }
 
-func run(mode agent.ExecutionMode, kubeconfigPath string, contextName string, outputFormat agent.OutputFormat, agentType agent.AgentType, mcpConfigPath string, providerName string) error {
+func run(mode agent.ExecutionMode, kubeconfigPath string, providerOpts []k8s.ProviderOption, contextName string, priceTablePath string, advisoryFeedPath string, outputFormat agent.OutputFormat, agentType agent.AgentType, mcpConfigPath string, providerName string, opts ...agent.Option) error {
 	// Set version in agent package for display
 	agent.AppVersion = version
 
@@ -161,7 +82,7 @@ func run(mode agent.ExecutionMode, kubeconfigPath string, contextName string, ou
 	log.Printf("Using kubeconfig: %s", kubeconfigPath)
 
 	// Initialize Kubernetes provider
-	k8sProvider, err := k8s.NewProvider(kubeconfigPath)
+	k8sProvider, err := k8s.NewProvider(kubeconfigPath, providerOpts...)
 	if err != nil {
 		return fmt.Errorf("failed to initialize kubernetes provider: %w", err)
 	}
@@ -173,6 +94,13 @@ func run(mode agent.ExecutionMode, kubeconfigPath string, contextName string, ou
 		log.Printf("Using context override: %s", contextName)
 	}
 
+	if err := configureCost(k8sProvider, priceTablePath); err != nil {
+		return err
+	}
+	if err := configureAdvisories(k8sProvider, advisoryFeedPath); err != nil {
+		return err
+	}
+
 	log.Printf("Successfully loaded %d cluster(s) from kubeconfig", len(k8sProvider.GetClusters()))
 
 	// Initialize LLM provider
@@ -183,14 +111,14 @@ func run(mode agent.ExecutionMode, kubeconfigPath string, contextName string, ou
 
 	// Initialize and run the agent
 	log.Println("Starting kopilot agent...")
-	if err := agent.Run(k8sProvider, mode, outputFormat, agentType, mcpConfigPath, provider); err != nil {
+	if err := agent.Run(k8sProvider, mode, outputFormat, agentType, mcpConfigPath, provider, opts...); err != nil {
 		return fmt.Errorf("failed to run agent: %w", err)
 	}
 
 	return nil
 }
 
-func runMCPServer(kubeconfigPath, contextName string, verbose bool) error {
+func runMCPServer(kubeconfigPath string, providerOpts []k8s.ProviderOption, contextName, priceTablePath, advisoryFeedPath string, verbose bool) error {
 	agent.AppVersion = version
 	if !verbose {
 		log.SetOutput(io.Discard)
@@ -198,7 +126,7 @@ func runMCPServer(kubeconfigPath, contextName string, verbose bool) error {
 	if _, err := os.Stat(kubeconfigPath); os.IsNotExist(err) { // #nosec G703
 		return fmt.Errorf("kubeconfig not found at %s: %w", kubeconfigPath, err)
 	}
-	k8sProvider, err := k8s.NewProvider(kubeconfigPath)
+	k8sProvider, err := k8s.NewProvider(kubeconfigPath, providerOpts...)
 	if err != nil {
 		return fmt.Errorf("failed to initialize kubernetes provider: %w", err)
 	}
@@ -207,5 +135,225 @@ func runMCPServer(kubeconfigPath, contextName string, verbose bool) error {
 			return fmt.Errorf("failed to set context: %w", err)
 		}
 	}
+	if err := configureCost(k8sProvider, priceTablePath); err != nil {
+		return err
+	}
+	if err := configureAdvisories(k8sProvider, advisoryFeedPath); err != nil {
+		return err
+	}
 	return agent.RunMCPServer(k8sProvider)
 }
+
+// runDashboard loads the kubeconfig like run, quietly as the dashboard owns
+// the screen, and starts the dashboard.
+func runDashboard(kubeconfigPath string, providerOpts []k8s.ProviderOption, contextName, priceTablePath, advisoryFeedPath, providerName string, interval time.Duration, opts ...agent.Option) error {
+	agent.AppVersion = version
+	if _, err := os.Stat(kubeconfigPath); os.IsNotExist(err) { // #nosec G703
+		return fmt.Errorf("kubeconfig not found at %s: %w", kubeconfigPath, err)
+	}
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	k8sProvider, err := k8s.NewProvider(kubeconfigPath, providerOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to initialize kubernetes provider: %w", err)
+	}
+	if contextName != "" {
+		if err := k8sProvider.SetCurrentContext(contextName); err != nil {
+			return fmt.Errorf("failed to set context: %w", err)
+		}
+	}
+	if err := configureCost(k8sProvider, priceTablePath); err != nil {
+		return err
+	}
+	if err := configureAdvisories(k8sProvider, advisoryFeedPath); err != nil {
+		return err
+	}
+	provider, err := agent.NewProviderByName(providerName)
+	if err != nil {
+		return err
+	}
+	if err := agent.RunDashboard(k8sProvider, provider, interval, opts...); err != nil {
+		return fmt.Errorf("failed to run dashboard: %w", err)
+	}
+	return nil
+}
+
+// defaultKubeconfigPath returns $KUBECONFIG, or ~/.kube/config.
+func defaultKubeconfigPath() string {
+	if path := os.Getenv("KUBECONFIG"); path != "" {
+		return path
+	}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(homeDir, ".kube", "config")
+	}
+	return ""
+}
+
+// runReportCommand implements "kopilot report": it renders a Markdown or
+// HTML health report of all clusters, or of the contexts given as arguments,
+// to stdout or a file. A report with warning or critical issues returns
+// issuesFound.
+func runReportCommand(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	kubeconfig := fs.String("kubeconfig", defaultKubeconfigPath(), "Path to kubeconfig file (default: $KUBECONFIG or ~/.kube/config)")
+	format := fs.String("format", "", "Report format: markdown or html (default: from the --output extension, else markdown)")
+	output := fs.String("output", "", "Write the report to this file instead of stdout")
+	fs.StringVar(output, "o", "", "Write the report to this file (shorthand)")
+	title := fs.String("title", "", "Report title (default: Kopilot Cluster Report)")
+	priceTable := fs.String("price-table", os.Getenv("KOPILOT_PRICE_TABLE"), "Path to a JSON node price table enabling cost estimates")
+	api := addAPIFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: kopilot report [flags] [context...]\n\n")
+		fmt.Fprintf(fs.Output(), "Render a health report of all clusters (or the given contexts) without an AI session.\n\n")
+		fmt.Fprintf(fs.Output(), "Flags:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nExamples:\n")
+		fmt.Fprintf(fs.Output(), "  kopilot report > status.md\n")
+		fmt.Fprintf(fs.Output(), "  kopilot report -o status.html prod staging\n")
+		fmt.Fprintf(fs.Output(), "\nExit status: 0 healthy, 1 warnings, 2 critical issues, 3 error.\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	log.SetFlags(0)
+
+	reportFormat := *format
+	if reportFormat == "" && *output != "" {
+		f, err := report.FormatForPath(*output)
+		if err != nil {
+			return err
+		}
+		reportFormat = f
+	}
+	reportFormat, err := report.ParseFormat(reportFormat)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(*kubeconfig); os.IsNotExist(err) { // #nosec G703
+		return fmt.Errorf("kubeconfig not found at %s: %w", *kubeconfig, err)
+	}
+	providerOpts, err := api.providerOptions()
+	if err != nil {
+		return err
+	}
+	k8sProvider, err := k8s.NewProvider(*kubeconfig, providerOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to initialize kubernetes provider: %w", err)
+	}
+	if err := configureCost(k8sProvider, *priceTable); err != nil {
+		return err
+	}
+	r, err := agent.GenerateReport(context.Background(), k8sProvider, fs.Args(), *title)
+	if err != nil {
+		return err
+	}
+	data, err := report.Render(r, reportFormat)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		if _, err := os.Stdout.Write(data); err != nil {
+			return err
+		}
+		return checkOutcome(r.Summary.Critical, r.Summary.Warnings)
+	}
+	if err := os.WriteFile(*output, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	log.Printf("Wrote %s report of %d cluster(s) to %s (%d critical, %d warning issue(s))",
+		reportFormat, r.Summary.Clusters, *output, r.Summary.Critical, r.Summary.Warnings)
+	return checkOutcome(r.Summary.Critical, r.Summary.Warnings)
+}
+
+// runCheck checks the given contexts, all clusters when empty, once without
+// starting an AI session. It prints the result in format, unless format is
+// empty, and writes the report to reportPath when it is set. A check finding
+// warning or critical issues returns issuesFound.
+func runCheck(kubeconfigPath string, providerOpts []k8s.ProviderOption, contexts []string, priceTablePath, reportPath string, format agent.OutputFormat, thresholds agent.HealthThresholds) error {
+	if reportPath != "" {
+		if err := agent.ValidateReportPath(reportPath); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(kubeconfigPath); os.IsNotExist(err) { // #nosec G703
+		return fmt.Errorf("kubeconfig not found at %s: %w", kubeconfigPath, err)
+	}
+	k8sProvider, err := k8s.NewProvider(kubeconfigPath, providerOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to initialize kubernetes provider: %w", err)
+	}
+	for _, contextName := range contexts {
+		if _, err := k8sProvider.GetClusterByContext(contextName); err != nil {
+			return err
+		}
+	}
+	if err := configureCost(k8sProvider, priceTablePath); err != nil {
+		return err
+	}
+	ctx, stopProgress := agent.ShowCheckProgress(context.Background(), os.Stderr)
+	r := agent.CheckClusters(ctx, k8sProvider, contexts, thresholds)
+	stopProgress()
+	if reportPath != "" {
+		if err := agent.WriteClusterReport(reportPath, r); err != nil {
+			return err
+		}
+		log.Printf("Wrote status of %d cluster(s) (%d reachable, %d healthy) to %s",
+			r.Summary.TotalClusters, r.Summary.Reachable, r.Summary.FullyHealthy, reportPath)
+	}
+	if format != "" {
+		if err := agent.WriteCheckResult(os.Stdout, r, format); err != nil {
+			return err
+		}
+	}
+	return checkOutcome(r.Summary.Critical, r.Summary.Warnings)
+}
+
+// configureCost loads the node price table and enables cost estimates. An
+// empty path falls back to ~/.kopilot/prices.json; estimates stay off when
+// that file does not exist.
+func configureCost(k8sProvider *k8s.Provider, path string) error {
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, ".kopilot", "prices.json")
+		if _, err := os.Stat(path); err != nil {
+			return nil
+		}
+	}
+	table, err := k8s.LoadPriceTable(path)
+	if err != nil {
+		return err
+	}
+	k8sProvider.SetPriceTable(table)
+	log.Printf("Cost estimates enabled from %s", path)
+	return nil
+}
+
+// configureAdvisories loads a node advisory feed replacing the bundled one.
+// An empty path falls back to ~/.kopilot/advisories.json; the bundled feed is
+// used when that file does not exist.
+func configureAdvisories(k8sProvider *k8s.Provider, path string) error {
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, ".kopilot", "advisories.json")
+		if _, err := os.Stat(path); err != nil {
+			return nil
+		}
+	}
+	feed, err := k8s.LoadAdvisoryFeed(path)
+	if err != nil {
+		return err
+	}
+	k8sProvider.SetAdvisoryFeed(feed)
+	log.Printf("Node advisory feed %s loaded from %s", feed.Version, path)
+	return nil
+}
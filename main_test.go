@@ -1,9 +1,17 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/agent"
+	"github.com/e9169/kopilot/pkg/debug"
+	"github.com/e9169/kopilot/pkg/k8s"
 
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
@@ -201,3 +209,227 @@ func BenchmarkKubeconfigPathLookup(b *testing.B) {
 		}
 	}
 }
+
+func TestConfigureCost(t *testing.T) {
+	tmpfile, cleanup := createTestKubeconfig(t)
+	defer cleanup()
+	provider, err := k8s.NewProvider(tmpfile)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+
+	// No flag and no ~/.kopilot/prices.json: estimates stay off.
+	t.Setenv("HOME", t.TempDir())
+	if err := configureCost(provider, ""); err != nil {
+		t.Errorf("configureCost without a table: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "prices.json")
+	if err := os.WriteFile(path, []byte(`{"instance_types":{"m5.large":0.096}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := configureCost(provider, path); err != nil {
+		t.Errorf("configureCost(%s): %v", path, err)
+	}
+	if err := configureCost(provider, filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("configureCost with a missing explicit path succeeded, want an error")
+	}
+}
+
+func TestConfigureAdvisories(t *testing.T) {
+	tmpfile, cleanup := createTestKubeconfig(t)
+	defer cleanup()
+	provider, err := k8s.NewProvider(tmpfile)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+
+	// No flag and no ~/.kopilot/advisories.json: the bundled feed stays.
+	t.Setenv("HOME", t.TempDir())
+	if err := configureAdvisories(provider, ""); err != nil {
+		t.Errorf("configureAdvisories without a feed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "advisories.json")
+	if err := os.WriteFile(path, []byte(`{"version":"local","advisories":[]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := configureAdvisories(provider, path); err != nil {
+		t.Errorf("configureAdvisories(%s): %v", path, err)
+	}
+	if err := configureAdvisories(provider, filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("configureAdvisories with a missing explicit path succeeded, want an error")
+	}
+}
+
+func TestRunCheckErrors(t *testing.T) {
+	if err := runCheck(filepath.Join(t.TempDir(), "missing"), nil, nil, "", "status.json", "", agent.HealthThresholds{}); err == nil {
+		t.Error("runCheck with a missing kubeconfig succeeded, want an error")
+	}
+
+	tmpfile, cleanup := createTestKubeconfig(t)
+	defer cleanup()
+	t.Setenv("HOME", t.TempDir())
+	if err := runCheck(tmpfile, nil, []string{"missing-context"}, "", "", agent.OutputText, agent.HealthThresholds{}); err == nil {
+		t.Error("runCheck of an unknown context succeeded, want an error")
+	}
+	// The format is checked before any cluster is contacted.
+	if err := runCheck(tmpfile, nil, nil, "", filepath.Join(t.TempDir(), "status.pdf"), "", agent.HealthThresholds{}); err == nil {
+		t.Error("runCheck with an unsupported extension succeeded, want an error")
+	}
+}
+
+func TestSplitContexts(t *testing.T) {
+	if got := splitContexts(" prod, ,staging "); len(got) != 2 || got[0] != "prod" || got[1] != "staging" {
+		t.Errorf("splitContexts = %q, want [prod staging]", got)
+	}
+	if got := splitContexts(""); got != nil {
+		t.Errorf("splitContexts(\"\") = %q, want nil", got)
+	}
+}
+
+func TestAPIFlags(t *testing.T) {
+	t.Setenv("KOPILOT_API_TIMEOUT", "1m")
+	t.Setenv("KOPILOT_API_RETRIES", "soon")
+	t.Setenv("KOPILOT_MAX_PODS", "100")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	api := addAPIFlags(fs)
+	if api.timeout != time.Minute || api.retries != k8s.DefaultAPIRetries || api.maxPods != 100 {
+		t.Errorf("env defaults = %s, %d retries, %d pods; want 1m, the default for an invalid value and 100", api.timeout, api.retries, api.maxPods)
+	}
+	if _, err := api.providerOptions(); err != nil {
+		t.Errorf("providerOptions() = %v", err)
+	}
+	for _, args := range [][]string{{"--api-timeout", "0s"}, {"--discovery-timeout", "-1s"}, {"--api-retries", "11"}, {"--max-pods", "-1"}, {"--live-state-after", "-1"}, {"--reach-timeout", "-1s"}} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		api := addAPIFlags(fs)
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := api.providerOptions(); err == nil {
+			t.Errorf("providerOptions(%q) succeeded, want an error", args)
+		}
+	}
+}
+
+func TestRunReportCommandErrors(t *testing.T) {
+	if err := runReportCommand([]string{"--help"}); err != nil {
+		t.Errorf("report --help: %v", err)
+	}
+	if err := runReportCommand([]string{"--format", "pdf"}); err == nil {
+		t.Error("report --format pdf succeeded, want an error")
+	}
+	if err := runReportCommand([]string{"-o", "status.json"}); err == nil {
+		t.Error("report -o status.json succeeded, want an error")
+	}
+	if err := runReportCommand([]string{"--kubeconfig", filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("report with a missing kubeconfig succeeded, want an error")
+	}
+
+	tmpfile, cleanup := createTestKubeconfig(t)
+	defer cleanup()
+	t.Setenv("HOME", t.TempDir())
+	if err := runReportCommand([]string{"--kubeconfig", tmpfile, "no-such-context"}); err == nil {
+		t.Error("report for an unknown context succeeded, want an error")
+	}
+}
+
+func TestSetupDebug(t *testing.T) {
+	defer debug.Enable()
+
+	if err := setupDebug("k8s,cache", false); err != nil {
+		t.Fatalf("setupDebug() error: %v", err)
+	}
+	if !debug.Enabled(debug.K8s) || !debug.Enabled(debug.Cache) || debug.Enabled(debug.Tools) {
+		t.Error("only k8s and cache should be enabled")
+	}
+	if err := setupDebug("", true); err != nil || !debug.Enabled(debug.Copilot) || !debug.Enabled(debug.Tools) {
+		t.Errorf("--verbose should enable every category (err: %v)", err)
+	}
+	if err := setupDebug("bogus", false); err == nil {
+		t.Error("unknown category should fail")
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	for _, args := range [][]string{{"version"}, {"--version"}, {"help"}, {"help", "check"}, {"serve", "--help"}, {"chat", "-h"}, {"dashboard", "--help"}} {
+		if err := runCommand(args); err != nil {
+			t.Errorf("kopilot %v: %v", args, err)
+		}
+	}
+	if err := runCommand([]string{"bogus"}); err == nil {
+		t.Error("unknown command succeeded, want an error")
+	}
+	if err := runCommand([]string{"help", "bogus"}); err == nil {
+		t.Error("help for an unknown command succeeded, want an error")
+	}
+	if err := runCommand([]string{"check", "--no-such-flag"}); !errors.Is(err, errUsage) {
+		t.Errorf("unknown flag: err = %v, want errUsage", err)
+	}
+	// --report and --mcp-server stay out of the chat command.
+	if err := runCommand([]string{"chat", "--report", "status.json"}); !errors.Is(err, errUsage) {
+		t.Errorf("chat --report: err = %v, want errUsage", err)
+	}
+	if err := runCommand([]string{"check", "prod"}); err == nil {
+		t.Error("check with an argument succeeded, want an error")
+	}
+	if err := runCommand([]string{"check", "--output", "yaml"}); err == nil {
+		t.Error("check --output yaml succeeded, want an error")
+	}
+	if err := runCommand([]string{"check", "--debug", "bogus"}); err == nil {
+		t.Error("check with an invalid --debug succeeded, want an error")
+	}
+	if err := runCommand([]string{"dashboard", "--interval", "0s"}); err == nil {
+		t.Error("dashboard --interval 0s succeeded, want an error")
+	}
+	if err := runCommand([]string{"dashboard", "--theme", "solarized"}); err == nil {
+		t.Error("dashboard with an unknown --theme succeeded, want an error")
+	}
+}
+
+func TestRunCheckCommand(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	missing := filepath.Join(t.TempDir(), "missing")
+	if err := runCommand([]string{"check", "--kubeconfig", missing}); err == nil {
+		t.Error("check with a missing kubeconfig succeeded, want an error")
+	}
+	// The legacy --report flag is the same check.
+	if err := runCommand([]string{"--kubeconfig", missing, "--report", "status.json"}); err == nil {
+		t.Error("--report with a missing kubeconfig succeeded, want an error")
+	}
+
+	tmpfile, cleanup := createTestKubeconfig(t)
+	defer cleanup()
+	if err := runCommand([]string{"check", "--kubeconfig", tmpfile, "--report", filepath.Join(t.TempDir(), "status.pdf")}); err == nil {
+		t.Error("check with an unsupported report extension succeeded, want an error")
+	}
+	badConfig := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(badConfig, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCommand([]string{"check", "--kubeconfig", tmpfile, "--config", badConfig}); err == nil {
+		t.Error("check with an invalid config succeeded, want an error")
+	}
+}
+
+func TestExitCodeOf(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{nil, exitHealthy},
+		{checkOutcome(0, 0), exitHealthy},
+		{checkOutcome(0, 2), exitWarnings},
+		{checkOutcome(1, 2), exitCritical},
+		{fmt.Errorf("wrapped: %w", checkOutcome(1, 0)), exitCritical},
+		{errUsage, exitError},
+		{errors.New("kubeconfig not found"), exitError},
+	} {
+		if got := exitCodeOf(tc.err); got != tc.want {
+			t.Errorf("exitCodeOf(%v) = %d, want %d", tc.err, got, tc.want)
+		}
+	}
+	if err := checkOutcome(1, 2); err.Error() != "1 critical and 2 warning issue(s) found" {
+		t.Errorf("issuesFound message = %q", err)
+	}
+}
@@ -0,0 +1,251 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains acknowledged issues (/ack), excluded from health summaries.
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+// Acknowledged resource kinds.
+const (
+	ackPod  = "pod"
+	ackNode = "node"
+)
+
+// Ack acknowledges a known issue, e.g. a pod expected to fail or an
+// intentionally cordoned node, so health summaries stop reporting it.
+// Cluster, Namespace and Name are glob patterns; an empty cluster matches all.
+type Ack struct {
+	Kind      string    `json:"kind"`
+	Cluster   string    `json:"cluster,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	Name      string    `json:"name"`
+	Reason    string    `json:"reason,omitempty"`
+	Added     time.Time `json:"added,omitempty"`
+}
+
+// String formats the ack as accepted by /ack, e.g. "pod prod/shop/api-*".
+func (a Ack) String() string {
+	target := a.Name
+	if a.Kind == ackPod {
+		target = a.Namespace + "/" + target
+	}
+	if a.Cluster != "" {
+		target = a.Cluster + "/" + target
+	}
+	return a.Kind + " " + target
+}
+
+// validate rejects unknown kinds, missing names and malformed patterns.
+func (a Ack) validate() error {
+	if a.Kind != ackPod && a.Kind != ackNode {
+		return fmt.Errorf("ack kind %q must be pod or node", a.Kind)
+	}
+	if a.Name == "" {
+		return fmt.Errorf("ack %q needs a name", a.String())
+	}
+	if a.Kind == ackPod && a.Namespace == "" {
+		return fmt.Errorf("ack %q needs a namespace", a.String())
+	}
+	for _, pattern := range []string{a.Cluster, a.Namespace, a.Name} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("ack pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// globMatch reports whether value matches pattern; an empty pattern matches all.
+func globMatch(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
+
+// matchesPod reports whether the ack covers pod of cluster.
+func (a Ack) matchesPod(cluster string, pod k8s.PodInfo) bool {
+	return a.Kind == ackPod && globMatch(a.Cluster, cluster) && globMatch(a.Namespace, pod.Namespace) && globMatch(a.Name, pod.Name)
+}
+
+// matchesNode reports whether the ack covers node of cluster.
+func (a Ack) matchesNode(cluster, node string) bool {
+	return a.Kind == ackNode && globMatch(a.Cluster, cluster) && globMatch(a.Name, node)
+}
+
+// parseAck reads "/ack" arguments: "pod [cluster/]namespace/name [reason]"
+// or "node [cluster/]name [reason]".
+func parseAck(args []string) (Ack, error) {
+	if len(args) < 2 {
+		return Ack{}, fmt.Errorf("usage: /ack pod [cluster/]namespace/name [reason] | /ack node [cluster/]name [reason]")
+	}
+	a := Ack{Kind: strings.ToLower(args[0]), Reason: strings.Join(args[2:], " "), Added: time.Now().UTC()}
+	parts := strings.Split(args[1], "/")
+	switch {
+	case a.Kind == ackPod && len(parts) == 2:
+		a.Namespace, a.Name = parts[0], parts[1]
+	case a.Kind == ackPod && len(parts) == 3:
+		a.Cluster, a.Namespace, a.Name = parts[0], parts[1], parts[2]
+	case a.Kind == ackNode && len(parts) == 1:
+		a.Name = parts[0]
+	case a.Kind == ackNode && len(parts) == 2:
+		a.Cluster, a.Name = parts[0], parts[1]
+	case a.Kind != ackPod && a.Kind != ackNode:
+		return Ack{}, fmt.Errorf("ack kind %q must be pod or node", args[0])
+	default:
+		return Ack{}, fmt.Errorf("invalid %s target %q", a.Kind, args[1])
+	}
+	return a, a.validate()
+}
+
+// DefaultAcksPath returns the file storing /ack entries:
+// $HOME/.kopilot/acks.json, falling back to ".kopilot/acks.json" on error.
+func DefaultAcksPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".kopilot", "acks.json")
+	}
+	return filepath.Join(home, ".kopilot", "acks.json")
+}
+
+// LoadAcks reads the /ack entries from path (the default path when empty).
+// If the file does not exist no entries are returned without error.
+func LoadAcks(path string) ([]Ack, error) {
+	if path == "" {
+		path = DefaultAcksPath()
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading acks: %w", err)
+	}
+	var acks []Ack
+	if err := json.Unmarshal(data, &acks); err != nil {
+		return nil, fmt.Errorf("parsing acks: %w", err)
+	}
+	return acks, nil
+}
+
+// saveAcks writes the /ack entries to path, creating parent directories as needed.
+func saveAcks(path string, acks []Ack) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating acks directory: %w", err)
+	}
+	if acks == nil {
+		acks = []Ack{}
+	}
+	data, err := json.MarshalIndent(acks, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding acks: %w", err)
+	}
+	// Write atomically via temp file in the same directory.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing acks: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("saving acks: %w", err)
+	}
+	return nil
+}
+
+// healthThresholds returns the configured thresholds with the /ack entries added.
+func (s *agentState) healthThresholds() HealthThresholds {
+	th := s.health
+	if len(s.acks) > 0 {
+		th.Acknowledged = append(append([]Ack(nil), th.Acknowledged...), s.acks...)
+	}
+	return th
+}
+
+// handleAckCommand processes "/ack" (list), "/ack pod|node <target> [reason]"
+// and "/unack <n|all>". Returns false when input is not an ack command.
+func handleAckCommand(state *agentState, input string) bool {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToLower(fields[0]) {
+	case "/ack":
+		if len(fields) == 1 {
+			printAcks(state)
+			return true
+		}
+		a, err := parseAck(fields[1:])
+		if err != nil {
+			fmt.Printf(fmtErrorBullet, colorRed, colorReset, err)
+			return true
+		}
+		acks := append(append([]Ack(nil), state.acks...), a)
+		if err := saveAcks(state.acksPath, acks); err != nil {
+			fmt.Printf(fmtErrorBullet, colorRed, colorReset, err)
+			return true
+		}
+		state.acks = acks
+		fmt.Printf("  %s●%s Acknowledged %s%s%s — excluded from health summaries\n", colorGreen, colorReset, colorCyan, a, colorReset)
+		return true
+
+	case "/unack":
+		if len(fields) != 2 {
+			fmt.Printf("  %s●%s Usage: /unack <n|all> (numbers from /ack)\n", colorRed, colorReset)
+			return true
+		}
+		var acks []Ack
+		if strings.ToLower(fields[1]) != "all" {
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n < 1 || n > len(state.acks) {
+				fmt.Printf("  %s●%s No acknowledged issue #%s — see /ack (entries from config.json cannot be removed here)\n", colorRed, colorReset, fields[1])
+				return true
+			}
+			acks = append(append(acks, state.acks[:n-1]...), state.acks[n:]...)
+		}
+		if err := saveAcks(state.acksPath, acks); err != nil {
+			fmt.Printf(fmtErrorBullet, colorRed, colorReset, err)
+			return true
+		}
+		removed := len(state.acks) - len(acks)
+		state.acks = acks
+		fmt.Printf("  %s●%s Removed %d acknowledged issue(s)\n", colorGreen, colorReset, removed)
+		return true
+	}
+	return false
+}
+
+// printAcks lists the /ack entries, numbered for /unack, then those of the config file.
+func printAcks(state *agentState) {
+	if len(state.acks) == 0 && len(state.health.Acknowledged) == 0 {
+		fmt.Printf("  %s●%s No acknowledged issues — e.g. /ack pod prod/batch/report-* known failure, /ack node staging/node-3 cordoned\n", colorDim, colorReset)
+		return
+	}
+	fmt.Println()
+	fmt.Printf("  %s%s%s\n", colorCyan, sectionRule("Acknowledged Issues"), colorReset)
+	fmt.Println()
+	for i, a := range state.acks {
+		fmt.Printf("  %s%2d.%s %s%s\n", colorCyan, i+1, colorReset, a, ackReason(a))
+	}
+	for _, a := range state.health.Acknowledged {
+		fmt.Printf("  %s  -%s %s%s %s(config)%s\n", colorDim, colorReset, a, ackReason(a), colorDim, colorReset)
+	}
+	fmt.Println()
+}
+
+// ackReason formats the optional reason of an ack.
+func ackReason(a Ack) string {
+	if a.Reason == "" {
+		return ""
+	}
+	return " — " + a.Reason
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestParseAck(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"pod", "batch/report-*"}, "pod batch/report-*"},
+		{[]string{"POD", "prod/batch/report-1", "known", "failure"}, "pod prod/batch/report-1"},
+		{[]string{"node", "node-3"}, "node node-3"},
+		{[]string{"node", "staging/node-3", "cordoned"}, "node staging/node-3"},
+	}
+	for _, tt := range tests {
+		a, err := parseAck(tt.args)
+		if err != nil || a.String() != tt.want {
+			t.Errorf("parseAck(%v) = %q, %v; want %q", tt.args, a, err, tt.want)
+		}
+	}
+	a, _ := parseAck([]string{"node", "node-3", "cordoned", "for", "repair"})
+	if a.Reason != "cordoned for repair" || a.Added.IsZero() {
+		t.Errorf("ack = %+v, want reason and timestamp", a)
+	}
+
+	for _, args := range [][]string{
+		{"pod"}, {"svc", "shop/web"}, {"pod", "web"}, {"node", "a/b/c"}, {"pod", "shop/["}, {"pod", "shop/"},
+	} {
+		if _, err := parseAck(args); err == nil {
+			t.Errorf("parseAck(%v) should fail", args)
+		}
+	}
+}
+
+func TestAckMatches(t *testing.T) {
+	pod := k8s.PodInfo{Name: "report-42", Namespace: "batch"}
+	if !(Ack{Kind: ackPod, Namespace: "batch", Name: "report-*"}).matchesPod("prod", pod) {
+		t.Error("pattern without cluster should match every cluster")
+	}
+	if (Ack{Kind: ackPod, Cluster: "staging", Namespace: "batch", Name: "report-*"}).matchesPod("prod", pod) {
+		t.Error("ack of another cluster should not match")
+	}
+	if (Ack{Kind: ackNode, Name: "report-*"}).matchesPod("prod", pod) {
+		t.Error("node ack should not match a pod")
+	}
+	if !(Ack{Kind: ackNode, Cluster: "prod-*", Name: "node-3"}).matchesNode("prod-eu", "node-3") {
+		t.Error("node ack should match")
+	}
+}
+
+func TestAcksFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "acks.json")
+	if acks, err := LoadAcks(path); err != nil || acks != nil {
+		t.Fatalf("missing file: %v, %v", acks, err)
+	}
+	want := []Ack{{Kind: ackNode, Name: "node-3", Reason: "cordoned"}}
+	if err := saveAcks(path, want); err != nil {
+		t.Fatal(err)
+	}
+	acks, err := LoadAcks(path)
+	if err != nil || len(acks) != 1 || acks[0].Reason != "cordoned" {
+		t.Errorf("LoadAcks = %+v, %v", acks, err)
+	}
+}
+
+func TestHandleAckCommand(t *testing.T) {
+	state := &agentState{
+		acksPath: filepath.Join(t.TempDir(), "acks.json"),
+		health:   HealthThresholds{Acknowledged: []Ack{{Kind: ackNode, Name: "gpu-*"}}},
+	}
+	if handleAckCommand(state, "/acknowledge") {
+		t.Error("/acknowledge is not an ack command")
+	}
+	for _, cmd := range []string{"/ack", "/ack pod batch/report-*", "/ack node staging/node-3 cordoned", "/ack pod web", "/ack"} {
+		if !handleAckCommand(state, cmd) {
+			t.Errorf("%q should be handled", cmd)
+		}
+	}
+	if len(state.acks) != 2 {
+		t.Fatalf("acks = %+v, want 2", state.acks)
+	}
+	if th := state.healthThresholds(); len(th.Acknowledged) != 3 || len(state.health.Acknowledged) != 1 {
+		t.Errorf("healthThresholds should merge config and /ack entries without changing the config: %+v", th.Acknowledged)
+	}
+	if saved, _ := LoadAcks(state.acksPath); len(saved) != 2 {
+		t.Errorf("saved acks = %+v", saved)
+	}
+
+	for _, cmd := range []string{"/unack", "/unack 9", "/unack 1"} {
+		handleAckCommand(state, cmd)
+	}
+	if len(state.acks) != 1 || state.acks[0].Kind != ackNode {
+		t.Errorf("after /unack 1: %+v", state.acks)
+	}
+	handleAckCommand(state, "/unack all")
+	if saved, err := LoadAcks(state.acksPath); len(state.acks) != 0 || len(saved) != 0 || err != nil {
+		t.Errorf("after /unack all: %+v, saved %+v, %v", state.acks, saved, err)
+	}
+}
+
+func TestAnalyzeClusterHealthAcknowledged(t *testing.T) {
+	statuses := []*k8s.ClusterStatus{{
+		ClusterInfo: k8s.ClusterInfo{Context: "prod", IsReachable: true},
+		NodeCount:   3, HealthyNodes: 2, PodCount: 10, HealthyPods: 9,
+		Nodes: []k8s.NodeInfo{
+			{Name: "node-1", Status: "Ready"}, {Name: "node-2", Status: "Ready"}, {Name: "node-3", Status: "NotReady"},
+		},
+		UnhealthyPods: []k8s.PodInfo{{Name: "report-1", Namespace: "batch", Status: "Failed"}},
+	}}
+	th := HealthThresholds{Acknowledged: []Ack{
+		{Kind: ackNode, Cluster: "prod", Name: "node-3"},
+		{Kind: ackPod, Namespace: "batch", Name: "report-*"},
+	}}
+	summary := analyzeClusterHealth(statuses, th)
+	if len(summary.issues) != 0 || summary.healthyCount != 1 || summary.acknowledged != 2 || summary.totalUnhealthyPods != 0 {
+		t.Errorf("acknowledged issues should be excluded: %+v", summary)
+	}
+
+	r := newClusterReport(statuses, th)
+	if r.Summary.Acknowledged != 2 {
+		t.Errorf("report summary = %+v, want 2 acknowledged", r.Summary)
+	}
+}
@@ -30,20 +30,64 @@ func TestDefineTools(t *testing.T) {
 
 	tools := defineTools(provider, state)
 
-	if len(tools) != 9 {
-		t.Errorf("defineTools() returned %d tools, want 9", len(tools))
+	if len(tools) != 53 {
+		t.Errorf("defineTools() returned %d tools, want 53", len(tools))
 	}
 
 	expectedNames := map[string]bool{
-		toolListClusters:     false,
-		toolGetClusterStatus: false,
-		toolCompareClusters:  false,
-		toolCheckAllClusters: false,
-		toolKubectlExec:      false,
-		toolSanitizeCluster:  false,
-		toolMCPListServers:   false,
-		toolMCPAddServer:     false,
-		toolMCPDeleteServer:  false,
+		toolListClusters:          false,
+		toolGetClusterStatus:      false,
+		toolCompareClusters:       false,
+		toolCheckAllClusters:      false,
+		toolKubectlExec:           false,
+		toolSanitizeCluster:       false,
+		toolMCPListServers:        false,
+		toolMCPAddServer:          false,
+		toolMCPDeleteServer:       false,
+		toolUndoLastOperation:     false,
+		toolExecutePlan:           false,
+		toolCheckGPUs:             false,
+		toolPortForward:           false,
+		toolCheckNodeOS:           false,
+		toolCheckImageArch:        false,
+		toolExecInPod:             false,
+		toolMigrateNamespace:      false,
+		toolGetResourceYAML:       false,
+		toolApplyResourceYAML:     false,
+		toolValidateManifest:      false,
+		toolRecommendResources:    false,
+		toolResilienceReport:      false,
+		toolCheckConnectivity:     false,
+		toolChaos:                 false,
+		toolCheckDNS:              false,
+		toolBulkLabel:             false,
+		toolReviewSATokens:        false,
+		toolGetQuotaUsage:         false,
+		toolRankEventNoise:        false,
+		toolClusterCapacity:       false,
+		toolRecentChanges:         false,
+		toolImageProvenance:       false,
+		toolDependencyMap:         false,
+		toolGenerateReport:        false,
+		toolGetHealthHistory:      false,
+		toolQueryPrometheus:       false,
+		toolQueryLogs:             false,
+		toolGetGitOpsStatus:       false,
+		toolDiffWorkloads:         false,
+		toolGetCustomResources:    false,
+		toolBulkDelete:            false,
+		toolExportResources:       false,
+		toolCheckIngressTLS:       false,
+		toolCheckDisruptionSafety: false,
+		toolDrainNode:             false,
+		toolCheckQuotaFairness:    false,
+		toolRestartWorkload:       false,
+		toolRolloutStatus:         false,
+		toolCheckJobs:             false,
+		toolCheckAutoscaling:      false,
+		toolCheckWebhooks:         false,
+		toolAgentInfo:             false,
+		toolWatchResource:         false,
 	}
 
 	for _, tool := range tools {
@@ -516,8 +560,8 @@ func TestDefineToolsWithState(t *testing.T) {
 
 	tools := defineTools(provider, state)
 
-	if len(tools) != 9 {
-		t.Errorf("defineTools() returned %d tools, want 9", len(tools))
+	if len(tools) != 53 {
+		t.Errorf("defineTools() returned %d tools, want 53", len(tools))
 	}
 
 	// Verify kubectl_exec tool exists
@@ -1457,3 +1501,23 @@ func TestCreateSessionWithModelIncludesMCPServers(t *testing.T) {
 		t.Errorf("Model = %q, want some-model", provider.lastConfig.Model)
 	}
 }
+
+// TestHandleContextCommandReload verifies a failed /context reload keeps the contexts.
+func TestHandleContextCommandReload(t *testing.T) {
+	provider := createMockProvider(t) // its kubeconfig file is already removed
+	idle := true
+	deps := &loopDeps{
+		ctx:         context.Background(),
+		k8sProvider: provider,
+		state:       &agentState{},
+		isIdle:      &idle,
+	}
+
+	handled, err := handleContextCommand(deps, "/context reload")
+	if err != nil || !handled {
+		t.Fatalf("handleContextCommand(/context reload) = %v, %v", handled, err)
+	}
+	if got := len(provider.GetClusters()); got != 2 {
+		t.Errorf("contexts after failed reload = %d, want 2", got)
+	}
+}
@@ -0,0 +1,190 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the agent_info tool (kopilot's own live settings).
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// AgentInfoParams defines no parameters for agent_info
+type AgentInfoParams struct{}
+
+// AgentInfoResult defines JSON output for agent_info
+type AgentInfoResult struct {
+	Version  string `json:"version"`
+	Provider string `json:"provider,omitempty"`
+	// Model is the model forced with /model; "auto" picks one per prompt.
+	Model        string `json:"model"`
+	Agent        string `json:"agent,omitempty"`
+	Mode         string `json:"mode"`
+	OutputFormat string `json:"output_format"`
+	Language     string `json:"language,omitempty"`
+	// KubectlPolicy is how strictly kubectl commands are validated.
+	KubectlPolicy string `json:"kubectl_policy"`
+	// ReadOnlyTools never change a cluster; WriteTools do, and run only in
+	// interactive mode after confirmation.
+	ReadOnlyTools []string       `json:"read_only_tools"`
+	WriteTools    []string       `json:"write_tools"`
+	Cache         AgentCacheInfo `json:"cache"`
+	// CurrentContext and Contexts are the kubeconfig contexts kopilot can use.
+	CurrentContext string   `json:"current_context"`
+	Contexts       []string `json:"contexts"`
+	// StartupContexts limits the startup check; empty means every context.
+	StartupContexts []string       `json:"startup_contexts,omitempty"`
+	Quota           AgentQuotaInfo `json:"quota"`
+}
+
+// AgentCacheInfo describes the cluster status cache.
+type AgentCacheInfo struct {
+	DefaultTTL string `json:"default_ttl"`
+	// Contexts are the per-context settings of the config file.
+	Contexts map[string]k8s.ContextSettings `json:"contexts,omitempty"`
+}
+
+// AgentQuotaInfo describes the premium request quota. Known is false until
+// the provider reported it, after the first message.
+type AgentQuotaInfo struct {
+	Known            bool    `json:"known"`
+	Unlimited        bool    `json:"unlimited,omitempty"`
+	RemainingPercent float64 `json:"remaining_percent,omitempty"`
+	Used             float64 `json:"used,omitempty"`
+	Total            float64 `json:"total,omitempty"`
+}
+
+func defineAgentInfoTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolAgentInfo,
+		"Report kopilot's own live settings: version, AI provider and model, execution mode (read-only or interactive), the enabled read-only and write tools, the status cache TTLs, the available contexts and per-context settings, and the premium quota. Use it to answer questions such as 'what can you do?', 'what mode are we in?' or 'can you make changes?' instead of guessing. Read-only.",
+		func(params AgentInfoParams, inv llm.ToolInvocation) (any, error) {
+			info := newAgentInfo(k8sProvider, state)
+			if isJSONOutput(state.outputFormat) {
+				return info, nil
+			}
+			return formatAgentInfo(info), nil
+		},
+	)
+}
+
+// setTools records the session's tools: their names for agent_info, and
+// the tools by name for the commands that run them without the model.
+func (s *agentState) setTools(tools []llm.Tool) {
+	s.toolNames = make([]string, len(tools))
+	s.toolsByName = make(map[string]llm.Tool, len(tools))
+	for i, t := range tools {
+		s.toolNames[i] = t.Name
+		s.toolsByName[t.Name] = t
+	}
+}
+
+// newAgentInfo snapshots the session settings.
+func newAgentInfo(k8sProvider *k8s.Provider, state *agentState) *AgentInfoResult {
+	info := &AgentInfoResult{
+		Version:         AppVersion,
+		Provider:        state.providerName,
+		Model:           "auto",
+		Agent:           string(state.selectedAgent),
+		Mode:            state.mode.String(),
+		OutputFormat:    string(state.outputFormat),
+		Language:        state.language,
+		KubectlPolicy:   string(KubectlStrict),
+		ReadOnlyTools:   []string{},
+		WriteTools:      []string{},
+		Cache:           AgentCacheInfo{DefaultTTL: k8sProvider.CacheTTL().String(), Contexts: state.contexts},
+		CurrentContext:  k8sProvider.GetCurrentContext(),
+		Contexts:        []string{},
+		StartupContexts: state.startup.Contexts,
+	}
+	if state.forcedModel != "" {
+		info.Model = state.forcedModel
+	}
+	if state.kubectlPolicy != "" {
+		info.KubectlPolicy = string(state.kubectlPolicy)
+	}
+	for _, name := range state.toolNames {
+		if _, ok := toolBudgets[name]; ok || name == toolAgentInfo {
+			info.ReadOnlyTools = append(info.ReadOnlyTools, name)
+		} else {
+			info.WriteTools = append(info.WriteTools, name)
+		}
+	}
+	sort.Strings(info.ReadOnlyTools)
+	sort.Strings(info.WriteTools)
+	for _, cluster := range k8sProvider.GetClusters() {
+		info.Contexts = append(info.Contexts, cluster.Context)
+	}
+	sort.Strings(info.Contexts)
+	if state.quotaUnlimited || state.quotaPercentage >= 0 {
+		info.Quota = AgentQuotaInfo{Known: true, Unlimited: state.quotaUnlimited}
+		if !state.quotaUnlimited {
+			info.Quota.RemainingPercent, info.Quota.Used, info.Quota.Total = state.quotaPercentage, state.quotaUsed, state.quotaTotal
+		}
+	}
+	return info
+}
+
+// formatAgentInfo formats an AgentInfoResult as human-readable text
+func formatAgentInfo(info *AgentInfoResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "kopilot %s\n", info.Version)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+	if info.Provider != "" {
+		fmt.Fprintf(&sb, "AI provider: %s, model: %s\n", info.Provider, info.Model)
+	}
+	if info.Agent != "" {
+		fmt.Fprintf(&sb, "Agent: %s\n", info.Agent)
+	}
+	fmt.Fprintf(&sb, "Mode: %s", info.Mode)
+	if info.Mode == ModeReadOnly.String() {
+		sb.WriteString(" (write tools are blocked)")
+	} else {
+		sb.WriteString(" (write tools ask for confirmation)")
+	}
+	fmt.Fprintf(&sb, "\nkubectl policy: %s\n", info.KubectlPolicy)
+	fmt.Fprintf(&sb, "Output: %s\n", info.OutputFormat)
+	if info.Language != "" {
+		fmt.Fprintf(&sb, "Reply language: %s\n", info.Language)
+	}
+
+	fmt.Fprintf(&sb, "\n🔍 %d read-only tool(s): %s\n", len(info.ReadOnlyTools), strings.Join(info.ReadOnlyTools, ", "))
+	fmt.Fprintf(&sb, "✏️  %d write tool(s): %s\n", len(info.WriteTools), strings.Join(info.WriteTools, ", "))
+
+	fmt.Fprintf(&sb, "\n📋 %d context(s), current: %s\n", len(info.Contexts), info.CurrentContext)
+	if len(info.StartupContexts) > 0 {
+		fmt.Fprintf(&sb, "   Startup check limited to: %s\n", strings.Join(info.StartupContexts, ", "))
+	}
+	fmt.Fprintf(&sb, "   Status cache TTL: %s\n", info.Cache.DefaultTTL)
+	names := make([]string, 0, len(info.Cache.Contexts))
+	for name := range info.Cache.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := info.Cache.Contexts[name]
+		var settings []string
+		if s.CacheTTL != "" {
+			settings = append(settings, "cache TTL "+s.CacheTTL)
+		}
+		if s.QPS > 0 {
+			settings = append(settings, fmt.Sprintf("qps %g", s.QPS))
+		}
+		if s.Burst > 0 {
+			settings = append(settings, fmt.Sprintf("burst %d", s.Burst))
+		}
+		fmt.Fprintf(&sb, "   %s: %s\n", name, strings.Join(settings, ", "))
+	}
+
+	switch q := info.Quota; {
+	case !q.Known:
+		sb.WriteString("\nPremium quota: not reported yet\n")
+	case q.Unlimited:
+		sb.WriteString("\nPremium quota: unlimited\n")
+	default:
+		fmt.Fprintf(&sb, "\nPremium quota: %.0f%% remaining (%.0f of %.0f used)\n", q.RemainingPercent, q.Used, q.Total)
+	}
+	return sb.String()
+}
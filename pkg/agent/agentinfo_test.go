@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestNewAgentInfo(t *testing.T) {
+	provider := createMockProvider(t)
+	state := &agentState{
+		mode:            ModeInteractive,
+		outputFormat:    OutputText,
+		providerName:    "copilot",
+		forcedModel:     "gpt-4.1",
+		quotaPercentage: 42,
+		quotaUsed:       58,
+		quotaTotal:      100,
+		contexts:        map[string]k8s.ContextSettings{"prod": {CacheTTL: "5m", QPS: 20}},
+	}
+	state.setTools(defineTools(provider, state))
+
+	info := newAgentInfo(provider, state)
+	if info.Mode != "interactive" || info.Model != "gpt-4.1" || info.Cache.DefaultTTL != "1m0s" {
+		t.Errorf("info = %+v", info)
+	}
+	if !slices.Contains(info.ReadOnlyTools, toolCheckAllClusters) || !slices.Contains(info.ReadOnlyTools, toolAgentInfo) {
+		t.Errorf("ReadOnlyTools = %v, want check_all_clusters and agent_info", info.ReadOnlyTools)
+	}
+	if !slices.Contains(info.WriteTools, toolDrainNode) || slices.Contains(info.WriteTools, toolCheckAllClusters) {
+		t.Errorf("WriteTools = %v, want drain_node and no check_all_clusters", info.WriteTools)
+	}
+	if len(info.ReadOnlyTools)+len(info.WriteTools) != len(state.toolNames) {
+		t.Errorf("%d+%d tools listed, want %d", len(info.ReadOnlyTools), len(info.WriteTools), len(state.toolNames))
+	}
+	if len(info.Contexts) != 2 || info.CurrentContext == "" {
+		t.Errorf("Contexts = %v, current %q", info.Contexts, info.CurrentContext)
+	}
+	if q := info.Quota; !q.Known || q.RemainingPercent != 42 {
+		t.Errorf("Quota = %+v, want 42%% remaining", q)
+	}
+
+	text := formatAgentInfo(info)
+	for _, want := range []string{
+		"AI provider: copilot, model: gpt-4.1",
+		"Mode: interactive (write tools ask for confirmation)",
+		"Status cache TTL: 1m0s",
+		"prod: cache TTL 5m, qps 20",
+		"Premium quota: 42% remaining (58 of 100 used)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+
+	state.quotaPercentage = -1
+	if q := newAgentInfo(provider, state).Quota; q.Known {
+		t.Errorf("Quota = %+v, want unknown before the provider reports it", q)
+	}
+}
@@ -0,0 +1,233 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the approval hook used to confirm write operations.
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ApprovalLocal asks the user at the terminal (default behaviour).
+	ApprovalLocal = "local"
+	// ApprovalWebhook delegates the decision to an external HTTP endpoint.
+	ApprovalWebhook = "webhook"
+	// ApprovalBoth requires the local user AND the external endpoint to approve (two-person rule).
+	ApprovalBoth = "both"
+
+	// defaultApprovalTimeout bounds how long kopilot waits for an external decision.
+	// Humans approving in Slack or PagerDuty need minutes, not seconds.
+	defaultApprovalTimeout = 5 * time.Minute
+)
+
+// ApprovalRequest describes a pending write operation awaiting approval.
+type ApprovalRequest struct {
+	Tool        string    `json:"tool"`
+	Cluster     string    `json:"cluster"`
+	Context     string    `json:"context"`
+	Command     string    `json:"command"`
+	Agent       string    `json:"agent,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+	// Objects lists every object a bulk operation will touch.
+	Objects []string `json:"objects,omitempty"`
+	// ConfirmCount, when set, must be typed back by a local approver instead
+	// of yes, so the count of a bulk deletion is confirmed explicitly.
+	ConfirmCount int `json:"confirm_count,omitempty"`
+	// Safety is the eviction safety analysis of drains, cordons and pod
+	// deletions, one finding per line.
+	Safety []string `json:"safety,omitempty"`
+}
+
+// ApprovalDecision is the outcome of an approval request.
+type ApprovalDecision struct {
+	Approved bool   `json:"approved"`
+	Approver string `json:"approver,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Approver decides whether a write operation may proceed.
+// Implementations must be safe to call from tool handler goroutines.
+type Approver interface {
+	// Name returns a short label shown to the user (e.g. "local", "webhook").
+	Name() string
+	// Approve blocks until a decision is available or ctx is done.
+	Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error)
+}
+
+// terminalApprover asks the local user for a yes/no answer.
+type terminalApprover struct {
+	in         io.Reader
+	out        io.Writer
+	jsonOutput bool
+}
+
+// newTerminalApprover returns an Approver reading from stdin and writing to stdout.
+func newTerminalApprover(jsonOutput bool) *terminalApprover {
+	return &terminalApprover{in: os.Stdin, out: os.Stdout, jsonOutput: jsonOutput}
+}
+
+func (a *terminalApprover) Name() string { return ApprovalLocal }
+
+func (a *terminalApprover) Approve(_ context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	if !a.jsonOutput {
+		fmt.Fprintf(a.out, "\n%s⚠️  Write Operation:%s %s%s%s\n", colorYellow, colorReset, colorBold, req.Command, colorReset)
+		fmt.Fprintf(a.out, "%sThis will modify the cluster state.%s\n", colorYellow, colorReset)
+		for _, obj := range req.Objects {
+			fmt.Fprintf(a.out, "  - %s\n", obj)
+		}
+		for _, line := range req.Safety {
+			fmt.Fprintf(a.out, "%s🛡️  %s%s\n", colorDim, line, colorReset)
+		}
+	}
+	if req.ConfirmCount > 0 {
+		fmt.Fprintf(a.out, "Type the number of objects (%d) to proceed: ", req.ConfirmCount)
+	} else {
+		fmt.Fprint(a.out, "Do you want to proceed? (yes/no): ")
+	}
+
+	reader := bufio.NewReader(a.in)
+	response, err := reader.ReadString('\n')
+	if err != nil {
+		return ApprovalDecision{}, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+
+	response = strings.TrimSpace(strings.ToLower(response))
+	approved := response == "yes" || response == "y"
+	if req.ConfirmCount > 0 {
+		approved = response == strconv.Itoa(req.ConfirmCount)
+	}
+	return ApprovalDecision{Approved: approved, Approver: ApprovalLocal}, nil
+}
+
+// WebhookApprover posts the ApprovalRequest as JSON to an external endpoint
+// (a Slack bot, PagerDuty workflow, or any service) and waits for a JSON
+// ApprovalDecision in the response body.
+type WebhookApprover struct {
+	URL     string
+	Token   string
+	Timeout time.Duration
+	Client  *http.Client
+}
+
+// NewWebhookApprover returns a WebhookApprover for url. The optional bearer
+// token is read from KOPILOT_APPROVAL_TOKEN.
+func NewWebhookApprover(url string) (*WebhookApprover, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("approval webhook URL must start with http:// or https://, got %q", url)
+	}
+	return &WebhookApprover{
+		URL:     url,
+		Token:   os.Getenv("KOPILOT_APPROVAL_TOKEN"),
+		Timeout: defaultApprovalTimeout,
+		Client:  &http.Client{},
+	}, nil
+}
+
+func (a *WebhookApprover) Name() string { return ApprovalWebhook }
+
+func (a *WebhookApprover) Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return ApprovalDecision{}, fmt.Errorf("encoding approval request: %w", err)
+	}
+
+	timeout := a.Timeout
+	if timeout <= 0 {
+		timeout = defaultApprovalTimeout
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(reqCtx, http.MethodPost, a.URL, bytes.NewReader(body))
+	if err != nil {
+		return ApprovalDecision{}, fmt.Errorf("building approval request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if a.Token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+a.Token)
+	}
+
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq) // #nosec G704 -- URL is operator-supplied configuration
+	if err != nil {
+		return ApprovalDecision{}, fmt.Errorf("calling approval webhook: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return ApprovalDecision{}, fmt.Errorf("approval webhook returned HTTP %d", resp.StatusCode)
+	}
+
+	var decision ApprovalDecision
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&decision); err != nil {
+		return ApprovalDecision{}, fmt.Errorf("decoding approval webhook response: %w", err)
+	}
+	if decision.Approver == "" {
+		decision.Approver = ApprovalWebhook
+	}
+	return decision, nil
+}
+
+// chainApprover requires every approver in order to approve. The first denial
+// or error short-circuits the chain, so a local "no" never pages anyone.
+type chainApprover struct {
+	approvers []Approver
+}
+
+func (c *chainApprover) Name() string {
+	names := make([]string, len(c.approvers))
+	for i, a := range c.approvers {
+		names[i] = a.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+func (c *chainApprover) Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	var approvedBy []string
+	for _, a := range c.approvers {
+		decision, err := a.Approve(ctx, req)
+		if err != nil {
+			return ApprovalDecision{}, fmt.Errorf("%s approval failed: %w", a.Name(), err)
+		}
+		if !decision.Approved {
+			return decision, nil
+		}
+		approvedBy = append(approvedBy, decision.Approver)
+	}
+	return ApprovalDecision{Approved: true, Approver: strings.Join(approvedBy, "+")}, nil
+}
+
+// BuildApprover returns the Approver for the given policy (local, webhook, or both).
+// webhookURL is required for the webhook and both policies.
+func BuildApprover(policy, webhookURL string, jsonOutput bool) (Approver, error) {
+	switch strings.ToLower(policy) {
+	case "", ApprovalLocal:
+		return newTerminalApprover(jsonOutput), nil
+	case ApprovalWebhook, ApprovalBoth:
+		if webhookURL == "" {
+			return nil, fmt.Errorf("approval policy %q requires --approval-webhook or KOPILOT_APPROVAL_WEBHOOK", policy)
+		}
+		webhook, err := NewWebhookApprover(webhookURL)
+		if err != nil {
+			return nil, err
+		}
+		if strings.ToLower(policy) == ApprovalWebhook {
+			return webhook, nil
+		}
+		return &chainApprover{approvers: []Approver{newTerminalApprover(jsonOutput), webhook}}, nil
+	default:
+		return nil, fmt.Errorf("unknown approval policy %q — use %s, %s, or %s", policy, ApprovalLocal, ApprovalWebhook, ApprovalBoth)
+	}
+}
@@ -0,0 +1,196 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testApprovalCommand = "kubectl --context prod delete pod web-1"
+
+// stubApprover returns a fixed decision and records whether it was called.
+type stubApprover struct {
+	name     string
+	decision ApprovalDecision
+	err      error
+	called   bool
+	req      ApprovalRequest
+}
+
+func (s *stubApprover) Name() string { return s.name }
+
+func (s *stubApprover) Approve(_ context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	s.called, s.req = true, req
+	return s.decision, s.err
+}
+
+func TestTerminalApprover(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"yes\n", true},
+		{"Y\n", true},
+		{"no\n", false},
+		{"\n", false},
+	}
+	for _, tt := range tests {
+		a := &terminalApprover{in: strings.NewReader(tt.input), out: io.Discard, jsonOutput: true}
+		decision, err := a.Approve(context.Background(), ApprovalRequest{Command: testApprovalCommand})
+		if err != nil {
+			t.Fatalf("Approve(%q) error: %v", tt.input, err)
+		}
+		if decision.Approved != tt.want {
+			t.Errorf("Approve(%q) = %v, want %v", tt.input, decision.Approved, tt.want)
+		}
+	}
+
+	a := &terminalApprover{in: strings.NewReader(""), out: io.Discard}
+	if _, err := a.Approve(context.Background(), ApprovalRequest{}); err == nil {
+		t.Error("expected error on EOF")
+	}
+}
+
+func TestTerminalApproverConfirmCount(t *testing.T) {
+	req := ApprovalRequest{Command: "kubectl --context prod delete 3 jobs", Objects: []string{"ci/a", "ci/b", "ci/c"}, ConfirmCount: 3}
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"3\n", true},
+		{" 3 \n", true},
+		{"yes\n", false},
+		{"4\n", false},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		a := &terminalApprover{in: strings.NewReader(tt.input), out: &out}
+		decision, err := a.Approve(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Approve(%q) error: %v", tt.input, err)
+		}
+		if decision.Approved != tt.want {
+			t.Errorf("Approve(%q) = %v, want %v", tt.input, decision.Approved, tt.want)
+		}
+		if !strings.Contains(out.String(), "  - ci/c\n") || !strings.Contains(out.String(), "Type the number of objects (3)") {
+			t.Errorf("prompt does not list the objects and ask for the count:\n%s", out.String())
+		}
+	}
+}
+
+func TestWebhookApprover(t *testing.T) {
+	var got ApprovalRequest
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"approved": true, "approver": "alice@slack"}`))
+	}))
+	defer srv.Close()
+
+	a, err := NewWebhookApprover(srv.URL)
+	if err != nil {
+		t.Fatalf("NewWebhookApprover() error: %v", err)
+	}
+	a.Token = "secret"
+
+	decision, err := a.Approve(context.Background(), ApprovalRequest{Context: "prod", Command: testApprovalCommand})
+	if err != nil {
+		t.Fatalf("Approve() error: %v", err)
+	}
+	if !decision.Approved || decision.Approver != "alice@slack" {
+		t.Errorf("unexpected decision: %+v", decision)
+	}
+	if got.Command != testApprovalCommand || got.Context != "prod" {
+		t.Errorf("webhook received unexpected request: %+v", got)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("Authorization header = %q, want bearer token", gotAuth)
+	}
+}
+
+func TestWebhookApproverErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	a, _ := NewWebhookApprover(srv.URL)
+	if _, err := a.Approve(context.Background(), ApprovalRequest{}); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected HTTP 403 error, got %v", err)
+	}
+
+	if _, err := NewWebhookApprover("ftp://example.com"); err == nil {
+		t.Error("expected error for non-HTTP URL")
+	}
+}
+
+func TestChainApprover(t *testing.T) {
+	first := &stubApprover{name: "local", decision: ApprovalDecision{Approved: true, Approver: "local"}}
+	second := &stubApprover{name: "webhook", decision: ApprovalDecision{Approved: true, Approver: "bob"}}
+	chain := &chainApprover{approvers: []Approver{first, second}}
+
+	decision, err := chain.Approve(context.Background(), ApprovalRequest{})
+	if err != nil || !decision.Approved {
+		t.Fatalf("both approvers approve: decision=%+v err=%v", decision, err)
+	}
+	if decision.Approver != "local+bob" {
+		t.Errorf("Approver = %q, want local+bob", decision.Approver)
+	}
+	if chain.Name() != "local+webhook" {
+		t.Errorf("Name() = %q", chain.Name())
+	}
+
+	// A local denial must not reach the external approver.
+	first.decision = ApprovalDecision{Approved: false, Approver: "local"}
+	second.called = false
+	decision, _ = chain.Approve(context.Background(), ApprovalRequest{})
+	if decision.Approved || second.called {
+		t.Errorf("local denial should short-circuit: decision=%+v secondCalled=%v", decision, second.called)
+	}
+
+	first.decision = ApprovalDecision{Approved: true}
+	second.err = errors.New("timeout")
+	if _, err := chain.Approve(context.Background(), ApprovalRequest{}); err == nil || !strings.Contains(err.Error(), "webhook approval failed") {
+		t.Errorf("expected wrapped webhook error, got %v", err)
+	}
+}
+
+func TestBuildApprover(t *testing.T) {
+	if a, err := BuildApprover("", "", false); err != nil || a.Name() != ApprovalLocal {
+		t.Errorf("default policy should be local: %v %v", a, err)
+	}
+	if _, err := BuildApprover(ApprovalWebhook, "", false); err == nil {
+		t.Error("webhook policy without URL should fail")
+	}
+	if a, err := BuildApprover(ApprovalBoth, "https://approve.example.com", false); err != nil || a.Name() != "local+webhook" {
+		t.Errorf("both policy: %v %v", a, err)
+	}
+	if _, err := BuildApprover("nobody", "", false); err == nil {
+		t.Error("unknown policy should fail")
+	}
+}
+
+func TestConfirmWriteOperationUsesApprover(t *testing.T) {
+	deny := &stubApprover{name: ApprovalWebhook, decision: ApprovalDecision{Approved: false, Approver: "oncall", Reason: "change freeze"}}
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON, approver: deny}
+
+	proceed, err := confirmWriteOperation(context.Background(), state, ApprovalRequest{Command: testApprovalCommand})
+	if err != nil || proceed {
+		t.Fatalf("denied write should not proceed: proceed=%v err=%v", proceed, err)
+	}
+	if !state.denyWritesUntilNextPrompt {
+		t.Error("external denial should latch further writes for this prompt")
+	}
+
+	allow := &stubApprover{name: ApprovalWebhook, decision: ApprovalDecision{Approved: true}}
+	state = &agentState{mode: ModeInteractive, outputFormat: OutputJSON, approver: allow}
+	if proceed, err := confirmWriteOperation(context.Background(), state, ApprovalRequest{}); err != nil || !proceed {
+		t.Errorf("approved write should proceed: proceed=%v err=%v", proceed, err)
+	}
+}
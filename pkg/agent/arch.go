@@ -0,0 +1,82 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the check_image_arch tool for mixed amd64/arm64 clusters.
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// CheckImageArchParams defines parameters for check_image_arch
+type CheckImageArchParams struct {
+	Context string `json:"context" jsonschema:"The context name of the cluster to inspect (from list_clusters)"`
+}
+
+func defineCheckImageArchTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolCheckImageArch,
+		"Report node CPU architectures (amd64/arm64) and flag pods failing with 'exec format error' or 'no matching manifest' errors, showing on which architectures the same image runs fine. Use this when pods crash on Graviton/ARM node pools or right after adding nodes of a new architecture.",
+		func(params CheckImageArchParams, inv llm.ToolInvocation) (any, error) {
+			ctx := inv.Ctx()
+			report, err := k8sProvider.CheckImageArch(ctx, params.Context)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check image architectures: %w", err)
+			}
+
+			if isJSONOutput(state.outputFormat) {
+				return report, nil
+			}
+			return formatArchReport(report), nil
+		},
+	)
+}
+
+// archIssueLabels maps issue kinds to short text-mode headings.
+var archIssueLabels = map[string]string{
+	k8s.ArchIssueExecFormat:      "EXEC FORMAT ERROR",
+	k8s.ArchIssueNoManifest:      "NO MANIFEST FOR PLATFORM",
+	k8s.ArchIssueNoMatchingNodes: "NO NODES FOR REQUESTED ARCH",
+}
+
+// formatArchReport formats an ArchReport as human-readable text
+func formatArchReport(report *k8s.ArchReport) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Image Architecture Report: %s\n", report.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	kind := "single-architecture"
+	if report.Mixed {
+		kind = "mixed-architecture"
+	}
+	fmt.Fprintf(&sb, "🧬 NODE ARCHITECTURES: %s (%s)\n\n", k8s.FormatDistribution(report.Distribution), kind)
+
+	if len(report.Issues) == 0 {
+		sb.WriteString("✅ No architecture-related failures found.\n")
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "⚠️  ARCHITECTURE ISSUES: %d\n", len(report.Issues))
+	for _, issue := range report.Issues {
+		target := issue.Namespace + "/" + issue.Pod
+		if issue.Container != "" {
+			target += " [" + issue.Container + "]"
+		}
+		fmt.Fprintf(&sb, "  🔴 %s — %s\n", archIssueLabels[issue.Kind], target)
+		if issue.Image != "" {
+			fmt.Fprintf(&sb, "     Image: %s\n", issue.Image)
+		}
+		if issue.Node != "" {
+			fmt.Fprintf(&sb, "     Node: %s (%s)\n", issue.Node, issue.NodeArch)
+		}
+		if len(issue.WorksOn) > 0 {
+			fmt.Fprintf(&sb, "     Runs fine on: %s — likely a single-arch image\n", strings.Join(issue.WorksOn, ", "))
+		}
+		fmt.Fprintf(&sb, "     %s\n", issue.Evidence)
+	}
+	sb.WriteString("\nFix: publish multi-arch images (docker buildx build --platform linux/amd64,linux/arm64 --push ...) " +
+		"or, until then, pin the workload with nodeSelector kubernetes.io/arch to the architecture the image supports.\n")
+	return sb.String()
+}
@@ -0,0 +1,32 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestFormatArchReport(t *testing.T) {
+	report := &k8s.ArchReport{
+		Context:      "prod",
+		Distribution: map[string]int{"amd64": 3, "arm64": 2},
+		Mixed:        true,
+		Issues: []k8s.ArchIssue{{
+			Kind: k8s.ArchIssueExecFormat, Namespace: "apps", Pod: "api-bad", Container: "app",
+			Image: "registry/api:1.0", Node: "grav-1", NodeArch: "arm64", WorksOn: []string{"amd64"},
+			Evidence: "exec /usr/bin/api: exec format error",
+		}},
+	}
+	text := formatArchReport(report)
+	for _, want := range []string{"amd64 3, arm64 2 (mixed-architecture)", "EXEC FORMAT ERROR — apps/api-bad [app]", "grav-1 (arm64)", "Runs fine on: amd64", "buildx"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report missing %q:\n%s", want, text)
+		}
+	}
+
+	report.Issues = nil
+	if text := formatArchReport(report); !strings.Contains(text, "No architecture-related failures") {
+		t.Errorf("clean report should say so:\n%s", text)
+	}
+}
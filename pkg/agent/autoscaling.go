@@ -0,0 +1,110 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the check_autoscaling tool (HPA problems and VPA recommendations).
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// CheckAutoscalingParams defines parameters for check_autoscaling
+type CheckAutoscalingParams struct {
+	Context       string `json:"context" jsonschema:"The context name of the cluster to check (from list_clusters)"`
+	Namespace     string `json:"namespace,omitempty" jsonschema:"Optional: restrict the check to a specific namespace; leave empty for all non-system namespaces"`
+	IncludeSystem bool   `json:"include_system,omitempty" jsonschema:"If true, include system namespaces (kube-system, kube-public, kube-node-lease)"`
+}
+
+func defineCheckAutoscalingTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolCheckAutoscaling,
+		"Autoscaling health: HorizontalPodAutoscalers that cannot scale, cannot read their metrics (<unknown> targets), sit at their max replicas or flap (rescaled up and down repeatedly in the last hour), with current/target metrics; plus Vertical Pod Autoscaler recommendations per container when the VPA is installed. Use for questions like 'why is this not scaling' or 'are my HPAs maxed out'. Read-only.",
+		func(params CheckAutoscalingParams, inv llm.ToolInvocation) (any, error) {
+			if params.Namespace != "" && !isValidKubernetesName(params.Namespace) {
+				return nil, fmt.Errorf("invalid namespace name: %s", params.Namespace)
+			}
+			report, err := k8sProvider.CheckAutoscaling(inv.Ctx(), params.Context, params.Namespace, params.IncludeSystem)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check autoscaling: %w", err)
+			}
+
+			if isJSONOutput(state.outputFormat) {
+				return report, nil
+			}
+			return formatAutoscalingReport(report), nil
+		},
+	)
+}
+
+// autoscalingIssueLabels maps autoscaling issues to text-mode headings.
+var autoscalingIssueLabels = map[string]string{
+	k8s.AutoscalingIssueUnableToScale:  "❌ UNABLE TO SCALE",
+	k8s.AutoscalingIssueMetricsUnknown: "❓ METRICS UNKNOWN",
+	k8s.AutoscalingIssueFlapping:       "🔁 FLAPPING",
+	k8s.AutoscalingIssueAtMax:          "📈 AT MAX REPLICAS",
+}
+
+// formatAutoscalingReport formats an AutoscalingReport as human-readable text
+func formatAutoscalingReport(report *k8s.AutoscalingReport) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Autoscaling: %s", report.Context)
+	if report.Namespace != "" {
+		fmt.Fprintf(&sb, " (namespace %s)", report.Namespace)
+	}
+	sb.WriteString("\n" + strings.Repeat("=", 80) + "\n\n")
+	fmt.Fprintf(&sb, "📋 %d HorizontalPodAutoscaler(s) checked\n", len(report.HPAs))
+
+	if len(report.Findings) == 0 {
+		sb.WriteString("\n✅ No HPAs unable to scale, with unknown metrics, flapping or at max replicas.\n")
+	}
+	issue := ""
+	for _, f := range report.Findings {
+		if f.Issue != issue {
+			issue = f.Issue
+			fmt.Fprintf(&sb, "\n%s (%d)\n", autoscalingIssueLabels[issue], report.Issues[issue])
+		}
+		fmt.Fprintf(&sb, "   %s/%s -> %s\n", f.Namespace, f.Name, f.Target)
+		fmt.Fprintf(&sb, "     %s\n", f.Message)
+	}
+
+	if report.VPAInstalled {
+		fmt.Fprintf(&sb, "\n📐 VPA recommendations (%d)\n", len(report.VPAs))
+		for _, v := range report.VPAs {
+			fmt.Fprintf(&sb, "   %s/%s -> %s  [mode %s]\n", v.Namespace, v.Name, v.Target, v.UpdateMode)
+			if v.Message != "" {
+				fmt.Fprintf(&sb, "     %s\n", v.Message)
+			}
+			for _, c := range v.Containers {
+				fmt.Fprintf(&sb, "     %s: target %s\n", c.Container, formatResourceMap(c.Target))
+			}
+		}
+	}
+
+	for _, w := range report.Warnings {
+		fmt.Fprintf(&sb, "\n⚠️  %s", w)
+	}
+	if len(report.Warnings) > 0 {
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// formatResourceMap renders a resource map as sorted "name=quantity" pairs.
+func formatResourceMap(m map[string]string) string {
+	if len(m) == 0 {
+		return "-"
+	}
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + m[name]
+	}
+	return strings.Join(parts, " ")
+}
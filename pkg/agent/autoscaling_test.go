@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+func TestFormatAutoscalingReport(t *testing.T) {
+	report := &k8s.AutoscalingReport{
+		Context: "prod", Namespace: "shop",
+		HPAs:   []k8s.HPAStatus{{Namespace: "shop", Name: "web"}, {Namespace: "shop", Name: "cart"}},
+		Issues: map[string]int{k8s.AutoscalingIssueFlapping: 1, k8s.AutoscalingIssueAtMax: 1},
+		Findings: []k8s.AutoscalingIssue{
+			{Issue: k8s.AutoscalingIssueFlapping, Namespace: "shop", Name: "cart", Target: "Deployment/cart", Message: "rescaled 5 time(s)"},
+			{Issue: k8s.AutoscalingIssueAtMax, Namespace: "shop", Name: "web", Target: "Deployment/web", Message: "at max replicas (10)"},
+		},
+		VPAInstalled: true,
+		VPAs: []k8s.VPARecommendation{{Namespace: "shop", Name: "web", Target: "Deployment/web", UpdateMode: "Off",
+			Containers: []k8s.VPAContainerRecommendation{{Container: "app", Target: map[string]string{"memory": "256Mi", "cpu": "250m"}}}}},
+		Warnings: []string{"could not list events"},
+	}
+	text := formatAutoscalingReport(report)
+	for _, want := range []string{
+		"Autoscaling: prod (namespace shop)",
+		"2 HorizontalPodAutoscaler(s) checked",
+		"🔁 FLAPPING (1)\n   shop/cart -> Deployment/cart\n     rescaled 5 time(s)",
+		"📈 AT MAX REPLICAS (1)",
+		"VPA recommendations (1)",
+		"shop/web -> Deployment/web  [mode Off]\n     app: target cpu=250m memory=256Mi",
+		"⚠️  could not list events",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+
+	text = formatAutoscalingReport(&k8s.AutoscalingReport{Context: "dev"})
+	if !strings.Contains(text, "No HPAs unable to scale") || strings.Contains(text, "VPA") {
+		t.Errorf("empty report output:\n%s", text)
+	}
+}
+
+func TestCheckAutoscalingToolValidation(t *testing.T) {
+	tool := defineCheckAutoscalingTool(newTestK8sProvider(t), &agentState{})
+	args := map[string]any{"context": "test-context", "namespace": "Bad_NS"}
+	if _, err := tool.Handler(args, llm.ToolInvocation{Context: context.Background()}); err == nil {
+		t.Error("expected an error for an invalid namespace")
+	}
+}
@@ -0,0 +1,286 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the background job manager behind long operations run
+// with background set (drains, rollouts) and the /jobs and /cancel commands.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxFinishedJobs is how many finished jobs /jobs keeps showing.
+const maxFinishedJobs = 20
+
+// errNoJobs is returned by tools asked to run in the background outside an
+// interactive session, where nobody could follow or cancel the job.
+var errNoJobs = errors.New("background jobs are only available in the interactive session; run without background")
+
+// jobStatus is the state of a background job.
+type jobStatus string
+
+const (
+	jobRunning   jobStatus = "running"
+	jobSucceeded jobStatus = "succeeded"
+	jobFailed    jobStatus = "failed"
+	jobCancelled jobStatus = "cancelled"
+)
+
+// jobFunc is the work of a background job. It reports progress through
+// progress, stops when ctx is cancelled and returns the text shown when the
+// job finishes.
+type jobFunc func(ctx context.Context, progress func(string)) (string, error)
+
+// JobInfo describes a background job, as listed by /jobs.
+type JobInfo struct {
+	ID          int       `json:"id"`
+	Tool        string    `json:"tool"`
+	Description string    `json:"description"`
+	Status      jobStatus `json:"status"`
+	Progress    string    `json:"progress,omitempty"`
+	Result      string    `json:"result,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at,omitempty"`
+}
+
+// backgroundJob is a job and the function that cancels it.
+type backgroundJob struct {
+	info   JobInfo
+	cancel context.CancelFunc
+}
+
+// jobManager runs long operations in goroutines while the chat continues and
+// announces them on out when they finish. It is safe for concurrent use.
+type jobManager struct {
+	out io.Writer
+	// outMu keeps the announcements of jobs finishing together apart.
+	outMu sync.Mutex
+	mu    sync.Mutex
+	next  int
+	jobs  map[int]*backgroundJob
+	wg    sync.WaitGroup
+}
+
+func newJobManager(out io.Writer) *jobManager {
+	return &jobManager{out: out, next: 1, jobs: make(map[int]*backgroundJob)}
+}
+
+// start runs fn in the background as job tool, described by description,
+// and returns the job's id.
+func (m *jobManager) start(tool, description string, fn jobFunc) JobInfo {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.mu.Lock()
+	job := &backgroundJob{
+		info:   JobInfo{ID: m.next, Tool: tool, Description: description, Status: jobRunning, StartedAt: time.Now()},
+		cancel: cancel,
+	}
+	m.jobs[job.info.ID] = job
+	m.next++
+	info := job.info
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer cancel()
+		result, err := fn(ctx, func(p string) { m.setProgress(info.ID, p) })
+		m.finish(ctx, info.ID, result, err)
+	}()
+	return info
+}
+
+// setProgress records the latest progress line of job id.
+func (m *jobManager) setProgress(id int, progress string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job, ok := m.jobs[id]; ok {
+		job.info.Progress = progress
+	}
+}
+
+// finish records the outcome of job id, announces it and drops the oldest
+// finished jobs beyond maxFinishedJobs.
+func (m *jobManager) finish(ctx context.Context, id int, result string, err error) {
+	m.mu.Lock()
+	job := m.jobs[id]
+	job.info.FinishedAt = time.Now()
+	job.info.Result = result
+	switch {
+	case ctx.Err() != nil:
+		job.info.Status = jobCancelled
+	case err != nil:
+		job.info.Status = jobFailed
+		job.info.Error = err.Error()
+	default:
+		job.info.Status = jobSucceeded
+	}
+	info := job.info
+	m.pruneLocked()
+	m.mu.Unlock()
+
+	m.outMu.Lock()
+	defer m.outMu.Unlock()
+	switch info.Status {
+	case jobSucceeded:
+		fmt.Fprintf(m.out, "\n  %s●%s Job #%d finished: %s\n", colorGreen, colorReset, info.ID, info.Description)
+	case jobFailed:
+		fmt.Fprintf(m.out, "\n  %s●%s Job #%d failed: %s: %s\n", colorRed, colorReset, info.ID, info.Description, info.Error)
+	}
+	if result != "" && info.Status != jobCancelled {
+		fmt.Fprintf(m.out, "%s\n", strings.TrimRight(result, "\n"))
+	}
+}
+
+// pruneLocked drops the oldest finished jobs beyond maxFinishedJobs; m.mu is held.
+func (m *jobManager) pruneLocked() {
+	var finished []int
+	for id, job := range m.jobs {
+		if job.info.Status != jobRunning {
+			finished = append(finished, id)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Ints(finished)
+	for _, id := range finished[:len(finished)-maxFinishedJobs] {
+		delete(m.jobs, id)
+	}
+}
+
+// list returns the jobs, oldest first.
+func (m *jobManager) list() []JobInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]JobInfo, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job.info)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs
+}
+
+// cancel stops job id; the job records itself as cancelled once its work returns.
+func (m *jobManager) cancel(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return fmt.Errorf("no job #%d", id)
+	}
+	if job.info.Status != jobRunning {
+		return fmt.Errorf("job #%d already %s", id, job.info.Status)
+	}
+	job.cancel()
+	return nil
+}
+
+// cancelAll stops every running job and waits for them; called on exit.
+func (m *jobManager) cancelAll() {
+	m.mu.Lock()
+	for _, job := range m.jobs {
+		job.cancel()
+	}
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+// runInBackground starts fn as a job of state's job manager and returns the
+// tool result announcing it.
+func runInBackground(state *agentState, tool, description string, fn jobFunc) (any, error) {
+	if state.jobs == nil {
+		return nil, errNoJobs
+	}
+	info := state.jobs.start(tool, description, fn)
+	if isJSONOutput(state.outputFormat) {
+		return info, nil
+	}
+	return fmt.Sprintf("⏳ Started job #%d: %s\n   It runs in the background while the chat continues. Follow it with /jobs, cancel it with /cancel %d.",
+		info.ID, description, info.ID), nil
+}
+
+// handleJobsCommand processes "/jobs".
+func handleJobsCommand(deps *loopDeps) (bool, error) {
+	var jobs []JobInfo
+	if deps.state.jobs != nil {
+		jobs = deps.state.jobs.list()
+	}
+	printJobs(jobs)
+	return true, nil
+}
+
+// handleCancelCommand processes "/cancel <id|all>".
+func handleCancelCommand(deps *loopDeps, input string) (bool, error) {
+	parts := strings.Fields(input)
+	if len(parts) != 2 {
+		fmt.Printf("  %s●%s Usage: /cancel <id|all>\n", colorRed, colorReset)
+		return true, nil
+	}
+	manager := deps.state.jobs
+	if manager == nil {
+		fmt.Printf("  %s●%s No background jobs\n", colorDim, colorReset)
+		return true, nil
+	}
+	if strings.ToLower(parts[1]) == "all" {
+		cancelled := 0
+		for _, job := range manager.list() {
+			if job.Status == jobRunning && manager.cancel(job.ID) == nil {
+				cancelled++
+			}
+		}
+		fmt.Printf("  %s●%s Cancelled %d job(s)\n", colorGreen, colorReset, cancelled)
+		return true, nil
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(parts[1], "#"))
+	if err != nil {
+		fmt.Printf("  %s●%s Invalid job id %q\n", colorRed, colorReset, parts[1])
+		return true, nil
+	}
+	if err := manager.cancel(id); err != nil {
+		fmt.Printf(fmtErrorBullet, colorRed, colorReset, err)
+		return true, nil
+	}
+	fmt.Printf("  %s●%s Cancelling job #%d\n", colorGreen, colorReset, id)
+	return true, nil
+}
+
+// printJobs shows the background jobs with their latest progress.
+func printJobs(jobs []JobInfo) {
+	if len(jobs) == 0 {
+		fmt.Printf("  %s●%s No background jobs\n", colorDim, colorReset)
+		return
+	}
+	fmt.Println()
+	fmt.Printf("  %s%s%s\n", colorCyan, sectionRule("Background Jobs"), colorReset)
+	fmt.Println()
+	for _, j := range jobs {
+		fmt.Printf("  %s[%d]%s %s %s  %s(%s)%s\n", colorCyan, j.ID, colorReset, j.Description, jobStatusLabel(j), colorDim, j.Tool, colorReset)
+		switch {
+		case j.Error != "":
+			fmt.Printf("      %s%s%s\n", colorRed, j.Error, colorReset)
+		case j.Progress != "":
+			fmt.Printf("      %s%s%s\n", colorDim, j.Progress, colorReset)
+		}
+	}
+	fmt.Println()
+}
+
+// jobStatusLabel is the colored status and age of j.
+func jobStatusLabel(j JobInfo) string {
+	switch j.Status {
+	case jobRunning:
+		return fmt.Sprintf("%srunning %s%s", colorYellow, time.Since(j.StartedAt).Round(time.Second), colorReset)
+	case jobSucceeded:
+		return fmt.Sprintf("%sdone in %s%s", colorGreen, j.FinishedAt.Sub(j.StartedAt).Round(time.Second), colorReset)
+	case jobFailed:
+		return fmt.Sprintf("%sfailed after %s%s", colorRed, j.FinishedAt.Sub(j.StartedAt).Round(time.Second), colorReset)
+	}
+	return fmt.Sprintf("%s%s%s", colorDim, j.Status, colorReset)
+}
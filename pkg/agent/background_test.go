@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestJobManager(t *testing.T) {
+	var out bytes.Buffer
+	m := newJobManager(&out)
+
+	done := m.start("drain_node", "drain node node-1 on prod", func(_ context.Context, progress func(string)) (string, error) {
+		progress("[1/2] shop/web-1 evicted")
+		return "Drain: node node-1", nil
+	})
+	failed := m.start("rollout_status", "rollout of deployment/api", func(context.Context, func(string)) (string, error) {
+		return "", errors.New("progress deadline exceeded")
+	})
+	m.wg.Wait()
+
+	jobs := m.list()
+	if len(jobs) != 2 || jobs[0].ID != done.ID || jobs[1].ID != failed.ID {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+	if jobs[0].Status != jobSucceeded || jobs[0].Progress != "[1/2] shop/web-1 evicted" || jobs[0].Result != "Drain: node node-1" {
+		t.Errorf("succeeded job = %+v", jobs[0])
+	}
+	if jobs[1].Status != jobFailed || jobs[1].Error != "progress deadline exceeded" {
+		t.Errorf("failed job = %+v", jobs[1])
+	}
+	for _, want := range []string{"Job #1 finished: drain node node-1 on prod", "Drain: node node-1", "Job #2 failed: rollout of deployment/api: progress deadline exceeded"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("announcements missing %q:\n%s", want, out.String())
+		}
+	}
+	if err := m.cancel(done.ID); err == nil || !strings.Contains(err.Error(), "already succeeded") {
+		t.Errorf("cancel finished job = %v", err)
+	}
+	if err := m.cancel(42); err == nil {
+		t.Error("cancelling an unknown job succeeded")
+	}
+}
+
+func TestJobManagerCancel(t *testing.T) {
+	var out bytes.Buffer
+	m := newJobManager(&out)
+	started := make(chan struct{})
+	info := m.start("drain_node", "drain node node-1", func(ctx context.Context, _ func(string)) (string, error) {
+		close(started)
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	<-started
+	if err := m.cancel(info.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	m.wg.Wait()
+	if jobs := m.list(); jobs[0].Status != jobCancelled || jobs[0].Error != "" {
+		t.Errorf("cancelled job = %+v", jobs[0])
+	}
+	if out.Len() != 0 {
+		t.Errorf("a cancelled job was announced: %q", out.String())
+	}
+}
+
+func TestJobManagerPrunesFinishedJobs(t *testing.T) {
+	m := newJobManager(&bytes.Buffer{})
+	for i := 0; i < maxFinishedJobs+5; i++ {
+		m.start("rollout_status", "rollout", func(context.Context, func(string)) (string, error) { return "", nil })
+		m.wg.Wait()
+	}
+	jobs := m.list()
+	if len(jobs) != maxFinishedJobs || jobs[0].ID != 6 {
+		t.Errorf("kept %d jobs starting at #%d, want %d starting at #6", len(jobs), jobs[0].ID, maxFinishedJobs)
+	}
+}
+
+func TestRunInBackgroundWithoutManager(t *testing.T) {
+	if _, err := runInBackground(&agentState{}, "drain_node", "drain", nil); !errors.Is(err, errNoJobs) {
+		t.Errorf("err = %v, want errNoJobs", err)
+	}
+}
+
+func TestHandleDrainNodeInBackground(t *testing.T) {
+	stubDisruptionSafety(t, &k8s.DisruptionReport{Safe: true}, nil)
+	opts := stubDrainNode(t, &k8s.DrainResult{Context: "test-context", Node: "node-1", Cordoned: true})
+	state := approvingState()
+	state.outputFormat = OutputText
+	state.jobs = newJobManager(&bytes.Buffer{})
+
+	out, err := handleDrainNode(context.Background(), newTestK8sProvider(t), state, DrainNodeParams{Context: "test-context", Node: "node-1", Background: true})
+	if err != nil {
+		t.Fatalf("handleDrainNode: %v", err)
+	}
+	if text := out.(string); !strings.Contains(text, "Started job #1: drain node node-1 on test-context") || !strings.Contains(text, "/cancel 1") {
+		t.Errorf("output = %s", text)
+	}
+	state.jobs.wg.Wait()
+	if jobs := state.jobs.list(); len(jobs) != 1 || jobs[0].Status != jobSucceeded || opts.Progress == nil {
+		t.Errorf("jobs = %+v, progress set %t", jobs, opts.Progress != nil)
+	}
+	if record, ok := state.undo.last(); !ok || record.Action != undoUncordon {
+		t.Errorf("undo record = %+v, want uncordon", record)
+	}
+}
+
+func TestFollowRolloutInBackground(t *testing.T) {
+	stubRollout(t, &k8s.RolloutStatus{Kind: k8s.KindDeployment, Name: "api", TimedOut: true, Message: "2 of 3 updated replicas are available"})
+	state := approvingState()
+	state.jobs = newJobManager(&bytes.Buffer{})
+
+	out, err := handleRestartWorkload(context.Background(), newTestK8sProvider(t), state, RestartWorkloadParams{Context: "test-context", Name: "api", Background: true})
+	if err != nil {
+		t.Fatalf("handleRestartWorkload: %v", err)
+	}
+	if info := out.(JobInfo); info.Tool != toolRestartWorkload || info.Description != "rollout of deployment/api in default on test-context" {
+		t.Errorf("job = %+v", info)
+	}
+	state.jobs.wg.Wait()
+	if jobs := state.jobs.list(); jobs[0].Status != jobFailed || !strings.Contains(jobs[0].Result, "⌛") {
+		t.Errorf("job = %+v, want a failed job for the timed-out rollout", jobs[0])
+	}
+}
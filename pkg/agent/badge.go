@@ -0,0 +1,65 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the context health badge shown in the REPL prompt.
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/report"
+)
+
+// badgeStaleAfter is how old the last known status may get before the badge
+// shows its age.
+const badgeStaleAfter = 10 * time.Minute
+
+// contextBadge returns the prompt badge of the current context's last known
+// health, e.g. "[prod-eu ✗ 2 issues]", from the status cache; it never
+// contacts the cluster. A context not checked yet shows only its name.
+func contextBadge(k8sProvider *k8s.Provider, state *agentState, now time.Time) string {
+	if k8sProvider == nil {
+		return ""
+	}
+	contextName := k8sProvider.GetCurrentContext()
+	if contextName == "" {
+		return ""
+	}
+	status, checkedAt := k8sProvider.LastKnownStatus(contextName)
+	return healthBadge(contextName, status, checkedAt, state.healthThresholds(), now)
+}
+
+// healthBadge renders the badge of contextName from its last known status,
+// checked at checkedAt; status is nil when it was not checked yet.
+func healthBadge(contextName string, status *k8s.ClusterStatus, checkedAt time.Time, thresholds HealthThresholds, now time.Time) string {
+	if status == nil {
+		return wrapPromptColor(colorDim) + "[" + contextName + "]" + wrapPromptColor(colorReset)
+	}
+	col, health := colorGreen, "✓"
+	if !status.IsReachable {
+		col, health = colorRed, "✗ down"
+	} else {
+		summary := analyzeClusterHealth([]*k8s.ClusterStatus{status}, thresholds)
+		critical, warnings := summary.countIssues(report.SeverityCritical), summary.countIssues(report.SeverityWarning)
+		switch {
+		case critical > 0:
+			col, health = colorRed, fmt.Sprintf("✗ %d issue(s)", critical+warnings)
+		case warnings > 0:
+			col, health = colorYellow, fmt.Sprintf("⚠ %d issue(s)", warnings)
+		}
+	}
+	age := ""
+	if d := now.Sub(checkedAt); d >= badgeStaleAfter {
+		age = " " + wrapPromptColor(colorDim) + d.Round(time.Minute).String() + " ago" + wrapPromptColor(col)
+	}
+	return wrapPromptColor(col) + "[" + contextName + " " + health + age + "]" + wrapPromptColor(colorReset)
+}
+
+// wrapPromptColor wraps an ANSI sequence in \x01…\x02 so readline does not
+// count it toward the visible prompt length.
+func wrapPromptColor(seq string) string {
+	if seq == "" {
+		return ""
+	}
+	return "\x01" + seq + "\x02"
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+// stripPromptColors removes the wrapped ANSI sequences of a prompt.
+func stripPromptColors(prompt string) string {
+	for _, col := range []string{colorReset, colorDim, colorRed, colorGreen, colorYellow} {
+		prompt = strings.ReplaceAll(prompt, wrapPromptColor(col), "")
+	}
+	return prompt
+}
+
+func TestHealthBadge(t *testing.T) {
+	now := time.Now()
+	healthy := &k8s.ClusterStatus{ClusterInfo: k8s.ClusterInfo{Context: "prod-eu", IsReachable: true}, NodeCount: 3, HealthyNodes: 3}
+	degraded := &k8s.ClusterStatus{ClusterInfo: k8s.ClusterInfo{Context: "prod-eu", IsReachable: true}, NodeCount: 3, HealthyNodes: 1,
+		PodCount: 10, HealthyPods: 9, UnhealthyPods: []k8s.PodInfo{{Name: "web", Namespace: "shop", Status: "Error"}}}
+	warning := &k8s.ClusterStatus{ClusterInfo: k8s.ClusterInfo{Context: "prod-eu", IsReachable: true}, NodeCount: 3, HealthyNodes: 3,
+		PodCount: 10, HealthyPods: 9, UnhealthyPods: []k8s.PodInfo{{Name: "web", Namespace: "shop", Status: "Error"}}}
+	down := &k8s.ClusterStatus{ClusterInfo: k8s.ClusterInfo{Context: "prod-eu"}, Error: "connection refused"}
+
+	for _, tc := range []struct {
+		name      string
+		status    *k8s.ClusterStatus
+		checkedAt time.Time
+		want      string
+		color     string
+	}{
+		{"unchecked", nil, time.Time{}, "[prod-eu]", colorDim},
+		{"healthy", healthy, now, "[prod-eu ✓]", colorGreen},
+		{"warnings", warning, now, "[prod-eu ⚠ 1 issue(s)]", colorYellow},
+		{"critical", degraded, now, "[prod-eu ✗ 2 issue(s)]", colorRed},
+		{"down", down, now, "[prod-eu ✗ down]", colorRed},
+		{"stale", healthy, now.Add(-42 * time.Minute), "[prod-eu ✓ 42m0s ago]", colorGreen},
+	} {
+		badge := healthBadge("prod-eu", tc.status, tc.checkedAt, HealthThresholds{}, now)
+		if got := stripPromptColors(badge); got != tc.want {
+			t.Errorf("%s: badge = %q, want %q", tc.name, got, tc.want)
+		}
+		if !strings.HasPrefix(badge, wrapPromptColor(tc.color)) {
+			t.Errorf("%s: badge %q should start in %q", tc.name, badge, tc.color)
+		}
+	}
+}
+
+func TestContextBadgeAndPrompt(t *testing.T) {
+	provider := createMockProvider(t)
+	state := &agentState{quotaPercentage: 50}
+	badge := contextBadge(provider, state, time.Now())
+	if got := stripPromptColors(badge); got != "["+provider.GetCurrentContext()+"]" {
+		t.Errorf("badge of an unchecked context = %q", got)
+	}
+	if contextBadge(nil, state, time.Now()) != "" {
+		t.Error("badge without a provider should be empty")
+	}
+
+	if got := stripPromptColors(rlPromptString(state, "[prod ✓]")); got != "[prod ✓] [50%] ❯ " {
+		t.Errorf("prompt = %q", got)
+	}
+	state.quotaUnlimited = true
+	if got := stripPromptColors(rlPromptString(state, "[prod ✓]")); got != "[prod ✓] ❯ " {
+		t.Errorf("prompt with unlimited quota = %q", got)
+	}
+	state.streamerMode = true
+	if got := rlPromptString(state, "[prod ✓]"); got != "❯ " {
+		t.Errorf("streamer mode prompt = %q, want no badge", got)
+	}
+}
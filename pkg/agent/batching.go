@@ -0,0 +1,256 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains tool batching: when the model calls the same per-cluster
+// tool for one context after another, e.g. get_cluster_status for every
+// cluster the user named in turn, the second call runs the tool for the
+// other contexts the prompt names concurrently and returns the results of
+// all of them merged into one.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/e9169/kopilot/pkg/debug"
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// batchableTools are the read-only tools that inspect one cluster given by
+// their "context" argument, whose other arguments mean the same on every
+// cluster.
+var batchableTools = map[string]bool{
+	toolGetClusterStatus: true,
+	toolClusterCapacity:  true,
+	toolCheckNodeOS:      true,
+	toolCheckWebhooks:    true,
+	toolCheckAutoscaling: true,
+	toolCheckJobs:        true,
+	toolGetQuotaUsage:    true,
+	toolCheckGPUs:        true,
+	toolReviewSATokens:   true,
+}
+
+// maxBatchedContexts bounds the contexts one batched call runs, including
+// the one the model asked for.
+const maxBatchedContexts = 10
+
+// toolBatcher remembers the calls of the current prompt to spot a tool
+// repeated across contexts. It is safe for concurrent use.
+type toolBatcher struct {
+	k8sProvider *k8s.Provider
+	mu          sync.Mutex
+	// named are the contexts the prompt names, the only ones batched.
+	named map[string]bool
+	// key identifies the streak: the tool, output format and arguments
+	// other than the context; seen are the contexts it covered.
+	key  string
+	seen map[string]bool
+}
+
+func newToolBatcher(k8sProvider *k8s.Provider) *toolBatcher {
+	return &toolBatcher{k8sProvider: k8sProvider}
+}
+
+// reset forgets the calls of the previous prompt and records the contexts
+// prompt names.
+func (b *toolBatcher) reset(prompt string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.key, b.seen = "", nil
+	b.named = make(map[string]bool)
+	for _, cluster := range b.k8sProvider.GetClusters() {
+		if namesWord(prompt, cluster.Context) {
+			b.named[cluster.Context] = true
+		}
+	}
+}
+
+// namesWord reports whether text contains word not directly preceded or
+// followed by a letter, digit, '-', '_' or '.' (as in "prod" and
+// "prod-eu").
+func namesWord(text, word string) bool {
+	isNamePart := func(c byte) bool {
+		return c == '-' || c == '_' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+	}
+	for i := 0; word != ""; {
+		j := strings.Index(text[i:], word)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		// A trailing '.' ends a sentence rather than continuing the name.
+		endsName := end == len(text) || !isNamePart(text[end]) || text[end] == '.' && (end+1 == len(text) || !isNamePart(text[end+1]))
+		if (start == 0 || !isNamePart(text[start-1])) && endsName {
+			return true
+		}
+		i = start + 1
+	}
+	return false
+}
+
+// plan records a call of the streak key for contextName. When the call
+// repeats the previous tool for another context, it returns the other
+// contexts the prompt names that the streak has not covered yet, marked as
+// covered so the call runs them together.
+func (b *toolBatcher) plan(key, contextName string) (others []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if key != b.key {
+		b.key, b.seen = key, map[string]bool{contextName: true}
+		return nil
+	}
+	repeated := !b.seen[contextName]
+	b.seen[contextName] = true
+	if !repeated {
+		return nil
+	}
+	for _, cluster := range b.k8sProvider.GetClusters() {
+		if len(others) == maxBatchedContexts-1 {
+			break
+		}
+		if b.named[cluster.Context] && !b.seen[cluster.Context] {
+			others = append(others, cluster.Context)
+			b.seen[cluster.Context] = true
+		}
+	}
+	slices.Sort(others)
+	return others
+}
+
+// BatchedToolResult is the JSON output of a batchable tool run for several
+// contexts at once.
+type BatchedToolResult struct {
+	Tool    string               `json:"tool"`
+	Results []BatchedToolContext `json:"results"`
+}
+
+// BatchedToolContext is the result of one context of a batched run.
+type BatchedToolContext struct {
+	Context string         `json:"context"`
+	Result  any            `json:"result,omitempty"`
+	Error   *llm.ToolError `json:"error,omitempty"`
+}
+
+// withToolBatching runs batchableTools for the other contexts the prompt
+// names when the model repeats one of them across contexts: the call runs
+// the tool for all of them concurrently and returns their results merged
+// into one, so the model needs no further call for them.
+func withToolBatching(tools []llm.Tool, batcher *toolBatcher, state *agentState) []llm.Tool {
+	for i := range tools {
+		name, handler := tools[i].Name, tools[i].Handler
+		if !batchableTools[name] {
+			continue
+		}
+		tools[i].Handler = func(params any, inv llm.ToolInvocation) (any, error) {
+			args, _ := llm.NormalizeToolArguments(params)
+			contextName := contextArg(args)
+			if contextName == "" {
+				return handler(params, inv)
+			}
+			rest := maps.Clone(args)
+			delete(rest, "context")
+			key := resultCacheKey(name, state.outputFormat, rest)
+			if key == "" {
+				return handler(params, inv)
+			}
+			others := batcher.plan(key, contextName)
+			if len(others) == 0 {
+				return handler(params, inv)
+			}
+			debug.Logf(debug.Tools, "%s repeated across contexts: running it for %s too", name, strings.Join(others, ", "))
+			return runBatched(batcher.k8sProvider, name, handler, params, args, inv, contextName, others, isJSONOutput(state.outputFormat))
+		}
+	}
+	return tools
+}
+
+// batchedRun is the outcome of one context of a batched run.
+type batchedRun struct {
+	context string
+	result  any
+	err     error
+}
+
+// runBatched runs handler for contextName with params and for each of
+// others with args, concurrently, and merges the results, each failure
+// classified as the model would receive it. It fails only when every
+// context failed, with the error of contextName.
+func runBatched(k8sProvider *k8s.Provider, name string, handler func(any, llm.ToolInvocation) (any, error), params any, args map[string]any, inv llm.ToolInvocation, contextName string, others []string, jsonOutput bool) (any, error) {
+	runs := make([]batchedRun, 1+len(others))
+	var wg sync.WaitGroup
+	for i, ctxName := range append([]string{contextName}, others...) {
+		runs[i].context = ctxName
+		wg.Go(func() {
+			if i == 0 {
+				runs[i].result, runs[i].err = handler(params, inv)
+				return
+			}
+			// Transient failures of the contexts the model did not ask for
+			// are not put to the user.
+			callArgs := maps.Clone(args)
+			callArgs["context"] = ctxName
+			callInv := inv
+			callInv.Context = context.WithValue(inv.Ctx(), noRetryPromptKey{}, true)
+			runs[i].result, runs[i].err = handler(callArgs, callInv)
+		})
+	}
+	wg.Wait()
+	if !slices.ContainsFunc(runs, func(r batchedRun) bool { return r.err == nil }) {
+		return nil, runs[0].err
+	}
+
+	if jsonOutput {
+		merged := BatchedToolResult{Tool: name}
+		for _, run := range runs {
+			entry := BatchedToolContext{Context: run.context, Result: run.result}
+			if run.err != nil {
+				entry.Result, entry.Error = nil, classifyToolError(run.err, k8sProvider)
+			}
+			merged.Results = append(merged.Results, entry)
+		}
+		return merged, nil
+	}
+
+	contexts := make([]string, len(runs))
+	for i, run := range runs {
+		contexts[i] = run.context
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s ran for %s together; the result of each follows.]\n", name, strings.Join(contexts, ", "))
+	for _, run := range runs {
+		fmt.Fprintf(&sb, "\n=== %s ===\n", run.context)
+		if run.err != nil {
+			toolErr := classifyToolError(run.err, k8sProvider)
+			fmt.Fprintf(&sb, "Error (%s): %s\n", toolErr.Code, toolErr.Message)
+			if toolErr.Hint != "" {
+				fmt.Fprintf(&sb, "Hint: %s\n", toolErr.Hint)
+			}
+			continue
+		}
+		text, ok := run.result.(string)
+		if !ok {
+			data, _ := json.MarshalIndent(run.result, "", "  ")
+			text = string(data)
+		}
+		sb.WriteString(strings.TrimRight(text, "\n") + "\n")
+	}
+	return sb.String(), nil
+}
+
+// noRetryPromptKey marks the context of the batched runs for contexts the
+// model did not ask for: their transient failures are not put to the user.
+type noRetryPromptKey struct{}
+
+// retryPromptAllowed reports whether a failing call may ask the user to
+// retry it.
+func retryPromptAllowed(ctx context.Context) bool {
+	return ctx.Value(noRetryPromptKey{}) == nil
+}
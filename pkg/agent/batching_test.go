@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// newBatchTestProvider returns a provider of the contexts a, b, c and d.
+func newBatchTestProvider(t *testing.T) *k8s.Provider {
+	t.Helper()
+	config := clientcmdapi.NewConfig()
+	config.Clusters["c"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		config.Contexts[name] = &clientcmdapi.Context{Cluster: "c"}
+	}
+	config.CurrentContext = "a"
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := k8s.NewProvider(path)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	return provider
+}
+
+func TestWithToolBatching(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	// When set, started lets the batched runs wait for one another, which
+	// only returns if they run concurrently.
+	var started *sync.WaitGroup
+	tools := []llm.Tool{{Name: toolGetClusterStatus, Handler: func(params any, _ llm.ToolInvocation) (any, error) {
+		ctx := params.(map[string]any)["context"].(string)
+		mu.Lock()
+		calls[ctx]++
+		mu.Unlock()
+		if started != nil {
+			started.Done()
+			started.Wait()
+		}
+		if ctx == "d" {
+			return nil, errors.New("connection refused")
+		}
+		return "status of " + ctx, nil
+	}}}
+	batcher := newToolBatcher(newBatchTestProvider(t))
+	state := &agentState{outputFormat: OutputText}
+	status := withToolBatching(tools, batcher, state)[0].Handler
+	call := func(ctx string) (any, error) {
+		return status(map[string]any{"context": ctx}, llm.ToolInvocation{Context: context.Background()})
+	}
+
+	batcher.reset("compare a, b, c and d.")
+	if out, _ := call("a"); out != "status of a" {
+		t.Fatalf("first call = %v, want a's result alone", out)
+	}
+	started = &sync.WaitGroup{}
+	started.Add(3)
+	out, err := call("b")
+	started = nil
+	want := "[get_cluster_status ran for b, c, d together; the result of each follows.]\n" +
+		"\n=== b ===\nstatus of b\n" +
+		"\n=== c ===\nstatus of c\n" +
+		"\n=== d ===\nError (unavailable): connection refused\n"
+	if err != nil || !strings.HasPrefix(fmt.Sprint(out), want) {
+		t.Fatalf("repeated call = %q, %v; want the merged result\n%s", out, err, want)
+	}
+	mu.Lock()
+	if fmt.Sprint(calls) != "map[a:1 b:1 c:1 d:1]" {
+		t.Errorf("handler calls = %v, want one per context", calls)
+	}
+	calls = map[string]int{}
+	mu.Unlock()
+
+	batcher.reset("status of a and b")
+	call("a")
+	if out, _ := call("b"); out != "status of b" {
+		t.Errorf("contexts not named in the prompt were batched: %q", out)
+	}
+	mu.Lock()
+	if fmt.Sprint(calls) != "map[a:1 b:1]" {
+		t.Errorf("handler calls = %v, want only the contexts asked for", calls)
+	}
+	mu.Unlock()
+
+	state.outputFormat = OutputJSON
+	batcher.reset("compare a, b, c and d.")
+	call("a")
+	started = &sync.WaitGroup{}
+	started.Add(3)
+	out, err = call("c")
+	merged, ok := out.(BatchedToolResult)
+	if err != nil || !ok || len(merged.Results) != 3 {
+		t.Fatalf("repeated call in JSON = %#v, %v; want a BatchedToolResult of c, b and d", out, err)
+	}
+	if r := merged.Results[0]; r.Context != "c" || r.Result != "status of c" || r.Error != nil {
+		t.Errorf("first result = %+v, want the context asked for", r)
+	}
+	if r := merged.Results[2]; r.Context != "d" || r.Result != nil || r.Error == nil || r.Error.Code != llm.ToolErrorUnavailable {
+		t.Errorf("failed context = %+v, want its classified error", r)
+	}
+}
+
+func TestWithToolBatchingAllFailed(t *testing.T) {
+	tools := []llm.Tool{{Name: toolCheckJobs, Handler: func(params any, _ llm.ToolInvocation) (any, error) {
+		return nil, fmt.Errorf("%s: connection refused", params.(map[string]any)["context"])
+	}}}
+	batcher := newToolBatcher(newBatchTestProvider(t))
+	jobs := withToolBatching(tools, batcher, &agentState{outputFormat: OutputText})[0].Handler
+	batcher.reset("jobs on a and b")
+	jobs(map[string]any{"context": "a"}, llm.ToolInvocation{Context: context.Background()})
+	out, err := jobs(map[string]any{"context": "b"}, llm.ToolInvocation{Context: context.Background()})
+	if out != nil || err == nil || err.Error() != "b: connection refused" {
+		t.Errorf("batched call with every context failing = %v, %v; want the error of the context asked for", out, err)
+	}
+}
+
+func TestNamesWord(t *testing.T) {
+	tests := []struct {
+		text, word string
+		want       bool
+	}{
+		{"check prod and staging", "prod", true},
+		{"check prod.", "prod", true},
+		{"check prod-eu", "prod", false},
+		{"check production", "prod", false},
+		{"is kind-dev.local up", "kind-dev", false},
+		{"", "prod", false},
+	}
+	for _, tt := range tests {
+		if got := namesWord(tt.text, tt.word); got != tt.want {
+			t.Errorf("namesWord(%q, %q) = %t, want %t", tt.text, tt.word, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the per-tool time budgets shown to the model and enforced on each call.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// defaultToolBudget covers tools whose Kubernetes calls are bounded by
+// k8s.DefaultAPITimeout, with room to format the result.
+const defaultToolBudget = k8s.DefaultAPITimeout + 15*time.Second
+
+// statusToolBudget covers tools waiting for cluster status checks, bounded
+// by k8s.Provider.StatusBudget, with room to format the result.
+const statusToolBudget = k8s.DefaultStatusBudget + 10*time.Second
+
+// toolBudget returns the time budget of the named tool. Budgets covering an
+// API call grow with an API timeout longer than the default; those covering
+// status checks follow the provider's status budget.
+func toolBudget(name string, apiTimeout, statusBudget time.Duration) time.Duration {
+	budget := toolBudgets[name]
+	if budget == defaultToolBudget && apiTimeout > k8s.DefaultAPITimeout {
+		budget = apiTimeout + 15*time.Second
+	}
+	if budget == statusToolBudget && statusBudget > 0 {
+		budget = statusBudget + 10*time.Second
+	}
+	return budget
+}
+
+// toolBudgets are the time budgets of read-only tools. Tools that wait for
+// write approval (kubectl_exec, apply_resource_yaml, execute_plan, ...) or run
+// until stopped (port_forward) have none: the user's decision must not race a
+// deadline. kubectl_exec bounds each command with KOPILOT_KUBECTL_TIMEOUT.
+var toolBudgets = map[string]time.Duration{
+	toolListClusters:          10 * time.Second,
+	toolGetClusterStatus:      statusToolBudget,
+	toolCompareClusters:       statusToolBudget,
+	toolCheckAllClusters:      statusToolBudget,
+	toolSanitizeCluster:       defaultToolBudget,
+	toolCheckGPUs:             defaultToolBudget,
+	toolCheckNodeOS:           defaultToolBudget,
+	toolCheckImageArch:        defaultToolBudget,
+	toolGetResourceYAML:       defaultToolBudget,
+	toolValidateManifest:      defaultToolBudget,
+	toolRecommendResources:    defaultToolBudget,
+	toolResilienceReport:      defaultToolBudget,
+	toolCheckDNS:              defaultToolBudget,
+	toolReviewSATokens:        defaultToolBudget,
+	toolGetQuotaUsage:         defaultToolBudget,
+	toolRankEventNoise:        defaultToolBudget,
+	toolClusterCapacity:       defaultToolBudget,
+	toolRecentChanges:         defaultToolBudget,
+	toolImageProvenance:       60 * time.Second,
+	toolDependencyMap:         defaultToolBudget,
+	toolGenerateReport:        statusToolBudget,
+	toolGetHealthHistory:      10 * time.Second,
+	toolQueryPrometheus:       defaultToolBudget,
+	toolQueryLogs:             defaultToolBudget,
+	toolGetGitOpsStatus:       defaultToolBudget,
+	toolDiffWorkloads:         defaultToolBudget,
+	toolGetCustomResources:    defaultToolBudget,
+	toolExportResources:       defaultToolBudget,
+	toolCheckIngressTLS:       defaultToolBudget,
+	toolCheckDisruptionSafety: defaultToolBudget,
+	toolCheckQuotaFairness:    defaultToolBudget,
+	toolCheckJobs:             defaultToolBudget,
+	toolCheckAutoscaling:      defaultToolBudget,
+	toolCheckWebhooks:         defaultToolBudget,
+}
+
+// errToolBudgetExceeded marks calls stopped because their time budget ran out.
+var errToolBudgetExceeded = errors.New("time budget exceeded")
+
+// withTimeBudget states the budget of t in its description and enforces it:
+// the call fails with an error naming the cluster when the budget runs out,
+// even if the handler does not honour its context.
+func withTimeBudget(t llm.Tool, apiTimeout, statusBudget time.Duration) llm.Tool {
+	budget := toolBudget(t.Name, apiTimeout, statusBudget)
+	if budget <= 0 {
+		return t
+	}
+	t.Description += fmt.Sprintf(" Time budget: %s.", budget)
+	name, handler := t.Name, t.Handler
+	t.Handler = func(params any, inv llm.ToolInvocation) (any, error) {
+		parent := inv.Ctx()
+		ctx, cancel := context.WithTimeout(parent, budget)
+		defer cancel()
+		inv.Context = ctx
+
+		type result struct {
+			value any
+			err   error
+		}
+		// Buffered so a handler ignoring its context can still finish.
+		done := make(chan result, 1)
+		go func() {
+			value, err := handler(params, inv)
+			done <- result{value, err}
+		}()
+
+		select {
+		case r := <-done:
+			if r.err != nil && errors.Is(r.err, context.DeadlineExceeded) && parent.Err() == nil {
+				return nil, budgetError(name, params, budget, r.err)
+			}
+			return r.value, r.err
+		case <-ctx.Done():
+			if err := parent.Err(); err != nil {
+				return nil, err
+			}
+			return nil, budgetError(name, params, budget, errToolBudgetExceeded)
+		}
+	}
+	return t
+}
+
+// budgetError explains a timeout in terms the model can repeat to the user,
+// naming the cluster of the call when it has one.
+func budgetError(tool string, params any, budget time.Duration, cause error) error {
+	args, _ := llm.NormalizeToolArguments(params)
+	if contextName, _ := args["context"].(string); contextName != "" {
+		return fmt.Errorf("cluster %s did not respond within %s (%s time budget); treat it as unresponsive instead of drawing conclusions from partial data: %w",
+			contextName, budget, tool, cause)
+	}
+	return fmt.Errorf("%s did not finish within its %s time budget; say so instead of drawing conclusions from partial data: %w", tool, budget, cause)
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// withBudget gives the tool name a budget for the duration of the test.
+func withBudget(t *testing.T, name string, budget time.Duration) {
+	t.Helper()
+	toolBudgets[name] = budget
+	t.Cleanup(func() { delete(toolBudgets, name) })
+}
+
+type budgetTestParams struct {
+	Context string `json:"context"`
+}
+
+func TestWithTimeBudget(t *testing.T) {
+	withBudget(t, "slow_tool", 50*time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+
+	slow := withTimeBudget(llm.DefineTool("slow_tool", "Slow.", func(_ budgetTestParams, _ llm.ToolInvocation) (any, error) {
+		<-release // ignores its context
+		return "late", nil
+	}), 0, 0)
+	if slow.Description != "Slow. Time budget: 50ms." {
+		t.Errorf("description = %q", slow.Description)
+	}
+
+	start := time.Now()
+	_, err := slow.Handler(map[string]any{"context": "prod-ap"}, llm.ToolInvocation{Context: context.Background()})
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("budget not enforced, call took %s", time.Since(start))
+	}
+	if !errors.Is(err, errToolBudgetExceeded) || !strings.Contains(err.Error(), "cluster prod-ap did not respond within 50ms") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err = slow.Handler(nil, llm.ToolInvocation{Context: context.Background()})
+	if err == nil || !strings.Contains(err.Error(), "slow_tool did not finish within its 50ms time budget") {
+		t.Errorf("unexpected error without context: %v", err)
+	}
+
+	parent, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := slow.Handler(nil, llm.ToolInvocation{Context: parent}); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled parent = %v, want context.Canceled", err)
+	}
+}
+
+func TestWithTimeBudgetHandlerResults(t *testing.T) {
+	withBudget(t, "quick_tool", time.Second)
+	var gotDeadline bool
+	quick := withTimeBudget(llm.DefineTool("quick_tool", "Quick.", func(p budgetTestParams, inv llm.ToolInvocation) (any, error) {
+		_, gotDeadline = inv.Ctx().Deadline()
+		if p.Context == "timeout" {
+			return nil, fmt.Errorf("listing pods: %w", context.DeadlineExceeded)
+		}
+		return "ok", nil
+	}), 0, 0)
+
+	if out, err := quick.Handler(nil, llm.ToolInvocation{Context: context.Background()}); out != "ok" || err != nil {
+		t.Errorf("Handler() = %v, %v", out, err)
+	}
+	if !gotDeadline {
+		t.Error("handler context should carry the budget deadline")
+	}
+	_, err := quick.Handler(map[string]any{"context": "timeout"}, llm.ToolInvocation{Context: context.Background()})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "cluster timeout did not respond within 1s") {
+		t.Errorf("inner deadline error = %v", err)
+	}
+}
+
+func TestToolBudgetsSkipApprovalTools(t *testing.T) {
+	for _, name := range []string{toolKubectlExec, toolApplyResourceYAML, toolMigrateNamespace, toolBulkLabel, toolCheckConnectivity, toolExecutePlan, toolPortForward, toolExecInPod, toolChaos, toolUndoLastOperation, toolDrainNode, toolRestartWorkload, toolRolloutStatus, toolWatchResource} {
+		if _, ok := toolBudgets[name]; ok {
+			t.Errorf("%s waits for approval or runs until stopped and must not have a time budget", name)
+		}
+	}
+	unbudgeted := withTimeBudget(llm.Tool{Name: toolKubectlExec, Description: "Run kubectl."}, 0, 0)
+	if unbudgeted.Description != "Run kubectl." || unbudgeted.Handler != nil {
+		t.Errorf("tool without budget should be unchanged: %+v", unbudgeted)
+	}
+}
+
+func TestToolBudgetFollowsAPITimeout(t *testing.T) {
+	if got := toolBudget(toolCheckDNS, 0, 0); got != defaultToolBudget {
+		t.Errorf("toolBudget(default) = %s, want %s", got, defaultToolBudget)
+	}
+	if got := toolBudget(toolCheckDNS, 2*time.Minute, 0); got != 2*time.Minute+15*time.Second {
+		t.Errorf("toolBudget(2m API timeout) = %s", got)
+	}
+	if got := toolBudget(toolListClusters, 2*time.Minute, time.Minute); got != toolBudgets[toolListClusters] {
+		t.Errorf("budgets not covering an API call should not grow, got %s", got)
+	}
+	if got := toolBudget(toolCheckAllClusters, 0, 0); got != statusToolBudget {
+		t.Errorf("toolBudget(check_all_clusters, default) = %s, want %s", got, statusToolBudget)
+	}
+	if got := toolBudget(toolCheckAllClusters, 0, 2*time.Minute); got != 2*time.Minute+10*time.Second {
+		t.Errorf("toolBudget(check_all_clusters, 2m status budget) = %s", got)
+	}
+}
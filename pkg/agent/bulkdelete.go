@@ -0,0 +1,267 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the bulk_delete tool: enumerated, count-confirmed deletions.
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// bulkDeleteProtectedKinds are never deleted in bulk: losing them takes
+// everything inside with them.
+var bulkDeleteProtectedKinds = map[string]bool{
+	"namespace": true, "namespaces": true, "ns": true,
+	"node": true, "nodes": true, "no": true,
+	"customresourcedefinition": true, "customresourcedefinitions": true, "crd": true, "crds": true,
+	"persistentvolume": true, "persistentvolumes": true, "pv": true,
+}
+
+// BulkDeleteParams defines parameters for bulk_delete
+type BulkDeleteParams struct {
+	Context       string `json:"context" jsonschema:"The cluster context name (required)"`
+	Kind          string `json:"kind" jsonschema:"Resource kind, e.g. pods, jobs, configmaps"`
+	Namespace     string `json:"namespace,omitempty" jsonschema:"Namespace to search; omit for the context default or cluster-scoped kinds"`
+	AllNamespaces bool   `json:"all_namespaces,omitempty" jsonschema:"Search all namespaces (requires selector or name_pattern)"`
+	Selector      string `json:"selector,omitempty" jsonschema:"Optional label selector choosing the objects, e.g. 'app=web,env=preview'"`
+	NamePattern   string `json:"name_pattern,omitempty" jsonschema:"Optional wildcard on object names, e.g. 'test-*' or 'job-2024??-*'"`
+	All           bool   `json:"all,omitempty" jsonschema:"Delete every object of the kind in the namespace; required when neither selector nor name_pattern is given"`
+	DryRun        bool   `json:"dry_run,omitempty" jsonschema:"If true, only list the objects that would be deleted"`
+	ConfirmCount  int    `json:"confirm_count,omitempty" jsonschema:"The number of objects the user agreed to delete after seeing the list; the deletion only runs when it equals the current count"`
+	BatchSize     int    `json:"batch_size,omitempty" jsonschema:"Objects per kubectl call (default 20, max 100)"`
+}
+
+// BulkDeleteResult defines JSON output for bulk_delete
+type BulkDeleteResult struct {
+	Cluster     string   `json:"cluster"`
+	Context     string   `json:"context"`
+	Kind        string   `json:"kind"`
+	Filter      string   `json:"filter"`
+	DryRun      bool     `json:"dry_run"`
+	Matched     int      `json:"matched"`
+	Objects     []string `json:"objects"`
+	NeedConfirm bool     `json:"need_confirm,omitempty"`
+	Batches     int      `json:"batches,omitempty"`
+	Deleted     int      `json:"deleted"`
+	Failed      []string `json:"failed,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+func defineBulkDeleteTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolBulkDelete,
+		"Delete many objects of one kind at once, chosen by label selector, name wildcard (e.g. 'test-*') or all of them in a namespace. Lists exactly which objects match first; the deletion only runs when confirm_count equals the number of listed objects, and the user types that number to approve. Objects are deleted by name in rate-limited batches. Use instead of kubectl delete with --all or wildcards, which are blocked.",
+		func(params BulkDeleteParams, inv llm.ToolInvocation) (any, error) {
+			return handleBulkDelete(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+// validateBulkDeleteParams checks names, the selector and the pattern, and
+// that the scope of the deletion is explicit.
+func validateBulkDeleteParams(params *BulkDeleteParams) error {
+	if params.Context == "" || params.Kind == "" {
+		return fmt.Errorf("context and kind are required")
+	}
+	if !isValidKubernetesName(params.Kind) {
+		return fmt.Errorf("invalid kind: %s", params.Kind)
+	}
+	if bulkDeleteProtectedKinds[strings.ToLower(params.Kind)] {
+		return fmt.Errorf("%s cannot be deleted in bulk; delete them one by one", params.Kind)
+	}
+	if params.Namespace != "" && !isValidKubernetesName(params.Namespace) {
+		return fmt.Errorf("invalid namespace name: %s", params.Namespace)
+	}
+	if params.Selector != "" {
+		if _, err := labels.Parse(params.Selector); err != nil {
+			return fmt.Errorf("invalid selector: %w", err)
+		}
+	}
+	if params.NamePattern != "" {
+		if _, err := path.Match(params.NamePattern, ""); err != nil {
+			return fmt.Errorf("invalid name_pattern %q: %w", params.NamePattern, err)
+		}
+	}
+	filtered := strings.TrimSpace(params.Selector) != "" || params.NamePattern != ""
+	switch {
+	case !filtered && params.AllNamespaces:
+		return fmt.Errorf("deleting every %s in all namespaces is not allowed: give a selector or name_pattern", params.Kind)
+	case !filtered && !params.All:
+		return fmt.Errorf("give a selector, a name_pattern, or all=true to delete every %s in the namespace", params.Kind)
+	}
+	if params.ConfirmCount < 0 {
+		return fmt.Errorf("confirm_count must not be negative")
+	}
+	if params.BatchSize == 0 {
+		params.BatchSize = defaultBulkBatchSize
+	}
+	if params.BatchSize < 1 || params.BatchSize > maxBulkBatchSize {
+		return fmt.Errorf("batch_size must be between 1 and %d", maxBulkBatchSize)
+	}
+	return nil
+}
+
+// bulkDeleteFilter describes how the objects were chosen.
+func bulkDeleteFilter(params BulkDeleteParams) string {
+	var parts []string
+	if params.Selector != "" {
+		parts = append(parts, fmt.Sprintf("selector %q", params.Selector))
+	}
+	if params.NamePattern != "" {
+		parts = append(parts, fmt.Sprintf("name %q", params.NamePattern))
+	}
+	if len(parts) == 0 {
+		return "all"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func handleBulkDelete(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params BulkDeleteParams) (any, error) {
+	if err := validateBulkDeleteParams(&params); err != nil {
+		return nil, err
+	}
+	cluster, err := getClusterForContext(k8sProvider, params.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := listBulkDeleteObjects(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	filter := bulkDeleteFilter(params)
+	if len(objects) > maxBulkObjects {
+		return nil, fmt.Errorf("%s matches %d %s (limit %d); narrow the selector, pattern or namespace", filter, len(objects), params.Kind, maxBulkObjects)
+	}
+
+	result := BulkDeleteResult{
+		Cluster: cluster.Name, Context: params.Context, Kind: params.Kind,
+		Filter: filter, DryRun: params.DryRun, Matched: len(objects), Objects: []string{},
+	}
+	for _, obj := range objects {
+		result.Objects = append(result.Objects, obj.id())
+	}
+	if params.DryRun || len(objects) == 0 {
+		return bulkDeleteResult(state, result), nil
+	}
+	// The count is checked against a fresh enumeration so objects created
+	// since the user saw the list are never deleted unseen.
+	if params.ConfirmCount != len(objects) {
+		result.NeedConfirm = true
+		return bulkDeleteResult(state, result), nil
+	}
+
+	prompt := fmt.Sprintf("kubectl --context %s delete %d %s (%s)", params.Context, len(objects), params.Kind, filter)
+	req := ApprovalRequest{
+		Tool: toolBulkDelete, Cluster: cluster.Name, Context: params.Context, Command: prompt,
+		Objects: result.Objects, ConfirmCount: len(objects),
+	}
+	proceed, cancelResult, err := enforceApproval(ctx, state, false, req)
+	if err != nil {
+		return nil, err
+	}
+	if !proceed {
+		return cancelResult, nil
+	}
+
+	deleteBulkBatches(ctx, state, params, objects, &result)
+	state.publishWrite(req, errorOf(result.Error))
+	return bulkDeleteResult(state, result), nil
+}
+
+// listBulkDeleteObjects returns the objects matching the selector and name
+// pattern, sorted by namespace and name.
+func listBulkDeleteObjects(ctx context.Context, params BulkDeleteParams) ([]bulkObject, error) {
+	objects, err := listBulkObjects(ctx, BulkLabelParams{
+		Context: params.Context, Kind: params.Kind, Selector: params.Selector,
+		Namespace: params.Namespace, AllNamespaces: params.AllNamespaces,
+	})
+	if err != nil || params.NamePattern == "" {
+		return objects, err
+	}
+	matched := []bulkObject{}
+	for _, obj := range objects {
+		if ok, _ := path.Match(params.NamePattern, obj.Metadata.Name); ok {
+			matched = append(matched, obj)
+		}
+	}
+	return matched, nil
+}
+
+// deleteBulkBatches deletes objects by name batch by batch, stopping at the
+// first failure; objects not deleted are reported in result.Failed.
+func deleteBulkBatches(ctx context.Context, state *agentState, params BulkDeleteParams, objects []bulkObject, result *BulkDeleteResult) {
+	batches := bulkBatches(objects, params.BatchSize)
+	result.Batches = len(batches)
+	for i, batch := range batches {
+		if i > 0 {
+			time.Sleep(bulkBatchPause)
+		}
+		if !isJSONOutput(state.outputFormat) {
+			fmt.Fprintf(state.stdout(), "\r\033[K%s   batch %d/%d: %d object(s), %d/%d done%s\n", colorDim, i+1, len(batches), len(batch), result.Deleted, len(objects), colorReset)
+		}
+		args := []string{"delete", params.Kind}
+		for _, obj := range batch {
+			args = append(args, obj.Metadata.Name)
+		}
+		if ns := batch[0].Metadata.Namespace; ns != "" {
+			args = append(args, "-n", ns)
+		}
+		args = append(args, "--ignore-not-found")
+		fullCommand, cmdArgs := buildKubectlCommand(params.Context, args)
+		printExecutionHeader(state, false, fullCommand)
+		if output, err := runThrottled(ctx, cmdArgs); err != nil {
+			result.Error = fmt.Sprintf("batch %d/%d failed: %v: %s", i+1, len(batches), err, strings.TrimSpace(string(output)))
+			for _, obj := range objects[result.Deleted:] {
+				result.Failed = append(result.Failed, obj.id())
+			}
+			return
+		}
+		result.Deleted += len(batch)
+	}
+}
+
+// bulkDeleteResult returns result as JSON or formatted text.
+func bulkDeleteResult(state *agentState, result BulkDeleteResult) any {
+	if isJSONOutput(state.outputFormat) {
+		return result
+	}
+	return formatBulkDeleteResult(result)
+}
+
+// formatBulkDeleteResult formats a BulkDeleteResult as human-readable text.
+// Every matched object is listed: the user confirms the count against it.
+func formatBulkDeleteResult(r BulkDeleteResult) string {
+	var sb strings.Builder
+	title := "Bulk Delete"
+	if r.DryRun {
+		title += " (dry run)"
+	}
+	fmt.Fprintf(&sb, "%s: %s, %s (%s)\n", title, r.Kind, r.Filter, r.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+	fmt.Fprintf(&sb, "📋 MATCHED: %d object(s)\n", r.Matched)
+	for _, obj := range r.Objects {
+		fmt.Fprintf(&sb, "  - %s\n", obj)
+	}
+
+	switch {
+	case r.Matched == 0:
+		sb.WriteString("\n✅ Nothing to delete\n")
+	case r.DryRun:
+		sb.WriteString("\nℹ️  Dry run: nothing was deleted.\n")
+	case r.NeedConfirm:
+		fmt.Fprintf(&sb, "\n⚠️  Nothing was deleted. Show this list to the user; if they agree, run again with confirm_count=%d.\n", r.Matched)
+	case r.Error != "":
+		fmt.Fprintf(&sb, "\n❌ FAILED after %d/%d object(s): %s\n", r.Deleted, r.Matched, r.Error)
+		fmt.Fprintf(&sb, "   Not deleted: %s\n", strings.Join(r.Failed, ", "))
+	default:
+		fmt.Fprintf(&sb, "\n🗑️  DELETED: %d object(s) in %d batch(es)\n", r.Deleted, r.Batches)
+	}
+	return sb.String()
+}
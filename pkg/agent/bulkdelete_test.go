@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidateBulkDeleteParams(t *testing.T) {
+	base := BulkDeleteParams{Context: "c", Kind: "jobs", Namespace: "ci", NamePattern: "test-*"}
+	tests := []struct {
+		name   string
+		modify func(p *BulkDeleteParams)
+		want   string
+	}{
+		{"no filter", func(p *BulkDeleteParams) { p.NamePattern = "" }, "all=true"},
+		{"everything everywhere", func(p *BulkDeleteParams) { p.NamePattern, p.All, p.AllNamespaces = "", true, true }, "not allowed"},
+		{"protected kind", func(p *BulkDeleteParams) { p.Kind = "namespaces" }, "cannot be deleted in bulk"},
+		{"bad pattern", func(p *BulkDeleteParams) { p.NamePattern = "test-[" }, "invalid name_pattern"},
+		{"bad selector", func(p *BulkDeleteParams) { p.Selector = "app==(" }, "invalid selector"},
+		{"batch size", func(p *BulkDeleteParams) { p.BatchSize = 500 }, "batch_size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := base
+			tt.modify(&p)
+			if err := validateBulkDeleteParams(&p); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	p := base
+	p.NamePattern, p.All = "", true
+	if err := validateBulkDeleteParams(&p); err != nil || p.BatchSize != defaultBulkBatchSize {
+		t.Errorf("all=true in a namespace: err = %v, batch size %d", err, p.BatchSize)
+	}
+}
+
+func TestHandleBulkDeleteAsksForCount(t *testing.T) {
+	calls := stubBulkKubectl(t, "")
+	state := approvingState()
+	out, err := handleBulkDelete(context.Background(), newTestK8sProvider(t), state, BulkDeleteParams{
+		Context: "test-context", Kind: "deployments", AllNamespaces: true, Selector: "app", NamePattern: "w*",
+	})
+	if err != nil {
+		t.Fatalf("handleBulkDelete: %v", err)
+	}
+	result := out.(BulkDeleteResult)
+	if !result.NeedConfirm || result.Matched != 2 || strings.Join(result.Objects, ",") != "batch/worker,shop/web" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if len(*calls) != 0 || state.approver.(*stubApprover).called {
+		t.Errorf("nothing may be deleted or approved before the count is confirmed")
+	}
+
+	// A stale count (the list changed since the user saw it) is asked again.
+	out, _ = handleBulkDelete(context.Background(), newTestK8sProvider(t), state, BulkDeleteParams{
+		Context: "test-context", Kind: "deployments", AllNamespaces: true, Selector: "app", ConfirmCount: 2,
+	})
+	if result := out.(BulkDeleteResult); !result.NeedConfirm || result.Matched != 3 || len(*calls) != 0 {
+		t.Errorf("stale count: %+v, %d call(s)", result, len(*calls))
+	}
+}
+
+func TestHandleBulkDeleteApply(t *testing.T) {
+	calls := stubBulkKubectl(t, "")
+	state := approvingState()
+	out, err := handleBulkDelete(context.Background(), newTestK8sProvider(t), state, BulkDeleteParams{
+		Context: "test-context", Kind: "deployments", AllNamespaces: true, Selector: "app", ConfirmCount: 3, BatchSize: 1,
+	})
+	if err != nil {
+		t.Fatalf("handleBulkDelete: %v", err)
+	}
+	result := out.(BulkDeleteResult)
+	if result.Deleted != 3 || result.Batches != 3 || result.Error != "" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	req := state.approver.(*stubApprover).req
+	if req.Tool != toolBulkDelete || req.ConfirmCount != 3 || len(req.Objects) != 3 {
+		t.Errorf("approval request = %+v, want the 3 objects and their count", req)
+	}
+	var cmds []string
+	for _, c := range *calls {
+		cmds = append(cmds, strings.Join(c, " "))
+	}
+	want := []string{
+		"--context test-context delete deployments worker -n batch --ignore-not-found",
+		"--context test-context delete deployments api -n shop --ignore-not-found",
+		"--context test-context delete deployments web -n shop --ignore-not-found",
+	}
+	if strings.Join(cmds, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands:\n%s\nwant:\n%s", strings.Join(cmds, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestHandleBulkDeleteDenied(t *testing.T) {
+	calls := stubBulkKubectl(t, "")
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON, approver: &stubApprover{name: "test"}}
+	out, err := handleBulkDelete(context.Background(), newTestK8sProvider(t), state, BulkDeleteParams{
+		Context: "test-context", Kind: "deployments", Namespace: "shop", All: true, ConfirmCount: 3,
+	})
+	if err != nil {
+		t.Fatalf("handleBulkDelete: %v", err)
+	}
+	if _, ok := out.(BulkDeleteResult); ok || len(*calls) != 0 {
+		t.Errorf("denied deletion ran: %v, %d call(s)", out, len(*calls))
+	}
+}
+
+func TestFormatBulkDeleteResult(t *testing.T) {
+	text := formatBulkDeleteResult(BulkDeleteResult{
+		Context: "prod", Kind: "jobs", Filter: `name "test-*"`, Matched: 2, Objects: []string{"ci/test-1", "ci/test-2"}, NeedConfirm: true,
+	})
+	for _, want := range []string{`Bulk Delete: jobs, name "test-*" (prod)`, "MATCHED: 2 object(s)", "  - ci/test-2\n", "confirm_count=2"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+}
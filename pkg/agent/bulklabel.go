@@ -0,0 +1,486 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the bulk_label tool: batched label/annotation changes with rollback.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	defaultBulkBatchSize = 20
+	maxBulkBatchSize     = 100
+	maxBulkObjects       = 500
+	bulkMaxRetries       = 3
+	bulkPreviewLimit     = 10
+)
+
+// bulkBatchPause is the pause between batches (and the base retry backoff when
+// the API server throttles); a variable so tests can shorten it.
+var bulkBatchPause = 500 * time.Millisecond
+
+// rollbackDir returns the directory holding generated rollback scripts
+// (~/.kopilot/rollback). It is a variable so tests can redirect it.
+var rollbackDir = func() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kopilot", "rollback"), nil
+}
+
+// throttledOutput matches kubectl errors caused by API server rate limiting.
+var throttledOutput = regexp.MustCompile(`(?i)TooManyRequests|429|rate limit|throttl`)
+
+// BulkLabelParams defines parameters for bulk_label
+type BulkLabelParams struct {
+	Context           string            `json:"context" jsonschema:"The cluster context name (required)"`
+	Kind              string            `json:"kind" jsonschema:"Resource kind, e.g. deployments, pods, namespaces, configmaps"`
+	Selector          string            `json:"selector" jsonschema:"Label selector choosing the objects to change (required), e.g. 'app=web,tier!=db'"`
+	Namespace         string            `json:"namespace,omitempty" jsonschema:"Namespace to search; omit for the context default or cluster-scoped kinds"`
+	AllNamespaces     bool              `json:"all_namespaces,omitempty" jsonschema:"Search all namespaces"`
+	Labels            map[string]string `json:"labels,omitempty" jsonschema:"Labels to add or overwrite"`
+	Annotations       map[string]string `json:"annotations,omitempty" jsonschema:"Annotations to add or overwrite"`
+	RemoveLabels      []string          `json:"remove_labels,omitempty" jsonschema:"Label keys to remove"`
+	RemoveAnnotations []string          `json:"remove_annotations,omitempty" jsonschema:"Annotation keys to remove"`
+	DryRun            bool              `json:"dry_run,omitempty" jsonschema:"If true, only preview which objects would change"`
+	BatchSize         int               `json:"batch_size,omitempty" jsonschema:"Objects per kubectl call (default 20, max 100)"`
+}
+
+// BulkLabelChange is the planned metadata change for one object.
+type BulkLabelChange struct {
+	Object  string   `json:"object"` // namespace/name or name
+	Changes []string `json:"changes"`
+}
+
+// BulkLabelResult defines JSON output for bulk_label
+type BulkLabelResult struct {
+	Cluster      string            `json:"cluster"`
+	Context      string            `json:"context"`
+	Kind         string            `json:"kind"`
+	Selector     string            `json:"selector"`
+	DryRun       bool              `json:"dry_run"`
+	Matched      int               `json:"matched"`
+	ToChange     int               `json:"to_change"`
+	Preview      []BulkLabelChange `json:"preview"`
+	Batches      int               `json:"batches,omitempty"`
+	Updated      int               `json:"updated"`
+	Failed       []string          `json:"failed,omitempty"`
+	Error        string            `json:"error,omitempty"`
+	RollbackFile string            `json:"rollback_file,omitempty"`
+}
+
+// bulkObject is one object returned by the selector query.
+type bulkObject struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Namespace   string            `json:"namespace"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+}
+
+// id returns namespace/name, or name for cluster-scoped objects.
+func (o bulkObject) id() string {
+	if o.Metadata.Namespace == "" {
+		return o.Metadata.Name
+	}
+	return o.Metadata.Namespace + "/" + o.Metadata.Name
+}
+
+// metadataEdit is the set of keys to write and remove in one metadata map.
+type metadataEdit struct {
+	set    map[string]string
+	remove []string
+}
+
+// diff describes how e changes current, or nil when it is a no-op.
+func (e metadataEdit) diff(what string, current map[string]string) []string {
+	var changes []string
+	for _, k := range sortedKeys(e.set) {
+		old, ok := current[k]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("+%s %s=%s", what, k, e.set[k]))
+		case old != e.set[k]:
+			changes = append(changes, fmt.Sprintf("~%s %s=%s (was %s)", what, k, e.set[k], old))
+		}
+	}
+	for _, k := range e.remove {
+		if old, ok := current[k]; ok {
+			changes = append(changes, fmt.Sprintf("-%s %s (was %s)", what, k, old))
+		}
+	}
+	return changes
+}
+
+// args returns the kubectl key=value / key- arguments applying e.
+func (e metadataEdit) args() []string {
+	var args []string
+	for _, k := range sortedKeys(e.set) {
+		args = append(args, k+"="+e.set[k])
+	}
+	for _, k := range e.remove {
+		args = append(args, k+"-")
+	}
+	return args
+}
+
+// revertArgs returns the kubectl arguments restoring current after e.
+func (e metadataEdit) revertArgs(current map[string]string) []string {
+	var args []string
+	for _, k := range sortedKeys(e.set) {
+		if old, ok := current[k]; ok {
+			if old != e.set[k] {
+				args = append(args, k+"="+old)
+			}
+		} else {
+			args = append(args, k+"-")
+		}
+	}
+	for _, k := range e.remove {
+		if old, ok := current[k]; ok {
+			args = append(args, k+"="+old)
+		}
+	}
+	return args
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func defineBulkLabelTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolBulkLabel,
+		"Add, change or remove labels and annotations on every object of a kind matching a label selector. Previews the changes (dry_run), writes a rollback script before touching anything, then applies the change in confirmed, rate-limited batches with progress. Use instead of kubectl label/annotate with --all or wildcards, which are blocked.",
+		func(params BulkLabelParams, inv llm.ToolInvocation) (any, error) {
+			return handleBulkLabel(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+// validateBulkLabelParams checks names, the selector, and every key and label value.
+func validateBulkLabelParams(params *BulkLabelParams) error {
+	if params.Context == "" || params.Kind == "" {
+		return fmt.Errorf("context and kind are required")
+	}
+	if strings.TrimSpace(params.Selector) == "" {
+		return fmt.Errorf("selector is required: bulk changes to every object of a kind are not allowed")
+	}
+	if _, err := labels.Parse(params.Selector); err != nil {
+		return fmt.Errorf("invalid selector: %w", err)
+	}
+	if !isValidKubernetesName(params.Kind) {
+		return fmt.Errorf("invalid kind: %s", params.Kind)
+	}
+	if params.Namespace != "" && !isValidKubernetesName(params.Namespace) {
+		return fmt.Errorf("invalid namespace name: %s", params.Namespace)
+	}
+	if len(params.Labels)+len(params.Annotations)+len(params.RemoveLabels)+len(params.RemoveAnnotations) == 0 {
+		return fmt.Errorf("nothing to change: give labels, annotations, remove_labels or remove_annotations")
+	}
+	for k, v := range params.Labels {
+		if errs := append(validation.IsQualifiedName(k), validation.IsValidLabelValue(v)...); len(errs) > 0 {
+			return fmt.Errorf("invalid label %s=%s: %s", k, v, strings.Join(errs, "; "))
+		}
+	}
+	for _, keys := range [][]string{sortedKeys(params.Annotations), params.RemoveLabels, params.RemoveAnnotations} {
+		for _, k := range keys {
+			if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+				return fmt.Errorf("invalid key %s: %s", k, strings.Join(errs, "; "))
+			}
+		}
+	}
+	if params.BatchSize == 0 {
+		params.BatchSize = defaultBulkBatchSize
+	}
+	if params.BatchSize < 1 || params.BatchSize > maxBulkBatchSize {
+		return fmt.Errorf("batch_size must be between 1 and %d", maxBulkBatchSize)
+	}
+	return nil
+}
+
+func handleBulkLabel(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params BulkLabelParams) (any, error) {
+	if err := validateBulkLabelParams(&params); err != nil {
+		return nil, err
+	}
+	cluster, err := getClusterForContext(k8sProvider, params.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := listBulkObjects(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) > maxBulkObjects {
+		return nil, fmt.Errorf("selector %q matches %d objects (limit %d); narrow the selector or namespace", params.Selector, len(objects), maxBulkObjects)
+	}
+
+	labelEdit := metadataEdit{set: params.Labels, remove: params.RemoveLabels}
+	annotationEdit := metadataEdit{set: params.Annotations, remove: params.RemoveAnnotations}
+	result := BulkLabelResult{
+		Cluster: cluster.Name, Context: params.Context, Kind: params.Kind,
+		Selector: params.Selector, DryRun: params.DryRun, Matched: len(objects),
+	}
+	var changed []bulkObject
+	for _, obj := range objects {
+		changes := append(labelEdit.diff("label", obj.Metadata.Labels), annotationEdit.diff("annotation", obj.Metadata.Annotations)...)
+		if len(changes) == 0 {
+			continue
+		}
+		changed = append(changed, obj)
+		result.Preview = append(result.Preview, BulkLabelChange{Object: obj.id(), Changes: changes})
+	}
+	result.ToChange = len(changed)
+
+	if params.DryRun || len(changed) == 0 {
+		return bulkLabelResult(state, result), nil
+	}
+
+	prompt := fmt.Sprintf("kubectl --context %s label/annotate %d %s matching %q  # %s",
+		params.Context, len(changed), params.Kind, params.Selector, strings.Join(append(labelEdit.args(), annotationEdit.args()...), " "))
+	proceed, cancelResult, err := enforceToolExecutionMode(ctx, state, toolBulkLabel, false, cluster.Name, params.Context, prompt)
+	if err != nil {
+		return nil, err
+	}
+	if !proceed {
+		return cancelResult, nil
+	}
+
+	// The rollback script is written before anything changes.
+	rollbackFile, err := writeBulkRollback(params, labelEdit, annotationEdit, changed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write rollback script: %w", err)
+	}
+	result.RollbackFile = rollbackFile
+
+	applyBulkBatches(ctx, state, params, labelEdit, annotationEdit, changed, &result)
+	state.publishWrite(ApprovalRequest{Tool: toolBulkLabel, Cluster: cluster.Name, Context: params.Context, Command: prompt}, errorOf(result.Error))
+	return bulkLabelResult(state, result), nil
+}
+
+// listBulkObjects returns the objects matching the selector, sorted by namespace and name.
+func listBulkObjects(ctx context.Context, params BulkLabelParams) ([]bulkObject, error) {
+	args := []string{"--context", params.Context, "get", params.Kind, "-l", params.Selector, "-o", "json"}
+	if params.AllNamespaces {
+		args = append(args, "-A")
+	} else if params.Namespace != "" {
+		args = append(args, "-n", params.Namespace)
+	}
+	out, err := runKubectlCommandFunc(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w: %s", params.Kind, err, strings.TrimSpace(string(out)))
+	}
+	var list struct {
+		Items []bulkObject `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse %s list: %w", params.Kind, err)
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].id() < list.Items[j].id() })
+	return list.Items, nil
+}
+
+// bulkBatches groups objects by namespace (one kubectl call cannot span
+// namespaces) and splits each group into batches of at most size.
+func bulkBatches(objects []bulkObject, size int) [][]bulkObject {
+	var batches [][]bulkObject
+	for i := 0; i < len(objects); {
+		j := i
+		for j < len(objects) && objects[j].Metadata.Namespace == objects[i].Metadata.Namespace && j-i < size {
+			j++
+		}
+		batches = append(batches, objects[i:j])
+		i = j
+	}
+	return batches
+}
+
+// kubectlMetadataArgs returns the kubectl arguments for verb (label or annotate)
+// on objects, or nil when edit is empty.
+func kubectlMetadataArgs(verb, kind string, objects []bulkObject, edit []string) []string {
+	if len(edit) == 0 {
+		return nil
+	}
+	args := []string{verb, kind}
+	for _, obj := range objects {
+		args = append(args, obj.Metadata.Name)
+	}
+	if ns := objects[0].Metadata.Namespace; ns != "" {
+		args = append(args, "-n", ns)
+	}
+	args = append(args, "--overwrite")
+	return append(args, edit...)
+}
+
+// runThrottled runs a kubectl command, retrying with exponential backoff
+// while the API server reports rate limiting.
+func runThrottled(ctx context.Context, cmdArgs []string) ([]byte, error) {
+	var output []byte
+	var err error
+	for attempt := 0; attempt <= bulkMaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(bulkBatchPause << attempt)
+		}
+		output, err = runKubectlCommandFunc(ctx, cmdArgs)
+		if err == nil || !throttledOutput.Match(output) {
+			return output, err
+		}
+	}
+	return output, err
+}
+
+// applyBulkBatches applies both edits batch by batch, stopping at the first
+// failure; objects not updated are reported in result.Failed.
+func applyBulkBatches(ctx context.Context, state *agentState, params BulkLabelParams, labelEdit, annotationEdit metadataEdit, objects []bulkObject, result *BulkLabelResult) {
+	batches := bulkBatches(objects, params.BatchSize)
+	result.Batches = len(batches)
+	for i, batch := range batches {
+		if i > 0 {
+			time.Sleep(bulkBatchPause)
+		}
+		if !isJSONOutput(state.outputFormat) {
+			fmt.Fprintf(state.stdout(), "\r\033[K%s   batch %d/%d: %d object(s), %d/%d done%s\n", colorDim, i+1, len(batches), len(batch), result.Updated, len(objects), colorReset)
+		}
+		for _, args := range [][]string{
+			kubectlMetadataArgs("label", params.Kind, batch, labelEdit.args()),
+			kubectlMetadataArgs("annotate", params.Kind, batch, annotationEdit.args()),
+		} {
+			if args == nil {
+				continue
+			}
+			fullCommand, cmdArgs := buildKubectlCommand(params.Context, args)
+			printExecutionHeader(state, false, fullCommand)
+			if output, err := runThrottled(ctx, cmdArgs); err != nil {
+				result.Error = fmt.Sprintf("batch %d/%d failed: %v: %s", i+1, len(batches), err, strings.TrimSpace(string(output)))
+				for _, obj := range objects[result.Updated:] {
+					result.Failed = append(result.Failed, obj.id())
+				}
+				return
+			}
+		}
+		result.Updated += len(batch)
+	}
+}
+
+// shellQuote quotes s for a POSIX shell when it contains anything but safe characters.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// bulkRollbackScript returns a shell script restoring the original labels and
+// annotations of objects, one kubectl call per object and verb.
+func bulkRollbackScript(params BulkLabelParams, labelEdit, annotationEdit metadataEdit, objects []bulkObject) string {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&sb, "# Rollback for bulk_label on %s matching %q (context %s), generated %s\n",
+		params.Kind, params.Selector, params.Context, time.Now().Format(time.RFC3339))
+	for _, obj := range objects {
+		for _, verb := range []struct {
+			name    string
+			edit    metadataEdit
+			current map[string]string
+		}{
+			{"label", labelEdit, obj.Metadata.Labels},
+			{"annotate", annotationEdit, obj.Metadata.Annotations},
+		} {
+			args := kubectlMetadataArgs(verb.name, params.Kind, []bulkObject{obj}, verb.edit.revertArgs(verb.current))
+			if args == nil {
+				continue
+			}
+			quoted := make([]string, len(args))
+			for i, a := range args {
+				quoted[i] = shellQuote(a)
+			}
+			fmt.Fprintf(&sb, "kubectl --context %s %s\n", shellQuote(params.Context), strings.Join(quoted, " "))
+		}
+	}
+	return sb.String()
+}
+
+// writeBulkRollback saves the rollback script and returns its path.
+func writeBulkRollback(params BulkLabelParams, labelEdit, annotationEdit metadataEdit, objects []bulkObject) (string, error) {
+	dir, err := rollbackDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("bulk-label-%s-%s.sh", unsafeFileChars.ReplaceAllString(params.Context, "_"), time.Now().Format("20060102-150405"))
+	path := filepath.Join(dir, name)
+	// #nosec G306 -- the script is meant to be run by the user who owns it
+	if err := os.WriteFile(path, []byte(bulkRollbackScript(params, labelEdit, annotationEdit, objects)), 0700); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// bulkLabelResult returns result as JSON or formatted text.
+func bulkLabelResult(state *agentState, result BulkLabelResult) any {
+	if isJSONOutput(state.outputFormat) {
+		return result
+	}
+	return formatBulkLabelResult(result)
+}
+
+// formatBulkLabelResult formats a BulkLabelResult as human-readable text
+func formatBulkLabelResult(r BulkLabelResult) string {
+	var sb strings.Builder
+	title := "Bulk Label"
+	if r.DryRun {
+		title += " (dry run)"
+	}
+	fmt.Fprintf(&sb, "%s: %s matching %q (%s)\n", title, r.Kind, r.Selector, r.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+	fmt.Fprintf(&sb, "📋 MATCHED: %d object(s), %d need changes\n", r.Matched, r.ToChange)
+
+	if len(r.Preview) > 0 {
+		sb.WriteString("\n🔍 CHANGES:\n")
+		for i, c := range r.Preview {
+			if i == bulkPreviewLimit {
+				fmt.Fprintf(&sb, "  ... and %d more object(s)\n", len(r.Preview)-bulkPreviewLimit)
+				break
+			}
+			fmt.Fprintf(&sb, "  %s: %s\n", c.Object, strings.Join(c.Changes, ", "))
+		}
+	}
+
+	switch {
+	case r.ToChange == 0:
+		sb.WriteString("\n✅ Nothing to do: every matching object already has the requested metadata\n")
+	case r.DryRun:
+		sb.WriteString("\nℹ️  Dry run: nothing was changed. Run again without dry_run to apply.\n")
+	case r.Error != "":
+		fmt.Fprintf(&sb, "\n❌ FAILED after %d/%d object(s): %s\n", r.Updated, r.ToChange, r.Error)
+		fmt.Fprintf(&sb, "   Not updated: %s\n", strings.Join(r.Failed, ", "))
+	default:
+		fmt.Fprintf(&sb, "\n✅ UPDATED: %d object(s) in %d batch(es)\n", r.Updated, r.Batches)
+	}
+	if r.RollbackFile != "" {
+		fmt.Fprintf(&sb, "↩️  ROLLBACK: sh %s\n", r.RollbackFile)
+	}
+	return sb.String()
+}
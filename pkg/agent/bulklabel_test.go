@@ -0,0 +1,190 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+const bulkListJSON = `{"items":[
+ {"metadata":{"name":"web","namespace":"shop","labels":{"app":"web","team":"old"}}},
+ {"metadata":{"name":"api","namespace":"shop","labels":{"app":"api","team":"payments"}}},
+ {"metadata":{"name":"worker","namespace":"batch","labels":{"app":"worker"},"annotations":{"note":"it's here"}}}
+]}`
+
+// stubBulkKubectl answers the list query with bulkListJSON and records every
+// other kubectl call; fail makes write calls return an error with that output.
+func stubBulkKubectl(t *testing.T, fail string) *[][]string {
+	t.Helper()
+	original, pause, dir := runKubectlCommandFunc, bulkBatchPause, rollbackDir
+	t.Cleanup(func() { runKubectlCommandFunc, bulkBatchPause, rollbackDir = original, pause, dir })
+	tmp := t.TempDir()
+	rollbackDir = func() (string, error) { return tmp, nil }
+	bulkBatchPause = 0
+
+	var calls [][]string
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
+		if len(args) > 2 && args[2] == "get" {
+			return []byte(bulkListJSON), nil
+		}
+		calls = append(calls, args)
+		if fail != "" {
+			return []byte(fail), errors.New("exit status 1")
+		}
+		return []byte("labeled"), nil
+	}
+	return &calls
+}
+
+func approvingState() *agentState {
+	return &agentState{mode: ModeInteractive, outputFormat: OutputJSON, approver: &stubApprover{name: "test", decision: ApprovalDecision{Approved: true}}}
+}
+
+func TestValidateBulkLabelParams(t *testing.T) {
+	base := BulkLabelParams{Context: "c", Kind: "deployments", Selector: "app=web", Labels: map[string]string{"team": "payments"}}
+	tests := []struct {
+		name   string
+		modify func(p *BulkLabelParams)
+		want   string
+	}{
+		{"no selector", func(p *BulkLabelParams) { p.Selector = " " }, "selector is required"},
+		{"bad selector", func(p *BulkLabelParams) { p.Selector = "app==(" }, "invalid selector"},
+		{"nothing to do", func(p *BulkLabelParams) { p.Labels = nil }, "nothing to change"},
+		{"bad label value", func(p *BulkLabelParams) { p.Labels = map[string]string{"team": "a b"} }, "invalid label"},
+		{"bad key", func(p *BulkLabelParams) { p.RemoveAnnotations = []string{"bad key"} }, "invalid key"},
+		{"batch size", func(p *BulkLabelParams) { p.BatchSize = 500 }, "batch_size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := base
+			tt.modify(&p)
+			if err := validateBulkLabelParams(&p); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	p := base
+	if err := validateBulkLabelParams(&p); err != nil || p.BatchSize != defaultBulkBatchSize {
+		t.Errorf("valid params: err = %v, batch size %d", err, p.BatchSize)
+	}
+}
+
+func TestHandleBulkLabelDryRun(t *testing.T) {
+	calls := stubBulkKubectl(t, "")
+	out, err := handleBulkLabel(context.Background(), newTestK8sProvider(t), approvingState(), BulkLabelParams{
+		Context: "test-context", Kind: "deployments", Selector: "app", AllNamespaces: true,
+		Labels: map[string]string{"team": "payments"}, DryRun: true,
+	})
+	if err != nil {
+		t.Fatalf("handleBulkLabel: %v", err)
+	}
+	result := out.(BulkLabelResult)
+	if result.Matched != 3 || result.ToChange != 2 || len(*calls) != 0 || result.RollbackFile != "" {
+		t.Errorf("dry run = %+v, %d write call(s)", result, len(*calls))
+	}
+	if result.Preview[0].Object != "batch/worker" || result.Preview[1].Changes[0] != "~label team=payments (was old)" {
+		t.Errorf("unexpected preview %+v", result.Preview)
+	}
+}
+
+func TestHandleBulkLabelApply(t *testing.T) {
+	calls := stubBulkKubectl(t, "")
+	out, err := handleBulkLabel(context.Background(), newTestK8sProvider(t), approvingState(), BulkLabelParams{
+		Context: "test-context", Kind: "deployments", Selector: "app", AllNamespaces: true,
+		Labels: map[string]string{"team": "payments"}, RemoveAnnotations: []string{"note"}, BatchSize: 1,
+	})
+	if err != nil {
+		t.Fatalf("handleBulkLabel: %v", err)
+	}
+	result := out.(BulkLabelResult)
+	if result.Updated != 2 || result.Batches != 2 || result.Error != "" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	var cmds []string
+	for _, c := range *calls {
+		cmds = append(cmds, strings.Join(c, " "))
+	}
+	want := []string{
+		"--context test-context label deployments worker -n batch --overwrite team=payments",
+		"--context test-context annotate deployments worker -n batch --overwrite note-",
+		"--context test-context label deployments web -n shop --overwrite team=payments",
+		"--context test-context annotate deployments web -n shop --overwrite note-",
+	}
+	if strings.Join(cmds, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands:\n%s\nwant:\n%s", strings.Join(cmds, "\n"), strings.Join(want, "\n"))
+	}
+
+	script, err := os.ReadFile(result.RollbackFile)
+	if err != nil {
+		t.Fatalf("rollback file: %v", err)
+	}
+	for _, line := range []string{
+		"kubectl --context test-context label deployments worker -n batch --overwrite team-",
+		`kubectl --context test-context annotate deployments worker -n batch --overwrite 'note=it'\''s here'`,
+		"kubectl --context test-context label deployments web -n shop --overwrite team=old",
+	} {
+		if !strings.Contains(string(script), line+"\n") {
+			t.Errorf("rollback script missing %q:\n%s", line, script)
+		}
+	}
+}
+
+func TestHandleBulkLabelStopsOnFailure(t *testing.T) {
+	calls := stubBulkKubectl(t, "Error from server (Forbidden)")
+	out, err := handleBulkLabel(context.Background(), newTestK8sProvider(t), approvingState(), BulkLabelParams{
+		Context: "test-context", Kind: "deployments", Selector: "app", AllNamespaces: true,
+		Labels: map[string]string{"team": "payments"}, BatchSize: 1,
+	})
+	if err != nil {
+		t.Fatalf("handleBulkLabel: %v", err)
+	}
+	result := out.(BulkLabelResult)
+	if len(*calls) != 1 || result.Updated != 0 || len(result.Failed) != 2 || !strings.Contains(result.Error, "Forbidden") {
+		t.Errorf("unexpected result %+v after %d call(s)", result, len(*calls))
+	}
+}
+
+func TestRunThrottledRetries(t *testing.T) {
+	original, pause := runKubectlCommandFunc, bulkBatchPause
+	t.Cleanup(func() { runKubectlCommandFunc, bulkBatchPause = original, pause })
+	bulkBatchPause = 0
+	attempts := 0
+	runKubectlCommandFunc = func(context.Context, []string) ([]byte, error) {
+		attempts++
+		if attempts < 3 {
+			return []byte("Error from server (TooManyRequests): the server has received too many requests"), errors.New("exit status 1")
+		}
+		return []byte("ok"), nil
+	}
+	if _, err := runThrottled(context.Background(), nil); err != nil || attempts != 3 {
+		t.Errorf("runThrottled(context.Background()) err = %v after %d attempt(s), want success on the 3rd", err, attempts)
+	}
+}
+
+func TestBulkBatches(t *testing.T) {
+	var objs []bulkObject
+	for _, ns := range []string{"a", "a", "a", "b"} {
+		var o bulkObject
+		o.Metadata.Namespace = ns
+		objs = append(objs, o)
+	}
+	batches := bulkBatches(objs, 2)
+	if len(batches) != 3 || len(batches[0]) != 2 || len(batches[1]) != 1 || batches[2][0].Metadata.Namespace != "b" {
+		t.Errorf("bulkBatches() = %v", batches)
+	}
+}
+
+func TestFormatBulkLabelResult(t *testing.T) {
+	text := formatBulkLabelResult(BulkLabelResult{
+		Context: "prod", Kind: "deployments", Selector: "app", Matched: 3, ToChange: 2, Updated: 2, Batches: 1,
+		Preview:      []BulkLabelChange{{Object: "shop/web", Changes: []string{"+label team=payments"}}},
+		RollbackFile: "/tmp/rb.sh",
+	})
+	for _, want := range []string{`Bulk Label: deployments matching "app" (prod)`, "MATCHED: 3 object(s), 2 need changes", "shop/web: +label team=payments", "UPDATED: 2 object(s) in 1 batch(es)", "ROLLBACK: sh /tmp/rb.sh"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the event bus that decouples cross-cutting subsystems
+// (debug bundles, tracing, notices, embedders) from the code that runs tools,
+// checks clusters and performs writes.
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/debug"
+	"github.com/e9169/kopilot/pkg/report"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// quotaLowPercentage is the default remaining premium quota, in percent, at
+// or below which EventQuotaLow is published; see QuotaBudget.WarnBelow.
+const quotaLowPercentage = 20
+
+// EventKind identifies what an Event reports.
+type EventKind string
+
+// Event kinds published on the event bus.
+const (
+	// EventToolExecuted follows every tool call, successful or not.
+	EventToolExecuted EventKind = "tool_executed"
+	// EventIssueDetected reports a health issue that the previous cluster
+	// check did not report.
+	EventIssueDetected EventKind = "issue_detected"
+	// EventIssueResolved reports a health issue of the previous cluster check
+	// that is gone.
+	EventIssueResolved EventKind = "issue_resolved"
+	// EventWritePerformed follows an approved write to a cluster, successful
+	// or not.
+	EventWritePerformed EventKind = "write_performed"
+	// EventQuotaLow reports that the remaining premium quota dropped to the
+	// warning level of the quota budget (default quotaLowPercentage) or
+	// below; it is published again only after the quota recovered.
+	EventQuotaLow EventKind = "quota_low"
+)
+
+// Event is something that happened in a session. Only the fields relevant
+// to its Kind are set.
+type Event struct {
+	Kind EventKind `json:"kind"`
+	Time time.Time `json:"time"`
+	// Tool is the tool that ran or performed the write.
+	Tool string `json:"tool,omitempty"`
+	// Cluster and Context identify the cluster of an issue or write.
+	Cluster string `json:"cluster,omitempty"`
+	Context string `json:"context,omitempty"`
+	// Severity is the severity of a detected or resolved issue.
+	Severity report.Severity `json:"severity,omitempty"`
+	// Message is the issue text, the write command or the quota notice.
+	Message string `json:"message,omitempty"`
+	// Duration is how long a tool call took.
+	Duration time.Duration `json:"duration,omitempty"`
+	// Error is the failure of a tool call or write, if any.
+	Error string `json:"error,omitempty"`
+	// QuotaRemaining is the remaining premium quota in percent, for EventQuotaLow.
+	QuotaRemaining float64 `json:"quota_remaining,omitempty"`
+
+	// call is the recorded tool call of EventToolExecuted, for /debug.
+	call *toolCall
+}
+
+// eventBus delivers events synchronously, in publish order, to the
+// subscribers of their kind. The zero value is ready to use.
+type eventBus struct {
+	mu   sync.RWMutex
+	next int
+	subs map[int]subscription
+}
+
+// subscription is one subscriber and the kinds it receives; no kinds means all.
+type subscription struct {
+	kinds []EventKind
+	fn    func(Event)
+}
+
+// wants reports whether the subscription receives events of kind.
+func (s subscription) wants(kind EventKind) bool {
+	if len(s.kinds) == 0 {
+		return true
+	}
+	for _, k := range s.kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// subscribe registers fn for events of kinds, or of every kind when none are
+// given, and returns a function that removes the subscription.
+func (b *eventBus) subscribe(fn func(Event), kinds ...EventKind) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs == nil {
+		b.subs = make(map[int]subscription)
+	}
+	id := b.next
+	b.next++
+	b.subs[id] = subscription{kinds: kinds, fn: fn}
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+	}
+}
+
+// publish delivers e to its subscribers in subscription order. A subscriber
+// that panics is logged and does not stop delivery to the others.
+func (b *eventBus) publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.mu.RLock()
+	ids := make([]int, 0, len(b.subs))
+	for id, s := range b.subs {
+		if s.wants(e.Kind) {
+			ids = append(ids, id)
+		}
+	}
+	subs := make([]func(Event), 0, len(ids))
+	sort.Ints(ids)
+	for _, id := range ids {
+		subs = append(subs, b.subs[id].fn)
+	}
+	b.mu.RUnlock()
+
+	for _, fn := range subs {
+		deliver(fn, e)
+	}
+}
+
+// deliver calls fn with e, recovering a panic so one broken subscriber
+// cannot take down the tool call that published the event.
+func deliver(fn func(Event), e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			debug.Logf(debug.Tools, "event subscriber panicked on %s: %v", e.Kind, r)
+		}
+	}()
+	fn(e)
+}
+
+// subscribeSubsystems connects the built-in subsystems to the event bus:
+// the /debug tool call record, the prompt trace span and --debug=tools.
+func (s *agentState) subscribeSubsystems() {
+	s.bus.subscribe(func(e Event) {
+		if e.call != nil {
+			s.setLastToolCall(e.call)
+		}
+	}, EventToolExecuted)
+	s.bus.subscribe(s.traceEvent)
+	s.bus.subscribe(logEvent, EventIssueDetected, EventIssueResolved, EventWritePerformed, EventQuotaLow)
+}
+
+// traceEvent adds e to the in-flight prompt span, if any.
+func (s *agentState) traceEvent(e Event) {
+	s.traceMu.Lock()
+	span := s.promptSpan
+	s.traceMu.Unlock()
+	if span == nil {
+		return
+	}
+	var attrs []attribute.KeyValue
+	for _, kv := range [][2]string{{"kopilot.tool", e.Tool}, {"kopilot.context", e.Context}, {"kopilot.cluster", e.Cluster}, {"kopilot.severity", string(e.Severity)}, {"error.message", e.Error}} {
+		if kv[1] != "" {
+			attrs = append(attrs, attribute.String(kv[0], kv[1]))
+		}
+	}
+	span.AddEvent("kopilot."+string(e.Kind), trace.WithTimestamp(e.Time), trace.WithAttributes(attrs...))
+}
+
+// logEvent writes e to --debug=tools; tool calls are already logged by llm.DefineTool.
+func logEvent(e Event) {
+	subject := e.Cluster
+	if e.Tool != "" {
+		subject = e.Tool + " " + e.Context
+	}
+	msg := fmt.Sprintf("event %s: %s %s", e.Kind, subject, e.Message)
+	if e.Error != "" {
+		msg += ": " + e.Error
+	}
+	debug.Logf(debug.Tools, "%s", msg)
+}
+
+// publishWrite publishes EventWritePerformed for the approved write req,
+// with err its failure, if any. Writes of undoUnsupported tools are noted in
+// the undo log even when they failed, since bulk operations fail partially.
+func (s *agentState) publishWrite(req ApprovalRequest, err error) {
+	e := Event{Kind: EventWritePerformed, Tool: req.Tool, Cluster: req.Cluster, Context: req.Context, Message: req.Command}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if reason, ok := undoUnsupported[req.Tool]; ok {
+		s.undo.skip(req.Tool, req.Command, reason)
+	}
+	s.bus.publish(e)
+}
+
+// errorOf turns the error message of a tool result back into an error.
+func errorOf(msg string) error {
+	if msg == "" {
+		return nil
+	}
+	return errors.New(msg)
+}
+
+// digits is replaced in issue messages so an issue whose counts change,
+// e.g. "3/10 pods unhealthy" becoming "4/10", is still the same issue.
+var digits = regexp.MustCompile(`[0-9]+`)
+
+// issueKey identifies an issue across cluster checks.
+func issueKey(issue report.Issue) string {
+	return issue.Cluster + "\x00" + string(issue.Severity) + "\x00" + digits.ReplaceAllString(issue.Message, "#")
+}
+
+// recordClusterReport makes r the latest cluster check for /export, records
+// it in the health history and publishes the issues that appeared or went
+// away since the previous check of this session.
+func (s *agentState) recordClusterReport(r *ClusterReport) {
+	prev := s.setLastReport(r)
+	recordHealthHistory(r)
+
+	var before []report.Issue
+	if prev != nil {
+		before = prev.Issues
+	}
+	for _, e := range issueChanges(before, r.Issues) {
+		e.Time = r.GeneratedAt
+		s.bus.publish(e)
+	}
+}
+
+// issueChanges returns EventIssueDetected for the issues of after missing
+// from before, then EventIssueResolved for those of before missing from after.
+func issueChanges(before, after []report.Issue) []Event {
+	was := make(map[string]bool, len(before))
+	for _, issue := range before {
+		was[issueKey(issue)] = true
+	}
+	is := make(map[string]bool, len(after))
+	var events []Event
+	for _, issue := range after {
+		key := issueKey(issue)
+		is[key] = true
+		if !was[key] {
+			events = append(events, Event{Kind: EventIssueDetected, Cluster: issue.Cluster, Severity: issue.Severity, Message: issue.Message})
+		}
+	}
+	for _, issue := range before {
+		if !is[issueKey(issue)] {
+			events = append(events, Event{Kind: EventIssueResolved, Cluster: issue.Cluster, Severity: issue.Severity, Message: issue.Message})
+		}
+	}
+	return events
+}
+
+// updateQuota records the remaining premium quota and publishes
+// EventQuotaLow when it drops to the budget's warning level or below.
+func (s *agentState) updateQuota(percentage float64, unlimited bool) {
+	s.quotaPercentage = percentage
+	s.quotaUnlimited = unlimited
+	low := !unlimited && percentage >= 0 && percentage <= s.quota.warnBelow()
+	if low && !s.quotaLowPublished {
+		s.bus.publish(Event{Kind: EventQuotaLow, QuotaRemaining: percentage,
+			Message: fmt.Sprintf("%.0f%% of the premium quota remaining", percentage)})
+	}
+	s.quotaLowPublished = low
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/report"
+)
+
+func TestEventBus(t *testing.T) {
+	var bus eventBus
+	var got []string
+	bus.subscribe(func(e Event) { got = append(got, "all:"+string(e.Kind)) })
+	bus.subscribe(func(Event) { panic("broken subscriber") }, EventQuotaLow)
+	unsubscribe := bus.subscribe(func(e Event) { got = append(got, "writes:"+e.Message) }, EventWritePerformed)
+
+	bus.publish(Event{Kind: EventWritePerformed, Message: "kubectl delete pod web"})
+	bus.publish(Event{Kind: EventQuotaLow})
+	unsubscribe()
+	bus.publish(Event{Kind: EventWritePerformed, Message: "kubectl scale"})
+
+	want := "all:write_performed,writes:kubectl delete pod web,all:quota_low,all:write_performed"
+	if strings.Join(got, ",") != want {
+		t.Errorf("delivered %v, want %s", got, want)
+	}
+}
+
+func TestIssueChanges(t *testing.T) {
+	before := []report.Issue{
+		{Severity: report.SeverityWarning, Cluster: "prod", Message: "3/10 pods unhealthy"},
+		{Severity: report.SeverityCritical, Cluster: "dev", Message: "UNREACHABLE - timeout"},
+	}
+	after := []report.Issue{
+		{Severity: report.SeverityWarning, Cluster: "prod", Message: "4/10 pods unhealthy"},
+		{Severity: report.SeverityWarning, Cluster: "prod", Message: "1 exposed endpoint problem(s)"},
+	}
+	var got []string
+	for _, e := range issueChanges(before, after) {
+		got = append(got, string(e.Kind)+" "+e.Cluster+": "+e.Message)
+	}
+	want := "issue_detected prod: 1 exposed endpoint problem(s),issue_resolved dev: UNREACHABLE - timeout"
+	if strings.Join(got, ",") != want {
+		t.Errorf("changes = %v, want %s", got, want)
+	}
+}
+
+func TestRecordClusterReportPublishesIssueChanges(t *testing.T) {
+	t.Setenv("KOPILOT_HEALTH_HISTORY", "off")
+	state := &agentState{}
+	var events []Event
+	state.bus.subscribe(func(e Event) { events = append(events, e) })
+
+	issue := report.Issue{Severity: report.SeverityWarning, Cluster: "prod", Message: "2/3 nodes healthy"}
+	first := &ClusterReport{CheckAllClustersResult: CheckAllClustersResult{Issues: []report.Issue{issue}}}
+	state.recordClusterReport(first)
+	state.recordClusterReport(&ClusterReport{CheckAllClustersResult: CheckAllClustersResult{Issues: []report.Issue{issue}}})
+	state.recordClusterReport(&ClusterReport{})
+
+	if len(events) != 2 || events[0].Kind != EventIssueDetected || events[1].Kind != EventIssueResolved || events[1].Cluster != "prod" {
+		t.Errorf("events = %+v", events)
+	}
+	if state.getLastReport() == first {
+		t.Error("the latest report was not recorded")
+	}
+}
+
+func TestUpdateQuotaPublishesOnce(t *testing.T) {
+	state := &agentState{}
+	low := 0
+	state.bus.subscribe(func(Event) { low++ }, EventQuotaLow)
+
+	for _, pct := range []float64{50, 18, 12, 60, 5} {
+		state.updateQuota(pct, false)
+	}
+	state.updateQuota(1, true)
+	if low != 2 || state.quotaPercentage != 1 || !state.quotaUnlimited {
+		t.Errorf("quota low published %d time(s), state %.0f%% unlimited=%v", low, state.quotaPercentage, state.quotaUnlimited)
+	}
+}
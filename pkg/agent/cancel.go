@@ -0,0 +1,41 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file lets Ctrl+C cancel a running turn: its tool calls stop their
+// Kubernetes API calls and kubectl processes instead of running on.
+package agent
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+// notifyInterrupt and stopInterrupt are signal.Notify and signal.Stop for
+// os.Interrupt; tests replace them.
+var (
+	notifyInterrupt = func(c chan<- os.Signal) { signal.Notify(c, os.Interrupt) }
+	stopInterrupt   = signal.Stop
+)
+
+// cancelTurnOnInterrupt makes the first Ctrl+C while the model responds
+// cancel the turn's context rather than quit: tool calls in flight return
+// cancelled, and later ones fail at once, so the turn winds down. A second
+// Ctrl+C quits as before. The returned function stops listening.
+func cancelTurnOnInterrupt(state *agentState) func() {
+	sigs := make(chan os.Signal, 1)
+	notifyInterrupt(sigs)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-sigs:
+			stopInterrupt(sigs)
+			if state.endTurn() {
+				fmt.Printf("\r  %s●%s Cancelling the turn; press Ctrl+C again to quit\n", colorYellow, colorReset)
+			}
+		case <-done:
+		}
+	}()
+	return func() {
+		stopInterrupt(sigs)
+		close(done)
+	}
+}
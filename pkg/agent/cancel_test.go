@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+func TestBeginEndTurn(t *testing.T) {
+	state := &agentState{}
+	if state.endTurn() {
+		t.Error("endTurn() with no turn should report false")
+	}
+
+	parent, cancelParent := context.WithCancel(context.Background())
+	first := state.beginTurn(parent)
+	second := state.beginTurn(parent)
+	if first.Err() == nil {
+		t.Error("beginTurn should cancel the previous turn")
+	}
+	if !state.endTurn() || !errors.Is(second.Err(), context.Canceled) {
+		t.Errorf("endTurn() should cancel the running turn, err = %v", second.Err())
+	}
+
+	third := state.beginTurn(parent)
+	cancelParent()
+	if third.Err() == nil {
+		t.Error("ending the session should cancel the turn")
+	}
+	state.endTurn()
+}
+
+func TestCancelTurnOnInterrupt(t *testing.T) {
+	origNotify, origStop := notifyInterrupt, stopInterrupt
+	t.Cleanup(func() { notifyInterrupt, stopInterrupt = origNotify, origStop })
+	var sigs chan<- os.Signal
+	stopped := make(chan struct{}, 2)
+	notifyInterrupt = func(c chan<- os.Signal) { sigs = c }
+	stopInterrupt = func(chan<- os.Signal) { stopped <- struct{}{} }
+
+	state := &agentState{}
+	ctx := state.beginTurn(context.Background())
+	stop := cancelTurnOnInterrupt(state)
+	sigs <- os.Interrupt
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Ctrl+C should cancel the turn")
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("the first Ctrl+C should restore the default handling")
+	}
+	stop()
+}
+
+func TestToolCallsUseTurnContext(t *testing.T) {
+	originalRunner := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = originalRunner })
+	started := make(chan struct{})
+	runKubectlCommandFunc = func(ctx context.Context, _ []string) ([]byte, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	state := &agentState{mode: ModeReadOnly, outputFormat: OutputJSON}
+	tool := defineKubectlExecTool(newTestK8sProvider(t), state)
+	ctx := state.beginTurn(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := tool.Handler(KubectlExecParams{Context: "test-context", Args: []string{"get", "pods"}},
+			llm.ToolInvocation{Context: ctx})
+		done <- err
+	}()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("kubectl_exec did not run kubectl")
+	}
+	state.endTurn()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("kubectl_exec should return once the turn is cancelled")
+	}
+}
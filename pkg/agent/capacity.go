@@ -0,0 +1,89 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the cluster_capacity tool (CPU/memory headroom and overcommit).
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// ClusterCapacityParams defines parameters for cluster_capacity
+type ClusterCapacityParams struct {
+	Context string `json:"context" jsonschema:"The context name of the cluster to inspect (from list_clusters)"`
+}
+
+func defineClusterCapacityTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolClusterCapacity,
+		"Aggregate node allocatable CPU and memory against pod requests across a cluster: headroom on schedulable nodes, the largest pod that still fits on a single node, per-node allocation, and nodes overcommitted on requests or limits. Use for 'can I fit another replica', 'do we need more nodes' or pods stuck Pending on Insufficient cpu/memory.",
+		func(params ClusterCapacityParams, inv llm.ToolInvocation) (any, error) {
+			report, err := k8sProvider.GetClusterCapacity(inv.Ctx(), params.Context)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get cluster capacity: %w", err)
+			}
+
+			if isJSONOutput(state.outputFormat) {
+				return report, nil
+			}
+			return formatCapacityReport(report), nil
+		},
+	)
+}
+
+// formatPodSize renders a PodSize, or "none" when no node has room.
+func formatPodSize(size *k8s.PodSize) string {
+	if size == nil {
+		return "none (no schedulable node has room)"
+	}
+	return fmt.Sprintf("%s CPU / %s memory on %s", size.CPU.Human, size.Memory.Human, size.Node)
+}
+
+// formatCapacityReport formats a CapacityReport as human-readable text
+func formatCapacityReport(report *k8s.CapacityReport) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Cluster Capacity: %s\n", report.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	sb.WriteString("📊 SCHEDULABLE CAPACITY (requests/allocatable):\n")
+	fmt.Fprintf(&sb, "  %s CPU     %s/%s  %5.1f%%  headroom %s\n", utilizationIcon(report.CPUPercent),
+		report.RequestedCPU.Human, report.AllocatableCPU.Human, report.CPUPercent, report.HeadroomCPU.Human)
+	fmt.Fprintf(&sb, "  %s Memory  %s/%s  %5.1f%%  headroom %s\n", utilizationIcon(report.MemoryPercent),
+		report.RequestedMemory.Human, report.AllocatableMemory.Human, report.MemoryPercent, report.HeadroomMemory.Human)
+	if report.PendingPods > 0 {
+		fmt.Fprintf(&sb, "  ⏳ %d pod(s) not yet scheduled\n", report.PendingPods)
+	}
+
+	sb.WriteString("\n📦 LARGEST POD THAT FITS ON ONE NODE:\n")
+	fmt.Fprintf(&sb, "  By CPU:    %s\n", formatPodSize(report.LargestByCPU))
+	fmt.Fprintf(&sb, "  By memory: %s\n", formatPodSize(report.LargestByMemory))
+
+	sb.WriteString("\n🖥️  NODES:\n")
+	for _, n := range report.Nodes {
+		note := ""
+		switch {
+		case !n.Ready:
+			note = "  ⚠️ NotReady"
+		case !n.Schedulable:
+			note = "  (cordoned or tainted)"
+		}
+		fmt.Fprintf(&sb, "  %s%s\n", n.Node, note)
+		fmt.Fprintf(&sb, "    %s CPU     %s/%s  %5.1f%%  limits %s\n", utilizationIcon(n.CPUPercent),
+			n.RequestedCPU.Human, n.AllocatableCPU.Human, n.CPUPercent, n.LimitsCPU.Human)
+		fmt.Fprintf(&sb, "    %s Memory  %s/%s  %5.1f%%  limits %s\n", utilizationIcon(n.MemoryPercent),
+			n.RequestedMemory.Human, n.AllocatableMemory.Human, n.MemoryPercent, n.LimitsMemory.Human)
+		fmt.Fprintf(&sb, "    Pods    %d/%d\n", n.Pods, n.MaxPods)
+	}
+
+	if len(report.Overcommitted) > 0 {
+		fmt.Fprintf(&sb, "\n⚠️  OVERCOMMITTED NODES: %d\n", len(report.Overcommitted))
+		for _, n := range report.Nodes {
+			for _, reason := range n.Overcommitted {
+				fmt.Fprintf(&sb, "  %s: %s\n", n.Node, reason)
+			}
+		}
+	}
+	return sb.String()
+}
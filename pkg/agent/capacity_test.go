@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestFormatCapacityReport(t *testing.T) {
+	report := &k8s.CapacityReport{
+		Context:           "prod",
+		AllocatableCPU:    k8s.CPUAmountFromMillicores(8000),
+		RequestedCPU:      k8s.CPUAmountFromMillicores(7600),
+		HeadroomCPU:       k8s.CPUAmountFromMillicores(400),
+		CPUPercent:        95,
+		AllocatableMemory: k8s.MemoryAmountFromBytes(16 << 30),
+		RequestedMemory:   k8s.MemoryAmountFromBytes(4 << 30),
+		HeadroomMemory:    k8s.MemoryAmountFromBytes(12 << 30),
+		MemoryPercent:     25,
+		PendingPods:       2,
+		LargestByCPU:      &k8s.PodSize{Node: "n1", CPU: k8s.CPUAmountFromMillicores(300), Memory: k8s.MemoryAmountFromBytes(6 << 30)},
+		Overcommitted:     []string{"n1"},
+		Nodes: []k8s.NodeCapacity{
+			{Node: "n1", Ready: true, Schedulable: true, Pods: 12, MaxPods: 110, Overcommitted: []string{"CPU limits at 150% of allocatable (throttling under load)"}},
+			{Node: "n2", Ready: true},
+		},
+	}
+	text := formatCapacityReport(report)
+	for _, want := range []string{
+		"Cluster Capacity: prod",
+		"🔴 CPU     7.6/8   95.0%  headroom 400m",
+		"🟢 Memory  4Gi/16Gi   25.0%  headroom 12Gi",
+		"⏳ 2 pod(s) not yet scheduled",
+		"By CPU:    300m CPU / 6Gi memory on n1",
+		"By memory: none (no schedulable node has room)",
+		"n2  (cordoned or tainted)",
+		"Pods    12/110",
+		"OVERCOMMITTED NODES: 1",
+		"n1: CPU limits at 150% of allocatable",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report missing %q:\n%s", want, text)
+		}
+	}
+}
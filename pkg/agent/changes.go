@@ -0,0 +1,105 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the recent_changes tool ("did someone deploy something?").
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+const (
+	defaultChangesWindow = "24h"
+	maxChangesListed     = 50
+)
+
+// RecentChangesParams defines parameters for recent_changes
+type RecentChangesParams struct {
+	Context       string   `json:"context" jsonschema:"The context name of the cluster to inspect (from list_clusters)"`
+	Namespace     string   `json:"namespace,omitempty" jsonschema:"Optional: restrict the listing to a specific namespace; leave empty for all non-system namespaces"`
+	Since         string   `json:"since,omitempty" jsonschema:"How far back to look, e.g. 30m, 6h or 2d (default 24h)"`
+	Kinds         []string `json:"kinds,omitempty" jsonschema:"Optional: only list these kinds (Deployment, ConfigMap, Secret); default all"`
+	IncludeSystem bool     `json:"include_system,omitempty" jsonschema:"If true, include system namespaces (kube-system, kube-public, kube-node-lease)"`
+}
+
+func defineRecentChangesTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolRecentChanges,
+		"List recently modified Deployments, ConfigMaps and Secrets, newest first, with who changed them (field manager such as kubectl, helm or argocd), rollout revision, images and restarts. Use first in incidents to answer 'did someone deploy or change something?'. Secret values are never read.",
+		func(params RecentChangesParams, inv llm.ToolInvocation) (any, error) {
+			return handleRecentChanges(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+func handleRecentChanges(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params RecentChangesParams) (any, error) {
+	if params.Namespace != "" && !isValidKubernetesName(params.Namespace) {
+		return nil, fmt.Errorf("invalid namespace name: %s", params.Namespace)
+	}
+	for _, kind := range params.Kinds {
+		valid := false
+		for _, k := range k8s.ChangeKinds {
+			valid = valid || strings.EqualFold(kind, k)
+		}
+		if !valid {
+			return nil, fmt.Errorf("unsupported kind %q (supported: %s)", kind, strings.Join(k8s.ChangeKinds, ", "))
+		}
+	}
+	if params.Since == "" {
+		params.Since = defaultChangesWindow
+	}
+	window, err := parseWindow(params.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := k8sProvider.RecentChanges(ctx, params.Context, params.Namespace, params.IncludeSystem, time.Now().Add(-window), params.Kinds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recent changes: %w", err)
+	}
+	if isJSONOutput(state.outputFormat) {
+		return report, nil
+	}
+	return formatRecentChanges(report, params.Since, time.Now()), nil
+}
+
+// changeIcons maps a changed kind to its list icon.
+var changeIcons = map[string]string{
+	"Deployment": "🚀",
+	"ConfigMap":  "📝",
+	"Secret":     "🔑",
+}
+
+// formatRecentChanges formats a RecentChangesReport as human-readable text
+func formatRecentChanges(report *k8s.RecentChangesReport, window string, now time.Time) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Recent Changes: %s (last %s)\n", report.Context, window)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	if len(report.Changes) == 0 {
+		sb.WriteString("✅ Nothing changed in the window.\n")
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "📊 SUMMARY: %d object(s) changed\n\n", len(report.Changes))
+	for i, c := range report.Changes {
+		if i == maxChangesListed {
+			fmt.Fprintf(&sb, "... and %d older change(s)\n", len(report.Changes)-maxChangesListed)
+			break
+		}
+		by := ""
+		if c.Manager != "" {
+			by = " by " + c.Manager
+		}
+		fmt.Fprintf(&sb, "%s %s  %s %s/%s  %s%s (%s ago)\n", changeIcons[c.Kind], c.ChangedAt.UTC().Format(time.RFC3339),
+			c.Kind, c.Namespace, c.Name, c.Operation, by, now.Sub(c.ChangedAt).Round(time.Minute))
+		if c.Detail != "" {
+			fmt.Fprintf(&sb, "   %s\n", c.Detail)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestFormatRecentChanges(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	report := &k8s.RecentChangesReport{Context: "prod", Changes: []k8s.ResourceChange{
+		{Kind: "Deployment", Namespace: "shop", Name: "web", ChangedAt: now.Add(-15 * time.Minute), Manager: "helm", Operation: "Update", Detail: "revision 7; images web:1.2"},
+		{Kind: "Secret", Namespace: "shop", Name: "creds", ChangedAt: now.Add(-2 * time.Hour), Operation: "Created"},
+	}}
+	text := formatRecentChanges(report, "24h", now)
+	for _, want := range []string{
+		"Recent Changes: prod (last 24h)",
+		"SUMMARY: 2 object(s) changed",
+		"🚀 2026-03-01T11:45:00Z  Deployment shop/web  Update by helm (15m0s ago)",
+		"   revision 7; images web:1.2",
+		"🔑 2026-03-01T10:00:00Z  Secret shop/creds  Created (2h0m0s ago)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report missing %q:\n%s", want, text)
+		}
+	}
+
+	empty := formatRecentChanges(&k8s.RecentChangesReport{Context: "prod"}, "1h", now)
+	if !strings.Contains(empty, "Nothing changed") {
+		t.Errorf("empty report = %q", empty)
+	}
+}
+
+func TestHandleRecentChangesValidation(t *testing.T) {
+	state := &agentState{mode: ModeReadOnly, outputFormat: OutputText}
+	cases := []RecentChangesParams{
+		{Context: "test-context", Namespace: "Bad_NS"},
+		{Context: "test-context", Kinds: []string{"Pod"}},
+		{Context: "test-context", Since: "yesterday"},
+	}
+	for _, params := range cases {
+		if _, err := handleRecentChanges(context.Background(), nil, state, params); err == nil {
+			t.Errorf("handleRecentChanges(context.Background(), %+v) succeeded, want validation error", params)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the chaos tool: small resilience drills with automatic revert.
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+const (
+	chaosActionDeletePod  = "delete_pod"
+	chaosActionCordonNode = "cordon_node"
+
+	defaultChaosMinutes = 5
+	maxChaosMinutes     = 60
+)
+
+// Provider calls used by the chaos tool; variables so tests can stub the cluster.
+var (
+	chaosDeletePodFunc = func(p *k8s.Provider, ctx context.Context, contextName, namespace, deployment string) (*k8s.ChaosPodResult, error) {
+		return p.ChaosDeletePod(ctx, contextName, namespace, deployment)
+	}
+	cordonNodeFunc = func(p *k8s.Provider, ctx context.Context, contextName, node string) (k8s.NodeHealth, error) {
+		return p.CordonNode(ctx, contextName, node)
+	}
+	uncordonNodeFunc = func(p *k8s.Provider, ctx context.Context, contextName, node string) (k8s.NodeHealth, error) {
+		return p.UncordonNode(ctx, contextName, node)
+	}
+)
+
+// ChaosParams defines parameters for chaos
+type ChaosParams struct {
+	Context    string `json:"context" jsonschema:"The cluster context name (required)"`
+	Action     string `json:"action" jsonschema:"Experiment to run: 'delete_pod' (delete one random Ready pod of a deployment) or 'cordon_node' (cordon a node temporarily)"`
+	Namespace  string `json:"namespace,omitempty" jsonschema:"Namespace of the deployment (delete_pod)"`
+	Deployment string `json:"deployment,omitempty" jsonschema:"Deployment whose pod to delete (delete_pod)"`
+	Node       string `json:"node,omitempty" jsonschema:"Node to cordon (cordon_node)"`
+	Minutes    int    `json:"minutes,omitempty" jsonschema:"How long the node stays cordoned before it is uncordoned automatically (cordon_node, default 5, max 60)"`
+}
+
+// ChaosCordonResult records a node-cordon drill.
+type ChaosCordonResult struct {
+	Context  string         `json:"context"`
+	Node     string         `json:"node"`
+	Before   k8s.NodeHealth `json:"before"`
+	RevertAt time.Time      `json:"revert_at"`
+}
+
+// chaosCordon is a cordon waiting for its automatic revert.
+type chaosCordon struct {
+	context, node string
+	revertAt      time.Time
+	timer         *time.Timer
+}
+
+// chaosManager uncordons nodes cordoned by the chaos tool when their time is
+// up, or all at once when the session ends. It is safe for concurrent use.
+type chaosManager struct {
+	k8sProvider *k8s.Provider
+	out         io.Writer // where automatic reverts are announced
+	mu          sync.Mutex
+	cordons     map[string]*chaosCordon // context + "/" + node
+}
+
+func newChaosManager(k8sProvider *k8s.Provider, out io.Writer) *chaosManager {
+	return &chaosManager{k8sProvider: k8sProvider, out: out, cordons: make(map[string]*chaosCordon)}
+}
+
+// schedule arranges for node to be uncordoned after d.
+func (m *chaosManager) schedule(contextName, node string, d time.Duration) time.Time {
+	key := contextName + "/" + node
+	c := &chaosCordon{context: contextName, node: node, revertAt: time.Now().Add(d)}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cordons[key] = c
+	c.timer = time.AfterFunc(d, func() { m.revert(key, true) })
+	return c.revertAt
+}
+
+// revert uncordons the node for key if it is still pending.
+func (m *chaosManager) revert(key string, announce bool) {
+	m.mu.Lock()
+	c, ok := m.cordons[key]
+	delete(m.cordons, key)
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+	c.timer.Stop()
+
+	after, err := uncordonNodeFunc(m.k8sProvider, context.Background(), c.context, c.node)
+	switch {
+	case err != nil:
+		fmt.Fprintf(m.out, "\n  %s●%s Chaos revert FAILED for node %s (%s): %v — run: kubectl --context %s uncordon %s\n",
+			colorRed, colorReset, c.node, c.context, err, c.context, c.node)
+	case announce:
+		fmt.Fprintf(m.out, "\n  %s●%s Chaos revert: node %s (%s) uncordoned, %d pod(s), ready=%t\n",
+			colorGreen, colorReset, c.node, c.context, after.Pods, after.Ready)
+	}
+}
+
+// RevertAll uncordons every pending node immediately; called on exit.
+func (m *chaosManager) RevertAll() {
+	m.mu.Lock()
+	keys := make([]string, 0, len(m.cordons))
+	for k := range m.cordons {
+		keys = append(keys, k)
+	}
+	m.mu.Unlock()
+	for _, k := range keys {
+		m.revert(k, false)
+	}
+}
+
+func defineChaosTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolChaos,
+		"Run a small, confirmed resilience drill: delete one random Ready pod of a healthy deployment with at least two replicas and measure recovery, or cordon a node for N minutes with automatic uncordon (also on exit). Records health before and after. Refuses system namespaces, degraded deployments, and the last schedulable node.",
+		func(params ChaosParams, inv llm.ToolInvocation) (any, error) {
+			return handleChaos(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+func handleChaos(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params ChaosParams) (any, error) {
+	if params.Context == "" {
+		return nil, fmt.Errorf("context is required")
+	}
+	var fullCommand string
+	switch params.Action {
+	case chaosActionDeletePod:
+		if params.Namespace == "" || params.Deployment == "" {
+			return nil, fmt.Errorf("namespace and deployment are required for delete_pod")
+		}
+		if !isValidKubernetesName(params.Namespace) || !isValidKubernetesName(params.Deployment) {
+			return nil, fmt.Errorf("invalid namespace or deployment name")
+		}
+		if k8s.IsSystemNamespace(params.Namespace) {
+			return nil, fmt.Errorf("refusing: chaos experiments are not allowed in system namespace %s", params.Namespace)
+		}
+		fullCommand = fmt.Sprintf("kubectl --context %s delete pod <random Ready pod of deployment/%s> -n %s", params.Context, params.Deployment, params.Namespace)
+	case chaosActionCordonNode:
+		if params.Node == "" || !isValidKubernetesName(params.Node) {
+			return nil, fmt.Errorf("a valid node is required for cordon_node")
+		}
+		if params.Minutes == 0 {
+			params.Minutes = defaultChaosMinutes
+		}
+		if params.Minutes < 1 || params.Minutes > maxChaosMinutes {
+			return nil, fmt.Errorf("minutes must be between 1 and %d", maxChaosMinutes)
+		}
+		fullCommand = fmt.Sprintf("kubectl --context %s cordon %s  (auto-uncordon after %d min)", params.Context, params.Node, params.Minutes)
+	default:
+		return nil, fmt.Errorf("unknown action %q: use %s or %s", params.Action, chaosActionDeletePod, chaosActionCordonNode)
+	}
+
+	cluster, err := getClusterForContext(k8sProvider, params.Context)
+	if err != nil {
+		return nil, err
+	}
+	req := ApprovalRequest{Tool: toolChaos, Cluster: cluster.Name, Context: params.Context, Command: fullCommand}
+	if params.Action == chaosActionCordonNode {
+		req.Safety = disruptionSafety(ctx, k8sProvider, params.Context, k8s.DisruptionTarget{Node: params.Node}, true)
+	}
+	proceed, cancelResult, err := enforceApproval(ctx, state, false, req)
+	if err != nil {
+		return nil, err
+	}
+	if !proceed {
+		return cancelResult, nil
+	}
+	printExecutionHeader(state, false, fullCommand)
+
+	if params.Action == chaosActionDeletePod {
+		result, err := chaosDeletePodFunc(k8sProvider, ctx, params.Context, params.Namespace, params.Deployment)
+		state.publishWrite(req, err)
+		if err != nil {
+			return nil, err
+		}
+		if isJSONOutput(state.outputFormat) {
+			return result, nil
+		}
+		return formatChaosPodResult(result), nil
+	}
+
+	if state.chaos == nil {
+		state.chaos = newChaosManager(k8sProvider, state.stdout())
+	}
+	before, err := cordonNodeFunc(k8sProvider, ctx, params.Context, params.Node)
+	state.publishWrite(req, err)
+	if err != nil {
+		return nil, err
+	}
+	result := ChaosCordonResult{
+		Context:  params.Context,
+		Node:     params.Node,
+		Before:   before,
+		RevertAt: state.chaos.schedule(params.Context, params.Node, time.Duration(params.Minutes)*time.Minute),
+	}
+	if isJSONOutput(state.outputFormat) {
+		return result, nil
+	}
+	return formatChaosCordonResult(result), nil
+}
+
+// formatChaosPodResult formats a ChaosPodResult as human-readable text
+func formatChaosPodResult(r *k8s.ChaosPodResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Chaos Drill: delete pod of %s/%s (%s)\n", r.Namespace, r.Deployment, r.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+	fmt.Fprintf(&sb, "💥 DELETED: pod %s on node %s\n", r.Pod, orNone(r.Node))
+	fmt.Fprintf(&sb, "📊 BEFORE: %d/%d ready, %d available\n", r.Before.Ready, r.Before.Desired, r.Before.Available)
+	fmt.Fprintf(&sb, "📊 AFTER:  %d/%d ready, %d available\n\n", r.After.Ready, r.After.Desired, r.After.Available)
+	if r.Recovered {
+		fmt.Fprintf(&sb, "✅ RECOVERED in %.0fs\n", r.RecoverySeconds)
+	} else {
+		fmt.Fprintf(&sb, "❌ NOT RECOVERED after %.0fs — check events and pending pods for the deployment\n", r.RecoverySeconds)
+	}
+	return sb.String()
+}
+
+// formatChaosCordonResult formats a ChaosCordonResult as human-readable text
+func formatChaosCordonResult(r ChaosCordonResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Chaos Drill: cordon node %s (%s)\n", r.Node, r.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+	fmt.Fprintf(&sb, "🚧 CORDONED: no new pods will be scheduled on %s (%d pod(s) keep running)\n", r.Node, r.Before.Pods)
+	fmt.Fprintf(&sb, "⏱️  AUTO-REVERT: uncordon at %s (also on exit)\n", r.RevertAt.Format(time.Kitchen))
+	sb.WriteString("\nWatch for pending pods meanwhile; scale or restart a workload to see how it schedules without this node.\n")
+	return sb.String()
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+// stubChaosCluster replaces the chaos provider calls; the returned function
+// lists the nodes uncordoned so far.
+func stubChaosCluster(t *testing.T) func() []string {
+	t.Helper()
+	del, cordon, uncordon := chaosDeletePodFunc, cordonNodeFunc, uncordonNodeFunc
+	t.Cleanup(func() { chaosDeletePodFunc, cordonNodeFunc, uncordonNodeFunc = del, cordon, uncordon })
+
+	var mu sync.Mutex
+	var uncordoned []string
+	chaosDeletePodFunc = func(_ *k8s.Provider, _ context.Context, contextName, namespace, deployment string) (*k8s.ChaosPodResult, error) {
+		return &k8s.ChaosPodResult{Context: contextName, Namespace: namespace, Deployment: deployment, Pod: deployment + "-1",
+			Before: k8s.DeploymentHealth{Desired: 3, Ready: 3}, After: k8s.DeploymentHealth{Desired: 3, Ready: 3}, Recovered: true, RecoverySeconds: 7}, nil
+	}
+	cordonNodeFunc = func(_ *k8s.Provider, _ context.Context, _, _ string) (k8s.NodeHealth, error) {
+		return k8s.NodeHealth{Ready: true, Pods: 12}, nil
+	}
+	uncordonNodeFunc = func(_ *k8s.Provider, _ context.Context, _, node string) (k8s.NodeHealth, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		uncordoned = append(uncordoned, node)
+		return k8s.NodeHealth{Ready: true}, nil
+	}
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), uncordoned...)
+	}
+}
+
+func TestHandleChaosValidation(t *testing.T) {
+	provider := newTestK8sProvider(t)
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON}
+	tests := []struct {
+		params ChaosParams
+		want   string
+	}{
+		{ChaosParams{Context: "test-context", Action: "nuke"}, "unknown action"},
+		{ChaosParams{Context: "test-context", Action: chaosActionDeletePod, Namespace: "shop"}, "required"},
+		{ChaosParams{Context: "test-context", Action: chaosActionDeletePod, Namespace: "kube-system", Deployment: "coredns"}, "system namespace"},
+		{ChaosParams{Context: "test-context", Action: chaosActionCordonNode, Node: "node-1", Minutes: 120}, "minutes"},
+	}
+	for _, tt := range tests {
+		if _, err := handleChaos(context.Background(), provider, state, tt.params); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("handleChaos(context.Background(), %+v) err = %v, want %q", tt.params, err, tt.want)
+		}
+	}
+}
+
+func TestHandleChaosDeclined(t *testing.T) {
+	stubChaosCluster(t)
+	called := false
+	chaosDeletePodFunc = func(*k8s.Provider, context.Context, string, string, string) (*k8s.ChaosPodResult, error) {
+		called = true
+		return nil, nil
+	}
+	approver := &stubApprover{name: "test", decision: ApprovalDecision{Approved: false}}
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON, approver: approver}
+
+	out, err := handleChaos(context.Background(), newTestK8sProvider(t), state, ChaosParams{Context: "test-context", Action: chaosActionDeletePod, Namespace: "shop", Deployment: "web"})
+	if err != nil || out != operationCancelledMessage || !approver.called || called {
+		t.Errorf("declined chaos = %v, %v (approver called %t, action ran %t)", out, err, approver.called, called)
+	}
+}
+
+func TestHandleChaosDeletePod(t *testing.T) {
+	stubChaosCluster(t)
+	state := &agentState{mode: ModeInteractive, approver: &stubApprover{name: "test", decision: ApprovalDecision{Approved: true}}, outputFormat: OutputText}
+	out, err := handleChaos(context.Background(), newTestK8sProvider(t), state, ChaosParams{Context: "test-context", Action: chaosActionDeletePod, Namespace: "shop", Deployment: "web"})
+	if err != nil {
+		t.Fatalf("handleChaos: %v", err)
+	}
+	text := out.(string)
+	for _, want := range []string{"DELETED: pod web-1", "BEFORE: 3/3 ready", "RECOVERED in 7s"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestHandleChaosCordonRevertsOnExit(t *testing.T) {
+	uncordoned := stubChaosCluster(t)
+	state := &agentState{mode: ModeInteractive, approver: &stubApprover{name: "test", decision: ApprovalDecision{Approved: true}}, outputFormat: OutputJSON}
+	out, err := handleChaos(context.Background(), newTestK8sProvider(t), state, ChaosParams{Context: "test-context", Action: chaosActionCordonNode, Node: "node-1"})
+	if err != nil {
+		t.Fatalf("handleChaos: %v", err)
+	}
+	result := out.(ChaosCordonResult)
+	if result.Before.Pods != 12 || time.Until(result.RevertAt) < 4*time.Minute {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if len(uncordoned()) != 0 {
+		t.Fatal("node should stay cordoned until the revert")
+	}
+
+	state.chaos.RevertAll()
+	state.chaos.RevertAll() // idempotent
+	if got := uncordoned(); len(got) != 1 || got[0] != "node-1" {
+		t.Errorf("uncordoned = %v, want [node-1]", got)
+	}
+}
+
+func TestChaosManagerTimer(t *testing.T) {
+	uncordoned := stubChaosCluster(t)
+	m := newChaosManager(nil, io.Discard)
+	m.schedule("ctx", "node-2", 10*time.Millisecond)
+	deadline := time.Now().Add(2 * time.Second)
+	for len(uncordoned()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	m.RevertAll()
+	if got := uncordoned(); len(got) != 1 {
+		t.Errorf("timer revert should uncordon exactly once, got %v", got)
+	}
+}
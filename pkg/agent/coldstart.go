@@ -0,0 +1,141 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the "since you last ran kopilot" summary shown at startup.
+package agent
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/debug"
+)
+
+// coldStartWindow is how recent the last recorded check of a cluster must be
+// for the startup summary to compare against it.
+const coldStartWindow = 30 * 24 * time.Hour
+
+// maxColdStartIssues is how many new or resolved issues are listed per cluster.
+const maxColdStartIssues = 5
+
+// ClusterChange is what changed in one cluster since its last recorded check.
+type ClusterChange struct {
+	Context  string       `json:"context"`
+	Cluster  string       `json:"cluster"`
+	LastSeen time.Time    `json:"last_seen"`
+	Trend    *HealthTrend `json:"trend"`
+	New      []string     `json:"new_issues,omitempty"`
+	Resolved []string     `json:"resolved_issues,omitempty"`
+}
+
+// changed reports whether anything about the cluster changed.
+func (c ClusterChange) changed() bool {
+	return len(c.Trend.Changes) > 0 || len(c.New) > 0 || len(c.Resolved) > 0
+}
+
+// sinceLastRun compares r with the latest recorded check of each of its
+// clusters; clusters without a previous record are left out.
+func sinceLastRun(previous map[string]HealthRecord, r *ClusterReport) []ClusterChange {
+	var changes []ClusterChange
+	for _, now := range healthRecords(r) {
+		before, ok := previous[now.Context]
+		if !ok {
+			continue
+		}
+		change := ClusterChange{Context: now.Context, Cluster: now.Cluster, LastSeen: before.Time, Trend: compareHealth(before, now)}
+		change.New, change.Resolved = issueTextChanges(before.Issues, now.Issues)
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// issueTextChanges returns the issues of after missing from before and those
+// of before missing from after, matched like issueKey so changing counts do
+// not make an issue new.
+func issueTextChanges(before, after []string) (added, removed []string) {
+	normalize := func(msg string) string { return digits.ReplaceAllString(msg, "#") }
+	was := make(map[string]bool, len(before))
+	for _, msg := range before {
+		was[normalize(msg)] = true
+	}
+	is := make(map[string]bool, len(after))
+	for _, msg := range after {
+		is[normalize(msg)] = true
+		if !was[normalize(msg)] {
+			added = append(added, msg)
+		}
+	}
+	for _, msg := range before {
+		if !is[normalize(msg)] {
+			removed = append(removed, msg)
+		}
+	}
+	return added, removed
+}
+
+// latestHealthRecords returns the latest recorded check within
+// coldStartWindow of each context.
+func latestHealthRecords(contexts []string, now time.Time) map[string]HealthRecord {
+	latest := make(map[string]HealthRecord)
+	for _, contextName := range contexts {
+		records, err := loadHealthRecords(contextName, now.Add(-coldStartWindow))
+		if err != nil {
+			debug.Logf(debug.Tools, "health history for %s not read: %v", contextName, err)
+			continue
+		}
+		if len(records) > 0 {
+			latest[contextName] = records[len(records)-1]
+		}
+	}
+	return latest
+}
+
+// printSinceLastRun shows the startup summary; nothing when no cluster had
+// a recorded check.
+func printSinceLastRun(w io.Writer, changes []ClusterChange) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "  %sSince you last ran kopilot:%s\n", colorDim, colorReset)
+	unchanged := 0
+	for _, c := range changes {
+		if !c.changed() {
+			unchanged++
+			continue
+		}
+		fmt.Fprintf(w, "    %s %s%s%s %s(last checked %s ago)%s\n", trendIcon(c.Trend.Verdict), colorCyan, c.Context, colorReset,
+			colorDim, time.Since(c.LastSeen).Round(time.Minute), colorReset)
+		for _, change := range c.Trend.Changes {
+			fmt.Fprintf(w, "        %s•%s %s\n", colorDim, colorReset, change)
+		}
+		printIssueList(w, "new", colorRed, c.New)
+		printIssueList(w, "resolved", colorGreen, c.Resolved)
+	}
+	if unchanged > 0 {
+		fmt.Fprintf(w, "    %s%d cluster(s) unchanged%s\n", colorDim, unchanged, colorReset)
+	}
+}
+
+// printIssueList prints up to maxColdStartIssues issues labelled label.
+func printIssueList(w io.Writer, label, color string, issues []string) {
+	for i, issue := range issues {
+		if i == maxColdStartIssues {
+			fmt.Fprintf(w, "        %s… %d more %s%s\n", colorDim, len(issues)-i, label, colorReset)
+			return
+		}
+		fmt.Fprintf(w, "        %s%s:%s %s\n", color, label, colorReset, issue)
+	}
+}
+
+// trendIcon is the icon of a HealthTrend verdict.
+func trendIcon(verdict string) string {
+	switch verdict {
+	case trendWorse:
+		return "📉"
+	case trendBetter:
+		return "📈"
+	case trendMixed:
+		return "↕️"
+	}
+	return "➖"
+}
@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/report"
+)
+
+func TestSinceLastRun(t *testing.T) {
+	lastSeen := time.Now().Add(-26 * time.Hour)
+	previous := map[string]HealthRecord{
+		"prod": {Time: lastSeen, Context: "prod", Reachable: true, Nodes: 3, ReadyNodes: 3, Pods: 40, UnhealthyPods: 2, Warnings: 2,
+			Issues: []string{"2/40 pods unhealthy", "1 exposed endpoint problem(s)"}},
+		"dev": {Time: lastSeen, Context: "dev", Reachable: true, Nodes: 1, ReadyNodes: 1},
+	}
+	r := &ClusterReport{
+		GeneratedAt: time.Now(),
+		CheckAllClustersResult: CheckAllClustersResult{
+			Issues: []report.Issue{
+				{Severity: report.SeverityWarning, Cluster: "prod", Message: "5/40 pods unhealthy"},
+				{Severity: report.SeverityCritical, Cluster: "prod", Message: "control-plane component etcd unhealthy (2/3 ready)"},
+			},
+			Clusters: []*k8s.ClusterStatus{
+				{ClusterInfo: k8s.ClusterInfo{Name: "prod-cluster", Context: "prod", IsReachable: true}, NodeCount: 3, HealthyNodes: 3, PodCount: 40, UnhealthyPods: make([]k8s.PodInfo, 5)},
+				{ClusterInfo: k8s.ClusterInfo{Name: "dev-cluster", Context: "dev", IsReachable: true}, NodeCount: 1, HealthyNodes: 1},
+				{ClusterInfo: k8s.ClusterInfo{Name: "new-cluster", Context: "new", IsReachable: true}},
+			},
+		},
+	}
+
+	changes := sinceLastRun(previous, r)
+	if len(changes) != 2 {
+		t.Fatalf("changes = %+v, want prod and dev only", changes)
+	}
+	prod := changes[0]
+	if prod.Context != "prod" || prod.Trend.Verdict != trendMixed || !prod.LastSeen.Equal(lastSeen) {
+		t.Errorf("prod = %+v", prod)
+	}
+	if !reflect.DeepEqual(prod.New, []string{"control-plane component etcd unhealthy (2/3 ready)"}) ||
+		!reflect.DeepEqual(prod.Resolved, []string{"1 exposed endpoint problem(s)"}) {
+		t.Errorf("prod new %q, resolved %q; a changed pod count is not a new issue", prod.New, prod.Resolved)
+	}
+	if changes[1].changed() {
+		t.Errorf("dev = %+v, want unchanged", changes[1])
+	}
+
+	var out bytes.Buffer
+	printSinceLastRun(&out, changes)
+	text := out.String()
+	for _, want := range []string{"Since you last ran kopilot:", "↕️ \033[36mprod", "(last checked 26h0m0s ago)", "unhealthy pods 2 → 5",
+		"new:\033[0m control-plane component etcd", "resolved:\033[0m 1 exposed endpoint", "1 cluster(s) unchanged"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("summary missing %q:\n%s", want, text)
+		}
+	}
+
+	out.Reset()
+	printSinceLastRun(&out, nil)
+	if out.Len() != 0 {
+		t.Errorf("summary without previous checks = %q", out.String())
+	}
+}
+
+func TestLatestHealthRecords(t *testing.T) {
+	withHealthHistoryDir(t)
+	now := time.Now()
+	for _, rec := range []HealthRecord{
+		{Time: now.Add(-60 * 24 * time.Hour), Context: "stale"},
+		{Time: now.Add(-48 * time.Hour), Context: "prod", UnhealthyPods: 1},
+		{Time: now.Add(-2 * time.Hour), Context: "prod", UnhealthyPods: 3},
+	} {
+		if err := appendHealthRecord(rec, defaultHealthHistoryRetention); err != nil {
+			t.Fatal(err)
+		}
+	}
+	latest := latestHealthRecords([]string{"prod", "stale", "missing"}, now)
+	if len(latest) != 1 || latest["prod"].UnhealthyPods != 3 {
+		t.Errorf("latest = %+v, want only the newest prod record", latest)
+	}
+}
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+// Config is the kopilot config file (~/.kopilot/config.json).
+type Config struct {
+	// Macros are user-defined slash commands expanding to prompts.
+	Macros []Macro `json:"macros,omitempty"`
+	// Health tunes what check_all_clusters reports as degraded and how severe.
+	Health HealthThresholds `json:"health,omitempty"`
+	// Prometheus maps context names to their Prometheus for query_prometheus;
+	// contexts not listed use the prometheus-operator Prometheus if any.
+	Prometheus map[string]k8s.PrometheusEndpoint `json:"prometheus,omitempty"`
+	// Logs maps context names to their Loki or Elasticsearch for query_logs.
+	Logs map[string]k8s.LogsEndpoint `json:"logs,omitempty"`
+	// Contexts tunes the status cache TTL and API rate limit of individual
+	// contexts, e.g. a longer TTL for a large production cluster.
+	Contexts map[string]k8s.ContextSettings `json:"contexts,omitempty"`
+	// Startup selects the checks run when an interactive session starts.
+	Startup StartupChecks `json:"startup,omitempty"`
+	// Theme is the colour theme of the interactive session: dark (default),
+	// light, minimal or none.
+	Theme string `json:"theme,omitempty"`
+	// Quota is the premium quota budget: when to warn, and when to stop
+	// using or ask before using the premium model.
+	Quota QuotaBudget `json:"quota,omitempty"`
+	// KubectlPolicy is how strictly kubectl_exec and execute_plan commands
+	// are validated: strict (default) or permissive.
+	KubectlPolicy string `json:"kubectl_policy,omitempty"`
+}
+
+// DefaultConfigPath returns the default path for the kopilot config file:
+// $HOME/.kopilot/config.json, falling back to ".kopilot/config.json" on error.
+func DefaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".kopilot", "config.json")
+	}
+	return filepath.Join(home, ".kopilot", "config.json")
+}
+
+// LoadConfig reads and validates the config file at path (the default path
+// when empty). If the file does not exist an empty config is returned.
+func LoadConfig(path string) (*Config, error) {
+	if path == "" {
+		path = DefaultConfigPath()
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+	if err := validateMacros(cfg.Macros); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if err := cfg.Health.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: health: %w", path, err)
+	}
+	for contextName, endpoint := range cfg.Prometheus {
+		if err := endpoint.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid config %s: prometheus %s: %w", path, contextName, err)
+		}
+	}
+	for contextName, endpoint := range cfg.Logs {
+		if err := endpoint.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid config %s: logs %s: %w", path, contextName, err)
+		}
+	}
+	if _, err := ParseTheme(cfg.Theme); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if _, err := ParseKubectlPolicy(cfg.KubectlPolicy); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if err := cfg.Startup.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: startup: %w", path, err)
+	}
+	if err := cfg.Quota.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: quota: %w", path, err)
+	}
+	for contextName, settings := range cfg.Contexts {
+		if err := settings.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid config %s: contexts %s: %w", path, contextName, err)
+		}
+	}
+	return &cfg, nil
+}
+
+// WithConfig applies the settings of the config file to the session.
+func WithConfig(cfg *Config) Option {
+	return func(s *agentState) {
+		if cfg != nil {
+			s.macros = cfg.Macros
+			s.health = cfg.Health
+			s.prometheus = cfg.Prometheus
+			s.logs = cfg.Logs
+			s.contexts = cfg.Contexts
+			s.startup = cfg.Startup
+			s.quota = cfg.Quota
+			s.kubectlPolicy, _ = ParseKubectlPolicy(cfg.KubectlPolicy)
+			if cfg.Theme != "" {
+				s.theme, _ = ParseTheme(cfg.Theme)
+			}
+		}
+	}
+}
+
+// configureContexts applies the per-context settings of the config file;
+// contexts missing from the kubeconfig are skipped with a warning.
+func configureContexts(k8sProvider *k8s.Provider, contexts map[string]k8s.ContextSettings) {
+	for contextName, settings := range contexts {
+		if err := k8sProvider.ConfigureContext(contextName, settings); err != nil {
+			log.Printf("Warning: ignoring settings of context %s: %v", contextName, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDefaultConfigPath(t *testing.T) {
+	if got := filepath.Base(DefaultConfigPath()); got != "config.json" {
+		t.Errorf("DefaultConfigPath() base = %q, want config.json", got)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || cfg == nil || len(cfg.Macros) != 0 {
+		t.Fatalf("missing file: cfg=%v err=%v, want empty config", cfg, err)
+	}
+
+	path := writeTestConfig(t, `{"macros": [{"name": "morning", "prompt": "check all prod clusters", "description": "daily check"}]}`)
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	if len(cfg.Macros) != 1 || cfg.Macros[0].Name != "morning" || cfg.Macros[0].Description != "daily check" {
+		t.Errorf("macros = %+v", cfg.Macros)
+	}
+
+	state := &agentState{}
+	WithConfig(cfg)(state)
+	WithConfig(nil)(state)
+	if len(state.macros) != 1 {
+		t.Errorf("WithConfig() macros = %+v", state.macros)
+	}
+
+	cfg, err = LoadConfig(writeTestConfig(t, `{"prometheus": {"prod": {"namespace": "monitoring", "service": "prometheus-k8s"}}}`))
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	WithConfig(cfg)(state)
+	if state.prometheus["prod"].Service != "prometheus-k8s" {
+		t.Errorf("WithConfig() prometheus = %+v", state.prometheus)
+	}
+
+	cfg, err = LoadConfig(writeTestConfig(t, `{"logs": {"prod": {"type": "elasticsearch", "url": "https://es.example.com", "index": "k8s-*"}}}`))
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	WithConfig(cfg)(state)
+	if state.logs["prod"].Index != "k8s-*" {
+		t.Errorf("WithConfig() logs = %+v", state.logs)
+	}
+	if state.kubectlPolicy != KubectlStrict {
+		t.Errorf("WithConfig() kubectl policy = %q, want strict by default", state.kubectlPolicy)
+	}
+
+	cfg, err = LoadConfig(writeTestConfig(t, `{"kubectl_policy": "permissive"}`))
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	WithConfig(cfg)(state)
+	if state.kubectlPolicy != KubectlPermissive {
+		t.Errorf("WithConfig() kubectl policy = %q, want permissive", state.kubectlPolicy)
+	}
+
+	cfg, err = LoadConfig(writeTestConfig(t, `{"contexts": {"test-context": {"cache_ttl": "5m", "qps": 20, "burst": 40}, "gone": {"qps": 1}}}`))
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	WithConfig(cfg)(state)
+	provider := newTestK8sProvider(t)
+	configureContexts(provider, state.contexts)
+	scoped, err := provider.ForContext("test-context")
+	if err != nil || scoped.Settings().CacheTTL != "5m" || scoped.Settings().QPS != 20 {
+		t.Errorf("context settings not applied: %v %+v", err, scoped)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := map[string]string{
+		"parsing config":     `{not json`,
+		"must be lowercase":  `{"macros": [{"name": "Morning", "prompt": "x"}]}`,
+		"built-in command":   `{"macros": [{"name": "help", "prompt": "x"}]}`,
+		"duplicate macro":    `{"macros": [{"name": "a", "prompt": "x"}, {"name": "a", "prompt": "y"}]}`,
+		"empty prompt":       `{"macros": [{"name": "a", "prompt": " "}]}`,
+		"prometheus prod":    `{"prometheus": {"prod": {"url": "prom:9090"}}}`,
+		"logs prod":          `{"logs": {"prod": {"type": "splunk", "url": "https://splunk"}}}`,
+		"contexts prod: qps": `{"contexts": {"prod": {"qps": -1}}}`,
+		"unknown check":      `{"startup": {"checks": ["nodes", "etcd"]}}`,
+		"cannot be combined": `{"startup": {"checks": ["none", "pods"]}}`,
+		"quota: warn_below":  `{"quota": {"warn_below": 120}}`,
+		"kubectl policy":     `{"kubectl_policy": "lax"}`,
+	}
+	for want, content := range tests {
+		_, err := LoadConfig(writeTestConfig(t, content))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadConfig(%s) error = %v, want %q", content, err, want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the check_connectivity tool (Service wiring, NetworkPolicy and DNS diagnosis).
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// dnsProbeImage is the image used for the optional DNS lookup pod.
+const dnsProbeImage = "busybox:1.36"
+
+// CheckConnectivityParams defines parameters for check_connectivity
+type CheckConnectivityParams struct {
+	Context         string `json:"context" jsonschema:"The cluster context name (required)"`
+	Namespace       string `json:"namespace" jsonschema:"Namespace of the target Service (required)"`
+	Service         string `json:"service" jsonschema:"Name of the target Service (required)"`
+	Port            string `json:"port,omitempty" jsonschema:"Service port number or name; defaults to the first port"`
+	SourceNamespace string `json:"source_namespace,omitempty" jsonschema:"Namespace of the client pod; defaults to the Service namespace"`
+	SourcePod       string `json:"source_pod,omitempty" jsonschema:"Client pod that cannot connect; enables NetworkPolicy evaluation for this source"`
+	DNSProbe        bool   `json:"dns_probe,omitempty" jsonschema:"If true, start a short-lived busybox pod in the source namespace to resolve the Service name (requires confirmation)"`
+}
+
+// CheckConnectivityResult wraps the report with the optional DNS probe outcome.
+type CheckConnectivityResult struct {
+	*k8s.ConnectivityReport
+	DNSProbe *k8s.ConnectivityCheck `json:"dns_probe,omitempty"`
+}
+
+func defineCheckConnectivityTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolCheckConnectivity,
+		"Diagnose why a client cannot reach a Service: checks the Service port and selector, matching and Ready pods (with near-miss label hints), target port resolution, EndpointSlices, and whether NetworkPolicies allow ingress/egress and DNS from a given source pod. Optionally resolves the Service name from a temporary debug pod. Use for \"why can't service A reach service B\" questions.",
+		func(params CheckConnectivityParams, inv llm.ToolInvocation) (any, error) {
+			return handleCheckConnectivity(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+func handleCheckConnectivity(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params CheckConnectivityParams) (any, error) {
+	if params.Context == "" || params.Namespace == "" || params.Service == "" {
+		return nil, fmt.Errorf("context, namespace and service are required")
+	}
+	for _, name := range []string{params.Namespace, params.Service, params.SourceNamespace, params.SourcePod} {
+		if name != "" && !isValidKubernetesName(name) {
+			return nil, fmt.Errorf("invalid resource name: %s", name)
+		}
+	}
+	if params.SourceNamespace == "" {
+		params.SourceNamespace = params.Namespace
+	}
+	cluster, err := getClusterForContext(k8sProvider, params.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := k8sProvider.DiagnoseConnectivity(ctx, params.Context, k8s.ConnectivityRequest{
+		Namespace:       params.Namespace,
+		Service:         params.Service,
+		Port:            params.Port,
+		SourceNamespace: params.SourceNamespace,
+		SourcePod:       params.SourcePod,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to diagnose connectivity: %w", err)
+	}
+	result := CheckConnectivityResult{ConnectivityReport: report}
+
+	if params.DNSProbe {
+		probe, cancelResult, err := runDNSProbe(ctx, state, toolCheckConnectivity, cluster.Name, params.Context, params.SourceNamespace, "", report.FQDN)
+		if err != nil {
+			return nil, err
+		}
+		if cancelResult != nil {
+			probe = &k8s.ConnectivityCheck{Name: "dns-probe", Status: k8s.CheckSkip, Detail: fmt.Sprint(cancelResult)}
+		}
+		result.DNSProbe = probe
+		if probe.Status == k8s.CheckFail {
+			result.Healthy = false
+		}
+	}
+
+	if isJSONOutput(state.outputFormat) {
+		return result, nil
+	}
+	return formatConnectivityResult(result), nil
+}
+
+// runDNSProbe resolves fqdn from a throwaway pod in namespace, or with
+// kubectl exec in pod when one is given. Both are writes, so they go through
+// the usual execution-mode gate for tool; a non-nil cancelResult means the
+// user declined or writes are blocked.
+func runDNSProbe(ctx context.Context, state *agentState, tool, clusterName, contextName, namespace, pod, fqdn string) (*k8s.ConnectivityCheck, any, error) {
+	args := []string{"exec", pod, "-n", namespace, "--", "nslookup", fqdn}
+	if pod == "" {
+		podName := fmt.Sprintf("kopilot-dns-%d", time.Now().Unix())
+		args = []string{"run", podName, "-n", namespace, "--rm", "-i", "--quiet", "--restart=Never",
+			"--image=" + dnsProbeImage, "--", "nslookup", fqdn}
+	}
+	fullCommand, cmdArgs := buildKubectlCommand(contextName, args)
+
+	proceed, cancelResult, err := enforceToolExecutionMode(ctx, state, tool, false, clusterName, contextName, fullCommand)
+	if err != nil || !proceed {
+		return nil, cancelResult, err
+	}
+	printExecutionHeader(state, false, fullCommand)
+
+	output, execErr := runKubectlCommandFunc(ctx, cmdArgs)
+	return dnsProbeCheck(fqdn, string(output), execErr), nil, nil
+}
+
+// dnsProbeCheck interprets busybox nslookup output.
+func dnsProbeCheck(fqdn, output string, execErr error) *k8s.ConnectivityCheck {
+	check := &k8s.ConnectivityCheck{Name: "dns-probe"}
+	out := strings.TrimSpace(output)
+	switch {
+	case strings.Contains(out, "NXDOMAIN") || strings.Contains(out, "can't find"):
+		check.Status, check.Detail = k8s.CheckFail, fmt.Sprintf("%s does not resolve (NXDOMAIN)", fqdn)
+	case strings.Contains(out, "timed out") || strings.Contains(out, "no servers could be reached"):
+		check.Status, check.Detail = k8s.CheckFail, "DNS server unreachable from the namespace; check CoreDNS and egress policies for UDP/TCP 53"
+	case execErr != nil:
+		check.Status, check.Detail = k8s.CheckWarn, fmt.Sprintf("probe pod failed: %v: %s", execErr, out)
+	default:
+		// Address lines before the first "Name:" describe the DNS server itself.
+		var addrs []string
+		answered := false
+		for _, line := range strings.Split(out, "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "Name:") {
+				answered = true
+			} else if addr, ok := strings.CutPrefix(line, "Address:"); ok && answered {
+				addrs = append(addrs, strings.TrimSpace(addr))
+			}
+		}
+		check.Status, check.Detail = k8s.CheckOK, fmt.Sprintf("%s resolves to %s", fqdn, orNone(strings.Join(addrs, ", ")))
+	}
+	return check
+}
+
+// connectivityCheckIcons maps check statuses to text-mode icons.
+var connectivityCheckIcons = map[string]string{
+	k8s.CheckOK:   "✅",
+	k8s.CheckWarn: "⚠️ ",
+	k8s.CheckFail: "❌",
+	k8s.CheckSkip: "⏭️ ",
+}
+
+// formatConnectivityResult formats a CheckConnectivityResult as human-readable text
+func formatConnectivityResult(r CheckConnectivityResult) string {
+	var sb strings.Builder
+	target := r.Service
+	if r.Source != "" {
+		target = r.Source + " → " + target
+	}
+	fmt.Fprintf(&sb, "Connectivity Check: %s (%s)\n", target, r.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	checks := r.Checks
+	if r.DNSProbe != nil {
+		checks = append(append([]k8s.ConnectivityCheck{}, checks...), *r.DNSProbe)
+	}
+	sb.WriteString("🔍 CHECKS:\n")
+	for _, c := range checks {
+		fmt.Fprintf(&sb, "  %s %-15s %s\n", connectivityCheckIcons[c.Status], c.Name, c.Detail)
+	}
+
+	if r.Healthy {
+		sb.WriteString("\n✅ VERDICT: no blocking problem found on the Kubernetes side")
+		if r.Source == "" {
+			sb.WriteString(" (give a source pod to evaluate NetworkPolicies)")
+		}
+		sb.WriteString("\n")
+	} else {
+		sb.WriteString("\n❌ VERDICT: traffic will fail — fix the checks marked ❌ above\n")
+	}
+	return sb.String()
+}
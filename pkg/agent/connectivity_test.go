@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestDNSProbeCheck(t *testing.T) {
+	const fqdn = "web.shop.svc.cluster.local"
+	tests := []struct {
+		name, output string
+		err          error
+		status       string
+		detail       string
+	}{
+		{"resolves", "Server:\t\t10.96.0.10\nAddress:\t10.96.0.10:53\n\nName:\tweb.shop.svc.cluster.local\nAddress: 10.0.0.10\n", nil, k8s.CheckOK, "resolves to 10.0.0.10"},
+		{"nxdomain", "** server can't find web.shop.svc.cluster.local: NXDOMAIN\n", errors.New("exit status 1"), k8s.CheckFail, "does not resolve"},
+		{"timeout", ";; connection timed out; no servers could be reached\n", errors.New("exit status 1"), k8s.CheckFail, "DNS server unreachable"},
+		{"pod failure", "error: image pull backoff", errors.New("exit status 1"), k8s.CheckWarn, "probe pod failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dnsProbeCheck(fqdn, tt.output, tt.err)
+			if got.Status != tt.status || !strings.Contains(got.Detail, tt.detail) {
+				t.Errorf("dnsProbeCheck() = %s %q, want %s containing %q", got.Status, got.Detail, tt.status, tt.detail)
+			}
+		})
+	}
+}
+
+func TestRunDNSProbe(t *testing.T) {
+	original := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = original })
+	var gotArgs []string
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
+		gotArgs = args
+		return []byte("Name:\tweb.shop.svc.cluster.local\nAddress: 10.0.0.10\n"), nil
+	}
+
+	approver := &stubApprover{name: "test", decision: ApprovalDecision{Approved: false}}
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON, approver: approver}
+	probe, cancelResult, err := runDNSProbe(context.Background(), state, toolCheckConnectivity, "test-cluster", "test-context", "frontend", "", "web.shop.svc.cluster.local")
+	if err != nil || probe != nil || cancelResult != operationCancelledMessage || !approver.called {
+		t.Fatalf("declined probe = %v, %v, %v", probe, cancelResult, err)
+	}
+	if gotArgs != nil {
+		t.Error("kubectl should not run when the probe is declined")
+	}
+
+	approver.decision = ApprovalDecision{Approved: true}
+	state.denyWritesUntilNextPrompt = false
+	probe, cancelResult, err = runDNSProbe(context.Background(), state, toolCheckConnectivity, "test-cluster", "test-context", "frontend", "", "web.shop.svc.cluster.local")
+	if err != nil || cancelResult != nil || probe.Status != k8s.CheckOK {
+		t.Fatalf("approved probe = %+v, %v, %v", probe, cancelResult, err)
+	}
+	cmd := strings.Join(gotArgs, " ")
+	for _, want := range []string{"--context test-context", "run kopilot-dns-", "-n frontend", "--rm", "--restart=Never", "--image=" + dnsProbeImage, "nslookup web.shop.svc.cluster.local"} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("command %q missing %q", cmd, want)
+		}
+	}
+}
+
+func TestHandleCheckConnectivityValidation(t *testing.T) {
+	state := &agentState{outputFormat: OutputJSON}
+	provider := newTestK8sProvider(t)
+	if _, err := handleCheckConnectivity(context.Background(), provider, state, CheckConnectivityParams{Context: "test-context", Namespace: "shop"}); err == nil {
+		t.Error("missing service should be rejected")
+	}
+	if _, err := handleCheckConnectivity(context.Background(), provider, state, CheckConnectivityParams{Context: "test-context", Namespace: "shop", Service: "web", SourcePod: "bad;name"}); err == nil {
+		t.Error("invalid source pod should be rejected")
+	}
+}
+
+func TestFormatConnectivityResult(t *testing.T) {
+	result := CheckConnectivityResult{
+		ConnectivityReport: &k8s.ConnectivityReport{
+			Context: "prod", Service: "shop/web", Source: "frontend/ui-1",
+			Checks: []k8s.ConnectivityCheck{
+				{Name: "selector", Status: k8s.CheckOK, Detail: "1/1 matching pod(s) Ready"},
+				{Name: "ingress-policy", Status: k8s.CheckFail, Detail: "BLOCKED"},
+			},
+		},
+		DNSProbe: &k8s.ConnectivityCheck{Name: "dns-probe", Status: k8s.CheckOK, Detail: "resolves"},
+	}
+	text := formatConnectivityResult(result)
+	for _, want := range []string{"Connectivity Check: frontend/ui-1 → shop/web (prod)", "❌ ingress-policy", "✅ dns-probe", "VERDICT: traffic will fail"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+	if len(result.Checks) != 2 {
+		t.Error("formatting must not modify the report's checks")
+	}
+}
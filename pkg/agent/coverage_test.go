@@ -1,12 +1,14 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
 	"testing"
 
 	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/report"
 )
 
 // ── agent.go helpers ──────────────────────────────────────────────────────────
@@ -130,7 +132,7 @@ func TestIsUnknownSlashCommand(t *testing.T) {
 		"/help", "/mode", "/status",
 		"/readonly", "/readonly on",
 		"/interactive", "/interactive on",
-		"/agent", "/agent list", "/agent debugger",
+		"/agent", "/agent list", "/agent debugger", "/run check_all_clusters",
 	}
 	for _, s := range known {
 		if isUnknownSlashCommand(s) {
@@ -139,7 +141,7 @@ func TestIsUnknownSlashCommand(t *testing.T) {
 	}
 
 	unknown := []string{
-		"/foo", "/delete", "/nuke", "/run", "/exec", "/FOO",
+		"/foo", "/delete", "/nuke", "/launch", "/exec", "/FOO",
 	}
 	for _, s := range unknown {
 		if !isUnknownSlashCommand(s) {
@@ -281,6 +283,8 @@ func TestWriteUnreachableClusterStatus(t *testing.T) {
 	status := &k8s.ClusterStatus{
 		ClusterInfo: k8s.ClusterInfo{Context: "test-ctx", Server: testServerURL},
 		Error:       "connection refused",
+		Failure:     k8s.FailureRefused,
+		Advice:      "Nothing is listening at the API server address.",
 	}
 	writeUnreachableClusterStatus(&b, status)
 	out := b.String()
@@ -290,12 +294,15 @@ func TestWriteUnreachableClusterStatus(t *testing.T) {
 	if !strings.Contains(out, "connection refused") {
 		t.Error("output should contain error message")
 	}
+	if !strings.Contains(out, "💡 connection refused: Nothing is listening") {
+		t.Errorf("output should contain the cause and advice, got: %s", out)
+	}
 
 	// No error field
 	var b2 strings.Builder
 	writeUnreachableClusterStatus(&b2, &k8s.ClusterStatus{ClusterInfo: k8s.ClusterInfo{Context: "c"}})
-	if strings.Contains(b2.String(), "Issue:") {
-		t.Error("output should not have Issue line when error is empty")
+	if strings.Contains(b2.String(), "Issue:") || strings.Contains(b2.String(), "💡") {
+		t.Error("output should not have Issue or advice lines when error is empty")
 	}
 }
 
@@ -329,12 +336,23 @@ func TestWriteNodeInfo(t *testing.T) {
 		NodeCount:    2,
 		HealthyNodes: 1,
 		Nodes: []k8s.NodeInfo{
-			{Name: "node-a", Status: "Ready", Roles: []string{"control-plane"}, Age: "10d"},
-			{Name: "node-b", Status: "NotReady", Roles: []string{"worker"}, Age: "5d"},
+			{Name: "node-a", Status: "Ready", Roles: []string{"control-plane"}, Age: "10d", KubeletVersion: "v1.29.4", ContainerRuntime: "containerd://1.7.13", InternalIP: "10.0.0.1"},
+			{Name: "node-b", Status: "NotReady", Roles: []string{"worker"}, Age: "5d", KubeletVersion: "v1.27.3"},
 		},
+		KubeletSkew: []k8s.KubeletSkew{{Node: "node-b", KubeletVersion: "v1.27.3", APIServerVersion: "v1.29.2", MinorVersions: -2}},
 	}
 	writeNodeInfo(&b, status)
 	out := b.String()
+	if !strings.Contains(out, "Kubelet: v1.29.4 | Runtime: containerd://1.7.13 | Internal IP: 10.0.0.1") {
+		t.Errorf("output should show the node system info: %s", out)
+	}
+	if !strings.Contains(out, "node node-b runs kubelet v1.27.3, 2 minor version(s) behind API server v1.29.2") {
+		t.Errorf("output should warn about version skew: %s", out)
+	}
+	status.IsReachable = true
+	if summary := analyzeClusterHealth([]*k8s.ClusterStatus{status}, HealthThresholds{}); !strings.Contains(fmt.Sprint(summary.issues), "1 node(s) with kubelet more than one minor version") {
+		t.Errorf("version skew should be a cluster issue: %+v", summary.issues)
+	}
 	if !strings.Contains(out, "2 total") {
 		t.Error("output should show total node count")
 	}
@@ -353,6 +371,161 @@ func TestWriteNodeInfo(t *testing.T) {
 	}
 }
 
+func TestWritePodInfo(t *testing.T) {
+	var b strings.Builder
+	writePodInfo(&b, &k8s.ClusterStatus{PodCount: 3})
+	if b.Len() != 0 {
+		t.Errorf("an unscoped status should not list pods: %s", b.String())
+	}
+
+	status := &k8s.ClusterStatus{
+		Filter:   k8s.HealthFilter{Namespace: "payments", LabelSelector: "app=payments"},
+		PodCount: 3, HealthyPods: 2,
+		UnhealthyPods: []k8s.PodInfo{{Name: "pay-1", Namespace: "payments", Status: "Pending", Reason: "Unschedulable", Restarts: 2}},
+	}
+	writePodInfo(&b, status)
+	for _, want := range []string{
+		"Pods (namespace payments, pods app=payments): 3 total, 2 healthy",
+		"❌ payments/pay-1: Pending (Unschedulable), 2 restart(s)",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("output should contain %q: %s", want, b.String())
+		}
+	}
+}
+
+func TestTruncatedPodCheckIsReported(t *testing.T) {
+	status := &k8s.ClusterStatus{
+		ClusterInfo: k8s.ClusterInfo{Context: "big", IsReachable: true},
+		PodCount:    50000, HealthyPods: 50000, PodsTruncated: true,
+	}
+	summary := analyzeClusterHealth([]*k8s.ClusterStatus{status}, HealthThresholds{})
+	if !strings.Contains(fmt.Sprint(summary.issues), "pod check stopped after 50000 pods") {
+		t.Errorf("a truncated pod check should be reported: %+v", summary.issues)
+	}
+	if summary.countIssues(report.SeverityInfo) != 1 {
+		t.Errorf("a truncated pod check should be informational: %+v", summary.issues)
+	}
+}
+
+func TestWriteCostInfo(t *testing.T) {
+	var b strings.Builder
+	writeCostInfo(&b, &k8s.ClusterStatus{Cost: &k8s.CostEstimate{
+		Currency: "EUR", Source: "price-table", HourlyCost: 1, MonthlyCost: 730, IdleHourlyCost: 0.25,
+		Namespaces:    []k8s.NamespaceCost{{Namespace: "shop", HourlyCost: 0.75, MonthlyCost: 547.5, Percent: 75}},
+		UnpricedNodes: []string{"edge-1"},
+	}})
+	out := b.String()
+	for _, want := range []string{
+		"💰 Estimated cost (price-table): 1.00 EUR/hour, ~730.00 EUR/month",
+		"shop                           ~547.50 EUR/month (75.0%)",
+		"(idle capacity)                ~182.50 EUR/month",
+		"1 node(s) missing from the price table: edge-1",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("cost output missing %q:\n%s", want, out)
+		}
+	}
+
+	var b2 strings.Builder
+	writeCostInfo(&b2, &k8s.ClusterStatus{})
+	if b2.Len() != 0 {
+		t.Error("should print nothing without a cost estimate")
+	}
+}
+
+func TestWriteCostRanking(t *testing.T) {
+	priced := func(ctx string, monthly float64) *k8s.ClusterStatus {
+		return &k8s.ClusterStatus{ClusterInfo: k8s.ClusterInfo{Context: ctx, IsReachable: true}, Cost: &k8s.CostEstimate{Currency: "USD", MonthlyCost: monthly}}
+	}
+	var b strings.Builder
+	writeCostRanking(&b, []*k8s.ClusterStatus{priced("dev", 100), priced("prod", 900), {ClusterInfo: k8s.ClusterInfo{Context: "lab"}}})
+	out := b.String()
+	if !strings.Contains(out, "1. prod") || !strings.Contains(out, "2. dev") || strings.Contains(out, "lab") {
+		t.Errorf("unexpected ranking:\n%s", out)
+	}
+
+	var b2 strings.Builder
+	writeCostRanking(&b2, []*k8s.ClusterStatus{priced("dev", 100)})
+	if b2.Len() != 0 {
+		t.Error("should not rank a single cluster")
+	}
+}
+
+func TestWriteExposureInfo(t *testing.T) {
+	var b strings.Builder
+	writeExposureInfo(&b, &k8s.ClusterStatus{ExposureIssues: []k8s.ExposureIssue{
+		{Namespace: "shop", Kind: "Service", Name: "lb", Problem: "no ready endpoints"},
+	}})
+	if out := b.String(); !strings.Contains(out, "Exposed endpoints with problems (1)") || !strings.Contains(out, "shop/Service/lb: no ready endpoints") {
+		t.Errorf("unexpected exposure output: %s", out)
+	}
+
+	var b2 strings.Builder
+	writeExposureInfo(&b2, &k8s.ClusterStatus{})
+	if b2.Len() != 0 {
+		t.Error("should print nothing without exposure issues")
+	}
+
+	summary := analyzeClusterHealth([]*k8s.ClusterStatus{{
+		ClusterInfo: k8s.ClusterInfo{Context: "prod", IsReachable: true}, NodeCount: 1, HealthyNodes: 1,
+		ExposureIssues: []k8s.ExposureIssue{{Namespace: "shop", Kind: "Service", Name: "lb", Problem: "no ready endpoints"}},
+	}}, HealthThresholds{})
+	if summary.healthyCount != 0 || len(summary.issues) != 1 || !strings.Contains(summary.issues[0].Message, "exposed endpoint") {
+		t.Errorf("exposure problems should count as a cluster issue: %+v", summary)
+	}
+}
+
+func TestWriteControlPlaneInfo(t *testing.T) {
+	controlPlane := []k8s.ControlPlaneComponent{
+		{Name: "kube-apiserver", Healthy: true, Ready: 3, Total: 3},
+		{Name: "etcd", Ready: 2, Total: 3, Message: "2/3 ready: etcd-cp-3 Running (CrashLoopBackOff)"},
+		{Name: "kube-scheduler", Ready: 2, Total: 3, Message: "2/3 ready: kube-scheduler-cp-3 Pending"},
+	}
+	var b strings.Builder
+	writeControlPlaneInfo(&b, &k8s.ClusterStatus{ControlPlane: controlPlane})
+	if out := b.String(); !strings.Contains(out, "✅ kube-apiserver (3/3)") || !strings.Contains(out, "❌ etcd: 2/3 ready: etcd-cp-3") {
+		t.Errorf("unexpected control plane output: %s", out)
+	}
+
+	summary := analyzeClusterHealth([]*k8s.ClusterStatus{{
+		ClusterInfo: k8s.ClusterInfo{Context: "prod", IsReachable: true}, NodeCount: 1, HealthyNodes: 1,
+		ControlPlane: controlPlane,
+	}}, HealthThresholds{})
+	if summary.healthyCount != 0 || len(summary.issues) != 2 || summary.issues[0].Severity != report.SeverityCritical ||
+		summary.issues[0].Message != "control-plane component etcd unhealthy (2/3 ready)" || summary.issues[1].Severity != report.SeverityWarning {
+		t.Errorf("control-plane problems should be a critical issue for etcd and a warning for the scheduler: %+v", summary.issues)
+	}
+}
+
+func TestWriteAutoscalingInfo(t *testing.T) {
+	issues := []k8s.AutoscalingIssue{
+		{Issue: k8s.AutoscalingIssueMetricsUnknown, Namespace: "shop", Name: "api", Target: "Deployment/api", Message: "FailedGetResourceMetric: no metrics"},
+		{Issue: k8s.AutoscalingIssueAtMax, Namespace: "shop", Name: "web", Target: "Deployment/web", Message: "at max replicas (10)"},
+	}
+	var b strings.Builder
+	writeAutoscalingInfo(&b, &k8s.ClusterStatus{AutoscalingIssues: issues})
+	if out := b.String(); !strings.Contains(out, "Autoscalers with problems (2") || !strings.Contains(out, "shop/api (Deployment/api) metrics_unknown: FailedGetResourceMetric") {
+		t.Errorf("unexpected autoscaling output: %s", out)
+	}
+
+	summary := analyzeClusterHealth([]*k8s.ClusterStatus{{
+		ClusterInfo: k8s.ClusterInfo{Context: "prod", IsReachable: true}, NodeCount: 1, HealthyNodes: 1,
+		AutoscalingIssues: issues,
+	}}, HealthThresholds{})
+	if summary.healthyCount != 0 || len(summary.issues) != 2 || summary.issues[0].Severity != report.SeverityWarning || summary.issues[1].Severity != report.SeverityInfo {
+		t.Errorf("broken autoscalers should be a warning and at-max informational: %+v", summary)
+	}
+
+	summary = analyzeClusterHealth([]*k8s.ClusterStatus{{
+		ClusterInfo: k8s.ClusterInfo{Context: "prod", IsReachable: true}, NodeCount: 1, HealthyNodes: 1,
+		AutoscalingIssues: issues[1:],
+	}}, HealthThresholds{})
+	if summary.healthyCount != 1 {
+		t.Errorf("an HPA at max replicas alone should leave the cluster healthy: %+v", summary)
+	}
+}
+
 func TestWriteNamespaceInfo(t *testing.T) {
 	var b strings.Builder
 	status := &k8s.ClusterStatus{
@@ -441,7 +614,7 @@ func TestAnalyzeClusterHealth(t *testing.T) {
 			Error:       "timeout",
 		},
 	}
-	summary := analyzeClusterHealth(statuses)
+	summary := analyzeClusterHealth(statuses, HealthThresholds{})
 
 	if summary.reachableCount != 3 {
 		t.Errorf("reachableCount = %d, want 3", summary.reachableCount)
@@ -453,7 +626,14 @@ func TestAnalyzeClusterHealth(t *testing.T) {
 		t.Errorf("totalUnhealthyPods = %d, want 3", summary.totalUnhealthyPods)
 	}
 	if len(summary.issues) != 3 { // degraded-nodes, unhealthy-pods, down
-		t.Errorf("issues count = %d, want 3: %v", len(summary.issues), summary.issues)
+		t.Fatalf("issues count = %d, want 3: %v", len(summary.issues), summary.issues)
+	}
+	// Sorted by severity: the unreachable cluster first.
+	if summary.issues[0].Cluster != "down" || summary.issues[0].Severity != report.SeverityCritical {
+		t.Errorf("first issue = %+v, want critical issue of down", summary.issues[0])
+	}
+	if summary.issues[1].Severity != report.SeverityWarning || summary.issues[2].Severity != report.SeverityWarning {
+		t.Errorf("one NotReady node and unhealthy pods should be warnings: %+v", summary.issues)
 	}
 }
 
@@ -487,6 +667,24 @@ func TestWriteCompactClusterStatus(t *testing.T) {
 			},
 			wantStr: "⚠️",
 		},
+		{
+			name: "exposure problems",
+			status: k8s.ClusterStatus{
+				ClusterInfo: k8s.ClusterInfo{Context: "prod", IsReachable: true},
+				NodeCount:   1, HealthyNodes: 1, PodCount: 5, HealthyPods: 5,
+				ExposureIssues: []k8s.ExposureIssue{{Namespace: "shop", Kind: "Ingress", Name: "web", Problem: "TLS secret web-tls not found"}},
+			},
+			wantStr: "1 exposed endpoint problem(s), e.g. shop/Ingress/web: TLS secret web-tls not found",
+		},
+		{
+			name: "cost estimate",
+			status: k8s.ClusterStatus{
+				ClusterInfo: k8s.ClusterInfo{Context: "prod", IsReachable: true},
+				NodeCount:   1, HealthyNodes: 1, PodCount: 5, HealthyPods: 5,
+				Cost: &k8s.CostEstimate{Currency: "USD", MonthlyCost: 1234.5},
+			},
+			wantStr: "💰 ~1234.50 USD/month",
+		},
 		{
 			name:    "down",
 			status:  k8s.ClusterStatus{ClusterInfo: k8s.ClusterInfo{Context: "old", IsReachable: false}},
@@ -496,7 +694,7 @@ func TestWriteCompactClusterStatus(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			var b strings.Builder
-			writeCompactClusterStatus(&b, &tc.status)
+			writeCompactClusterStatus(&b, &tc.status, strings.HasPrefix(tc.name, "degraded"))
 			if !strings.Contains(b.String(), tc.wantStr) {
 				t.Errorf("writeCompactClusterStatus(%s) missing %q, got: %s", tc.name, tc.wantStr, b.String())
 			}
@@ -538,7 +736,7 @@ func TestBuildKubectlCommand(t *testing.T) {
 func TestEnforceExecutionModeReadOnly(t *testing.T) {
 	// Write op in read-only mode (JSON output) → blocked with cancel message, no error
 	state := &agentState{mode: ModeReadOnly, outputFormat: OutputJSON}
-	proceed, result, err := enforceExecutionMode(state, false, "prod", "ctx", testCmdDeletePod)
+	proceed, result, err := enforceExecutionMode(context.Background(), state, false, "prod", "ctx", testCmdDeletePod)
 	if proceed || result == nil || err != nil {
 		t.Errorf("write op in read-only (JSON) should be blocked with cancel msg: proceed=%v result=%v err=%v", proceed, result, err)
 	}
@@ -547,7 +745,7 @@ func TestEnforceExecutionModeReadOnly(t *testing.T) {
 	}
 
 	// Read op in read-only mode → allowed
-	proceed, _, err = enforceExecutionMode(state, true, "prod", "ctx", testCmdGetPods)
+	proceed, _, err = enforceExecutionMode(context.Background(), state, true, "prod", "ctx", testCmdGetPods)
 	if !proceed || err != nil {
 		t.Errorf("read op in read-only should be allowed: proceed=%v err=%v", proceed, err)
 	}
@@ -556,7 +754,7 @@ func TestEnforceExecutionModeReadOnly(t *testing.T) {
 func TestEnforceExecutionModeDeniedWriteLatch(t *testing.T) {
 	state := &agentState{mode: ModeReadOnly, outputFormat: OutputJSON, denyWritesUntilNextPrompt: true}
 
-	proceed, result, err := enforceExecutionMode(state, false, "prod", "ctx", testCmdDeletePod)
+	proceed, result, err := enforceExecutionMode(context.Background(), state, false, "prod", "ctx", testCmdDeletePod)
 	if proceed || err != nil {
 		t.Fatalf("latched deny should block write without error: proceed=%v err=%v", proceed, err)
 	}
@@ -569,7 +767,7 @@ func TestEnforceExecutionModeDeniedWriteLatch(t *testing.T) {
 		t.Errorf("unexpected latch message: %q", msg)
 	}
 
-	proceed, _, err = enforceExecutionMode(state, true, "prod", "ctx", testCmdGetPods)
+	proceed, _, err = enforceExecutionMode(context.Background(), state, true, "prod", "ctx", testCmdGetPods)
 	if !proceed || err != nil {
 		t.Errorf("latched deny should not block read-only commands: proceed=%v err=%v", proceed, err)
 	}
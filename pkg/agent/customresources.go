@@ -0,0 +1,216 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the get_custom_resources tool for browsing CRDs and their instances.
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+	"sigs.k8s.io/yaml"
+)
+
+// maxCustomResourceYAML bounds the YAML shown when describing a custom resource.
+const maxCustomResourceYAML = 16 << 10
+
+// GetCustomResourcesParams defines parameters for get_custom_resources
+type GetCustomResourcesParams struct {
+	Context   string `json:"context" jsonschema:"The context name of the cluster to inspect (from list_clusters)"`
+	Resource  string `json:"resource,omitempty" jsonschema:"Custom resource to list: plural, kind, short name or <plural>.<group> (e.g. certificates, Certificate or certificates.cert-manager.io); leave empty to list the installed CRDs"`
+	Namespace string `json:"namespace,omitempty" jsonschema:"Optional: only instances in this namespace (required with name for namespaced resources)"`
+	Name      string `json:"name,omitempty" jsonschema:"Optional: describe this instance (full spec and status) instead of listing"`
+	Group     string `json:"group,omitempty" jsonschema:"Optional: when listing CRDs, only groups containing this text (e.g. istio or cert-manager)"`
+}
+
+// CustomResourceDetail is the JSON result of describing one custom resource.
+type CustomResourceDetail struct {
+	Context    string                  `json:"context"`
+	CRD        k8s.CRDInfo             `json:"crd"`
+	Conditions []k8s.ResourceCondition `json:"conditions,omitempty"`
+	Object     map[string]any          `json:"object"`
+}
+
+func defineGetCustomResourcesTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolGetCustomResources,
+		"Browse custom resources (CRDs) that the other tools cannot see: list the installed CRDs, list the instances of one custom resource with their status conditions (Ready, Synced, ...), or describe one instance with its full spec and status. Works for any operator (cert-manager, Istio, Crossplane, databases, ...). Read-only.",
+		func(params GetCustomResourcesParams, inv llm.ToolInvocation) (any, error) {
+			if _, err := getClusterForContext(k8sProvider, params.Context); err != nil {
+				return nil, err
+			}
+			ctx := inv.Ctx()
+			switch {
+			case params.Resource == "" && params.Name != "":
+				return nil, fmt.Errorf("resource is required with name")
+			case params.Resource == "":
+				crds, err := k8sProvider.ListCRDs(ctx, params.Context, params.Group)
+				if err != nil {
+					return nil, err
+				}
+				if isJSONOutput(state.outputFormat) {
+					return crds, nil
+				}
+				return formatCRDs(params.Context, crds), nil
+			case params.Name != "":
+				crd, obj, err := k8sProvider.GetCustomResource(ctx, params.Context, params.Resource, params.Namespace, params.Name)
+				if err != nil {
+					return nil, err
+				}
+				detail := &CustomResourceDetail{Context: params.Context, CRD: *crd, Conditions: k8s.ResourceConditions(obj), Object: withoutManagedFields(obj.Object)}
+				if isJSONOutput(state.outputFormat) {
+					return detail, nil
+				}
+				return formatCustomResourceDetail(detail)
+			default:
+				list, err := k8sProvider.ListCustomResources(ctx, params.Context, params.Resource, params.Namespace)
+				if err != nil {
+					return nil, err
+				}
+				if isJSONOutput(state.outputFormat) {
+					return list, nil
+				}
+				return formatCustomResourceList(list), nil
+			}
+		},
+	)
+}
+
+// formatCRDs lists CRDs grouped by API group.
+func formatCRDs(contextName string, crds []k8s.CRDInfo) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🧩 %d custom resource definition(s) in %s\n", len(crds), contextName)
+	group := ""
+	for _, c := range crds {
+		if c.Group != group {
+			group = c.Group
+			fmt.Fprintf(&sb, "\n%s:\n", group)
+		}
+		scope := "cluster-scoped"
+		if c.Namespaced {
+			scope = "namespaced"
+		}
+		names := c.Plural
+		if len(c.ShortNames) > 0 {
+			names += ", " + strings.Join(c.ShortNames, ", ")
+		}
+		fmt.Fprintf(&sb, "  %s (%s) %s, %s\n", c.Kind, names, strings.Join(c.Versions, "/"), scope)
+	}
+	return sb.String()
+}
+
+// formatCustomResourceList lists instances with their age, phase and conditions.
+func formatCustomResourceList(list *k8s.CustomResourceList) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📦 %s (%s %s) in %s: %d instance(s)\n\n", list.CRD.Name, list.CRD.Kind, list.CRD.Version, list.Context, len(list.Items))
+	if len(list.Items) == 0 {
+		sb.WriteString("  (none)\n")
+	}
+	for _, item := range list.Items {
+		name := item.Name
+		if item.Namespace != "" {
+			name = item.Namespace + "/" + name
+		}
+		fmt.Fprintf(&sb, "%s %s  age %s", conditionsIcon(item.Conditions), name, time.Since(item.Created).Round(time.Minute))
+		if item.Phase != "" {
+			fmt.Fprintf(&sb, "  phase %s", item.Phase)
+		}
+		for _, c := range item.Conditions {
+			fmt.Fprintf(&sb, "  %s=%s", c.Type, c.Status)
+		}
+		sb.WriteString("\n")
+		for _, c := range item.Conditions {
+			if c.Status == "False" && c.Message != "" {
+				fmt.Fprintf(&sb, "   %s\n", strings.TrimPrefix(c.Reason+": "+c.Message, ": "))
+			}
+		}
+	}
+	if list.Truncated {
+		fmt.Fprintf(&sb, "\n⚠️  Only the first %d instances are listed; narrow the search with namespace.\n", k8s.MaxCustomResources)
+	}
+	return sb.String()
+}
+
+// conditionsIcon flags instances with a False Ready-like condition.
+func conditionsIcon(conditions []k8s.ResourceCondition) string {
+	icon := "•"
+	for _, c := range conditions {
+		switch {
+		case c.Status == "False" && isReadyCondition(c.Type):
+			return "❌"
+		case c.Status == "True" && isReadyCondition(c.Type):
+			icon = "✅"
+		}
+	}
+	return icon
+}
+
+// isReadyCondition reports whether a True condition of this type means the resource is fine.
+func isReadyCondition(conditionType string) bool {
+	switch conditionType {
+	case "Ready", "Available", "Synced", "Healthy", "Established", "Reconciled":
+		return true
+	}
+	return false
+}
+
+// withoutManagedFields copies obj without the bookkeeping metadata that
+// would drown its spec and status.
+func withoutManagedFields(obj map[string]any) map[string]any {
+	clean := deepCopyJSON(obj)
+	if meta, ok := clean["metadata"].(map[string]any); ok {
+		delete(meta, "managedFields")
+		if annotations, ok := meta["annotations"].(map[string]any); ok {
+			delete(annotations, lastAppliedAnnotation)
+			if len(annotations) == 0 {
+				delete(meta, "annotations")
+			}
+		}
+	}
+	return clean
+}
+
+// formatCustomResourceDetail shows the conditions and the YAML of one
+// instance, status included.
+func formatCustomResourceDetail(d *CustomResourceDetail) (string, error) {
+	out, err := yaml.Marshal(d.Object)
+	if err != nil {
+		return "", fmt.Errorf("failed to render YAML: %w", err)
+	}
+	text := string(out)
+	if len(text) > maxCustomResourceYAML {
+		text = text[:maxCustomResourceYAML] + "\n... (truncated)\n"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📄 %s %s in %s\n\n", d.CRD.Kind, manifestName(d.Object), d.Context)
+	if len(d.Conditions) > 0 {
+		sb.WriteString("Conditions:\n")
+		for _, c := range d.Conditions {
+			fmt.Fprintf(&sb, "  %s=%s", c.Type, c.Status)
+			if c.Reason != "" {
+				fmt.Fprintf(&sb, " (%s)", c.Reason)
+			}
+			if !c.LastTransitionTime.IsZero() {
+				fmt.Fprintf(&sb, " since %s", c.LastTransitionTime.UTC().Format(time.RFC3339))
+			}
+			if c.Message != "" {
+				fmt.Fprintf(&sb, ": %s", c.Message)
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(text)
+	return sb.String(), nil
+}
+
+// manifestName returns namespace/name, or name for cluster-scoped objects.
+func manifestName(obj map[string]any) string {
+	meta, _ := obj["metadata"].(map[string]any)
+	name, _ := meta["name"].(string)
+	if namespace, _ := meta["namespace"].(string); namespace != "" {
+		return namespace + "/" + name
+	}
+	return name
+}
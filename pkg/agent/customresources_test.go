@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestFormatCRDs(t *testing.T) {
+	crds := []k8s.CRDInfo{
+		{Name: "certificates.cert-manager.io", Group: "cert-manager.io", Kind: "Certificate", Plural: "certificates", ShortNames: []string{"cert"}, Namespaced: true, Versions: []string{"v1"}},
+		{Name: "clusterissuers.cert-manager.io", Group: "cert-manager.io", Kind: "ClusterIssuer", Plural: "clusterissuers", Versions: []string{"v1"}},
+		{Name: "gateways.networking.istio.io", Group: "networking.istio.io", Kind: "Gateway", Plural: "gateways", Namespaced: true, Versions: []string{"v1beta1", "v1"}},
+	}
+	text := formatCRDs("prod", crds)
+	for _, want := range []string{
+		"3 custom resource definition(s) in prod",
+		"cert-manager.io:\n  Certificate (certificates, cert) v1, namespaced\n  ClusterIssuer (clusterissuers) v1, cluster-scoped",
+		"networking.istio.io:\n  Gateway (gateways) v1beta1/v1, namespaced",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestFormatCustomResourceList(t *testing.T) {
+	list := &k8s.CustomResourceList{
+		Context: "prod",
+		CRD:     k8s.CRDInfo{Name: "certificates.cert-manager.io", Kind: "Certificate", Version: "v1"},
+		Items: []k8s.CustomResource{
+			{Namespace: "shop", Name: "api", Created: time.Now().Add(-2 * time.Hour), Conditions: []k8s.ResourceCondition{
+				{Type: "Ready", Status: "False", Reason: "Failed", Message: "ACME challenge failed"},
+			}},
+			{Namespace: "shop", Name: "web", Created: time.Now().Add(-time.Hour), Phase: "Issued", Conditions: []k8s.ResourceCondition{{Type: "Ready", Status: "True"}}},
+			{Name: "plain", Created: time.Now()},
+		},
+		Truncated: true,
+	}
+	text := formatCustomResourceList(list)
+	for _, want := range []string{
+		"certificates.cert-manager.io (Certificate v1) in prod: 3 instance(s)",
+		"❌ shop/api  age 2h0m0s  Ready=False\n   Failed: ACME challenge failed",
+		"✅ shop/web  age 1h0m0s  phase Issued  Ready=True",
+		"• plain  age 0s",
+		"Only the first 200 instances",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestFormatCustomResourceDetail(t *testing.T) {
+	obj := map[string]any{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "Certificate",
+		"metadata": map[string]any{
+			"name": "web", "namespace": "shop",
+			"managedFields": []any{map[string]any{"manager": "kubectl"}},
+			"annotations":   map[string]any{lastAppliedAnnotation: "{}"},
+		},
+		"spec":   map[string]any{"secretName": "web-tls"},
+		"status": map[string]any{"notAfter": "2025-01-01T00:00:00Z"},
+	}
+	detail := &CustomResourceDetail{
+		Context:    "prod",
+		CRD:        k8s.CRDInfo{Kind: "Certificate"},
+		Conditions: []k8s.ResourceCondition{{Type: "Ready", Status: "True", Reason: "Ready", Message: "up to date", LastTransitionTime: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}},
+		Object:     withoutManagedFields(obj),
+	}
+	text, err := formatCustomResourceDetail(detail)
+	if err != nil {
+		t.Fatalf("formatCustomResourceDetail: %v", err)
+	}
+	for _, want := range []string{
+		"Certificate shop/web in prod",
+		"Ready=True (Ready) since 2024-05-01T10:00:00Z: up to date",
+		"secretName: web-tls",
+		"notAfter:",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "managedFields") || strings.Contains(text, "annotations") {
+		t.Errorf("bookkeeping metadata should be removed:\n%s", text)
+	}
+	if _, ok := obj["metadata"].(map[string]any)["managedFields"]; !ok {
+		t.Error("withoutManagedFields must not modify its input")
+	}
+}
@@ -0,0 +1,534 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains "kopilot dashboard": a terminal UI showing a live health
+// grid of every context beside a read-only chat with the agent.
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+	"github.com/e9169/kopilot/pkg/report"
+)
+
+// DefaultDashboardInterval is how often the dashboard refreshes the grid.
+const DefaultDashboardInterval = 30 * time.Second
+
+// dashboardContextWidth caps the context column of the grid.
+const dashboardContextWidth = 28
+
+// Grid statuses, worst first.
+const (
+	dashboardDown     = "down"
+	dashboardCritical = "critical"
+	dashboardWarning  = "warning"
+	dashboardHealthy  = "healthy"
+)
+
+// dashboardRow is one context of the health grid.
+type dashboardRow struct {
+	Context  string
+	Status   string
+	Nodes    string
+	Pods     string
+	Critical int
+	Warnings int
+}
+
+// Messages of the dashboard program. The chat ones carry the session events.
+type (
+	dashboardTickMsg   struct{}
+	dashboardReportMsg struct{ report *ClusterReport }
+	chatDeltaMsg       struct{ text string }
+	chatMessageMsg     struct{ text string }
+	chatErrorMsg       struct{ text string }
+	chatIdleMsg        struct{}
+)
+
+// chatEntry is one message of the chat pane.
+type chatEntry struct {
+	role string // "user", "assistant" or "error"
+	text string
+}
+
+// dashboardStyles are the lipgloss styles of the dashboard; all plain when
+// colours are off.
+type dashboardStyles struct {
+	title, dim, bold, accent, healthy, warning, critical lipgloss.Style
+	pane                                                 lipgloss.Style
+}
+
+func newDashboardStyles(color bool) dashboardStyles {
+	s := dashboardStyles{
+		title: lipgloss.NewStyle(), dim: lipgloss.NewStyle(), bold: lipgloss.NewStyle(), accent: lipgloss.NewStyle(),
+		healthy: lipgloss.NewStyle(), warning: lipgloss.NewStyle(), critical: lipgloss.NewStyle(),
+		pane: lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, false, false, true).PaddingLeft(1),
+	}
+	if !color {
+		return s
+	}
+	s.title = s.title.Bold(true).Foreground(lipgloss.Color("6"))
+	s.dim = s.dim.Faint(true)
+	s.bold = s.bold.Bold(true)
+	s.accent = s.accent.Foreground(lipgloss.Color("6"))
+	s.healthy = s.healthy.Foreground(lipgloss.Color("2"))
+	s.warning = s.warning.Foreground(lipgloss.Color("3"))
+	s.critical = s.critical.Bold(true).Foreground(lipgloss.Color("1"))
+	s.pane = s.pane.BorderForeground(lipgloss.Color("8"))
+	return s
+}
+
+// dashboardModel is the bubbletea model of the dashboard. check and send are
+// its links to the provider and the AI session, so it can be driven in tests.
+type dashboardModel struct {
+	// check returns a fresh report; force skips the status cache.
+	check    func(force bool) *ClusterReport
+	send     func(prompt string) error
+	interval time.Duration
+	styles   dashboardStyles
+
+	report     *ClusterReport
+	refreshing bool
+	chat       []chatEntry
+	// streaming is set while deltas extend the last chat entry; busy until
+	// the session is idle again.
+	streaming bool
+	busy      bool
+
+	input         textinput.Model
+	view          viewport.Model
+	width, height int
+}
+
+func newDashboardModel(check func(force bool) *ClusterReport, send func(prompt string) error, interval time.Duration, color bool) *dashboardModel {
+	input := textinput.New()
+	input.Prompt = "❯ "
+	input.Placeholder = "Ask about your clusters"
+	input.Focus()
+	return &dashboardModel{
+		check:    check,
+		send:     send,
+		interval: interval,
+		styles:   newDashboardStyles(color),
+		input:    input,
+		view:     viewport.New(0, 0),
+	}
+}
+
+// Init starts the first check and the refresh timer.
+func (m *dashboardModel) Init() tea.Cmd {
+	return tea.Batch(textinput.Blink, m.refresh(false), m.tick())
+}
+
+func (m *dashboardModel) tick() tea.Cmd {
+	return tea.Tick(m.interval, func(time.Time) tea.Msg { return dashboardTickMsg{} })
+}
+
+// refresh checks the clusters in the background, unless a check is running.
+func (m *dashboardModel) refresh(force bool) tea.Cmd {
+	if m.refreshing {
+		return nil
+	}
+	m.refreshing = true
+	check := m.check
+	return func() tea.Msg { return dashboardReportMsg{report: check(force)} }
+}
+
+// Update handles keys, window sizes, reports and session events.
+func (m *dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.layout()
+		return m, nil
+	case tea.KeyMsg:
+		return m.onKey(msg)
+	case tea.MouseMsg:
+		var cmd tea.Cmd
+		m.view, cmd = m.view.Update(msg)
+		return m, cmd
+	case dashboardTickMsg:
+		return m, tea.Batch(m.refresh(false), m.tick())
+	case dashboardReportMsg:
+		m.refreshing = false
+		m.report = msg.report
+		return m, nil
+	case chatDeltaMsg:
+		if !m.streaming {
+			m.chat = append(m.chat, chatEntry{role: "assistant"})
+			m.streaming = true
+		}
+		m.chat[len(m.chat)-1].text += msg.text
+	case chatMessageMsg:
+		if !m.streaming && msg.text != "" {
+			m.chat = append(m.chat, chatEntry{role: "assistant", text: msg.text})
+		}
+		m.streaming = false
+	case chatErrorMsg:
+		m.chat = append(m.chat, chatEntry{role: "error", text: msg.text})
+		m.streaming, m.busy = false, false
+	case chatIdleMsg:
+		m.streaming, m.busy = false, false
+	default:
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return m, cmd
+	}
+	m.updateChat()
+	return m, nil
+}
+
+func (m *dashboardModel) onKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC, tea.KeyEsc:
+		return m, tea.Quit
+	case tea.KeyCtrlR:
+		return m, m.refresh(true)
+	case tea.KeyPgUp:
+		m.view.PageUp()
+		return m, nil
+	case tea.KeyPgDown:
+		m.view.PageDown()
+		return m, nil
+	case tea.KeyEnter:
+		prompt := strings.TrimSpace(m.input.Value())
+		if prompt == "" || m.busy {
+			return m, nil
+		}
+		if isExitCommand(prompt) {
+			return m, tea.Quit
+		}
+		m.input.Reset()
+		m.chat = append(m.chat, chatEntry{role: "user", text: prompt})
+		m.busy, m.streaming = true, false
+		m.updateChat()
+		send := m.send
+		return m, func() tea.Msg {
+			if err := send(prompt); err != nil {
+				return chatErrorMsg{text: err.Error()}
+			}
+			return nil
+		}
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// paneWidths splits the width between the grid and the chat pane, which
+// includes its border and padding.
+func (m *dashboardModel) paneWidths() (grid, chat int) {
+	grid = m.width * 3 / 5
+	return grid, m.width - grid
+}
+
+// layout sizes the chat pane after a resize: the body is the height less
+// the header and footer lines, the transcript the body less the input line.
+func (m *dashboardModel) layout() {
+	_, chat := m.paneWidths()
+	m.view.Width = max(chat-2, 1)
+	m.view.Height = max(m.height-3, 1)
+	m.input.Width = max(chat-5, 1)
+	m.updateChat()
+}
+
+// updateChat renders the transcript into the viewport and scrolls to its end.
+func (m *dashboardModel) updateChat() {
+	m.view.SetContent(m.renderChat(m.view.Width))
+	m.view.GotoBottom()
+}
+
+func (m *dashboardModel) renderChat(width int) string {
+	wrap := lipgloss.NewStyle().Width(max(width, 1))
+	parts := make([]string, 0, len(m.chat)+1)
+	for _, e := range m.chat {
+		switch e.role {
+		case "user":
+			parts = append(parts, wrap.Render(m.styles.accent.Render("You: ")+e.text))
+		case "error":
+			parts = append(parts, wrap.Render(m.styles.critical.Render("Error: ")+e.text))
+		default:
+			parts = append(parts, wrap.Render(m.styles.bold.Render("kopilot: ")+e.text))
+		}
+	}
+	if m.busy && !m.streaming {
+		parts = append(parts, m.styles.dim.Render("Thinking..."))
+	}
+	if len(parts) == 0 {
+		return m.styles.dim.Render(wrap.Render("Ask about the clusters on the left, e.g. \"why is a pod failing?\". The chat is read-only."))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// View draws the header, the grid beside the chat pane, and the key help.
+func (m *dashboardModel) View() string {
+	if m.width == 0 {
+		return "Starting dashboard..."
+	}
+	gridWidth, chatWidth := m.paneWidths()
+	bodyHeight := max(m.height-2, 1)
+
+	title := "kopilot dashboard"
+	var info string
+	switch {
+	case m.report != nil:
+		info = fmt.Sprintf(" · %d context(s) · updated %s · every %s",
+			m.report.Summary.TotalClusters, m.report.GeneratedAt.Local().Format("15:04:05"), m.interval)
+		if m.refreshing {
+			info += " · refreshing"
+		}
+	case m.refreshing:
+		info = " · checking clusters..."
+	}
+	header := m.styles.title.Render(truncateText(title, m.width)) + m.styles.dim.Render(truncateText(info, m.width-len(title)))
+
+	grid := lipgloss.NewStyle().Width(gridWidth).Height(bodyHeight).MaxHeight(bodyHeight).
+		Render(m.renderGrid(gridWidth-1, bodyHeight))
+	chat := m.styles.pane.Width(chatWidth - 1).Height(bodyHeight).MaxHeight(bodyHeight).
+		Render(lipgloss.JoinVertical(lipgloss.Left, m.view.View(), m.input.View()))
+	footer := m.styles.dim.Render(truncateText("enter ask · pgup/pgdn scroll · ctrl+r refresh now · esc quit", m.width))
+	return lipgloss.JoinVertical(lipgloss.Left, header, lipgloss.JoinHorizontal(lipgloss.Top, grid, chat), footer)
+}
+
+// renderGrid draws a row per context, then the focus and the critical and
+// warning issues as far as the height allows.
+func (m *dashboardModel) renderGrid(width, height int) string {
+	if m.report == nil {
+		return m.styles.dim.Render("Checking clusters...")
+	}
+	rows := dashboardRows(m.report)
+	contextWidth := len("CONTEXT")
+	for _, row := range rows {
+		contextWidth = max(contextWidth, len([]rune(row.Context)))
+	}
+	contextWidth = min(contextWidth, dashboardContextWidth)
+
+	var lines []string
+	line := func(s string) { lines = append(lines, truncateText(s, width)) }
+	format := fmt.Sprintf("%%-%ds  %%-8s  %%-7s  %%-9s  %%s", contextWidth)
+	lines = append(lines, m.styles.bold.Render(truncateText(fmt.Sprintf(format, "CONTEXT", "STATUS", "NODES", "PODS", "ISSUES"), width)))
+	for _, row := range rows {
+		issues := ""
+		if row.Critical > 0 || row.Warnings > 0 {
+			issues = fmt.Sprintf("%d crit, %d warn", row.Critical, row.Warnings)
+		}
+		text := truncateText(fmt.Sprintf(format, truncateText(row.Context, contextWidth), row.Status, row.Nodes, row.Pods, issues), width)
+		// Colour the status column only; it starts after the context column.
+		start := contextWidth + 2
+		if len([]rune(text)) > start {
+			runes := []rune(text)
+			end := min(start+len(row.Status), len(runes))
+			text = string(runes[:start]) + m.statusStyle(row.Status).Render(string(runes[start:end])) + string(runes[end:])
+		}
+		lines = append(lines, text)
+	}
+
+	if focus := m.report.Focus; focus != nil {
+		lines = append(lines, "")
+		line(fmt.Sprintf("👉 Focus: %s: %s", focus.Cluster, focus.Message))
+	}
+	var issues []report.Issue
+	for _, issue := range m.report.Issues {
+		if issue.Severity == report.SeverityCritical || issue.Severity == report.SeverityWarning {
+			issues = append(issues, issue)
+		}
+	}
+	// Critical issues first, each severity in report order.
+	rank := func(i report.Issue) int {
+		if i.Severity == report.SeverityCritical {
+			return 0
+		}
+		return 1
+	}
+	slices.SortStableFunc(issues, func(a, b report.Issue) int { return cmp.Compare(rank(a), rank(b)) })
+	if len(issues) > 0 {
+		lines = append(lines, "", m.styles.bold.Render("ISSUES"))
+	}
+	for i, issue := range issues {
+		if len(lines) >= height-1 && i < len(issues)-1 {
+			lines = append(lines, m.styles.dim.Render(fmt.Sprintf("... %d more issue(s)", len(issues)-i)))
+			break
+		}
+		symbol, style := "⚠", m.styles.warning
+		if issue.Severity == report.SeverityCritical {
+			symbol, style = "✗", m.styles.critical
+		}
+		lines = append(lines, style.Render(symbol)+" "+truncateText(issue.Cluster+": "+issue.Message, width-2))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m *dashboardModel) statusStyle(status string) lipgloss.Style {
+	switch status {
+	case dashboardHealthy:
+		return m.styles.healthy
+	case dashboardWarning:
+		return m.styles.warning
+	}
+	return m.styles.critical
+}
+
+// dashboardRows builds the grid rows of a report, in the report's context
+// order. A reachable context's status is its worst unacknowledged issue.
+func dashboardRows(r *ClusterReport) []dashboardRow {
+	rows := make([]dashboardRow, 0, len(r.Clusters))
+	for _, status := range r.Clusters {
+		row := dashboardRow{Context: status.Context, Nodes: "-", Pods: "-"}
+		for _, issue := range r.Issues {
+			if issue.Cluster != status.Context {
+				continue
+			}
+			switch issue.Severity {
+			case report.SeverityCritical:
+				row.Critical++
+			case report.SeverityWarning:
+				row.Warnings++
+			}
+		}
+		switch {
+		case !status.IsReachable:
+			row.Status = dashboardDown
+		case row.Critical > 0:
+			row.Status = dashboardCritical
+		case row.Warnings > 0:
+			row.Status = dashboardWarning
+		default:
+			row.Status = dashboardHealthy
+		}
+		if status.IsReachable {
+			row.Nodes = fmt.Sprintf("%d/%d", status.HealthyNodes, status.NodeCount)
+			row.Pods = fmt.Sprintf("%d/%d", status.HealthyPods, status.PodCount)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// truncateText shortens s to width runes, ending it with "…".
+func truncateText(s string, width int) string {
+	runes := []rune(s)
+	if width <= 0 {
+		return ""
+	}
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// RunDashboard starts the dashboard: a grid of every context refreshed from
+// k8sProvider each interval, beside a chat with the agent. The chat runs in
+// read-only mode, as the dashboard has no room for write confirmations.
+func RunDashboard(k8sProvider *k8s.Provider, provider llm.Provider, interval time.Duration, opts ...Option) error {
+	// Log lines would tear the screen; the dashboard shows errors itself.
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	if interval <= 0 {
+		interval = DefaultDashboardInterval
+	}
+	state := &agentState{
+		outputFormat:    OutputText,
+		quotaPercentage: -1,
+		selectedAgent:   AgentDefault,
+		mcpConfigPath:   DefaultMCPConfigPath(),
+		sessionStart:    time.Now(),
+		providerName:    provider.Name(),
+		in:              strings.NewReader(""),
+		out:             io.Discard,
+	}
+	for _, opt := range opts {
+		opt(state)
+	}
+	state.mode = ModeReadOnly
+	state.subscribeSubsystems()
+	configureContexts(k8sProvider, state.contexts)
+	state.acksPath = DefaultAcksPath()
+	if acks, err := LoadAcks(state.acksPath); err == nil {
+		state.acks = acks
+	}
+	// A shorter interval than the cache TTL would redraw cached statuses.
+	if interval < k8sProvider.CacheTTL() {
+		k8sProvider.SetCacheTTL(interval)
+	}
+	state.portForwards = k8s.NewPortForwardManager(k8sProvider)
+	defer state.portForwards.StopAll()
+	state.jobs = newJobManager(io.Discard)
+	defer state.jobs.cancelAll()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := provider.Start(ctx); err != nil {
+		return err
+	}
+	defer func() { _ = provider.Stop() }()
+	session, err := createSessionWithModel(ctx, provider, k8sProvider, state, modelCostEffective)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = session.Disconnect() }()
+
+	check := func(force bool) *ClusterReport {
+		if force {
+			k8sProvider.ClearCache()
+		}
+		r := newClusterReport(k8sProvider.GetAllClusterStatuses(ctx), state.healthThresholds())
+		state.setLastReport(r)
+		return r
+	}
+	send := func(prompt string) error {
+		prompt += languageHint(state.language, prompt)
+		if err := session.SendPrompt(ctx, prompt); err != nil {
+			return fmt.Errorf("failed to send message: %w", err)
+		}
+		trackTurnModelUsage(state, modelCostEffective)
+		return nil
+	}
+	color := resolveTheme(state.theme, os.Getenv, true) != themes[ThemeNone]
+	program := tea.NewProgram(newDashboardModel(check, send, interval, color),
+		tea.WithAltScreen(), tea.WithMouseCellMotion(), tea.WithContext(ctx))
+
+	session.On(func(event llm.Event) {
+		switch event.Type {
+		case llm.EventDelta:
+			if d, ok := event.Data.(*llm.DeltaData); ok {
+				program.Send(chatDeltaMsg{text: d.Content})
+			}
+		case llm.EventMessage:
+			if d, ok := event.Data.(*llm.MessageData); ok {
+				if d.Content != "" {
+					state.setLastResponse(d.Content)
+				}
+				program.Send(chatMessageMsg{text: d.Content})
+			}
+		case llm.EventError:
+			msg := "(unknown session error)"
+			if d, ok := event.Data.(*llm.ErrorData); ok && d.Message != "" {
+				msg = d.Message
+			}
+			program.Send(chatErrorMsg{text: msg})
+		case llm.EventIdle:
+			program.Send(chatIdleMsg{})
+		case llm.EventUsage:
+			onUsageEvent(event, state)
+		}
+	})
+
+	if _, err := program.Run(); err != nil {
+		return fmt.Errorf("dashboard: %w", err)
+	}
+	return nil
+}
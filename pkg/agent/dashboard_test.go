@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/report"
+)
+
+func testDashboardReport() *ClusterReport {
+	return &ClusterReport{CheckAllClustersResult: CheckAllClustersResult{
+		Summary: CheckAllClustersSummary{TotalClusters: 3},
+		Clusters: []*k8s.ClusterStatus{
+			{ClusterInfo: k8s.ClusterInfo{Context: "prod", IsReachable: true}, NodeCount: 3, HealthyNodes: 2, PodCount: 40, HealthyPods: 38},
+			{ClusterInfo: k8s.ClusterInfo{Context: "staging", IsReachable: true}, NodeCount: 1, HealthyNodes: 1, PodCount: 10, HealthyPods: 10},
+			{ClusterInfo: k8s.ClusterInfo{Context: "dev"}},
+		},
+		Issues: []report.Issue{
+			{Severity: report.SeverityWarning, Cluster: "prod", Message: "1/3 nodes NotReady"},
+			{Severity: report.SeverityCritical, Cluster: "prod", Message: "2 pod(s) CrashLoopBackOff"},
+			{Severity: report.SeverityInfo, Cluster: "staging", Message: "1 pod(s) Pending for less than 2m0s"},
+			{Severity: report.SeverityCritical, Cluster: "dev", Message: "cluster unreachable"},
+		},
+	}}
+}
+
+func TestDashboardRows(t *testing.T) {
+	rows := dashboardRows(testDashboardReport())
+	want := []dashboardRow{
+		{Context: "prod", Status: dashboardCritical, Nodes: "2/3", Pods: "38/40", Critical: 1, Warnings: 1},
+		{Context: "staging", Status: dashboardHealthy, Nodes: "1/1", Pods: "10/10"},
+		{Context: "dev", Status: dashboardDown, Nodes: "-", Pods: "-", Critical: 1},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestDashboardView(t *testing.T) {
+	m := newDashboardModel(func(bool) *ClusterReport { return testDashboardReport() }, nil, DefaultDashboardInterval, false)
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 20})
+	m.Update(dashboardReportMsg{report: testDashboardReport()})
+	view := m.View()
+	for _, want := range []string{"kopilot dashboard", "3 context(s)", "CONTEXT", "prod", "critical", "38/40", "staging", "healthy", "down", "✗ prod: 2 pod(s) CrashLoopBackOff", "⚠ prod: 1/3 nodes NotReady", "Ask about the clusters"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view lacks %q:\n%s", want, view)
+		}
+	}
+	if strings.Contains(view, "Pending") {
+		t.Errorf("view lists an informational issue:\n%s", view)
+	}
+	if lines := strings.Split(view, "\n"); len(lines) != 20 {
+		t.Errorf("view has %d lines, want the terminal height 20", len(lines))
+	}
+}
+
+func TestDashboardRefresh(t *testing.T) {
+	var forced []bool
+	m := newDashboardModel(func(force bool) *ClusterReport {
+		forced = append(forced, force)
+		return testDashboardReport()
+	}, nil, DefaultDashboardInterval, false)
+	cmd := m.refresh(false)
+	if cmd == nil || m.refresh(false) != nil {
+		t.Fatal("refresh should start one check at a time")
+	}
+	m.Update(cmd())
+	if m.refreshing || m.report == nil {
+		t.Fatal("report message should end the refresh")
+	}
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if cmd == nil {
+		t.Fatal("ctrl+r should refresh")
+	}
+	cmd()
+	if len(forced) != 2 || forced[0] || !forced[1] {
+		t.Errorf("checks forced = %v, want [false true]", forced)
+	}
+}
+
+func TestDashboardChat(t *testing.T) {
+	var sent []string
+	m := newDashboardModel(nil, func(prompt string) error {
+		sent = append(sent, prompt)
+		return nil
+	}, DefaultDashboardInterval, false)
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+	m.input.SetValue("  why is prod critical?  ")
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil || !m.busy || m.input.Value() != "" {
+		t.Fatalf("enter should send the prompt (busy %v, input %q)", m.busy, m.input.Value())
+	}
+	cmd()
+	if len(sent) != 1 || sent[0] != "why is prod critical?" {
+		t.Errorf("sent %q", sent)
+	}
+	m.input.SetValue("another")
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil {
+		t.Error("a prompt should wait for the previous answer")
+	}
+
+	m.Update(chatDeltaMsg{text: "Two pods "})
+	m.Update(chatDeltaMsg{text: "crash."})
+	m.Update(chatMessageMsg{text: "Two pods crash."})
+	m.Update(chatIdleMsg{})
+	if m.busy || len(m.chat) != 2 || m.chat[1] != (chatEntry{role: "assistant", text: "Two pods crash."}) {
+		t.Fatalf("streamed answer: busy %v, chat %+v", m.busy, m.chat)
+	}
+
+	// An unstreamed answer arrives whole.
+	m.Update(chatMessageMsg{text: "Done."})
+	m.Update(chatErrorMsg{text: "quota exceeded"})
+	if len(m.chat) != 4 || m.chat[2].text != "Done." || m.chat[3].role != "error" {
+		t.Errorf("chat = %+v", m.chat)
+	}
+	if view := m.View(); !strings.Contains(view, "You: why is prod critical?") || !strings.Contains(view, "Error: quota exceeded") {
+		t.Errorf("chat pane lacks the transcript:\n%s", view)
+	}
+
+	failing := newDashboardModel(nil, func(string) error { return errors.New("session closed") }, DefaultDashboardInterval, false)
+	failing.input.SetValue("hello")
+	_, cmd = failing.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if msg, ok := cmd().(chatErrorMsg); !ok || msg.text != "session closed" {
+		t.Errorf("failed send = %#v, want a chat error", msg)
+	}
+
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc}); cmd == nil {
+		t.Error("esc should quit")
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	for _, tt := range []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"prod", 10, "prod"},
+		{"production-eu", 6, "produ…"},
+		{"prod", 0, ""},
+	} {
+		if got := truncateText(tt.s, tt.width); got != tt.want {
+			t.Errorf("truncateText(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains tool call recording and the /debug bundles built from it.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/llm"
+	"sigs.k8s.io/yaml"
+)
+
+// maxBundleOutput bounds the tool output kept in a debug bundle.
+const maxBundleOutput = 64 * 1024
+
+// redactedValue replaces secrets in debug bundles.
+const redactedValue = "[REDACTED]"
+
+// secretPatterns match credentials that must not leave the machine in a
+// debug bundle; the first group, if any, is kept.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`),
+	regexp.MustCompile(`eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]*`),
+	regexp.MustCompile(`(?i)((?:password|passwd|token|secret|api[_-]?key|client[_-]?secret|private[_-]?key|access[_-]?key)\\?"?\s*[:=]\s*\\?"?)[^"\\\s,}]+`),
+	regexp.MustCompile(`(?i)(--(?:token|password|client-key|client-certificate)[= ])\S+`),
+}
+
+// toolCall is one recorded tool invocation.
+type toolCall struct {
+	Tool     string
+	CallID   string
+	Params   string
+	Output   string
+	Error    string
+	Started  time.Time
+	Duration time.Duration
+}
+
+// recordToolCalls wraps every tool handler to publish EventToolExecuted with
+// the recorded call, so the latest call, and the latest failed one, can be
+// packaged with /debug.
+func recordToolCalls(tools []llm.Tool, state *agentState) []llm.Tool {
+	for i := range tools {
+		name, handler := tools[i].Name, tools[i].Handler
+		tools[i].Handler = func(params any, inv llm.ToolInvocation) (any, error) {
+			args, raw := llm.NormalizeToolArguments(params)
+			call := &toolCall{Tool: name, CallID: inv.ID, Params: raw, Started: time.Now()}
+			result, err := handler(params, inv)
+			call.Duration = time.Since(call.Started)
+			if s, ok := result.(string); ok {
+				call.Output = s
+			} else if result != nil {
+				call.Output = llm.ResultString(result)
+			}
+			if err != nil {
+				call.Error = err.Error()
+			}
+			state.bus.publish(Event{Kind: EventToolExecuted, Time: call.Started, Tool: name, Context: contextArg(args),
+				Duration: call.Duration, Error: call.Error, call: call})
+			return result, err
+		}
+	}
+	return tools
+}
+
+// contextArg returns the "context" argument of a tool call, if any.
+func contextArg(args map[string]any) string {
+	contextName, _ := args["context"].(string)
+	return contextName
+}
+
+// setLastToolCall records call as the latest tool invocation.
+func (s *agentState) setLastToolCall(call *toolCall) {
+	s.toolCallMu.Lock()
+	defer s.toolCallMu.Unlock()
+	s.lastToolCall = call
+	if call.Error != "" {
+		s.lastFailedToolCall = call
+	}
+}
+
+// getLastToolCall returns the latest tool invocation, or the latest failed
+// one when failed is set; nil when there is none.
+func (s *agentState) getLastToolCall(failed bool) *toolCall {
+	s.toolCallMu.Lock()
+	defer s.toolCallMu.Unlock()
+	if failed {
+		return s.lastFailedToolCall
+	}
+	return s.lastToolCall
+}
+
+// DebugBundle describes one tool invocation for a bug report.
+type DebugBundle struct {
+	GeneratedAt     time.Time       `json:"generated_at"`
+	KopilotVersion  string          `json:"kopilot_version"`
+	GoVersion       string          `json:"go_version"`
+	Platform        string          `json:"platform"`
+	AIProvider      string          `json:"ai_provider"`
+	Model           string          `json:"model"`
+	Agent           string          `json:"agent"`
+	Mode            string          `json:"mode"`
+	OutputFormat    string          `json:"output_format"`
+	Tool            string          `json:"tool"`
+	CallID          string          `json:"call_id,omitempty"`
+	Params          json.RawMessage `json:"params"`
+	Output          string          `json:"output"`
+	OutputTruncated bool            `json:"output_truncated,omitempty"`
+	Error           string          `json:"error,omitempty"`
+	StartedAt       time.Time       `json:"started_at"`
+	DurationMS      int64           `json:"duration_ms"`
+	Cluster         *BundleCluster  `json:"cluster,omitempty"`
+}
+
+// BundleCluster identifies the cluster a bundled tool call ran against.
+type BundleCluster struct {
+	Context string `json:"context"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// redactSecrets masks tokens, passwords and keys in s, and the values of
+// the Secrets it contains.
+func redactSecrets(s string) string {
+	s = redactSecretObjects(s)
+	for _, re := range secretPatterns {
+		if re.NumSubexp() > 0 {
+			s = re.ReplaceAllString(s, "${1}"+redactedValue)
+		} else {
+			s = re.ReplaceAllString(s, redactedValue)
+		}
+	}
+	return s
+}
+
+// redactSecretObjects strips the data and stringData values of the Secrets
+// in s, as export_resources does, whatever their keys are named (tls.key,
+// .dockerconfigjson). s is a JSON document, such as a tool's parameters or
+// JSON result, or text whose Kubernetes objects start on a line of their
+// own, such as kubectl output as JSON or YAML after a header. Secrets in the
+// string fields of a JSON document are stripped too. s is returned as is
+// when it holds no Secret.
+func redactSecretObjects(s string) string {
+	if !strings.Contains(s, "Secret") {
+		return s
+	}
+	var doc any
+	if err := json.Unmarshal([]byte(s), &doc); err == nil {
+		if !redactSecretTree(doc) {
+			return s
+		}
+		return marshalLike(s, doc)
+	}
+	for start := 0; start < len(s); {
+		rest := s[start:]
+		if strings.HasPrefix(rest, "{") || strings.HasPrefix(rest, "apiVersion:") {
+			if redacted, ok := redactSecretDocument(rest); ok {
+				return s[:start] + redacted
+			}
+		}
+		next := strings.IndexByte(rest, '\n')
+		if next < 0 {
+			break
+		}
+		start += next + 1
+	}
+	return s
+}
+
+// redactSecretDocument strips the Secrets of the kubectl output text, a JSON
+// object or YAML documents. It reports false when text does not parse or
+// holds no Secret.
+func redactSecretDocument(text string) (string, bool) {
+	if strings.HasPrefix(text, "{") {
+		var doc any
+		if err := json.Unmarshal([]byte(text), &doc); err != nil || !redactSecretTree(doc) {
+			return "", false
+		}
+		return marshalLike(text, doc) + "\n", true
+	}
+	var docs []string
+	changed := false
+	for part := range strings.SplitSeq(text, "\n---\n") {
+		var doc any
+		if err := yaml.Unmarshal([]byte(part), &doc); err != nil {
+			return "", false
+		}
+		if !redactSecretTree(doc) {
+			docs = append(docs, part)
+			continue
+		}
+		data, err := yaml.Marshal(doc)
+		if err != nil {
+			return "", false
+		}
+		docs, changed = append(docs, strings.TrimSuffix(string(data), "\n")), true
+	}
+	if !changed {
+		return "", false
+	}
+	out := strings.Join(docs, "\n---\n")
+	if strings.HasSuffix(text, "\n") && !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	return out, true
+}
+
+// redactSecretTree strips the Secrets in the decoded JSON value v in place:
+// the objects of kind Secret at any depth, such as the items of a list, and
+// those in its string values. It reports whether it changed anything.
+func redactSecretTree(v any) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		if redactSecretData(v) {
+			// The last applied configuration repeats the Secret, values included.
+			if annotations, ok := nestedValue(v, "metadata", "annotations"); ok {
+				if annotations, ok := annotations.(map[string]any); ok && annotations[lastAppliedAnnotation] != nil {
+					annotations[lastAppliedAnnotation] = redactedValue
+				}
+			}
+			changed = true
+		}
+		for k, value := range v {
+			if s, ok := value.(string); ok {
+				if redacted := redactSecretObjects(s); redacted != s {
+					v[k], changed = redacted, true
+				}
+				continue
+			}
+			changed = redactSecretTree(value) || changed
+		}
+	case []any:
+		for i, value := range v {
+			if s, ok := value.(string); ok {
+				if redacted := redactSecretObjects(s); redacted != s {
+					v[i], changed = redacted, true
+				}
+				continue
+			}
+			changed = redactSecretTree(value) || changed
+		}
+	}
+	return changed
+}
+
+// marshalLike encodes v as JSON, indented when the original text is.
+func marshalLike(original string, v any) string {
+	var data []byte
+	if strings.Contains(strings.TrimSpace(original), "\n") {
+		data, _ = json.MarshalIndent(v, "", "    ")
+	} else {
+		data, _ = json.Marshal(v)
+	}
+	return string(data)
+}
+
+// newDebugBundle packages call with the session settings. serverVersion
+// looks up the version of the cluster named by the call's "context"
+// parameter; it may be nil.
+func newDebugBundle(ctx context.Context, state *agentState, model string, call *toolCall, serverVersion func(context.Context, string) (string, error)) *DebugBundle {
+	b := &DebugBundle{
+		GeneratedAt:    time.Now().UTC(),
+		KopilotVersion: AppVersion,
+		GoVersion:      runtime.Version(),
+		Platform:       runtime.GOOS + "/" + runtime.GOARCH,
+		AIProvider:     state.providerName,
+		Model:          model,
+		Agent:          string(state.selectedAgent),
+		Mode:           state.mode.String(),
+		OutputFormat:   string(state.outputFormat),
+		Tool:           call.Tool,
+		CallID:         call.CallID,
+		Params:         json.RawMessage(redactSecrets(call.Params)),
+		Output:         redactSecrets(call.Output),
+		Error:          redactSecrets(call.Error),
+		StartedAt:      call.Started.UTC(),
+		DurationMS:     call.Duration.Milliseconds(),
+	}
+	if !json.Valid(b.Params) {
+		raw, _ := json.Marshal(string(b.Params))
+		b.Params = raw
+	}
+	if r := []rune(b.Output); len(r) > maxBundleOutput {
+		b.Output, b.OutputTruncated = string(r[:maxBundleOutput]), true
+	}
+
+	contextName, _ := llm.ParseToolArgumentsString(call.Params)["context"].(string)
+	if contextName != "" && serverVersion != nil {
+		b.Cluster = &BundleCluster{Context: contextName}
+		if version, err := serverVersion(ctx, contextName); err != nil {
+			b.Cluster.Error = err.Error()
+		} else {
+			b.Cluster.Version = version
+		}
+	}
+	return b
+}
+
+// writeDebugBundle writes b as indented JSON readable only by the user.
+func writeDebugBundle(path string, b *DebugBundle) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding debug bundle: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("writing debug bundle: %w", err)
+	}
+	return nil
+}
+
+// handleDebugCommand processes "/debug last|failed [path]": it writes the
+// latest (or latest failed) tool call as a bundle to attach to bug reports.
+func handleDebugCommand(deps *loopDeps, input string, ts *turnState) (bool, error) {
+	fields := strings.Fields(input)
+	if len(fields) < 2 || len(fields) > 3 || (strings.ToLower(fields[1]) != "last" && strings.ToLower(fields[1]) != "failed") {
+		fmt.Printf("  %s●%s Usage: /debug last|failed [path.json]\n", colorRed, colorReset)
+		return true, nil
+	}
+	failed := strings.ToLower(fields[1]) == "failed"
+	call := deps.state.getLastToolCall(failed)
+	if call == nil {
+		what := "tool call"
+		if failed {
+			what = "failed tool call"
+		}
+		fmt.Printf("  %s●%s No %s recorded in this session yet\n", colorDim, colorReset, what)
+		return true, nil
+	}
+	path := fmt.Sprintf("kopilot-debug-%s-%s.json", call.Tool, call.Started.Format("20060102-150405"))
+	if len(fields) == 3 {
+		path = fields[2]
+	}
+
+	var serverVersion func(context.Context, string) (string, error)
+	if deps.k8sProvider != nil {
+		serverVersion = deps.k8sProvider.GetServerVersion
+	}
+	b := newDebugBundle(deps.ctx, deps.state, ts.model, call, serverVersion)
+	if err := writeDebugBundle(path, b); err != nil {
+		fmt.Printf(fmtErrorBullet, colorRed, colorReset, err)
+		return true, nil
+	}
+	status := "succeeded"
+	if call.Error != "" {
+		status = "failed"
+	}
+	fmt.Printf("  %s●%s Wrote %s%s%s (%s %s in %s; secrets redacted, review before sharing)\n",
+		colorGreen, colorReset, colorCyan, path, colorReset, call.Tool, status, call.Duration.Round(time.Millisecond))
+	return true, nil
+}
@@ -0,0 +1,190 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the dependency_map tool (which workloads talk to which services).
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// DependencyMapParams defines parameters for dependency_map
+type DependencyMapParams struct {
+	Context       string   `json:"context,omitempty" jsonschema:"The context name of the cluster to map (from list_clusters)"`
+	Contexts      []string `json:"contexts,omitempty" jsonschema:"Optional: map several clusters together (overrides context); clusterset.local references are linked across them"`
+	Namespace     string   `json:"namespace,omitempty" jsonschema:"Optional: only map dependencies of this namespace's workloads; leave empty for all non-system namespaces"`
+	Focus         string   `json:"focus,omitempty" jsonschema:"Optional: service or workload to analyze, as namespace/name or kind/namespace/name; shows everything that depends on it (impact) and what it depends on"`
+	Format        string   `json:"format,omitempty" jsonschema:"Output format: text (default), mermaid or dot (Graphviz)"`
+	IncludeSystem bool     `json:"include_system,omitempty" jsonschema:"If true, include system namespaces (kube-system, kube-public, kube-node-lease)"`
+}
+
+// dependencyMapResult is the JSON result of dependency_map.
+type dependencyMapResult struct {
+	*k8s.DependencyMap
+	Focus    string   `json:"focus,omitempty"`
+	Impacted []string `json:"impacted,omitempty"`
+}
+
+func defineDependencyMapTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolDependencyMap,
+		"Map which workloads depend on which services, inferred from service addresses in env vars and ConfigMaps, ExternalName services, Service selectors and NetworkPolicy rules (best-effort: configuration, not observed traffic). With focus, lists everything impacted if that service or workload fails. Can merge several clusters and export Mermaid or DOT diagrams for documentation.",
+		func(params DependencyMapParams, inv llm.ToolInvocation) (any, error) {
+			return handleDependencyMap(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+func handleDependencyMap(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params DependencyMapParams) (any, error) {
+	contexts := params.Contexts
+	if len(contexts) == 0 {
+		if params.Context == "" {
+			return nil, fmt.Errorf("context or contexts is required")
+		}
+		contexts = []string{params.Context}
+	}
+	if params.Namespace != "" && !isValidKubernetesName(params.Namespace) {
+		return nil, fmt.Errorf("invalid namespace name: %s", params.Namespace)
+	}
+	format, err := parseDiagramFormat(params.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	maps := make([]*k8s.DependencyMap, 0, len(contexts))
+	for _, contextName := range contexts {
+		m, err := k8sProvider.GetDependencyMap(ctx, contextName, params.Namespace, params.IncludeSystem)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map dependencies: %w", err)
+		}
+		maps = append(maps, m)
+	}
+	result := &dependencyMapResult{DependencyMap: k8s.MergeDependencyMaps(maps)}
+
+	if params.Focus != "" {
+		node, ok := result.FindNode(params.Focus)
+		if !ok {
+			return nil, fmt.Errorf("%s is not in the dependency map (use namespace/name or kind/namespace/name)", params.Focus)
+		}
+		result.Focus = node.ID
+		result.Impacted = result.Dependents(node.ID)
+		result.DependencyMap = result.DependencyMap.Focus(node.ID)
+	}
+
+	switch {
+	case format != diagramText:
+		return dependencyDiagram(result.DependencyMap).render(format), nil
+	case isJSONOutput(state.outputFormat):
+		return result, nil
+	}
+	return formatDependencyMap(result), nil
+}
+
+// dependencyKindCounts counts workload, service and missing-service nodes.
+func dependencyKindCounts(m *k8s.DependencyMap) (workloads, services, missing int) {
+	for _, n := range m.Nodes {
+		switch {
+		case n.Kind != "Service":
+			workloads++
+		case n.Missing:
+			missing++
+		default:
+			services++
+		}
+	}
+	return workloads, services, missing
+}
+
+// formatDependencyMap formats a dependency map as human-readable text
+func formatDependencyMap(result *dependencyMapResult) string {
+	m := result.DependencyMap
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Dependency Map: %s", strings.Join(m.Contexts, ", "))
+	if m.Namespace != "" {
+		fmt.Fprintf(&sb, " (namespace %s)", m.Namespace)
+	}
+	sb.WriteString("\n" + strings.Repeat("=", 80) + "\n\n")
+
+	workloads, services, missing := dependencyKindCounts(m)
+	fmt.Fprintf(&sb, "📊 SUMMARY: %d workload(s), %d service(s), %d dependencies\n", workloads, services, len(m.Edges))
+	sb.WriteString("   Inferred from configuration (env, ConfigMaps, selectors, NetworkPolicies), not observed traffic.\n\n")
+
+	if result.Focus != "" {
+		fmt.Fprintf(&sb, "🎯 IMPACT: if %s fails, %d dependent(s) are affected\n", result.Focus, len(result.Impacted))
+		for _, id := range result.Impacted {
+			fmt.Fprintf(&sb, "   - %s\n", id)
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(m.Edges) == 0 {
+		sb.WriteString("No dependencies found.\n")
+	} else {
+		sb.WriteString("🔗 DEPENDENCIES:\n")
+		from := ""
+		for _, e := range m.Edges {
+			if e.From != from {
+				from = e.From
+				fmt.Fprintf(&sb, "%s\n", from)
+			}
+			fmt.Fprintf(&sb, "   → %-6s %s  [%s]\n", e.Kind, e.To, strings.Join(e.Evidence, "; "))
+		}
+	}
+
+	if missing > 0 {
+		sb.WriteString("\n⚠️  MISSING SERVICES (referenced but not found):\n")
+		for _, n := range m.Nodes {
+			if n.Missing {
+				fmt.Fprintf(&sb, "   - %s\n", n.ID)
+			}
+		}
+	}
+	if len(m.CrossClusterRefs) > 0 {
+		sb.WriteString("\n🌐 CROSS-CLUSTER REFERENCES (not served by the mapped clusters):\n")
+		for _, r := range m.CrossClusterRefs {
+			fmt.Fprintf(&sb, "   - %s → %s.%s.svc.clusterset.local  [%s]\n", r.From, r.Service, r.Namespace, r.Evidence)
+		}
+		sb.WriteString("   Hint: pass the other clusters in contexts to link them.\n")
+	}
+	return sb.String()
+}
+
+// dependencyNodeLabel is the display label of a node in diagrams.
+func dependencyNodeLabel(n k8s.DependencyNode) string {
+	label := strings.ToLower(n.Kind) + " " + n.Namespace + "/" + n.Name
+	if n.Missing {
+		label += " (missing)"
+	}
+	return label
+}
+
+// dependencyDiagram converts the map for the diagram writers: services are
+// rounded, routes dotted and policy edges bold, one group per context when
+// several clusters are mapped.
+func dependencyDiagram(m *k8s.DependencyMap) *diagram {
+	d := &diagram{Name: "dependencies"}
+	for _, n := range m.Nodes {
+		node := diagramNode{ID: n.ID, Label: dependencyNodeLabel(n), Group: n.Context, Shape: shapeBox}
+		if n.Kind == "Service" {
+			node.Shape = shapeRound
+		}
+		if n.Missing {
+			node.Class = "missing"
+		}
+		d.Nodes = append(d.Nodes, node)
+	}
+	for _, e := range m.Edges {
+		edge := diagramEdge{From: e.From, To: e.To, Label: e.Kind, Style: edgeSolid}
+		switch e.Kind {
+		case k8s.DependencyRoutes:
+			edge.Style = edgeDotted
+		case k8s.DependencyAllows:
+			edge.Style = edgeBold
+		}
+		d.Edges = append(d.Edges, edge)
+	}
+	return d
+}
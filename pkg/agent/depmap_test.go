@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func sampleDependencyMap() *k8s.DependencyMap {
+	return &k8s.DependencyMap{
+		Contexts:  []string{"prod"},
+		Namespace: "shop",
+		Nodes: []k8s.DependencyNode{
+			{ID: "deployment/shop/api", Kind: "Deployment", Namespace: "shop", Name: "api"},
+			{ID: "deployment/shop/db", Kind: "Deployment", Namespace: "shop", Name: "db"},
+			{ID: "service/finance/billing", Kind: "Service", Namespace: "finance", Name: "billing", Missing: true},
+			{ID: "service/shop/db", Kind: "Service", Namespace: "shop", Name: "db"},
+		},
+		Edges: []k8s.DependencyEdge{
+			{From: "deployment/shop/api", To: "service/finance/billing", Kind: k8s.DependencyCalls, Evidence: []string{"env BILLING_URL"}},
+			{From: "deployment/shop/api", To: "service/shop/db", Kind: k8s.DependencyCalls, Evidence: []string{"env DB_HOST"}},
+			{From: "service/shop/db", To: "deployment/shop/db", Kind: k8s.DependencyRoutes, Evidence: []string{"selector app=db"}},
+		},
+		CrossClusterRefs: []k8s.CrossClusterRef{{From: "deployment/shop/api", Namespace: "cache", Service: "redis", Evidence: "env CACHE"}},
+	}
+}
+
+func TestFormatDependencyMap(t *testing.T) {
+	m := sampleDependencyMap()
+	text := formatDependencyMap(&dependencyMapResult{DependencyMap: m, Focus: "service/shop/db", Impacted: m.Dependents("service/shop/db")})
+	for _, want := range []string{
+		"Dependency Map: prod (namespace shop)",
+		"📊 SUMMARY: 2 workload(s), 1 service(s), 3 dependencies",
+		"🎯 IMPACT: if service/shop/db fails, 1 dependent(s) are affected",
+		"   - deployment/shop/api",
+		"   → calls  service/shop/db  [env DB_HOST]",
+		"   → routes deployment/shop/db  [selector app=db]",
+		"MISSING SERVICES",
+		"   - service/finance/billing",
+		"deployment/shop/api → redis.cache.svc.clusterset.local  [env CACHE]",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report missing %q:\n%s", want, text)
+		}
+	}
+
+	empty := formatDependencyMap(&dependencyMapResult{DependencyMap: &k8s.DependencyMap{Contexts: []string{"prod"}}})
+	if !strings.Contains(empty, "No dependencies found.") {
+		t.Errorf("empty map = %q", empty)
+	}
+}
+
+func TestDependencyDiagramMermaid(t *testing.T) {
+	out := dependencyDiagram(sampleDependencyMap()).mermaid()
+	for _, want := range []string{
+		"flowchart LR\n",
+		`  n0["deployment shop/api"]`,
+		`  n3(["service shop/db"])`,
+		`  n2(["service finance/billing (missing)"])`,
+		"  class n2 missing",
+		"  n0 -->|calls| n3",
+		"  n3 -.->|routes| n1",
+		"classDef missing",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("mermaid missing %q:\n%s", want, out)
+		}
+	}
+
+	merged := sampleDependencyMap()
+	for i := range merged.Nodes {
+		merged.Nodes[i].Context = `pr"od`
+	}
+	if out := dependencyDiagram(merged).mermaid(); !strings.Contains(out, `  subgraph c0["pr#quot;od"]`) || !strings.Contains(out, "  end\n") {
+		t.Errorf("mermaid should group contexts in escaped subgraphs:\n%s", out)
+	}
+}
+
+func TestDependencyDiagramDOT(t *testing.T) {
+	m := sampleDependencyMap()
+	for i := range m.Nodes {
+		m.Nodes[i].Context = "prod"
+	}
+	out := dependencyDiagram(m).dot()
+	for _, want := range []string{
+		"digraph dependencies {",
+		`  subgraph cluster_0 {`,
+		`    label="prod";`,
+		`    "service/shop/db" [label="service shop/db", shape=ellipse];`,
+		`    "service/finance/billing" [label="service finance/billing (missing)", shape=ellipse, style=dashed, color=red];`,
+		`  "deployment/shop/api" -> "service/shop/db" [label="calls"];`,
+		`  "service/shop/db" -> "deployment/shop/db" [label="routes", style=dotted];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dot missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestHandleDependencyMapValidation(t *testing.T) {
+	state := &agentState{mode: ModeReadOnly, outputFormat: OutputText}
+	cases := []DependencyMapParams{
+		{},
+		{Context: "test-context", Namespace: "Bad_NS"},
+		{Context: "test-context", Format: "svg"},
+	}
+	for _, params := range cases {
+		if _, err := handleDependencyMap(context.Background(), nil, state, params); err == nil {
+			t.Errorf("handleDependencyMap(context.Background(), %+v) succeeded, want validation error", params)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the Mermaid and Graphviz (DOT) writers shared by graph-like tool outputs.
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diagram formats accepted by tools with graph-like output.
+const (
+	diagramText    = "text"
+	diagramMermaid = "mermaid"
+	diagramDOT     = "dot"
+)
+
+// Node shapes: boxes for workloads and steps, rounded (stadium in Mermaid,
+// ellipse in DOT) for services and start/end markers.
+const (
+	shapeBox   = "box"
+	shapeRound = "round"
+)
+
+// Edge styles.
+const (
+	edgeSolid  = "solid"
+	edgeDotted = "dotted"
+	edgeBold   = "bold"
+)
+
+// diagramClass styles highlighted nodes in both formats.
+type diagramClass struct {
+	mermaid string // classDef body
+	dot     string // extra node attributes
+}
+
+// diagramClasses are the node classes a diagram may use.
+var diagramClasses = map[string]diagramClass{
+	"missing":    {mermaid: "stroke:#d33,stroke-dasharray:4 4", dot: "style=dashed, color=red"},
+	"succeeded":  {mermaid: "stroke:#2a2,stroke-width:2px", dot: "color=green"},
+	"failed":     {mermaid: "stroke:#d33,stroke-width:2px", dot: "color=red, penwidth=2"},
+	"skipped":    {mermaid: "stroke:#999,color:#999", dot: "style=dashed, color=gray, fontcolor=gray"},
+	"rolledback": {mermaid: "stroke:#d90,stroke-dasharray:4 4", dot: "style=dashed, color=orange"},
+}
+
+// diagramClassOrder fixes the order of classDef lines.
+var diagramClassOrder = []string{"missing", "succeeded", "failed", "skipped", "rolledback"}
+
+// diagramNode is one vertex. Group, when set, draws the node inside a
+// subgraph (Mermaid) or cluster (DOT) of that name.
+type diagramNode struct {
+	ID    string
+	Label string
+	Group string
+	Shape string
+	Class string
+}
+
+// diagramEdge is one directed, labelled edge.
+type diagramEdge struct {
+	From  string
+	To    string
+	Label string
+	Style string
+}
+
+// diagram is a directed graph rendered by mermaid and dot.
+type diagram struct {
+	Name  string // DOT graph name
+	Nodes []diagramNode
+	Edges []diagramEdge
+}
+
+// parseDiagramFormat normalizes a format parameter; empty means text.
+func parseDiagramFormat(format string) (string, error) {
+	switch f := strings.ToLower(format); f {
+	case "":
+		return diagramText, nil
+	case diagramText, diagramMermaid, diagramDOT:
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported format %q (supported: text, mermaid, dot)", format)
+}
+
+// render returns the diagram in format, which must be mermaid or dot.
+func (d *diagram) render(format string) string {
+	if format == diagramDOT {
+		return d.dot()
+	}
+	return d.mermaid()
+}
+
+// groups returns the node groups in first-seen order and their nodes.
+func (d *diagram) groups() ([]string, map[string][]diagramNode) {
+	var order []string
+	groups := make(map[string][]diagramNode)
+	for _, n := range d.Nodes {
+		if _, ok := groups[n.Group]; !ok {
+			order = append(order, n.Group)
+		}
+		groups[n.Group] = append(groups[n.Group], n)
+	}
+	return order, groups
+}
+
+// usedClasses returns the classes referenced by nodes, in diagramClassOrder.
+func (d *diagram) usedClasses() []string {
+	used := map[string]bool{}
+	for _, n := range d.Nodes {
+		used[n.Class] = true
+	}
+	var classes []string
+	for _, c := range diagramClassOrder {
+		if used[c] {
+			classes = append(classes, c)
+		}
+	}
+	return classes
+}
+
+// mermaid renders the diagram as a left-to-right Mermaid flowchart.
+func (d *diagram) mermaid() string {
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	ids := make(map[string]string, len(d.Nodes))
+	for i, n := range d.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+	}
+	escape := strings.NewReplacer(`"`, "#quot;").Replace
+
+	order, groups := d.groups()
+	for i, group := range order {
+		indent := "  "
+		if group != "" {
+			fmt.Fprintf(&sb, "  subgraph c%d[\"%s\"]\n", i, escape(group))
+			indent = "    "
+		}
+		for _, n := range groups[group] {
+			open, end := "[\"", "\"]"
+			if n.Shape == shapeRound {
+				open, end = "([\"", "\"])"
+			}
+			fmt.Fprintf(&sb, "%s%s%s%s%s\n", indent, ids[n.ID], open, escape(n.Label), end)
+			if n.Class != "" {
+				fmt.Fprintf(&sb, "%sclass %s %s\n", indent, ids[n.ID], n.Class)
+			}
+		}
+		if group != "" {
+			sb.WriteString("  end\n")
+		}
+	}
+	for _, e := range d.Edges {
+		arrow := "-->"
+		switch e.Style {
+		case edgeDotted:
+			arrow = "-.->"
+		case edgeBold:
+			arrow = "==>"
+		}
+		if e.Label == "" {
+			fmt.Fprintf(&sb, "  %s %s %s\n", ids[e.From], arrow, ids[e.To])
+			continue
+		}
+		fmt.Fprintf(&sb, "  %s %s|%s| %s\n", ids[e.From], arrow, escape(e.Label), ids[e.To])
+	}
+	for _, c := range d.usedClasses() {
+		fmt.Fprintf(&sb, "  classDef %s %s\n", c, diagramClasses[c].mermaid)
+	}
+	return sb.String()
+}
+
+// dotQuote quotes s as a Graphviz ID.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// dot renders the diagram as a Graphviz digraph, one cluster subgraph per group.
+func (d *diagram) dot() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "digraph %s {\n  rankdir=LR;\n  node [shape=box];\n", d.Name)
+	order, groups := d.groups()
+	for i, group := range order {
+		indent := "  "
+		if group != "" {
+			fmt.Fprintf(&sb, "  subgraph cluster_%d {\n    label=%s;\n", i, dotQuote(group))
+			indent = "    "
+		}
+		for _, n := range groups[group] {
+			attrs := "label=" + dotQuote(n.Label)
+			if n.Shape == shapeRound {
+				attrs += ", shape=ellipse"
+			}
+			if class, ok := diagramClasses[n.Class]; ok {
+				attrs += ", " + class.dot
+			}
+			fmt.Fprintf(&sb, "%s%s [%s];\n", indent, dotQuote(n.ID), attrs)
+		}
+		if group != "" {
+			sb.WriteString("  }\n")
+		}
+	}
+	for _, e := range d.Edges {
+		var attrs []string
+		if e.Label != "" {
+			attrs = append(attrs, "label="+dotQuote(e.Label))
+		}
+		switch e.Style {
+		case edgeDotted:
+			attrs = append(attrs, "style=dotted")
+		case edgeBold:
+			attrs = append(attrs, "style=bold")
+		}
+		if len(attrs) == 0 {
+			fmt.Fprintf(&sb, "  %s -> %s;\n", dotQuote(e.From), dotQuote(e.To))
+			continue
+		}
+		fmt.Fprintf(&sb, "  %s -> %s [%s];\n", dotQuote(e.From), dotQuote(e.To), strings.Join(attrs, ", "))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
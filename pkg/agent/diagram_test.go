@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDiagramFormat(t *testing.T) {
+	for in, want := range map[string]string{"": diagramText, "TEXT": diagramText, "Mermaid": diagramMermaid, "dot": diagramDOT} {
+		if got, err := parseDiagramFormat(in); err != nil || got != want {
+			t.Errorf("parseDiagramFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := parseDiagramFormat("svg"); err == nil {
+		t.Error("parseDiagramFormat(svg) should fail")
+	}
+}
+
+func sampleDiagram() *diagram {
+	return &diagram{
+		Name: "sample",
+		Nodes: []diagramNode{
+			{ID: "a", Label: `say "hi"`, Shape: shapeBox},
+			{ID: "b", Label: "b", Group: "g1", Shape: shapeRound, Class: "failed"},
+		},
+		Edges: []diagramEdge{
+			{From: "a", To: "b", Style: edgeSolid},
+			{From: "b", To: "a", Label: "back", Style: edgeBold},
+		},
+	}
+}
+
+func TestDiagramMermaid(t *testing.T) {
+	out := sampleDiagram().render(diagramMermaid)
+	for _, want := range []string{
+		"flowchart LR\n",
+		`  n0["say #quot;hi#quot;"]`,
+		`  subgraph c1["g1"]`,
+		`    n1(["b"])`,
+		"    class n1 failed",
+		"  n0 --> n1\n",
+		"  n1 ==>|back| n0",
+		"  classDef failed ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("mermaid missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "classDef missing") {
+		t.Error("unused classes should not be defined")
+	}
+}
+
+func TestDiagramDOT(t *testing.T) {
+	out := sampleDiagram().render(diagramDOT)
+	for _, want := range []string{
+		"digraph sample {",
+		`  "a" [label="say \"hi\""];`,
+		`    label="g1";`,
+		`    "b" [label="b", shape=ellipse, color=red, penwidth=2];`,
+		`  "a" -> "b";`,
+		`  "b" -> "a" [label="back", style=bold];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dot missing %q:\n%s", want, out)
+		}
+	}
+	if got := dotQuote(`a"b\c`); got != `"a\"b\\c"` {
+		t.Errorf("dotQuote() = %s", got)
+	}
+}
@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	if got := unifiedDiff("a", "b", "x\ny\n", "x\ny\n"); got != "" {
+		t.Errorf("identical inputs should produce no diff, got %q", got)
+	}
+
+	oldText := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	newText := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\nextra\n"
+	want := `--- live
++++ proposed
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -12,3 +12,4 @@
+ l
+ m
+ n
++extra
+`
+	if got := unifiedDiff("live", "proposed", oldText, newText); got != want {
+		t.Errorf("unifiedDiff() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnifiedDiffMergesNearbyHunks(t *testing.T) {
+	got := unifiedDiff("a", "b", "1\n2\n3\n4\n5\n", "1\nX\n3\n4\nY\n")
+	if strings.Count(got, "@@ ") != 1 {
+		t.Errorf("nearby changes should share one hunk:\n%s", got)
+	}
+	if !strings.Contains(got, "@@ -1,5 +1,5 @@") {
+		t.Errorf("unexpected hunk header:\n%s", got)
+	}
+}
+
+func TestUnifiedDiffFromEmpty(t *testing.T) {
+	got := unifiedDiff("live", "proposed", "", "kind: ConfigMap\n")
+	if !strings.Contains(got, "@@ -0,0 +1,1 @@\n+kind: ConfigMap\n") {
+		t.Errorf("creation diff:\n%s", got)
+	}
+}
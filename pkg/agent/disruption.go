@@ -0,0 +1,204 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the check_disruption_safety tool and the safety analysis shown when
+// drains, cordons and pod deletions are confirmed.
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// checkDisruptionSafetyFunc is the provider call behind the safety analysis;
+// a variable so tests can stub the cluster.
+var checkDisruptionSafetyFunc = func(p *k8s.Provider, ctx context.Context, contextName string, target k8s.DisruptionTarget) (*k8s.DisruptionReport, error) {
+	return p.CheckDisruptionSafety(ctx, contextName, target)
+}
+
+// CheckDisruptionSafetyParams defines parameters for check_disruption_safety
+type CheckDisruptionSafetyParams struct {
+	Context   string   `json:"context" jsonschema:"The cluster context name (required)"`
+	Node      string   `json:"node,omitempty" jsonschema:"Node about to be drained or cordoned; every pod on it is checked"`
+	Namespace string   `json:"namespace,omitempty" jsonschema:"Namespace of the pods about to be deleted (defaults to the context namespace)"`
+	Pods      []string `json:"pods,omitempty" jsonschema:"Pods about to be deleted or evicted, instead of a node"`
+}
+
+func defineCheckDisruptionSafetyTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolCheckDisruptionSafety,
+		"Before draining or cordoning a node or deleting pods, report what would break: PodDisruptionBudgets that would block the drain (or be violated by a deletion), workloads whose only replica or all replicas would go down, pods without a controller that are not recreated, and pods losing emptyDir data. Give a node, or a namespace and pod names. Read-only.",
+		func(params CheckDisruptionSafetyParams, inv llm.ToolInvocation) (any, error) {
+			cluster, err := getClusterForContext(k8sProvider, params.Context)
+			if err != nil {
+				return nil, err
+			}
+			target, err := disruptionTargetFromParams(params, cluster.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			report, err := checkDisruptionSafetyFunc(k8sProvider, inv.Ctx(), params.Context, target)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check disruption safety: %w", err)
+			}
+			if isJSONOutput(state.outputFormat) {
+				return report, nil
+			}
+			return formatDisruptionReport(report), nil
+		},
+	)
+}
+
+// disruptionTargetFromParams validates params into a target; pods without a
+// namespace are looked up in defaultNamespace, as kubectl does.
+func disruptionTargetFromParams(params CheckDisruptionSafetyParams, defaultNamespace string) (k8s.DisruptionTarget, error) {
+	switch {
+	case params.Node != "" && len(params.Pods) > 0:
+		return k8s.DisruptionTarget{}, fmt.Errorf("give either a node or pods, not both")
+	case params.Node != "":
+		if !isValidKubernetesName(params.Node) {
+			return k8s.DisruptionTarget{}, fmt.Errorf("invalid node name: %s", params.Node)
+		}
+		return k8s.DisruptionTarget{Node: params.Node}, nil
+	case len(params.Pods) == 0:
+		return k8s.DisruptionTarget{}, fmt.Errorf("a node or pods are required")
+	}
+	target := k8s.DisruptionTarget{Namespace: params.Namespace, Pods: params.Pods}
+	if target.Namespace == "" {
+		target.Namespace = defaultNamespace
+	}
+	if target.Namespace == "" {
+		target.Namespace = "default"
+	}
+	if !isValidKubernetesName(target.Namespace) {
+		return k8s.DisruptionTarget{}, fmt.Errorf("invalid namespace name: %s", target.Namespace)
+	}
+	for _, pod := range target.Pods {
+		if !isValidKubernetesName(pod) {
+			return k8s.DisruptionTarget{}, fmt.Errorf("invalid pod name: %s", pod)
+		}
+	}
+	return target, nil
+}
+
+// disruptionTargetFromArgs recognises the kubectl commands that evict or
+// delete pods: drain and cordon of one node, delete of nodes, and delete of
+// named pods. Selectors and --all are refused by validation or bulk_delete.
+func disruptionTargetFromArgs(args []string, defaultNamespace string) (k8s.DisruptionTarget, bool) {
+	if len(args) == 0 {
+		return k8s.DisruptionTarget{}, false
+	}
+	positional, flags, ambiguous := splitKubectlArgs(args[1:])
+	if ambiguous || len(positional) == 0 {
+		return k8s.DisruptionTarget{}, false
+	}
+	switch args[0] {
+	case "drain", "cordon":
+		if len(positional) != 1 {
+			return k8s.DisruptionTarget{}, false
+		}
+		return k8s.DisruptionTarget{Node: positional[0]}, true
+	case "delete":
+	default:
+		return k8s.DisruptionTarget{}, false
+	}
+
+	kind, names := positional[0], positional[1:]
+	if k, _, found := strings.Cut(kind, "/"); found {
+		kind, names = k, nil
+		for _, p := range positional {
+			pk, n, _ := strings.Cut(p, "/")
+			if pk != kind || n == "" {
+				return k8s.DisruptionTarget{}, false
+			}
+			names = append(names, n)
+		}
+	}
+	if len(names) == 0 {
+		return k8s.DisruptionTarget{}, false
+	}
+	switch kind {
+	case "node", "nodes", "no":
+		if len(names) != 1 {
+			return k8s.DisruptionTarget{}, false
+		}
+		return k8s.DisruptionTarget{Node: names[0]}, true
+	case "pod", "pods", "po":
+		namespace := flags["-n"]
+		if ns := flags["--namespace"]; ns != "" {
+			namespace = ns
+		}
+		target, err := disruptionTargetFromParams(CheckDisruptionSafetyParams{Namespace: namespace, Pods: names}, defaultNamespace)
+		return target, err == nil
+	}
+	return k8s.DisruptionTarget{}, false
+}
+
+// disruptionSafety runs the safety analysis for a confirmation prompt. The
+// analysis never blocks the operation: a failure becomes a line of its own.
+func disruptionSafety(ctx context.Context, k8sProvider *k8s.Provider, contextName string, target k8s.DisruptionTarget, cordon bool) []string {
+	report, err := checkDisruptionSafetyFunc(k8sProvider, ctx, contextName, target)
+	if err != nil {
+		return []string{fmt.Sprintf("eviction safety check failed: %v", err)}
+	}
+	summary := fmt.Sprintf("%d pod(s) affected", report.Pods)
+	if cordon {
+		summary = fmt.Sprintf("cordoning evicts nothing; a later drain affects %d pod(s)", report.Pods)
+	}
+	if report.Safe {
+		summary += ": no blocking PDBs, single-replica workloads or unmanaged pods"
+	}
+	return append([]string{summary}, report.Warnings()...)
+}
+
+// formatDisruptionReport formats a DisruptionReport as human-readable text
+func formatDisruptionReport(r *k8s.DisruptionReport) string {
+	var sb strings.Builder
+	target := "pods"
+	if r.Node != "" {
+		target = "node " + r.Node
+	}
+	fmt.Fprintf(&sb, "Disruption Safety: %s (%s)\n", target, r.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+	fmt.Fprintf(&sb, "Pods evicted or deleted: %d", r.Pods)
+	if r.DaemonSetPods > 0 {
+		fmt.Fprintf(&sb, " (%d DaemonSet pod(s) stay)", r.DaemonSetPods)
+	}
+	sb.WriteString("\n\n")
+
+	if len(r.PDBViolations) > 0 {
+		sb.WriteString("🚧 PODDISRUPTIONBUDGETS:\n")
+		for _, v := range r.PDBViolations {
+			fmt.Fprintf(&sb, "  - %s/%s allows %d, covers %s\n", v.Namespace, v.Name, v.DisruptionsAllowed, strings.Join(v.Pods, ", "))
+		}
+		sb.WriteString("\n")
+	}
+	if len(r.AtRisk) > 0 {
+		sb.WriteString("⚠️  WORKLOADS LOSING EVERY REPLICA:\n")
+		for _, w := range r.AtRisk {
+			fmt.Fprintf(&sb, "  - %s/%s (%d running): %s\n", w.Namespace, w.Workload, w.Replicas, strings.Join(w.Pods, ", "))
+		}
+		sb.WriteString("\n")
+	}
+	for _, line := range []struct {
+		title string
+		pods  []string
+	}{
+		{"🧍 NOT RECREATED (no controller)", r.Unmanaged},
+		{"💾 EMPTYDIR DATA LOST", r.LocalStorage},
+		{"❓ NOT FOUND", r.Missing},
+	} {
+		if len(line.pods) > 0 {
+			fmt.Fprintf(&sb, "%s: %s\n\n", line.title, strings.Join(line.pods, ", "))
+		}
+	}
+
+	if r.Safe {
+		sb.WriteString("✅ Safe: no blocking PDBs, single-replica workloads or unmanaged pods\n")
+	} else {
+		sb.WriteString("❌ Not safe as is: scale up, relax the PDB, or accept the downtime before proceeding\n")
+	}
+	return sb.String()
+}
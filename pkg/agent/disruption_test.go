@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+// stubDisruptionSafety replaces the provider call with a fixed report and
+// records the targets it was asked about.
+func stubDisruptionSafety(t *testing.T, report *k8s.DisruptionReport, err error) *[]k8s.DisruptionTarget {
+	t.Helper()
+	original := checkDisruptionSafetyFunc
+	t.Cleanup(func() { checkDisruptionSafetyFunc = original })
+	var targets []k8s.DisruptionTarget
+	checkDisruptionSafetyFunc = func(_ *k8s.Provider, _ context.Context, _ string, target k8s.DisruptionTarget) (*k8s.DisruptionReport, error) {
+		targets = append(targets, target)
+		return report, err
+	}
+	return &targets
+}
+
+func unsafeDrainReport() *k8s.DisruptionReport {
+	return &k8s.DisruptionReport{
+		Context: "prod", Node: "node-1", Pods: 4, DaemonSetPods: 2,
+		PDBViolations: []k8s.PDBViolation{{Namespace: "shop", Name: "web", DisruptionsAllowed: 0, Pods: []string{"web-1"}}},
+		AtRisk:        []k8s.WorkloadAtRisk{{Namespace: "shop", Workload: "deployment/cart", Replicas: 1, Pods: []string{"cart-1"}}},
+		Unmanaged:     []string{"shop/debug"},
+	}
+}
+
+func TestDisruptionTargetFromArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string // node or namespace/pods; empty when not recognised
+	}{
+		{[]string{"drain", "node-1", "--ignore-daemonsets"}, "node-1"},
+		{[]string{"cordon", "node-1"}, "node-1"},
+		{[]string{"delete", "node", "node-1"}, "node-1"},
+		{[]string{"delete", "nodes/node-1"}, "node-1"},
+		{[]string{"delete", "pod", "a", "b", "-n", "shop"}, "shop/a,b"},
+		{[]string{"delete", "pod/a", "pod/b", "--namespace=shop"}, "shop/a,b"},
+		{[]string{"delete", "po", "a"}, "team/a"},
+		{[]string{"delete", "pod/a", "deploy/b"}, ""},
+		{[]string{"delete", "pods", "-l", "app=web"}, ""},
+		{[]string{"delete", "deployment", "web"}, ""},
+		{[]string{"uncordon", "node-1"}, ""},
+		{[]string{"drain", "-l", "pool=old"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		target, ok := disruptionTargetFromArgs(tt.args, "team")
+		got := ""
+		if ok {
+			got = target.Node
+			if got == "" {
+				got = target.Namespace + "/" + strings.Join(target.Pods, ",")
+			}
+		}
+		if got != tt.want {
+			t.Errorf("disruptionTargetFromArgs(%v) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestDisruptionTargetFromParams(t *testing.T) {
+	tests := []struct {
+		params  CheckDisruptionSafetyParams
+		wantErr string
+	}{
+		{CheckDisruptionSafetyParams{Node: "node-1"}, ""},
+		{CheckDisruptionSafetyParams{Pods: []string{"web-1"}}, ""},
+		{CheckDisruptionSafetyParams{}, "a node or pods are required"},
+		{CheckDisruptionSafetyParams{Node: "n", Pods: []string{"p"}}, "not both"},
+		{CheckDisruptionSafetyParams{Node: "bad;name"}, "invalid node name"},
+		{CheckDisruptionSafetyParams{Namespace: "BAD", Pods: []string{"p"}}, "invalid namespace name"},
+		{CheckDisruptionSafetyParams{Pods: []string{"p q"}}, "invalid pod name"},
+	}
+	for _, tt := range tests {
+		_, err := disruptionTargetFromParams(tt.params, "")
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("disruptionTargetFromParams(%+v) error = %v, want %q", tt.params, err, tt.wantErr)
+		}
+	}
+	target, _ := disruptionTargetFromParams(CheckDisruptionSafetyParams{Pods: []string{"p"}}, "")
+	if target.Namespace != "default" {
+		t.Errorf("namespace = %q, want default", target.Namespace)
+	}
+}
+
+func TestDisruptionSafety(t *testing.T) {
+	stubDisruptionSafety(t, unsafeDrainReport(), nil)
+	lines := disruptionSafety(context.Background(), nil, "prod", k8s.DisruptionTarget{Node: "node-1"}, false)
+	if len(lines) != 4 || lines[0] != "4 pod(s) affected" || !strings.Contains(lines[1], "PDB shop/web") {
+		t.Errorf("lines = %q", lines)
+	}
+
+	stubDisruptionSafety(t, &k8s.DisruptionReport{Node: "node-1", Pods: 3, Safe: true}, nil)
+	lines = disruptionSafety(context.Background(), nil, "prod", k8s.DisruptionTarget{Node: "node-1"}, true)
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "cordoning evicts nothing; a later drain affects 3 pod(s): no blocking PDBs") {
+		t.Errorf("lines = %q", lines)
+	}
+
+	stubDisruptionSafety(t, nil, errors.New("forbidden"))
+	if lines = disruptionSafety(context.Background(), nil, "prod", k8s.DisruptionTarget{Node: "node-1"}, false); len(lines) != 1 || !strings.Contains(lines[0], "safety check failed: forbidden") {
+		t.Errorf("lines = %q", lines)
+	}
+}
+
+func TestKubectlExecDrainShowsSafety(t *testing.T) {
+	stubBulkKubectl(t, "")
+	targets := stubDisruptionSafety(t, unsafeDrainReport(), nil)
+	state := approvingState()
+	if _, err := handleKubectlExec(context.Background(), newTestK8sProvider(t), state, KubectlExecParams{
+		Context: "test-context", Args: []string{"drain", "node-1", "--ignore-daemonsets"},
+	}); err != nil {
+		t.Fatalf("handleKubectlExec: %v", err)
+	}
+	if len(*targets) != 1 || (*targets)[0].Node != "node-1" {
+		t.Errorf("targets = %+v, want node-1", *targets)
+	}
+	req := state.approver.(*stubApprover).req
+	if len(req.Safety) != 4 || !strings.Contains(strings.Join(req.Safety, "\n"), "shop/deployment/cart loses its only replica") {
+		t.Errorf("approval safety = %q", req.Safety)
+	}
+
+	// Read-only commands are not analysed.
+	if _, err := handleKubectlExec(context.Background(), newTestK8sProvider(t), state, KubectlExecParams{
+		Context: "test-context", Args: []string{"get", "pods"},
+	}); err != nil {
+		t.Fatalf("handleKubectlExec: %v", err)
+	}
+	if len(*targets) != 1 {
+		t.Errorf("read-only command ran the safety analysis")
+	}
+}
+
+func TestTerminalApproverShowsSafety(t *testing.T) {
+	var out strings.Builder
+	a := &terminalApprover{in: strings.NewReader("no\n"), out: &out}
+	req := ApprovalRequest{Command: "kubectl drain node-1", Safety: []string{"PDB shop/web allows 0 disruption(s)"}}
+	if _, err := a.Approve(context.Background(), req); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	if !strings.Contains(out.String(), "PDB shop/web allows 0 disruption(s)") {
+		t.Errorf("prompt does not show the safety analysis:\n%s", out.String())
+	}
+}
+
+func TestFormatDisruptionReport(t *testing.T) {
+	out := formatDisruptionReport(unsafeDrainReport())
+	for _, want := range []string{
+		"Disruption Safety: node node-1 (prod)",
+		"Pods evicted or deleted: 4 (2 DaemonSet pod(s) stay)",
+		"shop/web allows 0, covers web-1",
+		"shop/deployment/cart (1 running): cart-1",
+		"NOT RECREATED (no controller): shop/debug",
+		"❌ Not safe as is",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if out := formatDisruptionReport(&k8s.DisruptionReport{Context: "prod", Pods: 1, Safe: true}); !strings.Contains(out, "✅ Safe") {
+		t.Errorf("safe report:\n%s", out)
+	}
+}
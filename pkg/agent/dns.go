@@ -0,0 +1,114 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the check_dns tool (CoreDNS health and optional in-cluster lookup).
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// CheckDNSParams defines parameters for check_dns
+type CheckDNSParams struct {
+	Context   string `json:"context" jsonschema:"The cluster context name (required)"`
+	Probe     bool   `json:"probe,omitempty" jsonschema:"If true, resolve a name from inside the cluster (requires confirmation)"`
+	Lookup    string `json:"lookup,omitempty" jsonschema:"Name to resolve when probing; defaults to kubernetes.default.svc.<cluster domain>"`
+	Namespace string `json:"namespace,omitempty" jsonschema:"Namespace to probe from (default: default)"`
+	DebugPod  string `json:"debug_pod,omitempty" jsonschema:"Existing pod (with nslookup) to run the lookup in; if empty, a temporary busybox pod is started"`
+}
+
+// CheckDNSResult wraps the DNS report with the optional lookup outcome.
+type CheckDNSResult struct {
+	*k8s.DNSReport
+	Lookup *k8s.ConnectivityCheck `json:"lookup,omitempty"`
+}
+
+func defineCheckDNSTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolCheckDNS,
+		"Check cluster DNS health: CoreDNS/kube-dns pod readiness and restarts, the kube-dns Service and its endpoints, and Corefile sanity (kubernetes, forward, cache, loop, health and ready plugins). Optionally resolves a name from inside the cluster with a temporary pod or an existing debug pod. Use when names do not resolve or lookups time out.",
+		func(params CheckDNSParams, inv llm.ToolInvocation) (any, error) {
+			return handleCheckDNS(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+func handleCheckDNS(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params CheckDNSParams) (any, error) {
+	if params.Context == "" {
+		return nil, fmt.Errorf("context is required")
+	}
+	if params.Namespace == "" {
+		params.Namespace = "default"
+	}
+	for _, name := range []string{params.Namespace, params.DebugPod} {
+		if name != "" && !isValidKubernetesName(name) {
+			return nil, fmt.Errorf("invalid resource name: %s", name)
+		}
+	}
+	if params.Lookup != "" && !isValidKubernetesName(strings.TrimSuffix(params.Lookup, ".")) {
+		return nil, fmt.Errorf("invalid lookup name: %s", params.Lookup)
+	}
+	cluster, err := getClusterForContext(k8sProvider, params.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := k8sProvider.CheckDNS(ctx, params.Context)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check DNS: %w", err)
+	}
+	result := CheckDNSResult{DNSReport: report}
+
+	if params.Probe {
+		name := params.Lookup
+		if name == "" {
+			name = "kubernetes.default.svc." + report.ClusterDomain
+		}
+		probe, cancelResult, err := runDNSProbe(ctx, state, toolCheckDNS, cluster.Name, params.Context, params.Namespace, params.DebugPod, name)
+		if err != nil {
+			return nil, err
+		}
+		if cancelResult != nil {
+			probe = &k8s.ConnectivityCheck{Name: "dns-probe", Status: k8s.CheckSkip, Detail: fmt.Sprint(cancelResult)}
+		}
+		result.Lookup = probe
+		if probe.Status == k8s.CheckFail {
+			result.Healthy = false
+		}
+	}
+
+	if isJSONOutput(state.outputFormat) {
+		return result, nil
+	}
+	return formatDNSResult(result), nil
+}
+
+// formatDNSResult formats a CheckDNSResult as human-readable text
+func formatDNSResult(r CheckDNSResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "DNS Health: %s (domain %s, service IP %s)\n", r.Context, r.ClusterDomain, orNone(r.ServiceIP))
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	checks := r.Checks
+	if r.Lookup != nil {
+		checks = append(append([]k8s.ConnectivityCheck{}, checks...), *r.Lookup)
+	}
+	sb.WriteString("🔍 CHECKS:\n")
+	for _, c := range checks {
+		fmt.Fprintf(&sb, "  %s %-15s %s\n", connectivityCheckIcons[c.Status], c.Name, c.Detail)
+	}
+
+	if r.Healthy {
+		sb.WriteString("\n✅ VERDICT: cluster DNS looks healthy")
+		if r.Lookup == nil {
+			sb.WriteString(" (set probe to test a lookup from inside the cluster)")
+		}
+		sb.WriteString("\n")
+	} else {
+		sb.WriteString("\n❌ VERDICT: it's DNS — fix the checks marked ❌ above\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestHandleCheckDNSValidation(t *testing.T) {
+	state := &agentState{outputFormat: OutputJSON}
+	provider := newTestK8sProvider(t)
+	for _, params := range []CheckDNSParams{
+		{},
+		{Context: "test-context", DebugPod: "bad;pod"},
+		{Context: "test-context", Lookup: "example.com; rm -rf /"},
+	} {
+		if _, err := handleCheckDNS(context.Background(), provider, state, params); err == nil {
+			t.Errorf("handleCheckDNS(context.Background(), %+v) should fail validation", params)
+		}
+	}
+}
+
+func TestRunDNSProbeInDebugPod(t *testing.T) {
+	original := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = original })
+	var gotArgs []string
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
+		gotArgs = args
+		return []byte("Name:\tkubernetes.default.svc.cluster.local\nAddress: 10.96.0.1\n"), nil
+	}
+
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON, approver: &stubApprover{name: "test", decision: ApprovalDecision{Approved: true}}}
+	probe, cancelResult, err := runDNSProbe(context.Background(), state, toolCheckDNS, "test-cluster", "test-context", "tools", "netshoot", "kubernetes.default.svc.cluster.local")
+	if err != nil || cancelResult != nil || probe.Status != k8s.CheckOK {
+		t.Fatalf("probe = %+v, %v, %v", probe, cancelResult, err)
+	}
+	cmd := strings.Join(gotArgs, " ")
+	if !strings.Contains(cmd, "exec netshoot -n tools -- nslookup kubernetes.default.svc.cluster.local") || strings.Contains(cmd, "run ") {
+		t.Errorf("unexpected command %q", cmd)
+	}
+}
+
+func TestFormatDNSResult(t *testing.T) {
+	result := CheckDNSResult{DNSReport: &k8s.DNSReport{
+		Context: "prod", ClusterDomain: "cluster.local", ServiceIP: "10.96.0.10",
+		Checks: []k8s.ConnectivityCheck{
+			{Name: "dns-pods", Status: k8s.CheckOK, Detail: "2/2 DNS pod(s) Ready"},
+			{Name: "dns-endpoints", Status: k8s.CheckFail, Detail: "no ready endpoints"},
+		},
+	}}
+	text := formatDNSResult(result)
+	for _, want := range []string{"DNS Health: prod (domain cluster.local, service IP 10.96.0.10)", "✅ dns-pods", "❌ dns-endpoints", "VERDICT: it's DNS"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+
+	result.Healthy = true
+	result.Lookup = &k8s.ConnectivityCheck{Name: "dns-probe", Status: k8s.CheckOK, Detail: "resolves"}
+	text = formatDNSResult(result)
+	if !strings.Contains(text, "✅ dns-probe") || strings.Contains(text, "set probe") {
+		t.Errorf("unexpected healthy output:\n%s", text)
+	}
+}
@@ -0,0 +1,210 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the drain_node tool: confirmed node drains through the eviction API.
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+const maxDrainTimeoutSeconds = 3600
+
+// drainNodeFunc is the provider call behind drain_node; a variable so tests
+// can stub the cluster.
+var drainNodeFunc = func(p *k8s.Provider, ctx context.Context, contextName, node string, opts k8s.DrainOptions) (*k8s.DrainResult, error) {
+	return p.DrainNode(ctx, contextName, node, opts)
+}
+
+// DrainNodeParams defines parameters for drain_node
+type DrainNodeParams struct {
+	Context            string `json:"context" jsonschema:"The cluster context name (required)"`
+	Node               string `json:"node" jsonschema:"The node to drain"`
+	GracePeriodSeconds *int   `json:"grace_period_seconds,omitempty" jsonschema:"Optional: termination grace period for the evicted pods; omit to keep each pod's own"`
+	TimeoutSeconds     int    `json:"timeout_seconds,omitempty" jsonschema:"How long to wait for all evictions, including waits on PodDisruptionBudgets (default 300, max 3600)"`
+	DeleteEmptyDirData bool   `json:"delete_emptydir_data,omitempty" jsonschema:"Allow evicting pods with emptyDir volumes, whose data is lost"`
+	Force              bool   `json:"force,omitempty" jsonschema:"Allow evicting pods without a controller, which are not recreated"`
+	UncordonOnFailure  bool   `json:"uncordon_on_failure,omitempty" jsonschema:"If the drain fails or times out, make the node schedulable again"`
+	Background         bool   `json:"background,omitempty" jsonschema:"Run the drain as a background job after confirmation so the chat continues; the user follows it with /jobs and cancels it with /cancel"`
+}
+
+func defineDrainNodeTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolDrainNode,
+		"Drain a node: cordon it, then evict its pods through the eviction API so PodDisruptionBudgets are respected (evictions a budget refuses are retried until the timeout). DaemonSet and static pods stay. Always asks for confirmation and shows the eviction safety analysis first; progress is shown per pod. Optionally uncordons the node when the drain fails, or runs in the background for long drains. Prefer this over kubectl drain.",
+		func(params DrainNodeParams, inv llm.ToolInvocation) (any, error) {
+			return handleDrainNode(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+// validateDrainNodeParams checks the node name and the limits.
+func validateDrainNodeParams(params *DrainNodeParams) error {
+	if params.Context == "" || params.Node == "" {
+		return fmt.Errorf("context and node are required")
+	}
+	if !isValidKubernetesName(params.Node) {
+		return fmt.Errorf("invalid node name: %s", params.Node)
+	}
+	if params.TimeoutSeconds == 0 {
+		params.TimeoutSeconds = int(k8s.DefaultDrainTimeout / time.Second)
+	}
+	if params.TimeoutSeconds < 1 || params.TimeoutSeconds > maxDrainTimeoutSeconds {
+		return fmt.Errorf("timeout_seconds must be between 1 and %d", maxDrainTimeoutSeconds)
+	}
+	if g := params.GracePeriodSeconds; g != nil && (*g < 0 || *g > maxDrainTimeoutSeconds) {
+		return fmt.Errorf("grace_period_seconds must be between 0 and %d", maxDrainTimeoutSeconds)
+	}
+	return nil
+}
+
+// drainCommand describes the drain in the approval prompt.
+func drainCommand(params DrainNodeParams) string {
+	cmd := fmt.Sprintf("drain node %s on %s (eviction API, timeout %ds", params.Node, params.Context, params.TimeoutSeconds)
+	if params.GracePeriodSeconds != nil {
+		cmd += fmt.Sprintf(", grace period %ds", *params.GracePeriodSeconds)
+	}
+	for _, flag := range []struct {
+		set  bool
+		name string
+	}{
+		{params.DeleteEmptyDirData, "delete emptyDir data"},
+		{params.Force, "force"},
+		{params.UncordonOnFailure, "uncordon on failure"},
+	} {
+		if flag.set {
+			cmd += ", " + flag.name
+		}
+	}
+	return cmd + ")"
+}
+
+func handleDrainNode(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params DrainNodeParams) (any, error) {
+	if err := validateDrainNodeParams(&params); err != nil {
+		return nil, err
+	}
+	cluster, err := getClusterForContext(k8sProvider, params.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	command := drainCommand(params)
+	req := ApprovalRequest{
+		Tool: toolDrainNode, Cluster: cluster.Name, Context: params.Context, Command: command,
+		Safety: disruptionSafety(ctx, k8sProvider, params.Context, k8s.DisruptionTarget{Node: params.Node}, false),
+	}
+	proceed, cancelResult, err := enforceApproval(ctx, state, false, req)
+	if err != nil {
+		return nil, err
+	}
+	if !proceed {
+		return cancelResult, nil
+	}
+	printExecutionHeader(state, false, command)
+
+	opts := k8s.DrainOptions{
+		Timeout:            time.Duration(params.TimeoutSeconds) * time.Second,
+		DeleteEmptyDirData: params.DeleteEmptyDirData,
+		Force:              params.Force,
+		UncordonOnFailure:  params.UncordonOnFailure,
+	}
+	if params.GracePeriodSeconds != nil {
+		grace := int64(*params.GracePeriodSeconds)
+		opts.GracePeriodSeconds = &grace
+	}
+	if params.Background {
+		return runInBackground(state, toolDrainNode, fmt.Sprintf("drain node %s on %s", params.Node, params.Context),
+			func(ctx context.Context, progress func(string)) (string, error) {
+				opts.Progress = func(e k8s.DrainEvent) { progress(drainEventLine(e)) }
+				result, err := runDrain(ctx, k8sProvider, state, params, req, opts)
+				if err != nil {
+					return "", err
+				}
+				return formatDrainResult(result), errorOf(result.Error)
+			})
+	}
+	if !isJSONOutput(state.outputFormat) {
+		opts.Progress = func(e k8s.DrainEvent) { printDrainEvent(state.stdout(), e) }
+	}
+	result, err := runDrain(ctx, k8sProvider, state, params, req, opts)
+	if err != nil {
+		return nil, err
+	}
+	if isJSONOutput(state.outputFormat) {
+		return result, nil
+	}
+	return formatDrainResult(result), nil
+}
+
+// runDrain drains the node of the approved req and records the uncordon that
+// undoes it.
+func runDrain(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params DrainNodeParams, req ApprovalRequest, opts k8s.DrainOptions) (*k8s.DrainResult, error) {
+	result, err := drainNodeFunc(k8sProvider, ctx, params.Context, params.Node, opts)
+	state.publishWrite(req, err)
+	if err != nil {
+		return nil, err
+	}
+	if result.Cordoned && !result.Uncordoned {
+		state.undo.push(undoRecord{
+			Time: time.Now(), Context: params.Context, Command: req.Command,
+			Kind: "node", Name: params.Node, Action: undoUncordon,
+		})
+	}
+	return result, nil
+}
+
+// printDrainEvent streams one drain event to w.
+func printDrainEvent(w io.Writer, e k8s.DrainEvent) {
+	fmt.Fprintf(w, "\r\033[K%s   %s%s\n", colorDim, drainEventLine(e), colorReset)
+}
+
+// drainEventLine describes one drain event.
+func drainEventLine(e k8s.DrainEvent) string {
+	subject := e.Pod
+	if subject == "" {
+		subject = "node"
+	}
+	line := fmt.Sprintf("[%d/%d] %s %s", e.Done, e.Total, subject, e.Status)
+	if e.Message != "" {
+		line += ": " + e.Message
+	}
+	return line
+}
+
+// formatDrainResult formats a DrainResult as human-readable text
+func formatDrainResult(r *k8s.DrainResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Drain: node %s (%s), %s\n", r.Node, r.Context, r.Duration)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+	if r.Cordoned {
+		sb.WriteString("🚧 Node cordoned\n")
+	} else {
+		sb.WriteString("🚧 Node was already cordoned\n")
+	}
+	fmt.Fprintf(&sb, "✅ EVICTED: %d pod(s)\n", len(r.Evicted))
+	for _, pod := range r.Evicted {
+		fmt.Fprintf(&sb, "  - %s\n", pod)
+	}
+	if len(r.Skipped) > 0 {
+		fmt.Fprintf(&sb, "⏭️  LEFT ON NODE (DaemonSet/static): %s\n", strings.Join(r.Skipped, ", "))
+	}
+	if r.Error != "" {
+		fmt.Fprintf(&sb, "\n❌ FAILED: %s\n", r.Error)
+		for _, pod := range r.Failed {
+			fmt.Fprintf(&sb, "  - %s\n", pod)
+		}
+		if r.Uncordoned {
+			sb.WriteString("↩️  Node uncordoned again (uncordon_on_failure)\n")
+		} else if r.Cordoned {
+			sb.WriteString("ℹ️  The node stays cordoned; undo_last_operation uncordons it\n")
+		}
+		return sb.String()
+	}
+	sb.WriteString("\n✅ Node drained. It stays cordoned until uncordoned (undo_last_operation).\n")
+	return sb.String()
+}
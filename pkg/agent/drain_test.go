@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+// stubDrainNode replaces the provider call with a fixed result and records
+// the options it was given.
+func stubDrainNode(t *testing.T, result *k8s.DrainResult) *k8s.DrainOptions {
+	t.Helper()
+	original := drainNodeFunc
+	t.Cleanup(func() { drainNodeFunc = original })
+	var got k8s.DrainOptions
+	drainNodeFunc = func(_ *k8s.Provider, _ context.Context, _, _ string, opts k8s.DrainOptions) (*k8s.DrainResult, error) {
+		got = opts
+		return result, nil
+	}
+	return &got
+}
+
+func TestValidateDrainNodeParams(t *testing.T) {
+	grace, negative := 30, -1
+	tests := []struct {
+		params DrainNodeParams
+		want   string
+	}{
+		{DrainNodeParams{Context: "c", Node: "node-1"}, ""},
+		{DrainNodeParams{Context: "c", Node: "node-1", GracePeriodSeconds: &grace, TimeoutSeconds: 60}, ""},
+		{DrainNodeParams{Context: "c"}, "context and node are required"},
+		{DrainNodeParams{Context: "c", Node: "node_1;"}, "invalid node name"},
+		{DrainNodeParams{Context: "c", Node: "node-1", TimeoutSeconds: 7200}, "timeout_seconds"},
+		{DrainNodeParams{Context: "c", Node: "node-1", GracePeriodSeconds: &negative}, "grace_period_seconds"},
+	}
+	for _, tt := range tests {
+		err := validateDrainNodeParams(&tt.params)
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("validateDrainNodeParams(%+v) = %v, want %q", tt.params, err, tt.want)
+		}
+	}
+	p := DrainNodeParams{Context: "c", Node: "node-1"}
+	if err := validateDrainNodeParams(&p); err != nil || p.TimeoutSeconds != 300 {
+		t.Errorf("default timeout = %d (err %v), want 300", p.TimeoutSeconds, err)
+	}
+}
+
+func TestDrainCommand(t *testing.T) {
+	grace := 10
+	got := drainCommand(DrainNodeParams{Context: "prod", Node: "node-1", TimeoutSeconds: 300, GracePeriodSeconds: &grace, Force: true, UncordonOnFailure: true})
+	want := "drain node node-1 on prod (eviction API, timeout 300s, grace period 10s, force, uncordon on failure)"
+	if got != want {
+		t.Errorf("drainCommand() = %q, want %q", got, want)
+	}
+}
+
+func TestHandleDrainNode(t *testing.T) {
+	stubDisruptionSafety(t, unsafeDrainReport(), nil)
+	opts := stubDrainNode(t, &k8s.DrainResult{Context: "test-context", Node: "node-1", Cordoned: true, Evicted: []string{"shop/web-1"}})
+	state := approvingState()
+	grace := 15
+
+	out, err := handleDrainNode(context.Background(), newTestK8sProvider(t), state, DrainNodeParams{
+		Context: "test-context", Node: "node-1", GracePeriodSeconds: &grace, TimeoutSeconds: 60, UncordonOnFailure: true,
+	})
+	if err != nil {
+		t.Fatalf("handleDrainNode: %v", err)
+	}
+	if result := out.(*k8s.DrainResult); len(result.Evicted) != 1 {
+		t.Errorf("result = %+v", result)
+	}
+	req := state.approver.(*stubApprover).req
+	if req.Tool != toolDrainNode || len(req.Safety) != 4 {
+		t.Errorf("approval request = %+v, want drain_node with the safety analysis", req)
+	}
+	if opts.Timeout != time.Minute || *opts.GracePeriodSeconds != 15 || !opts.UncordonOnFailure || opts.Progress != nil {
+		t.Errorf("options = %+v", *opts)
+	}
+	if record, ok := state.undo.last(); !ok || record.Action != undoUncordon || record.Name != "node-1" {
+		t.Errorf("undo record = %+v, want uncordon node-1", record)
+	}
+}
+
+func TestHandleDrainNodeDenied(t *testing.T) {
+	stubDisruptionSafety(t, &k8s.DisruptionReport{Safe: true}, nil)
+	original := drainNodeFunc
+	t.Cleanup(func() { drainNodeFunc = original })
+	drainNodeFunc = func(*k8s.Provider, context.Context, string, string, k8s.DrainOptions) (*k8s.DrainResult, error) {
+		t.Fatal("the drain ran without approval")
+		return nil, nil
+	}
+
+	state := approvingState()
+	state.approver = &stubApprover{name: "test", decision: ApprovalDecision{Approved: false}}
+	if _, err := handleDrainNode(context.Background(), newTestK8sProvider(t), state, DrainNodeParams{Context: "test-context", Node: "node-1"}); err != nil {
+		t.Fatalf("handleDrainNode: %v", err)
+	}
+}
+
+func TestFormatDrainResult(t *testing.T) {
+	out := formatDrainResult(&k8s.DrainResult{
+		Context: "prod", Node: "node-1", Cordoned: true, Duration: "42s",
+		Evicted: []string{"shop/web-1"}, Skipped: []string{"kube-system/fluentd-x"},
+	})
+	for _, want := range []string{"Drain: node node-1 (prod), 42s", "Node cordoned", "EVICTED: 1 pod(s)", "fluentd-x", "Node drained"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	out = formatDrainResult(&k8s.DrainResult{
+		Node: "node-1", Cordoned: true, Uncordoned: true,
+		Failed: []string{"shop/db-0"}, Error: "1 pod(s) could not be evicted: timed out",
+	})
+	for _, want := range []string{"FAILED: 1 pod(s) could not be evicted: timed out", "  - shop/db-0", "Node uncordoned again"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains Engine, the API for embedding kopilot's health checks and
+// tools in other Go programs.
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// Engine runs kopilot's health checks and Kubernetes tools without the
+// interactive CLI or an LLM provider, for programs that embed kopilot. The
+// tool set is the one served by RunMCPServer; session tools such as
+// port_forward, exec_in_pod and chaos are left out.
+//
+// An Engine is read-only and returns structured results by default. Write
+// tools need WithMode(ModeInteractive) and WithApprover, so confirmations go
+// to the embedding program; without an Approver they are read from
+// WithInput and fail when there is none. What tools print while they run
+// goes to WithOutput, or is discarded.
+//
+// Engine, its options and the types it returns are the stable API of this
+// package; everything else may change between releases.
+type Engine struct {
+	k8sProvider *k8s.Provider
+	state       *agentState
+	tools       []llm.Tool
+	byName      map[string]llm.Tool
+}
+
+// NewEngine returns an Engine for the clusters of k8sProvider.
+func NewEngine(k8sProvider *k8s.Provider, opts ...Option) *Engine {
+	state := &agentState{
+		mode:            ModeReadOnly,
+		outputFormat:    OutputJSON,
+		quotaPercentage: -1,
+		in:              strings.NewReader(""),
+		out:             io.Discard,
+	}
+	for _, opt := range opts {
+		opt(state)
+	}
+	state.subscribeSubsystems()
+	configureContexts(k8sProvider, state.contexts)
+
+	tools := recordToolCalls(withToolErrors(withRetries(withSchemaLimits(defineK8sTools(k8sProvider, state), k8sProvider), state), k8sProvider), state)
+	state.setTools(tools)
+	byName := make(map[string]llm.Tool, len(tools))
+	for _, t := range tools {
+		byName[t.Name] = t
+	}
+	return &Engine{k8sProvider: k8sProvider, state: state, tools: tools, byName: byName}
+}
+
+// Tools returns the tool definitions, with their JSON schemas, for
+// registering with an LLM framework or an MCP server.
+func (e *Engine) Tools() []llm.Tool {
+	return append([]llm.Tool(nil), e.tools...)
+}
+
+// CallTool runs the named tool with JSON-style arguments, as an LLM would
+// call it. The result is a string for OutputText, otherwise the tool's
+// result struct.
+func (e *Engine) CallTool(ctx context.Context, name string, args map[string]any) (any, error) {
+	t, ok := e.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown tool %q", name)
+	}
+	if args == nil {
+		args = map[string]any{}
+	}
+	return t.Handler(args, llm.ToolInvocation{Name: name, Context: ctx})
+}
+
+// Subscribe calls fn for every event of the given kinds, or of every kind
+// when none are given: tool calls, health issues that appear or go away
+// between CheckClusters (or check_all_clusters) runs, and performed writes.
+// Events are delivered synchronously on the goroutine that caused them, so
+// fn should return quickly and must not call the Engine. The returned
+// function cancels the subscription.
+func (e *Engine) Subscribe(fn func(Event), kinds ...EventKind) (unsubscribe func()) {
+	return e.state.bus.subscribe(fn, kinds...)
+}
+
+// CheckClusters checks every cluster in parallel and returns the report
+// check_all_clusters is built from: per-cluster status, detected issues with
+// their severity, and a summary. Like the tool, it records the result in the
+// health history.
+func (e *Engine) CheckClusters(ctx context.Context) *ClusterReport {
+	r := newClusterReport(e.k8sProvider.GetAllClusterStatuses(ctx), e.state.healthThresholds())
+	e.state.recordClusterReport(r)
+	return r
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestEngineTools(t *testing.T) {
+	engine := NewEngine(newTestK8sProvider(t))
+	tools := engine.Tools()
+	if want := len(defineK8sTools(newTestK8sProvider(t), &agentState{})); len(tools) != want {
+		t.Errorf("Tools() returned %d tools, want the %d served over MCP", len(tools), want)
+	}
+	tools[0].Name = "changed"
+	if engine.Tools()[0].Name == "changed" {
+		t.Error("Tools() exposes the engine's own slice")
+	}
+
+	out, err := engine.CallTool(context.Background(), toolListClusters, nil)
+	if err != nil {
+		t.Fatalf("CallTool(list_clusters): %v", err)
+	}
+	if _, ok := out.(string); ok {
+		t.Errorf("list_clusters returned text %q, want a structured result by default", out)
+	}
+	if _, err := engine.CallTool(context.Background(), "exec_in_pod", nil); err == nil || !strings.Contains(err.Error(), "unknown tool") {
+		t.Errorf("session tool error = %v, want unknown tool", err)
+	}
+}
+
+func TestEngineWritesAreReadOnlyByDefault(t *testing.T) {
+	restarted, _ := stubRollout(t, nil)
+	engine := NewEngine(newTestK8sProvider(t))
+	out, err := engine.CallTool(context.Background(), toolRestartWorkload, map[string]any{"context": "test-context", "name": "api"})
+	if err != nil || *restarted != "" || !strings.Contains(out.(string), "blocked in read-only mode") {
+		t.Errorf("restart in a read-only engine = %v, %v (restarted %q)", out, err, *restarted)
+	}
+}
+
+func TestEngineApproverAndOutput(t *testing.T) {
+	restarted, _ := stubRollout(t, &k8s.RolloutStatus{Kind: k8s.KindDeployment, Name: "api", Done: true})
+	approver := &stubApprover{name: "platform", decision: ApprovalDecision{Approved: true, Approver: "platform"}}
+	var printed bytes.Buffer
+	engine := NewEngine(newTestK8sProvider(t), WithMode(ModeInteractive), WithApprover(approver), WithOutputFormat(OutputText), WithOutput(&printed))
+
+	out, err := engine.CallTool(context.Background(), toolRestartWorkload, map[string]any{"context": "test-context", "name": "api", "no_wait": true})
+	if err != nil || *restarted != "default/Deployment/api" {
+		t.Fatalf("restart = %v, %v (restarted %q)", out, err, *restarted)
+	}
+	if approver.req.Tool != toolRestartWorkload {
+		t.Errorf("approval request = %+v", approver.req)
+	}
+	if text := printed.String(); !strings.Contains(text, "Waiting for platform approval") || !strings.Contains(text, "Executing:") {
+		t.Errorf("printed output = %q", text)
+	}
+}
+
+func TestEngineTerminalInput(t *testing.T) {
+	restarted, _ := stubRollout(t, nil)
+	var printed bytes.Buffer
+	engine := NewEngine(newTestK8sProvider(t), WithMode(ModeInteractive), WithInput(strings.NewReader("yes\nno\n")), WithOutput(&printed))
+	args := map[string]any{"context": "test-context", "name": "api", "no_wait": true}
+
+	if _, err := engine.CallTool(context.Background(), toolRestartWorkload, args); err != nil || *restarted == "" {
+		t.Fatalf("first restart: %v (restarted %q)", err, *restarted)
+	}
+	*restarted = ""
+	out, err := engine.CallTool(context.Background(), toolRestartWorkload, args)
+	if err != nil || *restarted != "" || out != operationCancelledMessage {
+		t.Errorf("second restart, answered no = %v, %v (restarted %q)", out, err, *restarted)
+	}
+	if !strings.Contains(printed.String(), "Do you want to proceed?") {
+		t.Errorf("printed output = %q", printed.String())
+	}
+
+	// Without input or an approver confirmations fail instead of reading stdin.
+	engine = NewEngine(newTestK8sProvider(t), WithMode(ModeInteractive))
+	if _, err := engine.CallTool(context.Background(), toolRestartWorkload, args); err == nil {
+		t.Error("confirmation without input succeeded")
+	}
+}
+
+func TestEngineCheckClusters(t *testing.T) {
+	t.Setenv("KOPILOT_HEALTH_HISTORY", "off")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := NewEngine(newTestK8sProvider(t)).CheckClusters(ctx)
+	if r.Summary.TotalClusters != 1 || r.Summary.Reachable != 0 {
+		t.Errorf("summary = %+v, want one unreachable cluster", r.Summary)
+	}
+}
+
+func TestEngineSubscribe(t *testing.T) {
+	stubRollout(t, nil)
+	approver := &stubApprover{name: "platform", decision: ApprovalDecision{Approved: true, Approver: "platform"}}
+	engine := NewEngine(newTestK8sProvider(t), WithMode(ModeInteractive), WithApprover(approver))
+
+	var kinds []EventKind
+	var write Event
+	unsubscribe := engine.Subscribe(func(e Event) {
+		kinds = append(kinds, e.Kind)
+		if e.Kind == EventWritePerformed {
+			write = e
+		}
+	}, EventWritePerformed, EventToolExecuted)
+
+	if _, err := engine.CallTool(context.Background(), toolRestartWorkload, map[string]any{"context": "test-context", "name": "api", "no_wait": true}); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	if len(kinds) != 2 || kinds[0] != EventWritePerformed || kinds[1] != EventToolExecuted {
+		t.Errorf("events = %v, want the write then the tool call", kinds)
+	}
+	if write.Tool != toolRestartWorkload || write.Context != "test-context" || write.Error != "" || !strings.Contains(write.Message, "api") {
+		t.Errorf("write event = %+v", write)
+	}
+
+	unsubscribe()
+	if _, err := engine.CallTool(context.Background(), toolListClusters, nil); err != nil {
+		t.Fatalf("list_clusters: %v", err)
+	}
+	if len(kinds) != 2 {
+		t.Errorf("events after unsubscribing = %v", kinds)
+	}
+}
@@ -0,0 +1,101 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the rank_event_noise tool (noisiest event sources per namespace).
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+const (
+	defaultEventWindow = "1h"
+	defaultEventTop    = 15
+	maxEventTop        = 100
+)
+
+// RankEventNoiseParams defines parameters for rank_event_noise
+type RankEventNoiseParams struct {
+	Context       string `json:"context" jsonschema:"The context name of the cluster to inspect (from list_clusters)"`
+	Namespace     string `json:"namespace,omitempty" jsonschema:"Optional: restrict the ranking to a specific namespace"`
+	Window        string `json:"window,omitempty" jsonschema:"How far back to look, e.g. 30m, 6h or 1d (default 1h; events are usually kept for about 1h)"`
+	IncludeNormal bool   `json:"include_normal,omitempty" jsonschema:"If true, count Normal events as well as Warnings"`
+	Top           int    `json:"top,omitempty" jsonschema:"Number of sources to return (default 15, max 100)"`
+}
+
+func defineRankEventNoiseTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolRankEventNoise,
+		"Aggregate event counts by reason per namespace over a time window and rank the noisiest sources (e.g. a FailedScheduling flood), flagging reasons that hit many objects as systemic and hinting at the usual cause. Use before digging into individual pods to find the systemic problem behind many symptoms.",
+		func(params RankEventNoiseParams, inv llm.ToolInvocation) (any, error) {
+			if params.Namespace != "" && !isValidKubernetesName(params.Namespace) {
+				return nil, fmt.Errorf("invalid namespace name: %s", params.Namespace)
+			}
+			if params.Window == "" {
+				params.Window = defaultEventWindow
+			}
+			window, err := parseWindow(params.Window)
+			if err != nil {
+				return nil, err
+			}
+			if params.Top == 0 {
+				params.Top = defaultEventTop
+			}
+			if params.Top < 1 || params.Top > maxEventTop {
+				return nil, fmt.Errorf("top must be between 1 and %d", maxEventTop)
+			}
+
+			report, err := k8sProvider.RankEventNoise(inv.Ctx(), params.Context, params.Namespace, window, !params.IncludeNormal, params.Top)
+			if err != nil {
+				return nil, fmt.Errorf("failed to rank events: %w", err)
+			}
+			if isJSONOutput(state.outputFormat) {
+				return report, nil
+			}
+			return formatEventNoiseReport(report, params.Window), nil
+		},
+	)
+}
+
+// formatEventNoiseReport formats an EventNoiseReport as human-readable text
+func formatEventNoiseReport(report *k8s.EventNoiseReport, window string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Event Noise: %s (last %s)\n", report.Context, window)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	if report.Total == 0 {
+		sb.WriteString("✅ No matching events in the window.\n")
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "📊 SUMMARY: %d occurrence(s) across %d namespace(s)\n\n", report.Total, len(report.Namespaces))
+	sb.WriteString("📂 NOISIEST NAMESPACES:\n")
+	for i, ns := range report.Namespaces {
+		if i == 5 {
+			fmt.Fprintf(&sb, "   ... and %d more namespace(s)\n", len(report.Namespaces)-5)
+			break
+		}
+		fmt.Fprintf(&sb, "   %-30s %6d  (top: %s)\n", orNone(ns.Namespace), ns.Count, ns.TopReason)
+	}
+
+	sb.WriteString("\n🔊 NOISIEST SOURCES:\n")
+	for i, s := range report.Sources {
+		icon := "⚠️ "
+		if s.Type != "Warning" {
+			icon = "ℹ️ "
+		}
+		fmt.Fprintf(&sb, "%2d. %s %s/%s  ×%d on %d object(s) [%s], last %s ago\n", i+1, icon, orNone(s.Namespace), s.Reason, s.Count, s.Objects,
+			strings.Join(s.Kinds, ", "), time.Since(s.LastSeen).Round(time.Second))
+		if s.Systemic {
+			sb.WriteString("       SYSTEMIC: many objects share this reason — look for one cause, not per-object fixes\n")
+		}
+		if s.Hint != "" {
+			fmt.Fprintf(&sb, "       Hint: %s\n", s.Hint)
+		}
+		fmt.Fprintf(&sb, "       Latest: %s\n", strings.TrimSpace(s.Sample))
+	}
+	return sb.String()
+}
@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestFormatEventNoiseReport(t *testing.T) {
+	report := &k8s.EventNoiseReport{
+		Context: "prod", Total: 67,
+		Namespaces: []k8s.NamespaceEventNoise{{Namespace: "shop", Count: 67, Warnings: 67, TopReason: "FailedScheduling"}},
+		Sources: []k8s.EventSource{
+			{Namespace: "shop", Reason: "FailedScheduling", Type: "Warning", Count: 60, Objects: 6, Kinds: []string{"Pod"},
+				LastSeen: time.Now(), Sample: "0/3 nodes are available\n", Systemic: true, Hint: "pods cannot be placed"},
+			{Namespace: "shop", Reason: "Unhealthy", Type: "Warning", Count: 7, Objects: 1, Kinds: []string{"Pod"}, LastSeen: time.Now(), Sample: "probe failed"},
+		},
+	}
+	text := formatEventNoiseReport(report, "1h")
+	for _, want := range []string{
+		"Event Noise: prod (last 1h)",
+		"SUMMARY: 67 occurrence(s) across 1 namespace(s)",
+		"(top: FailedScheduling)",
+		"1. ⚠️  shop/FailedScheduling  ×60 on 6 object(s) [Pod]",
+		"SYSTEMIC",
+		"Hint: pods cannot be placed",
+		"Latest: 0/3 nodes are available\n",
+		"2. ⚠️  shop/Unhealthy  ×7 on 1 object(s)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Count(text, "SYSTEMIC") != 1 {
+		t.Errorf("only the first source is systemic:\n%s", text)
+	}
+
+	empty := formatEventNoiseReport(&k8s.EventNoiseReport{Context: "prod"}, "30m")
+	if !strings.Contains(empty, "No matching events") {
+		t.Errorf("empty report = %q", empty)
+	}
+}
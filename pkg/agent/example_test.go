@@ -0,0 +1,32 @@
+package agent_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/e9169/kopilot/pkg/agent"
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+// Embed the health checks and tools in another program.
+func ExampleNewEngine() {
+	provider, err := k8s.NewProvider(os.Getenv("KUBECONFIG"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	engine := agent.NewEngine(provider, agent.WithOutput(os.Stderr))
+
+	ctx := context.Background()
+	report := engine.CheckClusters(ctx)
+	fmt.Printf("%d/%d clusters reachable\n", report.Summary.Reachable, report.Summary.TotalClusters)
+
+	jobs, err := engine.CallTool(ctx, "check_jobs", map[string]any{"context": provider.GetCurrentContext()})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, f := range jobs.(*k8s.JobsReport).Findings {
+		fmt.Println(f.Issue, f.Namespace, f.Name)
+	}
+}
@@ -0,0 +1,214 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains cluster status report export for /export and the
+// "kopilot check" command.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/report"
+	"sigs.k8s.io/yaml"
+)
+
+// Report formats, chosen from the file extension.
+const (
+	reportJSON     = "json"
+	reportYAML     = "yaml"
+	reportMarkdown = "markdown"
+	reportHTML     = "html"
+)
+
+// ClusterReport is a check_all_clusters snapshot written by /export and --report.
+type ClusterReport struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	CheckAllClustersResult
+}
+
+// newClusterReport builds a report from the statuses of one check_all_clusters run.
+func newClusterReport(statuses []*k8s.ClusterStatus, thresholds HealthThresholds) *ClusterReport {
+	summary := analyzeClusterHealth(statuses, thresholds)
+	return &ClusterReport{
+		GeneratedAt: time.Now().UTC(),
+		CheckAllClustersResult: CheckAllClustersResult{
+			Summary: CheckAllClustersSummary{
+				TotalClusters: len(statuses),
+				Reachable:     summary.reachableCount,
+				FullyHealthy:  summary.healthyCount,
+				UnhealthyPods: summary.totalUnhealthyPods,
+				Critical:      summary.countIssues(report.SeverityCritical),
+				Warnings:      summary.countIssues(report.SeverityWarning),
+				Info:          summary.countIssues(report.SeverityInfo),
+				Acknowledged:  summary.acknowledged,
+			},
+			Issues:   summary.issues,
+			Focus:    summary.focus,
+			Clusters: statuses,
+		},
+	}
+}
+
+// setLastReport records the latest check_all_clusters result for /export
+// and returns the one it replaces.
+func (s *agentState) setLastReport(r *ClusterReport) *ClusterReport {
+	s.reportMu.Lock()
+	defer s.reportMu.Unlock()
+	prev := s.lastReport
+	s.lastReport = r
+	return prev
+}
+
+// getLastReport returns the latest check_all_clusters result, or nil.
+func (s *agentState) getLastReport() *ClusterReport {
+	s.reportMu.Lock()
+	defer s.reportMu.Unlock()
+	return s.lastReport
+}
+
+// reportFormatForPath picks the report format from the file extension.
+func reportFormatForPath(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return reportJSON, nil
+	case ".yaml", ".yml":
+		return reportYAML, nil
+	case ".md", ".markdown":
+		return reportMarkdown, nil
+	case ".html", ".htm":
+		return reportHTML, nil
+	}
+	return "", fmt.Errorf("unsupported report extension %q (use .json, .yaml, .md or .html)", filepath.Ext(path))
+}
+
+// renderClusterReport encodes r in the given format; Markdown and HTML use
+// the full report layout of generate_report.
+func renderClusterReport(r *ClusterReport, format string) ([]byte, error) {
+	switch format {
+	case reportJSON:
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode report: %w", err)
+		}
+		return append(data, '\n'), nil
+	case reportYAML:
+		data, err := yaml.Marshal(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode report: %w", err)
+		}
+		return data, nil
+	case reportMarkdown:
+		return report.Render(report.New("", r.Clusters, r.GeneratedAt), report.FormatMarkdown)
+	case reportHTML:
+		return report.Render(report.New("", r.Clusters, r.GeneratedAt), report.FormatHTML)
+	}
+	return nil, fmt.Errorf("unsupported report format %q", format)
+}
+
+// WriteClusterReport writes r to path in the format of its extension.
+func WriteClusterReport(path string, r *ClusterReport) error {
+	format, err := reportFormatForPath(path)
+	if err != nil {
+		return err
+	}
+	data, err := renderClusterReport(r, format)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// CheckClusters checks the given contexts once, all clusters when empty, like
+// check_all_clusters, and records the result in the health history when
+// KOPILOT_HEALTH_HISTORY enables it; it backs "kopilot check". An unknown context is reported as unreachable.
+func CheckClusters(ctx context.Context, k8sProvider *k8s.Provider, contexts []string, thresholds HealthThresholds) *ClusterReport {
+	var statuses []*k8s.ClusterStatus
+	if len(contexts) == 0 {
+		statuses = k8sProvider.GetAllClusterStatuses(ctx)
+	} else {
+		statuses = k8sProvider.GetClusterStatuses(ctx, contexts)
+	}
+	r := newClusterReport(statuses, thresholds)
+	recordHealthHistory(r)
+	return r
+}
+
+// ValidateReportPath reports an error when path has no supported report
+// extension, so a batch check can fail before contacting any cluster.
+func ValidateReportPath(path string) error {
+	_, err := reportFormatForPath(path)
+	return err
+}
+
+// WriteCheckResult writes r to w as JSON, or as text: the check_all_clusters
+// output, or for a single cluster the get_cluster_status card followed by
+// its issues. Text written to a terminal is fitted to its width.
+func WriteCheckResult(w io.Writer, r *ClusterReport, format OutputFormat) error {
+	if isJSONOutput(format) {
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode check result: %w", err)
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+	text := formatClusterReport(r)
+	if len(r.Clusters) == 1 {
+		var b strings.Builder
+		b.WriteString(formatClusterStatus(r.Clusters[0]))
+		writeIssues(&b, r.Issues)
+		text = b.String()
+	}
+	_, err := io.WriteString(w, fitText(text, writerWidth(w)))
+	return err
+}
+
+// ExportClusterReport checks all clusters and writes the report to path. The
+// result is recorded in the health history.
+func ExportClusterReport(ctx context.Context, k8sProvider *k8s.Provider, path string, thresholds HealthThresholds) (*ClusterReport, error) {
+	if _, err := reportFormatForPath(path); err != nil {
+		return nil, err
+	}
+	r := CheckClusters(ctx, k8sProvider, nil, thresholds)
+	if err := WriteClusterReport(path, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// handleExportCommand processes "/export <path>": it writes the latest
+// check_all_clusters result, checking all clusters first when there is none.
+func handleExportCommand(deps *loopDeps, input string) (bool, error) {
+	parts := strings.Fields(input)
+	if len(parts) != 2 {
+		fmt.Printf("  %s●%s Usage: /export <path.json|.yaml|.md|.html>\n", colorRed, colorReset)
+		return true, nil
+	}
+	path := parts[1]
+	if _, err := reportFormatForPath(path); err != nil {
+		fmt.Printf(fmtErrorBullet, colorRed, colorReset, err)
+		return true, nil
+	}
+	r := deps.state.getLastReport()
+	if r == nil {
+		fmt.Printf("  %s●%s No cluster check yet — checking all clusters...\n", colorCyan, colorReset)
+		r = newClusterReport(deps.k8sProvider.GetAllClusterStatuses(deps.ctx), deps.state.healthThresholds())
+		deps.state.recordClusterReport(r)
+	}
+	if err := WriteClusterReport(path, r); err != nil {
+		fmt.Printf(fmtErrorBullet, colorRed, colorReset, err)
+		return true, nil
+	}
+	fmt.Printf("  %s●%s Exported status of %d cluster(s) from %s to %s\n", colorGreen, colorReset,
+		r.Summary.TotalClusters, r.GeneratedAt.Local().Format("15:04:05"), path)
+	return true, nil
+}
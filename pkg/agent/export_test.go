@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"sigs.k8s.io/yaml"
+)
+
+func sampleClusterReport() *ClusterReport {
+	return newClusterReport([]*k8s.ClusterStatus{
+		{
+			ClusterInfo: k8s.ClusterInfo{Context: "prod", Server: "https://prod.example.com", IsReachable: true},
+			Version:     "1.31.0", NodeCount: 3, HealthyNodes: 3, PodCount: 10, HealthyPods: 8,
+			Cost: &k8s.CostEstimate{Currency: "USD", MonthlyCost: 420},
+		},
+		{
+			ClusterInfo: k8s.ClusterInfo{Context: "dev|<b>", Server: "https://dev.example.com"},
+			Error:       "connection refused",
+		},
+	}, HealthThresholds{})
+}
+
+func TestReportFormatForPath(t *testing.T) {
+	for path, want := range map[string]string{
+		"status.json":    reportJSON,
+		"out/status.YML": reportYAML,
+		"status.yaml":    reportYAML,
+		"status.md":      reportMarkdown,
+		"status.html":    reportHTML,
+		"status.htm":     reportHTML,
+	} {
+		if got, err := reportFormatForPath(path); err != nil || got != want {
+			t.Errorf("reportFormatForPath(%q) = %q, %v; want %q", path, got, err, want)
+		}
+	}
+	for _, path := range []string{"status.pdf", "status"} {
+		if _, err := reportFormatForPath(path); err == nil {
+			t.Errorf("reportFormatForPath(%q) succeeded, want an error", path)
+		}
+	}
+}
+
+func TestNewClusterReport(t *testing.T) {
+	report := sampleClusterReport()
+	want := CheckAllClustersSummary{TotalClusters: 2, Reachable: 1, FullyHealthy: 0, UnhealthyPods: 2, Critical: 1, Warnings: 1}
+	if report.Summary != want {
+		t.Errorf("Summary = %+v, want %+v", report.Summary, want)
+	}
+	if len(report.Issues) != 2 || report.GeneratedAt.IsZero() {
+		t.Errorf("report = %+v, want 2 issues and a timestamp", report)
+	}
+}
+
+func TestRenderClusterReport(t *testing.T) {
+	report := sampleClusterReport()
+
+	data, err := renderClusterReport(report, reportJSON)
+	if err != nil {
+		t.Fatalf("render json: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json report does not parse: %v", err)
+	}
+	for _, key := range []string{"generated_at", "summary", "issues", "clusters"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("json report missing %q", key)
+		}
+	}
+
+	data, err = renderClusterReport(report, reportYAML)
+	if err != nil {
+		t.Fatalf("render yaml: %v", err)
+	}
+	if err := yaml.Unmarshal(data, &decoded); err != nil || !strings.Contains(string(data), "total_clusters: 2") {
+		t.Errorf("yaml report = %s (err %v)", data, err)
+	}
+
+	data, err = renderClusterReport(report, reportMarkdown)
+	if err != nil {
+		t.Fatalf("render markdown: %v", err)
+	}
+	for _, want := range []string{
+		"# Kopilot Cluster Report",
+		"| prod | ⚠️ DEGRADED | 3/3 | 8/10 | 1.31.0 | ~420.00 USD |",
+		`| dev\|<b> | ❌ DOWN | - | - | - | - |`,
+		`| 🔴 critical | dev\|<b> | Cluster unreachable: connection refused |`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("markdown report missing %q:\n%s", want, data)
+		}
+	}
+
+	data, err = renderClusterReport(report, reportHTML)
+	if err != nil {
+		t.Fatalf("render html: %v", err)
+	}
+	for _, want := range []string{
+		"<title>Kopilot Cluster Report</title>",
+		`<td class="degraded">⚠️ DEGRADED</td>`,
+		"<td>dev|&lt;b&gt;</td>",
+		"<td>~420.00 USD</td>",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("html report missing %q:\n%s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "<b>") {
+		t.Error("html report should escape cluster names")
+	}
+}
+
+func TestWriteClusterReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status.md")
+	if err := WriteClusterReport(path, sampleClusterReport()); err != nil {
+		t.Fatalf("WriteClusterReport() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasPrefix(string(data), "# Kopilot Cluster Report") {
+		t.Errorf("written report = %q (err %v)", data, err)
+	}
+	if err := WriteClusterReport(filepath.Join(t.TempDir(), "status.txt"), sampleClusterReport()); err == nil {
+		t.Error("WriteClusterReport with .txt succeeded, want an error")
+	}
+}
+
+func TestWriteCheckResult(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteCheckResult(&out, sampleClusterReport(), OutputText); err != nil {
+		t.Fatalf("WriteCheckResult(text) error = %v", err)
+	}
+	for _, want := range []string{"⚠️  prod - DEGRADED", "❌ dev|<b> - DOWN", "📊 Summary: 1/2 reachable", "👉 Investigate first: dev|<b>"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("text result missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	single := newClusterReport(sampleClusterReport().Clusters[:1], HealthThresholds{})
+	if err := WriteCheckResult(&out, single, OutputText); err != nil {
+		t.Fatalf("WriteCheckResult(single) error = %v", err)
+	}
+	if text := out.String(); !strings.HasPrefix(text, "Cluster Status:") || !strings.Contains(text, "🚨 Issues:") || strings.Contains(text, "📊 Summary") {
+		t.Errorf("single-cluster result should be the cluster card and its issues:\n%s", text)
+	}
+
+	out.Reset()
+	if err := WriteCheckResult(&out, sampleClusterReport(), OutputJSON); err != nil {
+		t.Fatalf("WriteCheckResult(json) error = %v", err)
+	}
+	var decoded ClusterReport
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || decoded.Summary.Critical != 1 || decoded.Focus == nil {
+		t.Errorf("json result = %s (err %v)", out.String(), err)
+	}
+}
+
+func TestLastReport(t *testing.T) {
+	state := &agentState{}
+	if state.getLastReport() != nil {
+		t.Error("new state should have no report")
+	}
+	report := sampleClusterReport()
+	state.setLastReport(report)
+	if state.getLastReport() != report {
+		t.Error("getLastReport should return the recorded report")
+	}
+}
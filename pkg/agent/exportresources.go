@@ -0,0 +1,351 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the export_resources tool: sanitized resource bundles for sharing.
+package agent
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// maxExportObjects bounds the objects written to one export bundle.
+const maxExportObjects = 500
+
+// Export bundle formats, chosen by the path extension.
+const (
+	exportTarball = "tar.gz"
+	exportYAML    = "yaml"
+)
+
+// exportDroppedMetadata are metadata fields that identify the live object
+// but mean nothing to the reader of a bundle.
+var exportDroppedMetadata = []string{"managedFields", "uid", "resourceVersion", "selfLink", "generation"}
+
+// ExportResourcesParams defines parameters for export_resources
+type ExportResourcesParams struct {
+	Context       string   `json:"context" jsonschema:"The cluster context name (required)"`
+	Kinds         []string `json:"kinds" jsonschema:"Resource kinds to export (required), e.g. [deployments, services, configmaps]"`
+	Namespace     string   `json:"namespace,omitempty" jsonschema:"Namespace to export from; omit for the context default or cluster-scoped kinds"`
+	AllNamespaces bool     `json:"all_namespaces,omitempty" jsonschema:"Export from all namespaces"`
+	Selector      string   `json:"selector,omitempty" jsonschema:"Optional label selector, e.g. 'app=web'"`
+	Path          string   `json:"path,omitempty" jsonschema:"Local file to write: .tar.gz/.tgz (one file per object) or .yaml (one multi-document file); default kopilot-export-<context>-<time>.tar.gz"`
+}
+
+// ExportResourcesResult defines JSON output for export_resources
+type ExportResourcesResult struct {
+	Context  string         `json:"context"`
+	Path     string         `json:"path"`
+	Format   string         `json:"format"`
+	Objects  int            `json:"objects"`
+	Kinds    map[string]int `json:"kinds"`
+	Secrets  int            `json:"secrets"`  // Secrets exported with their values removed
+	Redacted int            `json:"redacted"` // other values redacted
+}
+
+func defineExportResourcesTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolExportResources,
+		"Export selected resources of a cluster to a local bundle for sharing with vendors or attaching to support tickets: a .tar.gz with one YAML file per object, or a single .yaml file. Objects are sanitized before writing: managedFields and other server bookkeeping are removed, Secret values are stripped (keys kept), and secret-looking env vars, ConfigMap keys, tokens and passwords are redacted. Status is kept. Does not change the cluster.",
+		func(params ExportResourcesParams, inv llm.ToolInvocation) (any, error) {
+			return handleExportResources(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+// validateExportParams checks names and the selector and resolves the bundle path and format.
+func validateExportParams(params *ExportResourcesParams) (string, error) {
+	if params.Context == "" || len(params.Kinds) == 0 {
+		return "", fmt.Errorf("context and kinds are required")
+	}
+	for _, kind := range params.Kinds {
+		if !isValidKubernetesName(kind) {
+			return "", fmt.Errorf("invalid kind: %s", kind)
+		}
+	}
+	if params.Namespace != "" && !isValidKubernetesName(params.Namespace) {
+		return "", fmt.Errorf("invalid namespace name: %s", params.Namespace)
+	}
+	if params.Selector != "" {
+		if _, err := labels.Parse(params.Selector); err != nil {
+			return "", fmt.Errorf("invalid selector: %w", err)
+		}
+	}
+	if params.Path == "" {
+		params.Path = fmt.Sprintf("kopilot-export-%s-%s.tar.gz", unsafeFileChars.ReplaceAllString(params.Context, "_"), time.Now().Format("20060102-150405"))
+	}
+	switch lower := strings.ToLower(params.Path); {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return exportTarball, nil
+	case strings.HasSuffix(lower, ".yaml"), strings.HasSuffix(lower, ".yml"):
+		return exportYAML, nil
+	}
+	return "", fmt.Errorf("unsupported export path %q: use .tar.gz, .tgz or .yaml", params.Path)
+}
+
+func handleExportResources(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params ExportResourcesParams) (any, error) {
+	format, err := validateExportParams(&params)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := getClusterForContext(k8sProvider, params.Context); err != nil {
+		return nil, err
+	}
+
+	objects, err := listExportObjects(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("no %s found to export", strings.Join(params.Kinds, ", "))
+	}
+	if len(objects) > maxExportObjects {
+		return nil, fmt.Errorf("%d objects match (limit %d); narrow the kinds, selector or namespace", len(objects), maxExportObjects)
+	}
+
+	result := ExportResourcesResult{Context: params.Context, Path: params.Path, Format: format, Kinds: map[string]int{}}
+	for _, obj := range objects {
+		kind, _ := nestedString(obj, "kind")
+		result.Kinds[kind]++
+		if sanitizeForExport(obj) {
+			result.Secrets++
+		}
+		result.Redacted += redactExportValues(obj)
+	}
+	result.Objects = len(objects)
+
+	var data []byte
+	if format == exportTarball {
+		data, err = exportTarballBytes(params, objects)
+	} else {
+		data, err = exportYAMLBytes(objects)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(params.Path, data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write export bundle: %w", err)
+	}
+
+	if isJSONOutput(state.outputFormat) {
+		return result, nil
+	}
+	return formatExportResult(result), nil
+}
+
+// listExportObjects returns the matching objects of every kind, sorted by
+// kind, namespace and name.
+func listExportObjects(ctx context.Context, params ExportResourcesParams) ([]map[string]any, error) {
+	args := []string{"--context", params.Context, "get", strings.Join(params.Kinds, ","), "-o", "json"}
+	if params.Selector != "" {
+		args = append(args, "-l", params.Selector)
+	}
+	if params.AllNamespaces {
+		args = append(args, "-A")
+	} else if params.Namespace != "" {
+		args = append(args, "-n", params.Namespace)
+	}
+	out, err := runKubectlCommandFunc(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w: %s", strings.Join(params.Kinds, ", "), err, strings.TrimSpace(string(out)))
+	}
+	// kubectl may print deprecation warnings ahead of the JSON document.
+	if i := bytes.IndexByte(out, '{'); i > 0 {
+		out = out[i:]
+	}
+	var list struct {
+		Items []map[string]any `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse exported resources: %w", err)
+	}
+	sort.SliceStable(list.Items, func(i, j int) bool { return exportFileName(list.Items[i]) < exportFileName(list.Items[j]) })
+	return list.Items, nil
+}
+
+// sanitizeForExport removes server bookkeeping from obj in place and strips
+// the values of a Secret, keeping its keys. It reports whether obj is a Secret.
+func sanitizeForExport(obj map[string]any) bool {
+	if meta, ok := obj["metadata"].(map[string]any); ok {
+		for _, f := range exportDroppedMetadata {
+			delete(meta, f)
+		}
+		if annotations, ok := meta["annotations"].(map[string]any); ok {
+			// The last applied configuration repeats the object, secrets included.
+			delete(annotations, lastAppliedAnnotation)
+			if len(annotations) == 0 {
+				delete(meta, "annotations")
+			}
+		}
+	}
+	return redactSecretData(obj)
+}
+
+// redactSecretData replaces every value under data and stringData of obj
+// with redactedValue when obj is a Secret, keeping the keys. It reports
+// whether obj is a Secret.
+func redactSecretData(obj map[string]any) bool {
+	if kind, _ := nestedString(obj, "kind"); kind != "Secret" {
+		return false
+	}
+	for _, field := range []string{"data", "stringData"} {
+		if values, ok := obj[field].(map[string]any); ok {
+			for k := range values {
+				values[k] = redactedValue
+			}
+		}
+	}
+	return true
+}
+
+// redactExportValues redacts ConfigMap data and env var values whose name
+// looks like a credential. It returns the number of values redacted.
+func redactExportValues(obj map[string]any) int {
+	count := 0
+	if kind, _ := nestedString(obj, "kind"); kind == "ConfigMap" {
+		if data, ok := obj["data"].(map[string]any); ok {
+			for k, v := range data {
+				if s, _ := v.(string); s != "" && k8s.IsSensitiveName(k) {
+					data[k] = redactedValue
+					count++
+				}
+			}
+		}
+	}
+	return count + redactEnvValues(obj)
+}
+
+// redactEnvValues redacts, anywhere in v, literal values of name/value
+// pairs (env vars) whose name looks like a credential.
+func redactEnvValues(v any) int {
+	count := 0
+	switch v := v.(type) {
+	case map[string]any:
+		if name, ok := v["name"].(string); ok && k8s.IsSensitiveName(name) {
+			if value, ok := v["value"].(string); ok && value != "" {
+				v["value"] = redactedValue
+				count++
+			}
+		}
+		for _, child := range v {
+			count += redactEnvValues(child)
+		}
+	case []any:
+		for _, child := range v {
+			count += redactEnvValues(child)
+		}
+	}
+	return count
+}
+
+// exportManifest renders obj as YAML with credentials in free text masked.
+func exportManifest(obj map[string]any) ([]byte, error) {
+	out, err := yaml.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render YAML: %w", err)
+	}
+	return []byte(redactSecrets(string(out))), nil
+}
+
+// exportFileName returns <kind>/<namespace>/<name>.yaml, or <kind>/<name>.yaml
+// for cluster-scoped objects.
+func exportFileName(obj map[string]any) string {
+	kind, _ := nestedString(obj, "kind")
+	name, _ := nestedString(obj, "metadata", "name")
+	namespace, _ := nestedString(obj, "metadata", "namespace")
+	return path.Join(strings.ToLower(kind), unsafeFileChars.ReplaceAllString(namespace, "_"), unsafeFileChars.ReplaceAllString(name, "_")+".yaml")
+}
+
+// exportYAMLBytes renders objects as one multi-document YAML file.
+func exportYAMLBytes(objects []map[string]any) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, obj := range objects {
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		manifest, err := exportManifest(obj)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(manifest)
+	}
+	return buf.Bytes(), nil
+}
+
+// exportTarballBytes packs one YAML file per object and an index describing
+// the export into a gzipped tarball.
+func exportTarballBytes(params ExportResourcesParams, objects []map[string]any) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	add := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: now}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	var index strings.Builder
+	fmt.Fprintf(&index, "Exported by kopilot %s from context %s at %s\n", AppVersion, params.Context, now.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&index, "Kinds: %s", strings.Join(params.Kinds, ", "))
+	if params.Selector != "" {
+		fmt.Fprintf(&index, "  Selector: %s", params.Selector)
+	}
+	index.WriteString("\nSecret values, credentials and server-managed fields were removed.\n\n")
+	for _, obj := range objects {
+		name := exportFileName(obj)
+		manifest, err := exportManifest(obj)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(name, manifest); err != nil {
+			return nil, fmt.Errorf("failed to write %s to the bundle: %w", name, err)
+		}
+		index.WriteString(name + "\n")
+	}
+	if err := add("INDEX.txt", []byte(index.String())); err != nil {
+		return nil, fmt.Errorf("failed to write the bundle index: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish the bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish the bundle: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// formatExportResult formats an ExportResourcesResult as human-readable text
+func formatExportResult(r ExportResourcesResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📦 Exported %d object(s) from %s to %s (%s)\n\n", r.Objects, r.Context, r.Path, r.Format)
+	for _, kind := range sortedIntKeys(r.Kinds) {
+		fmt.Fprintf(&sb, "  %s: %d\n", kind, r.Kinds[kind])
+	}
+	fmt.Fprintf(&sb, "\n🔒 %d Secret(s) exported without values, %d other value(s) redacted; managedFields removed.\n", r.Secrets, r.Redacted)
+	sb.WriteString("   Redaction is pattern-based: review the bundle before sharing it.\n")
+	return sb.String()
+}
+
+func sortedIntKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
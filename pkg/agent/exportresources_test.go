@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const exportListJSON = `Warning: v1 ComponentStatus is deprecated
+{"kind":"List","items":[
+ {"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web","namespace":"shop","uid":"u1","resourceVersion":"42","managedFields":[{"manager":"kubectl"}],
+  "annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"password\":\"leak\"}"}},
+  "spec":{"template":{"spec":{"containers":[{"name":"web","image":"web:1","args":["--token=abc123"],
+   "env":[{"name":"DB_PASSWORD","value":"hunter2"},{"name":"LOG_LEVEL","value":"debug"},{"name":"API_TOKEN","valueFrom":{"secretKeyRef":{"name":"api","key":"token"}}}]}]}}},
+  "status":{"readyReplicas":1}},
+ {"apiVersion":"v1","kind":"Secret","metadata":{"name":"api","namespace":"shop"},"type":"Opaque","data":{"token":"c2VjcmV0"}},
+ {"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings","namespace":"shop"},"data":{"SMTP_PASSWORD":"pw","color":"blue"}}
+]}`
+
+func stubExportKubectl(t *testing.T) *[]string {
+	t.Helper()
+	original := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = original })
+	var args []string
+	runKubectlCommandFunc = func(_ context.Context, a []string) ([]byte, error) {
+		args = a
+		return []byte(exportListJSON), nil
+	}
+	return &args
+}
+
+func TestValidateExportParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		params ExportResourcesParams
+		want   string
+	}{
+		{"no kinds", ExportResourcesParams{Context: "c"}, "kinds are required"},
+		{"bad kind", ExportResourcesParams{Context: "c", Kinds: []string{"pods;rm"}}, "invalid kind"},
+		{"bad selector", ExportResourcesParams{Context: "c", Kinds: []string{"pods"}, Selector: "app==("}, "invalid selector"},
+		{"bad extension", ExportResourcesParams{Context: "c", Kinds: []string{"pods"}, Path: "out.zip"}, "unsupported export path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := validateExportParams(&tt.params); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	p := ExportResourcesParams{Context: "prod/eu", Kinds: []string{"pods"}}
+	if format, err := validateExportParams(&p); err != nil || format != exportTarball || !strings.HasPrefix(p.Path, "kopilot-export-prod_eu-") {
+		t.Errorf("default path = %q, %q, %v", p.Path, format, err)
+	}
+	p.Path = "bundle.YML"
+	if format, err := validateExportParams(&p); err != nil || format != exportYAML {
+		t.Errorf("yaml path: %q, %v", format, err)
+	}
+}
+
+// readExportTarball returns the files of a gzipped tarball by name.
+func readExportTarball(t *testing.T, path string) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open bundle: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("tar: %v", err)
+		}
+		data, _ := io.ReadAll(tr)
+		files[h.Name] = string(data)
+	}
+}
+
+func TestHandleExportResourcesTarball(t *testing.T) {
+	args := stubExportKubectl(t)
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	out, err := handleExportResources(context.Background(), newTestK8sProvider(t), approvingState(), ExportResourcesParams{
+		Context: "test-context", Kinds: []string{"deployments", "secrets", "configmaps"}, Namespace: "shop", Path: path,
+	})
+	if err != nil {
+		t.Fatalf("handleExportResources: %v", err)
+	}
+	if got := strings.Join(*args, " "); got != "--context test-context get deployments,secrets,configmaps -o json -n shop" {
+		t.Errorf("kubectl args = %s", got)
+	}
+	result := out.(ExportResourcesResult)
+	if result.Objects != 3 || result.Secrets != 1 || result.Redacted != 2 || result.Kinds["Deployment"] != 1 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	files := readExportTarball(t, path)
+	deploy := files["deployment/shop/web.yaml"]
+	for _, leak := range []string{"hunter2", "abc123", "leak", "managedFields", "resourceVersion", "uid:"} {
+		if strings.Contains(deploy, leak) {
+			t.Errorf("deployment manifest contains %q:\n%s", leak, deploy)
+		}
+	}
+	for _, want := range []string{"LOG_LEVEL", "value: debug", "readyReplicas: 1", "secretKeyRef"} {
+		if !strings.Contains(deploy, want) {
+			t.Errorf("deployment manifest missing %q:\n%s", want, deploy)
+		}
+	}
+	if secret := files["secret/shop/api.yaml"]; strings.Contains(secret, "c2VjcmV0") || !strings.Contains(secret, "token: "+redactedValue) {
+		t.Errorf("secret values not stripped:\n%s", secret)
+	}
+	if cm := files["configmap/shop/settings.yaml"]; strings.Contains(cm, "pw\n") || !strings.Contains(cm, "color: blue") {
+		t.Errorf("configmap not redacted as expected:\n%s", cm)
+	}
+	if index := files["INDEX.txt"]; !strings.Contains(index, "context test-context") || !strings.Contains(index, "secret/shop/api.yaml") {
+		t.Errorf("index:\n%s", index)
+	}
+}
+
+func TestHandleExportResourcesYAML(t *testing.T) {
+	stubExportKubectl(t)
+	path := filepath.Join(t.TempDir(), "bundle.yaml")
+	state := approvingState()
+	state.outputFormat = OutputText
+	out, err := handleExportResources(context.Background(), newTestK8sProvider(t), state, ExportResourcesParams{
+		Context: "test-context", Kinds: []string{"deployments", "secrets", "configmaps"}, AllNamespaces: true, Path: path,
+	})
+	if err != nil {
+		t.Fatalf("handleExportResources: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read bundle: %v", err)
+	}
+	if strings.Count(string(data), "\n---\n") != 2 || strings.Contains(string(data), "hunter2") {
+		t.Errorf("unexpected YAML bundle:\n%s", data)
+	}
+	text := out.(string)
+	for _, want := range []string{"Exported 3 object(s) from test-context", "Deployment: 1", "1 Secret(s) exported without values, 2 other value(s) redacted"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+}
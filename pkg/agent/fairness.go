@@ -0,0 +1,99 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the check_quota_fairness tool (multi-tenant requests vs quota and usage).
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// CheckQuotaFairnessParams defines parameters for check_quota_fairness
+type CheckQuotaFairnessParams struct {
+	Context       string `json:"context" jsonschema:"The context name of the cluster to inspect (from list_clusters)"`
+	GroupLabel    string `json:"group_label,omitempty" jsonschema:"Optional: namespace label naming the owning team, e.g. team; namespaces sharing a value are reported together, the rest on their own"`
+	IncludeSystem bool   `json:"include_system,omitempty" jsonschema:"If true, include system namespaces (kube-system, kube-public, kube-node-lease)"`
+}
+
+func defineCheckQuotaFairnessTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolCheckQuotaFairness,
+		"Multi-tenant fairness report for shared clusters: for each namespace, or each team when group_label is given, compare requested CPU and memory against its ResourceQuota, its share of the cluster and its actual usage from metrics-server, and rank tenants by overcommitment (requested but unused capacity). Use for capacity governance questions such as 'which teams reserve more than they use' or 'who holds the biggest share of the cluster'.",
+		func(params CheckQuotaFairnessParams, inv llm.ToolInvocation) (any, error) {
+			if params.GroupLabel != "" {
+				if errs := validation.IsQualifiedName(params.GroupLabel); len(errs) > 0 {
+					return nil, fmt.Errorf("invalid group_label %q: %s", params.GroupLabel, strings.Join(errs, "; "))
+				}
+			}
+			report, err := k8sProvider.GetQuotaFairness(inv.Ctx(), params.Context, params.GroupLabel, params.IncludeSystem)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check quota fairness: %w", err)
+			}
+
+			if isJSONOutput(state.outputFormat) {
+				return report, nil
+			}
+			return formatQuotaFairnessReport(report), nil
+		},
+	)
+}
+
+// formatQuotaFairnessReport formats a QuotaFairnessReport as human-readable text
+func formatQuotaFairnessReport(report *k8s.QuotaFairnessReport) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Quota Fairness: %s\n", report.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	tenants := fmt.Sprintf("%d namespace(s)", len(report.Tenants))
+	if report.GroupLabel != "" {
+		tenants = fmt.Sprintf("%d team(s) by label %s", len(report.Tenants), report.GroupLabel)
+	}
+	fmt.Fprintf(&sb, "📊 CLUSTER: requests %s/%s CPU, %s/%s memory across %s\n",
+		report.RequestedCPU.Human, report.AllocatableCPU.Human, report.RequestedMemory.Human, report.AllocatableMemory.Human, tenants)
+	if report.UsageAvailable {
+		sb.WriteString("   Ranked by idle requests (requested but unused) as a share of the cluster\n")
+	} else {
+		fmt.Fprintf(&sb, "   ⚠️  No usage data (%s): ranked by requested share only\n", report.UsageError)
+	}
+
+	rank := "of the cluster requested"
+	if report.UsageAvailable {
+		rank = "of the cluster requested but idle"
+	}
+	for i, t := range report.Tenants {
+		icon := "✅"
+		if len(t.Findings) > 0 {
+			icon = "⚠️ "
+		}
+		fmt.Fprintf(&sb, "\n%d. %s %s  (%.1f%% %s, %d pod(s))\n", i+1, icon, t.Tenant, t.Overcommitment, rank, t.Pods)
+		if len(t.Namespaces) > 1 || t.Namespaces[0] != t.Tenant {
+			fmt.Fprintf(&sb, "   Namespaces: %s\n", strings.Join(t.Namespaces, ", "))
+		}
+		cpuQuota, memQuota := "  | no quota", "  | no quota"
+		if t.QuotaCPU != nil {
+			cpuQuota = fmt.Sprintf("  | %5.1f%% of quota %s", t.QuotaCPUPercent, t.QuotaCPU.Human)
+		}
+		if t.QuotaMemory != nil {
+			memQuota = fmt.Sprintf("  | %5.1f%% of quota %s", t.QuotaMemoryPercent, t.QuotaMemory.Human)
+		}
+		fmt.Fprintf(&sb, "   CPU     requests %-8s %5.1f%% of cluster%s%s\n", t.RequestedCPU.Human, t.CPUShare,
+			cpuQuota, fairnessUsage(report.UsageAvailable, t.UsedCPU.Human, t.CPUEfficiency))
+		fmt.Fprintf(&sb, "   Memory  requests %-8s %5.1f%% of cluster%s%s\n", t.RequestedMemory.Human, t.MemoryShare,
+			memQuota, fairnessUsage(report.UsageAvailable, t.UsedMemory.Human, t.MemoryEfficiency))
+		for _, f := range t.Findings {
+			fmt.Fprintf(&sb, "   ⚠️  %s\n", f)
+		}
+	}
+	return sb.String()
+}
+
+// fairnessUsage renders the usage column of a fairness line.
+func fairnessUsage(available bool, used string, efficiency float64) string {
+	if !available {
+		return ""
+	}
+	return fmt.Sprintf("  | uses %s (%.0f%%)", used, efficiency)
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+func TestCheckQuotaFairnessToolValidation(t *testing.T) {
+	provider := createMockProvider(t)
+	tool := defineCheckQuotaFairnessTool(provider, &agentState{outputFormat: OutputText})
+	inv := llm.ToolInvocation{Context: context.Background()}
+	_, err := tool.Handler(map[string]any{"context": "prod", "group_label": "team owner"}, inv)
+	if err == nil || !strings.Contains(err.Error(), "invalid group_label") {
+		t.Errorf("error = %v, want an invalid group_label error", err)
+	}
+}
+
+func TestFormatQuotaFairnessReport(t *testing.T) {
+	cpuQuota := k8s.CPUAmountFromMillicores(4500)
+	report := &k8s.QuotaFairnessReport{
+		Context: "prod", GroupLabel: "team", UsageAvailable: true,
+		AllocatableCPU: k8s.CPUAmountFromMillicores(10000), AllocatableMemory: k8s.MemoryAmountFromBytes(40 << 30),
+		RequestedCPU: k8s.CPUAmountFromMillicores(5000), RequestedMemory: k8s.MemoryAmountFromBytes(10 << 30),
+		Tenants: []k8s.TenantFairness{
+			{
+				Tenant: "shop", Namespaces: []string{"checkout-dev", "checkout-prod"}, Pods: 3,
+				RequestedCPU: k8s.CPUAmountFromMillicores(4000), RequestedMemory: k8s.MemoryAmountFromBytes(8 << 30),
+				CPUShare: 40, MemoryShare: 20, QuotaCPU: &cpuQuota, QuotaCPUPercent: 88.9,
+				UsedCPU: k8s.CPUAmountFromMillicores(400), UsedMemory: k8s.MemoryAmountFromBytes(1 << 30),
+				CPUEfficiency: 10, MemoryEfficiency: 12.5, Overcommitment: 36,
+				Findings: []string{"uses 10% of the CPU it requests (3.6 idle)"},
+			},
+			{Tenant: "sandbox", Namespaces: []string{"sandbox"}, RequestedCPU: k8s.CPUAmountFromMillicores(100)},
+		},
+	}
+	text := formatQuotaFairnessReport(report)
+	for _, want := range []string{
+		"requests 5/10 CPU, 10Gi/40Gi memory across 2 team(s) by label team",
+		"1. ⚠️  shop  (36.0% of the cluster requested but idle, 3 pod(s))",
+		"Namespaces: checkout-dev, checkout-prod",
+		"88.9% of quota 4.5",
+		"uses 400m (10%)",
+		"Memory  requests 8Gi",
+		"| no quota",
+		"2. ✅ sandbox",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "Namespaces: sandbox") {
+		t.Errorf("single-namespace tenant listed its namespace:\n%s", text)
+	}
+
+	report.UsageAvailable, report.UsageError = false, "metrics API unavailable"
+	text = formatQuotaFairnessReport(report)
+	if !strings.Contains(text, "No usage data (metrics API unavailable)") || strings.Contains(text, "uses 400m") {
+		t.Errorf("output without usage:\n%s", text)
+	}
+}
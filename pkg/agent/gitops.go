@@ -0,0 +1,93 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the get_gitops_status tool for Argo CD and Flux applications.
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// GetGitOpsStatusParams defines parameters for get_gitops_status
+type GetGitOpsStatusParams struct {
+	Context      string `json:"context" jsonschema:"The context name of the cluster to inspect (from list_clusters)"`
+	Namespace    string `json:"namespace,omitempty" jsonschema:"Optional: only applications in this namespace (Argo CD keeps Applications in argocd, Flux usually in flux-system); leave empty for all namespaces"`
+	OnlyProblems bool   `json:"only_problems,omitempty" jsonschema:"Only list applications that are out of sync, unhealthy or suspended"`
+}
+
+func defineGetGitOpsStatusTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolGetGitOpsStatus,
+		"List the GitOps applications of a cluster (Argo CD Applications, Flux Kustomizations and HelmReleases) with their sync and health status, deployed source and revision, and the error of failed syncs. Use it to answer \"is anything out of sync in prod?\". Read-only.",
+		func(params GetGitOpsStatusParams, inv llm.ToolInvocation) (any, error) {
+			report, err := k8sProvider.GetGitOpsStatus(inv.Ctx(), params.Context, params.Namespace)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get GitOps status: %w", err)
+			}
+			if params.OnlyProblems {
+				apps := []k8s.GitOpsApp{}
+				for _, a := range report.Apps {
+					if a.NeedsAttention() {
+						apps = append(apps, a)
+					}
+				}
+				report.Apps = apps
+			}
+
+			if isJSONOutput(state.outputFormat) {
+				return report, nil
+			}
+			return formatGitOpsReport(report, params.OnlyProblems), nil
+		},
+	)
+}
+
+// formatGitOpsReport formats a GitOpsReport as human-readable text
+func formatGitOpsReport(report *k8s.GitOpsReport, onlyProblems bool) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🔄 GitOps status of %s\n\n", report.Context)
+	if len(report.Tools) == 0 {
+		sb.WriteString("No Argo CD or Flux CRDs found in this cluster.\n")
+	} else {
+		fmt.Fprintf(&sb, "Tools: %s | Out of sync: %d | Unhealthy: %d\n\n", strings.Join(report.Tools, ", "), report.OutOfSync, report.Unhealthy)
+	}
+
+	for _, a := range report.Apps {
+		icon := "✅"
+		switch {
+		case a.Suspended:
+			icon = "⏸️ "
+		case a.Health == k8s.GitOpsDegraded || a.Health == "Missing":
+			icon = "❌"
+		case a.NeedsAttention():
+			icon = "⚠️ "
+		}
+		fmt.Fprintf(&sb, "%s %s %s/%s (%s): %s, %s", icon, a.Kind, a.Namespace, a.Name, a.Tool, a.Sync, a.Health)
+		if a.Revision != "" {
+			fmt.Fprintf(&sb, " @ %s", a.Revision)
+		}
+		sb.WriteString("\n")
+		if a.Source != "" {
+			fmt.Fprintf(&sb, "   source: %s\n", a.Source)
+		}
+		if a.Message != "" && a.NeedsAttention() {
+			fmt.Fprintf(&sb, "   %s\n", a.Message)
+		}
+	}
+	if len(report.Apps) == 0 && len(report.Tools) > 0 {
+		if onlyProblems {
+			sb.WriteString("Every application is synced and healthy.\n")
+		} else {
+			sb.WriteString("No applications found.\n")
+		}
+	}
+	for _, w := range report.Warnings {
+		fmt.Fprintf(&sb, "\n⚠️  %s", w)
+	}
+	if len(report.Warnings) > 0 {
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestFormatGitOpsReport(t *testing.T) {
+	report := &k8s.GitOpsReport{
+		Context: "prod",
+		Tools:   []string{k8s.GitOpsArgoCD, k8s.GitOpsFlux},
+		Apps: []k8s.GitOpsApp{
+			{Tool: k8s.GitOpsArgoCD, Kind: "Application", Namespace: "argocd", Name: "api", Sync: k8s.GitOpsOutOfSync, Health: k8s.GitOpsDegraded,
+				Source: "https://git/shop apps/api", Message: "sync failed: one or more objects failed to apply"},
+			{Tool: k8s.GitOpsFlux, Kind: "Kustomization", Namespace: "flux-system", Name: "legacy", Sync: k8s.GitOpsSynced, Health: k8s.GitOpsSuspended, Suspended: true},
+			{Tool: k8s.GitOpsArgoCD, Kind: "Application", Namespace: "argocd", Name: "web", Sync: k8s.GitOpsSynced, Health: k8s.GitOpsHealthy, Revision: "abc123"},
+		},
+		OutOfSync: 1,
+		Unhealthy: 1,
+		Warnings:  []string{"could not list helmreleases.helm.toolkit.fluxcd.io: forbidden"},
+	}
+	text := formatGitOpsReport(report, false)
+	for _, want := range []string{
+		"Tools: argocd, flux | Out of sync: 1 | Unhealthy: 1",
+		"❌ Application argocd/api (argocd): OutOfSync, Degraded",
+		"source: https://git/shop apps/api",
+		"sync failed: one or more objects",
+		"⏸️  Kustomization flux-system/legacy (flux): Synced, Suspended",
+		"✅ Application argocd/web (argocd): Synced, Healthy @ abc123",
+		"forbidden",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+
+	if text := formatGitOpsReport(&k8s.GitOpsReport{Context: "dev"}, false); !strings.Contains(text, "No Argo CD or Flux CRDs") {
+		t.Errorf("no tools output:\n%s", text)
+	}
+	if text := formatGitOpsReport(&k8s.GitOpsReport{Context: "dev", Tools: []string{k8s.GitOpsFlux}}, true); !strings.Contains(text, "Every application is synced and healthy") {
+		t.Errorf("only problems output:\n%s", text)
+	}
+}
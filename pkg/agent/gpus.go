@@ -0,0 +1,159 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the check_gpus tool for GPU and extended resource reporting.
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// CheckGPUsParams defines parameters for check_gpus
+type CheckGPUsParams struct {
+	Context  string `json:"context" jsonschema:"The context name of the cluster to inspect (from list_clusters)"`
+	Resource string `json:"resource,omitempty" jsonschema:"Optional: only report this extended resource (e.g. 'nvidia.com/gpu' or 'hugepages-2Mi'); leave empty for all GPUs and extended resources"`
+}
+
+func defineCheckGPUsTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolCheckGPUs,
+		"Report GPU and other extended resource (nvidia.com/gpu, amd.com/gpu, hugepages, device plugins) capacity vs allocation per node and cluster-wide, the pods consuming them, and pods pending because those resources are scarce.",
+		func(params CheckGPUsParams, inv llm.ToolInvocation) (any, error) {
+			ctx := inv.Ctx()
+			report, err := k8sProvider.GetExtendedResources(ctx, params.Context)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check extended resources: %w", err)
+			}
+			if params.Resource != "" {
+				report = filterExtendedResourceReport(report, params.Resource)
+			}
+
+			if isJSONOutput(state.outputFormat) {
+				return report, nil
+			}
+			return formatExtendedResourceReport(report), nil
+		},
+	)
+}
+
+// filterExtendedResourceReport keeps only entries for resource.
+func filterExtendedResourceReport(report *k8s.ExtendedResourceReport, resource string) *k8s.ExtendedResourceReport {
+	keepUsage := func(in []k8s.ExtendedResourceUsage) []k8s.ExtendedResourceUsage {
+		var out []k8s.ExtendedResourceUsage
+		for _, u := range in {
+			if u.Resource == resource {
+				out = append(out, u)
+			}
+		}
+		return out
+	}
+	keepPods := func(in []k8s.ExtendedResourcePod) []k8s.ExtendedResourcePod {
+		out := []k8s.ExtendedResourcePod{}
+		for _, p := range in {
+			if _, ok := p.Requests[resource]; ok {
+				out = append(out, p)
+			}
+		}
+		return out
+	}
+
+	filtered := &k8s.ExtendedResourceReport{
+		Context: report.Context,
+		Totals:  keepUsage(report.Totals),
+		Nodes:   []k8s.NodeExtendedResources{},
+		Pods:    keepPods(report.Pods),
+		Pending: keepPods(report.Pending),
+	}
+	for _, n := range report.Nodes {
+		if res := keepUsage(n.Resources); len(res) > 0 {
+			filtered.Nodes = append(filtered.Nodes, k8s.NodeExtendedResources{Node: n.Node, Ready: n.Ready, Resources: res})
+		}
+	}
+	return filtered
+}
+
+// formatExtendedResourceReport formats an ExtendedResourceReport as human-readable text
+func formatExtendedResourceReport(report *k8s.ExtendedResourceReport) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "GPU & Extended Resources: %s\n", report.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	if len(report.Totals) == 0 && len(report.Pending) == 0 {
+		sb.WriteString("No GPUs or other extended resources are advertised by any node.\n")
+		return sb.String()
+	}
+
+	sb.WriteString("📊 CLUSTER TOTALS (requested/allocatable):\n")
+	for _, t := range report.Totals {
+		fmt.Fprintf(&sb, "  %s %-28s %-14s %5.1f%%  (capacity %d)\n",
+			utilizationIcon(t.Percent), t.Resource, t.Human, t.Percent, t.Capacity)
+	}
+	sb.WriteString("\n")
+
+	if len(report.Nodes) > 0 {
+		sb.WriteString("🖥️  NODES:\n")
+		for _, n := range report.Nodes {
+			ready := ""
+			if !n.Ready {
+				ready = "  ⚠️ NotReady"
+			}
+			fmt.Fprintf(&sb, "  %s%s\n", n.Node, ready)
+			for _, u := range n.Resources {
+				fmt.Fprintf(&sb, "    %-28s %-14s %5.1f%%\n", u.Resource, u.Human, u.Percent)
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(report.Pending) > 0 {
+		fmt.Fprintf(&sb, "⏳ PENDING PODS WAITING ON EXTENDED RESOURCES: %d\n", len(report.Pending))
+		for _, p := range report.Pending {
+			fmt.Fprintf(&sb, "  %s/%s  requests %s\n", p.Namespace, p.Pod, formatResourceRequests(p.Requests))
+			if p.Reason != "" {
+				fmt.Fprintf(&sb, "    Reason: %s\n", p.Reason)
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(report.Pods) > 0 {
+		fmt.Fprintf(&sb, "🧩 CONSUMING PODS: %d\n", len(report.Pods))
+		for _, p := range report.Pods {
+			fmt.Fprintf(&sb, "  %s/%s on %s  %s\n", p.Namespace, p.Pod, p.Node, formatResourceRequests(p.Requests))
+		}
+	}
+	return sb.String()
+}
+
+// utilizationIcon returns a traffic-light emoji for an allocation percentage.
+func utilizationIcon(percent float64) string {
+	switch {
+	case percent >= 90:
+		return "🔴"
+	case percent >= 70:
+		return "🟡"
+	default:
+		return "🟢"
+	}
+}
+
+// formatResourceRequests renders a request map as "name=value" pairs in stable order.
+func formatResourceRequests(requests map[string]int64) string {
+	names := make([]string, 0, len(requests))
+	for name := range requests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		v := fmt.Sprintf("%d", requests[name])
+		if strings.HasPrefix(name, "hugepages-") {
+			v = k8s.FormatMemoryBytes(requests[name])
+		}
+		parts[i] = name + "=" + v
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func testExtendedResourceReport() *k8s.ExtendedResourceReport {
+	return &k8s.ExtendedResourceReport{
+		Context: "ml-prod",
+		Totals: []k8s.ExtendedResourceUsage{
+			{Resource: "hugepages-2Mi", Capacity: 1 << 30, Allocatable: 1 << 30, Requested: 512 << 20, Percent: 50, Human: "512Mi/1Gi"},
+			{Resource: "nvidia.com/gpu", Capacity: 8, Allocatable: 8, Requested: 8, Percent: 100, Human: "8/8"},
+		},
+		Nodes: []k8s.NodeExtendedResources{{
+			Node:  "gpu-a",
+			Ready: false,
+			Resources: []k8s.ExtendedResourceUsage{
+				{Resource: "nvidia.com/gpu", Allocatable: 8, Requested: 8, Percent: 100, Human: "8/8"},
+			},
+		}},
+		Pods: []k8s.ExtendedResourcePod{
+			{Namespace: "ml", Pod: "train-1", Node: "gpu-a", Requests: map[string]int64{"nvidia.com/gpu": 8}},
+		},
+		Pending: []k8s.ExtendedResourcePod{
+			{Namespace: "ml", Pod: "train-2", Requests: map[string]int64{"nvidia.com/gpu": 2, "hugepages-2Mi": 2 << 20}, Reason: "Insufficient nvidia.com/gpu"},
+		},
+	}
+}
+
+func TestFormatExtendedResourceReport(t *testing.T) {
+	text := formatExtendedResourceReport(testExtendedResourceReport())
+	for _, want := range []string{
+		"GPU & Extended Resources: ml-prod",
+		"🔴 nvidia.com/gpu",
+		"⚠️ NotReady",
+		"ml/train-2  requests hugepages-2Mi=2Mi, nvidia.com/gpu=2",
+		"Reason: Insufficient nvidia.com/gpu",
+		"ml/train-1 on gpu-a",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report missing %q:\n%s", want, text)
+		}
+	}
+
+	empty := formatExtendedResourceReport(&k8s.ExtendedResourceReport{Context: "dev"})
+	if !strings.Contains(empty, "No GPUs or other extended resources") {
+		t.Errorf("empty report should explain absence of resources: %s", empty)
+	}
+}
+
+func TestFilterExtendedResourceReport(t *testing.T) {
+	filtered := filterExtendedResourceReport(testExtendedResourceReport(), "hugepages-2Mi")
+	if len(filtered.Totals) != 1 || filtered.Totals[0].Resource != "hugepages-2Mi" {
+		t.Errorf("unexpected totals: %+v", filtered.Totals)
+	}
+	if len(filtered.Nodes) != 0 {
+		t.Errorf("node without hugepages should be dropped: %+v", filtered.Nodes)
+	}
+	if len(filtered.Pods) != 0 || len(filtered.Pending) != 1 {
+		t.Errorf("pods should be filtered by request: pods=%v pending=%v", filtered.Pods, filtered.Pending)
+	}
+}
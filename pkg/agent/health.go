@@ -0,0 +1,153 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the health thresholds and severity model of check_all_clusters.
+package agent
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/report"
+)
+
+// Default severity thresholds.
+const (
+	defaultCriticalNotReadyNodes = 2
+	defaultPendingGrace          = 2 * time.Minute
+)
+
+// severityOrder ranks severities, most severe first.
+var severityOrder = map[report.Severity]int{report.SeverityCritical: 0, report.SeverityWarning: 1, report.SeverityInfo: 2}
+
+// HealthThresholds tunes what check_all_clusters reports as degraded and how
+// severe each issue is; zero values use the defaults.
+type HealthThresholds struct {
+	// CriticalNotReadyNodes is the number of NotReady nodes from which a
+	// cluster is critical instead of a warning (default 2). A cluster with
+	// no Ready node is always critical.
+	CriticalNotReadyNodes int `json:"critical_not_ready_nodes,omitempty"`
+	// ToleratedNotReadyPercent is the share of NotReady nodes (0-100) that is
+	// only informational and does not degrade the cluster (default 0).
+	ToleratedNotReadyPercent float64 `json:"tolerated_not_ready_percent,omitempty"`
+	// IgnoreNamespaces are glob patterns such as "dev-*" of namespaces whose
+	// unhealthy pods are not reported.
+	IgnoreNamespaces []string `json:"ignore_namespaces,omitempty"`
+	// PendingGrace is how long a Pending pod is only informational, as a Go
+	// duration such as "90s" or "5m" (default 2m).
+	PendingGrace string `json:"pending_grace,omitempty"`
+	// Acknowledged are known issues excluded from health summaries; /ack
+	// adds more at runtime.
+	Acknowledged []Ack `json:"acknowledged,omitempty"`
+}
+
+// validate rejects out-of-range thresholds, malformed patterns and durations.
+func (t HealthThresholds) validate() error {
+	if t.CriticalNotReadyNodes < 0 {
+		return fmt.Errorf("critical_not_ready_nodes must not be negative")
+	}
+	if t.ToleratedNotReadyPercent < 0 || t.ToleratedNotReadyPercent > 100 {
+		return fmt.Errorf("tolerated_not_ready_percent must be between 0 and 100")
+	}
+	for _, pattern := range t.IgnoreNamespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("ignore_namespaces pattern %q: %w", pattern, err)
+		}
+	}
+	for _, a := range t.Acknowledged {
+		if err := a.validate(); err != nil {
+			return err
+		}
+	}
+	if t.PendingGrace != "" {
+		d, err := time.ParseDuration(t.PendingGrace)
+		if err != nil || d < 0 {
+			return fmt.Errorf("pending_grace %q must be a duration such as 2m", t.PendingGrace)
+		}
+	}
+	return nil
+}
+
+// criticalNotReadyNodes returns the configured node threshold or the default.
+func (t HealthThresholds) criticalNotReadyNodes() int {
+	if t.CriticalNotReadyNodes > 0 {
+		return t.CriticalNotReadyNodes
+	}
+	return defaultCriticalNotReadyNodes
+}
+
+// pendingGrace returns the configured grace period or the default.
+func (t HealthThresholds) pendingGrace() time.Duration {
+	if d, err := time.ParseDuration(t.PendingGrace); err == nil && d >= 0 {
+		return d
+	}
+	return defaultPendingGrace
+}
+
+// nodeSeverity classifies NotReady nodes: critical from the threshold on or
+// when no node is Ready, a warning below it.
+func (t HealthThresholds) nodeSeverity(notReady, total int) report.Severity {
+	if notReady >= t.criticalNotReadyNodes() || notReady >= total {
+		return report.SeverityCritical
+	}
+	return report.SeverityWarning
+}
+
+// toleratesNotReady reports whether notReady of total nodes is within the
+// tolerated share; a cluster with no Ready node is never tolerated.
+func (t HealthThresholds) toleratesNotReady(notReady, total int) bool {
+	return notReady < total && float64(notReady)*100 <= t.ToleratedNotReadyPercent*float64(total)
+}
+
+// ignoresNamespace reports whether unhealthy pods of namespace are ignored.
+func (t HealthThresholds) ignoresNamespace(namespace string) bool {
+	for _, pattern := range t.IgnoreNamespaces {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// acknowledgesPod reports whether an ack covers pod of cluster.
+func (t HealthThresholds) acknowledgesPod(cluster string, pod k8s.PodInfo) bool {
+	for _, a := range t.Acknowledged {
+		if a.matchesPod(cluster, pod) {
+			return true
+		}
+	}
+	return false
+}
+
+// acknowledgedNotReady counts the NotReady nodes of status covered by an ack.
+func (t HealthThresholds) acknowledgedNotReady(status *k8s.ClusterStatus) int {
+	n := 0
+	for _, node := range status.Nodes {
+		if node.Status == "Ready" {
+			continue
+		}
+		for _, a := range t.Acknowledged {
+			if a.matchesNode(status.Context, node.Name) {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// isRecentlyPending reports whether pod is Pending and younger than the grace period.
+func (t HealthThresholds) isRecentlyPending(pod k8s.PodInfo, now time.Time) bool {
+	return pod.Status == "Pending" && !pod.Created.IsZero() && now.Sub(pod.Created) < t.pendingGrace()
+}
+
+// severityIcon returns the text icon of a severity.
+func severityIcon(s report.Severity) string {
+	switch s {
+	case report.SeverityCritical:
+		return "❌"
+	case report.SeverityWarning:
+		return "⚠️ "
+	}
+	return "ℹ️ "
+}
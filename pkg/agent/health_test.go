@@ -0,0 +1,155 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/report"
+)
+
+func TestHealthThresholdsDefaults(t *testing.T) {
+	var th HealthThresholds
+	if th.criticalNotReadyNodes() != defaultCriticalNotReadyNodes || th.pendingGrace() != defaultPendingGrace {
+		t.Errorf("zero thresholds = %d, %s; want defaults", th.criticalNotReadyNodes(), th.pendingGrace())
+	}
+	th = HealthThresholds{CriticalNotReadyNodes: 3, PendingGrace: "5m"}
+	if th.criticalNotReadyNodes() != 3 || th.pendingGrace() != 5*time.Minute {
+		t.Errorf("thresholds = %d, %s; want 3, 5m", th.criticalNotReadyNodes(), th.pendingGrace())
+	}
+}
+
+func TestHealthThresholdsValidate(t *testing.T) {
+	for _, th := range []HealthThresholds{{}, {CriticalNotReadyNodes: 1, PendingGrace: "90s", ToleratedNotReadyPercent: 20, IgnoreNamespaces: []string{"dev-*"}}} {
+		if err := th.validate(); err != nil {
+			t.Errorf("validate(%+v) = %v", th, err)
+		}
+	}
+	for _, th := range []HealthThresholds{
+		{CriticalNotReadyNodes: -1}, {PendingGrace: "soon"}, {PendingGrace: "-1m"},
+		{ToleratedNotReadyPercent: 101}, {IgnoreNamespaces: []string{"dev-["}},
+		{Acknowledged: []Ack{{Kind: "service", Name: "web"}}},
+	} {
+		if err := th.validate(); err == nil {
+			t.Errorf("validate(%+v) should fail", th)
+		}
+	}
+}
+
+func TestNodeSeverity(t *testing.T) {
+	var th HealthThresholds
+	tests := []struct {
+		notReady, total int
+		want            report.Severity
+	}{
+		{1, 5, report.SeverityWarning},
+		{2, 5, report.SeverityCritical},
+		{1, 1, report.SeverityCritical},
+	}
+	for _, tt := range tests {
+		if got := th.nodeSeverity(tt.notReady, tt.total); got != tt.want {
+			t.Errorf("nodeSeverity(%d, %d) = %s, want %s", tt.notReady, tt.total, got, tt.want)
+		}
+	}
+	if got := (HealthThresholds{CriticalNotReadyNodes: 3}).nodeSeverity(2, 5); got != report.SeverityWarning {
+		t.Errorf("nodeSeverity with threshold 3 = %s, want warning", got)
+	}
+}
+
+func TestAnalyzeClusterHealthPendingPods(t *testing.T) {
+	now := time.Now()
+	statuses := []*k8s.ClusterStatus{{
+		ClusterInfo: k8s.ClusterInfo{Context: "prod", IsReachable: true},
+		NodeCount:   2, HealthyNodes: 2, PodCount: 10, HealthyPods: 8,
+		UnhealthyPods: []k8s.PodInfo{
+			{Name: "new", Namespace: "shop", Status: "Pending", Created: now.Add(-30 * time.Second)},
+			{Name: "old", Namespace: "shop", Status: "Pending", Created: now.Add(-10 * time.Minute)},
+		},
+	}}
+
+	summary := analyzeClusterHealth(statuses, HealthThresholds{})
+	if len(summary.issues) != 2 || summary.issues[0].Severity != report.SeverityWarning || summary.issues[1].Severity != report.SeverityInfo {
+		t.Fatalf("issues = %+v, want one warning and one info", summary.issues)
+	}
+	if summary.healthyCount != 0 || summary.totalUnhealthyPods != 2 {
+		t.Errorf("summary = %+v", summary)
+	}
+
+	statuses[0].UnhealthyPods = statuses[0].UnhealthyPods[:1]
+	statuses[0].HealthyPods = 9
+	summary = analyzeClusterHealth(statuses, HealthThresholds{})
+	if len(summary.issues) != 1 || summary.issues[0].Severity != report.SeverityInfo || summary.healthyCount != 1 {
+		t.Errorf("a recently Pending pod alone should be info and keep the cluster healthy: %+v", summary)
+	}
+
+	summary = analyzeClusterHealth(statuses, HealthThresholds{PendingGrace: "10s"})
+	if len(summary.issues) != 1 || summary.issues[0].Severity != report.SeverityWarning {
+		t.Errorf("Pending past the grace period should be a warning: %+v", summary.issues)
+	}
+}
+
+func TestWriteIssues(t *testing.T) {
+	var b strings.Builder
+	writeIssues(&b, nil)
+	if b.Len() != 0 {
+		t.Errorf("no issues should print nothing, got %q", b.String())
+	}
+	writeIssues(&b, []report.Issue{
+		{Severity: report.SeverityCritical, Cluster: "prod", Message: "UNREACHABLE - timeout"},
+		{Severity: report.SeverityInfo, Cluster: "dev", Message: "1 pod(s) Pending for less than 2m0s"},
+	})
+	out := b.String()
+	if !strings.Contains(out, "❌ prod: UNREACHABLE") || !strings.Contains(out, "ℹ️  dev: 1 pod(s) Pending") {
+		t.Errorf("writeIssues output = %q", out)
+	}
+}
+
+func TestToleratesNotReady(t *testing.T) {
+	th := HealthThresholds{ToleratedNotReadyPercent: 20}
+	tests := []struct {
+		notReady, total int
+		want            bool
+	}{
+		{1, 10, true},
+		{2, 10, true},
+		{3, 10, false},
+		{1, 1, false},
+	}
+	for _, tt := range tests {
+		if got := th.toleratesNotReady(tt.notReady, tt.total); got != tt.want {
+			t.Errorf("toleratesNotReady(%d, %d) = %v, want %v", tt.notReady, tt.total, got, tt.want)
+		}
+	}
+	if (HealthThresholds{}).toleratesNotReady(1, 10) {
+		t.Error("no NotReady node should be tolerated by default")
+	}
+}
+
+func TestAnalyzeClusterHealthThresholds(t *testing.T) {
+	statuses := []*k8s.ClusterStatus{{
+		ClusterInfo: k8s.ClusterInfo{Context: "dev", IsReachable: true},
+		NodeCount:   10, HealthyNodes: 9, PodCount: 10, HealthyPods: 8,
+		UnhealthyPods: []k8s.PodInfo{
+			{Name: "a", Namespace: "dev-alice", Status: "Failed"},
+			{Name: "b", Namespace: "sandbox", Status: "Failed"},
+		},
+	}}
+
+	summary := analyzeClusterHealth(statuses, HealthThresholds{})
+	if summary.healthyCount != 0 || summary.totalUnhealthyPods != 2 || len(degradedClusters(summary.issues)) != 1 {
+		t.Fatalf("default thresholds should degrade the cluster: %+v", summary)
+	}
+
+	th := HealthThresholds{ToleratedNotReadyPercent: 10, IgnoreNamespaces: []string{"dev-*", "sandbox"}}
+	summary = analyzeClusterHealth(statuses, th)
+	if summary.healthyCount != 1 || summary.totalUnhealthyPods != 0 {
+		t.Errorf("tolerated nodes and ignored namespaces should keep the cluster healthy: %+v", summary)
+	}
+	if len(summary.issues) != 1 || summary.issues[0].Severity != report.SeverityInfo || !strings.Contains(summary.issues[0].Message, "tolerated 10%") {
+		t.Errorf("issues = %+v, want one info about tolerated nodes", summary.issues)
+	}
+	if len(degradedClusters(summary.issues)) != 0 {
+		t.Error("info issues should not degrade a cluster")
+	}
+}
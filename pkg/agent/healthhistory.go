@@ -0,0 +1,360 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the on-disk health check history and the get_health_history tool.
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/debug"
+	"github.com/e9169/kopilot/pkg/llm"
+	"github.com/e9169/kopilot/pkg/report"
+)
+
+const (
+	defaultHealthHistoryWindow = "7d"
+	defaultHealthHistoryLimit  = 20
+	maxHealthHistoryLimit      = 500
+	// defaultHealthHistoryRetention is how long records are kept when
+	// KOPILOT_HEALTH_HISTORY=on; it matches the startup summary's window.
+	defaultHealthHistoryRetention = coldStartWindow
+	// maxHealthRecords caps the records kept per context, so frequent
+	// scheduled checks cannot grow a file without bound within the retention.
+	maxHealthRecords = 10000
+)
+
+// Health trend verdicts.
+const (
+	trendWorse     = "worse"
+	trendBetter    = "better"
+	trendMixed     = "mixed"
+	trendUnchanged = "unchanged"
+)
+
+// HealthRecord is the outcome of one health check of one cluster.
+type HealthRecord struct {
+	Time          time.Time `json:"time"`
+	Context       string    `json:"context"`
+	Cluster       string    `json:"cluster"`
+	Reachable     bool      `json:"reachable"`
+	Version       string    `json:"version,omitempty"`
+	Nodes         int       `json:"nodes"`
+	ReadyNodes    int       `json:"ready_nodes"`
+	Pods          int       `json:"pods"`
+	UnhealthyPods int       `json:"unhealthy_pods"`
+	Critical      int       `json:"critical"`
+	Warnings      int       `json:"warnings"`
+	Issues        []string  `json:"issues,omitempty"`
+}
+
+// healthHistoryDir returns the directory holding health records (~/.kopilot/history).
+// It is a variable so tests can redirect it.
+var healthHistoryDir = func() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kopilot", "history"), nil
+}
+
+// healthHistoryRetention returns how long health records are kept, and
+// whether they are recorded at all; see historyRetention.
+func healthHistoryRetention() (time.Duration, bool) {
+	return historyRetention("KOPILOT_HEALTH_HISTORY", defaultHealthHistoryRetention)
+}
+
+// healthHistoryEnabled reports whether health checks are recorded.
+func healthHistoryEnabled() bool {
+	_, enabled := healthHistoryRetention()
+	return enabled
+}
+
+// healthHistoryFile returns the JSONL file holding records for contextName.
+func healthHistoryFile(contextName string) (string, error) {
+	dir, err := healthHistoryDir()
+	if err != nil {
+		return "", err
+	}
+	return historyFile(dir, contextName)
+}
+
+// healthRecords builds one record per cluster of r.
+func healthRecords(r *ClusterReport) []HealthRecord {
+	records := make([]HealthRecord, 0, len(r.Clusters))
+	for _, status := range r.Clusters {
+		rec := HealthRecord{
+			Time:          r.GeneratedAt,
+			Context:       status.Context,
+			Cluster:       status.Name,
+			Reachable:     status.IsReachable,
+			Version:       status.Version,
+			Nodes:         status.NodeCount,
+			ReadyNodes:    status.HealthyNodes,
+			Pods:          status.PodCount,
+			UnhealthyPods: len(status.UnhealthyPods),
+		}
+		for _, issue := range r.Issues {
+			if issue.Cluster != status.Context {
+				continue
+			}
+			switch issue.Severity {
+			case report.SeverityCritical:
+				rec.Critical++
+			case report.SeverityWarning:
+				rec.Warnings++
+			}
+			rec.Issues = append(rec.Issues, issue.Message)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+// appendHealthRecord appends rec to its context's history file, first
+// dropping the records older than retention.
+func appendHealthRecord(rec HealthRecord, retention time.Duration) error {
+	path, err := healthHistoryFile(rec.Context)
+	if err != nil {
+		return fmt.Errorf("failed to open health history: %w", err)
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := appendHistoryLine(path, line, rec.Time.Add(-retention), maxHealthRecords); err != nil {
+		return fmt.Errorf("failed to write health history: %w", err)
+	}
+	return nil
+}
+
+// recordHealthHistory appends the result of a health check to the history
+// when recording is enabled. Failures only affect the history, so they are
+// logged under --debug=tools.
+func recordHealthHistory(r *ClusterReport) {
+	retention, enabled := healthHistoryRetention()
+	if !enabled {
+		return
+	}
+	for _, rec := range healthRecords(r) {
+		if err := appendHealthRecord(rec, retention); err != nil {
+			debug.Logf(debug.Tools, "health history for %s not recorded: %v", rec.Context, err)
+		}
+	}
+}
+
+// loadHealthRecords returns the records for contextName taken since `since`,
+// oldest first. Unparseable lines are skipped.
+func loadHealthRecords(contextName string, since time.Time) ([]HealthRecord, error) {
+	path, err := healthHistoryFile(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open health history: %w", err)
+	}
+	records, err := readHealthRecords(path, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read health history: %w", err)
+	}
+	return records, nil
+}
+
+// readHealthRecords returns the records of the history file at path taken
+// since `since`; nil when the file does not exist.
+func readHealthRecords(path string, since time.Time) ([]HealthRecord, error) {
+	// #nosec G304 -- path is built from the kopilot data dir and a sanitized context name
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	records := []HealthRecord{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var rec HealthRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil || rec.Time.Before(since) {
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// HealthTrend compares the oldest and latest record of a window.
+type HealthTrend struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Verdict string    `json:"verdict"`
+	Changes []string  `json:"changes"`
+}
+
+// compareHealth describes how the cluster changed from `from` to `to`.
+// Each metric is judged on its own; the verdict is mixed when some got
+// better and others worse.
+func compareHealth(from, to HealthRecord) *HealthTrend {
+	trend := &HealthTrend{From: from.Time, To: to.Time, Changes: []string{}}
+	worse, better := 0, 0
+	judge := func(name string, before, after int, higherIsWorse bool) {
+		if before == after {
+			return
+		}
+		trend.Changes = append(trend.Changes, fmt.Sprintf("%s %d → %d", name, before, after))
+		if (after > before) == higherIsWorse {
+			worse++
+		} else {
+			better++
+		}
+	}
+
+	if from.Reachable != to.Reachable {
+		if to.Reachable {
+			trend.Changes = append(trend.Changes, "cluster reachable again")
+			better++
+		} else {
+			trend.Changes = append(trend.Changes, "cluster became unreachable")
+			worse++
+		}
+	}
+	// Node and pod counts of an unreachable cluster are unknown.
+	if from.Reachable && to.Reachable {
+		judge("NotReady nodes", from.Nodes-from.ReadyNodes, to.Nodes-to.ReadyNodes, true)
+		judge("unhealthy pods", from.UnhealthyPods, to.UnhealthyPods, true)
+		if from.Nodes != to.Nodes {
+			trend.Changes = append(trend.Changes, fmt.Sprintf("nodes %d → %d", from.Nodes, to.Nodes))
+		}
+		if from.Version != to.Version && from.Version != "" && to.Version != "" {
+			trend.Changes = append(trend.Changes, fmt.Sprintf("version %s → %s", from.Version, to.Version))
+		}
+	}
+	judge("critical issues", from.Critical, to.Critical, true)
+	judge("warnings", from.Warnings, to.Warnings, true)
+
+	switch {
+	case worse > 0 && better > 0:
+		trend.Verdict = trendMixed
+	case worse > 0:
+		trend.Verdict = trendWorse
+	case better > 0:
+		trend.Verdict = trendBetter
+	default:
+		trend.Verdict = trendUnchanged
+	}
+	return trend
+}
+
+// GetHealthHistoryParams defines parameters for get_health_history
+type GetHealthHistoryParams struct {
+	Context string `json:"context" jsonschema:"The cluster context name (required)"`
+	Window  string `json:"window,omitempty" jsonschema:"How far back to look, e.g. 24h or 7d (default 7d)"`
+	Limit   int    `json:"limit,omitempty" jsonschema:"Maximum number of most recent checks to list (default 20)"`
+}
+
+// GetHealthHistoryResult is the outcome of get_health_history.
+type GetHealthHistoryResult struct {
+	Context string         `json:"context"`
+	Window  string         `json:"window"`
+	Total   int            `json:"total"`
+	Records []HealthRecord `json:"records"`
+	Trend   *HealthTrend   `json:"trend,omitempty"`
+}
+
+func defineGetHealthHistoryTool(state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolGetHealthHistory,
+		"Show the recorded health check history of a cluster (check_all_clusters and kopilot check runs, when recording is enabled) over a time window, with a trend comparing the oldest and latest check. Use this to answer questions like 'has this cluster gotten worse since yesterday?'.",
+		func(params GetHealthHistoryParams, _ llm.ToolInvocation) (any, error) {
+			return handleGetHealthHistory(state, params)
+		},
+	)
+}
+
+func handleGetHealthHistory(state *agentState, params GetHealthHistoryParams) (any, error) {
+	if params.Context == "" {
+		return nil, fmt.Errorf("context is required")
+	}
+	if params.Window == "" {
+		params.Window = defaultHealthHistoryWindow
+	}
+	window, err := parseWindow(params.Window)
+	if err != nil {
+		return nil, err
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultHealthHistoryLimit
+	}
+	if params.Limit < 0 || params.Limit > maxHealthHistoryLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxHealthHistoryLimit)
+	}
+
+	records, err := loadHealthRecords(params.Context, time.Now().Add(-window))
+	if err != nil {
+		return nil, err
+	}
+	result := GetHealthHistoryResult{
+		Context: params.Context,
+		Window:  params.Window,
+		Total:   len(records),
+		Records: records[max(0, len(records)-params.Limit):],
+	}
+	if len(records) >= 2 {
+		result.Trend = compareHealth(records[0], records[len(records)-1])
+	}
+	if result.Records == nil {
+		result.Records = []HealthRecord{}
+	}
+
+	if isJSONOutput(state.outputFormat) {
+		return result, nil
+	}
+	return formatHealthHistory(result), nil
+}
+
+// formatHealthHistory renders the history as a timeline followed by the trend.
+func formatHealthHistory(r GetHealthHistoryResult) string {
+	var b strings.Builder
+	if r.Total == 0 {
+		fmt.Fprintf(&b, "No health history for %s in the last %s. History is recorded by check_all_clusters and kopilot check when KOPILOT_HEALTH_HISTORY is on.\n", r.Context, r.Window)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "📈 Health history for %s (last %s, %d check(s)", r.Context, r.Window, r.Total)
+	if len(r.Records) < r.Total {
+		fmt.Fprintf(&b, ", latest %d shown", len(r.Records))
+	}
+	b.WriteString(")\n\n")
+
+	for _, rec := range r.Records {
+		when := rec.Time.Local().Format("2006-01-02 15:04")
+		if !rec.Reachable {
+			fmt.Fprintf(&b, "%s  ❌ unreachable\n", when)
+			continue
+		}
+		icon := "✅"
+		switch {
+		case rec.Critical > 0:
+			icon = "❌"
+		case rec.Warnings > 0:
+			icon = "⚠️ "
+		}
+		fmt.Fprintf(&b, "%s  %s %d/%d nodes ready, %d/%d pods unhealthy", when, icon, rec.ReadyNodes, rec.Nodes, rec.UnhealthyPods, rec.Pods)
+		if rec.Critical > 0 || rec.Warnings > 0 {
+			fmt.Fprintf(&b, " (%d critical, %d warning(s))", rec.Critical, rec.Warnings)
+		}
+		b.WriteString("\n")
+	}
+
+	if r.Trend != nil {
+		fmt.Fprintf(&b, "\n📊 Trend since %s: %s\n", r.Trend.From.Local().Format("2006-01-02 15:04"), r.Trend.Verdict)
+		for _, c := range r.Trend.Changes {
+			fmt.Fprintf(&b, "  - %s\n", c)
+		}
+	}
+	return b.String()
+}
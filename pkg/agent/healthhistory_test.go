@@ -0,0 +1,207 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/report"
+)
+
+// withHealthHistoryDir points the health history at a temporary directory.
+func withHealthHistoryDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	original := healthHistoryDir
+	t.Cleanup(func() { healthHistoryDir = original })
+	healthHistoryDir = func() (string, error) { return dir, nil }
+	return dir
+}
+
+func TestHealthRecords(t *testing.T) {
+	r := &ClusterReport{
+		GeneratedAt: time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC),
+		CheckAllClustersResult: CheckAllClustersResult{
+			Issues: []report.Issue{
+				{Severity: report.SeverityCritical, Cluster: "prod", Message: "1/3 nodes healthy"},
+				{Severity: report.SeverityWarning, Cluster: "prod", Message: "2/40 pods unhealthy"},
+				{Severity: report.SeverityInfo, Cluster: "prod", Message: "1 pod(s) Pending for less than 5m0s"},
+				{Severity: report.SeverityCritical, Cluster: "dev", Message: "UNREACHABLE - timeout"},
+			},
+			Clusters: []*k8s.ClusterStatus{
+				{ClusterInfo: k8s.ClusterInfo{Name: "prod-cluster", Context: "prod", IsReachable: true}, Version: "v1.31.0",
+					NodeCount: 3, HealthyNodes: 1, PodCount: 40, UnhealthyPods: make([]k8s.PodInfo, 3)},
+				{ClusterInfo: k8s.ClusterInfo{Name: "dev-cluster", Context: "dev"}},
+			},
+		},
+	}
+	records := healthRecords(r)
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	prod := records[0]
+	if prod.Context != "prod" || prod.Cluster != "prod-cluster" || !prod.Time.Equal(r.GeneratedAt) || !prod.Reachable ||
+		prod.ReadyNodes != 1 || prod.UnhealthyPods != 3 || prod.Critical != 1 || prod.Warnings != 1 || len(prod.Issues) != 3 {
+		t.Errorf("unexpected prod record: %+v", prod)
+	}
+	if dev := records[1]; dev.Reachable || dev.Critical != 1 || len(dev.Issues) != 1 {
+		t.Errorf("unexpected dev record: %+v", dev)
+	}
+}
+
+func TestHealthHistoryRoundTrip(t *testing.T) {
+	dir := withHealthHistoryDir(t)
+	now := time.Now()
+	for _, rec := range []HealthRecord{
+		{Time: now.Add(-48 * time.Hour), Context: "arn:aws:eks:x/prod", Reachable: true},
+		{Time: now, Context: "arn:aws:eks:x/prod", Reachable: true, UnhealthyPods: 2},
+	} {
+		if err := appendHealthRecord(rec, 30*24*time.Hour); err != nil {
+			t.Fatalf("appendHealthRecord: %v", err)
+		}
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "arn_aws_eks_x_prod-*.jsonl")); len(files) != 1 {
+		t.Errorf("history file should use a sanitized context name: %v", files)
+	}
+
+	records, err := loadHealthRecords("arn:aws:eks:x/prod", now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("loadHealthRecords: %v", err)
+	}
+	if len(records) != 1 || records[0].UnhealthyPods != 2 {
+		t.Errorf("unexpected records: %+v", records)
+	}
+
+	if records, err := loadHealthRecords("missing", time.Time{}); err != nil || records != nil {
+		t.Errorf("missing history = %v, %v; want nil, nil", records, err)
+	}
+}
+
+func TestHealthHistoryRetention(t *testing.T) {
+	dir := withHealthHistoryDir(t)
+	now := time.Now()
+	path, _ := healthHistoryFile("prod")
+	old := `{"time":"` + now.Add(-40*24*time.Hour).Format(time.RFC3339) + `","context":"prod"}` + "\n"
+	recent := `{"time":"` + now.Add(-time.Hour).Format(time.RFC3339) + `","context":"prod","unhealthy_pods":1}` + "\n"
+	if err := os.WriteFile(path, []byte(old+recent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := appendHealthRecord(HealthRecord{Time: now, Context: "prod", UnhealthyPods: 2}, 30*24*time.Hour); err != nil {
+		t.Fatalf("appendHealthRecord: %v", err)
+	}
+	records, err := loadHealthRecords("prod", time.Time{})
+	if err != nil {
+		t.Fatalf("loadHealthRecords: %v", err)
+	}
+	if len(records) != 2 || records[0].UnhealthyPods != 1 || records[1].UnhealthyPods != 2 {
+		t.Errorf("records older than the retention should be pruned: %+v", records)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("pruning left extra files: %v", entries)
+	}
+}
+
+func TestRecordHealthHistoryOptIn(t *testing.T) {
+	report := &ClusterReport{GeneratedAt: time.Now(), CheckAllClustersResult: CheckAllClustersResult{
+		Clusters: []*k8s.ClusterStatus{{ClusterInfo: k8s.ClusterInfo{Context: "prod"}}},
+	}}
+	for _, value := range []string{"", "off", "soon"} {
+		dir := withHealthHistoryDir(t)
+		t.Setenv("KOPILOT_HEALTH_HISTORY", value)
+		recordHealthHistory(report)
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("KOPILOT_HEALTH_HISTORY=%q: history recorded: %v", value, entries)
+		}
+	}
+
+	for value, want := range map[string]time.Duration{"on": defaultHealthHistoryRetention, "14d": 14 * 24 * time.Hour} {
+		t.Setenv("KOPILOT_HEALTH_HISTORY", value)
+		if got, enabled := healthHistoryRetention(); !enabled || got != want {
+			t.Errorf("KOPILOT_HEALTH_HISTORY=%q: retention = %v, %v; want %v", value, got, enabled, want)
+		}
+	}
+	withHealthHistoryDir(t)
+	recordHealthHistory(report)
+	if records, _ := loadHealthRecords("prod", time.Time{}); len(records) != 1 {
+		t.Errorf("history not recorded when enabled: %+v", records)
+	}
+}
+
+func TestCompareHealth(t *testing.T) {
+	base := HealthRecord{Reachable: true, Version: "v1.30.0", Nodes: 3, ReadyNodes: 3, UnhealthyPods: 1}
+	tests := []struct {
+		name    string
+		to      func(r HealthRecord) HealthRecord
+		verdict string
+		change  string
+	}{
+		{"unchanged", func(r HealthRecord) HealthRecord { return r }, trendUnchanged, ""},
+		{"more unhealthy pods", func(r HealthRecord) HealthRecord { r.UnhealthyPods, r.Warnings = 4, 1; return r }, trendWorse, "unhealthy pods 1 → 4"},
+		{"node recovered", func(r HealthRecord) HealthRecord { r.ReadyNodes, r.UnhealthyPods = 3, 0; return r }, trendBetter, "unhealthy pods 1 → 0"},
+		{"mixed", func(r HealthRecord) HealthRecord { r.ReadyNodes, r.UnhealthyPods = 2, 0; return r }, trendMixed, "NotReady nodes 0 → 1"},
+		{"unreachable", func(r HealthRecord) HealthRecord { r.Reachable, r.Critical = false, 1; return r }, trendWorse, "cluster became unreachable"},
+		{"upgraded", func(r HealthRecord) HealthRecord { r.Version = "v1.31.0"; return r }, trendUnchanged, "version v1.30.0 → v1.31.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trend := compareHealth(base, tt.to(base))
+			if trend.Verdict != tt.verdict {
+				t.Errorf("verdict = %s, want %s (changes %v)", trend.Verdict, tt.verdict, trend.Changes)
+			}
+			if tt.change != "" && !strings.Contains(strings.Join(trend.Changes, "; "), tt.change) {
+				t.Errorf("changes %v should contain %q", trend.Changes, tt.change)
+			}
+		})
+	}
+}
+
+func TestHandleGetHealthHistory(t *testing.T) {
+	withHealthHistoryDir(t)
+	now := time.Now()
+	for i, unhealthy := range []int{0, 1, 5} {
+		rec := HealthRecord{Time: now.Add(time.Duration(i-3) * time.Hour), Context: "prod", Reachable: true, Nodes: 3, ReadyNodes: 3, Pods: 40, UnhealthyPods: unhealthy}
+		if err := appendHealthRecord(rec, 30*24*time.Hour); err != nil {
+			t.Fatalf("appendHealthRecord: %v", err)
+		}
+	}
+
+	state := &agentState{outputFormat: OutputJSON}
+	out, err := handleGetHealthHistory(state, GetHealthHistoryParams{Context: "prod", Window: "24h", Limit: 2})
+	if err != nil {
+		t.Fatalf("handleGetHealthHistory: %v", err)
+	}
+	result := out.(GetHealthHistoryResult)
+	if result.Total != 3 || len(result.Records) != 2 || result.Records[1].UnhealthyPods != 5 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if result.Trend == nil || result.Trend.Verdict != trendWorse {
+		t.Errorf("trend = %+v, want worse", result.Trend)
+	}
+
+	state.outputFormat = OutputText
+	out, err = handleGetHealthHistory(state, GetHealthHistoryParams{Context: "prod"})
+	if err != nil {
+		t.Fatalf("handleGetHealthHistory: %v", err)
+	}
+	text := out.(string)
+	for _, want := range []string{"Health history for prod (last 7d, 3 check(s))", "3/3 nodes ready, 5/40 pods unhealthy", "Trend since", "worse", "unhealthy pods 0 → 5"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+
+	out, err = handleGetHealthHistory(state, GetHealthHistoryParams{Context: "staging"})
+	if err != nil || !strings.Contains(out.(string), "No health history for staging") {
+		t.Errorf("empty history = %v, %v", out, err)
+	}
+
+	for _, params := range []GetHealthHistoryParams{{}, {Context: "prod", Window: "soon"}, {Context: "prod", Limit: -1}} {
+		if _, err := handleGetHealthHistory(state, params); err == nil {
+			t.Errorf("expected error for %+v", params)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the per-context JSONL files shared by the health and
+// usage histories: opt-in retention, collision-free names and pruning.
+package agent
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/debug"
+)
+
+// historyRetention reads the opt-in setting of a history from the
+// environment variable env and returns how long records are kept, and
+// whether they are recorded at all: on keeps def, a window such as 14d or
+// 72h keeps that long, and unset, off or invalid values disable recording.
+func historyRetention(env string, def time.Duration) (time.Duration, bool) {
+	value := strings.ToLower(strings.TrimSpace(os.Getenv(env)))
+	switch value {
+	case "", "off", "false", "0":
+		return 0, false
+	case "on", "true", "1":
+		return def, true
+	}
+	d, err := parseWindow(value)
+	if err != nil {
+		debug.Logf(debug.Tools, "history disabled: %s: %v", env, err)
+		return 0, false
+	}
+	return d, true
+}
+
+// historyFile returns the JSONL file of contextName in dir, creating dir.
+// The sanitized name keeps the file recognizable; the hash of the full name
+// keeps contexts that sanitize alike (prod:a and prod/a) apart.
+func historyFile(dir, contextName string) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(contextName))
+	name := unsafeFileChars.ReplaceAllString(contextName, "_") + "-" + hex.EncodeToString(sum[:4]) + ".jsonl"
+	return filepath.Join(dir, name), nil
+}
+
+var (
+	// historyMu serializes writes to the history files.
+	historyMu sync.Mutex
+	// prunedHistory holds the files pruned by this process; each is pruned
+	// before its first append, which bounds it for scheduled runs and long
+	// sessions alike.
+	prunedHistory = make(map[string]bool)
+)
+
+// appendHistoryLine appends line to the history file at path, first
+// dropping the records taken before `before` and all but the latest
+// maxRecords.
+func appendHistoryLine(path string, line []byte, before time.Time, maxRecords int) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	if !prunedHistory[path] {
+		if err := pruneHistoryFile(path, before, maxRecords); err != nil {
+			return err
+		}
+		prunedHistory[path] = true
+	}
+	// #nosec G304 -- path is built from the kopilot data dir and a sanitized context name
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, writeErr := f.Write(append(line, '\n'))
+	return errors.Join(writeErr, f.Close())
+}
+
+// pruneHistoryFile rewrites the history file at path without the records
+// taken before `before` or unparseable, keeping at most maxRecords of the
+// latest ones. Records are JSON objects with a "time" field.
+func pruneHistoryFile(path string, before time.Time, maxRecords int) error {
+	// #nosec G304 -- path is built from the kopilot data dir and a sanitized context name
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var kept [][]byte
+	for line := range strings.SplitSeq(string(data), "\n") {
+		var rec struct {
+			Time time.Time `json:"time"`
+		}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil || rec.Time.Before(before) {
+			continue
+		}
+		kept = append(kept, []byte(line))
+	}
+	kept = kept[max(0, len(kept)-maxRecords):]
+
+	tmp := path + ".tmp"
+	// #nosec G304 -- path is built from the kopilot data dir and a sanitized context name
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, line := range kept {
+		if _, err = w.Write(append(line, '\n')); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err = errors.Join(err, f.Close()); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryRetention(t *testing.T) {
+	tests := map[string]struct {
+		retention time.Duration
+		enabled   bool
+	}{
+		"":     {0, false},
+		"off":  {0, false},
+		"soon": {0, false},
+		"on":   {time.Hour, true},
+		"true": {time.Hour, true},
+		"14d":  {14 * 24 * time.Hour, true},
+		"72h":  {72 * time.Hour, true},
+	}
+	for value, want := range tests {
+		t.Setenv("KOPILOT_TEST_HISTORY", value)
+		if retention, enabled := historyRetention("KOPILOT_TEST_HISTORY", time.Hour); retention != want.retention || enabled != want.enabled {
+			t.Errorf("%q: historyRetention = %v, %t; want %v, %t", value, retention, enabled, want.retention, want.enabled)
+		}
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	dir := t.TempDir()
+	a, _ := historyFile(dir, "prod:a")
+	b, _ := historyFile(dir, "prod/a")
+	if a == b || !strings.HasPrefix(filepath.Base(a), "prod_a-") {
+		t.Errorf("historyFile = %s and %s, want distinct files named after the context", a, b)
+	}
+}
+
+func TestAppendHistoryLinePrunes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prod.jsonl")
+	now := time.Now()
+	var lines []string
+	for i := 5; i > 0; i-- {
+		lines = append(lines, fmt.Sprintf(`{"time":%q,"n":%d}`, now.Add(-time.Duration(i)*time.Hour).Format(time.RFC3339), i))
+	}
+	lines = append(lines, "not json")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Records older than 4h go, then all but the latest 2 of the rest.
+	if err := appendHistoryLine(path, []byte(`{"time":"`+now.Format(time.RFC3339)+`","n":0}`), now.Add(-4*time.Hour-time.Minute), 2); err != nil {
+		t.Fatalf("appendHistoryLine: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	got := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(got) != 3 || !strings.Contains(got[0], `"n":2`) || !strings.Contains(got[1], `"n":1`) || !strings.Contains(got[2], `"n":0`) {
+		t.Errorf("pruned history = %q", got)
+	}
+
+	// A file is pruned once per process; later appends only append.
+	if err := appendHistoryLine(path, []byte(`{"time":"`+now.Format(time.RFC3339)+`","n":-1}`), now, 1); err != nil {
+		t.Fatalf("appendHistoryLine: %v", err)
+	}
+	if data, _ := os.ReadFile(path); strings.Count(string(data), "\n") != 4 {
+		t.Errorf("second append pruned again: %q", data)
+	}
+}
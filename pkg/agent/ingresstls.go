@@ -0,0 +1,92 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the check_ingress_tls tool (Ingress certificates and DNS).
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// CheckIngressTLSParams defines parameters for check_ingress_tls
+type CheckIngressTLSParams struct {
+	Context   string `json:"context" jsonschema:"The context name of the cluster to inspect (from list_clusters)"`
+	Namespace string `json:"namespace,omitempty" jsonschema:"Optional: only Ingresses in this namespace; leave empty for all namespaces"`
+	Host      string `json:"host,omitempty" jsonschema:"Optional: only this host, e.g. shop.example.com"`
+	Probe     bool   `json:"probe,omitempty" jsonschema:"If true, also resolve each host in DNS and fetch the certificate it serves on port 443, from this machine (outside the cluster)"`
+}
+
+func defineCheckIngressTLSTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolCheckIngressTLS,
+		"Check the TLS certificates of Ingress hosts: that the certificate in each TLS secret covers the host and is not expired or about to expire. With probe, also resolves each host in DNS from this machine and compares it with the Ingress address, and fetches the certificate actually served on port 443 to confirm it is trusted, valid and the one in the secret. Use for \"why does the site show a certificate error?\". Read-only.",
+		func(params CheckIngressTLSParams, inv llm.ToolInvocation) (any, error) {
+			if _, err := getClusterForContext(k8sProvider, params.Context); err != nil {
+				return nil, err
+			}
+			if params.Namespace != "" && !isValidKubernetesName(params.Namespace) {
+				return nil, fmt.Errorf("invalid namespace name: %s", params.Namespace)
+			}
+			report, err := k8sProvider.CheckIngressTLS(inv.Ctx(), params.Context, params.Namespace, params.Host, params.Probe)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check ingress TLS: %w", err)
+			}
+			if params.Host != "" && len(report.Hosts) == 0 {
+				return nil, fmt.Errorf("no Ingress in %s serves host %s", params.Context, params.Host)
+			}
+			if isJSONOutput(state.outputFormat) {
+				return report, nil
+			}
+			return formatIngressTLSReport(report), nil
+		},
+	)
+}
+
+// formatIngressTLSReport formats an IngressTLSReport as human-readable text
+func formatIngressTLSReport(report *k8s.IngressTLSReport) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🔐 Ingress TLS in %s: %d host(s), %d with problems\n\n", report.Context, len(report.Hosts), report.Failing)
+	if len(report.Hosts) == 0 {
+		sb.WriteString("No Ingress hosts found.\n")
+	}
+	for _, c := range report.Hosts {
+		icon := "✅"
+		if len(c.Problems) > 0 {
+			icon = "❌"
+		}
+		fmt.Fprintf(&sb, "%s %s (ingress %s/%s)", icon, c.Host, c.Namespace, c.Ingress)
+		if c.TLSSecret == "" {
+			sb.WriteString(" - no TLS")
+		}
+		sb.WriteString("\n")
+		if c.SecretCert != nil {
+			fmt.Fprintf(&sb, "   secret %s: %s, issuer %s, expires %s\n", c.TLSSecret, c.SecretCert.Subject, c.SecretCert.Issuer, certExpiry(c.SecretCert.NotAfter))
+		}
+		if c.Probed {
+			fmt.Fprintf(&sb, "   DNS: %s", strings.Join(c.ResolvedIPs, ", "))
+			if len(c.Addresses) > 0 {
+				fmt.Fprintf(&sb, " (ingress address %s)", strings.Join(c.Addresses, ", "))
+			}
+			sb.WriteString("\n")
+		}
+		if c.ServedCert != nil {
+			fmt.Fprintf(&sb, "   served: %s, issuer %s, expires %s\n", c.ServedCert.Subject, c.ServedCert.Issuer, certExpiry(c.ServedCert.NotAfter))
+		}
+		for _, p := range c.Problems {
+			fmt.Fprintf(&sb, "   ⚠️  %s\n", p)
+		}
+	}
+	return sb.String()
+}
+
+// certExpiry formats an expiry date with the days left.
+func certExpiry(notAfter time.Time) string {
+	left := time.Until(notAfter)
+	if left < 0 {
+		return fmt.Sprintf("%s (expired)", notAfter.UTC().Format(time.DateOnly))
+	}
+	return fmt.Sprintf("%s (%d days)", notAfter.UTC().Format(time.DateOnly), int(left.Hours()/24))
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestFormatIngressTLSReport(t *testing.T) {
+	notAfter := time.Now().Add(90 * 24 * time.Hour)
+	report := &k8s.IngressTLSReport{
+		Context: "prod",
+		Failing: 1,
+		Hosts: []k8s.IngressHostCheck{
+			{
+				Namespace: "shop", Ingress: "web", Host: "shop.example.com", TLSSecret: "web-tls",
+				Addresses:  []string{"203.0.113.10"},
+				SecretCert: &k8s.CertInfo{Subject: "shop.example.com", Issuer: "R3", NotAfter: notAfter},
+				Probed:     true, ResolvedIPs: []string{"198.51.100.7"},
+				ServedCert: &k8s.CertInfo{Subject: "Kubernetes Ingress Controller Fake Certificate", Issuer: "Kubernetes Ingress Controller Fake Certificate", NotAfter: notAfter},
+				Problems:   []string{"shop.example.com resolves to 198.51.100.7, not the ingress address 203.0.113.10"},
+			},
+			{Namespace: "docs", Ingress: "docs", Host: "docs.example.com"},
+		},
+	}
+	out := formatIngressTLSReport(report)
+	for _, want := range []string{
+		"prod: 2 host(s), 1 with problems",
+		"❌ shop.example.com (ingress shop/web)",
+		"secret web-tls: shop.example.com, issuer R3",
+		"DNS: 198.51.100.7 (ingress address 203.0.113.10)",
+		"served: Kubernetes Ingress Controller Fake Certificate",
+		"⚠️  shop.example.com resolves to 198.51.100.7",
+		"✅ docs.example.com (ingress docs/docs) - no TLS",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestFormatIngressTLSReportEmpty(t *testing.T) {
+	out := formatIngressTLSReport(&k8s.IngressTLSReport{Context: "dev"})
+	if !strings.Contains(out, "No Ingress hosts found") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestCertExpiry(t *testing.T) {
+	if got := certExpiry(time.Now().Add(-time.Hour)); !strings.HasSuffix(got, "(expired)") {
+		t.Errorf("certExpiry(past) = %q", got)
+	}
+	if got := certExpiry(time.Now().Add(10*24*time.Hour + time.Hour)); !strings.HasSuffix(got, "(10 days)") {
+		t.Errorf("certExpiry(10 days) = %q", got)
+	}
+}
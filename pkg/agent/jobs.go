@@ -0,0 +1,91 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the check_jobs tool (failed Jobs, long-running Jobs and missed CronJob schedules).
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+const maxLongRunningMinutes = 7 * 24 * 60
+
+// CheckJobsParams defines parameters for check_jobs
+type CheckJobsParams struct {
+	Context            string `json:"context" jsonschema:"The context name of the cluster to check (from list_clusters)"`
+	Namespace          string `json:"namespace,omitempty" jsonschema:"Optional: restrict the check to a specific namespace; leave empty for all non-system namespaces"`
+	LongRunningMinutes int    `json:"long_running_minutes,omitempty" jsonschema:"Report active Jobs running longer than this many minutes (default 60, max 10080)"`
+	IncludeSystem      bool   `json:"include_system,omitempty" jsonschema:"If true, include system namespaces (kube-system, kube-public, kube-node-lease)"`
+}
+
+func defineCheckJobsTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolCheckJobs,
+		"Batch workload health: failed Jobs (with the failure reason, leaving out CronJob runs a later run recovered), Jobs running longer than a threshold, and CronJobs that are suspended, have an invalid schedule or missed scheduled runs (with the likely cause: concurrencyPolicy Forbid, startingDeadlineSeconds or too many missed starts). Use for questions like 'did last night's backups run' or 'which jobs are failing'. Read-only.",
+		func(params CheckJobsParams, inv llm.ToolInvocation) (any, error) {
+			if params.Namespace != "" && !isValidKubernetesName(params.Namespace) {
+				return nil, fmt.Errorf("invalid namespace name: %s", params.Namespace)
+			}
+			if params.LongRunningMinutes < 0 || params.LongRunningMinutes > maxLongRunningMinutes {
+				return nil, fmt.Errorf("long_running_minutes must be between 1 and %d", maxLongRunningMinutes)
+			}
+			report, err := k8sProvider.CheckJobs(inv.Ctx(), params.Context, params.Namespace,
+				time.Duration(params.LongRunningMinutes)*time.Minute, params.IncludeSystem)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check jobs: %w", err)
+			}
+
+			if isJSONOutput(state.outputFormat) {
+				return report, nil
+			}
+			return formatJobsReport(report), nil
+		},
+	)
+}
+
+// jobIssueLabels maps job issues to text-mode headings.
+var jobIssueLabels = map[string]string{
+	k8s.JobIssueFailed:          "❌ FAILED",
+	k8s.JobIssueMissedSchedule:  "⏰ MISSED SCHEDULE",
+	k8s.JobIssueInvalidSchedule: "⚠️  INVALID SCHEDULE",
+	k8s.JobIssueLongRunning:     "🐢 LONG-RUNNING",
+	k8s.JobIssueSuspended:       "⏸️  SUSPENDED",
+}
+
+// formatJobsReport formats a JobsReport as human-readable text
+func formatJobsReport(report *k8s.JobsReport) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Jobs: %s", report.Context)
+	if report.Namespace != "" {
+		fmt.Fprintf(&sb, " (namespace %s)", report.Namespace)
+	}
+	sb.WriteString("\n" + strings.Repeat("=", 80) + "\n\n")
+	fmt.Fprintf(&sb, "📋 %d Job(s) and %d CronJob(s) checked; long-running threshold %s\n", report.Jobs, report.CronJobs, report.LongRunning)
+
+	if len(report.Findings) == 0 {
+		sb.WriteString("\n✅ No failed, long-running, suspended or late jobs.\n")
+		return sb.String()
+	}
+
+	issue := ""
+	for _, f := range report.Findings {
+		if f.Issue != issue {
+			issue = f.Issue
+			fmt.Fprintf(&sb, "\n%s (%d)\n", jobIssueLabels[issue], report.Issues[issue])
+		}
+		name := strings.ToLower(f.Kind) + "/" + f.Name
+		if f.CronJob != "" {
+			name += " (cronjob/" + f.CronJob + ")"
+		}
+		reason := ""
+		if f.Reason != "" {
+			reason = "  [" + f.Reason + "]"
+		}
+		fmt.Fprintf(&sb, "   %s/%s%s\n", f.Namespace, name, reason)
+		fmt.Fprintf(&sb, "     %s\n", f.Message)
+	}
+	return sb.String()
+}
@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+func TestFormatJobsReport(t *testing.T) {
+	report := &k8s.JobsReport{
+		Context: "prod", Namespace: "batch", LongRunning: "1h0m0s", Jobs: 4, CronJobs: 2,
+		Issues: map[string]int{k8s.JobIssueFailed: 1, k8s.JobIssueMissedSchedule: 1},
+		Findings: []k8s.JobFinding{
+			{Issue: k8s.JobIssueFailed, Kind: "Job", Namespace: "batch", Name: "backup-29", CronJob: "backup", Reason: "BackoffLimitExceeded", Message: "6 pod(s) failed"},
+			{Issue: k8s.JobIssueMissedSchedule, Kind: "CronJob", Namespace: "batch", Name: "nightly", Message: "2 scheduled run(s) missed"},
+		},
+	}
+	text := formatJobsReport(report)
+	for _, want := range []string{
+		"Jobs: prod (namespace batch)",
+		"4 Job(s) and 2 CronJob(s) checked; long-running threshold 1h0m0s",
+		"❌ FAILED (1)",
+		"batch/job/backup-29 (cronjob/backup)  [BackoffLimitExceeded]",
+		"⏰ MISSED SCHEDULE (1)",
+		"batch/cronjob/nightly\n     2 scheduled run(s) missed",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+
+	if text := formatJobsReport(&k8s.JobsReport{Context: "dev"}); !strings.Contains(text, "No failed, long-running") {
+		t.Errorf("empty report output:\n%s", text)
+	}
+}
+
+func TestCheckJobsToolValidation(t *testing.T) {
+	tool := defineCheckJobsTool(newTestK8sProvider(t), &agentState{})
+	for _, args := range []map[string]any{
+		{"context": "test-context", "namespace": "Bad_NS"},
+		{"context": "test-context", "long_running_minutes": -1},
+		{"context": "test-context", "long_running_minutes": 20000},
+	} {
+		if _, err := tool.Handler(args, llm.ToolInvocation{Context: context.Background()}); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
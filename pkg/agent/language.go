@@ -0,0 +1,140 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the reply language setting and prompt language detection.
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// languageAuto follows the language of each prompt.
+const languageAuto = "auto"
+
+// languageNames maps ISO 639-1 codes to the language names used in the system message.
+var languageNames = map[string]string{
+	"en": "English", "es": "Spanish", "fr": "French", "de": "German",
+	"pt": "Portuguese", "it": "Italian", "nl": "Dutch", "pl": "Polish",
+	"sv": "Swedish", "ru": "Russian", "uk": "Ukrainian", "tr": "Turkish",
+	"ja": "Japanese", "zh": "Chinese", "ko": "Korean",
+}
+
+// languageNameRe matches a free-form language name such as "Catalan".
+var languageNameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z -]{1,30}$`)
+
+// stopwords are frequent words identifying Latin-script languages.
+var stopwords = map[string][]string{
+	"English":    {"the", "is", "are", "what", "why", "how", "my", "show", "with", "and", "all", "which", "there"},
+	"Spanish":    {"el", "la", "los", "las", "que", "por", "para", "con", "una", "está", "están", "qué", "cómo", "hay", "mis", "muestra", "todos"},
+	"French":     {"le", "les", "des", "est", "une", "pour", "dans", "avec", "pourquoi", "comment", "sont", "mes", "du", "montre", "tous"},
+	"German":     {"der", "die", "das", "und", "ist", "nicht", "mit", "für", "ein", "eine", "warum", "wie", "sind", "meine", "zeige", "alle"},
+	"Portuguese": {"os", "não", "com", "uma", "para", "por", "está", "estão", "meus", "porque", "são", "da", "mostre", "todos"},
+	"Italian":    {"il", "gli", "che", "non", "con", "una", "per", "sono", "perché", "come", "della", "mostra", "tutti"},
+	"Dutch":      {"het", "een", "en", "niet", "met", "voor", "waarom", "hoe", "zijn", "mijn", "van", "toon", "alle"},
+}
+
+// ParseLanguage normalizes the --language value: "auto" (or empty) follows
+// the prompt, an ISO code such as "es" or a name such as "Spanish" fixes the
+// reply language. It returns the language name, or "" for auto.
+func ParseLanguage(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.EqualFold(value, languageAuto) {
+		return "", nil
+	}
+	if name, ok := languageNames[strings.ToLower(value)]; ok {
+		return name, nil
+	}
+	if !languageNameRe.MatchString(value) {
+		return "", fmt.Errorf("unsupported language %q (use auto, a code such as es or a name such as Spanish)", value)
+	}
+	return strings.ToUpper(value[:1]) + strings.ToLower(value[1:]), nil
+}
+
+// WithLanguage fixes the reply language; an empty language follows the prompt.
+func WithLanguage(language string) Option {
+	return func(s *agentState) {
+		s.language = language
+	}
+}
+
+// languageDirective returns the system message section on the reply language.
+func languageDirective(language string) string {
+	keep := "Keep kubectl commands, resource names, YAML, log lines and tool output exactly as they are; translate only your own explanations."
+	if language == "" {
+		return "REPLY LANGUAGE: Reply in the language of the user's latest message. " + keep
+	}
+	return fmt.Sprintf("REPLY LANGUAGE: Always reply in %s, whatever language the user writes in. %s", language, keep)
+}
+
+// detectLanguage guesses the language of text from its script or, for Latin
+// script, its most frequent words. It returns "" when unsure.
+func detectLanguage(text string) string {
+	var kana, hangul, han, cyrillic, ukrainian int
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				ukrainian++
+			}
+		}
+	}
+	switch {
+	case kana > 0:
+		return "Japanese"
+	case hangul > 0:
+		return "Korean"
+	case han > 0:
+		return "Chinese"
+	case ukrainian > 0:
+		return "Ukrainian"
+	case cyrillic > 0:
+		return "Russian"
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) })
+	scores := make(map[string]int, len(stopwords))
+	for _, w := range words {
+		for language, list := range stopwords {
+			for _, s := range list {
+				if w == s {
+					scores[language]++
+				}
+			}
+		}
+	}
+	best, bestScore, tie := "", 0, false
+	for language, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tie = language, score, false
+		case score == bestScore:
+			tie = true
+		}
+	}
+	if bestScore < 2 || tie {
+		return ""
+	}
+	return best
+}
+
+// languageHint returns a note appended to the prompt asking for a reply in
+// the detected language; it is empty for English, undetected text or when
+// the reply language is fixed.
+func languageHint(language, text string) string {
+	if language != "" {
+		return ""
+	}
+	detected := detectLanguage(text)
+	if detected == "" || detected == "English" {
+		return ""
+	}
+	return fmt.Sprintf("\n\n(Reply in %s.)", detected)
+}
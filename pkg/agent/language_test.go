@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLanguage(t *testing.T) {
+	tests := map[string]string{
+		"":         "",
+		"auto":     "",
+		"AUTO":     "",
+		"es":       "Spanish",
+		"JA":       "Japanese",
+		"german":   "German",
+		"Catalan":  "Catalan",
+		" french ": "French",
+	}
+	for in, want := range tests {
+		if got, err := ParseLanguage(in); err != nil || got != want {
+			t.Errorf("ParseLanguage(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"es;rm -rf", "x", "123"} {
+		if _, err := ParseLanguage(in); err == nil {
+			t.Errorf("ParseLanguage(%q) should fail", in)
+		}
+	}
+}
+
+func TestLanguageDirective(t *testing.T) {
+	if d := languageDirective(""); !strings.Contains(d, "language of the user's latest message") || !strings.Contains(d, "kubectl commands") {
+		t.Errorf("auto directive = %q", d)
+	}
+	if d := languageDirective("German"); !strings.Contains(d, "Always reply in German") {
+		t.Errorf("fixed directive = %q", d)
+	}
+	if msg := buildSystemMessage(AgentDebugger, "Spanish"); !strings.Contains(msg, "Always reply in Spanish") {
+		t.Error("system message should contain the language directive")
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := map[string]string{
+		"why are all the pods in my cluster crashing":      "English",
+		"¿por qué los pods están fallando en el clúster?":  "Spanish",
+		"pourquoi les pods sont en erreur dans le cluster": "French",
+		"warum sind die Pods nicht bereit":                 "German",
+		"por que os pods não estão prontos":                "Portuguese",
+		"perché i pod non sono pronti":                     "Italian",
+		"waarom zijn de pods niet klaar":                   "Dutch",
+		"ポッドが起動しないのはなぜですか":                                 "Japanese",
+		"为什么集群里的 pod 在崩溃":                                  "Chinese",
+		"클러스터 상태를 보여줘":                                     "Korean",
+		"почему поды не запускаются":                       "Russian",
+		"чому поди не запускаються і падають":              "Ukrainian",
+		"kubectl get pods":                                 "",
+		"":                                                 "",
+	}
+	for in, want := range tests {
+		if got := detectLanguage(in); got != want {
+			t.Errorf("detectLanguage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLanguageHint(t *testing.T) {
+	if hint := languageHint("", "warum sind die Pods nicht bereit"); hint != "\n\n(Reply in German.)" {
+		t.Errorf("hint = %q", hint)
+	}
+	for _, tc := range [][2]string{{"", "why are the pods crashing"}, {"", "kubectl get pods"}, {"French", "warum sind die Pods nicht bereit"}} {
+		if hint := languageHint(tc[0], tc[1]); hint != "" {
+			t.Errorf("languageHint(%q, %q) = %q, want none", tc[0], tc[1], hint)
+		}
+	}
+}
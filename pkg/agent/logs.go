@@ -0,0 +1,100 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the query_logs tool (historical logs from Loki or Elasticsearch).
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// QueryLogsParams defines parameters for query_logs
+type QueryLogsParams struct {
+	Context   string `json:"context" jsonschema:"The cluster context name (required)"`
+	Namespace string `json:"namespace,omitempty" jsonschema:"Namespace of the pods"`
+	Pod       string `json:"pod,omitempty" jsonschema:"Pod name, or a prefix ending in * to match every pod of a workload (e.g. web-7d9f*), including pods that no longer exist"`
+	Container string `json:"container,omitempty" jsonschema:"Container name"`
+	Contains  string `json:"contains,omitempty" jsonschema:"Only lines containing this text (case-sensitive), e.g. panic or OOM"`
+	Query     string `json:"query,omitempty" jsonschema:"Raw LogQL (Loki) or Lucene (Elasticsearch) query, replacing namespace/pod/container/contains"`
+	Since     string `json:"since,omitempty" jsonschema:"Look-back window, e.g. 30m, 6h or 2d (default: 1h)"`
+	Limit     int    `json:"limit,omitempty" jsonschema:"Maximum number of lines, latest first kept (default: 100, max: 500)"`
+}
+
+func defineQueryLogsTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolQueryLogs,
+		"Search historical logs of a cluster in its log aggregation backend (Loki or Elasticsearch, configured per context in ~/.kopilot/config.json). Unlike kubectl logs this reaches pods that crashed, were evicted or replaced, and any point within the backend's retention. Filter by namespace, pod (or pod prefix), container and text. Read-only.",
+		func(params QueryLogsParams, inv llm.ToolInvocation) (any, error) {
+			return handleQueryLogs(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+func handleQueryLogs(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params QueryLogsParams) (any, error) {
+	if params.Context == "" {
+		return nil, fmt.Errorf("context is required")
+	}
+	if params.Query == "" && params.Namespace == "" && params.Pod == "" {
+		return nil, fmt.Errorf("namespace or pod is required unless query is given")
+	}
+	if params.Limit < 0 || params.Limit > k8s.MaxLogEntries {
+		return nil, fmt.Errorf("limit must be between 1 and %d", k8s.MaxLogEntries)
+	}
+	q := k8s.LogQuery{
+		Namespace: params.Namespace,
+		Pod:       params.Pod,
+		Container: params.Container,
+		Contains:  params.Contains,
+		Query:     params.Query,
+		Limit:     params.Limit,
+	}
+	if params.Since != "" {
+		d, err := parseWindow(params.Since)
+		if err != nil {
+			return nil, fmt.Errorf("invalid since %q", params.Since)
+		}
+		q.Since = d
+	}
+	if _, err := getClusterForContext(k8sProvider, params.Context); err != nil {
+		return nil, err
+	}
+	endpoint, ok := state.logs[params.Context]
+	if !ok {
+		return nil, fmt.Errorf("no log backend configured for context %q; add it under \"logs\" in ~/.kopilot/config.json, or use kubectl logs (--previous for the last crash)", params.Context)
+	}
+
+	result, err := k8sProvider.QueryLogs(ctx, params.Context, endpoint, q)
+	if err != nil {
+		return nil, err
+	}
+	if isJSONOutput(state.outputFormat) {
+		return result, nil
+	}
+	return formatLogResult(result), nil
+}
+
+// formatLogResult renders one line per entry, prefixed with its time and pod.
+func formatLogResult(r *k8s.LogResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📜 Logs on %s (%s): %s\n\n", r.Context, r.Endpoint, r.Query)
+	if len(r.Entries) == 0 {
+		b.WriteString("  (no matching lines)\n")
+	}
+	for _, e := range r.Entries {
+		source := e.Pod
+		if source != "" && e.Container != "" {
+			source += "/" + e.Container
+		}
+		if source != "" {
+			source = " [" + source + "]"
+		}
+		fmt.Fprintf(&b, "%s%s %s\n", e.Time.Local().Format("01-02 15:04:05"), source, e.Line)
+	}
+	if r.Truncated {
+		fmt.Fprintf(&b, "\n⚠️  Showing the latest %d lines only; narrow the search with contains, container or a shorter since.\n", len(r.Entries))
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestHandleQueryLogs(t *testing.T) {
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query().Get("query")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[{"stream":{"pod":"web-1","container":"app"},"values":[["1700000000000000000","panic: boom"]]}]}}`))
+	}))
+	defer server.Close()
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters["prod"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	for _, name := range []string{"prod", "staging"} {
+		config.Contexts[name] = &clientcmdapi.Context{Cluster: "prod"}
+	}
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := k8s.NewProvider(path)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+
+	state := &agentState{outputFormat: OutputText, logs: map[string]k8s.LogsEndpoint{"prod": {URL: server.URL}}}
+	out, err := handleQueryLogs(context.Background(), provider, state, QueryLogsParams{Context: "prod", Namespace: "shop", Pod: "web*", Contains: "panic", Since: "2d"})
+	if err != nil {
+		t.Fatalf("handleQueryLogs: %v", err)
+	}
+	if text := out.(string); !strings.Contains(text, "[web-1/app] panic: boom") {
+		t.Errorf("unexpected output:\n%s", text)
+	}
+	if gotQuery != `{namespace="shop", pod=~"web.*"} |= "panic"` {
+		t.Errorf("query = %s", gotQuery)
+	}
+
+	state.outputFormat = OutputJSON
+	out, err = handleQueryLogs(context.Background(), provider, state, QueryLogsParams{Context: "prod", Query: `{app="web"}`})
+	if err != nil {
+		t.Fatalf("handleQueryLogs raw query: %v", err)
+	}
+	if r := out.(*k8s.LogResult); len(r.Entries) != 1 || gotQuery != `{app="web"}` {
+		t.Errorf("unexpected result %+v (query %s)", r, gotQuery)
+	}
+
+	for want, params := range map[string]QueryLogsParams{
+		"context is required":       {Namespace: "shop"},
+		"namespace or pod":          {Context: "prod", Contains: "panic"},
+		"limit must be between":     {Context: "prod", Namespace: "shop", Limit: 1000},
+		"invalid since":             {Context: "prod", Namespace: "shop", Since: "yesterday"},
+		"does not exist":            {Context: "missing", Namespace: "shop"},
+		"no log backend configured": {Context: "staging", Namespace: "shop"},
+	} {
+		if _, err := handleQueryLogs(context.Background(), provider, state, params); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%+v: error = %v, want %q", params, err, want)
+		}
+	}
+}
+
+func TestFormatLogResult(t *testing.T) {
+	r := &k8s.LogResult{
+		Context:   "prod",
+		Endpoint:  "loki monitoring/loki:3100",
+		Query:     `{namespace="shop"}`,
+		Entries:   []k8s.LogEntry{{Time: time.Unix(1700000000, 0), Line: "no pod label"}},
+		Truncated: true,
+	}
+	text := formatLogResult(r)
+	for _, want := range []string{"Logs on prod (loki monitoring/loki:3100)", " no pod label", "latest 1 lines only"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "[") {
+		t.Errorf("entry without pod should have no source:\n%s", text)
+	}
+	if text := formatLogResult(&k8s.LogResult{}); !strings.Contains(text, "(no matching lines)") {
+		t.Errorf("empty result output:\n%s", text)
+	}
+}
@@ -0,0 +1,121 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains user-defined slash-command macros and command completion.
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+// Macro is a user-defined slash command expanding to a prompt, e.g.
+// /morning → "check all prod clusters and summarize changes since yesterday".
+type Macro struct {
+	// Name is the command name without the leading slash.
+	Name string `json:"name"`
+	// Prompt is the text sent in place of the command. $1..$9 are replaced by
+	// the command arguments and $@ by all of them; without placeholders the
+	// arguments are appended.
+	Prompt string `json:"prompt"`
+	// Description is shown in /help; the prompt is shown when empty.
+	Description string `json:"description,omitempty"`
+}
+
+var (
+	// macroNameRe matches a valid macro name.
+	macroNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+	// macroArgRe matches $1..$9 and $@ argument placeholders.
+	macroArgRe = regexp.MustCompile(`\$([1-9@])`)
+)
+
+// validateMacros rejects invalid or duplicate macro names, names shadowing
+// built-in commands and empty prompts.
+func validateMacros(macros []Macro) error {
+	seen := make(map[string]bool, len(macros))
+	for _, m := range macros {
+		if !macroNameRe.MatchString(m.Name) {
+			return fmt.Errorf("macro name %q must be lowercase letters, digits, - or _ (max 32 characters)", m.Name)
+		}
+		if isBuiltinCommand("/" + m.Name) {
+			return fmt.Errorf("macro /%s conflicts with a built-in command", m.Name)
+		}
+		if seen[m.Name] {
+			return fmt.Errorf("duplicate macro /%s", m.Name)
+		}
+		if strings.TrimSpace(m.Prompt) == "" {
+			return fmt.Errorf("macro /%s has an empty prompt", m.Name)
+		}
+		seen[m.Name] = true
+	}
+	return nil
+}
+
+// expandMacro returns the prompt of the macro invoked by input, if any.
+func expandMacro(macros []Macro, input string) (string, bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "", false
+	}
+	name := strings.ToLower(strings.TrimPrefix(fields[0], "/"))
+	for _, m := range macros {
+		if m.Name == name {
+			return applyMacroArgs(m.Prompt, fields[1:]), true
+		}
+	}
+	return "", false
+}
+
+// applyMacroArgs substitutes the argument placeholders of prompt; missing
+// arguments expand to nothing.
+func applyMacroArgs(prompt string, args []string) string {
+	if !macroArgRe.MatchString(prompt) {
+		if len(args) == 0 {
+			return prompt
+		}
+		return prompt + " " + strings.Join(args, " ")
+	}
+	return macroArgRe.ReplaceAllStringFunc(prompt, func(ref string) string {
+		if ref == "$@" {
+			return strings.Join(args, " ")
+		}
+		n, _ := strconv.Atoi(ref[1:])
+		if n > len(args) {
+			return ""
+		}
+		return args[n-1]
+	})
+}
+
+// printMacros lists the configured macros in /help.
+func printMacros(macros []Macro) {
+	if len(macros) == 0 {
+		return
+	}
+	fmt.Printf("  %sMacros%s\n", colorDim, colorReset)
+	for _, m := range macros {
+		desc := m.Description
+		if desc == "" {
+			desc = m.Prompt
+			if r := []rune(desc); len(r) > 60 {
+				desc = string(r[:57]) + "..."
+			}
+		}
+		fmt.Printf("    %s/%-17s%s %s\n", colorCyan, m.Name, colorReset, desc)
+	}
+	fmt.Println()
+}
+
+// newCommandCompleter completes built-in commands and macros at the start of the line.
+func newCommandCompleter(macros []Macro) *readline.PrefixCompleter {
+	items := make([]readline.PrefixCompleterInterface, 0, len(builtinCommands)+len(macros))
+	for _, cmd := range builtinCommands {
+		items = append(items, readline.PcItem(cmd))
+	}
+	for _, m := range macros {
+		items = append(items, readline.PcItem("/"+m.Name))
+	}
+	return readline.NewPrefixCompleter(items...)
+}
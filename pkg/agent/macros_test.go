@@ -0,0 +1,63 @@
+package agent
+
+import "testing"
+
+func TestExpandMacro(t *testing.T) {
+	macros := []Macro{
+		{Name: "morning", Prompt: "check all prod clusters and summarize changes since yesterday"},
+		{Name: "pods", Prompt: "list unhealthy pods in namespace $1 of $2"},
+		{Name: "ask", Prompt: "explain: $@"},
+	}
+	tests := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"/morning", "check all prod clusters and summarize changes since yesterday", true},
+		{"/Morning focus on eu", "check all prod clusters and summarize changes since yesterday focus on eu", true},
+		{"/pods payments prod-eu", "list unhealthy pods in namespace payments of prod-eu", true},
+		{"/pods payments", "list unhealthy pods in namespace payments of ", true},
+		{"/ask why is it slow", "explain: why is it slow", true},
+		{"/evening", "", false},
+		{"morning", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := expandMacro(macros, tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("expandMacro(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIsBuiltinCommand(t *testing.T) {
+	for _, in := range []string{"/help", "/SET ns=x", "/export out.md"} {
+		if !isBuiltinCommand(in) {
+			t.Errorf("isBuiltinCommand(%q) = false", in)
+		}
+	}
+	for _, in := range []string{"/morning", "/helpme", "help"} {
+		if isBuiltinCommand(in) {
+			t.Errorf("isBuiltinCommand(%q) = true", in)
+		}
+	}
+}
+
+func TestNewCommandCompleter(t *testing.T) {
+	c := newCommandCompleter([]Macro{{Name: "morning", Prompt: "x"}})
+	candidates, _ := c.Do([]rune("/mor"), 4)
+	if len(candidates) != 1 || string(candidates[0]) != "ning " {
+		t.Errorf("completion of /mor = %q", candidates)
+	}
+	candidates, _ = c.Do([]rune("/he"), 3)
+	if len(candidates) != 1 || string(candidates[0]) != "lp " {
+		t.Errorf("completion of /he = %q", candidates)
+	}
+}
+
+func TestPrintMacros(t *testing.T) {
+	printMacros(nil)
+	printMacros([]Macro{
+		{Name: "morning", Prompt: "check all prod clusters", Description: "daily check"},
+		{Name: "long", Prompt: "a very long prompt that goes on and on well past the sixty character limit of help"},
+	})
+}
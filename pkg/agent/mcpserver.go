@@ -13,8 +13,8 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 )
 
-// RunMCPServer starts kopilot as a stdio MCP server, exposing the 6 Kubernetes
-// tools to any MCP client (e.g. Claude Code). No LLM provider is instantiated.
+// RunMCPServer starts kopilot as a stdio MCP server, exposing the Kubernetes
+// tools from defineK8sTools to any MCP client (e.g. Claude Code). No LLM provider is instantiated.
 //
 // Write operations via kubectl_exec are blocked (ModeReadOnly) because
 // interactive confirmation prompts would corrupt the JSON-RPC stream on stdio.
@@ -23,11 +23,14 @@ func RunMCPServer(k8sProvider *k8s.Provider) error {
 	log.SetOutput(os.Stderr)
 
 	state := &agentState{
-		mode:         ModeReadOnly,
-		outputFormat: OutputJSON,
+		mode:            ModeReadOnly,
+		outputFormat:    OutputJSON,
+		quotaPercentage: -1,
 	}
+	state.subscribeSubsystems()
 
-	tools := defineK8sTools(k8sProvider, state)
+	tools := withOutputGuard(withSchemaLimits(defineK8sTools(k8sProvider, state), k8sProvider), state)
+	state.setTools(tools)
 
 	s := server.NewMCPServer("kopilot", AppVersion)
 	for _, t := range tools {
@@ -62,7 +65,7 @@ func bridgeTool(t llm.Tool) (mcp.Tool, server.ToolHandlerFunc) {
 	mcpTool := mcp.NewToolWithRawSchema(t.Name, t.Description, rawSchema)
 
 	handler := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		result, err := t.Handler(req.GetArguments(), llm.ToolInvocation{Name: req.Params.Name})
+		result, err := t.Handler(req.GetArguments(), llm.ToolInvocation{Name: req.Params.Name, Context: ctx})
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
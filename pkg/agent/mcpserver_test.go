@@ -14,8 +14,8 @@ func TestDefineK8sToolsCount(t *testing.T) {
 	provider := createMockProvider(t)
 	state := &agentState{mode: ModeReadOnly, outputFormat: OutputJSON}
 	tools := defineK8sTools(provider, state)
-	if len(tools) != 6 {
-		t.Errorf("defineK8sTools returned %d tools, want 6", len(tools))
+	if len(tools) != 45 {
+		t.Errorf("defineK8sTools returned %d tools, want 45", len(tools))
 	}
 }
 
@@ -23,8 +23,8 @@ func TestDefineToolsCountUnchanged(t *testing.T) {
 	provider := createMockProvider(t)
 	state := &agentState{mode: ModeReadOnly, outputFormat: OutputJSON}
 	tools := defineTools(provider, state)
-	if len(tools) != 9 {
-		t.Errorf("defineTools returned %d tools, want 9", len(tools))
+	if len(tools) != 53 {
+		t.Errorf("defineTools returned %d tools, want 53", len(tools))
 	}
 }
 
@@ -0,0 +1,503 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the migrate_namespace tool for cloning a namespace to another cluster.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// Secret handling modes for migrate_namespace.
+const (
+	secretsSkip        = "skip"
+	secretsInclude     = "include"
+	secretsPlaceholder = "placeholder"
+
+	secretPlaceholderValue = "REPLACE_ME"
+)
+
+// migrateKinds are the namespaced resources exported by migrate_namespace, in
+// apply order: identities and config before the workloads that reference them.
+var migrateKinds = []string{
+	"serviceaccounts",
+	"secrets",
+	"configmaps",
+	"roles",
+	"rolebindings",
+	"persistentvolumeclaims",
+	"services",
+	"deployments",
+	"statefulsets",
+	"daemonsets",
+	"cronjobs",
+	"jobs",
+	"ingresses",
+	"horizontalpodautoscalers",
+	"poddisruptionbudgets",
+	"networkpolicies",
+}
+
+// migrateKindOrder ranks object kinds by apply order.
+var migrateKindOrder = map[string]int{
+	"ServiceAccount":          0,
+	"Secret":                  1,
+	"ConfigMap":               2,
+	"Role":                    3,
+	"RoleBinding":             4,
+	"PersistentVolumeClaim":   5,
+	"Service":                 6,
+	"Deployment":              7,
+	"StatefulSet":             8,
+	"DaemonSet":               9,
+	"CronJob":                 10,
+	"Job":                     11,
+	"Ingress":                 12,
+	"HorizontalPodAutoscaler": 13,
+	"PodDisruptionBudget":     14,
+	"NetworkPolicy":           15,
+}
+
+// migrateDroppedAnnotations are set by controllers in the source cluster and
+// must not be carried over.
+var migrateDroppedAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+	"deployment.kubernetes.io/revision",
+	"pv.kubernetes.io/bind-completed",
+	"pv.kubernetes.io/bound-by-controller",
+	"volume.beta.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/selected-node",
+}
+
+// MigrateNamespaceParams defines parameters for migrate_namespace
+type MigrateNamespaceParams struct {
+	SourceContext   string `json:"source_context" jsonschema:"Context of the cluster to copy the namespace from"`
+	Namespace       string `json:"namespace" jsonschema:"Namespace to migrate"`
+	TargetContext   string `json:"target_context" jsonschema:"Context of the cluster to copy the namespace to"`
+	TargetNamespace string `json:"target_namespace,omitempty" jsonschema:"Namespace name in the target cluster; defaults to the source name"`
+	Secrets         string `json:"secrets,omitempty" jsonschema:"How to handle Secrets: 'skip' (default, list them for manual re-creation), 'placeholder' (copy keys with REPLACE_ME values), or 'include' (copy values)"`
+	Apply           bool   `json:"apply,omitempty" jsonschema:"Apply the plan to the target cluster after confirmation; false (default) only returns the reviewable plan"`
+}
+
+// MigratedObject is one object in a migration plan with the rewrites applied to it.
+type MigratedObject struct {
+	Kind    string   `json:"kind"`
+	Name    string   `json:"name"`
+	Changes []string `json:"changes,omitempty"`
+}
+
+// SkippedObject is an exported object left out of the migration.
+type SkippedObject struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// MigrateNamespaceResult is the migration plan and, when applied, its outcome.
+type MigrateNamespaceResult struct {
+	SourceContext   string           `json:"source_context"`
+	Namespace       string           `json:"namespace"`
+	TargetContext   string           `json:"target_context"`
+	TargetNamespace string           `json:"target_namespace"`
+	Secrets         string           `json:"secrets"`
+	Objects         []MigratedObject `json:"objects"`
+	Skipped         []SkippedObject  `json:"skipped"`
+	Warnings        []string         `json:"warnings"`
+	Applied         bool             `json:"applied"`
+	Output          string           `json:"output,omitempty"`
+	Error           string           `json:"error,omitempty"`
+}
+
+func defineMigrateNamespaceTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolMigrateNamespace,
+		"Clone a namespace from one cluster to another: exports its workloads, services, config, RBAC, PVCs and policies, strips cluster-specific fields (UIDs, cluster IPs, node ports, bound volumes, controller annotations), and returns a reviewable plan. Call first with apply=false and show the plan; call again with apply=true to apply it to the target cluster after user confirmation. Persistent volume data is not copied.",
+		func(params MigrateNamespaceParams, inv llm.ToolInvocation) (any, error) {
+			return handleMigrateNamespace(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+func validateMigrateParams(params *MigrateNamespaceParams) error {
+	if params.SourceContext == "" || params.TargetContext == "" {
+		return fmt.Errorf("source_context and target_context are required")
+	}
+	if params.Namespace == "" {
+		return fmt.Errorf("namespace is required")
+	}
+	if params.TargetNamespace == "" {
+		params.TargetNamespace = params.Namespace
+	}
+	if params.SourceContext == params.TargetContext && params.Namespace == params.TargetNamespace {
+		return fmt.Errorf("source and target are the same namespace in the same cluster")
+	}
+	if params.Secrets == "" {
+		params.Secrets = secretsSkip
+	}
+	switch params.Secrets {
+	case secretsSkip, secretsInclude, secretsPlaceholder:
+	default:
+		return fmt.Errorf("secrets must be one of %s, %s, %s", secretsSkip, secretsPlaceholder, secretsInclude)
+	}
+	for _, ns := range []string{params.Namespace, params.TargetNamespace} {
+		if !isValidKubernetesName(ns) {
+			return fmt.Errorf("invalid namespace name: %s", ns)
+		}
+	}
+	return nil
+}
+
+func handleMigrateNamespace(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params MigrateNamespaceParams) (any, error) {
+	if err := validateMigrateParams(&params); err != nil {
+		return nil, err
+	}
+	if _, err := getClusterForContext(k8sProvider, params.SourceContext); err != nil {
+		return nil, err
+	}
+	target, err := getClusterForContext(k8sProvider, params.TargetContext)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := exportNamespace(ctx, params.SourceContext, params.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	objects, result := planMigration(items, params)
+	if _, err := runKubectlCommandFunc(ctx, []string{"--context", params.TargetContext, "get", "namespace", params.TargetNamespace, "-o", "name"}); err == nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("namespace %s already exists in %s; objects with the same names will be updated in place", params.TargetNamespace, params.TargetContext))
+	}
+
+	if !params.Apply || len(objects) == 0 {
+		return migrationResult(state, result), nil
+	}
+
+	printMigrationPlan(state, result)
+	manifestPath, cleanup, err := stageMigrationManifest(objects, params.TargetNamespace)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	fullCommand, cmdArgs := buildKubectlCommand(params.TargetContext, []string{"apply", "-f", manifestPath})
+	prompt := fmt.Sprintf("%s  # %d objects from %s/%s", fullCommand, len(objects), params.SourceContext, params.Namespace)
+	proceed, cancelResult, err := enforceToolExecutionMode(ctx, state, toolMigrateNamespace, false, target.Name, params.TargetContext, prompt)
+	if err != nil {
+		return nil, err
+	}
+	if !proceed {
+		return cancelResult, nil
+	}
+
+	printExecutionHeader(state, false, fullCommand)
+	output, execErr := runKubectlCommandFunc(ctx, cmdArgs)
+	state.publishWrite(ApprovalRequest{Tool: toolMigrateNamespace, Cluster: target.Name, Context: params.TargetContext, Command: prompt}, execErr)
+	result.Output = string(output)
+	if execErr != nil {
+		result.Error = execErr.Error()
+	} else {
+		result.Applied = true
+	}
+	return migrationResult(state, result), nil
+}
+
+// exportNamespace returns every migratable object in namespace.
+func exportNamespace(ctx context.Context, contextName, namespace string) ([]map[string]any, error) {
+	args := []string{"--context", contextName, "get", strings.Join(migrateKinds, ","), "-n", namespace, "-o", "json"}
+	out, err := runKubectlCommandFunc(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export namespace %s: %w: %s", namespace, err, strings.TrimSpace(string(out)))
+	}
+	// kubectl may print deprecation warnings ahead of the JSON document.
+	if i := strings.IndexByte(string(out), '{'); i > 0 {
+		out = out[i:]
+	}
+	var list struct {
+		Items []map[string]any `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse exported resources: %w", err)
+	}
+	return list.Items, nil
+}
+
+// planMigration filters and rewrites exported objects for the target namespace.
+func planMigration(items []map[string]any, params MigrateNamespaceParams) ([]map[string]any, MigrateNamespaceResult) {
+	result := MigrateNamespaceResult{
+		SourceContext:   params.SourceContext,
+		Namespace:       params.Namespace,
+		TargetContext:   params.TargetContext,
+		TargetNamespace: params.TargetNamespace,
+		Secrets:         params.Secrets,
+		Objects:         []MigratedObject{},
+		Skipped:         []SkippedObject{},
+		Warnings:        []string{},
+	}
+
+	sort.SliceStable(items, func(a, b int) bool {
+		ka, _ := nestedString(items[a], "kind")
+		kb, _ := nestedString(items[b], "kind")
+		return migrateKindOrder[ka] < migrateKindOrder[kb]
+	})
+
+	var objects []map[string]any
+	hasPVC, hasIngress, hasLB := false, false, false
+	for _, obj := range items {
+		kind, _ := nestedString(obj, "kind")
+		name, _ := nestedString(obj, "metadata", "name")
+		if reason := migrationSkipReason(obj, kind, name, params.Secrets); reason != "" {
+			result.Skipped = append(result.Skipped, SkippedObject{Kind: kind, Name: name, Reason: reason})
+			continue
+		}
+		changes := rewriteForMigration(obj, kind, params)
+		objects = append(objects, obj)
+		result.Objects = append(result.Objects, MigratedObject{Kind: kind, Name: name, Changes: changes})
+
+		switch kind {
+		case "PersistentVolumeClaim":
+			hasPVC = true
+		case "Ingress":
+			hasIngress = true
+		case "Service":
+			if t, _ := nestedString(obj, "spec", "type"); t == "LoadBalancer" {
+				hasLB = true
+			}
+		}
+	}
+
+	if hasPVC {
+		result.Warnings = append(result.Warnings, "PVCs are re-created empty: volume data is not copied. Use a backup tool (e.g. Velero) to move the data, and check the storage classes exist in the target cluster")
+	}
+	if hasLB {
+		result.Warnings = append(result.Warnings, "LoadBalancer services will receive new external addresses; update DNS records that point at the old ones")
+	}
+	if hasIngress {
+		result.Warnings = append(result.Warnings, "Ingress hosts will resolve to the source cluster until DNS is switched; check the ingress class exists in the target cluster")
+	}
+	if params.Secrets == secretsInclude {
+		result.Warnings = append(result.Warnings, "secret values are copied as-is; make sure the target cluster is allowed to hold them")
+	}
+	return objects, result
+}
+
+// migrationSkipReason returns why obj should not be migrated, or "".
+func migrationSkipReason(obj map[string]any, kind, name, secretsMode string) string {
+	if refs, ok := nestedValue(obj, "metadata", "ownerReferences"); ok {
+		if list, ok := refs.([]any); ok && len(list) > 0 {
+			return "owned by another object that is migrated instead"
+		}
+	}
+	switch kind {
+	case "ServiceAccount":
+		if name == "default" {
+			return "created automatically in every namespace"
+		}
+	case "ConfigMap":
+		if name == "kube-root-ca.crt" {
+			return "published automatically by the control plane"
+		}
+	case "Secret":
+		if t, _ := nestedString(obj, "type"); t == "kubernetes.io/service-account-token" {
+			return "service account tokens are issued by the target cluster"
+		}
+		if secretsMode == secretsSkip {
+			return "secrets=skip; re-create it in the target cluster"
+		}
+	}
+	return ""
+}
+
+// rewriteForMigration strips source-cluster fields from obj in place and
+// returns a description of each change that is not routine metadata cleanup.
+func rewriteForMigration(obj map[string]any, kind string, params MigrateNamespaceParams) []string {
+	var changes []string
+	stripServerFields(obj)
+	meta, _ := obj["metadata"].(map[string]any)
+	if meta != nil {
+		meta["namespace"] = params.TargetNamespace
+		if annotations, ok := meta["annotations"].(map[string]any); ok {
+			for _, a := range migrateDroppedAnnotations {
+				delete(annotations, a)
+			}
+			if len(annotations) == 0 {
+				delete(meta, "annotations")
+			}
+		}
+	}
+	if params.TargetNamespace != params.Namespace {
+		changes = append(changes, rewriteNamespaceReferences(obj, kind, params.Namespace, params.TargetNamespace)...)
+	}
+
+	spec, _ := obj["spec"].(map[string]any)
+	switch kind {
+	case "Service":
+		if spec == nil {
+			break
+		}
+		if ip, _ := spec["clusterIP"].(string); ip != "" && ip != "None" {
+			delete(spec, "clusterIP")
+			delete(spec, "clusterIPs")
+			changes = append(changes, "cluster IP reassigned by target")
+		}
+		delete(spec, "healthCheckNodePort")
+		if ports, ok := spec["ports"].([]any); ok {
+			for _, p := range ports {
+				if pm, ok := p.(map[string]any); ok {
+					if _, had := pm["nodePort"]; had {
+						delete(pm, "nodePort")
+						changes = appendOnce(changes, "node ports reassigned by target")
+					}
+				}
+			}
+		}
+	case "PersistentVolumeClaim":
+		if spec != nil {
+			if _, had := spec["volumeName"]; had {
+				delete(spec, "volumeName")
+				changes = append(changes, "unbound from source volume; target provisions a new one")
+			}
+		}
+	case "Job":
+		// Jobs carry a generated selector and controller-uid labels.
+		if spec != nil {
+			delete(spec, "selector")
+		}
+		for _, path := range [][]string{{"metadata", "labels"}, {"spec", "template", "metadata", "labels"}} {
+			if labels, ok := nestedValue(obj, path...); ok {
+				if lm, ok := labels.(map[string]any); ok {
+					for _, l := range []string{"controller-uid", "batch.kubernetes.io/controller-uid", "job-name", "batch.kubernetes.io/job-name"} {
+						delete(lm, l)
+					}
+				}
+			}
+		}
+		changes = append(changes, "generated selector removed")
+	case "Secret":
+		if params.Secrets == secretsPlaceholder {
+			if data, ok := obj["data"].(map[string]any); ok {
+				stringData := make(map[string]any, len(data))
+				for k := range data {
+					stringData[k] = secretPlaceholderValue
+				}
+				delete(obj, "data")
+				obj["stringData"] = stringData
+				changes = append(changes, fmt.Sprintf("values replaced with %s", secretPlaceholderValue))
+			}
+		}
+	}
+	return changes
+}
+
+// rewriteNamespaceReferences updates RoleBinding subjects that point at the
+// source namespace, which would otherwise grant nothing in the target.
+func rewriteNamespaceReferences(obj map[string]any, kind, from, to string) []string {
+	if kind != "RoleBinding" {
+		return nil
+	}
+	subjects, ok := obj["subjects"].([]any)
+	if !ok {
+		return nil
+	}
+	var changes []string
+	for _, s := range subjects {
+		if sm, ok := s.(map[string]any); ok && sm["namespace"] == from {
+			sm["namespace"] = to
+			changes = appendOnce(changes, fmt.Sprintf("subject namespace %s → %s", from, to))
+		}
+	}
+	return changes
+}
+
+func appendOnce(list []string, s string) []string {
+	for _, existing := range list {
+		if existing == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// stageMigrationManifest writes the target Namespace and objects as a v1 List
+// to a temporary file for kubectl apply.
+func stageMigrationManifest(objects []map[string]any, namespace string) (string, func(), error) {
+	items := make([]any, 0, len(objects)+1)
+	items = append(items, map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]any{"name": namespace},
+	})
+	for _, o := range objects {
+		items = append(items, o)
+	}
+	return stageManifest(map[string]any{"apiVersion": "v1", "kind": "List", "items": items})
+}
+
+// printMigrationPlan shows the plan before the confirmation prompt.
+func printMigrationPlan(state *agentState, r MigrateNamespaceResult) {
+	if isJSONOutput(state.outputFormat) {
+		return
+	}
+	resumeSpinner := pauseSpinner()
+	defer resumeSpinner()
+	fmt.Fprint(state.stdout(), "\r\033[K\n"+formatMigrationPlan(r))
+}
+
+func migrationResult(state *agentState, r MigrateNamespaceResult) any {
+	if isJSONOutput(state.outputFormat) {
+		return r
+	}
+	return formatMigrationPlan(r)
+}
+
+// formatMigrationPlan formats a MigrateNamespaceResult as human-readable text
+func formatMigrationPlan(r MigrateNamespaceResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Namespace Migration: %s/%s → %s/%s\n", r.SourceContext, r.Namespace, r.TargetContext, r.TargetNamespace)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	fmt.Fprintf(&sb, "📦 OBJECTS TO APPLY: %d (secrets: %s)\n", len(r.Objects), r.Secrets)
+	for _, o := range r.Objects {
+		fmt.Fprintf(&sb, "  • %s/%s", o.Kind, o.Name)
+		if len(o.Changes) > 0 {
+			fmt.Fprintf(&sb, " — %s", strings.Join(o.Changes, "; "))
+		}
+		sb.WriteString("\n")
+	}
+	if len(r.Skipped) > 0 {
+		fmt.Fprintf(&sb, "\n⏭️  SKIPPED: %d\n", len(r.Skipped))
+		for _, s := range r.Skipped {
+			fmt.Fprintf(&sb, "  • %s/%s — %s\n", s.Kind, s.Name, s.Reason)
+		}
+	}
+	if len(r.Warnings) > 0 {
+		sb.WriteString("\n⚠️  REVIEW BEFORE APPLYING:\n")
+		for _, w := range r.Warnings {
+			fmt.Fprintf(&sb, "  • %s\n", w)
+		}
+	}
+
+	sb.WriteString("\n")
+	switch {
+	case r.Error != "":
+		fmt.Fprintf(&sb, "❌ Apply failed: %s\n", r.Error)
+		if r.Output != "" {
+			sb.WriteString(r.Output + "\n")
+		}
+	case r.Applied:
+		fmt.Fprintf(&sb, "✅ Applied to %s/%s\n", r.TargetContext, r.TargetNamespace)
+		if r.Output != "" {
+			sb.WriteString(r.Output + "\n")
+		}
+	case len(r.Objects) == 0:
+		sb.WriteString("Nothing to migrate.\n")
+	default:
+		sb.WriteString("This is a plan only; nothing was changed. Re-run with apply=true to apply it after confirmation.\n")
+	}
+	return sb.String()
+}
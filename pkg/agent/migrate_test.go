@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+const migrateExport = `Warning: autoscaling/v2beta2 is deprecated
+{"apiVersion":"v1","kind":"List","items":[
+ {"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web","namespace":"shop","uid":"u1","resourceVersion":"9",
+   "annotations":{"deployment.kubernetes.io/revision":"4"}},"spec":{"replicas":2},"status":{"readyReplicas":2}},
+ {"apiVersion":"v1","kind":"ServiceAccount","metadata":{"name":"default","namespace":"shop"}},
+ {"apiVersion":"v1","kind":"ServiceAccount","metadata":{"name":"web","namespace":"shop"}},
+ {"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"kube-root-ca.crt","namespace":"shop"}},
+ {"apiVersion":"v1","kind":"Secret","type":"Opaque","metadata":{"name":"db","namespace":"shop"},"data":{"password":"c2VjcmV0"}},
+ {"apiVersion":"v1","kind":"Service","metadata":{"name":"web","namespace":"shop"},
+   "spec":{"type":"LoadBalancer","clusterIP":"10.0.0.5","clusterIPs":["10.0.0.5"],"ports":[{"port":80,"nodePort":31000}]}},
+ {"apiVersion":"v1","kind":"PersistentVolumeClaim","metadata":{"name":"data","namespace":"shop",
+   "annotations":{"pv.kubernetes.io/bind-completed":"yes"}},"spec":{"volumeName":"pvc-123"}},
+ {"apiVersion":"batch/v1","kind":"Job","metadata":{"name":"nightly-1","namespace":"shop",
+   "ownerReferences":[{"kind":"CronJob","name":"nightly"}]}},
+ {"apiVersion":"rbac.authorization.k8s.io/v1","kind":"RoleBinding","metadata":{"name":"web","namespace":"shop"},
+   "subjects":[{"kind":"ServiceAccount","name":"web","namespace":"shop"}]}
+]}`
+
+func TestExportAndPlanMigration(t *testing.T) {
+	original := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = original })
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
+		return []byte(migrateExport), nil
+	}
+
+	items, err := exportNamespace(context.Background(), "src", "shop")
+	if err != nil {
+		t.Fatalf("exportNamespace(context.Background()) error = %v", err)
+	}
+	if len(items) != 9 {
+		t.Fatalf("exported %d items, want 9", len(items))
+	}
+
+	params := MigrateNamespaceParams{SourceContext: "src", Namespace: "shop", TargetContext: "dst", TargetNamespace: "shop-v2", Secrets: secretsPlaceholder}
+	objects, result := planMigration(items, params)
+
+	var kinds []string
+	for _, o := range result.Objects {
+		kinds = append(kinds, o.Kind+"/"+o.Name)
+	}
+	want := "ServiceAccount/web Secret/db RoleBinding/web PersistentVolumeClaim/data Service/web Deployment/web"
+	if got := strings.Join(kinds, " "); got != want {
+		t.Errorf("objects = %s\nwant      %s", got, want)
+	}
+	if len(result.Skipped) != 3 {
+		t.Errorf("skipped = %+v, want default SA, root CA and owned Job", result.Skipped)
+	}
+	if len(result.Warnings) != 2 {
+		t.Errorf("warnings = %v, want PVC and LoadBalancer warnings", result.Warnings)
+	}
+
+	byName := make(map[string]map[string]any)
+	for _, o := range objects {
+		kind, _ := nestedString(o, "kind")
+		byName[kind] = o
+	}
+	if ns, _ := nestedString(byName["Deployment"], "metadata", "namespace"); ns != "shop-v2" {
+		t.Errorf("deployment namespace = %q, want shop-v2", ns)
+	}
+	if _, ok := nestedValue(byName["Deployment"], "metadata", "uid"); ok {
+		t.Error("uid should be stripped")
+	}
+	if _, ok := nestedValue(byName["Deployment"], "metadata", "annotations"); ok {
+		t.Error("controller annotations should be stripped")
+	}
+	if _, ok := nestedValue(byName["Service"], "spec", "clusterIP"); ok {
+		t.Error("clusterIP should be stripped")
+	}
+	if port, _ := nestedValue(byName["Service"], "spec", "ports"); strings.Contains(mustJSON(t, port), "nodePort") {
+		t.Error("nodePort should be stripped")
+	}
+	if _, ok := nestedValue(byName["PersistentVolumeClaim"], "spec", "volumeName"); ok {
+		t.Error("volumeName should be stripped")
+	}
+	if v, _ := nestedString(byName["Secret"], "stringData", "password"); v != secretPlaceholderValue {
+		t.Errorf("secret placeholder = %q", v)
+	}
+	if _, ok := byName["Secret"]["data"]; ok {
+		t.Error("secret data should be removed in placeholder mode")
+	}
+	if !strings.Contains(mustJSON(t, byName["RoleBinding"]), `"namespace":"shop-v2"`) {
+		t.Error("rolebinding subject namespace should be rewritten")
+	}
+}
+
+func TestMigrationSkipsSecretsByDefault(t *testing.T) {
+	items := []map[string]any{{"kind": "Secret", "type": "Opaque", "metadata": map[string]any{"name": "db"}}}
+	objects, result := planMigration(items, MigrateNamespaceParams{Namespace: "a", TargetNamespace: "a", Secrets: secretsSkip})
+	if len(objects) != 0 || len(result.Skipped) != 1 || !strings.Contains(result.Skipped[0].Reason, "secrets=skip") {
+		t.Errorf("secret should be skipped: objects=%v skipped=%+v", objects, result.Skipped)
+	}
+}
+
+func TestValidateMigrateParams(t *testing.T) {
+	tests := []struct {
+		params  MigrateNamespaceParams
+		wantErr string
+	}{
+		{MigrateNamespaceParams{Namespace: "a", TargetContext: "dst"}, "required"},
+		{MigrateNamespaceParams{SourceContext: "src", TargetContext: "dst"}, "namespace is required"},
+		{MigrateNamespaceParams{SourceContext: "c", TargetContext: "c", Namespace: "a"}, "same namespace"},
+		{MigrateNamespaceParams{SourceContext: "src", TargetContext: "dst", Namespace: "a", Secrets: "all"}, "secrets must be"},
+		{MigrateNamespaceParams{SourceContext: "src", TargetContext: "dst", Namespace: "Bad_NS"}, "invalid namespace"},
+	}
+	for _, tt := range tests {
+		if err := validateMigrateParams(&tt.params); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("validateMigrateParams(%+v) error = %v, want containing %q", tt.params, err, tt.wantErr)
+		}
+	}
+
+	ok := MigrateNamespaceParams{SourceContext: "src", TargetContext: "dst", Namespace: "shop"}
+	if err := validateMigrateParams(&ok); err != nil || ok.TargetNamespace != "shop" || ok.Secrets != secretsSkip {
+		t.Errorf("defaults not applied: %+v, %v", ok, err)
+	}
+}
+
+func TestMigrateNamespaceApply(t *testing.T) {
+	provider := newTestK8sProvider(t)
+
+	original := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = original })
+	var applied string
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
+		switch args[2] {
+		case "get":
+			if args[3] == "namespace" {
+				return []byte("not found"), errors.New("exit status 1")
+			}
+			return []byte(migrateExport), nil
+		case "apply":
+			data, err := os.ReadFile(args[4])
+			if err != nil {
+				t.Fatalf("manifest not staged: %v", err)
+			}
+			applied = string(data)
+			return []byte("deployment.apps/web created\n"), nil
+		}
+		t.Fatalf("unexpected kubectl call: %v", args)
+		return nil, nil
+	}
+
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON, approver: &stubApprover{name: ApprovalLocal, decision: ApprovalDecision{Approved: true}}}
+	params := MigrateNamespaceParams{SourceContext: "test-context", Namespace: "shop", TargetContext: "test-context", TargetNamespace: "shop-copy"}
+
+	plan, err := handleMigrateNamespace(context.Background(), provider, state, params)
+	if err != nil {
+		t.Fatalf("plan error: %v", err)
+	}
+	if r := plan.(MigrateNamespaceResult); r.Applied || applied != "" {
+		t.Fatal("apply=false must not apply anything")
+	}
+
+	params.Apply = true
+	out, err := handleMigrateNamespace(context.Background(), provider, state, params)
+	if err != nil {
+		t.Fatalf("apply error: %v", err)
+	}
+	result := out.(MigrateNamespaceResult)
+	if !result.Applied || result.Error != "" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if !strings.Contains(applied, `"kind":"Namespace","metadata":{"name":"shop-copy"}`) {
+		t.Errorf("manifest should create the target namespace first:\n%s", applied)
+	}
+
+	if text := formatMigrationPlan(result); !strings.Contains(text, "✅ Applied to test-context/shop-copy") {
+		t.Errorf("formatted result:\n%s", text)
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,81 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the check_node_os tool for mixed Linux/Windows clusters.
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// CheckNodeOSParams defines parameters for check_node_os
+type CheckNodeOSParams struct {
+	Context string `json:"context" jsonschema:"The context name of the cluster to inspect (from list_clusters)"`
+}
+
+func defineCheckNodeOSTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolCheckNodeOS,
+		"Report the node operating system distribution (Linux/Windows) of a cluster and flag pods that run on a node of the wrong OS, Linux workloads without an OS selector scheduled onto Windows nodes, and pods pending because no node of the requested OS is available.",
+		func(params CheckNodeOSParams, inv llm.ToolInvocation) (any, error) {
+			ctx := inv.Ctx()
+			report, err := k8sProvider.CheckNodeOS(ctx, params.Context)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check node operating systems: %w", err)
+			}
+
+			if isJSONOutput(state.outputFormat) {
+				return report, nil
+			}
+			return formatNodeOSReport(report), nil
+		},
+	)
+}
+
+// osIssueLabels maps issue kinds to short text-mode headings.
+var osIssueLabels = map[string]string{
+	k8s.OSIssueMismatch:          "OS MISMATCH",
+	k8s.OSIssueUnpinnedOnWindows: "NO OS SELECTOR ON WINDOWS NODE",
+	k8s.OSIssueNoMatchingNodes:   "NO NODES FOR REQUESTED OS",
+	k8s.OSIssuePendingSelector:   "PENDING ON OS SELECTOR",
+}
+
+// formatNodeOSReport formats a NodeOSReport as human-readable text
+func formatNodeOSReport(report *k8s.NodeOSReport) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Node OS Report: %s\n", report.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	kind := "single-OS"
+	if report.Mixed {
+		kind = "mixed-OS"
+	}
+	fmt.Fprintf(&sb, "🖥️  OS DISTRIBUTION: %s (%s)\n", k8s.FormatDistribution(report.Distribution), kind)
+	for _, n := range report.Nodes {
+		icon := "✅"
+		if !n.Ready {
+			icon = "❌"
+		}
+		image := ""
+		if n.OSImage != "" {
+			image = " — " + n.OSImage
+		}
+		fmt.Fprintf(&sb, "  %s %-8s %s (%d pods)%s\n", icon, n.OS, n.Node, n.PodsCount, image)
+	}
+	sb.WriteString("\n")
+
+	if len(report.Issues) == 0 {
+		sb.WriteString("✅ No OS-related scheduling problems found.\n")
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "⚠️  OS SCHEDULING ISSUES: %d\n", len(report.Issues))
+	for _, issue := range report.Issues {
+		fmt.Fprintf(&sb, "  🔴 %s — %s/%s (%s)\n", osIssueLabels[issue.Kind], issue.Namespace, issue.Pod, issue.Phase)
+		fmt.Fprintf(&sb, "     %s\n", issue.Message)
+	}
+	sb.WriteString("\nFix: pin workloads with nodeSelector kubernetes.io/os (linux or windows) and taint Windows nodes (e.g. os=windows:NoSchedule) so only tolerating pods land there.\n")
+	return sb.String()
+}
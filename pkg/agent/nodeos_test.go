@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestFormatNodeOSReport(t *testing.T) {
+	report := &k8s.NodeOSReport{
+		Context:      "hybrid",
+		Distribution: map[string]int{"linux": 2, "windows": 1},
+		Mixed:        true,
+		Nodes: []k8s.NodeOSEntry{
+			{Node: "lin-1", OS: "linux", Ready: true, PodsCount: 4},
+			{Node: "win-1", OS: "windows", Ready: false, OSImage: "Windows Server 2022 Datacenter"},
+		},
+		Issues: []k8s.OSIssue{{
+			Kind: k8s.OSIssueUnpinnedOnWindows, Namespace: "shop", Pod: "web-1", Phase: "Pending",
+			Message: "pod has no OS selector and was scheduled onto Windows node win-1",
+		}},
+	}
+	text := formatNodeOSReport(report)
+	for _, want := range []string{"linux 2, windows 1 (mixed-OS)", "❌ windows  win-1", "Windows Server 2022", "NO OS SELECTOR ON WINDOWS NODE — shop/web-1", "Fix:"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report missing %q:\n%s", want, text)
+		}
+	}
+
+	report.Issues = nil
+	report.Mixed = false
+	if text := formatNodeOSReport(report); !strings.Contains(text, "No OS-related scheduling problems") {
+		t.Errorf("clean report should say so:\n%s", text)
+	}
+}
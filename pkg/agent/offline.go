@@ -0,0 +1,143 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains offline mode: when the LLM provider cannot start, for
+// example because the Copilot CLI is missing or not logged in, the session
+// keeps running without a model. Slash commands, !commands and the /check,
+// /kubectl and /run commands, which run tools directly, keep working, and
+// each prompt tries the provider again.
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// offlineSession stands in for the session while the LLM provider is
+// unavailable; prompts fail with the reason.
+type offlineSession struct {
+	reason error
+}
+
+func (s offlineSession) Disconnect() error                            { return nil }
+func (s offlineSession) SendPrompt(_ context.Context, _ string) error { return s.reason }
+func (s offlineSession) On(_ func(llm.Event))                         {}
+
+// goOffline switches the session to offline mode because the provider
+// failed with reason, and returns the stand-in session.
+func goOffline(deps *loopDeps, reason error) llm.Session {
+	deps.state.offline = reason
+	if deps.state.toolsByName == nil {
+		sessionTools(deps.k8sProvider, deps.state)
+	}
+	return offlineSession{reason: reason}
+}
+
+// printOfflineNotice tells why the session runs offline and what still works.
+func printOfflineNotice(deps *loopDeps) {
+	reason := deps.state.offline
+	fmt.Printf("  %s●%s %s is unavailable: %v\n", colorYellow, colorReset, deps.provider.Name(), reason)
+	fmt.Printf("    %sContinuing offline: /check, /kubectl, /run, other slash commands and !commands work; prompts retry %s%s\n",
+		colorDim, deps.provider.Name(), colorReset)
+}
+
+// goOnline retries the provider of an offline session and, when it starts,
+// replaces the stand-in session with a real one. It reports whether the
+// session is online.
+func goOnline(deps *loopDeps, ts *turnState) bool {
+	if deps.state.offline == nil {
+		return true
+	}
+	err := deps.provider.Start(deps.ctx)
+	if err == nil {
+		var session llm.Session
+		if session, err = createSessionWithModel(deps.ctx, deps.provider, deps.k8sProvider, deps.state, ts.model); err == nil {
+			setupSessionEventHandler(session, deps.isIdle, deps.state)
+			ts.session = session
+			deps.state.offline = nil
+			fmt.Printf("  %s●%s Connected to %s — back online\n", colorGreen, colorReset, deps.provider.Name())
+			return true
+		}
+		if stopErr := deps.provider.Stop(); stopErr != nil {
+			log.Printf("Warning: error stopping provider: %v", stopErr)
+		}
+	}
+	deps.state.offline = err
+	fmt.Printf("  %s●%s Offline, the prompt was not sent: %v\n", colorYellow, colorReset, err)
+	fmt.Printf("    %sUse /check, /kubectl or /run, fix the provider (e.g. copilot auth login), or switch with /provider%s\n", colorDim, colorReset)
+	return false
+}
+
+// callToolDirectly runs the named tool of the session with args, without
+// the model, as a turn Ctrl+C can cancel, and prints its result.
+func callToolDirectly(deps *loopDeps, name string, args map[string]any) {
+	tool, ok := deps.state.toolsByName[name]
+	if !ok {
+		fmt.Printf("  %s●%s Unknown tool %s\n", colorRed, colorReset, name)
+		return
+	}
+	defer cancelTurnOnInterrupt(deps.state)()
+	ctx := deps.state.beginTurn(deps.ctx)
+	defer deps.state.endTurn()
+	result, err := tool.Handler(args, llm.ToolInvocation{Name: name, Context: ctx})
+	if err != nil {
+		fmt.Printf(fmtErrorBullet, colorRed, colorReset, err)
+		return
+	}
+	if text, ok := result.(string); ok {
+		fmt.Println(text)
+		return
+	}
+	fmt.Println(llm.ResultString(result))
+}
+
+// handleCheckCommand processes "/check [context]": check_all_clusters, or
+// get_cluster_status of one context, run without the model.
+func handleCheckCommand(deps *loopDeps, input string) (bool, error) {
+	parts := strings.Fields(input)
+	switch len(parts) {
+	case 1:
+		callToolDirectly(deps, toolCheckAllClusters, map[string]any{})
+	case 2:
+		callToolDirectly(deps, toolGetClusterStatus, map[string]any{"context": parts[1]})
+	default:
+		fmt.Printf("  %s●%s Usage: /check [context]\n", colorRed, colorReset)
+	}
+	return true, nil
+}
+
+// handleKubectlCommand processes "/kubectl <args> [--context name]":
+// kubectl_exec run without the model, against the current context unless
+// --context is given. Write commands ask for confirmation as usual.
+func handleKubectlCommand(deps *loopDeps, input string) (bool, error) {
+	args, contextName := kubectlContextArg(strings.Fields(input)[1:])
+	if len(args) == 0 {
+		fmt.Printf("  %s●%s Usage: /kubectl <args> [--context name]\n", colorRed, colorReset)
+		return true, nil
+	}
+	if contextName == "" {
+		contextName = deps.k8sProvider.GetCurrentContext()
+	}
+	callToolDirectly(deps, toolKubectlExec, map[string]any{"context": contextName, "args": args})
+	return true, nil
+}
+
+// kubectlContextArg removes a --context flag from args and returns its value.
+func kubectlContextArg(args []string) ([]string, string) {
+	var rest []string
+	contextName := ""
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--context" && i+1 < len(args):
+			contextName = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--context="):
+			contextName = strings.TrimPrefix(args[i], "--context=")
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return rest, contextName
+}
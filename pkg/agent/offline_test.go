@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// flakyProvider fails to start until startErr is cleared.
+type flakyProvider struct {
+	fakeProvider
+	startErr error
+	stops    int
+}
+
+func (p *flakyProvider) Start(_ context.Context) error { return p.startErr }
+func (p *flakyProvider) Stop() error                   { p.stops++; return nil }
+
+func TestOfflineMode(t *testing.T) {
+	provider := &flakyProvider{fakeProvider: fakeProvider{session: &fakeSession{}}, startErr: errors.New("copilot CLI is not authenticated")}
+	isIdle := true
+	deps := &loopDeps{
+		ctx:         context.Background(),
+		provider:    provider,
+		k8sProvider: newTestK8sProvider(t),
+		state: &agentState{
+			mode:          ModeReadOnly,
+			outputFormat:  OutputJSON,
+			selectedAgent: AgentDefault,
+			mcpConfigPath: filepath.Join(t.TempDir(), "mcp.json"),
+		},
+		isIdle: &isIdle,
+	}
+
+	session := goOffline(deps, provider.startErr)
+	if deps.state.offline == nil || deps.state.toolsByName[toolCheckAllClusters].Handler == nil {
+		t.Fatalf("goOffline: offline %v, %d tool(s)", deps.state.offline, len(deps.state.toolsByName))
+	}
+	if err := session.SendPrompt(context.Background(), "hi"); err != provider.startErr {
+		t.Errorf("SendPrompt() offline = %v", err)
+	}
+	if got := rlPromptString(&agentState{offline: provider.startErr}, ""); !strings.Contains(got, "[offline]") {
+		t.Errorf("prompt offline = %q", got)
+	}
+
+	ts := &turnState{session: session, model: "test-model"}
+	if got, err := switchToModel(deps, session, "other-model"); got != session || err != nil {
+		t.Errorf("switchToModel() offline = %v, %v; want the offline session", got, err)
+	}
+	if goOnline(deps, ts) || ts.session != session {
+		t.Error("goOnline() succeeded while the provider fails")
+	}
+
+	provider.startErr = nil
+	if !goOnline(deps, ts) || deps.state.offline != nil || ts.session != provider.session {
+		t.Errorf("goOnline() after the provider recovered: offline %v, session replaced %v", deps.state.offline, ts.session == provider.session)
+	}
+	if provider.lastConfig == nil || provider.lastConfig.Model != "test-model" {
+		t.Errorf("session created with %+v", provider.lastConfig)
+	}
+}
+
+func TestHandleKubectlCommand(t *testing.T) {
+	var got map[string]any
+	state := &agentState{}
+	state.setTools([]llm.Tool{{Name: toolKubectlExec, Handler: func(params any, inv llm.ToolInvocation) (any, error) {
+		got = params.(map[string]any)
+		if inv.Context == nil {
+			t.Error("tool called without the turn's context")
+		}
+		return "ok", nil
+	}}})
+	deps := &loopDeps{ctx: context.Background(), k8sProvider: newTestK8sProvider(t), state: state}
+
+	tests := []struct {
+		input   string
+		context string
+		args    []string
+	}{
+		{"/kubectl get pods -n kube-system --context prod", "prod", []string{"get", "pods", "-n", "kube-system"}},
+		{"/kubectl --context=staging get nodes", "staging", []string{"get", "nodes"}},
+		{"/kubectl get ns", deps.k8sProvider.GetCurrentContext(), []string{"get", "ns"}},
+	}
+	for _, tt := range tests {
+		got = nil
+		if handled, err := handleKubectlCommand(deps, tt.input); !handled || err != nil {
+			t.Fatalf("handleKubectlCommand(%q) = %v, %v", tt.input, handled, err)
+		}
+		if got["context"] != tt.context || !reflect.DeepEqual(got["args"], tt.args) {
+			t.Errorf("handleKubectlCommand(%q) called kubectl_exec with %v", tt.input, got)
+		}
+	}
+
+	got = nil
+	handleKubectlCommand(deps, "/kubectl --context prod")
+	handleCheckCommand(deps, "/check a b")
+	if got != nil {
+		t.Errorf("usage errors called kubectl_exec with %v", got)
+	}
+}
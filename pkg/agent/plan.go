@@ -0,0 +1,392 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains change-plan mode: multi-step operations confirmed once and rolled back on failure.
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// maxPlanSteps bounds the number of commands a single plan may contain.
+const maxPlanSteps = 20
+
+// planRollbackTimeout bounds the rollback of a failed plan, which runs
+// detached from the turn.
+const planRollbackTimeout = 2 * time.Minute
+
+// Plan step and plan statuses reported in ExecutePlanResult.
+const (
+	planStepSucceeded      = "succeeded"
+	planStepFailed         = "failed"
+	planStepSkipped        = "skipped"
+	planStepRolledBack     = "rolled_back"
+	planStepRollbackFailed = "rollback_failed"
+	planStepNotReversible  = "not_reversible"
+
+	planCompleted  = "completed"
+	planRolledBack = "rolled_back"
+	planFailed     = "failed"
+)
+
+// PlanStep is one kubectl command in a change plan.
+type PlanStep struct {
+	Description string   `json:"description" jsonschema:"Short human-readable explanation of what this step does"`
+	Args        []string `json:"args" jsonschema:"The kubectl command arguments for this step (e.g., ['scale', 'deployment/web', '--replicas=3', '-n', 'prod'])"`
+}
+
+// ExecutePlanParams defines parameters for execute_plan
+type ExecutePlanParams struct {
+	Context string     `json:"context" jsonschema:"The cluster context name every step runs against (required)"`
+	Title   string     `json:"title" jsonschema:"One-line summary of the overall change shown to the user"`
+	Steps   []PlanStep `json:"steps" jsonschema:"Ordered kubectl steps; executed sequentially after a single confirmation"`
+	Format  string     `json:"format,omitempty" jsonschema:"Output format: text (default), or mermaid or dot (Graphviz) for a diagram of the steps, their outcome and any rollback"`
+}
+
+// PlanStepResult reports the outcome of one plan step or rollback action.
+type PlanStepResult struct {
+	Step        int    `json:"step"`
+	Description string `json:"description,omitempty"`
+	Command     string `json:"command"`
+	Status      string `json:"status"`
+	Output      string `json:"output,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ExecutePlanResult defines JSON output for execute_plan
+type ExecutePlanResult struct {
+	Title    string           `json:"title"`
+	Cluster  string           `json:"cluster"`
+	Context  string           `json:"context"`
+	Status   string           `json:"status"`
+	Steps    []PlanStepResult `json:"steps"`
+	Rollback []PlanStepResult `json:"rollback,omitempty"`
+}
+
+// plannedStep is a validated step ready for execution.
+type plannedStep struct {
+	description string
+	args        []string
+	fullCommand string
+	cmdArgs     []string
+	readOnly    bool
+}
+
+func defineExecutePlanTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolExecutePlan,
+		"Execute a multi-step change plan against one cluster. All steps are shown to the user and confirmed once, then run sequentially; if a step fails, previously completed write steps are rolled back in reverse order. Prefer this over several kubectl_exec calls when a change needs more than one write command.",
+		func(params ExecutePlanParams, inv llm.ToolInvocation) (any, error) {
+			return handleExecutePlan(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+// validatePlan checks every step up front so nothing runs if any step is invalid.
+func validatePlan(params ExecutePlanParams, policy KubectlPolicy) error {
+	if params.Context == "" {
+		return fmt.Errorf("context is required")
+	}
+	if len(params.Steps) == 0 {
+		return fmt.Errorf("plan must contain at least one step")
+	}
+	if len(params.Steps) > maxPlanSteps {
+		return fmt.Errorf("plan has %d steps; at most %d are allowed", len(params.Steps), maxPlanSteps)
+	}
+	if _, err := parseDiagramFormat(params.Format); err != nil {
+		return err
+	}
+	for i, step := range params.Steps {
+		if len(step.Args) == 0 {
+			return fmt.Errorf("step %d: kubectl arguments are required", i+1)
+		}
+		if err := validateKubectlCommand(step.Args, policy); err != nil {
+			return &llm.ToolError{Code: err.Code, Message: fmt.Sprintf("step %d: %s", i+1, err.Message), Hint: err.Hint, Retriable: err.Retriable, Err: err}
+		}
+	}
+	return nil
+}
+
+func preparePlanSteps(params ExecutePlanParams) ([]plannedStep, bool) {
+	steps := make([]plannedStep, len(params.Steps))
+	allReadOnly := true
+	for i, step := range params.Steps {
+		args := sanitizeKubectlArgs(step.Args)
+		fullCommand, cmdArgs := buildKubectlCommand(params.Context, args)
+		readOnly := isReadOnlyCommand(args)
+		allReadOnly = allReadOnly && readOnly
+		steps[i] = plannedStep{
+			description: step.Description,
+			args:        args,
+			fullCommand: fullCommand,
+			cmdArgs:     cmdArgs,
+			readOnly:    readOnly,
+		}
+	}
+	return steps, allReadOnly
+}
+
+func handleExecutePlan(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params ExecutePlanParams) (any, error) {
+	if err := validatePlan(params, state.kubectlPolicy); err != nil {
+		return nil, err
+	}
+	cluster, err := getClusterForContext(k8sProvider, params.Context)
+	if err != nil {
+		return nil, err
+	}
+	steps, allReadOnly := preparePlanSteps(params)
+
+	printPlan(state, params.Title, cluster.Name, params.Context, steps)
+
+	commands := make([]string, len(steps))
+	for i, s := range steps {
+		commands[i] = s.fullCommand
+	}
+	proceed, cancelResult, err := enforceToolExecutionMode(ctx, state, toolExecutePlan, allReadOnly, cluster.Name, params.Context, strings.Join(commands, "\n"))
+	if err != nil {
+		return nil, err
+	}
+	if !proceed {
+		return cancelResult, nil
+	}
+
+	result := runPlan(ctx, state, params.Context, steps)
+	if !allReadOnly {
+		var planErr error
+		if result.Status != planCompleted {
+			planErr = fmt.Errorf("plan %s", result.Status)
+		}
+		state.publishWrite(ApprovalRequest{Tool: toolExecutePlan, Cluster: cluster.Name, Context: params.Context, Command: strings.Join(commands, "\n")}, planErr)
+	}
+	result.Title = params.Title
+	result.Cluster = cluster.Name
+	result.Context = params.Context
+
+	if format, _ := parseDiagramFormat(params.Format); format != diagramText {
+		return planDiagram(result).render(format), nil
+	}
+	if isJSONOutput(state.outputFormat) {
+		return result, nil
+	}
+	return formatPlanResult(result), nil
+}
+
+// runPlan executes steps in order. On the first failure, remaining steps are
+// skipped and completed write steps are reversed newest first.
+func runPlan(ctx context.Context, state *agentState, contextName string, steps []plannedStep) ExecutePlanResult {
+	result := ExecutePlanResult{Status: planCompleted, Steps: make([]PlanStepResult, len(steps))}
+	undoRecords := make([]*undoRecord, len(steps))
+
+	failedAt := -1
+	for i, step := range steps {
+		result.Steps[i] = PlanStepResult{Step: i + 1, Description: step.description, Command: step.fullCommand}
+		if failedAt >= 0 {
+			result.Steps[i].Status = planStepSkipped
+			continue
+		}
+
+		printExecutionHeader(state, step.readOnly, step.fullCommand)
+		if !step.readOnly {
+			undoRecords[i] = captureUndoRecord(ctx, contextName, step.args, step.fullCommand)
+		}
+		output, execErr := runKubectlCommandFunc(ctx, step.cmdArgs)
+		result.Steps[i].Output = string(output)
+		if execErr != nil {
+			result.Steps[i].Status = planStepFailed
+			result.Steps[i].Error = execErr.Error()
+			failedAt = i
+			continue
+		}
+		result.Steps[i].Status = planStepSucceeded
+	}
+
+	if failedAt < 0 {
+		// The plan succeeded: make each write individually undoable afterwards,
+		// in order, so /undo also knows when the latest one cannot be undone.
+		for i, r := range undoRecords {
+			switch {
+			case r != nil:
+				state.undo.push(*r)
+			case !steps[i].readOnly:
+				state.undo.skip(toolExecutePlan, steps[i].fullCommand, uncapturedTargetReason)
+			}
+		}
+		return result
+	}
+
+	// Roll back even when the user aborted the turn or a step ran out of
+	// time: a half-applied plan must not stay behind.
+	rollbackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), planRollbackTimeout)
+	defer cancel()
+	result.Status = planRolledBack
+	for i := failedAt - 1; i >= 0; i-- {
+		if steps[i].readOnly {
+			continue
+		}
+		rb := rollbackPlanStep(rollbackCtx, state, i, steps[i], undoRecords[i])
+		result.Rollback = append(result.Rollback, rb)
+		if rb.Status != planStepRolledBack {
+			result.Status = planFailed
+			result.Steps[i].Status = rb.Status
+			if undoRecords[i] != nil {
+				// Keep the record so the user can retry with /undo.
+				state.undo.push(*undoRecords[i])
+			} else {
+				state.undo.skip(toolExecutePlan, steps[i].fullCommand, uncapturedTargetReason)
+			}
+			continue
+		}
+		result.Steps[i].Status = planStepRolledBack
+	}
+	return result
+}
+
+// rollbackPlanStep reverses a completed write step without asking again:
+// the user already approved the plan, which includes its rollback.
+func rollbackPlanStep(ctx context.Context, state *agentState, index int, step plannedStep, record *undoRecord) PlanStepResult {
+	rb := PlanStepResult{Step: index + 1, Description: step.description}
+	if record == nil {
+		rb.Command = step.fullCommand
+		rb.Status = planStepNotReversible
+		rb.Error = "no prior state was recorded for this command; revert it manually"
+		return rb
+	}
+	rb.Description = describeUndo(*record)
+
+	fullCommand, cmdArgs, cleanup, err := undoCommand(*record)
+	if err != nil {
+		rb.Status = planStepRollbackFailed
+		rb.Error = err.Error()
+		return rb
+	}
+	defer cleanup()
+
+	rb.Command = fullCommand
+	printExecutionHeader(state, false, fullCommand)
+	output, execErr := runKubectlCommandFunc(ctx, cmdArgs)
+	rb.Output = string(output)
+	if execErr != nil {
+		rb.Status = planStepRollbackFailed
+		rb.Error = execErr.Error()
+		return rb
+	}
+	rb.Status = planStepRolledBack
+	return rb
+}
+
+// printPlan displays the whole plan before the single confirmation prompt.
+func printPlan(state *agentState, title, clusterName, contextName string, steps []plannedStep) {
+	if isJSONOutput(state.outputFormat) {
+		return
+	}
+	resumeSpinner := pauseSpinner()
+	defer resumeSpinner()
+	out := state.stdout()
+
+	fmt.Fprintf(out, "\r\033[K\n%s📋 Change plan:%s %s%s%s\n", colorCyan, colorReset, colorBold, title, colorReset)
+	fmt.Fprintf(out, "%s   Cluster: %s (%s) — %d step(s)%s\n\n", colorDim, clusterName, contextName, len(steps), colorReset)
+	for i, s := range steps {
+		icon := "⚡"
+		if s.readOnly {
+			icon = "🔍"
+		}
+		fmt.Fprintf(out, "  %s[%d]%s %s %s\n", colorCyan, i+1, colorReset, icon, s.description)
+		fmt.Fprintf(out, "      %s%s%s\n", colorDim, s.fullCommand, colorReset)
+	}
+	fmt.Fprintf(out, "\n%sIf a step fails, completed write steps are rolled back in reverse order.%s\n", colorDim, colorReset)
+}
+
+// planStatusIcons maps step statuses to their text-mode markers.
+var planStatusIcons = map[string]string{
+	planStepSucceeded:      "✅",
+	planStepFailed:         "❌",
+	planStepSkipped:        "⏭️ ",
+	planStepRolledBack:     "↩️ ",
+	planStepRollbackFailed: "⚠️ ",
+	planStepNotReversible:  "⚠️ ",
+}
+
+func formatPlanResult(r ExecutePlanResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan: %s\n", r.Title)
+	fmt.Fprintf(&b, "Cluster: %s (%s)\n", r.Cluster, r.Context)
+	switch r.Status {
+	case planCompleted:
+		fmt.Fprintf(&b, "Status: ✅ all %d step(s) completed\n\n", len(r.Steps))
+	case planRolledBack:
+		b.WriteString("Status: ↩️  a step failed; completed changes were rolled back\n\n")
+	default:
+		b.WriteString("Status: ⚠️  a step failed and rollback was incomplete — manual action required\n\n")
+	}
+
+	for _, s := range r.Steps {
+		fmt.Fprintf(&b, "%s [%d] %s — %s\n", planStatusIcons[s.Status], s.Step, s.Description, s.Status)
+		fmt.Fprintf(&b, "    %s\n", s.Command)
+		if s.Error != "" {
+			fmt.Fprintf(&b, "    Error: %s\n", s.Error)
+		}
+		if out := strings.TrimSpace(s.Output); out != "" {
+			fmt.Fprintf(&b, "    %s\n", strings.ReplaceAll(out, "\n", "\n    "))
+		}
+	}
+
+	if len(r.Rollback) > 0 {
+		b.WriteString("\nRollback:\n")
+		for _, s := range r.Rollback {
+			fmt.Fprintf(&b, "%s [%d] %s — %s\n", planStatusIcons[s.Status], s.Step, s.Description, s.Status)
+			if s.Command != "" {
+				fmt.Fprintf(&b, "    %s\n", s.Command)
+			}
+			if s.Error != "" {
+				fmt.Fprintf(&b, "    Error: %s\n", s.Error)
+			}
+		}
+	}
+	return b.String()
+}
+
+// planStatusClasses maps step statuses to diagram node classes.
+var planStatusClasses = map[string]string{
+	planStepSucceeded:      "succeeded",
+	planStepFailed:         "failed",
+	planStepSkipped:        "skipped",
+	planStepRolledBack:     "rolledback",
+	planStepRollbackFailed: "failed",
+	planStepNotReversible:  "failed",
+}
+
+// planDiagram draws the executed plan: steps chained in order, coloured by
+// outcome, with the rollback path as dotted "undo" edges from the failed step.
+func planDiagram(r ExecutePlanResult) *diagram {
+	d := &diagram{Name: "plan"}
+	id := func(step int) string { return fmt.Sprintf("step%d", step) }
+	failed := ""
+	for i, s := range r.Steps {
+		d.Nodes = append(d.Nodes, diagramNode{
+			ID:    id(s.Step),
+			Label: fmt.Sprintf("%d. %s (%s)", s.Step, s.Description, s.Status),
+			Group: r.Title,
+			Shape: shapeBox,
+			Class: planStatusClasses[s.Status],
+		})
+		if i > 0 {
+			d.Edges = append(d.Edges, diagramEdge{From: id(r.Steps[i-1].Step), To: id(s.Step), Style: edgeSolid})
+		}
+		if s.Status == planStepFailed {
+			failed = id(s.Step)
+		}
+	}
+	from := failed
+	for _, rb := range r.Rollback {
+		if from == "" {
+			break
+		}
+		d.Edges = append(d.Edges, diagramEdge{From: from, To: id(rb.Step), Label: "undo", Style: edgeDotted})
+		from = id(rb.Step)
+	}
+	return d
+}
@@ -0,0 +1,262 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+func TestValidatePlan(t *testing.T) {
+	step := PlanStep{Description: "scale", Args: []string{"scale", "deployment/web", "--replicas=2"}}
+	tests := []struct {
+		name    string
+		params  ExecutePlanParams
+		wantErr string
+	}{
+		{"valid", ExecutePlanParams{Context: "ctx", Steps: []PlanStep{step}}, ""},
+		{"missing context", ExecutePlanParams{Steps: []PlanStep{step}}, "context is required"},
+		{"no steps", ExecutePlanParams{Context: "ctx"}, "at least one step"},
+		{"too many steps", ExecutePlanParams{Context: "ctx", Steps: make([]PlanStep, maxPlanSteps+1)}, "at most"},
+		{"empty args", ExecutePlanParams{Context: "ctx", Steps: []PlanStep{step, {}}}, "step 2"},
+		{"injection", ExecutePlanParams{Context: "ctx", Steps: []PlanStep{{Args: []string{"get", "pods", ";", "rm"}}}}, "step 1: validation failed"},
+		{"bad format", ExecutePlanParams{Context: "ctx", Steps: []PlanStep{step}, Format: "svg"}, "unsupported format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePlan(tt.params, KubectlStrict)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validatePlan() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	err := validatePlan(ExecutePlanParams{Context: "ctx", Steps: []PlanStep{{Args: []string{"apply", "-f", "../x.yaml"}}}}, KubectlPermissive)
+	var toolErr *llm.ToolError
+	if !errors.As(err, &toolErr) || toolErr.Code != llm.ToolErrorInvalidArguments || !errors.As(toolErr.Err, &toolErr) {
+		t.Errorf("validatePlan() error = %#v, want the step's rejection wrapped", err)
+	}
+}
+
+func TestExecutePlanSucceeds(t *testing.T) {
+	provider := newTestK8sProvider(t)
+
+	originalRunner := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = originalRunner })
+
+	var executed []string
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
+		cmd := strings.Join(args, " ")
+		if args[2] == "get" {
+			return []byte(`{"spec":{"replicas":1}}`), nil
+		}
+		executed = append(executed, cmd)
+		return []byte("ok\n"), nil
+	}
+
+	approver := &stubApprover{name: ApprovalLocal, decision: ApprovalDecision{Approved: true}}
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON, approver: approver}
+	result, err := handleExecutePlan(context.Background(), provider, state, ExecutePlanParams{
+		Context: "test-context",
+		Title:   "scale web and api",
+		Steps: []PlanStep{
+			{Description: "scale web", Args: []string{"scale", "deployment/web", "--replicas=3"}},
+			{Description: "scale api", Args: []string{"scale", "deployment/api", "--replicas=3"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("handleExecutePlan returned error: %v", err)
+	}
+	plan, ok := result.(ExecutePlanResult)
+	if !ok {
+		t.Fatalf("result should be ExecutePlanResult, got %T", result)
+	}
+	if plan.Status != planCompleted || len(executed) != 2 {
+		t.Errorf("status = %s, executed = %v", plan.Status, executed)
+	}
+	if !approver.called {
+		t.Error("plan should be confirmed once before executing")
+	}
+	if n := len(state.undo.list()); n != 2 {
+		t.Errorf("successful plan should record %d undo entries, got %d", 2, n)
+	}
+}
+
+func TestExecutePlanRollsBackOnFailure(t *testing.T) {
+	provider := newTestK8sProvider(t)
+
+	originalRunner := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = originalRunner })
+
+	var executed []string
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
+		cmd := strings.Join(args[2:], " ")
+		if args[2] == "get" {
+			return []byte(`{"spec":{"replicas":1}}`), nil
+		}
+		executed = append(executed, cmd)
+		if strings.Contains(cmd, "deployment/broken") {
+			return []byte("not found"), errors.New("exit status 1")
+		}
+		return []byte("ok\n"), nil
+	}
+
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON,
+		approver: &stubApprover{name: ApprovalLocal, decision: ApprovalDecision{Approved: true}}}
+	result, err := handleExecutePlan(context.Background(), provider, state, ExecutePlanParams{
+		Context: "test-context",
+		Title:   "three step change",
+		Steps: []PlanStep{
+			{Description: "scale web", Args: []string{"scale", "deployment/web", "--replicas=3"}},
+			{Description: "scale broken", Args: []string{"scale", "deployment/broken", "--replicas=3"}},
+			{Description: "scale api", Args: []string{"scale", "deployment/api", "--replicas=3"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("handleExecutePlan returned error: %v", err)
+	}
+	plan := result.(ExecutePlanResult)
+
+	if plan.Status != planRolledBack {
+		t.Errorf("Status = %s, want %s", plan.Status, planRolledBack)
+	}
+	wantStatuses := []string{planStepRolledBack, planStepFailed, planStepSkipped}
+	for i, want := range wantStatuses {
+		if plan.Steps[i].Status != want {
+			t.Errorf("step %d status = %s, want %s", i+1, plan.Steps[i].Status, want)
+		}
+	}
+	last := executed[len(executed)-1]
+	if last != "scale deployment/web --replicas=1" {
+		t.Errorf("rollback command = %q, want scale back to 1", last)
+	}
+	if _, ok := state.undo.last(); ok {
+		t.Error("rolled-back steps must not remain in the undo log")
+	}
+}
+
+func TestExecutePlanRollsBackAfterCancel(t *testing.T) {
+	provider := newTestK8sProvider(t)
+
+	originalRunner := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = originalRunner })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var rolledBack bool
+	runKubectlCommandFunc = func(runCtx context.Context, args []string) ([]byte, error) {
+		if err := runCtx.Err(); err != nil {
+			return nil, err
+		}
+		cmd := strings.Join(args[2:], " ")
+		switch {
+		case args[2] == "get":
+			return []byte(`{"spec":{"replicas":1}}`), nil
+		case strings.Contains(cmd, "deployment/api"):
+			cancel() // the user aborts the turn while the second step runs
+			return nil, context.Canceled
+		case cmd == "scale deployment/web --replicas=1":
+			rolledBack = true
+		}
+		return []byte("ok\n"), nil
+	}
+
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON,
+		approver: &stubApprover{name: ApprovalLocal, decision: ApprovalDecision{Approved: true}}}
+	result, err := handleExecutePlan(ctx, provider, state, ExecutePlanParams{
+		Context: "test-context",
+		Steps: []PlanStep{
+			{Description: "scale web", Args: []string{"scale", "deployment/web", "--replicas=3"}},
+			{Description: "scale api", Args: []string{"scale", "deployment/api", "--replicas=3"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("handleExecutePlan returned error: %v", err)
+	}
+	if plan := result.(ExecutePlanResult); plan.Status != planRolledBack || !rolledBack {
+		t.Errorf("status = %s, rolled back = %t; want the first step reverted after the cancel", plan.Status, rolledBack)
+	}
+}
+
+func TestExecutePlanDeclined(t *testing.T) {
+	provider := newTestK8sProvider(t)
+
+	originalRunner := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = originalRunner })
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
+		t.Fatalf("declined plan must not execute: %v", args)
+		return nil, nil
+	}
+
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON,
+		approver: &stubApprover{name: ApprovalLocal, decision: ApprovalDecision{Approved: false}}}
+	result, err := handleExecutePlan(context.Background(), provider, state, ExecutePlanParams{
+		Context: "test-context",
+		Steps:   []PlanStep{{Args: []string{"delete", "pod", "web-1"}}},
+	})
+	if err != nil {
+		t.Fatalf("handleExecutePlan returned error: %v", err)
+	}
+	if result != operationCancelledMessage {
+		t.Errorf("result = %v, want cancellation message", result)
+	}
+}
+
+func TestFormatPlanResult(t *testing.T) {
+	text := formatPlanResult(ExecutePlanResult{
+		Title:   "rotate",
+		Cluster: "prod",
+		Context: "prod-ctx",
+		Status:  planFailed,
+		Steps: []PlanStepResult{
+			{Step: 1, Description: "label", Command: "kubectl label pod/a x=y", Status: planStepNotReversible},
+			{Step: 2, Description: "scale", Command: "kubectl scale deploy/b", Status: planStepFailed, Error: "boom"},
+		},
+		Rollback: []PlanStepResult{{Step: 1, Description: "label", Status: planStepNotReversible, Error: "revert manually"}},
+	})
+	for _, want := range []string{"Plan: rotate", "manual action required", "Error: boom", "Rollback:", "revert manually"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("formatted plan missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestPlanDiagram(t *testing.T) {
+	d := planDiagram(ExecutePlanResult{
+		Title:  "rotate",
+		Status: planRolledBack,
+		Steps: []PlanStepResult{
+			{Step: 1, Description: "label", Status: planStepRolledBack},
+			{Step: 2, Description: "scale", Status: planStepRolledBack},
+			{Step: 3, Description: "restart", Status: planStepFailed},
+			{Step: 4, Description: "verify", Status: planStepSkipped},
+		},
+		Rollback: []PlanStepResult{{Step: 2, Status: planStepRolledBack}, {Step: 1, Status: planStepRolledBack}},
+	})
+	out := d.mermaid()
+	for _, want := range []string{
+		`  subgraph c0["rotate"]`,
+		`    n2["3. restart (failed)"]`,
+		"    class n2 failed",
+		"    class n3 skipped",
+		"  n0 --> n1\n",
+		"  n2 -.->|undo| n1",
+		"  n1 -.->|undo| n0",
+		"  classDef rolledback ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("plan diagram missing %q:\n%s", want, out)
+		}
+	}
+	if dot := d.dot(); !strings.Contains(dot, `"step3" -> "step2" [label="undo", style=dotted];`) {
+		t.Errorf("dot plan diagram missing rollback edge:\n%s", dot)
+	}
+}
@@ -0,0 +1,299 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the exec_in_pod tool.
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chzyer/readline"
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+	"golang.org/x/term"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+const (
+	// maxExecOutputBytes caps captured stdout and stderr each, so a chatty
+	// command cannot flood the model context.
+	maxExecOutputBytes = 64 * 1024
+	// execTimeout bounds non-interactive commands; interactive sessions end when the user exits.
+	execTimeout = 2 * time.Minute
+	// defaultShell is started when an interactive session has no command.
+	defaultShell = "/bin/sh"
+)
+
+// ExecInPodParams defines parameters for exec_in_pod
+type ExecInPodParams struct {
+	Context     string   `json:"context" jsonschema:"The cluster context name (required)"`
+	Namespace   string   `json:"namespace,omitempty" jsonschema:"Namespace of the pod; defaults to the context's namespace, or 'default'"`
+	Pod         string   `json:"pod" jsonschema:"Name of a running pod"`
+	Container   string   `json:"container,omitempty" jsonschema:"Container name; defaults to the pod's default container"`
+	Command     []string `json:"command,omitempty" jsonschema:"Command and arguments as an array, e.g. ['cat', '/etc/resolv.conf']; required unless interactive"`
+	Interactive bool     `json:"interactive,omitempty" jsonschema:"Hand the user an interactive terminal session in the container instead of capturing output; command defaults to /bin/sh"`
+}
+
+// ExecInPodResult is the outcome of a non-interactive exec.
+type ExecInPodResult struct {
+	Cluster   string `json:"cluster"`
+	Context   string `json:"context"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container,omitempty"`
+	Command   string `json:"command"`
+	ExitCode  int    `json:"exit_code"`
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// execInPodFunc runs an exec request; replaced in tests.
+var execInPodFunc = func(p *k8s.Provider, ctx context.Context, contextName string, req k8s.ExecRequest) (int, error) {
+	return p.ExecInPod(ctx, contextName, req)
+}
+
+func defineExecInPodTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolExecInPod,
+		"Run a command inside a container of a running pod and return its output and exit code, or (interactive=true) open an interactive shell for the user in their terminal. Prefer this over kubectl_exec with 'exec'. Always requires user confirmation, and is blocked in read-only mode.",
+		func(params ExecInPodParams, inv llm.ToolInvocation) (any, error) {
+			return handleExecInPod(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+func handleExecInPod(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params ExecInPodParams) (any, error) {
+	if params.Context == "" {
+		return nil, fmt.Errorf("context is required")
+	}
+	if params.Pod == "" {
+		return nil, fmt.Errorf("pod is required")
+	}
+	if len(params.Command) == 0 {
+		if !params.Interactive {
+			return nil, fmt.Errorf("command is required for non-interactive exec")
+		}
+		params.Command = []string{defaultShell}
+	}
+
+	cluster, err := getClusterForContext(k8sProvider, params.Context)
+	if err != nil {
+		return nil, err
+	}
+	if params.Namespace == "" {
+		params.Namespace = cluster.Namespace
+	}
+	if params.Namespace == "" {
+		params.Namespace = "default"
+	}
+
+	fullCommand := describeExec(params)
+	// Exec can run anything in the container, so it is always treated as a write.
+	proceed, cancelResult, err := enforceToolExecutionMode(ctx, state, toolExecInPod, false, cluster.Name, params.Context, fullCommand)
+	if err != nil {
+		return nil, err
+	}
+	if !proceed {
+		return cancelResult, nil
+	}
+
+	req := k8s.ExecRequest{
+		Namespace: params.Namespace,
+		Pod:       params.Pod,
+		Container: params.Container,
+		Command:   params.Command,
+	}
+	approval := ApprovalRequest{Tool: toolExecInPod, Cluster: cluster.Name, Context: params.Context, Command: fullCommand}
+	if params.Interactive {
+		result, err := runInteractiveExec(ctx, k8sProvider, params, req)
+		state.publishWrite(approval, err)
+		return result, err
+	}
+
+	printExecutionHeader(state, false, fullCommand)
+	stdout := &cappedBuffer{limit: maxExecOutputBytes}
+	stderr := &cappedBuffer{limit: maxExecOutputBytes}
+	req.Stdout, req.Stderr = stdout, stderr
+
+	ctx, cancel := context.WithTimeout(ctx, execTimeout)
+	defer cancel()
+	exitCode, err := execInPodFunc(k8sProvider, ctx, params.Context, req)
+	state.publishWrite(approval, err)
+	if err != nil {
+		return nil, err
+	}
+
+	result := ExecInPodResult{
+		Cluster:   cluster.Name,
+		Context:   params.Context,
+		Namespace: params.Namespace,
+		Pod:       params.Pod,
+		Container: params.Container,
+		Command:   strings.Join(params.Command, " "),
+		ExitCode:  exitCode,
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Truncated: stdout.truncated || stderr.truncated,
+	}
+	if isJSONOutput(state.outputFormat) {
+		return result, nil
+	}
+	return formatExecResult(result), nil
+}
+
+// describeExec renders an exec request in kubectl syntax for confirmation prompts.
+func describeExec(params ExecInPodParams) string {
+	var sb strings.Builder
+	sb.WriteString("exec ")
+	if params.Interactive {
+		sb.WriteString("-it ")
+	}
+	fmt.Fprintf(&sb, "%s -n %s", params.Pod, params.Namespace)
+	if params.Container != "" {
+		fmt.Fprintf(&sb, " -c %s", params.Container)
+	}
+	fmt.Fprintf(&sb, " --context %s -- %s", params.Context, strings.Join(params.Command, " "))
+	return sb.String()
+}
+
+// runInteractiveExec hands the user's terminal to a TTY session in the container
+// and returns once the remote shell exits.
+func runInteractiveExec(ctx context.Context, k8sProvider *k8s.Provider, params ExecInPodParams, req k8s.ExecRequest) (any, error) {
+	fd := int(os.Stdin.Fd()) // #nosec G115 -- file descriptors fit in int
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("interactive exec requires a terminal; pass a command to run it non-interactively")
+	}
+
+	resumeSpinner := pauseSpinner()
+	defer resumeSpinner()
+	fmt.Printf("\n%s🖥️  Attaching to %s/%s%s — exit the shell to return to kopilot\n\n", colorCyan, params.Namespace, params.Pod, colorReset)
+
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put terminal in raw mode: %w", err)
+	}
+	stdin := readline.NewCancelableStdin(os.Stdin)
+	sizes := watchTerminalSize(fd)
+	defer func() {
+		sizes.stop()
+		_ = stdin.Close()
+		_ = term.Restore(fd, oldState)
+		fmt.Println()
+	}()
+
+	req.Stdin = stdin
+	req.Stdout = os.Stdout
+	req.TTY = true
+	req.SizeQueue = sizes
+
+	exitCode, err := execInPodFunc(k8sProvider, ctx, params.Context, req)
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Sprintf("Interactive session in %s/%s ended with exit code %d. The user saw the session output directly.", params.Namespace, params.Pod, exitCode), nil
+}
+
+// terminalSizeQueue reports local terminal size changes to the remote TTY.
+// It polls rather than using SIGWINCH so it works on every platform.
+type terminalSizeQueue struct {
+	sizes chan remotecommand.TerminalSize
+	done  chan struct{}
+}
+
+func watchTerminalSize(fd int) *terminalSizeQueue {
+	q := &terminalSizeQueue{sizes: make(chan remotecommand.TerminalSize, 1), done: make(chan struct{})}
+	go func() {
+		defer close(q.sizes)
+		var last remotecommand.TerminalSize
+		ticker := time.NewTicker(250 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			if w, h, err := term.GetSize(fd); err == nil {
+				size := remotecommand.TerminalSize{Width: uint16(w), Height: uint16(h)} // #nosec G115 -- terminal dimensions fit in uint16
+				if size != last {
+					last = size
+					select {
+					case q.sizes <- size:
+					case <-q.done:
+						return
+					}
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-q.done:
+				return
+			}
+		}
+	}()
+	return q
+}
+
+// Next implements remotecommand.TerminalSizeQueue; nil ends the queue.
+func (q *terminalSizeQueue) Next() *remotecommand.TerminalSize {
+	size, ok := <-q.sizes
+	if !ok {
+		return nil
+	}
+	return &size
+}
+
+func (q *terminalSizeQueue) stop() {
+	close(q.done)
+}
+
+// cappedBuffer keeps the first limit bytes written and records whether more was discarded.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
+
+// formatExecResult formats an ExecInPodResult as human-readable text
+func formatExecResult(r ExecInPodResult) string {
+	var sb strings.Builder
+	target := r.Namespace + "/" + r.Pod
+	if r.Container != "" {
+		target += " [" + r.Container + "]"
+	}
+	icon := "✅"
+	if r.ExitCode != 0 {
+		icon = "❌"
+	}
+	fmt.Fprintf(&sb, "%s %s: %s (exit code %d)\n", icon, target, r.Command, r.ExitCode)
+	if r.Stdout != "" {
+		sb.WriteString("\n" + r.Stdout)
+		if !strings.HasSuffix(r.Stdout, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	if r.Stderr != "" {
+		sb.WriteString("\nstderr:\n" + r.Stderr)
+		if !strings.HasSuffix(r.Stderr, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	if r.Truncated {
+		fmt.Fprintf(&sb, "\n⚠️  Output truncated to %d KiB per stream.\n", maxExecOutputBytes/1024)
+	}
+	return sb.String()
+}
@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestHandleExecInPodValidation(t *testing.T) {
+	state := &agentState{outputFormat: OutputJSON}
+	tests := []struct {
+		params  ExecInPodParams
+		wantErr string
+	}{
+		{ExecInPodParams{Pod: "web", Command: []string{"ls"}}, "context is required"},
+		{ExecInPodParams{Context: "ctx", Command: []string{"ls"}}, "pod is required"},
+		{ExecInPodParams{Context: "ctx", Pod: "web"}, "command is required"},
+	}
+	for _, tt := range tests {
+		if _, err := handleExecInPod(context.Background(), nil, state, tt.params); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("handleExecInPod(context.Background(), %+v) error = %v, want containing %q", tt.params, err, tt.wantErr)
+		}
+	}
+}
+
+func TestHandleExecInPodRunsAfterConfirmation(t *testing.T) {
+	provider := newTestK8sProvider(t)
+
+	original := execInPodFunc
+	t.Cleanup(func() { execInPodFunc = original })
+	var got k8s.ExecRequest
+	execInPodFunc = func(_ *k8s.Provider, _ context.Context, contextName string, req k8s.ExecRequest) (int, error) {
+		got = req
+		_, _ = req.Stdout.Write([]byte("nameserver 10.0.0.10\n"))
+		_, _ = req.Stderr.Write([]byte("warning\n"))
+		return 3, nil
+	}
+
+	approver := &stubApprover{name: ApprovalLocal, decision: ApprovalDecision{Approved: true}}
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON, approver: approver}
+	result, err := handleExecInPod(context.Background(), provider, state, ExecInPodParams{
+		Context: "test-context", Pod: "web", Container: "app", Command: []string{"cat", "/etc/resolv.conf"},
+	})
+	if err != nil {
+		t.Fatalf("handleExecInPod returned error: %v", err)
+	}
+	if !approver.called {
+		t.Error("exec should be confirmed before running")
+	}
+	res, ok := result.(ExecInPodResult)
+	if !ok {
+		t.Fatalf("result should be ExecInPodResult, got %T", result)
+	}
+	if res.ExitCode != 3 || res.Stdout != "nameserver 10.0.0.10\n" || res.Stderr != "warning\n" || res.Namespace != "default" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if got.Pod != "web" || got.Container != "app" || got.TTY || strings.Join(got.Command, " ") != "cat /etc/resolv.conf" {
+		t.Errorf("unexpected exec request: %+v", got)
+	}
+}
+
+func TestHandleExecInPodContextNamespace(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["c"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	config.Contexts["payments"] = &clientcmdapi.Context{Cluster: "c", Namespace: "payments"}
+	config.CurrentContext = "payments"
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := k8s.NewProvider(path)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+
+	original := execInPodFunc
+	t.Cleanup(func() { execInPodFunc = original })
+	var got k8s.ExecRequest
+	execInPodFunc = func(_ *k8s.Provider, _ context.Context, _ string, req k8s.ExecRequest) (int, error) {
+		got = req
+		return 0, nil
+	}
+
+	approver := &stubApprover{name: ApprovalLocal, decision: ApprovalDecision{Approved: true}}
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON, approver: approver}
+	result, err := handleExecInPod(context.Background(), provider, state, ExecInPodParams{
+		Context: "payments", Pod: "api", Command: []string{"env"},
+	})
+	if err != nil {
+		t.Fatalf("handleExecInPod returned error: %v", err)
+	}
+	if res, _ := result.(ExecInPodResult); got.Namespace != "payments" || res.Namespace != "payments" {
+		t.Errorf("exec ran in namespace %q (result %q), want the context's namespace payments", got.Namespace, res.Namespace)
+	}
+	if !strings.Contains(approver.req.Command, "-n payments") {
+		t.Errorf("approval command = %q, want the context's namespace", approver.req.Command)
+	}
+}
+
+func TestHandleExecInPodDeclined(t *testing.T) {
+	provider := newTestK8sProvider(t)
+
+	original := execInPodFunc
+	t.Cleanup(func() { execInPodFunc = original })
+	execInPodFunc = func(*k8s.Provider, context.Context, string, k8s.ExecRequest) (int, error) {
+		t.Fatal("exec must not run when declined")
+		return 0, nil
+	}
+
+	approver := &stubApprover{name: ApprovalLocal, decision: ApprovalDecision{Approved: false}}
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON, approver: approver}
+	result, err := handleExecInPod(context.Background(), provider, state, ExecInPodParams{Context: "test-context", Pod: "web", Command: []string{"ls"}})
+	if err != nil {
+		t.Fatalf("handleExecInPod returned error: %v", err)
+	}
+	if result != operationCancelledMessage {
+		t.Errorf("declined exec should be cancelled, got %v", result)
+	}
+}
+
+func TestDescribeExec(t *testing.T) {
+	got := describeExec(ExecInPodParams{Context: "prod", Namespace: "shop", Pod: "web", Container: "app", Command: []string{"/bin/sh"}, Interactive: true})
+	want := "exec -it web -n shop -c app --context prod -- /bin/sh"
+	if got != want {
+		t.Errorf("describeExec() = %q, want %q", got, want)
+	}
+}
+
+func TestCappedBufferAndFormat(t *testing.T) {
+	buf := &cappedBuffer{limit: 4}
+	if n, err := buf.Write([]byte("abcdef")); n != 6 || err != nil {
+		t.Errorf("Write() = %d, %v; want 6, nil", n, err)
+	}
+	if buf.String() != "abcd" || !buf.truncated {
+		t.Errorf("buffer = %q truncated=%v", buf.String(), buf.truncated)
+	}
+
+	text := formatExecResult(ExecInPodResult{Namespace: "shop", Pod: "web", Command: "ls", ExitCode: 1, Stdout: "a", Stderr: "denied", Truncated: true})
+	for _, want := range []string{"❌ shop/web: ls (exit code 1)", "stderr:\ndenied", "truncated"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("formatted result missing %q:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the port_forward tool and the /forwards command.
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// PortForwardParams defines parameters for port_forward
+type PortForwardParams struct {
+	Context    string `json:"context" jsonschema:"The cluster context name (required)"`
+	Namespace  string `json:"namespace,omitempty" jsonschema:"Namespace of the target; defaults to 'default'"`
+	Target     string `json:"target" jsonschema:"What to forward to: 'svc/NAME', 'deployment/NAME', 'pod/NAME', or a bare pod name"`
+	LocalPort  int    `json:"local_port,omitempty" jsonschema:"Local port on 127.0.0.1; 0 or omitted picks a free port"`
+	RemotePort int    `json:"remote_port,omitempty" jsonschema:"Service or container port to forward to; 0 or omitted uses the first exposed port"`
+}
+
+func definePortForwardTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolPortForward,
+		"Start a background port-forward from 127.0.0.1 to a pod, service, or deployment (e.g. forward grafana in monitoring to localhost:3000). The forward stays open until the user stops it with /forwards stop or exits kopilot. Does not modify the cluster.",
+		func(params PortForwardParams, inv llm.ToolInvocation) (any, error) {
+			return handlePortForward(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+func handlePortForward(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params PortForwardParams) (any, error) {
+	if params.Context == "" {
+		return nil, fmt.Errorf("context is required")
+	}
+	if params.Target == "" {
+		return nil, fmt.Errorf("target is required")
+	}
+	if params.LocalPort < 0 || params.LocalPort > 65535 || params.RemotePort < 0 || params.RemotePort > 65535 {
+		return nil, fmt.Errorf("ports must be between 0 and 65535")
+	}
+	if _, err := getClusterForContext(k8sProvider, params.Context); err != nil {
+		return nil, err
+	}
+	if state.portForwards == nil {
+		state.portForwards = k8s.NewPortForwardManager(k8sProvider)
+	}
+
+	info, err := state.portForwards.Start(ctx, params.Context, params.Namespace, params.Target, params.LocalPort, params.RemotePort)
+	if err != nil {
+		return nil, err
+	}
+
+	if isJSONOutput(state.outputFormat) {
+		return info, nil
+	}
+	return fmt.Sprintf("🔌 Forwarding %s → %s/%s (pod %s, port %d)\n   Forward #%d is running in the background. List with /forwards, stop with /forwards stop %d.",
+		info.LocalAddress(), info.Namespace, info.Target, info.Pod, info.RemotePort, info.ID, info.ID), nil
+}
+
+// handleForwardsCommand processes "/forwards", "/forwards list", and "/forwards stop <id|all>".
+func handleForwardsCommand(deps *loopDeps, input string) (bool, error) {
+	parts := strings.Fields(input)
+	manager := deps.state.portForwards
+
+	if len(parts) == 1 || (len(parts) == 2 && strings.ToLower(parts[1]) == subCmdList) {
+		var forwards []k8s.PortForwardInfo
+		if manager != nil {
+			forwards = manager.List()
+		}
+		printForwards(forwards)
+		return true, nil
+	}
+
+	if strings.ToLower(parts[1]) != "stop" || len(parts) != 3 {
+		fmt.Printf("  %s●%s Usage: /forwards [list] | /forwards stop <id|all>\n", colorRed, colorReset)
+		return true, nil
+	}
+	if manager == nil {
+		fmt.Printf("  %s●%s No active port-forwards\n", colorDim, colorReset)
+		return true, nil
+	}
+	if strings.ToLower(parts[2]) == "all" {
+		manager.StopAll()
+		fmt.Printf("  %s●%s Stopped all port-forwards\n", colorGreen, colorReset)
+		return true, nil
+	}
+	id, err := strconv.Atoi(parts[2])
+	if err != nil {
+		fmt.Printf("  %s●%s Invalid forward id %q\n", colorRed, colorReset, parts[2])
+		return true, nil
+	}
+	if err := manager.Stop(id); err != nil {
+		fmt.Printf(fmtErrorBullet, colorRed, colorReset, err)
+		return true, nil
+	}
+	fmt.Printf("  %s●%s Stopped port-forward #%d\n", colorGreen, colorReset, id)
+	return true, nil
+}
+
+// printForwards shows the active port-forwards.
+func printForwards(forwards []k8s.PortForwardInfo) {
+	if len(forwards) == 0 {
+		fmt.Printf("  %s●%s No active port-forwards\n", colorDim, colorReset)
+		return
+	}
+	fmt.Println()
+	fmt.Printf("  %s%s%s\n", colorCyan, sectionRule("Port Forwards"), colorReset)
+	fmt.Println()
+	for _, f := range forwards {
+		fmt.Printf("  %s[%d]%s %s → %s/%s:%d  %s(pod %s, %s, up %s)%s\n",
+			colorCyan, f.ID, colorReset, f.LocalAddress(), f.Namespace, f.Target, f.RemotePort,
+			colorDim, f.Pod, f.Context, time.Since(f.StartedAt).Round(time.Second), colorReset)
+	}
+	fmt.Println()
+}
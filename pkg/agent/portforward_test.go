@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestHandlePortForwardValidation(t *testing.T) {
+	state := &agentState{outputFormat: OutputJSON}
+	tests := []struct {
+		params  PortForwardParams
+		wantErr string
+	}{
+		{PortForwardParams{Target: "svc/grafana"}, "context is required"},
+		{PortForwardParams{Context: "ctx"}, "target is required"},
+		{PortForwardParams{Context: "ctx", Target: "svc/grafana", LocalPort: 70000}, "between 0 and 65535"},
+	}
+	for _, tt := range tests {
+		if _, err := handlePortForward(context.Background(), nil, state, tt.params); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("handlePortForward(context.Background(), %+v) error = %v, want containing %q", tt.params, err, tt.wantErr)
+		}
+	}
+
+	provider := newTestK8sProvider(t)
+	if _, err := handlePortForward(context.Background(), provider, state, PortForwardParams{Context: "missing", Target: "svc/grafana"}); err == nil || !strings.Contains(err.Error(), "cluster not found") {
+		t.Errorf("unknown context should be rejected, got %v", err)
+	}
+}
+
+func TestHandleForwardsCommandWithoutManager(t *testing.T) {
+	deps := &loopDeps{state: &agentState{}}
+	for _, input := range []string{"/forwards", "/forwards list", "/forwards stop 1", "/forwards bogus"} {
+		handled, err := handleForwardsCommand(deps, input)
+		if !handled || err != nil {
+			t.Errorf("handleForwardsCommand(%q) = %v, %v", input, handled, err)
+		}
+	}
+	if isUnknownSlashCommand("/forwards stop all") {
+		t.Error("/forwards should be a known command")
+	}
+}
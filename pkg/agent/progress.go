@@ -0,0 +1,123 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file shows the progress of multi-cluster checks: on the spinner line
+// during check_all_clusters, and on stderr for the startup check and
+// "kopilot check".
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+// slowClusterAfter is how long a context may be pending before the progress
+// line names it as slow.
+const slowClusterAfter = 5 * time.Second
+
+// progressNames is the most pending contexts the progress line names.
+const progressNames = 3
+
+// spinnerStatus replaces the spinner label while set.
+var spinnerStatus atomic.Pointer[string]
+
+// setSpinnerStatus shows status on the spinner line; empty restores the
+// label.
+func setSpinnerStatus(status string) {
+	if status == "" {
+		spinnerStatus.Store(nil)
+		return
+	}
+	spinnerStatus.Store(&status)
+}
+
+// currentSpinnerLabel returns the spinner status, or the label.
+func currentSpinnerLabel() string {
+	if status := spinnerStatus.Load(); status != nil {
+		return *status
+	}
+	return spinnerLabel
+}
+
+// watchClusterProgress returns ctx carrying a progress channel for the
+// checks run under it. It calls show with a progress line at each update,
+// and every second while contexts are pending so slow ones show up. The
+// returned function stops it.
+func watchClusterProgress(ctx context.Context, show func(string)) (context.Context, func()) {
+	progress := make(chan k8s.ClusterProgress, 16)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		var last *k8s.ClusterProgress
+		for {
+			select {
+			case <-done:
+				return
+			case p := <-progress:
+				last = &p
+				show(progressLabel(p, time.Now()))
+			case now := <-ticker.C:
+				if last != nil && last.Done < last.Total {
+					show(progressLabel(*last, now))
+				}
+			}
+		}
+	}()
+	return k8s.WithProgress(ctx, progress), func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// progressLabel describes p: the count of checked contexts, then the
+// pending ones once few are left or they are slow.
+func progressLabel(p k8s.ClusterProgress, now time.Time) string {
+	label := fmt.Sprintf("checked %d/%d cluster(s)", p.Done, p.Total)
+	pending := len(p.Pending)
+	slow := now.Sub(p.Started) >= slowClusterAfter
+	if pending == 0 || (!slow && pending > progressNames) {
+		return label
+	}
+	names := strings.Join(p.Pending[:min(pending, progressNames)], ", ")
+	if pending > progressNames {
+		names += fmt.Sprintf(" +%d", pending-progressNames)
+	}
+	if slow {
+		return label + " · slow: " + names
+	}
+	return label + " · waiting on " + names
+}
+
+// spinnerClusterProgress shows the progress of the checks run under ctx on
+// the spinner line until the returned function is called.
+func spinnerClusterProgress(ctx context.Context) (context.Context, func()) {
+	ctx, stop := watchClusterProgress(ctx, setSpinnerStatus)
+	return ctx, func() {
+		stop()
+		setSpinnerStatus("")
+	}
+}
+
+// ShowCheckProgress prints the progress of the checks run under the
+// returned context on w, one line redrawn in place, when w is a terminal.
+// The returned function erases the line.
+func ShowCheckProgress(ctx context.Context, w io.Writer) (context.Context, func()) {
+	width := writerWidth(w)
+	if width == 0 {
+		return ctx, func() {}
+	}
+	ctx, stop := watchClusterProgress(ctx, func(label string) {
+		fmt.Fprintf(w, "\r%s\033[K", spinnerLine(terminalText("⏳"), label, width))
+	})
+	return ctx, func() {
+		stop()
+		fmt.Fprint(w, "\r\033[K")
+	}
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestProgressLabel(t *testing.T) {
+	start := time.Now()
+	p := k8s.ClusterProgress{Done: 36, Total: 40, Pending: []string{"eu-1", "eu-2", "us-1", "us-2"}, Started: start}
+	tests := []struct {
+		name    string
+		pending []string
+		elapsed time.Duration
+		want    string
+	}{
+		{"many pending", p.Pending, time.Second, "checked 36/40 cluster(s)"},
+		{"few pending", p.Pending[:2], time.Second, "checked 36/40 cluster(s) · waiting on eu-1, eu-2"},
+		{"slow", p.Pending, slowClusterAfter, "checked 36/40 cluster(s) · slow: eu-1, eu-2, us-1 +1"},
+		{"done", nil, slowClusterAfter, "checked 36/40 cluster(s)"},
+	}
+	for _, tt := range tests {
+		p.Pending = tt.pending
+		if got := progressLabel(p, start.Add(tt.elapsed)); got != tt.want {
+			t.Errorf("%s: progressLabel = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSpinnerClusterProgress(t *testing.T) {
+	k8sProvider := createMockProvider(t)
+	var mu sync.Mutex
+	var labels []string
+	ctx, stop := watchClusterProgress(context.Background(), func(label string) {
+		mu.Lock()
+		defer mu.Unlock()
+		labels = append(labels, label)
+	})
+	statuses := k8sProvider.GetClusterStatuses(ctx, []string{"missing-a", "missing-b"})
+	// Let the watcher take the last update before stopping it.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(labels)
+		mu.Unlock()
+		if n >= 3 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop()
+	if len(statuses) != 2 {
+		t.Fatalf("got %d statuses, want 2", len(statuses))
+	}
+	if len(labels) < 3 || labels[0] != "checked 0/2 cluster(s) · waiting on missing-a, missing-b" || labels[len(labels)-1] != "checked 2/2 cluster(s)" {
+		t.Errorf("labels = %q", labels)
+	}
+
+	ctx, stopSpinner := spinnerClusterProgress(context.Background())
+	k8sProvider.GetClusterStatuses(ctx, []string{"missing-a"})
+	deadline = time.Now().Add(2 * time.Second)
+	for !strings.HasPrefix(currentSpinnerLabel(), "checked 1/1") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := currentSpinnerLabel(); got != "checked 1/1 cluster(s)" {
+		t.Errorf("spinner label during the check = %q", got)
+	}
+	stopSpinner()
+	if got := currentSpinnerLabel(); got != spinnerLabel {
+		t.Errorf("spinner label after the check = %q, want %q", got, spinnerLabel)
+	}
+}
+
+func TestShowCheckProgressOffTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	ctx, stop := ShowCheckProgress(context.Background(), &buf)
+	if ctx != context.Background() {
+		t.Error("ShowCheckProgress should not watch checks when w is not a terminal")
+	}
+	stop()
+	if buf.Len() != 0 {
+		t.Errorf("wrote %q to a non-terminal", buf.String())
+	}
+}
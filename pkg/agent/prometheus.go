@@ -0,0 +1,146 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the query_prometheus tool (PromQL against each cluster's Prometheus).
+package agent
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// QueryPrometheusParams defines parameters for query_prometheus
+type QueryPrometheusParams struct {
+	Context string `json:"context" jsonschema:"The cluster context name (required)"`
+	Query   string `json:"query" jsonschema:"The PromQL expression, e.g. sum by (pod) (rate(container_cpu_usage_seconds_total{namespace=\"shop\"}[5m])) (required)"`
+	Range   string `json:"range,omitempty" jsonschema:"Run a range query over this look-back window, e.g. 1h or 2d; omit for an instant query"`
+	Step    string `json:"step,omitempty" jsonschema:"Resolution of a range query, e.g. 1m (default: about 120 points over the range)"`
+}
+
+func defineQueryPrometheusTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolQueryPrometheus,
+		"Run a PromQL query against the Prometheus of a cluster (configured per context in ~/.kopilot/config.json, or the prometheus-operator Prometheus reached through the API server proxy). Use this to correlate Kubernetes state with metrics when troubleshooting, e.g. CPU throttling, memory growth, request rates or error ratios. Instant query by default; set range for a time series. Read-only.",
+		func(params QueryPrometheusParams, inv llm.ToolInvocation) (any, error) {
+			return handleQueryPrometheus(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+func handleQueryPrometheus(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params QueryPrometheusParams) (any, error) {
+	if params.Context == "" || strings.TrimSpace(params.Query) == "" {
+		return nil, fmt.Errorf("context and query are required")
+	}
+	q := k8s.PrometheusQuery{Query: params.Query}
+	if params.Range != "" {
+		d, err := parseWindow(params.Range)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q", params.Range)
+		}
+		q.Range = d
+	}
+	if params.Step != "" {
+		d, err := time.ParseDuration(params.Step)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid step %q", params.Step)
+		}
+		if q.Range == 0 {
+			return nil, fmt.Errorf("step needs a range")
+		}
+		q.Step = d
+	}
+	if _, err := getClusterForContext(k8sProvider, params.Context); err != nil {
+		return nil, err
+	}
+
+	var endpoint *k8s.PrometheusEndpoint
+	if e, ok := state.prometheus[params.Context]; ok {
+		endpoint = &e
+	}
+	result, err := k8sProvider.QueryPrometheus(ctx, params.Context, endpoint, q)
+	if err != nil {
+		return nil, err
+	}
+	if isJSONOutput(state.outputFormat) {
+		return result, nil
+	}
+	return formatPrometheusResult(result), nil
+}
+
+// formatPrometheusResult renders vectors as one line per series and range
+// results as last/min/max per series, keeping the output compact for the model.
+func formatPrometheusResult(r *k8s.PrometheusResult) string {
+	var b strings.Builder
+	source := r.Endpoint
+	if r.Discovered {
+		source += ", discovered via prometheus-operator"
+	}
+	fmt.Fprintf(&b, "📈 PromQL on %s (%s): %s\n\n", r.Context, source, r.Query)
+	if len(r.Series) == 0 {
+		b.WriteString("  (no data)\n")
+	}
+	for _, s := range r.Series {
+		labels := formatPromLabels(s.Metric)
+		switch {
+		case len(s.Values) == 0:
+			fmt.Fprintf(&b, "  %s (no samples)\n", labels)
+		case r.ResultType == "matrix":
+			last := s.Values[len(s.Values)-1]
+			lo, hi := promMinMax(s.Values)
+			fmt.Fprintf(&b, "  %s last %s, min %s, max %s (%d samples, %s to %s)\n", labels, last.Value,
+				formatPromValue(lo), formatPromValue(hi), len(s.Values),
+				s.Values[0].Time.Local().Format("01-02 15:04"), last.Time.Local().Format("01-02 15:04"))
+		default:
+			fmt.Fprintf(&b, "  %s %s\n", labels, s.Values[0].Value)
+		}
+	}
+	if r.Truncated {
+		fmt.Fprintf(&b, "\n⚠️  Result truncated to %d series and %d samples per series; narrow the query with labels or aggregation.\n", k8s.MaxPrometheusSeries, k8s.MaxPrometheusPoints)
+	}
+	for _, w := range r.Warnings {
+		fmt.Fprintf(&b, "⚠️  %s\n", w)
+	}
+	return b.String()
+}
+
+// formatPromLabels formats a series as name{label="value", ...}.
+func formatPromLabels(metric map[string]string) string {
+	keys := make([]string, 0, len(metric))
+	for k := range metric {
+		if k != "__name__" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%q", k, metric[k])
+	}
+	return metric["__name__"] + "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// promMinMax returns the smallest and largest numeric values of samples.
+func promMinMax(samples []k8s.PrometheusSample) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, s := range samples {
+		v, err := strconv.ParseFloat(s.Value, 64)
+		if err != nil || math.IsNaN(v) {
+			continue
+		}
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	return lo, hi
+}
+
+func formatPromValue(v float64) string {
+	if math.IsInf(v, 0) {
+		return "n/a"
+	}
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestHandleQueryPrometheus(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"up","job":"api"},"value":[1700000000,"1"]}]}}`))
+	}))
+	defer server.Close()
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters["prod"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	config.AuthInfos["user"] = &clientcmdapi.AuthInfo{Token: "test-token"}
+	config.Contexts["prod"] = &clientcmdapi.Context{Cluster: "prod", AuthInfo: "user"}
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := k8s.NewProvider(path)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+
+	state := &agentState{outputFormat: OutputText, prometheus: map[string]k8s.PrometheusEndpoint{"prod": {URL: server.URL}}}
+	out, err := handleQueryPrometheus(context.Background(), provider, state, QueryPrometheusParams{Context: "prod", Query: "up"})
+	if err != nil {
+		t.Fatalf("handleQueryPrometheus: %v", err)
+	}
+	if text := out.(string); !strings.Contains(text, `up{job="api"} 1`) || !strings.Contains(text, "PromQL on prod ("+server.URL+")") {
+		t.Errorf("unexpected output:\n%s", text)
+	}
+	if gotPath != "/api/v1/query" {
+		t.Errorf("path = %s, want /api/v1/query", gotPath)
+	}
+
+	state.outputFormat = OutputJSON
+	out, err = handleQueryPrometheus(context.Background(), provider, state, QueryPrometheusParams{Context: "prod", Query: "up", Range: "1h", Step: "1m"})
+	if err != nil {
+		t.Fatalf("handleQueryPrometheus range: %v", err)
+	}
+	if r := out.(*k8s.PrometheusResult); r.Discovered || r.Query != "up" || gotPath != "/api/v1/query_range" {
+		t.Errorf("unexpected range result %+v (path %s)", r, gotPath)
+	}
+
+	for want, params := range map[string]QueryPrometheusParams{
+		"context and query are required": {Context: "prod"},
+		"invalid range":                  {Context: "prod", Query: "up", Range: "soon"},
+		"invalid step":                   {Context: "prod", Query: "up", Range: "1h", Step: "x"},
+		"step needs a range":             {Context: "prod", Query: "up", Step: "1m"},
+		"does not exist":                 {Context: "staging", Query: "up"},
+	} {
+		if _, err := handleQueryPrometheus(context.Background(), provider, state, params); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%+v: error = %v, want %q", params, err, want)
+		}
+	}
+}
+
+func TestFormatPrometheusResult(t *testing.T) {
+	at := func(sec int64) time.Time { return time.Unix(sec, 0) }
+	r := &k8s.PrometheusResult{
+		Context:    "prod",
+		Endpoint:   "monitoring/prometheus-operated:9090",
+		Discovered: true,
+		Query:      "rate(x[5m])",
+		ResultType: "matrix",
+		Series: []k8s.PrometheusSeries{{
+			Metric: map[string]string{"pod": "web-1", "namespace": "shop"},
+			Values: []k8s.PrometheusSample{{Time: at(0), Value: "0.5"}, {Time: at(60), Value: "NaN"}, {Time: at(120), Value: "0.2"}, {Time: at(180), Value: "0.3"}},
+		}},
+		Truncated: true,
+		Warnings:  []string{"partial response"},
+	}
+	text := formatPrometheusResult(r)
+	for _, want := range []string{
+		"discovered via prometheus-operator",
+		`{namespace="shop", pod="web-1"} last 0.3, min 0.2, max 0.5 (4 samples`,
+		"Result truncated",
+		"partial response",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+
+	if text := formatPrometheusResult(&k8s.PrometheusResult{ResultType: "vector"}); !strings.Contains(text, "(no data)") {
+		t.Errorf("empty result output:\n%s", text)
+	}
+}
@@ -0,0 +1,113 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the image_provenance tool (digest, build time and VCS revision of running images).
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// ImageProvenanceParams defines parameters for image_provenance
+type ImageProvenanceParams struct {
+	Context      string `json:"context" jsonschema:"The context name of the cluster to inspect (from list_clusters)"`
+	Image        string `json:"image" jsonschema:"Image as written in the pod spec, e.g. ghcr.io/org/api:1.4.2; omit the tag to match every tag of the repository"`
+	Namespace    string `json:"namespace,omitempty" jsonschema:"Optional: only search this namespace"`
+	SkipRegistry bool   `json:"skip_registry,omitempty" jsonschema:"If true, report only the digests running in the cluster without contacting the registry"`
+}
+
+func defineImageProvenanceTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolImageProvenance,
+		"Resolve what a running image tag really is: the digests containers run (and where), and from the registry (public images) each digest's build time and VCS labels such as org.opencontainers.image.revision and source, plus whether the tag has since moved to another digest. Use to state exactly which code revision is running where.",
+		func(params ImageProvenanceParams, inv llm.ToolInvocation) (any, error) {
+			if strings.TrimSpace(params.Image) == "" {
+				return nil, fmt.Errorf("image is required")
+			}
+			if params.Namespace != "" && !isValidKubernetesName(params.Namespace) {
+				return nil, fmt.Errorf("invalid namespace name: %s", params.Namespace)
+			}
+			report, err := k8sProvider.GetImageProvenance(inv.Ctx(), params.Context, params.Namespace, params.Image, !params.SkipRegistry)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get image provenance: %w", err)
+			}
+
+			if isJSONOutput(state.outputFormat) {
+				return report, nil
+			}
+			return formatProvenanceReport(report), nil
+		},
+	)
+}
+
+// shortDigest abbreviates a sha256 digest for display.
+func shortDigest(digest string) string {
+	if digest == "" {
+		return "(not reported yet)"
+	}
+	algo, hex, ok := strings.Cut(digest, ":")
+	if !ok || len(hex) <= 12 {
+		return digest
+	}
+	return algo + ":" + hex[:12]
+}
+
+// formatProvenanceReport formats a ProvenanceReport as human-readable text
+func formatProvenanceReport(report *k8s.ProvenanceReport) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Image Provenance: %s (%s)\n", report.Query, report.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	if len(report.Images) == 0 {
+		sb.WriteString("No running container uses this image.\n")
+		return sb.String()
+	}
+
+	for _, img := range report.Images {
+		fmt.Fprintf(&sb, "📦 %s\n", img.Image)
+		if img.TagDigest != "" {
+			fmt.Fprintf(&sb, "   Tag %s now points to %s\n", img.Reference.Tag, shortDigest(img.TagDigest))
+		}
+		if img.TagMoved {
+			sb.WriteString("   ⚠️  The tag has moved: no running container uses its current digest (restarted pods would pull different code)\n")
+		}
+		if img.RegistryError != "" {
+			fmt.Fprintf(&sb, "   ⚠️  Registry: %s\n", img.RegistryError)
+		}
+		for _, d := range img.Digests {
+			fmt.Fprintf(&sb, "   🔖 %s  (%d container(s))\n", shortDigest(d.Digest), len(d.Locations))
+			if d.Revision != "" {
+				fmt.Fprintf(&sb, "      Revision: %s\n", d.Revision)
+			}
+			if d.Source != "" {
+				fmt.Fprintf(&sb, "      Source:   %s\n", d.Source)
+			}
+			if d.Version != "" {
+				fmt.Fprintf(&sb, "      Version:  %s\n", d.Version)
+			}
+			if d.Created != "" {
+				fmt.Fprintf(&sb, "      Built:    %s\n", d.Created)
+			}
+			if d.Error != "" {
+				fmt.Fprintf(&sb, "      ⚠️  %s\n", d.Error)
+			} else if d.Digest != "" && d.Revision == "" && d.Created != "" {
+				sb.WriteString("      (no VCS revision label on this image)\n")
+			}
+			for i, loc := range d.Locations {
+				if i == 5 {
+					fmt.Fprintf(&sb, "      ... and %d more\n", len(d.Locations)-5)
+					break
+				}
+				node := loc.Node
+				if node == "" {
+					node = "(unscheduled)"
+				}
+				fmt.Fprintf(&sb, "      - %s/%s [%s] on %s\n", loc.Namespace, loc.Pod, loc.Container, node)
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestFormatProvenanceReport(t *testing.T) {
+	report := &k8s.ProvenanceReport{Context: "prod", Query: "ghcr.io/org/api:1.4", Images: []k8s.ImageProvenance{{
+		Image:     "ghcr.io/org/api:1.4",
+		Reference: k8s.ImageReference{Registry: "ghcr.io", Repository: "org/api", Tag: "1.4"},
+		TagDigest: "sha256:ffffffffffffffffffff",
+		TagMoved:  true,
+		Digests: []k8s.DigestProvenance{
+			{Digest: "sha256:0123456789abcdef0123", Revision: "abc123", Source: "https://github.com/org/api", Created: "2026-05-01T10:00:00Z",
+				Locations: []k8s.ImageLocation{{Namespace: "shop", Pod: "api-1", Container: "app", Node: "n1"}}},
+			{Digest: "sha256:aaaaaaaaaaaaaaaaaaaa", Error: "registry ghcr.io requires credentials for org/api (private image?)",
+				Locations: []k8s.ImageLocation{{Namespace: "shop", Pod: "api-2", Container: "app"}}},
+		},
+	}}}
+	text := formatProvenanceReport(report)
+	for _, want := range []string{
+		"Image Provenance: ghcr.io/org/api:1.4 (prod)",
+		"Tag 1.4 now points to sha256:ffffffffffff",
+		"The tag has moved",
+		"🔖 sha256:0123456789ab  (1 container(s))",
+		"Revision: abc123",
+		"Source:   https://github.com/org/api",
+		"Built:    2026-05-01T10:00:00Z",
+		"- shop/api-1 [app] on n1",
+		"⚠️  registry ghcr.io requires credentials",
+		"- shop/api-2 [app] on (unscheduled)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report missing %q:\n%s", want, text)
+		}
+	}
+
+	empty := formatProvenanceReport(&k8s.ProvenanceReport{Context: "prod", Query: "nginx"})
+	if !strings.Contains(empty, "No running container uses this image") {
+		t.Errorf("empty report = %q", empty)
+	}
+}
+
+func TestShortDigest(t *testing.T) {
+	cases := map[string]string{
+		"":                        "(not reported yet)",
+		"sha256:0123456789abcdef": "sha256:0123456789ab",
+		"sha256:abc":              "sha256:abc",
+	}
+	for in, want := range cases {
+		if got := shortDigest(in); got != want {
+			t.Errorf("shortDigest(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the get_quota_usage tool (ResourceQuota consumption and LimitRanges).
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// GetQuotaUsageParams defines parameters for get_quota_usage
+type GetQuotaUsageParams struct {
+	Context          string  `json:"context" jsonschema:"The context name of the cluster to inspect (from list_clusters)"`
+	Namespace        string  `json:"namespace,omitempty" jsonschema:"Optional: restrict the report to a specific namespace; leave empty for all non-system namespaces"`
+	IncludeSystem    bool    `json:"include_system,omitempty" jsonschema:"If true, include system namespaces (kube-system, kube-public, kube-node-lease)"`
+	ThresholdPercent float64 `json:"threshold_percent,omitempty" jsonschema:"Usage percentage at which a quota counts as near its limit (default 80)"`
+}
+
+func defineGetQuotaUsageTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolGetQuotaUsage,
+		"Report ResourceQuota consumption (used vs hard, with percentages) and LimitRange defaults, min/max and ratios per namespace, highlighting namespaces near their quota and listing namespaces without any quota. Use for capacity questions such as 'which teams are about to hit their quota' or 'why was my pod rejected for exceeding quota'.",
+		func(params GetQuotaUsageParams, inv llm.ToolInvocation) (any, error) {
+			if params.Namespace != "" && !isValidKubernetesName(params.Namespace) {
+				return nil, fmt.Errorf("invalid namespace name: %s", params.Namespace)
+			}
+			if params.ThresholdPercent == 0 {
+				params.ThresholdPercent = k8s.DefaultQuotaThreshold
+			}
+			if params.ThresholdPercent < 0 || params.ThresholdPercent > 100 {
+				return nil, fmt.Errorf("threshold_percent must be between 0 and 100")
+			}
+			report, err := k8sProvider.GetQuotaUsage(inv.Ctx(), params.Context, params.Namespace, params.IncludeSystem, params.ThresholdPercent)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get quota usage: %w", err)
+			}
+
+			if isJSONOutput(state.outputFormat) {
+				return report, nil
+			}
+			return formatQuotaReport(report), nil
+		},
+	)
+}
+
+// quotaBar renders pct as a 10-cell usage bar.
+func quotaBar(pct float64) string {
+	filled := int(pct/10 + 0.5)
+	filled = max(0, min(filled, 10))
+	return strings.Repeat("█", filled) + strings.Repeat("░", 10-filled)
+}
+
+// formatQuotaReport formats a QuotaReport as human-readable text
+func formatQuotaReport(report *k8s.QuotaReport) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Quota Usage: %s\n", report.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	quotaNamespaces := 0
+	for _, ns := range report.Namespaces {
+		if len(ns.Quotas) > 0 {
+			quotaNamespaces++
+		}
+	}
+	fmt.Fprintf(&sb, "📊 SUMMARY: %d namespace(s) with quotas, %d at or above %.0f%%, %d without quota\n",
+		quotaNamespaces, report.NearLimit, report.Threshold, len(report.WithoutQuota))
+
+	for _, ns := range report.Namespaces {
+		icon := "✅"
+		if len(ns.NearLimit) > 0 {
+			icon = "⚠️ "
+		}
+		fmt.Fprintf(&sb, "\n%s %s", icon, ns.Namespace)
+		if len(ns.Quotas) > 0 {
+			fmt.Fprintf(&sb, "  (peak %.0f%%)", ns.MaxPercent)
+		}
+		sb.WriteString("\n")
+		for _, q := range ns.Quotas {
+			fmt.Fprintf(&sb, "   ResourceQuota %s:\n", q.Name)
+			for _, r := range q.Resources {
+				marker := ""
+				if r.Percent >= report.Threshold {
+					marker = "  ⚠️"
+				}
+				fmt.Fprintf(&sb, "     %-28s %s %5.1f%%  %s / %s%s\n", r.Resource, quotaBar(r.Percent), r.Percent, r.Used, r.Hard, marker)
+			}
+		}
+		for _, lr := range ns.LimitRanges {
+			var parts []string
+			for _, kv := range [][2]string{{"default", lr.Default}, {"defaultRequest", lr.DefaultRequest}, {"min", lr.Min}, {"max", lr.Max}, {"maxRatio", lr.MaxRatio}} {
+				if kv[1] != "" {
+					parts = append(parts, kv[0]+"="+kv[1])
+				}
+			}
+			fmt.Fprintf(&sb, "   LimitRange %s %s/%s: %s\n", lr.LimitRange, lr.Type, lr.Resource, strings.Join(parts, " "))
+		}
+	}
+
+	if len(report.WithoutQuota) > 0 {
+		fmt.Fprintf(&sb, "\nℹ️  WITHOUT QUOTA: %s\n", strings.Join(report.WithoutQuota, ", "))
+	}
+	return sb.String()
+}
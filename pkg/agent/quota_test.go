@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestFormatQuotaReport(t *testing.T) {
+	report := &k8s.QuotaReport{
+		Context: "prod", Threshold: 80, NearLimit: 1, WithoutQuota: []string{"scratch"},
+		Namespaces: []k8s.NamespaceQuota{{
+			Namespace: "team-a", MaxPercent: 90, NearLimit: []string{"compute/requests.cpu"},
+			Quotas: []k8s.QuotaUsage{{Name: "compute", Resources: []k8s.QuotaResource{
+				{Resource: "requests.cpu", Used: "3600m", Hard: "4", Percent: 90},
+				{Resource: "requests.memory", Used: "2Gi", Hard: "8Gi", Percent: 25},
+			}}},
+			LimitRanges: []k8s.LimitRangeSetting{{LimitRange: "defaults", Type: "Container", Resource: "cpu", Default: "500m", DefaultRequest: "100m"}},
+		}},
+	}
+	text := formatQuotaReport(report)
+	for _, want := range []string{
+		"SUMMARY: 1 namespace(s) with quotas, 1 at or above 80%, 1 without quota",
+		"⚠️  team-a  (peak 90%)",
+		"█████████░  90.0%  3600m / 4  ⚠️",
+		"███░░░░░░░  25.0%  2Gi / 8Gi\n",
+		"LimitRange defaults Container/cpu: default=500m defaultRequest=100m",
+		"WITHOUT QUOTA: scratch",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestQuotaBar(t *testing.T) {
+	for pct, want := range map[float64]string{0: "░░░░░░░░░░", 55: "██████░░░░", 140: "██████████"} {
+		if got := quotaBar(pct); got != want {
+			t.Errorf("quotaBar(%v) = %s, want %s", pct, got, want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the premium quota budget: when to warn about the
+// remaining premium quota, when automatic model routing stops choosing the
+// premium model, and the /quota command showing and changing the budget.
+package agent
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// QuotaBudget sets how the premium quota is spent. Percentages are of the
+// remaining quota, as reported by the provider's usage events.
+type QuotaBudget struct {
+	// WarnBelow is the remaining quota at or below which kopilot warns,
+	// once per drop; 0 uses quotaLowPercentage.
+	WarnBelow float64 `json:"warn_below,omitempty"`
+	// StopPremiumBelow is the remaining quota at or below which automatic
+	// model routing uses the cost-effective model instead of the premium
+	// one; 0 never stops. A model forced with /model is always used.
+	StopPremiumBelow float64 `json:"stop_premium_below,omitempty"`
+	// ConfirmPremium asks before each request routed to the premium model
+	// once the remaining quota is at or below WarnBelow.
+	ConfirmPremium bool `json:"confirm_premium,omitempty"`
+}
+
+// warnBelow returns WarnBelow, or its default.
+func (b QuotaBudget) warnBelow() float64 {
+	if b.WarnBelow == 0 {
+		return quotaLowPercentage
+	}
+	return b.WarnBelow
+}
+
+// validate checks that the percentages are within 0-100.
+func (b QuotaBudget) validate() error {
+	if b.WarnBelow < 0 || b.WarnBelow > 100 {
+		return fmt.Errorf("warn_below must be between 0 and 100, got %g", b.WarnBelow)
+	}
+	if b.StopPremiumBelow < 0 || b.StopPremiumBelow > 100 {
+		return fmt.Errorf("stop_premium_below must be between 0 and 100, got %g", b.StopPremiumBelow)
+	}
+	return nil
+}
+
+// premiumPromptMu serialises premium confirmations with other terminal prompts.
+var premiumPromptMu sync.Mutex
+
+// newTerminalPremiumPrompt returns a confirmation asking on out whether to
+// send a request to the premium model with remaining percent of the quota
+// left; anything but yes declines.
+func newTerminalPremiumPrompt(in io.Reader, out io.Writer) func(model string, remaining float64) bool {
+	reader := bufio.NewReader(in)
+	return func(model string, remaining float64) bool {
+		premiumPromptMu.Lock()
+		defer premiumPromptMu.Unlock()
+		fmt.Fprintf(out, "  %s⚠ %.0f%% of the premium quota left.%s Use %s for this prompt? [y/N] ", colorYellow, remaining, colorReset, model)
+		response, err := reader.ReadString('\n')
+		if err != nil && response == "" {
+			return false
+		}
+		answer := strings.ToLower(strings.TrimSpace(response))
+		return answer == "y" || answer == "yes"
+	}
+}
+
+// applyQuotaBudget returns the model for a prompt automatic routing sent
+// to model: the cost-effective model instead of the premium one when the
+// remaining quota reached StopPremiumBelow, or when the user declines the
+// premium model under ConfirmPremium.
+func applyQuotaBudget(state *agentState, model string) string {
+	if model == modelCostEffective || state.forcedModel != "" || state.quotaUnlimited || state.quotaPercentage < 0 {
+		return model
+	}
+	remaining := state.quotaPercentage
+	switch {
+	case state.quota.StopPremiumBelow > 0 && remaining <= state.quota.StopPremiumBelow:
+		if !isJSONOutput(state.outputFormat) {
+			fmt.Printf("  %s● %.0f%% of the premium quota left, at or below the budget of %.0f%%: using %s (see /quota)%s\n",
+				colorDim, remaining, state.quota.StopPremiumBelow, modelCostEffective, colorReset)
+		}
+		return modelCostEffective
+	case state.quota.ConfirmPremium && remaining <= state.quota.warnBelow():
+		if state.askPremium == nil || !state.askPremium(model, remaining) {
+			return modelCostEffective
+		}
+	}
+	return model
+}
+
+// handleQuotaCommand processes /quota: without arguments it shows the
+// remaining premium quota and the budget; "warn <pct>", "stop <pct|off>"
+// and "confirm on|off" change the budget for the session.
+func handleQuotaCommand(state *agentState, input string) bool {
+	parts := strings.Fields(input)
+	if len(parts) == 1 {
+		printQuota(state)
+		return true
+	}
+	if len(parts) != 3 {
+		printQuotaUsage()
+		return true
+	}
+	budget := state.quota
+	value := strings.ToLower(parts[2])
+	switch strings.ToLower(parts[1]) {
+	case "warn":
+		pct, err := parseQuotaPercent(value)
+		if err != nil || pct == 0 {
+			printQuotaUsage()
+			return true
+		}
+		budget.WarnBelow = pct
+	case "stop":
+		if value == "off" {
+			value = "0"
+		}
+		pct, err := parseQuotaPercent(value)
+		if err != nil {
+			printQuotaUsage()
+			return true
+		}
+		budget.StopPremiumBelow = pct
+	case "confirm":
+		if value != "on" && value != "off" {
+			printQuotaUsage()
+			return true
+		}
+		budget.ConfirmPremium = value == "on"
+	default:
+		printQuotaUsage()
+		return true
+	}
+	state.quota = budget
+	fmt.Printf("  %s●%s Quota budget: %s\n", colorGreen, colorReset, describeQuotaBudget(budget))
+	return true
+}
+
+// parseQuotaPercent parses a percentage between 0 and 100, with or without "%".
+func parseQuotaPercent(s string) (float64, error) {
+	pct, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil || pct < 0 || pct > 100 {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	return pct, nil
+}
+
+func printQuotaUsage() {
+	fmt.Printf("  %s●%s Usage: /quota [warn <pct> | stop <pct|off> | confirm on|off]\n", colorRed, colorReset)
+}
+
+// describeQuotaBudget summarises the budget on one line.
+func describeQuotaBudget(b QuotaBudget) string {
+	stop := "never"
+	if b.StopPremiumBelow > 0 {
+		stop = fmt.Sprintf("at %.0f%%", b.StopPremiumBelow)
+	}
+	confirm := "off"
+	if b.ConfirmPremium {
+		confirm = "on"
+	}
+	return fmt.Sprintf("warn at %.0f%%, stop premium %s, confirm premium %s", b.warnBelow(), stop, confirm)
+}
+
+// printQuota shows the remaining premium quota and the budget.
+func printQuota(state *agentState) {
+	fmt.Println()
+	fmt.Printf("  %s%s%s\n", colorCyan, sectionRule("Premium Quota"), colorReset)
+	fmt.Println()
+	switch {
+	case state.quotaUnlimited:
+		fmt.Printf("  Remaining:      unlimited\n")
+	case state.quotaPercentage < 0:
+		fmt.Printf("  Remaining:      not yet available (send a message first)\n")
+	case state.quotaTotal > 0:
+		fmt.Printf("  Remaining:      %.0f%% (%.0f of %.0f requests used)\n", state.quotaPercentage, state.quotaUsed, state.quotaTotal)
+	default:
+		fmt.Printf("  Remaining:      %.0f%%\n", state.quotaPercentage)
+	}
+	fmt.Printf("  Budget:         %s\n", describeQuotaBudget(state.quota))
+	if !state.quotaUnlimited && state.quotaPercentage >= 0 {
+		switch {
+		case state.forcedModel != "":
+			fmt.Printf("  Premium model:  %s forced with /model; the budget does not apply\n", state.forcedModel)
+		case state.quota.StopPremiumBelow > 0 && state.quotaPercentage <= state.quota.StopPremiumBelow:
+			fmt.Printf("  Premium model:  %spaused%s, prompts use %s\n", colorYellow, colorReset, modelCostEffective)
+		case state.quota.ConfirmPremium && state.quotaPercentage <= state.quota.warnBelow():
+			fmt.Printf("  Premium model:  asked before each use\n")
+		default:
+			fmt.Printf("  Premium model:  available\n")
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestApplyQuotaBudget(t *testing.T) {
+	budget := QuotaBudget{WarnBelow: 25, StopPremiumBelow: 10}
+	confirm := QuotaBudget{WarnBelow: 25, ConfirmPremium: true}
+	tests := []struct {
+		name      string
+		remaining float64
+		unlimited bool
+		forced    string
+		budget    QuotaBudget
+		asked     bool
+		answer    bool
+		want      string
+	}{
+		{"plenty left", 60, false, "", budget, false, false, modelPremium},
+		{"below stop", 8, false, "", budget, false, false, modelCostEffective},
+		{"unknown quota", -1, false, "", budget, false, false, modelPremium},
+		{"unlimited", 0, true, "", budget, false, false, modelPremium},
+		{"forced model", 8, false, modelPremium, budget, false, false, modelPremium},
+		{"no stop", 2, false, "", QuotaBudget{}, false, false, modelPremium},
+		{"confirm accepted", 20, false, "", confirm, true, true, modelPremium},
+		{"confirm declined", 20, false, "", confirm, true, false, modelCostEffective},
+		{"confirm above warn", 30, false, "", confirm, false, false, modelPremium},
+	}
+	for _, tt := range tests {
+		asked := false
+		state := &agentState{
+			outputFormat:    OutputJSON,
+			quotaPercentage: tt.remaining,
+			quotaUnlimited:  tt.unlimited,
+			forcedModel:     tt.forced,
+			quota:           tt.budget,
+			askPremium: func(model string, remaining float64) bool {
+				asked = true
+				return tt.answer
+			},
+		}
+		if got := applyQuotaBudget(state, modelPremium); got != tt.want || asked != tt.asked {
+			t.Errorf("%s: applyQuotaBudget() = %s (asked %v), want %s (asked %v)", tt.name, got, asked, tt.want, tt.asked)
+		}
+	}
+	if got := applyQuotaBudget(&agentState{quotaPercentage: 1, quota: budget}, modelCostEffective); got != modelCostEffective {
+		t.Errorf("applyQuotaBudget() of the cost-effective model = %s", got)
+	}
+}
+
+func TestHandleQuotaCommand(t *testing.T) {
+	state := &agentState{quotaPercentage: 40, quotaUsed: 180, quotaTotal: 300}
+	for _, input := range []string{"/quota", "/quota warn 30%", "/quota stop 5", "/quota confirm on"} {
+		if !handleQuotaCommand(state, input) {
+			t.Errorf("handleQuotaCommand(%q) not handled", input)
+		}
+	}
+	want := QuotaBudget{WarnBelow: 30, StopPremiumBelow: 5, ConfirmPremium: true}
+	if state.quota != want {
+		t.Errorf("budget = %+v, want %+v", state.quota, want)
+	}
+	for _, input := range []string{"/quota stop 150", "/quota warn 0", "/quota confirm maybe", "/quota pause 5", "/quota warn"} {
+		handleQuotaCommand(state, input)
+	}
+	if state.quota != want {
+		t.Errorf("invalid commands changed the budget to %+v", state.quota)
+	}
+	handleQuotaCommand(state, "/quota stop off")
+	if state.quota.StopPremiumBelow != 0 {
+		t.Errorf("/quota stop off left %g", state.quota.StopPremiumBelow)
+	}
+	if got := describeQuotaBudget(QuotaBudget{}); got != "warn at 20%, stop premium never, confirm premium off" {
+		t.Errorf("describeQuotaBudget() = %q", got)
+	}
+}
+
+func TestQuotaWarningFollowsBudget(t *testing.T) {
+	var warnings []Event
+	state := &agentState{quota: QuotaBudget{WarnBelow: 30}}
+	state.bus.subscribe(func(e Event) { warnings = append(warnings, e) }, EventQuotaLow)
+	state.updateQuota(35, false)
+	state.updateQuota(28, false)
+	if len(warnings) != 1 || warnings[0].QuotaRemaining != 28 {
+		t.Errorf("quota warnings = %+v, want one at 28%%", warnings)
+	}
+}
+
+func TestTerminalPremiumPrompt(t *testing.T) {
+	var out bytes.Buffer
+	ask := newTerminalPremiumPrompt(strings.NewReader("y\nno\n"), &out)
+	if !ask(modelPremium, 12) || ask(modelPremium, 12) || ask(modelPremium, 12) {
+		t.Error("premium prompt answers should be yes, no, then no at EOF")
+	}
+	if !strings.Contains(out.String(), "12% of the premium quota left") {
+		t.Errorf("prompt = %q", out.String())
+	}
+}
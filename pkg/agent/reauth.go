@@ -0,0 +1,78 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the /reauth command, which renews the expired login
+// behind a context's credential plugin and rechecks the cluster, so the
+// session recovers without a restart.
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+// runLoginCommandFunc runs a login command on the terminal; a variable so
+// tests can stub it. A credential plugin's output is its token, so it is
+// discarded rather than shown.
+var runLoginCommandFunc = func(ctx context.Context, login k8s.LoginCommand) error {
+	cmd := exec.CommandContext(ctx, login.Args[0], login.Args[1:]...)
+	cmd.Env = append(os.Environ(), login.Env...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if login.Plugin {
+		cmd.Stdout = io.Discard
+	}
+	return cmd.Run()
+}
+
+// handleReauthCommand processes "/reauth [context]": it runs the login of
+// the context's credential plugin, the current context's by default, drops
+// the context's clients and cached status, and checks the cluster again.
+func handleReauthCommand(deps *loopDeps, input string) (bool, error) {
+	parts := strings.Fields(input)
+	if len(parts) > 2 {
+		fmt.Printf("  %s●%s Usage: /reauth [context]\n", colorRed, colorReset)
+		return true, nil
+	}
+	contextName := deps.k8sProvider.GetCurrentContext()
+	if len(parts) == 2 {
+		contextName = parts[1]
+	}
+	login, ok, err := deps.k8sProvider.LoginCommand(contextName)
+	if err != nil {
+		fmt.Printf(fmtErrorBullet, colorRed, colorReset, err)
+		return true, nil
+	}
+	if !ok {
+		fmt.Printf("  %s●%s Context %s has no credential plugin: renew its token or certificate in the kubeconfig, then run /context reload\n",
+			colorYellow, colorReset, contextName)
+		return true, nil
+	}
+
+	fmt.Printf("  %s●%s Renewing the credentials of %s: %s\n", colorCyan, colorReset, contextName, login)
+	if err := runLoginCommandFunc(deps.ctx, login); err != nil {
+		fmt.Printf("  %s●%s Login failed: %v\n", colorRed, colorReset, err)
+		return true, nil
+	}
+	if err := deps.k8sProvider.Reauthenticate(contextName); err != nil {
+		fmt.Printf(fmtErrorBullet, colorRed, colorReset, err)
+		return true, nil
+	}
+
+	status, err := deps.k8sProvider.GetClusterStatus(deps.ctx, contextName)
+	switch {
+	case err != nil:
+		fmt.Printf(fmtErrorBullet, colorRed, colorReset, err)
+	case status.IsReachable:
+		fmt.Printf("  %s●%s %s is reachable again (%s)\n", colorGreen, colorReset, contextName, status.Version)
+	default:
+		fmt.Printf("  %s●%s %s is still failing: %s\n", colorRed, colorReset, contextName, status.Error)
+		if status.Advice != "" {
+			fmt.Printf("    %s%s%s\n", colorDim, status.Advice, colorReset)
+		}
+	}
+	return true, nil
+}
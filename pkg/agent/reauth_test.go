@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestHandleReauthCommand(t *testing.T) {
+	// The cluster is down, so the recheck after the login fails quickly.
+	config := clientcmdapi.NewConfig()
+	config.Clusters["eks"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:1"}
+	config.AuthInfos["sso"] = &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{
+		Command: "aws", Args: []string{"eks", "get-token", "--profile", "prod"},
+	}}
+	config.AuthInfos["token"] = &clientcmdapi.AuthInfo{Token: "test-token"}
+	config.Contexts["prod"] = &clientcmdapi.Context{Cluster: "eks", AuthInfo: "sso"}
+	config.Contexts["static"] = &clientcmdapi.Context{Cluster: "eks", AuthInfo: "token"}
+	config.CurrentContext = "prod"
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := k8s.NewProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ran []string
+	orig := runLoginCommandFunc
+	runLoginCommandFunc = func(_ context.Context, login k8s.LoginCommand) error {
+		ran = append(ran, login.String())
+		return nil
+	}
+	defer func() { runLoginCommandFunc = orig }()
+
+	deps := &loopDeps{ctx: context.Background(), k8sProvider: provider, state: &agentState{}}
+	for _, input := range []string{"/reauth", "/reauth static", "/reauth missing", "/reauth a b"} {
+		if handled, err := handleReauthCommand(deps, input); !handled || err != nil {
+			t.Errorf("handleReauthCommand(%q) = %v, %v", input, handled, err)
+		}
+	}
+	if len(ran) != 1 || ran[0] != "aws sso login --profile prod" {
+		t.Errorf("logins run = %q, want only the SSO login of prod", ran)
+	}
+}
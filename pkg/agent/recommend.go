@@ -0,0 +1,274 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the recommend_resources tool (requests, limits and quota from observed usage).
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	defaultRecommendWindow   = "7d"
+	defaultRecommendHeadroom = 15
+)
+
+// RecommendResourcesParams defines parameters for recommend_resources
+type RecommendResourcesParams struct {
+	Context         string `json:"context" jsonschema:"The cluster context name (required)"`
+	Namespace       string `json:"namespace" jsonschema:"Namespace to size (required)"`
+	Window          string `json:"window,omitempty" jsonschema:"How far back to look at recorded usage, e.g. 24h or 7d (default 7d)"`
+	HeadroomPercent int    `json:"headroom_percent,omitempty" jsonschema:"Safety margin added on top of observed P95 usage, in percent (default 15)"`
+}
+
+// RecommendResourcesResult is the outcome of recommend_resources.
+type RecommendResourcesResult struct {
+	Cluster         string                      `json:"cluster"`
+	Context         string                      `json:"context"`
+	Namespace       string                      `json:"namespace"`
+	Window          string                      `json:"window"`
+	HeadroomPercent int                         `json:"headroom_percent"`
+	LowConfidence   bool                        `json:"low_confidence"`
+	Notes           []string                    `json:"notes"`
+	Recommendation  *k8s.ResourceRecommendation `json:"recommendation"`
+	YAML            string                      `json:"yaml"`
+}
+
+func defineRecommendResourcesTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolRecommendResources,
+		"Recommend container requests/limits and a namespace ResourceQuota from observed P95 usage (metrics-server samples recorded over a time window), with ready-to-apply YAML. Read-only: nothing is changed. Use this for right-sizing or quota planning questions.",
+		func(params RecommendResourcesParams, inv llm.ToolInvocation) (any, error) {
+			return handleRecommendResources(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+func handleRecommendResources(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params RecommendResourcesParams) (any, error) {
+	if params.Context == "" || params.Namespace == "" {
+		return nil, fmt.Errorf("context and namespace are required")
+	}
+	if !isValidKubernetesName(params.Namespace) {
+		return nil, fmt.Errorf("invalid namespace name: %s", params.Namespace)
+	}
+	if params.Window == "" {
+		params.Window = defaultRecommendWindow
+	}
+	window, err := parseWindow(params.Window)
+	if err != nil {
+		return nil, err
+	}
+	if params.HeadroomPercent == 0 {
+		params.HeadroomPercent = defaultRecommendHeadroom
+	}
+	if params.HeadroomPercent < 0 || params.HeadroomPercent > 200 {
+		return nil, fmt.Errorf("headroom_percent must be between 1 and 200")
+	}
+	cluster, err := getClusterForContext(k8sProvider, params.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	result := RecommendResourcesResult{
+		Cluster:         cluster.Name,
+		Context:         params.Context,
+		Namespace:       params.Namespace,
+		Window:          params.Window,
+		HeadroomPercent: params.HeadroomPercent,
+		Notes:           []string{},
+	}
+
+	// Take a fresh sample, recorded so the history grows with every use
+	// when the usage history is enabled; an unavailable metrics API is only
+	// fatal if there is no history either.
+	sample, sampleErr := sampleUsageFunc(ctx, k8sProvider, params.Context, params.Namespace)
+	retention, recording := usageHistoryRetention()
+	if sampleErr == nil && recording {
+		if err := appendUsageSample(sample, retention); err != nil {
+			result.Notes = append(result.Notes, fmt.Sprintf("could not record usage sample: %v", err))
+		}
+	}
+	samples, err := loadUsageSamples(params.Context, params.Namespace, time.Now().Add(-window))
+	if err != nil {
+		return nil, err
+	}
+	if sampleErr == nil && !recording && len(sample.Containers) > 0 {
+		samples = append(samples, *sample)
+	}
+	if len(samples) == 0 {
+		if sampleErr != nil {
+			return nil, sampleErr
+		}
+		return nil, fmt.Errorf("no running pods with metrics in namespace %s", params.Namespace)
+	}
+	if sampleErr != nil {
+		result.Notes = append(result.Notes, fmt.Sprintf("no fresh sample taken: %v", sampleErr))
+	}
+
+	result.Recommendation = k8s.RecommendResources(samples, float64(params.HeadroomPercent)/100)
+	if len(samples) < k8s.MinRecommendationSamples {
+		result.LowConfidence = true
+		result.Notes = append(result.Notes, fmt.Sprintf(
+			"only %d sample(s) in the window; set KOPILOT_USAGE_HISTORY=on and KOPILOT_USAGE_SAMPLE_INTERVAL (e.g. 5m) to record usage in the background", len(samples)))
+	}
+	result.YAML, err = recommendationYAML(params.Namespace, result.Recommendation)
+	if err != nil {
+		return nil, err
+	}
+
+	if isJSONOutput(state.outputFormat) {
+		return result, nil
+	}
+	return formatRecommendResources(result), nil
+}
+
+// parseWindow parses a look-back window, accepting a day suffix ("7d")
+// in addition to time.ParseDuration syntax.
+func parseWindow(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid window %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window %q", s)
+	}
+	return d, nil
+}
+
+// podTemplatePath returns the path to the pod spec inside a workload of the
+// given kind, or nil for kinds that cannot be patched in place.
+func podTemplatePath(kind string) []string {
+	switch kind {
+	case "deployment", "statefulset", "daemonset":
+		return []string{"spec", "template", "spec"}
+	case "cronjob":
+		return []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	default:
+		return nil
+	}
+}
+
+// workloadAPIVersions maps patchable workload kinds to their apiVersion and Kind.
+var workloadAPIVersions = map[string][2]string{
+	"deployment":  {"apps/v1", "Deployment"},
+	"statefulset": {"apps/v1", "StatefulSet"},
+	"daemonset":   {"apps/v1", "DaemonSet"},
+	"cronjob":     {"batch/v1", "CronJob"},
+}
+
+// recommendationYAML renders the ResourceQuota and one strategic merge
+// patch per workload as a multi-document YAML stream.
+func recommendationYAML(namespace string, rec *k8s.ResourceRecommendation) (string, error) {
+	var docs []string
+
+	quota := map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ResourceQuota",
+		"metadata":   map[string]any{"name": "compute", "namespace": namespace},
+		"spec": map[string]any{"hard": map[string]any{
+			"requests.cpu":    k8s.QuantityString(rec.Quota.RequestsCPU.Millicores, false),
+			"requests.memory": k8s.QuantityString(rec.Quota.RequestsMemory.Bytes, true),
+			"limits.memory":   k8s.QuantityString(rec.Quota.LimitsMemory.Bytes, true),
+			"pods":            strconv.Itoa(rec.Quota.Pods),
+		}},
+	}
+	out, err := yaml.Marshal(quota)
+	if err != nil {
+		return "", err
+	}
+	docs = append(docs, "# kubectl apply -f -\n"+string(out))
+
+	// Group containers by workload, preserving the recommendation order.
+	var workloads []string
+	containers := make(map[string][]any)
+	for _, c := range rec.Containers {
+		if _, seen := containers[c.Workload]; !seen {
+			workloads = append(workloads, c.Workload)
+		}
+		containers[c.Workload] = append(containers[c.Workload], map[string]any{
+			"name": c.Container,
+			"resources": map[string]any{
+				"requests": map[string]any{
+					"cpu":    k8s.QuantityString(c.RequestCPU.Millicores, false),
+					"memory": k8s.QuantityString(c.RequestMemory.Bytes, true),
+				},
+				"limits": map[string]any{
+					"memory": k8s.QuantityString(c.LimitMemory.Bytes, true),
+				},
+			},
+		})
+	}
+	for _, w := range workloads {
+		kind, name, _ := strings.Cut(w, "/")
+		path := podTemplatePath(kind)
+		if path == nil {
+			docs = append(docs, fmt.Sprintf("# %s is not managed by a patchable controller; set resources in its owner instead\n", w))
+			continue
+		}
+		var node any = map[string]any{"containers": containers[w]}
+		for i := len(path) - 1; i >= 0; i-- {
+			node = map[string]any{path[i]: node}
+		}
+		patch := node.(map[string]any)
+		gvk := workloadAPIVersions[kind]
+		patch["apiVersion"], patch["kind"] = gvk[0], gvk[1]
+		patch["metadata"] = map[string]any{"name": name, "namespace": namespace}
+		out, err := yaml.Marshal(patch)
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, fmt.Sprintf("# kubectl patch %s %s -n %s --type strategic --patch-file <this document>\n%s", kind, name, namespace, out))
+	}
+	return strings.Join(docs, "---\n"), nil
+}
+
+// formatRecommendResources formats a RecommendResourcesResult as human-readable text
+func formatRecommendResources(r RecommendResourcesResult) string {
+	rec := r.Recommendation
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Resource Recommendations: %s/%s (%s)\n", r.Cluster, r.Namespace, r.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	fmt.Fprintf(&sb, "📈 BASIS: %d sample(s) from %s to %s (window %s), P95 + %d%% headroom\n",
+		rec.Samples, rec.From.Format(time.RFC3339), rec.To.Format(time.RFC3339), r.Window, r.HeadroomPercent)
+	if r.LowConfidence {
+		sb.WriteString("⚠️  LOW CONFIDENCE: too few samples for a reliable P95\n")
+	}
+	for _, n := range r.Notes {
+		fmt.Fprintf(&sb, "  • %s\n", n)
+	}
+
+	sb.WriteString("\n📦 CONTAINERS:\n")
+	for _, c := range rec.Containers {
+		fmt.Fprintf(&sb, "  %s [%s] ×%d\n", c.Workload, c.Container, c.Replicas)
+		fmt.Fprintf(&sb, "     CPU request:    %s → %s (P95 %s)\n", orUnset(c.CurrentCPU.Millicores, c.CurrentCPU.Human), c.RequestCPU.Human, c.P95CPU.Human)
+		fmt.Fprintf(&sb, "     Memory request: %s → %s (P95 %s)\n", orUnset(c.CurrentMemory.Bytes, c.CurrentMemory.Human), c.RequestMemory.Human, c.P95Memory.Human)
+		fmt.Fprintf(&sb, "     Memory limit:   %s (peak %s)\n", c.LimitMemory.Human, c.MaxMemory.Human)
+	}
+
+	fmt.Fprintf(&sb, "\n🧮 NAMESPACE QUOTA: requests.cpu %s, requests.memory %s, limits.memory %s, pods %d\n",
+		rec.Quota.RequestsCPU.Human, rec.Quota.RequestsMemory.Human, rec.Quota.LimitsMemory.Human, rec.Quota.Pods)
+	sb.WriteString("   CPU limits are intentionally not recommended: they throttle bursts without protecting other workloads.\n")
+
+	sb.WriteString("\n📝 YAML:\n")
+	sb.WriteString(r.YAML)
+	return sb.String()
+}
+
+// orUnset returns human, or "unset" when the raw value is zero.
+func orUnset(raw int64, human string) string {
+	if raw == 0 {
+		return "unset"
+	}
+	return human
+}
@@ -0,0 +1,167 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+// withUsageHistoryDir points the usage history at a temporary directory.
+func withUsageHistoryDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	original := usageHistoryDir
+	t.Cleanup(func() { usageHistoryDir = original })
+	usageHistoryDir = func() (string, error) { return dir, nil }
+	return dir
+}
+
+func TestUsageHistoryRoundTrip(t *testing.T) {
+	dir := withUsageHistoryDir(t)
+	now := time.Now()
+	old := &k8s.UsageSample{Time: now.Add(-48 * time.Hour), Context: "arn:aws:eks:x/prod", Containers: []k8s.ContainerUsage{{Namespace: "shop", Workload: "deployment/web", Container: "app"}}}
+	recent := &k8s.UsageSample{Time: now, Context: "arn:aws:eks:x/prod", Containers: []k8s.ContainerUsage{
+		{Namespace: "shop", Workload: "deployment/web", Container: "app", CPUMilli: 5},
+		{Namespace: "other", Workload: "deployment/api", Container: "app"},
+	}}
+	for _, s := range []*k8s.UsageSample{old, recent} {
+		if err := appendUsageSample(s, defaultUsageHistoryRetention); err != nil {
+			t.Fatalf("appendUsageSample: %v", err)
+		}
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "arn_aws_eks_x_prod-*.jsonl")); len(files) != 1 {
+		t.Errorf("history file should use a sanitized context name: %v", files)
+	}
+
+	samples, err := loadUsageSamples("arn:aws:eks:x/prod", "shop", now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("loadUsageSamples: %v", err)
+	}
+	if len(samples) != 1 || len(samples[0].Containers) != 1 || samples[0].Containers[0].CPUMilli != 5 {
+		t.Errorf("unexpected samples: %+v", samples)
+	}
+
+	if samples, err := loadUsageSamples("missing", "shop", time.Time{}); err != nil || samples != nil {
+		t.Errorf("missing history = %v, %v; want nil, nil", samples, err)
+	}
+}
+
+func TestUsageSampleInterval(t *testing.T) {
+	t.Setenv("KOPILOT_USAGE_HISTORY", "")
+	t.Setenv("KOPILOT_USAGE_SAMPLE_INTERVAL", "5m")
+	if got := usageSampleInterval(); got != 0 {
+		t.Errorf("sampler runs without the usage history: interval %v", got)
+	}
+
+	t.Setenv("KOPILOT_USAGE_HISTORY", "on")
+	for value, want := range map[string]time.Duration{"": 0, "nope": 0, "-5m": 0, "10s": time.Minute, "5m": 5 * time.Minute} {
+		t.Setenv("KOPILOT_USAGE_SAMPLE_INTERVAL", value)
+		if got := usageSampleInterval(); got != want {
+			t.Errorf("usageSampleInterval(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	for in, want := range map[string]time.Duration{"7d": 7 * 24 * time.Hour, "36h": 36 * time.Hour} {
+		if got, err := parseWindow(in); err != nil || got != want {
+			t.Errorf("parseWindow(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"0d", "xd", "-1h", "week"} {
+		if _, err := parseWindow(in); err == nil {
+			t.Errorf("parseWindow(%q) should fail", in)
+		}
+	}
+}
+
+func TestRecommendationYAML(t *testing.T) {
+	rec := &k8s.ResourceRecommendation{
+		Containers: []k8s.ContainerRecommendation{
+			{Workload: "deployment/web", Container: "app", RequestCPU: k8s.CPUAmountFromMillicores(250), RequestMemory: k8s.MemoryAmountFromBytes(256 << 20), LimitMemory: k8s.MemoryAmountFromBytes(512 << 20)},
+			{Workload: "cronjob/backup", Container: "dump", RequestCPU: k8s.CPUAmountFromMillicores(100), RequestMemory: k8s.MemoryAmountFromBytes(64 << 20), LimitMemory: k8s.MemoryAmountFromBytes(128 << 20)},
+			{Workload: "pod/debug", Container: "sh"},
+		},
+		Quota: k8s.QuotaRecommendation{RequestsCPU: k8s.CPUAmountFromMillicores(1500), RequestsMemory: k8s.MemoryAmountFromBytes(2 << 30), LimitsMemory: k8s.MemoryAmountFromBytes(4 << 30), Pods: 6},
+	}
+	out, err := recommendationYAML("shop", rec)
+	if err != nil {
+		t.Fatalf("recommendationYAML: %v", err)
+	}
+	for _, want := range []string{
+		"kind: ResourceQuota", "requests.cpu: 1500m", "requests.memory: 2Gi", "pods: \"6\"",
+		"kind: Deployment", "cpu: 250m", "memory: 512Mi",
+		"kind: CronJob", "jobTemplate:",
+		"# pod/debug is not managed by a patchable controller",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("YAML missing %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "---\n"); n != 3 {
+		t.Errorf("got %d document separators, want 3", n)
+	}
+}
+
+func TestHandleRecommendResources(t *testing.T) {
+	withUsageHistoryDir(t)
+	t.Setenv("KOPILOT_USAGE_HISTORY", "off")
+	provider := newTestK8sProvider(t)
+	state := &agentState{outputFormat: OutputJSON}
+
+	if _, err := handleRecommendResources(context.Background(), provider, state, RecommendResourcesParams{Context: "test-context"}); err == nil {
+		t.Error("missing namespace should be rejected")
+	}
+
+	original := sampleUsageFunc
+	t.Cleanup(func() { sampleUsageFunc = original })
+	sampleUsageFunc = func(_ context.Context, _ *k8s.Provider, contextName, namespace string) (*k8s.UsageSample, error) {
+		return nil, errors.New("metrics API unavailable")
+	}
+	if _, err := handleRecommendResources(context.Background(), provider, state, RecommendResourcesParams{Context: "test-context", Namespace: "shop"}); err == nil {
+		t.Error("no history and no metrics should fail")
+	}
+
+	sampleUsageFunc = func(_ context.Context, _ *k8s.Provider, contextName, namespace string) (*k8s.UsageSample, error) {
+		return &k8s.UsageSample{Time: time.Now(), Context: contextName, Namespace: namespace, Containers: []k8s.ContainerUsage{
+			{Namespace: namespace, Workload: "deployment/web", Pod: "web-a", Container: "app", CPUMilli: 100, MemoryBytes: 100 << 20},
+		}}, nil
+	}
+	out, err := handleRecommendResources(context.Background(), provider, state, RecommendResourcesParams{Context: "test-context", Namespace: "shop"})
+	if err != nil {
+		t.Fatalf("handleRecommendResources: %v", err)
+	}
+	result := out.(RecommendResourcesResult)
+	if !result.LowConfidence || result.HeadroomPercent != 15 || result.Window != "7d" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if got := result.Recommendation.Containers[0].RequestCPU.Millicores; got != 115 {
+		t.Errorf("request CPU = %dm, want 115m", got)
+	}
+
+	// Without the usage history the sample is not recorded.
+	out, _ = handleRecommendResources(context.Background(), provider, state, RecommendResourcesParams{Context: "test-context", Namespace: "shop"})
+	if n := out.(RecommendResourcesResult).Recommendation.Samples; n != 1 {
+		t.Errorf("second call without history saw %d samples, want 1", n)
+	}
+
+	// With it, the sample is recorded, so the next call sees two.
+	t.Setenv("KOPILOT_USAGE_HISTORY", "on")
+	handleRecommendResources(context.Background(), provider, state, RecommendResourcesParams{Context: "test-context", Namespace: "shop"})
+	out, _ = handleRecommendResources(context.Background(), provider, state, RecommendResourcesParams{Context: "test-context", Namespace: "shop"})
+	if n := out.(RecommendResourcesResult).Recommendation.Samples; n != 2 {
+		t.Errorf("call after a recorded sample saw %d samples, want 2", n)
+	}
+
+	text := formatRecommendResources(result)
+	for _, want := range []string{"LOW CONFIDENCE", "deployment/web [app]", "NAMESPACE QUOTA", "kind: ResourceQuota"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("formatted result missing %q:\n%s", want, text)
+		}
+	}
+}
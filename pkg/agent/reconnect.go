@@ -0,0 +1,218 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file supervises the LLM backend process, the Copilot CLI: it pings
+// the backend while the model responds and before each prompt, and when the
+// backend crashed or hung it restarts it and carries the recent conversation
+// over to a new session instead of leaving the REPL hanging.
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// backendPingInterval is how often the backend is pinged while the model
+// responds; a variable so tests can shorten it.
+var backendPingInterval = 15 * time.Second
+
+const (
+	// backendPingTimeout bounds a ping; a hung backend never answers.
+	backendPingTimeout = 10 * time.Second
+	// backendPingFailures is how many pings in a row must fail during a
+	// turn before the backend is restarted.
+	backendPingFailures = 2
+	// maxReplayedExchanges and maxReplayedChars bound the conversation
+	// carried over to the session of a restarted backend.
+	maxReplayedExchanges = 6
+	maxReplayedChars     = 600
+)
+
+// exchange is a prompt of the user and the model's final answer to it.
+type exchange struct {
+	prompt   string
+	response string
+}
+
+// recordPrompt starts an exchange with prompt; the messages of the model
+// until the turn ends are its answer.
+func (s *agentState) recordPrompt(prompt string) {
+	s.responseMu.Lock()
+	defer s.responseMu.Unlock()
+	s.exchanges = append(s.exchanges, exchange{prompt: prompt})
+	if len(s.exchanges) > maxReplayedExchanges {
+		s.exchanges = s.exchanges[len(s.exchanges)-maxReplayedExchanges:]
+	}
+	s.exchangeOpen = true
+}
+
+// recordResponse sets the answer of the open exchange; messages outside a
+// recorded prompt, such as those to /compact or a replay, are ignored.
+// The caller holds responseMu.
+func (s *agentState) recordResponse(text string) {
+	if s.exchangeOpen && len(s.exchanges) > 0 {
+		s.exchanges[len(s.exchanges)-1].response = text
+	}
+}
+
+// closeExchange ends the open exchange and reports whether there was one.
+func (s *agentState) closeExchange() bool {
+	s.responseMu.Lock()
+	defer s.responseMu.Unlock()
+	open := s.exchangeOpen
+	s.exchangeOpen = false
+	return open
+}
+
+// clearExchanges forgets the recorded conversation, for /clear.
+func (s *agentState) clearExchanges() {
+	s.responseMu.Lock()
+	defer s.responseMu.Unlock()
+	s.exchanges, s.exchangeOpen = nil, false
+}
+
+// replayPrompt returns the prompt restoring the recorded conversation in a
+// new session, or "" when nothing was recorded.
+func (s *agentState) replayPrompt() string {
+	s.responseMu.RLock()
+	defer s.responseMu.RUnlock()
+	if len(s.exchanges) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "[CONTEXT FROM PREVIOUS SESSION (last %d exchanges)]\n", len(s.exchanges))
+	for _, e := range s.exchanges {
+		fmt.Fprintf(&b, "User: %s\n", truncateRunes(e.prompt, maxReplayedChars))
+		if e.response != "" {
+			fmt.Fprintf(&b, "Assistant: %s\n", truncateRunes(e.response, maxReplayedChars))
+		}
+	}
+	b.WriteString("[END CONTEXT]\nThe previous session ended unexpectedly. Acknowledge in one short sentence.")
+	return b.String()
+}
+
+// truncateRunes shortens s to at most n runes, marking the cut with "…".
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
+
+// setBackendLost records why the backend was found dead during a turn.
+func (s *agentState) setBackendLost(err error) {
+	s.backendMu.Lock()
+	defer s.backendMu.Unlock()
+	s.backendLost = err
+}
+
+// takeBackendLost returns and clears the error recorded by setBackendLost.
+func (s *agentState) takeBackendLost() error {
+	s.backendMu.Lock()
+	defer s.backendMu.Unlock()
+	err := s.backendLost
+	s.backendLost = nil
+	return err
+}
+
+// superviseBackend pings the backend of deps.provider while the model
+// responds. When it stops answering, the failure is recorded for
+// reconnectBackend and the wait for the turn ends. The returned func stops
+// the supervision; providers without a backend process are not supervised.
+func superviseBackend(deps *loopDeps) (stop func()) {
+	checker, ok := deps.provider.(llm.HealthChecker)
+	if !ok || *deps.isIdle {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(backendPingInterval)
+		defer ticker.Stop()
+		failures := 0
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if *deps.isIdle {
+				return
+			}
+			ctx, cancel := context.WithTimeout(deps.ctx, backendPingTimeout)
+			err := checker.Ping(ctx)
+			cancel()
+			if err == nil {
+				failures = 0
+				continue
+			}
+			if failures++; failures >= backendPingFailures {
+				*deps.isIdle = true
+				deps.state.setBackendLost(err)
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// checkBackend pings the backend before a prompt is sent and reconnects
+// when it crashed since the last turn.
+func checkBackend(deps *loopDeps, ts *turnState) error {
+	checker, ok := deps.provider.(llm.HealthChecker)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(deps.ctx, backendPingTimeout)
+	err := checker.Ping(ctx)
+	cancel()
+	if err == nil {
+		return nil
+	}
+	return reconnectBackend(deps, ts, err)
+}
+
+// reconnectBackend restarts the backend, which failed with cause, creates a
+// new session with the model of ts, and restores the recent conversation in
+// it. The turn in flight, if any, is lost; the user is told to resend it.
+func reconnectBackend(deps *loopDeps, ts *turnState, cause error) error {
+	checker, ok := deps.provider.(llm.HealthChecker)
+	if !ok {
+		return cause
+	}
+	name := deps.provider.Name()
+	fmt.Printf("\n  %s●%s %s stopped responding (%v); restarting it...\n", colorYellow, colorReset, name, cause)
+	deps.state.setAbortCurrentTurn(nil)
+	deps.state.endTurn()
+	deps.state.endPromptSpan(cause)
+	interrupted := deps.state.closeExchange()
+
+	if err := checker.Restart(deps.ctx); err != nil {
+		return fmt.Errorf("failed to restart %s: %w", name, err)
+	}
+	session, err := createSessionWithModel(deps.ctx, deps.provider, deps.k8sProvider, deps.state, ts.model)
+	if err != nil {
+		return fmt.Errorf("failed to recreate the session after restarting %s: %w", name, err)
+	}
+	setupSessionEventHandler(session, deps.isIdle, deps.state)
+	*deps.isIdle = true
+	ts.session = session
+
+	if replay := deps.state.replayPrompt(); replay != "" {
+		*deps.isIdle = false
+		if err := session.SendPrompt(deps.ctx, replay); err != nil {
+			*deps.isIdle = true
+			log.Printf("Warning: failed to restore the conversation: %v", err)
+		} else {
+			waitForIdle(deps.isIdle)
+		}
+	}
+	fmt.Printf("  %s●%s Reconnected to %s with a new session carrying over the recent conversation\n", colorGreen, colorReset, name)
+	if interrupted {
+		fmt.Printf("  %s●%s The prompt in flight was not answered; send it again\n", colorYellow, colorReset)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// echoSession is an llm.Session that records its prompts and goes idle
+// after each, as a session answering instantly would.
+type echoSession struct {
+	fakeSession
+	prompts []string
+}
+
+func (s *echoSession) SendPrompt(_ context.Context, prompt string) error {
+	s.prompts = append(s.prompts, prompt)
+	s.emit(llm.Event{Type: llm.EventIdle})
+	return nil
+}
+
+// restartingProvider is an llm.HealthChecker whose backend is dead until
+// it is restarted.
+type restartingProvider struct {
+	session  *echoSession
+	dead     atomic.Bool
+	restarts int
+}
+
+func (p *restartingProvider) Name() string                  { return "fake" }
+func (p *restartingProvider) Start(_ context.Context) error { return nil }
+func (p *restartingProvider) Stop() error                   { return nil }
+func (p *restartingProvider) CreateSession(_ context.Context, _ *llm.SessionConfig) (llm.Session, error) {
+	return p.session, nil
+}
+
+func (p *restartingProvider) Ping(_ context.Context) error {
+	if p.dead.Load() {
+		return errors.New("CLI process exited unexpectedly")
+	}
+	return nil
+}
+
+func (p *restartingProvider) Restart(_ context.Context) error {
+	p.restarts++
+	p.dead.Store(false)
+	return nil
+}
+
+func newReconnectDeps(t *testing.T, provider llm.Provider, isIdle *bool) *loopDeps {
+	t.Helper()
+	return &loopDeps{
+		ctx:         context.Background(),
+		provider:    provider,
+		k8sProvider: newTestK8sProvider(t),
+		state: &agentState{
+			mode:          ModeReadOnly,
+			outputFormat:  OutputJSON,
+			selectedAgent: AgentDefault,
+			mcpConfigPath: filepath.Join(t.TempDir(), "mcp.json"),
+		},
+		isIdle: isIdle,
+	}
+}
+
+func TestReplayPrompt(t *testing.T) {
+	state := &agentState{}
+	if got := state.replayPrompt(); got != "" {
+		t.Errorf("replayPrompt() without exchanges = %q", got)
+	}
+	state.setLastResponse("ignored: no prompt recorded")
+	for i := range maxReplayedExchanges + 2 {
+		state.recordPrompt("prompt " + string(rune('a'+i)))
+		state.setLastResponse("thinking")
+		state.setLastResponse("answer " + string(rune('a'+i)))
+		state.closeExchange()
+	}
+	state.setLastResponse("compact summary")
+	state.recordPrompt(strings.Repeat("x", maxReplayedChars+10))
+
+	got := state.replayPrompt()
+	for _, want := range []string{"(last 6 exchanges)", "User: prompt d\nAssistant: answer d\n", "User: prompt h\nAssistant: answer h\n", "x…\n[END CONTEXT]"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("replayPrompt() = %q, want it to contain %q", got, want)
+		}
+	}
+	for _, unwanted := range []string{"prompt c", "ignored", "thinking", "compact summary"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("replayPrompt() = %q, should not contain %q", got, unwanted)
+		}
+	}
+	if !state.closeExchange() {
+		t.Error("closeExchange() after an unanswered prompt = false")
+	}
+	state.clearExchanges()
+	if got := state.replayPrompt(); got != "" {
+		t.Errorf("replayPrompt() after clearExchanges = %q", got)
+	}
+}
+
+func TestCheckBackendReconnects(t *testing.T) {
+	provider := &restartingProvider{session: &echoSession{}}
+	isIdle := true
+	deps := newReconnectDeps(t, provider, &isIdle)
+	deps.state.recordPrompt("why is checkout crashing?")
+	deps.state.setLastResponse("the image tag does not exist")
+	deps.state.closeExchange()
+	old := &fakeSession{}
+	ts := &turnState{session: old, model: "test-model"}
+
+	if err := checkBackend(deps, ts); err != nil || provider.restarts != 0 || ts.session != old {
+		t.Fatalf("checkBackend() of a live backend = %v, %d restart(s)", err, provider.restarts)
+	}
+
+	provider.dead.Store(true)
+	if err := checkBackend(deps, ts); err != nil {
+		t.Fatalf("checkBackend() failed: %v", err)
+	}
+	if provider.restarts != 1 || ts.session != provider.session || !isIdle {
+		t.Errorf("after reconnecting: %d restart(s), session replaced %v, idle %v", provider.restarts, ts.session == provider.session, isIdle)
+	}
+	if len(provider.session.prompts) != 1 || !strings.Contains(provider.session.prompts[0], "Assistant: the image tag does not exist") {
+		t.Errorf("replayed prompts = %q", provider.session.prompts)
+	}
+}
+
+func TestSuperviseBackend(t *testing.T) {
+	orig := backendPingInterval
+	backendPingInterval = time.Millisecond
+	defer func() { backendPingInterval = orig }()
+
+	provider := &restartingProvider{session: &echoSession{}}
+	provider.dead.Store(true)
+	isIdle := false
+	deps := newReconnectDeps(t, provider, &isIdle)
+	stop := superviseBackend(deps)
+	defer stop()
+	var lost error
+	for deadline := time.Now().Add(5 * time.Second); lost == nil && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		lost = deps.state.takeBackendLost()
+	}
+	if lost == nil || !isIdle {
+		t.Fatalf("superviseBackend did not end the wait for a dead backend: %v, idle %v", lost, isIdle)
+	}
+	if err := deps.state.takeBackendLost(); err != nil {
+		t.Errorf("takeBackendLost() did not clear the failure: %v", err)
+	}
+
+	// Providers without a backend process are not supervised.
+	isIdle = false
+	superviseBackend(&loopDeps{provider: &fakeProvider{}, isIdle: &isIdle})()
+}
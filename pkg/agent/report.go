@@ -0,0 +1,99 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the generate_report tool (Markdown/HTML health reports).
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+	"github.com/e9169/kopilot/pkg/report"
+)
+
+// GenerateReportParams defines parameters for generate_report
+type GenerateReportParams struct {
+	Contexts []string `json:"contexts,omitempty" jsonschema:"Optional: context names of the clusters to include; leave empty for all clusters"`
+	Format   string   `json:"format,omitempty" jsonschema:"Report format: markdown (default) or html; inferred from the path extension when omitted"`
+	Path     string   `json:"path,omitempty" jsonschema:"Optional: local file to write the report to (.md or .html); without it the report is returned inline"`
+	Title    string   `json:"title,omitempty" jsonschema:"Optional report title (default: Kopilot Cluster Report)"`
+}
+
+// GenerateReportResult defines JSON output for generate_report
+type GenerateReportResult struct {
+	Format  string         `json:"format"`
+	Path    string         `json:"path,omitempty"`
+	Summary report.Summary `json:"summary"`
+	Issues  []report.Issue `json:"issues"`
+	Content string         `json:"content,omitempty"`
+}
+
+func defineGenerateReportTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolGenerateReport,
+		"Generate a polished Markdown or HTML health report of all or selected clusters: summary table, one section per cluster (nodes, unhealthy pods, costs) and an issue list ranked by severity (critical, warning, info). Returns the report inline, or writes it to a local .md/.html file for tickets and email.",
+		func(params GenerateReportParams, inv llm.ToolInvocation) (any, error) {
+			return handleGenerateReport(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+// reportFormat resolves the format from the explicit parameter or the path.
+func reportFormat(format, path string) (string, error) {
+	if format == "" && path != "" {
+		return report.FormatForPath(path)
+	}
+	return report.ParseFormat(format)
+}
+
+func handleGenerateReport(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params GenerateReportParams) (any, error) {
+	format, err := reportFormat(params.Format, params.Path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := GenerateReport(ctx, k8sProvider, params.Contexts, params.Title)
+	if err != nil {
+		return nil, err
+	}
+	data, err := report.Render(r, format)
+	if err != nil {
+		return nil, err
+	}
+
+	result := GenerateReportResult{Format: format, Path: params.Path, Summary: r.Summary, Issues: r.Issues}
+	if params.Path == "" {
+		result.Content = string(data)
+	} else if err := os.WriteFile(params.Path, data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write report: %w", err)
+	}
+
+	if isJSONOutput(state.outputFormat) {
+		return result, nil
+	}
+	if params.Path == "" {
+		return result.Content, nil
+	}
+	return fmt.Sprintf("📄 Wrote %s report of %d cluster(s) to %s: %d healthy, %d degraded, %d down; %d critical issue(s), %d warning(s).",
+		format, r.Summary.Clusters, params.Path, r.Summary.Healthy, r.Summary.Degraded, r.Summary.Down, r.Summary.Critical, r.Summary.Warnings), nil
+}
+
+// GenerateReport collects the status of the given contexts (all clusters when
+// empty) and builds a health report; it backs generate_report and the
+// "kopilot report" command.
+func GenerateReport(ctx context.Context, k8sProvider *k8s.Provider, contexts []string, title string) (*report.Report, error) {
+	var statuses []*k8s.ClusterStatus
+	if len(contexts) == 0 {
+		statuses = k8sProvider.GetAllClusterStatuses(ctx)
+	} else {
+		for _, contextName := range contexts {
+			status, err := k8sProvider.GetClusterStatus(ctx, contextName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get status of %s: %w", contextName, err)
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return report.New(title, statuses, time.Now().UTC()), nil
+}
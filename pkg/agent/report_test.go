@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/report"
+)
+
+func TestReportFormat(t *testing.T) {
+	tests := []struct {
+		format, path, want string
+		wantErr            bool
+	}{
+		{"", "", report.FormatMarkdown, false},
+		{"", "status.html", report.FormatHTML, false},
+		{"markdown", "status.html", report.FormatMarkdown, false},
+		{"", "status.json", "", true},
+		{"pdf", "", "", true},
+	}
+	for _, tt := range tests {
+		got, err := reportFormat(tt.format, tt.path)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("reportFormat(%q, %q) = %q, %v; want %q (error %v)", tt.format, tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestHandleGenerateReportErrors(t *testing.T) {
+	provider := newTestK8sProvider(t)
+	state := &agentState{mode: ModeReadOnly, outputFormat: OutputText}
+	if _, err := handleGenerateReport(context.Background(), provider, state, GenerateReportParams{Format: "pdf"}); err == nil {
+		t.Error("unsupported format should fail")
+	}
+	if _, err := handleGenerateReport(context.Background(), provider, state, GenerateReportParams{Contexts: []string{"no-such-context"}}); err == nil {
+		t.Error("unknown context should fail")
+	}
+}
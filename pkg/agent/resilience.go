@@ -0,0 +1,117 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the resilience_report tool (failure domains, replicas, PDB coverage).
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// ResilienceReportParams defines parameters for resilience_report
+type ResilienceReportParams struct {
+	Context       string `json:"context" jsonschema:"The context name of the cluster to inspect (from list_clusters)"`
+	Namespace     string `json:"namespace,omitempty" jsonschema:"Optional: restrict the report to a specific namespace; leave empty for all non-system namespaces"`
+	IncludeSystem bool   `json:"include_system,omitempty" jsonschema:"If true, include system namespaces (kube-system, kube-public, kube-node-lease)"`
+}
+
+func defineResilienceReportTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolResilienceReport,
+		"Grade each Deployment and StatefulSet (A-F) on how well it survives node and zone loss: replica count, spread of running pods across nodes and zones, pod anti-affinity or topology spread constraints, and PodDisruptionBudget coverage. Use before DR exercises, node pool upgrades, or when asked about single points of failure.",
+		func(params ResilienceReportParams, inv llm.ToolInvocation) (any, error) {
+			ctx := inv.Ctx()
+			report, err := k8sProvider.GetResilienceReport(ctx, params.Context, params.Namespace, params.IncludeSystem)
+			if err != nil {
+				return nil, fmt.Errorf("failed to build resilience report: %w", err)
+			}
+
+			if isJSONOutput(state.outputFormat) {
+				return report, nil
+			}
+			return formatResilienceReport(report), nil
+		},
+	)
+}
+
+// formatZoneCounts renders a zone -> count map as "a:2 b:1", sorted by zone.
+func formatZoneCounts(zones map[string]int) string {
+	names := make([]string, 0, len(zones))
+	for z := range zones {
+		names = append(names, z)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, z := range names {
+		label := z
+		if label == "" {
+			label = "(no zone)"
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d", label, zones[z]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatResilienceReport formats a ResilienceReport as human-readable text
+func formatResilienceReport(report *k8s.ResilienceReport) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Resilience Report: %s\n", report.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	switch {
+	case len(report.Zones) == 0:
+		sb.WriteString("🌍 FAILURE DOMAINS: nodes carry no topology.kubernetes.io/zone label\n")
+	default:
+		fmt.Fprintf(&sb, "🌍 FAILURE DOMAINS: %d zone(s) with ready nodes (%s)", len(report.Zones), formatZoneCounts(report.Zones))
+		if len(report.Regions) > 0 {
+			fmt.Fprintf(&sb, " in %s", strings.Join(report.Regions, ", "))
+		}
+		sb.WriteString("\n")
+		if len(report.Zones) == 1 {
+			sb.WriteString("   ⚠️  Single-zone cluster: a zone outage takes down every workload regardless of its grade.\n")
+		}
+	}
+
+	if len(report.Workloads) == 0 {
+		sb.WriteString("\nNo Deployments or StatefulSets in scope.\n")
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "📊 GRADES: A:%d B:%d C:%d D:%d F:%d across %d workload(s)\n\n",
+		report.Grades["A"], report.Grades["B"], report.Grades["C"], report.Grades["D"], report.Grades["F"], len(report.Workloads))
+
+	for _, w := range report.Workloads {
+		fmt.Fprintf(&sb, "%s %s/%s/%s  grade %s  score %d/100\n",
+			sanitizeGradeIcon(w.Grade), w.Namespace, w.Kind, w.Name, w.Grade, w.Score)
+		spread := "none"
+		switch {
+		case w.AntiAffinity && w.TopologySpread:
+			spread = "anti-affinity + topology spread"
+		case w.AntiAffinity:
+			spread = "anti-affinity"
+		case w.TopologySpread:
+			spread = "topology spread"
+		}
+		pdb := w.PDB
+		if pdb == "" {
+			pdb = "none"
+		}
+		fmt.Fprintf(&sb, "     Replicas: %d/%d ready on %d node(s)  |  Zones: %s  |  Spread: %s  |  PDB: %s\n",
+			w.ReadyReplicas, w.Replicas, w.Nodes, orNone(formatZoneCounts(w.Zones)), spread, pdb)
+		for _, f := range w.Findings {
+			fmt.Fprintf(&sb, "     • [%s] %s: %s\n", strings.ToUpper(string(f.Severity)), f.RuleID, f.Message)
+		}
+	}
+	return sb.String()
+}
+
+// orNone returns s, or "none" when s is empty.
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
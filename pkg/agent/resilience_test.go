@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestFormatZoneCounts(t *testing.T) {
+	if got := formatZoneCounts(map[string]int{"b": 1, "a": 2, "": 1}); got != "(no zone):1 a:2 b:1" {
+		t.Errorf("formatZoneCounts() = %q", got)
+	}
+	if got := formatZoneCounts(nil); got != "" {
+		t.Errorf("formatZoneCounts(nil) = %q, want empty", got)
+	}
+}
+
+func TestFormatResilienceReport(t *testing.T) {
+	report := &k8s.ResilienceReport{
+		Context: "prod",
+		Regions: []string{"eu-west-1"},
+		Zones:   map[string]int{"eu-west-1a": 2},
+		Grades:  map[string]int{"D": 1},
+		Workloads: []k8s.WorkloadResilience{{
+			Namespace: "shop", Kind: "Deployment", Name: "cart", Replicas: 1, ReadyReplicas: 1, Nodes: 1,
+			Zones: map[string]int{"eu-west-1a": 1}, Score: 50, Grade: "D",
+			Findings: []k8s.ResilienceFinding{{RuleID: "RES-001", Severity: k8s.SanitizeCritical, Message: "1 replica(s)"}},
+		}},
+	}
+	text := formatResilienceReport(report)
+	for _, want := range []string{
+		"Resilience Report: prod",
+		"1 zone(s) with ready nodes (eu-west-1a:2) in eu-west-1",
+		"Single-zone cluster",
+		"A:0 B:0 C:0 D:1 F:0 across 1 workload(s)",
+		"🔴 shop/Deployment/cart  grade D  score 50/100",
+		"Spread: none  |  PDB: none",
+		"[CRITICAL] RES-001: 1 replica(s)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report missing %q:\n%s", want, text)
+		}
+	}
+
+	empty := formatResilienceReport(&k8s.ResilienceReport{Context: "dev"})
+	if !strings.Contains(empty, "no topology.kubernetes.io/zone label") || !strings.Contains(empty, "No Deployments or StatefulSets") {
+		t.Errorf("empty report:\n%s", empty)
+	}
+}
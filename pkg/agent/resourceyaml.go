@@ -0,0 +1,365 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the get_resource_yaml and apply_resource_yaml edit loop.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+	"sigs.k8s.io/yaml"
+)
+
+// lastAppliedAnnotation is kubectl's client-side apply bookkeeping; it is
+// noise when editing and is regenerated on apply.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// yamlDocumentSeparator matches a "---" line between YAML documents.
+var yamlDocumentSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// GetResourceYAMLParams defines parameters for get_resource_yaml
+type GetResourceYAMLParams struct {
+	Context   string `json:"context" jsonschema:"The cluster context name (required)"`
+	Kind      string `json:"kind" jsonschema:"Resource kind, e.g. deployment, configmap, ingress"`
+	Name      string `json:"name" jsonschema:"Resource name"`
+	Namespace string `json:"namespace,omitempty" jsonschema:"Namespace; omit for cluster-scoped resources or the context default"`
+}
+
+// ApplyResourceYAMLParams defines parameters for apply_resource_yaml
+type ApplyResourceYAMLParams struct {
+	Context  string `json:"context" jsonschema:"The cluster context name (required)"`
+	Manifest string `json:"manifest" jsonschema:"The complete edited YAML manifest of a single object, usually based on get_resource_yaml output"`
+}
+
+// ResourceYAMLResult is the manifest returned by get_resource_yaml.
+type ResourceYAMLResult struct {
+	Context   string `json:"context"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	YAML      string `json:"yaml"`
+}
+
+// ApplyResourceYAMLResult is the outcome of apply_resource_yaml.
+type ApplyResourceYAMLResult struct {
+	Cluster string `json:"cluster"`
+	Context string `json:"context"`
+	Object  string `json:"object"`
+	Created bool   `json:"created"`
+	Diff    string `json:"diff"`
+	Applied bool   `json:"applied"`
+	Output  string `json:"output,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func defineGetResourceYAMLTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolGetResourceYAML,
+		"Fetch the YAML manifest of a single resource, with server-managed fields (status, managedFields, uid, resourceVersion) removed so it is ready to edit. Use this before apply_resource_yaml to propose changes.",
+		func(params GetResourceYAMLParams, inv llm.ToolInvocation) (any, error) {
+			return handleGetResourceYAML(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+func defineApplyResourceYAMLTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolApplyResourceYAML,
+		"Apply an edited YAML manifest for a single object. Shows the user a diff against the live object and applies it only after confirmation; the change can be reverted with undo_last_operation. Send the full manifest, not a patch.",
+		func(params ApplyResourceYAMLParams, inv llm.ToolInvocation) (any, error) {
+			return handleApplyResourceYAML(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+func handleGetResourceYAML(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params GetResourceYAMLParams) (any, error) {
+	if params.Context == "" || params.Kind == "" || params.Name == "" {
+		return nil, fmt.Errorf("context, kind, and name are required")
+	}
+	if params.Namespace != "" && !isValidKubernetesName(params.Namespace) {
+		return nil, fmt.Errorf("invalid namespace name: %s", params.Namespace)
+	}
+	if _, err := getClusterForContext(k8sProvider, params.Context); err != nil {
+		return nil, err
+	}
+
+	obj, err := fetchLiveObject(ctx, params.Context, params.Kind, params.Name, params.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, fmt.Errorf("%s/%s not found", params.Kind, params.Name)
+	}
+	out, err := manifestYAML(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	result := ResourceYAMLResult{Context: params.Context, Kind: params.Kind, Name: params.Name, Namespace: params.Namespace, YAML: out}
+	if isJSONOutput(state.outputFormat) {
+		return result, nil
+	}
+	return fmt.Sprintf("# %s/%s (%s)\n%s", params.Kind, params.Name, params.Context, out), nil
+}
+
+func handleApplyResourceYAML(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params ApplyResourceYAMLParams) (any, error) {
+	if params.Context == "" {
+		return nil, fmt.Errorf("context is required")
+	}
+	proposed, err := parseSingleManifest(params.Manifest)
+	if err != nil {
+		return nil, err
+	}
+	kind, _ := nestedString(proposed, "kind")
+	name, _ := nestedString(proposed, "metadata", "name")
+	namespace, _ := nestedString(proposed, "metadata", "namespace")
+	if namespace != "" && !isValidKubernetesName(namespace) {
+		return nil, fmt.Errorf("invalid namespace name: %s", namespace)
+	}
+
+	cluster, err := getClusterForContext(k8sProvider, params.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	live, err := fetchLiveObject(ctx, params.Context, kind, name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	liveYAML := ""
+	if live != nil {
+		if liveYAML, err = manifestYAML(live); err != nil {
+			return nil, err
+		}
+	}
+	proposedYAML, err := manifestYAML(proposed)
+	if err != nil {
+		return nil, err
+	}
+
+	object := strings.ToLower(kind) + "/" + name
+	if namespace != "" {
+		object = namespace + "/" + object
+	}
+	result := ApplyResourceYAMLResult{
+		Cluster: cluster.Name,
+		Context: params.Context,
+		Object:  object,
+		Created: live == nil,
+		Diff:    unifiedDiff("live/"+object, "proposed/"+object, liveYAML, proposedYAML),
+	}
+	if result.Diff == "" {
+		result.Output = "no changes: the manifest matches the live object"
+		return applyYAMLResult(state, result), nil
+	}
+
+	printManifestDiff(state, result)
+
+	manifestPath, cleanup, err := stageManifest(proposed)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	fullCommand, cmdArgs := buildKubectlCommand(params.Context, []string{"apply", "-f", manifestPath})
+	prompt := fmt.Sprintf("%s  # %s", fullCommand, object)
+	proceed, cancelResult, err := enforceToolExecutionMode(ctx, state, toolApplyResourceYAML, false, cluster.Name, params.Context, prompt)
+	if err != nil {
+		return nil, err
+	}
+	if !proceed {
+		return cancelResult, nil
+	}
+
+	printExecutionHeader(state, false, prompt)
+	output, execErr := runKubectlCommandFunc(ctx, cmdArgs)
+	state.publishWrite(ApprovalRequest{Tool: toolApplyResourceYAML, Cluster: cluster.Name, Context: params.Context, Command: prompt}, execErr)
+	result.Output = string(output)
+	if execErr != nil {
+		result.Error = execErr.Error()
+		return applyYAMLResult(state, result), nil
+	}
+	result.Applied = true
+	if record := applyUndoRecord(params.Context, prompt, kind, name, namespace, live); record != nil {
+		state.undo.push(*record)
+	}
+	return applyYAMLResult(state, result), nil
+}
+
+// parseSingleManifest decodes YAML or JSON holding exactly one object with kind and name.
+func parseSingleManifest(manifest string) (map[string]any, error) {
+	if strings.TrimSpace(manifest) == "" {
+		return nil, fmt.Errorf("manifest is required")
+	}
+	docs := 0
+	for _, doc := range yamlDocumentSeparator.Split(manifest, -1) {
+		if strings.TrimSpace(doc) != "" {
+			docs++
+		}
+	}
+	if docs > 1 {
+		return nil, fmt.Errorf("manifest contains %d documents; apply one object at a time", docs)
+	}
+
+	data, err := yaml.YAMLToJSON([]byte(manifest))
+	if err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(data, &obj); err != nil || obj == nil {
+		return nil, fmt.Errorf("manifest must be a single object")
+	}
+	if kind, _ := nestedString(obj, "kind"); kind == "" || kind == "List" {
+		return nil, fmt.Errorf("manifest must have a kind and describe a single object")
+	}
+	if _, ok := nestedString(obj, "apiVersion"); !ok {
+		return nil, fmt.Errorf("manifest must have an apiVersion")
+	}
+	if name, _ := nestedString(obj, "metadata", "name"); name == "" {
+		return nil, fmt.Errorf("manifest must have metadata.name")
+	}
+	return obj, nil
+}
+
+// fetchLiveObject returns the object as JSON, or nil if it does not exist.
+func fetchLiveObject(ctx context.Context, contextName, kind, name, namespace string) (map[string]any, error) {
+	args := []string{"--context", contextName, "get", kind, name, "-o", "json", "--ignore-not-found"}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	out, err := runKubectlCommandFunc(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s/%s: %w: %s", kind, name, err, strings.TrimSpace(string(out)))
+	}
+	if strings.TrimSpace(string(out)) == "" {
+		return nil, nil
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(out, &obj); err != nil {
+		return nil, fmt.Errorf("failed to parse %s/%s: %w", kind, name, err)
+	}
+	return obj, nil
+}
+
+// manifestYAML renders obj as YAML without server-managed fields.
+func manifestYAML(obj map[string]any) (string, error) {
+	clean := stripServerFields(deepCopyJSON(obj))
+	if meta, ok := clean["metadata"].(map[string]any); ok {
+		if annotations, ok := meta["annotations"].(map[string]any); ok {
+			delete(annotations, lastAppliedAnnotation)
+			if len(annotations) == 0 {
+				delete(meta, "annotations")
+			}
+		}
+	}
+	out, err := yaml.Marshal(clean)
+	if err != nil {
+		return "", fmt.Errorf("failed to render YAML: %w", err)
+	}
+	return string(out), nil
+}
+
+// deepCopyJSON copies a decoded JSON object so it can be modified safely.
+func deepCopyJSON(obj map[string]any) map[string]any {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return obj
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return obj
+	}
+	return out
+}
+
+// stageManifest writes obj as JSON to a temporary file for kubectl apply -f.
+func stageManifest(obj map[string]any) (string, func(), error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	f, err := os.CreateTemp("", "kopilot-apply-*.json")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to stage manifest: %w", err)
+	}
+	path := f.Name()
+	cleanup := func() { _ = os.Remove(path) }
+	_, writeErr := f.Write(data)
+	if err := errors.Join(writeErr, f.Close()); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to stage manifest: %w", err)
+	}
+	return path, cleanup, nil
+}
+
+// applyUndoRecord builds the record that reverses an apply: restore the
+// previous manifest, or delete the object if the apply created it.
+func applyUndoRecord(contextName, command, kind, name, namespace string, live map[string]any) *undoRecord {
+	record := &undoRecord{
+		Time:      time.Now(),
+		Context:   contextName,
+		Command:   command,
+		Kind:      strings.ToLower(kind),
+		Name:      name,
+		Namespace: namespace,
+		Action:    undoDelete,
+	}
+	if live == nil {
+		return record
+	}
+	manifest, err := json.Marshal(stripServerFields(live))
+	if err != nil {
+		return nil
+	}
+	record.Action = undoReplace
+	record.Manifest = manifest
+	return record
+}
+
+// printManifestDiff shows the diff before the confirmation prompt.
+func printManifestDiff(state *agentState, r ApplyResourceYAMLResult) {
+	if isJSONOutput(state.outputFormat) {
+		return
+	}
+	resumeSpinner := pauseSpinner()
+	defer resumeSpinner()
+	out := state.stdout()
+	verb := "Changes to"
+	if r.Created {
+		verb = "Creating"
+	}
+	fmt.Fprintf(out, "\r\033[K\n%s📝 %s %s%s (%s)\n\n", colorCyan, verb, r.Object, colorReset, r.Context)
+	fmt.Fprint(out, colorizeDiff(r.Diff))
+	fmt.Fprintln(out)
+}
+
+func applyYAMLResult(state *agentState, r ApplyResourceYAMLResult) any {
+	if isJSONOutput(state.outputFormat) {
+		return r
+	}
+	var sb strings.Builder
+	switch {
+	case r.Diff == "":
+		fmt.Fprintf(&sb, "✅ %s: %s\n", r.Object, r.Output)
+		return sb.String()
+	case r.Error != "":
+		fmt.Fprintf(&sb, "❌ Apply of %s failed: %s\n", r.Object, r.Error)
+	default:
+		fmt.Fprintf(&sb, "✅ Applied %s on %s — revert with undo_last_operation\n", r.Object, r.Context)
+	}
+	if r.Output != "" {
+		sb.WriteString(r.Output)
+		if !strings.HasSuffix(r.Output, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	sb.WriteString("\nDiff:\n" + r.Diff)
+	return sb.String()
+}
@@ -0,0 +1,144 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+const liveConfigMap = `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"app","namespace":"shop",
+  "uid":"abc","resourceVersion":"42","managedFields":[{"manager":"kubectl"}],
+  "annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{}"}},"data":{"LOG_LEVEL":"info"}}`
+
+func TestParseSingleManifest(t *testing.T) {
+	tests := []struct {
+		manifest string
+		wantErr  string
+	}{
+		{"", "manifest is required"},
+		{"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\n", "2 documents"},
+		{"apiVersion: v1\nmetadata:\n  name: a\n", "kind"},
+		{"kind: ConfigMap\nmetadata:\n  name: a\n", "apiVersion"},
+		{"apiVersion: v1\nkind: ConfigMap\n", "metadata.name"},
+		{"- a\n- b\n", "single object"},
+	}
+	for _, tt := range tests {
+		if _, err := parseSingleManifest(tt.manifest); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("parseSingleManifest(%q) error = %v, want containing %q", tt.manifest, err, tt.wantErr)
+		}
+	}
+
+	obj, err := parseSingleManifest("---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n")
+	if err != nil {
+		t.Fatalf("leading separator should be accepted: %v", err)
+	}
+	if name, _ := nestedString(obj, "metadata", "name"); name != "a" {
+		t.Errorf("name = %q", name)
+	}
+}
+
+func TestGetResourceYAMLStripsServerFields(t *testing.T) {
+	provider := newTestK8sProvider(t)
+	original := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = original })
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) { return []byte(liveConfigMap), nil }
+
+	out, err := handleGetResourceYAML(context.Background(), provider, &agentState{outputFormat: OutputJSON}, GetResourceYAMLParams{
+		Context: "test-context", Kind: "configmap", Name: "app", Namespace: "shop",
+	})
+	if err != nil {
+		t.Fatalf("handleGetResourceYAML error: %v", err)
+	}
+	y := out.(ResourceYAMLResult).YAML
+	for _, unwanted := range []string{"uid", "resourceVersion", "managedFields", "last-applied"} {
+		if strings.Contains(y, unwanted) {
+			t.Errorf("YAML should not contain %q:\n%s", unwanted, y)
+		}
+	}
+	if !strings.Contains(y, "LOG_LEVEL: info") {
+		t.Errorf("YAML missing data:\n%s", y)
+	}
+}
+
+func TestApplyResourceYAMLShowsDiffAndRecordsUndo(t *testing.T) {
+	provider := newTestK8sProvider(t)
+	original := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = original })
+
+	var applied map[string]any
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
+		switch args[2] {
+		case "get":
+			return []byte(liveConfigMap), nil
+		case "apply":
+			data, err := os.ReadFile(args[4])
+			if err != nil {
+				t.Fatalf("manifest not staged: %v", err)
+			}
+			if err := json.Unmarshal(data, &applied); err != nil {
+				t.Fatalf("staged manifest is not JSON: %v", err)
+			}
+			return []byte("configmap/app configured\n"), nil
+		}
+		t.Fatalf("unexpected kubectl call: %v", args)
+		return nil, nil
+	}
+
+	approver := &stubApprover{name: ApprovalLocal, decision: ApprovalDecision{Approved: true}}
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON, approver: approver}
+	manifest := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n  namespace: shop\ndata:\n  LOG_LEVEL: debug\n"
+
+	out, err := handleApplyResourceYAML(context.Background(), provider, state, ApplyResourceYAMLParams{Context: "test-context", Manifest: manifest})
+	if err != nil {
+		t.Fatalf("handleApplyResourceYAML error: %v", err)
+	}
+	result := out.(ApplyResourceYAMLResult)
+	if !result.Applied || result.Created || !approver.called {
+		t.Errorf("unexpected result: %+v (approver called: %v)", result, approver.called)
+	}
+	if !strings.Contains(result.Diff, "-  LOG_LEVEL: info\n+  LOG_LEVEL: debug") {
+		t.Errorf("diff should show the data change only:\n%s", result.Diff)
+	}
+	if strings.Contains(result.Diff, "uid") {
+		t.Errorf("diff should ignore server fields:\n%s", result.Diff)
+	}
+	if v, _ := nestedString(applied, "data", "LOG_LEVEL"); v != "debug" {
+		t.Errorf("applied manifest = %v", applied)
+	}
+
+	record, ok := state.undo.last()
+	if !ok || record.Action != undoReplace || record.Namespace != "shop" || !strings.Contains(string(record.Manifest), `"LOG_LEVEL":"info"`) {
+		t.Errorf("undo record = %+v (%s)", record, record.Manifest)
+	}
+}
+
+func TestApplyResourceYAMLNoChanges(t *testing.T) {
+	provider := newTestK8sProvider(t)
+	original := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = original })
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
+		if args[2] != "get" {
+			t.Fatalf("nothing should be applied, got %v", args)
+		}
+		return []byte(liveConfigMap), nil
+	}
+
+	state := &agentState{mode: ModeInteractive}
+	manifest := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n  namespace: shop\ndata:\n  LOG_LEVEL: info\n"
+	out, err := handleApplyResourceYAML(context.Background(), provider, state, ApplyResourceYAMLParams{Context: "test-context", Manifest: manifest})
+	if err != nil {
+		t.Fatalf("handleApplyResourceYAML error: %v", err)
+	}
+	if text, _ := out.(string); !strings.Contains(text, "no changes") {
+		t.Errorf("expected no-changes result, got %v", out)
+	}
+}
+
+func TestApplyUndoRecordForCreate(t *testing.T) {
+	record := applyUndoRecord("ctx", "kubectl apply", "ConfigMap", "new", "shop", nil)
+	if record.Action != undoDelete || record.target() != "configmap/new" {
+		t.Errorf("create should be undone by delete, got %+v", record)
+	}
+}
@@ -0,0 +1,125 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the tool result cache: when the model calls
+// list_clusters or get_cluster_status again with the same arguments within
+// the cluster status cache TTL, the earlier result is returned without
+// querying the clusters or formatting it again.
+package agent
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/debug"
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// cachedTools are the tools whose results are cached.
+var cachedTools = map[string]bool{
+	toolListClusters:     true,
+	toolGetClusterStatus: true,
+}
+
+// cachedResult is a tool result and when it expires.
+type cachedResult struct {
+	result     any
+	expiresAt  time.Time
+	generation uint64
+}
+
+// resultCache holds the results of cachedTools for one session. Entries
+// live for the status cache TTL of their context and are dropped when the
+// provider's cache generation changes, e.g. after a reload or /reauth, or
+// when a tool that changes the clusters runs. It is safe for concurrent use.
+type resultCache struct {
+	k8sProvider *k8s.Provider
+	mu          sync.Mutex
+	entries     map[string]cachedResult
+}
+
+func newResultCache(k8sProvider *k8s.Provider) *resultCache {
+	return &resultCache{k8sProvider: k8sProvider, entries: make(map[string]cachedResult)}
+}
+
+// resultCacheKey identifies a call: the tool, the output format and the
+// arguments, whose map keys encoding/json sorts.
+func resultCacheKey(tool string, format OutputFormat, args map[string]any) string {
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return ""
+	}
+	return tool + "\x00" + string(format) + "\x00" + string(raw)
+}
+
+// get returns the unexpired result cached under key.
+func (c *resultCache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expiresAt) || e.generation != c.k8sProvider.CacheGeneration() {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.result, true
+}
+
+// put caches result under key for the status cache TTL of contextName, or
+// the default TTL without a context. A TTL of 0 disables caching.
+func (c *resultCache) put(key, contextName string, result any, generation uint64) {
+	ttl := c.k8sProvider.CacheTTL()
+	if contextName != "" {
+		ttl = c.k8sProvider.ContextCacheTTL(contextName)
+	}
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cachedResult{result: result, expiresAt: time.Now().Add(ttl), generation: generation}
+}
+
+// clear drops every cached result.
+func (c *resultCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// withResultCache serves repeated calls of cachedTools from cache, and
+// clears the cache after any call that may change the clusters.
+func withResultCache(tools []llm.Tool, cache *resultCache, state *agentState) []llm.Tool {
+	for i := range tools {
+		name, handler := tools[i].Name, tools[i].Handler
+		if !cachedTools[name] {
+			tools[i].Handler = func(params any, inv llm.ToolInvocation) (any, error) {
+				if !isRepeatableCall(name, params) {
+					defer cache.clear()
+				}
+				return handler(params, inv)
+			}
+			continue
+		}
+		tools[i].Handler = func(params any, inv llm.ToolInvocation) (any, error) {
+			args, _ := llm.NormalizeToolArguments(params)
+			key := resultCacheKey(name, state.outputFormat, args)
+			if key == "" {
+				return handler(params, inv)
+			}
+			if result, ok := cache.get(key); ok {
+				debug.Logf(debug.Cache, "%s: served from the result cache", name)
+				return result, nil
+			}
+			generation := cache.k8sProvider.CacheGeneration()
+			result, err := handler(params, inv)
+			if err == nil {
+				cache.put(key, contextArg(args), result, generation)
+			}
+			return result, err
+		}
+	}
+	return tools
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestWithResultCache(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["prod"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	for _, name := range []string{"prod", "staging"} {
+		config.Contexts[name] = &clientcmdapi.Context{Cluster: "prod"}
+	}
+	config.CurrentContext = "prod"
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := k8s.NewProvider(path)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+
+	calls := map[string]int{}
+	handler := func(name string) func(any, llm.ToolInvocation) (any, error) {
+		return func(any, llm.ToolInvocation) (any, error) {
+			calls[name]++
+			return name, nil
+		}
+	}
+	tools := withResultCache([]llm.Tool{
+		{Name: toolGetClusterStatus, Handler: handler(toolGetClusterStatus)},
+		{Name: toolRestartWorkload, Handler: handler(toolRestartWorkload)},
+	}, newResultCache(provider), &agentState{outputFormat: OutputText})
+	status, restart := tools[0].Handler, tools[1].Handler
+	prod := map[string]any{"context": "prod"}
+
+	for range 3 {
+		if _, err := status(prod, llm.ToolInvocation{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := status(map[string]any{"context": "staging"}, llm.ToolInvocation{}); err != nil {
+		t.Fatal(err)
+	}
+	if calls[toolGetClusterStatus] != 2 {
+		t.Fatalf("get_cluster_status ran %d times, want 2 (prod once, staging once)", calls[toolGetClusterStatus])
+	}
+
+	_, _ = restart(map[string]any{"context": "prod"}, llm.ToolInvocation{})
+	_, _ = status(prod, llm.ToolInvocation{})
+	if calls[toolGetClusterStatus] != 3 {
+		t.Errorf("a write tool did not clear the cache: get_cluster_status ran %d times, want 3", calls[toolGetClusterStatus])
+	}
+
+	provider.ClearCache()
+	_, _ = status(prod, llm.ToolInvocation{})
+	if calls[toolGetClusterStatus] != 4 {
+		t.Errorf("ClearCache did not invalidate the cache: get_cluster_status ran %d times, want 4", calls[toolGetClusterStatus])
+	}
+
+	provider.SetCacheTTL(0)
+	_, _ = status(map[string]any{"context": "staging", "namespace": "shop"}, llm.ToolInvocation{})
+	_, _ = status(map[string]any{"context": "staging", "namespace": "shop"}, llm.ToolInvocation{})
+	if calls[toolGetClusterStatus] != 6 {
+		t.Errorf("a TTL of 0 still cached: get_cluster_status ran %d times, want 6", calls[toolGetClusterStatus])
+	}
+}
@@ -0,0 +1,213 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the retry policy for tool calls failing transiently.
+package agent
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/debug"
+	"github.com/e9169/kopilot/pkg/llm"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// retryChoice is the user's answer after a tool failed transiently twice.
+type retryChoice int
+
+const (
+	retrySkip retryChoice = iota
+	retryNow
+	retryWait
+)
+
+var (
+	// autoRetryDelay is the pause before the automatic retry.
+	autoRetryDelay = 2 * time.Second
+	// retryWaitDelay is how long "wait" pauses before retrying.
+	retryWaitDelay = 30 * time.Second
+	// retryPromptMu keeps prompts of parallel tool calls from interleaving.
+	retryPromptMu sync.Mutex
+)
+
+// transientMessages are fragments of error messages (including kubectl
+// output) that indicate a failure likely to go away on its own.
+var transientMessages = []string{
+	"connection refused",
+	"connection reset",
+	"i/o timeout",
+	"tls handshake timeout",
+	"timed out",
+	"did not respond within",
+	"too many requests",
+	"service unavailable",
+	"the server is currently unable to handle the request",
+	"etcdserver: request timed out",
+	"etcdserver: leader changed",
+	"http2: client connection lost",
+	"unexpected eof",
+	"bad gateway",
+	"gateway timeout",
+}
+
+// isTransientFailure reports whether a failed tool call is worth repeating:
+// timeouts, throttling, unavailable API servers and dropped connections.
+// For tools returning output alongside the error (kubectl_exec), the output
+// is checked too since it carries kubectl's stderr.
+func isTransientFailure(result any, err error) bool {
+	switch {
+	case err == nil, errors.Is(err, context.Canceled):
+		return false
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, errToolBudgetExceeded),
+		errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	case apierrors.IsServerTimeout(err), apierrors.IsTimeout(err), apierrors.IsTooManyRequests(err),
+		apierrors.IsServiceUnavailable(err), apierrors.IsUnexpectedServerError(err):
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	text := strings.ToLower(err.Error())
+	if output, ok := result.(string); ok {
+		text += "\n" + strings.ToLower(output)
+	}
+	for _, m := range transientMessages {
+		if strings.Contains(text, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRepeatableCall reports whether the call can run again without side
+// effects: read-only tools (those with a time budget) and read-only
+// kubectl_exec commands.
+func isRepeatableCall(tool string, params any) bool {
+	if _, ok := toolBudgets[tool]; ok {
+		return true
+	}
+	if tool != toolKubectlExec {
+		return false
+	}
+	args, _ := llm.NormalizeToolArguments(params)
+	list, ok := args["args"].([]any)
+	if !ok || len(list) == 0 {
+		return false
+	}
+	kubectlArgs := make([]string, 0, len(list))
+	for _, a := range list {
+		s, ok := a.(string)
+		if !ok {
+			return false
+		}
+		kubectlArgs = append(kubectlArgs, s)
+	}
+	return isReadOnlyCommand(sanitizeKubectlArgs(kubectlArgs))
+}
+
+// withRetries applies the transient failure policy to repeatable tools: one
+// automatic retry, then the user chooses to retry, wait or skip. Without a
+// prompt (JSON output, or a run the model did not ask for yet) the error is
+// returned after the automatic retry.
+func withRetries(tools []llm.Tool, state *agentState) []llm.Tool {
+	for i := range tools {
+		name, handler := tools[i].Name, tools[i].Handler
+		tools[i].Handler = func(params any, inv llm.ToolInvocation) (any, error) {
+			result, err := handler(params, inv)
+			if !isTransientFailure(result, err) || !isRepeatableCall(name, params) {
+				return result, err
+			}
+			ctx := inv.Ctx()
+			prompt := retryPromptAllowed(ctx)
+			call := describeRetryCall(name, params)
+			debug.Logf(debug.Tools, "%s failed transiently, retrying: %v", call, err)
+			if prompt && !isJSONOutput(state.outputFormat) {
+				fmt.Fprintf(state.stdout(), "  %s↻ %s failed transiently, retrying once...%s\n", colorDim, call, colorReset)
+			}
+			if !sleepCtx(ctx, autoRetryDelay) {
+				return result, err
+			}
+			result, err = handler(params, inv)
+
+			for isTransientFailure(result, err) {
+				if state.askRetry == nil || !prompt {
+					return result, fmt.Errorf("%w (transient failure, still failing after a retry; suggest trying again shortly)", err)
+				}
+				switch state.askRetry(call, err) {
+				case retryNow:
+				case retryWait:
+					if !sleepCtx(ctx, retryWaitDelay) {
+						return result, err
+					}
+				default:
+					return result, &llm.ToolError{Code: llm.ToolErrorUnavailable, Message: fmt.Sprintf("%v (transient failure; the user chose to skip this call)", err),
+						Hint: "the user chose to skip this call; do not retry it", Err: err}
+				}
+				if ctx.Err() != nil {
+					return result, err
+				}
+				result, err = handler(params, inv)
+			}
+			return result, err
+		}
+	}
+	return tools
+}
+
+// describeRetryCall names a call in retry messages: the tool, and the
+// context it ran for when it has one.
+func describeRetryCall(tool string, params any) string {
+	args, _ := llm.NormalizeToolArguments(params)
+	if contextName := contextArg(args); contextName != "" {
+		return fmt.Sprintf("%s on %s", tool, contextName)
+	}
+	return tool
+}
+
+// sleepCtx waits for d; it returns false when ctx ends first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// newTerminalRetryPrompt asks on the terminal whether to retry a tool call
+// that keeps failing transiently. Anything but r or w skips.
+func newTerminalRetryPrompt(in io.Reader, out io.Writer) func(tool string, err error) retryChoice {
+	reader := bufio.NewReader(in)
+	return func(tool string, err error) retryChoice {
+		retryPromptMu.Lock()
+		defer retryPromptMu.Unlock()
+		resumeSpinner := pauseSpinner()
+		defer resumeSpinner()
+
+		fmt.Fprintf(out, "\n%s⚠️  %s is still failing:%s %v\n", colorYellow, tool, colorReset, err)
+		fmt.Fprintf(out, "[r]etry now, [w]ait %s and retry, or [s]kip? ", retryWaitDelay)
+		response, readErr := reader.ReadString('\n')
+		if readErr != nil && response == "" {
+			return retrySkip
+		}
+		switch strings.ToLower(strings.TrimSpace(response)) {
+		case "r", "retry":
+			return retryNow
+		case "w", "wait":
+			return retryWait
+		default:
+			return retrySkip
+		}
+	}
+}
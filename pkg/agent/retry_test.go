@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/llm"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestIsTransientFailure(t *testing.T) {
+	gr := schema.GroupResource{Resource: "pods"}
+	tests := []struct {
+		name   string
+		result any
+		err    error
+		want   bool
+	}{
+		{"no error", nil, nil, false},
+		{"canceled", nil, context.Canceled, false},
+		{"not found", nil, apierrors.NewNotFound(gr, "web"), false},
+		{"forbidden", nil, apierrors.NewForbidden(gr, "web", errors.New("rbac")), false},
+		{"deadline", nil, fmt.Errorf("list pods: %w", context.DeadlineExceeded), true},
+		{"budget", nil, budgetError(toolCheckDNS, nil, time.Second, errToolBudgetExceeded), true},
+		{"throttled", nil, apierrors.NewTooManyRequests("slow down", 1), true},
+		{"unavailable", nil, apierrors.NewServiceUnavailable("etcd"), true},
+		{"refused", nil, errors.New(`Get "https://10.0.0.1:6443/api": dial tcp 10.0.0.1:6443: connect: connection refused`), true},
+		{"kubectl stderr", "Unable to connect to the server: net/http: TLS handshake timeout", errors.New("exit status 1"), true},
+		{"kubectl usage error", "error: unknown flag: --foo", errors.New("exit status 1"), false},
+	}
+	for _, tt := range tests {
+		if got := isTransientFailure(tt.result, tt.err); got != tt.want {
+			t.Errorf("%s: isTransientFailure = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsRepeatableCall(t *testing.T) {
+	tests := []struct {
+		tool   string
+		params any
+		want   bool
+	}{
+		{toolCheckDNS, map[string]any{"context": "prod"}, true},
+		{toolKubectlExec, map[string]any{"context": "prod", "args": []any{"get", "pods"}}, true},
+		{toolKubectlExec, map[string]any{"context": "prod", "args": []any{"delete", "pod", "web"}}, false},
+		{toolKubectlExec, map[string]any{"context": "prod"}, false},
+		{toolApplyResourceYAML, map[string]any{"context": "prod"}, false},
+	}
+	for _, tt := range tests {
+		if got := isRepeatableCall(tt.tool, tt.params); got != tt.want {
+			t.Errorf("isRepeatableCall(%s, %v) = %v, want %v", tt.tool, tt.params, got, tt.want)
+		}
+	}
+}
+
+func withRetryDelays(t *testing.T) {
+	t.Helper()
+	auto, wait := autoRetryDelay, retryWaitDelay
+	autoRetryDelay, retryWaitDelay = time.Millisecond, time.Millisecond
+	t.Cleanup(func() { autoRetryDelay, retryWaitDelay = auto, wait })
+}
+
+// flakyTool fails transiently for the first failures calls.
+func flakyTool(name string, failures int, calls *int) llm.Tool {
+	return llm.Tool{Name: name, Handler: func(params any, inv llm.ToolInvocation) (any, error) {
+		*calls++
+		if *calls <= failures {
+			return nil, errors.New("dial tcp: connect: connection refused")
+		}
+		return "ok", nil
+	}}
+}
+
+func TestWithRetries(t *testing.T) {
+	withRetryDelays(t)
+	invoke := func(tool llm.Tool) (any, error) {
+		return tool.Handler(map[string]any{"context": "prod"}, llm.ToolInvocation{Context: context.Background()})
+	}
+
+	t.Run("automatic retry succeeds", func(t *testing.T) {
+		calls := 0
+		state := &agentState{outputFormat: OutputJSON}
+		tools := withRetries([]llm.Tool{flakyTool(toolCheckDNS, 1, &calls)}, state)
+		if out, err := invoke(tools[0]); err != nil || out != "ok" || calls != 2 {
+			t.Errorf("got %v, %v after %d calls", out, err, calls)
+		}
+	})
+
+	t.Run("no prompt returns annotated error", func(t *testing.T) {
+		calls := 0
+		state := &agentState{outputFormat: OutputJSON}
+		tools := withRetries([]llm.Tool{flakyTool(toolCheckDNS, 5, &calls)}, state)
+		_, err := invoke(tools[0])
+		if err == nil || !strings.Contains(err.Error(), "still failing after a retry") || calls != 2 {
+			t.Errorf("err = %v after %d calls", err, calls)
+		}
+	})
+
+	t.Run("no prompt for batched runs", func(t *testing.T) {
+		calls := 0
+		state := &agentState{outputFormat: OutputJSON, askRetry: func(string, error) retryChoice {
+			t.Error("asked about a run the model did not ask for")
+			return retrySkip
+		}}
+		tools := withRetries([]llm.Tool{flakyTool(toolCheckDNS, 5, &calls)}, state)
+		ctx := context.WithValue(context.Background(), noRetryPromptKey{}, true)
+		_, err := tools[0].Handler(map[string]any{"context": "prod"}, llm.ToolInvocation{Context: ctx})
+		if err == nil || calls != 2 {
+			t.Errorf("err = %v after %d calls", err, calls)
+		}
+	})
+
+	t.Run("user retries then skips", func(t *testing.T) {
+		calls := 0
+		var asked []retryChoice
+		answers := []retryChoice{retryWait, retrySkip}
+		state := &agentState{outputFormat: OutputJSON, askRetry: func(call string, _ error) retryChoice {
+			if call != "check_dns on prod" {
+				t.Errorf("prompt names %q, want the tool and its context", call)
+			}
+			c := answers[len(asked)]
+			asked = append(asked, c)
+			return c
+		}}
+		tools := withRetries([]llm.Tool{flakyTool(toolCheckDNS, 5, &calls)}, state)
+		_, err := invoke(tools[0])
+		if err == nil || !strings.Contains(err.Error(), "chose to skip") || calls != 3 || len(asked) != 2 {
+			t.Errorf("err = %v after %d calls and %d prompts", err, calls, len(asked))
+		}
+	})
+
+	t.Run("user retry succeeds", func(t *testing.T) {
+		calls := 0
+		state := &agentState{outputFormat: OutputJSON, askRetry: func(string, error) retryChoice { return retryNow }}
+		tools := withRetries([]llm.Tool{flakyTool(toolCheckDNS, 2, &calls)}, state)
+		if out, err := invoke(tools[0]); err != nil || out != "ok" || calls != 3 {
+			t.Errorf("got %v, %v after %d calls", out, err, calls)
+		}
+	})
+
+	t.Run("write tools are not retried", func(t *testing.T) {
+		calls := 0
+		state := &agentState{outputFormat: OutputJSON}
+		tools := withRetries([]llm.Tool{flakyTool(toolApplyResourceYAML, 1, &calls)}, state)
+		if _, err := invoke(tools[0]); err == nil || calls != 1 {
+			t.Errorf("err = %v after %d calls", err, calls)
+		}
+	})
+}
+
+func TestTerminalRetryPrompt(t *testing.T) {
+	for input, want := range map[string]retryChoice{
+		"r\n":     retryNow,
+		"wait\n":  retryWait,
+		"s\n":     retrySkip,
+		"\n":      retrySkip,
+		"":        retrySkip,
+		"maybe\n": retrySkip,
+	} {
+		var out bytes.Buffer
+		ask := newTerminalRetryPrompt(strings.NewReader(input), &out)
+		if got := ask(toolCheckDNS, errors.New("connection refused")); got != want {
+			t.Errorf("input %q: choice = %v, want %v", input, got, want)
+		}
+		if !strings.Contains(out.String(), "check_dns is still failing") {
+			t.Errorf("prompt output:\n%s", out.String())
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the restart_workload and rollout_status tools.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+const maxRolloutTimeoutSeconds = 3600
+
+// Provider calls behind the rollout tools; variables so tests can stub the cluster.
+var (
+	restartWorkloadFunc = func(p *k8s.Provider, ctx context.Context, contextName, namespace, kind, name string) (*k8s.RestartResult, error) {
+		return p.RestartWorkload(ctx, contextName, namespace, kind, name)
+	}
+	getRolloutStatusFunc = func(p *k8s.Provider, ctx context.Context, contextName, namespace, kind, name string) (*k8s.RolloutStatus, error) {
+		return p.GetRolloutStatus(ctx, contextName, namespace, kind, name)
+	}
+	waitForRolloutFunc = func(p *k8s.Provider, ctx context.Context, contextName, namespace, kind, name string, opts k8s.RolloutOptions) (*k8s.RolloutStatus, error) {
+		return p.WaitForRollout(ctx, contextName, namespace, kind, name, opts)
+	}
+)
+
+// RestartWorkloadParams defines parameters for restart_workload
+type RestartWorkloadParams struct {
+	Context        string `json:"context" jsonschema:"The cluster context name (required)"`
+	Namespace      string `json:"namespace,omitempty" jsonschema:"Namespace of the workload (defaults to the context namespace)"`
+	Kind           string `json:"kind,omitempty" jsonschema:"deployment (default), statefulset or daemonset"`
+	Name           string `json:"name" jsonschema:"Name of the workload to restart"`
+	NoWait         bool   `json:"no_wait,omitempty" jsonschema:"Return right after the restart instead of following the rollout until it is healthy"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty" jsonschema:"How long to follow the rollout (default 300, max 3600)"`
+	Background     bool   `json:"background,omitempty" jsonschema:"Follow the rollout as a background job so the chat continues; the user follows it with /jobs and cancels it with /cancel"`
+}
+
+// RolloutStatusParams defines parameters for rollout_status
+type RolloutStatusParams struct {
+	Context        string `json:"context" jsonschema:"The cluster context name (required)"`
+	Namespace      string `json:"namespace,omitempty" jsonschema:"Namespace of the workload (defaults to the context namespace)"`
+	Kind           string `json:"kind,omitempty" jsonschema:"deployment (default), statefulset or daemonset"`
+	Name           string `json:"name" jsonschema:"Name of the workload"`
+	Wait           bool   `json:"wait,omitempty" jsonschema:"Follow the rollout with live updates until it completes, fails or times out, instead of returning the current status"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty" jsonschema:"How long to wait when wait is set (default 300, max 3600)"`
+	Background     bool   `json:"background,omitempty" jsonschema:"With wait, follow the rollout as a background job so the chat continues; the user follows it with /jobs and cancels it with /cancel"`
+}
+
+func defineRestartWorkloadTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolRestartWorkload,
+		"Rolling restart of a Deployment, StatefulSet or DaemonSet (like kubectl rollout restart), then follow the rollout with live progress until every new pod is available, so 'restart the api and tell me when it is healthy' is one call; with background the rollout is followed as a background job instead. Always asks for confirmation. A restart cannot be undone.",
+		func(params RestartWorkloadParams, inv llm.ToolInvocation) (any, error) {
+			return handleRestartWorkload(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+func defineRolloutStatusTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolRolloutStatus,
+		"Rollout status of a Deployment, StatefulSet or DaemonSet (like kubectl rollout status): updated, ready and available replicas and whether the rollout is complete or exceeded its progress deadline. With wait, follows the rollout with live updates until it finishes, optionally as a background job. Read-only.",
+		func(params RolloutStatusParams, inv llm.ToolInvocation) (any, error) {
+			target, err := validateRolloutTarget(k8sProvider, params.Context, params.Namespace, params.Kind, params.Name, &params.TimeoutSeconds)
+			if err != nil {
+				return nil, err
+			}
+			if params.Wait && params.Background {
+				return followRolloutInBackground(k8sProvider, state, toolRolloutStatus, params.Context, target, params.Name, params.TimeoutSeconds)
+			}
+			var status *k8s.RolloutStatus
+			if params.Wait {
+				status, err = waitForRolloutFunc(k8sProvider, inv.Ctx(), params.Context, target.namespace, target.kind, params.Name, rolloutOptions(state, params.TimeoutSeconds))
+			} else {
+				status, err = getRolloutStatusFunc(k8sProvider, inv.Ctx(), params.Context, target.namespace, target.kind, params.Name)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to get rollout status: %w", err)
+			}
+			if isJSONOutput(state.outputFormat) {
+				return status, nil
+			}
+			return formatRolloutStatus(status), nil
+		},
+	)
+}
+
+// rolloutTarget is a validated workload reference.
+type rolloutTarget struct {
+	cluster   *k8s.ClusterInfo
+	namespace string
+	kind      string
+}
+
+// validateRolloutTarget checks the workload reference and the timeout; the
+// namespace defaults to the context's, as with kubectl.
+func validateRolloutTarget(k8sProvider *k8s.Provider, contextName, namespace, kind, name string, timeoutSeconds *int) (rolloutTarget, error) {
+	if contextName == "" || name == "" {
+		return rolloutTarget{}, fmt.Errorf("context and name are required")
+	}
+	kind, err := k8s.NormalizeWorkloadKind(kind)
+	if err != nil {
+		return rolloutTarget{}, err
+	}
+	if !isValidKubernetesName(name) {
+		return rolloutTarget{}, fmt.Errorf("invalid workload name: %s", name)
+	}
+	if *timeoutSeconds == 0 {
+		*timeoutSeconds = int(k8s.DefaultRolloutTimeout / time.Second)
+	}
+	if *timeoutSeconds < 1 || *timeoutSeconds > maxRolloutTimeoutSeconds {
+		return rolloutTarget{}, fmt.Errorf("timeout_seconds must be between 1 and %d", maxRolloutTimeoutSeconds)
+	}
+	cluster, err := getClusterForContext(k8sProvider, contextName)
+	if err != nil {
+		return rolloutTarget{}, err
+	}
+	if namespace == "" {
+		namespace = cluster.Namespace
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	if !isValidKubernetesName(namespace) {
+		return rolloutTarget{}, fmt.Errorf("invalid namespace name: %s", namespace)
+	}
+	return rolloutTarget{cluster: cluster, namespace: namespace, kind: kind}, nil
+}
+
+// rolloutOptions streams progress to the terminal unless output is JSON.
+func rolloutOptions(state *agentState, timeoutSeconds int) k8s.RolloutOptions {
+	opts := k8s.RolloutOptions{Timeout: time.Duration(timeoutSeconds) * time.Second}
+	if !isJSONOutput(state.outputFormat) {
+		opts.Progress = func(s k8s.RolloutStatus) { printRolloutProgress(state.stdout(), s) }
+	}
+	return opts
+}
+
+func handleRestartWorkload(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params RestartWorkloadParams) (any, error) {
+	target, err := validateRolloutTarget(k8sProvider, params.Context, params.Namespace, params.Kind, params.Name, &params.TimeoutSeconds)
+	if err != nil {
+		return nil, err
+	}
+	kind, namespace := target.kind, target.namespace
+
+	command := fmt.Sprintf("rollout restart %s/%s -n %s on %s", strings.ToLower(kind), params.Name, namespace, params.Context)
+	req := ApprovalRequest{Tool: toolRestartWorkload, Cluster: target.cluster.Name, Context: params.Context, Command: command}
+	proceed, cancelResult, err := enforceApproval(ctx, state, false, req)
+	if err != nil {
+		return nil, err
+	}
+	if !proceed {
+		return cancelResult, nil
+	}
+	printExecutionHeader(state, false, command)
+
+	result, err := restartWorkloadFunc(k8sProvider, ctx, params.Context, namespace, kind, params.Name)
+	state.publishWrite(req, err)
+	if err != nil {
+		return nil, err
+	}
+	if !params.NoWait && params.Background {
+		return followRolloutInBackground(k8sProvider, state, toolRestartWorkload, params.Context, target, params.Name, params.TimeoutSeconds)
+	}
+	if !params.NoWait {
+		status, err := waitForRolloutFunc(k8sProvider, ctx, params.Context, namespace, kind, params.Name, rolloutOptions(state, params.TimeoutSeconds))
+		if err != nil {
+			return nil, fmt.Errorf("restarted %s/%s, but following the rollout failed: %w", strings.ToLower(kind), params.Name, err)
+		}
+		result.Rollout = status
+	}
+	if isJSONOutput(state.outputFormat) {
+		return result, nil
+	}
+	return formatRestartResult(result), nil
+}
+
+// followRolloutInBackground follows the rollout of the target workload as a
+// background job of tool.
+func followRolloutInBackground(k8sProvider *k8s.Provider, state *agentState, tool, contextName string, target rolloutTarget, name string, timeoutSeconds int) (any, error) {
+	description := fmt.Sprintf("rollout of %s/%s in %s on %s", strings.ToLower(target.kind), name, target.namespace, contextName)
+	return runInBackground(state, tool, description, func(ctx context.Context, progress func(string)) (string, error) {
+		opts := k8s.RolloutOptions{
+			Timeout:  time.Duration(timeoutSeconds) * time.Second,
+			Progress: func(s k8s.RolloutStatus) { progress(fmt.Sprintf("[%s] %s", s.Duration, s.Message)) },
+		}
+		status, err := waitForRolloutFunc(k8sProvider, ctx, contextName, target.namespace, target.kind, name, opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to follow the rollout: %w", err)
+		}
+		var failure error
+		if status.Failed || status.TimedOut {
+			failure = errors.New(status.Message)
+		}
+		return formatRolloutStatus(status), failure
+	})
+}
+
+// printRolloutProgress streams one rollout change to w.
+func printRolloutProgress(w io.Writer, s k8s.RolloutStatus) {
+	fmt.Fprintf(w, "\r\033[K%s   [%s] %s/%s: %s%s\n", colorDim, s.Duration, strings.ToLower(s.Kind), s.Name, s.Message, colorReset)
+}
+
+// formatRestartResult formats a RestartResult as human-readable text
+func formatRestartResult(r *k8s.RestartResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🔄 Restarted %s/%s in %s (%s) at %s\n", strings.ToLower(r.Kind), r.Name, r.Namespace, r.Context, r.RestartedAt)
+	if r.Rollout == nil {
+		sb.WriteString("Rollout not followed; check it with rollout_status.\n")
+		return sb.String()
+	}
+	sb.WriteString(formatRolloutStatus(r.Rollout))
+	return sb.String()
+}
+
+// formatRolloutStatus formats a RolloutStatus as human-readable text
+func formatRolloutStatus(s *k8s.RolloutStatus) string {
+	var sb strings.Builder
+	icon := "⏳"
+	switch {
+	case s.Done:
+		icon = "✅"
+	case s.Failed:
+		icon = "❌"
+	case s.TimedOut:
+		icon = "⌛"
+	}
+	fmt.Fprintf(&sb, "%s Rollout of %s/%s in %s: %s\n", icon, strings.ToLower(s.Kind), s.Name, s.Namespace, s.Message)
+	fmt.Fprintf(&sb, "   Replicas: %d desired, %d updated, %d ready, %d available\n", s.Desired, s.Updated, s.Ready, s.Available)
+	switch {
+	case s.TimedOut:
+		fmt.Fprintf(&sb, "   Still in progress after %s; inspect the new pods' events and logs\n", s.Duration)
+	case s.Failed:
+		sb.WriteString("   The rollout stopped; inspect the new pods' events and logs, or roll back with kubectl rollout undo\n")
+	case s.Duration != "":
+		fmt.Fprintf(&sb, "   Followed for %s\n", s.Duration)
+	}
+	return sb.String()
+}
@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// stubRollout replaces the restart and wait calls, recording the restarted
+// workload as "namespace/kind/name" and the wait options.
+func stubRollout(t *testing.T, status *k8s.RolloutStatus) (restarted *string, waited *k8s.RolloutOptions) {
+	t.Helper()
+	restart, wait := restartWorkloadFunc, waitForRolloutFunc
+	t.Cleanup(func() { restartWorkloadFunc, waitForRolloutFunc = restart, wait })
+	restarted, waited = new(string), new(k8s.RolloutOptions)
+	restartWorkloadFunc = func(_ *k8s.Provider, _ context.Context, contextName, namespace, kind, name string) (*k8s.RestartResult, error) {
+		*restarted = namespace + "/" + kind + "/" + name
+		return &k8s.RestartResult{Context: contextName, Namespace: namespace, Kind: kind, Name: name, RestartedAt: "2026-10-16T09:30:00Z"}, nil
+	}
+	waitForRolloutFunc = func(_ *k8s.Provider, _ context.Context, _, _, _, _ string, opts k8s.RolloutOptions) (*k8s.RolloutStatus, error) {
+		*waited = opts
+		return status, nil
+	}
+	return restarted, waited
+}
+
+func TestValidateRolloutTarget(t *testing.T) {
+	provider := newTestK8sProvider(t)
+	timeout := 0
+	target, err := validateRolloutTarget(provider, "test-context", "", "sts", "db", &timeout)
+	if err != nil || target.kind != k8s.KindStatefulSet || target.namespace != "default" || timeout != 300 {
+		t.Errorf("target = %+v, timeout %d, err %v", target, timeout, err)
+	}
+	for want, args := range map[string][]string{
+		"context and name are required": {"test-context", "", "deployment", ""},
+		"unsupported workload kind":     {"test-context", "", "cronjob", "api"},
+		"invalid workload name":         {"test-context", "", "deployment", "api;rm"},
+		"invalid namespace name":        {"test-context", "Shop_1", "deployment", "api"},
+		"does not exist":                {"missing", "", "deployment", "api"},
+	} {
+		timeout := 0
+		if _, err := validateRolloutTarget(provider, args[0], args[1], args[2], args[3], &timeout); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%v: error = %v, want %q", args, err, want)
+		}
+	}
+	timeout = 7200
+	if _, err := validateRolloutTarget(provider, "test-context", "", "", "api", &timeout); err == nil || !strings.Contains(err.Error(), "timeout_seconds") {
+		t.Errorf("long timeout error = %v", err)
+	}
+}
+
+func TestHandleRestartWorkload(t *testing.T) {
+	restarted, waited := stubRollout(t, &k8s.RolloutStatus{Kind: k8s.KindDeployment, Name: "api", Done: true, Message: "successfully rolled out"})
+	state := approvingState()
+
+	out, err := handleRestartWorkload(context.Background(), newTestK8sProvider(t), state, RestartWorkloadParams{Context: "test-context", Namespace: "shop", Name: "api", TimeoutSeconds: 60})
+	if err != nil {
+		t.Fatalf("handleRestartWorkload: %v", err)
+	}
+	result := out.(*k8s.RestartResult)
+	if *restarted != "shop/Deployment/api" || result.Rollout == nil || !result.Rollout.Done {
+		t.Errorf("restarted %q, result %+v", *restarted, result)
+	}
+	if waited.Timeout != time.Minute || waited.Progress != nil {
+		t.Errorf("wait options = %+v, want 1m without terminal progress for JSON output", *waited)
+	}
+	req := state.approver.(*stubApprover).req
+	if req.Tool != toolRestartWorkload || req.Command != "rollout restart deployment/api -n shop on test-context" {
+		t.Errorf("approval request = %+v", req)
+	}
+}
+
+func TestHandleRestartWorkloadNoWaitAndDenied(t *testing.T) {
+	restarted, _ := stubRollout(t, nil)
+	original := waitForRolloutFunc
+	waitForRolloutFunc = func(*k8s.Provider, context.Context, string, string, string, string, k8s.RolloutOptions) (*k8s.RolloutStatus, error) {
+		t.Fatal("the rollout was followed with no_wait")
+		return nil, nil
+	}
+	defer func() { waitForRolloutFunc = original }()
+
+	state := approvingState()
+	state.outputFormat = OutputText
+	out, err := handleRestartWorkload(context.Background(), newTestK8sProvider(t), state, RestartWorkloadParams{Context: "test-context", Name: "api", NoWait: true})
+	if err != nil || !strings.Contains(out.(string), "Rollout not followed") {
+		t.Errorf("no_wait result = %v, %v", out, err)
+	}
+
+	*restarted = ""
+	state.approver = &stubApprover{name: "test", decision: ApprovalDecision{Approved: false}}
+	if _, err := handleRestartWorkload(context.Background(), newTestK8sProvider(t), state, RestartWorkloadParams{Context: "test-context", Name: "api"}); err != nil {
+		t.Fatalf("handleRestartWorkload: %v", err)
+	}
+	if *restarted != "" {
+		t.Error("the workload was restarted without approval")
+	}
+}
+
+func TestRolloutStatusTool(t *testing.T) {
+	original := getRolloutStatusFunc
+	t.Cleanup(func() { getRolloutStatusFunc = original })
+	getRolloutStatusFunc = func(_ *k8s.Provider, _ context.Context, _, namespace, kind, name string) (*k8s.RolloutStatus, error) {
+		return &k8s.RolloutStatus{Namespace: namespace, Kind: kind, Name: name, Desired: 3, Updated: 1, Message: "1 of 3 new replicas updated"}, nil
+	}
+
+	tool := defineRolloutStatusTool(newTestK8sProvider(t), &agentState{outputFormat: OutputText})
+	out, err := tool.Handler(map[string]any{"context": "test-context", "name": "api"}, llm.ToolInvocation{Context: context.Background()})
+	if err != nil {
+		t.Fatalf("rollout_status: %v", err)
+	}
+	if text := out.(string); !strings.Contains(text, "⏳ Rollout of deployment/api in default: 1 of 3 new replicas updated") {
+		t.Errorf("output = %s", text)
+	}
+}
+
+func TestFormatRolloutStatus(t *testing.T) {
+	tests := []struct {
+		status k8s.RolloutStatus
+		want   []string
+	}{
+		{k8s.RolloutStatus{Kind: "Deployment", Name: "api", Namespace: "shop", Done: true, Message: "successfully rolled out", Desired: 2, Updated: 2, Ready: 2, Available: 2, Duration: "34s"},
+			[]string{"✅ Rollout of deployment/api in shop", "2 desired, 2 updated, 2 ready, 2 available", "Followed for 34s"}},
+		{k8s.RolloutStatus{Kind: "Deployment", Name: "api", Failed: true, Message: "exceeded its progress deadline"},
+			[]string{"❌", "rollout undo"}},
+		{k8s.RolloutStatus{Kind: "StatefulSet", Name: "db", TimedOut: true, Message: "1 of 3 pods ready", Duration: "5m0s"},
+			[]string{"⌛ Rollout of statefulset/db", "Still in progress after 5m0s"}},
+	}
+	for _, tt := range tests {
+		out := formatRolloutStatus(&tt.status)
+		for _, want := range tt.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("output missing %q:\n%s", want, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the /run command, which calls any tool of the session
+// directly with JSON arguments, so deterministic operations cost no model
+// request and no premium quota.
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// runKubectl is the /run shorthand for kubectl_exec taking kubectl's own
+// arguments, e.g. /run kubectl get pods --context prod.
+const runKubectl = "kubectl"
+
+// handleRunCommand processes "/run [tool [json-args]]": without a tool it
+// lists the tools; otherwise it runs the tool with the JSON object of
+// arguments, or none, and prints the result.
+func handleRunCommand(deps *loopDeps, input string) (bool, error) {
+	rest := strings.TrimSpace(input[len("/run"):])
+	if rest == "" {
+		printRunnableTools(deps.state)
+		return true, nil
+	}
+	name, rawArgs, _ := strings.Cut(rest, " ")
+	if name == runKubectl {
+		return handleKubectlCommand(deps, "/kubectl "+rawArgs)
+	}
+	if _, ok := deps.state.toolsByName[name]; !ok {
+		fmt.Printf("  %s●%s Unknown tool %s — type %s/run%s to list the tools\n", colorRed, colorReset, name, colorCyan, colorReset)
+		return true, nil
+	}
+	args, err := parseRunArgs(rawArgs)
+	if err != nil {
+		fmt.Printf("  %s●%s %v\n", colorRed, colorReset, err)
+		fmt.Printf("    %sUsage: /run %s {\"context\": \"prod\", ...}%s\n", colorDim, name, colorReset)
+		return true, nil
+	}
+	callToolDirectly(deps, name, args)
+	return true, nil
+}
+
+// parseRunArgs parses the JSON object of /run arguments; empty is none.
+func parseRunArgs(raw string) (map[string]any, error) {
+	args := map[string]any{}
+	if raw = strings.TrimSpace(raw); raw == "" {
+		return args, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &args); err != nil || args == nil {
+		return nil, fmt.Errorf("arguments must be a JSON object: %s", raw)
+	}
+	return args, nil
+}
+
+// printRunnableTools lists the tools /run can call.
+func printRunnableTools(state *agentState) {
+	names := make([]string, 0, len(state.toolsByName))
+	for name := range state.toolsByName {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	fmt.Printf("  %s●%s %d tools; run one with /run <tool> [json-args], or /run kubectl <args>:\n", colorCyan, colorReset, len(names))
+	for _, line := range wrapWords(names, terminalWidth()-4) {
+		fmt.Printf("    %s%s%s\n", colorDim, line, colorReset)
+	}
+}
+
+// wrapWords joins words with ", " into lines at most width wide.
+func wrapWords(words []string, width int) []string {
+	var lines []string
+	line := ""
+	for _, w := range words {
+		switch {
+		case line == "":
+			line = w
+		case len(line)+2+len(w) > width:
+			lines = append(lines, line+",")
+			line = w
+		default:
+			line += ", " + w
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+func TestHandleRunCommand(t *testing.T) {
+	calls := map[string]any{}
+	record := func(name string) llm.Tool {
+		return llm.Tool{Name: name, Handler: func(params any, _ llm.ToolInvocation) (any, error) {
+			calls[name] = params
+			return "ok", nil
+		}}
+	}
+	state := &agentState{}
+	state.setTools([]llm.Tool{record(toolCheckAllClusters), record(toolRankEventNoise), record(toolKubectlExec)})
+	deps := &loopDeps{ctx: context.Background(), k8sProvider: newTestK8sProvider(t), state: state}
+
+	for _, input := range []string{
+		"/run",
+		"/run check_all_clusters",
+		`/run rank_event_noise {"context": "prod", "top": 5}`,
+		"/run kubectl get pods --context prod",
+		"/run rank_event_noise context=prod",
+		"/run no_such_tool",
+	} {
+		if handled, err := handleRunCommand(deps, input); !handled || err != nil {
+			t.Errorf("handleRunCommand(%q) = %v, %v", input, handled, err)
+		}
+	}
+	want := map[string]any{
+		toolCheckAllClusters: map[string]any{},
+		toolRankEventNoise:   map[string]any{"context": "prod", "top": float64(5)},
+		toolKubectlExec:      map[string]any{"context": "prod", "args": []string{"get", "pods"}},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("tool calls = %v, want %v", calls, want)
+	}
+}
+
+func TestWrapWords(t *testing.T) {
+	got := wrapWords([]string{"chaos", "check_dns", "check_gpus", "list_clusters"}, 22)
+	want := []string{"chaos, check_dns,", "check_gpus,", "list_clusters"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrapWords() = %q, want %q", got, want)
+	}
+	for _, line := range got {
+		if len(strings.TrimSuffix(line, ",")) > 22 {
+			t.Errorf("line %q is wider than 22", line)
+		}
+	}
+}
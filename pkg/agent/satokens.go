@@ -0,0 +1,78 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the review_sa_tokens tool (long-lived ServiceAccount token review).
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// ReviewSATokensParams defines parameters for review_sa_tokens
+type ReviewSATokensParams struct {
+	Context       string `json:"context" jsonschema:"The context name of the cluster to review (from list_clusters)"`
+	Namespace     string `json:"namespace,omitempty" jsonschema:"Optional: restrict the review to a specific namespace; leave empty for all non-system namespaces"`
+	IncludeSystem bool   `json:"include_system,omitempty" jsonschema:"If true, include system namespaces (kube-system, kube-public, kube-node-lease)"`
+}
+
+func defineReviewSATokensTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolReviewSATokens,
+		"Security review of long-lived ServiceAccount token secrets: lists each token with its age, last use (when the cluster tracks it), the workloads that mount it or run as its ServiceAccount, and the roles it carries, ranked by risk with a migration recommendation to bound, expiring tokens. Use for access reviews and token hygiene questions.",
+		func(params ReviewSATokensParams, inv llm.ToolInvocation) (any, error) {
+			if params.Namespace != "" && !isValidKubernetesName(params.Namespace) {
+				return nil, fmt.Errorf("invalid namespace name: %s", params.Namespace)
+			}
+			review, err := k8sProvider.ReviewServiceAccountTokens(inv.Ctx(), params.Context, params.Namespace, params.IncludeSystem)
+			if err != nil {
+				return nil, fmt.Errorf("failed to review service account tokens: %w", err)
+			}
+
+			if isJSONOutput(state.outputFormat) {
+				return review, nil
+			}
+			return formatTokenReview(review), nil
+		},
+	)
+}
+
+// tokenRiskIcons maps token risks to text-mode icons.
+var tokenRiskIcons = map[k8s.SanitizeSeverity]string{
+	k8s.SanitizeCritical: "🔴",
+	k8s.SanitizeMajor:    "🟠",
+	k8s.SanitizeMinor:    "🟡",
+}
+
+// formatTokenReview formats a TokenReview as human-readable text
+func formatTokenReview(review *k8s.TokenReview) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "ServiceAccount Token Review: %s\n", review.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	if len(review.Tokens) == 0 {
+		sb.WriteString("✅ No long-lived ServiceAccount token secrets in scope; workloads use bound, expiring tokens.\n")
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "🔑 LONG-LIVED TOKENS: %d (critical: %d, major: %d, minor: %d)\n\n", len(review.Tokens),
+		review.Risks[string(k8s.SanitizeCritical)], review.Risks[string(k8s.SanitizeMajor)], review.Risks[string(k8s.SanitizeMinor)])
+
+	for _, t := range review.Tokens {
+		fmt.Fprintf(&sb, "%s %s/%s  [%s]  serviceaccount %s\n", tokenRiskIcons[t.Risk], t.Namespace, t.Secret, strings.ToUpper(string(t.Risk)), orNone(t.ServiceAccount))
+		lastUsed := t.LastUsed
+		if lastUsed == "" {
+			lastUsed = "not tracked"
+		}
+		fmt.Fprintf(&sb, "     Age: %dd  |  Last used: %s  |  Roles: %s\n", t.AgeDays, lastUsed, orNone(strings.Join(t.Roles, ", ")))
+		if len(t.MountedBy) > 0 {
+			fmt.Fprintf(&sb, "     Mounted by: %s\n", strings.Join(t.MountedBy, ", "))
+		}
+		if len(t.RunningAs) > 0 {
+			fmt.Fprintf(&sb, "     Running as the ServiceAccount: %s\n", strings.Join(t.RunningAs, ", "))
+		}
+		fmt.Fprintf(&sb, "     → %s\n", t.Recommendation)
+	}
+	return sb.String()
+}
@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestFormatTokenReview(t *testing.T) {
+	review := &k8s.TokenReview{
+		Context: "prod",
+		Risks:   map[string]int{"critical": 1},
+		Tokens: []k8s.ServiceAccountToken{{
+			Namespace: "ops", Secret: "dashboard-token", ServiceAccount: "dashboard", AgeDays: 400,
+			Roles: []string{"ClusterRole/cluster-admin"}, MountedBy: []string{"deployment/dashboard"},
+			Risk: k8s.SanitizeCritical, Recommendation: "Replace the secret volume.",
+		}},
+	}
+	text := formatTokenReview(review)
+	for _, want := range []string{
+		"ServiceAccount Token Review: prod",
+		"LONG-LIVED TOKENS: 1 (critical: 1, major: 0, minor: 0)",
+		"🔴 ops/dashboard-token  [CRITICAL]  serviceaccount dashboard",
+		"Age: 400d  |  Last used: not tracked  |  Roles: ClusterRole/cluster-admin",
+		"Mounted by: deployment/dashboard",
+		"→ Replace the secret volume.",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+
+	if text := formatTokenReview(&k8s.TokenReview{Context: "dev"}); !strings.Contains(text, "No long-lived ServiceAccount token secrets") {
+		t.Errorf("empty review output:\n%s", text)
+	}
+}
@@ -0,0 +1,160 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file tightens the tool parameter schemas with what is known about the
+// kubeconfig, and checks calls against them.
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// maxContextEnum is the most contexts listed as an enum; beyond that the
+// enum would bloat every request and only the context count is enforced.
+const maxContextEnum = 100
+
+// namespacePattern matches a namespace name, an RFC 1123 label, or the
+// empty string the tools read as "all namespaces" or the default.
+const namespacePattern = `^([a-z0-9]([-a-z0-9]*[a-z0-9])?)?$`
+
+// namespaceMaxLength is the longest namespace name.
+const namespaceMaxLength = 63
+
+var namespaceRE = regexp.MustCompile(namespacePattern)
+
+// contextProperties and namespaceProperties are the tool parameters naming a
+// kubeconfig context and a namespace.
+var (
+	contextProperties   = []string{"context", "source_context", "target_context", "other_context"}
+	namespaceProperties = []string{"namespace", "source_namespace", "target_namespace", "other_namespace"}
+)
+
+// withSchemaLimits adds to the tool schemas an enum of the kubeconfig
+// contexts, a namespace pattern and a maxItems for context lists, and rejects
+// calls breaking them before the tool runs, naming the valid values. The
+// schemas are fixed when the session is created, but calls are checked
+// against the provider's contexts at call time, so contexts added by a
+// kubeconfig reload are accepted right away.
+func withSchemaLimits(tools []llm.Tool, k8sProvider *k8s.Provider) []llm.Tool {
+	if k8sProvider == nil {
+		return tools
+	}
+	contexts := kubeconfigContexts(k8sProvider)
+	for i := range tools {
+		props, ok := tools[i].Parameters["properties"].(map[string]any)
+		if !ok || !tightenProperties(props, contexts) {
+			continue
+		}
+		name, handler := tools[i].Name, tools[i].Handler
+		tools[i].Handler = func(params any, inv llm.ToolInvocation) (any, error) {
+			if err := checkToolParams(params, kubeconfigContexts(k8sProvider)); err != nil {
+				return nil, invalidCallError(name, err)
+			}
+			return handler(params, inv)
+		}
+	}
+	return tools
+}
+
+// kubeconfigContexts returns the sorted context names of the provider.
+func kubeconfigContexts(k8sProvider *k8s.Provider) []string {
+	var contexts []string
+	for _, cluster := range k8sProvider.GetClusters() {
+		contexts = append(contexts, cluster.Context)
+	}
+	slices.Sort(contexts)
+	return contexts
+}
+
+// invalidCallError reports a call of tool rejected by checkToolParams.
+func invalidCallError(tool string, err error) *llm.ToolError {
+	e := &llm.ToolError{
+		Code:    llm.ToolErrorInvalidArguments,
+		Message: fmt.Sprintf("invalid %s call: %v", tool, err),
+		Hint:    "fix the argument named in the message and call again",
+		Err:     err,
+	}
+	if strings.Contains(err.Error(), "unknown context") {
+		e.Code, e.Hint = llm.ToolErrorContextNotFound, "call again with one of the available contexts, from list_clusters"
+	}
+	return e
+}
+
+// tightenProperties adds the limits to the context and namespace properties
+// of a schema and reports whether it has any.
+func tightenProperties(props map[string]any, contexts []string) bool {
+	tightened := false
+	for name, prop := range props {
+		schema, ok := prop.(map[string]any)
+		if !ok {
+			continue
+		}
+		switch {
+		case slices.Contains(contextProperties, name) && schema["type"] == "string":
+			if len(contexts) > 0 && len(contexts) <= maxContextEnum {
+				schema["enum"] = contexts
+			}
+			tightened = true
+		case name == "contexts" && schema["type"] == "array":
+			if len(contexts) > 0 {
+				schema["maxItems"] = len(contexts)
+			}
+			schema["uniqueItems"] = true
+			if items, ok := schema["items"].(map[string]any); ok && len(contexts) > 0 && len(contexts) <= maxContextEnum {
+				items["enum"] = contexts
+			}
+			tightened = true
+		case slices.Contains(namespaceProperties, name) && schema["type"] == "string":
+			schema["pattern"] = namespacePattern
+			schema["maxLength"] = namespaceMaxLength
+			tightened = true
+		}
+	}
+	return tightened
+}
+
+// checkToolParams checks the context and namespace arguments of a call.
+func checkToolParams(params any, contexts []string) error {
+	args, _ := llm.NormalizeToolArguments(params)
+	for _, name := range contextProperties {
+		if value, ok := args[name].(string); ok && value != "" {
+			if err := checkContext(name, value, contexts); err != nil {
+				return err
+			}
+		}
+	}
+	if list, ok := args["contexts"].([]any); ok {
+		seen := make(map[string]bool, len(list))
+		for _, item := range list {
+			value, _ := item.(string)
+			if seen[value] {
+				return fmt.Errorf("contexts: %q listed twice", value)
+			}
+			seen[value] = true
+			if err := checkContext("contexts", value, contexts); err != nil {
+				return err
+			}
+		}
+	}
+	for _, name := range namespaceProperties {
+		if value, ok := args[name].(string); ok && (len(value) > namespaceMaxLength || !namespaceRE.MatchString(value)) {
+			return fmt.Errorf("%s: %q is not a valid namespace name (lowercase letters, digits and '-', at most %d characters)", name, value, namespaceMaxLength)
+		}
+	}
+	return nil
+}
+
+// checkContext reports a context missing from the kubeconfig.
+func checkContext(name, value string, contexts []string) error {
+	if slices.Contains(contexts, value) {
+		return nil
+	}
+	if len(contexts) > maxContextEnum {
+		return fmt.Errorf("%s: unknown context %q; call list_clusters for the available contexts", name, value)
+	}
+	return fmt.Errorf("%s: unknown context %q; available contexts: %s", name, value, strings.Join(contexts, ", "))
+}
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+type schemaTestParams struct {
+	Context   string   `json:"context"`
+	Contexts  []string `json:"contexts,omitempty"`
+	Namespace string   `json:"namespace,omitempty"`
+	Name      string   `json:"name,omitempty"`
+}
+
+func TestWithSchemaLimits(t *testing.T) {
+	provider := createMockProvider(t)
+	var contexts []string
+	for _, cluster := range provider.GetClusters() {
+		contexts = append(contexts, cluster.Context)
+	}
+	slices.Sort(contexts)
+	called := 0
+	tool := llm.DefineTool("test_tool", "test", func(_ schemaTestParams, _ llm.ToolInvocation) (any, error) {
+		called++
+		return "ok", nil
+	})
+	tools := withSchemaLimits([]llm.Tool{tool}, provider)
+
+	props := tools[0].Parameters["properties"].(map[string]any)
+	if enum, _ := props["context"].(map[string]any)["enum"].([]string); len(enum) != len(contexts) {
+		t.Errorf("context enum = %v, want %v", enum, contexts)
+	}
+	list := props["contexts"].(map[string]any)
+	if list["maxItems"] != len(contexts) || list["uniqueItems"] != true {
+		t.Errorf("contexts schema = %v, want maxItems %d and uniqueItems", list, len(contexts))
+	}
+	if props["namespace"].(map[string]any)["pattern"] != namespacePattern {
+		t.Errorf("namespace schema = %v, want pattern %s", props["namespace"], namespacePattern)
+	}
+	if _, ok := props["name"].(map[string]any)["enum"]; ok {
+		t.Error("name should not be limited")
+	}
+
+	tests := []struct {
+		name    string
+		params  map[string]any
+		wantErr string
+	}{
+		{"valid", map[string]any{"context": contexts[0], "namespace": "kube-system"}, ""},
+		{"empty namespace", map[string]any{"context": contexts[0], "namespace": ""}, ""},
+		{"context list", map[string]any{"context": contexts[0], "contexts": []any{contexts[0], contexts[1]}}, ""},
+		{"unknown context", map[string]any{"context": "prod"}, `unknown context "prod"; available contexts: ` + strings.Join(contexts, ", ")},
+		{"unknown in list", map[string]any{"contexts": []any{contexts[0], "prod"}}, `contexts: unknown context "prod"`},
+		{"duplicate in list", map[string]any{"contexts": []any{contexts[0], contexts[0]}}, "listed twice"},
+		{"bad namespace", map[string]any{"context": contexts[0], "namespace": "Kube_System"}, "not a valid namespace name"},
+		{"long namespace", map[string]any{"context": contexts[0], "namespace": strings.Repeat("a", 64)}, "not a valid namespace name"},
+	}
+	for _, tt := range tests {
+		called = 0
+		_, err := tools[0].Handler(tt.params, llm.ToolInvocation{})
+		if tt.wantErr == "" {
+			if err != nil || called != 1 {
+				t.Errorf("%s: err = %v, called = %d, want the tool to run", tt.name, err, called)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+		if called != 0 {
+			t.Errorf("%s: tool ran despite the invalid call", tt.name)
+		}
+	}
+
+	_, err := tools[0].Handler(map[string]any{"context": "prod"}, llm.ToolInvocation{})
+	if e := llm.AsToolError(err); e.Code != llm.ToolErrorContextNotFound || e.Hint == "" {
+		t.Errorf("unknown context error = %+v, want context_not_found with a hint", e)
+	}
+	_, err = tools[0].Handler(map[string]any{"context": contexts[0], "namespace": "Kube_System"}, llm.ToolInvocation{})
+	if e := llm.AsToolError(err); e.Code != llm.ToolErrorInvalidArguments {
+		t.Errorf("bad namespace error = %+v, want invalid_arguments", e)
+	}
+}
+
+func TestWithSchemaLimitsAfterReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(path, []byte(testKubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := k8s.NewProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := llm.DefineTool("test_tool", "test", func(_ schemaTestParams, _ llm.ToolInvocation) (any, error) {
+		return "ok", nil
+	})
+	tools := withSchemaLimits([]llm.Tool{tool}, provider)
+	if _, err := tools[0].Handler(map[string]any{"context": "staging"}, llm.ToolInvocation{}); err == nil {
+		t.Fatal("unknown context accepted before the reload")
+	}
+
+	staging := strings.Replace(testKubeconfig, "contexts:\n", "contexts:\n- name: staging\n  context:\n    cluster: test-cluster\n    user: test-user\n", 1)
+	if err := os.WriteFile(path, []byte(staging), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if out, err := tools[0].Handler(map[string]any{"context": "staging"}, llm.ToolInvocation{}); err != nil || out != "ok" {
+		t.Errorf("context added by the reload = %v, %v; want the tool to run", out, err)
+	}
+}
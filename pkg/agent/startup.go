@@ -0,0 +1,227 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the configurable checks run when an interactive session starts.
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/report"
+)
+
+// Startup check selections; the areas are the parts of a cluster check.
+const (
+	startupAll          = "all"
+	startupNone         = "none"
+	startupNodes        = "nodes"
+	startupPods         = "pods"
+	startupControlPlane = "control_plane"
+	startupEndpoints    = "endpoints"
+	startupAutoscaling  = "autoscaling"
+
+	// startupCurrentContext in contexts stands for the current context.
+	startupCurrentContext = "current"
+)
+
+// startupAreas are the areas a startup check can be limited to.
+var startupAreas = []string{startupNodes, startupPods, startupControlPlane, startupEndpoints, startupAutoscaling}
+
+// StartupChecks selects what is checked when an interactive session starts.
+// The zero value only shows what changed since the last recorded check, for
+// clusters that have one.
+type StartupChecks struct {
+	// Checks are the areas to check at startup (nodes, pods, control_plane,
+	// endpoints, autoscaling), "all", or "none" to check nothing.
+	Checks []string `json:"checks,omitempty"`
+	// Contexts limits the startup check to these contexts; "current" is the
+	// current context. Empty means every context.
+	Contexts []string `json:"contexts,omitempty"`
+	// Prompt is sent to the agent as the session's first message, for checks
+	// beyond cluster health, e.g. "run check_jobs on prod" or a macro such as
+	// "/morning".
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// validate reports unknown check names and contradictory selections.
+func (c StartupChecks) validate() error {
+	for _, check := range c.Checks {
+		switch check {
+		case startupAll, startupNone:
+			if len(c.Checks) > 1 {
+				return fmt.Errorf("checks: %q cannot be combined with other checks", check)
+			}
+		default:
+			if !slices.Contains(startupAreas, check) {
+				return fmt.Errorf("checks: unknown check %q (use %s, %s or %s)", check, strings.Join(startupAreas, ", "), startupAll, startupNone)
+			}
+		}
+	}
+	for _, contextName := range c.Contexts {
+		if strings.TrimSpace(contextName) == "" {
+			return fmt.Errorf("contexts: empty context name")
+		}
+	}
+	return nil
+}
+
+// disabled reports whether startup checks are turned off.
+func (c StartupChecks) disabled() bool {
+	return len(c.Checks) == 1 && c.Checks[0] == startupNone
+}
+
+// areas returns the selected areas, every area when none are selected.
+func (c StartupChecks) areas() []string {
+	if len(c.Checks) == 0 || c.Checks[0] == startupAll {
+		return startupAreas
+	}
+	return c.Checks
+}
+
+// complete reports whether the check covers every area of every cluster,
+// so its report can stand in for a check_all_clusters run.
+func (c StartupChecks) complete() bool {
+	return len(c.Contexts) == 0 && len(c.areas()) == len(startupAreas)
+}
+
+// contextNames resolves the selected contexts, every context when none are
+// selected. Contexts missing from the kubeconfig are kept and reported as
+// unreachable by the check.
+func (c StartupChecks) contextNames(k8sProvider *k8s.Provider) []string {
+	var names []string
+	if len(c.Contexts) == 0 {
+		for _, cluster := range k8sProvider.GetClusters() {
+			names = append(names, cluster.Context)
+		}
+		return names
+	}
+	for _, contextName := range c.Contexts {
+		if contextName == startupCurrentContext {
+			contextName = k8sProvider.GetCurrentContext()
+		}
+		if contextName != "" && !slices.Contains(names, contextName) {
+			names = append(names, contextName)
+		}
+	}
+	return names
+}
+
+// startupResult is the outcome of the startup check.
+type startupResult struct {
+	// Areas are the checked areas; nil when only the changes since the last
+	// recorded check are shown.
+	Areas []string
+	// Report is the check of the selected clusters, limited to Areas.
+	Report *ClusterReport
+	// Changes is what changed since each cluster's last recorded check.
+	Changes []ClusterChange
+}
+
+// runStartupChecks runs the startup check selected in the config. Without a
+// selection it only checks when a cluster has a recent recorded check, to
+// show what changed since. A complete check becomes the latest report, so
+// the session's first check_all_clusters publishes issue changes against
+// it; it is not recorded in the history, which that check does.
+func runStartupChecks(ctx context.Context, k8sProvider *k8s.Provider, state *agentState) *startupResult {
+	cfg := state.startup
+	if cfg.disabled() {
+		return nil
+	}
+	contexts := cfg.contextNames(k8sProvider)
+	var previous map[string]HealthRecord
+	if healthHistoryEnabled() && cfg.complete() {
+		previous = latestHealthRecords(contexts, time.Now())
+	}
+	if len(cfg.Checks) == 0 && len(previous) == 0 {
+		return nil
+	}
+
+	ctx, stopProgress := ShowCheckProgress(ctx, os.Stderr)
+	statuses := k8sProvider.GetClusterStatuses(ctx, contexts)
+	stopProgress()
+	result := &startupResult{}
+	if len(cfg.Checks) > 0 {
+		result.Areas = cfg.areas()
+		for i, status := range statuses {
+			statuses[i] = limitToAreas(status, result.Areas)
+		}
+	}
+	result.Report = newClusterReport(statuses, state.healthThresholds())
+	if cfg.complete() {
+		state.setLastReport(result.Report)
+		result.Changes = sinceLastRun(previous, result.Report)
+	}
+	return result
+}
+
+// limitToAreas returns a copy of status without the results of the areas
+// that were not selected, so they raise no issues. Reachability is always
+// checked.
+func limitToAreas(status *k8s.ClusterStatus, areas []string) *k8s.ClusterStatus {
+	limited := *status
+	if !slices.Contains(areas, startupNodes) {
+		limited.NodeCount, limited.HealthyNodes, limited.Nodes, limited.KubeletSkew = 0, 0, nil, nil
+	}
+	if !slices.Contains(areas, startupPods) {
+		limited.PodCount, limited.HealthyPods, limited.UnhealthyPods = 0, 0, nil
+	}
+	if !slices.Contains(areas, startupControlPlane) {
+		limited.ControlPlane = nil
+	}
+	if !slices.Contains(areas, startupEndpoints) {
+		limited.ExposureIssues = nil
+	}
+	if !slices.Contains(areas, startupAutoscaling) {
+		limited.AutoscalingIssues = nil
+	}
+	return &limited
+}
+
+// printStartupResult shows the issues of a selected startup check, then
+// what changed since the last session.
+func printStartupResult(w io.Writer, result *startupResult) {
+	if result == nil {
+		return
+	}
+	if result.Areas != nil {
+		summary := result.Report.Summary
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "  %sStartup check (%s) of %d cluster(s):%s\n", colorDim, strings.Join(result.Areas, ", "), summary.TotalClusters, colorReset)
+		if summary.Critical+summary.Warnings == 0 {
+			fmt.Fprintf(w, "    ✅ No issues\n")
+		}
+		for _, issue := range result.Report.Issues {
+			if issue.Severity == report.SeverityInfo {
+				continue
+			}
+			fmt.Fprintf(w, "    %s %s%s%s: %s\n", severityIcon(issue.Severity), colorCyan, issue.Cluster, colorReset, issue.Message)
+		}
+		if focus := result.Report.Focus; focus != nil {
+			fmt.Fprintf(w, "    👉 Investigate first: %s%s%s: %s %s(%s)%s\n", colorCyan, focus.Cluster, colorReset, focus.Message, colorDim, focus.Reason, colorReset)
+		}
+	}
+	printSinceLastRun(w, result.Changes)
+}
+
+// sendStartupPrompt sends the configured startup prompt, expanding a macro,
+// as the session's first message.
+func sendStartupPrompt(deps *loopDeps, ts *turnState) error {
+	input := strings.TrimSpace(deps.state.startup.Prompt)
+	if input == "" {
+		return nil
+	}
+	if prompt, ok := expandMacro(deps.state.macros, input); ok {
+		input = prompt
+	}
+	fmt.Printf("  %s→ %s%s\n", colorDim, input, colorReset)
+	if err := sendToModel(deps, ts, input); err != nil {
+		deps.state.setAbortCurrentTurn(nil)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/report"
+)
+
+func TestStartupChecksSelection(t *testing.T) {
+	provider := newTestK8sProvider(t)
+	tests := []struct {
+		cfg      StartupChecks
+		areas    []string
+		complete bool
+		contexts []string
+	}{
+		{StartupChecks{}, startupAreas, true, []string{"test-context"}},
+		{StartupChecks{Checks: []string{"all"}}, startupAreas, true, []string{"test-context"}},
+		{StartupChecks{Checks: []string{"nodes"}}, []string{"nodes"}, false, []string{"test-context"}},
+		{StartupChecks{Contexts: []string{"current", "test-context", "prod"}}, startupAreas, false, []string{"test-context", "prod"}},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.validate(); err != nil {
+			t.Errorf("%+v: validate: %v", tt.cfg, err)
+		}
+		if got := tt.cfg.areas(); !reflect.DeepEqual(got, tt.areas) {
+			t.Errorf("%+v: areas = %v, want %v", tt.cfg, got, tt.areas)
+		}
+		if got := tt.cfg.complete(); got != tt.complete {
+			t.Errorf("%+v: complete = %t", tt.cfg, got)
+		}
+		if got := tt.cfg.contextNames(provider); !reflect.DeepEqual(got, tt.contexts) {
+			t.Errorf("%+v: contexts = %v, want %v", tt.cfg, got, tt.contexts)
+		}
+	}
+	if !(StartupChecks{Checks: []string{"none"}}).disabled() || (StartupChecks{}).disabled() {
+		t.Error("only checks [none] disables the startup check")
+	}
+	if err := (StartupChecks{Contexts: []string{" "}}).validate(); err == nil {
+		t.Error("an empty context name should be rejected")
+	}
+}
+
+func TestLimitToAreas(t *testing.T) {
+	status := &k8s.ClusterStatus{
+		ClusterInfo: k8s.ClusterInfo{Context: "prod", IsReachable: true},
+		NodeCount:   3, HealthyNodes: 1, PodCount: 10, HealthyPods: 7, UnhealthyPods: make([]k8s.PodInfo, 3),
+		ExposureIssues: []k8s.ExposureIssue{{Name: "lb"}},
+		ControlPlane:   []k8s.ControlPlaneComponent{{Name: "etcd"}},
+	}
+	limited := limitToAreas(status, []string{startupPods})
+	if limited.NodeCount != 0 || limited.ExposureIssues != nil || limited.ControlPlane != nil || limited.PodCount != 10 || !limited.IsReachable {
+		t.Errorf("limited = %+v", limited)
+	}
+	if status.NodeCount != 3 {
+		t.Error("limitToAreas modified the original status")
+	}
+
+	r := newClusterReport([]*k8s.ClusterStatus{limited}, HealthThresholds{})
+	if len(r.Issues) != 1 || r.Issues[0].Message != "3/10 pods unhealthy" {
+		t.Errorf("issues of a pods-only check = %+v", r.Issues)
+	}
+}
+
+func TestRunStartupChecks(t *testing.T) {
+	t.Setenv("KOPILOT_HEALTH_HISTORY", "off")
+	provider := newTestK8sProvider(t)
+
+	state := &agentState{startup: StartupChecks{Checks: []string{"none"}}}
+	if result := runStartupChecks(context.Background(), provider, state); result != nil {
+		t.Errorf("checks [none] ran %+v", result)
+	}
+	state.startup = StartupChecks{}
+	if result := runStartupChecks(context.Background(), provider, state); result != nil {
+		t.Errorf("without history or selection the startup check ran %+v", result)
+	}
+
+	state.startup = StartupChecks{Checks: []string{"nodes"}, Contexts: []string{"current"}}
+	result := runStartupChecks(context.Background(), provider, state)
+	if result == nil || !reflect.DeepEqual(result.Areas, []string{"nodes"}) || result.Report.Summary.TotalClusters != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+	if state.lastReport != nil {
+		t.Error("a partial startup check should not become the latest report")
+	}
+}
+
+func TestPrintStartupResult(t *testing.T) {
+	var out bytes.Buffer
+	printStartupResult(&out, &startupResult{
+		Areas: []string{"nodes", "pods"},
+		Report: &ClusterReport{CheckAllClustersResult: CheckAllClustersResult{
+			Summary: CheckAllClustersSummary{TotalClusters: 2, Warnings: 1},
+			Issues: []report.Issue{
+				{Severity: report.SeverityWarning, Cluster: "prod", Message: "3/10 pods unhealthy"},
+				{Severity: report.SeverityInfo, Cluster: "prod", Message: "1 pod(s) Pending for less than 2m0s"},
+			},
+		}},
+	})
+	text := out.String()
+	if !strings.Contains(text, "Startup check (nodes, pods) of 2 cluster(s):") || !strings.Contains(text, "prod\033[0m: 3/10 pods unhealthy") {
+		t.Errorf("output = %s", text)
+	}
+	if strings.Contains(text, "Pending") || strings.Contains(text, "No issues") {
+		t.Errorf("informational issues should be left out: %s", text)
+	}
+
+	out.Reset()
+	printStartupResult(&out, nil)
+	if out.Len() != 0 {
+		t.Errorf("output without a startup check = %q", out.String())
+	}
+}
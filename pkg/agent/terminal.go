@@ -0,0 +1,192 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file adapts terminal output to what the terminal renders: ANSI escape
+// codes, and emoji and box drawing characters.
+package agent
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"github.com/chzyer/readline"
+)
+
+// terminal describes what the interactive session's terminal renders.
+type terminal struct {
+	// ansi is set when colour and cursor escape codes are rendered.
+	ansi bool
+	// unicode is set when the terminal uses UTF-8 and shows emoji and box
+	// drawing characters; otherwise they are replaced with ASCII.
+	unicode bool
+	// console is set on a Windows console that needs readline's console
+	// writer to turn escape codes into console API calls.
+	console bool
+}
+
+// asciiOutput is set by setupTerminal when symbols are replaced with ASCII.
+var asciiOutput bool
+
+// detectTerminal works out the terminal from the environment. A dumb
+// terminal gets no escape codes; a locale naming a charset other than UTF-8
+// gets ASCII. Windows consoles are settled by prepareConsole, which can
+// switch them to escape codes and UTF-8; Windows Terminal, VS Code and
+// mintty handle both already.
+func detectTerminal(getenv func(string) string, goos string) terminal {
+	t := terminal{ansi: getenv("TERM") != "dumb", unicode: true}
+	locale := getenv("LC_ALL")
+	if locale == "" {
+		locale = getenv("LC_CTYPE")
+	}
+	if locale == "" {
+		locale = getenv("LANG")
+	}
+	if locale != "" {
+		lower := strings.ToLower(locale)
+		t.unicode = strings.Contains(lower, "utf-8") || strings.Contains(lower, "utf8")
+	}
+	if goos == "windows" && getenv("WT_SESSION") == "" && getenv("TERM_PROGRAM") == "" && getenv("TERM") == "" {
+		t.console = true
+	}
+	return t
+}
+
+// setupTerminal adapts the session's output to the terminal: it applies the
+// named theme, no colours without ANSI support or with NO_COLOR, routes stdout through a filter replacing what the terminal
+// cannot show, and through readline's console writer on Windows consoles
+// without escape code support. The returned function flushes the filter and
+// restores stdout.
+func setupTerminal(theme string) func() {
+	t := prepareConsole(detectTerminal(os.Getenv, runtime.GOOS))
+	applyTheme(resolveTheme(theme, os.Getenv, t.ansi))
+	if !t.unicode {
+		asciiOutput = true
+		spinnerFrames = []string{"|", "/", "-", `\`}
+	}
+	if t.ansi && t.unicode && !t.console {
+		return func() {}
+	}
+
+	var out io.Writer = os.Stdout
+	if t.console {
+		// readline.Stdout wraps the original stdout in a writer turning escape
+		// codes into console calls.
+		out = readline.Stdout
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return func() {}
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = filterTerminal(out, r, !t.unicode, !t.ansi)
+	}()
+	return func() {
+		os.Stdout = stdout
+		_ = w.Close()
+		<-done
+		_ = r.Close()
+	}
+}
+
+// asciiGlyphs are the ASCII stand-ins of the symbols kopilot prints.
+var asciiGlyphs = map[rune]string{
+	'━': "=", '─': "-", '—': "-", '–': "-", '…': "...",
+	'●': "*", '•': "*", '❯': ">", '→': "->", '←': "<-", '↩': "<-", '×': "x",
+	'█': "#", '░': ".",
+	'✅': "[OK]", '✓': "[OK]", '🟢': "[OK]",
+	'❌': "[X]", '✗': "[X]", '🔴': "[X]", '💀': "[X]",
+	'⚠': "[!]", '🟡': "[!]", '🟠': "[!]", '🚨': "[!]",
+	'ℹ': "[i]", '🔵': "[i]",
+	'⏳': "...", '⌛': "...",
+	'👉': "=>",
+	'🔒': "[RO]", '🔓': "[RW]",
+}
+
+// asciiGlyph returns the ASCII stand-in of r: its glyph, "*" for other
+// symbols and pictographs, nothing for joiners and variation selectors. Letters
+// are kept, as their text matters more than their rendering.
+func asciiGlyph(r rune) (string, bool) {
+	if r < 0x80 {
+		return "", false
+	}
+	if s, ok := asciiGlyphs[r]; ok {
+		return s, true
+	}
+	switch {
+	case r == 0xFE0F || r == 0x200D:
+		return "", true
+	case unicode.Is(unicode.So, r) || unicode.Is(unicode.Sm, r):
+		return "*", true
+	}
+	return "", false
+}
+
+// terminalText returns s as the terminal shows it. The prompt goes through
+// it, as readline needs its visible width.
+func terminalText(s string) string {
+	if !asciiOutput {
+		return s
+	}
+	var sb strings.Builder
+	for _, r := range s {
+		if glyph, ok := asciiGlyph(r); ok {
+			sb.WriteString(glyph)
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// filterTerminal copies r to w, replacing non-ASCII symbols when ascii is
+// set and dropping escape codes when stripANSI is set. It writes whenever
+// the input pauses, so prompts show up before the input is read.
+func filterTerminal(w io.Writer, r io.Reader, ascii, stripANSI bool) error {
+	in := bufio.NewReader(r)
+	var out []byte
+	// escape is 1 after ESC and 2 inside a control sequence (ESC [ ...).
+	escape := 0
+	for {
+		c, _, err := in.ReadRune()
+		if err != nil {
+			if len(out) > 0 {
+				_, _ = w.Write(out)
+			}
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		switch {
+		case stripANSI && escape == 1:
+			escape = 0
+			if c == '[' {
+				escape = 2
+			}
+		case stripANSI && escape == 2:
+			if c >= 0x40 && c <= 0x7E {
+				escape = 0
+			}
+		case stripANSI && c == 0x1B:
+			escape = 1
+		default:
+			if s, ok := asciiGlyph(c); ascii && ok {
+				out = append(out, s...)
+			} else {
+				out = append(out, string(c)...)
+			}
+		}
+		if in.Buffered() == 0 && len(out) > 0 {
+			if _, err := w.Write(out); err != nil {
+				return err
+			}
+			out = out[:0]
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package agent
+
+// prepareConsole returns t unchanged: only Windows consoles need preparing.
+func prepareConsole(t terminal) terminal {
+	return t
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDetectTerminal(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		goos string
+		want terminal
+	}{
+		{"utf-8 locale", map[string]string{"TERM": "xterm-256color", "LANG": "en_US.UTF-8"}, "linux", terminal{ansi: true, unicode: true}},
+		{"no locale", map[string]string{"TERM": "xterm"}, "darwin", terminal{ansi: true, unicode: true}},
+		{"latin-1 locale", map[string]string{"TERM": "xterm", "LANG": "de_DE.ISO-8859-1"}, "linux", terminal{ansi: true}},
+		{"LC_ALL wins", map[string]string{"LC_ALL": "C", "LANG": "en_US.utf8"}, "linux", terminal{ansi: true}},
+		{"dumb", map[string]string{"TERM": "dumb", "LANG": "en_US.UTF-8"}, "linux", terminal{unicode: true}},
+		{"windows console", map[string]string{}, "windows", terminal{ansi: true, unicode: true, console: true}},
+		{"windows terminal", map[string]string{"WT_SESSION": "1"}, "windows", terminal{ansi: true, unicode: true}},
+		{"mintty", map[string]string{"TERM": "xterm"}, "windows", terminal{ansi: true, unicode: true}},
+	}
+	for _, tt := range tests {
+		got := detectTerminal(func(k string) string { return tt.env[k] }, tt.goos)
+		if got != tt.want {
+			t.Errorf("%s: detectTerminal = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterTerminal(t *testing.T) {
+	input := "\r\033[K\033[36m✅ prod\033[0m: ⚠️ 2 issue(s) — café →\n"
+	tests := []struct {
+		name             string
+		ascii, stripANSI bool
+		want             string
+	}{
+		{"unchanged", false, false, input},
+		{"ascii", true, false, "\r\033[K\033[36m[OK] prod\033[0m: [!] 2 issue(s) - café ->\n"},
+		{"no ansi", false, true, "\r✅ prod: ⚠️ 2 issue(s) — café →\n"},
+		{"both", true, true, "\r[OK] prod: [!] 2 issue(s) - café ->\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := filterTerminal(&out, strings.NewReader(input), tt.ascii, tt.stripANSI); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, out.String(), tt.want)
+		}
+	}
+}
+
+func TestTerminalText(t *testing.T) {
+	prompt := "[prod ✗ 2 issue(s)] ❯ "
+	if got := terminalText(prompt); got != prompt {
+		t.Errorf("terminalText = %q, want it unchanged", got)
+	}
+	asciiOutput = true
+	defer func() { asciiOutput = false }()
+	if got, want := terminalText(prompt), "[prod [X] 2 issue(s)] > "; got != want {
+		t.Errorf("terminalText = %q, want %q", got, want)
+	}
+	if got := terminalText("🧹 done"); got != "* done" {
+		t.Errorf("terminalText = %q, want other symbols as *", got)
+	}
+}
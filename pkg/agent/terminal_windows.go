@@ -0,0 +1,37 @@
+//go:build windows
+
+package agent
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// utf8CodePage is the Windows code page number of UTF-8.
+const utf8CodePage = 65001
+
+// prepareConsole switches a Windows console to escape code processing and
+// the UTF-8 code page, so colours, cursor movement and non-English replies
+// render. Consoles that cannot process escape codes (before Windows 10) keep
+// console set and are written through readline's console writer. Console
+// fonts lack emoji, so symbols are replaced with ASCII.
+func prepareConsole(t terminal) terminal {
+	if !t.console {
+		return t
+	}
+	handle := windows.Handle(os.Stdout.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		// Not a console: output is redirected or goes to a terminal emulator.
+		t.console = false
+		return t
+	}
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 ||
+		windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil {
+		t.console = false
+	}
+	_ = windows.SetConsoleOutputCP(utf8CodePage)
+	t.unicode = false
+	return t
+}
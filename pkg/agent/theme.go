@@ -0,0 +1,91 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the colour themes of the interactive session.
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Theme names. ThemeNone prints no escape codes; it is used for NO_COLOR,
+// --no-color and terminals without ANSI support.
+const (
+	ThemeDark    = "dark"
+	ThemeLight   = "light"
+	ThemeMinimal = "minimal"
+	ThemeNone    = "none"
+)
+
+// Theme holds the escape codes of each colour role; an empty code prints
+// nothing.
+type Theme struct {
+	Reset string
+	// Error marks failures and critical issues, Success confirmations and
+	// healthy results, Warning warnings and read-only mode, and Accent names,
+	// commands and headings.
+	Error   string
+	Success string
+	Warning string
+	Accent  string
+	Bold    string
+	Dim     string
+	// Input colours the text being typed at the prompt.
+	Input string
+}
+
+// themes are the built-in themes. dark, the default, suits dark terminal
+// backgrounds; light swaps the colours hard to read on white; minimal keeps
+// only bold and dim.
+var themes = map[string]Theme{
+	ThemeDark: {
+		Reset: "\033[0m", Error: "\033[31m", Success: "\033[32m", Warning: "\033[33m", Accent: "\033[36m",
+		Bold: "\033[1m", Dim: "\033[2m", Input: "\033[38;2;6;182;212m", // Cyan (#06b6d4), matching kopilot website
+	},
+	ThemeLight: {
+		Reset: "\033[0m", Error: "\033[31m", Success: "\033[32m", Warning: "\033[38;5;130m", Accent: "\033[34m",
+		Bold: "\033[1m", Dim: "\033[38;5;244m", Input: "\033[38;2;8;145;178m", // Dark cyan (#0891b2)
+	},
+	ThemeMinimal: {
+		Reset: "\033[0m", Error: "\033[1m", Warning: "\033[1m", Bold: "\033[1m", Dim: "\033[2m",
+	},
+	ThemeNone: {},
+}
+
+// ParseTheme validates a theme name; empty selects the default dark theme.
+func ParseTheme(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return ThemeDark, nil
+	}
+	if _, ok := themes[value]; !ok {
+		return "", fmt.Errorf("unknown theme %q (use %s, %s, %s or %s)", value, ThemeDark, ThemeLight, ThemeMinimal, ThemeNone)
+	}
+	return value, nil
+}
+
+// WithTheme selects the colour theme of the interactive session. NO_COLOR
+// in the environment overrides it with ThemeNone.
+func WithTheme(name string) Option {
+	return func(s *agentState) {
+		s.theme = name
+	}
+}
+
+// resolveTheme returns the theme to use: none when NO_COLOR is set to any
+// value (see no-color.org) or the terminal lacks ANSI support, otherwise the
+// named theme, dark when unset or unknown.
+func resolveTheme(name string, getenv func(string) string, ansi bool) Theme {
+	if getenv("NO_COLOR") != "" || !ansi {
+		return themes[ThemeNone]
+	}
+	if theme, ok := themes[name]; ok {
+		return theme
+	}
+	return themes[ThemeDark]
+}
+
+// applyTheme sets the colour codes printed by the session.
+func applyTheme(t Theme) {
+	colorReset, colorBold, colorDim, colorUserInput = t.Reset, t.Bold, t.Dim, t.Input
+	colorRed, colorGreen, colorYellow, colorCyan = t.Error, t.Success, t.Warning, t.Accent
+}
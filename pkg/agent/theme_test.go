@@ -0,0 +1,53 @@
+package agent
+
+import "testing"
+
+func TestParseTheme(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", ThemeDark, false},
+		{"Light", ThemeLight, false},
+		{" minimal ", ThemeMinimal, false},
+		{"none", ThemeNone, false},
+		{"solarized", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTheme(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseTheme(%q) = %q, %v; want %q, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestResolveTheme(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+	if got := resolveTheme(ThemeLight, env(nil), true); got != themes[ThemeLight] {
+		t.Errorf("light theme = %+v", got)
+	}
+	if got := resolveTheme("", env(nil), true); got != themes[ThemeDark] {
+		t.Errorf("default theme = %+v, want dark", got)
+	}
+	if got := resolveTheme(ThemeLight, env(map[string]string{"NO_COLOR": "1"}), true); got != (Theme{}) {
+		t.Errorf("NO_COLOR theme = %+v, want no colours", got)
+	}
+	if got := resolveTheme(ThemeDark, env(nil), false); got != (Theme{}) {
+		t.Errorf("non-ANSI theme = %+v, want no colours", got)
+	}
+}
+
+func TestApplyTheme(t *testing.T) {
+	defer applyTheme(themes[ThemeDark])
+	applyTheme(themes[ThemeNone])
+	if colorRed != "" || colorReset != "" || colorUserInput != "" {
+		t.Errorf("colours after the none theme: %q %q %q", colorRed, colorReset, colorUserInput)
+	}
+	applyTheme(themes[ThemeLight])
+	if colorCyan != themes[ThemeLight].Accent || colorYellow != themes[ThemeLight].Warning {
+		t.Errorf("colours after the light theme: %q %q", colorCyan, colorYellow)
+	}
+}
@@ -0,0 +1,140 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file tracks the model requests and tokens of the run per model, from
+// the providers' usage events, for /usage and the summary printed on exit.
+package agent
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// unknownModel names usage whose provider did not say which model served it.
+const unknownModel = "(unknown model)"
+
+// modelUsage is what one model consumed since kopilot started. Unlike the
+// turn counters it survives /clear, /compact and /provider: the tokens were
+// spent all the same.
+type modelUsage struct {
+	model        string
+	requests     int
+	inputTokens  int64
+	outputTokens int64
+	cachedTokens int64
+	cost         float64
+}
+
+// add accumulates o into u.
+func (u *modelUsage) add(o modelUsage) {
+	u.requests += o.requests
+	u.inputTokens += o.inputTokens
+	u.outputTokens += o.outputTokens
+	u.cachedTokens += o.cachedTokens
+	u.cost += o.cost
+}
+
+// recordModelUsage counts the model request reported by a usage event. It
+// runs on the session's event goroutine.
+func (s *agentState) recordModelUsage(d *llm.UsageData) {
+	model := d.Model
+	if model == "" {
+		model = unknownModel
+	}
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+	if s.modelUsage == nil {
+		s.modelUsage = map[string]*modelUsage{}
+	}
+	u, ok := s.modelUsage[model]
+	if !ok {
+		u = &modelUsage{model: model}
+		s.modelUsage[model] = u
+	}
+	u.add(modelUsage{
+		requests:     1,
+		inputTokens:  d.InputTokens,
+		outputTokens: d.OutputTokens,
+		cachedTokens: d.CachedTokens,
+		cost:         d.Cost,
+	})
+}
+
+// usageByModel returns a copy of the usage per model, sorted by model, and
+// the total.
+func (s *agentState) usageByModel() ([]modelUsage, modelUsage) {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+	rows := make([]modelUsage, 0, len(s.modelUsage))
+	var total modelUsage
+	for _, u := range s.modelUsage {
+		rows = append(rows, *u)
+		total.add(*u)
+	}
+	slices.SortFunc(rows, func(a, b modelUsage) int {
+		switch {
+		case a.model < b.model:
+			return -1
+		case a.model > b.model:
+			return 1
+		}
+		return 0
+	})
+	return rows, total
+}
+
+// formatTokens shortens a token count: 950, 12.3k, 1.2M.
+func formatTokens(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+// describeModelUsage summarises u on one line.
+func describeModelUsage(u modelUsage) string {
+	noun := "requests"
+	if u.requests == 1 {
+		noun = "request"
+	}
+	line := fmt.Sprintf("%d %s, %s in / %s out tokens", u.requests, noun, formatTokens(u.inputTokens), formatTokens(u.outputTokens))
+	if u.cachedTokens > 0 {
+		line += fmt.Sprintf(" (%s cached)", formatTokens(u.cachedTokens))
+	}
+	if u.cost > 0 {
+		line += fmt.Sprintf(", cost %.2f", u.cost)
+	}
+	return line
+}
+
+// printModelUsage prints the usage per model, for /usage.
+func printModelUsage(state *agentState) {
+	rows, total := state.usageByModel()
+	if len(rows) == 0 {
+		fmt.Printf("  Tokens:         none reported yet\n")
+		return
+	}
+	fmt.Printf("  Tokens:         %s\n", describeModelUsage(total))
+	for _, u := range rows {
+		fmt.Printf("    %s%-24s%s %s\n", colorDim, u.model, colorReset, describeModelUsage(u))
+	}
+}
+
+// printExitSummary prints the run's model usage as kopilot exits; nothing
+// when no model request was made.
+func printExitSummary(state *agentState) {
+	rows, total := state.usageByModel()
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Printf("  %sSession summary: %s%s\n", colorDim, describeModelUsage(total), colorReset)
+	if len(rows) > 1 {
+		for _, u := range rows {
+			fmt.Printf("    %s%-24s %s%s\n", colorDim, u.model, describeModelUsage(u), colorReset)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+func TestRecordModelUsage(t *testing.T) {
+	state := &agentState{}
+	onUsageEvent(llm.Event{Type: llm.EventUsage, Data: &llm.UsageData{QuotaPercentage: -1, Model: "gpt-4.1", InputTokens: 1000, OutputTokens: 50, CachedTokens: 800, Cost: 1}}, state)
+	onUsageEvent(llm.Event{Type: llm.EventUsage, Data: &llm.UsageData{QuotaPercentage: -1, Model: "gpt-4.1", InputTokens: 500, OutputTokens: 25, Cost: 1}}, state)
+	onUsageEvent(llm.Event{Type: llm.EventUsage, Data: &llm.UsageData{QuotaPercentage: -1, Model: "gpt-4o-mini", InputTokens: 200, OutputTokens: 10}}, state)
+	onUsageEvent(llm.Event{Type: llm.EventUsage, Data: &llm.UsageData{QuotaPercentage: -1}}, state)
+
+	rows, total := state.usageByModel()
+	if len(rows) != 3 {
+		t.Fatalf("rows = %+v, want 3 models", rows)
+	}
+	if rows[0].model != unknownModel || rows[1].model != "gpt-4.1" || rows[2].model != "gpt-4o-mini" {
+		t.Errorf("rows not sorted by model: %+v", rows)
+	}
+	want := modelUsage{model: "gpt-4.1", requests: 2, inputTokens: 1500, outputTokens: 75, cachedTokens: 800, cost: 2}
+	if rows[1] != want {
+		t.Errorf("gpt-4.1 = %+v, want %+v", rows[1], want)
+	}
+	if total.requests != 4 || total.inputTokens != 1700 || total.outputTokens != 85 {
+		t.Errorf("total = %+v", total)
+	}
+	if state.quotaPercentage != 0 {
+		t.Errorf("usage without a quota snapshot changed the quota to %v", state.quotaPercentage)
+	}
+}
+
+func TestFormatTokens(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0"},
+		{950, "950"},
+		{12345, "12.3k"},
+		{1_250_000, "1.2M"},
+	}
+	for _, tt := range tests {
+		if got := formatTokens(tt.n); got != tt.want {
+			t.Errorf("formatTokens(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestDescribeModelUsage(t *testing.T) {
+	tests := []struct {
+		u    modelUsage
+		want string
+	}{
+		{modelUsage{requests: 1, inputTokens: 900, outputTokens: 40}, "1 request, 900 in / 40 out tokens"},
+		{modelUsage{requests: 3, inputTokens: 4200, outputTokens: 300, cachedTokens: 2000, cost: 3}, "3 requests, 4.2k in / 300 out tokens (2.0k cached), cost 3.00"},
+	}
+	for _, tt := range tests {
+		if got := describeModelUsage(tt.u); got != tt.want {
+			t.Errorf("describeModelUsage(%+v) = %q, want %q", tt.u, got, tt.want)
+		}
+	}
+}
+
+func TestPrintModelUsage(t *testing.T) {
+	state := &agentState{}
+	printModelUsage(state)
+	printExitSummary(state)
+	state.recordModelUsage(&llm.UsageData{Model: "gpt-4.1", InputTokens: 10})
+	state.recordModelUsage(&llm.UsageData{Model: "gpt-4o-mini", InputTokens: 10})
+	printModelUsage(state)
+	printExitSummary(state)
+}
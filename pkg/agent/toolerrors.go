@@ -0,0 +1,88 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file turns the errors of tool calls into llm.ToolError values, so the
+// model receives a code, a recovery hint and whether retrying may help
+// instead of a bare Go error string.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// contextNotFoundMessage matches the errors of kopilot and kubectl for a
+// context missing from the kubeconfig: `cluster context "prod" not found`,
+// `context prod not found`, `context "prod" does not exist` and kubectl's
+// `context was not found for specified context: prod`.
+var contextNotFoundMessage = regexp.MustCompile(`\bcontext\s+\S+\s+(?:not found|does not exist)\b|\bcontext was not found for specified context\b`)
+
+// withToolErrors returns the errors of every tool as llm.ToolError values;
+// errors that already are one pass through unchanged.
+func withToolErrors(tools []llm.Tool, k8sProvider *k8s.Provider) []llm.Tool {
+	for i := range tools {
+		handler := tools[i].Handler
+		tools[i].Handler = func(params any, inv llm.ToolInvocation) (any, error) {
+			result, err := handler(params, inv)
+			if err != nil {
+				return result, classifyToolError(err, k8sProvider)
+			}
+			return result, nil
+		}
+	}
+	return tools
+}
+
+// contextNotFoundError reports that contextName is not in the kubeconfig.
+func contextNotFoundError(k8sProvider *k8s.Provider, contextName string) *llm.ToolError {
+	return &llm.ToolError{
+		Code:    llm.ToolErrorContextNotFound,
+		Message: fmt.Sprintf("cluster not found - context '%s' does not exist in your kubeconfig", contextName),
+		Hint:    "call again with one of the available contexts: " + getAvailableContexts(k8sProvider),
+	}
+}
+
+// classifyToolError returns err as an llm.ToolError with the code and hint
+// of its cause.
+func classifyToolError(err error, k8sProvider *k8s.Provider) *llm.ToolError {
+	var toolErr *llm.ToolError
+	if errors.As(err, &toolErr) {
+		return toolErr
+	}
+	e := &llm.ToolError{Code: llm.ToolErrorFailed, Message: err.Error(), Err: err}
+	msg := err.Error()
+	switch {
+	case errors.Is(err, context.Canceled):
+		e.Code, e.Hint = llm.ToolErrorCancelled, "the user interrupted the call; do not retry it unless they ask"
+	case errors.Is(err, errToolBudgetExceeded), errors.Is(err, context.DeadlineExceeded):
+		e.Code, e.Retriable = llm.ToolErrorTimeout, true
+		e.Hint = "retry once, or narrow the call (e.g. a namespace or label selector) so it finishes in time"
+	case errors.Is(err, exec.ErrNotFound):
+		e.Code = llm.ToolErrorNotInstalled
+		e.Hint = "a program the tool runs (e.g. kubectl) is not installed or not in PATH; tell the user to install it, do not retry"
+	case contextNotFoundMessage.MatchString(msg):
+		e.Code = llm.ToolErrorContextNotFound
+		e.Hint = "call again with one of the available contexts: " + getAvailableContexts(k8sProvider)
+	case apierrors.IsUnauthorized(err), strings.Contains(msg, "getting credentials"), strings.Contains(msg, "Error from server (Unauthorized)"):
+		e.Code = llm.ToolErrorUnauthorized
+		e.Hint = "the cluster rejected the credentials or the login expired; tell the user to run /reauth for the context, do not retry"
+	case apierrors.IsForbidden(err), strings.Contains(msg, "Error from server (Forbidden)"):
+		e.Code = llm.ToolErrorForbidden
+		e.Hint = "the user's RBAC role does not allow this; tell the user which permission is missing, do not retry"
+	case apierrors.IsNotFound(err), strings.Contains(msg, "Error from server (NotFound)"):
+		e.Code = llm.ToolErrorNotFound
+		e.Hint = "check the name and namespace, e.g. list the resources of the namespace first, then call again"
+	case errors.Is(err, errNoJobs):
+		e.Code, e.Hint = llm.ToolErrorInvalidArguments, "call again without background"
+	case isTransientFailure(nil, err):
+		e.Code, e.Retriable = llm.ToolErrorUnavailable, true
+		e.Hint = "the cluster is busy or briefly unreachable; retry shortly"
+	}
+	return e
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/llm"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestClassifyToolError(t *testing.T) {
+	provider := newTestK8sProvider(t)
+	pods := schema.GroupResource{Resource: "pods"}
+	tests := []struct {
+		name      string
+		err       error
+		code      string
+		retriable bool
+	}{
+		{"plain", errors.New("boom"), llm.ToolErrorFailed, false},
+		{"cancelled", fmt.Errorf("list pods: %w", context.Canceled), llm.ToolErrorCancelled, false},
+		{"budget", fmt.Errorf("check_dns: %w", errToolBudgetExceeded), llm.ToolErrorTimeout, true},
+		{"context", fmt.Errorf(`cluster context %q not found`, "prod"), llm.ToolErrorContextNotFound, false},
+		{"unquoted context", errors.New("context prod not found"), llm.ToolErrorContextNotFound, false},
+		{"unauthorized", apierrors.NewUnauthorized("token expired"), llm.ToolErrorUnauthorized, false},
+		{"forbidden", apierrors.NewForbidden(pods, "web-1", errors.New("no RBAC")), llm.ToolErrorForbidden, false},
+		{"not found", apierrors.NewNotFound(pods, "web-1"), llm.ToolErrorNotFound, false},
+		{"other not found", errors.New("MCP server \"docs\" not found"), llm.ToolErrorFailed, false},
+		{"busy", apierrors.NewTooManyRequests("slow down", 1), llm.ToolErrorUnavailable, true},
+		{"background", errNoJobs, llm.ToolErrorInvalidArguments, false},
+	}
+	for _, tt := range tests {
+		e := classifyToolError(tt.err, provider)
+		if e.Code != tt.code || e.Retriable != tt.retriable || e.Message != tt.err.Error() {
+			t.Errorf("%s: classifyToolError = %+v, want code %s, retriable %t", tt.name, e, tt.code, tt.retriable)
+		}
+		if tt.code != llm.ToolErrorFailed && e.Hint == "" {
+			t.Errorf("%s: no hint", tt.name)
+		}
+		if !errors.Is(e, tt.err) {
+			t.Errorf("%s: the ToolError does not wrap the error", tt.name)
+		}
+	}
+
+	structured := &llm.ToolError{Code: llm.ToolErrorInvalidArguments, Message: "bad"}
+	if e := classifyToolError(fmt.Errorf("wrapped: %w", structured), provider); e != structured {
+		t.Errorf("a ToolError was reclassified: %+v", e)
+	}
+	if e := contextNotFoundError(provider, "prod"); e.Code != llm.ToolErrorContextNotFound || !strings.Contains(e.Hint, "test-context") {
+		t.Errorf("contextNotFoundError = %+v, want the available contexts in the hint", e)
+	}
+}
+
+func TestWithToolErrors(t *testing.T) {
+	tools := withToolErrors([]llm.Tool{
+		{Name: "ok", Handler: func(any, llm.ToolInvocation) (any, error) { return "fine", nil }},
+		{Name: "fail", Handler: func(any, llm.ToolInvocation) (any, error) { return nil, errNoJobs }},
+	}, newTestK8sProvider(t))
+	if out, err := tools[0].Handler(nil, llm.ToolInvocation{}); out != "fine" || err != nil {
+		t.Errorf("ok = %v, %v", out, err)
+	}
+	_, err := tools[1].Handler(nil, llm.ToolInvocation{})
+	var toolErr *llm.ToolError
+	if !errors.As(err, &toolErr) || toolErr.Code != llm.ToolErrorInvalidArguments {
+		t.Errorf("fail = %v, want an invalid_arguments ToolError", err)
+	}
+}
+
+func TestClassifyKubectlExecErrors(t *testing.T) {
+	provider := newTestK8sProvider(t)
+	originalRunner := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = originalRunner })
+
+	tests := []struct {
+		name   string
+		output string
+		err    error
+		code   string
+	}{
+		{"object missing", `Error from server (NotFound): pods "web-1" not found`, errors.New("exit status 1"), llm.ToolErrorNotFound},
+		{"context missing", "error: context was not found for specified context: prod", errors.New("exit status 1"), llm.ToolErrorContextNotFound},
+		{"forbidden", `Error from server (Forbidden): pods "web-1" is forbidden: User "dev" cannot get resource "pods"`, errors.New("exit status 1"), llm.ToolErrorForbidden},
+		{"other failure", "error: the server doesn't have a resource type \"pod\"", errors.New("exit status 1"), llm.ToolErrorFailed},
+		{"no kubectl", "", fmt.Errorf("kubectl not found in PATH: %w", &exec.Error{Name: "kubectl", Err: exec.ErrNotFound}), llm.ToolErrorNotInstalled},
+	}
+	for _, tt := range tests {
+		runKubectlCommandFunc = func(context.Context, []string) ([]byte, error) {
+			return []byte(tt.output), tt.err
+		}
+		for _, format := range []OutputFormat{OutputText, OutputJSON} {
+			state := &agentState{mode: ModeInteractive, outputFormat: format}
+			_, err := handleKubectlExec(context.Background(), provider, state, KubectlExecParams{Context: "test-context", Args: []string{"get", "pod", "web-1"}})
+			if err == nil {
+				t.Fatalf("%s: handleKubectlExec succeeded", tt.name)
+			}
+			e := classifyToolError(err, provider)
+			if e.Code != tt.code || !strings.Contains(e.Message, tt.output) {
+				t.Errorf("%s (%s): classified as %s with message %q, want %s quoting kubectl", tt.name, format, e.Code, e.Message, tt.code)
+			}
+		}
+	}
+}
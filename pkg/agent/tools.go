@@ -12,11 +12,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/e9169/kopilot/pkg/debug"
 	"github.com/e9169/kopilot/pkg/k8s"
 	"github.com/e9169/kopilot/pkg/llm"
+	"github.com/e9169/kopilot/pkg/report"
+	"github.com/e9169/kopilot/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// defineK8sTools returns the 6 Kubernetes operational tools.
+// defineK8sTools returns the Kubernetes operational tools.
 // Used by both the interactive REPL mode and --mcp-server mode.
 func defineK8sTools(k8sProvider *k8s.Provider, state *agentState) []llm.Tool {
 	tools := []llm.Tool{
@@ -26,14 +30,55 @@ func defineK8sTools(k8sProvider *k8s.Provider, state *agentState) []llm.Tool {
 		defineCheckAllClustersTool(k8sProvider, state),
 		defineKubectlExecTool(k8sProvider, state),
 		defineSanitizeClusterTool(k8sProvider, state),
+		defineCheckGPUsTool(k8sProvider, state),
+		defineCheckNodeOSTool(k8sProvider, state),
+		defineCheckImageArchTool(k8sProvider, state),
+		defineMigrateNamespaceTool(k8sProvider, state),
+		defineGetResourceYAMLTool(k8sProvider, state),
+		defineApplyResourceYAMLTool(k8sProvider, state),
+		defineValidateManifestTool(k8sProvider, state),
+		defineRecommendResourcesTool(k8sProvider, state),
+		defineResilienceReportTool(k8sProvider, state),
+		defineCheckConnectivityTool(k8sProvider, state),
+		defineCheckDNSTool(k8sProvider, state),
+		defineBulkLabelTool(k8sProvider, state),
+		defineReviewSATokensTool(k8sProvider, state),
+		defineGetQuotaUsageTool(k8sProvider, state),
+		defineRankEventNoiseTool(k8sProvider, state),
+		defineClusterCapacityTool(k8sProvider, state),
+		defineRecentChangesTool(k8sProvider, state),
+		defineImageProvenanceTool(k8sProvider, state),
+		defineDependencyMapTool(k8sProvider, state),
+		defineGenerateReportTool(k8sProvider, state),
+		defineGetHealthHistoryTool(state),
+		defineQueryPrometheusTool(k8sProvider, state),
+		defineQueryLogsTool(k8sProvider, state),
+		defineGetGitOpsStatusTool(k8sProvider, state),
+		defineDiffWorkloadsTool(k8sProvider, state),
+		defineGetCustomResourcesTool(k8sProvider, state),
+		defineBulkDeleteTool(k8sProvider, state),
+		defineExportResourcesTool(k8sProvider, state),
+		defineCheckIngressTLSTool(k8sProvider, state),
+		defineCheckDisruptionSafetyTool(k8sProvider, state),
+		defineDrainNodeTool(k8sProvider, state),
+		defineCheckQuotaFairnessTool(k8sProvider, state),
+		defineRestartWorkloadTool(k8sProvider, state),
+		defineRolloutStatusTool(k8sProvider, state),
+		defineCheckJobsTool(k8sProvider, state),
+		defineCheckAutoscalingTool(k8sProvider, state),
+		defineCheckWebhooksTool(k8sProvider, state),
+		defineAgentInfoTool(k8sProvider, state),
+		defineWatchResourceTool(k8sProvider, state),
 	}
 	for i := range tools {
-		tools[i] = fixEmptySchema(tools[i])
+		tools[i] = fixEmptySchema(withTimeBudget(tools[i], k8sProvider.APITimeout(), k8sProvider.StatusBudget()))
 	}
 	return tools
 }
 
-// defineTools returns all 9 tools: the 6 K8s tools plus the 3 MCP management tools.
+// defineTools returns the K8s tools plus the MCP management tools and the
+// session-scoped tools (undo_last_operation, execute_plan, port_forward,
+// exec_in_pod, chaos).
 // Used by the interactive REPL mode only.
 func defineTools(k8sProvider *k8s.Provider, state *agentState) []llm.Tool {
 	tools := defineK8sTools(k8sProvider, state)
@@ -41,6 +86,11 @@ func defineTools(k8sProvider *k8s.Provider, state *agentState) []llm.Tool {
 		defineMCPListServersTool(state),
 		defineMCPAddServerTool(state),
 		defineMCPDeleteServerTool(state),
+		defineUndoLastOperationTool(k8sProvider, state),
+		defineExecutePlanTool(k8sProvider, state),
+		definePortForwardTool(k8sProvider, state),
+		defineExecInPodTool(k8sProvider, state),
+		defineChaosTool(k8sProvider, state),
 	}
 	for i := range mcpTools {
 		mcpTools[i] = fixEmptySchema(mcpTools[i])
@@ -114,15 +164,42 @@ func defineListClustersTool(k8sProvider *k8s.Provider, state *agentState) llm.To
 
 // GetClusterStatusParams defines parameters for get_cluster_status
 type GetClusterStatusParams struct {
-	Context string `json:"context" jsonschema:"The context name of the cluster to query (from list_clusters)"`
+	Context       string `json:"context" jsonschema:"The context name of the cluster to query (from list_clusters)"`
+	Namespace     string `json:"namespace,omitempty" jsonschema:"Only check the pods of this namespace"`
+	LabelSelector string `json:"label_selector,omitempty" jsonschema:"Only check the pods matching this label selector, e.g. app=payments"`
+	FieldSelector string `json:"field_selector,omitempty" jsonschema:"Only check the pods matching this field selector, e.g. status.phase!=Running or spec.nodeName=node-1"`
+	NodeSelector  string `json:"node_selector,omitempty" jsonschema:"Only check the nodes matching this label selector, e.g. node-role.kubernetes.io/worker"`
+}
+
+// healthFilter returns the node and pod scope of the parameters.
+func (params GetClusterStatusParams) healthFilter() k8s.HealthFilter {
+	return k8s.HealthFilter{
+		Namespace:     params.Namespace,
+		LabelSelector: params.LabelSelector,
+		FieldSelector: params.FieldSelector,
+		NodeSelector:  params.NodeSelector,
+	}
 }
 
 // writeUnreachableClusterStatus writes status for an unreachable cluster
 func writeUnreachableClusterStatus(result *strings.Builder, status *k8s.ClusterStatus) {
-	fmt.Fprintf(result, "❌ %s - DOWN (%s)\n", status.Context, status.Server)
+	if status.Transient {
+		fmt.Fprintf(result, "⏳ %s - BUSY (%s)\n", status.Context, status.Server)
+	} else {
+		fmt.Fprintf(result, "❌ %s - DOWN (%s)\n", status.Context, status.Server)
+	}
 	if status.Error != "" {
 		fmt.Fprintf(result, "   Issue: %s\n", status.Error)
 	}
+	writeFailureAdvice(result, status)
+}
+
+// writeFailureAdvice writes the classified cause of a failed check and how
+// to fix it, when known.
+func writeFailureAdvice(result *strings.Builder, status *k8s.ClusterStatus) {
+	if status.Advice != "" {
+		fmt.Fprintf(result, "   💡 %s: %s\n", strings.ReplaceAll(string(status.Failure), "_", " "), status.Advice)
+	}
 }
 
 // writeClusterInfo writes basic cluster information
@@ -138,9 +215,49 @@ func writeClusterInfo(result *strings.Builder, status *k8s.ClusterStatus) {
 	result.WriteString("\n")
 }
 
+// maxStatusUnhealthyPods caps the unhealthy pods listed by get_cluster_status.
+const maxStatusUnhealthyPods = 10
+
+// writePodInfo writes the pod health of a cluster status scoped by a filter;
+// unscoped statuses leave pods to check_all_clusters and the triage tools.
+func writePodInfo(result *strings.Builder, status *k8s.ClusterStatus) {
+	if status.Filter.IsZero() {
+		return
+	}
+	fmt.Fprintf(result, "Pods (%s): %d total, %d healthy\n", status.Filter, status.PodCount, status.HealthyPods)
+	if status.PodsTruncated {
+		fmt.Fprintf(result, "⚠️  Stopped after %d pods; counts cover only those\n", status.PodCount)
+	}
+	for i, pod := range status.UnhealthyPods {
+		if i == maxStatusUnhealthyPods {
+			fmt.Fprintf(result, "  ... and %d more\n", len(status.UnhealthyPods)-i)
+			break
+		}
+		fmt.Fprintf(result, "  ❌ %s/%s: %s", pod.Namespace, pod.Name, pod.Status)
+		if pod.Reason != "" {
+			fmt.Fprintf(result, " (%s)", pod.Reason)
+		}
+		if pod.Restarts > 0 {
+			fmt.Fprintf(result, ", %d restart(s)", pod.Restarts)
+		}
+		result.WriteString("\n")
+	}
+	result.WriteString("\n")
+}
+
 // writeNodeInfo writes node information for a cluster
 func writeNodeInfo(result *strings.Builder, status *k8s.ClusterStatus) {
-	fmt.Fprintf(result, "Nodes: %d total, %d healthy\n", status.NodeCount, status.HealthyNodes)
+	if status.Filter.NodeSelector != "" {
+		fmt.Fprintf(result, "Nodes (%s): %d total, %d healthy\n", status.Filter.NodeSelector, status.NodeCount, status.HealthyNodes)
+	} else {
+		fmt.Fprintf(result, "Nodes: %d total, %d healthy\n", status.NodeCount, status.HealthyNodes)
+	}
+	if len(status.OSDistribution) > 1 {
+		fmt.Fprintf(result, "⚠️  Mixed-OS cluster: %s (use check_node_os to find mis-scheduled pods)\n", k8s.FormatDistribution(status.OSDistribution))
+	}
+	if len(status.ArchDistribution) > 1 {
+		fmt.Fprintf(result, "ℹ️  Mixed-architecture cluster: %s (use check_image_arch to find single-arch images)\n", k8s.FormatDistribution(status.ArchDistribution))
+	}
 	if len(status.Nodes) > 0 {
 		result.WriteString("\nNode Details:\n")
 		for _, node := range status.Nodes {
@@ -150,8 +267,119 @@ func writeNodeInfo(result *strings.Builder, status *k8s.ClusterStatus) {
 			}
 			roles := strings.Join(node.Roles, ", ")
 			fmt.Fprintf(result, "  %s %s\n", statusIcon, node.Name)
-			fmt.Fprintf(result, "     Status: %s | Roles: %s | Age: %s\n", node.Status, roles, node.Age)
+			fmt.Fprintf(result, "     Status: %s | Roles: %s | OS: %s/%s | Age: %s\n", node.Status, roles, node.OS, node.Arch, node.Age)
+			if details := nodeDetails(node); details != "" {
+				fmt.Fprintf(result, "     %s\n", details)
+			}
+		}
+	}
+	for _, skew := range status.KubeletSkew {
+		fmt.Fprintf(result, "⚠️  Version skew: node %s runs kubelet %s, %d minor version(s) %s API server %s\n",
+			skew.Node, skew.KubeletVersion, absInt(skew.MinorVersions), skewDirection(skew.MinorVersions), skew.APIServerVersion)
+	}
+	result.WriteString("\n")
+}
+
+// nodeDetails is the kubelet, runtime, OS image, kernel and addresses of node.
+func nodeDetails(node k8s.NodeInfo) string {
+	var parts []string
+	for _, f := range []struct{ label, value string }{
+		{"Kubelet", node.KubeletVersion},
+		{"Runtime", node.ContainerRuntime},
+		{"Image", node.OSImage},
+		{"Kernel", node.KernelVersion},
+		{"Internal IP", node.InternalIP},
+		{"External IP", node.ExternalIP},
+	} {
+		if f.value != "" {
+			parts = append(parts, f.label+": "+f.value)
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+// skewDirection describes the sign of a kubelet minor version skew.
+func skewDirection(minorVersions int) string {
+	if minorVersions < 0 {
+		return "behind"
+	}
+	return "ahead of"
+}
+
+// absInt returns the absolute value of n.
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// writeExposureInfo writes Ingresses and LoadBalancer Services with problems
+func writeExposureInfo(result *strings.Builder, status *k8s.ClusterStatus) {
+	if len(status.ExposureIssues) == 0 {
+		return
+	}
+	fmt.Fprintf(result, "⚠️  Exposed endpoints with problems (%d):\n", len(status.ExposureIssues))
+	for _, issue := range status.ExposureIssues {
+		fmt.Fprintf(result, "  ❌ %s/%s/%s: %s\n", issue.Namespace, issue.Kind, issue.Name, issue.Problem)
+	}
+	result.WriteString("\n")
+}
+
+// writeControlPlaneInfo writes the control-plane components, when visible
+func writeControlPlaneInfo(result *strings.Builder, status *k8s.ClusterStatus) {
+	if len(status.ControlPlane) == 0 {
+		return
+	}
+	result.WriteString("🧠 Control Plane:\n")
+	for _, c := range status.ControlPlane {
+		if c.Healthy {
+			fmt.Fprintf(result, "  ✅ %s (%d/%d)\n", c.Name, c.Ready, c.Total)
+		} else {
+			fmt.Fprintf(result, "  ❌ %s: %s\n", c.Name, c.Message)
+		}
+	}
+	result.WriteString("\n")
+}
+
+// writeAutoscalingInfo writes HorizontalPodAutoscalers with problems
+func writeAutoscalingInfo(result *strings.Builder, status *k8s.ClusterStatus) {
+	if len(status.AutoscalingIssues) == 0 {
+		return
+	}
+	fmt.Fprintf(result, "⚠️  Autoscalers with problems (%d, use check_autoscaling for details):\n", len(status.AutoscalingIssues))
+	for _, issue := range status.AutoscalingIssues {
+		fmt.Fprintf(result, "  ❌ %s/%s (%s) %s: %s\n", issue.Namespace, issue.Name, issue.Target, issue.Issue, issue.Message)
+	}
+	result.WriteString("\n")
+}
+
+// formatMoney renders an amount with its currency code.
+func formatMoney(amount float64, currency string) string {
+	return fmt.Sprintf("%.2f %s", amount, currency)
+}
+
+// writeCostInfo writes the estimated cluster cost and the most expensive namespaces
+func writeCostInfo(result *strings.Builder, status *k8s.ClusterStatus) {
+	cost := status.Cost
+	if cost == nil {
+		return
+	}
+	fmt.Fprintf(result, "💰 Estimated cost (%s): %s/hour, ~%s/month\n", cost.Source,
+		formatMoney(cost.HourlyCost, cost.Currency), formatMoney(cost.MonthlyCost, cost.Currency))
+	for i, ns := range cost.Namespaces {
+		if i == 5 {
+			fmt.Fprintf(result, "  ... and %d more namespace(s)\n", len(cost.Namespaces)-5)
+			break
 		}
+		fmt.Fprintf(result, "  %-30s ~%s/month (%.1f%%)\n", ns.Namespace, formatMoney(ns.MonthlyCost, cost.Currency), ns.Percent)
+	}
+	fmt.Fprintf(result, "  %-30s ~%s/month\n", "(idle capacity)", formatMoney(cost.IdleHourlyCost*k8s.HoursPerMonth, cost.Currency))
+	if len(cost.UnpricedNodes) > 0 {
+		fmt.Fprintf(result, "  ⚠️  %d node(s) missing from the price table: %s\n", len(cost.UnpricedNodes), strings.Join(cost.UnpricedNodes, ", "))
+	}
+	if cost.OpenCostWarning != "" {
+		fmt.Fprintf(result, "  ⚠️  %s\n", cost.OpenCostWarning)
 	}
 	result.WriteString("\n")
 }
@@ -171,10 +399,10 @@ func writeNamespaceInfo(result *strings.Builder, status *k8s.ClusterStatus) {
 func defineGetClusterStatusTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
 	return llm.DefineTool(
 		toolGetClusterStatus,
-		"Get detailed status information for a specific Kubernetes cluster including reachability, nodes, version, and health metrics. IMPORTANT: Present the tool output exactly as received - it contains visual card/box formatting. Do NOT convert it to a table.",
+		"Get detailed status information for a specific Kubernetes cluster including reachability, nodes, version, and health metrics. Scope the node and pod checks with namespace, label_selector, field_selector and node_selector (e.g. \"check only pods with app=payments\") instead of checking every pod of a large cluster; a scoped check skips the cluster-wide exposure, autoscaling, control plane and cost checks. IMPORTANT: Present the tool output exactly as received - it contains visual card/box formatting. Do NOT convert it to a table.",
 		func(params GetClusterStatusParams, inv llm.ToolInvocation) (any, error) {
-			ctx := context.Background()
-			status, err := k8sProvider.GetClusterStatus(ctx, params.Context)
+			ctx := inv.Ctx()
+			status, err := k8sProvider.GetFilteredClusterStatus(ctx, params.Context, params.healthFilter())
 			if err != nil {
 				return nil, fmt.Errorf("failed to get cluster status: %w", err)
 			}
@@ -182,29 +410,38 @@ func defineGetClusterStatusTool(k8sProvider *k8s.Provider, state *agentState) ll
 			if isJSONOutput(state.outputFormat) {
 				return status, nil
 			}
+			return formatClusterStatus(status), nil
+		},
+	)
+}
 
-			var result strings.Builder
+// formatClusterStatus renders the get_cluster_status card of status.
+func formatClusterStatus(status *k8s.ClusterStatus) string {
+	var result strings.Builder
 
-			// Cluster header
-			fmt.Fprintf(&result, "Cluster Status: %s\n", status.Name)
-			result.WriteString(strings.Repeat("=", 80) + "\n\n")
+	// Cluster header
+	fmt.Fprintf(&result, "Cluster Status: %s\n", status.Name)
+	result.WriteString(strings.Repeat("=", 80) + "\n\n")
 
-			// Check if unreachable
-			if !status.IsReachable {
-				writeUnreachableClusterStatus(&result, status)
-				return result.String(), nil
-			}
+	// Check if unreachable
+	if !status.IsReachable {
+		writeUnreachableClusterStatus(&result, status)
+		return result.String()
+	}
 
-			result.WriteString("✅ Status: REACHABLE\n\n")
+	result.WriteString("✅ Status: REACHABLE\n\n")
 
-			// Write cluster information
-			writeClusterInfo(&result, status)
-			writeNodeInfo(&result, status)
-			writeNamespaceInfo(&result, status)
+	// Write cluster information
+	writeClusterInfo(&result, status)
+	writeNodeInfo(&result, status)
+	writePodInfo(&result, status)
+	writeControlPlaneInfo(&result, status)
+	writeExposureInfo(&result, status)
+	writeAutoscalingInfo(&result, status)
+	writeCostInfo(&result, status)
+	writeNamespaceInfo(&result, status)
 
-			return result.String(), nil
-		},
-	)
+	return result.String()
 }
 
 // CompareClusterParams defines parameters for compare_clusters
@@ -233,6 +470,7 @@ type ComparisonData struct {
 	Nodes        string
 	HealthyNodes string
 	APIServer    string
+	MonthlyCost  string
 	Error        string
 }
 
@@ -262,6 +500,9 @@ func buildComparisonData(k8sProvider *k8s.Provider, ctx context.Context, context
 		if status.HealthyNodes < status.NodeCount {
 			data.Status = "⚠️  Degraded"
 		}
+		if status.Cost != nil {
+			data.MonthlyCost = "~" + formatMoney(status.Cost.MonthlyCost, status.Cost.Currency)
+		}
 	} else {
 		data.Status = "❌ Unreachable"
 		data.Error = status.Error
@@ -291,6 +532,10 @@ func writeComparisonEntry(result *strings.Builder, index int, comp ComparisonDat
 		fmt.Fprintf(result, "    API Server: %s\n", comp.APIServer)
 	}
 
+	if comp.MonthlyCost != "" {
+		fmt.Fprintf(result, "    Estimated Cost: %s/month\n", comp.MonthlyCost)
+	}
+
 	if comp.Error != "" {
 		fmt.Fprintf(result, "    Error: %s\n", comp.Error)
 	}
@@ -318,7 +563,7 @@ func defineCompareClustersTool(k8sProvider *k8s.Provider, state *agentState) llm
 				return nil, fmt.Errorf("at least one context must be provided")
 			}
 
-			ctx := context.Background()
+			ctx := inv.Ctx()
 
 			// Build comparison data for each cluster
 			comparisons := make([]ComparisonData, 0, len(params.Contexts))
@@ -365,12 +610,17 @@ type CheckAllClustersSummary struct {
 	Reachable     int `json:"reachable"`
 	FullyHealthy  int `json:"fully_healthy"`
 	UnhealthyPods int `json:"unhealthy_pods"`
+	Critical      int `json:"critical"`
+	Warnings      int `json:"warnings"`
+	Info          int `json:"info"`
+	Acknowledged  int `json:"acknowledged"`
 }
 
 // CheckAllClustersResult defines JSON output for check_all_clusters
 type CheckAllClustersResult struct {
 	Summary  CheckAllClustersSummary `json:"summary"`
-	Issues   []string                `json:"issues"`
+	Issues   []report.Issue          `json:"issues"`
+	Focus    *TriageFocus            `json:"focus,omitempty"`
 	Clusters []*k8s.ClusterStatus    `json:"clusters"`
 }
 
@@ -379,61 +629,234 @@ type clusterHealthSummary struct {
 	reachableCount     int
 	healthyCount       int
 	totalUnhealthyPods int
-	issues             []string
+	issues             []report.Issue
+	// acknowledged counts NotReady nodes and unhealthy pods skipped by /ack.
+	acknowledged int
+	// triage holds the ranking facts of each issue, in issue order.
+	triage []issueTriage
+	// focus is the issue to investigate first, once the issues are ranked.
+	focus *TriageFocus
+}
+
+// addIssue records an issue of the given severity.
+func (s *clusterHealthSummary) addIssue(severity report.Severity, cluster, format string, args ...any) {
+	s.addTriagedIssue(issueTriage{}, severity, cluster, format, args...)
+}
+
+// addTriagedIssue records an issue with the facts it is ranked by.
+func (s *clusterHealthSummary) addTriagedIssue(t issueTriage, severity report.Severity, cluster, format string, args ...any) {
+	s.issues = append(s.issues, report.Issue{Severity: severity, Cluster: cluster, Message: fmt.Sprintf(format, args...)})
+	s.triage = append(s.triage, t)
+}
+
+// countIssues returns the number of issues of the given severity.
+func (s *clusterHealthSummary) countIssues(severity report.Severity) int {
+	n := 0
+	for _, issue := range s.issues {
+		if issue.Severity == severity {
+			n++
+		}
+	}
+	return n
 }
 
-// processReachableCluster processes health checks for a reachable cluster
-func processReachableCluster(status *k8s.ClusterStatus, summary *clusterHealthSummary) {
+// processReachableCluster processes health checks for a reachable cluster.
+// Informational issues alone do not make a cluster unhealthy.
+func processReachableCluster(status *k8s.ClusterStatus, summary *clusterHealthSummary, thresholds HealthThresholds, now time.Time) {
 	summary.reachableCount++
 	hasIssues := false
 
-	// Check node health
-	if status.HealthyNodes < status.NodeCount && status.NodeCount > 0 {
-		summary.issues = append(summary.issues, fmt.Sprintf("⚠️  %s: %d/%d nodes healthy", status.Context, status.HealthyNodes, status.NodeCount))
-		hasIssues = true
+	// Check node health; acknowledged nodes are skipped and a tolerated
+	// share of NotReady nodes is informational
+	notReady := status.NodeCount - status.HealthyNodes
+	if acked := thresholds.acknowledgedNotReady(status); acked > 0 {
+		notReady -= acked
+		summary.acknowledged += acked
+	}
+	if notReady > 0 && status.NodeCount > 0 {
+		if thresholds.toleratesNotReady(notReady, status.NodeCount) {
+			summary.addIssue(report.SeverityInfo, status.Context, "%d/%d nodes healthy (within tolerated %g%% NotReady)",
+				status.HealthyNodes, status.NodeCount, thresholds.ToleratedNotReadyPercent)
+		} else {
+			summary.addTriagedIssue(issueTriage{area: triageNodes, affected: notReady, unit: "node(s)"},
+				thresholds.nodeSeverity(notReady, status.NodeCount), status.Context,
+				"%d/%d nodes healthy", status.HealthyNodes, status.NodeCount)
+			hasIssues = true
+		}
+	}
+
+	if status.PodsTruncated {
+		summary.addIssue(report.SeverityInfo, status.Context, "pod check stopped after %d pods; counts cover only those (raise --max-pods or scope get_cluster_status)", status.PodCount)
 	}
 
-	// Check pod health
+	// Check pod health; pods of ignored namespaces and acknowledged pods are
+	// skipped and recently scheduled Pending pods are informational
 	if status.HealthyPods < status.PodCount && status.PodCount > 0 {
 		unhealthyCount := status.PodCount - status.HealthyPods
+		pending := 0
+		for _, pod := range status.UnhealthyPods {
+			switch {
+			case thresholds.ignoresNamespace(pod.Namespace):
+				unhealthyCount--
+			case thresholds.acknowledgesPod(status.Context, pod):
+				unhealthyCount--
+				summary.acknowledged++
+			case thresholds.isRecentlyPending(pod, now):
+				pending++
+			}
+		}
 		summary.totalUnhealthyPods += unhealthyCount
-		summary.issues = append(summary.issues, fmt.Sprintf("⚠️  %s: %d/%d pods unhealthy", status.Context, unhealthyCount, status.PodCount))
+		if pending > 0 {
+			summary.addIssue(report.SeverityInfo, status.Context, "%d pod(s) Pending for less than %s", pending, thresholds.pendingGrace())
+		}
+		if unhealthyCount > pending {
+			counted := func(pod k8s.PodInfo) bool {
+				return !thresholds.ignoresNamespace(pod.Namespace) && !thresholds.acknowledgesPod(status.Context, pod) && !thresholds.isRecentlyPending(pod, now)
+			}
+			summary.addTriagedIssue(podTriage(status.UnhealthyPods, unhealthyCount-pending, counted),
+				report.SeverityWarning, status.Context, "%d/%d pods unhealthy", unhealthyCount-pending, status.PodCount)
+			hasIssues = true
+		}
+	}
+
+	// Check kubelet versions against the API server
+	if len(status.KubeletSkew) > 0 {
+		summary.addTriagedIssue(issueTriage{area: triageNodes, affected: len(status.KubeletSkew), unit: "node(s)"},
+			report.SeverityWarning, status.Context, "%d node(s) with kubelet more than one minor version from the API server", len(status.KubeletSkew))
 		hasIssues = true
 	}
 
+	// Check control-plane components; etcd or API server instances down
+	// threaten the whole cluster
+	for _, c := range status.ControlPlane {
+		if c.Healthy {
+			continue
+		}
+		severity := report.SeverityWarning
+		if c.Name == "etcd" || c.Name == "kube-apiserver" {
+			severity = report.SeverityCritical
+		}
+		summary.addTriagedIssue(issueTriage{area: triageControlPlane, affected: c.Total - c.Ready, unit: "instance(s)"},
+			severity, status.Context, "control-plane component %s unhealthy (%d/%d ready)", c.Name, c.Ready, c.Total)
+		hasIssues = true
+	}
+
+	// Check exposed endpoints (Ingresses and LoadBalancer Services)
+	if len(status.ExposureIssues) > 0 {
+		summary.addTriagedIssue(issueTriage{area: triageApps, affected: len(status.ExposureIssues), unit: "endpoint(s)"},
+			report.SeverityWarning, status.Context, "%d exposed endpoint problem(s)", len(status.ExposureIssues))
+		hasIssues = true
+	}
+
+	// Check autoscalers; an HPA at its maximum is informational, one that
+	// cannot scale or read its metrics is a warning
+	broken, atMax := 0, 0
+	for _, issue := range status.AutoscalingIssues {
+		switch {
+		case thresholds.ignoresNamespace(issue.Namespace):
+		case issue.Issue == k8s.AutoscalingIssueAtMax:
+			atMax++
+		default:
+			broken++
+		}
+	}
+	if broken > 0 {
+		summary.addTriagedIssue(issueTriage{area: triageApps, affected: broken, unit: "autoscaler(s)"},
+			report.SeverityWarning, status.Context, "%d autoscaler(s) unable to scale or read metrics", broken)
+		hasIssues = true
+	}
+	if atMax > 0 {
+		summary.addIssue(report.SeverityInfo, status.Context, "%d HPA(s) at max replicas", atMax)
+	}
+
 	if !hasIssues && status.NodeCount > 0 {
 		summary.healthyCount++
 	}
 }
 
 // analyzeClusterHealth analyzes all cluster statuses and returns a summary
-func analyzeClusterHealth(statuses []*k8s.ClusterStatus) clusterHealthSummary {
+// with issues ranked for triage
+func analyzeClusterHealth(statuses []*k8s.ClusterStatus, thresholds HealthThresholds) clusterHealthSummary {
 	summary := clusterHealthSummary{
-		issues: []string{},
+		issues: []report.Issue{},
 	}
+	now := time.Now()
 
 	for _, status := range statuses {
 		if status.IsReachable {
-			processReachableCluster(status, &summary)
+			processReachableCluster(status, &summary, thresholds, now)
 		} else {
-			summary.issues = append(summary.issues, fmt.Sprintf("❌ %s: UNREACHABLE - %s", status.Context, status.Error))
+			summary.addTriagedIssue(issueTriage{area: triageControlPlane}, report.SeverityCritical, status.Context, "UNREACHABLE - %s", status.Error)
 		}
 	}
 
+	summary.rankIssues(now)
+	summary.focus = summary.triageFocus(now)
 	return summary
 }
 
-// writeCompactClusterStatus writes a single-line cluster status
-func writeCompactClusterStatus(result *strings.Builder, status *k8s.ClusterStatus) {
-	if !status.IsReachable {
+// degradedClusters returns the clusters with warning or critical issues
+func degradedClusters(issues []report.Issue) map[string]bool {
+	degraded := make(map[string]bool)
+	for _, issue := range issues {
+		if issue.Severity != report.SeverityInfo {
+			degraded[issue.Cluster] = true
+		}
+	}
+	return degraded
+}
+
+// writeIssues lists issues with their severity icon
+func writeIssues(result *strings.Builder, issues []report.Issue) {
+	if len(issues) == 0 {
+		return
+	}
+	result.WriteString("\n🚨 Issues:\n")
+	for _, issue := range issues {
+		fmt.Fprintf(result, "  %s %s: %s\n", severityIcon(issue.Severity), issue.Cluster, issue.Message)
+	}
+}
+
+// writeCompactClusterStatus writes a single-line cluster status; degraded
+// comes from degradedClusters so the health thresholds apply
+func writeCompactClusterStatus(result *strings.Builder, status *k8s.ClusterStatus, degraded bool) {
+	if !status.IsReachable && status.Transient {
+		fmt.Fprintf(result, "⏳ %s - BUSY (%s): throttling or timing out\n", status.Context, status.Server)
+	} else if !status.IsReachable {
 		fmt.Fprintf(result, "❌ %s - DOWN (%s)\n", status.Context, status.Server)
-	} else if status.HealthyNodes < status.NodeCount || status.HealthyPods < status.PodCount {
+		writeFailureAdvice(result, status)
+	} else if degraded {
 		fmt.Fprintf(result, "⚠️  %s - DEGRADED (nodes: %d/%d, pods: %d/%d)\n",
 			status.Context, status.HealthyNodes, status.NodeCount, status.HealthyPods, status.PodCount)
 	} else {
 		fmt.Fprintf(result, "✅ %s - HEALTHY (nodes: %d, pods: %d, v%s)\n",
 			status.Context, status.NodeCount, status.PodCount, status.Version)
 	}
+	if status.IsReachable && len(status.ExposureIssues) > 0 {
+		fmt.Fprintf(result, "   ⚠️  %d exposed endpoint problem(s), e.g. %s/%s/%s: %s\n", len(status.ExposureIssues),
+			status.ExposureIssues[0].Namespace, status.ExposureIssues[0].Kind, status.ExposureIssues[0].Name, status.ExposureIssues[0].Problem)
+	}
+	if status.IsReachable && status.Cost != nil {
+		fmt.Fprintf(result, "   💰 ~%s/month\n", formatMoney(status.Cost.MonthlyCost, status.Cost.Currency))
+	}
+}
+
+// writeCostRanking lists clusters with a cost estimate from most to least expensive
+func writeCostRanking(result *strings.Builder, statuses []*k8s.ClusterStatus) {
+	var priced []*k8s.ClusterStatus
+	for _, status := range statuses {
+		if status.IsReachable && status.Cost != nil {
+			priced = append(priced, status)
+		}
+	}
+	if len(priced) < 2 {
+		return
+	}
+	sort.SliceStable(priced, func(i, j int) bool { return priced[i].Cost.MonthlyCost > priced[j].Cost.MonthlyCost })
+	result.WriteString("💰 Estimated monthly cost:\n")
+	for i, status := range priced {
+		fmt.Fprintf(result, "  %d. %-30s ~%s\n", i+1, status.Context, formatMoney(status.Cost.MonthlyCost, status.Cost.Currency))
+	}
 }
 
 func defineCheckAllClustersTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
@@ -441,49 +864,63 @@ func defineCheckAllClustersTool(k8sProvider *k8s.Provider, state *agentState) ll
 		toolCheckAllClusters,
 		"Check the status of ALL clusters in parallel for fast health monitoring. This is the most efficient way to get a complete overview of all clusters including their health status, node counts, version information, and any issues. Use this for initial health checks or when you need a full cluster overview. IMPORTANT: Present the tool output exactly as received - it already contains visual card formatting. Do NOT convert it to a table.",
 		func(params CheckAllClustersParams, inv llm.ToolInvocation) (any, error) {
-			ctx := context.Background()
+			ctx, stopProgress := spinnerClusterProgress(inv.Ctx())
 			statuses := k8sProvider.GetAllClusterStatuses(ctx)
+			stopProgress()
 
 			// Analyze cluster health
-			summary := analyzeClusterHealth(statuses)
+			r := newClusterReport(statuses, state.healthThresholds())
+			state.recordClusterReport(r)
 
 			if isJSONOutput(state.outputFormat) {
-				return CheckAllClustersResult{
-					Summary: CheckAllClustersSummary{
-						TotalClusters: len(statuses),
-						Reachable:     summary.reachableCount,
-						FullyHealthy:  summary.healthyCount,
-						UnhealthyPods: summary.totalUnhealthyPods,
-					},
-					Issues:   summary.issues,
-					Clusters: statuses,
-				}, nil
+				return r.CheckAllClustersResult, nil
 			}
+			return formatClusterReport(r), nil
+		},
+	)
+}
 
-			var result strings.Builder
+// formatClusterReport renders the check_all_clusters output of r.
+func formatClusterReport(r *ClusterReport) string {
+	statuses := r.Clusters
+	degraded := degradedClusters(r.Issues)
+	summary := r.Summary
 
-			// Write compact cluster status
-			for i, status := range statuses {
-				if i > 0 {
-					result.WriteString("\n")
-				}
-				writeCompactClusterStatus(&result, status)
-			}
+	var result strings.Builder
 
-			// Write summary at the end
-			result.WriteString("\n")
-			fmt.Fprintf(&result, "📊 Summary: %d/%d reachable", summary.reachableCount, len(statuses))
-			if summary.healthyCount > 0 {
-				fmt.Fprintf(&result, ", %d healthy", summary.healthyCount)
-			}
-			if summary.totalUnhealthyPods > 0 {
-				fmt.Fprintf(&result, ", %d unhealthy pods", summary.totalUnhealthyPods)
-			}
+	// Write compact cluster status
+	for i, status := range statuses {
+		if i > 0 {
 			result.WriteString("\n")
+		}
+		writeCompactClusterStatus(&result, status, degraded[status.Context])
+	}
 
-			return result.String(), nil
-		},
-	)
+	writeIssues(&result, r.Issues)
+	writeFocus(&result, r.Focus)
+
+	// Write summary at the end
+	result.WriteString("\n")
+	fmt.Fprintf(&result, "📊 Summary: %d/%d reachable", summary.Reachable, len(statuses))
+	if summary.FullyHealthy > 0 {
+		fmt.Fprintf(&result, ", %d healthy", summary.FullyHealthy)
+	}
+	if summary.UnhealthyPods > 0 {
+		fmt.Fprintf(&result, ", %d unhealthy pods", summary.UnhealthyPods)
+	}
+	if summary.Critical > 0 {
+		fmt.Fprintf(&result, ", %d critical", summary.Critical)
+	}
+	if summary.Warnings > 0 {
+		fmt.Fprintf(&result, ", %d warning(s)", summary.Warnings)
+	}
+	if summary.Acknowledged > 0 {
+		fmt.Fprintf(&result, ", %d acknowledged", summary.Acknowledged)
+	}
+	result.WriteString("\n")
+	writeCostRanking(&result, statuses)
+
+	return result.String()
 }
 
 // KubectlExecParams defines parameters for kubectl_exec
@@ -512,25 +949,20 @@ func handleWriteDenied(state *agentState) {
 func defineKubectlExecTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
 	return llm.DefineTool(
 		toolKubectlExec,
-		"Execute kubectl commands against a specific Kubernetes cluster. Use this to perform operations like getting resources, scaling deployments, checking logs, describing resources, etc. Always specify the context and provide the kubectl arguments as an array.",
+		fmt.Sprintf("Execute kubectl commands against a specific Kubernetes cluster. Use this to perform operations like getting resources, scaling deployments, checking logs, describing resources, etc. Always specify the context and provide the kubectl arguments as an array. Time budget: %s per command, not counting write approval.", kubectlTimeout()),
 		func(params KubectlExecParams, inv llm.ToolInvocation) (any, error) {
-			return handleKubectlExec(k8sProvider, state, params)
+			return handleKubectlExec(inv.Ctx(), k8sProvider, state, params)
 		},
 	)
 }
 
-func handleKubectlExec(k8sProvider *k8s.Provider, state *agentState, params KubectlExecParams) (any, error) {
+func handleKubectlExec(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params KubectlExecParams) (any, error) {
 	if err := validateKubectlExecParams(params); err != nil {
 		return nil, err
 	}
 
-	if err := validateKubectlCommand(params.Args); err != nil {
-		fullCmd := fmt.Sprintf("kubectl %s", strings.Join(params.Args, " "))
-		validationErr := fmt.Errorf("validation failed: %w", err)
-		if isJSONOutput(state.outputFormat) {
-			return buildKubectlJSONResult("unknown", params.Context, fullCmd, nil, validationErr)
-		}
-		return buildKubectlTextResult("unknown", params.Context, fullCmd, nil, validationErr)
+	if err := validateKubectlCommand(params.Args, state.kubectlPolicy); err != nil {
+		return nil, err
 	}
 
 	sanitizedArgs := sanitizeKubectlArgs(params.Args)
@@ -544,7 +976,13 @@ func handleKubectlExec(k8sProvider *k8s.Provider, state *agentState, params Kube
 	fullCommand, cmdArgs := buildKubectlCommand(params.Context, sanitizedArgs)
 	isReadOnly := isReadOnlyCommand(sanitizedArgs)
 
-	proceed, cancelResult, err := enforceExecutionMode(state, isReadOnly, clusterName, params.Context, fullCommand)
+	req := ApprovalRequest{Tool: toolKubectlExec, Cluster: clusterName, Context: params.Context, Command: fullCommand}
+	if !isReadOnly {
+		if target, ok := disruptionTargetFromArgs(sanitizedArgs, cluster.Namespace); ok {
+			req.Safety = disruptionSafety(ctx, k8sProvider, params.Context, target, sanitizedArgs[0] == "cordon")
+		}
+	}
+	proceed, cancelResult, err := enforceApproval(ctx, state, isReadOnly, req)
 	if err != nil {
 		return nil, err
 	}
@@ -554,7 +992,21 @@ func handleKubectlExec(k8sProvider *k8s.Provider, state *agentState, params Kube
 
 	printExecutionHeader(state, isReadOnly, fullCommand)
 
-	output, execErr := runKubectlCommandFunc(cmdArgs)
+	// Capture the prior object state so the write can be undone later.
+	var undo *undoRecord
+	if !isReadOnly {
+		undo = captureUndoRecord(ctx, params.Context, sanitizedArgs, fullCommand)
+	}
+
+	output, execErr := runKubectlCommandFunc(ctx, cmdArgs)
+	if execErr == nil && undo != nil {
+		state.undo.push(*undo)
+	} else if execErr == nil && !isReadOnly {
+		state.undo.skip(toolKubectlExec, fullCommand, uncapturedTargetReason)
+	}
+	if !isReadOnly {
+		state.publishWrite(req, execErr)
+	}
 	if isJSONOutput(state.outputFormat) {
 		return buildKubectlJSONResult(clusterName, params.Context, fullCommand, output, execErr)
 	}
@@ -574,8 +1026,7 @@ func validateKubectlExecParams(params KubectlExecParams) error {
 func getClusterForContext(k8sProvider *k8s.Provider, contextName string) (*k8s.ClusterInfo, error) {
 	cluster, err := k8sProvider.GetClusterByContext(contextName)
 	if err != nil {
-		return nil, fmt.Errorf("cluster not found - context '%s' does not exist in your kubeconfig. Available contexts: %s",
-			contextName, getAvailableContexts(k8sProvider))
+		return nil, contextNotFoundError(k8sProvider, contextName)
 	}
 	return cluster, nil
 }
@@ -617,7 +1068,25 @@ func handleReadOnlyModeWriteBlock(state *agentState, isReadOnly bool, clusterNam
 	return true, nil, nil
 }
 
-func enforceExecutionMode(state *agentState, isReadOnly bool, clusterName, contextName, fullCommand string) (bool, any, error) {
+func enforceExecutionMode(ctx context.Context, state *agentState, isReadOnly bool, clusterName, contextName, fullCommand string) (bool, any, error) {
+	return enforceToolExecutionMode(ctx, state, toolKubectlExec, isReadOnly, clusterName, contextName, fullCommand)
+}
+
+// enforceToolExecutionMode applies read-only blocking and write confirmation on
+// behalf of tool, which is reported to external approvers.
+func enforceToolExecutionMode(ctx context.Context, state *agentState, tool string, isReadOnly bool, clusterName, contextName, fullCommand string) (bool, any, error) {
+	return enforceApproval(ctx, state, isReadOnly, ApprovalRequest{
+		Tool:    tool,
+		Cluster: clusterName,
+		Context: contextName,
+		Command: fullCommand,
+	})
+}
+
+// enforceApproval is enforceToolExecutionMode for callers that fill in more
+// of the approval request, such as the objects of a bulk operation.
+func enforceApproval(ctx context.Context, state *agentState, isReadOnly bool, req ApprovalRequest) (bool, any, error) {
+	clusterName, contextName, fullCommand := req.Cluster, req.Context, req.Command
 	if !isReadOnly && state.denyWritesUntilNextPrompt {
 		return false, denyWriteMessage(state), nil
 	}
@@ -633,7 +1102,7 @@ func enforceExecutionMode(state *agentState, isReadOnly bool, clusterName, conte
 	}
 
 	if !isReadOnly && state.mode == ModeInteractive {
-		proceed, err := confirmWriteOperation(state, fullCommand)
+		proceed, err := confirmWriteOperation(ctx, state, req)
 		if err != nil {
 			return false, nil, err
 		}
@@ -651,12 +1120,13 @@ func enforceExecutionMode(state *agentState, isReadOnly bool, clusterName, conte
 func offerModeSwitch(state *agentState, fullCommand string) (bool, error) {
 	resumeSpinner := pauseSpinner()
 	defer resumeSpinner()
+	out := state.stdout()
 
-	fmt.Printf("\n%s🔒 Blocked:%s %s%s%s\n", colorRed, colorReset, colorBold, fullCommand, colorReset)
-	fmt.Printf("%sThis write operation requires interactive mode.%s\n", colorYellow, colorReset)
-	fmt.Print("Switch to interactive mode to proceed? (yes/no): ")
+	fmt.Fprintf(out, "\n%s🔒 Blocked:%s %s%s%s\n", colorRed, colorReset, colorBold, fullCommand, colorReset)
+	fmt.Fprintf(out, "%sThis write operation requires interactive mode.%s\n", colorYellow, colorReset)
+	fmt.Fprint(out, "Switch to interactive mode to proceed? (yes/no): ")
 
-	reader := bufio.NewReader(os.Stdin)
+	reader := bufio.NewReader(state.stdin())
 	response, err := reader.ReadString('\n')
 	if err != nil {
 		return false, fmt.Errorf("failed to read confirmation: %w", err)
@@ -665,54 +1135,73 @@ func offerModeSwitch(state *agentState, fullCommand string) (bool, error) {
 	response = strings.TrimSpace(strings.ToLower(response))
 	if response != "yes" && response != "y" {
 		handleWriteDenied(state)
-		fmt.Printf("\n%s❌ Operation cancelled by user%s\n\n", colorRed, colorReset)
+		fmt.Fprintf(out, "\n%s❌ Operation cancelled by user%s\n\n", colorRed, colorReset)
 		return false, nil
 	}
 
 	state.mode = ModeInteractive
-	fmt.Printf("  %s●%s Switched to %s🔓 interactive%s mode\n\n", colorGreen, colorReset, colorGreen, colorReset)
+	fmt.Fprintf(out, "  %s●%s Switched to %s🔓 interactive%s mode\n\n", colorGreen, colorReset, colorGreen, colorReset)
 	return true, nil
 }
 
-func confirmWriteOperation(state *agentState, fullCommand string) (bool, error) {
+func confirmWriteOperation(ctx context.Context, state *agentState, req ApprovalRequest) (bool, error) {
 	resumeSpinner := pauseSpinner()
 	defer resumeSpinner()
+	out := state.stdout()
 
-	if !isJSONOutput(state.outputFormat) {
-		fmt.Printf("\n%s⚠️  Write Operation:%s %s%s%s\n", colorYellow, colorReset, colorBold, fullCommand, colorReset)
-		fmt.Printf("%sThis will modify the cluster state.%s\n", colorYellow, colorReset)
+	approver := state.approver
+	if approver == nil {
+		approver = &terminalApprover{in: state.stdin(), out: out, jsonOutput: isJSONOutput(state.outputFormat)}
+	}
+	if approver.Name() != ApprovalLocal && !isJSONOutput(state.outputFormat) {
+		fmt.Fprintf(out, "\n%s⏳ Waiting for %s approval:%s %s%s%s\n", colorYellow, approver.Name(), colorReset, colorBold, req.Command, colorReset)
 	}
-	fmt.Print("Do you want to proceed? (yes/no): ")
 
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
+	req.Agent = string(state.selectedAgent)
+	req.RequestedAt = time.Now()
+	decision, err := approver.Approve(ctx, req)
 	if err != nil {
-		return false, fmt.Errorf("failed to read confirmation: %w", err)
+		return false, err
 	}
 
-	response = strings.TrimSpace(strings.ToLower(response))
-	if response != "yes" && response != "y" {
+	if !decision.Approved {
 		handleWriteDenied(state)
 		if !isJSONOutput(state.outputFormat) {
-			fmt.Printf("\n%s❌ Operation cancelled by user%s\n\n", colorRed, colorReset)
+			fmt.Fprintf(out, "\n%s❌ Operation cancelled by %s%s\n", colorRed, describeApprover(decision), colorReset)
+			if decision.Reason != "" {
+				fmt.Fprintf(out, "%s   Reason: %s%s\n", colorDim, decision.Reason, colorReset)
+			}
+			fmt.Fprintln(out)
 		}
 		return false, nil
 	}
 	if !isJSONOutput(state.outputFormat) {
-		fmt.Println()
+		if decision.Approver != ApprovalLocal {
+			fmt.Fprintf(out, "%s✅ Approved by %s%s\n", colorGreen, decision.Approver, colorReset)
+		}
+		fmt.Fprintln(out)
 	}
 
 	return true, nil
 }
 
+// describeApprover returns who rejected a write, for the cancellation message.
+func describeApprover(decision ApprovalDecision) string {
+	if decision.Approver == "" || decision.Approver == ApprovalLocal {
+		return "user"
+	}
+	return decision.Approver
+}
+
 func printExecutionHeader(state *agentState, isReadOnly bool, fullCommand string) {
 	if isJSONOutput(state.outputFormat) {
 		return
 	}
+	out := state.stdout()
 	if isReadOnly {
-		fmt.Printf("\r\033[K%s🔍 Executing:%s %s%s%s\n", colorCyan, colorReset, colorBold, fullCommand, colorReset)
+		fmt.Fprintf(out, "\r\033[K%s🔍 Executing:%s %s%s%s\n", colorCyan, colorReset, colorBold, fullCommand, colorReset)
 	} else {
-		fmt.Printf("\r\033[K%s⚡ Executing:%s %s%s%s\n", colorYellow, colorReset, colorBold, fullCommand, colorReset)
+		fmt.Fprintf(out, "\r\033[K%s⚡ Executing:%s %s%s%s\n", colorYellow, colorReset, colorBold, fullCommand, colorReset)
 	}
 }
 
@@ -734,22 +1223,46 @@ func kubectlTimeout() time.Duration {
 	return d
 }
 
-func runKubectlCommand(cmdArgs []string) ([]byte, error) {
+func runKubectlCommand(ctx context.Context, cmdArgs []string) ([]byte, error) {
 	kubectlPath, err := exec.LookPath("kubectl")
 	if err != nil {
 		return nil, fmt.Errorf("kubectl not found in PATH: %w", err)
 	}
 	timeout := kubectlTimeout()
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
+	ctx, span := telemetry.Start(ctx, "kubectl", attribute.String("kubectl.verb", kubectlVerb(cmdArgs)))
+	debug.Logf(debug.K8s, "kubectl %s", strings.Join(cmdArgs, " "))
+	start := time.Now()
 	cmd := exec.CommandContext(ctx, kubectlPath, cmdArgs...)
 	out, execErr := cmd.CombinedOutput()
-	if ctx.Err() == context.DeadlineExceeded {
-		return out, fmt.Errorf("kubectl command timed out after %s", timeout)
-	}
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		execErr = fmt.Errorf("kubectl command did not finish within its %s time budget (KOPILOT_KUBECTL_TIMEOUT)", timeout)
+	case context.Canceled:
+		execErr = fmt.Errorf("kubectl command cancelled: %w", context.Canceled)
+	}
+	telemetry.End(span, execErr)
+	debug.Logf(debug.K8s, "kubectl %s -> %d bytes in %s (err: %v)", kubectlVerb(cmdArgs), len(out), time.Since(start).Round(time.Millisecond), execErr)
 	return out, execErr
 }
 
+// kubectlVerb returns the first argument that is not a flag or a flag value
+// (--context and -n take one), e.g. "get" for "--context prod get pods".
+// Only the verb is traced: arguments may carry secret literals.
+func kubectlVerb(cmdArgs []string) string {
+	for i := 0; i < len(cmdArgs); i++ {
+		arg := cmdArgs[i]
+		switch {
+		case arg == "--context" || arg == "-n" || arg == "--namespace":
+			i++
+		case !strings.HasPrefix(arg, "-"):
+			return arg
+		}
+	}
+	return ""
+}
+
 func buildKubectlJSONResult(clusterName, contextName, fullCommand string, output []byte, execErr error) (any, error) {
 	result := KubectlExecResult{
 		Cluster: clusterName,
@@ -764,11 +1277,28 @@ func buildKubectlJSONResult(clusterName, contextName, fullCommand string, output
 			exitCode := exitErr.ExitCode()
 			result.ExitCode = &exitCode
 		}
-		return result, fmt.Errorf("kubectl command failed on cluster %s (%s): %w", clusterName, contextName, execErr)
+		return result, kubectlFailure(clusterName, contextName, output, execErr)
 	}
 	return result, nil
 }
 
+// maxKubectlErrorOutput bounds the kubectl output quoted in an error.
+const maxKubectlErrorOutput = 2000
+
+// kubectlFailure returns the error of a failed kubectl command with the end
+// of its output, which holds kubectl's own message: providers send the model
+// only the error of a failed call, not its result.
+func kubectlFailure(clusterName, contextName string, output []byte, execErr error) error {
+	out := strings.TrimSpace(string(output))
+	if out == "" {
+		return fmt.Errorf("kubectl command failed on cluster %s (%s): %w", clusterName, contextName, execErr)
+	}
+	if len(out) > maxKubectlErrorOutput {
+		out = "…" + strings.ToValidUTF8(out[len(out)-maxKubectlErrorOutput:], "")
+	}
+	return fmt.Errorf("kubectl command failed on cluster %s (%s): %w: %s", clusterName, contextName, execErr, out)
+}
+
 func buildKubectlTextResult(clusterName, contextName, fullCommand string, output []byte, execErr error) (string, error) {
 	var result strings.Builder
 	fmt.Fprintf(&result, "Cluster: %s (%s)\n", clusterName, contextName)
@@ -785,7 +1315,7 @@ func buildKubectlTextResult(clusterName, contextName, fullCommand string, output
 	result.WriteString(string(output))
 
 	if execErr != nil {
-		return result.String(), fmt.Errorf("kubectl command failed on cluster %s (%s): %w", clusterName, contextName, execErr)
+		return result.String(), kubectlFailure(clusterName, contextName, output, execErr)
 	}
 
 	return result.String(), nil
@@ -801,9 +1331,9 @@ type SanitizeClusterParams struct {
 func defineSanitizeClusterTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
 	return llm.DefineTool(
 		toolSanitizeCluster,
-		"Lint all Deployments, StatefulSets, and DaemonSets in a cluster against Kubernetes best practices and security rules (CIS Benchmark, NSA/CISA guidelines). Returns a 0-100 score with an A-F grade, per-namespace breakdowns, and detailed findings per workload.",
+		"Lint all Deployments, StatefulSets, and DaemonSets in a cluster against Kubernetes best practices and security rules (CIS Benchmark, NSA/CISA guidelines). Returns a 0-100 score with an A-F grade, per-namespace breakdowns, and detailed findings per workload. Cluster-wide runs also match node kernels, OS images, container runtimes and kubelets against the node advisory feed (known CVEs and end-of-life images), reported separately from the score.",
 		func(params SanitizeClusterParams, inv llm.ToolInvocation) (any, error) {
-			ctx := context.Background()
+			ctx := inv.Ctx()
 			report, err := k8sProvider.SanitizeCluster(ctx, params.Context, params.Namespace, params.IncludeSystem)
 			if err != nil {
 				return nil, fmt.Errorf("failed to sanitize cluster: %w", err)
@@ -853,9 +1383,44 @@ func formatSanitizeResult(report *k8s.SanitizeResult) string {
 	if report.TotalFindings == 0 {
 		sb.WriteString("✅ No findings — all scanned workloads pass best-practice checks.\n")
 	}
+	writeNodeAdvisories(&sb, report)
 	return sb.String()
 }
 
+// writeNodeAdvisories appends the node advisory section of a sanitize report.
+// Advisories are reported next to the workload score, not folded into it.
+func writeNodeAdvisories(sb *strings.Builder, report *k8s.SanitizeResult) {
+	if report.AdvisoryFeed == "" {
+		return
+	}
+	if len(report.NodeAdvisories) == 0 {
+		fmt.Fprintf(sb, "\n✅ No node advisories (feed %s)\n", report.AdvisoryFeed)
+		return
+	}
+	fmt.Fprintf(sb, "\n🧬 NODE ADVISORIES (feed %s): %d\n", report.AdvisoryFeed, len(report.NodeAdvisories))
+	possible := false
+	for _, a := range report.NodeAdvisories {
+		title := a.ID
+		if a.Name != "" {
+			title += " " + a.Name
+		}
+		fmt.Fprintf(sb, "  - [%s] %s: %s %s — %s", strings.ToUpper(a.Severity), a.Node, a.Component, a.Version, title)
+		if a.Confidence == k8s.AdvisoryPossible {
+			sb.WriteString(" (possible)")
+			possible = true
+		}
+		sb.WriteString("\n")
+		fmt.Fprintf(sb, "      %s", a.Summary)
+		if a.FixedIn != "" {
+			fmt.Fprintf(sb, "; fixed in %s", a.FixedIn)
+		}
+		sb.WriteString("\n")
+	}
+	if possible {
+		sb.WriteString("  (possible: distribution kernels backport fixes without changing the upstream version; check the vendor's advisory)\n")
+	}
+}
+
 // sanitizeGradeIcon returns an emoji for a letter grade
 func sanitizeGradeIcon(grade string) string {
 	switch grade {
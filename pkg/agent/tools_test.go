@@ -352,3 +352,50 @@ func TestFormatSanitizeResultNoFindings(t *testing.T) {
 		t.Error("formatSanitizeResult output missing context name")
 	}
 }
+
+func TestFormatSanitizeResultNodeAdvisories(t *testing.T) {
+	report := &k8s.SanitizeResult{
+		Context:      "prod",
+		Grade:        "A",
+		Score:        100,
+		AdvisoryFeed: "2026-10-01",
+		NodeAdvisories: []k8s.NodeAdvisory{
+			{Node: "node-a", Component: k8s.AdvisoryRuntime, Version: "containerd://1.7.2", ID: "CVE-2024-21626",
+				Name: "runc Leaky Vessels", Severity: k8s.AdvisoryCritical, Confidence: k8s.AdvisoryLikely, FixedIn: "1.7.13", Summary: "escape"},
+			{Node: "node-b", Component: k8s.AdvisoryKernel, Version: "5.15.0-1034-aws", ID: "CVE-2022-0847",
+				Severity: k8s.AdvisoryCritical, Confidence: k8s.AdvisoryPossible, FixedIn: "5.15.25", Summary: "overwrite"},
+		},
+	}
+	result := formatSanitizeResult(report)
+	for _, want := range []string{"NODE ADVISORIES (feed 2026-10-01): 2", "[CRITICAL] node-a", "CVE-2024-21626 runc Leaky Vessels", "fixed in 1.7.13", "(possible)", "backport"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("output missing %q:\n%s", want, result)
+		}
+	}
+
+	report.NodeAdvisories = nil
+	if result := formatSanitizeResult(report); !strings.Contains(result, "No node advisories (feed 2026-10-01)") {
+		t.Errorf("clean nodes not reported:\n%s", result)
+	}
+	report.AdvisoryFeed = ""
+	if result := formatSanitizeResult(report); strings.Contains(result, "advisories") {
+		t.Errorf("advisory section without a feed:\n%s", result)
+	}
+}
+
+func TestKubectlVerb(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"get", "pods", "-n", "default"}, "get"},
+		{[]string{"--context", "prod", "-n", "kube-system", "delete", "pod", "x"}, "delete"},
+		{[]string{"--all-namespaces"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := kubectlVerb(tt.args); got != tt.want {
+			t.Errorf("kubectlVerb(%v) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the prompt-level OpenTelemetry span of the interactive loop.
+package agent
+
+import (
+	"context"
+	"errors"
+
+	"github.com/e9169/kopilot/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// startPromptSpan starts the span covering one user prompt, from sending it
+// until the model goes idle, and makes it the parent of the model, tool,
+// kubectl and Kubernetes API spans of the turn. The prompt text is not
+// recorded, only its length.
+func (s *agentState) startPromptSpan(ctx context.Context, model string, promptLength int) context.Context {
+	// A turn aborted by /clear or Ctrl+C may never have gone idle.
+	s.endPromptSpan(nil)
+
+	ctx, span := telemetry.Start(ctx, "kopilot.prompt",
+		attribute.String("kopilot.agent", string(s.selectedAgent)),
+		attribute.String("kopilot.mode", s.mode.String()),
+		attribute.String("gen_ai.request.model", model),
+		attribute.Int("kopilot.prompt.length", promptLength))
+	s.traceMu.Lock()
+	s.promptSpan = span
+	s.traceMu.Unlock()
+	telemetry.SetActive(ctx)
+	return ctx
+}
+
+// endPromptSpan ends the in-flight prompt span, if any, recording err.
+func (s *agentState) endPromptSpan(err error) {
+	s.traceMu.Lock()
+	span := s.promptSpan
+	s.promptSpan = nil
+	s.traceMu.Unlock()
+	if span == nil {
+		return
+	}
+	telemetry.SetActive(nil)
+	telemetry.End(span, err)
+}
+
+// promptSpanError converts a session error event into the span error.
+func promptSpanError(message string) error {
+	if message == "" {
+		message = "(unknown session error)"
+	}
+	return errors.New(message)
+}
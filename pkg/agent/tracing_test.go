@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestPromptSpan(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	defer otel.SetTracerProvider(prev)
+
+	state := &agentState{mode: ModeReadOnly, selectedAgent: AgentDebugger}
+	ctx := state.startPromptSpan(context.Background(), "gpt-4o", 42)
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		t.Fatal("prompt context should carry a span")
+	}
+	// A new prompt ends the previous span, e.g. after Ctrl+C.
+	state.startPromptSpan(context.Background(), "gpt-4o", 7)
+	state.endPromptSpan(promptSpanError(""))
+	state.endPromptSpan(errors.New("ignored: no span in flight"))
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	if spans[0].Name() != "kopilot.prompt" || spans[0].Status().Code == codes.Error {
+		t.Errorf("first span = %s %+v, want ok kopilot.prompt", spans[0].Name(), spans[0].Status())
+	}
+	if spans[1].Status().Description != "(unknown session error)" {
+		t.Errorf("second span status = %+v", spans[1].Status())
+	}
+}
@@ -0,0 +1,142 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the triage ranking of check_all_clusters issues.
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/report"
+)
+
+// Triage areas of an issue. An unreachable cluster or broken control plane
+// affects everything on the cluster, a node its pods, system pods the
+// cluster's add-ons and application pods single workloads.
+const (
+	triageControlPlane = "control plane"
+	triageNodes        = "nodes"
+	triageSystem       = "system namespaces"
+	triageApps         = "application namespaces"
+)
+
+// triageAreaOrder ranks triage areas, most urgent first; issues without an
+// area rank last.
+var triageAreaOrder = map[string]int{triageControlPlane: 0, triageNodes: 1, triageSystem: 2, triageApps: 3}
+
+// triageRecentWindow is how recent a problem must be to rank above older
+// ones of the same area: a fresh breakage is likelier to need action. The
+// focus reason calls it "the last hour".
+const triageRecentWindow = time.Hour
+
+// issueTriage are the facts an issue is ranked by, after its severity.
+type issueTriage struct {
+	area string
+	// affected is the blast radius: how many units the issue affects.
+	affected int
+	unit     string
+	// newest is when the newest affected object appeared; zero when unknown.
+	newest time.Time
+}
+
+// recent reports whether the issue's newest affected object appeared within
+// triageRecentWindow of now.
+func (t issueTriage) recent(now time.Time) bool {
+	return !t.newest.IsZero() && now.Sub(t.newest) <= triageRecentWindow
+}
+
+// TriageFocus is the issue worth investigating first among several, with
+// why it ranks first.
+type TriageFocus struct {
+	report.Issue
+	Reason string `json:"reason"`
+}
+
+// podTriage ranks an unhealthy-pods issue: system namespaces when any
+// counted pod runs in one, with the newest pod's creation time.
+func podTriage(pods []k8s.PodInfo, affected int, counted func(k8s.PodInfo) bool) issueTriage {
+	t := issueTriage{area: triageApps, affected: affected, unit: "pod(s)"}
+	for _, pod := range pods {
+		if !counted(pod) {
+			continue
+		}
+		if k8s.IsSystemNamespace(pod.Namespace) {
+			t.area = triageSystem
+		}
+		if pod.Created.After(t.newest) {
+			t.newest = pod.Created
+		}
+	}
+	return t
+}
+
+// rankIssues orders the issues by severity, then area, then recentness and
+// then blast radius, keeping the cluster order among equals.
+func (s *clusterHealthSummary) rankIssues(now time.Time) {
+	order := make([]int, len(s.issues))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		i, j := order[a], order[b]
+		if si, sj := severityOrder[s.issues[i].Severity], severityOrder[s.issues[j].Severity]; si != sj {
+			return si < sj
+		}
+		ti, tj := s.triage[i], s.triage[j]
+		if ai, aj := triageAreaRank(ti.area), triageAreaRank(tj.area); ai != aj {
+			return ai < aj
+		}
+		if ri, rj := ti.recent(now), tj.recent(now); ri != rj {
+			return ri
+		}
+		return ti.affected > tj.affected
+	})
+	issues := make([]report.Issue, len(order))
+	triage := make([]issueTriage, len(order))
+	for k, i := range order {
+		issues[k], triage[k] = s.issues[i], s.triage[i]
+	}
+	s.issues, s.triage = issues, triage
+}
+
+// triageAreaRank returns the rank of area in triageAreaOrder.
+func triageAreaRank(area string) int {
+	if rank, ok := triageAreaOrder[area]; ok {
+		return rank
+	}
+	return len(triageAreaOrder)
+}
+
+// triageFocus returns the top-ranked issue of a ranked summary when at least
+// two warning or critical issues compete for attention, nil otherwise.
+func (s *clusterHealthSummary) triageFocus(now time.Time) *TriageFocus {
+	if len(s.issues) == 0 || s.countIssues(report.SeverityCritical)+s.countIssues(report.SeverityWarning) < 2 {
+		return nil
+	}
+	return &TriageFocus{Issue: s.issues[0], Reason: s.triage[0].reason(s.issues[0].Severity, now)}
+}
+
+// reason explains why an issue ranks first.
+func (t issueTriage) reason(severity report.Severity, now time.Time) string {
+	reason := string(severity)
+	if t.area != "" {
+		reason += ", " + t.area
+	}
+	if t.affected > 0 {
+		reason += fmt.Sprintf(", %d %s affected", t.affected, t.unit)
+	}
+	if t.recent(now) {
+		reason += ", started within the last hour"
+	}
+	return reason
+}
+
+// writeFocus names the issue to investigate first.
+func writeFocus(result *strings.Builder, focus *TriageFocus) {
+	if focus == nil {
+		return
+	}
+	fmt.Fprintf(result, "\n👉 Investigate first: %s: %s (%s)\n", focus.Cluster, focus.Message, focus.Reason)
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/report"
+)
+
+func TestAnalyzeClusterHealthRanksIssues(t *testing.T) {
+	now := time.Now()
+	old, fresh := now.Add(-48*time.Hour), now.Add(-10*time.Minute)
+	crashing := func(namespace string, created time.Time) k8s.PodInfo {
+		return k8s.PodInfo{Name: "p", Namespace: namespace, Status: "CrashLoopBackOff", Created: created}
+	}
+	statuses := []*k8s.ClusterStatus{
+		{ClusterInfo: k8s.ClusterInfo{Context: "apps", IsReachable: true}, NodeCount: 3, HealthyNodes: 3, PodCount: 20, HealthyPods: 15,
+			UnhealthyPods:  []k8s.PodInfo{crashing("shop", old), crashing("shop", old), crashing("shop", old), crashing("shop", old), crashing("shop", old)},
+			ExposureIssues: []k8s.ExposureIssue{{Namespace: "shop", Kind: "Ingress", Name: "web", Problem: "no endpoints"}}},
+		{ClusterInfo: k8s.ClusterInfo{Context: "fresh", IsReachable: true}, NodeCount: 3, HealthyNodes: 3, PodCount: 20, HealthyPods: 19,
+			UnhealthyPods: []k8s.PodInfo{crashing("shop", fresh)}},
+		{ClusterInfo: k8s.ClusterInfo{Context: "sys", IsReachable: true}, NodeCount: 3, HealthyNodes: 3, PodCount: 20, HealthyPods: 19,
+			UnhealthyPods: []k8s.PodInfo{crashing("kube-system", old)}},
+		{ClusterInfo: k8s.ClusterInfo{Context: "nodes", IsReachable: true}, NodeCount: 3, HealthyNodes: 2},
+		{ClusterInfo: k8s.ClusterInfo{Context: "cp", IsReachable: true}, NodeCount: 3, HealthyNodes: 3,
+			ControlPlane: []k8s.ControlPlaneComponent{{Name: "kube-scheduler", Ready: 1, Total: 2}}},
+	}
+
+	summary := analyzeClusterHealth(statuses, HealthThresholds{})
+	var got []string
+	for _, issue := range summary.issues {
+		got = append(got, issue.Cluster+": "+issue.Message)
+	}
+	want := []string{
+		"cp: control-plane component kube-scheduler unhealthy (1/2 ready)",
+		"nodes: 2/3 nodes healthy",
+		"sys: 1/20 pods unhealthy",
+		"fresh: 1/20 pods unhealthy",
+		"apps: 5/20 pods unhealthy",
+		"apps: 1 exposed endpoint problem(s)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("issues ranked\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	focus := summary.focus
+	if focus == nil || focus.Cluster != "cp" || focus.Reason != "warning, control plane, 1 instance(s) affected" {
+		t.Fatalf("focus = %+v", focus)
+	}
+
+	// A critical issue outranks every warning, whatever its area.
+	statuses = append(statuses, &k8s.ClusterStatus{ClusterInfo: k8s.ClusterInfo{Context: "big", IsReachable: true}, NodeCount: 4, HealthyNodes: 1})
+	summary = analyzeClusterHealth(statuses, HealthThresholds{})
+	if summary.issues[0].Cluster != "big" || summary.issues[0].Severity != report.SeverityCritical {
+		t.Errorf("first issue = %+v, want the critical node issue of big", summary.issues[0])
+	}
+}
+
+func TestTriageFocus(t *testing.T) {
+	now := time.Now()
+	single := analyzeClusterHealth([]*k8s.ClusterStatus{
+		{ClusterInfo: k8s.ClusterInfo{Context: "prod", IsReachable: true}, NodeCount: 3, HealthyNodes: 3, PodCount: 2, HealthyPods: 1,
+			UnhealthyPods: []k8s.PodInfo{{Name: "web", Namespace: "shop", Status: "Error", Created: now.Add(-5 * time.Minute)}}},
+	}, HealthThresholds{})
+	if single.focus != nil {
+		t.Errorf("focus with one issue = %+v, want none", single.focus)
+	}
+
+	var b strings.Builder
+	writeFocus(&b, nil)
+	if b.Len() != 0 {
+		t.Errorf("writeFocus(nil) = %q", b.String())
+	}
+	reason := issueTriage{area: triageApps, affected: 1, unit: "pod(s)", newest: now.Add(-5 * time.Minute)}.reason(report.SeverityWarning, now)
+	writeFocus(&b, &TriageFocus{Issue: report.Issue{Cluster: "prod", Message: "1/2 pods unhealthy"}, Reason: reason})
+	if want := "👉 Investigate first: prod: 1/2 pods unhealthy (warning, application namespaces, 1 pod(s) affected, started within the last hour)"; !strings.Contains(b.String(), want) {
+		t.Errorf("writeFocus = %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,615 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains undo tracking for write operations.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// maxUndoRecords bounds the undo history kept per session.
+const maxUndoRecords = 20
+
+// undoAction describes how a recorded write is reversed.
+type undoAction string
+
+const (
+	// undoScale restores the previous replica count.
+	undoScale undoAction = "scale"
+	// undoReplace re-applies the previous manifest over the modified object.
+	undoReplace undoAction = "replace"
+	// undoRecreate creates a deleted object again from its previous manifest.
+	undoRecreate undoAction = "recreate"
+	// undoDelete removes an object that the write created.
+	undoDelete undoAction = "delete"
+	// undoUncordon marks a cordoned or drained node schedulable again.
+	undoUncordon undoAction = "uncordon"
+	// undoCordon marks an uncordoned node unschedulable again.
+	undoCordon undoAction = "cordon"
+)
+
+// undoRecord captures one successful write operation and the state needed to reverse it.
+type undoRecord struct {
+	Time      time.Time  `json:"time"`
+	Context   string     `json:"context"`
+	Command   string     `json:"command"`
+	Kind      string     `json:"kind"`
+	Name      string     `json:"name"`
+	Namespace string     `json:"namespace,omitempty"`
+	Action    undoAction `json:"action"`
+	Replicas  int64      `json:"replicas,omitempty"`
+	Manifest  []byte     `json:"-"`
+}
+
+// undoUnsupported lists the write tools whose changes are not recorded,
+// with why they cannot be undone. Their writes are noted instead, so /undo
+// says so rather than silently reverting an earlier write.
+var undoUnsupported = map[string]string{
+	toolRestartWorkload:  "a rollout restart replaces the pods and leaves no previous state to restore",
+	toolBulkLabel:        "label changes to many objects are not recorded",
+	toolBulkDelete:       "the manifests of bulk-deleted objects are not kept",
+	toolMigrateNamespace: "objects copied to another cluster are not recorded",
+	toolExecInPod:        "commands run inside a container cannot be reversed",
+	toolChaos:            "chaos drills revert themselves: the deployment replaces a deleted pod and a cordoned node is uncordoned when the drill ends",
+}
+
+// uncapturedTargetReason is why a kubectl write whose target could not be
+// captured beforehand cannot be undone.
+const uncapturedTargetReason = "its target could not be captured before the change (selectors, --all, several names or -f manifests)"
+
+// undoUnsupportedNote names the tools whose writes cannot be undone.
+func undoUnsupportedNote() string {
+	tools := make([]string, 0, len(undoUnsupported))
+	for tool := range undoUnsupported {
+		tools = append(tools, tool)
+	}
+	slices.Sort(tools)
+	return "Writes by " + strings.Join(tools, ", ") + ", and kubectl_exec commands and execute_plan steps that address several objects, are not recorded and cannot be undone."
+}
+
+// unrecordedWrite is a successful write that cannot be undone.
+type unrecordedWrite struct {
+	Time    time.Time `json:"time"`
+	Tool    string    `json:"tool"`
+	Command string    `json:"command"`
+	Reason  string    `json:"reason"`
+}
+
+// target returns the kubectl "kind/name" reference for the record.
+func (r undoRecord) target() string {
+	return r.Kind + "/" + r.Name
+}
+
+// undoLog is a bounded, concurrency-safe stack of undo records.
+type undoLog struct {
+	mu      sync.Mutex
+	records []undoRecord
+	// unrecorded is the latest write that could not be recorded, if it
+	// came after the latest record.
+	unrecorded *unrecordedWrite
+}
+
+func (l *undoLog) push(r undoRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.unrecorded = nil
+	l.records = append(l.records, r)
+	if len(l.records) > maxUndoRecords {
+		l.records = l.records[len(l.records)-maxUndoRecords:]
+	}
+}
+
+// skip notes a successful write that cannot be undone.
+func (l *undoLog) skip(tool, command, reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.unrecorded = &unrecordedWrite{Time: time.Now(), Tool: tool, Command: command, Reason: reason}
+}
+
+// lastUnrecorded returns the write noted by skip, if no write was recorded since.
+func (l *undoLog) lastUnrecorded() (unrecordedWrite, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.unrecorded == nil {
+		return unrecordedWrite{}, false
+	}
+	return *l.unrecorded, true
+}
+
+func (l *undoLog) last() (undoRecord, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.records) == 0 {
+		return undoRecord{}, false
+	}
+	return l.records[len(l.records)-1], true
+}
+
+func (l *undoLog) pop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.records) > 0 {
+		l.records = l.records[:len(l.records)-1]
+	}
+}
+
+// list returns the records newest first.
+func (l *undoLog) list() []undoRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]undoRecord, len(l.records))
+	for i, r := range l.records {
+		out[len(l.records)-1-i] = r
+	}
+	return out
+}
+
+// writeTarget is the single object a kubectl write command operates on.
+type writeTarget struct {
+	verb      string
+	kind      string
+	name      string
+	namespace string
+}
+
+// kubectlValueFlags lists flags that consume the following argument as their value.
+var kubectlValueFlags = map[string]bool{
+	"-n": true, "--namespace": true, "-l": true, "--selector": true,
+	"-p": true, "--patch": true, "-f": true, "--filename": true,
+	"-o": true, "--output": true, "--type": true, "-c": true, "--container": true,
+	"--replicas": true, "--timeout": true, "--grace-period": true,
+	"--field-selector": true, "--image": true, "--port": true, "--name": true,
+	"--min": true, "--max": true, "--cpu-percent": true, "--target-port": true,
+}
+
+// splitKubectlArgs separates positional arguments from flags. Selector, --all,
+// and -f flags make the target ambiguous.
+func splitKubectlArgs(args []string) (positional []string, flags map[string]string, ambiguous bool) {
+	flags = make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !hasValue && kubectlValueFlags[name] && i+1 < len(args) {
+			value = args[i+1]
+			i++
+		}
+		flags[name] = value
+		switch name {
+		case "-l", "--selector", "--all", "-A", "--all-namespaces", "-f", "--filename", "--field-selector":
+			ambiguous = true
+		}
+	}
+	return positional, flags, ambiguous
+}
+
+// parseWriteTarget extracts the single object a write command touches.
+// It returns false for commands that address several objects (selectors,
+// --all, multiple names, -f manifests) since those cannot be undone safely.
+func parseWriteTarget(args []string) (writeTarget, bool) {
+	if len(args) == 0 {
+		return writeTarget{}, false
+	}
+	positional, flags, ambiguous := splitKubectlArgs(args[1:])
+	if ambiguous {
+		return writeTarget{}, false
+	}
+	t := writeTarget{verb: args[0], namespace: flags["-n"]}
+	if ns := flags["--namespace"]; ns != "" {
+		t.namespace = ns
+	}
+
+	switch t.verb {
+	case "cordon", "uncordon", "drain":
+		if len(positional) != 1 {
+			return writeTarget{}, false
+		}
+		t.kind, t.name = "node", positional[0]
+		return t, true
+	case "run":
+		if len(positional) < 1 {
+			return writeTarget{}, false
+		}
+		t.kind, t.name = "pod", positional[0]
+		return t, true
+	case "create":
+		return parseCreateTarget(t, positional)
+	case "set", "rollout":
+		// "set image deployment/web ..." / "rollout restart deployment/web"
+		if len(positional) < 2 {
+			return writeTarget{}, false
+		}
+		t.verb = t.verb + " " + positional[0]
+		positional = positional[1:]
+	}
+
+	kind, name, rest, ok := parseKindName(positional)
+	if !ok {
+		return writeTarget{}, false
+	}
+	// "delete pod a b" addresses several objects; label/annotate/taint pass key=value pairs.
+	if t.verb == "delete" && len(rest) > 0 {
+		return writeTarget{}, false
+	}
+	t.kind, t.name = kind, name
+	switch t.verb {
+	case "expose":
+		t.kind = "service"
+		if n := flags["--name"]; n != "" {
+			t.name = n
+		}
+	case "autoscale":
+		t.kind = "horizontalpodautoscaler"
+		if n := flags["--name"]; n != "" {
+			t.name = n
+		}
+	}
+	return t, true
+}
+
+// parseCreateTarget handles "create <kind> [<subtype>] <name>".
+func parseCreateTarget(t writeTarget, positional []string) (writeTarget, bool) {
+	if len(positional) < 2 {
+		return writeTarget{}, false
+	}
+	t.kind, t.name = positional[0], positional[1]
+	// "create secret generic NAME" and "create service clusterip NAME" carry a subtype.
+	if (t.kind == "secret" || t.kind == "service" || t.kind == "svc") && len(positional) >= 3 {
+		t.name = positional[2]
+	}
+	return t, true
+}
+
+// parseKindName accepts either "kind/name" or "kind name" and returns the remaining positionals.
+func parseKindName(positional []string) (kind, name string, rest []string, ok bool) {
+	if len(positional) == 0 {
+		return "", "", nil, false
+	}
+	if k, n, found := strings.Cut(positional[0], "/"); found {
+		if k == "" || n == "" {
+			return "", "", nil, false
+		}
+		return k, n, positional[1:], true
+	}
+	if len(positional) < 2 {
+		return "", "", nil, false
+	}
+	return positional[0], positional[1], positional[2:], true
+}
+
+// undoActionForVerb maps a write verb to the reversal strategy, given whether
+// the object existed before the write.
+func undoActionForVerb(verb string, existedBefore bool) (undoAction, bool) {
+	switch verb {
+	case "cordon", "drain":
+		return undoUncordon, true
+	case "uncordon":
+		return undoCordon, true
+	case "scale":
+		return undoScale, existedBefore
+	case "delete":
+		return undoRecreate, existedBefore
+	case "create", "run", "expose", "autoscale":
+		return undoDelete, !existedBefore
+	default:
+		return undoReplace, existedBefore
+	}
+}
+
+// captureUndoRecord fetches the current state of the object a write command
+// is about to modify. It returns nil when the command cannot be undone.
+func captureUndoRecord(ctx context.Context, contextName string, args []string, fullCommand string) *undoRecord {
+	target, ok := parseWriteTarget(args)
+	if !ok {
+		return nil
+	}
+
+	getArgs := []string{"--context", contextName, "get", target.kind, target.name, "-o", "json"}
+	if target.namespace != "" {
+		getArgs = append(getArgs, "-n", target.namespace)
+	}
+	out, err := runKubectlCommandFunc(ctx, getArgs)
+	existed := err == nil
+
+	action, ok := undoActionForVerb(strings.Fields(target.verb)[0], existed)
+	if !ok {
+		return nil
+	}
+	record := &undoRecord{
+		Time:      time.Now(),
+		Context:   contextName,
+		Command:   fullCommand,
+		Kind:      target.kind,
+		Name:      target.name,
+		Namespace: target.namespace,
+		Action:    action,
+	}
+	if !existed {
+		return record
+	}
+
+	var obj map[string]any
+	if err := json.Unmarshal(out, &obj); err != nil {
+		return nil
+	}
+	if ns, ok := nestedString(obj, "metadata", "namespace"); ok && record.Namespace == "" {
+		record.Namespace = ns
+	}
+	if action == undoScale {
+		replicas, ok := nestedNumber(obj, "spec", "replicas")
+		if !ok {
+			return nil
+		}
+		record.Replicas = replicas
+		return record
+	}
+	manifest, err := json.Marshal(stripServerFields(obj))
+	if err != nil {
+		return nil
+	}
+	record.Manifest = manifest
+	return record
+}
+
+// stripServerFields removes fields the API server owns so the manifest can be
+// re-applied with replace or create.
+func stripServerFields(obj map[string]any) map[string]any {
+	delete(obj, "status")
+	if meta, ok := obj["metadata"].(map[string]any); ok {
+		for _, f := range []string{"resourceVersion", "uid", "creationTimestamp", "generation", "managedFields", "selfLink"} {
+			delete(meta, f)
+		}
+	}
+	return obj
+}
+
+func nestedString(obj map[string]any, path ...string) (string, bool) {
+	v, ok := nestedValue(obj, path...)
+	if !ok {
+		return "", false
+	}
+	s, ok := v.(string)
+	return s, ok
+}
+
+func nestedNumber(obj map[string]any, path ...string) (int64, bool) {
+	v, ok := nestedValue(obj, path...)
+	if !ok {
+		return 0, false
+	}
+	f, ok := v.(float64)
+	return int64(f), ok
+}
+
+func nestedValue(obj map[string]any, path ...string) (any, bool) {
+	var cur any = obj
+	for _, p := range path {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		cur, ok = m[p]
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// buildUndoArgs returns the kubectl arguments (without --context) that reverse r.
+// manifestPath must point to r.Manifest on disk for replace and recreate actions.
+func buildUndoArgs(r undoRecord, manifestPath string) []string {
+	var args []string
+	switch r.Action {
+	case undoScale:
+		args = []string{"scale", r.target(), fmt.Sprintf("--replicas=%d", r.Replicas)}
+	case undoReplace:
+		return []string{"replace", "-f", manifestPath}
+	case undoRecreate:
+		return []string{"create", "-f", manifestPath}
+	case undoDelete:
+		args = []string{"delete", r.target()}
+	case undoUncordon:
+		return []string{"uncordon", r.Name}
+	case undoCordon:
+		return []string{"cordon", r.Name}
+	}
+	if r.Namespace != "" {
+		args = append(args, "-n", r.Namespace)
+	}
+	return args
+}
+
+// describeUndo returns a one-line explanation of what undoing r will do.
+func describeUndo(r undoRecord) string {
+	switch r.Action {
+	case undoScale:
+		return fmt.Sprintf("scale %s back to %d replica(s)", r.target(), r.Replicas)
+	case undoReplace:
+		return fmt.Sprintf("restore the previous manifest of %s", r.target())
+	case undoRecreate:
+		return fmt.Sprintf("re-create deleted %s from its previous manifest", r.target())
+	case undoDelete:
+		return fmt.Sprintf("delete %s created by the operation", r.target())
+	case undoUncordon:
+		return fmt.Sprintf("uncordon node %s", r.Name)
+	case undoCordon:
+		return fmt.Sprintf("cordon node %s", r.Name)
+	}
+	return "unknown undo action"
+}
+
+// summary returns the tool and the first line of the command of w.
+func (w unrecordedWrite) summary() string {
+	command, _, _ := strings.Cut(w.Command, "\n")
+	return fmt.Sprintf("%s (%s)", w.Tool, command)
+}
+
+// describeUnrecorded explains that w, the latest write, cannot be undone.
+func describeUnrecorded(w unrecordedWrite) string {
+	return fmt.Sprintf("the latest write, %s, cannot be undone: %s", w.summary(), w.Reason)
+}
+
+// UndoResult defines JSON output for undo_last_operation
+type UndoResult struct {
+	Undone  undoRecord `json:"undone"`
+	Command string     `json:"command"`
+	Output  string     `json:"output"`
+	// NotUndone is a later write that could not be undone, if any.
+	NotUndone *unrecordedWrite `json:"not_undone,omitempty"`
+}
+
+// performUndo reverses the most recent recorded write after the usual
+// execution-mode checks and confirmation. When a later write could not be
+// recorded, it says so before asking, since that write stays in place.
+func performUndo(ctx context.Context, k8sProvider *k8s.Provider, state *agentState) (any, error) {
+	record, ok := state.undo.last()
+	skipped, hasSkipped := state.undo.lastUnrecorded()
+	if !ok {
+		if hasSkipped {
+			return fmt.Sprintf("Nothing to undo — %s. %s", describeUnrecorded(skipped), undoUnsupportedNote()), nil
+		}
+		return "Nothing to undo — no write operations have been recorded in this session. " + undoUnsupportedNote(), nil
+	}
+	var note string
+	if hasSkipped {
+		note = fmt.Sprintf("Note: %s; undoing the latest recorded write instead.", describeUnrecorded(skipped))
+		if !isJSONOutput(state.outputFormat) {
+			fmt.Fprintf(state.stdout(), "  %s⚠ %s%s\n", colorYellow, note, colorReset)
+		}
+	}
+
+	clusterName := record.Context
+	if k8sProvider != nil {
+		if cluster, err := k8sProvider.GetClusterByContext(record.Context); err == nil {
+			clusterName = cluster.Name
+		}
+	}
+
+	fullCommand, cmdArgs, cleanup, err := undoCommand(record)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	proceed, cancelResult, err := enforceToolExecutionMode(ctx, state, toolUndoLastOperation, false, clusterName, record.Context, fullCommand)
+	if err != nil {
+		return nil, err
+	}
+	if !proceed {
+		return cancelResult, nil
+	}
+
+	printExecutionHeader(state, false, fullCommand)
+	output, execErr := runKubectlCommandFunc(ctx, cmdArgs)
+	state.publishWrite(ApprovalRequest{Tool: toolUndoLastOperation, Cluster: clusterName, Context: record.Context, Command: fullCommand}, execErr)
+	if execErr != nil {
+		return nil, fmt.Errorf("undo failed for %q: %w\n%s", record.Command, execErr, string(output))
+	}
+	state.undo.pop()
+
+	if isJSONOutput(state.outputFormat) {
+		result := UndoResult{Undone: record, Command: fullCommand, Output: string(output)}
+		if hasSkipped {
+			result.NotUndone = &skipped
+		}
+		return result, nil
+	}
+	text := fmt.Sprintf("↩️  Undid: %s\n   (%s)\n\n%s", record.Command, describeUndo(record), string(output))
+	if note != "" {
+		text = note + "\n" + text
+	}
+	return text, nil
+}
+
+// undoCommand builds the kubectl invocation that reverses record, staging the
+// recorded manifest in a temporary file when needed. The returned cleanup
+// function removes that file and must always be called on success.
+func undoCommand(record undoRecord) (string, []string, func(), error) {
+	cleanup := func() {}
+	manifestPath := ""
+	if record.Action == undoReplace || record.Action == undoRecreate {
+		f, err := os.CreateTemp("", "kopilot-undo-*.json")
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("failed to stage undo manifest: %w", err)
+		}
+		manifestPath = f.Name()
+		cleanup = func() { _ = os.Remove(manifestPath) }
+		_, writeErr := f.Write(record.Manifest)
+		if err := errors.Join(writeErr, f.Close()); err != nil {
+			cleanup()
+			return "", nil, nil, fmt.Errorf("failed to stage undo manifest: %w", err)
+		}
+	}
+	fullCommand, cmdArgs := buildKubectlCommand(record.Context, buildUndoArgs(record, manifestPath))
+	return fullCommand, cmdArgs, cleanup, nil
+}
+
+// UndoLastOperationParams defines no parameters for undo_last_operation
+type UndoLastOperationParams struct{}
+
+func defineUndoLastOperationTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolUndoLastOperation,
+		"Undo the most recent successful write operation performed in this session (restores the previous manifest, reverses a scale, re-creates a deleted object, or removes a created one). Requires the same confirmation as any other write. "+undoUnsupportedNote()+" When the latest write is one of those, tell the user it stays in place before undoing an earlier one.",
+		func(_ UndoLastOperationParams, inv llm.ToolInvocation) (any, error) {
+			return performUndo(inv.Ctx(), k8sProvider, state)
+		},
+	)
+}
+
+// handleUndoCommand processes "/undo" (reverse the last write) and "/undo list".
+func handleUndoCommand(deps *loopDeps, input string) (bool, error) {
+	parts := strings.Fields(input)
+	if len(parts) >= 2 && strings.ToLower(parts[1]) == subCmdList {
+		printUndoList(deps.state)
+		return true, nil
+	}
+	result, err := performUndo(deps.ctx, deps.k8sProvider, deps.state)
+	if err != nil {
+		fmt.Printf(fmtErrorBullet, colorRed, colorReset, err)
+		return true, nil
+	}
+	if text, ok := result.(string); ok {
+		fmt.Println(text)
+	}
+	return true, nil
+}
+
+// printUndoList shows the recorded write operations, newest first.
+func printUndoList(state *agentState) {
+	records := state.undo.list()
+	skipped, hasSkipped := state.undo.lastUnrecorded()
+	if len(records) == 0 && !hasSkipped {
+		fmt.Printf("  %s●%s No write operations recorded in this session\n", colorDim, colorReset)
+		fmt.Printf("    %s%s%s\n", colorDim, undoUnsupportedNote(), colorReset)
+		return
+	}
+	fmt.Println()
+	fmt.Printf("  %s%s%s\n", colorCyan, sectionRule("Undo History"), colorReset)
+	fmt.Println()
+	if hasSkipped {
+		fmt.Printf("  %s⚠%s %s %s\n", colorYellow, colorReset, skipped.Time.Format("15:04:05"), skipped.summary())
+		fmt.Printf("      %snot undoable: %s%s\n", colorDim, skipped.Reason, colorReset)
+	}
+	for i, r := range records {
+		fmt.Printf("  %s[%d]%s %s %s\n", colorCyan, i+1, colorReset, r.Time.Format("15:04:05"), r.Command)
+		fmt.Printf("      %sundo: %s%s\n", colorDim, describeUndo(r), colorReset)
+	}
+	fmt.Println()
+	fmt.Printf("  %s%s%s\n", colorDim, undoUnsupportedNote(), colorReset)
+	fmt.Println()
+}
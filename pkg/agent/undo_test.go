@@ -0,0 +1,308 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseWriteTarget(t *testing.T) {
+	tests := []struct {
+		args   []string
+		want   writeTarget
+		wantOK bool
+	}{
+		{[]string{"scale", "deployment/web", "--replicas=3", "-n", "prod"}, writeTarget{"scale", "deployment", "web", "prod"}, true},
+		{[]string{"scale", "deployment", "web", "--replicas", "3"}, writeTarget{"scale", "deployment", "web", ""}, true},
+		{[]string{"delete", "pod", "web-1", "--namespace=default"}, writeTarget{"delete", "pod", "web-1", "default"}, true},
+		{[]string{"label", "pod/web-1", "tier=frontend"}, writeTarget{"label", "pod", "web-1", ""}, true},
+		{[]string{"set", "image", "deployment/web", "app=nginx:1.27"}, writeTarget{"set image", "deployment", "web", ""}, true},
+		{[]string{"rollout", "restart", "deployment/web"}, writeTarget{"rollout restart", "deployment", "web", ""}, true},
+		{[]string{"cordon", "node-1"}, writeTarget{"cordon", "node", "node-1", ""}, true},
+		{[]string{"create", "secret", "generic", "creds", "--from-literal=a=b"}, writeTarget{"create", "secret", "creds", ""}, true},
+		{[]string{"create", "namespace", "team-a"}, writeTarget{"create", "namespace", "team-a", ""}, true},
+		{[]string{"run", "debug", "--image=busybox"}, writeTarget{"run", "pod", "debug", ""}, true},
+		{[]string{"expose", "deployment", "web", "--port", "80", "--name", "web-svc"}, writeTarget{"expose", "service", "web-svc", ""}, true},
+		// Multi-object or manifest-driven writes cannot be undone reliably.
+		{[]string{"delete", "pod", "a", "b"}, writeTarget{}, false},
+		{[]string{"delete", "pods", "-l", "app=web"}, writeTarget{}, false},
+		{[]string{"apply", "-f", "web.yaml"}, writeTarget{}, false},
+		{[]string{"cordon", "node-1", "node-2"}, writeTarget{}, false},
+		{[]string{"scale", "deployment"}, writeTarget{}, false},
+		{nil, writeTarget{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseWriteTarget(tt.args)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("parseWriteTarget(%v) = %+v, %v; want %+v, %v", tt.args, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestStripServerFields(t *testing.T) {
+	obj := map[string]any{
+		"kind": "ConfigMap",
+		"metadata": map[string]any{
+			"name":              "cfg",
+			"resourceVersion":   "42",
+			"uid":               "abc",
+			"creationTimestamp": "2024-01-01T00:00:00Z",
+			"managedFields":     []any{},
+		},
+		"status": map[string]any{"phase": "Active"},
+	}
+	got := stripServerFields(obj)
+	meta := got["metadata"].(map[string]any)
+	for _, f := range []string{"resourceVersion", "uid", "creationTimestamp", "managedFields"} {
+		if _, ok := meta[f]; ok {
+			t.Errorf("metadata.%s should be stripped", f)
+		}
+	}
+	if _, ok := got["status"]; ok {
+		t.Error("status should be stripped")
+	}
+	if meta["name"] != "cfg" {
+		t.Error("metadata.name must be preserved")
+	}
+}
+
+func TestBuildUndoArgs(t *testing.T) {
+	tests := []struct {
+		record undoRecord
+		want   string
+	}{
+		{undoRecord{Kind: "deployment", Name: "web", Namespace: "prod", Action: undoScale, Replicas: 2}, "scale deployment/web --replicas=2 -n prod"},
+		{undoRecord{Kind: "pod", Name: "debug", Action: undoDelete}, "delete pod/debug"},
+		{undoRecord{Kind: "node", Name: "node-1", Action: undoUncordon}, "uncordon node-1"},
+		{undoRecord{Kind: "configmap", Name: "cfg", Action: undoReplace}, "replace -f /tmp/m.json"},
+		{undoRecord{Kind: "configmap", Name: "cfg", Action: undoRecreate}, "create -f /tmp/m.json"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(buildUndoArgs(tt.record, "/tmp/m.json"), " "); got != tt.want {
+			t.Errorf("buildUndoArgs(%s) = %q, want %q", tt.record.Action, got, tt.want)
+		}
+	}
+}
+
+func TestUndoLogBounded(t *testing.T) {
+	var l undoLog
+	for i := 0; i < maxUndoRecords+5; i++ {
+		l.push(undoRecord{Replicas: int64(i)})
+	}
+	if n := len(l.list()); n != maxUndoRecords {
+		t.Fatalf("undo log kept %d records, want %d", n, maxUndoRecords)
+	}
+	last, ok := l.last()
+	if !ok || last.Replicas != maxUndoRecords+4 {
+		t.Errorf("last() = %+v, %v", last, ok)
+	}
+	l.pop()
+	if last, _ := l.last(); last.Replicas != maxUndoRecords+3 {
+		t.Errorf("after pop last() = %+v", last)
+	}
+}
+
+func TestUndoScaleRoundTrip(t *testing.T) {
+	provider := newTestK8sProvider(t)
+
+	originalRunner := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = originalRunner })
+
+	var calls []string
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
+		cmd := strings.Join(args, " ")
+		calls = append(calls, cmd)
+		if strings.Contains(cmd, " get ") {
+			return []byte(`{"metadata":{"name":"web","namespace":"prod"},"spec":{"replicas":2}}`), nil
+		}
+		return []byte("ok\n"), nil
+	}
+
+	allow := &stubApprover{name: ApprovalLocal, decision: ApprovalDecision{Approved: true}}
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON, approver: allow}
+
+	if _, err := handleKubectlExec(context.Background(), provider, state, KubectlExecParams{
+		Context: "test-context",
+		Args:    []string{"scale", "deployment/web", "--replicas=5", "-n", "prod"},
+	}); err != nil {
+		t.Fatalf("handleKubectlExec returned error: %v", err)
+	}
+	record, ok := state.undo.last()
+	if !ok || record.Action != undoScale || record.Replicas != 2 {
+		t.Fatalf("expected scale record with 2 replicas, got %+v (ok=%v)", record, ok)
+	}
+
+	result, err := performUndo(context.Background(), provider, state)
+	if err != nil {
+		t.Fatalf("performUndo returned error: %v", err)
+	}
+	if _, ok := result.(UndoResult); !ok {
+		t.Fatalf("result should be UndoResult, got %T", result)
+	}
+	want := "--context test-context scale deployment/web --replicas=2 -n prod"
+	if calls[len(calls)-1] != want {
+		t.Errorf("undo command = %q, want %q", calls[len(calls)-1], want)
+	}
+	if _, ok := state.undo.last(); ok {
+		t.Error("undo record should be removed after a successful undo")
+	}
+}
+
+func TestUndoReplaceStagesManifest(t *testing.T) {
+	provider := newTestK8sProvider(t)
+
+	originalRunner := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = originalRunner })
+
+	var staged map[string]any
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
+		if args[2] == "replace" {
+			data, err := os.ReadFile(args[4])
+			if err != nil {
+				t.Fatalf("reading staged manifest: %v", err)
+			}
+			_ = json.Unmarshal(data, &staged)
+		}
+		return []byte("ok\n"), nil
+	}
+
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputText,
+		approver: &stubApprover{name: ApprovalLocal, decision: ApprovalDecision{Approved: true}}}
+	state.undo.push(undoRecord{
+		Context:  "test-context",
+		Command:  "kubectl --context test-context label configmap/cfg a=b",
+		Kind:     "configmap",
+		Name:     "cfg",
+		Action:   undoReplace,
+		Manifest: []byte(`{"kind":"ConfigMap","metadata":{"name":"cfg"}}`),
+	})
+
+	if _, err := performUndo(context.Background(), provider, state); err != nil {
+		t.Fatalf("performUndo returned error: %v", err)
+	}
+	if staged["kind"] != "ConfigMap" {
+		t.Errorf("replace did not receive the recorded manifest: %v", staged)
+	}
+}
+
+func TestUndoFailureKeepsRecord(t *testing.T) {
+	provider := newTestK8sProvider(t)
+
+	originalRunner := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = originalRunner })
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
+		return []byte("forbidden"), errors.New("exit status 1")
+	}
+
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON,
+		approver: &stubApprover{name: ApprovalLocal, decision: ApprovalDecision{Approved: true}}}
+	state.undo.push(undoRecord{Context: "test-context", Kind: "node", Name: "n1", Action: undoUncordon})
+
+	if _, err := performUndo(context.Background(), provider, state); err == nil {
+		t.Fatal("expected error when the undo command fails")
+	}
+	if _, ok := state.undo.last(); !ok {
+		t.Error("record should be kept when undo fails")
+	}
+}
+
+func TestPerformUndoEmpty(t *testing.T) {
+	state := &agentState{outputFormat: OutputText}
+	result, err := performUndo(context.Background(), nil, state)
+	if err != nil {
+		t.Fatalf("performUndo returned error: %v", err)
+	}
+	if text, _ := result.(string); !strings.Contains(text, "Nothing to undo") {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
+
+func TestPerformUndoAfterUnrecordedWrite(t *testing.T) {
+	provider := newTestK8sProvider(t)
+	originalRunner := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = originalRunner })
+	var calls []string
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
+		calls = append(calls, strings.Join(args, " "))
+		return []byte("ok\n"), nil
+	}
+
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON,
+		approver: &stubApprover{name: ApprovalLocal, decision: ApprovalDecision{Approved: true}}}
+	state.publishWrite(ApprovalRequest{Tool: toolRestartWorkload, Context: "test-context", Command: "kubectl rollout restart deployment/web"}, nil)
+	result, err := performUndo(context.Background(), provider, state)
+	if text, _ := result.(string); err != nil || !strings.Contains(text, "restart_workload (kubectl rollout restart deployment/web), cannot be undone") {
+		t.Errorf("undo after an unrecorded write = %v, %v", result, err)
+	}
+
+	state.undo.push(undoRecord{Context: "test-context", Kind: "node", Name: "n1", Action: undoUncordon})
+	state.publishWrite(ApprovalRequest{Tool: toolBulkDelete, Context: "test-context", Command: "delete 3 job(s)\njob/a"}, nil)
+	result, err = performUndo(context.Background(), provider, state)
+	if err != nil {
+		t.Fatalf("performUndo returned error: %v", err)
+	}
+	undone, ok := result.(UndoResult)
+	if !ok || undone.NotUndone == nil || undone.NotUndone.Tool != toolBulkDelete || len(calls) != 1 {
+		t.Errorf("undo = %+v, want the uncordon undone and the bulk delete reported", result)
+	}
+
+	state.undo.push(undoRecord{Context: "test-context", Kind: "node", Name: "n1", Action: undoUncordon})
+	if _, ok := state.undo.lastUnrecorded(); ok {
+		t.Error("a recorded write should clear the unrecorded one before it")
+	}
+}
+
+func TestPerformUndoAfterChaosAndUncapturedPlanStep(t *testing.T) {
+	stubChaosCluster(t)
+	provider := newTestK8sProvider(t)
+	originalRunner := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = originalRunner })
+	var undone []string
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
+		if slices.Contains(args, "uncordon") {
+			undone = append(undone, strings.Join(args, " "))
+		}
+		return []byte("ok\n"), nil
+	}
+
+	state := &agentState{mode: ModeInteractive, outputFormat: OutputJSON,
+		approver: &stubApprover{name: ApprovalLocal, decision: ApprovalDecision{Approved: true}}}
+	undoAfter := func(write func() error) *unrecordedWrite {
+		t.Helper()
+		state.undo.push(undoRecord{Context: "test-context", Kind: "node", Name: "n1", Action: undoUncordon})
+		if err := write(); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		result, err := performUndo(context.Background(), provider, state)
+		if err != nil {
+			t.Fatalf("performUndo: %v", err)
+		}
+		return result.(UndoResult).NotUndone
+	}
+
+	notUndone := undoAfter(func() error {
+		_, err := handleChaos(context.Background(), provider, state, ChaosParams{Context: "test-context", Action: chaosActionDeletePod, Namespace: "shop", Deployment: "web"})
+		return err
+	})
+	if notUndone == nil || notUndone.Tool != toolChaos {
+		t.Errorf("undo after a chaos drill reported %+v, want the chaos write", notUndone)
+	}
+
+	notUndone = undoAfter(func() error {
+		_, err := handleExecutePlan(context.Background(), provider, state, ExecutePlanParams{
+			Context: "test-context",
+			Steps:   []PlanStep{{Description: "apply manifests", Args: []string{"apply", "-f", "deploy/web.yaml"}}},
+		})
+		return err
+	})
+	if notUndone == nil || notUndone.Tool != toolExecutePlan || !strings.Contains(notUndone.Command, "apply -f deploy/web.yaml") {
+		t.Errorf("undo after an uncaptured plan step reported %+v, want the plan step", notUndone)
+	}
+	if len(undone) != 2 {
+		t.Errorf("undo should still revert the earlier recorded writes: %v", undone)
+	}
+}
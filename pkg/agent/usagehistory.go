@@ -0,0 +1,165 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the on-disk usage sample history and its background sampler.
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+// unsafeFileChars matches characters that may not appear in a history file name;
+// context names often contain ':' and '/' (e.g. EKS ARNs).
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// usageHistoryDir returns the directory holding usage samples (~/.kopilot/usage).
+// It is a variable so tests can redirect it.
+var usageHistoryDir = func() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kopilot", "usage"), nil
+}
+
+const (
+	// defaultUsageHistoryRetention is how long samples are kept when
+	// KOPILOT_USAGE_HISTORY=on; twice recommend_resources' default window.
+	defaultUsageHistoryRetention = 14 * 24 * time.Hour
+	// maxUsageSamples caps the samples kept per context: cluster-wide
+	// samples list every container, so they are far larger than health records.
+	maxUsageSamples = 5000
+)
+
+// usageHistoryRetention returns how long usage samples are kept, and
+// whether they are recorded at all; see historyRetention.
+func usageHistoryRetention() (time.Duration, bool) {
+	return historyRetention("KOPILOT_USAGE_HISTORY", defaultUsageHistoryRetention)
+}
+
+// usageHistoryFile returns the JSONL file holding samples for contextName.
+func usageHistoryFile(contextName string) (string, error) {
+	dir, err := usageHistoryDir()
+	if err != nil {
+		return "", err
+	}
+	return historyFile(dir, contextName)
+}
+
+// appendUsageSample appends sample to its context's history file, first
+// dropping the samples older than retention.
+func appendUsageSample(sample *k8s.UsageSample, retention time.Duration) error {
+	path, err := usageHistoryFile(sample.Context)
+	if err != nil {
+		return fmt.Errorf("failed to open usage history: %w", err)
+	}
+	line, err := json.Marshal(sample)
+	if err != nil {
+		return err
+	}
+	if err := appendHistoryLine(path, line, sample.Time.Add(-retention), maxUsageSamples); err != nil {
+		return fmt.Errorf("failed to write usage history: %w", err)
+	}
+	return nil
+}
+
+// loadUsageSamples returns the samples for contextName taken since `since`,
+// restricted to containers in namespace. Samples that saw no containers in
+// the namespace are dropped. Unparseable lines are skipped.
+func loadUsageSamples(contextName, namespace string, since time.Time) ([]k8s.UsageSample, error) {
+	path, err := usageHistoryFile(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage history: %w", err)
+	}
+	// #nosec G304 -- path is built from the kopilot data dir and a sanitized context name
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage history: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var samples []k8s.UsageSample
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var s k8s.UsageSample
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil || s.Time.Before(since) {
+			continue
+		}
+		kept := s.Containers[:0]
+		for _, c := range s.Containers {
+			if c.Namespace == namespace {
+				kept = append(kept, c)
+			}
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		s.Containers, s.Namespace = kept, namespace
+		samples = append(samples, s)
+	}
+	return samples, scanner.Err()
+}
+
+// sampleUsageFunc takes a usage snapshot; it is a variable so tests can stub the metrics API.
+var sampleUsageFunc = func(ctx context.Context, p *k8s.Provider, contextName, namespace string) (*k8s.UsageSample, error) {
+	return p.SampleUsage(ctx, contextName, namespace)
+}
+
+// usageSampleInterval returns the background sampling interval.
+// KOPILOT_USAGE_SAMPLE_INTERVAL accepts any value parseable by time.ParseDuration
+// (e.g. "5m"). Absent, invalid, zero or negative values disable the sampler,
+// as does a disabled usage history, which is what it records to.
+func usageSampleInterval() time.Duration {
+	if _, enabled := usageHistoryRetention(); !enabled {
+		return 0
+	}
+	d, err := time.ParseDuration(os.Getenv("KOPILOT_USAGE_SAMPLE_INTERVAL"))
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return max(d, time.Minute)
+}
+
+// startUsageSampler records a cluster-wide usage sample for the current context
+// every interval until ctx is cancelled. It stops after the first failure,
+// which usually means metrics-server is not installed.
+func startUsageSampler(ctx context.Context, k8sProvider *k8s.Provider, interval time.Duration) {
+	retention, enabled := usageHistoryRetention()
+	if interval <= 0 || !enabled {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			sample, err := sampleUsageFunc(ctx, k8sProvider, k8sProvider.GetCurrentContext(), "")
+			if err == nil {
+				err = appendUsageSample(sample, retention)
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Usage sampler stopped: %v", err)
+				}
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
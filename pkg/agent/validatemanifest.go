@@ -0,0 +1,181 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the validate_manifest tool (server-side dry-run validation).
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// kustomizationFiles are the file names that mark a directory as a kustomization.
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// ValidateManifestParams defines parameters for validate_manifest
+type ValidateManifestParams struct {
+	Context   string `json:"context" jsonschema:"The cluster context to validate against (required)"`
+	Manifest  string `json:"manifest,omitempty" jsonschema:"YAML manifest to validate; may contain several documents separated by ---"`
+	Path      string `json:"path,omitempty" jsonschema:"Local manifest file, directory, or kustomization directory to validate instead of manifest"`
+	Namespace string `json:"namespace,omitempty" jsonschema:"Namespace for objects that do not set one"`
+}
+
+// ValidateManifestResult reports the outcome of a server-side dry run.
+type ValidateManifestResult struct {
+	Cluster  string   `json:"cluster"`
+	Context  string   `json:"context"`
+	Source   string   `json:"source"`
+	Valid    bool     `json:"valid"`
+	Objects  []string `json:"objects"`
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+}
+
+func defineValidateManifestTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolValidateManifest,
+		"Validate YAML manifests against a cluster with a server-side dry run (schema, unknown fields, admission webhooks and policies) without changing anything. Accepts inline YAML or a local file, directory, or kustomization path. Use this to verify manifests before suggesting an apply.",
+		func(params ValidateManifestParams, inv llm.ToolInvocation) (any, error) {
+			return handleValidateManifest(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+func handleValidateManifest(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params ValidateManifestParams) (any, error) {
+	if params.Context == "" {
+		return nil, fmt.Errorf("context is required")
+	}
+	if (params.Manifest == "") == (params.Path == "") {
+		return nil, fmt.Errorf("provide exactly one of manifest or path")
+	}
+	if params.Namespace != "" && !isValidKubernetesName(params.Namespace) {
+		return nil, fmt.Errorf("invalid namespace name: %s", params.Namespace)
+	}
+	cluster, err := getClusterForContext(k8sProvider, params.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	source, sourceArgs, cleanup, err := manifestSourceArgs(params)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	args := append([]string{"apply", "--dry-run=server", "--validate=strict"}, sourceArgs...)
+	if params.Namespace != "" {
+		args = append(args, "-n", params.Namespace)
+	}
+	fullCommand, cmdArgs := buildKubectlCommand(params.Context, args)
+	printExecutionHeader(state, true, fullCommand)
+
+	output, execErr := runKubectlCommandFunc(ctx, cmdArgs)
+	result := parseDryRunOutput(string(output), execErr)
+	result.Cluster = cluster.Name
+	result.Context = params.Context
+	result.Source = source
+
+	if isJSONOutput(state.outputFormat) {
+		return result, nil
+	}
+	return formatValidateManifestResult(result), nil
+}
+
+// manifestSourceArgs returns a description of the manifest source and the
+// kubectl -f/-k arguments for it, staging inline YAML in a temporary file.
+func manifestSourceArgs(params ValidateManifestParams) (string, []string, func(), error) {
+	noop := func() {}
+	if params.Manifest != "" {
+		f, err := os.CreateTemp("", "kopilot-validate-*.yaml")
+		if err != nil {
+			return "", nil, noop, fmt.Errorf("failed to stage manifest: %w", err)
+		}
+		path := f.Name()
+		cleanup := func() { _ = os.Remove(path) }
+		_, writeErr := f.WriteString(params.Manifest)
+		if err := errors.Join(writeErr, f.Close()); err != nil {
+			cleanup()
+			return "", nil, noop, fmt.Errorf("failed to stage manifest: %w", err)
+		}
+		return "inline manifest", []string{"-f", path}, cleanup, nil
+	}
+
+	path := params.Path
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, noop, fmt.Errorf("cannot read %s: %w", params.Path, err)
+	}
+	if !info.IsDir() {
+		return path, []string{"-f", path}, noop, nil
+	}
+	for _, name := range kustomizationFiles {
+		if _, err := os.Stat(filepath.Join(path, name)); err == nil {
+			return path + " (kustomize)", []string{"-k", path}, noop, nil
+		}
+	}
+	return path, []string{"-f", path, "--recursive"}, noop, nil
+}
+
+// parseDryRunOutput splits kubectl apply --dry-run=server output into
+// validated objects, errors, and warnings.
+func parseDryRunOutput(output string, execErr error) ValidateManifestResult {
+	result := ValidateManifestResult{Objects: []string{}, Errors: []string{}, Warnings: []string{}}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "Warning:"):
+			result.Warnings = append(result.Warnings, strings.TrimSpace(strings.TrimPrefix(line, "Warning:")))
+		case strings.HasSuffix(line, "(server dry run)"):
+			result.Objects = append(result.Objects, strings.TrimSpace(strings.TrimSuffix(line, "(server dry run)")))
+		default:
+			// Everything else is an error line ("Error from server ...", "error: ...")
+			// or the continuation of one.
+			result.Errors = append(result.Errors, line)
+		}
+	}
+	if execErr != nil && len(result.Errors) == 0 {
+		result.Errors = append(result.Errors, execErr.Error())
+	}
+	result.Valid = execErr == nil && len(result.Errors) == 0
+	return result
+}
+
+// formatValidateManifestResult formats a ValidateManifestResult as human-readable text
+func formatValidateManifestResult(r ValidateManifestResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Manifest Validation: %s → %s (%s)\n", r.Source, r.Cluster, r.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	if r.Valid {
+		fmt.Fprintf(&sb, "✅ VALID: %d object(s) accepted by the API server (dry run, nothing changed)\n", len(r.Objects))
+	} else {
+		fmt.Fprintf(&sb, "❌ INVALID: %d error(s)\n", len(r.Errors))
+		for _, e := range r.Errors {
+			fmt.Fprintf(&sb, "  • %s\n", e)
+		}
+	}
+	if len(r.Objects) > 0 {
+		sb.WriteString("\n📦 OBJECTS:\n")
+		for _, o := range r.Objects {
+			fmt.Fprintf(&sb, "  • %s\n", o)
+		}
+	}
+	if len(r.Warnings) > 0 {
+		sb.WriteString("\n⚠️  WARNINGS:\n")
+		for _, w := range r.Warnings {
+			fmt.Fprintf(&sb, "  • %s\n", w)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDryRunOutput(t *testing.T) {
+	ok := parseDryRunOutput("deployment.apps/web created (server dry run)\nWarning: spec.template.spec.nodeSelector[beta.kubernetes.io/os]: deprecated\nservice/web unchanged (server dry run)\n", nil)
+	if !ok.Valid || len(ok.Objects) != 2 || len(ok.Warnings) != 1 || len(ok.Errors) != 0 {
+		t.Errorf("valid output parsed as %+v", ok)
+	}
+
+	bad := parseDryRunOutput(`Error from server (BadRequest): error when creating "m.yaml": Deployment in version "v1" cannot be handled as a Deployment: strict decoding error: unknown field "spec.replica"`+"\n", errors.New("exit status 1"))
+	if bad.Valid || len(bad.Errors) != 1 || !strings.Contains(bad.Errors[0], "unknown field") {
+		t.Errorf("invalid output parsed as %+v", bad)
+	}
+
+	silent := parseDryRunOutput("", errors.New("exit status 1"))
+	if silent.Valid || len(silent.Errors) != 1 {
+		t.Errorf("a failing command without output should still be invalid: %+v", silent)
+	}
+}
+
+func TestManifestSourceArgs(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(file, []byte("kind: ConfigMap\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	kustomize := filepath.Join(dir, "overlay")
+	if err := os.Mkdir(kustomize, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(kustomize, "kustomization.yaml"), []byte("resources: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{file, "-f " + file},
+		{kustomize, "-k " + kustomize},
+		{dir, "-f " + dir + " --recursive"},
+	}
+	for _, tt := range tests {
+		_, args, cleanup, err := manifestSourceArgs(ValidateManifestParams{Path: tt.path})
+		if err != nil {
+			t.Fatalf("manifestSourceArgs(%s) error = %v", tt.path, err)
+		}
+		cleanup()
+		if got := strings.Join(args, " "); got != tt.want {
+			t.Errorf("manifestSourceArgs(%s) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	if _, _, _, err := manifestSourceArgs(ValidateManifestParams{Path: filepath.Join(dir, "missing")}); err == nil {
+		t.Error("missing path should be an error")
+	}
+
+	_, args, cleanup, err := manifestSourceArgs(ValidateManifestParams{Manifest: "kind: ConfigMap\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	staged := args[1]
+	if data, err := os.ReadFile(staged); err != nil || string(data) != "kind: ConfigMap\n" {
+		t.Errorf("inline manifest not staged: %q, %v", data, err)
+	}
+	cleanup()
+	if _, err := os.Stat(staged); !os.IsNotExist(err) {
+		t.Error("cleanup should remove the staged manifest")
+	}
+}
+
+func TestHandleValidateManifest(t *testing.T) {
+	provider := newTestK8sProvider(t)
+	state := &agentState{outputFormat: OutputJSON}
+
+	if _, err := handleValidateManifest(context.Background(), provider, state, ValidateManifestParams{Context: "test-context"}); err == nil {
+		t.Error("missing manifest and path should be rejected")
+	}
+
+	original := runKubectlCommandFunc
+	t.Cleanup(func() { runKubectlCommandFunc = original })
+	var gotArgs []string
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
+		gotArgs = args
+		return []byte("configmap/app created (server dry run)\n"), nil
+	}
+
+	out, err := handleValidateManifest(context.Background(), provider, state, ValidateManifestParams{Context: "test-context", Manifest: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n", Namespace: "shop"})
+	if err != nil {
+		t.Fatalf("handleValidateManifest error: %v", err)
+	}
+	result := out.(ValidateManifestResult)
+	if !result.Valid || len(result.Objects) != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	cmd := strings.Join(gotArgs, " ")
+	for _, want := range []string{"--context test-context apply", "--dry-run=server", "--validate=strict", "-n shop"} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("command %q missing %q", cmd, want)
+		}
+	}
+
+	if text := formatValidateManifestResult(result); !strings.Contains(text, "✅ VALID: 1 object(s)") {
+		t.Errorf("formatted result:\n%s", text)
+	}
+}
@@ -6,8 +6,36 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// KubectlPolicy selects how strictly the arguments of kubectl_exec and
+// execute_plan commands are checked.
+type KubectlPolicy string
+
+const (
+	// KubectlStrict, the default, allows only the subcommands in
+	// allowedCommands and rejects shell syntax in any argument.
+	KubectlStrict KubectlPolicy = "strict"
+	// KubectlPermissive also allows other subcommands, such as kubectl
+	// plugins, and shell characters, which are harmless because kubectl
+	// runs without a shell. Namespace, bulk delete and credential flag
+	// checks still apply, and writes still need confirmation.
+	KubectlPermissive KubectlPolicy = "permissive"
 )
 
+// ParseKubectlPolicy parses a kubectl policy name; empty means strict.
+func ParseKubectlPolicy(value string) (KubectlPolicy, error) {
+	switch p := KubectlPolicy(strings.ToLower(strings.TrimSpace(value))); p {
+	case "":
+		return KubectlStrict, nil
+	case KubectlStrict, KubectlPermissive:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown kubectl policy %q (use %s or %s)", value, KubectlStrict, KubectlPermissive)
+}
+
 var (
 	// kubernetesNameRegexp validates a Kubernetes resource name per RFC 1123 DNS subdomain rules.
 	kubernetesNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
@@ -29,17 +57,19 @@ var (
 		"certificate": true,
 	}
 
-	// Command injection patterns to block
-	injectionPatterns = []*regexp.Regexp{
-		regexp.MustCompile(`[;&|]`),      // Shell operators
-		regexp.MustCompile(`\$\(`),       // Command substitution
-		regexp.MustCompile("`"),          // Backticks
-		regexp.MustCompile(`>\s*/`),      // File redirect to root
-		regexp.MustCompile(`<\s*/`),      // File read from root
-		regexp.MustCompile(`\.\./`),      // Directory traversal
-		regexp.MustCompile(`~[^/\s]*\/`), // Home directory expansion
-		regexp.MustCompile(`\$\{`),       // Variable expansion
-		regexp.MustCompile(`\x00`),       // Null bytes
+	// injectionPatterns are rejected in kubectl arguments. Patterns marked
+	// strictOnly are shell syntax, allowed by KubectlPermissive; paths out of
+	// the working directory or into a home directory are rejected by both.
+	injectionPatterns = []argumentPattern{
+		{regexp.MustCompile(`[;&|]`), "shell operator", true},
+		{regexp.MustCompile(`\$\(`), "command substitution", true},
+		{regexp.MustCompile("`"), "backticks", true},
+		{regexp.MustCompile(`>\s*/`), "file redirect to root", true},
+		{regexp.MustCompile(`<\s*/`), "file read from root", true},
+		{regexp.MustCompile(`\.\./`), "directory traversal", false},
+		{regexp.MustCompile(`~[^/\s]*\/`), "home directory expansion", false},
+		{regexp.MustCompile(`\$\{`), "variable expansion", true},
+		{regexp.MustCompile(`\x00`), "null byte", false},
 	}
 
 	// Allowed kubectl subcommands (whitelist approach)
@@ -86,21 +116,40 @@ var (
 	}
 )
 
-// validateKubectlCommand performs comprehensive validation on kubectl commands
-func validateKubectlCommand(args []string) error {
+// argumentPattern is a pattern rejected in kubectl arguments.
+type argumentPattern struct {
+	re         *regexp.Regexp
+	what       string
+	strictOnly bool
+}
+
+// kubectlRejection returns the structured error of a command that failed
+// validation.
+func kubectlRejection(hint, format string, args ...any) *llm.ToolError {
+	return &llm.ToolError{
+		Code:    llm.ToolErrorInvalidArguments,
+		Message: "validation failed: " + fmt.Sprintf(format, args...),
+		Hint:    hint,
+	}
+}
+
+// validateKubectlCommand performs comprehensive validation on kubectl
+// commands under policy (strict when empty) and returns why it rejects one.
+func validateKubectlCommand(args []string, policy KubectlPolicy) *llm.ToolError {
 	if len(args) == 0 {
-		return fmt.Errorf("no kubectl command provided")
+		return kubectlRejection(`pass the kubectl arguments without "kubectl", e.g. ["get", "pods"]`, "no kubectl command provided")
 	}
 
 	command := args[0]
 
 	// Check if command is in allowed list
-	if !allowedCommands[command] {
-		return fmt.Errorf("kubectl command '%s' is not allowed. Use standard kubectl commands only", command)
+	if !allowedCommands[command] && policy != KubectlPermissive {
+		return kubectlRejection(`use a standard kubectl command such as get, describe, logs or apply; other subcommands and plugins need "kubectl_policy": "permissive" in the config file`,
+			"kubectl command '%s' is not allowed. Use standard kubectl commands only", command)
 	}
 
 	// Check all arguments for injection patterns
-	if err := checkInjectionPatterns(args); err != nil {
+	if err := checkInjectionPatterns(args, policy); err != nil {
 		return err
 	}
 
@@ -110,45 +159,44 @@ func validateKubectlCommand(args []string) error {
 	}
 
 	// Additional validation for dangerous commands
-	if err := validateDangerousCommands(command, args); err != nil {
-		return err
-	}
-
-	return nil
+	return validateDangerousCommands(command, args)
 }
 
 // checkInjectionPatterns checks for command injection patterns
-func checkInjectionPatterns(args []string) error {
+func checkInjectionPatterns(args []string, policy KubectlPolicy) *llm.ToolError {
 	fullCommand := strings.Join(args, " ")
 	for _, pattern := range injectionPatterns {
-		if pattern.MatchString(fullCommand) {
-			return fmt.Errorf("potential command injection detected in arguments: %s", pattern.String())
+		if pattern.strictOnly && policy == KubectlPermissive {
+			continue
+		}
+		if pattern.re.MatchString(fullCommand) {
+			return kubectlRejection("kubectl runs without a shell: pass plain arguments, one per item, and filter the output yourself instead of piping it",
+				"potential command injection detected in arguments: %s", pattern.what)
 		}
 	}
 	return nil
 }
 
 // validateNamespaceFlags validates namespace flag values
-func validateNamespaceFlags(args []string) error {
+func validateNamespaceFlags(args []string) *llm.ToolError {
 	for i, arg := range args {
+		ns, ok := "", false
 		if (arg == "-n" || arg == "--namespace") && i+1 < len(args) {
-			ns := args[i+1]
-			if !isValidKubernetesName(ns) {
-				return fmt.Errorf("invalid namespace name: %s", ns)
-			}
+			ns, ok = args[i+1], true
 		}
 		if strings.HasPrefix(arg, "--namespace=") {
-			ns := strings.TrimPrefix(arg, "--namespace=")
-			if !isValidKubernetesName(ns) {
-				return fmt.Errorf("invalid namespace name: %s", ns)
-			}
+			ns, ok = strings.TrimPrefix(arg, "--namespace="), true
+		}
+		if ok && !isValidKubernetesName(ns) {
+			return kubectlRejection("namespace names are lowercase letters, digits, '-' and '.'; list the namespaces with kubectl get namespaces",
+				"invalid namespace name: %s", ns)
 		}
 	}
 	return nil
 }
 
 // validateDangerousCommands performs additional validation for dangerous commands
-func validateDangerousCommands(command string, args []string) error {
+func validateDangerousCommands(command string, args []string) *llm.ToolError {
 	if !dangerousCommands[command] {
 		return nil
 	}
@@ -157,7 +205,8 @@ func validateDangerousCommands(command string, args []string) error {
 	if command == "delete" {
 		for _, arg := range args {
 			if arg == "--all" || strings.Contains(arg, "*") {
-				return fmt.Errorf("bulk delete operations with --all or wildcards require extra caution")
+				return kubectlRejection("call bulk_delete with the same selection instead",
+					"bulk delete operations with --all or wildcards require extra caution: use the bulk_delete tool, which lists the objects and asks to confirm their count")
 			}
 		}
 	}
@@ -1,12 +1,15 @@
 package agent
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
 )
 
 const testKubeconfigFlag = "--kubeconfig"
@@ -56,14 +59,53 @@ func TestValidateKubectlCommand(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validateKubectlCommand(tt.args)
+			err := validateKubectlCommand(tt.args, "")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("validateKubectlCommand() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if err != nil && (err.Code != llm.ToolErrorInvalidArguments || err.Hint == "") {
+				t.Errorf("validateKubectlCommand() error = %+v, want invalid_arguments with a hint", err)
+			}
 		})
 	}
 }
 
+func TestValidateKubectlCommandPermissive(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"plugin", []string{"neat", "get", "pod", "web"}, false},
+		{"go template pipe", []string{"get", "pods", "-o", `go-template={{range .items}}{{.metadata.name | printf "%s\n"}}{{end}}`}, false},
+		{"relative manifest", []string{"apply", "-f", "deploy/web.yaml"}, false},
+		{"path traversal", []string{"apply", "-f", "../deploy.yaml"}, true},
+		{"home directory", []string{"apply", "-f", "~root/.kube/config"}, true},
+		{"null byte", []string{"get", "pods\x00"}, true},
+		{"bulk delete all", []string{"delete", "pods", "--all"}, true},
+		{"invalid namespace", []string{"get", "pods", "-n", "Invalid_Name!"}, true},
+	}
+	for _, tt := range tests {
+		if err := validateKubectlCommand(tt.args, KubectlPermissive); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateKubectlCommand() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+	if err := validateKubectlCommand([]string{"neat", "get", "pod", "web"}, KubectlStrict); err == nil {
+		t.Error("strict policy allowed a plugin")
+	}
+}
+
+func TestParseKubectlPolicy(t *testing.T) {
+	for value, want := range map[string]KubectlPolicy{"": KubectlStrict, "strict": KubectlStrict, " Permissive ": KubectlPermissive} {
+		if got, err := ParseKubectlPolicy(value); err != nil || got != want {
+			t.Errorf("ParseKubectlPolicy(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseKubectlPolicy("lax"); err == nil {
+		t.Error("ParseKubectlPolicy accepted an unknown policy")
+	}
+}
+
 // TestIsValidKubernetesName tests Kubernetes name validation
 func TestIsValidKubernetesName(t *testing.T) {
 	tests := []struct {
@@ -149,34 +191,36 @@ func TestHandleKubectlExecValidationBlocksBeforeProviderLookup(t *testing.T) {
 	originalRunner := runKubectlCommandFunc
 	t.Cleanup(func() { runKubectlCommandFunc = originalRunner })
 
-	runKubectlCommandFunc = func(args []string) ([]byte, error) {
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
 		t.Fatalf("kubectl runner should not be called for invalid args: %v", args)
 		return nil, nil
 	}
 
 	state := &agentState{mode: ModeReadOnly, outputFormat: OutputText}
-	result, err := handleKubectlExec(nil, state, KubectlExecParams{
+	result, err := handleKubectlExec(context.Background(), nil, state, KubectlExecParams{
 		Context: "missing-context",
 		Args:    []string{"get", "pods", "|", "cat"},
 	})
-	if err == nil {
-		t.Fatal("expected validation error")
+	if result != nil {
+		t.Errorf("validation result = %v, want none", result)
 	}
-
-	text, ok := result.(string)
-	if !ok {
-		t.Fatalf("validation result should be text, got %T", result)
+	var toolErr *llm.ToolError
+	if !errors.As(err, &toolErr) || toolErr.Code != llm.ToolErrorInvalidArguments {
+		t.Fatalf("err = %v, want an invalid_arguments ToolError", err)
 	}
-	for _, want := range []string{"validation failed", "potential command injection", "kubectl get pods | cat"} {
-		if !strings.Contains(text, want) {
-			t.Errorf("validation result missing %q: %s", want, text)
+	for _, want := range []string{"validation failed", "potential command injection", "shell operator"} {
+		if !strings.Contains(toolErr.Message, want) {
+			t.Errorf("validation error missing %q: %s", want, toolErr.Message)
 		}
 	}
+	if !strings.Contains(toolErr.Hint, "without a shell") {
+		t.Errorf("hint = %q", toolErr.Hint)
+	}
 }
 
 func TestHandleKubectlExecValidationErrorJSON(t *testing.T) {
 	state := &agentState{mode: ModeReadOnly, outputFormat: OutputJSON}
-	result, err := handleKubectlExec(nil, state, KubectlExecParams{
+	_, err := handleKubectlExec(context.Background(), nil, state, KubectlExecParams{
 		Context: "missing-context",
 		Args:    []string{"delete", "pods", "--all"},
 	})
@@ -184,18 +228,11 @@ func TestHandleKubectlExecValidationErrorJSON(t *testing.T) {
 		t.Fatal("expected validation error")
 	}
 
-	payload, ok := result.(KubectlExecResult)
-	if !ok {
-		t.Fatalf("validation result should be KubectlExecResult, got %T", result)
-	}
-	if payload.Cluster != "unknown" {
-		t.Errorf("Cluster = %q, want unknown", payload.Cluster)
-	}
-	if !strings.Contains(payload.Error, "validation failed") {
-		t.Errorf("Error should include validation failure, got %q", payload.Error)
-	}
-	if !strings.Contains(payload.Error, "bulk delete") {
-		t.Errorf("Error should include validation detail, got %q", payload.Error)
+	got := llm.ToolErrorString(err)
+	for _, want := range []string{`"code":"invalid_arguments"`, "validation failed", "bulk delete", `"hint":"call bulk_delete`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("structured error missing %q: %s", want, got)
+		}
 	}
 }
 
@@ -206,13 +243,13 @@ func TestHandleKubectlExecSanitizesArgsBeforeExecution(t *testing.T) {
 	t.Cleanup(func() { runKubectlCommandFunc = originalRunner })
 
 	var gotArgs []string
-	runKubectlCommandFunc = func(args []string) ([]byte, error) {
+	runKubectlCommandFunc = func(_ context.Context, args []string) ([]byte, error) {
 		gotArgs = append([]string(nil), args...)
 		return []byte("pod/test\n"), nil
 	}
 
 	state := &agentState{mode: ModeReadOnly, outputFormat: OutputJSON}
-	result, err := handleKubectlExec(provider, state, KubectlExecParams{
+	result, err := handleKubectlExec(context.Background(), provider, state, KubectlExecParams{
 		Context: "test-context",
 		Args:    []string{"get", "pods", "--token", "secret-token", "-w", "--namespace=default"},
 	})
@@ -295,7 +332,7 @@ func BenchmarkValidateKubectlCommand(b *testing.B) {
 	args := []string{"get", "pods", "-n", "default", "-o", "json"}
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = validateKubectlCommand(args)
+		_ = validateKubectlCommand(args, KubectlStrict)
 	}
 }
 
@@ -0,0 +1,115 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains session variables (/set, /unset) and their expansion in user input.
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// varNameRe matches a valid session variable name.
+	varNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// varRefRe matches $name and ${name} references.
+	varRefRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+// expandVars replaces $name and ${name} with session variable values.
+// References to undefined variables are left untouched, so shell variables
+// in !commands keep working.
+func expandVars(input string, vars map[string]string) string {
+	if len(vars) == 0 || !strings.Contains(input, "$") {
+		return input
+	}
+	return varRefRe.ReplaceAllStringFunc(input, func(ref string) string {
+		m := varRefRe.FindStringSubmatch(ref)
+		name := m[1]
+		if name == "" {
+			name = m[2]
+		}
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return ref
+	})
+}
+
+// handleVarsCommand processes "/set", "/set name=value", "/set name" and
+// "/unset <name|all>". Returns false when input is not a variable command.
+func handleVarsCommand(state *agentState, input string) bool {
+	trimmed := strings.TrimSpace(input)
+	fields := strings.Fields(trimmed)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToLower(fields[0]) {
+	case "/set":
+		arg := strings.TrimSpace(trimmed[len(fields[0]):])
+		if arg == "" {
+			printVars(state.vars)
+			return true
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		name = strings.TrimSpace(name)
+		if !varNameRe.MatchString(name) {
+			fmt.Printf("  %s●%s Invalid variable name %q — use letters, digits and _ (e.g. /set ns=payments)\n", colorRed, colorReset, name)
+			return true
+		}
+		if !hasValue {
+			if v, ok := state.vars[name]; ok {
+				fmt.Printf("  %s●%s %s=%s\n", colorCyan, colorReset, name, v)
+			} else {
+				fmt.Printf("  %s●%s %s is not set\n", colorDim, colorReset, name)
+			}
+			return true
+		}
+		if state.vars == nil {
+			state.vars = make(map[string]string)
+		}
+		state.vars[name] = strings.TrimSpace(value)
+		fmt.Printf("  %s●%s Set %s$%s%s = %s\n", colorGreen, colorReset, colorCyan, name, colorReset, state.vars[name])
+		return true
+
+	case "/unset":
+		if len(fields) != 2 {
+			fmt.Printf("  %s●%s Usage: /unset <name|all>\n", colorRed, colorReset)
+			return true
+		}
+		name := fields[1]
+		if strings.ToLower(name) == "all" {
+			state.vars = nil
+			fmt.Printf("  %s●%s Cleared all session variables\n", colorGreen, colorReset)
+			return true
+		}
+		if _, ok := state.vars[name]; !ok {
+			fmt.Printf("  %s●%s %s is not set\n", colorDim, colorReset, name)
+			return true
+		}
+		delete(state.vars, name)
+		fmt.Printf("  %s●%s Unset %s\n", colorGreen, colorReset, name)
+		return true
+	}
+	return false
+}
+
+// printVars lists the session variables sorted by name.
+func printVars(vars map[string]string) {
+	if len(vars) == 0 {
+		fmt.Printf("  %s●%s No session variables — set one with /set ns=payments, use it as $ns\n", colorDim, colorReset)
+		return
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Println()
+	fmt.Printf("  %s%s%s\n", colorCyan, sectionRule("Session Variables"), colorReset)
+	fmt.Println()
+	for _, name := range names {
+		fmt.Printf("  %s$%s%s = %s\n", colorCyan, name, colorReset, vars[name])
+	}
+	fmt.Println()
+}
@@ -0,0 +1,55 @@
+package agent
+
+import "testing"
+
+func TestExpandVars(t *testing.T) {
+	vars := map[string]string{"ns": "payments", "ctx": "prod-eu"}
+	tests := []struct {
+		in, want string
+	}{
+		{"get pods in $ns", "get pods in payments"},
+		{"/context use $ctx", "/context use prod-eu"},
+		{"logs of ${ns}-api on $ctx", "logs of payments-api on prod-eu"},
+		{"!echo $HOME $ns", "!echo $HOME payments"},
+		{"costs $5 in $nsx", "costs $5 in $nsx"},
+		{"no variables here", "no variables here"},
+	}
+	for _, tt := range tests {
+		if got := expandVars(tt.in, vars); got != tt.want {
+			t.Errorf("expandVars(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if got := expandVars("get pods in $ns", nil); got != "get pods in $ns" {
+		t.Errorf("expandVars without variables = %q", got)
+	}
+}
+
+func TestHandleVarsCommand(t *testing.T) {
+	state := &agentState{}
+	if handleVarsCommand(state, "/settings") {
+		t.Error("/settings is not a variable command")
+	}
+	for _, cmd := range []string{"/set", "/set ns = payments", "/set greeting=hello world", "/set 9bad=x", "/set ns"} {
+		if !handleVarsCommand(state, cmd) {
+			t.Errorf("%q should be handled", cmd)
+		}
+	}
+	if state.vars["ns"] != "payments" || state.vars["greeting"] != "hello world" {
+		t.Errorf("vars = %v", state.vars)
+	}
+	if _, ok := state.vars["9bad"]; ok {
+		t.Error("invalid names must be rejected")
+	}
+
+	handleVarsCommand(state, "/unset ns")
+	if _, ok := state.vars["ns"]; ok {
+		t.Error("/unset ns should remove the variable")
+	}
+	handleVarsCommand(state, "/unset all")
+	if len(state.vars) != 0 {
+		t.Errorf("/unset all left %v", state.vars)
+	}
+	if isUnknownSlashCommand("/set ns=payments") || isUnknownSlashCommand("/unset ns") {
+		t.Error("/set and /unset must be known commands")
+	}
+}
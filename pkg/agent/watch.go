@@ -0,0 +1,177 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the watch_resource tool.
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+const maxWatchDurationSeconds = 900
+
+// watchPodsFunc is the provider call behind watch_resource; a variable so
+// tests can stub the cluster.
+var watchPodsFunc = func(p *k8s.Provider, ctx context.Context, contextName, namespace, kind, name string, opts k8s.WatchOptions) (*k8s.WatchResult, error) {
+	return p.WatchPods(ctx, contextName, namespace, kind, name, opts)
+}
+
+// WatchResourceParams defines parameters for watch_resource
+type WatchResourceParams struct {
+	Context         string `json:"context" jsonschema:"The cluster context name (required)"`
+	Namespace       string `json:"namespace,omitempty" jsonschema:"Namespace to watch (defaults to the context namespace)"`
+	Kind            string `json:"kind,omitempty" jsonschema:"pod (default), deployment, statefulset or daemonset; for a workload its pods are watched"`
+	Name            string `json:"name,omitempty" jsonschema:"Name of the pod or workload; omit to watch the pods matching label_selector"`
+	LabelSelector   string `json:"label_selector,omitempty" jsonschema:"Label selector of the pods to watch when no name is given, e.g. app=api"`
+	DurationSeconds int    `json:"duration_seconds,omitempty" jsonschema:"How long to watch (default 120, max 900)"`
+	UntilHealthy    bool   `json:"until_healthy,omitempty" jsonschema:"End the watch early as soon as every watched pod is running and ready"`
+	Background      bool   `json:"background,omitempty" jsonschema:"Watch as a background job so the chat continues; the user follows it with /jobs and cancels it with /cancel"`
+}
+
+func defineWatchResourceTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolWatchResource,
+		"Watch a pod, the pods of a Deployment, StatefulSet or DaemonSet, or the pods matching a label selector for a bounded time (default 2 minutes) and report each change of phase, readiness, restarts or reason as it happens, then a summary: use it to confirm that a fix took effect, e.g. 'watch the api pods for 2 minutes'. With until_healthy it ends once every pod is healthy; with background it runs as a background job. Read-only.",
+		func(params WatchResourceParams, inv llm.ToolInvocation) (any, error) {
+			return handleWatchResource(inv.Ctx(), k8sProvider, state, params)
+		},
+	)
+}
+
+func handleWatchResource(ctx context.Context, k8sProvider *k8s.Provider, state *agentState, params WatchResourceParams) (any, error) {
+	namespace, kind, err := validateWatchTarget(k8sProvider, &params)
+	if err != nil {
+		return nil, err
+	}
+	opts := k8s.WatchOptions{
+		Duration:      time.Duration(params.DurationSeconds) * time.Second,
+		LabelSelector: params.LabelSelector,
+		UntilHealthy:  params.UntilHealthy,
+	}
+	if params.Background {
+		description := fmt.Sprintf("watch of %s in %s on %s", watchTargetName(kind, params.Name, params.LabelSelector), namespace, params.Context)
+		return runInBackground(state, toolWatchResource, description, func(ctx context.Context, progress func(string)) (string, error) {
+			opts.Progress = func(c k8s.PodChange) { progress(formatPodChange(c)) }
+			result, err := watchPodsFunc(k8sProvider, ctx, params.Context, namespace, kind, params.Name, opts)
+			if err != nil {
+				return "", fmt.Errorf("failed to watch pods: %w", err)
+			}
+			return formatWatchResult(result), nil
+		})
+	}
+	if !isJSONOutput(state.outputFormat) {
+		opts.Progress = func(c k8s.PodChange) { printPodChange(state.stdout(), c) }
+	}
+	result, err := watchPodsFunc(k8sProvider, ctx, params.Context, namespace, kind, params.Name, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch pods: %w", err)
+	}
+	if isJSONOutput(state.outputFormat) {
+		return result, nil
+	}
+	return formatWatchResult(result), nil
+}
+
+// validateWatchTarget checks the watched pods and the duration, defaulting
+// it, and returns the namespace, defaulting to the context's as with
+// kubectl, and the normalized kind.
+func validateWatchTarget(k8sProvider *k8s.Provider, params *WatchResourceParams) (string, string, error) {
+	if params.Context == "" {
+		return "", "", fmt.Errorf("context is required")
+	}
+	kind, err := k8s.NormalizeWatchKind(params.Kind)
+	if err != nil {
+		return "", "", err
+	}
+	switch {
+	case params.Name != "" && params.LabelSelector != "":
+		return "", "", fmt.Errorf("set name or label_selector, not both")
+	case params.Name == "" && (kind != k8s.KindPod || params.LabelSelector == ""):
+		return "", "", fmt.Errorf("name is required, or label_selector to watch pods by label")
+	case params.Name != "" && !isValidKubernetesName(params.Name):
+		return "", "", fmt.Errorf("invalid name: %s", params.Name)
+	}
+	if params.DurationSeconds == 0 {
+		params.DurationSeconds = int(k8s.DefaultWatchDuration / time.Second)
+	}
+	if params.DurationSeconds < 1 || params.DurationSeconds > maxWatchDurationSeconds {
+		return "", "", fmt.Errorf("duration_seconds must be between 1 and %d", maxWatchDurationSeconds)
+	}
+	cluster, err := getClusterForContext(k8sProvider, params.Context)
+	if err != nil {
+		return "", "", err
+	}
+	namespace := params.Namespace
+	if namespace == "" {
+		namespace = cluster.Namespace
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	if !isValidKubernetesName(namespace) {
+		return "", "", fmt.Errorf("invalid namespace name: %s", namespace)
+	}
+	return namespace, kind, nil
+}
+
+// watchTargetName names the watched pods, e.g. "deployment/api" or
+// "pods app=api".
+func watchTargetName(kind, name, selector string) string {
+	if name == "" {
+		return "pods " + selector
+	}
+	return strings.ToLower(kind) + "/" + name
+}
+
+// printPodChange streams one pod change to w.
+func printPodChange(w io.Writer, c k8s.PodChange) {
+	fmt.Fprintf(w, "\r\033[K%s   %s%s\n", colorDim, formatPodChange(c), colorReset)
+}
+
+// formatPodChange formats one pod change on a line.
+func formatPodChange(c k8s.PodChange) string {
+	line := fmt.Sprintf("[%s] %s %s: %s, %s ready, %d restart(s)", c.At, c.Name, c.Event, c.Phase, c.Ready, c.Restarts)
+	if c.Reason != "" {
+		line += " (" + c.Reason + ")"
+	}
+	return line
+}
+
+// formatWatchResult formats a WatchResult as human-readable text
+func formatWatchResult(r *k8s.WatchResult) string {
+	var sb strings.Builder
+	icon := "⚠️"
+	switch {
+	case len(r.Pods) > 0 && r.Healthy == len(r.Pods) && r.Restarts == 0:
+		icon = "✅"
+	case len(r.Pods) == 0:
+		icon = "❔"
+	}
+	target := watchTargetName(r.Kind, r.Name, r.Selector)
+	fmt.Fprintf(&sb, "%s Watched %s in %s (%s): %s\n", icon, target, r.Namespace, r.Context, r.Message)
+	if len(r.Changes) > 0 {
+		sb.WriteString("Changes:\n")
+		for _, c := range r.Changes {
+			fmt.Fprintf(&sb, "   %s\n", formatPodChange(c))
+		}
+		if r.ChangesTruncated {
+			sb.WriteString("   ... later changes omitted\n")
+		}
+	}
+	if len(r.Pods) > 0 {
+		sb.WriteString("Pods now:\n")
+		for _, p := range r.Pods {
+			fmt.Fprintf(&sb, "   %s: %s, %s ready, %d restart(s)", p.Name, p.Phase, p.Ready, p.Restarts)
+			if p.Reason != "" {
+				fmt.Fprintf(&sb, " (%s)", p.Reason)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+// stubWatchPods replaces the watch, recording the target as
+// "namespace/kind/name" and the options, and streaming one change.
+func stubWatchPods(t *testing.T, result *k8s.WatchResult) (target *string, watched *k8s.WatchOptions) {
+	t.Helper()
+	original := watchPodsFunc
+	t.Cleanup(func() { watchPodsFunc = original })
+	target, watched = new(string), new(k8s.WatchOptions)
+	watchPodsFunc = func(_ *k8s.Provider, _ context.Context, _, namespace, kind, name string, opts k8s.WatchOptions) (*k8s.WatchResult, error) {
+		*target = namespace + "/" + kind + "/" + name
+		*watched = opts
+		if opts.Progress != nil {
+			opts.Progress(k8s.PodChange{At: "3s", Event: k8s.PodChanged, WatchedPod: k8s.WatchedPod{Name: "api-1", Phase: "Running", Ready: "1/1", Restarts: 1}})
+		}
+		return result, nil
+	}
+	return target, watched
+}
+
+func TestValidateWatchTarget(t *testing.T) {
+	provider := newTestK8sProvider(t)
+	params := WatchResourceParams{Context: "test-context", Kind: "deploy", Name: "api"}
+	namespace, kind, err := validateWatchTarget(provider, &params)
+	if err != nil || namespace != "default" || kind != k8s.KindDeployment || params.DurationSeconds != 120 {
+		t.Errorf("validateWatchTarget() = %q, %q, %v; duration %d", namespace, kind, err, params.DurationSeconds)
+	}
+	for want, p := range map[string]WatchResourceParams{
+		"context is required":       {Name: "api"},
+		"name is required":          {Context: "test-context", Kind: "deployment", LabelSelector: "app=api"},
+		"not both":                  {Context: "test-context", Name: "api", LabelSelector: "app=api"},
+		"invalid name":              {Context: "test-context", Name: "api;rm"},
+		"unsupported workload kind": {Context: "test-context", Kind: "service", Name: "api"},
+		"duration_seconds must be":  {Context: "test-context", Name: "api", DurationSeconds: 3600},
+		"invalid namespace name":    {Context: "test-context", Namespace: "Shop_1", Name: "api"},
+		"does not exist":            {Context: "missing", Name: "api"},
+		"to watch pods by label":    {Context: "test-context"},
+	} {
+		if _, _, err := validateWatchTarget(provider, &p); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%+v: error = %v, want %q", p, err, want)
+		}
+	}
+}
+
+func TestHandleWatchResource(t *testing.T) {
+	result := &k8s.WatchResult{
+		Context: "test-context", Namespace: "shop", Kind: k8s.KindPod, Selector: "app=api", Duration: "2m0s",
+		Changes:  []k8s.PodChange{{At: "3s", Event: k8s.PodChanged, WatchedPod: k8s.WatchedPod{Name: "api-1", Phase: "Running", Ready: "0/1", Restarts: 1, Reason: "CrashLoopBackOff"}}},
+		Pods:     []k8s.WatchedPod{{Name: "api-1", Phase: "Running", Ready: "1/1", Restarts: 1, Healthy: true}},
+		Healthy:  1,
+		Restarts: 1,
+		Message:  "1 of 1 pod(s) healthy, 1 change(s) and 1 restart(s) in 2m0s",
+	}
+	target, watched := stubWatchPods(t, result)
+	var out bytes.Buffer
+	state := &agentState{outputFormat: OutputText, out: &out}
+
+	text, err := handleWatchResource(context.Background(), newTestK8sProvider(t), state, WatchResourceParams{Context: "test-context", Namespace: "shop", LabelSelector: "app=api", DurationSeconds: 60, UntilHealthy: true})
+	if err != nil {
+		t.Fatalf("handleWatchResource() failed: %v", err)
+	}
+	if *target != "shop/Pod/" || watched.Duration != time.Minute || watched.LabelSelector != "app=api" || !watched.UntilHealthy {
+		t.Errorf("watched %q with %+v", *target, *watched)
+	}
+	if !strings.Contains(out.String(), "[3s] api-1 changed: Running, 1/1 ready, 1 restart(s)") {
+		t.Errorf("streamed progress = %q", out.String())
+	}
+	for _, want := range []string{"⚠️ Watched pods app=api in shop (test-context)", "[3s] api-1 changed: Running, 0/1 ready, 1 restart(s) (CrashLoopBackOff)", "Pods now:\n   api-1: Running, 1/1 ready"} {
+		if !strings.Contains(text.(string), want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+
+	state.outputFormat = OutputJSON
+	if got, err := handleWatchResource(context.Background(), newTestK8sProvider(t), state, WatchResourceParams{Context: "test-context", Kind: "sts", Name: "db"}); err != nil || got != result || watched.Progress != nil {
+		t.Errorf("JSON output = %v, %v; progress set: %v", got, err, watched.Progress != nil)
+	}
+}
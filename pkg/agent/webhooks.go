@@ -0,0 +1,67 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the check_webhooks tool (admission webhooks with missing or failing backends).
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// CheckWebhooksParams defines parameters for check_webhooks
+type CheckWebhooksParams struct {
+	Context string `json:"context" jsonschema:"The context name of the cluster to check (from list_clusters)"`
+}
+
+func defineCheckWebhooksTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolCheckWebhooks,
+		"Admission webhook health: ValidatingWebhookConfigurations and MutatingWebhookConfigurations whose backing service is missing, lacks the called port or has no ready endpoints, that have no caBundle, or whose calls failed in the last hour (from 'failed calling webhook' events). Webhooks with failurePolicy Fail are flagged as blocking: a broken one rejects every matching create or update. Use when deployments, pods or other writes fail with 'Internal error occurred' or time out for no visible reason. Read-only.",
+		func(params CheckWebhooksParams, inv llm.ToolInvocation) (any, error) {
+			report, err := k8sProvider.CheckWebhooks(inv.Ctx(), params.Context)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check webhooks: %w", err)
+			}
+
+			if isJSONOutput(state.outputFormat) {
+				return report, nil
+			}
+			return formatWebhookReport(report), nil
+		},
+	)
+}
+
+// formatWebhookReport formats a WebhookReport as human-readable text
+func formatWebhookReport(report *k8s.WebhookReport) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Admission webhooks: %s\n", report.Context)
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+	fmt.Fprintf(&sb, "📋 %d webhook(s) checked\n", report.Webhooks)
+
+	if len(report.Findings) == 0 {
+		sb.WriteString("\n✅ Every webhook backend is present and ready, with no recent call failures.\n")
+	} else {
+		fmt.Fprintf(&sb, "\n⚠️  %d problem(s), %d blocking (failurePolicy Fail)\n", len(report.Findings), report.Blocking)
+	}
+	for _, f := range report.Findings {
+		icon := "⚠️ "
+		if f.Blocking {
+			icon = "❌"
+		}
+		fmt.Fprintf(&sb, "\n%s %s (%s %s, failurePolicy %s)\n", icon, f.Webhook, f.Kind, f.Configuration, f.FailurePolicy)
+		if f.Backend != "" {
+			fmt.Fprintf(&sb, "     backend: %s\n", f.Backend)
+		}
+		fmt.Fprintf(&sb, "     %s\n", f.Problem)
+	}
+
+	for _, w := range report.Warnings {
+		fmt.Fprintf(&sb, "\n⚠️  %s", w)
+	}
+	if len(report.Warnings) > 0 {
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
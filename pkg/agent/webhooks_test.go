@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func TestFormatWebhookReport(t *testing.T) {
+	report := &k8s.WebhookReport{
+		Context: "prod", Webhooks: 3, Blocking: 1,
+		Findings: []k8s.WebhookFinding{
+			{Kind: "MutatingWebhookConfiguration", Configuration: "injector", Webhook: "inject.mesh.io", Backend: "service/mesh/injector:443",
+				FailurePolicy: "Fail", Blocking: true, Problem: "service mesh/injector has no ready endpoints; is the webhook pod running?"},
+			{Kind: "ValidatingWebhookConfiguration", Configuration: "gatekeeper", Webhook: "check.gatekeeper.sh", Backend: "service/policy/gatekeeper:443",
+				FailurePolicy: "Ignore", Problem: "no caBundle"},
+		},
+	}
+	text := formatWebhookReport(report)
+	for _, want := range []string{
+		"Admission webhooks: prod",
+		"3 webhook(s) checked",
+		"2 problem(s), 1 blocking",
+		"❌ inject.mesh.io (MutatingWebhookConfiguration injector, failurePolicy Fail)\n     backend: service/mesh/injector:443\n     service mesh/injector has no ready endpoints",
+		"⚠️  check.gatekeeper.sh (ValidatingWebhookConfiguration gatekeeper, failurePolicy Ignore)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+
+	if text := formatWebhookReport(&k8s.WebhookReport{Context: "dev"}); !strings.Contains(text, "Every webhook backend is present") {
+		t.Errorf("empty report output:\n%s", text)
+	}
+}
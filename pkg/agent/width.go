@@ -0,0 +1,209 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file fits terminal output to the terminal width: section rules, the
+// spinner line, and tool output whose rules and long lines would otherwise
+// wrap untidily on narrow terminals and tmux splits.
+package agent
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/chzyer/readline"
+	"golang.org/x/term"
+)
+
+// defaultTerminalWidth is assumed when the width cannot be read.
+const defaultTerminalWidth = 80
+
+// sectionRuleWidth is the width of the "━━ Title ━━" headings on terminals
+// wide enough for it.
+const sectionRuleWidth = 60
+
+// minRuleLength is the shortest line of one repeated character that
+// fitText treats as a rule and resizes rather than wraps.
+const minRuleLength = 20
+
+// screenWidth reads the width of the terminal on stdout, or stderr, and
+// returns -1 when neither is a terminal. It reads file descriptor 1 rather
+// than os.Stdout, which setupTerminal may replace with a pipe.
+var screenWidth = readline.GetScreenWidth
+
+// terminalWidth returns the width of the terminal, $COLUMNS when it cannot
+// be read, or defaultTerminalWidth.
+func terminalWidth() int {
+	if w := screenWidth(); w > 0 {
+		return w
+	}
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols
+	}
+	return defaultTerminalWidth
+}
+
+// writerWidth returns the width of the terminal w writes to, or 0 when w is
+// not a terminal, so output to files and pipes is left alone.
+func writerWidth(w io.Writer) int {
+	f, ok := w.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return 0
+	}
+	if width, _, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+		return width
+	}
+	return defaultTerminalWidth
+}
+
+// sectionRule returns a "━━ title ━━━" heading that fits after the
+// two-space indent of the session's output.
+func sectionRule(title string) string {
+	width := min(sectionRuleWidth, terminalWidth()-2)
+	head := "━━ " + title + " "
+	if fill := width - lipgloss.Width(head); fill > 0 {
+		return head + strings.Repeat("━", fill)
+	}
+	return "━━ " + title
+}
+
+// spinnerLine returns the spinner line for the given frame, its label
+// shortened to fit the width: a wrapped line could not be erased with a
+// carriage return. The last column stays free for the same reason.
+func spinnerLine(frame, label string, width int) string {
+	head := "  " + colorCyan + frame + colorReset
+	if width <= lipgloss.Width(head) {
+		return ""
+	}
+	room := width - lipgloss.Width(head) - 2
+	if room <= len("...") {
+		return head
+	}
+	return head + " " + truncateText(label+"...", room)
+}
+
+// fitText fits text to width for the terminal: rules, lines of one repeated
+// character such as the "====" under tool output titles, shrink to the
+// width, and longer lines wrap at spaces with their continuation indented
+// under the line's text. Lines inside ``` code fences are kept, as wrapping
+// would break commands and YAML meant to be copied.
+func fitText(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	fenced := false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+			out = append(out, line)
+			continue
+		}
+		switch {
+		case fenced || lipgloss.Width(line) <= width:
+			out = append(out, line)
+		case isRule(line):
+			r, _ := firstRune(line)
+			out = append(out, strings.Repeat(string(r), width))
+		default:
+			out = append(out, wrapLine(line, width)...)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// isRule reports whether line is at least minRuleLength of one character
+// such as '=', '-' or '━'.
+func isRule(line string) bool {
+	r, ok := firstRune(line)
+	if !ok || !strings.ContainsRune("=-━─", r) {
+		return false
+	}
+	n := 0
+	for _, c := range line {
+		if c != r {
+			return false
+		}
+		n++
+	}
+	return n >= minRuleLength
+}
+
+func firstRune(s string) (rune, bool) {
+	for _, r := range s {
+		return r, true
+	}
+	return 0, false
+}
+
+// wrapLine wraps line at spaces to width. Continuation lines are indented
+// like the line, plus the width of a leading bullet or icon, so lists keep
+// their shape; a word longer than the width is split.
+func wrapLine(line string, width int) []string {
+	body := strings.TrimLeft(line, " ")
+	indent := line[:len(line)-len(body)]
+	words := strings.Fields(body)
+	if len(words) == 0 {
+		return []string{line}
+	}
+	hang := indent
+	if first := words[0]; len(words) > 1 && lipgloss.Width(first) <= 3 && !isWordy(first) {
+		hang += strings.Repeat(" ", lipgloss.Width(first)+1)
+	}
+	if lipgloss.Width(hang) > width/2 {
+		hang = ""
+	}
+
+	var lines []string
+	current := indent
+	empty := true
+	for _, word := range words {
+		sep := " "
+		if empty {
+			sep = ""
+		}
+		if lipgloss.Width(current)+len(sep)+lipgloss.Width(word) <= width {
+			current += sep + word
+			empty = false
+			continue
+		}
+		if !empty {
+			lines = append(lines, current)
+			current, empty = hang, true
+		}
+		for lipgloss.Width(current)+lipgloss.Width(word) > width {
+			// Split a word too long for a line of its own.
+			room := max(width-lipgloss.Width(current), 1)
+			runes := []rune(word)
+			n := 0
+			for n < len(runes) && lipgloss.Width(string(runes[:n+1])) <= room {
+				n++
+			}
+			n = max(n, 1)
+			lines = append(lines, current+string(runes[:n]))
+			word = string(runes[n:])
+			current = hang
+		}
+		if word != "" {
+			current += word
+			empty = false
+		}
+	}
+	if !empty {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// isWordy reports whether s contains a letter or digit, telling a word from
+// a bullet such as "•", "-" or "✅".
+func isWordy(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
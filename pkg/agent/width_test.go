@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// withScreenWidth makes terminalWidth return width for the test.
+func withScreenWidth(t *testing.T, width int) {
+	t.Helper()
+	orig := screenWidth
+	screenWidth = func() int { return width }
+	t.Cleanup(func() { screenWidth = orig })
+}
+
+func TestTerminalWidth(t *testing.T) {
+	withScreenWidth(t, 132)
+	if got := terminalWidth(); got != 132 {
+		t.Errorf("terminalWidth() = %d, want the screen width 132", got)
+	}
+	withScreenWidth(t, -1)
+	t.Setenv("COLUMNS", "50")
+	if got := terminalWidth(); got != 50 {
+		t.Errorf("terminalWidth() = %d, want $COLUMNS 50", got)
+	}
+	t.Setenv("COLUMNS", "")
+	if got := terminalWidth(); got != defaultTerminalWidth {
+		t.Errorf("terminalWidth() = %d, want the default %d", got, defaultTerminalWidth)
+	}
+	if got := writerWidth(&bytes.Buffer{}); got != 0 {
+		t.Errorf("writerWidth(buffer) = %d, want 0", got)
+	}
+}
+
+func TestSectionRule(t *testing.T) {
+	withScreenWidth(t, 200)
+	if got := lipgloss.Width(sectionRule("Session Usage")); got != sectionRuleWidth {
+		t.Errorf("wide terminal rule width = %d, want %d", got, sectionRuleWidth)
+	}
+	withScreenWidth(t, 40)
+	rule := sectionRule("Session Usage")
+	if !strings.HasPrefix(rule, "━━ Session Usage ━") || lipgloss.Width(rule) != 38 {
+		t.Errorf("narrow terminal rule = %q (width %d), want width 38", rule, lipgloss.Width(rule))
+	}
+	withScreenWidth(t, 10)
+	if got := sectionRule("Session Usage"); got != "━━ Session Usage" {
+		t.Errorf("tiny terminal rule = %q, want the bare title", got)
+	}
+}
+
+func TestSpinnerLine(t *testing.T) {
+	applyTheme(themes[ThemeNone])
+	t.Cleanup(func() { applyTheme(themes[ThemeDark]) })
+	if got := spinnerLine("⠋", "thinking", 80); got != "  ⠋ thinking..." {
+		t.Errorf("spinnerLine = %q", got)
+	}
+	if got := spinnerLine("⠋", "thinking", 12); got != "  ⠋ thinki…" {
+		t.Errorf("narrow spinnerLine = %q, want a shortened label", got)
+	}
+	if got := spinnerLine("⠋", "thinking", 7); got != "  ⠋" {
+		t.Errorf("very narrow spinnerLine = %q, want the frame only", got)
+	}
+	if got := spinnerLine("⠋", "thinking", 2); got != "" {
+		t.Errorf("tiny spinnerLine = %q, want nothing", got)
+	}
+}
+
+func TestFitText(t *testing.T) {
+	text := strings.Join([]string{
+		"Cluster Status",
+		strings.Repeat("=", 80),
+		"",
+		"  • prod: 2 pod(s) in CrashLoopBackOff in namespace payments",
+		"short",
+		"```",
+		"kubectl get pods --namespace payments --field-selector status.phase!=Running",
+		"```",
+	}, "\n")
+	got := fitText(text, 30)
+	want := strings.Join([]string{
+		"Cluster Status",
+		strings.Repeat("=", 30),
+		"",
+		"  • prod: 2 pod(s) in",
+		"    CrashLoopBackOff in",
+		"    namespace payments",
+		"short",
+		"```",
+		"kubectl get pods --namespace payments --field-selector status.phase!=Running",
+		"```",
+	}, "\n")
+	if got != want {
+		t.Errorf("fitText =\n%s\nwant\n%s", got, want)
+	}
+	if got := fitText(text, 0); got != text {
+		t.Error("fitText with no width should keep the text")
+	}
+	if got := fitText(text, 200); got != text {
+		t.Error("fitText should keep text narrower than the width")
+	}
+}
+
+func TestWrapLine(t *testing.T) {
+	got := wrapLine("image registry.example.com/team/very-long-image-name:v1.2.3 not found", 20)
+	for _, line := range got {
+		if lipgloss.Width(line) > 20 {
+			t.Errorf("line %q is wider than 20", line)
+		}
+	}
+	if joined := strings.Join(got, ""); strings.ReplaceAll(joined, " ", "") != "imageregistry.example.com/team/very-long-image-name:v1.2.3notfound" {
+		t.Errorf("wrapLine lost text: %q", got)
+	}
+	// Words keep a continuation under the line's indent, not a bullet hang.
+	if got := wrapLine("  Pods unhealthy in namespace payments", 20); got[1] != "  namespace payments" {
+		t.Errorf("wrapLine = %q", got)
+	}
+}
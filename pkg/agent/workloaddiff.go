@@ -0,0 +1,97 @@
+// Package agent provides the core Copilot agent functionality for Kubernetes cluster operations.
+// This file contains the diff_workloads tool comparing two namespaces or clusters.
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+// DiffWorkloadsParams defines parameters for diff_workloads
+type DiffWorkloadsParams struct {
+	Context        string `json:"context" jsonschema:"The context name of the first (reference) cluster, e.g. staging (required)"`
+	Namespace      string `json:"namespace" jsonschema:"The namespace in the first cluster (required)"`
+	OtherContext   string `json:"other_context,omitempty" jsonschema:"The context name of the second cluster, e.g. prod (default: same as context)"`
+	OtherNamespace string `json:"other_namespace,omitempty" jsonschema:"The namespace in the second cluster (default: same as namespace)"`
+	ShowIdentical  bool   `json:"show_identical,omitempty" jsonschema:"Also list workloads that are identical on both sides"`
+}
+
+func defineDiffWorkloadsTool(k8sProvider *k8s.Provider, state *agentState) llm.Tool {
+	return llm.DefineTool(
+		toolDiffWorkloads,
+		"Compare the workloads (Deployments, StatefulSets, DaemonSets, CronJobs) of two namespaces, or of the same namespace in two clusters: which exist on one side only, and for the others differences in replica counts, container images and environment variables. Use it for staging-vs-prod parity checks. Values of secret-looking env vars are hidden. Read-only.",
+		func(params DiffWorkloadsParams, inv llm.ToolInvocation) (any, error) {
+			if params.Context == "" || params.Namespace == "" {
+				return nil, fmt.Errorf("context and namespace are required")
+			}
+			left := k8s.WorkloadLocation{Context: params.Context, Namespace: params.Namespace}
+			right := k8s.WorkloadLocation{Context: params.OtherContext, Namespace: params.OtherNamespace}
+			if right.Context == "" {
+				right.Context = left.Context
+			}
+			if right.Namespace == "" {
+				right.Namespace = left.Namespace
+			}
+			if left == right {
+				return nil, fmt.Errorf("other_context or other_namespace must differ from context and namespace")
+			}
+			for _, contextName := range []string{left.Context, right.Context} {
+				if _, err := getClusterForContext(k8sProvider, contextName); err != nil {
+					return nil, err
+				}
+			}
+
+			report, err := k8sProvider.DiffWorkloads(inv.Ctx(), left, right)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compare workloads: %w", err)
+			}
+			if !params.ShowIdentical {
+				workloads := []k8s.WorkloadComparison{}
+				for _, w := range report.Workloads {
+					if w.State != k8s.WorkloadIdentical {
+						workloads = append(workloads, w)
+					}
+				}
+				report.Workloads = workloads
+			}
+
+			if isJSONOutput(state.outputFormat) {
+				return report, nil
+			}
+			return formatWorkloadDiffReport(report), nil
+		},
+	)
+}
+
+// formatWorkloadDiffReport formats a WorkloadDiffReport as human-readable text
+func formatWorkloadDiffReport(report *k8s.WorkloadDiffReport) string {
+	var sb strings.Builder
+	left, right := report.Left.String(), report.Right.String()
+	fmt.Fprintf(&sb, "🔀 Workloads: %s vs %s\n\n", left, right)
+	fmt.Fprintf(&sb, "Identical: %d | Different: %d | Only in %s: %d | Only in %s: %d\n\n",
+		report.Counts[k8s.WorkloadIdentical], report.Counts[k8s.WorkloadDifferent],
+		left, report.Counts[k8s.WorkloadOnlyLeft], right, report.Counts[k8s.WorkloadOnlyRight])
+
+	for _, w := range report.Workloads {
+		switch w.State {
+		case k8s.WorkloadOnlyLeft:
+			fmt.Fprintf(&sb, "➖ %s %s: only in %s\n", w.Kind, w.Name, left)
+		case k8s.WorkloadOnlyRight:
+			fmt.Fprintf(&sb, "➕ %s %s: only in %s\n", w.Kind, w.Name, right)
+		case k8s.WorkloadIdentical:
+			fmt.Fprintf(&sb, "✅ %s %s: identical\n", w.Kind, w.Name)
+		default:
+			fmt.Fprintf(&sb, "⚠️  %s %s:\n", w.Kind, w.Name)
+			for _, d := range w.Differences {
+				fmt.Fprintf(&sb, "   %s: %s → %s\n", d.Field, d.Left, d.Right)
+			}
+		}
+	}
+	if len(report.Workloads) == 0 {
+		sb.WriteString("No differences found.\n")
+	}
+	return sb.String()
+}
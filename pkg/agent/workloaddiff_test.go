@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+	"github.com/e9169/kopilot/pkg/llm"
+)
+
+func TestDiffWorkloadsToolValidation(t *testing.T) {
+	provider := createMockProvider(t)
+	tool := defineDiffWorkloadsTool(provider, &agentState{outputFormat: OutputText})
+	inv := llm.ToolInvocation{Context: context.Background()}
+	for want, params := range map[string]map[string]any{
+		"context and namespace are required": {"context": "staging"},
+		"must differ":                        {"context": "staging", "namespace": "shop", "other_namespace": "shop"},
+		"does not exist":                     {"context": "staging", "namespace": "shop", "other_context": "prod"},
+	} {
+		if _, err := tool.Handler(params, inv); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%v: error = %v, want %q", params, err, want)
+		}
+	}
+}
+
+func TestFormatWorkloadDiffReport(t *testing.T) {
+	report := &k8s.WorkloadDiffReport{
+		Left:  k8s.WorkloadLocation{Context: "staging", Namespace: "shop"},
+		Right: k8s.WorkloadLocation{Context: "prod", Namespace: "shop"},
+		Workloads: []k8s.WorkloadComparison{
+			{Kind: "CronJob", Name: "cleanup", State: k8s.WorkloadOnlyLeft},
+			{Kind: "Deployment", Name: "web", State: k8s.WorkloadDifferent, Differences: []k8s.WorkloadDifference{
+				{Field: "container app image", Left: "web:1.4", Right: "web:1.3"},
+			}},
+			{Kind: "StatefulSet", Name: "cache", State: k8s.WorkloadOnlyRight},
+		},
+		Counts: map[string]int{k8s.WorkloadIdentical: 4, k8s.WorkloadDifferent: 1, k8s.WorkloadOnlyLeft: 1, k8s.WorkloadOnlyRight: 1},
+	}
+	text := formatWorkloadDiffReport(report)
+	for _, want := range []string{
+		"staging/shop vs prod/shop",
+		"Identical: 4 | Different: 1 | Only in staging/shop: 1 | Only in prod/shop: 1",
+		"➖ CronJob cleanup: only in staging/shop",
+		"container app image: web:1.4 → web:1.3",
+		"➕ StatefulSet cache: only in prod/shop",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+	report.Workloads = nil
+	if text := formatWorkloadDiffReport(report); !strings.Contains(text, "No differences found") {
+		t.Errorf("empty output:\n%s", text)
+	}
+}
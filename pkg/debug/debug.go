@@ -0,0 +1,158 @@
+// Package debug provides category-based debug logging for kopilot
+// (--debug=copilot,k8s,cache,tools) so users see only the traffic they
+// care about when diagnosing why the agent made a particular call.
+package debug
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Category selects one kind of debug output.
+type Category string
+
+// Debug categories.
+const (
+	// Copilot logs the model traffic: sessions, prompts, replies and usage.
+	Copilot Category = "copilot"
+	// K8s logs Kubernetes API requests and kubectl runs.
+	K8s Category = "k8s"
+	// Cache logs cluster status cache hits, misses and evictions.
+	Cache Category = "cache"
+	// Tools logs tool calls with their arguments, duration and errors.
+	Tools Category = "tools"
+)
+
+// Categories lists every category, in --debug help order.
+var Categories = []Category{Copilot, K8s, Cache, Tools}
+
+// maxValueLen bounds prompts and arguments written by Truncate.
+const maxValueLen = 500
+
+var (
+	mu      sync.RWMutex
+	enabled = map[Category]bool{}
+	out     = io.Writer(os.Stderr)
+)
+
+// Parse reads a comma-separated category list; "all" selects every category.
+func Parse(spec string) ([]Category, error) {
+	var cats []Category
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name == "all" {
+			return Categories, nil
+		}
+		if !isCategory(Category(name)) {
+			return nil, fmt.Errorf("unknown debug category %q (use %s or all)", name, joinCategories())
+		}
+		cats = append(cats, Category(name))
+	}
+	return cats, nil
+}
+
+// isCategory reports whether c is a known category.
+func isCategory(c Category) bool {
+	for _, known := range Categories {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
+
+// joinCategories returns the category names separated by commas.
+func joinCategories() string {
+	names := make([]string, len(Categories))
+	for i, c := range Categories {
+		names[i] = string(c)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Enable turns the given categories on, replacing the previous selection.
+func Enable(cats ...Category) {
+	mu.Lock()
+	defer mu.Unlock()
+	enabled = make(map[Category]bool, len(cats))
+	for _, c := range cats {
+		enabled[c] = true
+	}
+}
+
+// Enabled reports whether category c is on.
+func Enabled(c Category) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return enabled[c]
+}
+
+// Any reports whether at least one category is on.
+func Any() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(enabled) > 0
+}
+
+// SetOutput redirects debug output (stderr by default).
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	out = w
+}
+
+// Logf writes one line prefixed with the time and category when c is on.
+func Logf(c Category, format string, args ...any) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if !enabled[c] {
+		return
+	}
+	fmt.Fprintf(out, "%s [%s] %s\n", time.Now().Format("15:04:05.000"), c, fmt.Sprintf(format, args...))
+}
+
+// Truncate shortens s for a log line and flattens newlines.
+func Truncate(s string) string {
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if r := []rune(s); len(r) > maxValueLen {
+		return string(r[:maxValueLen]) + fmt.Sprintf("... (%d chars)", len(r))
+	}
+	return s
+}
+
+// transport logs each HTTP request made through it under the k8s category.
+type transport struct {
+	next http.RoundTripper
+}
+
+// WrapTransport returns rt logging every request as
+// "GET host/path -> 200 (12ms)". It matches rest.Config.WrapTransport.
+func WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return &transport{next: rt}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !Enabled(K8s) {
+		return t.next.RoundTrip(req)
+	}
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	target := req.URL.Host + req.URL.Path
+	if req.URL.RawQuery != "" {
+		target += "?" + req.URL.RawQuery
+	}
+	if err != nil {
+		Logf(K8s, "%s %s -> error after %s: %v", req.Method, target, time.Since(start).Round(time.Millisecond), err)
+		return resp, err
+	}
+	Logf(K8s, "%s %s -> %d (%s)", req.Method, target, resp.StatusCode, time.Since(start).Round(time.Millisecond))
+	return resp, err
+}
@@ -0,0 +1,96 @@
+package debug
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// capture enables cats and collects the output until the test ends.
+func capture(t *testing.T, cats ...Category) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	Enable(cats...)
+	t.Cleanup(func() {
+		Enable()
+		SetOutput(os.Stderr)
+	})
+	return &buf
+}
+
+func TestParse(t *testing.T) {
+	cats, err := Parse(" k8s, Tools ,,")
+	if err != nil || len(cats) != 2 || cats[0] != K8s || cats[1] != Tools {
+		t.Errorf("Parse = %v, %v", cats, err)
+	}
+	if cats, err := Parse("all"); err != nil || len(cats) != len(Categories) {
+		t.Errorf("Parse(all) = %v, %v", cats, err)
+	}
+	if cats, err := Parse(""); err != nil || len(cats) != 0 {
+		t.Errorf("Parse(\"\") = %v, %v", cats, err)
+	}
+	if _, err := Parse("k8s,network"); err == nil || !strings.Contains(err.Error(), "copilot, k8s, cache, tools") {
+		t.Errorf("Parse(network) error = %v", err)
+	}
+}
+
+func TestLogf(t *testing.T) {
+	buf := capture(t, K8s)
+	if !Enabled(K8s) || Enabled(Tools) || !Any() {
+		t.Fatal("only k8s should be enabled")
+	}
+	Logf(K8s, "GET %s", "/api")
+	Logf(Tools, "hidden")
+	out := buf.String()
+	if !strings.Contains(out, "[k8s] GET /api") || strings.Contains(out, "hidden") {
+		t.Errorf("output = %q", out)
+	}
+
+	Enable()
+	if Any() {
+		t.Error("Enable() should turn every category off")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate("a\nb"); got != `a\nb` {
+		t.Errorf("Truncate = %q", got)
+	}
+	long := strings.Repeat("x", maxValueLen+10)
+	if got := Truncate(long); !strings.HasSuffix(got, "... (510 chars)") {
+		t.Errorf("Truncate(long) = %q", got[len(got)-20:])
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestWrapTransport(t *testing.T) {
+	buf := capture(t, K8s)
+	rt := WrapTransport(roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusNotFound}, nil
+	}))
+	req := httptest.NewRequest(http.MethodGet, "https://api.example.com/api/v1/pods?limit=5", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "GET api.example.com/api/v1/pods?limit=5 -> 404") {
+		t.Errorf("output = %q", out)
+	}
+
+	failing := WrapTransport(roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}))
+	if _, err := failing.RoundTrip(req); err == nil {
+		t.Fatal("error should be returned")
+	}
+	if out := buf.String(); !strings.Contains(out, "connection refused") {
+		t.Errorf("output = %q", out)
+	}
+}
@@ -0,0 +1,342 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the node advisory feed: known issues in node kernels, OS
+// images, container runtimes and kubelets.
+package k8s
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/e9169/kopilot/pkg/debug"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Node components an advisory can apply to.
+const (
+	AdvisoryKernel  = "kernel"
+	AdvisoryOSImage = "os_image"
+	AdvisoryRuntime = "container_runtime"
+	AdvisoryKubelet = "kubelet"
+)
+
+// Advisory severities, from worst.
+const (
+	AdvisoryCritical = "critical"
+	AdvisoryHigh     = "high"
+	AdvisoryMedium   = "medium"
+)
+
+// Match confidence of a NodeAdvisory.
+const (
+	// AdvisoryLikely: the version is in the affected range.
+	AdvisoryLikely = "likely"
+	// AdvisoryPossible: a distribution kernel in the affected upstream range;
+	// vendors backport fixes without changing the upstream version.
+	AdvisoryPossible = "possible"
+)
+
+//go:embed advisories.json
+var bundledAdvisoryData []byte
+
+// bundledAdvisories parses the feed compiled into the binary once.
+var bundledAdvisories = sync.OnceValues(func() (*AdvisoryFeed, error) {
+	return parseAdvisoryFeed(bundledAdvisoryData, "bundled")
+})
+
+// Advisory is one known issue of a node component. Versions are affected
+// from Introduced (inclusive, any when empty) up to the Fixed version of
+// their own major.minor branch; versions from the highest Fixed on are not
+// affected. Advisories without versions (end-of-life OS images) match on
+// Product alone.
+type Advisory struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name,omitempty"`
+	Severity   string   `json:"severity"`
+	Component  string   `json:"component"`
+	Product    string   `json:"product,omitempty"` // OS image or runtime name, e.g. "Ubuntu 18.04", "containerd"
+	Introduced string   `json:"introduced,omitempty"`
+	Fixed      []string `json:"fixed,omitempty"`
+	Summary    string   `json:"summary"`
+	URL        string   `json:"url,omitempty"`
+}
+
+// AdvisoryFeed is a versioned list of advisories.
+type AdvisoryFeed struct {
+	Version    string     `json:"version"`
+	Advisories []Advisory `json:"advisories"`
+	// Source is the file the feed was read from, or "bundled".
+	Source string `json:"-"`
+}
+
+// NodeAdvisory is an advisory that applies to a node.
+type NodeAdvisory struct {
+	Node       string `json:"node"`
+	Component  string `json:"component"`
+	Version    string `json:"version"`
+	ID         string `json:"id"`
+	Name       string `json:"name,omitempty"`
+	Severity   string `json:"severity"`
+	Confidence string `json:"confidence"`
+	FixedIn    string `json:"fixed_in,omitempty"`
+	Summary    string `json:"summary"`
+	URL        string `json:"url,omitempty"`
+}
+
+// BundledAdvisoryFeed returns the feed compiled into the binary.
+func BundledAdvisoryFeed() (*AdvisoryFeed, error) {
+	return bundledAdvisories()
+}
+
+// LoadAdvisoryFeed reads a JSON advisory feed from path, replacing the
+// bundled one; this is how the feed is updated without a new release or
+// network access.
+func LoadAdvisoryFeed(path string) (*AdvisoryFeed, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is operator-provided configuration
+	if err != nil {
+		return nil, fmt.Errorf("failed to read advisory feed: %w", err)
+	}
+	return parseAdvisoryFeed(data, path)
+}
+
+func parseAdvisoryFeed(data []byte, source string) (*AdvisoryFeed, error) {
+	var feed AdvisoryFeed
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return nil, fmt.Errorf("failed to parse advisory feed %s: %w", source, err)
+	}
+	for _, a := range feed.Advisories {
+		if err := a.validate(); err != nil {
+			return nil, fmt.Errorf("advisory feed %s: %w", source, err)
+		}
+	}
+	feed.Source = source
+	return &feed, nil
+}
+
+func (a Advisory) validate() error {
+	if a.ID == "" {
+		return fmt.Errorf("advisory without id")
+	}
+	switch a.Severity {
+	case AdvisoryCritical, AdvisoryHigh, AdvisoryMedium:
+	default:
+		return fmt.Errorf("advisory %s: unknown severity %q", a.ID, a.Severity)
+	}
+	switch a.Component {
+	case AdvisoryKernel, AdvisoryKubelet:
+	case AdvisoryOSImage, AdvisoryRuntime:
+		if a.Product == "" {
+			return fmt.Errorf("advisory %s: %s advisories need a product", a.ID, a.Component)
+		}
+	default:
+		return fmt.Errorf("advisory %s: unknown component %q", a.ID, a.Component)
+	}
+	if a.Component != AdvisoryOSImage && len(a.Fixed) == 0 {
+		return fmt.Errorf("advisory %s: fixed versions are required", a.ID)
+	}
+	for _, v := range append([]string{a.Introduced}, a.Fixed...) {
+		if v != "" && parseNodeVersion(v) == nil {
+			return fmt.Errorf("advisory %s: invalid version %q", a.ID, v)
+		}
+	}
+	return nil
+}
+
+// SetAdvisoryFeed replaces the advisory feed used by security audits. Pass
+// nil to go back to the bundled feed.
+func (p *Provider) SetAdvisoryFeed(feed *AdvisoryFeed) {
+	p.cacheMutex.Lock()
+	defer p.cacheMutex.Unlock()
+	p.advisoryFeed = feed
+}
+
+// getAdvisoryFeed returns the configured feed, or the bundled one.
+func (p *Provider) getAdvisoryFeed() (*AdvisoryFeed, error) {
+	p.cacheMutex.RLock()
+	feed := p.advisoryFeed
+	p.cacheMutex.RUnlock()
+	if feed != nil {
+		return feed, nil
+	}
+	return BundledAdvisoryFeed()
+}
+
+// addNodeAdvisories matches the cluster's nodes against the advisory feed.
+// Node access is optional for an audit: without it the section is left out.
+func (p *Provider) addNodeAdvisories(ctx context.Context, clientset kubernetes.Interface, result *SanitizeResult) {
+	feed, err := p.getAdvisoryFeed()
+	if err != nil {
+		debug.Logf(debug.K8s, "advisory feed unavailable: %v", err)
+		return
+	}
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		debug.Logf(debug.K8s, "node advisories skipped: %v", err)
+		return
+	}
+	result.NodeAdvisories = MatchNodeAdvisories(nodes.Items, feed)
+	result.AdvisoryFeed = feed.Version
+}
+
+// MatchNodeAdvisories returns the advisories that apply to each node, worst
+// severity first.
+func MatchNodeAdvisories(nodes []corev1.Node, feed *AdvisoryFeed) []NodeAdvisory {
+	var matches []NodeAdvisory
+	for i := range nodes {
+		info := nodes[i].Status.NodeInfo
+		runtime, runtimeVersion, _ := strings.Cut(info.ContainerRuntimeVersion, "://")
+		for _, a := range feed.Advisories {
+			var version, confidence, fixedIn string
+			var ok bool
+			switch a.Component {
+			case AdvisoryKernel:
+				version = info.KernelVersion
+				confidence, fixedIn, ok = matchVersion(a, version)
+				if ok && confidence == AdvisoryLikely && strings.ContainsAny(version, "-+") {
+					confidence = AdvisoryPossible
+				}
+			case AdvisoryKubelet:
+				version = info.KubeletVersion
+				confidence, fixedIn, ok = matchVersion(a, version)
+			case AdvisoryRuntime:
+				version = info.ContainerRuntimeVersion
+				if strings.EqualFold(runtime, a.Product) {
+					confidence, fixedIn, ok = matchVersion(a, runtimeVersion)
+				}
+			case AdvisoryOSImage:
+				version = info.OSImage
+				ok = containsProduct(info.OSImage, a.Product)
+				confidence = AdvisoryLikely
+			}
+			if !ok {
+				continue
+			}
+			matches = append(matches, NodeAdvisory{
+				Node: nodes[i].Name, Component: a.Component, Version: version,
+				ID: a.ID, Name: a.Name, Severity: a.Severity, Confidence: confidence,
+				FixedIn: fixedIn, Summary: a.Summary, URL: a.URL,
+			})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if ri, rj := advisoryRank(matches[i].Severity), advisoryRank(matches[j].Severity); ri != rj {
+			return ri < rj
+		}
+		if matches[i].ID != matches[j].ID {
+			return matches[i].ID < matches[j].ID
+		}
+		return matches[i].Node < matches[j].Node
+	})
+	return matches
+}
+
+func advisoryRank(severity string) int {
+	switch severity {
+	case AdvisoryCritical:
+		return 0
+	case AdvisoryHigh:
+		return 1
+	}
+	return 2
+}
+
+// matchVersion reports whether version is affected by a, and the fixed
+// version of its branch.
+func matchVersion(a Advisory, version string) (confidence, fixedIn string, affected bool) {
+	v := parseNodeVersion(version)
+	if v == nil {
+		return "", "", false
+	}
+	if a.Introduced != "" && compareVersions(v, parseNodeVersion(a.Introduced)) < 0 {
+		return "", "", false
+	}
+	var newest []int
+	for _, f := range a.Fixed {
+		fv := parseNodeVersion(f)
+		if len(fv) >= 2 && len(v) >= 2 && fv[0] == v[0] && fv[1] == v[1] {
+			if compareVersions(v, fv) >= 0 {
+				return "", "", false
+			}
+			return AdvisoryLikely, f, true
+		}
+		if newest == nil || compareVersions(fv, newest) > 0 {
+			newest, fixedIn = fv, f
+		}
+	}
+	if compareVersions(v, newest) >= 0 {
+		return "", "", false
+	}
+	// A branch without its own fix: upgrade to a fixed release.
+	return AdvisoryLikely, fixedIn, true
+}
+
+// parseNodeVersion parses the dotted numeric part of a version such as
+// "v1.27.3-eks-1", "5.15.0-1034-aws" or "1.7.2", ignoring any suffix.
+func parseNodeVersion(s string) []int {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+~ _"); i >= 0 {
+		s = s[:i]
+	}
+	if s == "" {
+		return nil
+	}
+	var parts []int
+	for _, p := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// compareVersions compares dotted versions; missing parts count as zero.
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// containsProduct reports whether image names product, case-insensitively
+// and not as the prefix of a longer version ("Amazon Linux 2" does not
+// match "Amazon Linux 2023").
+func containsProduct(image, product string) bool {
+	image, product = strings.ToLower(image), strings.ToLower(product)
+	for start := 0; ; {
+		i := strings.Index(image[start:], product)
+		if i < 0 {
+			return false
+		}
+		end := start + i + len(product)
+		if end == len(image) || !isAlnum(image[end]) {
+			return true
+		}
+		start += i + 1
+	}
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z'
+}
@@ -0,0 +1,199 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func advisoryNode(name, kernel, image, runtime, kubelet string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{
+			KernelVersion:           kernel,
+			OSImage:                 image,
+			ContainerRuntimeVersion: runtime,
+			KubeletVersion:          kubelet,
+		}},
+	}
+}
+
+func TestBundledAdvisoryFeed(t *testing.T) {
+	feed, err := BundledAdvisoryFeed()
+	if err != nil {
+		t.Fatalf("BundledAdvisoryFeed() error = %v", err)
+	}
+	if feed.Version == "" || len(feed.Advisories) == 0 || feed.Source != "bundled" {
+		t.Errorf("feed = version %q, %d advisories, source %q", feed.Version, len(feed.Advisories), feed.Source)
+	}
+}
+
+func TestMatchVersion(t *testing.T) {
+	dirtyPipe := Advisory{ID: "CVE-2022-0847", Introduced: "5.8", Fixed: []string{"5.10.102", "5.15.25", "5.16.11"}}
+	tests := []struct {
+		version  string
+		affected bool
+		fixedIn  string
+	}{
+		{"5.4.250", false, ""}, // before Introduced
+		{"5.10.101", true, "5.10.102"},
+		{"5.10.102", false, ""},
+		{"5.15.20", true, "5.15.25"},
+		{"5.15.30", false, ""},
+		{"5.11.3", true, "5.16.11"}, // branch without its own fix
+		{"6.2.0", false, ""},
+		{"unknown", false, ""},
+	}
+	for _, tt := range tests {
+		_, fixedIn, affected := matchVersion(dirtyPipe, tt.version)
+		if affected != tt.affected || fixedIn != tt.fixedIn {
+			t.Errorf("matchVersion(%s) = %v, %q; want %v, %q", tt.version, affected, fixedIn, tt.affected, tt.fixedIn)
+		}
+	}
+}
+
+func TestParseNodeVersion(t *testing.T) {
+	tests := map[string][]int{
+		"v1.27.3-eks-a5565ad":           {1, 27, 3},
+		"5.15.0-1034-aws":               {5, 15, 0},
+		"5.10.184-175.731.amzn2.x86_64": {5, 10, 184},
+		"1.7.2":                         {1, 7, 2},
+		"":                              nil,
+		"abc":                           nil,
+	}
+	for in, want := range tests {
+		if got := parseNodeVersion(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("parseNodeVersion(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestContainsProduct(t *testing.T) {
+	tests := []struct {
+		image, product string
+		want           bool
+	}{
+		{"Amazon Linux 2", "Amazon Linux 2", true},
+		{"Amazon Linux 2023.5.20240805", "Amazon Linux 2", false},
+		{"Ubuntu 18.04.6 LTS", "Ubuntu 18.04", true},
+		{"Ubuntu 22.04.4 LTS", "Ubuntu 18.04", false},
+		{"CentOS Linux 7 (Core)", "centos linux 7", true},
+	}
+	for _, tt := range tests {
+		if got := containsProduct(tt.image, tt.product); got != tt.want {
+			t.Errorf("containsProduct(%q, %q) = %v, want %v", tt.image, tt.product, got, tt.want)
+		}
+	}
+}
+
+func TestMatchNodeAdvisories(t *testing.T) {
+	feed, err := BundledAdvisoryFeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := []corev1.Node{
+		*advisoryNode("old", "5.15.20", "Ubuntu 18.04.6 LTS", "containerd://1.7.2", "v1.22.1"),
+		*advisoryNode("vendor", "5.15.0-1034-aws", "Ubuntu 22.04.4 LTS", "containerd://1.7.20", "v1.30.2"),
+		*advisoryNode("current", "6.8.0", "Bottlerocket OS 1.20.0", "containerd://1.7.20+bottlerocket", "v1.30.2"),
+	}
+	matches := MatchNodeAdvisories(nodes, feed)
+
+	got := map[string]NodeAdvisory{}
+	for _, m := range matches {
+		got[m.Node+" "+m.ID] = m
+	}
+	for _, key := range []string{"old CVE-2022-0847", "old CVE-2024-1086", "old CVE-2024-21626", "old CVE-2021-25741", "old EOL-UBUNTU-18.04"} {
+		if m, ok := got[key]; !ok || m.Confidence != AdvisoryLikely {
+			t.Errorf("%s: got %+v, want a likely match", key, m)
+		}
+	}
+	if m := got["old CVE-2024-21626"]; m.FixedIn != "1.7.13" || m.Version != "containerd://1.7.2" {
+		t.Errorf("runtime match = %+v", m)
+	}
+	if m, ok := got["vendor CVE-2022-0847"]; !ok || m.Confidence != AdvisoryPossible {
+		t.Errorf("vendor kernel Dirty Pipe = %+v, want a possible match", m)
+	}
+	for key := range got {
+		if strings.HasPrefix(key, "current ") {
+			t.Errorf("unexpected match %s", key)
+		}
+	}
+	for i := 1; i < len(matches); i++ {
+		if advisoryRank(matches[i-1].Severity) > advisoryRank(matches[i].Severity) {
+			t.Fatalf("matches not ordered by severity: %+v", matches)
+		}
+	}
+}
+
+func TestLoadAdvisoryFeed(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	feed, err := LoadAdvisoryFeed(write("ok.json", `{"version":"local-1","advisories":[
+		{"id":"X-1","severity":"critical","component":"kernel","fixed":["6.1.100"],"summary":"s"}]}`))
+	if err != nil {
+		t.Fatalf("LoadAdvisoryFeed() error = %v", err)
+	}
+	if feed.Version != "local-1" || !strings.HasSuffix(feed.Source, "ok.json") {
+		t.Errorf("feed = %+v", feed)
+	}
+
+	bad := map[string]string{
+		"severity.json": `{"advisories":[{"id":"X","severity":"urgent","component":"kernel","fixed":["1.0"]}]}`,
+		"product.json":  `{"advisories":[{"id":"X","severity":"high","component":"os_image"}]}`,
+		"fixed.json":    `{"advisories":[{"id":"X","severity":"high","component":"kubelet"}]}`,
+		"version.json":  `{"advisories":[{"id":"X","severity":"high","component":"kubelet","fixed":["latest"]}]}`,
+		"syntax.json":   `{"advisories":`,
+	}
+	for name, content := range bad {
+		if _, err := LoadAdvisoryFeed(write(name, content)); err == nil {
+			t.Errorf("LoadAdvisoryFeed(%s) succeeded, want an error", name)
+		}
+	}
+	if _, err := LoadAdvisoryFeed(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadAdvisoryFeed(missing) succeeded, want an error")
+	}
+}
+
+func TestAddNodeAdvisories(t *testing.T) {
+	p := &Provider{}
+	p.SetAdvisoryFeed(&AdvisoryFeed{Version: "test-feed", Advisories: []Advisory{
+		{ID: "X-1", Severity: AdvisoryCritical, Component: AdvisoryKubelet, Fixed: []string{"1.30.0"}, Summary: "s"},
+	}})
+	clientset := fake.NewClientset(
+		advisoryNode("node-a", "6.8.0", "Ubuntu 22.04", "containerd://1.7.20", "v1.29.4"),
+		advisoryNode("node-b", "6.8.0", "Ubuntu 22.04", "containerd://1.7.20", "v1.30.1"),
+	)
+
+	result := &SanitizeResult{}
+	p.addNodeAdvisories(context.Background(), clientset, result)
+	if result.AdvisoryFeed != "test-feed" || len(result.NodeAdvisories) != 1 || result.NodeAdvisories[0].Node != "node-a" {
+		t.Errorf("result = %q, %+v", result.AdvisoryFeed, result.NodeAdvisories)
+	}
+
+	// Without node access the section is left out rather than failing the audit.
+	clientset.PrependReactor("list", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+	result = &SanitizeResult{}
+	p.addNodeAdvisories(context.Background(), clientset, result)
+	if result.AdvisoryFeed != "" || result.NodeAdvisories != nil {
+		t.Errorf("result without node access = %q, %+v", result.AdvisoryFeed, result.NodeAdvisories)
+	}
+}
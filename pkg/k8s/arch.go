@@ -0,0 +1,315 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains CPU architecture (amd64/arm64) image compatibility checks.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Architecture issue kinds reported in ArchReport.
+const (
+	// ArchIssueExecFormat: the container binary was built for another CPU architecture.
+	ArchIssueExecFormat = "exec_format_error"
+	// ArchIssueNoManifest: the image has no manifest for the node's platform.
+	ArchIssueNoManifest = "no_matching_manifest"
+	// ArchIssueNoMatchingNodes: the pod is pinned to an architecture no ready node provides.
+	ArchIssueNoMatchingNodes = "no_matching_nodes"
+)
+
+// execFormatPatterns identify a binary built for the wrong architecture.
+var execFormatPatterns = []string{"exec format error"}
+
+// noManifestPatterns identify a pull of an image that lacks the node's platform.
+var noManifestPatterns = []string{
+	"no matching manifest for",
+	"does not match the specified platform",
+	"no match for platform in manifest",
+}
+
+// ArchIssue flags a pod failing (or blocked) because of CPU architecture.
+type ArchIssue struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container,omitempty"`
+	Image     string `json:"image,omitempty"`
+	Node      string `json:"node,omitempty"`
+	NodeArch  string `json:"node_arch,omitempty"`
+	// WorksOn lists architectures where the same image is running healthily,
+	// which strongly suggests a single-arch image.
+	WorksOn  []string `json:"works_on,omitempty"`
+	Evidence string   `json:"evidence"`
+}
+
+// ArchReport summarises node architectures and architecture-related failures.
+type ArchReport struct {
+	Context      string         `json:"context"`
+	Distribution map[string]int `json:"distribution"`
+	Mixed        bool           `json:"mixed"`
+	Issues       []ArchIssue    `json:"issues"`
+}
+
+// nodeArch returns the node's CPU architecture from the well-known label,
+// falling back to the kubelet-reported value.
+func nodeArch(node *corev1.Node) string {
+	if arch := node.Labels[corev1.LabelArchStable]; arch != "" {
+		return arch
+	}
+	if arch := node.Status.NodeInfo.Architecture; arch != "" {
+		return arch
+	}
+	return "unknown"
+}
+
+// archDistribution counts nodes per CPU architecture.
+func archDistribution(nodes []NodeInfo) map[string]int {
+	dist := make(map[string]int)
+	for _, n := range nodes {
+		dist[n.Arch]++
+	}
+	return dist
+}
+
+// matchArchFailure classifies msg as an architecture failure, if it is one.
+func matchArchFailure(msg string) (string, bool) {
+	lower := strings.ToLower(msg)
+	for _, p := range execFormatPatterns {
+		if strings.Contains(lower, p) {
+			return ArchIssueExecFormat, true
+		}
+	}
+	for _, p := range noManifestPatterns {
+		if strings.Contains(lower, p) {
+			return ArchIssueNoManifest, true
+		}
+	}
+	return "", false
+}
+
+// containerFailureMessages returns the waiting and last-terminated messages for each container.
+func containerFailureMessages(pod *corev1.Pod) map[string][]string {
+	out := make(map[string][]string)
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		if w := cs.State.Waiting; w != nil && w.Message != "" {
+			out[cs.Name] = append(out[cs.Name], w.Message)
+		}
+		if t := cs.State.Terminated; t != nil && t.Message != "" {
+			out[cs.Name] = append(out[cs.Name], t.Message)
+		}
+		if t := cs.LastTerminationState.Terminated; t != nil && t.Message != "" {
+			out[cs.Name] = append(out[cs.Name], t.Message)
+		}
+	}
+	return out
+}
+
+// containerImages maps container names to their images, including init containers.
+func containerImages(pod *corev1.Pod) map[string]string {
+	images := make(map[string]string)
+	for _, c := range pod.Spec.InitContainers {
+		images[c.Name] = c.Image
+	}
+	for _, c := range pod.Spec.Containers {
+		images[c.Name] = c.Image
+	}
+	return images
+}
+
+// healthyImageArches records, per image, the architectures where a container
+// using it is running and ready.
+func healthyImageArches(pods []corev1.Pod, archByNode map[string]string) map[string]map[string]bool {
+	out := make(map[string]map[string]bool)
+	for i := range pods {
+		pod := &pods[i]
+		arch := archByNode[pod.Spec.NodeName]
+		if arch == "" {
+			continue
+		}
+		images := containerImages(pod)
+		for _, cs := range pod.Status.ContainerStatuses {
+			if !cs.Ready || cs.State.Running == nil {
+				continue
+			}
+			image := images[cs.Name]
+			if out[image] == nil {
+				out[image] = make(map[string]bool)
+			}
+			out[image][arch] = true
+		}
+	}
+	return out
+}
+
+// buildArchReport cross-references node architectures with container failures and events.
+func buildArchReport(nodes []corev1.Node, pods []corev1.Pod, events []corev1.Event) *ArchReport {
+	report := &ArchReport{Distribution: make(map[string]int), Issues: []ArchIssue{}}
+
+	archByNode := make(map[string]string, len(nodes))
+	readyArch := make(map[string]bool)
+	for i := range nodes {
+		arch := nodeArch(&nodes[i])
+		archByNode[nodes[i].Name] = arch
+		report.Distribution[arch]++
+		if isNodeReady(&nodes[i]) {
+			readyArch[arch] = true
+		}
+	}
+	report.Mixed = len(report.Distribution) > 1
+	healthy := healthyImageArches(pods, archByNode)
+
+	// Event messages indexed by pod, since kubelet often reports pull and
+	// start failures only as events.
+	eventsByPod := make(map[string][]string)
+	for _, ev := range events {
+		if ev.InvolvedObject.Kind == "Pod" {
+			key := ev.InvolvedObject.Namespace + "/" + ev.InvolvedObject.Name
+			eventsByPod[key] = append(eventsByPod[key], ev.Message)
+		}
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+		if issue, ok := pinnedArchIssue(pod, readyArch); ok {
+			report.Issues = append(report.Issues, issue)
+			continue
+		}
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		report.Issues = append(report.Issues, podArchIssues(pod, archByNode[pod.Spec.NodeName], healthy, eventsByPod[pod.Namespace+"/"+pod.Name])...)
+	}
+
+	sort.Slice(report.Issues, func(a, b int) bool {
+		x, y := report.Issues[a], report.Issues[b]
+		if x.Namespace != y.Namespace {
+			return x.Namespace < y.Namespace
+		}
+		if x.Pod != y.Pod {
+			return x.Pod < y.Pod
+		}
+		return x.Container < y.Container
+	})
+	return report
+}
+
+// pinnedArchIssue flags unscheduled pods pinned to an architecture with no ready nodes.
+func pinnedArchIssue(pod *corev1.Pod, readyArch map[string]bool) (ArchIssue, bool) {
+	if pod.Spec.NodeName != "" || pod.Status.Phase != corev1.PodPending {
+		return ArchIssue{}, false
+	}
+	want := pod.Spec.NodeSelector[corev1.LabelArchStable]
+	if want == "" {
+		want = requiredAffinityValue(pod, corev1.LabelArchStable)
+	}
+	if want == "" || readyArch[want] {
+		return ArchIssue{}, false
+	}
+	return ArchIssue{
+		Kind:      ArchIssueNoMatchingNodes,
+		Namespace: pod.Namespace,
+		Pod:       pod.Name,
+		Evidence:  fmt.Sprintf("pod requires %s nodes but the cluster has no ready %s nodes", want, want),
+	}, true
+}
+
+// podArchIssues returns one issue per container whose failures match an architecture pattern.
+func podArchIssues(pod *corev1.Pod, arch string, healthy map[string]map[string]bool, events []string) []ArchIssue {
+	images := containerImages(pod)
+	var issues []ArchIssue
+	seen := make(map[string]bool)
+
+	add := func(container, kind, evidence string) {
+		if seen[container] {
+			return
+		}
+		seen[container] = true
+		issue := ArchIssue{
+			Kind:      kind,
+			Namespace: pod.Namespace,
+			Pod:       pod.Name,
+			Container: container,
+			Image:     images[container],
+			Node:      pod.Spec.NodeName,
+			NodeArch:  arch,
+			Evidence:  evidence,
+		}
+		for other := range healthy[issue.Image] {
+			if other != arch {
+				issue.WorksOn = append(issue.WorksOn, other)
+			}
+		}
+		sort.Strings(issue.WorksOn)
+		issues = append(issues, issue)
+	}
+
+	for container, msgs := range containerFailureMessages(pod) {
+		for _, msg := range msgs {
+			if kind, ok := matchArchFailure(msg); ok {
+				add(container, kind, msg)
+				break
+			}
+		}
+	}
+	for _, msg := range events {
+		kind, ok := matchArchFailure(msg)
+		if !ok {
+			continue
+		}
+		// Attribute the event to the container whose image it mentions, if any.
+		container := ""
+		for name, image := range images {
+			if strings.Contains(msg, image) {
+				container = name
+				break
+			}
+		}
+		add(container, kind, msg)
+	}
+	return issues
+}
+
+// collectArchReport lists nodes, pods, and events and builds an ArchReport.
+func collectArchReport(ctx context.Context, clientset kubernetes.Interface) (*ArchReport, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	// Events are best effort: the report is still useful from container statuses alone.
+	var events []corev1.Event
+	if list, err := clientset.CoreV1().Events("").List(ctx, metav1.ListOptions{}); err == nil {
+		events = list.Items
+	}
+	return buildArchReport(nodes.Items, pods.Items, events), nil
+}
+
+// CheckImageArch reports node CPU architectures and pods failing with
+// exec-format or missing-platform errors, cross-referenced with where the same
+// image runs successfully.
+func (p *Provider) CheckImageArch(ctx context.Context, contextName string) (*ArchReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	report, err := collectArchReport(queryCtx, clientset)
+	if err != nil {
+		return nil, err
+	}
+	report.Context = contextName
+	return report, nil
+}
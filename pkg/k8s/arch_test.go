@@ -0,0 +1,127 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func archNode(name, arch string) corev1.Node {
+	node := osNode(name, OSLinux, true)
+	node.Labels[corev1.LabelArchStable] = arch
+	return node
+}
+
+func archPod(name, node, image string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps"},
+		Spec: corev1.PodSpec{
+			NodeName:   node,
+			Containers: []corev1.Container{{Name: "app", Image: image}},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "app", Ready: true,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			}},
+		},
+	}
+}
+
+func TestNodeArchFallback(t *testing.T) {
+	node := &corev1.Node{Status: corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{Architecture: "arm64"}}}
+	if got := nodeArch(node); got != "arm64" {
+		t.Errorf("nodeArch() = %q, want arm64", got)
+	}
+	if got := nodeArch(&corev1.Node{}); got != "unknown" {
+		t.Errorf("nodeArch() = %q, want unknown", got)
+	}
+}
+
+func TestMatchArchFailure(t *testing.T) {
+	tests := []struct {
+		msg  string
+		kind string
+		ok   bool
+	}{
+		{"failed to create containerd task: exec /app/server: Exec format error", ArchIssueExecFormat, true},
+		{"Failed to pull image \"x\": no matching manifest for linux/arm64/v8 in the manifest list entries", ArchIssueNoManifest, true},
+		{"image with reference x was found but does not match the specified platform", ArchIssueNoManifest, true},
+		{"Back-off pulling image", "", false},
+	}
+	for _, tt := range tests {
+		kind, ok := matchArchFailure(tt.msg)
+		if kind != tt.kind || ok != tt.ok {
+			t.Errorf("matchArchFailure(%q) = %q, %v; want %q, %v", tt.msg, kind, ok, tt.kind, tt.ok)
+		}
+	}
+}
+
+func TestCollectArchReport(t *testing.T) {
+	nodes := []corev1.Node{archNode("x86-1", "amd64"), archNode("grav-1", "arm64")}
+	healthy := archPod("api-good", "x86-1", "registry/api:1.0")
+
+	crashing := archPod("api-bad", "grav-1", "registry/api:1.0")
+	crashing.Status.ContainerStatuses[0] = corev1.ContainerStatus{
+		Name: "app",
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+			Reason: "CrashLoopBackOff", Message: "back-off restarting failed container",
+		}},
+		LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+			ExitCode: 1, Message: "exec /usr/bin/api: exec format error",
+		}},
+	}
+
+	pulling := archPod("worker-1", "grav-1", "registry/worker:2.3")
+	pulling.Status.ContainerStatuses[0] = corev1.ContainerStatus{
+		Name:  "app",
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+	}
+	pullEvent := corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "worker-1.1", Namespace: "apps"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "apps", Name: "worker-1"},
+		Type:           corev1.EventTypeWarning,
+		Reason:         "Failed",
+		Message:        "Failed to pull image \"registry/worker:2.3\": no matching manifest for linux/arm64 in the manifest list entries",
+	}
+
+	pinned := archPod("batch-1", "", "registry/batch:1")
+	pinned.Status = corev1.PodStatus{Phase: corev1.PodPending}
+	pinned.Spec.NodeSelector = map[string]string{corev1.LabelArchStable: "s390x"}
+
+	objects := []runtime.Object{&nodes[0], &nodes[1], &healthy, &crashing, &pulling, &pinned, &pullEvent}
+	report, err := collectArchReport(context.Background(), fake.NewClientset(objects...))
+	if err != nil {
+		t.Fatalf("collectArchReport() error = %v", err)
+	}
+
+	if !report.Mixed || report.Distribution["amd64"] != 1 || report.Distribution["arm64"] != 1 {
+		t.Fatalf("unexpected distribution: %+v mixed=%v", report.Distribution, report.Mixed)
+	}
+	if len(report.Issues) != 3 {
+		t.Fatalf("got %d issues, want 3: %+v", len(report.Issues), report.Issues)
+	}
+
+	byPod := make(map[string]ArchIssue)
+	for _, issue := range report.Issues {
+		byPod[issue.Pod] = issue
+	}
+	bad := byPod["api-bad"]
+	if bad.Kind != ArchIssueExecFormat || bad.NodeArch != "arm64" || bad.Container != "app" {
+		t.Errorf("api-bad issue = %+v", bad)
+	}
+	if len(bad.WorksOn) != 1 || bad.WorksOn[0] != "amd64" {
+		t.Errorf("api-bad WorksOn = %v, want [amd64]", bad.WorksOn)
+	}
+	if w := byPod["worker-1"]; w.Kind != ArchIssueNoManifest || w.Image != "registry/worker:2.3" {
+		t.Errorf("worker-1 issue = %+v", w)
+	}
+	if b := byPod["batch-1"]; b.Kind != ArchIssueNoMatchingNodes {
+		t.Errorf("batch-1 issue = %+v", b)
+	}
+}
@@ -0,0 +1,441 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the HorizontalPodAutoscaler and VerticalPodAutoscaler collector.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/debug"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// hpaFlapWindow is how far back rescale events are counted; the API
+	// server keeps events for an hour by default.
+	hpaFlapWindow = time.Hour
+	// hpaFlapRescales is the number of rescales in both directions within
+	// hpaFlapWindow that counts as flapping.
+	hpaFlapRescales = 4
+)
+
+// Autoscaling report issues, from worst.
+const (
+	AutoscalingIssueUnableToScale  = "unable_to_scale"
+	AutoscalingIssueMetricsUnknown = "metrics_unknown"
+	AutoscalingIssueFlapping       = "flapping"
+	AutoscalingIssueAtMax          = "at_max"
+)
+
+// vpaKind is the Vertical Pod Autoscaler CRD, listed like the GitOps CRDs.
+var vpaKind = gitOpsKind{kind: "VerticalPodAutoscaler", group: "autoscaling.k8s.io", resource: "verticalpodautoscalers", versions: []string{"v1"}}
+
+// AutoscalingIssue is an autoscaler that needs attention.
+type AutoscalingIssue struct {
+	Issue     string `json:"issue"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Target    string `json:"target"` // Kind/name of the scaled workload
+	Message   string `json:"message"`
+}
+
+// HPAStatus summarizes one HorizontalPodAutoscaler.
+type HPAStatus struct {
+	Namespace       string `json:"namespace"`
+	Name            string `json:"name"`
+	Target          string `json:"target"`
+	MinReplicas     int32  `json:"min_replicas"`
+	MaxReplicas     int32  `json:"max_replicas"`
+	CurrentReplicas int32  `json:"current_replicas"`
+	DesiredReplicas int32  `json:"desired_replicas"`
+	// Metrics are "name current/target" pairs, e.g. "cpu 92%/70%".
+	Metrics       []string `json:"metrics"`
+	LastScaleTime string   `json:"last_scale_time,omitempty"` // RFC3339
+	// Rescales counts rescale events within the last hour.
+	Rescales int32 `json:"rescales,omitempty"`
+}
+
+// VPAContainerRecommendation is the recommendation for one container; each
+// map holds cpu and memory quantities.
+type VPAContainerRecommendation struct {
+	Container  string            `json:"container"`
+	Target     map[string]string `json:"target"`
+	LowerBound map[string]string `json:"lower_bound,omitempty"`
+	UpperBound map[string]string `json:"upper_bound,omitempty"`
+}
+
+// VPARecommendation is the recommendation of one VerticalPodAutoscaler.
+type VPARecommendation struct {
+	Namespace  string                       `json:"namespace"`
+	Name       string                       `json:"name"`
+	Target     string                       `json:"target"`
+	UpdateMode string                       `json:"update_mode"`
+	Containers []VPAContainerRecommendation `json:"containers"`
+	// Message explains a missing recommendation.
+	Message string `json:"message,omitempty"`
+}
+
+// AutoscalingReport is the outcome of CheckAutoscaling.
+type AutoscalingReport struct {
+	Context   string             `json:"context"`
+	Namespace string             `json:"namespace,omitempty"`
+	HPAs      []HPAStatus        `json:"hpas"`
+	Findings  []AutoscalingIssue `json:"findings"`
+	Issues    map[string]int     `json:"issues"` // issue -> finding count
+	// VPAInstalled reports whether the VerticalPodAutoscaler CRD is served.
+	VPAInstalled bool                `json:"vpa_installed"`
+	VPAs         []VPARecommendation `json:"vpas,omitempty"`
+	// Warnings note data that could not be read, e.g. for lack of RBAC.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// hpaCondition returns the HPA condition of type t, or nil.
+func hpaCondition(hpa *autoscalingv2.HorizontalPodAutoscaler, t autoscalingv2.HorizontalPodAutoscalerConditionType) *autoscalingv2.HorizontalPodAutoscalerCondition {
+	for i := range hpa.Status.Conditions {
+		if hpa.Status.Conditions[i].Type == t {
+			return &hpa.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// hpaStatus summarizes hpa.
+func hpaStatus(hpa *autoscalingv2.HorizontalPodAutoscaler) HPAStatus {
+	s := HPAStatus{
+		Namespace:       hpa.Namespace,
+		Name:            hpa.Name,
+		Target:          hpa.Spec.ScaleTargetRef.Kind + "/" + hpa.Spec.ScaleTargetRef.Name,
+		MinReplicas:     1,
+		MaxReplicas:     hpa.Spec.MaxReplicas,
+		CurrentReplicas: hpa.Status.CurrentReplicas,
+		DesiredReplicas: hpa.Status.DesiredReplicas,
+		Metrics:         hpaMetrics(hpa),
+	}
+	if hpa.Spec.MinReplicas != nil {
+		s.MinReplicas = *hpa.Spec.MinReplicas
+	}
+	if hpa.Status.LastScaleTime != nil {
+		s.LastScaleTime = hpa.Status.LastScaleTime.UTC().Format(time.RFC3339)
+	}
+	return s
+}
+
+// hpaMetrics pairs each metric target with its current value, which is
+// "<unknown>" when the metric cannot be read.
+func hpaMetrics(hpa *autoscalingv2.HorizontalPodAutoscaler) []string {
+	current := make(map[string]string, len(hpa.Status.CurrentMetrics))
+	for _, m := range hpa.Status.CurrentMetrics {
+		name, value := metricStatusValue(m)
+		current[name] = value
+	}
+	metrics := make([]string, 0, len(hpa.Spec.Metrics))
+	for _, m := range hpa.Spec.Metrics {
+		name, target := metricSpecTarget(m)
+		value, ok := current[name]
+		if !ok || value == "" {
+			value = "<unknown>"
+		}
+		metrics = append(metrics, fmt.Sprintf("%s %s/%s", name, value, target))
+	}
+	return metrics
+}
+
+func metricSpecTarget(m autoscalingv2.MetricSpec) (string, string) {
+	switch {
+	case m.Resource != nil:
+		return string(m.Resource.Name), metricTargetValue(m.Resource.Target)
+	case m.ContainerResource != nil:
+		return m.ContainerResource.Container + "/" + string(m.ContainerResource.Name), metricTargetValue(m.ContainerResource.Target)
+	case m.Pods != nil:
+		return m.Pods.Metric.Name, metricTargetValue(m.Pods.Target)
+	case m.Object != nil:
+		return m.Object.Metric.Name, metricTargetValue(m.Object.Target)
+	case m.External != nil:
+		return m.External.Metric.Name, metricTargetValue(m.External.Target)
+	}
+	return string(m.Type), "?"
+}
+
+func metricStatusValue(m autoscalingv2.MetricStatus) (string, string) {
+	switch {
+	case m.Resource != nil:
+		return string(m.Resource.Name), metricCurrentValue(m.Resource.Current)
+	case m.ContainerResource != nil:
+		return m.ContainerResource.Container + "/" + string(m.ContainerResource.Name), metricCurrentValue(m.ContainerResource.Current)
+	case m.Pods != nil:
+		return m.Pods.Metric.Name, metricCurrentValue(m.Pods.Current)
+	case m.Object != nil:
+		return m.Object.Metric.Name, metricCurrentValue(m.Object.Current)
+	case m.External != nil:
+		return m.External.Metric.Name, metricCurrentValue(m.External.Current)
+	}
+	return string(m.Type), ""
+}
+
+func metricTargetValue(t autoscalingv2.MetricTarget) string {
+	switch {
+	case t.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *t.AverageUtilization)
+	case t.AverageValue != nil:
+		return t.AverageValue.String() + " avg"
+	case t.Value != nil:
+		return t.Value.String()
+	}
+	return "?"
+}
+
+func metricCurrentValue(v autoscalingv2.MetricValueStatus) string {
+	switch {
+	case v.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *v.AverageUtilization)
+	case v.AverageValue != nil:
+		return v.AverageValue.String()
+	case v.Value != nil:
+		return v.Value.String()
+	}
+	return ""
+}
+
+// hpaIssues reports an HPA that cannot scale, cannot read its metrics, or is
+// held at its maximum because it would scale further.
+func hpaIssues(hpa *autoscalingv2.HorizontalPodAutoscaler, s HPAStatus) []AutoscalingIssue {
+	issue := func(kind, format string, args ...any) AutoscalingIssue {
+		return AutoscalingIssue{Issue: kind, Namespace: s.Namespace, Name: s.Name, Target: s.Target, Message: fmt.Sprintf(format, args...)}
+	}
+	var issues []AutoscalingIssue
+	if c := hpaCondition(hpa, autoscalingv2.AbleToScale); c != nil && c.Status == corev1.ConditionFalse {
+		issues = append(issues, issue(AutoscalingIssueUnableToScale, "%s: %s", c.Reason, c.Message))
+	}
+	// ScalingDisabled means the target was scaled to zero on purpose.
+	if c := hpaCondition(hpa, autoscalingv2.ScalingActive); c != nil && c.Status == corev1.ConditionFalse && c.Reason != "ScalingDisabled" {
+		issues = append(issues, issue(AutoscalingIssueMetricsUnknown, "%s: %s", c.Reason, c.Message))
+	}
+	if s.MaxReplicas > 0 && s.CurrentReplicas >= s.MaxReplicas && s.DesiredReplicas >= s.MaxReplicas {
+		msg := fmt.Sprintf("at max replicas (%d)", s.MaxReplicas)
+		if c := hpaCondition(hpa, autoscalingv2.ScalingLimited); c != nil && c.Status == corev1.ConditionTrue && c.Reason == "TooManyReplicas" {
+			msg += " and capped: the metrics ask for more"
+		}
+		if len(s.Metrics) > 0 {
+			msg += "; " + strings.Join(s.Metrics, ", ")
+		}
+		issues = append(issues, issue(AutoscalingIssueAtMax, "%s", msg))
+	}
+	return issues
+}
+
+// hpaRescales counts the rescales of each HPA (namespace/name) up and down
+// since since, from SuccessfulRescale events.
+func hpaRescales(events []corev1.Event, since time.Time) (up, down map[string]int32) {
+	up, down = make(map[string]int32), make(map[string]int32)
+	for i := range events {
+		ev := &events[i]
+		if ev.InvolvedObject.Kind != "HorizontalPodAutoscaler" || ev.Reason != "SuccessfulRescale" || eventLastSeen(ev).Before(since) {
+			continue
+		}
+		key := ev.InvolvedObject.Namespace + "/" + ev.InvolvedObject.Name
+		// "New size: 5; reason: cpu resource utilization (percentage of request) above target"
+		switch {
+		case strings.Contains(ev.Message, "above target"):
+			up[key] += eventCount(ev)
+		case strings.Contains(ev.Message, "below target"):
+			down[key] += eventCount(ev)
+		}
+	}
+	return up, down
+}
+
+var autoscalingIssueRank = map[string]int{
+	AutoscalingIssueUnableToScale: 0, AutoscalingIssueMetricsUnknown: 1, AutoscalingIssueFlapping: 2, AutoscalingIssueAtMax: 3,
+}
+
+func sortAutoscalingIssues(issues []AutoscalingIssue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if autoscalingIssueRank[a.Issue] != autoscalingIssueRank[b.Issue] {
+			return autoscalingIssueRank[a.Issue] < autoscalingIssueRank[b.Issue]
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+}
+
+// buildAutoscalingReport assesses the HPAs in scope; events are the rescale
+// events used to find flapping HPAs.
+func buildAutoscalingReport(hpas []autoscalingv2.HorizontalPodAutoscaler, events []corev1.Event, targetNamespace string, includeSystem bool, now time.Time) *AutoscalingReport {
+	report := &AutoscalingReport{
+		Namespace: targetNamespace,
+		HPAs:      make([]HPAStatus, 0),
+		Findings:  make([]AutoscalingIssue, 0),
+		Issues:    make(map[string]int),
+	}
+	up, down := hpaRescales(events, now.Add(-hpaFlapWindow))
+	for i := range hpas {
+		hpa := &hpas[i]
+		if !shouldScanNamespace(hpa.Namespace, targetNamespace, includeSystem) {
+			continue
+		}
+		s := hpaStatus(hpa)
+		key := hpa.Namespace + "/" + hpa.Name
+		s.Rescales = up[key] + down[key]
+		report.HPAs = append(report.HPAs, s)
+		report.Findings = append(report.Findings, hpaIssues(hpa, s)...)
+		if up[key] > 0 && down[key] > 0 && s.Rescales >= hpaFlapRescales {
+			report.Findings = append(report.Findings, AutoscalingIssue{
+				Issue: AutoscalingIssueFlapping, Namespace: s.Namespace, Name: s.Name, Target: s.Target,
+				Message: fmt.Sprintf("rescaled %d time(s) in the last %s (%d up, %d down); consider a longer scale-down stabilization window or a wider metric target",
+					s.Rescales, hpaFlapWindow, up[key], down[key]),
+			})
+		}
+	}
+
+	sort.Slice(report.HPAs, func(i, j int) bool {
+		a, b := report.HPAs[i], report.HPAs[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	sortAutoscalingIssues(report.Findings)
+	for _, f := range report.Findings {
+		report.Issues[f.Issue]++
+	}
+	return report
+}
+
+// vpaRecommendation reads a VerticalPodAutoscaler's update mode and
+// per-container recommendation.
+func vpaRecommendation(obj *unstructured.Unstructured) VPARecommendation {
+	vpa := VPARecommendation{
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		Target:     nestedString(obj.Object, "spec", "targetRef", "kind") + "/" + nestedString(obj.Object, "spec", "targetRef", "name"),
+		UpdateMode: stringOr(nestedString(obj.Object, "spec", "updatePolicy", "updateMode"), "Auto"),
+		Containers: []VPAContainerRecommendation{},
+	}
+	recs, _, _ := unstructured.NestedSlice(obj.Object, "status", "recommendation", "containerRecommendations")
+	for _, r := range recs {
+		rec, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+		vpa.Containers = append(vpa.Containers, VPAContainerRecommendation{
+			Container:  nestedString(rec, "containerName"),
+			Target:     nestedStringMap(rec, "target"),
+			LowerBound: nestedStringMap(rec, "lowerBound"),
+			UpperBound: nestedStringMap(rec, "upperBound"),
+		})
+	}
+	if len(vpa.Containers) == 0 {
+		vpa.Message = "no recommendation yet"
+		conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		for _, c := range conditions {
+			if cond, ok := c.(map[string]any); ok && nestedString(cond, "type") == "RecommendationProvided" && nestedString(cond, "status") == "False" {
+				vpa.Message = strings.TrimPrefix(nestedString(cond, "reason")+": "+nestedString(cond, "message"), ": ")
+			}
+		}
+	}
+	return vpa
+}
+
+// nestedStringMap returns the map of strings at fields, or nil.
+func nestedStringMap(obj map[string]any, fields ...string) map[string]string {
+	m, _, _ := unstructured.NestedStringMap(obj, fields...)
+	return m
+}
+
+// CheckAutoscaling reports HorizontalPodAutoscalers that cannot scale, cannot
+// read their metrics, are at their maximum or flap, and lists Vertical Pod
+// Autoscaler recommendations when the VPA CRD is installed. If
+// targetNamespace is non-empty, only that namespace is checked. If
+// includeSystem is false, system namespaces are excluded.
+func (p *Provider) CheckAutoscaling(ctx context.Context, contextName, targetNamespace string, includeSystem bool) (*AutoscalingReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+	client, err := p.createDynamicClient(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	// Without discovery the VPA version is tried directly.
+	resources, err := p.APIResources(ctx, contextName)
+	if err != nil {
+		debug.Logf(debug.K8s, "autoscaling %s: %v", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	report, err := checkAutoscaling(queryCtx, clientset, client, resources, targetNamespace, includeSystem, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	report.Context = contextName
+	return report, nil
+}
+
+func checkAutoscaling(ctx context.Context, clientset kubernetes.Interface, client dynamic.Interface, resources APIResources, targetNamespace string, includeSystem bool, now time.Time) (*AutoscalingReport, error) {
+	hpas, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list horizontalpodautoscalers: %w", err)
+	}
+	var warnings []string
+	events, err := clientset.CoreV1().Events(targetNamespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "involvedObject.kind=HorizontalPodAutoscaler,reason=SuccessfulRescale",
+	})
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("could not list events, flapping is not detected: %v", err))
+		events = &corev1.EventList{}
+	}
+	report := buildAutoscalingReport(hpas.Items, events.Items, targetNamespace, includeSystem, now)
+	report.Warnings = warnings
+
+	items, served, err := listGitOpsKind(ctx, client, resources, vpaKind, targetNamespace)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", vpaKind.resource, err)
+		}
+		report.Warnings = append(report.Warnings, fmt.Sprintf("could not list %s.%s: %v", vpaKind.resource, vpaKind.group, err))
+	}
+	report.VPAInstalled = served
+	for i := range items {
+		if shouldScanNamespace(items[i].GetNamespace(), targetNamespace, includeSystem) {
+			report.VPAs = append(report.VPAs, vpaRecommendation(&items[i]))
+		}
+	}
+	sort.Slice(report.VPAs, func(i, j int) bool {
+		a, b := report.VPAs[i], report.VPAs[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return report, nil
+}
+
+// collectAutoscalingIssues lists HPAs that cannot scale, cannot read their
+// metrics or are at their maximum, for cluster status. Flapping needs events
+// and is only reported by CheckAutoscaling.
+func collectAutoscalingIssues(ctx context.Context, clientset kubernetes.Interface) ([]AutoscalingIssue, error) {
+	hpas, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	issues := make([]AutoscalingIssue, 0)
+	for i := range hpas.Items {
+		issues = append(issues, hpaIssues(&hpas.Items[i], hpaStatus(&hpas.Items[i]))...)
+	}
+	sortAutoscalingIssues(issues)
+	return issues, nil
+}
@@ -0,0 +1,150 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+var autoscalingNow = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+// testHPA returns a CPU-scaled HPA for deployment name with the given
+// replica counts and conditions.
+func testHPA(namespace, name string, current, desired, maxReplicas int32, conditions ...autoscalingv2.HorizontalPodAutoscalerCondition) *autoscalingv2.HorizontalPodAutoscaler {
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: name},
+			MinReplicas:    ptr.To(int32(2)),
+			MaxReplicas:    maxReplicas,
+			Metrics: []autoscalingv2.MetricSpec{{
+				Type:     autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricSource{Name: corev1.ResourceCPU, Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: ptr.To(int32(70))}},
+			}},
+		},
+		Status: autoscalingv2.HorizontalPodAutoscalerStatus{
+			CurrentReplicas: current,
+			DesiredReplicas: desired,
+			CurrentMetrics: []autoscalingv2.MetricStatus{{
+				Type:     autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricStatus{Name: corev1.ResourceCPU, Current: autoscalingv2.MetricValueStatus{AverageUtilization: ptr.To(int32(92))}},
+			}},
+			Conditions: conditions,
+		},
+	}
+}
+
+func hpaCond(t autoscalingv2.HorizontalPodAutoscalerConditionType, status corev1.ConditionStatus, reason string) autoscalingv2.HorizontalPodAutoscalerCondition {
+	return autoscalingv2.HorizontalPodAutoscalerCondition{Type: t, Status: status, Reason: reason, Message: reason + " message"}
+}
+
+func rescaleEvent(namespace, hpa, direction string, count int32, ago time.Duration) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: hpa + "." + direction, Namespace: namespace},
+		InvolvedObject: corev1.ObjectReference{Kind: "HorizontalPodAutoscaler", Namespace: namespace, Name: hpa},
+		Reason:         "SuccessfulRescale",
+		Message:        "New size: 3; reason: cpu resource utilization (percentage of request) " + direction + " target",
+		Count:          count,
+		LastTimestamp:  metav1.NewTime(autoscalingNow.Add(-ago)),
+	}
+}
+
+func newVPAClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Group: vpaKind.group, Version: "v1", Resource: vpaKind.resource}: "VerticalPodAutoscalerList",
+	}, objects...)
+}
+
+func TestCheckAutoscaling(t *testing.T) {
+	clientset := fake.NewClientset(
+		testHPA("shop", "web", 10, 10, 10, hpaCond(autoscalingv2.ScalingLimited, corev1.ConditionTrue, "TooManyReplicas")),
+		testHPA("shop", "api", 3, 3, 10, hpaCond(autoscalingv2.ScalingActive, corev1.ConditionFalse, "FailedGetResourceMetric")),
+		testHPA("shop", "worker", 4, 4, 10, hpaCond(autoscalingv2.AbleToScale, corev1.ConditionFalse, "FailedGetScale")),
+		testHPA("shop", "idle", 0, 0, 10, hpaCond(autoscalingv2.ScalingActive, corev1.ConditionFalse, "ScalingDisabled")),
+		testHPA("shop", "cart", 5, 5, 10),
+		testHPA("kube-system", "dns", 5, 5, 5),
+		rescaleEvent("shop", "cart", "above", 3, 10*time.Minute),
+		rescaleEvent("shop", "cart", "below", 2, 20*time.Minute),
+		rescaleEvent("shop", "api", "above", 5, 3*time.Hour),
+	)
+	vpa := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "autoscaling.k8s.io/v1", "kind": "VerticalPodAutoscaler",
+		"metadata": map[string]any{"name": "web", "namespace": "shop"},
+		"spec":     map[string]any{"targetRef": map[string]any{"kind": "Deployment", "name": "web"}, "updatePolicy": map[string]any{"updateMode": "Off"}},
+		"status": map[string]any{"recommendation": map[string]any{"containerRecommendations": []any{map[string]any{
+			"containerName": "app", "target": map[string]any{"cpu": "250m", "memory": "256Mi"},
+		}}}},
+	}}
+	pending := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "autoscaling.k8s.io/v1", "kind": "VerticalPodAutoscaler",
+		"metadata": map[string]any{"name": "api", "namespace": "shop"},
+		"spec":     map[string]any{"targetRef": map[string]any{"kind": "Deployment", "name": "api"}},
+	}}
+
+	report, err := checkAutoscaling(context.Background(), clientset, newVPAClient(vpa, pending), nil, "", false, autoscalingNow)
+	if err != nil {
+		t.Fatalf("checkAutoscaling: %v", err)
+	}
+	if len(report.HPAs) != 5 {
+		t.Fatalf("hpas = %d, want 5 (kube-system excluded)", len(report.HPAs))
+	}
+	var got []string
+	for _, f := range report.Findings {
+		got = append(got, f.Issue+" "+f.Name)
+	}
+	want := "unable_to_scale worker,metrics_unknown api,flapping cart,at_max web"
+	if strings.Join(got, ",") != want {
+		t.Errorf("findings = %v, want %s", got, want)
+	}
+	for _, f := range report.Findings {
+		if f.Name == "web" && (!strings.Contains(f.Message, "capped") || !strings.Contains(f.Message, "cpu 92%/70%")) {
+			t.Errorf("at_max message = %q", f.Message)
+		}
+	}
+	if report.Issues[AutoscalingIssueFlapping] != 1 {
+		t.Errorf("issues = %v", report.Issues)
+	}
+
+	if !report.VPAInstalled || len(report.VPAs) != 2 {
+		t.Fatalf("vpa installed = %v, vpas = %+v", report.VPAInstalled, report.VPAs)
+	}
+	if v := report.VPAs[0]; v.Name != "api" || v.UpdateMode != "Auto" || v.Message != "no recommendation yet" {
+		t.Errorf("vpa api = %+v", v)
+	}
+	if v := report.VPAs[1]; v.UpdateMode != "Off" || len(v.Containers) != 1 || v.Containers[0].Target["cpu"] != "250m" {
+		t.Errorf("vpa web = %+v", v)
+	}
+}
+
+func TestCollectAutoscalingIssues(t *testing.T) {
+	clientset := fake.NewClientset(
+		testHPA("shop", "web", 10, 10, 10),
+		testHPA("shop", "cart", 5, 5, 10),
+	)
+	issues, err := collectAutoscalingIssues(context.Background(), clientset)
+	if err != nil {
+		t.Fatalf("collectAutoscalingIssues: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Issue != AutoscalingIssueAtMax || issues[0].Target != "Deployment/web" {
+		t.Errorf("issues = %+v", issues)
+	}
+}
+
+func TestHPAMetricsUnknown(t *testing.T) {
+	hpa := testHPA("shop", "web", 2, 2, 10)
+	hpa.Status.CurrentMetrics = nil
+	if m := hpaMetrics(hpa); len(m) != 1 || m[0] != "cpu <unknown>/70%" {
+		t.Errorf("metrics = %v", m)
+	}
+}
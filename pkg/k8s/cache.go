@@ -4,47 +4,80 @@ package k8s
 
 import (
 	"time"
+
+	"github.com/e9169/kopilot/pkg/debug"
 )
 
 // getCachedStatus retrieves a cached cluster status if it exists and is not expired
 func (p *Provider) getCachedStatus(contextName string) *ClusterStatus {
-	p.cacheMutex.RLock()
-	defer p.cacheMutex.RUnlock()
-
-	cached, exists := p.cache[contextName]
-	if !exists {
+	c, err := p.ForContext(contextName)
+	if err != nil {
 		return nil
 	}
+	return c.cachedStatus()
+}
 
-	if time.Now().After(cached.ExpiresAt) {
-		// Cache expired
-		return nil
+// LastKnownStatus returns the latest status checked for contextName, even
+// when it has expired from the cache, and when it was checked. It returns nil
+// when the context was not checked since the cache was last cleared; it never
+// contacts the cluster.
+func (p *Provider) LastKnownStatus(contextName string) (*ClusterStatus, time.Time) {
+	c, err := p.ForContext(contextName)
+	if err != nil {
+		return nil, time.Time{}
 	}
-
-	return cached.Status
+	return c.lastStatus()
 }
 
-// cacheStatus stores a cluster status in the cache
+// cacheStatus stores a cluster status in the cache of its context
 func (p *Provider) cacheStatus(contextName string, status *ClusterStatus) {
-	p.cacheMutex.Lock()
-	defer p.cacheMutex.Unlock()
-
-	p.cache[contextName] = &CachedClusterStatus{
-		Status:    status,
-		ExpiresAt: time.Now().Add(p.cacheTTL),
+	if c, err := p.ForContext(contextName); err == nil {
+		c.storeStatus(status)
 	}
 }
 
 // ClearCache clears all cached cluster statuses
 func (p *Provider) ClearCache() {
-	p.cacheMutex.Lock()
-	defer p.cacheMutex.Unlock()
-	p.cache = make(map[string]*CachedClusterStatus)
+	cleared := 0
+	for _, c := range p.contextScopes() {
+		if c.clearStatus() {
+			cleared++
+		}
+	}
+	p.cacheGeneration.Add(1)
+	debug.Logf(debug.Cache, "cleared %d cached status(es)", cleared)
+}
+
+// CacheGeneration returns a counter that changes whenever cached statuses
+// are dropped before they expire, the kubeconfig is reloaded or the current
+// context changes. Callers caching results derived from the clusters keep
+// them only while it is unchanged.
+func (p *Provider) CacheGeneration() uint64 {
+	return p.cacheGeneration.Load()
 }
 
-// SetCacheTTL sets the cache time-to-live duration
+// ContextCacheTTL returns the status cache TTL of contextName, which may
+// override the default; an unknown context has the default.
+func (p *Provider) ContextCacheTTL(contextName string) time.Duration {
+	c, err := p.ForContext(contextName)
+	if err != nil {
+		return p.CacheTTL()
+	}
+	return c.cacheTTL()
+}
+
+// SetCacheTTL sets the default cache time-to-live duration; contexts
+// configured with their own TTL keep it
 func (p *Provider) SetCacheTTL(ttl time.Duration) {
 	p.cacheMutex.Lock()
 	defer p.cacheMutex.Unlock()
 	p.cacheTTL = ttl
 }
+
+// CacheTTL returns the default cache time-to-live duration; contexts may
+// override it with ContextSettings
+func (p *Provider) CacheTTL() time.Duration {
+	p.cacheMutex.RLock()
+	defer p.cacheMutex.RUnlock()
+	return p.cacheTTL
+}
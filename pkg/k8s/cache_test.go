@@ -77,6 +77,14 @@ func TestCacheExpiration(t *testing.T) {
 	if cached := provider.getCachedStatus(contextName); cached != nil {
 		t.Error("Expected nil after cache expiration")
 	}
+
+	// The last known status outlives the cache entry
+	if last, checkedAt := provider.LastKnownStatus(contextName); last != status || checkedAt.IsZero() {
+		t.Errorf("LastKnownStatus = %v, %v; want the expired status", last, checkedAt)
+	}
+	if last, _ := provider.LastKnownStatus("no-such-context"); last != nil {
+		t.Errorf("LastKnownStatus of an unknown context = %v, want nil", last)
+	}
 }
 
 // TestClearCache tests clearing the cache
@@ -138,6 +146,42 @@ func TestSetCacheTTL(t *testing.T) {
 	}
 }
 
+func TestCacheGenerationAndContextTTL(t *testing.T) {
+	kubeconfigPath, cleanup := createTempKubeconfig(t, 2)
+	defer cleanup()
+
+	provider, err := NewProvider(kubeconfigPath)
+	if err != nil {
+		t.Fatalf(errNewProvider, err)
+	}
+
+	gen := provider.CacheGeneration()
+	provider.ClearCache()
+	if provider.CacheGeneration() == gen {
+		t.Error("ClearCache did not change the cache generation")
+	}
+	gen = provider.CacheGeneration()
+	if err := provider.SetCurrentContext(testContext2); err != nil {
+		t.Fatal(err)
+	}
+	if provider.CacheGeneration() == gen {
+		t.Error("SetCurrentContext did not change the cache generation")
+	}
+
+	if err := provider.ConfigureContext(testContext1, ContextSettings{CacheTTL: "5m"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := provider.ContextCacheTTL(testContext1); got != 5*time.Minute {
+		t.Errorf("ContextCacheTTL(%s) = %v, want 5m", testContext1, got)
+	}
+	if got := provider.ContextCacheTTL(testContext2); got != time.Minute {
+		t.Errorf("ContextCacheTTL(%s) = %v, want the default 1m", testContext2, got)
+	}
+	if got := provider.ContextCacheTTL("missing"); got != time.Minute {
+		t.Errorf("ContextCacheTTL(missing) = %v, want the default 1m", got)
+	}
+}
+
 // BenchmarkCacheWrite benchmarks writing to the cache
 func BenchmarkCacheWrite(b *testing.B) {
 	kubeconfigPath, cleanup := createTempKubeconfig(&testing.T{}, 1)
@@ -0,0 +1,246 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the CPU and memory capacity and headroom analysis.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NodeCapacity is the CPU and memory allocation of one node.
+type NodeCapacity struct {
+	Node              string       `json:"node"`
+	Ready             bool         `json:"ready"`
+	Schedulable       bool         `json:"schedulable"` // ready, not cordoned and without NoSchedule taints
+	AllocatableCPU    CPUAmount    `json:"allocatable_cpu"`
+	AllocatableMemory MemoryAmount `json:"allocatable_memory"`
+	RequestedCPU      CPUAmount    `json:"requested_cpu"`
+	RequestedMemory   MemoryAmount `json:"requested_memory"`
+	LimitsCPU         CPUAmount    `json:"limits_cpu"`
+	LimitsMemory      MemoryAmount `json:"limits_memory"`
+	FreeCPU           CPUAmount    `json:"free_cpu"`
+	FreeMemory        MemoryAmount `json:"free_memory"`
+	CPUPercent        float64      `json:"cpu_percent"`    // requests vs allocatable
+	MemoryPercent     float64      `json:"memory_percent"` // requests vs allocatable
+	Pods              int          `json:"pods"`
+	MaxPods           int64        `json:"max_pods"`
+	Overcommitted     []string     `json:"overcommitted,omitempty"`
+}
+
+// PodSize is the largest pod a node can still fit.
+type PodSize struct {
+	Node   string       `json:"node"`
+	CPU    CPUAmount    `json:"cpu"`
+	Memory MemoryAmount `json:"memory"`
+}
+
+// CapacityReport is the cluster-wide capacity and headroom analysis.
+type CapacityReport struct {
+	Context           string         `json:"context"`
+	Nodes             []NodeCapacity `json:"nodes"`
+	AllocatableCPU    CPUAmount      `json:"allocatable_cpu"` // schedulable nodes only
+	AllocatableMemory MemoryAmount   `json:"allocatable_memory"`
+	RequestedCPU      CPUAmount      `json:"requested_cpu"`
+	RequestedMemory   MemoryAmount   `json:"requested_memory"`
+	HeadroomCPU       CPUAmount      `json:"headroom_cpu"`
+	HeadroomMemory    MemoryAmount   `json:"headroom_memory"`
+	CPUPercent        float64        `json:"cpu_percent"`
+	MemoryPercent     float64        `json:"memory_percent"`
+	// LargestByCPU and LargestByMemory are the biggest pods that still fit on
+	// a single schedulable node; nil when no node has room for another pod.
+	LargestByCPU    *PodSize `json:"largest_by_cpu,omitempty"`
+	LargestByMemory *PodSize `json:"largest_by_memory,omitempty"`
+	Overcommitted   []string `json:"overcommitted"` // node names
+	PendingPods     int      `json:"pending_pods"`  // pods not yet bound to a node
+}
+
+// podRequestsAndLimits returns the CPU (millicores) and memory (bytes)
+// requests and limits of a pod the way the scheduler counts them: the larger
+// of the sum of app containers and any single init container, plus overhead.
+func podRequestsAndLimits(pod *corev1.Pod) (reqCPU, reqMem, limCPU, limMem int64) {
+	for _, c := range pod.Spec.Containers {
+		reqCPU += c.Resources.Requests.Cpu().MilliValue()
+		reqMem += c.Resources.Requests.Memory().Value()
+		limCPU += c.Resources.Limits.Cpu().MilliValue()
+		limMem += c.Resources.Limits.Memory().Value()
+	}
+	for _, c := range pod.Spec.InitContainers {
+		reqCPU = max(reqCPU, c.Resources.Requests.Cpu().MilliValue())
+		reqMem = max(reqMem, c.Resources.Requests.Memory().Value())
+		limCPU = max(limCPU, c.Resources.Limits.Cpu().MilliValue())
+		limMem = max(limMem, c.Resources.Limits.Memory().Value())
+	}
+	if pod.Spec.Overhead != nil {
+		reqCPU += pod.Spec.Overhead.Cpu().MilliValue()
+		reqMem += pod.Spec.Overhead.Memory().Value()
+	}
+	return reqCPU, reqMem, limCPU, limMem
+}
+
+// isNodeSchedulable reports whether new pods without tolerations can land on node.
+func isNodeSchedulable(node *corev1.Node) bool {
+	if !isNodeReady(node) || node.Spec.Unschedulable {
+		return false
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect == corev1.TaintEffectNoSchedule || taint.Effect == corev1.TaintEffectNoExecute {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeOvercommit lists why a node is overcommitted: requests above
+// allocatable (possible through static pods) or limits above allocatable.
+func nodeOvercommit(n *NodeCapacity) []string {
+	var out []string
+	if n.RequestedCPU.Millicores > n.AllocatableCPU.Millicores {
+		out = append(out, fmt.Sprintf("CPU requests %s exceed allocatable %s", n.RequestedCPU.Human, n.AllocatableCPU.Human))
+	}
+	if n.RequestedMemory.Bytes > n.AllocatableMemory.Bytes {
+		out = append(out, fmt.Sprintf("memory requests %s exceed allocatable %s", n.RequestedMemory.Human, n.AllocatableMemory.Human))
+	}
+	if n.LimitsCPU.Millicores > n.AllocatableCPU.Millicores {
+		out = append(out, fmt.Sprintf("CPU limits at %s of allocatable (throttling under load)", FormatPercent(n.LimitsCPU.Millicores, n.AllocatableCPU.Millicores)))
+	}
+	if n.LimitsMemory.Bytes > n.AllocatableMemory.Bytes {
+		out = append(out, fmt.Sprintf("memory limits at %s of allocatable (OOM kills or evictions under load)", FormatPercent(n.LimitsMemory.Bytes, n.AllocatableMemory.Bytes)))
+	}
+	return out
+}
+
+// buildCapacityReport sums pod requests and limits per node and derives
+// headroom from the schedulable nodes. Pods in terminal phases are ignored.
+func buildCapacityReport(nodes []corev1.Node, pods []corev1.Pod) *CapacityReport {
+	type usage struct {
+		reqCPU, reqMem, limCPU, limMem int64
+		pods                           int
+	}
+	byNode := make(map[string]*usage)
+	report := &CapacityReport{Nodes: []NodeCapacity{}, Overcommitted: []string{}}
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if pod.Spec.NodeName == "" {
+			report.PendingPods++
+			continue
+		}
+		u := byNode[pod.Spec.NodeName]
+		if u == nil {
+			u = &usage{}
+			byNode[pod.Spec.NodeName] = u
+		}
+		reqCPU, reqMem, limCPU, limMem := podRequestsAndLimits(pod)
+		u.reqCPU += reqCPU
+		u.reqMem += reqMem
+		u.limCPU += limCPU
+		u.limMem += limMem
+		u.pods++
+	}
+
+	var allocCPU, allocMem, reqCPU, reqMem, headCPU, headMem int64
+	for i := range nodes {
+		node := &nodes[i]
+		u := byNode[node.Name]
+		if u == nil {
+			u = &usage{}
+		}
+		cpu := node.Status.Allocatable.Cpu().MilliValue()
+		mem := node.Status.Allocatable.Memory().Value()
+		n := NodeCapacity{
+			Node:              node.Name,
+			Ready:             isNodeReady(node),
+			Schedulable:       isNodeSchedulable(node),
+			AllocatableCPU:    CPUAmountFromMillicores(cpu),
+			AllocatableMemory: MemoryAmountFromBytes(mem),
+			RequestedCPU:      CPUAmountFromMillicores(u.reqCPU),
+			RequestedMemory:   MemoryAmountFromBytes(u.reqMem),
+			LimitsCPU:         CPUAmountFromMillicores(u.limCPU),
+			LimitsMemory:      MemoryAmountFromBytes(u.limMem),
+			FreeCPU:           CPUAmountFromMillicores(max(cpu-u.reqCPU, 0)),
+			FreeMemory:        MemoryAmountFromBytes(max(mem-u.reqMem, 0)),
+			CPUPercent:        Percent(u.reqCPU, cpu),
+			MemoryPercent:     Percent(u.reqMem, mem),
+			Pods:              u.pods,
+			MaxPods:           node.Status.Allocatable.Pods().Value(),
+		}
+		n.Overcommitted = nodeOvercommit(&n)
+		if len(n.Overcommitted) > 0 {
+			report.Overcommitted = append(report.Overcommitted, n.Node)
+		}
+		report.Nodes = append(report.Nodes, n)
+
+		if !n.Schedulable {
+			continue
+		}
+		allocCPU += cpu
+		allocMem += mem
+		reqCPU += u.reqCPU
+		reqMem += u.reqMem
+		headCPU += n.FreeCPU.Millicores
+		headMem += n.FreeMemory.Bytes
+
+		if n.MaxPods > 0 && int64(n.Pods) >= n.MaxPods {
+			continue
+		}
+		size := &PodSize{Node: n.Node, CPU: n.FreeCPU, Memory: n.FreeMemory}
+		if report.LargestByCPU == nil || size.CPU.Millicores > report.LargestByCPU.CPU.Millicores {
+			report.LargestByCPU = size
+		}
+		if report.LargestByMemory == nil || size.Memory.Bytes > report.LargestByMemory.Memory.Bytes {
+			report.LargestByMemory = size
+		}
+	}
+
+	report.AllocatableCPU = CPUAmountFromMillicores(allocCPU)
+	report.AllocatableMemory = MemoryAmountFromBytes(allocMem)
+	report.RequestedCPU = CPUAmountFromMillicores(reqCPU)
+	report.RequestedMemory = MemoryAmountFromBytes(reqMem)
+	report.HeadroomCPU = CPUAmountFromMillicores(headCPU)
+	report.HeadroomMemory = MemoryAmountFromBytes(headMem)
+	report.CPUPercent = Percent(reqCPU, allocCPU)
+	report.MemoryPercent = Percent(reqMem, allocMem)
+	sort.Strings(report.Overcommitted)
+	sort.Slice(report.Nodes, func(i, j int) bool { return report.Nodes[i].Node < report.Nodes[j].Node })
+	return report
+}
+
+// collectCapacityReport lists nodes and pods and builds the capacity report.
+func collectCapacityReport(ctx context.Context, clientset kubernetes.Interface) (*CapacityReport, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	return buildCapacityReport(nodes.Items, pods.Items), nil
+}
+
+// GetClusterCapacity aggregates node allocatable CPU and memory against pod
+// requests, reporting headroom, the largest pod that still fits on a single
+// node, and nodes whose requests or limits exceed their allocatable.
+func (p *Provider) GetClusterCapacity(ctx context.Context, contextName string) (*CapacityReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	report, err := collectCapacityReport(queryCtx, clientset)
+	if err != nil {
+		return nil, err
+	}
+	report.Context = contextName
+	return report, nil
+}
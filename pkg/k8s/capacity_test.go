@@ -0,0 +1,114 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func capacityNode(name, cpu, memory string, maxPods int64) *corev1.Node {
+	node := osNode(name, "linux", true)
+	node.Status.Allocatable = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(memory),
+		corev1.ResourcePods:   *resource.NewQuantity(maxPods, resource.DecimalSI),
+	}
+	return &node
+}
+
+func capacityPod(name, node, reqCPU, reqMem, limCPU, limMem string) *corev1.Pod {
+	res := corev1.ResourceRequirements{Requests: corev1.ResourceList{}, Limits: corev1.ResourceList{}}
+	if reqCPU != "" {
+		res.Requests[corev1.ResourceCPU] = resource.MustParse(reqCPU)
+	}
+	if reqMem != "" {
+		res.Requests[corev1.ResourceMemory] = resource.MustParse(reqMem)
+	}
+	if limCPU != "" {
+		res.Limits[corev1.ResourceCPU] = resource.MustParse(limCPU)
+	}
+	if limMem != "" {
+		res.Limits[corev1.ResourceMemory] = resource.MustParse(limMem)
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps"},
+		Spec:       corev1.PodSpec{NodeName: node, Containers: []corev1.Container{{Name: "app", Resources: res}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func TestPodRequestsAndLimits(t *testing.T) {
+	pod := capacityPod("web", "n1", "250m", "256Mi", "500m", "512Mi")
+	pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: "sidecar", Resources: corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("50m")},
+	}})
+	pod.Spec.InitContainers = []corev1.Container{{Name: "migrate", Resources: corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+	}}}
+	pod.Spec.Overhead = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}
+
+	reqCPU, reqMem, limCPU, limMem := podRequestsAndLimits(pod)
+	if reqCPU != 400 || reqMem != 1<<30 || limCPU != 500 || limMem != 512<<20 {
+		t.Errorf("got req %dm/%d lim %dm/%d, want req 400m/1Gi lim 500m/512Mi", reqCPU, reqMem, limCPU, limMem)
+	}
+}
+
+func TestCollectCapacityReport(t *testing.T) {
+	cordoned := capacityNode("n3", "8", "32Gi", 110)
+	cordoned.Spec.Unschedulable = true
+	tainted := capacityNode("cp", "2", "4Gi", 110)
+	tainted.Spec.Taints = []corev1.Taint{{Key: "node-role.kubernetes.io/control-plane", Effect: corev1.TaintEffectNoSchedule}}
+	full := capacityNode("n4", "4", "8Gi", 1)
+
+	done := capacityPod("job", "n1", "4", "", "", "")
+	done.Status.Phase = corev1.PodSucceeded
+	clientset := fake.NewClientset(
+		capacityNode("n1", "4", "16Gi", 110), capacityNode("n2", "2", "4Gi", 110), cordoned, tainted, full,
+		capacityPod("a", "n1", "1", "4Gi", "2", "8Gi"),
+		capacityPod("b", "n1", "500m", "2Gi", "4", "20Gi"),
+		capacityPod("c", "n2", "500m", "3Gi", "", ""),
+		capacityPod("d", "n4", "100m", "128Mi", "", ""),
+		capacityPod("pending", "", "8", "", "", ""),
+		done,
+	)
+
+	report, err := collectCapacityReport(context.Background(), clientset)
+	if err != nil {
+		t.Fatalf("collectCapacityReport: %v", err)
+	}
+	if len(report.Nodes) != 5 || report.PendingPods != 1 {
+		t.Fatalf("Nodes = %d, PendingPods = %d, want 5 and 1", len(report.Nodes), report.PendingPods)
+	}
+	// Schedulable nodes: n1, n2 and n4 (n3 is cordoned, cp tainted).
+	if report.AllocatableCPU.Millicores != 10000 || report.RequestedCPU.Millicores != 2100 || report.HeadroomCPU.Millicores != 7900 {
+		t.Errorf("CPU alloc/req/headroom = %d/%d/%d, want 10000/2100/7900",
+			report.AllocatableCPU.Millicores, report.RequestedCPU.Millicores, report.HeadroomCPU.Millicores)
+	}
+	if report.HeadroomMemory.Human != "18.9Gi" {
+		t.Errorf("HeadroomMemory = %s, want 18.9Gi", report.HeadroomMemory.Human)
+	}
+	// n4 is at its pod limit, so the largest pod fits on n1.
+	if report.LargestByCPU == nil || report.LargestByCPU.Node != "n1" || report.LargestByCPU.CPU.Human != "2.5" {
+		t.Errorf("LargestByCPU = %+v, want 2.5 cores on n1", report.LargestByCPU)
+	}
+	if report.LargestByMemory == nil || report.LargestByMemory.Node != "n1" || report.LargestByMemory.Memory.Human != "10Gi" {
+		t.Errorf("LargestByMemory = %+v, want 10Gi on n1", report.LargestByMemory)
+	}
+	if len(report.Overcommitted) != 1 || report.Overcommitted[0] != "n1" {
+		t.Fatalf("Overcommitted = %v, want [n1]", report.Overcommitted)
+	}
+	n1 := report.Nodes[1]
+	if n1.Node != "n1" || len(n1.Overcommitted) != 2 || !strings.Contains(n1.Overcommitted[0], "CPU limits at 150%") {
+		t.Errorf("n1 = %+v, want CPU and memory limit overcommit", n1)
+	}
+	for _, n := range report.Nodes {
+		if wantSchedulable := n.Node != "n3" && n.Node != "cp"; n.Schedulable != wantSchedulable {
+			t.Errorf("%s Schedulable = %v, want %v", n.Node, n.Schedulable, wantSchedulable)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the recent change listing for Deployments, ConfigMaps and Secrets.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	changeCauseAnnotation = "kubernetes.io/change-cause"
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+	revisionAnnotation    = "deployment.kubernetes.io/revision"
+)
+
+// ChangeKinds lists the kinds RecentChanges inspects.
+var ChangeKinds = []string{"Deployment", "ConfigMap", "Secret"}
+
+// ResourceChange is the latest modification of one object.
+type ResourceChange struct {
+	Kind      string    `json:"kind"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	ChangedAt time.Time `json:"changed_at"`
+	Manager   string    `json:"manager,omitempty"`   // field manager of the latest write (kubectl, helm, argocd, ...)
+	Operation string    `json:"operation,omitempty"` // Apply, Update or Created
+	Source    string    `json:"source"`              // managedFields, annotation or creationTimestamp
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// RecentChangesReport lists objects changed since a point in time, newest first.
+type RecentChangesReport struct {
+	Context string           `json:"context"`
+	Since   time.Time        `json:"since"`
+	Changes []ResourceChange `json:"changes"`
+}
+
+// lastChange returns when and by whom an object's spec or data was last
+// written. Status subresource writes are ignored since controllers update
+// them continuously; objects without managedFields fall back to their
+// creation time.
+func lastChange(meta *metav1.ObjectMeta) ResourceChange {
+	change := ResourceChange{
+		Namespace: meta.Namespace,
+		Name:      meta.Name,
+		ChangedAt: meta.CreationTimestamp.Time,
+		Operation: "Created",
+		Source:    "creationTimestamp",
+	}
+	for _, mf := range meta.ManagedFields {
+		if mf.Subresource != "" || mf.Time == nil || !mf.Time.After(change.ChangedAt) {
+			continue
+		}
+		change.ChangedAt = mf.Time.Time
+		change.Manager = mf.Manager
+		change.Operation = string(mf.Operation)
+		change.Source = "managedFields"
+	}
+	return change
+}
+
+// deploymentChange adds rollout details and the kubectl restart annotation,
+// which can be newer than the managedFields entry that carried it.
+func deploymentChange(d *appsv1.Deployment) ResourceChange {
+	change := lastChange(&d.ObjectMeta)
+	change.Kind = "Deployment"
+	if restarted, err := time.Parse(time.RFC3339, d.Spec.Template.Annotations[restartedAtAnnotation]); err == nil && restarted.After(change.ChangedAt) {
+		change.ChangedAt = restarted
+		change.Source = "annotation"
+		change.Operation = "Restart"
+	}
+
+	var details []string
+	if rev := d.Annotations[revisionAnnotation]; rev != "" {
+		details = append(details, "revision "+rev)
+	}
+	images := make([]string, 0, len(d.Spec.Template.Spec.Containers))
+	for _, c := range d.Spec.Template.Spec.Containers {
+		images = append(images, c.Image)
+	}
+	if len(images) > 0 {
+		details = append(details, "images "+strings.Join(images, ", "))
+	}
+	if cause := d.Annotations[changeCauseAnnotation]; cause != "" {
+		details = append(details, fmt.Sprintf("cause %q", cause))
+	}
+	change.Detail = strings.Join(details, "; ")
+	return change
+}
+
+// configMapChange describes a ConfigMap change by its keys.
+func configMapChange(cm *corev1.ConfigMap) ResourceChange {
+	change := lastChange(&cm.ObjectMeta)
+	change.Kind = "ConfigMap"
+	keys := make(map[string]bool, len(cm.Data)+len(cm.BinaryData))
+	for k := range cm.Data {
+		keys[k] = true
+	}
+	for k := range cm.BinaryData {
+		keys[k] = true
+	}
+	change.Detail = fmt.Sprintf("%d key(s)", len(keys))
+	return change
+}
+
+// secretChange describes a Secret change by its type and key count; values are never read.
+func secretChange(s *corev1.Secret) ResourceChange {
+	change := lastChange(&s.ObjectMeta)
+	change.Kind = "Secret"
+	change.Detail = fmt.Sprintf("type %s, %d key(s)", s.Type, len(s.Data))
+	return change
+}
+
+// wantKind reports whether kind is selected; an empty selection selects every kind.
+func wantKind(kinds []string, kind string) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, k := range kinds {
+		if strings.EqualFold(k, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectRecentChanges lists the selected kinds and keeps objects changed at or after since.
+func collectRecentChanges(ctx context.Context, clientset kubernetes.Interface, targetNamespace string, includeSystem bool, since time.Time, kinds []string) ([]ResourceChange, error) {
+	var changes []ResourceChange
+	if wantKind(kinds, "Deployment") {
+		list, err := clientset.AppsV1().Deployments(targetNamespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deployments: %w", err)
+		}
+		for i := range list.Items {
+			changes = append(changes, deploymentChange(&list.Items[i]))
+		}
+	}
+	if wantKind(kinds, "ConfigMap") {
+		list, err := clientset.CoreV1().ConfigMaps(targetNamespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list configmaps: %w", err)
+		}
+		for i := range list.Items {
+			changes = append(changes, configMapChange(&list.Items[i]))
+		}
+	}
+	if wantKind(kinds, "Secret") {
+		list, err := clientset.CoreV1().Secrets(targetNamespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list secrets: %w", err)
+		}
+		for i := range list.Items {
+			// Token secrets are minted by the control plane, not deployed by anyone.
+			if list.Items[i].Type == corev1.SecretTypeServiceAccountToken {
+				continue
+			}
+			changes = append(changes, secretChange(&list.Items[i]))
+		}
+	}
+
+	recent := make([]ResourceChange, 0, len(changes))
+	for _, c := range changes {
+		if c.ChangedAt.Before(since) || !shouldScanNamespace(c.Namespace, targetNamespace, includeSystem) {
+			continue
+		}
+		recent = append(recent, c)
+	}
+	sort.SliceStable(recent, func(i, j int) bool {
+		if !recent[i].ChangedAt.Equal(recent[j].ChangedAt) {
+			return recent[i].ChangedAt.After(recent[j].ChangedAt)
+		}
+		return recent[i].Kind+"/"+recent[i].Namespace+"/"+recent[i].Name < recent[j].Kind+"/"+recent[j].Namespace+"/"+recent[j].Name
+	})
+	return recent, nil
+}
+
+// RecentChanges lists Deployments, ConfigMaps and Secrets modified at or
+// after since, newest first, using managedFields timestamps and rollout
+// annotations. kinds restricts the listing to some of ChangeKinds. If
+// targetNamespace is non-empty, only that namespace is listed. If
+// includeSystem is false, system namespaces are excluded.
+func (p *Provider) RecentChanges(ctx context.Context, contextName, targetNamespace string, includeSystem bool, since time.Time, kinds []string) (*RecentChangesReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	changes, err := collectRecentChanges(queryCtx, clientset, targetNamespace, includeSystem, since, kinds)
+	if err != nil {
+		return nil, err
+	}
+	return &RecentChangesReport{Context: contextName, Since: since, Changes: changes}, nil
+}
@@ -0,0 +1,92 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func managedMeta(namespace, name string, created time.Time, fields ...metav1.ManagedFieldsEntry) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: name, Namespace: namespace, CreationTimestamp: metav1.NewTime(created), ManagedFields: fields}
+}
+
+func managedBy(manager string, op metav1.ManagedFieldsOperationType, at time.Time, subresource string) metav1.ManagedFieldsEntry {
+	t := metav1.NewTime(at)
+	return metav1.ManagedFieldsEntry{Manager: manager, Operation: op, Time: &t, Subresource: subresource}
+}
+
+func TestLastChange(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	meta := managedMeta("shop", "web", created,
+		managedBy("helm", metav1.ManagedFieldsOperationUpdate, created.Add(time.Hour), ""),
+		managedBy("kube-controller-manager", metav1.ManagedFieldsOperationUpdate, created.Add(3*time.Hour), "status"),
+		managedBy("kubectl-edit", metav1.ManagedFieldsOperationUpdate, created.Add(2*time.Hour), ""),
+	)
+	c := lastChange(&meta)
+	if c.Manager != "kubectl-edit" || !c.ChangedAt.Equal(created.Add(2*time.Hour)) || c.Source != "managedFields" {
+		t.Errorf("lastChange = %+v, want kubectl-edit at +2h ignoring the status write", c)
+	}
+
+	bare := managedMeta("shop", "old", created)
+	if c := lastChange(&bare); c.Operation != "Created" || !c.ChangedAt.Equal(created) {
+		t.Errorf("lastChange without managedFields = %+v, want creation time", c)
+	}
+}
+
+func TestCollectRecentChanges(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-72 * time.Hour)
+	restarted := appDeployment("shop", "web", 2, corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "web:1.2"}}})
+	restarted.ObjectMeta = managedMeta("shop", "web", old, managedBy("helm", metav1.ManagedFieldsOperationUpdate, old, ""))
+	restarted.Annotations = map[string]string{revisionAnnotation: "7"}
+	restarted.Spec.Template.Annotations = map[string]string{restartedAtAnnotation: now.Add(-time.Hour).Format(time.RFC3339)}
+	stale := appDeployment("shop", "stale", 1, corev1.PodSpec{})
+	stale.ObjectMeta = managedMeta("shop", "stale", old)
+
+	clientset := fake.NewClientset(
+		restarted, stale,
+		&corev1.ConfigMap{ObjectMeta: managedMeta("shop", "settings", old,
+			managedBy("kubectl-client-side-apply", metav1.ManagedFieldsOperationUpdate, now.Add(-10*time.Minute), "")),
+			Data: map[string]string{"a": "1", "b": "2"}},
+		&corev1.Secret{ObjectMeta: managedMeta("shop", "creds", now.Add(-2*time.Hour)), Type: corev1.SecretTypeOpaque, Data: map[string][]byte{"password": []byte("x")}},
+		&corev1.Secret{ObjectMeta: managedMeta("shop", "token", now), Type: corev1.SecretTypeServiceAccountToken},
+		&corev1.ConfigMap{ObjectMeta: managedMeta("kube-system", "coredns", now)},
+	)
+
+	changes, err := collectRecentChanges(context.Background(), clientset, "", false, now.Add(-24*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("collectRecentChanges: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("changes = %+v, want settings, web and creds", changes)
+	}
+	if changes[0].Name != "settings" || changes[0].Manager != "kubectl-client-side-apply" || changes[0].Detail != "2 key(s)" {
+		t.Errorf("changes[0] = %+v, want the ConfigMap edit", changes[0])
+	}
+	if changes[1].Name != "web" || changes[1].Operation != "Restart" || changes[1].Detail != "revision 7; images web:1.2" {
+		t.Errorf("changes[1] = %+v, want the restarted Deployment", changes[1])
+	}
+	if changes[2].Name != "creds" || changes[2].Detail != "type Opaque, 1 key(s)" {
+		t.Errorf("changes[2] = %+v, want the new Secret", changes[2])
+	}
+
+	secrets, err := collectRecentChanges(context.Background(), clientset, "shop", false, now.Add(-24*time.Hour), []string{"secret"})
+	if err != nil {
+		t.Fatalf("collectRecentChanges: %v", err)
+	}
+	if len(secrets) != 1 || secrets[0].Kind != "Secret" {
+		t.Errorf("secrets = %+v, want only creds", secrets)
+	}
+}
+
+func TestDeploymentChangeCause(t *testing.T) {
+	d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Annotations: map[string]string{changeCauseAnnotation: "hotfix"}}}
+	if c := deploymentChange(d); c.Detail != `cause "hotfix"` {
+		t.Errorf("Detail = %q", c.Detail)
+	}
+}
@@ -0,0 +1,249 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the small, guarded chaos actions behind the chaos tool.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Chaos recovery polling; variables so tests can shorten them.
+var (
+	chaosRecoveryTimeout = 3 * time.Minute
+	chaosPollInterval    = 2 * time.Second
+)
+
+// DeploymentHealth is a replica snapshot of a Deployment.
+type DeploymentHealth struct {
+	Desired   int32 `json:"desired"`
+	Ready     int32 `json:"ready"`
+	Available int32 `json:"available"`
+	Updated   int32 `json:"updated"`
+}
+
+// NodeHealth is a scheduling snapshot of a node.
+type NodeHealth struct {
+	Ready         bool `json:"ready"`
+	Unschedulable bool `json:"unschedulable"`
+	Pods          int  `json:"pods"`
+}
+
+// ChaosPodResult records a pod-deletion drill.
+type ChaosPodResult struct {
+	Context         string           `json:"context"`
+	Namespace       string           `json:"namespace"`
+	Deployment      string           `json:"deployment"`
+	Pod             string           `json:"pod"`
+	Node            string           `json:"node"`
+	Before          DeploymentHealth `json:"before"`
+	After           DeploymentHealth `json:"after"`
+	Recovered       bool             `json:"recovered"`
+	RecoverySeconds float64          `json:"recovery_seconds"`
+}
+
+func getDeploymentHealth(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (DeploymentHealth, error) {
+	d, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return DeploymentHealth{}, fmt.Errorf("failed to get deployment %s/%s: %w", namespace, name, err)
+	}
+	return DeploymentHealth{
+		Desired:   replicasOrDefault(d.Spec.Replicas),
+		Ready:     d.Status.ReadyReplicas,
+		Available: d.Status.AvailableReplicas,
+		Updated:   d.Status.UpdatedReplicas,
+	}, nil
+}
+
+// pickChaosPod returns a random Ready pod among pods, or nil.
+func pickChaosPod(pods []corev1.Pod) *corev1.Pod {
+	var ready []*corev1.Pod
+	for i := range pods {
+		if isPodReady(&pods[i]) && pods[i].DeletionTimestamp == nil {
+			ready = append(ready, &pods[i])
+		}
+	}
+	if len(ready) == 0 {
+		return nil
+	}
+	// #nosec G404 -- picking a victim pod needs no cryptographic randomness
+	return ready[rand.IntN(len(ready))]
+}
+
+// deleteRandomPod deletes one Ready pod of a healthy Deployment with at least
+// two replicas and waits for the Deployment to return to its prior Ready count.
+func deleteRandomPod(ctx context.Context, clientset kubernetes.Interface, namespace, deployment string) (*ChaosPodResult, error) {
+	before, err := getDeploymentHealth(ctx, clientset, namespace, deployment)
+	if err != nil {
+		return nil, err
+	}
+	if before.Ready < 2 {
+		return nil, fmt.Errorf("refusing: deployment %s/%s has %d ready replica(s); deleting a pod would cause an outage", namespace, deployment, before.Ready)
+	}
+	if before.Ready < before.Desired {
+		return nil, fmt.Errorf("refusing: deployment %s/%s is already degraded (%d/%d ready)", namespace, deployment, before.Ready, before.Desired)
+	}
+
+	d, err := clientset.AppsV1().Deployments(namespace).Get(ctx, deployment, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment %s/%s: %w", namespace, deployment, err)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil || selector.Empty() {
+		return nil, fmt.Errorf("deployment %s/%s has no usable selector", namespace, deployment)
+	}
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	victim := pickChaosPod(pods.Items)
+	if victim == nil {
+		return nil, fmt.Errorf("no Ready pods found for deployment %s/%s", namespace, deployment)
+	}
+
+	result := &ChaosPodResult{Namespace: namespace, Deployment: deployment, Pod: victim.Name, Node: victim.Spec.NodeName, Before: before}
+	start := time.Now()
+	if err := clientset.CoreV1().Pods(namespace).Delete(ctx, victim.Name, metav1.DeleteOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to delete pod %s: %w", victim.Name, err)
+	}
+
+	// Recovered once the victim is gone and the Deployment is back to its prior Ready count.
+	pollErr := wait.PollUntilContextTimeout(ctx, chaosPollInterval, chaosRecoveryTimeout, false, func(ctx context.Context) (bool, error) {
+		_, err := clientset.CoreV1().Pods(namespace).Get(ctx, victim.Name, metav1.GetOptions{})
+		if err == nil || !apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		h, err := getDeploymentHealth(ctx, clientset, namespace, deployment)
+		if err != nil {
+			return false, nil
+		}
+		result.After = h
+		return h.Ready >= before.Ready, nil
+	})
+	result.Recovered = pollErr == nil
+	result.RecoverySeconds = time.Since(start).Seconds()
+	if !result.Recovered {
+		// Report the latest state even if the wait timed out.
+		if h, err := getDeploymentHealth(context.WithoutCancel(ctx), clientset, namespace, deployment); err == nil {
+			result.After = h
+		}
+	}
+	return result, nil
+}
+
+func getNodeHealth(ctx context.Context, clientset kubernetes.Interface, name string) (NodeHealth, error) {
+	node, err := clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return NodeHealth{}, fmt.Errorf("failed to get node %s: %w", name, err)
+	}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + name})
+	if err != nil {
+		return NodeHealth{}, fmt.Errorf("failed to list pods on node %s: %w", name, err)
+	}
+	return NodeHealth{Ready: isNodeReady(node), Unschedulable: node.Spec.Unschedulable, Pods: len(pods.Items)}, nil
+}
+
+// checkCordonSafety refuses to cordon a node that is not Ready, already
+// cordoned, or the last schedulable Ready node in the cluster.
+func checkCordonSafety(ctx context.Context, clientset kubernetes.Interface, name string) error {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	found, others := false, 0
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if node.Name == name {
+			found = true
+			if node.Spec.Unschedulable {
+				return fmt.Errorf("refusing: node %s is already cordoned", name)
+			}
+			if !isNodeReady(node) {
+				return fmt.Errorf("refusing: node %s is not Ready", name)
+			}
+			continue
+		}
+		if !node.Spec.Unschedulable && isNodeReady(node) {
+			others++
+		}
+	}
+	if !found {
+		return fmt.Errorf("node %s not found", name)
+	}
+	if others == 0 {
+		return fmt.Errorf("refusing: %s is the only schedulable Ready node", name)
+	}
+	return nil
+}
+
+func setNodeUnschedulable(ctx context.Context, clientset kubernetes.Interface, name string, unschedulable bool) error {
+	patch := fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable)
+	if _, err := clientset.CoreV1().Nodes().Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to update node %s: %w", name, err)
+	}
+	return nil
+}
+
+// ChaosDeletePod deletes a random Ready pod of a Deployment and waits for it
+// to recover, recording replica health before and after.
+func (p *Provider) ChaosDeletePod(ctx context.Context, contextName, namespace, deployment string) (*ChaosPodResult, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout()+chaosRecoveryTimeout)
+	defer cancel()
+
+	result, err := deleteRandomPod(queryCtx, clientset, namespace, deployment)
+	if err != nil {
+		return nil, err
+	}
+	result.Context = contextName
+	return result, nil
+}
+
+// CordonNode marks a node unschedulable after the safety checks and returns
+// its health before the change.
+func (p *Provider) CordonNode(ctx context.Context, contextName, node string) (NodeHealth, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return NodeHealth{}, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	if err := checkCordonSafety(queryCtx, clientset, node); err != nil {
+		return NodeHealth{}, err
+	}
+	before, err := getNodeHealth(queryCtx, clientset, node)
+	if err != nil {
+		return NodeHealth{}, err
+	}
+	return before, setNodeUnschedulable(queryCtx, clientset, node, true)
+}
+
+// UncordonNode marks a node schedulable again and returns its health afterwards.
+func (p *Provider) UncordonNode(ctx context.Context, contextName, node string) (NodeHealth, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return NodeHealth{}, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	if err := setNodeUnschedulable(queryCtx, clientset, node, false); err != nil {
+		return NodeHealth{}, err
+	}
+	return getNodeHealth(queryCtx, clientset, node)
+}
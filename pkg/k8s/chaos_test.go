@@ -0,0 +1,103 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func shortChaosPolling(t *testing.T) {
+	t.Helper()
+	timeout, interval := chaosRecoveryTimeout, chaosPollInterval
+	t.Cleanup(func() { chaosRecoveryTimeout, chaosPollInterval = timeout, interval })
+	chaosRecoveryTimeout, chaosPollInterval = 200*time.Millisecond, 10*time.Millisecond
+}
+
+func TestDeleteRandomPod(t *testing.T) {
+	shortChaosPolling(t)
+	deploy := appDeployment("apps", "web", 3, corev1.PodSpec{})
+	clientset := fake.NewClientset(deploy,
+		readyPod("apps", "web-1", map[string]string{"app": "web"}),
+		readyPod("apps", "web-2", map[string]string{"app": "web"}),
+		readyPod("apps", "web-3", map[string]string{"app": "web"}),
+		readyPod("apps", "other", map[string]string{"app": "other"}),
+	)
+
+	result, err := deleteRandomPod(context.Background(), clientset, "apps", "web")
+	if err != nil {
+		t.Fatalf("deleteRandomPod error: %v", err)
+	}
+	if !strings.HasPrefix(result.Pod, "web-") {
+		t.Errorf("victim %s is not a web pod", result.Pod)
+	}
+	if !result.Recovered || result.Before.Ready != 3 || result.After.Ready != 3 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	pods, _ := clientset.CoreV1().Pods("apps").List(context.Background(), metav1.ListOptions{})
+	if len(pods.Items) != 3 {
+		t.Errorf("expected exactly one pod deleted, %d remain", len(pods.Items))
+	}
+}
+
+func TestDeleteRandomPodRefusals(t *testing.T) {
+	single := appDeployment("apps", "solo", 1, corev1.PodSpec{})
+	degraded := appDeployment("apps", "api", 3, corev1.PodSpec{})
+	degraded.Status.ReadyReplicas = 2
+	clientset := fake.NewClientset(single, degraded)
+
+	for name, want := range map[string]string{"solo": "would cause an outage", "api": "already degraded", "missing": "failed to get deployment"} {
+		if _, err := deleteRandomPod(context.Background(), clientset, "apps", name); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", name, err, want)
+		}
+	}
+}
+
+func TestPickChaosPod(t *testing.T) {
+	notReady := readyPod("apps", "a", nil)
+	notReady.Status.Conditions[0].Status = corev1.ConditionFalse
+	if got := pickChaosPod([]corev1.Pod{*notReady}); got != nil {
+		t.Errorf("pickChaosPod() = %s, want nil for unready pods", got.Name)
+	}
+	if got := pickChaosPod([]corev1.Pod{*notReady, *readyPod("apps", "b", nil)}); got == nil || got.Name != "b" {
+		t.Errorf("pickChaosPod() should choose the only Ready pod")
+	}
+}
+
+func TestCordonSafetyAndToggle(t *testing.T) {
+	ready := func(name string) *corev1.Node { n := osNode(name, OSLinux, true); return &n }
+	cordoned := ready("node-c")
+	cordoned.Spec.Unschedulable = true
+	notReady := osNode("node-d", OSLinux, false)
+	clientset := fake.NewClientset(ready("node-a"), ready("node-b"), cordoned, &notReady)
+	ctx := context.Background()
+
+	for node, want := range map[string]string{"node-c": "already cordoned", "node-d": "not Ready", "node-x": "not found"} {
+		if err := checkCordonSafety(ctx, clientset, node); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", node, err, want)
+		}
+	}
+	if err := checkCordonSafety(ctx, clientset, "node-a"); err != nil {
+		t.Fatalf("node-a should be safe to cordon: %v", err)
+	}
+
+	if err := setNodeUnschedulable(ctx, clientset, "node-a", true); err != nil {
+		t.Fatalf("cordon: %v", err)
+	}
+	if h, err := getNodeHealth(ctx, clientset, "node-a"); err != nil || !h.Unschedulable || !h.Ready {
+		t.Errorf("after cordon: %+v, %v", h, err)
+	}
+	if err := checkCordonSafety(ctx, clientset, "node-b"); err == nil || !strings.Contains(err.Error(), "only schedulable") {
+		t.Errorf("last schedulable node: err = %v", err)
+	}
+	if err := setNodeUnschedulable(ctx, clientset, "node-a", false); err != nil {
+		t.Fatalf("uncordon: %v", err)
+	}
+	if h, _ := getNodeHealth(ctx, clientset, "node-a"); h.Unschedulable {
+		t.Error("node-a should be schedulable after uncordon")
+	}
+}
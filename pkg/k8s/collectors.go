@@ -9,9 +9,13 @@ import (
 	"strings"
 	"time"
 
+	"github.com/e9169/kopilot/pkg/debug"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -20,39 +24,140 @@ const (
 	DefaultAPITimeout = 30 * time.Second
 	// DiscoveryTimeout is the timeout for discovery API calls (version checks)
 	DiscoveryTimeout = 10 * time.Second
+	// DefaultStatusBudget is Provider.StatusBudget with the default timeouts.
+	DefaultStatusBudget = DefaultReachTimeout + DiscoveryTimeout + DefaultAPITimeout + statusBudgetMargin
+	// statusBudgetMargin is the time a status check has, beyond its API
+	// calls, to build the status.
+	statusBudgetMargin = 5 * time.Second
 )
 
-// getClusterVersion gets the Kubernetes version from the cluster
-func getClusterVersion(ctx context.Context, clientset kubernetes.Interface) (string, error) {
-	// Use a shorter timeout for version discovery
-	discoveryCtx, cancel := context.WithTimeout(ctx, DiscoveryTimeout)
+// DefaultMaxPods is the default cap on the pods a cluster status counts;
+// see WithMaxPods.
+const DefaultMaxPods = 50000
+
+// podListPageSize is how many pods each list request returns.
+const podListPageSize = 500
+
+// clusterStatusBudget overrides Provider.StatusBudget when positive; it is a
+// variable so tests can shorten it.
+var clusterStatusBudget time.Duration
+
+// getClusterVersion gets the Kubernetes version from the cluster, giving up
+// after timeout
+func getClusterVersion(ctx context.Context, clientset kubernetes.Interface, timeout time.Duration) (string, error) {
+	discoveryCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// Note: ServerVersion doesn't accept context in current client-go version
-	// but we still create the context for future compatibility
-	_ = discoveryCtx
-	versionInfo, err := clientset.Discovery().ServerVersion()
-	if err != nil {
-		return "", err
+	// ServerVersion takes no context, so wait for it in the background; the
+	// buffered channel lets it finish after a timeout.
+	type result struct {
+		version string
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		versionInfo, err := clientset.Discovery().ServerVersion()
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		done <- result{version: versionInfo.GitVersion}
+	}()
+	select {
+	case r := <-done:
+		return r.version, r.err
+	case <-discoveryCtx.Done():
+		return "", fmt.Errorf("version check timed out after %s: %w", timeout, discoveryCtx.Err())
+	}
+}
+
+// HealthFilter scopes the node and pod health collection of a cluster
+// status, so large clusters can be checked one application or node pool at a
+// time. The zero value collects every node and pod.
+type HealthFilter struct {
+	// Namespace limits the pods to one namespace.
+	Namespace string
+	// LabelSelector and FieldSelector select the pods, e.g. "app=payments"
+	// and "status.phase!=Running".
+	LabelSelector string
+	FieldSelector string
+	// NodeSelector is a label selector for the nodes, e.g.
+	// "node-role.kubernetes.io/worker".
+	NodeSelector string
+}
+
+// IsZero reports whether f selects every node and pod.
+func (f HealthFilter) IsZero() bool {
+	return f == HealthFilter{}
+}
+
+// Validate parses the selectors, so a mistyped one is reported before any
+// API call.
+func (f HealthFilter) Validate() error {
+	if _, err := labels.Parse(f.LabelSelector); err != nil {
+		return fmt.Errorf("invalid label selector %q: %w", f.LabelSelector, err)
+	}
+	if _, err := fields.ParseSelector(f.FieldSelector); err != nil {
+		return fmt.Errorf("invalid field selector %q: %w", f.FieldSelector, err)
 	}
-	return versionInfo.GitVersion, nil
+	if _, err := labels.Parse(f.NodeSelector); err != nil {
+		return fmt.Errorf("invalid node selector %q: %w", f.NodeSelector, err)
+	}
+	return nil
 }
 
-// collectNodeInfo collects node information from the cluster
-func collectNodeInfo(ctx context.Context, clientset kubernetes.Interface) ([]NodeInfo, int, error) {
-	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+// String describes f, e.g. "namespace payments, pods app=payments".
+func (f HealthFilter) String() string {
+	var parts []string
+	if f.Namespace != "" {
+		parts = append(parts, "namespace "+f.Namespace)
+	}
+	if pods := strings.Trim(f.LabelSelector+","+f.FieldSelector, ","); pods != "" {
+		parts = append(parts, "pods "+pods)
+	}
+	if f.NodeSelector != "" {
+		parts = append(parts, "nodes "+f.NodeSelector)
+	}
+	if len(parts) == 0 {
+		return "all nodes and pods"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// collectNodeInfo collects information on the nodes matching the filter's
+// node selector
+func collectNodeInfo(ctx context.Context, clientset kubernetes.Interface, filter HealthFilter) ([]NodeInfo, int, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: filter.NodeSelector})
 	if err != nil {
 		return nil, 0, err
 	}
+	items := make([]*corev1.Node, len(nodes.Items))
+	for i := range nodes.Items {
+		items[i] = &nodes.Items[i]
+	}
+	nodeInfos, healthyCount := summarizeNodes(items)
+	return nodeInfos, healthyCount, nil
+}
 
-	nodeInfos := make([]NodeInfo, 0, len(nodes.Items))
+// summarizeNodes returns the information of nodes and how many are Ready.
+func summarizeNodes(nodes []*corev1.Node) ([]NodeInfo, int) {
+	nodeInfos := make([]NodeInfo, 0, len(nodes))
 	healthyCount := 0
 
-	for _, node := range nodes.Items {
+	for _, node := range nodes {
 		nodeInfo := NodeInfo{
 			Name:  node.Name,
-			Roles: getNodeRoles(&node),
+			Roles: getNodeRoles(node),
 			Age:   time.Since(node.CreationTimestamp.Time).Round(time.Hour).String(),
+			OS:    nodeOS(node),
+			Arch:  nodeArch(node),
+
+			KubeletVersion:   node.Status.NodeInfo.KubeletVersion,
+			ContainerRuntime: node.Status.NodeInfo.ContainerRuntimeVersion,
+			OSImage:          node.Status.NodeInfo.OSImage,
+			KernelVersion:    node.Status.NodeInfo.KernelVersion,
+			InternalIP:       nodeAddress(node, corev1.NodeInternalIP),
+			ExternalIP:       nodeAddress(node, corev1.NodeExternalIP),
 		}
 
 		// Determine node status
@@ -72,7 +177,38 @@ func collectNodeInfo(ctx context.Context, clientset kubernetes.Interface) ([]Nod
 		nodeInfos = append(nodeInfos, nodeInfo)
 	}
 
-	return nodeInfos, healthyCount, nil
+	return nodeInfos, healthyCount
+}
+
+// nodeAddress returns the node's first address of type t, or "".
+func nodeAddress(node *corev1.Node, t corev1.NodeAddressType) string {
+	for _, a := range node.Status.Addresses {
+		if a.Type == t {
+			return a.Address
+		}
+	}
+	return ""
+}
+
+// kubeletVersionSkew returns the nodes whose kubelet minor version differs
+// from the API server's by more than one. Versions that do not parse are
+// skipped.
+func kubeletVersionSkew(serverVersion string, nodes []NodeInfo) []KubeletSkew {
+	server := parseNodeVersion(serverVersion)
+	if len(server) < 2 {
+		return nil
+	}
+	var skew []KubeletSkew
+	for _, node := range nodes {
+		kubelet := parseNodeVersion(node.KubeletVersion)
+		if len(kubelet) < 2 || kubelet[0] != server[0] {
+			continue
+		}
+		if d := kubelet[1] - server[1]; d > 1 || d < -1 {
+			skew = append(skew, KubeletSkew{Node: node.Name, KubeletVersion: node.KubeletVersion, APIServerVersion: serverVersion, MinorVersions: d})
+		}
+	}
+	return skew
 }
 
 // collectNamespaceList collects the list of namespaces from the cluster
@@ -116,6 +252,7 @@ func extractPodInfo(pod *corev1.Pod) PodInfo {
 		Name:      pod.Name,
 		Namespace: pod.Namespace,
 		Status:    string(pod.Status.Phase),
+		Created:   pod.CreationTimestamp.Time,
 	}
 
 	// Get restart count
@@ -140,26 +277,72 @@ func extractPodInfo(pod *corev1.Pod) PodInfo {
 	return podInfo
 }
 
-// collectPodHealth collects pod health information from the cluster
-func collectPodHealth(ctx context.Context, clientset kubernetes.Interface) (int, int, []PodInfo, error) {
-	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return 0, 0, nil, err
-	}
+// podHealth is the pod health collectPodHealth aggregates.
+type podHealth struct {
+	total     int
+	healthy   int
+	unhealthy []PodInfo
+	// truncated is set when the listing stopped before the last pod.
+	truncated bool
+}
 
-	totalPods := len(pods.Items)
-	healthyPods := 0
-	unhealthyPods := make([]PodInfo, 0)
+// collectPodHealth collects health information on the pods matching the
+// filter; the selectors are applied by the API server. Pods are listed a page
+// at a time and counted as they arrive, so a large cluster's pods are never
+// held at once; listing stops after maxPods pods unless it is 0.
+func collectPodHealth(ctx context.Context, clientset kubernetes.Interface, filter HealthFilter, maxPods int) (podHealth, error) {
+	health := podHealth{unhealthy: make([]PodInfo, 0)}
+	opts := metav1.ListOptions{LabelSelector: filter.LabelSelector, FieldSelector: filter.FieldSelector}
+	truncated, err := eachPod(ctx, clientset, filter.Namespace, opts, func(pod *corev1.Pod) bool {
+		return health.add(pod, maxPods)
+	})
+	health.truncated = truncated
+	return health, err
+}
 
-	for _, pod := range pods.Items {
-		if isPodHealthy(&pod) {
-			healthyPods++
-		} else {
-			unhealthyPods = append(unhealthyPods, extractPodInfo(&pod))
-		}
+// add counts pod, unless maxPods pods were counted already; 0 means no cap.
+// It reports whether pod was counted.
+func (h *podHealth) add(pod *corev1.Pod, maxPods int) bool {
+	if maxPods > 0 && h.total == maxPods {
+		return false
+	}
+	h.total++
+	if isPodHealthy(pod) {
+		h.healthy++
+	} else {
+		h.unhealthy = append(h.unhealthy, extractPodInfo(pod))
 	}
+	return true
+}
 
-	return totalPods, healthyPods, unhealthyPods, nil
+// eachPod calls fn on each pod of namespace ("" for all) matching opts,
+// listing podListPageSize pods per request. It stops when fn returns false and
+// reports whether the listing ended early: stopped by fn, or because the
+// continue token expired, which happens when paging through a large cluster
+// takes longer than the API server keeps its snapshot. Any other failure,
+// of the first page or a later one, is returned as an error, and the pods
+// fn already saw must then be discarded.
+func eachPod(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions, fn func(*corev1.Pod) bool) (bool, error) {
+	opts.Limit = podListPageSize
+	for page := 0; ; page++ {
+		pods, err := clientset.CoreV1().Pods(namespace).List(ctx, opts)
+		if err != nil {
+			if page > 0 && apierrors.IsResourceExpired(err) {
+				debug.Logf(debug.K8s, "pod listing expired after %d page(s): %v", page, err)
+				return true, nil
+			}
+			return false, err
+		}
+		for i := range pods.Items {
+			if !fn(&pods.Items[i]) {
+				return true, nil
+			}
+		}
+		if pods.Continue == "" {
+			return false, nil
+		}
+		opts.Continue = pods.Continue
+	}
 }
 
 // systemNamespaces contains Kubernetes-managed namespaces excluded from sanitization by default
@@ -169,6 +352,11 @@ var systemNamespaces = map[string]bool{
 	"kube-node-lease": true,
 }
 
+// IsSystemNamespace reports whether ns is one of the Kubernetes-managed namespaces.
+func IsSystemNamespace(ns string) bool {
+	return systemNamespaces[ns]
+}
+
 const (
 	sanitizePenaltyCritical = 10
 	sanitizePenaltyMajor    = 5
@@ -2,15 +2,20 @@ package k8s
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"testing"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 const (
@@ -28,7 +33,7 @@ func TestGetClusterVersionWithContext(t *testing.T) {
 	clientset := fake.NewClientset()
 
 	ctx := context.Background()
-	version, err := getClusterVersion(ctx, clientset)
+	version, err := getClusterVersion(ctx, clientset, DiscoveryTimeout)
 	if err != nil {
 		t.Fatalf("getClusterVersion() failed: %v", err)
 	}
@@ -50,7 +55,7 @@ func TestGetClusterVersionWithTimeout(t *testing.T) {
 	// Wait a moment to ensure timeout
 	time.Sleep(10 * time.Millisecond)
 
-	_, err := getClusterVersion(ctx, clientset)
+	_, err := getClusterVersion(ctx, clientset, DiscoveryTimeout)
 	// With fake clientset, this might not timeout, but it shouldn't panic
 	if err != nil {
 		t.Logf("Expected timeout or success, got error: %v", err)
@@ -68,6 +73,15 @@ func TestCollectNodeInfoWithContext(t *testing.T) {
 					Conditions: []corev1.NodeCondition{
 						{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
 					},
+					NodeInfo: corev1.NodeSystemInfo{
+						KubeletVersion: "v1.29.4", ContainerRuntimeVersion: "containerd://1.7.13",
+						OSImage: "Ubuntu 22.04.4 LTS", KernelVersion: "5.15.0-1057-aws",
+					},
+					Addresses: []corev1.NodeAddress{
+						{Type: corev1.NodeHostName, Address: "node-1"},
+						{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+						{Type: corev1.NodeExternalIP, Address: "203.0.113.7"},
+					},
 				},
 			},
 			{
@@ -84,7 +98,7 @@ func TestCollectNodeInfoWithContext(t *testing.T) {
 	clientset := fake.NewClientset(nodes)
 	ctx := context.Background()
 
-	nodeList, readyCount, err := collectNodeInfo(ctx, clientset)
+	nodeList, readyCount, err := collectNodeInfo(ctx, clientset, HealthFilter{})
 	if err != nil {
 		t.Fatalf("collectNodeInfo() failed: %v", err)
 	}
@@ -96,6 +110,31 @@ func TestCollectNodeInfoWithContext(t *testing.T) {
 	if readyCount != 1 {
 		t.Errorf("ReadyNodes = %d, want 1", readyCount)
 	}
+
+	want := NodeInfo{KubeletVersion: "v1.29.4", ContainerRuntime: "containerd://1.7.13", OSImage: "Ubuntu 22.04.4 LTS",
+		KernelVersion: "5.15.0-1057-aws", InternalIP: "10.0.0.1", ExternalIP: "203.0.113.7"}
+	got := nodeList[0]
+	if got.KubeletVersion != want.KubeletVersion || got.ContainerRuntime != want.ContainerRuntime || got.OSImage != want.OSImage ||
+		got.KernelVersion != want.KernelVersion || got.InternalIP != want.InternalIP || got.ExternalIP != want.ExternalIP {
+		t.Errorf("node-1 = %+v, want system info %+v", got, want)
+	}
+}
+
+func TestKubeletVersionSkew(t *testing.T) {
+	nodes := []NodeInfo{
+		{Name: "current", KubeletVersion: "v1.29.4"},
+		{Name: "one-behind", KubeletVersion: "v1.28.9-eks-1"},
+		{Name: "two-behind", KubeletVersion: "v1.27.3"},
+		{Name: "ahead", KubeletVersion: "v1.31.0"},
+		{Name: "unknown", KubeletVersion: ""},
+	}
+	skew := kubeletVersionSkew("v1.29.2", nodes)
+	if len(skew) != 2 || skew[0].Node != "two-behind" || skew[0].MinorVersions != -2 || skew[1].Node != "ahead" || skew[1].MinorVersions != 2 {
+		t.Errorf("skew = %+v", skew)
+	}
+	if skew := kubeletVersionSkew("unknown", nodes); skew != nil {
+		t.Errorf("skew without a server version = %+v", skew)
+	}
 }
 
 // TestCollectNamespaceListWithContext tests namespace collection with context
@@ -139,8 +178,9 @@ func TestCollectPodHealthWithContext(t *testing.T) {
 			},
 			{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      "pending-pod",
-					Namespace: "default",
+					Name:              "pending-pod",
+					Namespace:         "default",
+					CreationTimestamp: metav1.NewTime(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)),
 				},
 				Status: corev1.PodStatus{
 					Phase: corev1.PodPending,
@@ -165,10 +205,11 @@ func TestCollectPodHealthWithContext(t *testing.T) {
 	clientset := fake.NewClientset(pods)
 	ctx := context.Background()
 
-	totalPods, healthyPods, unhealthyPods, err := collectPodHealth(ctx, clientset)
+	health, err := collectPodHealth(ctx, clientset, HealthFilter{}, 0)
 	if err != nil {
 		t.Fatalf("collectPodHealth() failed: %v", err)
 	}
+	totalPods, healthyPods, unhealthyPods := health.total, health.healthy, health.unhealthy
 
 	if totalPods != 3 {
 		t.Errorf("TotalPods = %d, want 3", totalPods)
@@ -179,7 +220,12 @@ func TestCollectPodHealthWithContext(t *testing.T) {
 	}
 
 	if len(unhealthyPods) != 2 {
-		t.Errorf("Got %d unhealthy pods, want 2", len(unhealthyPods))
+		t.Fatalf("Got %d unhealthy pods, want 2", len(unhealthyPods))
+	}
+	for _, pod := range unhealthyPods {
+		if pod.Name == "pending-pod" && !pod.Created.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+			t.Errorf("Created = %v, want the pod creation time", pod.Created)
+		}
 	}
 }
 
@@ -274,7 +320,7 @@ func BenchmarkCollectNodeInfo(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _, _ = collectNodeInfo(ctx, clientset)
+		_, _, _ = collectNodeInfo(ctx, clientset, HealthFilter{})
 	}
 }
 
@@ -293,7 +339,7 @@ func BenchmarkCollectPodHealth(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _, _, _ = collectPodHealth(ctx, clientset)
+		_, _ = collectPodHealth(ctx, clientset, HealthFilter{}, 0)
 	}
 }
 
@@ -909,3 +955,134 @@ func TestCollectNetworkResourcesNamespaceFilter(t *testing.T) {
 		t.Errorf("got %d workloads with targetNamespace=production, want 1", len(allWorkloads))
 	}
 }
+
+func TestCollectHealthWithFilter(t *testing.T) {
+	pod := func(name, ns, app string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, Labels: map[string]string{"app": app}},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		}
+	}
+	node := func(name, pool string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"pool": pool}}}
+	}
+	clientset := fake.NewClientset(
+		pod("pay-1", "payments", "payments"), pod("pay-2", "payments", "payments"),
+		pod("web-1", "payments", "web"), pod("pay-3", "staging", "payments"),
+		node("node-1", "gpu"), node("node-2", "general"),
+	)
+	ctx := context.Background()
+
+	health, err := collectPodHealth(ctx, clientset, HealthFilter{Namespace: "payments", LabelSelector: "app=payments"}, 0)
+	if err != nil {
+		t.Fatalf("collectPodHealth() failed: %v", err)
+	}
+	if health.total != 2 || len(health.unhealthy) != 2 {
+		t.Errorf("collectPodHealth() = %d pods, %d unhealthy; want 2, 2", health.total, len(health.unhealthy))
+	}
+
+	nodes, _, err := collectNodeInfo(ctx, clientset, HealthFilter{NodeSelector: "pool=gpu"})
+	if err != nil {
+		t.Fatalf("collectNodeInfo() failed: %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].Name != "node-1" {
+		t.Errorf("collectNodeInfo() = %+v, want node-1 only", nodes)
+	}
+}
+
+func TestHealthFilter(t *testing.T) {
+	if !(HealthFilter{}).IsZero() || (HealthFilter{Namespace: "a"}).IsZero() {
+		t.Error("IsZero() should only hold for the zero filter")
+	}
+	filter := HealthFilter{Namespace: "payments", LabelSelector: "app=payments", FieldSelector: "status.phase!=Running", NodeSelector: "pool=gpu"}
+	if err := filter.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	if got, want := filter.String(), "namespace payments, pods app=payments,status.phase!=Running, nodes pool=gpu"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	for _, bad := range []HealthFilter{{LabelSelector: "app in (a"}, {FieldSelector: "a=b=c"}, {NodeSelector: "!!"}} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Validate(%+v) should fail", bad)
+		}
+	}
+}
+
+// pagedPodClient serves n pods, every tenth unhealthy, a page of the
+// requested limit at a time; pages after expireAfter fail as expired.
+func pagedPodClient(n, expireAfter int) (*fake.Clientset, *[]int64) {
+	clientset := fake.NewClientset()
+	var limits []int64
+	clientset.PrependReactor("list", "pods", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		opts := a.(k8stesting.ListActionImpl).ListOptions
+		limits = append(limits, opts.Limit)
+		start, _ := strconv.Atoi(opts.Continue)
+		if expireAfter > 0 && start >= expireAfter {
+			return true, nil, apierrors.NewResourceExpired("continue token expired")
+		}
+		end := min(start+int(opts.Limit), n)
+		list := &corev1.PodList{}
+		for i := start; i < end; i++ {
+			phase := corev1.PodSucceeded
+			if i%10 == 0 {
+				phase = corev1.PodFailed
+			}
+			list.Items = append(list.Items, corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d", i), Namespace: "default"},
+				Status:     corev1.PodStatus{Phase: phase},
+			})
+		}
+		if end < n {
+			list.Continue = strconv.Itoa(end)
+		}
+		return true, list, nil
+	})
+	return clientset, &limits
+}
+
+func TestCollectPodHealthPaginates(t *testing.T) {
+	ctx := context.Background()
+	clientset, limits := pagedPodClient(1234, 0)
+	health, err := collectPodHealth(ctx, clientset, HealthFilter{}, 0)
+	if err != nil {
+		t.Fatalf("collectPodHealth() failed: %v", err)
+	}
+	if health.total != 1234 || health.healthy != 1234-124 || len(health.unhealthy) != 124 || health.truncated {
+		t.Errorf("collectPodHealth() = %d total, %d healthy, %d unhealthy, truncated %v", health.total, health.healthy, len(health.unhealthy), health.truncated)
+	}
+	if len(*limits) != 3 || (*limits)[0] != podListPageSize {
+		t.Errorf("list calls = %v, want 3 pages of %d", *limits, podListPageSize)
+	}
+
+	clientset, limits = pagedPodClient(1234, 0)
+	if health, _ = collectPodHealth(ctx, clientset, HealthFilter{}, 600); health.total != 600 || !health.truncated || len(*limits) != 2 {
+		t.Errorf("capped collectPodHealth() = %d total, truncated %v after %d page(s); want 600, true, 2", health.total, health.truncated, len(*limits))
+	}
+	clientset, _ = pagedPodClient(500, 0)
+	if health, _ = collectPodHealth(ctx, clientset, HealthFilter{}, 500); health.total != 500 || health.truncated {
+		t.Errorf("a cap equal to the pod count should not truncate: %d total, truncated %v", health.total, health.truncated)
+	}
+
+	clientset, _ = pagedPodClient(1234, 1000)
+	health, err = collectPodHealth(ctx, clientset, HealthFilter{}, 0)
+	if err != nil || health.total != 1000 || !health.truncated {
+		t.Errorf("expired collectPodHealth() = %d total, truncated %v, %v; want 1000, true, nil", health.total, health.truncated, err)
+	}
+	clientset, _ = pagedPodClient(1234, -1)
+	clientset.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewResourceExpired("expired")
+	})
+	if _, err := collectPodHealth(ctx, clientset, HealthFilter{}, 0); err == nil {
+		t.Error("a failing first page should be an error")
+	}
+	clientset, _ = pagedPodClient(1234, -1)
+	clientset.PrependReactor("list", "pods", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		if a.(k8stesting.ListActionImpl).ListOptions.Continue == "" {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewServiceUnavailable("etcd leader changed")
+	})
+	if _, err := collectPodHealth(ctx, clientset, HealthFilter{}, 0); !apierrors.IsServiceUnavailable(err) {
+		t.Errorf("a failing later page: err = %v, want it returned", err)
+	}
+}
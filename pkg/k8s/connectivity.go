@@ -0,0 +1,527 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains Service → Endpoints → Pod and NetworkPolicy connectivity diagnostics.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Connectivity check statuses.
+const (
+	CheckOK   = "ok"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+	CheckSkip = "skip"
+)
+
+// ConnectivityCheck is one step of the connectivity diagnosis.
+type ConnectivityCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// ConnectivityRequest identifies the traffic to diagnose. Source fields are
+// optional; without a source pod only the target side is checked.
+type ConnectivityRequest struct {
+	Namespace       string
+	Service         string
+	Port            string // service port number or name; empty for the first port
+	SourceNamespace string
+	SourcePod       string
+}
+
+// ConnectivityReport is the outcome of DiagnoseConnectivity.
+type ConnectivityReport struct {
+	Context      string              `json:"context"`
+	Service      string              `json:"service"` // namespace/name
+	Source       string              `json:"source,omitempty"`
+	FQDN         string              `json:"fqdn"`
+	ClusterIP    string              `json:"cluster_ip,omitempty"`
+	Port         int32               `json:"port,omitempty"`
+	TargetPort   string              `json:"target_port,omitempty"`
+	Selector     map[string]string   `json:"selector,omitempty"`
+	MatchingPods int                 `json:"matching_pods"`
+	ReadyPods    int                 `json:"ready_pods"`
+	Endpoints    int                 `json:"ready_endpoints"`
+	Checks       []ConnectivityCheck `json:"checks"`
+	Healthy      bool                `json:"healthy"`
+}
+
+func (r *ConnectivityReport) add(name, status, format string, args ...any) {
+	r.Checks = append(r.Checks, ConnectivityCheck{Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+}
+
+// isPodReady reports whether pod is running with its Ready condition true.
+func isPodReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// selectorNearMisses describes pods that match every selector label but one,
+// the usual symptom of a typo or a renamed label.
+func selectorNearMisses(selector map[string]string, pods []corev1.Pod) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, pod := range pods {
+		var mismatch []string
+		for k, v := range selector {
+			if got, ok := pod.Labels[k]; !ok {
+				mismatch = append(mismatch, fmt.Sprintf("missing %s", k))
+			} else if got != v {
+				mismatch = append(mismatch, fmt.Sprintf("%s=%s (want %s)", k, got, v))
+			}
+		}
+		if len(mismatch) != 1 || seen[mismatch[0]] {
+			continue
+		}
+		seen[mismatch[0]] = true
+		out = append(out, fmt.Sprintf("pod %s: %s", pod.Name, mismatch[0]))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// selectServicePort picks the service port named or numbered by want, or the first port.
+func selectServicePort(svc *corev1.Service, want string) (*corev1.ServicePort, error) {
+	if len(svc.Spec.Ports) == 0 {
+		return nil, fmt.Errorf("service has no ports")
+	}
+	if want == "" {
+		return &svc.Spec.Ports[0], nil
+	}
+	for i := range svc.Spec.Ports {
+		p := &svc.Spec.Ports[i]
+		if p.Name == want || fmt.Sprint(p.Port) == want {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("service has no port %q", want)
+}
+
+// resolveTargetPort returns the container port number and name the service
+// port forwards to on pod, or ok=false when a named port is not declared.
+func resolveTargetPort(sp *corev1.ServicePort, pod *corev1.Pod) (int32, string, bool) {
+	target := sp.TargetPort
+	if target.Type == intstr.Int && target.IntVal == 0 {
+		target = intstr.FromInt32(sp.Port)
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, cp := range c.Ports {
+			if (target.Type == intstr.String && cp.Name == target.StrVal) ||
+				(target.Type == intstr.Int && cp.ContainerPort == target.IntVal) {
+				return cp.ContainerPort, cp.Name, true
+			}
+		}
+	}
+	if target.Type == intstr.Int {
+		// Declaring container ports is optional; an undeclared number still works.
+		return target.IntVal, "", true
+	}
+	return 0, target.StrVal, false
+}
+
+// policyPeer is one end of a connection for NetworkPolicy evaluation.
+type policyPeer struct {
+	namespace       string
+	labels          map[string]string
+	namespaceLabels map[string]string
+}
+
+// peerMatches reports whether any NetworkPolicyPeer selects peer. policyNS is
+// the namespace of the policy, which scopes peers without a namespace selector.
+// ipBlock peers cannot be evaluated for pod IPs and are reported separately.
+func peerMatches(peers []networkingv1.NetworkPolicyPeer, policyNS string, peer policyPeer) (matched, hasIPBlock bool) {
+	if len(peers) == 0 {
+		return true, false
+	}
+	for _, p := range peers {
+		if p.IPBlock != nil {
+			hasIPBlock = true
+			continue
+		}
+		if p.NamespaceSelector != nil {
+			nsSel, err := metav1.LabelSelectorAsSelector(p.NamespaceSelector)
+			if err != nil || !nsSel.Matches(labels.Set(peer.namespaceLabels)) {
+				continue
+			}
+		} else if peer.namespace != policyNS {
+			continue
+		}
+		if p.PodSelector != nil {
+			podSel, err := metav1.LabelSelectorAsSelector(p.PodSelector)
+			if err != nil || !podSel.Matches(labels.Set(peer.labels)) {
+				continue
+			}
+		}
+		return true, hasIPBlock
+	}
+	return false, hasIPBlock
+}
+
+// portMatches reports whether a rule's port list admits port/name/protocol.
+func portMatches(ports []networkingv1.NetworkPolicyPort, port int32, name string, protocol corev1.Protocol) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	for _, p := range ports {
+		proto := corev1.ProtocolTCP
+		if p.Protocol != nil {
+			proto = *p.Protocol
+		}
+		if proto != protocol {
+			continue
+		}
+		switch {
+		case p.Port == nil:
+			return true
+		case p.Port.Type == intstr.String:
+			if name != "" && p.Port.StrVal == name {
+				return true
+			}
+		case p.EndPort != nil:
+			if port >= p.Port.IntVal && port <= *p.EndPort {
+				return true
+			}
+		case p.Port.IntVal == port:
+			return true
+		}
+	}
+	return false
+}
+
+// policyHasType reports whether policy governs direction (Ingress or Egress),
+// applying the API defaulting rules when PolicyTypes is empty.
+func policyHasType(policy *networkingv1.NetworkPolicy, direction networkingv1.PolicyType) bool {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return direction == networkingv1.PolicyTypeIngress || len(policy.Spec.Egress) > 0
+	}
+	for _, t := range policy.Spec.PolicyTypes {
+		if t == direction {
+			return true
+		}
+	}
+	return false
+}
+
+// policyVerdict is the outcome of evaluating NetworkPolicies in one direction.
+type policyVerdict struct {
+	isolating []string // policies selecting the pod for this direction
+	allowing  []string // isolating policies with a rule admitting the traffic
+	ipBlocks  bool     // an ipBlock rule might admit the traffic
+}
+
+// evaluatePolicies checks whether traffic between subject (the pod the
+// policies select) and other is allowed in direction on the given port.
+func evaluatePolicies(policies []networkingv1.NetworkPolicy, direction networkingv1.PolicyType, subject, other policyPeer, port int32, portName string, protocol corev1.Protocol) policyVerdict {
+	var v policyVerdict
+	for i := range policies {
+		policy := &policies[i]
+		if policy.Namespace != subject.namespace || !policyHasType(policy, direction) {
+			continue
+		}
+		sel, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+		if err != nil || !sel.Matches(labels.Set(subject.labels)) {
+			continue
+		}
+		v.isolating = append(v.isolating, policy.Name)
+
+		allowed := false
+		if direction == networkingv1.PolicyTypeIngress {
+			for _, rule := range policy.Spec.Ingress {
+				matched, ipBlock := peerMatches(rule.From, policy.Namespace, other)
+				v.ipBlocks = v.ipBlocks || ipBlock
+				if matched && portMatches(rule.Ports, port, portName, protocol) {
+					allowed = true
+					break
+				}
+			}
+		} else {
+			for _, rule := range policy.Spec.Egress {
+				matched, ipBlock := peerMatches(rule.To, policy.Namespace, other)
+				v.ipBlocks = v.ipBlocks || ipBlock
+				if matched && portMatches(rule.Ports, port, portName, protocol) {
+					allowed = true
+					break
+				}
+			}
+		}
+		if allowed {
+			v.allowing = append(v.allowing, policy.Name)
+		}
+	}
+	return v
+}
+
+// dnsEgressAllowed reports whether egress policies selecting subject allow
+// UDP port 53 to some destination. Peers are not evaluated: DNS servers run
+// in different namespaces per distribution, so any port-53 rule counts.
+func dnsEgressAllowed(policies []networkingv1.NetworkPolicy, subject policyPeer) (isolated, allowed bool) {
+	for i := range policies {
+		policy := &policies[i]
+		if policy.Namespace != subject.namespace || !policyHasType(policy, networkingv1.PolicyTypeEgress) {
+			continue
+		}
+		sel, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+		if err != nil || !sel.Matches(labels.Set(subject.labels)) {
+			continue
+		}
+		isolated = true
+		for _, rule := range policy.Spec.Egress {
+			if portMatches(rule.Ports, 53, "dns", corev1.ProtocolUDP) {
+				return true, true
+			}
+		}
+	}
+	return isolated, false
+}
+
+// reportPolicyVerdict turns a policyVerdict into a check.
+func reportPolicyVerdict(r *ConnectivityReport, name string, v policyVerdict, what string) {
+	switch {
+	case len(v.isolating) == 0:
+		r.add(name, CheckOK, "no NetworkPolicy restricts %s", what)
+	case len(v.allowing) > 0:
+		r.add(name, CheckOK, "%s allowed by %s", what, strings.Join(v.allowing, ", "))
+	case v.ipBlocks:
+		r.add(name, CheckWarn, "%s isolated by %s; only ipBlock rules could allow it (depends on pod IPs and CNI)", what, strings.Join(v.isolating, ", "))
+	default:
+		r.add(name, CheckFail, "%s is BLOCKED: isolated by %s and no rule allows it", what, strings.Join(v.isolating, ", "))
+	}
+}
+
+// connectivityData is the cluster state DiagnoseConnectivity evaluates.
+type connectivityData struct {
+	service         *corev1.Service
+	pods            []corev1.Pod // pods in the service namespace
+	slices          []discoveryv1.EndpointSlice
+	policies        []networkingv1.NetworkPolicy
+	namespaceLabels map[string]map[string]string
+	source          *corev1.Pod
+}
+
+// diagnoseConnectivity runs all checks against data.
+func diagnoseConnectivity(req ConnectivityRequest, data connectivityData) *ConnectivityReport {
+	svc := data.service
+	r := &ConnectivityReport{
+		Service:   req.Namespace + "/" + req.Service,
+		FQDN:      fmt.Sprintf("%s.%s.svc.cluster.local", req.Service, req.Namespace),
+		ClusterIP: svc.Spec.ClusterIP,
+		Selector:  svc.Spec.Selector,
+		Checks:    []ConnectivityCheck{},
+	}
+	if data.source != nil {
+		r.Source = data.source.Namespace + "/" + data.source.Name
+	}
+
+	if svc.Spec.Type == corev1.ServiceTypeExternalName {
+		r.add("service", CheckWarn, "ExternalName service resolving to %s; no endpoints or policies to check in-cluster", svc.Spec.ExternalName)
+		r.Healthy = true
+		return r
+	}
+	sp, err := selectServicePort(svc, req.Port)
+	if err != nil {
+		r.add("service", CheckFail, "%v", err)
+		return r
+	}
+	r.Port = sp.Port
+	r.TargetPort = sp.TargetPort.String()
+	r.add("service", CheckOK, "%s type %s, cluster IP %s, port %d → target %s/%s", r.Service, svc.Spec.Type, orDash(svc.Spec.ClusterIP), sp.Port, r.TargetPort, sp.Protocol)
+
+	// Selector → pods.
+	var targets []corev1.Pod
+	if len(svc.Spec.Selector) == 0 {
+		r.add("selector", CheckWarn, "service has no selector; endpoints must be managed manually")
+	} else {
+		sel := labels.SelectorFromSet(svc.Spec.Selector)
+		for _, pod := range data.pods {
+			if sel.Matches(labels.Set(pod.Labels)) && pod.DeletionTimestamp == nil {
+				targets = append(targets, pod)
+				if isPodReady(&pod) {
+					r.ReadyPods++
+				}
+			}
+		}
+		r.MatchingPods = len(targets)
+		switch {
+		case r.MatchingPods == 0:
+			detail := fmt.Sprintf("no pods match selector %s", sel)
+			if near := selectorNearMisses(svc.Spec.Selector, data.pods); len(near) > 0 {
+				detail += "; near misses: " + strings.Join(near, "; ")
+			}
+			r.add("selector", CheckFail, "%s", detail)
+		case r.ReadyPods == 0:
+			r.add("selector", CheckFail, "%d pod(s) match selector %s but none are Ready", r.MatchingPods, sel)
+		default:
+			r.add("selector", CheckOK, "%d/%d matching pod(s) Ready", r.ReadyPods, r.MatchingPods)
+		}
+	}
+
+	// Target port declared on the pods.
+	var targetPort int32
+	var targetPortName string
+	if len(targets) > 0 {
+		port, name, ok := resolveTargetPort(sp, &targets[0])
+		targetPort, targetPortName = port, name
+		if ok {
+			r.add("target-port", CheckOK, "target port resolves to container port %d on pod %s", port, targets[0].Name)
+		} else {
+			r.add("target-port", CheckFail, "named target port %q is not declared by any container in pod %s", name, targets[0].Name)
+		}
+	}
+
+	// EndpointSlices.
+	notReady := 0
+	for _, slice := range data.slices {
+		for _, ep := range slice.Endpoints {
+			if ep.Conditions.Ready == nil || *ep.Conditions.Ready {
+				r.Endpoints += len(ep.Addresses)
+			} else {
+				notReady += len(ep.Addresses)
+			}
+		}
+	}
+	switch {
+	case r.Endpoints > 0:
+		r.add("endpoints", CheckOK, "%d ready endpoint address(es), %d not ready", r.Endpoints, notReady)
+	case r.ReadyPods > 0:
+		r.add("endpoints", CheckFail, "%d Ready pod(s) but no ready endpoints; check the target port and the endpoint controller", r.ReadyPods)
+	default:
+		r.add("endpoints", CheckFail, "no ready endpoints (%d not ready); connections to %s will be refused or time out", notReady, r.Service)
+	}
+
+	// NetworkPolicies.
+	if len(targets) > 0 {
+		target := policyPeer{namespace: req.Namespace, labels: targets[0].Labels, namespaceLabels: data.namespaceLabels[req.Namespace]}
+		if data.source == nil {
+			v := evaluatePolicies(data.policies, networkingv1.PolicyTypeIngress, target, policyPeer{}, targetPort, targetPortName, sp.Protocol)
+			if len(v.isolating) == 0 {
+				r.add("ingress-policy", CheckOK, "no NetworkPolicy restricts ingress to the target pods")
+			} else {
+				r.add("ingress-policy", CheckWarn, "ingress to the target pods is restricted by %s; give a source pod to evaluate it", strings.Join(v.isolating, ", "))
+			}
+		} else {
+			src := data.source
+			source := policyPeer{namespace: src.Namespace, labels: src.Labels, namespaceLabels: data.namespaceLabels[src.Namespace]}
+			reportPolicyVerdict(r, "ingress-policy",
+				evaluatePolicies(data.policies, networkingv1.PolicyTypeIngress, target, source, targetPort, targetPortName, sp.Protocol),
+				fmt.Sprintf("ingress from %s to port %d", r.Source, targetPort))
+			reportPolicyVerdict(r, "egress-policy",
+				evaluatePolicies(data.policies, networkingv1.PolicyTypeEgress, source, target, targetPort, targetPortName, sp.Protocol),
+				fmt.Sprintf("egress from %s to %s port %d", r.Source, r.Service, targetPort))
+			if isolated, allowed := dnsEgressAllowed(data.policies, source); isolated && !allowed {
+				r.add("dns-egress", CheckFail, "egress from %s is restricted and no rule allows UDP port 53; DNS lookups of %s will fail", r.Source, r.FQDN)
+			}
+		}
+	}
+
+	r.Healthy = true
+	for _, c := range r.Checks {
+		if c.Status == CheckFail {
+			r.Healthy = false
+		}
+	}
+	return r
+}
+
+// orDash returns s, or "-" when s is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// collectConnectivityData fetches the objects diagnoseConnectivity needs.
+func collectConnectivityData(ctx context.Context, clientset kubernetes.Interface, req ConnectivityRequest) (connectivityData, error) {
+	var data connectivityData
+	svc, err := clientset.CoreV1().Services(req.Namespace).Get(ctx, req.Service, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return data, fmt.Errorf("service %s/%s not found", req.Namespace, req.Service)
+	}
+	if err != nil {
+		return data, fmt.Errorf("failed to get service: %w", err)
+	}
+	data.service = svc
+
+	pods, err := clientset.CoreV1().Pods(req.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return data, fmt.Errorf("failed to list pods: %w", err)
+	}
+	data.pods = pods.Items
+
+	slices, err := clientset.DiscoveryV1().EndpointSlices(req.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + req.Service,
+	})
+	if err != nil {
+		return data, fmt.Errorf("failed to list endpoint slices: %w", err)
+	}
+	data.slices = slices.Items
+
+	policies, err := clientset.NetworkingV1().NetworkPolicies("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return data, fmt.Errorf("failed to list network policies: %w", err)
+	}
+	data.policies = policies.Items
+
+	namespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return data, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	data.namespaceLabels = make(map[string]map[string]string, len(namespaces.Items))
+	for _, ns := range namespaces.Items {
+		data.namespaceLabels[ns.Name] = ns.Labels
+	}
+
+	if req.SourcePod != "" {
+		src, err := clientset.CoreV1().Pods(req.SourceNamespace).Get(ctx, req.SourcePod, metav1.GetOptions{})
+		if err != nil {
+			return data, fmt.Errorf("failed to get source pod %s/%s: %w", req.SourceNamespace, req.SourcePod, err)
+		}
+		data.source = src
+	}
+	return data, nil
+}
+
+// DiagnoseConnectivity checks the Service → EndpointSlice → Pod wiring of a
+// service and, when a source pod is given, whether NetworkPolicies allow it
+// to reach the service's pods.
+func (p *Provider) DiagnoseConnectivity(ctx context.Context, contextName string, req ConnectivityRequest) (*ConnectivityReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	if req.SourcePod != "" && req.SourceNamespace == "" {
+		req.SourceNamespace = req.Namespace
+	}
+	data, err := collectConnectivityData(queryCtx, clientset, req)
+	if err != nil {
+		return nil, err
+	}
+	report := diagnoseConnectivity(req, data)
+	report.Context = contextName
+	return report, nil
+}
@@ -0,0 +1,202 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func readyPod(namespace, name string, podLabels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: podLabels},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app", Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+		}}},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func webService(targetPort intstr.IntOrString) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: corev1.ServiceSpec{
+			Type:      corev1.ServiceTypeClusterIP,
+			ClusterIP: "10.0.0.10",
+			Selector:  map[string]string{"app": "web"},
+			Ports:     []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: targetPort, Protocol: corev1.ProtocolTCP}},
+		},
+	}
+}
+
+func webSlice(ready bool) *discoveryv1.EndpointSlice {
+	return &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "shop", Labels: map[string]string{discoveryv1.LabelServiceName: "web"}},
+		Endpoints:  []discoveryv1.Endpoint{{Addresses: []string{"10.1.0.5"}, Conditions: discoveryv1.EndpointConditions{Ready: &ready}}},
+	}
+}
+
+func checkStatus(r *ConnectivityReport, name string) (string, string) {
+	for _, c := range r.Checks {
+		if c.Name == name {
+			return c.Status, c.Detail
+		}
+	}
+	return "", ""
+}
+
+func TestDiagnoseConnectivityHealthy(t *testing.T) {
+	clientset := fake.NewClientset(
+		webService(intstr.FromString("http")),
+		readyPod("shop", "web-1", map[string]string{"app": "web"}),
+		webSlice(true),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}},
+	)
+	data, err := collectConnectivityData(context.Background(), clientset, ConnectivityRequest{Namespace: "shop", Service: "web"})
+	if err != nil {
+		t.Fatalf("collectConnectivityData: %v", err)
+	}
+	r := diagnoseConnectivity(ConnectivityRequest{Namespace: "shop", Service: "web"}, data)
+	if !r.Healthy || r.MatchingPods != 1 || r.ReadyPods != 1 || r.Endpoints != 1 {
+		t.Errorf("unexpected report %+v", r)
+	}
+	if status, detail := checkStatus(r, "target-port"); status != CheckOK || !strings.Contains(detail, "8080") {
+		t.Errorf("target-port = %s %q", status, detail)
+	}
+	if r.FQDN != "web.shop.svc.cluster.local" {
+		t.Errorf("FQDN = %s", r.FQDN)
+	}
+}
+
+func TestDiagnoseConnectivitySelectorMismatch(t *testing.T) {
+	data := connectivityData{
+		service: webService(intstr.FromInt32(8080)),
+		pods:    []corev1.Pod{*readyPod("shop", "web-1", map[string]string{"app": "web-v2"})},
+	}
+	r := diagnoseConnectivity(ConnectivityRequest{Namespace: "shop", Service: "web"}, data)
+	if r.Healthy {
+		t.Error("selector mismatch should be unhealthy")
+	}
+	status, detail := checkStatus(r, "selector")
+	if status != CheckFail || !strings.Contains(detail, "app=web-v2 (want web)") {
+		t.Errorf("selector = %s %q", status, detail)
+	}
+	if status, _ := checkStatus(r, "endpoints"); status != CheckFail {
+		t.Errorf("endpoints = %s, want fail", status)
+	}
+}
+
+func TestDiagnoseConnectivityNamedPortMissing(t *testing.T) {
+	data := connectivityData{
+		service: webService(intstr.FromString("metrics")),
+		pods:    []corev1.Pod{*readyPod("shop", "web-1", map[string]string{"app": "web"})},
+		slices:  []discoveryv1.EndpointSlice{*webSlice(false)},
+	}
+	r := diagnoseConnectivity(ConnectivityRequest{Namespace: "shop", Service: "web"}, data)
+	if status, _ := checkStatus(r, "target-port"); status != CheckFail {
+		t.Errorf("target-port = %s, want fail", status)
+	}
+	if status, detail := checkStatus(r, "endpoints"); status != CheckFail || !strings.Contains(detail, "Ready pod(s) but no ready endpoints") {
+		t.Errorf("endpoints = %s %q", status, detail)
+	}
+}
+
+func TestDiagnoseConnectivityNetworkPolicy(t *testing.T) {
+	tcp := corev1.ProtocolTCP
+	port := intstr.FromInt32(8080)
+	denyAll := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default-deny", Namespace: "shop"},
+		Spec:       networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}},
+	}
+	allowFrontend := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-frontend", Namespace: "shop"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "frontend"}},
+					PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "ui"}},
+				}},
+				Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &port}},
+			}},
+		},
+	}
+	egressNoDNS := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "egress-lockdown", Namespace: "frontend"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress:      []networkingv1.NetworkPolicyEgressRule{{Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &port}}}},
+		},
+	}
+
+	base := func(source *corev1.Pod, policies ...networkingv1.NetworkPolicy) *ConnectivityReport {
+		data := connectivityData{
+			service:         webService(intstr.FromString("http")),
+			pods:            []corev1.Pod{*readyPod("shop", "web-1", map[string]string{"app": "web"})},
+			slices:          []discoveryv1.EndpointSlice{*webSlice(true)},
+			policies:        policies,
+			namespaceLabels: map[string]map[string]string{"frontend": {"team": "frontend"}, "batch": {}},
+			source:          source,
+		}
+		return diagnoseConnectivity(ConnectivityRequest{Namespace: "shop", Service: "web"}, data)
+	}
+
+	ui := readyPod("frontend", "ui-1", map[string]string{"app": "ui"})
+	r := base(ui, denyAll, allowFrontend)
+	if status, detail := checkStatus(r, "ingress-policy"); status != CheckOK || !strings.Contains(detail, "allow-frontend") {
+		t.Errorf("allowed source: ingress-policy = %s %q", status, detail)
+	}
+
+	job := readyPod("batch", "job-1", map[string]string{"app": "ui"})
+	r = base(job, denyAll, allowFrontend)
+	if status, detail := checkStatus(r, "ingress-policy"); status != CheckFail || !strings.Contains(detail, "BLOCKED") {
+		t.Errorf("blocked source: ingress-policy = %s %q", status, detail)
+	}
+	if r.Healthy {
+		t.Error("blocked traffic should be unhealthy")
+	}
+
+	r = base(ui, egressNoDNS)
+	if status, _ := checkStatus(r, "egress-policy"); status != CheckOK {
+		t.Errorf("egress-policy = %s, want ok", status)
+	}
+	if status, _ := checkStatus(r, "dns-egress"); status != CheckFail {
+		t.Errorf("dns-egress = %s, want fail", status)
+	}
+
+	r = base(nil, denyAll)
+	if status, _ := checkStatus(r, "ingress-policy"); status != CheckWarn {
+		t.Errorf("no source: ingress-policy = %s, want warn", status)
+	}
+}
+
+func TestPortMatchesRange(t *testing.T) {
+	tcp := corev1.ProtocolTCP
+	start, end := intstr.FromInt32(8000), int32(9000)
+	ports := []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &start, EndPort: &end}}
+	if !portMatches(ports, 8080, "", corev1.ProtocolTCP) {
+		t.Error("8080 should match 8000-9000")
+	}
+	if portMatches(ports, 8080, "", corev1.ProtocolUDP) {
+		t.Error("UDP should not match a TCP rule")
+	}
+	if portMatches(ports, 9001, "", corev1.ProtocolTCP) {
+		t.Error("9001 should not match 8000-9000")
+	}
+}
+
+func TestCollectConnectivityDataMissingService(t *testing.T) {
+	_, err := collectConnectivityData(context.Background(), fake.NewClientset(), ConnectivityRequest{Namespace: "shop", Service: "nope"})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("err = %v, want not found", err)
+	}
+}
@@ -0,0 +1,126 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the control-plane collector: the health of the API
+// server, scheduler, controller manager and etcd of self-managed clusters.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// controlPlaneComponents are the components looked for, by the "component"
+// label kubeadm and most installers put on their static pods.
+var controlPlaneComponents = []string{"kube-apiserver", "etcd", "kube-scheduler", "kube-controller-manager"}
+
+// Control-plane health sources.
+const (
+	ControlPlaneSourcePods            = "pods"
+	ControlPlaneSourceComponentStatus = "componentstatus"
+)
+
+// ControlPlaneComponent is the health of one control-plane component.
+type ControlPlaneComponent struct {
+	// Name is the component, e.g. etcd or kube-scheduler.
+	Name string `json:"name"`
+	// Healthy is set when every instance is running and ready.
+	Healthy bool `json:"healthy"`
+	// Ready and Total count the instances (pods) of the component.
+	Ready int `json:"ready"`
+	Total int `json:"total"`
+	// Source is where the health was read: kube-system pods or the
+	// deprecated componentstatuses API.
+	Source string `json:"source"`
+	// Message explains an unhealthy component.
+	Message string `json:"message,omitempty"`
+}
+
+// collectControlPlaneHealth reports the control-plane components of a
+// self-managed cluster from their pods in kube-system, falling back to
+// componentstatuses. Managed clusters show neither, so the result is empty
+// and their control plane is not reported.
+func collectControlPlaneHealth(ctx context.Context, clientset kubernetes.Interface) ([]ControlPlaneComponent, error) {
+	pods, err := clientset.CoreV1().Pods(metav1.NamespaceSystem).List(ctx, metav1.ListOptions{LabelSelector: "tier=control-plane"})
+	if err != nil {
+		return nil, err
+	}
+	if components := controlPlaneFromPods(pods.Items); len(components) > 0 {
+		return components, nil
+	}
+	return controlPlaneFromComponentStatuses(ctx, clientset)
+}
+
+// controlPlaneFromPods groups control-plane pods by their component label.
+func controlPlaneFromPods(pods []corev1.Pod) []ControlPlaneComponent {
+	byName := make(map[string][]*corev1.Pod)
+	for i := range pods {
+		name := pods[i].Labels["component"]
+		if name != "" {
+			byName[name] = append(byName[name], &pods[i])
+		}
+	}
+	components := make([]ControlPlaneComponent, 0, len(byName))
+	for _, name := range controlPlaneComponents {
+		instances := byName[name]
+		if len(instances) == 0 {
+			continue
+		}
+		c := ControlPlaneComponent{Name: name, Total: len(instances), Source: ControlPlaneSourcePods}
+		var problems []string
+		for _, pod := range instances {
+			if pod.Status.Phase == corev1.PodRunning && isPodHealthy(pod) {
+				c.Ready++
+				continue
+			}
+			info := extractPodInfo(pod)
+			problem := pod.Name + " " + info.Status
+			if info.Reason != "" {
+				problem += " (" + info.Reason + ")"
+			}
+			problems = append(problems, problem)
+		}
+		c.Healthy = c.Ready == c.Total
+		if !c.Healthy {
+			sort.Strings(problems)
+			c.Message = fmt.Sprintf("%d/%d ready: %s", c.Ready, c.Total, strings.Join(problems, ", "))
+		}
+		components = append(components, c)
+	}
+	return components
+}
+
+// controlPlaneFromComponentStatuses reads the deprecated componentstatuses
+// API, which some self-managed clusters still serve. An error means the
+// control plane is not visible, which is not a failure.
+func controlPlaneFromComponentStatuses(ctx context.Context, clientset kubernetes.Interface) ([]ControlPlaneComponent, error) {
+	statuses, err := clientset.CoreV1().ComponentStatuses().List(ctx, metav1.ListOptions{}) //nolint:staticcheck // deprecated, but the only source on some clusters
+	if err != nil {
+		return []ControlPlaneComponent{}, nil
+	}
+	components := make([]ControlPlaneComponent, 0, len(statuses.Items))
+	for _, cs := range statuses.Items {
+		c := ControlPlaneComponent{Name: cs.Name, Total: 1, Source: ControlPlaneSourceComponentStatus}
+		for _, cond := range cs.Conditions {
+			if cond.Type != corev1.ComponentHealthy {
+				continue
+			}
+			c.Healthy = cond.Status == corev1.ConditionTrue
+			if !c.Healthy {
+				c.Message = strings.TrimSpace(cond.Message + " " + cond.Error)
+			}
+		}
+		if c.Healthy {
+			c.Ready = 1
+		} else if c.Message == "" {
+			c.Message = "no Healthy condition reported"
+		}
+		components = append(components, c)
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i].Name < components[j].Name })
+	return components, nil
+}
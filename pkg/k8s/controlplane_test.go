@@ -0,0 +1,82 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// controlPlanePod returns a kubeadm-style static pod of component on node.
+func controlPlanePod(component, node string, phase corev1.PodPhase, ready bool) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: component + "-" + node, Namespace: metav1.NamespaceSystem,
+			Labels: map[string]string{"component": component, "tier": "control-plane"},
+		},
+		Status: corev1.PodStatus{Phase: phase, ContainerStatuses: []corev1.ContainerStatus{{Name: component, Ready: ready}}},
+	}
+	if !ready {
+		pod.Status.ContainerStatuses[0].State.Waiting = &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}
+	}
+	return pod
+}
+
+func TestCollectControlPlaneHealthFromPods(t *testing.T) {
+	clientset := fake.NewClientset(
+		controlPlanePod("kube-apiserver", "cp-1", corev1.PodRunning, true),
+		controlPlanePod("etcd", "cp-1", corev1.PodRunning, true),
+		controlPlanePod("etcd", "cp-2", corev1.PodRunning, false),
+		controlPlanePod("kube-scheduler", "cp-1", corev1.PodRunning, true),
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: metav1.NamespaceSystem, Labels: map[string]string{"k8s-app": "kube-dns"}}},
+	)
+	components, err := collectControlPlaneHealth(context.Background(), clientset)
+	if err != nil {
+		t.Fatalf("collectControlPlaneHealth: %v", err)
+	}
+	if len(components) != 3 {
+		t.Fatalf("components = %+v, want apiserver, etcd and scheduler", components)
+	}
+	if c := components[0]; c.Name != "kube-apiserver" || !c.Healthy || c.Source != ControlPlaneSourcePods {
+		t.Errorf("apiserver = %+v", c)
+	}
+	etcd := components[1]
+	if etcd.Name != "etcd" || etcd.Healthy || etcd.Ready != 1 || etcd.Total != 2 {
+		t.Errorf("etcd = %+v", etcd)
+	}
+	if !strings.Contains(etcd.Message, "1/2 ready: etcd-cp-2 Running (CrashLoopBackOff)") {
+		t.Errorf("etcd message = %q", etcd.Message)
+	}
+}
+
+func TestCollectControlPlaneHealthFromComponentStatuses(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.ComponentStatus{
+			ObjectMeta: metav1.ObjectMeta{Name: "scheduler"},
+			Conditions: []corev1.ComponentCondition{{Type: corev1.ComponentHealthy, Status: corev1.ConditionTrue, Message: "ok"}},
+		},
+		&corev1.ComponentStatus{
+			ObjectMeta: metav1.ObjectMeta{Name: "etcd-0"},
+			Conditions: []corev1.ComponentCondition{{Type: corev1.ComponentHealthy, Status: corev1.ConditionFalse, Error: "connection refused"}},
+		},
+	)
+	components, err := collectControlPlaneHealth(context.Background(), clientset)
+	if err != nil {
+		t.Fatalf("collectControlPlaneHealth: %v", err)
+	}
+	if len(components) != 2 || components[0].Name != "etcd-0" || components[0].Healthy || components[0].Message != "connection refused" {
+		t.Errorf("components = %+v", components)
+	}
+	if c := components[1]; !c.Healthy || c.Ready != 1 || c.Source != ControlPlaneSourceComponentStatus {
+		t.Errorf("scheduler = %+v", c)
+	}
+
+	// A managed cluster shows neither pods nor component statuses.
+	components, err = collectControlPlaneHealth(context.Background(), fake.NewClientset())
+	if err != nil || len(components) != 0 {
+		t.Errorf("managed cluster: components = %+v, err %v", components, err)
+	}
+}
@@ -0,0 +1,315 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the optional cost estimation from a price table or OpenCost.
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// HoursPerMonth is the average number of hours in a month used for monthly estimates.
+	HoursPerMonth = 730
+
+	defaultCostCurrency = "USD"
+	defaultOpenCostPort = 9003
+	openCostIdleKey     = "__idle__"
+)
+
+// PriceTable maps node instance types to hourly prices. Nodes whose type is
+// not listed are priced from their allocatable CPU and memory instead.
+type PriceTable struct {
+	Currency      string             `json:"currency,omitempty"`
+	InstanceTypes map[string]float64 `json:"instance_types"`            // hourly price per node
+	CPUCoreHour   float64            `json:"cpu_core_hour,omitempty"`   // fallback price per allocatable core
+	MemoryGiBHour float64            `json:"memory_gib_hour,omitempty"` // fallback price per allocatable GiB
+	// OpenCost, when set, takes namespace costs from an in-cluster OpenCost
+	// service reached through the API server proxy.
+	OpenCost *OpenCostSource `json:"opencost,omitempty"`
+}
+
+// OpenCostSource locates the OpenCost API service in each cluster.
+type OpenCostSource struct {
+	Namespace string `json:"namespace"`
+	Service   string `json:"service"`
+	Port      int    `json:"port,omitempty"`
+}
+
+// NodeCost is the estimated price of one node.
+type NodeCost struct {
+	Node         string  `json:"node"`
+	InstanceType string  `json:"instance_type,omitempty"`
+	HourlyCost   float64 `json:"hourly_cost"`
+	PricedBy     string  `json:"priced_by"` // instance-type, resources or unpriced
+}
+
+// NamespaceCost is the share of cluster cost attributed to one namespace.
+type NamespaceCost struct {
+	Namespace   string  `json:"namespace"`
+	HourlyCost  float64 `json:"hourly_cost"`
+	MonthlyCost float64 `json:"monthly_cost"`
+	Percent     float64 `json:"percent"` // of the cluster cost
+}
+
+// CostEstimate is the estimated running cost of one cluster.
+type CostEstimate struct {
+	Currency        string          `json:"currency"`
+	Source          string          `json:"source"` // price-table or opencost
+	HourlyCost      float64         `json:"hourly_cost"`
+	MonthlyCost     float64         `json:"monthly_cost"`
+	IdleHourlyCost  float64         `json:"idle_hourly_cost"` // node cost not claimed by pod requests
+	Nodes           []NodeCost      `json:"nodes"`
+	Namespaces      []NamespaceCost `json:"namespaces"`
+	UnpricedNodes   []string        `json:"unpriced_nodes,omitempty"`
+	OpenCostWarning string          `json:"opencost_warning,omitempty"`
+}
+
+// LoadPriceTable reads a JSON price table from path.
+func LoadPriceTable(path string) (*PriceTable, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is operator-provided configuration
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price table: %w", err)
+	}
+	var table PriceTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to parse price table %s: %w", path, err)
+	}
+	if table.Currency == "" {
+		table.Currency = defaultCostCurrency
+	}
+	if table.CPUCoreHour < 0 || table.MemoryGiBHour < 0 {
+		return nil, fmt.Errorf("price table %s: prices must not be negative", path)
+	}
+	for name, price := range table.InstanceTypes {
+		if price < 0 {
+			return nil, fmt.Errorf("price table %s: instance type %q has a negative price", path, name)
+		}
+	}
+	if oc := table.OpenCost; oc != nil {
+		if oc.Namespace == "" || oc.Service == "" {
+			return nil, fmt.Errorf("price table %s: opencost needs namespace and service", path)
+		}
+		if oc.Port == 0 {
+			oc.Port = defaultOpenCostPort
+		}
+	}
+	return &table, nil
+}
+
+// SetPriceTable enables cost estimates in cluster status reports. Pass nil to disable them.
+func (p *Provider) SetPriceTable(table *PriceTable) {
+	p.cacheMutex.Lock()
+	p.priceTable = table
+	p.cacheMutex.Unlock()
+	p.ClearCache()
+}
+
+// getPriceTable returns the configured price table, or nil when cost estimation is off.
+func (p *Provider) getPriceTable() *PriceTable {
+	p.cacheMutex.RLock()
+	defer p.cacheMutex.RUnlock()
+	return p.priceTable
+}
+
+// nodeInstanceType returns the cloud instance type label of a node.
+func nodeInstanceType(node *corev1.Node) string {
+	if t := node.Labels[corev1.LabelInstanceTypeStable]; t != "" {
+		return t
+	}
+	return node.Labels[corev1.LabelInstanceType]
+}
+
+// priceNode returns the hourly price of a node from the instance type table,
+// falling back to per-core and per-GiB prices of its allocatable resources.
+func priceNode(node *corev1.Node, table *PriceTable) NodeCost {
+	nc := NodeCost{Node: node.Name, InstanceType: nodeInstanceType(node), PricedBy: "unpriced"}
+	if price, ok := table.InstanceTypes[nc.InstanceType]; ok && nc.InstanceType != "" {
+		nc.HourlyCost = price
+		nc.PricedBy = "instance-type"
+		return nc
+	}
+	if table.CPUCoreHour > 0 || table.MemoryGiBHour > 0 {
+		cores := float64(node.Status.Allocatable.Cpu().MilliValue()) / 1000
+		gib := float64(node.Status.Allocatable.Memory().Value()) / (1 << 30)
+		nc.HourlyCost = cores*table.CPUCoreHour + gib*table.MemoryGiBHour
+		nc.PricedBy = "resources"
+	}
+	return nc
+}
+
+// buildCostEstimate prices every node and attributes node cost to namespaces
+// by pod requests: half a node's price follows its CPU and half its memory,
+// and whatever pod requests do not claim is reported as idle.
+func buildCostEstimate(nodes []corev1.Node, pods []corev1.Pod, table *PriceTable) *CostEstimate {
+	estimate := &CostEstimate{Currency: table.Currency, Source: "price-table", Nodes: []NodeCost{}, Namespaces: []NamespaceCost{}}
+
+	type nodeShare struct {
+		cost     float64
+		cpu, mem int64
+		byNS     map[string]float64 // namespace -> fraction of the node
+	}
+	shares := make(map[string]*nodeShare, len(nodes))
+	for i := range nodes {
+		node := &nodes[i]
+		nc := priceNode(node, table)
+		estimate.Nodes = append(estimate.Nodes, nc)
+		estimate.HourlyCost += nc.HourlyCost
+		if nc.PricedBy == "unpriced" {
+			estimate.UnpricedNodes = append(estimate.UnpricedNodes, node.Name)
+		}
+		shares[node.Name] = &nodeShare{
+			cost: nc.HourlyCost,
+			cpu:  node.Status.Allocatable.Cpu().MilliValue(),
+			mem:  node.Status.Allocatable.Memory().Value(),
+			byNS: make(map[string]float64),
+		}
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+		share := shares[pod.Spec.NodeName]
+		if share == nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		reqCPU, reqMem, _, _ := podRequestsAndLimits(pod)
+		var fraction float64
+		if share.cpu > 0 {
+			fraction += 0.5 * float64(reqCPU) / float64(share.cpu)
+		}
+		if share.mem > 0 {
+			fraction += 0.5 * float64(reqMem) / float64(share.mem)
+		}
+		share.byNS[pod.Namespace] += fraction
+	}
+
+	byNS := make(map[string]float64)
+	var allocated float64
+	for _, share := range shares {
+		total := 0.0
+		for _, f := range share.byNS {
+			total += f
+		}
+		// Overcommitted nodes (requests above allocatable) are split pro rata.
+		scale := 1.0
+		if total > 1 {
+			scale = 1 / total
+		}
+		for ns, f := range share.byNS {
+			cost := share.cost * f * scale
+			byNS[ns] += cost
+			allocated += cost
+		}
+	}
+	estimate.IdleHourlyCost = max(estimate.HourlyCost-allocated, 0)
+	estimate.Namespaces = namespaceCosts(byNS, estimate.HourlyCost)
+	estimate.MonthlyCost = estimate.HourlyCost * HoursPerMonth
+	sort.Slice(estimate.Nodes, func(i, j int) bool { return estimate.Nodes[i].Node < estimate.Nodes[j].Node })
+	sort.Strings(estimate.UnpricedNodes)
+	return estimate
+}
+
+// namespaceCosts turns hourly costs per namespace into a list sorted by cost.
+func namespaceCosts(byNS map[string]float64, clusterHourly float64) []NamespaceCost {
+	out := make([]NamespaceCost, 0, len(byNS))
+	for ns, hourly := range byNS {
+		nc := NamespaceCost{Namespace: ns, HourlyCost: hourly, MonthlyCost: hourly * HoursPerMonth}
+		if clusterHourly > 0 {
+			nc.Percent = roundOneDecimal(hourly / clusterHourly * 100)
+		}
+		out = append(out, nc)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].HourlyCost != out[j].HourlyCost {
+			return out[i].HourlyCost > out[j].HourlyCost
+		}
+		return out[i].Namespace < out[j].Namespace
+	})
+	return out
+}
+
+// openCostAllocation is the subset of the OpenCost /allocation/compute response kopilot reads.
+type openCostAllocation struct {
+	Code int `json:"code"`
+	Data []map[string]struct {
+		TotalCost float64 `json:"totalCost"`
+	} `json:"data"`
+	Message string `json:"message"`
+}
+
+// parseOpenCostAllocation returns hourly cost per namespace and the idle cost
+// from a one-day, namespace-aggregated OpenCost allocation response.
+func parseOpenCostAllocation(body []byte) (map[string]float64, float64, error) {
+	var resp openCostAllocation
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse OpenCost response: %w", err)
+	}
+	if resp.Code != 200 || len(resp.Data) == 0 {
+		return nil, 0, fmt.Errorf("OpenCost returned code %d: %s", resp.Code, resp.Message)
+	}
+	byNS := make(map[string]float64)
+	var idle float64
+	for name, alloc := range resp.Data[0] {
+		hourly := alloc.TotalCost / 24
+		if name == openCostIdleKey {
+			idle = hourly
+			continue
+		}
+		byNS[name] = hourly
+	}
+	return byNS, idle, nil
+}
+
+// fetchOpenCostAllocation queries OpenCost through the API server service proxy.
+func fetchOpenCostAllocation(ctx context.Context, clientset kubernetes.Interface, src *OpenCostSource) ([]byte, error) {
+	return clientset.CoreV1().Services(src.Namespace).
+		ProxyGet("http", src.Service, fmt.Sprint(src.Port), "/allocation/compute", map[string]string{
+			"window":     "1d",
+			"aggregate":  "namespace",
+			"accumulate": "true",
+		}).DoRaw(ctx)
+}
+
+// estimateClusterCost lists nodes and pods and prices them with table. When
+// OpenCost is configured and answers, its namespace costs replace the
+// request-based attribution; otherwise the failure is kept as a warning.
+func estimateClusterCost(ctx context.Context, clientset kubernetes.Interface, table *PriceTable) (*CostEstimate, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	estimate := buildCostEstimate(nodes.Items, pods.Items, table)
+
+	if table.OpenCost != nil {
+		body, err := fetchOpenCostAllocation(ctx, clientset, table.OpenCost)
+		if err == nil {
+			var byNS map[string]float64
+			var idle float64
+			if byNS, idle, err = parseOpenCostAllocation(body); err == nil {
+				total := idle
+				for _, v := range byNS {
+					total += v
+				}
+				estimate.Source = "opencost"
+				estimate.HourlyCost = total
+				estimate.MonthlyCost = total * HoursPerMonth
+				estimate.IdleHourlyCost = idle
+				estimate.Namespaces = namespaceCosts(byNS, total)
+			}
+		}
+		if err != nil {
+			estimate.OpenCostWarning = fmt.Sprintf("OpenCost unavailable, using the price table: %v", err)
+		}
+	}
+	return estimate, nil
+}
@@ -0,0 +1,173 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	restclient "k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// proxyResponse is a canned service proxy response.
+type proxyResponse struct {
+	body []byte
+	err  error
+}
+
+func (r proxyResponse) DoRaw(context.Context) ([]byte, error)         { return r.body, r.err }
+func (r proxyResponse) Stream(context.Context) (io.ReadCloser, error) { return nil, r.err }
+
+func withOpenCost(clientset *fake.Clientset, resp proxyResponse) {
+	clientset.AddProxyReactor("services", func(k8stesting.Action) (bool, restclient.ResponseWrapper, error) {
+		return true, resp, nil
+	})
+}
+
+func pricedNode(name, instanceType, cpu, memory string) *corev1.Node {
+	node := capacityNode(name, cpu, memory, 110)
+	if instanceType != "" {
+		node.Labels[corev1.LabelInstanceTypeStable] = instanceType
+	}
+	return node
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestLoadPriceTable(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	table, err := LoadPriceTable(write("ok.json", `{"instance_types":{"m5.large":0.096},"opencost":{"namespace":"opencost","service":"opencost"}}`))
+	if err != nil {
+		t.Fatalf("LoadPriceTable: %v", err)
+	}
+	if table.Currency != "USD" || table.InstanceTypes["m5.large"] != 0.096 || table.OpenCost.Port != 9003 {
+		t.Errorf("table = %+v, want USD defaults and the OpenCost port", table)
+	}
+
+	for name, content := range map[string]string{
+		"negative.json": `{"instance_types":{"m5.large":-1}}`,
+		"opencost.json": `{"opencost":{"service":"opencost"}}`,
+		"broken.json":   `{`,
+	} {
+		if _, err := LoadPriceTable(write(name, content)); err == nil {
+			t.Errorf("LoadPriceTable(%s) succeeded, want an error", name)
+		}
+	}
+	if _, err := LoadPriceTable(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadPriceTable(missing) succeeded, want an error")
+	}
+}
+
+func TestBuildCostEstimate(t *testing.T) {
+	table := &PriceTable{Currency: "EUR", InstanceTypes: map[string]float64{"m5.xlarge": 0.2}, CPUCoreHour: 0.03, MemoryGiBHour: 0.005}
+	nodes := []corev1.Node{
+		*pricedNode("a", "m5.xlarge", "4", "16Gi"),
+		*pricedNode("b", "custom", "2", "8Gi"),
+	}
+	pods := []corev1.Pod{
+		*capacityPod("web", "a", "2", "8Gi", "", ""), // half of node a
+		*capacityPod("batch", "b", "2", "8Gi", "", ""),
+		*capacityPod("pending", "", "8", "", "", ""),
+	}
+	pods[1].Namespace = "jobs"
+
+	estimate := buildCostEstimate(nodes, pods, table)
+	// b is priced by resources: 2 cores * 0.03 + 8 GiB * 0.005 = 0.1
+	if !approxEqual(estimate.HourlyCost, 0.3) || !approxEqual(estimate.MonthlyCost, 0.3*HoursPerMonth) {
+		t.Errorf("HourlyCost = %v, MonthlyCost = %v, want 0.3 and 219", estimate.HourlyCost, estimate.MonthlyCost)
+	}
+	if estimate.Nodes[1].PricedBy != "resources" || estimate.Nodes[0].PricedBy != "instance-type" {
+		t.Errorf("Nodes = %+v", estimate.Nodes)
+	}
+	if len(estimate.Namespaces) != 2 || estimate.Namespaces[0].Namespace != "apps" || !approxEqual(estimate.Namespaces[0].HourlyCost, 0.1) {
+		t.Errorf("Namespaces = %+v, want apps (half of a) first", estimate.Namespaces)
+	}
+	if !approxEqual(estimate.IdleHourlyCost, 0.1) {
+		t.Errorf("IdleHourlyCost = %v, want the unrequested half of node a", estimate.IdleHourlyCost)
+	}
+
+	unpriced := buildCostEstimate(nodes, nil, &PriceTable{Currency: "USD"})
+	if len(unpriced.UnpricedNodes) != 2 || unpriced.HourlyCost != 0 {
+		t.Errorf("UnpricedNodes = %v, HourlyCost = %v, want both nodes unpriced", unpriced.UnpricedNodes, unpriced.HourlyCost)
+	}
+}
+
+func TestBuildCostEstimateOvercommitted(t *testing.T) {
+	table := &PriceTable{Currency: "USD", InstanceTypes: map[string]float64{"small": 1}}
+	nodes := []corev1.Node{*pricedNode("a", "small", "1", "1Gi")}
+	pods := []corev1.Pod{*capacityPod("a", "a", "2", "2Gi", "", ""), *capacityPod("b", "a", "2", "2Gi", "", "")}
+	pods[1].Namespace = "other"
+
+	estimate := buildCostEstimate(nodes, pods, table)
+	if !approxEqual(estimate.IdleHourlyCost, 0) || !approxEqual(estimate.Namespaces[0].HourlyCost+estimate.Namespaces[1].HourlyCost, 1) {
+		t.Errorf("estimate = %+v, want the node cost split without exceeding it", estimate)
+	}
+}
+
+func TestParseOpenCostAllocation(t *testing.T) {
+	body := []byte(`{"code":200,"data":[{"shop":{"totalCost":24},"__idle__":{"totalCost":12}}]}`)
+	byNS, idle, err := parseOpenCostAllocation(body)
+	if err != nil {
+		t.Fatalf("parseOpenCostAllocation: %v", err)
+	}
+	if byNS["shop"] != 1 || idle != 0.5 || len(byNS) != 1 {
+		t.Errorf("byNS = %v, idle = %v, want shop 1/h and idle 0.5/h", byNS, idle)
+	}
+
+	if _, _, err := parseOpenCostAllocation([]byte(`{"code":400,"message":"bad window"}`)); err == nil || !strings.Contains(err.Error(), "bad window") {
+		t.Errorf("err = %v, want the OpenCost message", err)
+	}
+}
+
+func TestEstimateClusterCostOpenCost(t *testing.T) {
+	objects := []runtime.Object{pricedNode("a", "m5.large", "2", "8Gi"), capacityPod("web", "a", "1", "4Gi", "", "")}
+	table := &PriceTable{Currency: "USD", InstanceTypes: map[string]float64{"m5.large": 0.1}, OpenCost: &OpenCostSource{Namespace: "opencost", Service: "opencost", Port: 9003}}
+
+	clientset := fake.NewClientset(objects...)
+	withOpenCost(clientset, proxyResponse{body: []byte(`{"code":200,"data":[{"apps":{"totalCost":4.8},"__idle__":{"totalCost":2.4}}]}`)})
+	estimate, err := estimateClusterCost(context.Background(), clientset, table)
+	if err != nil {
+		t.Fatalf("estimateClusterCost: %v", err)
+	}
+	if estimate.Source != "opencost" || !approxEqual(estimate.HourlyCost, 0.3) || !approxEqual(estimate.IdleHourlyCost, 0.1) {
+		t.Errorf("estimate = %+v, want OpenCost totals", estimate)
+	}
+	if estimate.Namespaces[0].Percent != 66.7 {
+		t.Errorf("Namespaces = %+v, want apps at 66.7%%", estimate.Namespaces)
+	}
+}
+
+func TestEstimateClusterCostOpenCostFallback(t *testing.T) {
+	clientset := fake.NewClientset(pricedNode("a", "m5.large", "2", "8Gi"), capacityPod("web", "a", "1", "4Gi", "", ""))
+	withOpenCost(clientset, proxyResponse{err: errors.New("service unavailable")})
+	table := &PriceTable{Currency: "USD", InstanceTypes: map[string]float64{"m5.large": 0.1}, OpenCost: &OpenCostSource{Namespace: "opencost", Service: "opencost", Port: 9003}}
+
+	estimate, err := estimateClusterCost(context.Background(), clientset, table)
+	if err != nil {
+		t.Fatalf("estimateClusterCost: %v", err)
+	}
+	if estimate.Source != "price-table" || estimate.OpenCostWarning == "" {
+		t.Errorf("estimate = %+v, want the price table with an OpenCost warning", estimate)
+	}
+	if !approxEqual(estimate.Namespaces[0].HourlyCost, 0.05) {
+		t.Errorf("Namespaces = %+v, want apps at 0.05/h", estimate.Namespaces)
+	}
+}
@@ -0,0 +1,55 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronScheduleNext(t *testing.T) {
+	from := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC) // a Friday
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 10, 16, 9, 45, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 2 * * mon-wed", time.Date(2026, 10, 19, 2, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"30 9 1,16 * *", time.Date(2026, 11, 1, 9, 30, 0, 0, time.UTC)},
+		// Both day fields restricted: either one matches.
+		{"0 12 1 * fri", time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"5/20 8-10 * * *", time.Date(2026, 10, 16, 9, 45, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		s, loc, err := parseCronSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("parseCronSchedule(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := s.next(from.In(loc)); !got.Equal(tt.want) {
+			t.Errorf("next(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseCronScheduleTimeZone(t *testing.T) {
+	s, loc, err := parseCronSchedule("CRON_TZ=Asia/Kolkata 0 9 * * *")
+	if err != nil {
+		t.Fatalf("parseCronSchedule: %v", err)
+	}
+	got := s.next(time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC).In(loc))
+	if want := time.Date(2026, 10, 16, 3, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("next = %v, want %v", got, want)
+	}
+}
+
+func TestParseCronScheduleErrors(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "* * * * funday", "TZ=Mars/Base * * * * *"} {
+		if _, _, err := parseCronSchedule(spec); err == nil {
+			t.Errorf("parseCronSchedule(%q) succeeded, want an error", spec)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains CRD discovery and browsing of arbitrary custom resources.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// MaxCustomResources bounds the instances listed by ListCustomResources.
+const MaxCustomResources = 200
+
+var crdResource = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+// CRDInfo describes one CustomResourceDefinition.
+type CRDInfo struct {
+	Name       string   `json:"name"` // <plural>.<group>
+	Group      string   `json:"group"`
+	Kind       string   `json:"kind"`
+	Plural     string   `json:"plural"`
+	Singular   string   `json:"singular,omitempty"`
+	ShortNames []string `json:"short_names,omitempty"`
+	Namespaced bool     `json:"namespaced"`
+	// Version is the version read: the storage version, or the first served one.
+	Version  string   `json:"version"`
+	Versions []string `json:"versions"`
+}
+
+// GVR returns the resource of the CRD in its read version.
+func (c CRDInfo) GVR() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: c.Group, Version: c.Version, Resource: c.Plural}
+}
+
+// ResourceCondition is one entry of status.conditions.
+type ResourceCondition struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"`
+	Reason             string    `json:"reason,omitempty"`
+	Message            string    `json:"message,omitempty"`
+	LastTransitionTime time.Time `json:"last_transition_time,omitzero"`
+}
+
+// CustomResource summarises one custom resource instance.
+type CustomResource struct {
+	Namespace  string              `json:"namespace,omitempty"`
+	Name       string              `json:"name"`
+	Created    time.Time           `json:"created"`
+	Conditions []ResourceCondition `json:"conditions,omitempty"`
+	// Phase is status.phase or status.state when the resource reports one.
+	Phase string `json:"phase,omitempty"`
+}
+
+// CustomResourceList lists the instances of one CRD.
+type CustomResourceList struct {
+	Context   string           `json:"context"`
+	CRD       CRDInfo          `json:"crd"`
+	Items     []CustomResource `json:"items"`
+	Truncated bool             `json:"truncated,omitempty"`
+}
+
+// ListCRDs lists the CRDs of a cluster, optionally only those whose group
+// contains groupFilter.
+func (p *Provider) ListCRDs(ctx context.Context, contextName, groupFilter string) ([]CRDInfo, error) {
+	client, err := p.createDynamicClient(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	crds, err := listCRDs(queryCtx, client)
+	if err != nil {
+		return nil, err
+	}
+	if groupFilter == "" {
+		return crds, nil
+	}
+	filtered := []CRDInfo{}
+	for _, c := range crds {
+		if strings.Contains(c.Group, strings.ToLower(groupFilter)) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered, nil
+}
+
+// ListCustomResources lists the instances of the custom resource named by
+// resource (plural, singular, kind, short name or <plural>.<group>) in
+// namespace, all namespaces when empty.
+func (p *Provider) ListCustomResources(ctx context.Context, contextName, resource, namespace string) (*CustomResourceList, error) {
+	client, err := p.createDynamicClient(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	list, err := listCustomResources(queryCtx, client, resource, namespace)
+	if err != nil {
+		return nil, err
+	}
+	list.Context = contextName
+	return list, nil
+}
+
+// GetCustomResource returns one custom resource with its CRD.
+func (p *Provider) GetCustomResource(ctx context.Context, contextName, resource, namespace, name string) (*CRDInfo, *unstructured.Unstructured, error) {
+	client, err := p.createDynamicClient(contextName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	return getCustomResource(queryCtx, client, resource, namespace, name)
+}
+
+func listCRDs(ctx context.Context, client dynamic.Interface) ([]CRDInfo, error) {
+	list, err := client.Resource(crdResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list custom resource definitions: %w", err)
+	}
+	crds := make([]CRDInfo, 0, len(list.Items))
+	for i := range list.Items {
+		if crd, ok := crdInfo(&list.Items[i]); ok {
+			crds = append(crds, crd)
+		}
+	}
+	sort.Slice(crds, func(i, j int) bool { return crds[i].Name < crds[j].Name })
+	return crds, nil
+}
+
+// crdInfo reads a CRD; ok is false when it serves no version.
+func crdInfo(obj *unstructured.Unstructured) (CRDInfo, bool) {
+	crd := CRDInfo{
+		Name:       obj.GetName(),
+		Group:      nestedString(obj.Object, "spec", "group"),
+		Kind:       nestedString(obj.Object, "spec", "names", "kind"),
+		Plural:     nestedString(obj.Object, "spec", "names", "plural"),
+		Singular:   nestedString(obj.Object, "spec", "names", "singular"),
+		Namespaced: nestedString(obj.Object, "spec", "scope") == "Namespaced",
+		Versions:   []string{},
+	}
+	crd.ShortNames, _, _ = unstructured.NestedStringSlice(obj.Object, "spec", "names", "shortNames")
+
+	versions, _, _ := unstructured.NestedSlice(obj.Object, "spec", "versions")
+	for _, v := range versions {
+		version, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		if served, _, _ := unstructured.NestedBool(version, "served"); !served {
+			continue
+		}
+		name := nestedString(version, "name")
+		crd.Versions = append(crd.Versions, name)
+		if storage, _, _ := unstructured.NestedBool(version, "storage"); storage || crd.Version == "" {
+			crd.Version = name
+		}
+	}
+	return crd, crd.Version != ""
+}
+
+// resolveCRD finds the CRD named by resource the way kubectl resolves
+// resource names.
+func resolveCRD(ctx context.Context, client dynamic.Interface, resource string) (CRDInfo, error) {
+	crds, err := listCRDs(ctx, client)
+	if err != nil {
+		return CRDInfo{}, err
+	}
+	want := strings.ToLower(resource)
+	var matches []CRDInfo
+	for _, c := range crds {
+		names := append([]string{c.Name, c.Plural, c.Singular, strings.ToLower(c.Kind)}, c.ShortNames...)
+		for _, n := range names {
+			if n != "" && n == want {
+				matches = append(matches, c)
+				break
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return CRDInfo{}, fmt.Errorf("no custom resource definition matches %q; list the CRDs to see what is installed", resource)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		for i, m := range matches {
+			names[i] = m.Name
+		}
+		return CRDInfo{}, fmt.Errorf("%q is ambiguous, use one of: %s", resource, strings.Join(names, ", "))
+	}
+}
+
+func listCustomResources(ctx context.Context, client dynamic.Interface, resource, namespace string) (*CustomResourceList, error) {
+	crd, err := resolveCRD(ctx, client, resource)
+	if err != nil {
+		return nil, err
+	}
+	ri := client.Resource(crd.GVR())
+	var list *unstructured.UnstructuredList
+	if crd.Namespaced {
+		list, err = ri.Namespace(namespace).List(ctx, metav1.ListOptions{})
+	} else {
+		list, err = ri.List(ctx, metav1.ListOptions{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", crd.Name, err)
+	}
+
+	result := &CustomResourceList{CRD: crd, Items: []CustomResource{}}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		result.Items = append(result.Items, CustomResource{
+			Namespace:  obj.GetNamespace(),
+			Name:       obj.GetName(),
+			Created:    obj.GetCreationTimestamp().Time,
+			Conditions: ResourceConditions(obj),
+			Phase:      stringOr(nestedString(obj.Object, "status", "phase"), nestedString(obj.Object, "status", "state")),
+		})
+	}
+	sort.Slice(result.Items, func(i, j int) bool {
+		a, b := result.Items[i], result.Items[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	if len(result.Items) > MaxCustomResources {
+		result.Items, result.Truncated = result.Items[:MaxCustomResources], true
+	}
+	return result, nil
+}
+
+func getCustomResource(ctx context.Context, client dynamic.Interface, resource, namespace, name string) (*CRDInfo, *unstructured.Unstructured, error) {
+	crd, err := resolveCRD(ctx, client, resource)
+	if err != nil {
+		return nil, nil, err
+	}
+	ri := client.Resource(crd.GVR())
+	var obj *unstructured.Unstructured
+	if crd.Namespaced {
+		if namespace == "" {
+			return nil, nil, fmt.Errorf("%s is namespaced: namespace is required", crd.Name)
+		}
+		obj, err = ri.Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	} else {
+		obj, err = ri.Get(ctx, name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get %s %s: %w", crd.Name, name, err)
+	}
+	return &crd, obj, nil
+}
+
+// ResourceConditions reads status.conditions in the metav1.Condition shape
+// most controllers use.
+func ResourceConditions(obj *unstructured.Unstructured) []ResourceCondition {
+	raw, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	var conditions []ResourceCondition
+	for _, c := range raw {
+		m, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		cond := ResourceCondition{
+			Type:    nestedString(m, "type"),
+			Status:  nestedString(m, "status"),
+			Reason:  nestedString(m, "reason"),
+			Message: nestedString(m, "message"),
+		}
+		if t, err := time.Parse(time.RFC3339, nestedString(m, "lastTransitionTime")); err == nil {
+			cond.LastTransitionTime = t
+		}
+		conditions = append(conditions, cond)
+	}
+	return conditions
+}
@@ -0,0 +1,121 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func testCRD(group, kind, plural, scope string, shortNames []any, versions ...map[string]any) *unstructured.Unstructured {
+	vs := make([]any, len(versions))
+	for i, v := range versions {
+		vs[i] = v
+	}
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]any{"name": plural + "." + group},
+		"spec": map[string]any{
+			"group":    group,
+			"scope":    scope,
+			"names":    map[string]any{"kind": kind, "plural": plural, "singular": strings.ToLower(kind), "shortNames": shortNames},
+			"versions": vs,
+		},
+	}}
+}
+
+func newCustomResourceClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		crdResource: "CustomResourceDefinitionList",
+		{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}:        "CertificateList",
+		{Group: "cert-manager.io", Version: "v1", Resource: "clusterissuers"}:      "ClusterIssuerList",
+		{Group: "networking.example.com", Version: "v2", Resource: "certificates"}: "CertificateList",
+	}, objects...)
+}
+
+func testCertificate(namespace, name, ready string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "Certificate",
+		"metadata":   map[string]any{"name": name, "namespace": namespace, "creationTimestamp": "2024-05-01T10:00:00Z"},
+		"spec":       map[string]any{"secretName": name + "-tls"},
+		"status": map[string]any{"conditions": []any{map[string]any{
+			"type": "Ready", "status": ready, "reason": "Issued", "message": "certificate is up to date", "lastTransitionTime": "2024-05-01T10:05:00Z",
+		}}},
+	}}
+}
+
+func TestCRDInfo(t *testing.T) {
+	crd, ok := crdInfo(testCRD("example.com", "Widget", "widgets", "Cluster", nil,
+		map[string]any{"name": "v1beta1", "served": true, "storage": false},
+		map[string]any{"name": "v1", "served": true, "storage": true},
+		map[string]any{"name": "v1alpha1", "served": false}))
+	if !ok || crd.Version != "v1" || strings.Join(crd.Versions, ",") != "v1beta1,v1" || crd.Namespaced || crd.Name != "widgets.example.com" {
+		t.Errorf("crdInfo = %+v, %v", crd, ok)
+	}
+	if _, ok := crdInfo(testCRD("example.com", "Old", "olds", "Cluster", nil, map[string]any{"name": "v1", "served": false})); ok {
+		t.Error("CRD without served versions should be skipped")
+	}
+}
+
+func TestListCustomResources(t *testing.T) {
+	v1 := map[string]any{"name": "v1", "served": true, "storage": true}
+	client := newCustomResourceClient(
+		testCRD("cert-manager.io", "Certificate", "certificates", "Namespaced", []any{"cert", "certs"}, v1),
+		testCRD("cert-manager.io", "ClusterIssuer", "clusterissuers", "Cluster", nil, v1),
+		testCRD("networking.example.com", "Certificate", "certificates", "Namespaced", nil, map[string]any{"name": "v2", "served": true, "storage": true}),
+		testCertificate("shop", "web", "True"),
+		testCertificate("shop", "api", "False"),
+		testCertificate("blog", "www", "True"),
+	)
+
+	crds, err := listCRDs(context.Background(), client)
+	if err != nil || len(crds) != 3 || crds[0].Name != "certificates.cert-manager.io" {
+		t.Fatalf("listCRDs = %+v, %v", crds, err)
+	}
+
+	list, err := listCustomResources(context.Background(), client, "certificates.cert-manager.io", "shop")
+	if err != nil {
+		t.Fatalf("listCustomResources: %v", err)
+	}
+	if len(list.Items) != 2 || list.Items[0].Name != "api" || list.Items[0].Conditions[0].Status != "False" ||
+		list.Items[0].Conditions[0].LastTransitionTime.IsZero() || list.Items[0].Created.IsZero() {
+		t.Errorf("unexpected list: %+v", list.Items)
+	}
+
+	if list, err := listCustomResources(context.Background(), client, "cert", ""); err != nil || len(list.Items) != 3 {
+		t.Errorf("short name, all namespaces = %+v, %v", list, err)
+	}
+	if _, err := listCustomResources(context.Background(), client, "certificate", ""); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("expected ambiguity error, got %v", err)
+	}
+	if _, err := listCustomResources(context.Background(), client, "widgets", ""); err == nil || !strings.Contains(err.Error(), "no custom resource definition") {
+		t.Errorf("expected unknown resource error, got %v", err)
+	}
+}
+
+func TestGetCustomResource(t *testing.T) {
+	v1 := map[string]any{"name": "v1", "served": true, "storage": true}
+	client := newCustomResourceClient(
+		testCRD("cert-manager.io", "Certificate", "certificates", "Namespaced", []any{"cert"}, v1),
+		testCertificate("shop", "web", "True"),
+	)
+	crd, obj, err := getCustomResource(context.Background(), client, "cert", "shop", "web")
+	if err != nil || crd.Kind != "Certificate" || obj.GetName() != "web" {
+		t.Fatalf("getCustomResource = %+v, %v, %v", crd, obj, err)
+	}
+	if conditions := ResourceConditions(obj); len(conditions) != 1 || conditions[0].Reason != "Issued" {
+		t.Errorf("conditions = %+v", conditions)
+	}
+	if _, _, err := getCustomResource(context.Background(), client, "cert", "", "web"); err == nil || !strings.Contains(err.Error(), "namespace is required") {
+		t.Errorf("expected namespace error, got %v", err)
+	}
+	if _, _, err := getCustomResource(context.Background(), client, "cert", "shop", "missing"); err == nil {
+		t.Error("expected not found error")
+	}
+}
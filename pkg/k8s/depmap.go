@@ -0,0 +1,589 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains best-effort service dependency mapping (who talks to whom).
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Dependency edge kinds. Every edge reads "From depends on To".
+const (
+	DependencyCalls  = "calls"  // workload references a service address (env, ConfigMap, ExternalName)
+	DependencyRoutes = "routes" // service selects the workload's pods
+	DependencyAllows = "allows" // a NetworkPolicy admits traffic from From to To
+)
+
+// clusterSetDomain is the Multi-Cluster Services DNS zone; references to it
+// can only be resolved by mapping the other clusters too.
+const clusterSetDomain = "clusterset.local"
+
+// addressEnvSuffixes mark environment variables that hold a host or URL, so a
+// bare service name in their value is treated as a reference.
+var addressEnvSuffixes = []string{"_HOST", "_HOSTNAME", "_ADDR", "_ADDRESS", "_URL", "_URI", "_ENDPOINT", "_ENDPOINTS", "_SERVER", "_SERVERS", "_SERVICE", "_DSN", "_BROKERS"}
+
+var (
+	// urlNoiseRe matches URL schemes, userinfo and paths, which are not hosts.
+	urlNoiseRe = regexp.MustCompile(`[a-z][a-z0-9+.-]*://|[^\s/@,;]*@|/[^\s,;]*`)
+	// hostTokenRe finds host-like tokens in lowercased values.
+	hostTokenRe = regexp.MustCompile(`[a-z0-9][a-z0-9.-]*`)
+)
+
+// DependencyNode is a workload or service in the dependency map.
+type DependencyNode struct {
+	ID        string `json:"id"`
+	Context   string `json:"context,omitempty"`
+	Kind      string `json:"kind"` // Deployment, StatefulSet, DaemonSet, CronJob or Service
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Missing   bool   `json:"missing,omitempty"` // referenced service that does not exist
+}
+
+// DependencyEdge is one inferred dependency; Evidence says why it was inferred.
+type DependencyEdge struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Kind     string   `json:"kind"`
+	Evidence []string `json:"evidence"`
+}
+
+// CrossClusterRef is a clusterset.local reference that may be served by
+// another cluster.
+type CrossClusterRef struct {
+	From      string `json:"from"`
+	Namespace string `json:"namespace"`
+	Service   string `json:"service"`
+	Evidence  string `json:"evidence"`
+}
+
+// DependencyMap is the outcome of GetDependencyMap.
+type DependencyMap struct {
+	Contexts         []string          `json:"contexts"`
+	Namespace        string            `json:"namespace,omitempty"`
+	Nodes            []DependencyNode  `json:"nodes"`
+	Edges            []DependencyEdge  `json:"edges"`
+	CrossClusterRefs []CrossClusterRef `json:"cross_cluster_refs,omitempty"`
+}
+
+// dependencyWorkload is a pod-template owning workload.
+type dependencyWorkload struct {
+	kind, namespace, name string
+	labels                map[string]string // pod template labels
+	spec                  corev1.PodSpec
+}
+
+// dependencyData holds the objects buildDependencyMap needs.
+type dependencyData struct {
+	workloads       []dependencyWorkload
+	services        []corev1.Service
+	configMaps      map[string]map[string]string // namespace/name -> data
+	policies        []networkingv1.NetworkPolicy
+	namespaceLabels map[string]map[string]string
+}
+
+// serviceHostRef is a service address found in a value.
+type serviceHostRef struct {
+	namespace, name string
+	qualified       bool   // written with .svc, so it must be a service
+	domain          string // cluster domain after .svc, possibly empty
+}
+
+// dependencyNodeID builds the node ID for kind namespace/name.
+func dependencyNodeID(kind, namespace, name string) string {
+	return strings.ToLower(kind) + "/" + namespace + "/" + name
+}
+
+// isAddressEnv reports whether an environment variable name suggests an address.
+func isAddressEnv(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, suffix := range addressEnvSuffixes {
+		if strings.HasSuffix(upper, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseServiceHosts finds service addresses in value: name.namespace,
+// name.namespace.svc[.<domain>], and bare names when bare is set (the value
+// is known to be an address). namespace is the namespace of the referrer.
+func parseServiceHosts(value, namespace string, bare bool) []serviceHostRef {
+	var refs []serviceHostRef
+	for _, token := range hostTokenRe.FindAllString(urlNoiseRe.ReplaceAllString(strings.ToLower(value), " "), -1) {
+		parts := strings.Split(strings.Trim(token, ".-"), ".")
+		switch {
+		case len(parts) == 1 && parts[0] != "":
+			if bare {
+				refs = append(refs, serviceHostRef{namespace: namespace, name: parts[0]})
+			}
+		case len(parts) == 2:
+			refs = append(refs, serviceHostRef{namespace: parts[1], name: parts[0]})
+		case len(parts) >= 3 && parts[2] == "svc":
+			refs = append(refs, serviceHostRef{namespace: parts[1], name: parts[0], qualified: true, domain: strings.Join(parts[3:], ".")})
+		}
+	}
+	return refs
+}
+
+// dependencyEnv is one environment value and where it came from.
+type dependencyEnv struct {
+	name, value, source string
+}
+
+// workloadEnv resolves the literal and ConfigMap-sourced environment of every
+// container. Secret references are never read.
+func workloadEnv(w dependencyWorkload, configMaps map[string]map[string]string) []dependencyEnv {
+	var out []dependencyEnv
+	containers := append(append([]corev1.Container{}, w.spec.InitContainers...), w.spec.Containers...)
+	for _, c := range containers {
+		for _, from := range c.EnvFrom {
+			if from.ConfigMapRef == nil {
+				continue
+			}
+			for key, value := range configMaps[w.namespace+"/"+from.ConfigMapRef.Name] {
+				out = append(out, dependencyEnv{from.Prefix + key, value, "configmap " + from.ConfigMapRef.Name + ": " + key})
+			}
+		}
+		for _, env := range c.Env {
+			switch {
+			case env.ValueFrom == nil:
+				out = append(out, dependencyEnv{env.Name, env.Value, "env " + env.Name})
+			case env.ValueFrom.ConfigMapKeyRef != nil:
+				ref := env.ValueFrom.ConfigMapKeyRef
+				if value, ok := configMaps[w.namespace+"/"+ref.Name][ref.Key]; ok {
+					out = append(out, dependencyEnv{env.Name, value, "configmap " + ref.Name + ": " + ref.Key})
+				}
+			}
+		}
+	}
+	return out
+}
+
+// dependencyGraph accumulates nodes and deduplicated edges.
+type dependencyGraph struct {
+	nodes map[string]*DependencyNode
+	edges map[[3]string]*DependencyEdge
+	refs  []CrossClusterRef
+}
+
+func (g *dependencyGraph) addNode(kind, namespace, name string) string {
+	id := dependencyNodeID(kind, namespace, name)
+	if g.nodes[id] == nil {
+		g.nodes[id] = &DependencyNode{ID: id, Kind: kind, Namespace: namespace, Name: name}
+	}
+	return id
+}
+
+func (g *dependencyGraph) addEdge(from, to, kind, evidence string) {
+	if from == to {
+		return
+	}
+	key := [3]string{from, to, kind}
+	e := g.edges[key]
+	if e == nil {
+		e = &DependencyEdge{From: from, To: to, Kind: kind}
+		g.edges[key] = e
+	}
+	for _, ev := range e.Evidence {
+		if ev == evidence {
+			return
+		}
+	}
+	e.Evidence = append(e.Evidence, evidence)
+}
+
+// linkServiceRefs adds calls edges from node to the services referenced in value.
+func (g *dependencyGraph) linkServiceRefs(from, value, namespace, evidence string, bare bool, services map[string]*corev1.Service) {
+	for _, ref := range parseServiceHosts(value, namespace, bare) {
+		key := ref.namespace + "/" + ref.name
+		if ref.domain == clusterSetDomain {
+			g.refs = append(g.refs, CrossClusterRef{From: from, Namespace: ref.namespace, Service: ref.name, Evidence: evidence})
+		}
+		switch {
+		case services[key] != nil:
+			g.addEdge(from, g.addNode("Service", ref.namespace, ref.name), DependencyCalls, evidence)
+		case ref.qualified && ref.domain != clusterSetDomain:
+			to := g.addNode("Service", ref.namespace, ref.name)
+			g.nodes[to].Missing = true
+			g.addEdge(from, to, DependencyCalls, evidence)
+		}
+	}
+}
+
+// isEmptySelector reports whether a selector is absent or selects everything.
+func isEmptySelector(sel *metav1.LabelSelector) bool {
+	return sel == nil || (len(sel.MatchLabels) == 0 && len(sel.MatchExpressions) == 0)
+}
+
+// linkNetworkPolicy adds allows edges between the workloads a policy selects
+// and the workloads its rules name. Rules admitting everyone, ipBlocks and
+// peers with only empty selectors say nothing about dependencies and are skipped.
+func (g *dependencyGraph) linkNetworkPolicy(policy *networkingv1.NetworkPolicy, data dependencyData, inScope func(namespace string) bool) {
+	sel, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+	if err != nil || !inScope(policy.Namespace) {
+		return
+	}
+	peerTargets := func(peers []networkingv1.NetworkPolicyPeer) []*dependencyWorkload {
+		var out []*dependencyWorkload
+		for i := range data.workloads {
+			w := &data.workloads[i]
+			if IsSystemNamespace(w.namespace) && !inScope(w.namespace) {
+				continue
+			}
+			peer := policyPeer{namespace: w.namespace, labels: w.labels, namespaceLabels: data.namespaceLabels[w.namespace]}
+			for _, p := range peers {
+				if p.IPBlock != nil || (isEmptySelector(p.PodSelector) && isEmptySelector(p.NamespaceSelector)) {
+					continue
+				}
+				if matched, _ := peerMatches([]networkingv1.NetworkPolicyPeer{p}, policy.Namespace, peer); matched {
+					out = append(out, w)
+					break
+				}
+			}
+		}
+		return out
+	}
+
+	for i := range data.workloads {
+		subject := &data.workloads[i]
+		if subject.namespace != policy.Namespace || !sel.Matches(labels.Set(subject.labels)) {
+			continue
+		}
+		subjectID := g.addNode(subject.kind, subject.namespace, subject.name)
+		if policyHasType(policy, networkingv1.PolicyTypeIngress) {
+			for _, rule := range policy.Spec.Ingress {
+				for _, peer := range peerTargets(rule.From) {
+					g.addEdge(g.addNode(peer.kind, peer.namespace, peer.name), subjectID, DependencyAllows, "networkpolicy "+policy.Name+" (ingress)")
+				}
+			}
+		}
+		if policyHasType(policy, networkingv1.PolicyTypeEgress) {
+			for _, rule := range policy.Spec.Egress {
+				for _, peer := range peerTargets(rule.To) {
+					g.addEdge(subjectID, g.addNode(peer.kind, peer.namespace, peer.name), DependencyAllows, "networkpolicy "+policy.Name+" (egress)")
+				}
+			}
+		}
+	}
+}
+
+// buildDependencyMap infers the dependency graph of the workloads and
+// services in scope from environment variables, ConfigMaps, ExternalName
+// services, Service selectors and NetworkPolicies.
+func buildDependencyMap(data dependencyData, targetNamespace string, includeSystem bool) *DependencyMap {
+	g := &dependencyGraph{nodes: make(map[string]*DependencyNode), edges: make(map[[3]string]*DependencyEdge)}
+	inScope := func(ns string) bool { return shouldScanNamespace(ns, targetNamespace, includeSystem) }
+
+	services := make(map[string]*corev1.Service, len(data.services))
+	for i := range data.services {
+		svc := &data.services[i]
+		services[svc.Namespace+"/"+svc.Name] = svc
+	}
+
+	for _, w := range data.workloads {
+		if !inScope(w.namespace) {
+			continue
+		}
+		id := g.addNode(w.kind, w.namespace, w.name)
+		for _, env := range workloadEnv(w, data.configMaps) {
+			bare := isAddressEnv(env.name) || strings.Contains(env.value, "://")
+			g.linkServiceRefs(id, env.value, w.namespace, env.source, bare, services)
+		}
+	}
+	for i := range data.services {
+		svc := &data.services[i]
+		if !inScope(svc.Namespace) {
+			continue
+		}
+		id := g.addNode("Service", svc.Namespace, svc.Name)
+		if svc.Spec.Type == corev1.ServiceTypeExternalName {
+			g.linkServiceRefs(id, svc.Spec.ExternalName, svc.Namespace, "externalName "+svc.Spec.ExternalName, false, services)
+		}
+	}
+	for i := range data.policies {
+		g.linkNetworkPolicy(&data.policies[i], data, inScope)
+	}
+
+	// Services in the map route to the workloads their selector picks.
+	for _, node := range g.nodes {
+		svc := services[node.Namespace+"/"+node.Name]
+		if node.Kind != "Service" || svc == nil || len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		sel := labels.SelectorFromSet(svc.Spec.Selector)
+		for _, w := range data.workloads {
+			if w.namespace == svc.Namespace && sel.Matches(labels.Set(w.labels)) {
+				g.addEdge(node.ID, g.addNode(w.kind, w.namespace, w.name), DependencyRoutes, "selector "+labels.Set(svc.Spec.Selector).String())
+			}
+		}
+	}
+
+	m := &DependencyMap{Namespace: targetNamespace, CrossClusterRefs: g.refs}
+	for _, n := range g.nodes {
+		m.Nodes = append(m.Nodes, *n)
+	}
+	for _, e := range g.edges {
+		sort.Strings(e.Evidence)
+		m.Edges = append(m.Edges, *e)
+	}
+	m.sort()
+	return m
+}
+
+// sort orders nodes by ID and edges by endpoints.
+func (m *DependencyMap) sort() {
+	sort.Slice(m.Nodes, func(i, j int) bool { return m.Nodes[i].ID < m.Nodes[j].ID })
+	sort.Slice(m.Edges, func(i, j int) bool {
+		a, b := m.Edges[i], m.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Kind < b.Kind
+	})
+	sort.Slice(m.CrossClusterRefs, func(i, j int) bool {
+		a, b := m.CrossClusterRefs[i], m.CrossClusterRefs[j]
+		return a.From+a.Namespace+a.Service < b.From+b.Namespace+b.Service
+	})
+}
+
+// Node returns the node with the given ID.
+func (m *DependencyMap) Node(id string) (DependencyNode, bool) {
+	for _, n := range m.Nodes {
+		if n.ID == id {
+			return n, true
+		}
+	}
+	return DependencyNode{}, false
+}
+
+// FindNode resolves a user reference: a node ID, "kind/namespace/name" or
+// "namespace/name" (services are preferred over workloads of the same name).
+func (m *DependencyMap) FindNode(ref string) (DependencyNode, bool) {
+	if n, ok := m.Node(ref); ok {
+		return n, true
+	}
+	var found *DependencyNode
+	for i := range m.Nodes {
+		n := &m.Nodes[i]
+		if !strings.EqualFold(ref, n.Namespace+"/"+n.Name) && !strings.EqualFold(ref, strings.ToLower(n.Kind)+"/"+n.Namespace+"/"+n.Name) {
+			continue
+		}
+		if found == nil || (n.Kind == "Service" && found.Kind != "Service") {
+			found = n
+		}
+	}
+	if found == nil {
+		return DependencyNode{}, false
+	}
+	return *found, true
+}
+
+// Dependents returns the IDs of every node that directly or transitively
+// depends on id: what is impacted when id fails.
+func (m *DependencyMap) Dependents(id string) []string {
+	reverse := make(map[string][]string)
+	for _, e := range m.Edges {
+		reverse[e.To] = append(reverse[e.To], e.From)
+	}
+	seen := map[string]bool{id: true}
+	queue := []string{id}
+	var out []string
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, from := range reverse[current] {
+			if !seen[from] {
+				seen[from] = true
+				out = append(out, from)
+				queue = append(queue, from)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Focus returns the part of the map around id: the node, everything that
+// depends on it and its direct dependencies.
+func (m *DependencyMap) Focus(id string) *DependencyMap {
+	keep := map[string]bool{id: true}
+	for _, d := range m.Dependents(id) {
+		keep[d] = true
+	}
+	for _, e := range m.Edges {
+		if e.From == id {
+			keep[e.To] = true
+		}
+	}
+	out := &DependencyMap{Contexts: m.Contexts, Namespace: m.Namespace}
+	for _, n := range m.Nodes {
+		if keep[n.ID] {
+			out.Nodes = append(out.Nodes, n)
+		}
+	}
+	for _, e := range m.Edges {
+		if keep[e.From] && keep[e.To] {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	for _, r := range m.CrossClusterRefs {
+		if keep[r.From] {
+			out.CrossClusterRefs = append(out.CrossClusterRefs, r)
+		}
+	}
+	return out
+}
+
+// MergeDependencyMaps combines the maps of several clusters. Node IDs are
+// prefixed with "<context>:" and clusterset.local references are linked to
+// the matching service of the other clusters; references no mapped cluster
+// serves are kept in CrossClusterRefs.
+func MergeDependencyMaps(maps []*DependencyMap) *DependencyMap {
+	if len(maps) == 1 {
+		return maps[0]
+	}
+	merged := &DependencyMap{}
+	prefix := func(m *DependencyMap, id string) string { return m.Contexts[0] + ":" + id }
+	for _, m := range maps {
+		merged.Contexts = append(merged.Contexts, m.Contexts...)
+		if merged.Namespace == "" {
+			merged.Namespace = m.Namespace
+		}
+		for _, n := range m.Nodes {
+			n.ID = prefix(m, n.ID)
+			n.Context = m.Contexts[0]
+			merged.Nodes = append(merged.Nodes, n)
+		}
+		for _, e := range m.Edges {
+			e.From, e.To = prefix(m, e.From), prefix(m, e.To)
+			merged.Edges = append(merged.Edges, e)
+		}
+	}
+	for _, m := range maps {
+		for _, ref := range m.CrossClusterRefs {
+			resolved := false
+			for _, other := range maps {
+				target := dependencyNodeID("Service", ref.Namespace, ref.Service)
+				if other == m {
+					continue
+				}
+				if n, ok := other.Node(target); !ok || n.Missing {
+					continue
+				}
+				merged.Edges = append(merged.Edges, DependencyEdge{
+					From: prefix(m, ref.From), To: prefix(other, target), Kind: DependencyCalls,
+					Evidence: []string{ref.Evidence + " (cross-cluster)"},
+				})
+				resolved = true
+			}
+			if !resolved {
+				ref.From = prefix(m, ref.From)
+				merged.CrossClusterRefs = append(merged.CrossClusterRefs, ref)
+			}
+		}
+	}
+	merged.sort()
+	return merged
+}
+
+// collectDependencyData lists the objects buildDependencyMap needs. ConfigMaps
+// are listed only in the target namespace when one is given.
+func collectDependencyData(ctx context.Context, clientset kubernetes.Interface, targetNamespace string) (dependencyData, error) {
+	var data dependencyData
+
+	deploys, err := clientset.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return data, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, d := range deploys.Items {
+		data.workloads = append(data.workloads, dependencyWorkload{"Deployment", d.Namespace, d.Name, d.Spec.Template.Labels, d.Spec.Template.Spec})
+	}
+	stss, err := clientset.AppsV1().StatefulSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return data, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for _, s := range stss.Items {
+		data.workloads = append(data.workloads, dependencyWorkload{"StatefulSet", s.Namespace, s.Name, s.Spec.Template.Labels, s.Spec.Template.Spec})
+	}
+	dss, err := clientset.AppsV1().DaemonSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return data, fmt.Errorf("failed to list daemonsets: %w", err)
+	}
+	for _, ds := range dss.Items {
+		data.workloads = append(data.workloads, dependencyWorkload{"DaemonSet", ds.Namespace, ds.Name, ds.Spec.Template.Labels, ds.Spec.Template.Spec})
+	}
+	cjs, err := clientset.BatchV1().CronJobs("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return data, fmt.Errorf("failed to list cronjobs: %w", err)
+	}
+	for _, cj := range cjs.Items {
+		tmpl := cj.Spec.JobTemplate.Spec.Template
+		data.workloads = append(data.workloads, dependencyWorkload{"CronJob", cj.Namespace, cj.Name, tmpl.Labels, tmpl.Spec})
+	}
+
+	services, err := clientset.CoreV1().Services("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return data, fmt.Errorf("failed to list services: %w", err)
+	}
+	data.services = services.Items
+
+	configMaps, err := clientset.CoreV1().ConfigMaps(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return data, fmt.Errorf("failed to list configmaps: %w", err)
+	}
+	data.configMaps = make(map[string]map[string]string, len(configMaps.Items))
+	for _, cm := range configMaps.Items {
+		data.configMaps[cm.Namespace+"/"+cm.Name] = cm.Data
+	}
+
+	policies, err := clientset.NetworkingV1().NetworkPolicies("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return data, fmt.Errorf("failed to list network policies: %w", err)
+	}
+	data.policies = policies.Items
+
+	namespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return data, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	data.namespaceLabels = make(map[string]map[string]string, len(namespaces.Items))
+	for _, ns := range namespaces.Items {
+		data.namespaceLabels[ns.Name] = ns.Labels
+	}
+	return data, nil
+}
+
+// GetDependencyMap infers which workloads depend on which services in one
+// cluster. The map is best-effort: it sees addresses configured in env vars,
+// ConfigMaps and ExternalName services, Service selectors and NetworkPolicy
+// rules, not actual traffic. Secret values are never read. If namespace is
+// non-empty, only dependencies of that namespace's workloads are mapped.
+func (p *Provider) GetDependencyMap(ctx context.Context, contextName, namespace string, includeSystem bool) (*DependencyMap, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	data, err := collectDependencyData(queryCtx, clientset, namespace)
+	if err != nil {
+		return nil, err
+	}
+	m := buildDependencyMap(data, namespace, includeSystem)
+	m.Contexts = []string{contextName}
+	return m, nil
+}
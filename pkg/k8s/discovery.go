@@ -0,0 +1,225 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the per-cluster API discovery cache and resource-name resolution.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/debug"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+// DefaultDiscoveryTTL is how long the API resources of a cluster are cached.
+// Resources change only when CRDs or aggregated APIs are installed, and a
+// name that does not resolve triggers a refresh anyway.
+const DefaultDiscoveryTTL = 10 * time.Minute
+
+// APIResource is one resource served by a cluster, in the version the server prefers.
+type APIResource struct {
+	Group      string   `json:"group,omitempty"`
+	Version    string   `json:"version"`
+	Resource   string   `json:"resource"` // plural, e.g. deployments
+	Kind       string   `json:"kind"`
+	Singular   string   `json:"singular,omitempty"`
+	ShortNames []string `json:"short_names,omitempty"`
+	Namespaced bool     `json:"namespaced"`
+	Verbs      []string `json:"verbs,omitempty"`
+}
+
+// GVR returns the resource in its preferred version.
+func (r APIResource) GVR() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: r.Group, Version: r.Version, Resource: r.Resource}
+}
+
+// String returns <resource>.<group>, or the resource for the core group.
+func (r APIResource) String() string {
+	if r.Group == "" {
+		return r.Resource
+	}
+	return r.Resource + "." + r.Group
+}
+
+// names returns every lowercase name that resolves to r, as kubectl accepts them.
+func (r APIResource) names() []string {
+	names := []string{r.Resource, r.Singular, strings.ToLower(r.Kind)}
+	names = append(names, r.ShortNames...)
+	if r.Group != "" {
+		names = append(names, r.Resource+"."+r.Group, strings.ToLower(r.Kind)+"."+r.Group)
+	}
+	return names
+}
+
+// APIResources is the discovered resource list of one cluster.
+type APIResources []APIResource
+
+// Find returns the resource with the given group and plural name.
+func (rs APIResources) Find(group, resource string) (APIResource, bool) {
+	for _, r := range rs {
+		if r.Group == group && r.Resource == resource {
+			return r, true
+		}
+	}
+	return APIResource{}, false
+}
+
+// Resolve finds the resource named by name: a plural, singular, kind or
+// short name (deploy, svc, cm), optionally qualified by its group
+// (certificates.cert-manager.io). When a name exists in several groups the
+// core group wins, as with kubectl; other ambiguities are an error.
+func (rs APIResources) Resolve(name string) (APIResource, error) {
+	want := strings.ToLower(strings.TrimSpace(name))
+	var matches []APIResource
+	for _, r := range rs {
+		for _, n := range r.names() {
+			if n != "" && n == want {
+				matches = append(matches, r)
+				break
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return APIResource{}, fmt.Errorf("the server has no resource type %q", name)
+	case 1:
+		return matches[0], nil
+	}
+	for _, m := range matches {
+		if m.Group == "" {
+			return m, nil
+		}
+	}
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = m.String()
+	}
+	return APIResource{}, fmt.Errorf("%q is ambiguous, use one of: %s", name, strings.Join(names, ", "))
+}
+
+// discoveryEntry caches the API resources of one cluster.
+type discoveryEntry struct {
+	resources APIResources
+	expiresAt time.Time
+}
+
+// APIResources returns the resources served by a cluster, from the cache
+// when it is fresh.
+func (p *Provider) APIResources(ctx context.Context, contextName string) (APIResources, error) {
+	p.discoveryMutex.Lock()
+	entry, ok := p.discovery[contextName]
+	p.discoveryMutex.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		debug.Logf(debug.Cache, "discovery %s: hit, %d resource(s)", contextName, len(entry.resources))
+		return entry.resources, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	scoped, err := p.ForContext(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+	restConfig, transport, err := scoped.transport()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+	// Discovery requests take no context; the client timeout bounds them.
+	config := rest.CopyConfig(restConfig)
+	config.Timeout = p.APITimeout()
+	httpClient := &http.Client{Transport: transport, Timeout: p.APITimeout()}
+	client, err := discovery.NewDiscoveryClientForConfigAndClient(config, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery client: %w", err)
+	}
+	resources, err := discoverAPIResources(client)
+	if err != nil {
+		return nil, err
+	}
+
+	p.discoveryMutex.Lock()
+	defer p.discoveryMutex.Unlock()
+	if p.discovery == nil {
+		p.discovery = make(map[string]*discoveryEntry)
+	}
+	p.discovery[contextName] = &discoveryEntry{resources: resources, expiresAt: time.Now().Add(DefaultDiscoveryTTL)}
+	debug.Logf(debug.Cache, "discovery %s: stored %d resource(s) for %s", contextName, len(resources), DefaultDiscoveryTTL)
+	return resources, nil
+}
+
+// ResolveResource resolves a resource name or alias on a cluster. A name the
+// cached list does not know refreshes the cache once, so CRDs installed
+// since the last discovery are found.
+func (p *Provider) ResolveResource(ctx context.Context, contextName, name string) (APIResource, error) {
+	resources, err := p.APIResources(ctx, contextName)
+	if err != nil {
+		return APIResource{}, err
+	}
+	r, err := resources.Resolve(name)
+	if err == nil || !p.RefreshDiscovery(contextName) {
+		return r, err
+	}
+	if resources, err = p.APIResources(ctx, contextName); err != nil {
+		return APIResource{}, err
+	}
+	return resources.Resolve(name)
+}
+
+// RefreshDiscovery drops the cached API resources of a cluster, or of every
+// cluster when contextName is empty. It reports whether anything was cached.
+func (p *Provider) RefreshDiscovery(contextName string) bool {
+	p.discoveryMutex.Lock()
+	defer p.discoveryMutex.Unlock()
+	if contextName == "" {
+		cached := len(p.discovery) > 0
+		p.discovery = nil
+		return cached
+	}
+	_, cached := p.discovery[contextName]
+	delete(p.discovery, contextName)
+	return cached
+}
+
+// discoverAPIResources lists the preferred version of every resource. Groups
+// that fail discovery (typically an unavailable aggregated API such as
+// metrics.k8s.io) are skipped rather than failing the whole list.
+func discoverAPIResources(client discovery.DiscoveryInterface) (APIResources, error) {
+	lists, err := discovery.ServerPreferredResources(client)
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("API discovery failed: %w", err)
+	}
+	if err != nil {
+		debug.Logf(debug.K8s, "API discovery partially failed: %v", err)
+	}
+
+	var resources APIResources
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, r := range list.APIResources {
+			if strings.Contains(r.Name, "/") {
+				continue // subresources such as pods/log
+			}
+			resources = append(resources, APIResource{
+				Group:      gv.Group,
+				Version:    gv.Version,
+				Resource:   r.Name,
+				Kind:       r.Kind,
+				Singular:   r.SingularName,
+				ShortNames: r.ShortNames,
+				Namespaced: r.Namespaced,
+				Verbs:      r.Verbs,
+			})
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].String() < resources[j].String() })
+	return resources, nil
+}
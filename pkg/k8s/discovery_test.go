@@ -0,0 +1,95 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func testAPIResources() APIResources {
+	return APIResources{
+		{Version: "v1", Resource: "services", Kind: "Service", Singular: "service", ShortNames: []string{"svc"}, Namespaced: true},
+		{Version: "v1", Resource: "configmaps", Kind: "ConfigMap", ShortNames: []string{"cm"}, Namespaced: true},
+		{Version: "v1", Resource: "events", Kind: "Event", ShortNames: []string{"ev"}, Namespaced: true},
+		{Group: "apps", Version: "v1", Resource: "deployments", Kind: "Deployment", ShortNames: []string{"deploy"}, Namespaced: true},
+		{Group: "events.k8s.io", Version: "v1", Resource: "events", Kind: "Event", ShortNames: []string{"ev"}, Namespaced: true},
+		{Group: "cert-manager.io", Version: "v1", Resource: "certificates", Kind: "Certificate", ShortNames: []string{"cert"}, Namespaced: true},
+		{Group: "networking.istio.io", Version: "v1", Resource: "certificates", Kind: "Certificate"},
+	}
+}
+
+func TestAPIResourcesResolve(t *testing.T) {
+	resources := testAPIResources()
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"deploy", "deployments.apps"},
+		{"Deployment", "deployments.apps"},
+		{"svc", "services"},
+		{"service", "services"},
+		{"cm", "configmaps"},
+		{"events", "events"},
+		{"events.events.k8s.io", "events.events.k8s.io"},
+		{"certificates.cert-manager.io", "certificates.cert-manager.io"},
+		{"certificate.networking.istio.io", "certificates.networking.istio.io"},
+	}
+	for _, tt := range tests {
+		r, err := resources.Resolve(tt.name)
+		if err != nil || r.String() != tt.want {
+			t.Errorf("Resolve(%q) = %s, %v, want %s", tt.name, r, err, tt.want)
+		}
+	}
+	if _, err := resources.Resolve("certificates"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Resolve(certificates) err = %v, want ambiguous", err)
+	}
+	if _, err := resources.Resolve("widgets"); err == nil || !strings.Contains(err.Error(), "no resource type") {
+		t.Errorf("Resolve(widgets) err = %v", err)
+	}
+	if r, ok := resources.Find("apps", "deployments"); !ok || r.GVR().String() != "apps/v1, Resource=deployments" {
+		t.Errorf("Find(apps, deployments) = %+v, %v", r, ok)
+	}
+}
+
+func TestDiscoverAPIResources(t *testing.T) {
+	client := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{}}
+	client.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "pods", Kind: "Pod", Namespaced: true, ShortNames: []string{"po"}, Verbs: []string{"get", "list"}},
+			{Name: "pods/log", Kind: "Pod", Namespaced: true},
+		}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "deployments", Kind: "Deployment", Namespaced: true, ShortNames: []string{"deploy"}}}},
+	}
+	resources, err := discoverAPIResources(client)
+	if err != nil {
+		t.Fatalf("discoverAPIResources: %v", err)
+	}
+	if len(resources) != 2 || resources[0].String() != "deployments.apps" || resources[1].Resource != "pods" {
+		t.Fatalf("resources = %+v, want deployments.apps and pods without subresources", resources)
+	}
+	if r := resources[0]; r.Group != "apps" || r.Version != "v1" || !r.Namespaced {
+		t.Errorf("deployments = %+v", r)
+	}
+}
+
+func TestProviderDiscoveryCache(t *testing.T) {
+	p := &Provider{discovery: map[string]*discoveryEntry{
+		"prod": {resources: testAPIResources(), expiresAt: time.Now().Add(time.Minute)},
+	}}
+	r, err := p.ResolveResource(context.Background(), "prod", "deploy")
+	if err != nil || r.Kind != "Deployment" {
+		t.Errorf("ResolveResource(deploy) = %+v, %v", r, err)
+	}
+	if !p.RefreshDiscovery("prod") || p.RefreshDiscovery("prod") {
+		t.Error("RefreshDiscovery should report a cached entry once")
+	}
+	p.discovery = map[string]*discoveryEntry{"a": {}, "b": {}}
+	if !p.RefreshDiscovery("") || len(p.discovery) != 0 {
+		t.Errorf("RefreshDiscovery(\"\") left %d entries", len(p.discovery))
+	}
+}
@@ -0,0 +1,256 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the eviction safety analysis run before drains, cordons and pod deletions.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DisruptionTarget names the pods an operation would disrupt: every pod on
+// Node, or the named Pods in Namespace.
+type DisruptionTarget struct {
+	Node      string
+	Namespace string
+	Pods      []string
+}
+
+// PDBViolation is a PodDisruptionBudget that allows fewer disruptions than
+// the operation causes.
+type PDBViolation struct {
+	Namespace          string   `json:"namespace"`
+	Name               string   `json:"name"`
+	DisruptionsAllowed int32    `json:"disruptions_allowed"`
+	Pods               []string `json:"pods"`
+}
+
+// WorkloadAtRisk is a workload whose every running replica is among the
+// disrupted pods, so it goes down until the replacements are Ready.
+type WorkloadAtRisk struct {
+	Namespace string   `json:"namespace"`
+	Workload  string   `json:"workload"` // kind/name
+	Replicas  int      `json:"replicas"` // running pods
+	Pods      []string `json:"pods"`
+}
+
+// DisruptionReport is the eviction safety analysis of a DisruptionTarget.
+type DisruptionReport struct {
+	Context string `json:"context"`
+	Node    string `json:"node,omitempty"`
+	// Pods is the number of pods that would be evicted or deleted.
+	Pods int `json:"pods"`
+	// DaemonSetPods are left in place by a drain.
+	DaemonSetPods int              `json:"daemonset_pods,omitempty"`
+	PDBViolations []PDBViolation   `json:"pdb_violations,omitempty"`
+	AtRisk        []WorkloadAtRisk `json:"workloads_at_risk,omitempty"`
+	// Unmanaged pods have no controller: they are not recreated, and a drain
+	// refuses them without --force.
+	Unmanaged []string `json:"unmanaged_pods,omitempty"`
+	// LocalStorage pods use emptyDir volumes whose data is lost; a drain
+	// refuses them without --delete-emptydir-data.
+	LocalStorage []string `json:"local_storage_pods,omitempty"`
+	Missing      []string `json:"missing_pods,omitempty"`
+	Safe         bool     `json:"safe"`
+}
+
+// Warnings summarises the report in one line per finding, for approval
+// prompts. Deleting pods bypasses PDBs, while eviction waits on them.
+func (r *DisruptionReport) Warnings() []string {
+	var warnings []string
+	for _, v := range r.PDBViolations {
+		effect := "the drain blocks until replacements are Ready"
+		if r.Node == "" {
+			effect = "deleting ignores the budget"
+		}
+		warnings = append(warnings, fmt.Sprintf("PDB %s/%s allows %d disruption(s) but covers %d affected pod(s); %s",
+			v.Namespace, v.Name, v.DisruptionsAllowed, len(v.Pods), effect))
+	}
+	for _, w := range r.AtRisk {
+		what := fmt.Sprintf("all %d replicas", w.Replicas)
+		if w.Replicas == 1 {
+			what = "its only replica"
+		}
+		warnings = append(warnings, fmt.Sprintf("%s/%s loses %s: unavailable until rescheduled", w.Namespace, w.Workload, what))
+	}
+	if len(r.Unmanaged) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d pod(s) without a controller are not recreated: %s", len(r.Unmanaged), strings.Join(r.Unmanaged, ", ")))
+	}
+	if len(r.LocalStorage) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d pod(s) lose emptyDir data: %s", len(r.LocalStorage), strings.Join(r.LocalStorage, ", ")))
+	}
+	if len(r.Missing) > 0 {
+		warnings = append(warnings, fmt.Sprintf("pod(s) not found: %s", strings.Join(r.Missing, ", ")))
+	}
+	return warnings
+}
+
+// CheckDisruptionSafety reports what evicting or deleting the pods of target
+// would break: PDBs that block or are violated, workloads that lose every
+// replica, pods that are not recreated and pods with local data.
+func (p *Provider) CheckDisruptionSafety(ctx context.Context, contextName string, target DisruptionTarget) (*DisruptionReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	report, err := analyzeDisruption(queryCtx, clientset, target)
+	if err != nil {
+		return nil, err
+	}
+	report.Context = contextName
+	return report, nil
+}
+
+// analyzeDisruption builds the report from the pods and PDBs of the cluster,
+// or of the target namespace when the target is a set of pods.
+func analyzeDisruption(ctx context.Context, clientset kubernetes.Interface, target DisruptionTarget) (*DisruptionReport, error) {
+	if target.Node == "" && len(target.Pods) == 0 {
+		return nil, fmt.Errorf("a node or pods are required")
+	}
+	namespace := ""
+	if target.Node == "" {
+		namespace = target.Namespace
+	} else if _, err := clientset.CoreV1().Nodes().Get(ctx, target.Node, metav1.GetOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to get node %s: %w", target.Node, err)
+	}
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	pdbs, err := clientset.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pod disruption budgets: %w", err)
+	}
+
+	report := &DisruptionReport{Node: target.Node}
+	affected := disruptedPods(pods.Items, target, report)
+	report.Pods = len(affected)
+	report.PDBViolations = pdbViolations(pdbs.Items, affected)
+	report.AtRisk = workloadsAtRisk(pods.Items, affected)
+	report.Safe = len(report.PDBViolations) == 0 && len(report.AtRisk) == 0 && len(report.Unmanaged) == 0
+	return report, nil
+}
+
+// disruptedPods returns the running pods of target, skipping DaemonSet pods
+// on a drained node and recording unmanaged, local-storage and missing pods.
+func disruptedPods(pods []corev1.Pod, target DisruptionTarget, report *DisruptionReport) []corev1.Pod {
+	wanted := make(map[string]bool, len(target.Pods))
+	for _, name := range target.Pods {
+		wanted[name] = true
+	}
+	var affected []corev1.Pod
+	for _, pod := range pods {
+		if target.Node != "" && pod.Spec.NodeName != target.Node {
+			continue
+		}
+		if target.Node == "" && !wanted[pod.Name] {
+			continue
+		}
+		delete(wanted, pod.Name)
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		controller := metav1.GetControllerOf(&pod)
+		if target.Node != "" && controller != nil && controller.Kind == "DaemonSet" {
+			report.DaemonSetPods++
+			continue
+		}
+		id := pod.Namespace + "/" + pod.Name
+		if controller == nil {
+			report.Unmanaged = append(report.Unmanaged, id)
+		}
+		for _, v := range pod.Spec.Volumes {
+			if v.EmptyDir != nil {
+				report.LocalStorage = append(report.LocalStorage, id)
+				break
+			}
+		}
+		affected = append(affected, pod)
+	}
+	for name := range wanted {
+		report.Missing = append(report.Missing, target.Namespace+"/"+name)
+	}
+	sort.Strings(report.Missing)
+	return affected
+}
+
+// pdbViolations returns the PDBs covering more affected pods than they
+// currently allow to be disrupted.
+func pdbViolations(pdbs []policyv1.PodDisruptionBudget, affected []corev1.Pod) []PDBViolation {
+	var violations []PDBViolation
+	for _, pdb := range pdbs {
+		if pdb.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() {
+			continue
+		}
+		var covered []string
+		for _, pod := range affected {
+			if pod.Namespace == pdb.Namespace && selector.Matches(labels.Set(pod.Labels)) {
+				covered = append(covered, pod.Name)
+			}
+		}
+		if len(covered) > 0 && int32(len(covered)) > pdb.Status.DisruptionsAllowed {
+			violations = append(violations, PDBViolation{
+				Namespace: pdb.Namespace, Name: pdb.Name,
+				DisruptionsAllowed: pdb.Status.DisruptionsAllowed, Pods: covered,
+			})
+		}
+	}
+	return violations
+}
+
+// isReplicated reports whether pod belongs to a controller that keeps
+// replicas running; Job pods run to completion and are retried instead.
+func isReplicated(pod *corev1.Pod) bool {
+	controller := metav1.GetControllerOf(pod)
+	return controller != nil && controller.Kind != "Job"
+}
+
+// workloadsAtRisk returns the workloads whose every running pod is affected.
+func workloadsAtRisk(all, affected []corev1.Pod) []WorkloadAtRisk {
+	running := make(map[string]int)
+	for i := range all {
+		if all[i].Status.Phase == corev1.PodRunning && isReplicated(&all[i]) {
+			running[all[i].Namespace+"/"+workloadName(&all[i])]++
+		}
+	}
+	hit := make(map[string]*WorkloadAtRisk)
+	var keys []string
+	for i := range affected {
+		pod := &affected[i]
+		if pod.Status.Phase != corev1.PodRunning || !isReplicated(pod) {
+			continue
+		}
+		workload := workloadName(pod)
+		key := pod.Namespace + "/" + workload
+		w, ok := hit[key]
+		if !ok {
+			w = &WorkloadAtRisk{Namespace: pod.Namespace, Workload: workload, Replicas: running[key]}
+			hit[key] = w
+			keys = append(keys, key)
+		}
+		w.Pods = append(w.Pods, pod.Name)
+	}
+	sort.Strings(keys)
+	var atRisk []WorkloadAtRisk
+	for _, key := range keys {
+		if w := hit[key]; len(w.Pods) >= w.Replicas {
+			atRisk = append(atRisk, *w)
+		}
+	}
+	return atRisk
+}
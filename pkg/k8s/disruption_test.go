@@ -0,0 +1,116 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// controlledPod is an appPod controlled by kind/owner.
+func controlledPod(name, app, node, kind, owner string) *corev1.Pod {
+	pod := appPod("apps", name, app, node)
+	controller := true
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: kind, Name: owner, Controller: &controller}}
+	if kind == "ReplicaSet" {
+		pod.Labels["pod-template-hash"] = strings.TrimPrefix(owner, app+"-")
+	}
+	return pod
+}
+
+func disruptionClientset() *fake.Clientset {
+	node1, node2 := osNode("node-1", OSLinux, true), osNode("node-2", OSLinux, true)
+	scratch := controlledPod("cache-0", "cache", "node-1", "StatefulSet", "cache")
+	scratch.Spec.Volumes = []corev1.Volume{{Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+	done := controlledPod("migrate-x1", "migrate", "node-1", "Job", "migrate")
+	done.Status.Phase = corev1.PodSucceeded
+	return fake.NewClientset(&node1, &node2,
+		// web: 2 replicas, both on node-1, PDB allows 1 disruption
+		controlledPod("web-abc-1", "web", "node-1", "ReplicaSet", "web-abc"),
+		controlledPod("web-abc-2", "web", "node-1", "ReplicaSet", "web-abc"),
+		// api: spread over both nodes
+		controlledPod("api-def-1", "api", "node-1", "ReplicaSet", "api-def"),
+		controlledPod("api-def-2", "api", "node-2", "ReplicaSet", "api-def"),
+		scratch,
+		controlledPod("fluentd-1", "fluentd", "node-1", "DaemonSet", "fluentd"),
+		appPod("apps", "debug", "debug", "node-1"),
+		done,
+		appPDB("web", "web", 1),
+		appPDB("api", "api", 1),
+	)
+}
+
+func TestAnalyzeDisruptionNode(t *testing.T) {
+	report, err := analyzeDisruption(context.Background(), disruptionClientset(), DisruptionTarget{Node: "node-1"})
+	if err != nil {
+		t.Fatalf("analyzeDisruption() error = %v", err)
+	}
+	if report.Pods != 5 || report.DaemonSetPods != 1 {
+		t.Errorf("Pods = %d, DaemonSetPods = %d, want 5 and 1", report.Pods, report.DaemonSetPods)
+	}
+	if len(report.PDBViolations) != 1 || report.PDBViolations[0].Name != "web" || len(report.PDBViolations[0].Pods) != 2 {
+		t.Errorf("PDBViolations = %+v, want web covering 2 pods", report.PDBViolations)
+	}
+	var atRisk []string
+	for _, w := range report.AtRisk {
+		atRisk = append(atRisk, w.Workload)
+	}
+	if strings.Join(atRisk, ",") != "deployment/web,statefulset/cache" {
+		t.Errorf("AtRisk = %v, want web and cache", atRisk)
+	}
+	if strings.Join(report.Unmanaged, ",") != "apps/debug" || strings.Join(report.LocalStorage, ",") != "apps/cache-0" {
+		t.Errorf("Unmanaged = %v, LocalStorage = %v", report.Unmanaged, report.LocalStorage)
+	}
+	if report.Safe {
+		t.Error("Safe = true, want false")
+	}
+
+	warnings := strings.Join(report.Warnings(), "\n")
+	for _, want := range []string{
+		"PDB apps/web allows 1 disruption(s) but covers 2 affected pod(s); the drain blocks",
+		"apps/deployment/web loses all 2 replicas",
+		"apps/statefulset/cache loses its only replica",
+		"without a controller are not recreated: apps/debug",
+		"lose emptyDir data: apps/cache-0",
+	} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("warnings missing %q:\n%s", want, warnings)
+		}
+	}
+}
+
+func TestAnalyzeDisruptionPods(t *testing.T) {
+	target := DisruptionTarget{Namespace: "apps", Pods: []string{"api-def-1", "gone"}}
+	report, err := analyzeDisruption(context.Background(), disruptionClientset(), target)
+	if err != nil {
+		t.Fatalf("analyzeDisruption() error = %v", err)
+	}
+	if report.Pods != 1 || len(report.PDBViolations) != 0 || len(report.AtRisk) != 0 || !report.Safe {
+		t.Errorf("report = %+v, want one safe pod", report)
+	}
+	if strings.Join(report.Missing, ",") != "apps/gone" {
+		t.Errorf("Missing = %v", report.Missing)
+	}
+
+	target.Pods = []string{"api-def-1", "api-def-2"}
+	report, err = analyzeDisruption(context.Background(), disruptionClientset(), target)
+	if err != nil {
+		t.Fatalf("analyzeDisruption() error = %v", err)
+	}
+	warnings := strings.Join(report.Warnings(), "\n")
+	if !strings.Contains(warnings, "deleting ignores the budget") || !strings.Contains(warnings, "loses all 2 replicas") {
+		t.Errorf("warnings = %s", warnings)
+	}
+}
+
+func TestAnalyzeDisruptionErrors(t *testing.T) {
+	if _, err := analyzeDisruption(context.Background(), disruptionClientset(), DisruptionTarget{}); err == nil {
+		t.Error("expected error for an empty target")
+	}
+	if _, err := analyzeDisruption(context.Background(), disruptionClientset(), DisruptionTarget{Node: "node-9"}); err == nil {
+		t.Error("expected error for an unknown node")
+	}
+}
@@ -0,0 +1,246 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains cluster DNS (CoreDNS / kube-dns) health checks.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// dnsNamespace, dnsService and dnsPodSelector locate cluster DNS; CoreDNS
+	// keeps the kube-dns names for compatibility.
+	dnsNamespace   = "kube-system"
+	dnsService     = "kube-dns"
+	dnsPodSelector = "k8s-app=kube-dns"
+	corednsConfig  = "coredns"
+
+	// DefaultClusterDomain is used when the Corefile does not name one.
+	DefaultClusterDomain = "cluster.local"
+
+	// dnsRestartWarning is the container restart count that flags a DNS pod.
+	dnsRestartWarning = 5
+)
+
+// DNSReport is the outcome of CheckDNS.
+type DNSReport struct {
+	Context       string              `json:"context"`
+	ClusterDomain string              `json:"cluster_domain"`
+	ServiceIP     string              `json:"service_ip,omitempty"`
+	Pods          int                 `json:"pods"`
+	ReadyPods     int                 `json:"ready_pods"`
+	Endpoints     int                 `json:"ready_endpoints"`
+	Checks        []ConnectivityCheck `json:"checks"`
+	Healthy       bool                `json:"healthy"`
+}
+
+func (r *DNSReport) add(name, status, format string, args ...any) {
+	r.Checks = append(r.Checks, ConnectivityCheck{Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+}
+
+// dnsData holds the objects checkDNSHealth needs. corefile is nil when the
+// coredns ConfigMap does not exist.
+type dnsData struct {
+	pods     []corev1.Pod
+	service  *corev1.Service
+	slices   []discoveryv1.EndpointSlice
+	corefile *string
+}
+
+// corefileDirectives returns the first word of every Corefile line, without
+// comments, and whether the braces balance.
+func corefileDirectives(corefile string) (map[string][]string, bool) {
+	directives := make(map[string][]string)
+	depth := 0
+	for _, line := range strings.Split(corefile, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if depth < 0 {
+			return directives, false
+		}
+		fields := strings.Fields(strings.Trim(line, "{} \t"))
+		if len(fields) > 0 {
+			directives[fields[0]] = append(directives[fields[0]], strings.Join(fields[1:], " "))
+		}
+	}
+	return directives, depth == 0
+}
+
+// checkCorefile validates the CoreDNS configuration and returns the cluster
+// domain served by its kubernetes plugin.
+func checkCorefile(r *DNSReport, corefile string) string {
+	domain := DefaultClusterDomain
+	directives, balanced := corefileDirectives(corefile)
+	if !balanced {
+		r.add("corefile", CheckFail, "Corefile has unbalanced braces; CoreDNS will refuse to load it")
+		return domain
+	}
+
+	var problems, warnings []string
+	if args, ok := directives["kubernetes"]; ok {
+		if zones := strings.Fields(args[0]); len(zones) > 0 {
+			domain = strings.TrimSuffix(zones[0], ".")
+		}
+	} else {
+		problems = append(problems, "no kubernetes plugin (cluster names will not resolve)")
+	}
+	_, hasForward := directives["forward"]
+	if _, ok := directives["proxy"]; ok {
+		problems = append(problems, "deprecated proxy plugin (removed in CoreDNS 1.7; use forward)")
+		hasForward = true
+	}
+	if !hasForward {
+		warnings = append(warnings, "no forward plugin (external names will not resolve)")
+	}
+	for _, plugin := range []string{"loop", "cache", "health", "ready"} {
+		if _, ok := directives[plugin]; !ok {
+			warnings = append(warnings, "no "+plugin+" plugin")
+		}
+	}
+
+	switch {
+	case len(problems) > 0:
+		r.add("corefile", CheckFail, "%s", strings.Join(append(problems, warnings...), "; "))
+	case len(warnings) > 0:
+		r.add("corefile", CheckWarn, "%s", strings.Join(warnings, "; "))
+	default:
+		r.add("corefile", CheckOK, "Corefile serves %s with forward, cache, loop, health and ready", domain)
+	}
+	return domain
+}
+
+// checkDNSHealth evaluates DNS pods, the kube-dns Service, its endpoints and
+// the CoreDNS configuration.
+func checkDNSHealth(data dnsData) *DNSReport {
+	r := &DNSReport{ClusterDomain: DefaultClusterDomain, Pods: len(data.pods)}
+
+	var restarting []string
+	for i := range data.pods {
+		pod := &data.pods[i]
+		if isPodReady(pod) {
+			r.ReadyPods++
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.RestartCount >= dnsRestartWarning {
+				restarting = append(restarting, fmt.Sprintf("%s (%d restarts)", pod.Name, cs.RestartCount))
+			}
+		}
+	}
+	switch {
+	case r.Pods == 0:
+		r.add("dns-pods", CheckFail, "no pods labelled %s in %s; cluster DNS is not running", dnsPodSelector, dnsNamespace)
+	case r.ReadyPods == 0:
+		r.add("dns-pods", CheckFail, "0/%d DNS pod(s) Ready", r.Pods)
+	case r.ReadyPods == 1:
+		r.add("dns-pods", CheckWarn, "1/%d DNS pod(s) Ready; a single replica is a single point of failure", r.Pods)
+	case r.ReadyPods < r.Pods:
+		r.add("dns-pods", CheckWarn, "%d/%d DNS pod(s) Ready", r.ReadyPods, r.Pods)
+	default:
+		r.add("dns-pods", CheckOK, "%d/%d DNS pod(s) Ready", r.ReadyPods, r.Pods)
+	}
+	if len(restarting) > 0 {
+		r.add("dns-restarts", CheckWarn, "frequently restarting: %s (check for OOMKills and forwarding loops)", strings.Join(restarting, ", "))
+	}
+
+	if data.service == nil {
+		r.add("dns-service", CheckFail, "service %s/%s not found; pods use it as their nameserver", dnsNamespace, dnsService)
+	} else {
+		r.ServiceIP = data.service.Spec.ClusterIP
+		r.add("dns-service", CheckOK, "service %s/%s has cluster IP %s", dnsNamespace, dnsService, orDash(r.ServiceIP))
+	}
+
+	notReady := 0
+	for _, slice := range data.slices {
+		for _, ep := range slice.Endpoints {
+			if ep.Conditions.Ready == nil || *ep.Conditions.Ready {
+				r.Endpoints += len(ep.Addresses)
+			} else {
+				notReady += len(ep.Addresses)
+			}
+		}
+	}
+	if r.Endpoints > 0 {
+		r.add("dns-endpoints", CheckOK, "%d ready endpoint address(es), %d not ready", r.Endpoints, notReady)
+	} else {
+		r.add("dns-endpoints", CheckFail, "no ready endpoints behind %s; every lookup will time out", dnsService)
+	}
+
+	if data.corefile == nil {
+		r.add("corefile", CheckSkip, "ConfigMap %s/%s not found (kube-dns or a managed DNS add-on?)", dnsNamespace, corednsConfig)
+	} else {
+		r.ClusterDomain = checkCorefile(r, *data.corefile)
+	}
+
+	r.Healthy = true
+	for _, c := range r.Checks {
+		if c.Status == CheckFail {
+			r.Healthy = false
+		}
+	}
+	return r
+}
+
+// collectDNSData fetches the objects checkDNSHealth needs.
+func collectDNSData(ctx context.Context, clientset kubernetes.Interface) (dnsData, error) {
+	var data dnsData
+	pods, err := clientset.CoreV1().Pods(dnsNamespace).List(ctx, metav1.ListOptions{LabelSelector: dnsPodSelector})
+	if err != nil {
+		return data, fmt.Errorf("failed to list DNS pods: %w", err)
+	}
+	data.pods = pods.Items
+
+	svc, err := clientset.CoreV1().Services(dnsNamespace).Get(ctx, dnsService, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		data.service = svc
+	case !apierrors.IsNotFound(err):
+		return data, fmt.Errorf("failed to get DNS service: %w", err)
+	}
+
+	slices, err := clientset.DiscoveryV1().EndpointSlices(dnsNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + dnsService,
+	})
+	if err != nil {
+		return data, fmt.Errorf("failed to list endpoint slices: %w", err)
+	}
+	data.slices = slices.Items
+
+	cm, err := clientset.CoreV1().ConfigMaps(dnsNamespace).Get(ctx, corednsConfig, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		corefile := cm.Data["Corefile"]
+		data.corefile = &corefile
+	case !apierrors.IsNotFound(err):
+		return data, fmt.Errorf("failed to get CoreDNS ConfigMap: %w", err)
+	}
+	return data, nil
+}
+
+// CheckDNS reports the health of cluster DNS: pods, the kube-dns Service and
+// its endpoints, and the CoreDNS Corefile.
+func (p *Provider) CheckDNS(ctx context.Context, contextName string) (*DNSReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	data, err := collectDNSData(queryCtx, clientset)
+	if err != nil {
+		return nil, err
+	}
+	report := checkDNSHealth(data)
+	report.Context = contextName
+	return report, nil
+}
@@ -0,0 +1,132 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const healthyCorefile = `.:53 {
+    errors
+    health { lameduck 5s }
+    ready
+    kubernetes corp.internal in-addr.arpa ip6.arpa {
+       pods insecure
+       fallthrough in-addr.arpa ip6.arpa
+    }
+    prometheus :9153
+    forward . /etc/resolv.conf
+    cache 30
+    loop # detect forwarding loops
+    reload
+}
+`
+
+func dnsObjects(corefile string, readyPods int) []runtime.Object {
+	objs := []runtime.Object{
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: dnsService, Namespace: dnsNamespace}, Spec: corev1.ServiceSpec{ClusterIP: "10.96.0.10"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: corednsConfig, Namespace: dnsNamespace}, Data: map[string]string{"Corefile": corefile}},
+	}
+	ready := true
+	slice := &discoveryv1.EndpointSlice{ObjectMeta: metav1.ObjectMeta{Name: "kube-dns-abc", Namespace: dnsNamespace, Labels: map[string]string{discoveryv1.LabelServiceName: dnsService}}}
+	for i := 0; i < readyPods; i++ {
+		name := fmt.Sprintf("coredns-%d", i)
+		objs = append(objs, readyPod(dnsNamespace, name, map[string]string{"k8s-app": "kube-dns"}))
+		slice.Endpoints = append(slice.Endpoints, discoveryv1.Endpoint{Addresses: []string{fmt.Sprintf("10.1.0.%d", i+1)}, Conditions: discoveryv1.EndpointConditions{Ready: &ready}})
+	}
+	return append(objs, slice)
+}
+
+func TestCheckDNSHealthy(t *testing.T) {
+	clientset := fake.NewClientset(dnsObjects(healthyCorefile, 2)...)
+	data, err := collectDNSData(context.Background(), clientset)
+	if err != nil {
+		t.Fatalf("collectDNSData: %v", err)
+	}
+	r := checkDNSHealth(data)
+	if !r.Healthy || r.ReadyPods != 2 || r.Endpoints != 2 || r.ServiceIP != "10.96.0.10" {
+		t.Errorf("unexpected report %+v", r)
+	}
+	if r.ClusterDomain != "corp.internal" {
+		t.Errorf("ClusterDomain = %q, want corp.internal", r.ClusterDomain)
+	}
+	for _, c := range r.Checks {
+		if c.Status != CheckOK {
+			t.Errorf("check %s = %s (%s), want ok", c.Name, c.Status, c.Detail)
+		}
+	}
+}
+
+func TestCheckDNSBroken(t *testing.T) {
+	clientset := fake.NewClientset()
+	data, err := collectDNSData(context.Background(), clientset)
+	if err != nil {
+		t.Fatalf("collectDNSData: %v", err)
+	}
+	r := checkDNSHealth(data)
+	if r.Healthy {
+		t.Error("a cluster without DNS pods should be unhealthy")
+	}
+	for name, want := range map[string]string{"dns-pods": CheckFail, "dns-service": CheckFail, "dns-endpoints": CheckFail, "corefile": CheckSkip} {
+		if got, detail := dnsCheckStatus(r, name); got != want {
+			t.Errorf("%s = %s (%s), want %s", name, got, detail, want)
+		}
+	}
+}
+
+func TestCheckDNSSingleRestartingReplica(t *testing.T) {
+	objs := dnsObjects(healthyCorefile, 1)
+	pod := objs[2].(*corev1.Pod)
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "coredns", RestartCount: 12}}
+	data, err := collectDNSData(context.Background(), fake.NewClientset(objs...))
+	if err != nil {
+		t.Fatalf("collectDNSData: %v", err)
+	}
+	r := checkDNSHealth(data)
+	if !r.Healthy {
+		t.Error("warnings alone should not make DNS unhealthy")
+	}
+	if got, detail := dnsCheckStatus(r, "dns-pods"); got != CheckWarn || !strings.Contains(detail, "single point of failure") {
+		t.Errorf("dns-pods = %s %q", got, detail)
+	}
+	if got, detail := dnsCheckStatus(r, "dns-restarts"); got != CheckWarn || !strings.Contains(detail, "coredns-0 (12 restarts)") {
+		t.Errorf("dns-restarts = %s %q", got, detail)
+	}
+}
+
+func TestCheckCorefile(t *testing.T) {
+	tests := []struct {
+		name, corefile, status, detail string
+	}{
+		{"healthy", healthyCorefile, CheckOK, "corp.internal"},
+		{"unbalanced", ".:53 {\n kubernetes cluster.local\n", CheckFail, "unbalanced braces"},
+		{"no kubernetes", ".:53 {\n forward . 8.8.8.8\n cache\n loop\n health\n ready\n}\n", CheckFail, "no kubernetes plugin"},
+		{"proxy", ".:53 {\n kubernetes cluster.local\n proxy . /etc/resolv.conf\n cache\n loop\n health\n ready\n}\n", CheckFail, "deprecated proxy"},
+		{"no forward", ".:53 {\n kubernetes cluster.local\n cache\n loop\n health\n ready\n}\n", CheckWarn, "no forward plugin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &DNSReport{}
+			checkCorefile(r, tt.corefile)
+			if len(r.Checks) != 1 || r.Checks[0].Status != tt.status || !strings.Contains(r.Checks[0].Detail, tt.detail) {
+				t.Errorf("checkCorefile() = %+v, want %s containing %q", r.Checks, tt.status, tt.detail)
+			}
+		})
+	}
+}
+
+func dnsCheckStatus(r *DNSReport, name string) (string, string) {
+	for _, c := range r.Checks {
+		if c.Name == name {
+			return c.Status, c.Detail
+		}
+	}
+	return "", ""
+}
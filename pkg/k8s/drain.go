@@ -0,0 +1,274 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains node draining through the eviction API.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultDrainTimeout bounds a whole drain, including waits on PDBs.
+const DefaultDrainTimeout = 5 * time.Minute
+
+// Intervals of the drain loop; variables so tests run fast.
+var (
+	// evictionRetryInterval is the wait after an eviction refused by a PDB.
+	evictionRetryInterval = 5 * time.Second
+	// drainPollInterval is how often evicted pods are checked for deletion.
+	drainPollInterval = 2 * time.Second
+)
+
+// mirrorPodAnnotation marks static pods the kubelet mirrors to the API; they
+// cannot be evicted.
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// Drain event statuses.
+const (
+	DrainCordoned = "cordoned"
+	DrainEvicting = "evicting"
+	DrainBlocked  = "blocked"
+	DrainEvicted  = "evicted"
+	DrainFailed   = "failed"
+)
+
+// DrainEvent reports progress of a drain, one pod at a time.
+type DrainEvent struct {
+	Pod     string `json:"pod,omitempty"` // namespace/name; empty for node events
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+}
+
+// DrainOptions controls DrainNode.
+type DrainOptions struct {
+	// GracePeriodSeconds overrides the termination grace period of the
+	// evicted pods; nil keeps each pod's own.
+	GracePeriodSeconds *int64
+	// Timeout bounds the drain; zero means DefaultDrainTimeout.
+	Timeout time.Duration
+	// DeleteEmptyDirData allows evicting pods whose emptyDir data is lost.
+	DeleteEmptyDirData bool
+	// Force allows evicting pods without a controller, which are not recreated.
+	Force bool
+	// UncordonOnFailure makes the node schedulable again when the drain fails,
+	// if the drain cordoned it.
+	UncordonOnFailure bool
+	// Progress, when set, receives every event; calls are serialised.
+	Progress func(DrainEvent)
+}
+
+// DrainResult is the outcome of a drain.
+type DrainResult struct {
+	Context string `json:"context"`
+	Node    string `json:"node"`
+	// Cordoned is set when the drain cordoned the node; it was already
+	// unschedulable otherwise.
+	Cordoned   bool     `json:"cordoned"`
+	Uncordoned bool     `json:"uncordoned,omitempty"`
+	Evicted    []string `json:"evicted"`
+	Failed     []string `json:"failed,omitempty"`
+	// Skipped are DaemonSet and mirror pods, which stay on the node.
+	Skipped  []string `json:"skipped,omitempty"`
+	Duration string   `json:"duration"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// DrainNode cordons a node and evicts its pods through the eviction API, so
+// PodDisruptionBudgets are respected: evictions a budget refuses are retried
+// until the timeout. DaemonSet and mirror pods are left in place.
+func (p *Provider) DrainNode(ctx context.Context, contextName, node string, opts DrainOptions) (*DrainResult, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultDrainTimeout
+	}
+
+	drainCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	result, err := drainNode(drainCtx, clientset, node, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.Context = contextName
+	return result, nil
+}
+
+// drainNode runs the drain; errors before any change are returned, while
+// failures during eviction are reported in the result.
+func drainNode(ctx context.Context, clientset kubernetes.Interface, node string, opts DrainOptions) (*DrainResult, error) {
+	start := time.Now()
+	n, err := clientset.CoreV1().Nodes().Get(ctx, node, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node %s: %w", node, err)
+	}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods on node %s: %w", node, err)
+	}
+	result := &DrainResult{Node: node, Evicted: []string{}}
+	evict, err := podsToEvict(pods.Items, node, opts, result)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	report := func(e DrainEvent) {
+		if opts.Progress == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		e.Done, e.Total = len(result.Evicted), len(evict)
+		opts.Progress(e)
+	}
+
+	if !n.Spec.Unschedulable {
+		if err := setNodeUnschedulable(ctx, clientset, node, true); err != nil {
+			return nil, err
+		}
+		result.Cordoned = true
+		report(DrainEvent{Status: DrainCordoned, Message: "node marked unschedulable"})
+	}
+
+	var wg sync.WaitGroup
+	for i := range evict {
+		pod := evict[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := pod.Namespace + "/" + pod.Name
+			err := evictAndWait(ctx, clientset, pod, opts.GracePeriodSeconds, func(status, msg string) {
+				report(DrainEvent{Pod: id, Status: status, Message: msg})
+			})
+			mu.Lock()
+			if err != nil {
+				result.Failed = append(result.Failed, id)
+			} else {
+				result.Evicted = append(result.Evicted, id)
+			}
+			mu.Unlock()
+			if err != nil {
+				report(DrainEvent{Pod: id, Status: DrainFailed, Message: err.Error()})
+			} else {
+				report(DrainEvent{Pod: id, Status: DrainEvicted})
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Strings(result.Evicted)
+	sort.Strings(result.Failed)
+	if len(result.Failed) > 0 {
+		result.Error = fmt.Sprintf("%d pod(s) could not be evicted", len(result.Failed))
+		if ctx.Err() != nil {
+			result.Error += ": timed out"
+		}
+		if opts.UncordonOnFailure && result.Cordoned {
+			// The drain context may be exhausted; the rollback gets its own.
+			undoCtx, cancel := context.WithTimeout(context.Background(), DefaultAPITimeout)
+			defer cancel()
+			if err := setNodeUnschedulable(undoCtx, clientset, node, false); err != nil {
+				result.Error += "; " + err.Error()
+			} else {
+				result.Uncordoned = true
+			}
+		}
+	}
+	result.Duration = time.Since(start).Round(time.Second).String()
+	return result, nil
+}
+
+// podsToEvict selects the pods a drain evicts, recording skipped ones in
+// result. Like kubectl drain it refuses, before changing anything, pods that
+// would be lost without Force or DeleteEmptyDirData.
+func podsToEvict(pods []corev1.Pod, node string, opts DrainOptions, result *DrainResult) ([]corev1.Pod, error) {
+	var evict []corev1.Pod
+	var unmanaged, localData []string
+	for _, pod := range pods {
+		id := pod.Namespace + "/" + pod.Name
+		if pod.Spec.NodeName != node || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		controller := metav1.GetControllerOf(&pod)
+		if _, mirror := pod.Annotations[mirrorPodAnnotation]; mirror || (controller != nil && controller.Kind == "DaemonSet") {
+			result.Skipped = append(result.Skipped, id)
+			continue
+		}
+		if controller == nil && !opts.Force {
+			unmanaged = append(unmanaged, id)
+		}
+		for _, v := range pod.Spec.Volumes {
+			if v.EmptyDir != nil && !opts.DeleteEmptyDirData {
+				localData = append(localData, id)
+				break
+			}
+		}
+		evict = append(evict, pod)
+	}
+	var problems []string
+	if len(unmanaged) > 0 {
+		problems = append(problems, fmt.Sprintf("pods without a controller would not be recreated (use force): %s", strings.Join(unmanaged, ", ")))
+	}
+	if len(localData) > 0 {
+		problems = append(problems, fmt.Sprintf("pods with emptyDir data would lose it (use delete_emptydir_data): %s", strings.Join(localData, ", ")))
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("cannot drain: %s", strings.Join(problems, "; "))
+	}
+	sort.Strings(result.Skipped)
+	return evict, nil
+}
+
+// evictAndWait evicts pod, retrying while a PDB refuses, and waits until it
+// is deleted or replaced by a pod of the same name.
+func evictAndWait(ctx context.Context, clientset kubernetes.Interface, pod corev1.Pod, gracePeriod *int64, progress func(status, msg string)) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta:    metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		DeleteOptions: &metav1.DeleteOptions{GracePeriodSeconds: gracePeriod},
+	}
+	progress(DrainEvicting, "")
+	for {
+		err := clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+		if err == nil || apierrors.IsNotFound(err) {
+			break
+		}
+		if !apierrors.IsTooManyRequests(err) {
+			return fmt.Errorf("eviction failed: %w", err)
+		}
+		progress(DrainBlocked, fmt.Sprintf("refused by a disruption budget, retrying in %s", evictionRetryInterval))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("eviction blocked by a disruption budget: %w", ctx.Err())
+		case <-time.After(evictionRetryInterval):
+		}
+	}
+
+	for {
+		current, err := clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) || (err == nil && current.UID != pod.UID) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("pod not deleted: %w", ctx.Err())
+		case <-time.After(drainPollInterval):
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fastDrain shortens the drain intervals for the test.
+func fastDrain(t *testing.T) {
+	t.Helper()
+	retry, poll := evictionRetryInterval, drainPollInterval
+	evictionRetryInterval, drainPollInterval = time.Millisecond, time.Millisecond
+	t.Cleanup(func() { evictionRetryInterval, drainPollInterval = retry, poll })
+}
+
+// evictionReactor deletes evicted pods, refusing the first refusals
+// evictions of pods named in blocked as a PDB would.
+func evictionReactor(client *fake.Clientset, blocked map[string]int) {
+	var mu sync.Mutex
+	client.PrependReactor("create", "pods", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		if a.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		name := a.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction).Name
+		mu.Lock()
+		defer mu.Unlock()
+		if blocked[name] != 0 {
+			if blocked[name] > 0 {
+				blocked[name]--
+			}
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		return true, nil, client.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), a.GetNamespace(), name)
+	})
+}
+
+func drainClientset() *fake.Clientset {
+	node1, node2 := osNode("node-1", OSLinux, true), osNode("node-2", OSLinux, true)
+	mirror := appPod("kube-system", "etcd-node-1", "etcd", "node-1")
+	mirror.Annotations = map[string]string{mirrorPodAnnotation: "hash"}
+	return fake.NewClientset(&node1, &node2,
+		controlledPod("web-abc-1", "web", "node-1", "ReplicaSet", "web-abc"),
+		controlledPod("web-abc-2", "web", "node-1", "ReplicaSet", "web-abc"),
+		controlledPod("web-abc-3", "web", "node-2", "ReplicaSet", "web-abc"),
+		controlledPod("fluentd-1", "fluentd", "node-1", "DaemonSet", "fluentd"),
+		mirror,
+	)
+}
+
+func TestDrainNode(t *testing.T) {
+	fastDrain(t)
+	client := drainClientset()
+	evictionReactor(client, map[string]int{"web-abc-2": 2})
+	var mu sync.Mutex
+	var events []DrainEvent
+	opts := DrainOptions{Progress: func(e DrainEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}}
+
+	result, err := drainNode(context.Background(), client, "node-1", opts)
+	if err != nil {
+		t.Fatalf("drainNode() error = %v", err)
+	}
+	if !result.Cordoned || result.Error != "" {
+		t.Errorf("Cordoned = %v, Error = %q", result.Cordoned, result.Error)
+	}
+	if got := strings.Join(result.Evicted, ","); got != "apps/web-abc-1,apps/web-abc-2" {
+		t.Errorf("Evicted = %s", got)
+	}
+	if got := strings.Join(result.Skipped, ","); got != "apps/fluentd-1,kube-system/etcd-node-1" {
+		t.Errorf("Skipped = %s", got)
+	}
+	node, _ := client.CoreV1().Nodes().Get(context.Background(), "node-1", metav1.GetOptions{})
+	if !node.Spec.Unschedulable {
+		t.Error("node-1 is not cordoned")
+	}
+
+	blocked, evicted := 0, 0
+	for _, e := range events {
+		switch e.Status {
+		case DrainBlocked:
+			blocked++
+		case DrainEvicted:
+			evicted++
+		}
+	}
+	if events[0].Status != DrainCordoned || blocked != 2 || evicted != 2 {
+		t.Errorf("events: first %q, %d blocked, %d evicted", events[0].Status, blocked, evicted)
+	}
+	if last := events[len(events)-1]; last.Done != 2 || last.Total != 2 {
+		t.Errorf("last event progress = %d/%d, want 2/2", last.Done, last.Total)
+	}
+}
+
+func TestDrainNodeTimeoutUncordons(t *testing.T) {
+	fastDrain(t)
+	client := drainClientset()
+	evictionReactor(client, map[string]int{"web-abc-1": -1})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	result, err := drainNode(ctx, client, "node-1", DrainOptions{UncordonOnFailure: true})
+	if err != nil {
+		t.Fatalf("drainNode() error = %v", err)
+	}
+	if strings.Join(result.Failed, ",") != "apps/web-abc-1" || !strings.Contains(result.Error, "timed out") {
+		t.Errorf("Failed = %v, Error = %q", result.Failed, result.Error)
+	}
+	if !result.Uncordoned {
+		t.Error("Uncordoned = false, want the node rolled back")
+	}
+	node, _ := client.CoreV1().Nodes().Get(context.Background(), "node-1", metav1.GetOptions{})
+	if node.Spec.Unschedulable {
+		t.Error("node-1 is still cordoned")
+	}
+}
+
+func TestDrainNodeRefusesLostPods(t *testing.T) {
+	client := drainClientset()
+	bare := appPod("apps", "debug", "debug", "node-1")
+	bare.Spec.Volumes = []corev1.Volume{{Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+	if _, err := client.CoreV1().Pods("apps").Create(context.Background(), bare, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := drainNode(context.Background(), client, "node-1", DrainOptions{})
+	if err == nil || !strings.Contains(err.Error(), "use force") || !strings.Contains(err.Error(), "use delete_emptydir_data") {
+		t.Fatalf("drainNode() error = %v, want force and emptyDir refusals", err)
+	}
+	node, _ := client.CoreV1().Nodes().Get(context.Background(), "node-1", metav1.GetOptions{})
+	if node.Spec.Unschedulable {
+		t.Error("a refused drain must not cordon the node")
+	}
+	if _, err := drainNode(context.Background(), client, "node-9", DrainOptions{}); err == nil {
+		t.Error("expected error for an unknown node")
+	}
+}
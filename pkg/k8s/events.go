@@ -0,0 +1,216 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the event noise ranking (event counts by namespace and reason).
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// systemicEventObjects is the number of distinct objects sharing one event
+// reason in a namespace at which the source is reported as systemic.
+const systemicEventObjects = 5
+
+// eventReasonHints points frequent event reasons at their usual systemic cause.
+var eventReasonHints = map[string]string{
+	"FailedScheduling":        "pods cannot be placed: check node capacity, taints, affinity and quota",
+	"BackOff":                 "containers crash-looping or images failing to pull: check logs and image references",
+	"Unhealthy":               "probes failing: check probe endpoints, timeouts and dependencies",
+	"FailedMount":             "volumes or secrets not mountable: check PVCs, CSI drivers and referenced secrets",
+	"FailedAttachVolume":      "volumes not attachable: check the CSI driver and volume zone vs node zone",
+	"FailedCreate":            "controllers cannot create pods: check quota, LimitRanges and admission webhooks",
+	"Failed":                  "container start or image pull failures: check image names and registry access",
+	"Evicted":                 "node pressure evictions: check node memory/disk and pod requests",
+	"FailedCreatePodSandBox":  "pod networking setup failing: check the CNI plugin and IP address exhaustion",
+	"NodeNotReady":            "nodes flapping: check kubelet and node conditions",
+	"FailedGetResourceMetric": "autoscaler cannot read metrics: check metrics-server",
+	"OOMKilling":              "kernel OOM kills: raise memory limits or fix leaks",
+}
+
+// EventSource is the aggregated noise of one reason in one namespace.
+type EventSource struct {
+	Namespace string    `json:"namespace"`
+	Reason    string    `json:"reason"`
+	Type      string    `json:"type"`
+	Count     int32     `json:"count"`   // occurrences of events last seen in the window
+	Objects   int       `json:"objects"` // distinct involved objects
+	Kinds     []string  `json:"kinds"`
+	LastSeen  time.Time `json:"last_seen"`
+	Sample    string    `json:"sample"` // most recent message
+	Systemic  bool      `json:"systemic"`
+	Hint      string    `json:"hint,omitempty"`
+}
+
+// NamespaceEventNoise is the event total of one namespace.
+type NamespaceEventNoise struct {
+	Namespace string `json:"namespace"`
+	Count     int32  `json:"count"`
+	Warnings  int32  `json:"warnings"`
+	TopReason string `json:"top_reason"`
+}
+
+// EventNoiseReport ranks the noisiest event sources over a window.
+type EventNoiseReport struct {
+	Context    string                `json:"context"`
+	Since      time.Time             `json:"since"`
+	Total      int32                 `json:"total"`
+	Sources    []EventSource         `json:"sources"`
+	Namespaces []NamespaceEventNoise `json:"namespaces"`
+}
+
+// eventLastSeen returns the latest time an event was observed.
+func eventLastSeen(ev *corev1.Event) time.Time {
+	last := ev.LastTimestamp.Time
+	if ev.Series != nil && ev.Series.LastObservedTime.After(last) {
+		last = ev.Series.LastObservedTime.Time
+	}
+	if ev.EventTime.After(last) {
+		last = ev.EventTime.Time
+	}
+	if last.IsZero() {
+		last = ev.CreationTimestamp.Time
+	}
+	return last
+}
+
+// eventCount returns how often an event occurred.
+func eventCount(ev *corev1.Event) int32 {
+	count := ev.Count
+	if ev.Series != nil && ev.Series.Count > count {
+		count = ev.Series.Count
+	}
+	return max(count, 1)
+}
+
+// rankEventNoise aggregates events last seen at or after since by namespace,
+// reason and type, and returns the top sources by count with per-namespace totals.
+func rankEventNoise(events []corev1.Event, since time.Time, warningsOnly bool, top int) *EventNoiseReport {
+	type sourceAcc struct {
+		EventSource
+		objects map[string]bool
+		kinds   map[string]bool
+	}
+	sources := make(map[string]*sourceAcc)
+	namespaces := make(map[string]*NamespaceEventNoise)
+	report := &EventNoiseReport{Since: since, Sources: []EventSource{}, Namespaces: []NamespaceEventNoise{}}
+
+	for i := range events {
+		ev := &events[i]
+		last := eventLastSeen(ev)
+		if last.Before(since) || (warningsOnly && ev.Type != corev1.EventTypeWarning) {
+			continue
+		}
+		count := eventCount(ev)
+		report.Total += count
+
+		nsNoise := namespaces[ev.Namespace]
+		if nsNoise == nil {
+			nsNoise = &NamespaceEventNoise{Namespace: ev.Namespace}
+			namespaces[ev.Namespace] = nsNoise
+		}
+		nsNoise.Count += count
+		if ev.Type == corev1.EventTypeWarning {
+			nsNoise.Warnings += count
+		}
+
+		key := ev.Namespace + "\x00" + ev.Reason + "\x00" + ev.Type
+		acc := sources[key]
+		if acc == nil {
+			acc = &sourceAcc{
+				EventSource: EventSource{Namespace: ev.Namespace, Reason: ev.Reason, Type: ev.Type, Hint: eventReasonHints[ev.Reason]},
+				objects:     make(map[string]bool),
+				kinds:       make(map[string]bool),
+			}
+			sources[key] = acc
+		}
+		acc.Count += count
+		acc.objects[ev.InvolvedObject.Kind+"/"+ev.InvolvedObject.Name] = true
+		acc.kinds[ev.InvolvedObject.Kind] = true
+		if !last.Before(acc.LastSeen) {
+			acc.LastSeen = last
+			acc.Sample = ev.Message
+		}
+	}
+
+	all := make([]EventSource, 0, len(sources))
+	for _, acc := range sources {
+		acc.Objects = len(acc.objects)
+		acc.Systemic = acc.Objects >= systemicEventObjects
+		acc.Kinds = sortedSet(acc.kinds)
+		all = append(all, acc.EventSource)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i], all[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Reason < b.Reason
+	})
+
+	// The top reason of a namespace is its first source in count order.
+	for _, s := range all {
+		if ns := namespaces[s.Namespace]; ns.TopReason == "" {
+			ns.TopReason = s.Reason
+		}
+	}
+	if top > 0 && len(all) > top {
+		all = all[:top]
+	}
+	report.Sources = all
+
+	for _, ns := range namespaces {
+		report.Namespaces = append(report.Namespaces, *ns)
+	}
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		a, b := report.Namespaces[i], report.Namespaces[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Namespace < b.Namespace
+	})
+	return report
+}
+
+// collectEventNoise lists events and ranks them with rankEventNoise.
+func collectEventNoise(ctx context.Context, clientset kubernetes.Interface, targetNamespace string, since time.Time, warningsOnly bool, top int) (*EventNoiseReport, error) {
+	events, err := clientset.CoreV1().Events(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+	return rankEventNoise(events.Items, since, warningsOnly, top), nil
+}
+
+// RankEventNoise aggregates the events of the last window by namespace and
+// reason and returns the top noisiest sources. If targetNamespace is
+// non-empty, only that namespace is considered.
+func (p *Provider) RankEventNoise(ctx context.Context, contextName, targetNamespace string, window time.Duration, warningsOnly bool, top int) (*EventNoiseReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	var report *EventNoiseReport
+	if live := p.liveFor(contextName); live != nil {
+		events, err := live.eventList(targetNamespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list events: %w", err)
+		}
+		report = rankEventNoise(events, time.Now().Add(-window), warningsOnly, top)
+	} else if report, err = collectEventNoise(queryCtx, clientset, targetNamespace, time.Now().Add(-window), warningsOnly, top); err != nil {
+		return nil, err
+	}
+	report.Context = contextName
+	return report, nil
+}
@@ -0,0 +1,95 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func noiseEvent(ns, name, reason, eventType, kind, object string, count int32, last time.Time) corev1.Event {
+	return corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: ns},
+		Reason:         reason,
+		Type:           eventType,
+		Message:        reason + " on " + object,
+		Count:          count,
+		LastTimestamp:  metav1.NewTime(last),
+		InvolvedObject: corev1.ObjectReference{Kind: kind, Name: object, Namespace: ns},
+	}
+}
+
+func TestRankEventNoise(t *testing.T) {
+	now := time.Now()
+	since := now.Add(-time.Hour)
+	var events []corev1.Event
+	for i := range 6 {
+		pod := fmt.Sprintf("web-%d", i)
+		events = append(events, noiseEvent("shop", "sched-"+pod, "FailedScheduling", corev1.EventTypeWarning, "Pod", pod, 10, now.Add(-time.Duration(i)*time.Minute)))
+	}
+	events = append(events,
+		noiseEvent("shop", "probe", "Unhealthy", corev1.EventTypeWarning, "Pod", "api-0", 7, now),
+		noiseEvent("shop", "pulled", "Pulled", corev1.EventTypeNormal, "Pod", "api-0", 50, now),
+		noiseEvent("batch", "old", "BackOff", corev1.EventTypeWarning, "Pod", "job-0", 100, now.Add(-2*time.Hour)),
+		noiseEvent("batch", "mount", "FailedMount", corev1.EventTypeWarning, "Pod", "job-1", 3, now),
+	)
+	series := noiseEvent("batch", "series", "BackOff", corev1.EventTypeWarning, "Pod", "job-2", 0, time.Time{})
+	series.Series = &corev1.EventSeries{Count: 9, LastObservedTime: metav1.NewMicroTime(now)}
+	events = append(events, series)
+
+	report := rankEventNoise(events, since, true, 0)
+	if report.Total != 60+7+3+9 {
+		t.Errorf("Total = %d, want 79", report.Total)
+	}
+	if len(report.Sources) != 4 {
+		t.Fatalf("Sources = %+v, want 4 entries", report.Sources)
+	}
+	top := report.Sources[0]
+	if top.Reason != "FailedScheduling" || top.Count != 60 || top.Objects != 6 || !top.Systemic || top.Hint == "" {
+		t.Errorf("top source = %+v, want systemic FailedScheduling x60 on 6 objects", top)
+	}
+	if top.Sample != "FailedScheduling on web-0" {
+		t.Errorf("Sample = %q, want the most recent message", top.Sample)
+	}
+	if report.Sources[1].Reason != "BackOff" || report.Sources[1].Count != 9 || report.Sources[1].Systemic {
+		t.Errorf("second source = %+v, want BackOff x9 from the event series", report.Sources[1])
+	}
+	for _, s := range report.Sources {
+		if s.Type != corev1.EventTypeWarning {
+			t.Errorf("source %+v is not a warning in warnings-only mode", s)
+		}
+	}
+	if len(report.Namespaces) != 2 || report.Namespaces[0].Namespace != "shop" || report.Namespaces[0].TopReason != "FailedScheduling" {
+		t.Errorf("Namespaces = %+v, want shop first with top FailedScheduling", report.Namespaces)
+	}
+	if report.Namespaces[1].TopReason != "BackOff" || report.Namespaces[1].Warnings != 12 {
+		t.Errorf("batch = %+v, want top BackOff and 12 warnings", report.Namespaces[1])
+	}
+
+	all := rankEventNoise(events, since, false, 2)
+	if len(all.Sources) != 2 || all.Sources[1].Reason != "Pulled" {
+		t.Errorf("Sources = %+v, want top 2 including Normal Pulled", all.Sources)
+	}
+	if all.Namespaces[0].Count != 117 || all.Namespaces[0].Warnings != 67 {
+		t.Errorf("shop = %+v, want 117 occurrences with 67 warnings", all.Namespaces[0])
+	}
+}
+
+func TestCollectEventNoise(t *testing.T) {
+	now := time.Now()
+	a := noiseEvent("shop", "a", "FailedMount", corev1.EventTypeWarning, "Pod", "web-0", 4, now)
+	b := noiseEvent("other", "b", "FailedMount", corev1.EventTypeWarning, "Pod", "web-0", 4, now)
+	clientset := fake.NewClientset(&a, &b)
+
+	report, err := collectEventNoise(context.Background(), clientset, "shop", now.Add(-time.Hour), true, 10)
+	if err != nil {
+		t.Fatalf("collectEventNoise: %v", err)
+	}
+	if report.Total != 4 || len(report.Namespaces) != 1 || report.Namespaces[0].Namespace != "shop" {
+		t.Errorf("report = %+v, want only the shop namespace", report)
+	}
+}
@@ -0,0 +1,157 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains in-container command execution via the pods/exec subresource.
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// defaultContainerAnnotation names the container kubectl exec uses when none is given.
+const defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+
+// ExecRequest describes a command to run inside a container.
+type ExecRequest struct {
+	Namespace string
+	Pod       string
+	// Container defaults to the pod's default-container annotation, then its first container.
+	Container string
+	Command   []string
+	Stdin     io.Reader
+	Stdout    io.Writer
+	Stderr    io.Writer
+	// TTY allocates a terminal in the container; Stderr is merged into Stdout.
+	TTY bool
+	// SizeQueue reports terminal size changes when TTY is set. Optional.
+	SizeQueue remotecommand.TerminalSizeQueue
+}
+
+// newExecutor builds the stream executor for an exec URL. It prefers the
+// WebSocket protocol and falls back to SPDY for older API servers.
+// Replaced in tests.
+var newExecutor = func(config *rest.Config, u *url.URL) (remotecommand.Executor, error) {
+	spdyExec, err := remotecommand.NewSPDYExecutor(config, http.MethodPost, u)
+	if err != nil {
+		return nil, err
+	}
+	wsExec, err := remotecommand.NewWebSocketExecutor(config, http.MethodGet, u.String())
+	if err != nil {
+		return nil, err
+	}
+	return remotecommand.NewFallbackExecutor(wsExec, spdyExec, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	})
+}
+
+// resolveExecContainer checks that the pod is running and returns the container to exec into.
+func resolveExecContainer(ctx context.Context, clientset kubernetes.Interface, namespace, podName, container string) (string, error) {
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get pod %s/%s: %w", namespace, podName, err)
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		return "", fmt.Errorf("pod %s/%s is %s; exec requires a running pod", namespace, podName, pod.Status.Phase)
+	}
+	if container == "" {
+		container = pod.Annotations[defaultContainerAnnotation]
+	}
+	if container == "" {
+		if len(pod.Spec.Containers) == 0 {
+			return "", fmt.Errorf("pod %s/%s has no containers", namespace, podName)
+		}
+		return pod.Spec.Containers[0].Name, nil
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name == container {
+			return container, nil
+		}
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		if c.Name == container {
+			return container, nil
+		}
+	}
+	return "", fmt.Errorf("container %q not found in pod %s/%s", container, namespace, podName)
+}
+
+// execURL builds the pods/exec subresource URL for req.
+func execURL(restClient rest.Interface, req ExecRequest) *url.URL {
+	return restClient.Post().
+		Resource("pods").
+		Namespace(req.Namespace).
+		Name(req.Pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: req.Container,
+			Command:   req.Command,
+			Stdin:     req.Stdin != nil,
+			Stdout:    req.Stdout != nil,
+			Stderr:    req.Stderr != nil && !req.TTY,
+			TTY:       req.TTY,
+		}, scheme.ParameterCodec).
+		URL()
+}
+
+// streamExec runs the exec stream and converts a remote non-zero exit into an exit code.
+func streamExec(ctx context.Context, executor remotecommand.Executor, req ExecRequest) (int, error) {
+	opts := remotecommand.StreamOptions{
+		Stdin:             req.Stdin,
+		Stdout:            req.Stdout,
+		Tty:               req.TTY,
+		TerminalSizeQueue: req.SizeQueue,
+	}
+	if !req.TTY {
+		opts.Stderr = req.Stderr
+	}
+	err := executor.StreamWithContext(ctx, opts)
+	if err == nil {
+		return 0, nil
+	}
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		return exitErr.ExitStatus(), nil
+	}
+	return -1, fmt.Errorf("exec in %s/%s failed: %w", req.Namespace, req.Pod, err)
+}
+
+// ExecInPod runs req.Command in a container and streams its I/O through req.
+// A command that runs and exits non-zero is not an error: its exit code is returned.
+// The call blocks until the command exits or ctx is cancelled.
+func (p *Provider) ExecInPod(ctx context.Context, contextName string, req ExecRequest) (int, error) {
+	if len(req.Command) == 0 {
+		return -1, fmt.Errorf("command is required")
+	}
+	if req.Namespace == "" {
+		req.Namespace = corev1.NamespaceDefault
+	}
+	clientset, restConfig, err := p.createClientset(contextName)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	req.Container, err = resolveExecContainer(queryCtx, clientset, req.Namespace, req.Pod, req.Container)
+	cancel()
+	if err != nil {
+		return -1, err
+	}
+
+	executor, err := newExecutor(restConfig, execURL(clientset.CoreV1().RESTClient(), req))
+	if err != nil {
+		return -1, fmt.Errorf("failed to create exec stream: %w", err)
+	}
+	return streamExec(ctx, executor, req)
+}
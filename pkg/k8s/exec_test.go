@@ -0,0 +1,107 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// fakeExecutor writes canned output and returns err.
+type fakeExecutor struct {
+	stdout, stderr string
+	err            error
+	opts           remotecommand.StreamOptions
+}
+
+func (f *fakeExecutor) Stream(opts remotecommand.StreamOptions) error {
+	return f.StreamWithContext(context.Background(), opts)
+}
+
+func (f *fakeExecutor) StreamWithContext(_ context.Context, opts remotecommand.StreamOptions) error {
+	f.opts = opts
+	if opts.Stdout != nil {
+		_, _ = opts.Stdout.Write([]byte(f.stdout))
+	}
+	if opts.Stderr != nil {
+		_, _ = opts.Stderr.Write([]byte(f.stderr))
+	}
+	return f.err
+}
+
+func TestResolveExecContainer(t *testing.T) {
+	running := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "sidecar"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	annotated := running.DeepCopy()
+	annotated.Name = "web-annotated"
+	annotated.Annotations = map[string]string{defaultContainerAnnotation: "sidecar"}
+	pending := running.DeepCopy()
+	pending.Name = "web-pending"
+	pending.Status.Phase = corev1.PodPending
+	clientset := fake.NewClientset(running, annotated, pending)
+
+	tests := []struct {
+		pod, container string
+		want, wantErr  string
+	}{
+		{"web", "", "app", ""},
+		{"web", "sidecar", "sidecar", ""},
+		{"web-annotated", "", "sidecar", ""},
+		{"web", "missing", "", "not found"},
+		{"web-pending", "", "", "requires a running pod"},
+		{"absent", "", "", "failed to get pod"},
+	}
+	for _, tt := range tests {
+		got, err := resolveExecContainer(context.Background(), clientset, "shop", tt.pod, tt.container)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("resolve(%s, %q) error = %v, want containing %q", tt.pod, tt.container, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("resolve(%s, %q) = %q, %v; want %q", tt.pod, tt.container, got, err, tt.want)
+		}
+	}
+}
+
+func TestStreamExec(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	req := ExecRequest{Namespace: "shop", Pod: "web", Stdout: &stdout, Stderr: &stderr}
+
+	code, err := streamExec(context.Background(), &fakeExecutor{stdout: "ok\n", stderr: "warn\n"}, req)
+	if err != nil || code != 0 || stdout.String() != "ok\n" || stderr.String() != "warn\n" {
+		t.Errorf("success: code=%d err=%v stdout=%q stderr=%q", code, err, stdout.String(), stderr.String())
+	}
+
+	exitErr := utilexec.CodeExitError{Err: errors.New("command terminated with exit code 2"), Code: 2}
+	code, err = streamExec(context.Background(), &fakeExecutor{err: exitErr}, req)
+	if err != nil || code != 2 {
+		t.Errorf("non-zero exit: code=%d err=%v, want 2 and no error", code, err)
+	}
+
+	code, err = streamExec(context.Background(), &fakeExecutor{err: errors.New("upgrade refused")}, req)
+	if err == nil || code != -1 || !strings.Contains(err.Error(), "shop/web") {
+		t.Errorf("stream failure: code=%d err=%v", code, err)
+	}
+
+	// With a TTY, stderr is merged into stdout by the container runtime.
+	tty := &fakeExecutor{}
+	req.TTY = true
+	if _, err := streamExec(context.Background(), tty, req); err != nil {
+		t.Fatalf("tty: %v", err)
+	}
+	if !tty.opts.Tty || tty.opts.Stderr != nil {
+		t.Errorf("tty stream options = %+v", tty.opts)
+	}
+}
@@ -0,0 +1,207 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file collects Ingress and LoadBalancer Service health for cluster status.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// exposurePendingGrace is how long a new Ingress or LoadBalancer Service may
+// wait for an address before it is reported.
+const exposurePendingGrace = 5 * time.Minute
+
+// ExposureIssue describes an externally exposed endpoint (Ingress or
+// LoadBalancer Service) that is not serving correctly.
+type ExposureIssue struct {
+	Namespace string
+	Kind      string
+	Name      string
+	Problem   string
+}
+
+// exposureData holds the objects needed to assess exposed endpoints.
+type exposureData struct {
+	services  map[string]*corev1.Service // namespace/name
+	endpoints map[string]int             // namespace/name -> ready endpoint addresses
+	ingresses []networkingv1.Ingress
+	secrets   func(namespace, name string) (*corev1.Secret, error)
+}
+
+// readyEndpointCounts sums ready endpoint addresses per owning Service.
+func readyEndpointCounts(slices []discoveryv1.EndpointSlice) map[string]int {
+	counts := make(map[string]int)
+	for _, slice := range slices {
+		svc := slice.Labels[discoveryv1.LabelServiceName]
+		if svc == "" {
+			continue
+		}
+		key := slice.Namespace + "/" + svc
+		for _, ep := range slice.Endpoints {
+			if ep.Conditions.Ready == nil || *ep.Conditions.Ready {
+				counts[key] += len(ep.Addresses)
+			}
+		}
+	}
+	return counts
+}
+
+// pendingTooLong reports whether an object created at created has had longer
+// than exposurePendingGrace to get an address.
+func pendingTooLong(created metav1.Time, now time.Time) bool {
+	return now.Sub(created.Time) > exposurePendingGrace
+}
+
+// checkLoadBalancer returns the problems of a type=LoadBalancer Service.
+func checkLoadBalancer(svc *corev1.Service, data exposureData, now time.Time) []string {
+	var problems []string
+	if len(svc.Status.LoadBalancer.Ingress) == 0 && pendingTooLong(svc.CreationTimestamp, now) {
+		problems = append(problems, fmt.Sprintf("no external address assigned after %s (load balancer pending)", now.Sub(svc.CreationTimestamp.Time).Round(time.Minute)))
+	}
+	if len(svc.Spec.Selector) > 0 && data.endpoints[svc.Namespace+"/"+svc.Name] == 0 {
+		problems = append(problems, "no ready endpoints; traffic to the load balancer will fail")
+	}
+	return problems
+}
+
+// checkIngressBackend returns the problem with an Ingress backend, if any.
+func checkIngressBackend(namespace string, backend *networkingv1.IngressBackend, data exposureData) string {
+	if backend == nil || backend.Service == nil {
+		return ""
+	}
+	ref := backend.Service
+	svc, ok := data.services[namespace+"/"+ref.Name]
+	if !ok {
+		return fmt.Sprintf("backend service %s not found", ref.Name)
+	}
+	portFound := false
+	for _, p := range svc.Spec.Ports {
+		if (ref.Port.Name != "" && p.Name == ref.Port.Name) || (ref.Port.Number != 0 && p.Port == ref.Port.Number) {
+			portFound = true
+			break
+		}
+	}
+	if !portFound {
+		port := ref.Port.Name
+		if port == "" {
+			port = fmt.Sprintf("%d", ref.Port.Number)
+		}
+		return fmt.Sprintf("backend service %s has no port %s", ref.Name, port)
+	}
+	if svc.Spec.Type != corev1.ServiceTypeExternalName && data.endpoints[namespace+"/"+ref.Name] == 0 {
+		return fmt.Sprintf("backend service %s has no ready endpoints", ref.Name)
+	}
+	return ""
+}
+
+// checkIngress returns the problems of an Ingress: missing address, failed
+// backends, and missing or malformed TLS secrets.
+func checkIngress(ing *networkingv1.Ingress, data exposureData, now time.Time) []string {
+	var problems []string
+	if len(ing.Status.LoadBalancer.Ingress) == 0 && pendingTooLong(ing.CreationTimestamp, now) {
+		problems = append(problems, "no address assigned; is an ingress controller running for its class?")
+	}
+
+	seen := make(map[string]bool)
+	addBackend := func(backend *networkingv1.IngressBackend) {
+		if p := checkIngressBackend(ing.Namespace, backend, data); p != "" && !seen[p] {
+			seen[p] = true
+			problems = append(problems, p)
+		}
+	}
+	addBackend(ing.Spec.DefaultBackend)
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for i := range rule.HTTP.Paths {
+			addBackend(&rule.HTTP.Paths[i].Backend)
+		}
+	}
+
+	for _, tls := range ing.Spec.TLS {
+		if tls.SecretName == "" || data.secrets == nil {
+			continue
+		}
+		secret, err := data.secrets(ing.Namespace, tls.SecretName)
+		switch {
+		case apierrors.IsNotFound(err):
+			problems = append(problems, fmt.Sprintf("TLS secret %s not found", tls.SecretName))
+		case err != nil:
+			// Secrets may be hidden by RBAC; the check is best effort.
+		case secret.Type != corev1.SecretTypeTLS:
+			problems = append(problems, fmt.Sprintf("TLS secret %s has type %s, want %s", tls.SecretName, secret.Type, corev1.SecretTypeTLS))
+		}
+	}
+	return problems
+}
+
+// assessExposure checks every LoadBalancer Service and Ingress in data.
+func assessExposure(data exposureData, now time.Time) []ExposureIssue {
+	issues := make([]ExposureIssue, 0)
+	for _, svc := range data.services {
+		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		for _, p := range checkLoadBalancer(svc, data, now) {
+			issues = append(issues, ExposureIssue{Namespace: svc.Namespace, Kind: "Service", Name: svc.Name, Problem: p})
+		}
+	}
+	for i := range data.ingresses {
+		ing := &data.ingresses[i]
+		for _, p := range checkIngress(ing, data, now) {
+			issues = append(issues, ExposureIssue{Namespace: ing.Namespace, Kind: "Ingress", Name: ing.Name, Problem: p})
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return issues
+}
+
+// collectExposureIssues lists Ingresses, Services and EndpointSlices across all
+// namespaces and returns the exposed endpoints with problems.
+func collectExposureIssues(ctx context.Context, clientset kubernetes.Interface) ([]ExposureIssue, error) {
+	svcs, err := clientset.CoreV1().Services("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	ings, err := clientset.NetworkingV1().Ingresses("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ingresses: %w", err)
+	}
+	slices, err := clientset.DiscoveryV1().EndpointSlices("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list endpoint slices: %w", err)
+	}
+
+	data := exposureData{
+		services:  make(map[string]*corev1.Service, len(svcs.Items)),
+		endpoints: readyEndpointCounts(slices.Items),
+		ingresses: ings.Items,
+		secrets: func(namespace, name string) (*corev1.Secret, error) {
+			return clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		},
+	}
+	for i := range svcs.Items {
+		svc := &svcs.Items[i]
+		data.services[svc.Namespace+"/"+svc.Name] = svc
+	}
+	return assessExposure(data, time.Now()), nil
+}
@@ -0,0 +1,129 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func webIngress(port networkingv1.ServiceBackendPort, tlsSecret string) *networkingv1.Ingress {
+	ing := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{
+			Host: "shop.example.com",
+			IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{Paths: []networkingv1.HTTPIngressPath{
+				{Path: "/", Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: "web", Port: port}}},
+				{Path: "/api", Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: "api", Port: port}}},
+			}}},
+		}}},
+		Status: networkingv1.IngressStatus{LoadBalancer: networkingv1.IngressLoadBalancerStatus{
+			Ingress: []networkingv1.IngressLoadBalancerIngress{{IP: "203.0.113.10"}},
+		}},
+	}
+	if tlsSecret != "" {
+		ing.Spec.TLS = []networkingv1.IngressTLS{{Hosts: []string{"shop.example.com"}, SecretName: tlsSecret}}
+	}
+	return ing
+}
+
+func problemsFor(issues []ExposureIssue, kind, name string) []string {
+	var out []string
+	for _, issue := range issues {
+		if issue.Kind == kind && issue.Name == name {
+			out = append(out, issue.Problem)
+		}
+	}
+	return out
+}
+
+func TestCollectExposureIssuesHealthy(t *testing.T) {
+	lb := webService(intstr.FromString("http"))
+	lb.Name = "web-lb"
+	lb.Spec.Type = corev1.ServiceTypeLoadBalancer
+	lb.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "203.0.113.20"}}
+	lbSlice := webSlice(true)
+	lbSlice.Name = "web-lb-abc"
+	lbSlice.Labels = map[string]string{"kubernetes.io/service-name": "web-lb"}
+	api := webService(intstr.FromString("http"))
+	api.Name = "api"
+	apiSlice := webSlice(true)
+	apiSlice.Name = "api-abc"
+	apiSlice.Labels = map[string]string{"kubernetes.io/service-name": "api"}
+
+	clientset := fake.NewClientset(
+		webService(intstr.FromString("http")), webSlice(true), lb, lbSlice, api, apiSlice,
+		webIngress(networkingv1.ServiceBackendPort{Name: "http"}, "web-tls"),
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "web-tls", Namespace: "shop"}, Type: corev1.SecretTypeTLS},
+	)
+	issues, err := collectExposureIssues(context.Background(), clientset)
+	if err != nil {
+		t.Fatalf("collectExposureIssues: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("expected no issues, got %+v", issues)
+	}
+}
+
+func TestCollectExposureIssuesProblems(t *testing.T) {
+	lb := webService(intstr.FromString("http"))
+	lb.Name = "pending-lb"
+	lb.Spec.Type = corev1.ServiceTypeLoadBalancer
+
+	clientset := fake.NewClientset(
+		webService(intstr.FromString("http")), webSlice(false), lb,
+		webIngress(networkingv1.ServiceBackendPort{Number: 8443}, "missing-tls"),
+	)
+	issues, err := collectExposureIssues(context.Background(), clientset)
+	if err != nil {
+		t.Fatalf("collectExposureIssues: %v", err)
+	}
+
+	lbProblems := strings.Join(problemsFor(issues, "Service", "pending-lb"), "; ")
+	for _, want := range []string{"no external address", "no ready endpoints"} {
+		if !strings.Contains(lbProblems, want) {
+			t.Errorf("load balancer problems %q missing %q", lbProblems, want)
+		}
+	}
+	ingProblems := strings.Join(problemsFor(issues, "Ingress", "web"), "; ")
+	for _, want := range []string{"backend service web has no port 8443", "backend service api not found", "TLS secret missing-tls not found"} {
+		if !strings.Contains(ingProblems, want) {
+			t.Errorf("ingress problems %q missing %q", ingProblems, want)
+		}
+	}
+}
+
+func TestCheckIngressAddressAndSecretType(t *testing.T) {
+	now := time.Now()
+	ing := webIngress(networkingv1.ServiceBackendPort{Name: "http"}, "web-tls")
+	ing.Status = networkingv1.IngressStatus{}
+	ing.CreationTimestamp = metav1.NewTime(now.Add(-time.Minute))
+	data := exposureData{
+		services:  map[string]*corev1.Service{"shop/web": webService(intstr.FromString("http"))},
+		endpoints: map[string]int{"shop/web": 1},
+		secrets: func(_, _ string) (*corev1.Secret, error) {
+			return &corev1.Secret{Type: corev1.SecretTypeOpaque}, nil
+		},
+	}
+	data.services["shop/api"] = data.services["shop/web"]
+	data.endpoints["shop/api"] = 1
+
+	problems := strings.Join(checkIngress(ing, data, now), "; ")
+	if strings.Contains(problems, "no address") {
+		t.Errorf("new ingress should get a grace period: %q", problems)
+	}
+	if !strings.Contains(problems, "has type Opaque") {
+		t.Errorf("non-TLS secret should be reported: %q", problems)
+	}
+
+	ing.CreationTimestamp = metav1.NewTime(now.Add(-time.Hour))
+	if problems := strings.Join(checkIngress(ing, data, now), "; "); !strings.Contains(problems, "no address assigned") {
+		t.Errorf("old ingress without address should be reported: %q", problems)
+	}
+}
@@ -0,0 +1,103 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file classifies why a cluster could not be checked, so the cause
+// comes with advice the user can act on rather than a raw error string.
+package k8s
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+)
+
+// FailureKind is the cause of a failed cluster check.
+type FailureKind string
+
+// Failure kinds, from the kubeconfig to the API server's answer.
+const (
+	// FailureConfig: the kubeconfig entry of the context is unusable.
+	FailureConfig FailureKind = "config"
+	// FailureDNS: the API server's host name does not resolve.
+	FailureDNS FailureKind = "dns"
+	// FailureRefused: nothing listens at the API server's address.
+	FailureRefused FailureKind = "connection_refused"
+	// FailureTimeout: the API server did not answer in time.
+	FailureTimeout FailureKind = "timeout"
+	// FailureTLS: the TLS handshake or the server certificate failed.
+	FailureTLS FailureKind = "tls"
+	// FailureCredentials: the exec credential plugin failed, typically
+	// because its login expired.
+	FailureCredentials FailureKind = "credentials"
+	// FailureUnauthorized: the API server rejected the credentials (401).
+	FailureUnauthorized FailureKind = "unauthorized"
+	// FailureForbidden: the credentials lack permission (403).
+	FailureForbidden FailureKind = "forbidden"
+	// FailureBusy: the API server kept throttling or timing out.
+	FailureBusy FailureKind = "busy"
+	// FailureUnknown: any other error.
+	FailureUnknown FailureKind = "unknown"
+)
+
+// classifyFailure returns the cause of err, from checking the cluster of
+// restConfig, and advice for fixing it. restConfig may be nil.
+func classifyFailure(err error, restConfig *rest.Config) (FailureKind, string) {
+	var dnsErr *net.DNSError
+	var certInvalid x509.CertificateInvalidError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var recordErr tls.RecordHeaderError
+	var netErr net.Error
+	msg := err.Error()
+	switch {
+	case IsTransient(err):
+		return FailureBusy, "The API server is overloaded; retry in a few minutes, or raise --api-retries."
+	case strings.Contains(msg, "getting credentials"):
+		return FailureCredentials, credentialAdvice(restConfig, "The credential plugin failed")
+	case apierrors.IsUnauthorized(err):
+		return FailureUnauthorized, credentialAdvice(restConfig, "The API server rejected the credentials")
+	case apierrors.IsForbidden(err):
+		return FailureForbidden, "The credentials are valid but lack permission; check the user's RBAC bindings, e.g. with kubectl auth can-i --list."
+	case errors.As(err, &dnsErr):
+		return FailureDNS, fmt.Sprintf("The host name %s does not resolve; check the server URL in the kubeconfig, and connect the VPN if the cluster is private.", dnsErr.Name)
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return FailureRefused, "Nothing is listening at the API server address; the cluster may be stopped or deleted (for local clusters start it, e.g. minikube start), or the port is wrong."
+	case errors.As(err, &certInvalid) && certInvalid.Reason == x509.Expired:
+		return FailureTLS, "The API server certificate or the client certificate has expired; renew it, or fetch fresh credentials for the cluster."
+	case errors.As(err, &unknownAuthority):
+		return FailureTLS, "The API server certificate is signed by an unknown authority; update certificate-authority-data in the kubeconfig, e.g. by fetching the credentials again."
+	case errors.As(err, &hostnameErr):
+		return FailureTLS, "The API server certificate does not match its address; use the host name from the certificate, or set tls-server-name in the kubeconfig."
+	case errors.As(err, &certInvalid), errors.As(err, &recordErr), strings.Contains(msg, "x509:"), strings.Contains(msg, "tls:"):
+		return FailureTLS, "The TLS handshake failed; check that the server URL uses https and the right port, and that the certificates in the kubeconfig are current."
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return FailureTimeout, "The API server did not answer in time; check the VPN, firewall or bastion, and whether the cluster is running."
+	}
+	return FailureUnknown, ""
+}
+
+// credentialAdvice tells how to refresh the credentials of restConfig: the
+// login of its exec plugin, or the kubeconfig's token or certificate.
+func credentialAdvice(restConfig *rest.Config, problem string) string {
+	if restConfig == nil || restConfig.ExecProvider == nil {
+		return problem + "; the token or client certificate in the kubeconfig is probably expired or revoked, fetch fresh credentials for the cluster."
+	}
+	login := loginCommand(restConfig.ExecProvider)
+	if login.Plugin {
+		return fmt.Sprintf("%s; the login of the credential plugin has probably expired, run %s to log in again.", problem, login)
+	}
+	return fmt.Sprintf("%s; your login has probably expired, run %s.", problem, login)
+}
+
+// setFailure records err as the failure of the status: its message, whether
+// it is transient, and its classified cause.
+func (s *ClusterStatus) setFailure(message string, transient bool, err error, restConfig *rest.Config) {
+	s.Error, s.Transient = message, transient
+	s.Failure, s.Advice = classifyFailure(err, restConfig)
+}
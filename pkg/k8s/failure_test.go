@@ -0,0 +1,72 @@
+package k8s
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	// Errors arrive wrapped in the url.Error of the failed request.
+	request := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://10.0.0.1:6443/version", Err: err}
+	}
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	tests := []struct {
+		name   string
+		err    error
+		want   FailureKind
+		advice string
+	}{
+		{"dns", request(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "api.prod.internal", IsNotFound: true}}), FailureDNS, "api.prod.internal does not resolve"},
+		{"refused", request(refused), FailureRefused, "Nothing is listening"},
+		{"probe timeout", fmt.Errorf("API server 10.0.0.1:6443 did not accept a connection within 1s: %w", context.DeadlineExceeded), FailureTimeout, "VPN"},
+		{"unknown authority", request(x509.UnknownAuthorityError{}), FailureTLS, "unknown authority"},
+		{"expired certificate", request(x509.CertificateInvalidError{Reason: x509.Expired}), FailureTLS, "expired"},
+		{"tls string", errors.New("remote error: tls: handshake failure"), FailureTLS, "TLS handshake failed"},
+		{"unauthorized", apierrors.NewUnauthorized("Unauthorized"), FailureUnauthorized, "fetch fresh credentials"},
+		{"forbidden", apierrors.NewForbidden(schema.GroupResource{Resource: "nodes"}, "", errors.New("denied")), FailureForbidden, "RBAC"},
+		{"busy", apierrors.NewTooManyRequests("slow down", 1), FailureBusy, "--api-retries"},
+		{"plugin", request(errors.New("getting credentials: exec: executable foo failed with exit code 1")), FailureCredentials, "credential plugin"},
+		{"unknown", errors.New("the server has asked for the client to provide credentials"), FailureUnknown, ""},
+	}
+	for _, tt := range tests {
+		kind, advice := classifyFailure(tt.err, &rest.Config{})
+		if kind != tt.want || !strings.Contains(advice, tt.advice) {
+			t.Errorf("%s: classifyFailure() = %q, %q; want %q with %q", tt.name, kind, advice, tt.want, tt.advice)
+		}
+	}
+}
+
+func TestCredentialAdvice(t *testing.T) {
+	exec := func(command string, args ...string) *rest.Config {
+		return &rest.Config{ExecProvider: &clientcmdapi.ExecConfig{Command: command, Args: args}}
+	}
+	awsEnv := exec("aws", "eks", "get-token")
+	awsEnv.ExecProvider.Env = []clientcmdapi.ExecEnvVar{{Name: "AWS_PROFILE", Value: "ops"}}
+	for want, config := range map[string]*rest.Config{
+		"run aws sso login --profile prod.":   exec("/usr/local/bin/aws", "eks", "get-token", "--profile", "prod"),
+		"run aws sso login --profile ops.":    awsEnv,
+		"run gcloud auth login.":              exec("gke-gcloud-auth-plugin"),
+		"run az login.":                       exec("kubelogin", "get-token"),
+		"run kubectl oidc-login get-token":    exec("kubectl", "oidc-login", "get-token"),
+		"run vault-k8s login to log in again": exec("vault-k8s", "login"),
+		"token or client certificate in the ": nil,
+	} {
+		if got := credentialAdvice(config, "The API server rejected the credentials"); !strings.Contains(got, want) {
+			t.Errorf("credentialAdvice() = %q, want it to contain %q", got, want)
+		}
+	}
+}
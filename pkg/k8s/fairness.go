@@ -0,0 +1,321 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the multi-tenant quota fairness report: requests per
+// namespace or team against their quota, the cluster and actual usage.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// lowEfficiencyPercent is the usage-to-requests percentage below which a
+// tenant is flagged as reserving far more than it uses.
+const lowEfficiencyPercent = 25.0
+
+// Requests below these are too small for an efficiency finding to matter.
+const (
+	minEfficiencyCPU    = 100               // millicores
+	minEfficiencyMemory = 128 * 1024 * 1024 // bytes
+)
+
+// TenantFairness is the resource footprint of one tenant: a namespace, or
+// every namespace sharing a value of the group label.
+type TenantFairness struct {
+	Tenant          string       `json:"tenant"`
+	Namespaces      []string     `json:"namespaces"`
+	Pods            int          `json:"pods"`
+	RequestedCPU    CPUAmount    `json:"requested_cpu"`
+	RequestedMemory MemoryAmount `json:"requested_memory"`
+	// CPUShare and MemoryShare are the requests as a percentage of the
+	// cluster's schedulable allocatable.
+	CPUShare    float64 `json:"cpu_share"`
+	MemoryShare float64 `json:"memory_share"`
+	// QuotaCPU and QuotaMemory sum the requests quotas of the tenant's
+	// namespaces; nil when none of them has one.
+	QuotaCPU           *CPUAmount    `json:"quota_cpu,omitempty"`
+	QuotaMemory        *MemoryAmount `json:"quota_memory,omitempty"`
+	QuotaCPUPercent    float64       `json:"quota_cpu_percent,omitempty"` // requests of the quota'd namespaces vs their quota
+	QuotaMemoryPercent float64       `json:"quota_memory_percent,omitempty"`
+	WithoutQuota       []string      `json:"without_quota,omitempty"` // namespaces with no requests quota
+	// Usage is only set when metrics-server answered.
+	UsedCPU          CPUAmount    `json:"used_cpu"`
+	UsedMemory       MemoryAmount `json:"used_memory"`
+	IdleCPU          CPUAmount    `json:"idle_cpu"` // requested but unused
+	IdleMemory       MemoryAmount `json:"idle_memory"`
+	CPUEfficiency    float64      `json:"cpu_efficiency"` // usage vs requests
+	MemoryEfficiency float64      `json:"memory_efficiency"`
+	// Overcommitment ranks tenants: idle requests as a percentage of the
+	// cluster, the larger of CPU and memory. Without usage data it is the
+	// larger requests share.
+	Overcommitment float64  `json:"overcommitment"`
+	Findings       []string `json:"findings,omitempty"`
+}
+
+// QuotaFairnessReport compares tenants' requests against their quotas,
+// the cluster and actual usage, most overcommitted first.
+type QuotaFairnessReport struct {
+	Context           string           `json:"context"`
+	GroupLabel        string           `json:"group_label,omitempty"`
+	AllocatableCPU    CPUAmount        `json:"allocatable_cpu"` // schedulable nodes only
+	AllocatableMemory MemoryAmount     `json:"allocatable_memory"`
+	RequestedCPU      CPUAmount        `json:"requested_cpu"`
+	RequestedMemory   MemoryAmount     `json:"requested_memory"`
+	UsageAvailable    bool             `json:"usage_available"`
+	UsageError        string           `json:"usage_error,omitempty"`
+	Tenants           []TenantFairness `json:"tenants"`
+}
+
+// fairnessPodMetrics reads pod metrics for the fairness report; a variable
+// so tests can stand in for metrics-server.
+var fairnessPodMetrics = fetchPodMetrics
+
+// namespaceFootprint accumulates the raw numbers of one namespace.
+type namespaceFootprint struct {
+	pods                     int
+	reqCPU, reqMem           int64
+	usedCPU, usedMem         int64
+	quotaCPU, quotaMem       int64 // -1 when no quota covers the resource
+	quotaReqCPU, quotaReqMem int64 // requests counted against quotaCPU/quotaMem
+}
+
+// quotaRequestsHard returns the tightest hard limit on requests of resource
+// across a namespace's quotas, or -1 when none sets one.
+func quotaRequestsHard(quotas []corev1.ResourceQuota, resource corev1.ResourceName) int64 {
+	hard := int64(-1)
+	for i := range quotas {
+		for _, name := range []corev1.ResourceName{"requests." + resource, resource} {
+			q, ok := quotas[i].Status.Hard[name]
+			if !ok {
+				continue
+			}
+			v := q.Value()
+			if resource == corev1.ResourceCPU {
+				v = q.MilliValue()
+			}
+			if hard < 0 || v < hard {
+				hard = v
+			}
+		}
+	}
+	return hard
+}
+
+// buildQuotaFairnessReport groups namespaces into tenants by groupLabel (each
+// namespace is its own tenant when empty or unset) and ranks them. metrics
+// is nil when metrics-server is unavailable.
+func buildQuotaFairnessReport(namespaces []corev1.Namespace, nodes []corev1.Node, pods []corev1.Pod, quotas []corev1.ResourceQuota, metrics *podMetricsList, groupLabel string) *QuotaFairnessReport {
+	report := &QuotaFairnessReport{GroupLabel: groupLabel, UsageAvailable: metrics != nil, Tenants: []TenantFairness{}}
+
+	var allocCPU, allocMem int64
+	for i := range nodes {
+		if isNodeSchedulable(&nodes[i]) {
+			allocCPU += nodes[i].Status.Allocatable.Cpu().MilliValue()
+			allocMem += nodes[i].Status.Allocatable.Memory().Value()
+		}
+	}
+	report.AllocatableCPU = CPUAmountFromMillicores(allocCPU)
+	report.AllocatableMemory = MemoryAmountFromBytes(allocMem)
+
+	quotasByNS := make(map[string][]corev1.ResourceQuota)
+	for _, q := range quotas {
+		quotasByNS[q.Namespace] = append(quotasByNS[q.Namespace], q)
+	}
+	footprints := make(map[string]*namespaceFootprint, len(namespaces))
+	for _, ns := range namespaces {
+		footprints[ns.Name] = &namespaceFootprint{
+			quotaCPU: quotaRequestsHard(quotasByNS[ns.Name], corev1.ResourceCPU),
+			quotaMem: quotaRequestsHard(quotasByNS[ns.Name], corev1.ResourceMemory),
+		}
+	}
+	for i := range pods {
+		pod := &pods[i]
+		fp := footprints[pod.Namespace]
+		if fp == nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		reqCPU, reqMem, _, _ := podRequestsAndLimits(pod)
+		fp.pods++
+		fp.reqCPU += reqCPU
+		fp.reqMem += reqMem
+	}
+	if metrics != nil {
+		for _, item := range metrics.Items {
+			fp := footprints[item.Metadata.Namespace]
+			if fp == nil {
+				continue
+			}
+			for _, c := range item.Containers {
+				fp.usedCPU += c.Usage.Cpu().MilliValue()
+				fp.usedMem += c.Usage.Memory().Value()
+			}
+		}
+	}
+
+	tenants := make(map[string]*TenantFairness)
+	sums := make(map[string]*namespaceFootprint)
+	for _, ns := range namespaces {
+		name := ns.Name
+		if v := ns.Labels[groupLabel]; groupLabel != "" && v != "" {
+			name = v
+		}
+		t, sum := tenants[name], sums[name]
+		if t == nil {
+			t = &TenantFairness{Tenant: name}
+			sum = &namespaceFootprint{quotaCPU: -1, quotaMem: -1}
+			tenants[name], sums[name] = t, sum
+		}
+		t.Namespaces = append(t.Namespaces, ns.Name)
+		fp := footprints[ns.Name]
+		sum.pods += fp.pods
+		sum.reqCPU += fp.reqCPU
+		sum.reqMem += fp.reqMem
+		sum.usedCPU += fp.usedCPU
+		sum.usedMem += fp.usedMem
+		if fp.quotaCPU >= 0 {
+			sum.quotaCPU = max(sum.quotaCPU, 0) + fp.quotaCPU
+			sum.quotaReqCPU += fp.reqCPU
+		}
+		if fp.quotaMem >= 0 {
+			sum.quotaMem = max(sum.quotaMem, 0) + fp.quotaMem
+			sum.quotaReqMem += fp.reqMem
+		}
+		if fp.quotaCPU < 0 && fp.quotaMem < 0 {
+			t.WithoutQuota = append(t.WithoutQuota, ns.Name)
+		}
+	}
+
+	var totalCPU, totalMem int64
+	for name, t := range tenants {
+		fillTenantFairness(t, sums[name], allocCPU, allocMem, metrics != nil)
+		totalCPU += t.RequestedCPU.Millicores
+		totalMem += t.RequestedMemory.Bytes
+		report.Tenants = append(report.Tenants, *t)
+	}
+	report.RequestedCPU = CPUAmountFromMillicores(totalCPU)
+	report.RequestedMemory = MemoryAmountFromBytes(totalMem)
+	sort.Slice(report.Tenants, func(i, j int) bool {
+		a, b := report.Tenants[i], report.Tenants[j]
+		if a.Overcommitment != b.Overcommitment {
+			return a.Overcommitment > b.Overcommitment
+		}
+		return a.Tenant < b.Tenant
+	})
+	return report
+}
+
+// fillTenantFairness derives the tenant's amounts, ranking and findings.
+func fillTenantFairness(t *TenantFairness, fp *namespaceFootprint, allocCPU, allocMem int64, withUsage bool) {
+	sort.Strings(t.Namespaces)
+	t.Pods = fp.pods
+	t.RequestedCPU = CPUAmountFromMillicores(fp.reqCPU)
+	t.RequestedMemory = MemoryAmountFromBytes(fp.reqMem)
+	t.CPUShare = Percent(fp.reqCPU, allocCPU)
+	t.MemoryShare = Percent(fp.reqMem, allocMem)
+	t.Overcommitment = max(t.CPUShare, t.MemoryShare)
+
+	if fp.quotaCPU >= 0 {
+		q := CPUAmountFromMillicores(fp.quotaCPU)
+		t.QuotaCPU = &q
+		t.QuotaCPUPercent = Percent(fp.quotaReqCPU, fp.quotaCPU)
+		if t.QuotaCPUPercent >= DefaultQuotaThreshold {
+			t.Findings = append(t.Findings, fmt.Sprintf("CPU requests at %s of quota", FormatPercent(fp.quotaReqCPU, fp.quotaCPU)))
+		}
+	}
+	if fp.quotaMem >= 0 {
+		q := MemoryAmountFromBytes(fp.quotaMem)
+		t.QuotaMemory = &q
+		t.QuotaMemoryPercent = Percent(fp.quotaReqMem, fp.quotaMem)
+		if t.QuotaMemoryPercent >= DefaultQuotaThreshold {
+			t.Findings = append(t.Findings, fmt.Sprintf("memory requests at %s of quota", FormatPercent(fp.quotaReqMem, fp.quotaMem)))
+		}
+	}
+	if len(t.WithoutQuota) > 0 && fp.pods > 0 {
+		t.Findings = append(t.Findings, fmt.Sprintf("no requests quota on %d namespace(s): nothing caps their share", len(t.WithoutQuota)))
+	}
+
+	if !withUsage {
+		return
+	}
+	t.UsedCPU = CPUAmountFromMillicores(fp.usedCPU)
+	t.UsedMemory = MemoryAmountFromBytes(fp.usedMem)
+	t.IdleCPU = CPUAmountFromMillicores(max(fp.reqCPU-fp.usedCPU, 0))
+	t.IdleMemory = MemoryAmountFromBytes(max(fp.reqMem-fp.usedMem, 0))
+	t.CPUEfficiency = Percent(fp.usedCPU, fp.reqCPU)
+	t.MemoryEfficiency = Percent(fp.usedMem, fp.reqMem)
+	t.Overcommitment = max(Percent(t.IdleCPU.Millicores, allocCPU), Percent(t.IdleMemory.Bytes, allocMem))
+	if fp.reqCPU >= minEfficiencyCPU && t.CPUEfficiency < lowEfficiencyPercent {
+		t.Findings = append(t.Findings, fmt.Sprintf("uses %s of the CPU it requests (%s idle)", FormatPercent(fp.usedCPU, fp.reqCPU), t.IdleCPU.Human))
+	}
+	if fp.reqMem >= minEfficiencyMemory && t.MemoryEfficiency < lowEfficiencyPercent {
+		t.Findings = append(t.Findings, fmt.Sprintf("uses %s of the memory it requests (%s idle)", FormatPercent(fp.usedMem, fp.reqMem), t.IdleMemory.Human))
+	}
+	if fp.usedCPU > fp.reqCPU && fp.reqCPU > 0 {
+		t.Findings = append(t.Findings, fmt.Sprintf("uses more CPU than it requests (%s of requests): its share is understated", FormatPercent(fp.usedCPU, fp.reqCPU)))
+	}
+}
+
+// collectQuotaFairnessReport lists namespaces, nodes, pods and quotas, and
+// pod metrics when metrics-server is available.
+func collectQuotaFairnessReport(ctx context.Context, clientset kubernetes.Interface, groupLabel string, includeSystem bool) (*QuotaFairnessReport, error) {
+	nsList, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	var namespaces []corev1.Namespace
+	for _, ns := range nsList.Items {
+		if shouldScanNamespace(ns.Name, "", includeSystem) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	quotas, err := clientset.CoreV1().ResourceQuotas("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource quotas: %w", err)
+	}
+
+	// Usage is optional: without metrics-server tenants are ranked by requests.
+	var metrics *podMetricsList
+	m, metricsErr := fairnessPodMetrics(ctx, clientset, "")
+	if metricsErr == nil {
+		metrics = &m
+	}
+	report := buildQuotaFairnessReport(namespaces, nodes.Items, pods.Items, quotas.Items, metrics, groupLabel)
+	if metricsErr != nil {
+		report.UsageError = metricsErr.Error()
+	}
+	return report, nil
+}
+
+// GetQuotaFairness compares each tenant's requested CPU and memory against
+// its ResourceQuota, its share of the cluster and its actual usage, ranking
+// tenants by overcommitment. Namespaces are grouped into tenants by the
+// value of groupLabel when set. If includeSystem is false, system
+// namespaces are excluded.
+func (p *Provider) GetQuotaFairness(ctx context.Context, contextName, groupLabel string, includeSystem bool) (*QuotaFairnessReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	report, err := collectQuotaFairnessReport(queryCtx, clientset, groupLabel, includeSystem)
+	if err != nil {
+		return nil, err
+	}
+	report.Context = contextName
+	return report, nil
+}
@@ -0,0 +1,181 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func tenantNamespace(name, team string) corev1.Namespace {
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if team != "" {
+		ns.Labels = map[string]string{"team": team}
+	}
+	return ns
+}
+
+func requestingPod(namespace, name, cpu, memory string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app",
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse(cpu), corev1.ResourceMemory: resource.MustParse(memory),
+			}},
+		}}},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func usageMetrics(namespace, pod, cpu, memory string) podMetrics {
+	return podMetrics{
+		Metadata: metav1.ObjectMeta{Name: pod, Namespace: namespace},
+		Containers: []containerMetrics{{Name: "app", Usage: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse(cpu), corev1.ResourceMemory: resource.MustParse(memory),
+		}}},
+	}
+}
+
+func fairnessNodes() []corev1.Node {
+	node := osNode("node-a", OSLinux, true)
+	node.Status.Allocatable = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10"), corev1.ResourceMemory: resource.MustParse("40Gi")}
+	return []corev1.Node{node}
+}
+
+func TestBuildQuotaFairnessReport(t *testing.T) {
+	namespaces := []corev1.Namespace{tenantNamespace("payments", ""), tenantNamespace("search", ""), tenantNamespace("idle", "")}
+	pods := []corev1.Pod{
+		requestingPod("payments", "api", "4", "8Gi"),
+		requestingPod("search", "indexer", "1", "2Gi"),
+		requestingPod("other", "ignored", "8", "8Gi"), // namespace not in scope
+	}
+	done := requestingPod("search", "job", "2", "1Gi")
+	done.Status.Phase = corev1.PodSucceeded
+	pods = append(pods, done)
+	quotas := []corev1.ResourceQuota{
+		*resourceQuota("payments", "compute", corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("4500m"), corev1.ResourceRequestsMemory: resource.MustParse("16Gi")}, nil),
+		*resourceQuota("search", "compute", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")}, nil),
+	}
+	metrics := &podMetricsList{Items: []podMetrics{
+		usageMetrics("payments", "api", "400m", "1Gi"),
+		usageMetrics("search", "indexer", "1200m", "1900Mi"),
+	}}
+
+	report := buildQuotaFairnessReport(namespaces, fairnessNodes(), pods, quotas, metrics, "")
+	if !report.UsageAvailable || report.AllocatableCPU.Millicores != 10000 || report.RequestedCPU.Millicores != 5000 {
+		t.Fatalf("report = %+v", report)
+	}
+	if len(report.Tenants) != 3 || report.Tenants[0].Tenant != "payments" {
+		t.Fatalf("tenants = %+v, want payments first", report.Tenants)
+	}
+	p := report.Tenants[0]
+	if p.CPUShare != 40 || p.QuotaCPU == nil || p.QuotaCPUPercent != 88.9 || p.CPUEfficiency != 10 || p.IdleCPU.Millicores != 3600 || p.Overcommitment != 36 {
+		t.Errorf("payments = %+v", p)
+	}
+	findings := strings.Join(p.Findings, "; ")
+	for _, want := range []string{"CPU requests at 88.9% of quota", "uses 10% of the CPU it requests", "uses 12.5% of the memory"} {
+		if !strings.Contains(findings, want) {
+			t.Errorf("payments findings %q missing %q", findings, want)
+		}
+	}
+
+	s := report.Tenants[1]
+	if s.Tenant != "search" || s.Pods != 1 || s.QuotaMemory != nil || s.QuotaCPUPercent != 50 || !strings.Contains(strings.Join(s.Findings, ";"), "uses more CPU than it requests") {
+		t.Errorf("search = %+v", s)
+	}
+	idle := report.Tenants[2]
+	if idle.Tenant != "idle" || len(idle.WithoutQuota) != 1 || len(idle.Findings) != 0 {
+		t.Errorf("idle = %+v, want no findings for an empty namespace without quota", idle)
+	}
+}
+
+func TestBuildQuotaFairnessReportGroupsAndWithoutUsage(t *testing.T) {
+	namespaces := []corev1.Namespace{
+		tenantNamespace("checkout-prod", "shop"), tenantNamespace("checkout-dev", "shop"), tenantNamespace("ml", "research"), tenantNamespace("sandbox", ""),
+	}
+	pods := []corev1.Pod{
+		requestingPod("checkout-prod", "api", "2", "4Gi"),
+		requestingPod("checkout-dev", "api", "1", "1Gi"),
+		requestingPod("ml", "train", "5", "4Gi"),
+		requestingPod("sandbox", "try", "100m", "128Mi"),
+	}
+	quotas := []corev1.ResourceQuota{
+		*resourceQuota("checkout-prod", "compute", corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("2")}, nil),
+	}
+
+	report := buildQuotaFairnessReport(namespaces, fairnessNodes(), pods, quotas, nil, "team")
+	if report.UsageAvailable || len(report.Tenants) != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+	// Without usage, tenants are ranked by their share of the cluster.
+	order := []string{report.Tenants[0].Tenant, report.Tenants[1].Tenant, report.Tenants[2].Tenant}
+	if strings.Join(order, ",") != "research,shop,sandbox" {
+		t.Errorf("order = %v", order)
+	}
+	shop := report.Tenants[1]
+	if strings.Join(shop.Namespaces, ",") != "checkout-dev,checkout-prod" || shop.RequestedCPU.Millicores != 3000 || shop.CPUShare != 30 {
+		t.Errorf("shop = %+v", shop)
+	}
+	// The quota only covers checkout-prod, so only its requests count against it.
+	if shop.QuotaCPU == nil || shop.QuotaCPU.Millicores != 2000 || shop.QuotaCPUPercent != 100 || len(shop.WithoutQuota) != 1 {
+		t.Errorf("shop quota = %+v", shop)
+	}
+	if shop.UsedCPU.Millicores != 0 || shop.CPUEfficiency != 0 {
+		t.Errorf("shop usage without metrics = %+v", shop)
+	}
+}
+
+func TestQuotaRequestsHard(t *testing.T) {
+	quotas := []corev1.ResourceQuota{
+		*resourceQuota("a", "wide", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8")}, nil),
+		*resourceQuota("a", "tight", corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("3"), corev1.ResourcePods: resource.MustParse("10")}, nil),
+	}
+	if got := quotaRequestsHard(quotas, corev1.ResourceCPU); got != 3000 {
+		t.Errorf("cpu hard = %d, want the tightest 3000", got)
+	}
+	if got := quotaRequestsHard(quotas, corev1.ResourceMemory); got != -1 {
+		t.Errorf("memory hard = %d, want -1", got)
+	}
+}
+
+func TestCollectQuotaFairnessReport(t *testing.T) {
+	nodes := fairnessNodes()
+	payments := tenantNamespace("payments", "")
+	system := tenantNamespace("kube-system", "")
+	pod := requestingPod("payments", "api", "1", "1Gi")
+	clientset := fake.NewClientset(&nodes[0], &payments, &system, &pod)
+
+	fetch := fairnessPodMetrics
+	t.Cleanup(func() { fairnessPodMetrics = fetch })
+	fairnessPodMetrics = func(context.Context, kubernetes.Interface, string) (podMetricsList, error) {
+		return podMetricsList{}, errors.New("metrics API unavailable")
+	}
+	report, err := collectQuotaFairnessReport(context.Background(), clientset, "", false)
+	if err != nil {
+		t.Fatalf("collectQuotaFairnessReport: %v", err)
+	}
+	if len(report.Tenants) != 1 || report.Tenants[0].Tenant != "payments" || report.Tenants[0].CPUShare != 10 {
+		t.Errorf("tenants = %+v", report.Tenants)
+	}
+	if report.UsageAvailable || report.UsageError == "" {
+		t.Errorf("usage = %v %q, want unavailable", report.UsageAvailable, report.UsageError)
+	}
+
+	fairnessPodMetrics = func(context.Context, kubernetes.Interface, string) (podMetricsList, error) {
+		return podMetricsList{Items: []podMetrics{usageMetrics("payments", "api", "100m", "512Mi")}}, nil
+	}
+	report, err = collectQuotaFairnessReport(context.Background(), clientset, "", false)
+	if err != nil {
+		t.Fatalf("collectQuotaFairnessReport: %v", err)
+	}
+	if !report.UsageAvailable || report.Tenants[0].UsedCPU.Millicores != 100 || report.Tenants[0].Overcommitment != 9 {
+		t.Errorf("with usage = %+v", report.Tenants[0])
+	}
+}
@@ -0,0 +1,306 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains collectors for Argo CD and Flux applications and their sync/health status.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/debug"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// GitOps tools reported by GetGitOpsStatus.
+const (
+	GitOpsArgoCD = "argocd"
+	GitOpsFlux   = "flux"
+)
+
+// Sync and health states of GitOpsApp. Argo CD states are kept as reported;
+// Flux Ready conditions are mapped onto them.
+const (
+	GitOpsSynced    = "Synced"
+	GitOpsOutOfSync = "OutOfSync"
+	GitOpsUnknown   = "Unknown"
+
+	GitOpsHealthy     = "Healthy"
+	GitOpsProgressing = "Progressing"
+	GitOpsDegraded    = "Degraded"
+	GitOpsSuspended   = "Suspended"
+)
+
+// gitOpsKind is a GitOps CRD; versions are tried in order so clusters with
+// older controllers are still read.
+type gitOpsKind struct {
+	tool     string
+	kind     string
+	group    string
+	resource string
+	versions []string
+}
+
+var gitOpsKinds = []gitOpsKind{
+	{GitOpsArgoCD, "Application", "argoproj.io", "applications", []string{"v1alpha1"}},
+	{GitOpsFlux, "Kustomization", "kustomize.toolkit.fluxcd.io", "kustomizations", []string{"v1", "v1beta2"}},
+	{GitOpsFlux, "HelmRelease", "helm.toolkit.fluxcd.io", "helmreleases", []string{"v2", "v2beta2", "v2beta1"}},
+}
+
+// GitOpsApp is one Argo CD Application or Flux Kustomization/HelmRelease.
+type GitOpsApp struct {
+	Tool      string `json:"tool"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Sync      string `json:"sync"`
+	Health    string `json:"health"`
+	// Source is the repository and path, chart or source reference deployed.
+	Source   string `json:"source,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	// Message explains a failed sync or an unhealthy state.
+	Message   string `json:"message,omitempty"`
+	Suspended bool   `json:"suspended,omitempty"`
+}
+
+// NeedsAttention reports whether the app is out of sync, unhealthy or suspended.
+func (a GitOpsApp) NeedsAttention() bool {
+	return a.Sync != GitOpsSynced || a.Health != GitOpsHealthy
+}
+
+// GitOpsReport lists the GitOps applications of a cluster.
+type GitOpsReport struct {
+	Context string `json:"context"`
+	// Tools are the GitOps tools whose CRDs are installed.
+	Tools     []string    `json:"tools"`
+	Apps      []GitOpsApp `json:"apps"`
+	OutOfSync int         `json:"out_of_sync"`
+	Unhealthy int         `json:"unhealthy"`
+	// Warnings note CRDs that could not be read, e.g. for lack of RBAC.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// GetGitOpsStatus lists Argo CD Applications and Flux Kustomizations and
+// HelmReleases in namespace (all namespaces when empty).
+func (p *Provider) GetGitOpsStatus(ctx context.Context, contextName, namespace string) (*GitOpsReport, error) {
+	client, err := p.createDynamicClient(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	// Without discovery each known version is tried in turn.
+	resources, err := p.APIResources(ctx, contextName)
+	if err != nil {
+		debug.Logf(debug.K8s, "gitops %s: %v", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	report, err := collectGitOpsStatus(queryCtx, client, resources, namespace)
+	if err != nil {
+		return nil, err
+	}
+	report.Context = contextName
+	return report, nil
+}
+
+// collectGitOpsStatus reads every known GitOps CRD served by the cluster;
+// resources is its discovered resource list, nil when unknown.
+func collectGitOpsStatus(ctx context.Context, client dynamic.Interface, resources APIResources, namespace string) (*GitOpsReport, error) {
+	report := &GitOpsReport{Tools: []string{}, Apps: []GitOpsApp{}}
+	for _, k := range gitOpsKinds {
+		items, served, err := listGitOpsKind(ctx, client, resources, k, namespace)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("failed to list %s: %w", k.resource, err)
+			}
+			report.Warnings = append(report.Warnings, fmt.Sprintf("could not list %s.%s: %v", k.resource, k.group, err))
+			continue
+		}
+		if !served {
+			continue
+		}
+		if len(report.Tools) == 0 || report.Tools[len(report.Tools)-1] != k.tool {
+			report.Tools = append(report.Tools, k.tool)
+		}
+		for i := range items {
+			var app GitOpsApp
+			if k.tool == GitOpsArgoCD {
+				app = argoApplication(&items[i])
+			} else {
+				app = fluxApp(k.kind, &items[i])
+			}
+			report.Apps = append(report.Apps, app)
+		}
+	}
+
+	sort.Slice(report.Apps, func(i, j int) bool {
+		a, b := report.Apps[i], report.Apps[j]
+		if a.NeedsAttention() != b.NeedsAttention() {
+			return a.NeedsAttention()
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	for _, a := range report.Apps {
+		if a.Sync == GitOpsOutOfSync {
+			report.OutOfSync++
+		}
+		if a.Health != GitOpsHealthy && a.Health != GitOpsSuspended {
+			report.Unhealthy++
+		}
+	}
+	return report, nil
+}
+
+// listGitOpsKind lists k in the version the cluster serves; served is false
+// when the CRD is not installed. Without resources the known versions are
+// tried in order.
+func listGitOpsKind(ctx context.Context, client dynamic.Interface, resources APIResources, k gitOpsKind, namespace string) ([]unstructured.Unstructured, bool, error) {
+	versions := k.versions
+	if resources != nil {
+		r, ok := resources.Find(k.group, k.resource)
+		if !ok {
+			return nil, false, nil
+		}
+		versions = []string{r.Version}
+	}
+	for _, version := range versions {
+		gvr := schema.GroupVersionResource{Group: k.group, Version: version, Resource: k.resource}
+		list, err := client.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		return list.Items, true, nil
+	}
+	return nil, false, nil
+}
+
+// argoApplication reads an Argo CD Application's sync and health status.
+func argoApplication(obj *unstructured.Unstructured) GitOpsApp {
+	app := GitOpsApp{
+		Tool:      GitOpsArgoCD,
+		Kind:      "Application",
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Sync:      stringOr(nestedString(obj.Object, "status", "sync", "status"), GitOpsUnknown),
+		Health:    stringOr(nestedString(obj.Object, "status", "health", "status"), GitOpsUnknown),
+		Revision:  nestedString(obj.Object, "status", "sync", "revision"),
+	}
+
+	source, _, _ := unstructured.NestedMap(obj.Object, "spec", "source")
+	if source == nil {
+		if sources, _, _ := unstructured.NestedSlice(obj.Object, "spec", "sources"); len(sources) > 0 {
+			source, _ = sources[0].(map[string]any)
+		}
+	}
+	if source != nil {
+		app.Source = nestedString(source, "repoURL")
+		if chart := nestedString(source, "chart"); chart != "" {
+			app.Source += " chart " + chart
+		} else if path := nestedString(source, "path"); path != "" {
+			app.Source += " " + path
+		}
+		if rev := nestedString(source, "targetRevision"); rev != "" && app.Revision == "" {
+			app.Revision = rev
+		}
+	}
+
+	// A failed sync operation explains most OutOfSync apps; conditions
+	// (ComparisonError, SyncError, ...) cover the rest.
+	if phase := nestedString(obj.Object, "status", "operationState", "phase"); phase == "Failed" || phase == "Error" {
+		app.Message = "sync " + strings.ToLower(phase) + ": " + nestedString(obj.Object, "status", "operationState", "message")
+	} else if conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions"); len(conditions) > 0 {
+		if c, ok := conditions[0].(map[string]any); ok {
+			app.Message = strings.TrimSpace(nestedString(c, "type") + ": " + nestedString(c, "message"))
+		}
+	} else if app.Health != GitOpsHealthy {
+		app.Message = nestedString(obj.Object, "status", "health", "message")
+	}
+	return app
+}
+
+// fluxApp maps a Flux Kustomization or HelmRelease Ready condition onto sync
+// and health: Ready=True is Synced/Healthy, False is OutOfSync/Degraded and
+// Unknown (reconciling) is Progressing.
+func fluxApp(kind string, obj *unstructured.Unstructured) GitOpsApp {
+	app := GitOpsApp{
+		Tool:      GitOpsFlux,
+		Kind:      kind,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Sync:      GitOpsUnknown,
+		Health:    GitOpsUnknown,
+		Revision:  nestedString(obj.Object, "status", "lastAppliedRevision"),
+	}
+	app.Suspended, _, _ = unstructured.NestedBool(obj.Object, "spec", "suspend")
+
+	sourceKind := nestedString(obj.Object, "spec", "sourceRef", "kind")
+	sourceName := nestedString(obj.Object, "spec", "sourceRef", "name")
+	if kind == "HelmRelease" {
+		chart := nestedString(obj.Object, "spec", "chart", "spec", "chart")
+		sourceKind = nestedString(obj.Object, "spec", "chart", "spec", "sourceRef", "kind")
+		sourceName = nestedString(obj.Object, "spec", "chart", "spec", "sourceRef", "name")
+		if chart != "" {
+			app.Source = "chart " + chart + " from "
+		}
+		if chartRef := nestedString(obj.Object, "spec", "chartRef", "name"); chartRef != "" {
+			sourceKind, sourceName = nestedString(obj.Object, "spec", "chartRef", "kind"), chartRef
+		}
+	}
+	if sourceName != "" {
+		app.Source += sourceKind + "/" + sourceName
+		if path := nestedString(obj.Object, "spec", "path"); path != "" {
+			app.Source += " " + path
+		}
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]any)
+		if !ok || nestedString(cond, "type") != "Ready" {
+			continue
+		}
+		switch nestedString(cond, "status") {
+		case "True":
+			app.Sync, app.Health = GitOpsSynced, GitOpsHealthy
+		case "False":
+			app.Sync, app.Health = GitOpsOutOfSync, GitOpsDegraded
+			app.Message = strings.TrimPrefix(nestedString(cond, "reason")+": "+nestedString(cond, "message"), ": ")
+		default:
+			app.Health = GitOpsProgressing
+			app.Message = nestedString(cond, "message")
+		}
+	}
+	// A newer revision that has not been applied yet is drift waiting on the next reconcile.
+	if attempted := nestedString(obj.Object, "status", "lastAttemptedRevision"); attempted != "" && app.Revision != "" && attempted != app.Revision {
+		app.Sync = GitOpsOutOfSync
+	}
+	if app.Suspended {
+		app.Health = GitOpsSuspended
+	}
+	return app
+}
+
+// nestedString returns the string at fields, or "" when absent or not a string.
+func nestedString(obj map[string]any, fields ...string) string {
+	s, _, _ := unstructured.NestedString(obj, fields...)
+	return s
+}
+
+func stringOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
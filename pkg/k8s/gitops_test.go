@@ -0,0 +1,146 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func gitOpsObject(apiVersion, kind, namespace, name string, spec, status map[string]any) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   map[string]any{"name": name, "namespace": namespace},
+		"spec":       spec,
+		"status":     status,
+	}}
+	return obj
+}
+
+func newGitOpsClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	listKinds := map[schema.GroupVersionResource]string{}
+	for _, k := range gitOpsKinds {
+		for _, v := range k.versions {
+			listKinds[schema.GroupVersionResource{Group: k.group, Version: v, Resource: k.resource}] = k.kind + "List"
+		}
+	}
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+}
+
+func TestCollectGitOpsStatus(t *testing.T) {
+	argoSynced := gitOpsObject("argoproj.io/v1alpha1", "Application", "argocd", "web",
+		map[string]any{"source": map[string]any{"repoURL": "https://git/shop", "path": "apps/web", "targetRevision": "main"}},
+		map[string]any{"sync": map[string]any{"status": "Synced", "revision": "abc123"}, "health": map[string]any{"status": "Healthy"}})
+	argoDrift := gitOpsObject("argoproj.io/v1alpha1", "Application", "argocd", "api",
+		map[string]any{"sources": []any{map[string]any{"repoURL": "https://charts", "chart": "api"}}},
+		map[string]any{
+			"sync":           map[string]any{"status": "OutOfSync"},
+			"health":         map[string]any{"status": "Degraded"},
+			"operationState": map[string]any{"phase": "Failed", "message": "one or more objects failed to apply"},
+		})
+	fluxReady := gitOpsObject("kustomize.toolkit.fluxcd.io/v1", "Kustomization", "flux-system", "infra",
+		map[string]any{"path": "./infra", "sourceRef": map[string]any{"kind": "GitRepository", "name": "fleet"}},
+		map[string]any{
+			"lastAppliedRevision": "main@sha1:aaa", "lastAttemptedRevision": "main@sha1:aaa",
+			"conditions": []any{map[string]any{"type": "Ready", "status": "True"}},
+		})
+	fluxFailed := gitOpsObject("helm.toolkit.fluxcd.io/v2", "HelmRelease", "shop", "redis",
+		map[string]any{"chart": map[string]any{"spec": map[string]any{"chart": "redis", "sourceRef": map[string]any{"kind": "HelmRepository", "name": "bitnami"}}}},
+		map[string]any{"conditions": []any{map[string]any{"type": "Ready", "status": "False", "reason": "UpgradeFailed", "message": "timed out"}}})
+	fluxSuspended := gitOpsObject("kustomize.toolkit.fluxcd.io/v1", "Kustomization", "flux-system", "legacy",
+		map[string]any{"suspend": true, "sourceRef": map[string]any{"kind": "GitRepository", "name": "fleet"}},
+		map[string]any{"conditions": []any{map[string]any{"type": "Ready", "status": "True"}}})
+
+	client := newGitOpsClient(argoSynced, argoDrift, fluxReady, fluxFailed, fluxSuspended)
+	report, err := collectGitOpsStatus(context.Background(), client, nil, "")
+	if err != nil {
+		t.Fatalf("collectGitOpsStatus: %v", err)
+	}
+	if strings.Join(report.Tools, ",") != "argocd,flux" || len(report.Apps) != 5 {
+		t.Fatalf("tools = %v, apps = %d", report.Tools, len(report.Apps))
+	}
+	if report.OutOfSync != 2 || report.Unhealthy != 2 {
+		t.Errorf("out of sync = %d, unhealthy = %d", report.OutOfSync, report.Unhealthy)
+	}
+
+	apps := map[string]GitOpsApp{}
+	for _, a := range report.Apps {
+		apps[a.Name] = a
+	}
+	if a := apps["web"]; a.Sync != GitOpsSynced || a.Source != "https://git/shop apps/web" || a.Revision != "abc123" || a.NeedsAttention() {
+		t.Errorf("web = %+v", a)
+	}
+	if a := apps["api"]; a.Source != "https://charts chart api" || !strings.Contains(a.Message, "sync failed: one or more") {
+		t.Errorf("api = %+v", a)
+	}
+	if a := apps["infra"]; a.Sync != GitOpsSynced || a.Health != GitOpsHealthy || a.Source != "GitRepository/fleet ./infra" {
+		t.Errorf("infra = %+v", a)
+	}
+	if a := apps["redis"]; a.Sync != GitOpsOutOfSync || a.Health != GitOpsDegraded || a.Message != "UpgradeFailed: timed out" ||
+		a.Source != "chart redis from HelmRepository/bitnami" {
+		t.Errorf("redis = %+v", a)
+	}
+	if a := apps["legacy"]; !a.Suspended || a.Health != GitOpsSuspended || !a.NeedsAttention() {
+		t.Errorf("legacy = %+v", a)
+	}
+	if !report.Apps[0].NeedsAttention() || report.Apps[len(report.Apps)-1].NeedsAttention() {
+		t.Errorf("apps needing attention should come first: %+v", report.Apps)
+	}
+
+	report, err = collectGitOpsStatus(context.Background(), client, nil, "shop")
+	if err != nil || len(report.Apps) != 1 || report.Apps[0].Name != "redis" {
+		t.Errorf("namespace filter = %+v, %v", report, err)
+	}
+}
+
+func TestCollectGitOpsStatusMissingCRDs(t *testing.T) {
+	client := newGitOpsClient()
+	client.PrependReactor("list", "*", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		gvr := a.GetResource()
+		switch {
+		case gvr.Group == "argoproj.io":
+			return true, nil, apierrors.NewForbidden(gvr.GroupResource(), "", nil)
+		case gvr.Group == "kustomize.toolkit.fluxcd.io" && gvr.Version == "v1beta2":
+			return false, nil, nil
+		default:
+			return true, nil, apierrors.NewNotFound(gvr.GroupResource(), "")
+		}
+	})
+	report, err := collectGitOpsStatus(context.Background(), client, nil, "")
+	if err != nil {
+		t.Fatalf("collectGitOpsStatus: %v", err)
+	}
+	if strings.Join(report.Tools, ",") != "flux" || len(report.Apps) != 0 {
+		t.Errorf("tools = %v, apps = %+v", report.Tools, report.Apps)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "applications.argoproj.io") {
+		t.Errorf("warnings = %v", report.Warnings)
+	}
+}
+
+func TestCollectGitOpsStatusUsesDiscovery(t *testing.T) {
+	client := newGitOpsClient()
+	var listed []string
+	client.PrependReactor("list", "*", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		listed = append(listed, a.GetResource().String())
+		return false, nil, nil
+	})
+	resources := APIResources{{Group: "kustomize.toolkit.fluxcd.io", Version: "v1beta2", Resource: "kustomizations", Kind: "Kustomization", Namespaced: true}}
+	report, err := collectGitOpsStatus(context.Background(), client, resources, "")
+	if err != nil {
+		t.Fatalf("collectGitOpsStatus: %v", err)
+	}
+	if strings.Join(report.Tools, ",") != "flux" || len(report.Warnings) != 0 {
+		t.Errorf("tools = %v, warnings = %v", report.Tools, report.Warnings)
+	}
+	if len(listed) != 1 || !strings.Contains(listed[0], "v1beta2") {
+		t.Errorf("listed %v, want only the discovered kustomizations version", listed)
+	}
+}
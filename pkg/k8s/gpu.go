@@ -0,0 +1,262 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains collectors for GPUs and other extended resources.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ExtendedResourceUsage reports allocation against capacity for one extended resource.
+// Counts are in the resource's native unit (devices for GPUs, bytes for hugepages).
+type ExtendedResourceUsage struct {
+	Resource    string  `json:"resource"`
+	Capacity    int64   `json:"capacity"`
+	Allocatable int64   `json:"allocatable"`
+	Requested   int64   `json:"requested"`
+	Percent     float64 `json:"percent"`
+	Human       string  `json:"human"`
+}
+
+// NodeExtendedResources lists the extended resources advertised by one node.
+type NodeExtendedResources struct {
+	Node      string                  `json:"node"`
+	Ready     bool                    `json:"ready"`
+	Resources []ExtendedResourceUsage `json:"resources"`
+}
+
+// ExtendedResourcePod is a pod requesting at least one extended resource.
+type ExtendedResourcePod struct {
+	Namespace string           `json:"namespace"`
+	Pod       string           `json:"pod"`
+	Node      string           `json:"node,omitempty"`
+	Phase     string           `json:"phase"`
+	Requests  map[string]int64 `json:"requests"`
+	// Reason is the scheduler message for pods that cannot be placed.
+	Reason string `json:"reason,omitempty"`
+}
+
+// ExtendedResourceReport summarises GPU and other extended resource allocation for a cluster.
+type ExtendedResourceReport struct {
+	Context string                  `json:"context"`
+	Totals  []ExtendedResourceUsage `json:"totals"`
+	Nodes   []NodeExtendedResources `json:"nodes"`
+	Pods    []ExtendedResourcePod   `json:"pods"`
+	Pending []ExtendedResourcePod   `json:"pending"`
+}
+
+// IsExtendedResource reports whether name is an extended resource (a
+// vendor-qualified name such as nvidia.com/gpu) or a hugepages size.
+func IsExtendedResource(name corev1.ResourceName) bool {
+	n := string(name)
+	if strings.HasPrefix(n, corev1.ResourceHugePagesPrefix) {
+		return true
+	}
+	if !strings.Contains(n, "/") {
+		return false
+	}
+	// Names under kubernetes.io are reserved for native resources.
+	domain := n[:strings.Index(n, "/")]
+	return domain != "kubernetes.io" && !strings.HasSuffix(domain, ".kubernetes.io")
+}
+
+// formatExtendedQuantity renders hugepages in binary units and device counts as integers.
+func formatExtendedQuantity(resource string, v int64) string {
+	if strings.HasPrefix(resource, corev1.ResourceHugePagesPrefix) {
+		return FormatMemoryBytes(v)
+	}
+	return fmt.Sprintf("%d", v)
+}
+
+// podExtendedRequests sums extended resource requests for a pod the way the
+// scheduler does: the larger of the sum of app containers and any single init container.
+func podExtendedRequests(pod *corev1.Pod) map[string]int64 {
+	requests := make(map[string]int64)
+	for _, c := range pod.Spec.Containers {
+		for name, q := range effectiveRequests(c) {
+			if IsExtendedResource(name) {
+				requests[string(name)] += q.Value()
+			}
+		}
+	}
+	for _, c := range pod.Spec.InitContainers {
+		for name, q := range effectiveRequests(c) {
+			if IsExtendedResource(name) && q.Value() > requests[string(name)] {
+				requests[string(name)] = q.Value()
+			}
+		}
+	}
+	return requests
+}
+
+// effectiveRequests returns container requests, falling back to limits:
+// extended resources may only be specified as limits, which imply equal requests.
+func effectiveRequests(c corev1.Container) corev1.ResourceList {
+	out := corev1.ResourceList{}
+	for name, q := range c.Resources.Limits {
+		out[name] = q
+	}
+	for name, q := range c.Resources.Requests {
+		out[name] = q
+	}
+	return out
+}
+
+// unschedulableReason returns the scheduler message for a pending pod that
+// could not be placed, or "" if it is not marked unschedulable.
+func unschedulableReason(pod *corev1.Pod) string {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse && cond.Reason == corev1.PodReasonUnschedulable {
+			return cond.Message
+		}
+	}
+	return ""
+}
+
+func isNodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// collectExtendedResources builds an ExtendedResourceReport from nodes and pods.
+// Pods in terminal phases do not hold devices and are ignored.
+func collectExtendedResources(ctx context.Context, clientset kubernetes.Interface) (*ExtendedResourceReport, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	report := &ExtendedResourceReport{
+		Nodes:   []NodeExtendedResources{},
+		Pods:    []ExtendedResourcePod{},
+		Pending: []ExtendedResourcePod{},
+	}
+
+	// requestedByNode[node][resource] = sum of requests from pods bound to node.
+	requestedByNode := make(map[string]map[string]int64)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		requests := podExtendedRequests(pod)
+		if len(requests) == 0 {
+			continue
+		}
+		entry := ExtendedResourcePod{
+			Namespace: pod.Namespace,
+			Pod:       pod.Name,
+			Node:      pod.Spec.NodeName,
+			Phase:     string(pod.Status.Phase),
+			Requests:  requests,
+		}
+		if pod.Spec.NodeName == "" {
+			entry.Reason = unschedulableReason(pod)
+			report.Pending = append(report.Pending, entry)
+			continue
+		}
+		report.Pods = append(report.Pods, entry)
+		if requestedByNode[pod.Spec.NodeName] == nil {
+			requestedByNode[pod.Spec.NodeName] = make(map[string]int64)
+		}
+		for name, v := range requests {
+			requestedByNode[pod.Spec.NodeName][name] += v
+		}
+	}
+
+	totals := make(map[string]*ExtendedResourceUsage)
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		entry := NodeExtendedResources{Node: node.Name, Ready: isNodeReady(node)}
+		for name, capQty := range node.Status.Capacity {
+			if !IsExtendedResource(name) {
+				continue
+			}
+			allocQty := node.Status.Allocatable[name]
+			usage := ExtendedResourceUsage{
+				Resource:    string(name),
+				Capacity:    capQty.Value(),
+				Allocatable: allocQty.Value(),
+				Requested:   requestedByNode[node.Name][string(name)],
+			}
+			// Hugepages are always advertised; skip sizes the node does not provide.
+			if usage.Capacity == 0 && usage.Requested == 0 {
+				continue
+			}
+			finishUsage(&usage)
+			entry.Resources = append(entry.Resources, usage)
+
+			t := totals[usage.Resource]
+			if t == nil {
+				t = &ExtendedResourceUsage{Resource: usage.Resource}
+				totals[usage.Resource] = t
+			}
+			t.Capacity += usage.Capacity
+			t.Allocatable += usage.Allocatable
+			t.Requested += usage.Requested
+		}
+		if len(entry.Resources) == 0 {
+			continue
+		}
+		sort.Slice(entry.Resources, func(a, b int) bool { return entry.Resources[a].Resource < entry.Resources[b].Resource })
+		report.Nodes = append(report.Nodes, entry)
+	}
+
+	for _, t := range totals {
+		finishUsage(t)
+		report.Totals = append(report.Totals, *t)
+	}
+	sort.Slice(report.Totals, func(a, b int) bool { return report.Totals[a].Resource < report.Totals[b].Resource })
+	sort.Slice(report.Nodes, func(a, b int) bool { return report.Nodes[a].Node < report.Nodes[b].Node })
+	sortExtendedPods(report.Pods)
+	sortExtendedPods(report.Pending)
+	return report, nil
+}
+
+// finishUsage fills the derived percentage and human-readable fields.
+func finishUsage(u *ExtendedResourceUsage) {
+	u.Percent = Percent(u.Requested, u.Allocatable)
+	u.Human = fmt.Sprintf("%s/%s", formatExtendedQuantity(u.Resource, u.Requested), formatExtendedQuantity(u.Resource, u.Allocatable))
+}
+
+func sortExtendedPods(pods []ExtendedResourcePod) {
+	sort.Slice(pods, func(a, b int) bool {
+		if pods[a].Namespace != pods[b].Namespace {
+			return pods[a].Namespace < pods[b].Namespace
+		}
+		return pods[a].Pod < pods[b].Pod
+	})
+}
+
+// GetExtendedResources reports GPU and other extended resource capacity,
+// allocation, consuming pods, and pods pending on extended resources.
+func (p *Provider) GetExtendedResources(ctx context.Context, contextName string) (*ExtendedResourceReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	report, err := collectExtendedResources(queryCtx, clientset)
+	if err != nil {
+		return nil, err
+	}
+	report.Context = contextName
+	return report, nil
+}
@@ -0,0 +1,141 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testGPUResource = "nvidia.com/gpu"
+
+func TestIsExtendedResource(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{testGPUResource, true},
+		{"amd.com/gpu", true},
+		{"hugepages-2Mi", true},
+		{"example.com/fpga", true},
+		{"cpu", false},
+		{"memory", false},
+		{"ephemeral-storage", false},
+		{"kubernetes.io/batch-cpu", false},
+		{"node.kubernetes.io/foo", false},
+	}
+	for _, tt := range tests {
+		if got := IsExtendedResource(corev1.ResourceName(tt.name)); got != tt.want {
+			t.Errorf("IsExtendedResource(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func gpuNode(name string, gpus int64, ready corev1.ConditionStatus) *corev1.Node {
+	list := corev1.ResourceList{
+		corev1.ResourceCPU: resource.MustParse("8"),
+		testGPUResource:    *resource.NewQuantity(gpus, resource.DecimalSI),
+		"hugepages-1Gi":    resource.MustParse("0"),
+	}
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Capacity:    list,
+			Allocatable: list,
+			Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+		},
+	}
+}
+
+func gpuPod(name, node string, gpus int64, phase corev1.PodPhase) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ml"},
+		Spec: corev1.PodSpec{
+			NodeName: node,
+			Containers: []corev1.Container{{
+				Name: "train",
+				Resources: corev1.ResourceRequirements{
+					// Extended resources are commonly set only as limits.
+					Limits: corev1.ResourceList{testGPUResource: *resource.NewQuantity(gpus, resource.DecimalSI)},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+	if node == "" {
+		pod.Status.Conditions = []corev1.PodCondition{{
+			Type:    corev1.PodScheduled,
+			Status:  corev1.ConditionFalse,
+			Reason:  corev1.PodReasonUnschedulable,
+			Message: "0/2 nodes are available: 2 Insufficient nvidia.com/gpu.",
+		}}
+	}
+	return pod
+}
+
+func TestCollectExtendedResources(t *testing.T) {
+	clientset := fake.NewClientset(
+		gpuNode("gpu-a", 4, corev1.ConditionTrue),
+		gpuNode("gpu-b", 4, corev1.ConditionFalse),
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cpu-only"}},
+		gpuPod("train-1", "gpu-a", 3, corev1.PodRunning),
+		gpuPod("train-2", "gpu-b", 2, corev1.PodRunning),
+		gpuPod("done", "gpu-a", 1, corev1.PodSucceeded),
+		gpuPod("waiting", "", 8, corev1.PodPending),
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Spec: corev1.PodSpec{NodeName: "cpu-only"}},
+	)
+
+	report, err := collectExtendedResources(context.Background(), clientset)
+	if err != nil {
+		t.Fatalf("collectExtendedResources() error: %v", err)
+	}
+
+	if len(report.Nodes) != 2 {
+		t.Fatalf("expected 2 nodes with extended resources, got %d", len(report.Nodes))
+	}
+	if report.Nodes[1].Ready {
+		t.Error("gpu-b should be reported as not ready")
+	}
+
+	var gpuTotal *ExtendedResourceUsage
+	for i := range report.Totals {
+		if report.Totals[i].Resource == testGPUResource {
+			gpuTotal = &report.Totals[i]
+		}
+		if report.Totals[i].Resource == "hugepages-1Gi" {
+			t.Error("hugepages sizes with zero capacity should be omitted")
+		}
+	}
+	if gpuTotal == nil {
+		t.Fatal("missing nvidia.com/gpu total")
+	}
+	if gpuTotal.Allocatable != 8 || gpuTotal.Requested != 5 || gpuTotal.Percent != 62.5 || gpuTotal.Human != "5/8" {
+		t.Errorf("unexpected GPU total: %+v", *gpuTotal)
+	}
+
+	if len(report.Pods) != 2 {
+		t.Errorf("expected 2 running GPU pods (succeeded pods release devices), got %d", len(report.Pods))
+	}
+	if len(report.Pending) != 1 || report.Pending[0].Pod != "waiting" {
+		t.Fatalf("expected one pending GPU pod, got %+v", report.Pending)
+	}
+	if report.Pending[0].Reason == "" || report.Pending[0].Requests[testGPUResource] != 8 {
+		t.Errorf("pending pod should carry scheduler reason and request: %+v", report.Pending[0])
+	}
+}
+
+func TestPodExtendedRequestsInitContainers(t *testing.T) {
+	gpu := func(n int64) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{Requests: corev1.ResourceList{testGPUResource: *resource.NewQuantity(n, resource.DecimalSI)}}
+	}
+	pod := &corev1.Pod{Spec: corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "warmup", Resources: gpu(4)}},
+		Containers:     []corev1.Container{{Name: "a", Resources: gpu(1)}, {Name: "b", Resources: gpu(1)}},
+	}}
+	if got := podExtendedRequests(pod)[testGPUResource]; got != 4 {
+		t.Errorf("effective GPU request = %d, want 4 (max of init and sum of app containers)", got)
+	}
+}
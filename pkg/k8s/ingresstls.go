@@ -0,0 +1,358 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the Ingress host check: TLS secret certificates, and
+// optionally DNS resolution and the certificate served from outside the cluster.
+package k8s
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// CertExpiryWarning is how close to expiry a certificate is flagged.
+	CertExpiryWarning = 14 * 24 * time.Hour
+
+	// ingressProbeTimeout bounds the DNS lookup and TLS handshake of one host.
+	ingressProbeTimeout = 10 * time.Second
+)
+
+// lookupHost and fetchServedCertificates reach the host from this machine;
+// variables so tests can replace them.
+var (
+	lookupHost              = net.DefaultResolver.LookupHost
+	fetchServedCertificates = dialServedCertificates
+)
+
+// CertInfo summarises an X.509 certificate.
+type CertInfo struct {
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	DNSNames    []string  `json:"dns_names,omitempty"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	Fingerprint string    `json:"sha256_fingerprint"`
+}
+
+func newCertInfo(cert *x509.Certificate) *CertInfo {
+	sum := sha256.Sum256(cert.Raw)
+	return &CertInfo{
+		Subject:     cert.Subject.CommonName,
+		Issuer:      cert.Issuer.CommonName,
+		DNSNames:    cert.DNSNames,
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		Fingerprint: hex.EncodeToString(sum[:]),
+	}
+}
+
+// IngressHostCheck is the outcome of checking one host of an Ingress.
+type IngressHostCheck struct {
+	Namespace string `json:"namespace"`
+	Ingress   string `json:"ingress"`
+	Host      string `json:"host"`
+	// Addresses are the load balancer IPs or hostnames in the Ingress status.
+	Addresses  []string  `json:"addresses,omitempty"`
+	TLSSecret  string    `json:"tls_secret,omitempty"`
+	SecretCert *CertInfo `json:"secret_cert,omitempty"`
+	// Probed is set when DNS and the served certificate were checked.
+	Probed      bool      `json:"probed"`
+	ResolvedIPs []string  `json:"resolved_ips,omitempty"`
+	ServedCert  *CertInfo `json:"served_cert,omitempty"`
+	Problems    []string  `json:"problems,omitempty"`
+}
+
+// IngressTLSReport lists the host checks of a cluster.
+type IngressTLSReport struct {
+	Context string             `json:"context"`
+	Hosts   []IngressHostCheck `json:"hosts"`
+	Failing int                `json:"failing"`
+}
+
+// CheckIngressTLS checks every Ingress host in namespace (all namespaces when
+// empty), or only host when set: the certificate in its TLS secret and, with
+// probe, that the name resolves to the Ingress address and serves a valid
+// certificate matching the secret.
+func (p *Provider) CheckIngressTLS(ctx context.Context, contextName, namespace, host string, probe bool) (*IngressTLSReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	checks, err := collectIngressHosts(queryCtx, clientset, namespace, host)
+	if err != nil {
+		return nil, err
+	}
+	report := &IngressTLSReport{Context: contextName, Hosts: checks}
+	if probe {
+		// Hosts are probed concurrently; each goroutine owns one check.
+		var wg sync.WaitGroup
+		for i := range report.Hosts {
+			wg.Add(1)
+			go func(check *IngressHostCheck) {
+				defer wg.Done()
+				probeIngressHost(ctx, check, time.Now())
+			}(&report.Hosts[i])
+		}
+		wg.Wait()
+	}
+	for _, c := range report.Hosts {
+		if len(c.Problems) > 0 {
+			report.Failing++
+		}
+	}
+	return report, nil
+}
+
+// collectIngressHosts returns one check per Ingress host with the
+// certificate of its TLS secret already checked.
+func collectIngressHosts(ctx context.Context, clientset kubernetes.Interface, namespace, host string) ([]IngressHostCheck, error) {
+	ings, err := clientset.NetworkingV1().Ingresses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ingresses: %w", err)
+	}
+	secrets := map[string]*x509.Certificate{}
+	secretErrors := map[string]string{}
+	checks := []IngressHostCheck{}
+	now := time.Now()
+	for i := range ings.Items {
+		ing := &ings.Items[i]
+		for _, h := range ingressHosts(ing) {
+			if host != "" && !strings.EqualFold(h, host) {
+				continue
+			}
+			check := IngressHostCheck{Namespace: ing.Namespace, Ingress: ing.Name, Host: h, Addresses: ingressAddresses(ing)}
+			check.TLSSecret = ingressTLSSecret(ing, h)
+			if check.TLSSecret != "" {
+				key := ing.Namespace + "/" + check.TLSSecret
+				if _, ok := secrets[key]; !ok && secretErrors[key] == "" {
+					secrets[key], err = secretCertificate(ctx, clientset, ing.Namespace, check.TLSSecret)
+					if err != nil {
+						secretErrors[key] = err.Error()
+					}
+				}
+				if msg := secretErrors[key]; msg != "" {
+					check.Problems = append(check.Problems, msg)
+				} else if cert := secrets[key]; cert != nil {
+					check.SecretCert = newCertInfo(cert)
+					check.Problems = append(check.Problems, certProblems("certificate in secret "+check.TLSSecret, cert, h, now)...)
+				}
+			}
+			checks = append(checks, check)
+		}
+	}
+	sort.Slice(checks, func(i, j int) bool {
+		a, b := checks[i], checks[j]
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		return a.Namespace+"/"+a.Ingress < b.Namespace+"/"+b.Ingress
+	})
+	return checks, nil
+}
+
+// ingressHosts returns the distinct hosts of the rules and TLS sections of ing.
+func ingressHosts(ing *networkingv1.Ingress) []string {
+	var hosts []string
+	add := func(h string) {
+		if h != "" && !slices.Contains(hosts, h) {
+			hosts = append(hosts, h)
+		}
+	}
+	for _, rule := range ing.Spec.Rules {
+		add(rule.Host)
+	}
+	for _, t := range ing.Spec.TLS {
+		for _, h := range t.Hosts {
+			add(h)
+		}
+	}
+	return hosts
+}
+
+// ingressAddresses returns the load balancer IPs and hostnames of ing.
+func ingressAddresses(ing *networkingv1.Ingress) []string {
+	var addrs []string
+	for _, lb := range ing.Status.LoadBalancer.Ingress {
+		if lb.IP != "" {
+			addrs = append(addrs, lb.IP)
+		}
+		if lb.Hostname != "" {
+			addrs = append(addrs, lb.Hostname)
+		}
+	}
+	return addrs
+}
+
+// ingressTLSSecret returns the secret of the TLS section covering host, or "".
+func ingressTLSSecret(ing *networkingv1.Ingress, host string) string {
+	for _, t := range ing.Spec.TLS {
+		for _, h := range t.Hosts {
+			if hostMatches(h, host) {
+				return t.SecretName
+			}
+		}
+	}
+	return ""
+}
+
+// hostMatches reports whether pattern (a host or a *.wildcard) covers host.
+func hostMatches(pattern, host string) bool {
+	pattern, host = strings.ToLower(pattern), strings.ToLower(host)
+	if pattern == host {
+		return true
+	}
+	suffix, ok := strings.CutPrefix(pattern, "*.")
+	if !ok {
+		return false
+	}
+	label, rest, found := strings.Cut(host, ".")
+	return found && label != "" && rest == suffix
+}
+
+// secretCertificate reads the leaf certificate of a TLS secret.
+func secretCertificate(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*x509.Certificate, error) {
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return nil, fmt.Errorf("TLS secret %s not found", name)
+	case apierrors.IsForbidden(err):
+		// Secrets may be hidden by RBAC; the secret check is best effort.
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read TLS secret %s: %w", name, err)
+	}
+	block, _ := pem.Decode(secret.Data[corev1.TLSCertKey])
+	if block == nil {
+		return nil, fmt.Errorf("TLS secret %s has no PEM certificate in %s", name, corev1.TLSCertKey)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("TLS secret %s: invalid certificate: %w", name, err)
+	}
+	return cert, nil
+}
+
+// certProblems returns why cert is not valid for host at now.
+func certProblems(what string, cert *x509.Certificate, host string, now time.Time) []string {
+	var problems []string
+	switch {
+	case now.After(cert.NotAfter):
+		problems = append(problems, fmt.Sprintf("%s expired on %s", what, cert.NotAfter.UTC().Format(time.DateOnly)))
+	case cert.NotAfter.Sub(now) < CertExpiryWarning:
+		problems = append(problems, fmt.Sprintf("%s expires in %d day(s), on %s", what, int(cert.NotAfter.Sub(now).Hours()/24), cert.NotAfter.UTC().Format(time.DateOnly)))
+	case now.Before(cert.NotBefore):
+		problems = append(problems, fmt.Sprintf("%s is not valid before %s", what, cert.NotBefore.UTC().Format(time.RFC3339)))
+	}
+	if !certCoversHost(cert, host) {
+		problems = append(problems, fmt.Sprintf("%s does not cover %s (names: %s)", what, host, strings.Join(cert.DNSNames, ", ")))
+	}
+	return problems
+}
+
+// certCoversHost reports whether cert is valid for host; a wildcard host
+// must be listed as is.
+func certCoversHost(cert *x509.Certificate, host string) bool {
+	if strings.HasPrefix(host, "*.") {
+		return slices.ContainsFunc(cert.DNSNames, func(n string) bool { return strings.EqualFold(n, host) })
+	}
+	return cert.VerifyHostname(host) == nil
+}
+
+// probeIngressHost resolves the host and fetches the certificate it serves
+// on port 443, from this machine.
+func probeIngressHost(ctx context.Context, check *IngressHostCheck, now time.Time) {
+	if strings.HasPrefix(check.Host, "*.") {
+		return // a wildcard names no single host to reach
+	}
+	check.Probed = true
+	probeCtx, cancel := context.WithTimeout(ctx, ingressProbeTimeout)
+	defer cancel()
+
+	ips, err := lookupHost(probeCtx, check.Host)
+	if err != nil {
+		check.Problems = append(check.Problems, fmt.Sprintf("%s does not resolve: %v", check.Host, err))
+		return
+	}
+	sort.Strings(ips)
+	check.ResolvedIPs = ips
+	if want := ingressIPs(probeCtx, check.Addresses); len(want) > 0 && !slices.ContainsFunc(ips, func(ip string) bool { return slices.Contains(want, ip) }) {
+		check.Problems = append(check.Problems, fmt.Sprintf("%s resolves to %s, not to the ingress address %s", check.Host, strings.Join(ips, ", "), strings.Join(check.Addresses, ", ")))
+	}
+
+	if check.TLSSecret == "" {
+		return // plain HTTP
+	}
+	chain, err := fetchServedCertificates(probeCtx, net.JoinHostPort(check.Host, "443"), check.Host)
+	if err != nil {
+		check.Problems = append(check.Problems, fmt.Sprintf("TLS connection to %s failed: %v", check.Host, err))
+		return
+	}
+	leaf := chain[0]
+	check.ServedCert = newCertInfo(leaf)
+	check.Problems = append(check.Problems, certProblems("served certificate", leaf, check.Host, now)...)
+	if check.SecretCert != nil && check.SecretCert.Fingerprint != check.ServedCert.Fingerprint {
+		check.Problems = append(check.Problems, fmt.Sprintf("served certificate (issuer %q) is not the one in secret %s; the ingress controller may not have loaded it and is serving its default certificate", check.ServedCert.Issuer, check.TLSSecret))
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{Intermediates: intermediates, CurrentTime: now}); err != nil && !now.After(leaf.NotAfter) {
+		check.Problems = append(check.Problems, fmt.Sprintf("served certificate is not trusted: %v", err))
+	}
+}
+
+// ingressIPs resolves the load balancer addresses of an Ingress to IPs;
+// hostnames that do not resolve are skipped.
+func ingressIPs(ctx context.Context, addresses []string) []string {
+	var ips []string
+	for _, a := range addresses {
+		if net.ParseIP(a) != nil {
+			ips = append(ips, a)
+			continue
+		}
+		if resolved, err := lookupHost(ctx, a); err == nil {
+			ips = append(ips, resolved...)
+		}
+	}
+	return ips
+}
+
+// dialServedCertificates returns the certificate chain served at addr for serverName.
+func dialServedCertificates(ctx context.Context, addr, serverName string) ([]*x509.Certificate, error) {
+	dialer := &tls.Dialer{Config: &tls.Config{
+		ServerName: serverName,
+		// The chain is verified by the caller, which reports why it fails.
+		InsecureSkipVerify: true, // #nosec G402
+	}}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	chain := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no certificate served")
+	}
+	return chain, nil
+}
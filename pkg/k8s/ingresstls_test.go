@@ -0,0 +1,193 @@
+package k8s
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// selfSignedCert returns a self-signed certificate for names valid until notAfter.
+func selfSignedCert(t *testing.T, notAfter time.Time, names ...string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: names[0]},
+		Issuer:       pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return cert
+}
+
+func tlsSecret(namespace, name string, cert *x509.Certificate) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{corev1.TLSCertKey: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})},
+	}
+}
+
+func tlsIngress(name string, tls map[string]string, hosts ...string) *networkingv1.Ingress {
+	ing := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: name}}
+	for _, h := range hosts {
+		ing.Spec.Rules = append(ing.Spec.Rules, networkingv1.IngressRule{Host: h})
+	}
+	for host, secret := range tls {
+		ing.Spec.TLS = append(ing.Spec.TLS, networkingv1.IngressTLS{Hosts: []string{host}, SecretName: secret})
+	}
+	ing.Status.LoadBalancer.Ingress = []networkingv1.IngressLoadBalancerIngress{{IP: "203.0.113.10"}}
+	return ing
+}
+
+func TestHostMatches(t *testing.T) {
+	tests := []struct {
+		pattern, host string
+		want          bool
+	}{
+		{"shop.example.com", "Shop.Example.com", true},
+		{"*.example.com", "shop.example.com", true},
+		{"*.example.com", "a.shop.example.com", false},
+		{"*.example.com", "example.com", false},
+		{"api.example.com", "shop.example.com", false},
+	}
+	for _, tt := range tests {
+		if got := hostMatches(tt.pattern, tt.host); got != tt.want {
+			t.Errorf("hostMatches(%q, %q) = %v, want %v", tt.pattern, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestCollectIngressHosts(t *testing.T) {
+	year := time.Now().Add(365 * 24 * time.Hour)
+	clientset := fake.NewSimpleClientset(
+		tlsIngress("web", map[string]string{"shop.example.com": "shop-tls"}, "shop.example.com", "plain.example.com"),
+		tlsIngress("old", map[string]string{"old.example.com": "old-tls"}, "old.example.com"),
+		tlsIngress("api", map[string]string{"api.example.com": "shop-tls"}, "api.example.com"),
+		tlsIngress("gone", map[string]string{"gone.example.com": "missing-tls"}, "gone.example.com"),
+		tlsSecret("shop", "shop-tls", selfSignedCert(t, year, "shop.example.com")),
+		tlsSecret("shop", "old-tls", selfSignedCert(t, time.Now().Add(-time.Hour), "old.example.com")),
+	)
+	checks, err := collectIngressHosts(context.Background(), clientset, "", "")
+	if err != nil {
+		t.Fatalf("collectIngressHosts: %v", err)
+	}
+	byHost := map[string]IngressHostCheck{}
+	for _, c := range checks {
+		byHost[c.Host] = c
+	}
+	if len(checks) != 5 {
+		t.Fatalf("got %d host checks, want 5: %+v", len(checks), checks)
+	}
+	if c := byHost["shop.example.com"]; len(c.Problems) != 0 || c.SecretCert == nil || c.SecretCert.Subject != "shop.example.com" {
+		t.Errorf("shop = %+v", c)
+	}
+	if c := byHost["plain.example.com"]; c.TLSSecret != "" || len(c.Problems) != 0 {
+		t.Errorf("plain = %+v", c)
+	}
+	wantProblem := map[string]string{
+		"old.example.com":  "certificate in secret old-tls expired",
+		"api.example.com":  "does not cover api.example.com",
+		"gone.example.com": "TLS secret missing-tls not found",
+	}
+	for host, want := range wantProblem {
+		if c := byHost[host]; len(c.Problems) != 1 || !strings.Contains(c.Problems[0], want) {
+			t.Errorf("%s problems = %v, want %q", host, c.Problems, want)
+		}
+	}
+
+	checks, _ = collectIngressHosts(context.Background(), clientset, "shop", "API.example.com")
+	if len(checks) != 1 || checks[0].Ingress != "api" {
+		t.Errorf("host filter = %+v", checks)
+	}
+}
+
+func stubIngressProbe(t *testing.T, ips map[string][]string, chain []*x509.Certificate) {
+	t.Helper()
+	lookup, fetch := lookupHost, fetchServedCertificates
+	t.Cleanup(func() { lookupHost, fetchServedCertificates = lookup, fetch })
+	lookupHost = func(_ context.Context, host string) ([]string, error) {
+		if addrs, ok := ips[host]; ok {
+			return addrs, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	fetchServedCertificates = func(context.Context, string, string) ([]*x509.Certificate, error) {
+		return chain, nil
+	}
+}
+
+func TestProbeIngressHost(t *testing.T) {
+	year := time.Now().Add(365 * 24 * time.Hour)
+	secretCert := selfSignedCert(t, year, "shop.example.com")
+	defaultCert := selfSignedCert(t, year, "ingress.local")
+
+	stubIngressProbe(t, map[string][]string{"shop.example.com": {"198.51.100.7"}}, []*x509.Certificate{defaultCert})
+	check := IngressHostCheck{Host: "shop.example.com", Addresses: []string{"203.0.113.10"}, TLSSecret: "shop-tls", SecretCert: newCertInfo(secretCert)}
+	probeIngressHost(context.Background(), &check, time.Now())
+	problems := strings.Join(check.Problems, "\n")
+	for _, want := range []string{
+		"resolves to 198.51.100.7, not to the ingress address 203.0.113.10",
+		"served certificate does not cover shop.example.com",
+		"is not the one in secret shop-tls",
+		"not trusted",
+	} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("problems missing %q:\n%s", want, problems)
+		}
+	}
+	if !check.Probed || check.ServedCert.Subject != "ingress.local" {
+		t.Errorf("check = %+v", check)
+	}
+
+	check = IngressHostCheck{Host: "new.example.com", TLSSecret: "new-tls"}
+	probeIngressHost(context.Background(), &check, time.Now())
+	if len(check.Problems) != 1 || !strings.Contains(check.Problems[0], "does not resolve") {
+		t.Errorf("unresolved host problems = %v", check.Problems)
+	}
+
+	check = IngressHostCheck{Host: "*.example.com"}
+	probeIngressHost(context.Background(), &check, time.Now())
+	if check.Probed {
+		t.Error("wildcard hosts must not be probed")
+	}
+}
+
+func TestDialServedCertificates(t *testing.T) {
+	srv := httptest.NewTLSServer(nil)
+	defer srv.Close()
+	chain, err := dialServedCertificates(context.Background(), srv.Listener.Addr().String(), "example.com")
+	if err != nil {
+		t.Fatalf("dialServedCertificates: %v", err)
+	}
+	if err := chain[0].VerifyHostname("example.com"); err != nil {
+		t.Errorf("served leaf: %v", err)
+	}
+}
@@ -0,0 +1,273 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the Job and CronJob health report.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultLongRunningJob is the run time after which an active Job is reported.
+const DefaultLongRunningJob = time.Hour
+
+const (
+	// cronMissedGrace absorbs controller and Job creation latency before a
+	// scheduled run counts as missed.
+	cronMissedGrace = 5 * time.Minute
+	// cronMaxMissed is the number of missed runs after which the CronJob
+	// controller stops scheduling a CronJob without startingDeadlineSeconds.
+	cronMaxMissed = 100
+)
+
+// Job report issues, from worst.
+const (
+	JobIssueFailed          = "failed"
+	JobIssueMissedSchedule  = "missed_schedule"
+	JobIssueInvalidSchedule = "invalid_schedule"
+	JobIssueLongRunning     = "long_running"
+	JobIssueSuspended       = "suspended"
+)
+
+// JobFinding is one Job or CronJob that needs attention.
+type JobFinding struct {
+	Issue     string `json:"issue"`
+	Kind      string `json:"kind"` // Job or CronJob
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	CronJob   string `json:"cronjob,omitempty"` // the CronJob that created the Job
+	Reason    string `json:"reason,omitempty"`
+	Message   string `json:"message"`
+	Since     string `json:"since,omitempty"` // RFC3339
+}
+
+// JobsReport is the outcome of CheckJobs.
+type JobsReport struct {
+	Context     string         `json:"context"`
+	Namespace   string         `json:"namespace,omitempty"`
+	LongRunning string         `json:"long_running_threshold"`
+	Jobs        int            `json:"jobs"`
+	CronJobs    int            `json:"cronjobs"`
+	Findings    []JobFinding   `json:"findings"`
+	Issues      map[string]int `json:"issues"` // issue -> finding count
+}
+
+// jobCondition returns the condition of type t when it is true.
+func jobCondition(job *batchv1.Job, t batchv1.JobConditionType) *batchv1.JobCondition {
+	for i := range job.Status.Conditions {
+		if c := &job.Status.Conditions[i]; c.Type == t && c.Status == corev1.ConditionTrue {
+			return c
+		}
+	}
+	return nil
+}
+
+// jobCronJob returns the name of the CronJob that owns job, if any.
+func jobCronJob(job *batchv1.Job) string {
+	for _, ref := range job.OwnerReferences {
+		if ref.Kind == "CronJob" && ref.Controller != nil && *ref.Controller {
+			return ref.Name
+		}
+	}
+	return ""
+}
+
+// jobFindings reports failed Jobs and Jobs running longer than longRunning.
+// A CronJob run that failed is left out once a later run of the same CronJob
+// succeeded.
+func jobFindings(jobs []batchv1.Job, longRunning time.Duration, now time.Time) []JobFinding {
+	lastSuccess := make(map[string]time.Time) // namespace/cronjob -> creation of the newest complete run
+	for i := range jobs {
+		job := &jobs[i]
+		if cj := jobCronJob(job); cj != "" && jobCondition(job, batchv1.JobComplete) != nil {
+			key := job.Namespace + "/" + cj
+			if created := job.CreationTimestamp.Time; created.After(lastSuccess[key]) {
+				lastSuccess[key] = created
+			}
+		}
+	}
+
+	var findings []JobFinding
+	for i := range jobs {
+		job := &jobs[i]
+		cj := jobCronJob(job)
+		finding := JobFinding{Kind: "Job", Namespace: job.Namespace, Name: job.Name, CronJob: cj}
+		if failed := jobCondition(job, batchv1.JobFailed); failed != nil {
+			if cj != "" && lastSuccess[job.Namespace+"/"+cj].After(job.CreationTimestamp.Time) {
+				continue
+			}
+			finding.Issue = JobIssueFailed
+			finding.Reason = failed.Reason
+			finding.Message = fmt.Sprintf("%d pod(s) failed", job.Status.Failed)
+			if failed.Message != "" {
+				finding.Message += ": " + failed.Message
+			}
+			finding.Since = failed.LastTransitionTime.UTC().Format(time.RFC3339)
+			findings = append(findings, finding)
+			continue
+		}
+		if jobCondition(job, batchv1.JobComplete) != nil || job.Status.StartTime == nil {
+			continue
+		}
+		if elapsed := now.Sub(job.Status.StartTime.Time); elapsed > longRunning {
+			finding.Issue = JobIssueLongRunning
+			finding.Message = fmt.Sprintf("running for %s (threshold %s), %d active pod(s)",
+				elapsed.Round(time.Minute), longRunning, job.Status.Active)
+			if job.Spec.ActiveDeadlineSeconds == nil {
+				finding.Message += "; no activeDeadlineSeconds, so nothing stops it"
+			}
+			finding.Since = job.Status.StartTime.UTC().Format(time.RFC3339)
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// cronJobFinding reports a CronJob that is suspended, has an unparsable
+// schedule, or has missed scheduled runs.
+func cronJobFinding(cj *batchv1.CronJob, now time.Time) *JobFinding {
+	finding := &JobFinding{Kind: "CronJob", Namespace: cj.Namespace, Name: cj.Name}
+	last := cj.CreationTimestamp.Time
+	lastRun := "never ran"
+	if cj.Status.LastScheduleTime != nil {
+		last = cj.Status.LastScheduleTime.Time
+		lastRun = "last run scheduled " + last.UTC().Format(time.RFC3339)
+		finding.Since = last.UTC().Format(time.RFC3339)
+	}
+
+	if cj.Spec.Suspend != nil && *cj.Spec.Suspend {
+		finding.Issue = JobIssueSuspended
+		finding.Message = fmt.Sprintf("suspended (schedule %q); %s", cj.Spec.Schedule, lastRun)
+		return finding
+	}
+
+	schedule, loc, err := parseCronSchedule(cj.Spec.Schedule)
+	if err == nil && cj.Spec.TimeZone != nil && *cj.Spec.TimeZone != "" {
+		loc, err = time.LoadLocation(*cj.Spec.TimeZone)
+	}
+	if err != nil {
+		finding.Issue = JobIssueInvalidSchedule
+		finding.Message = fmt.Sprintf("schedule %q cannot be evaluated: %v", cj.Spec.Schedule, err)
+		return finding
+	}
+
+	due := schedule.next(last.In(loc))
+	if due.IsZero() || now.Sub(due) <= cronMissedGrace {
+		return nil
+	}
+	missed := 0
+	for t := due; !t.IsZero() && !t.After(now) && missed <= cronMaxMissed; t = schedule.next(t) {
+		missed++
+	}
+	count := fmt.Sprintf("%d", missed)
+	if missed > cronMaxMissed {
+		count = fmt.Sprintf("more than %d", cronMaxMissed)
+	}
+	finding.Issue = JobIssueMissedSchedule
+	finding.Message = fmt.Sprintf("%s scheduled run(s) missed since %s (schedule %q); %s",
+		count, due.UTC().Format(time.RFC3339), cj.Spec.Schedule, lastRun)
+
+	switch deadline := cj.Spec.StartingDeadlineSeconds; {
+	case cj.Spec.ConcurrencyPolicy == batchv1.ForbidConcurrent && len(cj.Status.Active) > 0:
+		finding.Reason = "ConcurrencyForbid"
+		finding.Message += "; concurrencyPolicy Forbid skips runs while the previous one is still active"
+	case missed > cronMaxMissed && deadline == nil:
+		finding.Reason = "TooManyMissedStarts"
+		finding.Message += fmt.Sprintf("; the controller stops scheduling after %d missed runs until startingDeadlineSeconds is set", cronMaxMissed)
+	case deadline != nil && now.Sub(due) > time.Duration(*deadline)*time.Second:
+		finding.Reason = "StartingDeadlineExceeded"
+		finding.Message += fmt.Sprintf("; runs later than startingDeadlineSeconds (%ds) are skipped", *deadline)
+	}
+	return finding
+}
+
+var jobIssueRank = map[string]int{
+	JobIssueFailed: 0, JobIssueMissedSchedule: 1, JobIssueInvalidSchedule: 2, JobIssueLongRunning: 3, JobIssueSuspended: 4,
+}
+
+// buildJobsReport assesses the Jobs and CronJobs in scope.
+func buildJobsReport(jobs []batchv1.Job, cronJobs []batchv1.CronJob, targetNamespace string, includeSystem bool, longRunning time.Duration, now time.Time) *JobsReport {
+	report := &JobsReport{
+		Namespace:   targetNamespace,
+		LongRunning: longRunning.String(),
+		Findings:    make([]JobFinding, 0),
+		Issues:      make(map[string]int),
+	}
+
+	var inScope []batchv1.Job
+	for _, job := range jobs {
+		if shouldScanNamespace(job.Namespace, targetNamespace, includeSystem) {
+			inScope = append(inScope, job)
+		}
+	}
+	report.Jobs = len(inScope)
+	report.Findings = append(report.Findings, jobFindings(inScope, longRunning, now)...)
+
+	for i := range cronJobs {
+		if !shouldScanNamespace(cronJobs[i].Namespace, targetNamespace, includeSystem) {
+			continue
+		}
+		report.CronJobs++
+		if f := cronJobFinding(&cronJobs[i], now); f != nil {
+			report.Findings = append(report.Findings, *f)
+		}
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if jobIssueRank[a.Issue] != jobIssueRank[b.Issue] {
+			return jobIssueRank[a.Issue] < jobIssueRank[b.Issue]
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	for _, f := range report.Findings {
+		report.Issues[f.Issue]++
+	}
+	return report
+}
+
+// CheckJobs reports failed Jobs, Jobs active for longer than longRunning
+// (DefaultLongRunningJob when zero), and CronJobs that are suspended or
+// missed scheduled runs. If targetNamespace is non-empty, only that
+// namespace is checked. If includeSystem is false, system namespaces are
+// excluded.
+func (p *Provider) CheckJobs(ctx context.Context, contextName, targetNamespace string, longRunning time.Duration, includeSystem bool) (*JobsReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	report, err := checkJobs(queryCtx, clientset, targetNamespace, longRunning, includeSystem, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	report.Context = contextName
+	return report, nil
+}
+
+func checkJobs(ctx context.Context, clientset kubernetes.Interface, targetNamespace string, longRunning time.Duration, includeSystem bool, now time.Time) (*JobsReport, error) {
+	if longRunning <= 0 {
+		longRunning = DefaultLongRunningJob
+	}
+	jobs, err := clientset.BatchV1().Jobs(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	cronJobs, err := clientset.BatchV1().CronJobs(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cronjobs: %w", err)
+	}
+	return buildJobsReport(jobs.Items, cronJobs.Items, targetNamespace, includeSystem, longRunning, now), nil
+}
@@ -0,0 +1,126 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+var jobsNow = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+// testJob returns a Job created ago before jobsNow, owned by cronJob when set,
+// with a true condition of the given type ("" for a running Job).
+func testJob(namespace, name, cronJob string, ago time.Duration, condition batchv1.JobConditionType) *batchv1.Job {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, CreationTimestamp: metav1.NewTime(jobsNow.Add(-ago))},
+		Status:     batchv1.JobStatus{StartTime: ptr.To(metav1.NewTime(jobsNow.Add(-ago)))},
+	}
+	if cronJob != "" {
+		job.OwnerReferences = []metav1.OwnerReference{{Kind: "CronJob", Name: cronJob, Controller: ptr.To(true)}}
+	}
+	switch condition {
+	case batchv1.JobFailed:
+		job.Status.Failed = 6
+		job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded",
+			Message: "Job has reached the specified backoff limit", LastTransitionTime: metav1.NewTime(jobsNow.Add(-ago / 2))}}
+	case batchv1.JobComplete:
+		job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+	default:
+		job.Status.Active = 1
+	}
+	return job
+}
+
+func testCronJob(namespace, name, schedule string, lastScheduled time.Duration) *batchv1.CronJob {
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, CreationTimestamp: metav1.NewTime(jobsNow.Add(-30 * 24 * time.Hour))},
+		Spec:       batchv1.CronJobSpec{Schedule: schedule},
+		Status:     batchv1.CronJobStatus{LastScheduleTime: ptr.To(metav1.NewTime(jobsNow.Add(-lastScheduled)))},
+	}
+}
+
+func TestCheckJobs(t *testing.T) {
+	suspended := testCronJob("batch", "paused", "@daily", 48*time.Hour)
+	suspended.Spec.Suspend = ptr.To(true)
+	forbid := testCronJob("batch", "sync", "*/10 * * * *", time.Hour)
+	forbid.Spec.ConcurrencyPolicy = batchv1.ForbidConcurrent
+	forbid.Status.Active = []corev1.ObjectReference{{Name: "sync-1"}}
+
+	clientset := fake.NewClientset(
+		testJob("batch", "migrate", "", 3*time.Hour, batchv1.JobFailed),
+		testJob("batch", "report-1", "report", 2*time.Hour, batchv1.JobFailed),
+		testJob("batch", "report-2", "report", time.Hour, batchv1.JobComplete),
+		testJob("batch", "backup-1", "backup", 3*time.Hour, ""),
+		testJob("batch", "quick", "", 10*time.Minute, ""),
+		testJob("kube-system", "system", "", 5*time.Hour, batchv1.JobFailed),
+		testCronJob("batch", "report", "0 * * * *", 30*time.Minute),
+		testCronJob("batch", "nightly", "0 2 * * *", 58*time.Hour),
+		testCronJob("batch", "broken", "0 0 31 13 *", time.Hour),
+		suspended, forbid,
+	)
+	report, err := checkJobs(context.Background(), clientset, "", 0, false, jobsNow)
+	if err != nil {
+		t.Fatalf("checkJobs: %v", err)
+	}
+	if report.Jobs != 5 || report.CronJobs != 5 || report.LongRunning != "1h0m0s" {
+		t.Errorf("report scope = %d jobs, %d cronjobs, threshold %s", report.Jobs, report.CronJobs, report.LongRunning)
+	}
+
+	var order []string
+	got := make(map[string]JobFinding)
+	for _, f := range report.Findings {
+		order = append(order, f.Issue+":"+f.Name)
+		got[f.Name] = f
+	}
+	want := []string{"failed:migrate", "missed_schedule:nightly", "missed_schedule:sync", "invalid_schedule:broken", "long_running:backup-1", "suspended:paused"}
+	if strings.Join(order, " ") != strings.Join(want, " ") {
+		t.Fatalf("findings = %v, want %v (report-1 recovered, quick under the threshold)", order, want)
+	}
+	if m := got["migrate"]; m.Reason != "BackoffLimitExceeded" || !strings.Contains(m.Message, "6 pod(s) failed: Job has reached") {
+		t.Errorf("failed job: %+v", m)
+	}
+	if n := got["nightly"]; !strings.Contains(n.Message, "2 scheduled run(s) missed since 2026-10-15T02:00:00Z") {
+		t.Errorf("missed cronjob: %+v", n)
+	}
+	if s := got["sync"]; s.Reason != "ConcurrencyForbid" || !strings.Contains(s.Message, "6 scheduled run(s) missed") {
+		t.Errorf("forbid cronjob: %+v", s)
+	}
+	if b := got["backup-1"]; b.CronJob != "backup" || !strings.Contains(b.Message, "running for 3h0m0s (threshold 1h0m0s)") || !strings.Contains(b.Message, "no activeDeadlineSeconds") {
+		t.Errorf("long-running job: %+v", b)
+	}
+	if report.Issues[JobIssueMissedSchedule] != 2 || report.Issues[JobIssueFailed] != 1 {
+		t.Errorf("issues = %v", report.Issues)
+	}
+}
+
+func TestCronJobFindingDeadlines(t *testing.T) {
+	stuck := testCronJob("batch", "stuck", "* * * * *", 5*time.Hour)
+	if f := cronJobFinding(stuck, jobsNow); f == nil || f.Reason != "TooManyMissedStarts" || !strings.Contains(f.Message, "more than 100") {
+		t.Errorf("stuck cronjob: %+v", f)
+	}
+
+	late := testCronJob("batch", "late", "@hourly", 3*time.Hour)
+	late.Spec.StartingDeadlineSeconds = ptr.To(int64(300))
+	if f := cronJobFinding(late, jobsNow); f == nil || f.Reason != "StartingDeadlineExceeded" {
+		t.Errorf("late cronjob: %+v", f)
+	}
+
+	// 09:00 in Kolkata is 03:30 UTC: the run 8h30m ago was on time.
+	zoned := testCronJob("batch", "zoned", "0 9 * * *", 8*time.Hour+30*time.Minute)
+	zoned.Spec.TimeZone = ptr.To("Asia/Kolkata")
+	if f := cronJobFinding(zoned, jobsNow); f != nil {
+		t.Errorf("on-time cronjob reported: %+v", f)
+	}
+
+	recent := testCronJob("batch", "recent", "57 * * * *", 63*time.Minute)
+	if f := cronJobFinding(recent, jobsNow); f != nil {
+		t.Errorf("run within the grace period reported: %+v", f)
+	}
+}
@@ -0,0 +1,207 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file keeps live state for frequently queried clusters: shared pod,
+// node and event informers whose local caches answer health checks without
+// listing from the API server each time.
+package k8s
+
+import (
+	"time"
+
+	"github.com/e9169/kopilot/pkg/debug"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// liveResync is how often the informers replay their caches; watches keep
+// them current in between.
+const liveResync = 10 * time.Minute
+
+// liveState holds the pod, node and event informers of one context.
+type liveState struct {
+	stop   chan struct{}
+	pods   corelisters.PodLister
+	nodes  corelisters.NodeLister
+	events corelisters.EventLister
+	synced []cache.InformerSynced
+}
+
+// startLiveState starts pod, node and event informers on clientset. They
+// fill their caches in the background; ready reports when they have.
+func startLiveState(clientset kubernetes.Interface, contextName string) *liveState {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, liveResync,
+		informers.WithTransform(stripManagedFields))
+	pods := factory.Core().V1().Pods()
+	nodes := factory.Core().V1().Nodes()
+	events := factory.Core().V1().Events()
+	l := &liveState{
+		stop:   make(chan struct{}),
+		pods:   pods.Lister(),
+		nodes:  nodes.Lister(),
+		events: events.Lister(),
+		synced: []cache.InformerSynced{pods.Informer().HasSynced, nodes.Informer().HasSynced, events.Informer().HasSynced},
+	}
+	factory.Start(l.stop)
+	debug.Logf(debug.Cache, "context %s: live state started", contextName)
+	return l
+}
+
+// stripManagedFields drops the managed fields of cached objects, which are
+// often larger than the rest of a pod and never read here.
+func stripManagedFields(obj any) (any, error) {
+	if accessor, err := meta.Accessor(obj); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	return obj, nil
+}
+
+// ready reports whether every informer has filled its cache.
+func (l *liveState) ready() bool {
+	for _, synced := range l.synced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+// close stops the informers.
+func (l *liveState) close() {
+	close(l.stop)
+}
+
+// nodeInfo returns the cached nodes matching the filter's node selector,
+// like collectNodeInfo.
+func (l *liveState) nodeInfo(filter HealthFilter) ([]NodeInfo, int, error) {
+	selector, err := labels.Parse(filter.NodeSelector)
+	if err != nil {
+		return nil, 0, err
+	}
+	nodes, err := l.nodes.List(selector)
+	if err != nil {
+		return nil, 0, err
+	}
+	infos, healthy := summarizeNodes(nodes)
+	return infos, healthy, nil
+}
+
+// podHealth returns the health of the cached pods matching the filter, like
+// collectPodHealth. The caches cannot apply field selectors; callers list
+// from the API server for those.
+func (l *liveState) podHealth(filter HealthFilter, maxPods int) (podHealth, error) {
+	health := podHealth{unhealthy: make([]PodInfo, 0)}
+	selector, err := labels.Parse(filter.LabelSelector)
+	if err != nil {
+		return health, err
+	}
+	var pods []*corev1.Pod
+	if filter.Namespace != "" {
+		pods, err = l.pods.Pods(filter.Namespace).List(selector)
+	} else {
+		pods, err = l.pods.List(selector)
+	}
+	if err != nil {
+		return health, err
+	}
+	for _, pod := range pods {
+		if !health.add(pod, maxPods) {
+			health.truncated = true
+			break
+		}
+	}
+	return health, nil
+}
+
+// eventList returns the cached events of namespace, or of every namespace.
+func (l *liveState) eventList(namespace string) ([]corev1.Event, error) {
+	var events []*corev1.Event
+	var err error
+	if namespace != "" {
+		events, err = l.events.Events(namespace).List(labels.Everything())
+	} else {
+		events, err = l.events.List(labels.Everything())
+	}
+	if err != nil {
+		return nil, err
+	}
+	items := make([]corev1.Event, len(events))
+	for i, e := range events {
+		items[i] = *e
+	}
+	return items, nil
+}
+
+// live returns the context's live state when it is ready to answer a query,
+// and otherwise nil. Each call counts as a query: once the context has been
+// queried as often as WithLiveState asks, its informers are started, and
+// queries are answered from their caches once they have synced.
+func (c *ContextProvider) live() *liveState {
+	after := c.parent.liveAfter
+	if after <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.liveState == nil {
+		c.queries++
+		if c.queries < after {
+			return nil
+		}
+		if err := c.buildClients(); err != nil {
+			return nil
+		}
+		c.liveState = startLiveState(c.clientset, c.name)
+		return nil
+	}
+	if !c.liveState.ready() {
+		return nil
+	}
+	return c.liveState
+}
+
+// liveFor returns the ready live state of contextName, counting the query;
+// see ContextProvider.live.
+func (p *Provider) liveFor(contextName string) *liveState {
+	c, err := p.ForContext(contextName)
+	if err != nil {
+		return nil
+	}
+	return c.live()
+}
+
+// stopLive stops the context's informers. Callers hold c.mu.
+func (c *ContextProvider) stopLive() {
+	if c.liveState != nil {
+		c.liveState.close()
+		c.liveState = nil
+		c.queries = 0
+		debug.Logf(debug.Cache, "context %s: live state stopped", c.name)
+	}
+}
+
+// LiveContexts returns the contexts whose informers are running, sorted.
+func (p *Provider) LiveContexts() []string {
+	var names []string
+	for _, c := range p.contextScopes() {
+		c.mu.Lock()
+		if c.liveState != nil {
+			names = append(names, c.name)
+		}
+		c.mu.Unlock()
+	}
+	return names
+}
+
+// StopLiveState stops the informers of every context, e.g. when the session
+// ends.
+func (p *Provider) StopLiveState() {
+	for _, c := range p.contextScopes() {
+		c.mu.Lock()
+		c.stopLive()
+		c.mu.Unlock()
+	}
+}
@@ -0,0 +1,112 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// liveTestProvider returns a provider whose first context uses clientset.
+// The fake API server cannot be dialled, so the reachability probe is off.
+func liveTestProvider(t *testing.T, clientset *fake.Clientset, opts ...ProviderOption) *Provider {
+	t.Helper()
+	kubeconfigPath, cleanup := createTempKubeconfig(t, 1)
+	t.Cleanup(cleanup)
+	provider, err := NewProvider(kubeconfigPath, append([]ProviderOption{WithReachTimeout(0)}, opts...)...)
+	if err != nil {
+		t.Fatalf(errNewProvider, err)
+	}
+	t.Cleanup(provider.StopLiveState)
+	c, err := provider.ForContext(testContext1)
+	if err != nil {
+		t.Fatalf("ForContext() failed: %v", err)
+	}
+	c.clientset, c.restConfig = clientset, &rest.Config{Host: "https://test"}
+	return provider
+}
+
+func TestLiveStateAnswersStatusChecks(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"pool": "gpu"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod", Labels: map[string]string{"app": "api"}}, Status: corev1.PodStatus{Phase: corev1.PodPending}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod"}, Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+		&corev1.Event{ObjectMeta: metav1.ObjectMeta{Name: "e1", Namespace: "prod"}, Reason: "BackOff", Type: corev1.EventTypeWarning, LastTimestamp: metav1.Now()},
+	)
+	var podLists int
+	clientset.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		podLists++
+		return false, nil, nil
+	})
+	provider := liveTestProvider(t, clientset, WithLiveState(2))
+	filter := HealthFilter{Namespace: "prod"}
+	ctx := context.Background()
+
+	// The first query lists from the API server, the second starts the
+	// informers, whose initial list is the second pod list.
+	for range 2 {
+		if _, err := provider.GetFilteredClusterStatus(ctx, testContext1, filter); err != nil {
+			t.Fatalf("GetFilteredClusterStatus() failed: %v", err)
+		}
+	}
+	if live := provider.LiveContexts(); len(live) != 1 || live[0] != testContext1 {
+		t.Fatalf("LiveContexts() = %v, want %s", live, testContext1)
+	}
+	c, _ := provider.ForContext(testContext1)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c.mu.Lock()
+		ready := c.liveState.ready()
+		c.mu.Unlock()
+		if ready {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("informers did not sync")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	listed := podLists
+	status, err := provider.GetFilteredClusterStatus(ctx, testContext1, filter)
+	if err != nil {
+		t.Fatalf("GetFilteredClusterStatus() failed: %v", err)
+	}
+	if podLists != listed {
+		t.Errorf("a status check with live state should not list pods, got %d more list(s)", podLists-listed)
+	}
+	if status.PodCount != 2 || status.HealthyPods != 1 || status.NodeCount != 1 {
+		t.Errorf("live status = %d pods, %d healthy, %d nodes; want 2, 1, 1", status.PodCount, status.HealthyPods, status.NodeCount)
+	}
+	if status, _ = provider.GetFilteredClusterStatus(ctx, testContext1, HealthFilter{LabelSelector: "app=api", NodeSelector: "pool=cpu"}); status.PodCount != 1 || status.NodeCount != 0 {
+		t.Errorf("live status with selectors = %d pods, %d nodes; want 1, 0", status.PodCount, status.NodeCount)
+	}
+
+	report, err := provider.RankEventNoise(ctx, testContext1, "prod", time.Hour, true, 5)
+	if err != nil || len(report.Sources) != 1 {
+		t.Errorf("RankEventNoise() from live state = %+v, %v; want one source", report, err)
+	}
+
+	provider.StopLiveState()
+	if live := provider.LiveContexts(); len(live) != 0 {
+		t.Errorf("LiveContexts() after StopLiveState() = %v", live)
+	}
+}
+
+func TestLiveStateDisabledByDefault(t *testing.T) {
+	provider := liveTestProvider(t, fake.NewClientset())
+	for range 5 {
+		if _, err := provider.GetFilteredClusterStatus(context.Background(), testContext1, HealthFilter{Namespace: "prod"}); err != nil {
+			t.Fatalf("GetFilteredClusterStatus() failed: %v", err)
+		}
+	}
+	if live := provider.LiveContexts(); len(live) != 0 {
+		t.Errorf("LiveContexts() = %v, want none without WithLiveState", live)
+	}
+}
@@ -0,0 +1,430 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains log searches against a cluster's Loki or Elasticsearch.
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Log backends supported by QueryLogs.
+const (
+	LogBackendLoki          = "loki"
+	LogBackendElasticsearch = "elasticsearch"
+)
+
+const (
+	// MaxLogEntries bounds the log lines returned by QueryLogs.
+	MaxLogEntries = 500
+	// DefaultLogEntries is the number of lines returned when no limit is given.
+	DefaultLogEntries = 100
+	// maxLogLine truncates very long lines (stack traces, JSON dumps).
+	maxLogLine = 2000
+
+	defaultLokiPort          = 3100
+	defaultElasticsearchPort = 9200
+	defaultElasticIndex      = "logstash-*"
+)
+
+// logsHTTPClient queries log backends given by URL.
+var logsHTTPClient = &http.Client{Timeout: DefaultAPITimeout}
+
+// LogsEndpoint locates the log aggregation backend of a cluster, reached at
+// a URL from this machine or as a Service through the API server proxy.
+type LogsEndpoint struct {
+	// Type is "loki" (default) or "elasticsearch".
+	Type      string `json:"type,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Service   string `json:"service,omitempty"`
+	Port      int    `json:"port,omitempty"`
+	// BearerTokenEnv names the environment variable holding a token sent
+	// to URL, so the token stays out of the config file.
+	BearerTokenEnv string `json:"bearer_token_env,omitempty"`
+	// Index is the Elasticsearch index pattern searched (default logstash-*).
+	Index string `json:"index,omitempty"`
+}
+
+// Validate checks the backend type and that the endpoint has a usable URL or Service.
+func (e LogsEndpoint) Validate() error {
+	switch e.backend() {
+	case LogBackendLoki, LogBackendElasticsearch:
+	default:
+		return fmt.Errorf("unsupported log backend %q (use %s or %s)", e.Type, LogBackendLoki, LogBackendElasticsearch)
+	}
+	switch {
+	case e.URL != "":
+		u, err := url.Parse(e.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("logs url %q must be an http(s) URL", e.URL)
+		}
+	case e.Namespace == "" || e.Service == "":
+		return fmt.Errorf("logs endpoint needs a url or a namespace and service")
+	}
+	if e.Port < 0 || e.Port > 65535 {
+		return fmt.Errorf("invalid logs port %d", e.Port)
+	}
+	return nil
+}
+
+// String describes the endpoint, e.g. "loki monitoring/loki:3100".
+func (e LogsEndpoint) String() string {
+	if e.URL != "" {
+		return e.backend() + " " + e.URL
+	}
+	return fmt.Sprintf("%s %s/%s:%d", e.backend(), e.Namespace, e.Service, e.port())
+}
+
+func (e LogsEndpoint) backend() string {
+	if e.Type == "" {
+		return LogBackendLoki
+	}
+	return strings.ToLower(e.Type)
+}
+
+func (e LogsEndpoint) port() int {
+	switch {
+	case e.Port != 0:
+		return e.Port
+	case e.backend() == LogBackendElasticsearch:
+		return defaultElasticsearchPort
+	default:
+		return defaultLokiPort
+	}
+}
+
+func (e LogsEndpoint) index() string {
+	if e.Index == "" {
+		return defaultElasticIndex
+	}
+	return e.Index
+}
+
+// LogQuery selects log lines. Namespace, Pod, Container and Contains build
+// the backend query; Query replaces it with raw LogQL or Lucene syntax.
+type LogQuery struct {
+	Namespace string
+	Pod       string // exact name, or a prefix ending in "*"
+	Container string
+	Contains  string // case-sensitive substring of the line
+	Query     string
+	Since     time.Duration // look-back window; 1h when 0
+	Limit     int           // DefaultLogEntries when 0, at most MaxLogEntries
+	End       time.Time     // end of the window; now when zero
+}
+
+func (q LogQuery) window(now time.Time) (time.Time, time.Time) {
+	end := q.End
+	if end.IsZero() {
+		end = now
+	}
+	since := q.Since
+	if since <= 0 {
+		since = time.Hour
+	}
+	return end.Add(-since), end
+}
+
+func (q LogQuery) limit() int {
+	if q.Limit <= 0 {
+		return DefaultLogEntries
+	}
+	return min(q.Limit, MaxLogEntries)
+}
+
+// LogEntry is one log line.
+type LogEntry struct {
+	Time      time.Time `json:"time"`
+	Namespace string    `json:"namespace,omitempty"`
+	Pod       string    `json:"pod,omitempty"`
+	Container string    `json:"container,omitempty"`
+	Line      string    `json:"line"`
+}
+
+// LogResult is the outcome of a log search, oldest entry first.
+type LogResult struct {
+	Context  string     `json:"context"`
+	Endpoint string     `json:"endpoint"`
+	Query    string     `json:"query"`
+	Entries  []LogEntry `json:"entries"`
+	// Truncated reports that more lines matched than were returned.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// QueryLogs searches the log backend of contextName for the latest lines
+// matching q within its window.
+func (p *Provider) QueryLogs(ctx context.Context, contextName string, endpoint LogsEndpoint, q LogQuery) (*LogResult, error) {
+	if err := endpoint.Validate(); err != nil {
+		return nil, err
+	}
+	api := serviceAPI{
+		url:       endpoint.URL,
+		tokenEnv:  endpoint.BearerTokenEnv,
+		namespace: endpoint.Namespace,
+		service:   endpoint.Service,
+		port:      endpoint.port(),
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	var result *LogResult
+	switch endpoint.backend() {
+	case LogBackendElasticsearch:
+		query, path, params := elasticRequest(endpoint.index(), q, time.Now())
+		body, err := p.fetchLogs(queryCtx, contextName, api, path, params)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", endpoint, err)
+		}
+		if result, err = parseElasticResponse(body, q.limit()); err != nil {
+			return nil, fmt.Errorf("%s: %w", endpoint, err)
+		}
+		result.Query = query
+	default:
+		query, err := lokiQuery(q)
+		if err != nil {
+			return nil, err
+		}
+		path, params := lokiRequest(query, q, time.Now())
+		body, err := p.fetchLogs(queryCtx, contextName, api, path, params)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", endpoint, err)
+		}
+		if result, err = parseLokiResponse(body, q.limit()); err != nil {
+			return nil, fmt.Errorf("%s: %w", endpoint, err)
+		}
+		result.Query = query
+	}
+	result.Context = contextName
+	result.Endpoint = endpoint.String()
+	return result, nil
+}
+
+// fetchLogs calls the backend, creating a client only for the service proxy.
+func (p *Provider) fetchLogs(ctx context.Context, contextName string, api serviceAPI, path string, params map[string]string) ([]byte, error) {
+	if api.url != "" {
+		return api.get(ctx, nil, logsHTTPClient, path, params)
+	}
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+	return api.get(ctx, clientset, logsHTTPClient, path, params)
+}
+
+// lokiQuery builds a LogQL query from q using the namespace, pod and
+// container labels of the Kubernetes service discovery of Promtail and
+// Grafana Agent.
+func lokiQuery(q LogQuery) (string, error) {
+	if q.Query != "" {
+		return q.Query, nil
+	}
+	var matchers []string
+	if q.Namespace != "" {
+		matchers = append(matchers, fmt.Sprintf("namespace=%q", q.Namespace))
+	}
+	if prefix, ok := strings.CutSuffix(q.Pod, "*"); ok {
+		matchers = append(matchers, fmt.Sprintf("pod=~%q", regexp.QuoteMeta(prefix)+".*"))
+	} else if q.Pod != "" {
+		matchers = append(matchers, fmt.Sprintf("pod=%q", q.Pod))
+	}
+	if q.Container != "" {
+		matchers = append(matchers, fmt.Sprintf("container=%q", q.Container))
+	}
+	if len(matchers) == 0 {
+		return "", fmt.Errorf("namespace or pod is required unless a raw query is given")
+	}
+	query := "{" + strings.Join(matchers, ", ") + "}"
+	if q.Contains != "" {
+		query += fmt.Sprintf(" |= %q", q.Contains)
+	}
+	return query, nil
+}
+
+// lokiRequest returns the query_range path and parameters for query.
+func lokiRequest(query string, q LogQuery, now time.Time) (string, map[string]string) {
+	start, end := q.window(now)
+	return "/loki/api/v1/query_range", map[string]string{
+		"query":     query,
+		"start":     strconv.FormatInt(start.UnixNano(), 10),
+		"end":       strconv.FormatInt(end.UnixNano(), 10),
+		"limit":     strconv.Itoa(q.limit() + 1), // one extra line tells truncation apart
+		"direction": "backward",
+	}
+}
+
+// parseLokiResponse merges the streams of a query_range response and keeps
+// the latest limit lines, oldest first.
+func parseLokiResponse(body []byte, limit int) (*LogResult, error) {
+	var resp struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Stream map[string]string `json:"stream"`
+				Values [][2]string       `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if resp.Status != "success" {
+		return nil, fmt.Errorf("query failed: %s", resp.Error)
+	}
+	if resp.Data.ResultType != "streams" {
+		return nil, fmt.Errorf("query returned %s, not log lines; use a log query rather than a metric query", resp.Data.ResultType)
+	}
+	var entries []LogEntry
+	for _, s := range resp.Data.Result {
+		for _, v := range s.Values {
+			ns, err := strconv.ParseInt(v[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed timestamp %q", v[0])
+			}
+			entries = append(entries, LogEntry{
+				Time:      time.Unix(0, ns).UTC(),
+				Namespace: s.Stream["namespace"],
+				Pod:       s.Stream["pod"],
+				Container: s.Stream["container"],
+				Line:      v[1],
+			})
+		}
+	}
+	return latestLogEntries(entries, limit), nil
+}
+
+// elasticRequest builds a Lucene query from q using the kubernetes.* fields
+// added by Fluent Bit and Fluentd, and returns it with the _search path and
+// parameters.
+func elasticRequest(index string, q LogQuery, now time.Time) (string, string, map[string]string) {
+	query := q.Query
+	if query == "" {
+		var terms []string
+		if q.Namespace != "" {
+			terms = append(terms, "kubernetes.namespace_name:"+luceneQuote(q.Namespace))
+		}
+		if prefix, ok := strings.CutSuffix(q.Pod, "*"); ok {
+			terms = append(terms, "kubernetes.pod_name:"+luceneEscape(prefix)+"*")
+		} else if q.Pod != "" {
+			terms = append(terms, "kubernetes.pod_name:"+luceneQuote(q.Pod))
+		}
+		if q.Container != "" {
+			terms = append(terms, "kubernetes.container_name:"+luceneQuote(q.Container))
+		}
+		if q.Contains != "" {
+			terms = append(terms, luceneQuote(q.Contains))
+		}
+		query = strings.Join(terms, " AND ")
+	}
+	start, end := q.window(now)
+	timeRange := fmt.Sprintf("@timestamp:[%q TO %q]", start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339))
+	if query == "" {
+		query = timeRange
+	} else {
+		query = "(" + query + ") AND " + timeRange
+	}
+	return query, "/" + index + "/_search", map[string]string{
+		"q":                query,
+		"size":             strconv.Itoa(q.limit()),
+		"sort":             "@timestamp:desc",
+		"track_total_hits": "true",
+	}
+}
+
+func luceneQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// luceneEscape escapes the Lucene special characters of an unquoted term.
+func luceneEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`+-=&|><!(){}[]^"~*?:\/ `, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// parseElasticResponse reads the hits of a _search response, newest first.
+func parseElasticResponse(body []byte, limit int) (*LogResult, error) {
+	var resp struct {
+		Error json.RawMessage `json:"error"`
+		Hits  struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				Source map[string]any `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(resp.Error) > 0 {
+		var e struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		}
+		if json.Unmarshal(resp.Error, &e) != nil || e.Reason == "" {
+			return nil, fmt.Errorf("query failed: %s", resp.Error)
+		}
+		return nil, fmt.Errorf("query failed (%s): %s", e.Type, e.Reason)
+	}
+	entries := make([]LogEntry, 0, len(resp.Hits.Hits))
+	for _, h := range resp.Hits.Hits {
+		entry := LogEntry{Line: firstString(h.Source, "log", "message")}
+		if ts, err := time.Parse(time.RFC3339Nano, firstString(h.Source, "@timestamp")); err == nil {
+			entry.Time = ts.UTC()
+		}
+		if k8sMeta, ok := h.Source["kubernetes"].(map[string]any); ok {
+			entry.Namespace = firstString(k8sMeta, "namespace_name")
+			entry.Pod = firstString(k8sMeta, "pod_name")
+			entry.Container = firstString(k8sMeta, "container_name")
+		}
+		entries = append(entries, entry)
+	}
+	result := latestLogEntries(entries, limit)
+	result.Truncated = result.Truncated || resp.Hits.Total.Value > len(result.Entries)
+	return result, nil
+}
+
+func firstString(m map[string]any, keys ...string) string {
+	for _, k := range keys {
+		if s, ok := m[k].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// latestLogEntries keeps the latest limit entries, oldest first, with
+// overlong lines shortened.
+func latestLogEntries(entries []LogEntry, limit int) *LogResult {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.After(entries[j].Time) })
+	result := &LogResult{Entries: []LogEntry{}}
+	if len(entries) > limit {
+		entries, result.Truncated = entries[:limit], true
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		e.Line = strings.TrimRight(e.Line, "\n")
+		if len(e.Line) > maxLogLine {
+			e.Line = e.Line[:maxLogLine] + "…"
+		}
+		result.Entries = append(result.Entries, e)
+	}
+	return result
+}
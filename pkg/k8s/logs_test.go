@@ -0,0 +1,155 @@
+package k8s
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestLogsEndpointValidate(t *testing.T) {
+	valid := []LogsEndpoint{
+		{URL: "https://loki.example.com"},
+		{Type: "elasticsearch", Namespace: "logging", Service: "elasticsearch"},
+		{Type: "Loki", Namespace: "monitoring", Service: "loki", Port: 3100},
+	}
+	for _, e := range valid {
+		if err := e.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", e, err)
+		}
+	}
+	invalid := []LogsEndpoint{
+		{},
+		{Type: "splunk", URL: "https://splunk"},
+		{URL: "loki:3100"},
+		{Namespace: "monitoring"},
+		{Namespace: "monitoring", Service: "loki", Port: -1},
+	}
+	for _, e := range invalid {
+		if err := e.Validate(); err == nil {
+			t.Errorf("Validate(%+v) expected error", e)
+		}
+	}
+	if got := (LogsEndpoint{Type: "elasticsearch", Namespace: "logging", Service: "es"}).String(); got != "elasticsearch logging/es:9200" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestLokiQuery(t *testing.T) {
+	tests := []struct {
+		q    LogQuery
+		want string
+	}{
+		{LogQuery{Namespace: "shop", Pod: "web-1", Container: "app"}, `{namespace="shop", pod="web-1", container="app"}`},
+		{LogQuery{Namespace: "shop", Pod: "web-7d9f*", Contains: `level="error"`}, `{namespace="shop", pod=~"web-7d9f.*"} |= "level=\"error\""`},
+		{LogQuery{Pod: "a.b*"}, `{pod=~"a\\.b.*"}`},
+		{LogQuery{Query: `{app="x"}`, Namespace: "ignored"}, `{app="x"}`},
+	}
+	for _, tt := range tests {
+		got, err := lokiQuery(tt.q)
+		if err != nil || got != tt.want {
+			t.Errorf("lokiQuery(%+v) = %s, %v; want %s", tt.q, got, err, tt.want)
+		}
+	}
+	if _, err := lokiQuery(LogQuery{Contains: "panic"}); err == nil {
+		t.Error("expected error without namespace or pod")
+	}
+
+	now := time.Unix(1700000000, 0)
+	path, params := lokiRequest(`{pod="x"}`, LogQuery{Since: time.Minute, Limit: 10}, now)
+	if path != "/loki/api/v1/query_range" || params["start"] != "1699999940000000000" || params["end"] != "1700000000000000000" ||
+		params["limit"] != "11" || params["direction"] != "backward" {
+		t.Errorf("lokiRequest = %s %v", path, params)
+	}
+}
+
+func TestParseLokiResponse(t *testing.T) {
+	body := `{"status":"success","data":{"resultType":"streams","result":[
+		{"stream":{"namespace":"shop","pod":"web-1","container":"app"},"values":[["1700000003000000000","third\n"],["1700000001000000000","first"]]},
+		{"stream":{"namespace":"shop","pod":"web-2","container":"app"},"values":[["1700000002000000000","second"]]}]}}`
+	r, err := parseLokiResponse([]byte(body), 2)
+	if err != nil {
+		t.Fatalf("parseLokiResponse: %v", err)
+	}
+	if !r.Truncated || len(r.Entries) != 2 || r.Entries[0].Line != "second" || r.Entries[0].Pod != "web-2" || r.Entries[1].Line != "third" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+
+	if _, err := parseLokiResponse([]byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`), 10); err == nil || !strings.Contains(err.Error(), "metric query") {
+		t.Errorf("expected metric query error, got %v", err)
+	}
+	if _, err := parseLokiResponse([]byte(`{"status":"error","error":"parse error"}`), 10); err == nil || !strings.Contains(err.Error(), "parse error") {
+		t.Errorf("expected query error, got %v", err)
+	}
+}
+
+func TestElasticRequest(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	query, path, params := elasticRequest("logs-*", LogQuery{Namespace: "shop", Pod: "web-7d9f*", Contains: "OOM", Since: time.Hour, Limit: 5}, now)
+	want := `(kubernetes.namespace_name:"shop" AND kubernetes.pod_name:web\-7d9f* AND "OOM") AND @timestamp:["2023-11-14T21:13:20Z" TO "2023-11-14T22:13:20Z"]`
+	if query != want || params["q"] != want {
+		t.Errorf("query = %s\nwant    %s", query, want)
+	}
+	if path != "/logs-*/_search" || params["size"] != "5" || params["sort"] != "@timestamp:desc" {
+		t.Errorf("request = %s %v", path, params)
+	}
+
+	query, _, _ = elasticRequest("logs-*", LogQuery{}, now)
+	if !strings.HasPrefix(query, "@timestamp:[") {
+		t.Errorf("empty query = %s", query)
+	}
+}
+
+func TestParseElasticResponse(t *testing.T) {
+	body := `{"hits":{"total":{"value":7},"hits":[
+		{"_source":{"@timestamp":"2024-05-01T10:00:02Z","log":"panic: boom","kubernetes":{"namespace_name":"shop","pod_name":"web-1","container_name":"app"}}},
+		{"_source":{"@timestamp":"2024-05-01T10:00:01Z","message":"starting"}}]}}`
+	r, err := parseElasticResponse([]byte(body), 2)
+	if err != nil {
+		t.Fatalf("parseElasticResponse: %v", err)
+	}
+	if !r.Truncated || len(r.Entries) != 2 || r.Entries[0].Line != "starting" || r.Entries[1].Pod != "web-1" || r.Entries[1].Line != "panic: boom" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+
+	_, err = parseElasticResponse([]byte(`{"error":{"type":"index_not_found_exception","reason":"no such index [logs-*]"},"status":404}`), 2)
+	if err == nil || !strings.Contains(err.Error(), "index_not_found_exception") {
+		t.Errorf("expected index error, got %v", err)
+	}
+}
+
+func TestQueryLogsURL(t *testing.T) {
+	var gotPath, gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQuery = r.URL.Path, r.URL.Query().Get("query")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[{"stream":{"pod":"web-1"},"values":[["1700000000000000000","hello"]]}]}}`))
+	}))
+	defer server.Close()
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters["prod"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	config.Contexts["prod"] = &clientcmdapi.Context{Cluster: "prod"}
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := NewProvider(path)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+
+	r, err := provider.QueryLogs(context.Background(), "prod", LogsEndpoint{URL: server.URL}, LogQuery{Namespace: "shop"})
+	if err != nil {
+		t.Fatalf("QueryLogs: %v", err)
+	}
+	if gotPath != "/loki/api/v1/query_range" || gotQuery != `{namespace="shop"}` || r.Query != gotQuery || len(r.Entries) != 1 ||
+		r.Context != "prod" || r.Endpoint != "loki "+server.URL {
+		t.Errorf("unexpected result %+v (path %s, query %s)", r, gotPath, gotQuery)
+	}
+}
@@ -0,0 +1,264 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains mixed-OS (Linux/Windows) node and workload checks.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Operating systems reported by kubelets.
+const (
+	OSLinux   = "linux"
+	OSWindows = "windows"
+)
+
+// OS issue kinds reported in NodeOSReport.
+const (
+	// OSIssueMismatch: the pod requests one OS but runs on a node of another.
+	OSIssueMismatch = "os_mismatch"
+	// OSIssueUnpinnedOnWindows: a pod without an OS selector landed on a Windows
+	// node; most images are Linux-only, so this usually fails at image pull or start.
+	OSIssueUnpinnedOnWindows = "unpinned_on_windows"
+	// OSIssueNoMatchingNodes: the pod requests an OS no ready node provides.
+	OSIssueNoMatchingNodes = "no_matching_nodes"
+	// OSIssuePendingSelector: the scheduler rejected nodes on the OS selector.
+	OSIssuePendingSelector = "pending_os_selector"
+)
+
+// NodeOSEntry is one node's operating system.
+type NodeOSEntry struct {
+	Node      string `json:"node"`
+	OS        string `json:"os"`
+	OSImage   string `json:"os_image,omitempty"`
+	Ready     bool   `json:"ready"`
+	Tainted   bool   `json:"tainted"`
+	PodsCount int    `json:"pods"`
+}
+
+// OSIssue flags a pod whose scheduling is wrong or blocked because of node OS.
+type OSIssue struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Node      string `json:"node,omitempty"`
+	NodeOS    string `json:"node_os,omitempty"`
+	PodOS     string `json:"pod_os,omitempty"`
+	Phase     string `json:"phase"`
+	Message   string `json:"message"`
+}
+
+// NodeOSReport summarises the OS distribution of a cluster and mixed-OS scheduling problems.
+type NodeOSReport struct {
+	Context      string         `json:"context"`
+	Distribution map[string]int `json:"distribution"`
+	Mixed        bool           `json:"mixed"`
+	Nodes        []NodeOSEntry  `json:"nodes"`
+	Issues       []OSIssue      `json:"issues"`
+}
+
+// nodeOS returns the node's operating system from the well-known label,
+// falling back to the kubelet-reported value.
+func nodeOS(node *corev1.Node) string {
+	if os := node.Labels[corev1.LabelOSStable]; os != "" {
+		return os
+	}
+	if os := node.Status.NodeInfo.OperatingSystem; os != "" {
+		return os
+	}
+	return "unknown"
+}
+
+// osDistribution counts nodes per operating system.
+func osDistribution(nodes []NodeInfo) map[string]int {
+	dist := make(map[string]int)
+	for _, n := range nodes {
+		dist[n.OS]++
+	}
+	return dist
+}
+
+// FormatDistribution renders a node distribution (by OS or architecture) as "linux 5, windows 2".
+func FormatDistribution(dist map[string]int) string {
+	names := make([]string, 0, len(dist))
+	for name := range dist {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %d", name, dist[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// podRequestedOS returns the OS a pod is constrained to via spec.os,
+// nodeSelector, or a required node affinity term. Empty means unconstrained.
+func podRequestedOS(pod *corev1.Pod) string {
+	if pod.Spec.OS != nil && pod.Spec.OS.Name != "" {
+		return string(pod.Spec.OS.Name)
+	}
+	if os := pod.Spec.NodeSelector[corev1.LabelOSStable]; os != "" {
+		return os
+	}
+	return requiredAffinityValue(pod, corev1.LabelOSStable)
+}
+
+// requiredAffinityValue returns the single value a required node affinity
+// "In" expression allows for key, or "" if unconstrained or ambiguous.
+func requiredAffinityValue(pod *corev1.Pod, key string) string {
+	aff := pod.Spec.Affinity
+	if aff == nil || aff.NodeAffinity == nil || aff.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return ""
+	}
+	for _, term := range aff.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, expr := range term.MatchExpressions {
+			if expr.Key == key && expr.Operator == corev1.NodeSelectorOpIn && len(expr.Values) == 1 {
+				return expr.Values[0]
+			}
+		}
+	}
+	return ""
+}
+
+// buildNodeOSReport classifies nodes by OS and flags mixed-OS scheduling problems.
+func buildNodeOSReport(nodes []corev1.Node, pods []corev1.Pod) *NodeOSReport {
+	report := &NodeOSReport{
+		Distribution: make(map[string]int),
+		Nodes:        make([]NodeOSEntry, 0, len(nodes)),
+		Issues:       []OSIssue{},
+	}
+
+	osByNode := make(map[string]string, len(nodes))
+	readyOS := make(map[string]bool)
+	podsPerNode := make(map[string]int)
+	for i := range pods {
+		if pods[i].Spec.NodeName != "" {
+			podsPerNode[pods[i].Spec.NodeName]++
+		}
+	}
+	for i := range nodes {
+		node := &nodes[i]
+		os := nodeOS(node)
+		ready := isNodeReady(node)
+		osByNode[node.Name] = os
+		report.Distribution[os]++
+		if ready {
+			readyOS[os] = true
+		}
+		report.Nodes = append(report.Nodes, NodeOSEntry{
+			Node:      node.Name,
+			OS:        os,
+			OSImage:   node.Status.NodeInfo.OSImage,
+			Ready:     ready,
+			Tainted:   len(node.Spec.Taints) > 0,
+			PodsCount: podsPerNode[node.Name],
+		})
+	}
+	report.Mixed = len(report.Distribution) > 1
+
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if issue, ok := classifyPodOS(pod, osByNode, readyOS, report.Mixed); ok {
+			report.Issues = append(report.Issues, issue)
+		}
+	}
+
+	sort.Slice(report.Nodes, func(a, b int) bool {
+		if report.Nodes[a].OS != report.Nodes[b].OS {
+			return report.Nodes[a].OS < report.Nodes[b].OS
+		}
+		return report.Nodes[a].Node < report.Nodes[b].Node
+	})
+	sort.Slice(report.Issues, func(a, b int) bool {
+		if report.Issues[a].Namespace != report.Issues[b].Namespace {
+			return report.Issues[a].Namespace < report.Issues[b].Namespace
+		}
+		return report.Issues[a].Pod < report.Issues[b].Pod
+	})
+	return report
+}
+
+// classifyPodOS returns the OS-related issue for pod, if any.
+func classifyPodOS(pod *corev1.Pod, osByNode map[string]string, readyOS map[string]bool, mixed bool) (OSIssue, bool) {
+	wantOS := podRequestedOS(pod)
+	issue := OSIssue{
+		Namespace: pod.Namespace,
+		Pod:       pod.Name,
+		Node:      pod.Spec.NodeName,
+		PodOS:     wantOS,
+		Phase:     string(pod.Status.Phase),
+	}
+
+	if pod.Spec.NodeName == "" {
+		if wantOS == "" {
+			return OSIssue{}, false
+		}
+		if !readyOS[wantOS] {
+			issue.Kind = OSIssueNoMatchingNodes
+			issue.Message = fmt.Sprintf("pod requires %s nodes but the cluster has no ready %s nodes", wantOS, wantOS)
+			return issue, true
+		}
+		msg := unschedulableReason(pod)
+		if strings.Contains(msg, "node affinity") || strings.Contains(msg, "node selector") || strings.Contains(msg, "untolerated taint") {
+			issue.Kind = OSIssuePendingSelector
+			issue.Message = fmt.Sprintf("pod requires %s nodes; scheduler: %s", wantOS, msg)
+			return issue, true
+		}
+		return OSIssue{}, false
+	}
+
+	issue.NodeOS = osByNode[pod.Spec.NodeName]
+	switch {
+	case wantOS != "" && issue.NodeOS != "" && wantOS != issue.NodeOS:
+		issue.Kind = OSIssueMismatch
+		issue.Message = fmt.Sprintf("pod requests %s but is running on %s node %s", wantOS, issue.NodeOS, pod.Spec.NodeName)
+		return issue, true
+	case wantOS == "" && mixed && issue.NodeOS == OSWindows:
+		issue.Kind = OSIssueUnpinnedOnWindows
+		issue.Message = fmt.Sprintf("pod has no OS selector and was scheduled onto Windows node %s; add nodeSelector kubernetes.io/os: linux (or windows) to its workload", pod.Spec.NodeName)
+		return issue, true
+	}
+	return OSIssue{}, false
+}
+
+// collectNodeOSReport lists nodes and pods and builds a NodeOSReport.
+func collectNodeOSReport(ctx context.Context, clientset kubernetes.Interface) (*NodeOSReport, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	return buildNodeOSReport(nodes.Items, pods.Items), nil
+}
+
+// CheckNodeOS reports the cluster's node OS distribution and pods that are
+// mis-scheduled or pending because of node operating system.
+func (p *Provider) CheckNodeOS(ctx context.Context, contextName string) (*NodeOSReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	report, err := collectNodeOSReport(queryCtx, clientset)
+	if err != nil {
+		return nil, err
+	}
+	report.Context = contextName
+	return report, nil
+}
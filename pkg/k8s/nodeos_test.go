@@ -0,0 +1,94 @@
+package k8s
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func osNode(name, os string, ready bool) corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{corev1.LabelOSStable: os}},
+		Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}}},
+	}
+}
+
+func osPod(name, node string, selector map[string]string) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps"},
+		Spec:       corev1.PodSpec{NodeName: node, NodeSelector: selector},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if node == "" {
+		pod.Status.Phase = corev1.PodPending
+		pod.Status.Conditions = []corev1.PodCondition{{
+			Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable,
+			Message: "0/3 nodes are available: 3 node(s) didn't match Pod's node affinity/selector.",
+		}}
+	}
+	return pod
+}
+
+func TestBuildNodeOSReport(t *testing.T) {
+	linux := map[string]string{corev1.LabelOSStable: OSLinux}
+	windows := map[string]string{corev1.LabelOSStable: OSWindows}
+	nodes := []corev1.Node{
+		osNode("lin-1", OSLinux, true),
+		osNode("lin-2", OSLinux, true),
+		osNode("win-1", OSWindows, false),
+	}
+	pods := []corev1.Pod{
+		osPod("pinned-ok", "lin-1", linux),
+		osPod("unpinned-linux", "lin-2", nil),
+		osPod("unpinned-on-windows", "win-1", nil),
+		osPod("wrong-os", "lin-1", windows),
+		osPod("waiting-windows", "", windows),
+		osPod("waiting-linux", "", linux),
+	}
+	// spec.os takes precedence over selectors.
+	pods[0].Spec.OS = &corev1.PodOS{Name: corev1.Linux}
+
+	report := buildNodeOSReport(nodes, pods)
+
+	if !report.Mixed || report.Distribution[OSLinux] != 2 || report.Distribution[OSWindows] != 1 {
+		t.Fatalf("unexpected distribution: %+v mixed=%v", report.Distribution, report.Mixed)
+	}
+
+	got := make(map[string]string)
+	for _, issue := range report.Issues {
+		got[issue.Pod] = issue.Kind
+	}
+	want := map[string]string{
+		"unpinned-on-windows": OSIssueUnpinnedOnWindows,
+		"wrong-os":            OSIssueMismatch,
+		"waiting-windows":     OSIssueNoMatchingNodes, // the only Windows node is NotReady
+		"waiting-linux":       OSIssuePendingSelector,
+	}
+	if len(got) != len(want) {
+		t.Errorf("issues = %v, want %v", got, want)
+	}
+	for pod, kind := range want {
+		if got[pod] != kind {
+			t.Errorf("pod %s: kind = %q, want %q", pod, got[pod], kind)
+		}
+	}
+}
+
+func TestPodRequestedOSAffinity(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+			MatchExpressions: []corev1.NodeSelectorRequirement{{Key: corev1.LabelOSStable, Operator: corev1.NodeSelectorOpIn, Values: []string{OSWindows}}},
+		}}},
+	}}}}
+	if got := podRequestedOS(pod); got != OSWindows {
+		t.Errorf("podRequestedOS() = %q, want windows", got)
+	}
+	if got := FormatDistribution(map[string]int{"windows": 1, "linux": 3}); got != "linux 3, windows 1" {
+		t.Errorf("FormatDistribution() = %q", got)
+	}
+}
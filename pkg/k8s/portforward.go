@@ -0,0 +1,388 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the background port-forward manager.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// portForwardReadyTimeout bounds how long Start waits for the tunnel to come up.
+const portForwardReadyTimeout = 15 * time.Second
+
+// PortForwardInfo describes an active port-forward.
+type PortForwardInfo struct {
+	ID         int       `json:"id"`
+	Context    string    `json:"context"`
+	Namespace  string    `json:"namespace"`
+	Target     string    `json:"target"`
+	Pod        string    `json:"pod"`
+	LocalPort  int       `json:"local_port"`
+	RemotePort int       `json:"remote_port"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+// LocalAddress returns the loopback address the forward listens on.
+func (i PortForwardInfo) LocalAddress() string {
+	return fmt.Sprintf("127.0.0.1:%d", i.LocalPort)
+}
+
+// forwardRequest carries everything needed to open one SPDY tunnel.
+type forwardRequest struct {
+	restConfig *rest.Config
+	clientset  kubernetes.Interface
+	namespace  string
+	pod        string
+	localPort  int
+	remotePort int
+	stopCh     chan struct{}
+}
+
+// forwardFunc opens a tunnel and returns the bound local port once it is ready.
+// done receives the tunnel's terminal error (nil on clean stop).
+type forwardFunc func(req forwardRequest) (localPort int, done <-chan error, err error)
+
+type activeForward struct {
+	info   PortForwardInfo
+	stopCh chan struct{}
+	once   sync.Once
+}
+
+func (f *activeForward) stop() {
+	f.once.Do(func() { close(f.stopCh) })
+}
+
+// PortForwardManager starts, tracks, and stops background port-forwards.
+// It is safe for concurrent use.
+type PortForwardManager struct {
+	clientsetFor func(contextName string) (kubernetes.Interface, *rest.Config, error)
+	forward      forwardFunc
+	// apiTimeout bounds resolving the target; zero uses DefaultAPITimeout.
+	apiTimeout time.Duration
+
+	mu       sync.Mutex
+	nextID   int
+	forwards map[int]*activeForward
+}
+
+// NewPortForwardManager returns a manager that opens forwards through provider's kubeconfig.
+func NewPortForwardManager(provider *Provider) *PortForwardManager {
+	return &PortForwardManager{
+		clientsetFor: provider.createClientset,
+		forward:      spdyForward,
+		apiTimeout:   provider.APITimeout(),
+		nextID:       1,
+		forwards:     make(map[int]*activeForward),
+	}
+}
+
+// Start resolves target (pod/NAME, svc/NAME, deployment/NAME, or a bare pod
+// name) to a running pod and forwards 127.0.0.1:localPort to it. A localPort
+// of 0 picks a free port; a remotePort of 0 uses the first port the target exposes.
+func (m *PortForwardManager) Start(ctx context.Context, contextName, namespace, target string, localPort, remotePort int) (*PortForwardInfo, error) {
+	if namespace == "" {
+		namespace = corev1.NamespaceDefault
+	}
+	clientset, restConfig, err := m.clientsetFor(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	timeout := m.apiTimeout
+	if timeout <= 0 {
+		timeout = DefaultAPITimeout
+	}
+	queryCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	pod, podPort, err := resolvePortForwardTarget(queryCtx, clientset, namespace, target, remotePort)
+	if err != nil {
+		return nil, err
+	}
+
+	fwd := &activeForward{stopCh: make(chan struct{})}
+	boundPort, done, err := m.forward(forwardRequest{
+		restConfig: restConfig,
+		clientset:  clientset,
+		namespace:  namespace,
+		pod:        pod,
+		localPort:  localPort,
+		remotePort: podPort,
+		stopCh:     fwd.stopCh,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("port-forward to %s/%s failed: %w", namespace, pod, err)
+	}
+
+	if remotePort == 0 {
+		remotePort = podPort
+	}
+	m.mu.Lock()
+	fwd.info = PortForwardInfo{
+		ID:         m.nextID,
+		Context:    contextName,
+		Namespace:  namespace,
+		Target:     target,
+		Pod:        pod,
+		LocalPort:  boundPort,
+		RemotePort: remotePort,
+		StartedAt:  time.Now(),
+	}
+	m.nextID++
+	m.forwards[fwd.info.ID] = fwd
+	info := fwd.info
+	m.mu.Unlock()
+
+	// Drop the forward from the list when the tunnel dies on its own (pod deleted, network loss).
+	go func() {
+		<-done
+		m.mu.Lock()
+		delete(m.forwards, info.ID)
+		m.mu.Unlock()
+	}()
+	return &info, nil
+}
+
+// List returns the active forwards ordered by ID.
+func (m *PortForwardManager) List() []PortForwardInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]PortForwardInfo, 0, len(m.forwards))
+	for _, f := range m.forwards {
+		out = append(out, f.info)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].ID < out[b].ID })
+	return out
+}
+
+// Stop closes the forward with the given ID.
+func (m *PortForwardManager) Stop(id int) error {
+	m.mu.Lock()
+	f, ok := m.forwards[id]
+	delete(m.forwards, id)
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no active port-forward with id %d", id)
+	}
+	f.stop()
+	return nil
+}
+
+// StopAll closes every active forward. It is called when kopilot exits.
+func (m *PortForwardManager) StopAll() {
+	m.mu.Lock()
+	forwards := m.forwards
+	m.forwards = make(map[int]*activeForward)
+	m.mu.Unlock()
+	for _, f := range forwards {
+		f.stop()
+	}
+}
+
+// resolvePortForwardTarget maps target to a running pod and the container port to dial.
+func resolvePortForwardTarget(ctx context.Context, clientset kubernetes.Interface, namespace, target string, remotePort int) (string, int, error) {
+	kind, name, found := strings.Cut(target, "/")
+	if !found {
+		kind, name = "pod", target
+	}
+	if name == "" {
+		return "", 0, fmt.Errorf("invalid port-forward target %q", target)
+	}
+
+	switch strings.ToLower(kind) {
+	case "pod", "pods", "po":
+		pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", 0, fmt.Errorf("pod %s/%s: %w", namespace, name, err)
+		}
+		if pod.Status.Phase != corev1.PodRunning {
+			return "", 0, fmt.Errorf("pod %s/%s is %s, not Running", namespace, name, pod.Status.Phase)
+		}
+		port := remotePort
+		if port == 0 {
+			port = firstContainerPort(pod)
+		}
+		if port == 0 {
+			return "", 0, fmt.Errorf("pod %s/%s declares no container ports; specify remote_port", namespace, name)
+		}
+		return pod.Name, port, nil
+	case "svc", "service", "services":
+		return resolveServiceTarget(ctx, clientset, namespace, name, remotePort)
+	case "deploy", "deployment", "deployments":
+		deploy, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", 0, fmt.Errorf("deployment %s/%s: %w", namespace, name, err)
+		}
+		if deploy.Spec.Selector == nil || len(deploy.Spec.Selector.MatchLabels) == 0 {
+			return "", 0, fmt.Errorf("deployment %s/%s has no matchLabels selector; forward to a pod instead", namespace, name)
+		}
+		pod, err := findRunningPod(ctx, clientset, namespace, deploy.Spec.Selector.MatchLabels)
+		if err != nil {
+			return "", 0, fmt.Errorf("deployment %s/%s: %w", namespace, name, err)
+		}
+		port := remotePort
+		if port == 0 {
+			port = firstContainerPort(pod)
+		}
+		if port == 0 {
+			return "", 0, fmt.Errorf("deployment %s/%s declares no container ports; specify remote_port", namespace, name)
+		}
+		return pod.Name, port, nil
+	}
+	return "", 0, fmt.Errorf("unsupported port-forward target kind %q (use pod, svc, or deployment)", kind)
+}
+
+// resolveServiceTarget picks a ready pod behind a service and translates the
+// service port into the pod's target port, as kubectl port-forward does.
+func resolveServiceTarget(ctx context.Context, clientset kubernetes.Interface, namespace, name string, remotePort int) (string, int, error) {
+	svc, err := clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", 0, fmt.Errorf("service %s/%s: %w", namespace, name, err)
+	}
+	if len(svc.Spec.Selector) == 0 {
+		return "", 0, fmt.Errorf("service %s/%s has no selector; forward to a pod instead", namespace, name)
+	}
+	pod, err := findRunningPod(ctx, clientset, namespace, svc.Spec.Selector)
+	if err != nil {
+		return "", 0, fmt.Errorf("service %s/%s: %w", namespace, name, err)
+	}
+	if len(svc.Spec.Ports) == 0 {
+		if remotePort == 0 {
+			return "", 0, fmt.Errorf("service %s/%s exposes no ports; specify remote_port", namespace, name)
+		}
+		return pod.Name, remotePort, nil
+	}
+
+	svcPort := svc.Spec.Ports[0]
+	if remotePort != 0 {
+		matched := false
+		for _, p := range svc.Spec.Ports {
+			if int(p.Port) == remotePort {
+				svcPort, matched = p, true
+				break
+			}
+		}
+		if !matched {
+			// Not a service port: assume the caller meant a container port.
+			return pod.Name, remotePort, nil
+		}
+	}
+
+	switch {
+	case svcPort.TargetPort.Type == intstr.String:
+		port := namedContainerPort(pod, svcPort.TargetPort.StrVal)
+		if port == 0 {
+			return "", 0, fmt.Errorf("pod %s has no container port named %q", pod.Name, svcPort.TargetPort.StrVal)
+		}
+		return pod.Name, port, nil
+	case svcPort.TargetPort.IntVal != 0:
+		return pod.Name, int(svcPort.TargetPort.IntVal), nil
+	default:
+		return pod.Name, int(svcPort.Port), nil
+	}
+}
+
+// findRunningPod returns a running pod matching selector, preferring ready pods.
+func findRunningPod(ctx context.Context, clientset kubernetes.Interface, namespace string, selector map[string]string) (*corev1.Pod, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(selector).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var running *corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if isPodHealthy(pod) {
+			return pod, nil
+		}
+		if running == nil {
+			running = pod
+		}
+	}
+	if running == nil {
+		return nil, fmt.Errorf("no running pods match selector %s", labels.SelectorFromSet(selector))
+	}
+	return running, nil
+}
+
+func firstContainerPort(pod *corev1.Pod) int {
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Protocol == "" || p.Protocol == corev1.ProtocolTCP {
+				return int(p.ContainerPort)
+			}
+		}
+	}
+	return 0
+}
+
+func namedContainerPort(pod *corev1.Pod, name string) int {
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == name {
+				return int(p.ContainerPort)
+			}
+		}
+	}
+	return 0
+}
+
+// spdyForward opens a port-forward through the API server using SPDY,
+// listening on the loopback interface only.
+func spdyForward(req forwardRequest) (int, <-chan error, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(req.restConfig)
+	if err != nil {
+		return 0, nil, err
+	}
+	url := req.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(req.namespace).Name(req.pod).
+		SubResource("portforward").URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	readyCh := make(chan struct{})
+	ports := []string{fmt.Sprintf("%d:%d", req.localPort, req.remotePort)}
+	fw, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, ports, req.stopCh, readyCh, io.Discard, io.Discard)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- fw.ForwardPorts() }()
+
+	select {
+	case <-readyCh:
+	case err := <-done:
+		if err == nil {
+			err = fmt.Errorf("port-forward closed before becoming ready")
+		}
+		return 0, nil, err
+	case <-time.After(portForwardReadyTimeout):
+		close(req.stopCh)
+		return 0, nil, fmt.Errorf("timed out after %s waiting for port-forward", portForwardReadyTimeout)
+	}
+
+	bound, err := fw.GetPorts()
+	if err != nil || len(bound) == 0 {
+		close(req.stopCh)
+		return 0, nil, fmt.Errorf("failed to determine forwarded port: %v", err)
+	}
+	return int(bound[0].Local), done, nil
+}
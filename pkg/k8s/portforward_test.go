@@ -0,0 +1,156 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+const testMonitoringNs = "monitoring"
+
+func grafanaObjects() []runtime.Object {
+	labels := map[string]string{"app": "grafana"}
+	readyPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "grafana-abc", Namespace: testMonitoringNs, Labels: labels},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  "grafana",
+			Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 3000}},
+		}}},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "grafana", Ready: true,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}},
+		},
+	}
+	pendingPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "grafana-pending", Namespace: testMonitoringNs, Labels: labels},
+		Status:     corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "grafana", Namespace: testMonitoringNs},
+		Spec: corev1.ServiceSpec{
+			Selector: labels,
+			Ports:    []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromString("http")}},
+		},
+	}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "grafana", Namespace: testMonitoringNs},
+		Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+	}
+	return []runtime.Object{readyPod, pendingPod, svc, deploy}
+}
+
+func TestResolvePortForwardTarget(t *testing.T) {
+	objs := grafanaObjects()
+	clientset := fake.NewClientset(objs...)
+	ctx := context.Background()
+
+	tests := []struct {
+		target     string
+		remotePort int
+		wantPod    string
+		wantPort   int
+		wantErr    string
+	}{
+		{"svc/grafana", 0, "grafana-abc", 3000, ""},
+		{"service/grafana", 80, "grafana-abc", 3000, ""},
+		{"svc/grafana", 9090, "grafana-abc", 9090, ""},
+		{"deployment/grafana", 0, "grafana-abc", 3000, ""},
+		{"grafana-abc", 0, "grafana-abc", 3000, ""},
+		{"pod/grafana-pending", 3000, "", 0, "not Running"},
+		{"svc/missing", 0, "", 0, "not found"},
+		{"statefulset/grafana", 0, "", 0, "unsupported"},
+	}
+	for _, tt := range tests {
+		pod, port, err := resolvePortForwardTarget(ctx, clientset, testMonitoringNs, tt.target, tt.remotePort)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("resolve(%q) error = %v, want containing %q", tt.target, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("resolve(%q) unexpected error: %v", tt.target, err)
+			continue
+		}
+		if pod != tt.wantPod || port != tt.wantPort {
+			t.Errorf("resolve(%q, %d) = %s:%d, want %s:%d", tt.target, tt.remotePort, pod, port, tt.wantPod, tt.wantPort)
+		}
+	}
+}
+
+func TestPortForwardManagerLifecycle(t *testing.T) {
+	clientset := fake.NewClientset(grafanaObjects()...)
+	done := make(chan error, 1)
+	var stopCh chan struct{}
+
+	m := &PortForwardManager{
+		clientsetFor: func(string) (kubernetes.Interface, *rest.Config, error) { return clientset, &rest.Config{}, nil },
+		forward: func(req forwardRequest) (int, <-chan error, error) {
+			stopCh = req.stopCh
+			if req.pod != "grafana-abc" || req.remotePort != 3000 {
+				t.Errorf("unexpected forward request: pod=%s port=%d", req.pod, req.remotePort)
+			}
+			return 3000, done, nil
+		},
+		nextID:   1,
+		forwards: make(map[int]*activeForward),
+	}
+
+	info, err := m.Start(context.Background(), "prod", testMonitoringNs, "svc/grafana", 3000, 80)
+	if err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	if info.ID != 1 || info.LocalAddress() != "127.0.0.1:3000" || info.RemotePort != 80 {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	if got := m.List(); len(got) != 1 || got[0].Pod != "grafana-abc" {
+		t.Fatalf("List() = %+v", got)
+	}
+
+	if err := m.Stop(99); err == nil {
+		t.Error("Stop() of unknown id should fail")
+	}
+	if err := m.Stop(info.ID); err != nil {
+		t.Fatalf("Stop() error: %v", err)
+	}
+	select {
+	case <-stopCh:
+	default:
+		t.Error("Stop() should close the tunnel's stop channel")
+	}
+	// StopAll after Stop must not double-close.
+	m.StopAll()
+	if len(m.List()) != 0 {
+		t.Error("forward should be removed after Stop")
+	}
+}
+
+func TestPortForwardManagerForwardError(t *testing.T) {
+	clientset := fake.NewClientset(grafanaObjects()...)
+	m := &PortForwardManager{
+		clientsetFor: func(string) (kubernetes.Interface, *rest.Config, error) { return clientset, &rest.Config{}, nil },
+		forward: func(forwardRequest) (int, <-chan error, error) {
+			return 0, nil, errors.New("address already in use")
+		},
+		nextID:   1,
+		forwards: make(map[int]*activeForward),
+	}
+	if _, err := m.Start(context.Background(), "prod", testMonitoringNs, "svc/grafana", 3000, 0); err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Errorf("expected forward error, got %v", err)
+	}
+	if len(m.List()) != 0 {
+		t.Error("failed forward must not be tracked")
+	}
+}
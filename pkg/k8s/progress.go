@@ -0,0 +1,81 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains progress reporting for multi-cluster checks.
+package k8s
+
+import (
+	"context"
+	"time"
+)
+
+// ClusterProgress reports how far a multi-cluster check has come. One is
+// sent when the check starts and each time a context finishes.
+type ClusterProgress struct {
+	// Context is the context just checked, empty in the first update.
+	Context string
+	Done    int
+	Total   int
+	// Pending lists the contexts still being checked, in check order.
+	Pending []string
+	// Started is when the check began, to tell slow contexts apart.
+	Started time.Time
+}
+
+type progressKey struct{}
+
+// WithProgress returns a context under which GetAllClusterStatuses and
+// GetClusterStatuses send a ClusterProgress to progress as they start and
+// as each context finishes. Sends never block the check: an update progress has no room for
+// is dropped, so give it a buffer. The channel is not closed.
+func WithProgress(ctx context.Context, progress chan<- ClusterProgress) context.Context {
+	return context.WithValue(ctx, progressKey{}, progress)
+}
+
+// progressFrom returns the progress channel of ctx, or nil.
+func progressFrom(ctx context.Context) chan<- ClusterProgress {
+	progress, _ := ctx.Value(progressKey{}).(chan<- ClusterProgress)
+	return progress
+}
+
+// progressTracker builds the ClusterProgress updates of one check.
+type progressTracker struct {
+	progress chan<- ClusterProgress
+	contexts []string
+	done     []bool
+	count    int
+	started  time.Time
+}
+
+func newProgressTracker(ctx context.Context, contexts []string) *progressTracker {
+	progress := progressFrom(ctx)
+	if progress == nil {
+		return nil
+	}
+	t := &progressTracker{progress: progress, contexts: contexts, done: make([]bool, len(contexts)), started: time.Now()}
+	t.send("")
+	return t
+}
+
+// finish records that the context at idx is checked and sends the update.
+// It is called from one goroutine at a time.
+func (t *progressTracker) finish(idx int) {
+	if t == nil {
+		return
+	}
+	t.done[idx] = true
+	t.count++
+	t.send(t.contexts[idx])
+}
+
+// send sends the current progress, unless the receiver has no room for it.
+func (t *progressTracker) send(contextName string) {
+	update := ClusterProgress{Context: contextName, Done: t.count, Total: len(t.contexts), Started: t.started}
+	for i, done := range t.done {
+		if !done {
+			update.Pending = append(update.Pending, t.contexts[i])
+		}
+	}
+	select {
+	case t.progress <- update:
+	default:
+	}
+}
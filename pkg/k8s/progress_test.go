@@ -0,0 +1,54 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetClusterStatusesProgress(t *testing.T) {
+	kubeconfigPath, cleanup := createTempKubeconfig(t, 1)
+	defer cleanup()
+	provider, err := NewProvider(kubeconfigPath)
+	if err != nil {
+		t.Fatalf(errNewProviderFailed, err)
+	}
+
+	progress := make(chan ClusterProgress, 4)
+	contexts := []string{"missing-a", "missing-b", "missing-c"}
+	statuses := provider.GetClusterStatuses(WithProgress(context.Background(), progress), contexts)
+	if len(statuses) != 3 {
+		t.Fatalf("got %d statuses, want 3", len(statuses))
+	}
+	close(progress)
+	var updates []ClusterProgress
+	for p := range progress {
+		updates = append(updates, p)
+	}
+	if len(updates) != 4 {
+		t.Fatalf("got %d progress updates, want a first one and one per context", len(updates))
+	}
+	if first := updates[0]; first.Context != "" || first.Done != 0 || len(first.Pending) != 3 {
+		t.Errorf("first update = %+v, want every context pending", first)
+	}
+	seen := map[string]bool{}
+	for i, p := range updates[1:] {
+		seen[p.Context] = true
+		if p.Done != i+1 || p.Total != 3 || len(p.Pending) != 2-i || p.Started.IsZero() {
+			t.Errorf("update %d = %+v", i, p)
+		}
+		for _, pending := range p.Pending {
+			if seen[pending] {
+				t.Errorf("update %d lists the finished context %s as pending", i, pending)
+			}
+		}
+	}
+	if len(seen) != 3 {
+		t.Errorf("updates named %v, want every context", seen)
+	}
+
+	// A full channel drops updates instead of holding up the check.
+	full := make(chan ClusterProgress)
+	if statuses := provider.GetClusterStatuses(WithProgress(context.Background(), full), contexts); len(statuses) != 3 {
+		t.Errorf("got %d statuses with an unread channel, want 3", len(statuses))
+	}
+}
@@ -0,0 +1,296 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains PromQL queries against a configured or discovered Prometheus.
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// prometheusOperatorSelector matches the governing Service the
+	// prometheus-operator creates for each Prometheus (prometheus-operated).
+	prometheusOperatorSelector = "operated-prometheus=true"
+	defaultPrometheusPort      = 9090
+
+	// MaxPrometheusSeries bounds the series returned by QueryPrometheus.
+	MaxPrometheusSeries = 50
+	// MaxPrometheusPoints bounds the samples of a range query per series.
+	MaxPrometheusPoints = 120
+)
+
+// prometheusHTTPClient queries Prometheus endpoints given by URL.
+var prometheusHTTPClient = &http.Client{Timeout: DefaultAPITimeout}
+
+// PrometheusEndpoint locates the Prometheus of a cluster: either a URL
+// reachable from this machine or a Service reached through the API server proxy.
+type PrometheusEndpoint struct {
+	URL       string `json:"url,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Service   string `json:"service,omitempty"`
+	Port      int    `json:"port,omitempty"`
+	// BearerTokenEnv names the environment variable holding a token sent
+	// to URL, so the token stays out of the config file.
+	BearerTokenEnv string `json:"bearer_token_env,omitempty"`
+}
+
+// Validate checks that the endpoint has a usable URL or Service.
+func (e PrometheusEndpoint) Validate() error {
+	switch {
+	case e.URL != "":
+		u, err := url.Parse(e.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("prometheus url %q must be an http(s) URL", e.URL)
+		}
+	case e.Namespace == "" || e.Service == "":
+		return fmt.Errorf("prometheus endpoint needs a url or a namespace and service")
+	}
+	if e.Port < 0 || e.Port > 65535 {
+		return fmt.Errorf("invalid prometheus port %d", e.Port)
+	}
+	return nil
+}
+
+// String describes the endpoint, e.g. "monitoring/prometheus-operated:9090".
+func (e PrometheusEndpoint) String() string {
+	if e.URL != "" {
+		return e.URL
+	}
+	return fmt.Sprintf("%s/%s:%d", e.Namespace, e.Service, e.port())
+}
+
+func (e PrometheusEndpoint) port() int {
+	if e.Port == 0 {
+		return defaultPrometheusPort
+	}
+	return e.Port
+}
+
+// PrometheusQuery is a PromQL instant query, or a range query when Range is set.
+type PrometheusQuery struct {
+	Query string
+	Range time.Duration // look-back window of a range query; 0 for an instant query
+	Step  time.Duration // resolution of a range query; derived from Range when 0
+	End   time.Time     // evaluation time; now when zero
+}
+
+// PrometheusSample is one value of a series.
+type PrometheusSample struct {
+	Time  time.Time `json:"time"`
+	Value string    `json:"value"`
+}
+
+// PrometheusSeries is one labelled series of a query result.
+type PrometheusSeries struct {
+	Metric map[string]string  `json:"metric"`
+	Values []PrometheusSample `json:"values"`
+}
+
+// PrometheusResult is the outcome of a PromQL query.
+type PrometheusResult struct {
+	Context    string             `json:"context"`
+	Endpoint   string             `json:"endpoint"`
+	Discovered bool               `json:"discovered"`
+	Query      string             `json:"query"`
+	ResultType string             `json:"result_type"` // vector, matrix, scalar or string
+	Series     []PrometheusSeries `json:"series"`
+	Truncated  bool               `json:"truncated,omitempty"`
+	Warnings   []string           `json:"warnings,omitempty"`
+}
+
+// QueryPrometheus runs q against the Prometheus of contextName. When
+// endpoint is nil the prometheus-operator Service is discovered.
+func (p *Provider) QueryPrometheus(ctx context.Context, contextName string, endpoint *PrometheusEndpoint, q PrometheusQuery) (*PrometheusResult, error) {
+	if strings.TrimSpace(q.Query) == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	discovered := endpoint == nil
+	if discovered {
+		if endpoint, err = discoverPrometheus(queryCtx, clientset); err != nil {
+			return nil, err
+		}
+	}
+	path, params := prometheusRequest(q, time.Now())
+	body, err := fetchPrometheus(queryCtx, clientset, *endpoint, path, params)
+	if err != nil {
+		return nil, fmt.Errorf("prometheus %s: %w", endpoint, err)
+	}
+	result, err := parsePrometheusResponse(body)
+	if err != nil {
+		return nil, fmt.Errorf("prometheus %s: %w", endpoint, err)
+	}
+	result.Context = contextName
+	result.Endpoint = endpoint.String()
+	result.Discovered = discovered
+	result.Query = q.Query
+	return result, nil
+}
+
+// discoverPrometheus finds the Service of a prometheus-operator managed Prometheus.
+func discoverPrometheus(ctx context.Context, clientset kubernetes.Interface) (*PrometheusEndpoint, error) {
+	services, err := clientset.CoreV1().Services("").List(ctx, metav1.ListOptions{LabelSelector: prometheusOperatorSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover prometheus: %w", err)
+	}
+	if len(services.Items) == 0 {
+		return nil, fmt.Errorf("no prometheus-operator Prometheus found (no Service labelled %s); configure the endpoint under \"prometheus\" in ~/.kopilot/config.json", prometheusOperatorSelector)
+	}
+	sort.Slice(services.Items, func(i, j int) bool {
+		a, b := services.Items[i], services.Items[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	svc := services.Items[0]
+	endpoint := &PrometheusEndpoint{Namespace: svc.Namespace, Service: svc.Name}
+	for _, port := range svc.Spec.Ports {
+		if port.Name == "web" || port.Port == defaultPrometheusPort {
+			endpoint.Port = int(port.Port)
+			break
+		}
+	}
+	return endpoint, nil
+}
+
+// prometheusRequest returns the API path and parameters of q evaluated at now.
+func prometheusRequest(q PrometheusQuery, now time.Time) (string, map[string]string) {
+	end := q.End
+	if end.IsZero() {
+		end = now
+	}
+	if q.Range <= 0 {
+		return "/api/v1/query", map[string]string{"query": q.Query, "time": formatPrometheusTime(end)}
+	}
+	step := q.Step
+	if step <= 0 {
+		// Aim for MaxPrometheusPoints samples, rounded to whole seconds.
+		step = max(time.Second, (q.Range / MaxPrometheusPoints).Round(time.Second))
+	}
+	return "/api/v1/query_range", map[string]string{
+		"query": q.Query,
+		"start": formatPrometheusTime(end.Add(-q.Range)),
+		"end":   formatPrometheusTime(end),
+		"step":  strconv.FormatFloat(step.Seconds(), 'f', -1, 64),
+	}
+}
+
+func formatPrometheusTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', -1, 64)
+}
+
+// fetchPrometheus calls the Prometheus HTTP API directly or through the API server proxy.
+func fetchPrometheus(ctx context.Context, clientset kubernetes.Interface, endpoint PrometheusEndpoint, path string, params map[string]string) ([]byte, error) {
+	api := serviceAPI{
+		url:       endpoint.URL,
+		tokenEnv:  endpoint.BearerTokenEnv,
+		namespace: endpoint.Namespace,
+		service:   endpoint.Service,
+		port:      endpoint.port(),
+	}
+	return api.get(ctx, clientset, prometheusHTTPClient, path, params)
+}
+
+// prometheusResponse is the Prometheus HTTP API envelope.
+type prometheusResponse struct {
+	Status    string   `json:"status"`
+	ErrorType string   `json:"errorType"`
+	Error     string   `json:"error"`
+	Warnings  []string `json:"warnings"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// parsePrometheusResponse converts a query response into series, keeping at
+// most MaxPrometheusSeries series of at most MaxPrometheusPoints samples.
+func parsePrometheusResponse(body []byte) (*PrometheusResult, error) {
+	var resp prometheusResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if resp.Status != "success" {
+		return nil, fmt.Errorf("query failed (%s): %s", resp.ErrorType, resp.Error)
+	}
+	result := &PrometheusResult{ResultType: resp.Data.ResultType, Warnings: resp.Warnings, Series: []PrometheusSeries{}}
+
+	switch resp.Data.ResultType {
+	case "vector", "matrix":
+		var raw []struct {
+			Metric map[string]string `json:"metric"`
+			Value  []any             `json:"value"`
+			Values [][]any           `json:"values"`
+		}
+		if err := json.Unmarshal(resp.Data.Result, &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse %s result: %w", resp.Data.ResultType, err)
+		}
+		if len(raw) > MaxPrometheusSeries {
+			raw, result.Truncated = raw[:MaxPrometheusSeries], true
+		}
+		for _, r := range raw {
+			s := PrometheusSeries{Metric: r.Metric, Values: []PrometheusSample{}}
+			if s.Metric == nil {
+				s.Metric = map[string]string{}
+			}
+			if r.Value != nil {
+				r.Values = [][]any{r.Value}
+			}
+			if len(r.Values) > MaxPrometheusPoints {
+				r.Values, result.Truncated = r.Values[len(r.Values)-MaxPrometheusPoints:], true
+			}
+			for _, v := range r.Values {
+				sample, err := parsePrometheusSample(v)
+				if err != nil {
+					return nil, err
+				}
+				s.Values = append(s.Values, sample)
+			}
+			result.Series = append(result.Series, s)
+		}
+	case "scalar", "string":
+		var v []any
+		if err := json.Unmarshal(resp.Data.Result, &v); err != nil {
+			return nil, fmt.Errorf("failed to parse %s result: %w", resp.Data.ResultType, err)
+		}
+		sample, err := parsePrometheusSample(v)
+		if err != nil {
+			return nil, err
+		}
+		result.Series = append(result.Series, PrometheusSeries{Metric: map[string]string{}, Values: []PrometheusSample{sample}})
+	default:
+		return nil, fmt.Errorf("unsupported result type %q", resp.Data.ResultType)
+	}
+	return result, nil
+}
+
+// parsePrometheusSample reads a [<unix seconds>, "<value>"] pair.
+func parsePrometheusSample(v []any) (PrometheusSample, error) {
+	if len(v) != 2 {
+		return PrometheusSample{}, fmt.Errorf("malformed sample %v", v)
+	}
+	ts, ok := v[0].(float64)
+	value, ok2 := v[1].(string)
+	if !ok || !ok2 {
+		return PrometheusSample{}, fmt.Errorf("malformed sample %v", v)
+	}
+	return PrometheusSample{Time: time.UnixMilli(int64(ts * 1000)).UTC(), Value: value}, nil
+}
@@ -0,0 +1,173 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	restclient "k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func prometheusService(namespace, name string, labels map[string]string, port int32) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "web", Port: port}}},
+	}
+}
+
+func TestPrometheusEndpointValidate(t *testing.T) {
+	valid := []PrometheusEndpoint{
+		{URL: "https://prom.example.com"},
+		{Namespace: "monitoring", Service: "prometheus", Port: 9090},
+	}
+	for _, e := range valid {
+		if err := e.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", e, err)
+		}
+	}
+	invalid := []PrometheusEndpoint{
+		{},
+		{URL: "prom.example.com"},
+		{URL: "ftp://prom"},
+		{Namespace: "monitoring"},
+		{Namespace: "monitoring", Service: "prometheus", Port: 70000},
+	}
+	for _, e := range invalid {
+		if err := e.Validate(); err == nil {
+			t.Errorf("Validate(%+v) expected error", e)
+		}
+	}
+	if got := (PrometheusEndpoint{Namespace: "monitoring", Service: "prometheus-operated"}).String(); got != "monitoring/prometheus-operated:9090" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestDiscoverPrometheus(t *testing.T) {
+	operated := map[string]string{"operated-prometheus": "true"}
+	clientset := fake.NewClientset(
+		prometheusService("monitoring", "prometheus-operated", operated, 9090),
+		prometheusService("apps", "web", map[string]string{"app": "web"}, 80),
+	)
+	endpoint, err := discoverPrometheus(context.Background(), clientset)
+	if err != nil {
+		t.Fatalf("discoverPrometheus: %v", err)
+	}
+	if endpoint.Namespace != "monitoring" || endpoint.Service != "prometheus-operated" || endpoint.port() != 9090 {
+		t.Errorf("unexpected endpoint: %+v", endpoint)
+	}
+
+	_, err = discoverPrometheus(context.Background(), fake.NewClientset())
+	if err == nil || !strings.Contains(err.Error(), "operated-prometheus=true") {
+		t.Errorf("expected discovery error, got %v", err)
+	}
+}
+
+func TestPrometheusRequest(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	path, params := prometheusRequest(PrometheusQuery{Query: "up"}, now)
+	if path != "/api/v1/query" || params["time"] != "1700000000" || params["query"] != "up" {
+		t.Errorf("instant query = %s %v", path, params)
+	}
+
+	path, params = prometheusRequest(PrometheusQuery{Query: "up", Range: time.Hour}, now)
+	if path != "/api/v1/query_range" || params["start"] != "1699996400" || params["end"] != "1700000000" || params["step"] != "30" {
+		t.Errorf("range query = %s %v", path, params)
+	}
+
+	_, params = prometheusRequest(PrometheusQuery{Query: "up", Range: time.Minute, Step: 15 * time.Second}, now)
+	if params["step"] != "15" {
+		t.Errorf("explicit step = %v", params["step"])
+	}
+}
+
+func TestParsePrometheusResponse(t *testing.T) {
+	vector := `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"pod":"web-1"},"value":[1700000000.5,"0.25"]}]},"warnings":["partial"]}`
+	r, err := parsePrometheusResponse([]byte(vector))
+	if err != nil {
+		t.Fatalf("vector: %v", err)
+	}
+	if r.ResultType != "vector" || len(r.Series) != 1 || r.Series[0].Metric["pod"] != "web-1" || r.Series[0].Values[0].Value != "0.25" ||
+		r.Series[0].Values[0].Time.UnixMilli() != 1700000000500 || len(r.Warnings) != 1 {
+		t.Errorf("unexpected vector result: %+v", r)
+	}
+
+	var values []string
+	for i := range MaxPrometheusPoints + 5 {
+		values = append(values, fmt.Sprintf(`[%d,"%d"]`, 1700000000+i, i))
+	}
+	matrix := `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[` + strings.Join(values, ",") + `]}]}}`
+	r, err = parsePrometheusResponse([]byte(matrix))
+	if err != nil {
+		t.Fatalf("matrix: %v", err)
+	}
+	if !r.Truncated || len(r.Series[0].Values) != MaxPrometheusPoints || r.Series[0].Values[0].Value != "5" {
+		t.Errorf("matrix should keep the latest %d points: truncated=%v len=%d first=%v", MaxPrometheusPoints, r.Truncated, len(r.Series[0].Values), r.Series[0].Values[0])
+	}
+
+	r, err = parsePrometheusResponse([]byte(`{"status":"success","data":{"resultType":"scalar","result":[1700000000,"42"]}}`))
+	if err != nil || len(r.Series) != 1 || r.Series[0].Values[0].Value != "42" {
+		t.Errorf("scalar = %+v, %v", r, err)
+	}
+
+	_, err = parsePrometheusResponse([]byte(`{"status":"error","errorType":"bad_data","error":"parse error at char 4"}`))
+	if err == nil || !strings.Contains(err.Error(), "bad_data") || !strings.Contains(err.Error(), "parse error") {
+		t.Errorf("expected query error, got %v", err)
+	}
+	if _, err := parsePrometheusResponse([]byte(`not json`)); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestFetchPrometheusThroughProxy(t *testing.T) {
+	clientset := fake.NewClientset()
+	var action k8stesting.ProxyGetAction
+	clientset.AddProxyReactor("services", func(a k8stesting.Action) (bool, restclient.ResponseWrapper, error) {
+		action = a.(k8stesting.ProxyGetAction)
+		return true, proxyResponse{body: []byte(`{"status":"success"}`)}, nil
+	})
+	endpoint := PrometheusEndpoint{Namespace: "monitoring", Service: "prometheus-operated"}
+	body, err := fetchPrometheus(context.Background(), clientset, endpoint, "/api/v1/query", map[string]string{"query": "up"})
+	if err != nil || string(body) != `{"status":"success"}` {
+		t.Fatalf("fetchPrometheus = %s, %v", body, err)
+	}
+	if action.GetNamespace() != "monitoring" || action.GetName() != "prometheus-operated" || action.GetPort() != "9090" ||
+		action.GetPath() != "/api/v1/query" || action.GetParams()["query"] != "up" {
+		t.Errorf("unexpected proxy call: %+v", action)
+	}
+}
+
+func TestFetchPrometheusURL(t *testing.T) {
+	var gotAuth, gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth, gotQuery = r.Header.Get("Authorization"), r.URL.Query().Get("query")
+		if r.URL.Path != "/prom/api/v1/query" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+	t.Setenv("TEST_PROM_TOKEN", "s3cret")
+
+	endpoint := PrometheusEndpoint{URL: server.URL + "/prom/", BearerTokenEnv: "TEST_PROM_TOKEN"}
+	body, err := fetchPrometheus(context.Background(), nil, endpoint, "/api/v1/query", map[string]string{"query": `up{job="x"}`})
+	if err != nil || string(body) != `{"status":"success"}` {
+		t.Fatalf("fetchPrometheus = %s, %v", body, err)
+	}
+	if gotAuth != "Bearer s3cret" || gotQuery != `up{job="x"}` {
+		t.Errorf("auth = %q, query = %q", gotAuth, gotQuery)
+	}
+
+	endpoint.URL = server.URL
+	if _, err := fetchPrometheus(context.Background(), nil, endpoint, "/api/v1/query", nil); err == nil || !strings.Contains(err.Error(), "HTTP 404") {
+		t.Errorf("expected HTTP error, got %v", err)
+	}
+}
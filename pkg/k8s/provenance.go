@@ -0,0 +1,248 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains image provenance lookup (digest, build time and VCS revision of running images).
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxProvenanceDigests caps registry lookups per image.
+const maxProvenanceDigests = 5
+
+// Labels and annotations carrying provenance, most specific first.
+var (
+	revisionLabels = []string{"org.opencontainers.image.revision", "org.label-schema.vcs-ref", "vcs-ref", "git-commit"}
+	sourceLabels   = []string{"org.opencontainers.image.source", "org.label-schema.vcs-url", "vcs-url"}
+	versionLabels  = []string{"org.opencontainers.image.version", "org.label-schema.version"}
+)
+
+// ImageLocation is one container running an image.
+type ImageLocation struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Node      string `json:"node,omitempty"`
+}
+
+// DigestProvenance describes one digest of an image running in the cluster.
+type DigestProvenance struct {
+	Digest    string            `json:"digest"` // empty when the runtime has not reported one yet
+	Created   string            `json:"created,omitempty"`
+	Revision  string            `json:"revision,omitempty"` // VCS commit
+	Source    string            `json:"source,omitempty"`   // VCS URL
+	Version   string            `json:"version,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Error     string            `json:"error,omitempty"` // registry lookup failure
+	Locations []ImageLocation   `json:"locations"`
+}
+
+// ImageProvenance describes one image reference used by running containers.
+type ImageProvenance struct {
+	Image     string             `json:"image"` // as written in the pod spec
+	Reference ImageReference     `json:"reference"`
+	Digests   []DigestProvenance `json:"digests"`
+	// TagDigest is the digest the tag resolves to in the registry now;
+	// TagMoved is set when no running container uses it.
+	TagDigest     string `json:"tag_digest,omitempty"`
+	TagMoved      bool   `json:"tag_moved"`
+	RegistryError string `json:"registry_error,omitempty"`
+}
+
+// ProvenanceReport is the outcome of GetImageProvenance.
+type ProvenanceReport struct {
+	Context string            `json:"context"`
+	Query   string            `json:"query"`
+	Images  []ImageProvenance `json:"images"`
+}
+
+// imageIDDigest extracts the repository digest from a container status
+// imageID such as "docker.io/library/nginx@sha256:..." or
+// "docker-pullable://nginx@sha256:...". Bare local image IDs yield "".
+func imageIDDigest(imageID string) string {
+	if _, digest, ok := strings.Cut(imageID, "@"); ok {
+		return digest
+	}
+	return ""
+}
+
+// hasExplicitVersion reports whether an image name pins a tag or digest.
+func hasExplicitVersion(image string) bool {
+	last := image[strings.LastIndex(image, "/")+1:]
+	return strings.ContainsAny(last, ":@")
+}
+
+// matchesImageQuery reports whether ref is the queried image. Without an
+// explicit tag or digest in the query, every tag of the repository matches.
+func matchesImageQuery(ref, query ImageReference, explicit bool) bool {
+	if ref.Registry != query.Registry || ref.Repository != query.Repository {
+		return false
+	}
+	if !explicit {
+		return true
+	}
+	if query.Digest != "" {
+		return ref.Digest == query.Digest
+	}
+	return ref.Tag == query.Tag
+}
+
+// firstLabel returns the value of the first key present in labels.
+func firstLabel(labels map[string]string, keys []string) string {
+	for _, k := range keys {
+		if v := labels[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// findImageProvenance groups the containers running the queried image by
+// image reference and digest, without consulting any registry.
+func findImageProvenance(pods []corev1.Pod, query string) ([]ImageProvenance, error) {
+	want, err := ParseImageReference(query)
+	if err != nil {
+		return nil, err
+	}
+	explicit := hasExplicitVersion(query)
+
+	type key struct{ image, digest string }
+	images := make(map[string]*ImageProvenance)
+	locations := make(map[key][]ImageLocation)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		specImages := containerImages(pod)
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		digests := make(map[string]string, len(statuses))
+		for _, cs := range statuses {
+			digests[cs.Name] = imageIDDigest(cs.ImageID)
+		}
+		for container, image := range specImages {
+			ref, err := ParseImageReference(image)
+			if err != nil || !matchesImageQuery(ref, want, explicit) {
+				continue
+			}
+			if images[image] == nil {
+				images[image] = &ImageProvenance{Image: image, Reference: ref}
+			}
+			k := key{image, digests[container]}
+			locations[k] = append(locations[k], ImageLocation{Namespace: pod.Namespace, Pod: pod.Name, Container: container, Node: pod.Spec.NodeName})
+		}
+	}
+
+	for k, locs := range locations {
+		sort.Slice(locs, func(i, j int) bool {
+			return locs[i].Namespace+"/"+locs[i].Pod+"/"+locs[i].Container < locs[j].Namespace+"/"+locs[j].Pod+"/"+locs[j].Container
+		})
+		img := images[k.image]
+		img.Digests = append(img.Digests, DigestProvenance{Digest: k.digest, Locations: locs})
+	}
+	out := make([]ImageProvenance, 0, len(images))
+	for _, img := range images {
+		// Most widely deployed digest first.
+		sort.Slice(img.Digests, func(i, j int) bool {
+			if len(img.Digests[i].Locations) != len(img.Digests[j].Locations) {
+				return len(img.Digests[i].Locations) > len(img.Digests[j].Locations)
+			}
+			return img.Digests[i].Digest < img.Digests[j].Digest
+		})
+		out = append(out, *img)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Image < out[j].Image })
+	return out, nil
+}
+
+// applyRegistryImage copies registry metadata into a digest entry.
+func applyRegistryImage(d *DigestProvenance, image *RegistryImage) {
+	d.Created = image.Created
+	d.Labels = image.Labels
+	d.Revision = firstLabel(image.Labels, revisionLabels)
+	d.Source = firstLabel(image.Labels, sourceLabels)
+	d.Version = firstLabel(image.Labels, versionLabels)
+}
+
+// resolveProvenance looks up each running digest and the current tag target
+// in the registry. Failures are recorded on the entries, not returned.
+func resolveProvenance(ctx context.Context, httpClient *http.Client, img *ImageProvenance) {
+	if img.Reference.Tag != "" && img.Reference.Digest == "" {
+		tagged, err := lookupRegistryImage(ctx, httpClient, img.Reference, "")
+		if err != nil {
+			img.RegistryError = err.Error()
+		} else {
+			img.TagDigest = tagged.Digest
+		}
+	}
+
+	moved := img.TagDigest != ""
+	for i := range img.Digests {
+		d := &img.Digests[i]
+		if d.Digest == "" {
+			continue
+		}
+		if d.Digest == img.TagDigest {
+			moved = false
+		}
+		if i >= maxProvenanceDigests {
+			d.Error = fmt.Sprintf("not looked up (more than %d digests running)", maxProvenanceDigests)
+			continue
+		}
+		ref := img.Reference
+		ref.Digest = d.Digest
+		image, err := lookupRegistryImage(ctx, httpClient, ref, "")
+		if err != nil {
+			d.Error = err.Error()
+			continue
+		}
+		applyRegistryImage(d, image)
+		if image.PlatformDigest == img.TagDigest {
+			moved = false
+		}
+	}
+	img.TagMoved = moved
+}
+
+// collectImageProvenance lists pods in scope and finds containers running the image.
+func collectImageProvenance(ctx context.Context, clientset kubernetes.Interface, namespace, image string) ([]ImageProvenance, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	return findImageProvenance(pods.Items, image)
+}
+
+// GetImageProvenance finds the containers running image (every tag of the
+// repository when image has no tag or digest), the digests they run and,
+// when lookup is true, each digest's build time and VCS labels from the
+// registry. Only public images can be looked up; the registry is contacted
+// only for images that are actually running. If namespace is non-empty,
+// only that namespace is searched.
+func (p *Provider) GetImageProvenance(ctx context.Context, contextName, namespace, image string, lookup bool) (*ProvenanceReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	images, err := collectImageProvenance(queryCtx, clientset, namespace, image)
+	if err != nil {
+		return nil, err
+	}
+	if lookup {
+		for i := range images {
+			resolveProvenance(ctx, registryHTTPClient, &images[i])
+		}
+	}
+	return &ProvenanceReport{Context: contextName, Query: image, Images: images}, nil
+}
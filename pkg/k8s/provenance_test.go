@@ -0,0 +1,109 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func imagePod(namespace, name, image, imageID string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       corev1.PodSpec{NodeName: "n1", Containers: []corev1.Container{{Name: "app", Image: image}}},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", Image: image, ImageID: imageID}},
+		},
+	}
+}
+
+func TestImageIDDigest(t *testing.T) {
+	cases := map[string]string{
+		"docker.io/library/nginx@sha256:abc": "sha256:abc",
+		"docker-pullable://nginx@sha256:def": "sha256:def",
+		"sha256:0123456789abcdef":            "",
+		"":                                   "",
+	}
+	for id, want := range cases {
+		if got := imageIDDigest(id); got != want {
+			t.Errorf("imageIDDigest(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestCollectImageProvenance(t *testing.T) {
+	clientset := fake.NewClientset(
+		imagePod("shop", "api-1", "ghcr.io/org/api:1.4", "ghcr.io/org/api@sha256:new"),
+		imagePod("shop", "api-2", "ghcr.io/org/api:1.4", "ghcr.io/org/api@sha256:new"),
+		imagePod("shop", "api-3", "ghcr.io/org/api:1.4", "ghcr.io/org/api@sha256:old"),
+		imagePod("shop", "api-4", "ghcr.io/org/api:1.3", "ghcr.io/org/api@sha256:prev"),
+		imagePod("shop", "web", "nginx:1.25", "docker.io/library/nginx@sha256:ngx"),
+	)
+
+	images, err := collectImageProvenance(context.Background(), clientset, "", "ghcr.io/org/api:1.4")
+	if err != nil {
+		t.Fatalf("collectImageProvenance: %v", err)
+	}
+	if len(images) != 1 || len(images[0].Digests) != 2 {
+		t.Fatalf("images = %+v, want one image with two digests", images)
+	}
+	if d := images[0].Digests[0]; d.Digest != "sha256:new" || len(d.Locations) != 2 || d.Locations[0].Pod != "api-1" {
+		t.Errorf("Digests[0] = %+v, want sha256:new on api-1 and api-2", d)
+	}
+
+	all, err := collectImageProvenance(context.Background(), clientset, "shop", "ghcr.io/org/api")
+	if err != nil {
+		t.Fatalf("collectImageProvenance: %v", err)
+	}
+	if len(all) != 2 || all[0].Image != "ghcr.io/org/api:1.3" {
+		t.Errorf("images = %+v, want both tags when the query has none", all)
+	}
+
+	hub, err := collectImageProvenance(context.Background(), clientset, "", "docker.io/library/nginx:1.25")
+	if err != nil || len(hub) != 1 || hub[0].Image != "nginx:1.25" {
+		t.Errorf("images = %+v, err = %v, want the short Docker Hub name matched", hub, err)
+	}
+}
+
+func TestResolveProvenance(t *testing.T) {
+	srv := fakeRegistry(t)
+	registry := strings.TrimPrefix(srv.URL, "https://")
+	pods := []corev1.Pod{
+		*imagePod("shop", "api-1", registry+"/org/api:1.4", registry+"/org/api@sha256:index"),
+		*imagePod("shop", "api-2", registry+"/org/api:1.4", registry+"/org/api@sha256:old"),
+		*imagePod("shop", "api-3", registry+"/org/api:1.4", ""),
+	}
+	images, err := findImageProvenance(pods, registry+"/org/api:1.4")
+	if err != nil || len(images) != 1 {
+		t.Fatalf("findImageProvenance = %+v, %v", images, err)
+	}
+	img := &images[0]
+	resolveProvenance(context.Background(), srv.Client(), img)
+
+	if img.TagDigest != "sha256:index" || img.TagMoved {
+		t.Errorf("TagDigest = %q, TagMoved = %v, want the tag still running", img.TagDigest, img.TagMoved)
+	}
+	byDigest := make(map[string]DigestProvenance)
+	for _, d := range img.Digests {
+		byDigest[d.Digest] = d
+	}
+	if d := byDigest["sha256:index"]; d.Revision != "abc123" || d.Source != "https://github.com/org/api" || d.Version != "1.4.0" {
+		t.Errorf("current digest = %+v, want OCI revision, source and version", d)
+	}
+	if d := byDigest["sha256:old"]; d.Revision != "0ld" || d.Created != "2026-01-01T10:00:00Z" {
+		t.Errorf("old digest = %+v, want the label-schema revision", d)
+	}
+	if d, ok := byDigest[""]; !ok || d.Error != "" || d.Revision != "" {
+		t.Errorf("unreported digest = %+v, want it listed without a lookup", d)
+	}
+
+	moved, _ := findImageProvenance(pods[1:2], registry+"/org/api:1.4")
+	resolveProvenance(context.Background(), srv.Client(), &moved[0])
+	if !moved[0].TagMoved {
+		t.Errorf("image = %+v, want TagMoved when only the old digest runs", moved[0])
+	}
+}
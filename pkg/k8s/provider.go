@@ -6,26 +6,147 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/e9169/kopilot/pkg/debug"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
+// ProviderOption configures a Provider at construction.
+type ProviderOption func(*Provider)
+
+// WithCacheTTL sets how long cluster statuses are cached (default 1 minute).
+func WithCacheTTL(ttl time.Duration) ProviderOption {
+	return func(p *Provider) {
+		p.cacheTTL = ttl
+	}
+}
+
+// WithPriceTable enables cost estimates in cluster status reports.
+func WithPriceTable(table *PriceTable) ProviderOption {
+	return func(p *Provider) {
+		p.priceTable = table
+	}
+}
+
+// WithAdvisoryFeed replaces the bundled node advisory feed.
+func WithAdvisoryFeed(feed *AdvisoryFeed) ProviderOption {
+	return func(p *Provider) {
+		p.advisoryFeed = feed
+	}
+}
+
+// WithAPITimeout sets the timeout of each Kubernetes API call (default
+// DefaultAPITimeout).
+func WithAPITimeout(timeout time.Duration) ProviderOption {
+	return func(p *Provider) {
+		if timeout > 0 {
+			p.apiTimeout = timeout
+		}
+	}
+}
+
+// WithDiscoveryTimeout sets the timeout of cluster version checks (default
+// DiscoveryTimeout).
+func WithDiscoveryTimeout(timeout time.Duration) ProviderOption {
+	return func(p *Provider) {
+		if timeout > 0 {
+			p.discoveryTimeout = timeout
+		}
+	}
+}
+
+// WithReachTimeout sets how long a status check waits for the API server to
+// accept a TCP connection before giving up on the cluster (default
+// DefaultReachTimeout), so unreachable clusters fail fast instead of after
+// the discovery timeout; 0 skips the probe.
+func WithReachTimeout(timeout time.Duration) ProviderOption {
+	return func(p *Provider) {
+		p.reachTimeout = max(0, timeout)
+	}
+}
+
+// WithAPIRetries sets how many times an API request failing with a transient
+// error is retried (default DefaultAPIRetries, at most 10); 0 disables
+// retries.
+func WithAPIRetries(retries int) ProviderOption {
+	return func(p *Provider) {
+		p.apiRetries = max(0, min(retries, maxAPIRetries))
+	}
+}
+
+// WithMaxPods caps the pods a cluster status counts (default
+// DefaultMaxPods); 0 removes the cap. Statuses that reach it are marked
+// PodsTruncated.
+func WithMaxPods(n int) ProviderOption {
+	return func(p *Provider) {
+		p.maxPods = max(0, n)
+	}
+}
+
+// WithLiveState starts pod, node and event informers for a context once it
+// has been queried afterQueries times, so later status checks and event
+// queries read from their local caches instead of listing from the API
+// server. The caches hold every pod, node and event of the cluster in memory
+// while the provider runs. 0, the default, never starts them.
+func WithLiveState(afterQueries int) ProviderOption {
+	return func(p *Provider) {
+		p.liveAfter = max(0, afterQueries)
+	}
+}
+
 // NewProvider creates a new Kubernetes provider
-func NewProvider(kubeconfigPath string) (*Provider, error) {
+func NewProvider(kubeconfigPath string, opts ...ProviderOption) (*Provider, error) {
 	// Load kubeconfig
 	rawConfig, err := clientcmd.LoadFromFile(kubeconfigPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
 	}
+	p := newProvider(rawConfig, opts)
+	p.kubeconfigPath = kubeconfigPath
+	return p, nil
+}
 
-	// Parse cluster information
-	clusters := make(map[string]*ClusterInfo)
-	currentContext := rawConfig.CurrentContext
+// NewProviderFromConfig creates a provider for an in-memory kubeconfig, for
+// programs that build or fetch cluster credentials themselves instead of
+// reading a kubeconfig file.
+func NewProviderFromConfig(config *clientcmdapi.Config, opts ...ProviderOption) (*Provider, error) {
+	if config == nil {
+		return nil, fmt.Errorf("kubeconfig is required")
+	}
+	return newProvider(config.DeepCopy(), opts), nil
+}
 
+func newProvider(rawConfig *clientcmdapi.Config, opts []ProviderOption) *Provider {
+	p := &Provider{
+		rawConfig:      rawConfig,
+		clusters:       parseClusters(rawConfig, rawConfig.CurrentContext),
+		currentContext: rawConfig.CurrentContext,
+		cacheTTL:       1 * time.Minute, // Default 1 minute cache
+
+		apiTimeout:       DefaultAPITimeout,
+		discoveryTimeout: DiscoveryTimeout,
+		apiRetries:       DefaultAPIRetries,
+		maxPods:          DefaultMaxPods,
+		reachTimeout:     DefaultReachTimeout,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// parseClusters returns the cluster information of every context of
+// rawConfig whose cluster is defined, by context name.
+func parseClusters(rawConfig *clientcmdapi.Config, currentContext string) map[string]*ClusterInfo {
+	clusters := make(map[string]*ClusterInfo)
 	for contextName, contextInfo := range rawConfig.Contexts {
 		clusterName := contextInfo.Cluster
 		cluster, ok := rawConfig.Clusters[clusterName]
@@ -42,60 +163,136 @@ func NewProvider(kubeconfigPath string) (*Provider, error) {
 			IsCurrent: contextName == currentContext,
 		}
 	}
+	return clusters
+}
 
-	return &Provider{
-		kubeconfigPath: kubeconfigPath,
-		rawConfig:      rawConfig,
-		clusters:       clusters,
-		currentContext: currentContext,
-		cache:          make(map[string]*CachedClusterStatus),
-		cacheTTL:       1 * time.Minute, // Default 1 minute cache
-	}, nil
+// APITimeout returns the timeout of each Kubernetes API call.
+func (p *Provider) APITimeout() time.Duration {
+	return p.apiTimeout
+}
+
+// DiscoveryTimeout returns the timeout of cluster version checks.
+func (p *Provider) DiscoveryTimeout() time.Duration {
+	return p.discoveryTimeout
+}
+
+// StatusTimeout returns how long a cluster status check may query the API
+// server: the discovery timeout for the version check, then one API timeout
+// for the node and pod lists and their retries.
+func (p *Provider) StatusTimeout() time.Duration {
+	return p.DiscoveryTimeout() + p.APITimeout()
+}
+
+// StatusBudget returns how long GetClusterStatuses waits for one cluster:
+// the reachability probe and StatusTimeout, with a margin to build the
+// status.
+func (p *Provider) StatusBudget() time.Duration {
+	if clusterStatusBudget > 0 {
+		return clusterStatusBudget
+	}
+	return p.ReachTimeout() + p.StatusTimeout() + statusBudgetMargin
+}
+
+// ReachTimeout returns the timeout of the reachability probe; 0 when it is
+// off.
+func (p *Provider) ReachTimeout() time.Duration {
+	return p.reachTimeout
+}
+
+// APIRetries returns how many times transient API errors are retried.
+func (p *Provider) APIRetries() int {
+	return p.apiRetries
+}
+
+// MaxPods returns the cap on the pods a cluster status counts; 0 means no
+// cap.
+func (p *Provider) MaxPods() int {
+	return p.maxPods
+}
+
+// kubeconfig returns the kubeconfig the provider was built from, or last
+// reloaded.
+func (p *Provider) kubeconfig() *clientcmdapi.Config {
+	p.clustersMutex.RLock()
+	defer p.clustersMutex.RUnlock()
+	return p.rawConfig
 }
 
-// GetClusters returns a list of all clusters in the kubeconfig
+// GetClusters returns a copy of every cluster in the kubeconfig, sorted by
+// context name
 func (p *Provider) GetClusters() []*ClusterInfo {
+	p.clustersMutex.RLock()
 	clusters := make([]*ClusterInfo, 0, len(p.clusters))
 	for _, cluster := range p.clusters {
-		clusters = append(clusters, cluster)
+		info := *cluster
+		clusters = append(clusters, &info)
 	}
+	p.clustersMutex.RUnlock()
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Context < clusters[j].Context })
 	return clusters
 }
 
-// GetClusterByContext returns cluster information for a specific context
+// GetClusterByContext returns a copy of the cluster information of a
+// specific context
 func (p *Provider) GetClusterByContext(contextName string) (*ClusterInfo, error) {
+	p.clustersMutex.RLock()
+	defer p.clustersMutex.RUnlock()
 	cluster, ok := p.clusters[contextName]
 	if !ok {
 		return nil, fmt.Errorf("cluster context %q not found", contextName)
 	}
-	return cluster, nil
+	info := *cluster
+	return &info, nil
 }
 
-// GetClusterStatus returns detailed status information for a cluster
-// createClientset creates a Kubernetes clientset for the given context
+// createClientset returns the clientset of the given context, shared by
+// every caller through its ContextProvider
 func (p *Provider) createClientset(contextName string) (kubernetes.Interface, *rest.Config, error) {
-	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		&clientcmd.ClientConfigLoadingRules{ExplicitPath: p.kubeconfigPath},
-		&clientcmd.ConfigOverrides{CurrentContext: contextName},
-	)
-
-	restConfig, err := clientConfig.ClientConfig()
+	c, err := p.ForContext(contextName)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create client config: %w", err)
 	}
+	return c.clients()
+}
 
-	clientset, err := kubernetes.NewForConfig(restConfig)
+// Clientset returns the clientset of the given context, created on first
+// use and shared with every check and tool querying the context until the
+// kubeconfig is reloaded.
+func (p *Provider) Clientset(contextName string) (kubernetes.Interface, error) {
+	clientset, _, err := p.createClientset(contextName)
+	return clientset, err
+}
+
+// createDynamicClient returns the client of the given context for resources
+// without typed clients, such as custom resources.
+func (p *Provider) createDynamicClient(contextName string) (dynamic.Interface, error) {
+	c, err := p.ForContext(contextName)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create clientset: %w", err)
+		return nil, fmt.Errorf("failed to create client config: %w", err)
 	}
-
-	return clientset, restConfig, nil
+	return c.DynamicClient()
 }
 
+// GetClusterStatus returns detailed status information for a cluster
 func (p *Provider) GetClusterStatus(ctx context.Context, contextName string) (*ClusterStatus, error) {
+	return p.GetFilteredClusterStatus(ctx, contextName, HealthFilter{})
+}
+
+// GetFilteredClusterStatus returns the status of a cluster with its node and
+// pod health scoped by filter. A filtered status is neither read from nor
+// stored in the cache, and leaves out the cluster-wide checks (exposure,
+// autoscaling, control plane and cost), so only the selected nodes and pods
+// are listed.
+func (p *Provider) GetFilteredClusterStatus(ctx context.Context, contextName string, filter HealthFilter) (*ClusterStatus, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	filtered := !filter.IsZero()
 	// Check cache first
-	if cached := p.getCachedStatus(contextName); cached != nil {
-		return cached, nil
+	if !filtered {
+		if cached := p.getCachedStatus(contextName); cached != nil {
+			return cached, nil
+		}
 	}
 
 	clusterInfo, err := p.GetClusterByContext(contextName)
@@ -105,23 +302,39 @@ func (p *Provider) GetClusterStatus(ctx context.Context, contextName string) (*C
 
 	status := &ClusterStatus{
 		ClusterInfo: *clusterInfo,
+		Filter:      filter,
 	}
 
 	// Create clientset for this specific context
 	clientset, restConfig, err := p.createClientset(contextName)
 	if err != nil {
 		status.Error = err.Error()
+		status.Failure, status.Advice = FailureConfig, "The context's entry in the kubeconfig is unusable; check its cluster, user and certificate files."
+		return status, nil
+	}
+
+	// Fail fast when the API server does not even accept a connection
+	if err := probeAPIServer(ctx, restConfig, p.ReachTimeout()); err != nil {
+		message, transient := describeReachError(err)
+		status.setFailure(message, transient, err, restConfig)
 		return status, nil
 	}
 
 	// Test connectivity with timeout
-	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	queryCtx, cancel := context.WithTimeout(ctx, p.StatusTimeout())
 	defer cancel()
 
 	// Get cluster version
-	version, err := getClusterVersion(queryCtx, clientset)
+	version, err := getClusterVersion(queryCtx, clientset, p.DiscoveryTimeout())
+	if apierrors.IsUnauthorized(err) && restConfig.ExecProvider != nil {
+		// The rejection made the credential plugin run again; a token it
+		// cached past its expiry has been replaced, so try once more.
+		debug.Logf(debug.K8s, "context %s: credentials rejected, retrying with renewed ones", contextName)
+		version, err = getClusterVersion(queryCtx, clientset, p.DiscoveryTimeout())
+	}
 	if err != nil {
-		status.Error = fmt.Sprintf("Failed to reach cluster: %v", err)
+		message, transient := describeReachError(err)
+		status.setFailure(message, transient, err, restConfig)
 		status.IsReachable = false
 		return status, nil
 	}
@@ -130,15 +343,26 @@ func (p *Provider) GetClusterStatus(ctx context.Context, contextName string) (*C
 	status.IsReachable = true
 	status.APIServerURL = restConfig.Host
 
+	live := p.liveFor(contextName)
+
 	// Collect node information
-	nodeInfos, healthyNodes, err := collectNodeInfo(queryCtx, clientset)
+	var nodeInfos []NodeInfo
+	var healthyNodes int
+	if live != nil {
+		nodeInfos, healthyNodes, err = live.nodeInfo(filter)
+	} else {
+		nodeInfos, healthyNodes, err = collectNodeInfo(queryCtx, clientset, filter)
+	}
 	if err != nil {
-		status.Error = fmt.Sprintf("Failed to list nodes: %v", err)
+		status.setFailure(fmt.Sprintf("Failed to list nodes: %v", err), IsTransient(err), err, restConfig)
 		return status, nil
 	}
 	status.Nodes = nodeInfos
 	status.NodeCount = len(nodeInfos)
+	status.OSDistribution = osDistribution(nodeInfos)
+	status.ArchDistribution = archDistribution(nodeInfos)
 	status.HealthyNodes = healthyNodes
+	status.KubeletSkew = kubeletVersionSkew(version, nodeInfos)
 
 	// Collect namespace list
 	namespaceList, err := collectNamespaceList(queryCtx, clientset)
@@ -147,11 +371,43 @@ func (p *Provider) GetClusterStatus(ctx context.Context, contextName string) (*C
 	}
 
 	// Collect pod health information
-	totalPods, healthyPods, unhealthyPods, err := collectPodHealth(queryCtx, clientset)
+	var health podHealth
+	if live != nil && filter.FieldSelector == "" {
+		health, err = live.podHealth(filter, p.maxPods)
+	} else {
+		health, err = collectPodHealth(queryCtx, clientset, filter, p.maxPods)
+	}
 	if err == nil {
-		status.PodCount = totalPods
-		status.HealthyPods = healthyPods
-		status.UnhealthyPods = unhealthyPods
+		status.PodCount = health.total
+		status.HealthyPods = health.healthy
+		status.UnhealthyPods = health.unhealthy
+		status.PodsTruncated = health.truncated
+	}
+
+	if filtered {
+		return status, nil
+	}
+
+	// Collect exposed endpoint health (best effort)
+	if exposureIssues, err := collectExposureIssues(queryCtx, clientset); err == nil {
+		status.ExposureIssues = exposureIssues
+	}
+
+	// Collect autoscaler problems (best effort)
+	if autoscalingIssues, err := collectAutoscalingIssues(queryCtx, clientset); err == nil {
+		status.AutoscalingIssues = autoscalingIssues
+	}
+
+	// Collect control-plane component health (best effort)
+	if controlPlane, err := collectControlPlaneHealth(queryCtx, clientset); err == nil {
+		status.ControlPlane = controlPlane
+	}
+
+	// Estimate running cost when a price table is configured (best effort)
+	if table := p.getPriceTable(); table != nil {
+		if cost, err := estimateClusterCost(queryCtx, clientset, table); err == nil {
+			status.Cost = cost
+		}
 	}
 
 	// Cache the result
@@ -159,37 +415,113 @@ func (p *Provider) GetClusterStatus(ctx context.Context, contextName string) (*C
 	return status, nil
 }
 
-// GetAllClusterStatuses returns status information for all clusters in parallel
+// describeReachError explains a failed version check: an API server that
+// answered but kept throttling or timing out through the retries is busy,
+// reported as transient; any other failure means it could not be reached.
+func describeReachError(err error) (string, bool) {
+	if IsTransient(err) {
+		return fmt.Sprintf("Cluster API server is busy (throttling or timing out after retries): %v", err), true
+	}
+	return fmt.Sprintf("Failed to reach cluster: %v", err), false
+}
+
+// GetServerVersion returns the Kubernetes version of the cluster of
+// contextName without collecting its full status.
+func (p *Provider) GetServerVersion(ctx context.Context, contextName string) (string, error) {
+	if _, err := p.GetClusterByContext(contextName); err != nil {
+		return "", err
+	}
+	clientset, restConfig, err := p.createClientset(contextName)
+	if err != nil {
+		return "", err
+	}
+	if err := probeAPIServer(ctx, restConfig, p.ReachTimeout()); err != nil {
+		return "", err
+	}
+	return getClusterVersion(ctx, clientset, p.DiscoveryTimeout())
+}
+
+// GetAllClusterStatuses returns status information for all clusters in parallel.
+// A cluster that does not answer within StatusBudget is reported as
+// unreachable so one hung API server cannot hold up the others.
 func (p *Provider) GetAllClusterStatuses(ctx context.Context) []*ClusterStatus {
 	clusters := p.GetClusters()
-	statuses := make([]*ClusterStatus, len(clusters))
+	contexts := make([]string, len(clusters))
+	for i, cluster := range clusters {
+		contexts[i] = cluster.Context
+	}
+	return p.GetClusterStatuses(ctx, contexts)
+}
+
+// GetClusterStatuses is GetAllClusterStatuses for the given contexts, in
+// their order. An unknown context is reported as unreachable. A context
+// from WithProgress receives an update as each context finishes.
+func (p *Provider) GetClusterStatuses(ctx context.Context, contexts []string) []*ClusterStatus {
+	statuses := make([]*ClusterStatus, len(contexts))
+	tracker := newProgressTracker(ctx, contexts)
 
 	var wg sync.WaitGroup
-	for i, cluster := range clusters {
+	var mu sync.Mutex
+	for i, contextName := range contexts {
 		wg.Add(1)
 		go func(idx int, contextName string) {
 			defer wg.Done()
-			status, err := p.GetClusterStatus(ctx, contextName)
-			if err != nil {
-				// Create a status with error if GetClusterStatus fails
-				statuses[idx] = &ClusterStatus{
-					ClusterInfo: ClusterInfo{
-						Context:     contextName,
-						Name:        contextName,
-						IsReachable: false,
-					},
-					Error: err.Error(),
-				}
-			} else {
-				statuses[idx] = status
-			}
-		}(i, cluster.Context)
+			statuses[idx] = p.getClusterStatusWithin(ctx, contextName, p.StatusBudget())
+			mu.Lock()
+			tracker.finish(idx)
+			mu.Unlock()
+		}(i, contextName)
 	}
 
 	wg.Wait()
 	return statuses
 }
 
+// getClusterStatusWithin returns the status of contextName, or an unreachable
+// status naming the budget when it is not available in time.
+func (p *Provider) getClusterStatusWithin(ctx context.Context, contextName string, budget time.Duration) *ClusterStatus {
+	unreachable := func(msg string) *ClusterStatus {
+		status := &ClusterStatus{
+			ClusterInfo: ClusterInfo{Context: contextName, Name: contextName},
+			Error:       msg,
+		}
+		if info, err := p.GetClusterByContext(contextName); err == nil {
+			status.ClusterInfo = *info
+			status.IsReachable = false
+		}
+		return status
+	}
+
+	budgetCtx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+	type result struct {
+		status *ClusterStatus
+		err    error
+	}
+	// Buffered so the goroutine does not block once the budget ran out; the
+	// lookup itself stops then, since it runs on budgetCtx.
+	done := make(chan result, 1)
+	go func() {
+		status, err := p.GetClusterStatus(budgetCtx, contextName)
+		done <- result{status, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return unreachable(r.err.Error())
+		}
+		return r.status
+	case <-budgetCtx.Done():
+		if ctx.Err() != nil {
+			return unreachable(ctx.Err().Error())
+		}
+		status := unreachable(fmt.Sprintf("did not respond within %s", budget))
+		status.Failure, status.Advice = classifyFailure(budgetCtx.Err(), nil)
+		return status
+	}
+}
+
 // SanitizeCluster inspects all Deployments, StatefulSets, and DaemonSets in the cluster against
 // Kubernetes best-practice and security rules, returning a scored report grouped by namespace.
 // If targetNamespace is non-empty, only that namespace is scanned.
@@ -200,7 +532,7 @@ func (p *Provider) SanitizeCluster(ctx context.Context, contextName, targetNames
 		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
 	}
 
-	queryCtx, cancel := context.WithTimeout(ctx, DefaultAPITimeout)
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
 	defer cancel()
 
 	findings, allWorkloads, err := collectSanitizeFindings(queryCtx, clientset, targetNamespace, includeSystem)
@@ -208,16 +540,25 @@ func (p *Provider) SanitizeCluster(ctx context.Context, contextName, targetNames
 		return nil, err
 	}
 
-	return buildSanitizeResult(contextName, findings, allWorkloads), nil
+	result := buildSanitizeResult(contextName, findings, allWorkloads)
+	if targetNamespace == "" {
+		p.addNodeAdvisories(queryCtx, clientset, result)
+	}
+	return result, nil
 }
 
 // GetCurrentContext returns the current context name
 func (p *Provider) GetCurrentContext() string {
+	p.clustersMutex.RLock()
+	defer p.clustersMutex.RUnlock()
 	return p.currentContext
 }
 
-// SetCurrentContext overrides the current context
+// SetCurrentContext overrides the current context. It is safe to call while
+// checks run; clusters already returned keep their IsCurrent value.
 func (p *Provider) SetCurrentContext(contextName string) error {
+	p.clustersMutex.Lock()
+	defer p.clustersMutex.Unlock()
 	if _, ok := p.clusters[contextName]; !ok {
 		return fmt.Errorf("cluster context %q not found", contextName)
 	}
@@ -226,6 +567,7 @@ func (p *Provider) SetCurrentContext(contextName string) error {
 	for _, cluster := range p.clusters {
 		cluster.IsCurrent = cluster.Context == contextName
 	}
+	p.cacheGeneration.Add(1)
 
 	return nil
 }
@@ -3,8 +3,11 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -243,6 +246,71 @@ func TestSetCurrentContext(t *testing.T) {
 	}
 }
 
+func TestGetClustersReturnsCopies(t *testing.T) {
+	kubeconfigPath, cleanup := createTempKubeconfig(t, 3)
+	defer cleanup()
+
+	provider, err := NewProvider(kubeconfigPath)
+	if err != nil {
+		t.Fatalf(errNewProviderFailed, err)
+	}
+	clusters := provider.GetClusters()
+	for i, want := range []string{"context-1", "context-2", "context-3"} {
+		if clusters[i].Context != want {
+			t.Errorf("GetClusters()[%d] = %s, want %s (sorted by context)", i, clusters[i].Context, want)
+		}
+	}
+	clusters[1].IsCurrent = true
+	clusters[1].Namespace = "changed"
+	if cluster, _ := provider.GetClusterByContext("context-2"); cluster.IsCurrent || cluster.Namespace == "changed" {
+		t.Errorf("changing a returned cluster changed the provider: %+v", cluster)
+	}
+	cluster, _ := provider.GetClusterByContext("context-3")
+	cluster.Server = "https://elsewhere"
+	if again, _ := provider.GetClusterByContext("context-3"); again.Server == "https://elsewhere" {
+		t.Error("changing GetClusterByContext's result changed the provider")
+	}
+}
+
+// TestSetCurrentContextConcurrent switches the context while other
+// goroutines read the clusters; run with -race.
+func TestSetCurrentContextConcurrent(t *testing.T) {
+	kubeconfigPath, cleanup := createTempKubeconfig(t, 3)
+	defer cleanup()
+
+	provider, err := NewProvider(kubeconfigPath)
+	if err != nil {
+		t.Fatalf(errNewProviderFailed, err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_ = provider.SetCurrentContext(fmt.Sprintf("context-%d", (i+j)%3+1))
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				current := 0
+				for _, cluster := range provider.GetClusters() {
+					if cluster.IsCurrent {
+						current++
+					}
+				}
+				if current != 1 {
+					t.Errorf("GetClusters() marked %d clusters current, want 1", current)
+				}
+				_ = provider.GetCurrentContext()
+				_, _ = provider.GetClusterByContext("context-1")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func TestGetClusterStatusInvalidContext(t *testing.T) {
 	kubeconfigPath, cleanup := createTempKubeconfig(t, 1)
 	defer cleanup()
@@ -261,6 +329,105 @@ func TestGetClusterStatusInvalidContext(t *testing.T) {
 	}
 }
 
+func TestGetServerVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/version" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"major": "1", "minor": "31", "gitVersion": "v1.31.2"}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewProvider(writeServerKubeconfig(t, server.URL))
+	if err != nil {
+		t.Fatalf(errNewProviderFailed, err)
+	}
+
+	version, err := provider.GetServerVersion(context.Background(), "local")
+	if err != nil || version != "v1.31.2" {
+		t.Errorf("GetServerVersion() = %q, %v; want v1.31.2", version, err)
+	}
+	if _, err := provider.GetServerVersion(context.Background(), "missing"); err == nil {
+		t.Error("GetServerVersion() expected error for non-existent context, got nil")
+	}
+}
+
+func TestNewProviderFromConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"major": "1", "minor": "32", "gitVersion": "v1.32.0"}`))
+	}))
+	defer server.Close()
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters["platform"] = &clientcmdapi.Cluster{Server: server.URL}
+	config.AuthInfos["bot"] = &clientcmdapi.AuthInfo{Token: "t"}
+	config.Contexts["platform-prod"] = &clientcmdapi.Context{Cluster: "platform", AuthInfo: "bot", Namespace: "apps"}
+	config.CurrentContext = "platform-prod"
+
+	feed := &AdvisoryFeed{Version: "test"}
+	provider, err := NewProviderFromConfig(config, WithCacheTTL(time.Second), WithAdvisoryFeed(feed))
+	if err != nil {
+		t.Fatalf("NewProviderFromConfig() failed: %v", err)
+	}
+	delete(config.Contexts, "platform-prod") // the provider keeps its own copy
+
+	cluster, err := provider.GetClusterByContext("platform-prod")
+	if err != nil || cluster.Namespace != "apps" || !cluster.IsCurrent {
+		t.Errorf("cluster = %+v, %v", cluster, err)
+	}
+	if version, err := provider.GetServerVersion(context.Background(), "platform-prod"); err != nil || version != "v1.32.0" {
+		t.Errorf("GetServerVersion() = %q, %v; want v1.32.0", version, err)
+	}
+	if got, _ := provider.getAdvisoryFeed(); got != feed || provider.cacheTTL != time.Second {
+		t.Errorf("options not applied: feed %v, ttl %v", got, provider.cacheTTL)
+	}
+	if _, err := NewProviderFromConfig(nil); err == nil {
+		t.Error("NewProviderFromConfig(nil) expected an error")
+	}
+}
+
+func TestGetAllClusterStatusesBudget(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	original := clusterStatusBudget
+	t.Cleanup(func() { clusterStatusBudget = original })
+	clusterStatusBudget = 100 * time.Millisecond
+
+	provider, err := NewProvider(writeServerKubeconfig(t, server.URL))
+	if err != nil {
+		t.Fatalf(errNewProviderFailed, err)
+	}
+	start := time.Now()
+	statuses := provider.GetAllClusterStatuses(context.Background())
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("GetAllClusterStatuses() took %s despite the budget", elapsed)
+	}
+	if len(statuses) != 1 || statuses[0].IsReachable || statuses[0].Error != "did not respond within 100ms" || statuses[0].Name != "local" {
+		t.Errorf("unexpected statuses: %+v", statuses[0])
+	}
+}
+
+// writeServerKubeconfig writes a kubeconfig with a single "local" context
+// pointing at server and returns its path.
+func writeServerKubeconfig(t *testing.T, server string) string {
+	t.Helper()
+	config := clientcmdapi.NewConfig()
+	config.Clusters["local"] = &clientcmdapi.Cluster{Server: server}
+	config.AuthInfos["user"] = &clientcmdapi.AuthInfo{Token: "test-token"}
+	config.Contexts["local"] = &clientcmdapi.Context{Cluster: "local", AuthInfo: "user"}
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
 // createTempKubeconfig creates a temporary kubeconfig file for testing
 func createTempKubeconfig(t *testing.T, numClusters int) (string, func()) {
 	t.Helper()
@@ -518,7 +685,7 @@ func TestCollectNodeInfo(t *testing.T) {
 		},
 	)
 
-	nodeInfos, healthyCount, err := collectNodeInfo(ctx, clientset)
+	nodeInfos, healthyCount, err := collectNodeInfo(ctx, clientset, HealthFilter{})
 	if err != nil {
 		t.Fatalf("collectNodeInfo() error = %v", err)
 	}
@@ -632,10 +799,11 @@ func TestCollectPodHealth(t *testing.T) {
 		},
 	)
 
-	totalPods, healthyPods, unhealthyPods, err := collectPodHealth(ctx, clientset)
+	health, err := collectPodHealth(ctx, clientset, HealthFilter{}, 0)
 	if err != nil {
 		t.Fatalf("collectPodHealth() error = %v", err)
 	}
+	totalPods, healthyPods, unhealthyPods := health.total, health.healthy, health.unhealthy
 
 	if totalPods != 3 {
 		t.Errorf("Expected 3 total pods, got %d", totalPods)
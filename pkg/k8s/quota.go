@@ -0,0 +1,225 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains ResourceQuota consumption and LimitRange reporting per namespace.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultQuotaThreshold is the usage percentage at which a quota counts as near its limit.
+const DefaultQuotaThreshold = 80.0
+
+// QuotaResource is the consumption of one resource in a ResourceQuota.
+type QuotaResource struct {
+	Resource string  `json:"resource"`
+	Used     string  `json:"used"`
+	Hard     string  `json:"hard"`
+	Percent  float64 `json:"percent"`
+}
+
+// QuotaUsage is one ResourceQuota and its consumption.
+type QuotaUsage struct {
+	Name      string          `json:"name"`
+	Resources []QuotaResource `json:"resources"`
+}
+
+// LimitRangeSetting is one resource entry of a LimitRange.
+type LimitRangeSetting struct {
+	LimitRange     string `json:"limit_range"`
+	Type           string `json:"type"` // Container, Pod or PersistentVolumeClaim
+	Resource       string `json:"resource"`
+	Min            string `json:"min,omitempty"`
+	Max            string `json:"max,omitempty"`
+	Default        string `json:"default,omitempty"`
+	DefaultRequest string `json:"default_request,omitempty"`
+	MaxRatio       string `json:"max_limit_request_ratio,omitempty"`
+}
+
+// NamespaceQuota reports the quotas and limit ranges of one namespace.
+type NamespaceQuota struct {
+	Namespace   string              `json:"namespace"`
+	Quotas      []QuotaUsage        `json:"quotas"`
+	LimitRanges []LimitRangeSetting `json:"limit_ranges"`
+	MaxPercent  float64             `json:"max_percent"`
+	NearLimit   []string            `json:"near_limit,omitempty"` // quota/resource at or above the threshold
+}
+
+// QuotaReport is the outcome of GetQuotaUsage.
+type QuotaReport struct {
+	Context      string           `json:"context"`
+	Threshold    float64          `json:"threshold"`
+	Namespaces   []NamespaceQuota `json:"namespaces"`
+	NearLimit    int              `json:"near_limit"`    // namespaces with a resource at or above the threshold
+	WithoutQuota []string         `json:"without_quota"` // namespaces in scope with no ResourceQuota
+}
+
+// quotaPercent returns used as a percentage of hard; 100 when hard is zero and used is not.
+func quotaPercent(used, hard resource.Quantity) float64 {
+	h := hard.AsApproximateFloat64()
+	if h == 0 {
+		if used.IsZero() {
+			return 0
+		}
+		return 100
+	}
+	return used.AsApproximateFloat64() / h * 100
+}
+
+// quantityOrEmpty returns q as a string, or "" when the resource is not set.
+func quantityOrEmpty(list corev1.ResourceList, name corev1.ResourceName) string {
+	if q, ok := list[name]; ok {
+		return q.String()
+	}
+	return ""
+}
+
+// limitRangeSettings flattens a LimitRange into one entry per type and resource.
+func limitRangeSettings(lr *corev1.LimitRange) []LimitRangeSetting {
+	var out []LimitRangeSetting
+	for _, item := range lr.Spec.Limits {
+		names := make(map[corev1.ResourceName]bool)
+		for _, list := range []corev1.ResourceList{item.Min, item.Max, item.Default, item.DefaultRequest, item.MaxLimitRequestRatio} {
+			for name := range list {
+				names[name] = true
+			}
+		}
+		sorted := make([]string, 0, len(names))
+		for name := range names {
+			sorted = append(sorted, string(name))
+		}
+		sort.Strings(sorted)
+		for _, name := range sorted {
+			rn := corev1.ResourceName(name)
+			out = append(out, LimitRangeSetting{
+				LimitRange:     lr.Name,
+				Type:           string(item.Type),
+				Resource:       name,
+				Min:            quantityOrEmpty(item.Min, rn),
+				Max:            quantityOrEmpty(item.Max, rn),
+				Default:        quantityOrEmpty(item.Default, rn),
+				DefaultRequest: quantityOrEmpty(item.DefaultRequest, rn),
+				MaxRatio:       quantityOrEmpty(item.MaxLimitRequestRatio, rn),
+			})
+		}
+	}
+	return out
+}
+
+// buildQuotaReport groups quotas and limit ranges by namespace and flags
+// resources at or above threshold percent. namespaces lists every namespace
+// in scope so those without a quota can be reported.
+func buildQuotaReport(namespaces []string, quotas []corev1.ResourceQuota, limitRanges []corev1.LimitRange, threshold float64) *QuotaReport {
+	byNS := make(map[string]*NamespaceQuota)
+	get := func(ns string) *NamespaceQuota {
+		if byNS[ns] == nil {
+			byNS[ns] = &NamespaceQuota{Namespace: ns, Quotas: []QuotaUsage{}, LimitRanges: []LimitRangeSetting{}}
+		}
+		return byNS[ns]
+	}
+
+	for i := range quotas {
+		q := &quotas[i]
+		nq := get(q.Namespace)
+		usage := QuotaUsage{Name: q.Name}
+		names := make([]string, 0, len(q.Status.Hard))
+		for name := range q.Status.Hard {
+			names = append(names, string(name))
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			hard := q.Status.Hard[corev1.ResourceName(name)]
+			used := q.Status.Used[corev1.ResourceName(name)]
+			pct := quotaPercent(used, hard)
+			usage.Resources = append(usage.Resources, QuotaResource{Resource: name, Used: used.String(), Hard: hard.String(), Percent: pct})
+			if pct > nq.MaxPercent {
+				nq.MaxPercent = pct
+			}
+			if pct >= threshold {
+				nq.NearLimit = append(nq.NearLimit, q.Name+"/"+name)
+			}
+		}
+		nq.Quotas = append(nq.Quotas, usage)
+	}
+	for i := range limitRanges {
+		nq := get(limitRanges[i].Namespace)
+		nq.LimitRanges = append(nq.LimitRanges, limitRangeSettings(&limitRanges[i])...)
+	}
+
+	report := &QuotaReport{Threshold: threshold, Namespaces: []NamespaceQuota{}, WithoutQuota: []string{}}
+	for _, ns := range namespaces {
+		nq, ok := byNS[ns]
+		if !ok || len(nq.Quotas) == 0 {
+			report.WithoutQuota = append(report.WithoutQuota, ns)
+		}
+		if ok {
+			if len(nq.NearLimit) > 0 {
+				report.NearLimit++
+			}
+			report.Namespaces = append(report.Namespaces, *nq)
+		}
+	}
+	sort.SliceStable(report.Namespaces, func(i, j int) bool {
+		a, b := report.Namespaces[i], report.Namespaces[j]
+		if a.MaxPercent != b.MaxPercent {
+			return a.MaxPercent > b.MaxPercent
+		}
+		return a.Namespace < b.Namespace
+	})
+	return report
+}
+
+// collectQuotaReport lists namespaces, ResourceQuotas and LimitRanges in scope.
+func collectQuotaReport(ctx context.Context, clientset kubernetes.Interface, targetNamespace string, includeSystem bool, threshold float64) (*QuotaReport, error) {
+	var namespaces []string
+	if targetNamespace != "" {
+		namespaces = []string{targetNamespace}
+	} else {
+		list, err := collectNamespaceList(ctx, clientset)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range list {
+			if shouldScanNamespace(ns, "", includeSystem) {
+				namespaces = append(namespaces, ns)
+			}
+		}
+	}
+
+	quotas, err := clientset.CoreV1().ResourceQuotas(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource quotas: %w", err)
+	}
+	limitRanges, err := clientset.CoreV1().LimitRanges(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list limit ranges: %w", err)
+	}
+	return buildQuotaReport(namespaces, quotas.Items, limitRanges.Items, threshold), nil
+}
+
+// GetQuotaUsage reports ResourceQuota consumption and LimitRange settings per
+// namespace, flagging resources used at or above threshold percent. If
+// targetNamespace is non-empty, only that namespace is reported. If
+// includeSystem is false, system namespaces are excluded.
+func (p *Provider) GetQuotaUsage(ctx context.Context, contextName, targetNamespace string, includeSystem bool, threshold float64) (*QuotaReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	report, err := collectQuotaReport(queryCtx, clientset, targetNamespace, includeSystem, threshold)
+	if err != nil {
+		return nil, err
+	}
+	report.Context = contextName
+	return report, nil
+}
@@ -0,0 +1,86 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func resourceQuota(namespace, name string, hard, used corev1.ResourceList) *corev1.ResourceQuota {
+	return &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status:     corev1.ResourceQuotaStatus{Hard: hard, Used: used},
+	}
+}
+
+func TestCollectQuotaReport(t *testing.T) {
+	ns := func(name string) *corev1.Namespace { return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}} }
+	clientset := fake.NewClientset(
+		ns("team-a"), ns("team-b"), ns("team-c"), ns("kube-system"),
+		resourceQuota("team-a", "compute",
+			corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("4"), corev1.ResourceRequestsMemory: resource.MustParse("8Gi")},
+			corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("3600m"), corev1.ResourceRequestsMemory: resource.MustParse("2Gi")}),
+		resourceQuota("team-b", "compute",
+			corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")},
+			corev1.ResourceList{corev1.ResourcePods: resource.MustParse("5")}),
+		resourceQuota("kube-system", "critical", corev1.ResourceList{corev1.ResourcePods: resource.MustParse("1")}, corev1.ResourceList{corev1.ResourcePods: resource.MustParse("1")}),
+		&corev1.LimitRange{
+			ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "team-c"},
+			Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+				Type:           corev1.LimitTypeContainer,
+				Default:        corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+				DefaultRequest: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+				Max:            corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+			}}},
+		},
+	)
+
+	report, err := collectQuotaReport(context.Background(), clientset, "", false, DefaultQuotaThreshold)
+	if err != nil {
+		t.Fatalf("collectQuotaReport: %v", err)
+	}
+	if len(report.Namespaces) != 3 || report.Namespaces[0].Namespace != "team-a" {
+		t.Fatalf("namespaces = %+v, want team-a first and no kube-system", report.Namespaces)
+	}
+	a := report.Namespaces[0]
+	if a.MaxPercent != 90 || len(a.NearLimit) != 1 || a.NearLimit[0] != "compute/requests.cpu" || report.NearLimit != 1 {
+		t.Errorf("team-a = %+v, near limit %d", a, report.NearLimit)
+	}
+	if got := a.Quotas[0].Resources[1]; got.Resource != "requests.memory" || got.Used != "2Gi" || got.Percent != 25 {
+		t.Errorf("memory usage = %+v", got)
+	}
+	if len(report.WithoutQuota) != 1 || report.WithoutQuota[0] != "team-c" {
+		t.Errorf("WithoutQuota = %v, want [team-c]", report.WithoutQuota)
+	}
+
+	var c NamespaceQuota
+	for _, nq := range report.Namespaces {
+		if nq.Namespace == "team-c" {
+			c = nq
+		}
+	}
+	if len(c.LimitRanges) != 2 || c.LimitRanges[0].Resource != "cpu" || c.LimitRanges[0].Default != "500m" || c.LimitRanges[0].DefaultRequest != "100m" || c.LimitRanges[1].Max != "2Gi" {
+		t.Errorf("team-c limit ranges = %+v", c.LimitRanges)
+	}
+}
+
+func TestQuotaPercent(t *testing.T) {
+	tests := []struct {
+		used, hard string
+		want       float64
+	}{
+		{"0", "0", 0},
+		{"1", "0", 100},
+		{"512Mi", "1Gi", 50},
+		{"250m", "1", 25},
+	}
+	for _, tt := range tests {
+		if got := quotaPercent(resource.MustParse(tt.used), resource.MustParse(tt.hard)); got != tt.want {
+			t.Errorf("quotaPercent(%s, %s) = %v, want %v", tt.used, tt.hard, got, tt.want)
+		}
+	}
+}
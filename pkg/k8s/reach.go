@@ -0,0 +1,82 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the reachability probe run before a status check: a
+// plain TCP connection to the API server, so a dead context fails within
+// about a second instead of waiting out the version check.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/debug"
+	"k8s.io/client-go/rest"
+)
+
+// DefaultReachTimeout bounds the reachability probe; see WithReachTimeout.
+const DefaultReachTimeout = time.Second
+
+// dialAPIServer opens the probe connection; a variable so tests can stub the
+// network.
+var dialAPIServer = func(ctx context.Context, address string) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", address)
+}
+
+// probeAPIServer checks that the API server of restConfig accepts a TCP
+// connection within timeout. It returns nil without dialling when timeout
+// is not positive, or when requests do not go straight to the server: through
+// a proxy or a custom dialer the direct connection says nothing about
+// whether the cluster can be reached.
+func probeAPIServer(ctx context.Context, restConfig *rest.Config, timeout time.Duration) error {
+	if timeout <= 0 || restConfig.Dial != nil {
+		return nil
+	}
+	address, ok := probeAddress(restConfig)
+	if !ok {
+		return nil
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := dialAPIServer(probeCtx, address)
+	if err != nil {
+		if probeCtx.Err() != nil && ctx.Err() == nil {
+			return fmt.Errorf("API server %s did not accept a connection within %s: %w", address, timeout, context.DeadlineExceeded)
+		}
+		return fmt.Errorf("API server %s is not reachable: %w", address, err)
+	}
+	_ = conn.Close()
+	debug.Logf(debug.K8s, "probe %s: connected in %s", address, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// probeAddress returns the host:port the API server of restConfig listens
+// on, and false when it cannot be probed directly.
+func probeAddress(restConfig *rest.Config) (string, bool) {
+	u, err := url.Parse(restConfig.Host)
+	if err != nil || u.Host == "" {
+		// A bare host:port, as rest.Config allows.
+		u, err = url.Parse("https://" + restConfig.Host)
+		if err != nil || u.Host == "" {
+			return "", false
+		}
+	}
+	if restConfig.Proxy != nil {
+		return "", false
+	}
+	if proxy, err := http.ProxyFromEnvironment(&http.Request{URL: u}); err != nil || proxy != nil {
+		return "", false
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), true
+}
@@ -0,0 +1,89 @@
+package k8s
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestProbeAddress(t *testing.T) {
+	for host, want := range map[string]string{
+		"https://10.0.0.1:6443":     "10.0.0.1:6443",
+		"https://api.example.com":   "api.example.com:443",
+		"http://127.0.0.1":          "127.0.0.1:80",
+		"https://[fd00::1]:6443/k8": "[fd00::1]:6443",
+		"10.0.0.2:6443":             "10.0.0.2:6443",
+	} {
+		if got, ok := probeAddress(&rest.Config{Host: host}); !ok || got != want {
+			t.Errorf("probeAddress(%q) = %q, %v; want %q", host, got, ok, want)
+		}
+	}
+	proxied := &rest.Config{Host: "https://10.0.0.1:6443", Proxy: func(*http.Request) (*url.URL, error) { return nil, nil }}
+	if _, ok := probeAddress(proxied); ok {
+		t.Error("a server behind a proxy should not be probed directly")
+	}
+}
+
+func TestProbeAPIServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := "https://" + listener.Addr().String()
+	ctx := context.Background()
+	if err := probeAPIServer(ctx, &rest.Config{Host: open}, time.Second); err != nil {
+		t.Errorf("probe of a listening server = %v", err)
+	}
+	_ = listener.Close()
+	if err := probeAPIServer(ctx, &rest.Config{Host: open}, time.Second); err == nil || !strings.Contains(err.Error(), "is not reachable") {
+		t.Errorf("probe of a closed port = %v, want not reachable", err)
+	}
+	if err := probeAPIServer(ctx, &rest.Config{Host: open}, 0); err != nil {
+		t.Errorf("a zero timeout should skip the probe, got %v", err)
+	}
+
+	original := dialAPIServer
+	t.Cleanup(func() { dialAPIServer = original })
+	dialAPIServer = func(ctx context.Context, _ string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	start := time.Now()
+	err = probeAPIServer(ctx, &rest.Config{Host: "https://10.255.0.1:6443"}, 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "did not accept a connection within 50ms") {
+		t.Errorf("probe of a silent server = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("probe took %s, want about the timeout", elapsed)
+	}
+}
+
+func TestClusterStatusFailsFastWhenUnreachable(t *testing.T) {
+	original := dialAPIServer
+	t.Cleanup(func() { dialAPIServer = original })
+	var dialled string
+	dialAPIServer = func(_ context.Context, address string) (net.Conn, error) {
+		dialled = address
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "dead.example.com", IsNotFound: true}}
+	}
+	provider, err := NewProvider(writeServerKubeconfig(t, "https://dead.example.com:6443"))
+	if err != nil {
+		t.Fatalf(errNewProviderFailed, err)
+	}
+	status, err := provider.GetClusterStatus(context.Background(), "local")
+	if err != nil {
+		t.Fatalf("GetClusterStatus() failed: %v", err)
+	}
+	if dialled != "dead.example.com:6443" || status.IsReachable || status.Transient || status.Failure != FailureDNS || !strings.Contains(status.Error, "Failed to reach cluster: API server dead.example.com:6443 is not reachable") {
+		t.Errorf("dialled %q, status = %+v", dialled, status)
+	}
+	if _, err := provider.GetServerVersion(context.Background(), "local"); err == nil {
+		t.Error("GetServerVersion() of an unreachable cluster succeeded")
+	}
+}
@@ -0,0 +1,88 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file helps recover from expired credentials without a restart: it
+// names the login that renews a context's credential plugin, and drops the
+// context's clients once the user has logged in again.
+package k8s
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/e9169/kopilot/pkg/debug"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// LoginCommand is a command renewing the credentials of a context.
+type LoginCommand struct {
+	// Args is the program and its arguments.
+	Args []string
+	// Env holds NAME=VALUE pairs added to the environment.
+	Env []string
+	// Plugin is set when Args runs the credential plugin itself, for plugins
+	// whose login kopilot does not know; it may prompt or open a browser.
+	Plugin bool
+}
+
+// String returns the command as it would be typed.
+func (l LoginCommand) String() string {
+	return strings.Join(l.Args, " ")
+}
+
+// loginCommand returns the login that renews the credentials of exec: the
+// cloud CLI's login for known plugins, and otherwise the plugin itself.
+func loginCommand(exec *clientcmdapi.ExecConfig) LoginCommand {
+	switch filepath.Base(exec.Command) {
+	case "aws", "aws-iam-authenticator":
+		login := LoginCommand{Args: []string{"aws", "sso", "login"}}
+		if i := slices.Index(exec.Args, "--profile"); i >= 0 && i+1 < len(exec.Args) {
+			login.Args = append(login.Args, "--profile", exec.Args[i+1])
+			return login
+		}
+		for _, env := range exec.Env {
+			if env.Name == "AWS_PROFILE" {
+				login.Args = append(login.Args, "--profile", env.Value)
+			}
+		}
+		return login
+	case "gke-gcloud-auth-plugin", "gcloud":
+		return LoginCommand{Args: []string{"gcloud", "auth", "login"}}
+	case "kubelogin", "az":
+		return LoginCommand{Args: []string{"az", "login"}}
+	}
+	login := LoginCommand{Args: append([]string{exec.Command}, exec.Args...), Plugin: true}
+	for _, env := range exec.Env {
+		login.Env = append(login.Env, env.Name+"="+env.Value)
+	}
+	return login
+}
+
+// LoginCommand returns the login renewing the credentials of contextName's
+// credential plugin, and false when its user has no plugin: static tokens and
+// certificates are renewed in the kubeconfig, then picked up with Reload.
+func (p *Provider) LoginCommand(contextName string) (LoginCommand, bool, error) {
+	config := p.kubeconfig()
+	context, ok := config.Contexts[contextName]
+	if !ok {
+		return LoginCommand{}, false, fmt.Errorf("context %q not found", contextName)
+	}
+	user := config.AuthInfos[context.AuthInfo]
+	if user == nil || user.Exec == nil {
+		return LoginCommand{}, false, nil
+	}
+	return loginCommand(user.Exec), true, nil
+}
+
+// Reauthenticate drops the clients and cached status of contextName after
+// the user logged in again, so the next request runs the credential plugin
+// for fresh credentials.
+func (p *Provider) Reauthenticate(contextName string) error {
+	c, err := p.ForContext(contextName)
+	if err != nil {
+		return err
+	}
+	c.invalidate()
+	debug.Logf(debug.K8s, "context %s: credentials renewed", contextName)
+	return nil
+}
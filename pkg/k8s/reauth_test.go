@@ -0,0 +1,108 @@
+package k8s
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// writeExecKubeconfig writes a kubeconfig whose "local" context points at
+// server with credentials from the exec plugin command.
+func writeExecKubeconfig(t *testing.T, server string, exec *clientcmdapi.ExecConfig) string {
+	t.Helper()
+	config := clientcmdapi.NewConfig()
+	config.Clusters["local"] = &clientcmdapi.Cluster{Server: server, InsecureSkipTLSVerify: true}
+	config.AuthInfos["user"] = &clientcmdapi.AuthInfo{Exec: exec}
+	config.Contexts["local"] = &clientcmdapi.Context{Cluster: "local", AuthInfo: "user"}
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoginCommand(t *testing.T) {
+	for want, exec := range map[string]*clientcmdapi.ExecConfig{
+		"aws sso login --profile prod": {Command: "aws", Args: []string{"eks", "get-token", "--profile", "prod"}},
+		"gcloud auth login":            {Command: "gke-gcloud-auth-plugin"},
+		"kubectl oidc-login get-token": {Command: "kubectl", Args: []string{"oidc-login", "get-token"}},
+	} {
+		provider, err := NewProvider(writeExecKubeconfig(t, "https://127.0.0.1:6443", exec))
+		if err != nil {
+			t.Fatalf(errNewProviderFailed, err)
+		}
+		login, ok, err := provider.LoginCommand("local")
+		if err != nil || !ok || login.String() != want || login.Plugin != (exec.Command == "kubectl") {
+			t.Errorf("LoginCommand() = %+v, %v, %v; want %q", login, ok, err, want)
+		}
+	}
+
+	provider, err := NewProvider(writeServerKubeconfig(t, "https://127.0.0.1:6443"))
+	if err != nil {
+		t.Fatalf(errNewProviderFailed, err)
+	}
+	if _, ok, err := provider.LoginCommand("local"); ok || err != nil {
+		t.Errorf("LoginCommand() of a token user = %v, %v; want no plugin", ok, err)
+	}
+	if _, _, err := provider.LoginCommand("missing"); err == nil {
+		t.Error("LoginCommand() of an unknown context succeeded")
+	}
+}
+
+func TestStatusRetriesRejectedPluginCredentials(t *testing.T) {
+	// The plugin hands out a new token on each run; the API server rejects
+	// the first one, as it would a token cached past its expiry.
+	dir := t.TempDir()
+	plugin := filepath.Join(dir, "plugin.sh")
+	script := "#!/bin/sh\necho x >> " + filepath.Join(dir, "runs") + "\n" +
+		`echo '{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":{"token":"'$(wc -l < ` + filepath.Join(dir, "runs") + ` | tr -d ' ')'"}}'` + "\n"
+	if err := os.WriteFile(plugin, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	var rejected atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer 1" {
+			rejected.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Unauthorized","code":401}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/version" {
+			_, _ = w.Write([]byte(`{"major": "1", "minor": "33", "gitVersion": "v1.33.1"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"kind":"List","apiVersion":"v1","items":[]}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewProvider(writeExecKubeconfig(t, server.URL, &clientcmdapi.ExecConfig{
+		Command: plugin, APIVersion: "client.authentication.k8s.io/v1", InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+	}))
+	if err != nil {
+		t.Fatalf(errNewProviderFailed, err)
+	}
+	status, err := provider.GetFilteredClusterStatus(context.Background(), "local", HealthFilter{Namespace: "default"})
+	if err != nil {
+		t.Fatalf("GetFilteredClusterStatus() failed: %v", err)
+	}
+	if rejected.Load() != 1 || !status.IsReachable || status.Version != "v1.33.1" || status.Error != "" {
+		t.Errorf("status after a rejected token = reachable %v, version %q, error %q; %d rejection(s)", status.IsReachable, status.Version, status.Error, rejected.Load())
+	}
+
+	if err := provider.Reauthenticate("local"); err != nil {
+		t.Errorf("Reauthenticate() failed: %v", err)
+	}
+	if err := provider.Reauthenticate("missing"); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Reauthenticate() of an unknown context = %v", err)
+	}
+}
@@ -0,0 +1,318 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains a minimal anonymous OCI registry client for image metadata.
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	dockerHubRegistry = "docker.io"
+	dockerHubAPIHost  = "registry-1.docker.io"
+
+	mediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+	mediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+
+	// maxRegistryResponse caps manifest and config downloads.
+	maxRegistryResponse = 4 << 20
+)
+
+// registryHTTPClient is used for registry requests. It is a variable so tests
+// can point it at a local TLS server.
+var registryHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// ImageReference is a parsed container image name.
+type ImageReference struct {
+	Registry   string `json:"registry"`
+	Repository string `json:"repository"`
+	Tag        string `json:"tag,omitempty"`
+	Digest     string `json:"digest,omitempty"`
+}
+
+// String renders the reference in its fully qualified form.
+func (r ImageReference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// ParseImageReference parses an image name the way container runtimes do:
+// a first component with a dot, a colon or "localhost" is the registry,
+// otherwise the image lives on Docker Hub (under library/ for single names).
+// A missing tag and digest means "latest".
+func ParseImageReference(image string) (ImageReference, error) {
+	var ref ImageReference
+	if image == "" || strings.ContainsAny(image, " \t\n") {
+		return ref, fmt.Errorf("invalid image reference %q", image)
+	}
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+		if !strings.Contains(ref.Digest, ":") {
+			return ref, fmt.Errorf("invalid digest in image reference %q", image)
+		}
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+
+	ref.Registry = dockerHubRegistry
+	if first, rest, ok := strings.Cut(name, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry = first
+		name = rest
+	}
+	if ref.Registry == "index.docker.io" {
+		ref.Registry = dockerHubRegistry
+	}
+	if ref.Registry == dockerHubRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	if name == "" || name != strings.ToLower(name) {
+		return ref, fmt.Errorf("invalid repository in image reference %q", image)
+	}
+	ref.Repository = name
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	return ref, nil
+}
+
+// registryAPIHost returns the host serving the registry API.
+func registryAPIHost(registry string) string {
+	if registry == dockerHubRegistry {
+		return dockerHubAPIHost
+	}
+	return registry
+}
+
+// registryClient fetches manifests and blobs anonymously, following the
+// bearer token challenge used by Docker Hub, GHCR, Quay and most others.
+type registryClient struct {
+	httpClient *http.Client
+	token      string
+}
+
+// get issues a GET against the registry API, obtaining an anonymous pull
+// token on a 401 challenge. It returns the body and response headers.
+func (c *registryClient) get(ctx context.Context, ref ImageReference, path string, accept []string) ([]byte, http.Header, error) {
+	target := "https://" + registryAPIHost(ref.Registry) + "/v2/" + ref.Repository + path
+	for attempt := 0; attempt < 2; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(accept) > 0 {
+			req.Header.Set("Accept", strings.Join(accept, ", "))
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("registry request failed: %w", err)
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxRegistryResponse))
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read registry response: %w", err)
+		}
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return body, resp.Header, nil
+		case resp.StatusCode == http.StatusUnauthorized && attempt == 0 && c.token == "":
+			token, err := c.fetchToken(ctx, resp.Header.Get("WWW-Authenticate"), ref.Repository)
+			if err != nil {
+				return nil, nil, err
+			}
+			c.token = token
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			return nil, nil, fmt.Errorf("registry %s requires credentials for %s (private image?)", ref.Registry, ref.Repository)
+		default:
+			return nil, nil, fmt.Errorf("registry returned %s for %s", resp.Status, path)
+		}
+	}
+	return nil, nil, fmt.Errorf("registry %s requires credentials for %s (private image?)", ref.Registry, ref.Repository)
+}
+
+// parseAuthChallenge extracts the parameters of a Bearer WWW-Authenticate header.
+func parseAuthChallenge(header string) (map[string]string, bool) {
+	scheme, rest, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for _, part := range strings.Split(rest, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			params[strings.ToLower(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return params, params["realm"] != ""
+}
+
+// fetchToken requests an anonymous pull token from the challenge realm.
+func (c *registryClient) fetchToken(ctx context.Context, challenge, repository string) (string, error) {
+	params, ok := parseAuthChallenge(challenge)
+	if !ok {
+		return "", fmt.Errorf("registry requires credentials (challenge %q)", challenge)
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Scheme != "https" {
+		return "", fmt.Errorf("registry token realm %q is not an https URL", params["realm"])
+	}
+	q := realm.Query()
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	q.Set("scope", "repository:"+repository+":pull")
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("registry token request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry token request returned %s (private image?)", resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxRegistryResponse)).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse registry token: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// ociManifest covers the fields of image indexes and manifests kopilot reads.
+type ociManifest struct {
+	MediaType string `json:"mediaType"`
+	Config    struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+		} `json:"platform"`
+	} `json:"manifests"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// ociImageConfig covers the fields of an image config blob kopilot reads.
+type ociImageConfig struct {
+	Created      string `json:"created"`
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Config       struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+}
+
+// RegistryImage is what the registry reports about one image tag.
+type RegistryImage struct {
+	Digest         string            `json:"digest"`                    // digest the reference points to (index or manifest)
+	PlatformDigest string            `json:"platform_digest,omitempty"` // manifest inspected within a multi-arch index
+	Platform       string            `json:"platform,omitempty"`        // os/arch of the inspected manifest
+	Created        string            `json:"created,omitempty"`         // image build time
+	Labels         map[string]string `json:"labels,omitempty"`          // config labels and manifest annotations
+	IsMultiArch    bool              `json:"multi_arch"`
+}
+
+// lookupRegistryImage resolves ref (by digest if set, else by tag) and reads
+// the config of the manifest for arch (amd64 when empty) to get the build
+// time and labels.
+func lookupRegistryImage(ctx context.Context, httpClient *http.Client, ref ImageReference, arch string) (*RegistryImage, error) {
+	c := &registryClient{httpClient: httpClient}
+	reference := ref.Digest
+	if reference == "" {
+		reference = ref.Tag
+	}
+	accept := []string{mediaTypeOCIIndex, mediaTypeOCIManifest, mediaTypeDockerManifestList, mediaTypeDockerManifest}
+	body, header, err := c.get(ctx, ref, "/manifests/"+reference, accept)
+	if err != nil {
+		return nil, err
+	}
+	var manifest ociManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	image := &RegistryImage{Digest: header.Get("Docker-Content-Digest"), Labels: make(map[string]string)}
+	if image.Digest == "" && ref.Digest != "" {
+		image.Digest = ref.Digest
+	}
+	for k, v := range manifest.Annotations {
+		image.Labels[k] = v
+	}
+
+	if len(manifest.Manifests) > 0 {
+		image.IsMultiArch = true
+		if arch == "" {
+			arch = "amd64"
+		}
+		chosen := manifest.Manifests[0].Digest
+		for _, m := range manifest.Manifests {
+			if m.Platform.OS == "linux" && m.Platform.Architecture == arch {
+				chosen = m.Digest
+				break
+			}
+		}
+		image.PlatformDigest = chosen
+		body, _, err = c.get(ctx, ref, "/manifests/"+chosen, accept)
+		if err != nil {
+			return nil, err
+		}
+		manifest = ociManifest{}
+		if err := json.Unmarshal(body, &manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		for k, v := range manifest.Annotations {
+			image.Labels[k] = v
+		}
+	}
+	if manifest.Config.Digest == "" {
+		return image, nil
+	}
+
+	body, _, err = c.get(ctx, ref, "/blobs/"+manifest.Config.Digest, nil)
+	if err != nil {
+		return nil, err
+	}
+	var config ociImageConfig
+	if err := json.Unmarshal(body, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse image config: %w", err)
+	}
+	image.Created = config.Created
+	if config.OS != "" {
+		image.Platform = config.OS + "/" + config.Architecture
+	}
+	for k, v := range config.Config.Labels {
+		image.Labels[k] = v
+	}
+	return image, nil
+}
@@ -0,0 +1,120 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseImageReference(t *testing.T) {
+	cases := []struct {
+		image string
+		want  ImageReference
+	}{
+		{"nginx", ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}},
+		{"nginx:1.25", ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25"}},
+		{"bitnami/redis:7", ImageReference{Registry: "docker.io", Repository: "bitnami/redis", Tag: "7"}},
+		{"index.docker.io/library/nginx:1", ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "1"}},
+		{"ghcr.io/org/api:v1.4.2", ImageReference{Registry: "ghcr.io", Repository: "org/api", Tag: "v1.4.2"}},
+		{"localhost:5000/app", ImageReference{Registry: "localhost:5000", Repository: "app", Tag: "latest"}},
+		{"quay.io/org/app@sha256:abc", ImageReference{Registry: "quay.io", Repository: "org/app", Digest: "sha256:abc"}},
+		{"quay.io/org/app:1@sha256:abc", ImageReference{Registry: "quay.io", Repository: "org/app", Tag: "1", Digest: "sha256:abc"}},
+	}
+	for _, tc := range cases {
+		got, err := ParseImageReference(tc.image)
+		if err != nil {
+			t.Errorf("ParseImageReference(%q): %v", tc.image, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseImageReference(%q) = %+v, want %+v", tc.image, got, tc.want)
+		}
+	}
+	for _, bad := range []string{"", "Nginx", "app@abc", "a b"} {
+		if _, err := ParseImageReference(bad); err == nil {
+			t.Errorf("ParseImageReference(%q) succeeded, want an error", bad)
+		}
+	}
+	if s := (ImageReference{Registry: "ghcr.io", Repository: "org/api", Tag: "1", Digest: "sha256:abc"}).String(); s != "ghcr.io/org/api:1@sha256:abc" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestParseAuthChallenge(t *testing.T) {
+	params, ok := parseAuthChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io"`)
+	if !ok || params["realm"] != "https://auth.docker.io/token" || params["service"] != "registry.docker.io" {
+		t.Errorf("params = %v, ok = %v", params, ok)
+	}
+	if _, ok := parseAuthChallenge(`Basic realm="registry"`); ok {
+		t.Error("Basic challenge accepted, want only Bearer")
+	}
+}
+
+// fakeRegistry serves a multi-arch image behind an anonymous token challenge.
+func fakeRegistry(t *testing.T) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:org/api:pull" {
+				http.Error(w, "bad scope", http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"token": "anon"})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer anon" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/org/api/manifests/1.4", "/v2/org/api/manifests/sha256:index":
+			w.Header().Set("Docker-Content-Digest", "sha256:index")
+			_, _ = w.Write([]byte(`{"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[
+				{"digest":"sha256:arm","platform":{"os":"linux","architecture":"arm64"}},
+				{"digest":"sha256:amd","platform":{"os":"linux","architecture":"amd64"}}]}`))
+		case "/v2/org/api/manifests/sha256:amd":
+			w.Header().Set("Docker-Content-Digest", "sha256:amd")
+			_, _ = w.Write([]byte(`{"config":{"digest":"sha256:cfg"},"annotations":{"org.opencontainers.image.source":"https://github.com/org/api"}}`))
+		case "/v2/org/api/manifests/sha256:old":
+			w.Header().Set("Docker-Content-Digest", "sha256:old")
+			_, _ = w.Write([]byte(`{"config":{"digest":"sha256:oldcfg"}}`))
+		case "/v2/org/api/blobs/sha256:cfg":
+			_, _ = w.Write([]byte(`{"created":"2026-05-01T10:00:00Z","os":"linux","architecture":"amd64","config":{"Labels":{"org.opencontainers.image.revision":"abc123","org.opencontainers.image.version":"1.4.0"}}}`))
+		case "/v2/org/api/blobs/sha256:oldcfg":
+			_, _ = w.Write([]byte(`{"created":"2026-01-01T10:00:00Z","os":"linux","architecture":"amd64","config":{"Labels":{"org.label-schema.vcs-ref":"0ld"}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestLookupRegistryImage(t *testing.T) {
+	srv := fakeRegistry(t)
+	ref := ImageReference{Registry: strings.TrimPrefix(srv.URL, "https://"), Repository: "org/api", Tag: "1.4"}
+
+	image, err := lookupRegistryImage(context.Background(), srv.Client(), ref, "")
+	if err != nil {
+		t.Fatalf("lookupRegistryImage: %v", err)
+	}
+	if image.Digest != "sha256:index" || image.PlatformDigest != "sha256:amd" || !image.IsMultiArch {
+		t.Errorf("image = %+v, want the index resolved to the amd64 manifest", image)
+	}
+	if image.Created != "2026-05-01T10:00:00Z" || image.Platform != "linux/amd64" {
+		t.Errorf("image = %+v, want config metadata", image)
+	}
+	if image.Labels["org.opencontainers.image.revision"] != "abc123" || image.Labels["org.opencontainers.image.source"] != "https://github.com/org/api" {
+		t.Errorf("Labels = %v, want config labels merged with manifest annotations", image.Labels)
+	}
+
+	ref.Repository = "org/missing"
+	if _, err := lookupRegistryImage(context.Background(), srv.Client(), ref, ""); err == nil {
+		t.Error("lookup of a missing repository succeeded, want an error")
+	}
+}
@@ -0,0 +1,104 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file reloads the kubeconfig, so contexts added or credentials rotated
+// while kopilot runs are picked up without a restart.
+package k8s
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/e9169/kopilot/pkg/debug"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ReloadResult lists the contexts a kubeconfig reload added, removed and
+// changed, each sorted by name.
+type ReloadResult struct {
+	Added   []string
+	Removed []string
+	// Changed are the contexts whose context, cluster or user entry
+	// changed; their clients and cached status were dropped.
+	Changed []string
+}
+
+// Reload re-reads the kubeconfig file. Contexts whose entries changed get new
+// clients and transports on next use, removed contexts lose their clients
+// and caches, and unchanged contexts keep theirs. The current context is
+// kept while it still exists. Providers created from an in-memory kubeconfig
+// have nothing to reload.
+func (p *Provider) Reload() (*ReloadResult, error) {
+	if p.kubeconfigPath == "" {
+		return nil, fmt.Errorf("the kubeconfig was not read from a file")
+	}
+	rawConfig, err := clientcmd.LoadFromFile(p.kubeconfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	p.clustersMutex.Lock()
+	current := p.currentContext
+	clusters := parseClusters(rawConfig, current)
+	if _, ok := clusters[current]; !ok {
+		current = rawConfig.CurrentContext
+		clusters = parseClusters(rawConfig, current)
+	}
+	result := &ReloadResult{}
+	for name := range clusters {
+		if _, ok := p.clusters[name]; !ok {
+			result.Added = append(result.Added, name)
+		} else if contextChanged(p.rawConfig, rawConfig, name) {
+			result.Changed = append(result.Changed, name)
+		}
+	}
+	for name := range p.clusters {
+		if _, ok := clusters[name]; !ok {
+			result.Removed = append(result.Removed, name)
+		}
+	}
+	p.rawConfig, p.clusters, p.currentContext = rawConfig, clusters, current
+	p.clustersMutex.Unlock()
+	p.cacheGeneration.Add(1)
+
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+	sort.Strings(result.Changed)
+
+	stale := append(append([]string{}, result.Removed...), result.Changed...)
+	p.scopesMutex.Lock()
+	for _, name := range result.Removed {
+		if c, ok := p.scopes[name]; ok {
+			c.invalidate()
+			delete(p.scopes, name)
+		}
+	}
+	for _, name := range result.Changed {
+		if c, ok := p.scopes[name]; ok {
+			c.invalidate()
+		}
+	}
+	p.scopesMutex.Unlock()
+
+	p.discoveryMutex.Lock()
+	for _, name := range stale {
+		delete(p.discovery, name)
+	}
+	p.discoveryMutex.Unlock()
+
+	debug.Logf(debug.Cache, "kubeconfig reloaded: %d added, %d removed, %d changed",
+		len(result.Added), len(result.Removed), len(result.Changed))
+	return result, nil
+}
+
+// contextChanged reports whether the context entry of name, or the cluster or
+// user entry it refers to, differs between two kubeconfigs.
+func contextChanged(old, updated *clientcmdapi.Config, name string) bool {
+	before, after := old.Contexts[name], updated.Contexts[name]
+	if before == nil || after == nil {
+		return before != after
+	}
+	return !reflect.DeepEqual(before, after) ||
+		!reflect.DeepEqual(old.Clusters[before.Cluster], updated.Clusters[after.Cluster]) ||
+		!reflect.DeepEqual(old.AuthInfos[before.AuthInfo], updated.AuthInfos[after.AuthInfo])
+}
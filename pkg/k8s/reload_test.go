@@ -0,0 +1,83 @@
+package k8s
+
+import (
+	"slices"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestReload(t *testing.T) {
+	kubeconfigPath, cleanup := createTempKubeconfig(t, 3)
+	defer cleanup()
+	provider, err := NewProvider(kubeconfigPath)
+	if err != nil {
+		t.Fatalf(errNewProvider, err)
+	}
+	for _, name := range []string{testContext1, testContext2, "context-3"} {
+		if _, err := provider.Clientset(name); err != nil {
+			t.Fatalf("Clientset(%s) failed: %v", name, err)
+		}
+		provider.cacheStatus(name, &ClusterStatus{Version: testClusterVersion})
+	}
+	unchanged, _ := provider.ForContext("context-3")
+	unchangedClientset, _ := unchanged.Clientset()
+	if err := provider.SetCurrentContext(testContext2); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := clientcmd.LoadFromFile(kubeconfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Clusters["cluster-1"].Server = "https://rotated.example.com"
+	delete(config.Contexts, testContext2)
+	config.Clusters["cluster-4"] = &clientcmdapi.Cluster{Server: "https://cluster-4.example.com"}
+	config.Contexts["context-4"] = &clientcmdapi.Context{Cluster: "cluster-4", AuthInfo: "user-1"}
+	if err := clientcmd.WriteToFile(*config, kubeconfigPath); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := provider.Reload()
+	if err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if !slices.Equal(result.Added, []string{"context-4"}) ||
+		!slices.Equal(result.Removed, []string{testContext2}) ||
+		!slices.Equal(result.Changed, []string{testContext1}) {
+		t.Errorf("Reload() = %+v", result)
+	}
+
+	if got := provider.GetCurrentContext(); got != testContext1 {
+		t.Errorf("current context = %q, want the kubeconfig's %q once %s is gone", got, testContext1, testContext2)
+	}
+	if _, err := provider.GetClusterByContext(testContext2); err == nil {
+		t.Error("removed context should be gone")
+	}
+	if info, _ := provider.GetClusterByContext(testContext1); info == nil || info.Server != "https://rotated.example.com" || !info.IsCurrent {
+		t.Errorf("changed context = %+v", info)
+	}
+	changed, _ := provider.ForContext(testContext1)
+	if changed.clientset != nil || provider.getCachedStatus(testContext1) != nil {
+		t.Error("changed context should lose its clients and cached status")
+	}
+	if again, _ := unchanged.Clientset(); again != unchangedClientset || provider.getCachedStatus("context-3") == nil {
+		t.Error("unchanged context should keep its clients and cached status")
+	}
+
+	result, err = provider.Reload()
+	if err != nil || len(result.Added)+len(result.Removed)+len(result.Changed) != 0 {
+		t.Errorf("second Reload() = %+v, %v; want no changes", result, err)
+	}
+}
+
+func TestReloadInMemoryConfig(t *testing.T) {
+	provider, err := NewProviderFromConfig(clientcmdapi.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Reload(); err == nil {
+		t.Error("Reload() of an in-memory kubeconfig should fail")
+	}
+}
@@ -0,0 +1,276 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the failure-domain (resilience) report for replicated workloads.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Resilience penalties are subtracted from 100 and graded with gradeFromScore.
+// A single replica alone lands in D; losing a zone or node taking out every
+// replica is almost as bad.
+const (
+	resiliencePenaltySingleReplica = 50
+	resiliencePenaltySingleNode    = 40
+	resiliencePenaltySingleZone    = 30
+	resiliencePenaltyNoPDB         = 15
+	resiliencePenaltyNoSpread      = 10
+	resiliencePenaltyPDBBlocks     = 5
+)
+
+// ResilienceFinding is one resilience problem found on a workload.
+type ResilienceFinding struct {
+	RuleID   string           `json:"rule_id"`
+	Severity SanitizeSeverity `json:"severity"`
+	Message  string           `json:"message"`
+	Penalty  int              `json:"penalty"`
+}
+
+// WorkloadResilience summarises how well one workload survives node and zone loss.
+type WorkloadResilience struct {
+	Namespace      string              `json:"namespace"`
+	Kind           string              `json:"kind"`
+	Name           string              `json:"name"`
+	Replicas       int32               `json:"replicas"`
+	ReadyReplicas  int32               `json:"ready_replicas"`
+	Zones          map[string]int      `json:"zones"` // zone -> running pods; "" when nodes are unlabelled
+	Nodes          int                 `json:"nodes"`
+	AntiAffinity   bool                `json:"anti_affinity"`
+	TopologySpread bool                `json:"topology_spread"`
+	PDB            string              `json:"pdb,omitempty"`
+	Score          int                 `json:"score"`
+	Grade          string              `json:"grade"`
+	Findings       []ResilienceFinding `json:"findings"`
+}
+
+// ResilienceReport is the failure-domain report for a cluster.
+type ResilienceReport struct {
+	Context   string               `json:"context"`
+	Regions   []string             `json:"regions"`
+	Zones     map[string]int       `json:"zones"` // zone -> ready nodes
+	Grades    map[string]int       `json:"grades"`
+	Workloads []WorkloadResilience `json:"workloads"`
+}
+
+// resilienceWorkload is the subset of a Deployment or StatefulSet the report needs.
+type resilienceWorkload struct {
+	namespace, kind, name string
+	replicas, ready       int32
+	selector              *metav1.LabelSelector
+	template              corev1.PodTemplateSpec
+}
+
+func deploymentWorkload(d *appsv1.Deployment) resilienceWorkload {
+	return resilienceWorkload{d.Namespace, "Deployment", d.Name, replicasOrDefault(d.Spec.Replicas), d.Status.ReadyReplicas, d.Spec.Selector, d.Spec.Template}
+}
+
+func statefulSetWorkload(s *appsv1.StatefulSet) resilienceWorkload {
+	return resilienceWorkload{s.Namespace, "StatefulSet", s.Name, replicasOrDefault(s.Spec.Replicas), s.Status.ReadyReplicas, s.Spec.Selector, s.Spec.Template}
+}
+
+func replicasOrDefault(r *int32) int32 {
+	if r == nil {
+		return 1
+	}
+	return *r
+}
+
+// hasPodAntiAffinity reports whether spec asks to keep its replicas apart.
+func hasPodAntiAffinity(spec corev1.PodSpec) bool {
+	a := spec.Affinity
+	if a == nil || a.PodAntiAffinity == nil {
+		return false
+	}
+	return len(a.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) > 0 ||
+		len(a.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution) > 0
+}
+
+// matchingPDB returns the PDB in pdbs selecting pods with podLabels, if any.
+func matchingPDB(pdbs []policyv1.PodDisruptionBudget, namespace string, podLabels map[string]string) *policyv1.PodDisruptionBudget {
+	for i := range pdbs {
+		pdb := &pdbs[i]
+		if pdb.Namespace != namespace || pdb.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() {
+			continue
+		}
+		if selector.Matches(labels.Set(podLabels)) {
+			return pdb
+		}
+	}
+	return nil
+}
+
+// assessWorkload builds the resilience entry for w from its pods, the
+// node-to-zone map, and the PDBs in the cluster. clusterZones is the number
+// of zones with ready nodes; zone spread is only expected when it exceeds one.
+func assessWorkload(w resilienceWorkload, pods []corev1.Pod, nodeZones map[string]string, clusterZones int, pdbs []policyv1.PodDisruptionBudget) WorkloadResilience {
+	r := WorkloadResilience{
+		Namespace:      w.namespace,
+		Kind:           w.kind,
+		Name:           w.name,
+		Replicas:       w.replicas,
+		ReadyReplicas:  w.ready,
+		Zones:          map[string]int{},
+		AntiAffinity:   hasPodAntiAffinity(w.template.Spec),
+		TopologySpread: len(w.template.Spec.TopologySpreadConstraints) > 0,
+		Findings:       []ResilienceFinding{},
+	}
+
+	nodes := map[string]bool{}
+	if selector, err := metav1.LabelSelectorAsSelector(w.selector); err == nil && !selector.Empty() {
+		for i := range pods {
+			pod := &pods[i]
+			if pod.Namespace != w.namespace || pod.Spec.NodeName == "" || pod.Status.Phase != corev1.PodRunning ||
+				!selector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+			nodes[pod.Spec.NodeName] = true
+			r.Zones[nodeZones[pod.Spec.NodeName]]++
+		}
+	}
+	r.Nodes = len(nodes)
+
+	add := func(id string, sev SanitizeSeverity, penalty int, format string, args ...any) {
+		r.Findings = append(r.Findings, ResilienceFinding{RuleID: id, Severity: sev, Message: fmt.Sprintf(format, args...), Penalty: penalty})
+	}
+
+	if w.replicas <= 1 {
+		add("RES-001", SanitizeCritical, resiliencePenaltySingleReplica, "%d replica(s) — any pod restart or node loss causes an outage", w.replicas)
+	} else {
+		if r.Nodes == 1 {
+			add("RES-002", SanitizeCritical, resiliencePenaltySingleNode, "all running replicas are on one node")
+		}
+		if _, unlabelled := r.Zones[""]; clusterZones > 1 && len(r.Zones) == 1 && !unlabelled {
+			add("RES-003", SanitizeCritical, resiliencePenaltySingleZone, "all running replicas are in one zone of %d", clusterZones)
+		}
+		if !r.AntiAffinity && !r.TopologySpread {
+			add("RES-004", SanitizeMajor, resiliencePenaltyNoSpread, "no pod anti-affinity or topology spread constraints; the scheduler may co-locate replicas")
+		}
+	}
+
+	if pdb := matchingPDB(pdbs, w.namespace, w.template.Labels); pdb == nil {
+		if w.replicas > 1 {
+			add("RES-005", SanitizeMajor, resiliencePenaltyNoPDB, "no PodDisruptionBudget; node drains may evict all replicas at once")
+		}
+	} else {
+		r.PDB = pdb.Name
+		if pdb.Status.DisruptionsAllowed == 0 && w.ready >= w.replicas {
+			add("RES-006", SanitizeMinor, resiliencePenaltyPDBBlocks, "PodDisruptionBudget %s allows no disruptions even when healthy; node drains will block", pdb.Name)
+		}
+	}
+
+	penalty := 0
+	for _, f := range r.Findings {
+		penalty += f.Penalty
+	}
+	r.Score = max(100-penalty, 0)
+	r.Grade = gradeFromScore(r.Score)
+	return r
+}
+
+// collectResilienceReport lists nodes, workloads, pods and PDBs and grades
+// every Deployment and StatefulSet in scope. DaemonSets are skipped: they
+// run one pod per node by design.
+func collectResilienceReport(ctx context.Context, clientset kubernetes.Interface, targetNamespace string, includeSystem bool) (*ResilienceReport, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	deploys, err := clientset.AppsV1().Deployments(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	statefulSets, err := clientset.AppsV1().StatefulSets(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	pods, err := clientset.CoreV1().Pods(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	pdbs, err := clientset.PolicyV1().PodDisruptionBudgets(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pod disruption budgets: %w", err)
+	}
+
+	report := &ResilienceReport{Regions: []string{}, Zones: map[string]int{}, Grades: map[string]int{}, Workloads: []WorkloadResilience{}}
+	nodeZones := make(map[string]string, len(nodes.Items))
+	regions := map[string]bool{}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		zone := node.Labels[corev1.LabelTopologyZone]
+		nodeZones[node.Name] = zone
+		if region := node.Labels[corev1.LabelTopologyRegion]; region != "" {
+			regions[region] = true
+		}
+		if zone != "" && isNodeReady(node) {
+			report.Zones[zone]++
+		}
+	}
+	for region := range regions {
+		report.Regions = append(report.Regions, region)
+	}
+	sort.Strings(report.Regions)
+
+	var workloads []resilienceWorkload
+	for i := range deploys.Items {
+		workloads = append(workloads, deploymentWorkload(&deploys.Items[i]))
+	}
+	for i := range statefulSets.Items {
+		workloads = append(workloads, statefulSetWorkload(&statefulSets.Items[i]))
+	}
+	for _, w := range workloads {
+		// Scaled-to-zero workloads are intentionally offline.
+		if w.replicas == 0 || !shouldScanNamespace(w.namespace, targetNamespace, includeSystem) {
+			continue
+		}
+		entry := assessWorkload(w, pods.Items, nodeZones, len(report.Zones), pdbs.Items)
+		report.Grades[entry.Grade]++
+		report.Workloads = append(report.Workloads, entry)
+	}
+
+	// Worst first, then by name for stable output.
+	sort.Slice(report.Workloads, func(a, b int) bool {
+		wa, wb := report.Workloads[a], report.Workloads[b]
+		if wa.Score != wb.Score {
+			return wa.Score < wb.Score
+		}
+		if wa.Namespace != wb.Namespace {
+			return wa.Namespace < wb.Namespace
+		}
+		return wa.Name < wb.Name
+	})
+	return report, nil
+}
+
+// GetResilienceReport grades Deployments and StatefulSets on replica count,
+// node/zone spread, anti-affinity or topology spread, and PDB coverage.
+// An empty namespace scans all non-system namespaces unless includeSystem is set.
+func (p *Provider) GetResilienceReport(ctx context.Context, contextName, namespace string, includeSystem bool) (*ResilienceReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	report, err := collectResilienceReport(queryCtx, clientset, namespace, includeSystem)
+	if err != nil {
+		return nil, err
+	}
+	report.Context = contextName
+	return report, nil
+}
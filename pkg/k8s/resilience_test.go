@@ -0,0 +1,141 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func zoneNode(name, zone string) *corev1.Node {
+	node := osNode(name, OSLinux, true)
+	node.Labels[corev1.LabelTopologyZone] = zone
+	node.Labels[corev1.LabelTopologyRegion] = "eu-west-1"
+	return &node
+}
+
+func appPod(namespace, name, app, node string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app": app}},
+		Spec:       corev1.PodSpec{NodeName: node},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func appDeployment(namespace, name string, replicas int32, spec corev1.PodSpec) *appsv1.Deployment {
+	selector := map[string]string{"app": name}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: selector}, Spec: spec},
+		},
+		Status: appsv1.DeploymentStatus{ReadyReplicas: replicas},
+	}
+}
+
+func appPDB(name, app string, allowed int32) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}},
+		Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: allowed},
+	}
+}
+
+func TestCollectResilienceReport(t *testing.T) {
+	spread := corev1.PodSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{TopologyKey: corev1.LabelTopologyZone, MaxSkew: 1}}}
+	antiAffinity := corev1.PodSpec{Affinity: &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{Weight: 100}},
+	}}}
+	dbReplicas := int32(3)
+	db := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "apps"},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &dbReplicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "db"}}, Spec: antiAffinity},
+		},
+		Status: appsv1.StatefulSetStatus{ReadyReplicas: 3},
+	}
+
+	objects := []runtime.Object{
+		zoneNode("node-a", "eu-west-1a"), zoneNode("node-b", "eu-west-1b"), zoneNode("node-c", "eu-west-1c"),
+		appDeployment("apps", "solo", 1, corev1.PodSpec{}),
+		appPod("apps", "solo-1", "solo", "node-a"),
+		appDeployment("apps", "web", 3, spread),
+		appPod("apps", "web-1", "web", "node-a"), appPod("apps", "web-2", "web", "node-b"), appPod("apps", "web-3", "web", "node-c"),
+		appPDB("web", "web", 1),
+		appDeployment("apps", "api", 2, corev1.PodSpec{}),
+		appPod("apps", "api-1", "api", "node-a"), appPod("apps", "api-2", "api", "node-a"),
+		db,
+		appPod("apps", "db-0", "db", "node-a"), appPod("apps", "db-1", "db", "node-b"), appPod("apps", "db-2", "db", "node-b"),
+		appPDB("db", "db", 0),
+		appDeployment("apps", "paused", 0, corev1.PodSpec{}),
+		appDeployment("kube-system", "coredns", 1, corev1.PodSpec{}),
+	}
+	report, err := collectResilienceReport(context.Background(), fake.NewClientset(objects...), "", false)
+	if err != nil {
+		t.Fatalf("collectResilienceReport error: %v", err)
+	}
+
+	if len(report.Zones) != 3 || len(report.Regions) != 1 {
+		t.Errorf("zones/regions = %v/%v", report.Zones, report.Regions)
+	}
+	if len(report.Workloads) != 4 {
+		t.Fatalf("got %d workloads, want 4 (system and scaled-to-zero skipped): %+v", len(report.Workloads), report.Workloads)
+	}
+
+	want := map[string]struct {
+		grade string
+		rules []string
+	}{
+		"api":  {"F", []string{"RES-002", "RES-003", "RES-004", "RES-005"}},
+		"solo": {"D", []string{"RES-001"}},
+		"db":   {"A", []string{"RES-006"}},
+		"web":  {"A", nil},
+	}
+	for _, w := range report.Workloads {
+		exp := want[w.Name]
+		if w.Grade != exp.grade {
+			t.Errorf("%s grade = %s (score %d), want %s", w.Name, w.Grade, w.Score, exp.grade)
+		}
+		if len(w.Findings) != len(exp.rules) {
+			t.Errorf("%s findings = %+v, want %v", w.Name, w.Findings, exp.rules)
+			continue
+		}
+		for i, f := range w.Findings {
+			if f.RuleID != exp.rules[i] {
+				t.Errorf("%s finding %d = %s, want %s", w.Name, i, f.RuleID, exp.rules[i])
+			}
+		}
+	}
+	if report.Workloads[0].Name != "api" {
+		t.Errorf("workloads should be sorted worst first, got %s", report.Workloads[0].Name)
+	}
+	if web := report.Workloads[3]; web.Nodes != 3 || web.PDB != "web" || !web.TopologySpread {
+		t.Errorf("unexpected web entry %+v", web)
+	}
+	if report.Grades["A"] != 2 || report.Grades["D"] != 1 || report.Grades["F"] != 1 {
+		t.Errorf("grades = %v", report.Grades)
+	}
+}
+
+func TestAssessWorkloadSingleZoneCluster(t *testing.T) {
+	w := deploymentWorkload(appDeployment("apps", "api", 2, corev1.PodSpec{}))
+	pods := []corev1.Pod{*appPod("apps", "api-1", "api", "node-a"), *appPod("apps", "api-2", "api", "node-b")}
+	got := assessWorkload(w, pods, map[string]string{"node-a": "z1", "node-b": "z1"}, 1, nil)
+	for _, f := range got.Findings {
+		if f.RuleID == "RES-003" {
+			t.Error("single-zone clusters should not be flagged for zone concentration")
+		}
+	}
+	if got.Nodes != 2 || got.Zones["z1"] != 2 {
+		t.Errorf("unexpected placement %+v", got)
+	}
+}
@@ -0,0 +1,190 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file retries API requests failing with transient errors, such as
+// throttling and timeouts, with exponential backoff and jitter.
+package k8s
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/debug"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// DefaultAPIRetries is how many times an API request failing with a
+	// transient error is retried.
+	DefaultAPIRetries = 3
+	// maxAPIRetries bounds WithAPIRetries.
+	maxAPIRetries = 10
+	// retryMaxDelay caps the backoff, and the Retry-After honoured, between
+	// two attempts.
+	retryMaxDelay = 8 * time.Second
+)
+
+// retryBaseDelay is the backoff before the first retry, doubled on each
+// further one; it is a variable so tests can shorten it.
+var retryBaseDelay = 250 * time.Millisecond
+
+// TransientError is returned when an API request still failed with a
+// transient error after its retries: the API server was reached but is
+// overloaded, throttling or slow, unlike a cluster that cannot be reached.
+type TransientError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *TransientError) Error() string {
+	return fmt.Sprintf("%v (gave up after %d attempt(s))", e.Err, e.Attempts)
+}
+
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+// IsTransient reports whether err is a transient API error: retries ran out,
+// or the API server answered that it is throttling, unavailable or timed out.
+func IsTransient(err error) bool {
+	var transient *TransientError
+	return errors.As(err, &transient) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err)
+}
+
+// retryTransport retries idempotent API requests failing with a transient
+// error: a 429, 502, 503 or 504 response, or a connection that timed out or
+// was reset once established. Failures to connect, such as refused
+// connections, unknown hosts and certificate errors, are returned at once, as
+// retrying would only delay reporting an unreachable cluster. Each attempt
+// stays within the request's context.
+type retryTransport struct {
+	next    http.RoundTripper
+	retries int
+	context string
+}
+
+// retryWrapper returns the transport wrapper retrying transient errors up to
+// retries times; zero disables retries.
+func retryWrapper(contextName string, retries int) func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		if retries <= 0 {
+			return next
+		}
+		return &retryTransport{next: next, retries: retries, context: contextName}
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !retryableRequest(req) {
+		return t.next.RoundTrip(req)
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if !transientResult(req, resp, err) {
+			return resp, err
+		}
+		if attempt > t.retries {
+			if err != nil {
+				return nil, &TransientError{Attempts: attempt, Err: err}
+			}
+			// Retries ran out here; client-go would start over on Retry-After.
+			resp.Header.Del("Retry-After")
+			return resp, nil
+		}
+
+		delay := backoff(attempt, retryAfter(resp))
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			_ = resp.Body.Close()
+		}
+		debug.Logf(debug.K8s, "%s %s %s: %s, retry %d/%d in %s",
+			t.context, req.Method, req.URL.Path, reason, attempt, t.retries, delay.Round(time.Millisecond))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryableRequest reports whether req can be sent again: reads without a
+// body that do not upgrade the connection, as exec and port-forward do.
+func retryableRequest(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	return req.Body == nil && req.Header.Get("Upgrade") == ""
+}
+
+// transientResult reports whether a response or error is worth a retry.
+func transientResult(req *http.Request, resp *http.Response, err error) bool {
+	if err == nil {
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	if req.Context().Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return false
+	}
+	var dnsErr *net.DNSError
+	var certErr *x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	if errors.As(err, &dnsErr) || errors.As(err, &certErr) || errors.As(err, &hostErr) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// retryAfter returns the delay a response asks for in its Retry-After
+// header, in seconds, or zero.
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// backoff returns the delay before retry attempt (1 for the first retry):
+// the server's Retry-After when it sent one, otherwise retryBaseDelay doubled
+// per attempt with half of it randomised, so clients throttled together do
+// not retry together. Both are capped at retryMaxDelay.
+func backoff(attempt int, after time.Duration) time.Duration {
+	if after > 0 {
+		return min(after, retryMaxDelay)
+	}
+	d := min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+	half := d / 2
+	return half + rand.N(half+1)
+}
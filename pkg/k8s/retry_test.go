@@ -0,0 +1,161 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const versionBody = `{"major": "1", "minor": "31", "gitVersion": "v1.31.2"}`
+
+// withRetryDelay shortens the retry backoff for the duration of the test.
+func withRetryDelay(t *testing.T) {
+	t.Helper()
+	old := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = old })
+}
+
+// flakyServer answers the first failures requests with status, then with
+// the version.
+func flakyServer(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte(versionBody))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	withRetryDelay(t)
+	server, requests := flakyServer(t, 2, http.StatusServiceUnavailable)
+	provider, err := NewProvider(writeServerKubeconfig(t, server.URL), WithAPIRetries(3))
+	if err != nil {
+		t.Fatalf(errNewProviderFailed, err)
+	}
+	version, err := provider.GetServerVersion(context.Background(), "local")
+	if err != nil || version != "v1.31.2" {
+		t.Fatalf("GetServerVersion() = %q, %v; want success after retries", version, err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+}
+
+func TestRetriesDisabled(t *testing.T) {
+	withRetryDelay(t)
+	server, requests := flakyServer(t, 1, http.StatusServiceUnavailable)
+	provider, err := NewProvider(writeServerKubeconfig(t, server.URL), WithAPIRetries(0))
+	if err != nil {
+		t.Fatalf(errNewProviderFailed, err)
+	}
+	if _, err := provider.GetServerVersion(context.Background(), "local"); err == nil {
+		t.Error("GetServerVersion() should fail without retries")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestRetriesExhaustedReportBusyCluster(t *testing.T) {
+	withRetryDelay(t)
+	server, requests := flakyServer(t, 100, http.StatusTooManyRequests)
+	provider, err := NewProvider(writeServerKubeconfig(t, server.URL), WithAPIRetries(2))
+	if err != nil {
+		t.Fatalf(errNewProviderFailed, err)
+	}
+	status, err := provider.GetClusterStatus(context.Background(), "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.IsReachable || !status.Transient || !strings.Contains(status.Error, "busy") {
+		t.Errorf("status = reachable %v, transient %v, error %q; want a busy cluster", status.IsReachable, status.Transient, status.Error)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("requests = %d, want 1 + 2 retries", got)
+	}
+}
+
+func TestUnreachableClusterIsNotRetried(t *testing.T) {
+	withRetryDelay(t)
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close() // connections are refused from now on
+
+	provider, err := NewProvider(writeServerKubeconfig(t, url), WithAPIRetries(3))
+	if err != nil {
+		t.Fatalf(errNewProviderFailed, err)
+	}
+	status, err := provider.GetClusterStatus(context.Background(), "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.IsReachable || status.Transient || !strings.HasPrefix(status.Error, "Failed to reach cluster") {
+		t.Errorf("status = transient %v, error %q; want an unreachable cluster", status.Transient, status.Error)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 1; attempt <= 8; attempt++ {
+		full := min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+		if d := backoff(attempt, 0); d < full/2 || d > full {
+			t.Errorf("backoff(%d) = %s, want between %s and %s", attempt, d, full/2, full)
+		}
+	}
+	if d := backoff(1, 2*time.Second); d != 2*time.Second {
+		t.Errorf("backoff should honour Retry-After, got %s", d)
+	}
+	if d := backoff(1, time.Hour); d != retryMaxDelay {
+		t.Errorf("backoff should cap Retry-After at %s, got %s", retryMaxDelay, d)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	if !IsTransient(&TransientError{Attempts: 4, Err: errors.New("connection reset")}) {
+		t.Error("TransientError should be transient")
+	}
+	if IsTransient(errors.New("dial tcp: connection refused")) {
+		t.Error("a connection failure should not be transient")
+	}
+}
+
+func TestRetryableRequest(t *testing.T) {
+	get, _ := http.NewRequest(http.MethodGet, "https://example.com/api", nil)
+	post, _ := http.NewRequest(http.MethodPost, "https://example.com/api", strings.NewReader("{}"))
+	upgrade, _ := http.NewRequest(http.MethodGet, "https://example.com/exec", nil)
+	upgrade.Header.Set("Upgrade", "SPDY/3.1")
+	if !retryableRequest(get) || retryableRequest(post) || retryableRequest(upgrade) {
+		t.Error("only reads without a body or upgrade should be retried")
+	}
+}
+
+func TestAPIOptions(t *testing.T) {
+	provider, err := NewProviderFromConfig(clientcmdapi.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.APITimeout() != DefaultAPITimeout || provider.DiscoveryTimeout() != DiscoveryTimeout || provider.APIRetries() != DefaultAPIRetries {
+		t.Errorf("defaults = %s, %s, %d", provider.APITimeout(), provider.DiscoveryTimeout(), provider.APIRetries())
+	}
+	provider, _ = NewProviderFromConfig(clientcmdapi.NewConfig(),
+		WithAPITimeout(time.Minute), WithDiscoveryTimeout(5*time.Second), WithAPIRetries(50))
+	if provider.APITimeout() != time.Minute || provider.DiscoveryTimeout() != 5*time.Second || provider.APIRetries() != maxAPIRetries {
+		t.Errorf("options = %s, %s, %d", provider.APITimeout(), provider.DiscoveryTimeout(), provider.APIRetries())
+	}
+	if provider.StatusTimeout() != time.Minute+5*time.Second || provider.StatusBudget() != DefaultReachTimeout+time.Minute+5*time.Second+statusBudgetMargin {
+		t.Errorf("status timeout, budget = %s, %s; want them to follow the API and discovery timeouts", provider.StatusTimeout(), provider.StatusBudget())
+	}
+}
@@ -0,0 +1,333 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains rollout restarts and rollout status of Deployments,
+// StatefulSets and DaemonSets.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultRolloutTimeout bounds a rollout wait.
+const DefaultRolloutTimeout = 5 * time.Minute
+
+// rolloutPollInterval is how often a rollout is checked while waiting; a
+// variable so tests run fast.
+var rolloutPollInterval = 2 * time.Second
+
+// Workload kinds that can be restarted and followed.
+const (
+	KindDeployment  = "Deployment"
+	KindStatefulSet = "StatefulSet"
+	KindDaemonSet   = "DaemonSet"
+)
+
+// NormalizeWorkloadKind maps kubectl spellings (deployment, deploy, sts, ds,
+// ...) to the kind restarts and rollouts support.
+func NormalizeWorkloadKind(kind string) (string, error) {
+	switch strings.ToLower(kind) {
+	case "", "deployment", "deployments", "deploy":
+		return KindDeployment, nil
+	case "statefulset", "statefulsets", "sts":
+		return KindStatefulSet, nil
+	case "daemonset", "daemonsets", "ds":
+		return KindDaemonSet, nil
+	}
+	return "", fmt.Errorf("unsupported workload kind %q: use deployment, statefulset or daemonset", kind)
+}
+
+// RolloutStatus is the progress of a workload's rollout, following the
+// completion rules of kubectl rollout status.
+type RolloutStatus struct {
+	Context   string `json:"context"`
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Desired   int32  `json:"desired"`
+	Updated   int32  `json:"updated"`
+	Ready     int32  `json:"ready"`
+	Available int32  `json:"available"`
+	Done      bool   `json:"done"`
+	// Failed is set when the Deployment exceeded its progress deadline.
+	Failed  bool   `json:"failed,omitempty"`
+	Message string `json:"message"`
+	// Duration is how long the rollout was followed; empty for a snapshot.
+	Duration string `json:"duration,omitempty"`
+	TimedOut bool   `json:"timed_out,omitempty"`
+}
+
+// RestartResult is the outcome of RestartWorkload.
+type RestartResult struct {
+	Context     string `json:"context"`
+	Namespace   string `json:"namespace"`
+	Kind        string `json:"kind"`
+	Name        string `json:"name"`
+	RestartedAt string `json:"restarted_at"`
+	// Rollout is the final status when the restart was followed.
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+}
+
+// RolloutOptions controls WaitForRollout.
+type RolloutOptions struct {
+	// Timeout bounds the wait; zero means DefaultRolloutTimeout.
+	Timeout time.Duration
+	// Progress, when set, receives the status every time it changes.
+	Progress func(RolloutStatus)
+}
+
+// RestartWorkload triggers a rolling restart the way kubectl rollout restart
+// does: by stamping the pod template with the restartedAt annotation.
+func (p *Provider) RestartWorkload(ctx context.Context, contextName, namespace, kind, name string) (*RestartResult, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	result, err := restartWorkload(queryCtx, clientset, namespace, kind, name, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	result.Context = contextName
+	return result, nil
+}
+
+// restartWorkload patches the restart annotation into the pod template.
+func restartWorkload(ctx context.Context, clientset kubernetes.Interface, namespace, kind, name string, now time.Time) (*RestartResult, error) {
+	kind, err := NormalizeWorkloadKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	stamp := now.Format(time.RFC3339)
+	patch := fmt.Appendf(nil, `{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`, restartedAtAnnotation, stamp)
+	apps := clientset.AppsV1()
+	switch kind {
+	case KindDeployment:
+		d, getErr := apps.Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if getErr != nil {
+			return nil, fmt.Errorf("failed to get deployment %s/%s: %w", namespace, name, getErr)
+		}
+		if d.Spec.Paused {
+			return nil, fmt.Errorf("deployment %s/%s is paused: resume it before restarting", namespace, name)
+		}
+		_, err = apps.Deployments(namespace).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case KindStatefulSet:
+		_, err = apps.StatefulSets(namespace).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case KindDaemonSet:
+		_, err = apps.DaemonSets(namespace).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to restart %s %s/%s: %w", strings.ToLower(kind), namespace, name, err)
+	}
+	return &RestartResult{Namespace: namespace, Kind: kind, Name: name, RestartedAt: stamp}, nil
+}
+
+// GetRolloutStatus returns the current rollout status of a workload.
+func (p *Provider) GetRolloutStatus(ctx context.Context, contextName, namespace, kind, name string) (*RolloutStatus, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	status, err := rolloutStatus(queryCtx, clientset, namespace, kind, name)
+	if err != nil {
+		return nil, err
+	}
+	status.Context = contextName
+	return status, nil
+}
+
+// WaitForRollout follows a workload's rollout until it completes, fails or
+// the timeout passes, reporting every change through opts.Progress. A
+// timeout is reported in the status rather than as an error.
+func (p *Provider) WaitForRollout(ctx context.Context, contextName, namespace, kind, name string, opts RolloutOptions) (*RolloutStatus, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultRolloutTimeout
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	status, err := waitForRollout(waitCtx, clientset, namespace, kind, name, opts.Progress)
+	if err != nil {
+		return nil, err
+	}
+	status.Context = contextName
+	return status, nil
+}
+
+// waitForRollout polls the rollout until it is done or failed, or ctx ends.
+func waitForRollout(ctx context.Context, clientset kubernetes.Interface, namespace, kind, name string, progress func(RolloutStatus)) (*RolloutStatus, error) {
+	kind, err := NormalizeWorkloadKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	var last string
+	for {
+		status, err := rolloutStatus(ctx, clientset, namespace, kind, name)
+		if err != nil {
+			if ctx.Err() == nil {
+				return nil, err
+			}
+			// The deadline hit mid-request: report the last known state.
+			status = &RolloutStatus{Namespace: namespace, Kind: kind, Name: name, Message: last}
+		}
+		status.Duration = time.Since(start).Round(time.Second).String()
+		if progress != nil && status.Message != last {
+			progress(*status)
+		}
+		last = status.Message
+		if status.Done || status.Failed {
+			return status, nil
+		}
+		if ctx.Err() != nil {
+			status.TimedOut = true
+			return status, nil
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(rolloutPollInterval):
+		}
+	}
+}
+
+// rolloutStatus reads a workload and evaluates its rollout.
+func rolloutStatus(ctx context.Context, clientset kubernetes.Interface, namespace, kind, name string) (*RolloutStatus, error) {
+	kind, err := NormalizeWorkloadKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	apps := clientset.AppsV1()
+	var status RolloutStatus
+	switch kind {
+	case KindDeployment:
+		d, err := apps.Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deployment %s/%s: %w", namespace, name, err)
+		}
+		status = deploymentRolloutStatus(d)
+	case KindStatefulSet:
+		s, err := apps.StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get statefulset %s/%s: %w", namespace, name, err)
+		}
+		status = statefulSetRolloutStatus(s)
+	case KindDaemonSet:
+		ds, err := apps.DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get daemonset %s/%s: %w", namespace, name, err)
+		}
+		status = daemonSetRolloutStatus(ds)
+	}
+	status.Namespace, status.Kind, status.Name = namespace, kind, name
+	return &status, nil
+}
+
+func deploymentRolloutStatus(d *appsv1.Deployment) RolloutStatus {
+	desired := int32(1)
+	if d.Spec.Replicas != nil {
+		desired = *d.Spec.Replicas
+	}
+	s := RolloutStatus{Desired: desired, Updated: d.Status.UpdatedReplicas, Ready: d.Status.ReadyReplicas, Available: d.Status.AvailableReplicas}
+	if d.Generation > d.Status.ObservedGeneration {
+		s.Message = "waiting for the deployment spec update to be observed"
+		return s
+	}
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			s.Failed = true
+			s.Message = fmt.Sprintf("exceeded its progress deadline: %s", c.Message)
+			return s
+		}
+	}
+	switch {
+	case d.Status.UpdatedReplicas < desired:
+		s.Message = fmt.Sprintf("%d of %d new replicas updated", d.Status.UpdatedReplicas, desired)
+	case d.Status.Replicas > d.Status.UpdatedReplicas:
+		s.Message = fmt.Sprintf("%d old replica(s) pending termination", d.Status.Replicas-d.Status.UpdatedReplicas)
+	case d.Status.AvailableReplicas < d.Status.UpdatedReplicas:
+		s.Message = fmt.Sprintf("%d of %d updated replicas available", d.Status.AvailableReplicas, d.Status.UpdatedReplicas)
+	default:
+		s.Done = true
+		s.Message = "successfully rolled out"
+	}
+	return s
+}
+
+func statefulSetRolloutStatus(sts *appsv1.StatefulSet) RolloutStatus {
+	desired := int32(1)
+	if sts.Spec.Replicas != nil {
+		desired = *sts.Spec.Replicas
+	}
+	s := RolloutStatus{Desired: desired, Updated: sts.Status.UpdatedReplicas, Ready: sts.Status.ReadyReplicas, Available: sts.Status.AvailableReplicas}
+	if sts.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+		s.Message = "OnDelete update strategy: pods only change when deleted, so the rollout cannot be followed"
+		s.Done = true
+		return s
+	}
+	if sts.Generation > sts.Status.ObservedGeneration {
+		s.Message = "waiting for the statefulset spec update to be observed"
+		return s
+	}
+	if sts.Status.ReadyReplicas < desired {
+		s.Message = fmt.Sprintf("%d of %d pods ready", sts.Status.ReadyReplicas, desired)
+		return s
+	}
+	if ru := sts.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.Partition != nil && *ru.Partition > 0 {
+		if want := desired - *ru.Partition; sts.Status.UpdatedReplicas < want {
+			s.Message = fmt.Sprintf("partitioned rollout: %d of %d new pods updated", sts.Status.UpdatedReplicas, want)
+			return s
+		}
+		s.Done = true
+		s.Message = fmt.Sprintf("partitioned rollout complete: %d new pod(s) rolled out", sts.Status.UpdatedReplicas)
+		return s
+	}
+	if sts.Status.UpdateRevision != sts.Status.CurrentRevision {
+		s.Message = fmt.Sprintf("%d of %d pods at the new revision", sts.Status.UpdatedReplicas, desired)
+		return s
+	}
+	s.Done = true
+	s.Message = "successfully rolled out"
+	return s
+}
+
+func daemonSetRolloutStatus(ds *appsv1.DaemonSet) RolloutStatus {
+	desired := ds.Status.DesiredNumberScheduled
+	s := RolloutStatus{Desired: desired, Updated: ds.Status.UpdatedNumberScheduled, Ready: ds.Status.NumberReady, Available: ds.Status.NumberAvailable}
+	if ds.Spec.UpdateStrategy.Type == appsv1.OnDeleteDaemonSetStrategyType {
+		s.Message = "OnDelete update strategy: pods only change when deleted, so the rollout cannot be followed"
+		s.Done = true
+		return s
+	}
+	if ds.Generation > ds.Status.ObservedGeneration {
+		s.Message = "waiting for the daemonset spec update to be observed"
+		return s
+	}
+	switch {
+	case ds.Status.UpdatedNumberScheduled < desired:
+		s.Message = fmt.Sprintf("%d of %d updated pods scheduled", ds.Status.UpdatedNumberScheduled, desired)
+	case ds.Status.NumberAvailable < desired:
+		s.Message = fmt.Sprintf("%d of %d updated pods available", ds.Status.NumberAvailable, desired)
+	default:
+		s.Done = true
+		s.Message = "successfully rolled out"
+	}
+	return s
+}
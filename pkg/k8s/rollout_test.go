@@ -0,0 +1,171 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func rolloutDeployment(replicas, updated, available, total int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2, Replicas: total, UpdatedReplicas: updated,
+			ReadyReplicas: available, AvailableReplicas: available,
+		},
+	}
+}
+
+func fastRollout(t *testing.T) {
+	t.Helper()
+	interval := rolloutPollInterval
+	rolloutPollInterval = time.Millisecond
+	t.Cleanup(func() { rolloutPollInterval = interval })
+}
+
+func TestNormalizeWorkloadKind(t *testing.T) {
+	for in, want := range map[string]string{"": KindDeployment, "deploy": KindDeployment, "Deployment": KindDeployment, "sts": KindStatefulSet, "daemonsets": KindDaemonSet} {
+		if got, err := NormalizeWorkloadKind(in); err != nil || got != want {
+			t.Errorf("NormalizeWorkloadKind(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := NormalizeWorkloadKind("cronjob"); err == nil {
+		t.Error("NormalizeWorkloadKind(cronjob) succeeded, want an error")
+	}
+}
+
+func TestDeploymentRolloutStatus(t *testing.T) {
+	stale := rolloutDeployment(3, 3, 3, 3)
+	stale.Generation = 3
+	failed := rolloutDeployment(3, 1, 1, 3)
+	failed.Status.Conditions = []appsv1.DeploymentCondition{{Type: appsv1.DeploymentProgressing, Reason: "ProgressDeadlineExceeded", Message: "ReplicaSet api-7d has timed out progressing"}}
+	tests := []struct {
+		d       *appsv1.Deployment
+		done    bool
+		message string
+	}{
+		{stale, false, "spec update to be observed"},
+		{failed, false, "progress deadline"},
+		{rolloutDeployment(3, 1, 1, 3), false, "1 of 3 new replicas updated"},
+		{rolloutDeployment(3, 3, 3, 4), false, "1 old replica(s) pending termination"},
+		{rolloutDeployment(3, 3, 2, 3), false, "2 of 3 updated replicas available"},
+		{rolloutDeployment(3, 3, 3, 3), true, "successfully rolled out"},
+	}
+	for _, tt := range tests {
+		s := deploymentRolloutStatus(tt.d)
+		if s.Done != tt.done || !strings.Contains(s.Message, tt.message) {
+			t.Errorf("status = %+v, want done=%v and %q", s, tt.done, tt.message)
+		}
+	}
+	if s := deploymentRolloutStatus(failed); !s.Failed {
+		t.Errorf("progress deadline not reported as failed: %+v", s)
+	}
+}
+
+func TestStatefulSetAndDaemonSetRolloutStatus(t *testing.T) {
+	replicas, partition := int32(3), int32(2)
+	sts := &appsv1.StatefulSet{
+		Spec: appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status: appsv1.StatefulSetStatus{
+			ReadyReplicas: 3, UpdatedReplicas: 1, CurrentRevision: "web-1", UpdateRevision: "web-2",
+		},
+	}
+	if s := statefulSetRolloutStatus(sts); s.Done || !strings.Contains(s.Message, "1 of 3 pods at the new revision") {
+		t.Errorf("statefulset mid-rollout = %+v", s)
+	}
+	sts.Spec.UpdateStrategy.RollingUpdate = &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition}
+	if s := statefulSetRolloutStatus(sts); !s.Done || !strings.Contains(s.Message, "partitioned rollout complete") {
+		t.Errorf("partitioned statefulset = %+v", s)
+	}
+	sts.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType}
+	if s := statefulSetRolloutStatus(sts); !s.Done || !strings.Contains(s.Message, "OnDelete") {
+		t.Errorf("OnDelete statefulset = %+v", s)
+	}
+
+	ds := &appsv1.DaemonSet{Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 4, UpdatedNumberScheduled: 4, NumberAvailable: 3}}
+	if s := daemonSetRolloutStatus(ds); s.Done || !strings.Contains(s.Message, "3 of 4 updated pods available") {
+		t.Errorf("daemonset = %+v", s)
+	}
+	ds.Status.NumberAvailable = 4
+	if s := daemonSetRolloutStatus(ds); !s.Done {
+		t.Errorf("daemonset = %+v, want done", s)
+	}
+}
+
+func TestRestartWorkload(t *testing.T) {
+	clientset := fake.NewClientset(rolloutDeployment(2, 2, 2, 2))
+	now := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+
+	result, err := restartWorkload(context.Background(), clientset, "shop", "deploy", "api", now)
+	if err != nil {
+		t.Fatalf("restartWorkload: %v", err)
+	}
+	if result.Kind != KindDeployment || result.RestartedAt != "2026-10-16T09:30:00Z" {
+		t.Errorf("result = %+v", result)
+	}
+	d, _ := clientset.AppsV1().Deployments("shop").Get(context.Background(), "api", metav1.GetOptions{})
+	if got := d.Spec.Template.Annotations[restartedAtAnnotation]; got != "2026-10-16T09:30:00Z" {
+		t.Errorf("restart annotation = %q", got)
+	}
+
+	d.Spec.Paused = true
+	if _, err := clientset.AppsV1().Deployments("shop").Update(context.Background(), d, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restartWorkload(context.Background(), clientset, "shop", "deployment", "api", now); err == nil || !strings.Contains(err.Error(), "paused") {
+		t.Errorf("paused deployment error = %v", err)
+	}
+	if _, err := restartWorkload(context.Background(), clientset, "shop", "statefulset", "missing", now); err == nil {
+		t.Error("restarting a missing statefulset succeeded, want an error")
+	}
+}
+
+func TestWaitForRollout(t *testing.T) {
+	fastRollout(t)
+	clientset := fake.NewClientset()
+	steps := []*appsv1.Deployment{
+		rolloutDeployment(2, 1, 1, 2),
+		rolloutDeployment(2, 1, 1, 2),
+		rolloutDeployment(2, 2, 1, 3),
+		rolloutDeployment(2, 2, 2, 2),
+	}
+	calls := 0
+	clientset.PrependReactor("get", "deployments", func(k8stesting.Action) (bool, runtime.Object, error) {
+		d := steps[min(calls, len(steps)-1)]
+		calls++
+		return true, d, nil
+	})
+
+	var messages []string
+	status, err := waitForRollout(context.Background(), clientset, "shop", "deployment", "api", func(s RolloutStatus) {
+		messages = append(messages, s.Message)
+	})
+	if err != nil {
+		t.Fatalf("waitForRollout: %v", err)
+	}
+	if !status.Done || status.Kind != KindDeployment || status.Duration == "" {
+		t.Errorf("status = %+v", status)
+	}
+	// Unchanged polls are not reported again.
+	if len(messages) != 3 || messages[2] != "successfully rolled out" {
+		t.Errorf("progress = %q", messages)
+	}
+
+	// A rollout that never completes times out without an error.
+	calls = 0
+	steps = steps[:1]
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	status, err = waitForRollout(ctx, clientset, "shop", "deployment", "api", nil)
+	if err != nil || !status.TimedOut || status.Done {
+		t.Errorf("timed out wait = %+v, %v", status, err)
+	}
+}
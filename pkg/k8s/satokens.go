@@ -0,0 +1,295 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the access review of long-lived ServiceAccount token secrets.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// Labels the legacy token tracking controller (Kubernetes 1.29+) sets on
+	// ServiceAccount token secrets.
+	legacyTokenLastUsedLabel      = "kubernetes.io/legacy-token-last-used"
+	legacyTokenInvalidSinceLabel  = "kubernetes.io/legacy-token-invalid-since"
+	legacyTokenStaleAfterDays     = 90
+	legacyTokenVeryStaleAfterDays = 365
+	clusterAdminRole              = "cluster-admin"
+)
+
+// ServiceAccountToken describes one long-lived ServiceAccount token secret.
+type ServiceAccountToken struct {
+	Namespace      string           `json:"namespace"`
+	Secret         string           `json:"secret"`
+	ServiceAccount string           `json:"service_account"`
+	AgeDays        int              `json:"age_days"`
+	LastUsed       string           `json:"last_used,omitempty"`     // date, when tracked by the cluster
+	InvalidSince   string           `json:"invalid_since,omitempty"` // date the token was auto-invalidated
+	OrphanedSecret bool             `json:"orphaned"`                // the ServiceAccount no longer exists
+	MountedBy      []string         `json:"mounted_by,omitempty"`    // workloads reading the secret directly
+	RunningAs      []string         `json:"running_as,omitempty"`    // workloads running as the ServiceAccount
+	Roles          []string         `json:"roles,omitempty"`         // roles bound to the ServiceAccount
+	Risk           SanitizeSeverity `json:"risk"`
+	Recommendation string           `json:"recommendation"`
+}
+
+// TokenReview is the outcome of ReviewServiceAccountTokens.
+type TokenReview struct {
+	Context string                `json:"context"`
+	Tokens  []ServiceAccountToken `json:"tokens"`
+	Risks   map[string]int        `json:"risks"` // risk -> token count
+}
+
+// tokenReviewData holds the objects reviewServiceAccountTokens needs.
+type tokenReviewData struct {
+	secrets         []corev1.Secret
+	serviceAccounts map[string]bool // namespace/name
+	pods            []corev1.Pod
+	roleBindings    []rbacv1.RoleBinding
+	clusterBindings []rbacv1.ClusterRoleBinding
+}
+
+// podSecretRefs returns the secrets a pod reads through volumes, projected
+// volumes, env and envFrom.
+func podSecretRefs(pod *corev1.Pod) map[string]bool {
+	refs := make(map[string]bool)
+	for _, v := range pod.Spec.Volumes {
+		if v.Secret != nil {
+			refs[v.Secret.SecretName] = true
+		}
+		if v.Projected != nil {
+			for _, src := range v.Projected.Sources {
+				if src.Secret != nil {
+					refs[src.Secret.Name] = true
+				}
+			}
+		}
+	}
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, c := range containers {
+		for _, e := range c.Env {
+			if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
+				refs[e.ValueFrom.SecretKeyRef.Name] = true
+			}
+		}
+		for _, e := range c.EnvFrom {
+			if e.SecretRef != nil {
+				refs[e.SecretRef.Name] = true
+			}
+		}
+	}
+	return refs
+}
+
+// serviceAccountRoles maps namespace/serviceaccount to the roles bound to it.
+func serviceAccountRoles(roleBindings []rbacv1.RoleBinding, clusterBindings []rbacv1.ClusterRoleBinding) map[string][]string {
+	roles := make(map[string][]string)
+	add := func(subjects []rbacv1.Subject, bindingNS, role string) {
+		for _, s := range subjects {
+			if s.Kind != rbacv1.ServiceAccountKind {
+				continue
+			}
+			ns := s.Namespace
+			if ns == "" {
+				ns = bindingNS
+			}
+			roles[ns+"/"+s.Name] = append(roles[ns+"/"+s.Name], role)
+		}
+	}
+	for _, b := range clusterBindings {
+		add(b.Subjects, "", "ClusterRole/"+b.RoleRef.Name)
+	}
+	for _, b := range roleBindings {
+		add(b.Subjects, b.Namespace, fmt.Sprintf("%s/%s (in %s)", b.RoleRef.Kind, b.RoleRef.Name, b.Namespace))
+	}
+	for k := range roles {
+		sort.Strings(roles[k])
+	}
+	return roles
+}
+
+// sortedSet returns the keys of set in order.
+func sortedSet(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for k := range set {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// assessToken sets the risk and recommendation of a token.
+func assessToken(t *ServiceAccountToken) {
+	clusterAdmin := false
+	for _, r := range t.Roles {
+		if r == "ClusterRole/"+clusterAdminRole {
+			clusterAdmin = true
+		}
+	}
+
+	switch {
+	case clusterAdmin && t.InvalidSince == "":
+		t.Risk = SanitizeCritical
+	case t.InvalidSince != "" || t.OrphanedSecret:
+		t.Risk = SanitizeMinor
+	case len(t.MountedBy) > 0 || t.AgeDays > legacyTokenVeryStaleAfterDays || len(t.Roles) > 0:
+		t.Risk = SanitizeMajor
+	default:
+		t.Risk = SanitizeMinor
+	}
+
+	switch {
+	case t.InvalidSince != "":
+		t.Recommendation = fmt.Sprintf("Invalidated since %s and pending cleanup; delete the secret.", t.InvalidSince)
+	case t.OrphanedSecret:
+		t.Recommendation = "The ServiceAccount no longer exists; delete the secret."
+	case len(t.MountedBy) > 0:
+		t.Recommendation = "Replace the secret volume/env with a projected serviceAccountToken volume (audience-bound, expiring, auto-rotated), then delete the secret."
+	case t.LastUsed == "" && t.AgeDays > legacyTokenStaleAfterDays:
+		t.Recommendation = "No workload reads it; if nothing outside the cluster uses it, delete the secret, otherwise issue expiring tokens with 'kubectl create token --duration' or the TokenRequest API."
+	default:
+		t.Recommendation = "Used outside the cluster (CI, dashboards, kubeconfigs): switch the client to expiring tokens from 'kubectl create token --duration' or the TokenRequest API, then delete the secret."
+	}
+	if clusterAdmin {
+		t.Recommendation += " The ServiceAccount is bound to cluster-admin: anyone holding this token owns the cluster."
+	}
+}
+
+// reviewServiceAccountTokens assesses every ServiceAccount token secret in scope.
+func reviewServiceAccountTokens(data tokenReviewData, targetNamespace string, includeSystem bool, now time.Time) []ServiceAccountToken {
+	roles := serviceAccountRoles(data.roleBindings, data.clusterBindings)
+
+	mountedBy := make(map[string]map[string]bool) // namespace/secret -> workloads
+	runningAs := make(map[string]map[string]bool) // namespace/serviceaccount -> workloads
+	for i := range data.pods {
+		pod := &data.pods[i]
+		workload := workloadName(pod)
+		for secret := range podSecretRefs(pod) {
+			key := pod.Namespace + "/" + secret
+			if mountedBy[key] == nil {
+				mountedBy[key] = make(map[string]bool)
+			}
+			mountedBy[key][workload] = true
+		}
+		sa := pod.Spec.ServiceAccountName
+		if sa == "" {
+			sa = "default"
+		}
+		key := pod.Namespace + "/" + sa
+		if runningAs[key] == nil {
+			runningAs[key] = make(map[string]bool)
+		}
+		runningAs[key][workload] = true
+	}
+
+	tokens := make([]ServiceAccountToken, 0)
+	for _, s := range data.secrets {
+		if s.Type != corev1.SecretTypeServiceAccountToken || !shouldScanNamespace(s.Namespace, targetNamespace, includeSystem) {
+			continue
+		}
+		sa := s.Annotations[corev1.ServiceAccountNameKey]
+		saKey := s.Namespace + "/" + sa
+		t := ServiceAccountToken{
+			Namespace:      s.Namespace,
+			Secret:         s.Name,
+			ServiceAccount: sa,
+			AgeDays:        int(now.Sub(s.CreationTimestamp.Time).Hours() / 24),
+			LastUsed:       s.Labels[legacyTokenLastUsedLabel],
+			InvalidSince:   s.Labels[legacyTokenInvalidSinceLabel],
+			OrphanedSecret: !data.serviceAccounts[saKey],
+			MountedBy:      sortedSet(mountedBy[s.Namespace+"/"+s.Name]),
+			RunningAs:      sortedSet(runningAs[saKey]),
+			Roles:          roles[saKey],
+		}
+		assessToken(&t)
+		tokens = append(tokens, t)
+	}
+
+	rank := map[SanitizeSeverity]int{SanitizeCritical: 0, SanitizeMajor: 1, SanitizeMinor: 2}
+	sort.SliceStable(tokens, func(i, j int) bool {
+		a, b := tokens[i], tokens[j]
+		if rank[a.Risk] != rank[b.Risk] {
+			return rank[a.Risk] < rank[b.Risk]
+		}
+		if a.AgeDays != b.AgeDays {
+			return a.AgeDays > b.AgeDays
+		}
+		return a.Namespace+"/"+a.Secret < b.Namespace+"/"+b.Secret
+	})
+	return tokens
+}
+
+// collectTokenReviewData fetches secrets, ServiceAccounts, pods and RBAC bindings.
+func collectTokenReviewData(ctx context.Context, clientset kubernetes.Interface, targetNamespace string) (tokenReviewData, error) {
+	var data tokenReviewData
+	secrets, err := clientset.CoreV1().Secrets(targetNamespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "type=" + string(corev1.SecretTypeServiceAccountToken),
+	})
+	if err != nil {
+		return data, fmt.Errorf("failed to list secrets: %w", err)
+	}
+	data.secrets = secrets.Items
+
+	sas, err := clientset.CoreV1().ServiceAccounts(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return data, fmt.Errorf("failed to list service accounts: %w", err)
+	}
+	data.serviceAccounts = make(map[string]bool, len(sas.Items))
+	for _, sa := range sas.Items {
+		data.serviceAccounts[sa.Namespace+"/"+sa.Name] = true
+	}
+
+	pods, err := clientset.CoreV1().Pods(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return data, fmt.Errorf("failed to list pods: %w", err)
+	}
+	data.pods = pods.Items
+
+	rbs, err := clientset.RbacV1().RoleBindings(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return data, fmt.Errorf("failed to list role bindings: %w", err)
+	}
+	data.roleBindings = rbs.Items
+
+	crbs, err := clientset.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return data, fmt.Errorf("failed to list cluster role bindings: %w", err)
+	}
+	data.clusterBindings = crbs.Items
+	return data, nil
+}
+
+// ReviewServiceAccountTokens lists long-lived ServiceAccount token secrets with
+// their age, last use, the workloads bound to them and the roles they carry.
+// If targetNamespace is non-empty, only that namespace is reviewed. If
+// includeSystem is false, system namespaces are excluded.
+func (p *Provider) ReviewServiceAccountTokens(ctx context.Context, contextName, targetNamespace string, includeSystem bool) (*TokenReview, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	data, err := collectTokenReviewData(queryCtx, clientset, targetNamespace)
+	if err != nil {
+		return nil, err
+	}
+	review := &TokenReview{
+		Context: contextName,
+		Tokens:  reviewServiceAccountTokens(data, targetNamespace, includeSystem, time.Now()),
+		Risks:   make(map[string]int),
+	}
+	for _, t := range review.Tokens {
+		review.Risks[string(t.Risk)]++
+	}
+	return review, nil
+}
@@ -0,0 +1,105 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func saTokenSecret(namespace, name, sa string, age time.Duration, labels map[string]string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: namespace, Labels: labels,
+			Annotations:       map[string]string{corev1.ServiceAccountNameKey: sa},
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}
+}
+
+func serviceAccount(namespace, name string) *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+}
+
+func TestReviewServiceAccountTokens(t *testing.T) {
+	const day = 24 * time.Hour
+	mounting := readyPod("ci", "builder", nil)
+	mounting.Spec.ServiceAccountName = "builder"
+	mounting.Spec.Volumes = []corev1.Volume{{Name: "token", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "builder-token"}}}}
+
+	clientset := fake.NewClientset(
+		serviceAccount("ci", "builder"), serviceAccount("ci", "deployer"), serviceAccount("ops", "dashboard"),
+		saTokenSecret("ci", "builder-token", "builder", 30*day, nil),
+		saTokenSecret("ci", "deployer-token", "deployer", 400*day, map[string]string{legacyTokenLastUsedLabel: "2026-10-01"}),
+		saTokenSecret("ops", "dashboard-token", "dashboard", 10*day, nil),
+		saTokenSecret("ops", "ghost-token", "ghost", 200*day, nil),
+		saTokenSecret("ops", "old-token", "dashboard", 500*day, map[string]string{legacyTokenInvalidSinceLabel: "2026-01-01"}),
+		saTokenSecret("kube-system", "system-token", "kube-proxy", 900*day, nil),
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "tls", Namespace: "ci"}, Type: corev1.SecretTypeTLS},
+		mounting,
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "dashboard-admin"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: clusterAdminRole},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "dashboard", Namespace: "ops"}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "ci"},
+			RoleRef:    rbacv1.RoleRef{Kind: "Role", Name: "edit"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "deployer"}},
+		},
+	)
+	data, err := collectTokenReviewData(context.Background(), clientset, "")
+	if err != nil {
+		t.Fatalf("collectTokenReviewData: %v", err)
+	}
+	tokens := reviewServiceAccountTokens(data, "", false, time.Now())
+
+	got := make(map[string]ServiceAccountToken)
+	var order []string
+	for _, tok := range tokens {
+		got[tok.Secret] = tok
+		order = append(order, tok.Secret)
+	}
+	if _, ok := got["system-token"]; ok || len(tokens) != 5 {
+		t.Fatalf("tokens = %v, want 5 non-system tokens", order)
+	}
+	if order[0] != "dashboard-token" || got["dashboard-token"].Risk != SanitizeCritical || !strings.Contains(got["dashboard-token"].Recommendation, "cluster-admin") {
+		t.Errorf("cluster-admin token should rank first as critical: %v %+v", order, got["dashboard-token"])
+	}
+	if b := got["builder-token"]; b.Risk != SanitizeMajor || len(b.MountedBy) != 1 || b.MountedBy[0] != "pod/builder" || !strings.Contains(b.Recommendation, "projected serviceAccountToken") {
+		t.Errorf("mounted token: %+v", b)
+	}
+	if d := got["deployer-token"]; d.Risk != SanitizeMajor || d.LastUsed != "2026-10-01" || len(d.Roles) != 1 || d.Roles[0] != "Role/edit (in ci)" {
+		t.Errorf("deployer token: %+v", d)
+	}
+	if g := got["ghost-token"]; !g.OrphanedSecret || g.Risk != SanitizeMinor {
+		t.Errorf("orphaned token: %+v", g)
+	}
+	if o := got["old-token"]; o.Risk != SanitizeMinor || !strings.Contains(o.Recommendation, "Invalidated since 2026-01-01") {
+		t.Errorf("invalidated token: %+v", o)
+	}
+}
+
+func TestPodSecretRefs(t *testing.T) {
+	pod := readyPod("ns", "p", nil)
+	pod.Spec.Volumes = []corev1.Volume{{Name: "p", VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+		Sources: []corev1.VolumeProjection{{Secret: &corev1.SecretProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "projected"}}}},
+	}}}}
+	pod.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "TOKEN", ValueFrom: &corev1.EnvVarSource{
+		SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "env"}, Key: "token"},
+	}}}
+	pod.Spec.Containers[0].EnvFrom = []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "envfrom"}}}}
+
+	refs := podSecretRefs(pod)
+	for _, name := range []string{"projected", "env", "envfrom"} {
+		if !refs[name] {
+			t.Errorf("podSecretRefs() missing %s: %v", name, refs)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains context-scoped providers: one client, status cache and
+// API rate limit per kubeconfig context.
+package k8s
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/debug"
+	"github.com/e9169/kopilot/pkg/telemetry"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Limits of ContextSettings.
+const (
+	maxContextCacheTTL = 24 * time.Hour
+	maxContextQPS      = 1000
+	maxContextBurst    = 2000
+)
+
+// ContextSettings tunes the client of one context; zero values keep the
+// provider defaults. A large, busy cluster can cache its status for longer
+// and a small one be queried more gently than the rest of the fleet.
+type ContextSettings struct {
+	// CacheTTL is how long the cluster status is cached, as a Go duration
+	// such as "5m".
+	CacheTTL string `json:"cache_ttl,omitempty"`
+	// QPS and Burst limit the requests sent to the API server
+	// (client-go defaults: 5 and 10).
+	QPS   float32 `json:"qps,omitempty"`
+	Burst int     `json:"burst,omitempty"`
+}
+
+// Validate checks that the settings are in range.
+func (s ContextSettings) Validate() error {
+	if s.CacheTTL != "" {
+		d, err := time.ParseDuration(s.CacheTTL)
+		if err != nil || d < 0 || d > maxContextCacheTTL {
+			return fmt.Errorf("cache_ttl %q must be a duration between 0s and %s", s.CacheTTL, maxContextCacheTTL)
+		}
+	}
+	if s.QPS < 0 || s.QPS > maxContextQPS {
+		return fmt.Errorf("qps must be between 0 and %d", maxContextQPS)
+	}
+	if s.Burst < 0 || s.Burst > maxContextBurst {
+		return fmt.Errorf("burst must be between 0 and %d", maxContextBurst)
+	}
+	return nil
+}
+
+// ContextProvider is a Provider scoped to one kubeconfig context. It owns the
+// context's clients, built once and reused, its cached status and its API
+// rate limit, so clusters of very different sizes and churn do not share one
+// lock or TTL. Provider methods taking a context name go through it.
+type ContextProvider struct {
+	parent *Provider
+	name   string
+
+	mu         sync.Mutex
+	settings   ContextSettings
+	clientset  kubernetes.Interface
+	restConfig *rest.Config
+	// httpClient is shared by the context's typed, dynamic and discovery
+	// clients, so they reuse one transport and its connections.
+	httpClient *http.Client
+	dynamic    dynamic.Interface
+	status     *CachedClusterStatus
+	// queries counts the queries that could use live state, and liveState
+	// holds the informers once started; see live.
+	queries   int
+	liveState *liveState
+}
+
+// ForContext returns the scoped provider of contextName, creating it on
+// first use. The same handle is returned on every call.
+func (p *Provider) ForContext(contextName string) (*ContextProvider, error) {
+	if _, err := p.GetClusterByContext(contextName); err != nil {
+		return nil, err
+	}
+	p.scopesMutex.Lock()
+	defer p.scopesMutex.Unlock()
+	if c, ok := p.scopes[contextName]; ok {
+		return c, nil
+	}
+	if p.scopes == nil {
+		p.scopes = make(map[string]*ContextProvider)
+	}
+	c := &ContextProvider{parent: p, name: contextName}
+	p.scopes[contextName] = c
+	return c, nil
+}
+
+// contextScopes returns the scoped providers created so far, by name.
+func (p *Provider) contextScopes() []*ContextProvider {
+	p.scopesMutex.Lock()
+	defer p.scopesMutex.Unlock()
+	scopes := make([]*ContextProvider, 0, len(p.scopes))
+	for _, c := range p.scopes {
+		scopes = append(scopes, c)
+	}
+	sort.Slice(scopes, func(i, j int) bool { return scopes[i].name < scopes[j].name })
+	return scopes
+}
+
+// ConfigureContext applies settings to contextName.
+func (p *Provider) ConfigureContext(contextName string, settings ContextSettings) error {
+	if err := settings.Validate(); err != nil {
+		return fmt.Errorf("context %s: %w", contextName, err)
+	}
+	c, err := p.ForContext(contextName)
+	if err != nil {
+		return err
+	}
+	c.Configure(settings)
+	return nil
+}
+
+// Name returns the context name.
+func (c *ContextProvider) Name() string {
+	return c.name
+}
+
+// Settings returns the settings applied with Configure.
+func (c *ContextProvider) Settings() ContextSettings {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.settings
+}
+
+// Configure replaces the settings of the context. A changed rate limit takes
+// effect on the next request; the cached status is dropped.
+func (c *ContextProvider) Configure(settings ContextSettings) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if settings.QPS != c.settings.QPS || settings.Burst != c.settings.Burst {
+		c.resetClients()
+	}
+	c.settings = settings
+	c.status = nil
+	debug.Logf(debug.Cache, "context %s: cache ttl %q, qps %g, burst %d", c.name, settings.CacheTTL, settings.QPS, settings.Burst)
+}
+
+// Clientset returns the context's clientset, creating it on first use.
+func (c *ContextProvider) Clientset() (kubernetes.Interface, error) {
+	clientset, _, err := c.clients()
+	return clientset, err
+}
+
+// clients returns the context's clientset and REST config, creating them on
+// first use.
+func (c *ContextProvider) clients() (kubernetes.Interface, *rest.Config, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.buildClients(); err != nil {
+		return nil, nil, err
+	}
+	return c.clientset, c.restConfig, nil
+}
+
+// buildClients creates the clientset, REST config and shared HTTP client
+// unless they exist. Callers hold c.mu.
+func (c *ContextProvider) buildClients() error {
+	if c.clientset != nil {
+		return nil
+	}
+
+	p := c.parent
+	var clientConfig clientcmd.ClientConfig
+	if p.kubeconfigPath == "" {
+		clientConfig = clientcmd.NewNonInteractiveClientConfig(*p.kubeconfig(), c.name, &clientcmd.ConfigOverrides{}, nil)
+	} else {
+		clientConfig = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: p.kubeconfigPath},
+			&clientcmd.ConfigOverrides{CurrentContext: c.name},
+		)
+	}
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return fmt.Errorf("failed to create client config: %w", err)
+	}
+	if c.settings.QPS > 0 {
+		restConfig.QPS = c.settings.QPS
+	}
+	if c.settings.Burst > 0 {
+		restConfig.Burst = c.settings.Burst
+	}
+	// Trace every API request; spans are no-ops unless tracing is set up.
+	restConfig.Wrap(telemetry.WrapTransport)
+	restConfig.Wrap(debug.WrapTransport)
+	// Outermost, so each attempt is traced and logged.
+	restConfig.Wrap(retryWrapper(c.name, p.apiRetries))
+
+	httpClient, err := rest.HTTPClientFor(restConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create transport: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfigAndClient(restConfig, httpClient)
+	if err != nil {
+		return fmt.Errorf("failed to create clientset: %w", err)
+	}
+	c.clientset, c.restConfig, c.httpClient = clientset, restConfig, httpClient
+	debug.Logf(debug.Cache, "context %s: clients created", c.name)
+	return nil
+}
+
+// DynamicClient returns the context's client for resources without typed
+// clients, such as custom resources, creating it on first use. It shares the
+// clientset's transport.
+func (c *ContextProvider) DynamicClient() (dynamic.Interface, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.buildClients(); err != nil {
+		return nil, err
+	}
+	if c.dynamic == nil {
+		client, err := dynamic.NewForConfigAndClient(c.restConfig, c.httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+		}
+		c.dynamic = client
+	}
+	return c.dynamic, nil
+}
+
+// transport returns the context's REST config and the HTTP transport its
+// clients share, creating them on first use.
+func (c *ContextProvider) transport() (*rest.Config, http.RoundTripper, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.buildClients(); err != nil {
+		return nil, nil, err
+	}
+	return c.restConfig, c.httpClient.Transport, nil
+}
+
+// resetClients drops the context's clients, which are rebuilt on next use.
+// Callers hold c.mu.
+func (c *ContextProvider) resetClients() {
+	c.stopLive()
+	if c.httpClient != nil {
+		c.httpClient.CloseIdleConnections()
+	}
+	c.clientset, c.restConfig, c.httpClient, c.dynamic = nil, nil, nil, nil
+}
+
+// invalidate drops the context's clients and cached status, after its
+// kubeconfig entry changed.
+func (c *ContextProvider) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resetClients()
+	c.status = nil
+	c.parent.cacheGeneration.Add(1)
+	debug.Logf(debug.Cache, "context %s: clients invalidated", c.name)
+}
+
+// cacheTTL returns the status cache TTL of the context.
+func (c *ContextProvider) cacheTTL() time.Duration {
+	if d, err := time.ParseDuration(c.settings.CacheTTL); err == nil && d > 0 {
+		return d
+	}
+	return c.parent.CacheTTL()
+}
+
+// cachedStatus returns the cached status if it has not expired.
+func (c *ContextProvider) cachedStatus() *ClusterStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.status == nil {
+		debug.Logf(debug.Cache, "status %s: miss", c.name)
+		return nil
+	}
+	if time.Now().After(c.status.ExpiresAt) {
+		debug.Logf(debug.Cache, "status %s: expired %s ago", c.name, time.Since(c.status.ExpiresAt).Round(time.Millisecond))
+		return nil
+	}
+	debug.Logf(debug.Cache, "status %s: hit, expires in %s", c.name, time.Until(c.status.ExpiresAt).Round(time.Millisecond))
+	return c.status.Status
+}
+
+// lastStatus returns the cached status even when it has expired, and when
+// it was stored.
+func (c *ContextProvider) lastStatus() (*ClusterStatus, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.status == nil {
+		return nil, time.Time{}
+	}
+	return c.status.Status, c.status.CheckedAt
+}
+
+// storeStatus caches status for the context's TTL.
+func (c *ContextProvider) storeStatus(status *ClusterStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ttl := c.cacheTTL()
+	now := time.Now()
+	c.status = &CachedClusterStatus{Status: status, ExpiresAt: now.Add(ttl), CheckedAt: now}
+	debug.Logf(debug.Cache, "status %s: stored for %s", c.name, ttl)
+}
+
+// clearStatus drops the cached status.
+func (c *ContextProvider) clearStatus() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cleared := c.status != nil
+	c.status = nil
+	return cleared
+}
@@ -0,0 +1,140 @@
+package k8s
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForContext(t *testing.T) {
+	kubeconfigPath, cleanup := createTempKubeconfig(t, 2)
+	defer cleanup()
+	provider, err := NewProvider(kubeconfigPath)
+	if err != nil {
+		t.Fatalf(errNewProvider, err)
+	}
+
+	first, err := provider.ForContext(testContext1)
+	if err != nil {
+		t.Fatalf("ForContext() failed: %v", err)
+	}
+	if again, _ := provider.ForContext(testContext1); again != first || first.Name() != testContext1 {
+		t.Error("ForContext() should return the same handle for a context")
+	}
+	if _, err := provider.ForContext("missing"); err == nil {
+		t.Error("ForContext() expected an error for a missing context")
+	}
+
+	clientset, err := first.Clientset()
+	if err != nil {
+		t.Fatalf("Clientset() failed: %v", err)
+	}
+	if again, _ := first.Clientset(); again != clientset {
+		t.Error("Clientset() should reuse the context's clientset")
+	}
+	if shared, _, _ := provider.createClientset(testContext1); shared != clientset {
+		t.Error("createClientset() should return the scoped clientset")
+	}
+	second, _ := provider.ForContext(testContext2)
+	if other, _ := second.Clientset(); other == clientset {
+		t.Error("contexts should not share a clientset")
+	}
+
+	first.Configure(ContextSettings{QPS: 50, Burst: 100})
+	_, restConfig, err := first.clients()
+	if err != nil || restConfig.QPS != 50 || restConfig.Burst != 100 {
+		t.Errorf("rate limit not applied: %v %+v", err, restConfig)
+	}
+	if again, _ := first.Clientset(); again == clientset {
+		t.Error("a new rate limit should rebuild the clientset")
+	}
+}
+
+func TestContextCacheTTL(t *testing.T) {
+	kubeconfigPath, cleanup := createTempKubeconfig(t, 2)
+	defer cleanup()
+	provider, err := NewProvider(kubeconfigPath)
+	if err != nil {
+		t.Fatalf(errNewProvider, err)
+	}
+	provider.SetCacheTTL(10 * time.Millisecond)
+	if err := provider.ConfigureContext(testContext1, ContextSettings{CacheTTL: "60s"}); err != nil {
+		t.Fatalf("ConfigureContext() failed: %v", err)
+	}
+
+	provider.cacheStatus(testContext1, &ClusterStatus{Version: testClusterVersion})
+	provider.cacheStatus(testContext2, &ClusterStatus{Version: testClusterVersion})
+	time.Sleep(15 * time.Millisecond)
+	if provider.getCachedStatus(testContext1) == nil {
+		t.Error("context-1 has its own 60s TTL and should still be cached")
+	}
+	if provider.getCachedStatus(testContext2) != nil {
+		t.Error("context-2 follows the 10ms provider TTL and should have expired")
+	}
+
+	for _, bad := range []ContextSettings{{CacheTTL: "-1s"}, {CacheTTL: "48h"}, {CacheTTL: "soon"}, {QPS: 5000}, {Burst: -1}} {
+		if err := provider.ConfigureContext(testContext1, bad); err == nil {
+			t.Errorf("ConfigureContext(%+v) expected an error", bad)
+		}
+	}
+	if err := provider.ConfigureContext("missing", ContextSettings{}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("ConfigureContext(missing) error = %v", err)
+	}
+}
+
+func TestContextClientsetReusedAcrossCalls(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"major": "1", "minor": "31", "gitVersion": "v1.31.2"}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewProvider(writeServerKubeconfig(t, server.URL))
+	if err != nil {
+		t.Fatalf(errNewProviderFailed, err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := provider.GetServerVersion(context.Background(), "local"); err != nil {
+			t.Fatalf("GetServerVersion() failed: %v", err)
+		}
+	}
+	scoped, _ := provider.ForContext("local")
+	if scoped.clientset == nil || requests.Load() != 3 {
+		t.Errorf("clientset cached = %v, requests = %d", scoped.clientset != nil, requests.Load())
+	}
+}
+
+func TestContextClientsShareTransport(t *testing.T) {
+	kubeconfigPath, cleanup := createTempKubeconfig(t, 1)
+	defer cleanup()
+	provider, err := NewProvider(kubeconfigPath)
+	if err != nil {
+		t.Fatalf(errNewProvider, err)
+	}
+	scoped, _ := provider.ForContext(testContext1)
+	dynamicClient, err := scoped.DynamicClient()
+	if err != nil {
+		t.Fatalf("DynamicClient() failed: %v", err)
+	}
+	if again, _ := provider.createDynamicClient(testContext1); again != dynamicClient {
+		t.Error("the dynamic client should be reused")
+	}
+	clientset, _ := provider.Clientset(testContext1)
+	if shared, _ := scoped.Clientset(); shared != clientset {
+		t.Error("Clientset() should return the scoped clientset")
+	}
+	_, transport, err := scoped.transport()
+	if err != nil || transport != scoped.httpClient.Transport {
+		t.Errorf("transport() should return the shared transport: %v", err)
+	}
+
+	scoped.Configure(ContextSettings{QPS: 20})
+	if rebuilt, _ := scoped.DynamicClient(); rebuilt == dynamicClient {
+		t.Error("a new rate limit should rebuild the dynamic client")
+	}
+}
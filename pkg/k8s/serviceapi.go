@@ -0,0 +1,68 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains GET requests to HTTP APIs running in or next to a cluster.
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxServiceAPIBody bounds the responses read from url endpoints.
+const maxServiceAPIBody = 8 << 20
+
+// serviceAPI is an HTTP API (Prometheus, Loki, Elasticsearch) reached at a
+// URL from this machine, or as a Service through the API server proxy.
+type serviceAPI struct {
+	url       string
+	tokenEnv  string // environment variable holding a bearer token for url
+	namespace string
+	service   string
+	port      int
+}
+
+// get requests path with the query params and returns the response body.
+// Error responses with a JSON body are returned as the body so callers can
+// report the API's own error message.
+func (a serviceAPI) get(ctx context.Context, clientset kubernetes.Interface, client *http.Client, path string, params map[string]string) ([]byte, error) {
+	if a.url == "" {
+		return clientset.CoreV1().Services(a.namespace).
+			ProxyGet("http", a.service, strconv.Itoa(a.port), path, params).DoRaw(ctx)
+	}
+
+	values := url.Values{}
+	for k, v := range params {
+		values.Set(k, v)
+	}
+	target := strings.TrimSuffix(a.url, "/") + path + "?" + values.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if a.tokenEnv != "" {
+		if token := os.Getenv(a.tokenEnv); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxServiceAPIBody))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && !json.Valid(body) {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
@@ -4,6 +4,7 @@ package k8s
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
@@ -23,16 +24,49 @@ type ClusterInfo struct {
 // ClusterStatus represents detailed status information about a cluster
 type ClusterStatus struct {
 	ClusterInfo
-	Version       string
-	NodeCount     int
-	HealthyNodes  int
-	Nodes         []NodeInfo
-	NamespaceList []string
-	APIServerURL  string
-	Error         string
-	PodCount      int
-	HealthyPods   int
-	UnhealthyPods []PodInfo
+	Version      string
+	NodeCount    int
+	HealthyNodes int
+	Nodes        []NodeInfo
+	// OSDistribution counts nodes per operating system (e.g. linux: 5, windows: 2).
+	OSDistribution map[string]int
+	// ArchDistribution counts nodes per CPU architecture (e.g. amd64: 4, arm64: 2).
+	ArchDistribution map[string]int
+	NamespaceList    []string
+	APIServerURL     string
+	Error            string
+	PodCount         int
+	HealthyPods      int
+	UnhealthyPods    []PodInfo
+	// ExposureIssues lists Ingresses and LoadBalancer Services with problems.
+	ExposureIssues []ExposureIssue
+	// AutoscalingIssues lists HorizontalPodAutoscalers that cannot scale,
+	// cannot read their metrics or are at their maximum.
+	AutoscalingIssues []AutoscalingIssue
+	// KubeletSkew lists nodes whose kubelet is more than one minor version
+	// older or newer than the API server.
+	KubeletSkew []KubeletSkew
+	// ControlPlane is the health of the API server, scheduler, controller
+	// manager and etcd; empty when the control plane is not visible, as on
+	// managed clusters.
+	ControlPlane []ControlPlaneComponent
+	// Cost is the estimated running cost; nil unless a price table is set.
+	Cost *CostEstimate
+	// Transient is set when Error comes from an API server that was reached
+	// but kept throttling or timing out through the retries, rather than one
+	// that could not be reached.
+	Transient bool
+	// Filter scoped the nodes and pods of the status; see
+	// GetFilteredClusterStatus.
+	Filter HealthFilter
+	// PodsTruncated is set when the pod check stopped at the pod cap, or
+	// when the listing expired part way, so PodCount, HealthyPods and
+	// UnhealthyPods cover only the pods listed.
+	PodsTruncated bool
+	// Failure is the cause of Error, and Advice how to fix it, when the
+	// check failed; Advice is empty for an unknown cause.
+	Failure FailureKind
+	Advice  string
 }
 
 // NodeInfo represents information about a Kubernetes node
@@ -41,6 +75,29 @@ type NodeInfo struct {
 	Status string
 	Roles  []string
 	Age    string
+	OS     string
+	Arch   string
+	// KubeletVersion, ContainerRuntime, OSImage and KernelVersion come from
+	// the node's system info, e.g. "v1.29.4", "containerd://1.7.13",
+	// "Ubuntu 22.04.4 LTS" and "5.15.0-1057-aws".
+	KubeletVersion   string
+	ContainerRuntime string
+	OSImage          string
+	KernelVersion    string
+	// InternalIP and ExternalIP are the node's first addresses of each type.
+	InternalIP string
+	ExternalIP string
+}
+
+// KubeletSkew is a node whose kubelet is more than one minor version away
+// from the API server.
+type KubeletSkew struct {
+	Node             string
+	KubeletVersion   string
+	APIServerVersion string
+	// MinorVersions is the kubelet minor version minus the API server's;
+	// negative when the kubelet is older.
+	MinorVersions int
 }
 
 // PodInfo represents information about an unhealthy pod
@@ -50,25 +107,70 @@ type PodInfo struct {
 	Status    string
 	Reason    string
 	Restarts  int32
+	// Created is the pod creation time.
+	Created time.Time
 }
 
 // CachedClusterStatus holds a cached cluster status with expiration
 type CachedClusterStatus struct {
 	Status    *ClusterStatus
 	ExpiresAt time.Time
+	// CheckedAt is when the status was stored.
+	CheckedAt time.Time
 }
 
 // Provider manages Kubernetes cluster information and operations
 type Provider struct {
 	kubeconfigPath string
 	rawConfig      *clientcmdapi.Config
+
+	// clustersMutex guards clusters and currentContext, which
+	// SetCurrentContext changes while checks read them. Callers get copies
+	// of the ClusterInfo values.
+	clustersMutex  sync.RWMutex
 	clusters       map[string]*ClusterInfo
 	currentContext string
 
-	// Caching support
+	// Caching support: cacheTTL is the default status TTL; statuses are
+	// cached per context in scopes.
 	cacheMutex sync.RWMutex
-	cache      map[string]*CachedClusterStatus
 	cacheTTL   time.Duration
+	// cacheGeneration counts the changes that make results derived from
+	// the clusters stale before the TTL: dropped statuses, a reload and a
+	// switch of the current context. See CacheGeneration.
+	cacheGeneration atomic.Uint64
+
+	// scopes are the context-scoped providers created by ForContext.
+	scopesMutex sync.Mutex
+	scopes      map[string]*ContextProvider
+
+	// discovery caches the API resources of each cluster.
+	discoveryMutex sync.Mutex
+	discovery      map[string]*discoveryEntry
+
+	// priceTable enables cost estimates; nil when cost estimation is off.
+	priceTable *PriceTable
+
+	// advisoryFeed replaces the bundled node advisory feed when set.
+	advisoryFeed *AdvisoryFeed
+
+	// apiTimeout bounds each API call, discoveryTimeout version checks, and
+	// apiRetries is how often transient API errors are retried. They are set
+	// at construction.
+	apiTimeout       time.Duration
+	discoveryTimeout time.Duration
+	apiRetries       int
+
+	// maxPods caps the pods a cluster status counts; 0 means no cap. Set at
+	// construction.
+	maxPods int
+
+	// liveAfter is the number of queries of a context after which its
+	// informers start; 0 disables live state. Set at construction.
+	liveAfter int
+	// reachTimeout bounds the TCP probe of an API server before a status
+	// check; 0 skips it. Set at construction.
+	reachTimeout time.Duration
 }
 
 // SanitizeSeverity defines the severity level of a sanitize finding
@@ -112,4 +214,8 @@ type SanitizeResult struct {
 	MajorCount     int                      `json:"major_count"`
 	MinorCount     int                      `json:"minor_count"`
 	Namespaces     []NamespaceSanitizeScore `json:"namespaces"`
+	// NodeAdvisories are known issues of the node kernels, OS images,
+	// runtimes and kubelets; they are reported beside the workload score.
+	NodeAdvisories []NodeAdvisory `json:"node_advisories,omitempty"`
+	AdvisoryFeed   string         `json:"advisory_feed,omitempty"`
 }
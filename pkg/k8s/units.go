@@ -0,0 +1,115 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains helpers for rendering CPU and memory quantities.
+package k8s
+
+import (
+	"math"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// CPUAmount carries a CPU quantity both as integer millicores (for machines)
+// and as a rounded human-readable string (for people).
+type CPUAmount struct {
+	Millicores int64  `json:"millicores"`
+	Human      string `json:"human"`
+}
+
+// MemoryAmount carries a memory quantity both as bytes (for machines)
+// and as a rounded binary-unit string such as "1.5Gi" (for people).
+type MemoryAmount struct {
+	Bytes int64  `json:"bytes"`
+	Human string `json:"human"`
+}
+
+// NewCPUAmount converts a resource.Quantity into a CPUAmount.
+func NewCPUAmount(q resource.Quantity) CPUAmount {
+	return CPUAmountFromMillicores(q.MilliValue())
+}
+
+// CPUAmountFromMillicores builds a CPUAmount from a millicore count.
+func CPUAmountFromMillicores(m int64) CPUAmount {
+	return CPUAmount{Millicores: m, Human: FormatMillicores(m)}
+}
+
+// NewMemoryAmount converts a resource.Quantity into a MemoryAmount.
+func NewMemoryAmount(q resource.Quantity) MemoryAmount {
+	return MemoryAmountFromBytes(q.Value())
+}
+
+// MemoryAmountFromBytes builds a MemoryAmount from a byte count.
+func MemoryAmountFromBytes(b int64) MemoryAmount {
+	return MemoryAmount{Bytes: b, Human: FormatMemoryBytes(b)}
+}
+
+// FormatMillicores renders millicores the way kubectl users expect:
+// values below one core stay in millicores ("250m"), whole and fractional
+// cores are shown with at most one decimal ("2", "1.5").
+func FormatMillicores(m int64) string {
+	if m < 0 {
+		return "-" + FormatMillicores(-m)
+	}
+	if m < 1000 {
+		return strconv.FormatInt(m, 10) + "m"
+	}
+	return formatOneDecimal(float64(m) / 1000)
+}
+
+// memoryUnits lists the binary suffixes used by FormatMemoryBytes, smallest first.
+var memoryUnits = []string{"Ki", "Mi", "Gi", "Ti", "Pi", "Ei"}
+
+// FormatMemoryBytes renders a byte count using binary suffixes (Ki, Mi, Gi, …)
+// with at most one decimal, matching the units used in Kubernetes manifests.
+func FormatMemoryBytes(b int64) string {
+	if b < 0 {
+		return "-" + FormatMemoryBytes(-b)
+	}
+	if b < 1024 {
+		return strconv.FormatInt(b, 10)
+	}
+	v := float64(b)
+	unit := ""
+	for _, u := range memoryUnits {
+		if v < 1024 {
+			break
+		}
+		v /= 1024
+		unit = u
+	}
+	// Rounding 1023.96Mi up would print "1024Mi"; promote it to the next unit instead.
+	if roundOneDecimal(v) >= 1024 && unit != memoryUnits[len(memoryUnits)-1] {
+		for i, u := range memoryUnits {
+			if u == unit {
+				v /= 1024
+				unit = memoryUnits[i+1]
+				break
+			}
+		}
+	}
+	return formatOneDecimal(v) + unit
+}
+
+// FormatPercent renders part/total as a percentage with one decimal.
+// A zero total yields "0%" rather than NaN.
+func FormatPercent(part, total int64) string {
+	return formatOneDecimal(Percent(part, total)) + "%"
+}
+
+// Percent returns part/total*100 rounded to one decimal, or 0 when total is 0.
+func Percent(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return roundOneDecimal(float64(part) / float64(total) * 100)
+}
+
+// roundOneDecimal rounds half away from zero to one decimal place.
+func roundOneDecimal(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// formatOneDecimal rounds to one decimal and drops a trailing ".0".
+func formatOneDecimal(v float64) string {
+	return strconv.FormatFloat(roundOneDecimal(v), 'f', -1, 64)
+}
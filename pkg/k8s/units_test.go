@@ -0,0 +1,90 @@
+package k8s
+
+import (
+	"encoding/json"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestFormatMillicores(t *testing.T) {
+	tests := []struct {
+		in   int64
+		want string
+	}{
+		{0, "0m"},
+		{250, "250m"},
+		{999, "999m"},
+		{1000, "1"},
+		{1500, "1.5"},
+		{1549, "1.5"},
+		{1550, "1.6"},
+		{64000, "64"},
+		{-500, "-500m"},
+	}
+	for _, tt := range tests {
+		if got := FormatMillicores(tt.in); got != tt.want {
+			t.Errorf("FormatMillicores(%d) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatMemoryBytes(t *testing.T) {
+	tests := []struct {
+		in   int64
+		want string
+	}{
+		{0, "0"},
+		{512, "512"},
+		{1024, "1Ki"},
+		{128 * 1024 * 1024, "128Mi"},
+		{1536 * 1024 * 1024, "1.5Gi"},
+		{1024*1024*1024 - 1024, "1Gi"}, // 1023.999Mi rounds up into the next unit
+		{16 * 1024 * 1024 * 1024 * 1024, "16Ti"},
+		{-1024, "-1Ki"},
+	}
+	for _, tt := range tests {
+		if got := FormatMemoryBytes(tt.in); got != tt.want {
+			t.Errorf("FormatMemoryBytes(%d) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNewCPUAndMemoryAmount(t *testing.T) {
+	cpu := NewCPUAmount(resource.MustParse("2500m"))
+	if cpu.Millicores != 2500 || cpu.Human != "2.5" {
+		t.Errorf("NewCPUAmount(2500m) = %+v", cpu)
+	}
+	mem := NewMemoryAmount(resource.MustParse("512Mi"))
+	if mem.Bytes != 512*1024*1024 || mem.Human != "512Mi" {
+		t.Errorf("NewMemoryAmount(512Mi) = %+v", mem)
+	}
+	// Decimal SI suffixes are normalised into binary units.
+	mem = NewMemoryAmount(resource.MustParse("1G"))
+	if mem.Bytes != 1000000000 || mem.Human != "953.7Mi" {
+		t.Errorf("NewMemoryAmount(1G) = %+v", mem)
+	}
+
+	b, err := json.Marshal(cpu)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(b) != `{"millicores":2500,"human":"2.5"}` {
+		t.Errorf("CPUAmount JSON = %s", b)
+	}
+}
+
+func TestPercent(t *testing.T) {
+	if got := Percent(1, 3); got != 33.3 {
+		t.Errorf("Percent(1,3) = %v, want 33.3", got)
+	}
+	if got := Percent(5, 0); got != 0 {
+		t.Errorf("Percent(5,0) = %v, want 0", got)
+	}
+	if got := FormatPercent(2, 3); got != "66.7%" {
+		t.Errorf("FormatPercent(2,3) = %q", got)
+	}
+	if got := FormatPercent(4, 4); got != "100%" {
+		t.Errorf("FormatPercent(4,4) = %q", got)
+	}
+}
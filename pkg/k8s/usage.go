@@ -0,0 +1,347 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains metrics-server usage sampling and resource recommendations.
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// metricsPodsPath is the metrics-server endpoint for pod usage.
+const metricsPodsPath = "/apis/metrics.k8s.io/v1beta1"
+
+// MinRecommendationSamples is the sample count below which recommendations are low confidence.
+const MinRecommendationSamples = 12
+
+// ContainerUsage is one container's observed usage alongside its configured resources.
+type ContainerUsage struct {
+	Namespace     string `json:"namespace"`
+	Workload      string `json:"workload"`
+	Pod           string `json:"pod"`
+	Container     string `json:"container"`
+	CPUMilli      int64  `json:"cpu_m"`
+	MemoryBytes   int64  `json:"memory_bytes"`
+	RequestCPU    int64  `json:"request_cpu_m,omitempty"`
+	RequestMemory int64  `json:"request_memory_bytes,omitempty"`
+	LimitCPU      int64  `json:"limit_cpu_m,omitempty"`
+	LimitMemory   int64  `json:"limit_memory_bytes,omitempty"`
+}
+
+// UsageSample is a point-in-time snapshot of container usage in one namespace.
+type UsageSample struct {
+	Time       time.Time        `json:"time"`
+	Context    string           `json:"context"`
+	Namespace  string           `json:"namespace"`
+	Containers []ContainerUsage `json:"containers"`
+}
+
+// podMetricsList mirrors the metrics.k8s.io PodMetricsList fields we use,
+// avoiding a dependency on the metrics client.
+type podMetricsList struct {
+	Items []podMetrics `json:"items"`
+}
+
+type podMetrics struct {
+	Metadata   metav1.ObjectMeta  `json:"metadata"`
+	Containers []containerMetrics `json:"containers"`
+}
+
+type containerMetrics struct {
+	Name  string              `json:"name"`
+	Usage corev1.ResourceList `json:"usage"`
+}
+
+// workloadName resolves the controller that owns a pod as "kind/name",
+// collapsing ReplicaSets to their Deployment and Jobs to their CronJob
+// when the names follow the controller conventions.
+func workloadName(pod *corev1.Pod) string {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Controller == nil || !*ref.Controller {
+			continue
+		}
+		switch ref.Kind {
+		case "ReplicaSet":
+			if hash := pod.Labels["pod-template-hash"]; hash != "" && strings.HasSuffix(ref.Name, "-"+hash) {
+				return "deployment/" + strings.TrimSuffix(ref.Name, "-"+hash)
+			}
+			return "replicaset/" + ref.Name
+		case "Job":
+			// CronJob-created jobs are named <cronjob>-<scheduled minute>.
+			if i := strings.LastIndex(ref.Name, "-"); i > 0 {
+				if _, err := strconv.ParseInt(ref.Name[i+1:], 10, 64); err == nil {
+					return "cronjob/" + ref.Name[:i]
+				}
+			}
+			return "job/" + ref.Name
+		default:
+			return strings.ToLower(ref.Kind) + "/" + ref.Name
+		}
+	}
+	return "pod/" + pod.Name
+}
+
+// buildUsageSample joins pod metrics with the pod specs they belong to.
+func buildUsageSample(metrics podMetricsList, pods []corev1.Pod) []ContainerUsage {
+	podByName := make(map[string]*corev1.Pod, len(pods))
+	for i := range pods {
+		podByName[pods[i].Name] = &pods[i]
+	}
+
+	var out []ContainerUsage
+	for _, item := range metrics.Items {
+		pod := podByName[item.Metadata.Name]
+		if pod == nil {
+			continue
+		}
+		specs := make(map[string]corev1.Container, len(pod.Spec.Containers))
+		for _, c := range pod.Spec.Containers {
+			specs[c.Name] = c
+		}
+		workload := workloadName(pod)
+		for _, c := range item.Containers {
+			cpu, mem := c.Usage[corev1.ResourceCPU], c.Usage[corev1.ResourceMemory]
+			usage := ContainerUsage{
+				Namespace:   pod.Namespace,
+				Workload:    workload,
+				Pod:         pod.Name,
+				Container:   c.Name,
+				CPUMilli:    cpu.MilliValue(),
+				MemoryBytes: mem.Value(),
+			}
+			if spec, ok := specs[c.Name]; ok {
+				usage.RequestCPU = spec.Resources.Requests.Cpu().MilliValue()
+				usage.RequestMemory = spec.Resources.Requests.Memory().Value()
+				usage.LimitCPU = spec.Resources.Limits.Cpu().MilliValue()
+				usage.LimitMemory = spec.Resources.Limits.Memory().Value()
+			}
+			out = append(out, usage)
+		}
+	}
+	sort.Slice(out, func(a, b int) bool {
+		if out[a].Namespace != out[b].Namespace {
+			return out[a].Namespace < out[b].Namespace
+		}
+		if out[a].Workload != out[b].Workload {
+			return out[a].Workload < out[b].Workload
+		}
+		if out[a].Pod != out[b].Pod {
+			return out[a].Pod < out[b].Pod
+		}
+		return out[a].Container < out[b].Container
+	})
+	return out
+}
+
+// fetchPodMetrics reads pod metrics for namespace (all namespaces if empty)
+// from metrics-server.
+func fetchPodMetrics(ctx context.Context, clientset kubernetes.Interface, namespace string) (podMetricsList, error) {
+	path := metricsPodsPath + "/pods"
+	if namespace != "" {
+		path = metricsPodsPath + "/namespaces/" + namespace + "/pods"
+	}
+	var metrics podMetricsList
+	raw, err := clientset.CoreV1().RESTClient().Get().AbsPath(path).DoRaw(ctx)
+	if err != nil {
+		return metrics, fmt.Errorf("metrics API unavailable (is metrics-server installed?): %w", err)
+	}
+	if err := json.Unmarshal(raw, &metrics); err != nil {
+		return metrics, fmt.Errorf("failed to parse pod metrics: %w", err)
+	}
+	return metrics, nil
+}
+
+// collectUsageSample reads pod metrics and specs for namespace.
+func collectUsageSample(ctx context.Context, clientset kubernetes.Interface, namespace string) ([]ContainerUsage, error) {
+	metrics, err := fetchPodMetrics(ctx, clientset, namespace)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	return buildUsageSample(metrics, pods.Items), nil
+}
+
+// SampleUsage takes a usage snapshot of namespace (all namespaces if empty)
+// from metrics-server.
+func (p *Provider) SampleUsage(ctx context.Context, contextName, namespace string) (*UsageSample, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	containers, err := collectUsageSample(queryCtx, clientset, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &UsageSample{Time: time.Now(), Context: contextName, Namespace: namespace, Containers: containers}, nil
+}
+
+// ContainerRecommendation is the recommended resources for one container of a workload.
+type ContainerRecommendation struct {
+	Workload      string       `json:"workload"`
+	Container     string       `json:"container"`
+	Samples       int          `json:"samples"`
+	P95CPU        CPUAmount    `json:"p95_cpu"`
+	P95Memory     MemoryAmount `json:"p95_memory"`
+	MaxMemory     MemoryAmount `json:"max_memory"`
+	CurrentCPU    CPUAmount    `json:"current_request_cpu"`
+	CurrentMemory MemoryAmount `json:"current_request_memory"`
+	RequestCPU    CPUAmount    `json:"request_cpu"`
+	RequestMemory MemoryAmount `json:"request_memory"`
+	LimitMemory   MemoryAmount `json:"limit_memory"`
+	// Replicas is the highest number of pods seen for the workload in one sample.
+	Replicas int `json:"replicas"`
+}
+
+// QuotaRecommendation is a namespace ResourceQuota sized from the container recommendations.
+type QuotaRecommendation struct {
+	RequestsCPU    CPUAmount    `json:"requests_cpu"`
+	RequestsMemory MemoryAmount `json:"requests_memory"`
+	LimitsMemory   MemoryAmount `json:"limits_memory"`
+	Pods           int          `json:"pods"`
+}
+
+// ResourceRecommendation is the output of RecommendResources.
+type ResourceRecommendation struct {
+	Samples    int                       `json:"samples"`
+	From       time.Time                 `json:"from"`
+	To         time.Time                 `json:"to"`
+	Containers []ContainerRecommendation `json:"containers"`
+	Quota      QuotaRecommendation       `json:"quota"`
+}
+
+// percentile returns the p-th percentile (0-100) of values using nearest rank.
+func percentile(values []int64, p float64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank-1, 0)]
+}
+
+// roundUp rounds v up to a multiple of step.
+func roundUp(v, step int64) int64 {
+	if v <= 0 {
+		return step
+	}
+	return (v + step - 1) / step * step
+}
+
+// RecommendResources derives requests and memory limits from observed usage:
+// requests are the P95 plus headroom (a fraction, e.g. 0.15), memory limits
+// the peak plus twice the headroom. CPU limits are deliberately not
+// recommended because they cause throttling without protecting neighbours.
+// The quota covers the peak replica count of every workload plus headroom.
+func RecommendResources(samples []UsageSample, headroom float64) *ResourceRecommendation {
+	rec := &ResourceRecommendation{Samples: len(samples), Containers: []ContainerRecommendation{}}
+	if len(samples) == 0 {
+		return rec
+	}
+
+	type series struct {
+		cpu, mem      []int64
+		latest        ContainerUsage
+		latestAt      time.Time
+		replicaCounts map[int]int // sample index -> pods seen
+	}
+	byKey := make(map[string]*series)
+	var keys []string
+	rec.From, rec.To = samples[0].Time, samples[0].Time
+	for i, s := range samples {
+		if s.Time.Before(rec.From) {
+			rec.From = s.Time
+		}
+		if s.Time.After(rec.To) {
+			rec.To = s.Time
+		}
+		for _, c := range s.Containers {
+			key := c.Workload + "\x00" + c.Container
+			sr := byKey[key]
+			if sr == nil {
+				sr = &series{replicaCounts: make(map[int]int)}
+				byKey[key] = sr
+				keys = append(keys, key)
+			}
+			sr.cpu = append(sr.cpu, c.CPUMilli)
+			sr.mem = append(sr.mem, c.MemoryBytes)
+			sr.replicaCounts[i]++
+			if !s.Time.Before(sr.latestAt) {
+				sr.latest, sr.latestAt = c, s.Time
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	var quotaCPU, quotaMem, quotaLimitMem int64
+	for _, key := range keys {
+		sr := byKey[key]
+		p95CPU, p95Mem := percentile(sr.cpu, 95), percentile(sr.mem, 95)
+		maxMem := percentile(sr.mem, 100)
+		replicas := 0
+		for _, n := range sr.replicaCounts {
+			replicas = max(replicas, n)
+		}
+		reqCPU := roundUp(int64(float64(p95CPU)*(1+headroom)), 5)
+		reqMem := roundUp(int64(float64(p95Mem)*(1+headroom)), 1<<20)
+		limMem := roundUp(int64(float64(maxMem)*(1+2*headroom)), 1<<20)
+		rec.Containers = append(rec.Containers, ContainerRecommendation{
+			Workload:      sr.latest.Workload,
+			Container:     sr.latest.Container,
+			Samples:       len(sr.cpu),
+			P95CPU:        CPUAmountFromMillicores(p95CPU),
+			P95Memory:     MemoryAmountFromBytes(p95Mem),
+			MaxMemory:     MemoryAmountFromBytes(maxMem),
+			CurrentCPU:    CPUAmountFromMillicores(sr.latest.RequestCPU),
+			CurrentMemory: MemoryAmountFromBytes(sr.latest.RequestMemory),
+			RequestCPU:    CPUAmountFromMillicores(reqCPU),
+			RequestMemory: MemoryAmountFromBytes(reqMem),
+			LimitMemory:   MemoryAmountFromBytes(limMem),
+			Replicas:      replicas,
+		})
+		quotaCPU += reqCPU * int64(replicas)
+		quotaMem += reqMem * int64(replicas)
+		quotaLimitMem += limMem * int64(replicas)
+	}
+
+	pods := 0
+	seenWorkload := make(map[string]bool)
+	for _, c := range rec.Containers {
+		if !seenWorkload[c.Workload] {
+			seenWorkload[c.Workload] = true
+			pods += c.Replicas
+		}
+	}
+	rec.Quota = QuotaRecommendation{
+		RequestsCPU:    CPUAmountFromMillicores(roundUp(int64(float64(quotaCPU)*(1+headroom)), 100)),
+		RequestsMemory: MemoryAmountFromBytes(roundUp(int64(float64(quotaMem)*(1+headroom)), 64<<20)),
+		LimitsMemory:   MemoryAmountFromBytes(roundUp(int64(float64(quotaLimitMem)*(1+headroom)), 64<<20)),
+		Pods:           int(math.Ceil(float64(pods) * (1 + headroom))),
+	}
+	return rec
+}
+
+// QuantityString renders a CPU or memory amount in manifest syntax ("250m", "512Mi").
+func QuantityString(v int64, memory bool) string {
+	if memory {
+		return resource.NewQuantity(v, resource.BinarySI).String()
+	}
+	return resource.NewMilliQuantity(v, resource.DecimalSI).String()
+}
@@ -0,0 +1,135 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func ownedPod(name, kind, owner string, labels map[string]string) corev1.Pod {
+	controller := true
+	return corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: name, Namespace: "apps", Labels: labels,
+		OwnerReferences: []metav1.OwnerReference{{Kind: kind, Name: owner, Controller: &controller}},
+	}}
+}
+
+func TestWorkloadName(t *testing.T) {
+	tests := []struct {
+		pod  corev1.Pod
+		want string
+	}{
+		{ownedPod("web-7d9f-abc", "ReplicaSet", "web-7d9f", map[string]string{"pod-template-hash": "7d9f"}), "deployment/web"},
+		{ownedPod("rs-abc", "ReplicaSet", "rs", nil), "replicaset/rs"},
+		{ownedPod("db-0", "StatefulSet", "db", nil), "statefulset/db"},
+		{ownedPod("backup-28930080-x", "Job", "backup-28930080", nil), "cronjob/backup"},
+		{ownedPod("migrate-x", "Job", "migrate", nil), "job/migrate"},
+		{corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "bare"}}, "pod/bare"},
+	}
+	for _, tt := range tests {
+		if got := workloadName(&tt.pod); got != tt.want {
+			t.Errorf("workloadName(%s) = %q, want %q", tt.pod.Name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildUsageSample(t *testing.T) {
+	pod := ownedPod("web-1-a", "ReplicaSet", "web-1", map[string]string{"pod-template-hash": "1"})
+	pod.Spec.Containers = []corev1.Container{{
+		Name: "app",
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+		},
+	}}
+
+	metrics := podMetricsList{Items: []podMetrics{
+		{
+			Metadata: metav1.ObjectMeta{Name: "web-1-a"},
+			Containers: []containerMetrics{{Name: "app", Usage: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("42m"), corev1.ResourceMemory: resource.MustParse("100Mi"),
+			}}},
+		},
+		{Metadata: metav1.ObjectMeta{Name: "gone"}}, // pod deleted between the two calls
+	}}
+
+	got := buildUsageSample(metrics, []corev1.Pod{pod})
+	if len(got) != 1 {
+		t.Fatalf("buildUsageSample() returned %d containers, want 1", len(got))
+	}
+	c := got[0]
+	if c.Workload != "deployment/web" || c.Namespace != "apps" || c.CPUMilli != 42 || c.MemoryBytes != 100<<20 {
+		t.Errorf("unexpected usage %+v", c)
+	}
+	if c.RequestCPU != 100 || c.RequestMemory != 128<<20 || c.LimitMemory != 256<<20 || c.LimitCPU != 0 {
+		t.Errorf("unexpected configured resources %+v", c)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []int64{5, 1, 4, 2, 3, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}
+	if got := percentile(values, 95); got != 19 {
+		t.Errorf("percentile(95) = %d, want 19", got)
+	}
+	if got := percentile(values, 100); got != 20 {
+		t.Errorf("percentile(100) = %d, want 20", got)
+	}
+	if got := percentile(nil, 95); got != 0 {
+		t.Errorf("percentile(nil) = %d, want 0", got)
+	}
+}
+
+func TestRecommendResources(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var samples []UsageSample
+	for i := range 20 {
+		samples = append(samples, UsageSample{
+			Time: start.Add(time.Duration(i) * time.Hour),
+			Containers: []ContainerUsage{
+				{Workload: "deployment/web", Pod: "web-a", Container: "app", CPUMilli: int64(10 * (i + 1)), MemoryBytes: int64(i+1) << 20},
+				{Workload: "deployment/web", Pod: "web-b", Container: "app", CPUMilli: 10, MemoryBytes: 1 << 20},
+			},
+		})
+	}
+
+	rec := RecommendResources(samples, 0.1)
+	if rec.Samples != 20 || !rec.From.Equal(start) || !rec.To.Equal(start.Add(19*time.Hour)) {
+		t.Errorf("unexpected basis: %d samples %v..%v", rec.Samples, rec.From, rec.To)
+	}
+	if len(rec.Containers) != 1 {
+		t.Fatalf("got %d container recommendations, want 1", len(rec.Containers))
+	}
+	c := rec.Containers[0]
+	if c.Replicas != 2 || c.Samples != 40 {
+		t.Errorf("replicas/samples = %d/%d, want 2/40", c.Replicas, c.Samples)
+	}
+	// 40 values: twenty at 10m plus 10m..200m; nearest-rank P95 is the 38th value, 180m.
+	if c.P95CPU.Millicores != 180 || c.RequestCPU.Millicores != 200 {
+		t.Errorf("P95/request CPU = %d/%d, want 180/200", c.P95CPU.Millicores, c.RequestCPU.Millicores)
+	}
+	if c.MaxMemory.Bytes != 20<<20 || c.LimitMemory.Bytes != 24<<20 {
+		t.Errorf("max/limit memory = %d/%d", c.MaxMemory.Bytes, c.LimitMemory.Bytes)
+	}
+	if rec.Quota.Pods != 3 || rec.Quota.RequestsCPU.Millicores != 500 {
+		t.Errorf("quota = %+v", rec.Quota)
+	}
+}
+
+func TestRecommendResourcesEmpty(t *testing.T) {
+	rec := RecommendResources(nil, 0.15)
+	if rec.Samples != 0 || len(rec.Containers) != 0 {
+		t.Errorf("expected empty recommendation, got %+v", rec)
+	}
+}
+
+func TestQuantityString(t *testing.T) {
+	if got := QuantityString(250, false); got != "250m" {
+		t.Errorf("QuantityString(250m) = %q", got)
+	}
+	if got := QuantityString(512<<20, true); got != "512Mi" {
+		t.Errorf("QuantityString(512Mi) = %q", got)
+	}
+}
@@ -0,0 +1,420 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains bounded watches of pods, directly or through their
+// Deployment, StatefulSet or DaemonSet, condensed to the changes that matter:
+// phase, readiness, restarts and the waiting or termination reason.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// DefaultWatchDuration bounds a watch that sets no duration.
+const DefaultWatchDuration = 2 * time.Minute
+
+// maxWatchChanges caps the changes kept in a WatchResult; later changes are
+// still reported through Progress.
+const maxWatchChanges = 200
+
+// KindPod selects pods by name or label selector rather than through a
+// workload.
+const KindPod = "Pod"
+
+// watchRetryInterval is how long a watch waits before listing again after
+// the API server ended it with an error; a variable so tests run fast.
+var watchRetryInterval = time.Second
+
+// Change events of a PodChange.
+const (
+	PodAdded   = "added"
+	PodChanged = "changed"
+	PodDeleted = "deleted"
+)
+
+// NormalizeWatchKind maps kubectl spellings of pods and workloads to the kind
+// a watch supports; an empty kind is Pod.
+func NormalizeWatchKind(kind string) (string, error) {
+	switch strings.ToLower(kind) {
+	case "", "pod", "pods", "po":
+		return KindPod, nil
+	}
+	return NormalizeWorkloadKind(kind)
+}
+
+// WatchedPod is the condensed state of a watched pod.
+type WatchedPod struct {
+	Name     string `json:"name"`
+	Phase    string `json:"phase"`
+	Ready    string `json:"ready"`
+	Restarts int32  `json:"restarts"`
+	Reason   string `json:"reason,omitempty"`
+	Healthy  bool   `json:"healthy"`
+}
+
+// PodChange is one change of a watched pod's condensed state.
+type PodChange struct {
+	// At is the time since the watch started.
+	At    string `json:"at"`
+	Event string `json:"event"`
+	WatchedPod
+}
+
+// WatchResult is the outcome of WatchPods.
+type WatchResult struct {
+	Context   string `json:"context"`
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name,omitempty"`
+	Selector  string `json:"selector,omitempty"`
+	// Duration is how long the pods were watched.
+	Duration string      `json:"duration"`
+	Changes  []PodChange `json:"changes"`
+	// ChangesTruncated is set when more changes happened than were kept.
+	ChangesTruncated bool `json:"changes_truncated,omitempty"`
+	// Pods is the state of every pod when the watch ended, by name.
+	Pods    []WatchedPod `json:"pods"`
+	Healthy int          `json:"healthy"`
+	// Restarts counts the container restarts during the watch.
+	Restarts int32 `json:"restarts"`
+	// Settled is set when the watch ended early because every pod was
+	// healthy, as WatchOptions.UntilHealthy asks.
+	Settled bool   `json:"settled,omitempty"`
+	Message string `json:"message"`
+}
+
+// WatchOptions controls WatchPods.
+type WatchOptions struct {
+	// Duration bounds the watch; zero means DefaultWatchDuration.
+	Duration time.Duration
+	// LabelSelector selects the pods of kind Pod when no name is given.
+	LabelSelector string
+	// UntilHealthy ends the watch as soon as there are pods and every one
+	// is healthy.
+	UntilHealthy bool
+	// Progress, when set, receives every change as it happens.
+	Progress func(PodChange)
+}
+
+// WatchPods watches the pods of a workload, a single pod or the pods
+// matching opts.LabelSelector for opts.Duration and reports their condensed
+// changes. Running out of time is how a watch normally ends, not an error.
+func (p *Provider) WatchPods(ctx context.Context, contextName, namespace, kind, name string, opts WatchOptions) (*WatchResult, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+	if opts.Duration <= 0 {
+		opts.Duration = DefaultWatchDuration
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	result, listOpts, err := resolveWatchTarget(queryCtx, clientset, namespace, kind, name, opts.LabelSelector)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	result.Context = contextName
+
+	watchCtx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+	if err := watchPods(watchCtx, clientset, listOpts, opts, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// resolveWatchTarget returns the result to fill in and the options that
+// select the target's pods: a workload's pod selector, a pod's name or the
+// given label selector.
+func resolveWatchTarget(ctx context.Context, clientset kubernetes.Interface, namespace, kind, name, labelSelector string) (*WatchResult, metav1.ListOptions, error) {
+	kind, err := NormalizeWatchKind(kind)
+	if err != nil {
+		return nil, metav1.ListOptions{}, err
+	}
+	result := &WatchResult{Namespace: namespace, Kind: kind, Name: name, Changes: []PodChange{}, Pods: []WatchedPod{}}
+	if kind == KindPod {
+		if name != "" {
+			return result, metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String()}, nil
+		}
+		if _, err := labels.Parse(labelSelector); err != nil {
+			return nil, metav1.ListOptions{}, fmt.Errorf("invalid label selector %q: %w", labelSelector, err)
+		}
+		result.Selector = labelSelector
+		return result, metav1.ListOptions{LabelSelector: labelSelector}, nil
+	}
+	if name == "" {
+		return nil, metav1.ListOptions{}, fmt.Errorf("a %s name is required", strings.ToLower(kind))
+	}
+
+	apps := clientset.AppsV1()
+	var selector *metav1.LabelSelector
+	switch kind {
+	case KindDeployment:
+		d, err := apps.Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, metav1.ListOptions{}, fmt.Errorf("failed to get deployment %s/%s: %w", namespace, name, err)
+		}
+		selector = d.Spec.Selector
+	case KindStatefulSet:
+		s, err := apps.StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, metav1.ListOptions{}, fmt.Errorf("failed to get statefulset %s/%s: %w", namespace, name, err)
+		}
+		selector = s.Spec.Selector
+	case KindDaemonSet:
+		ds, err := apps.DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, metav1.ListOptions{}, fmt.Errorf("failed to get daemonset %s/%s: %w", namespace, name, err)
+		}
+		selector = ds.Spec.Selector
+	}
+	parsed, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil || parsed.Empty() {
+		return nil, metav1.ListOptions{}, fmt.Errorf("%s %s/%s has no usable pod selector", strings.ToLower(kind), namespace, name)
+	}
+	result.Selector = parsed.String()
+	return result, metav1.ListOptions{LabelSelector: result.Selector}, nil
+}
+
+// podWatch tracks the watched pods and the changes recorded so far.
+type podWatch struct {
+	start  time.Time
+	opts   WatchOptions
+	result *WatchResult
+	pods   map[string]WatchedPod
+	// initialRestarts are the restarts of the pods present at the start.
+	initialRestarts map[string]int32
+	// changes counts every change, including those not kept.
+	changes int
+}
+
+// watchPods lists the selected pods, then watches them until ctx ends or, with
+// UntilHealthy, they are all healthy. When the API server ends the watch, it
+// resumes from the last seen version, or lists again after an error.
+func watchPods(ctx context.Context, clientset kubernetes.Interface, listOpts metav1.ListOptions, opts WatchOptions, result *WatchResult) error {
+	w := &podWatch{start: time.Now(), opts: opts, result: result, pods: map[string]WatchedPod{}, initialRestarts: map[string]int32{}}
+	pods := clientset.CoreV1().Pods(result.Namespace)
+	first := true
+	for ctx.Err() == nil {
+		list, err := pods.List(ctx, listOpts)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			if first {
+				return fmt.Errorf("failed to list pods in %s: %w", result.Namespace, err)
+			}
+			w.pause(ctx)
+			continue
+		}
+		w.sync(list.Items, first)
+		first = false
+		if w.settled() {
+			break
+		}
+		if w.follow(ctx, pods, listOpts, list.ResourceVersion) {
+			break
+		}
+		w.pause(ctx)
+	}
+	w.finish()
+	return nil
+}
+
+// follow watches from version until ctx ends or the pods settle, which it
+// reports, resuming the watch each time the API server closes it. It
+// returns false when the watch failed and the pods must be listed again.
+func (w *podWatch) follow(ctx context.Context, pods typedcorev1.PodInterface, listOpts metav1.ListOptions, version string) bool {
+	for {
+		opts := listOpts
+		opts.ResourceVersion = version
+		watcher, err := pods.Watch(ctx, opts)
+		if err != nil {
+			return ctx.Err() != nil
+		}
+		version, err = w.consume(ctx, watcher, version)
+		watcher.Stop()
+		if ctx.Err() != nil || w.settled() {
+			return true
+		}
+		if err != nil {
+			return false
+		}
+	}
+}
+
+// consume records the watcher's events until its channel closes or ctx
+// ends, returning the last seen resource version.
+func (w *podWatch) consume(ctx context.Context, watcher watch.Interface, version string) (string, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return version, nil
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return version, nil
+			}
+			if event.Type == watch.Error {
+				return version, fmt.Errorf("watch ended: %w", apierrors.FromObject(event.Object))
+			}
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok {
+				continue
+			}
+			version = pod.ResourceVersion
+			if event.Type == watch.Deleted {
+				w.remove(pod.Name)
+			} else {
+				w.update(pod)
+			}
+			if w.settled() {
+				return version, nil
+			}
+		}
+	}
+}
+
+// pause waits before listing again, unless ctx ends first.
+func (w *podWatch) pause(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(watchRetryInterval):
+	}
+}
+
+// sync records the difference between listed pods and the known ones; the
+// first listing is the starting state and records no changes.
+func (w *podWatch) sync(items []corev1.Pod, first bool) {
+	seen := make(map[string]bool, len(items))
+	for i := range items {
+		seen[items[i].Name] = true
+		if first {
+			state := watchedPod(&items[i])
+			w.pods[state.Name] = state
+			w.initialRestarts[state.Name] = state.Restarts
+			continue
+		}
+		w.update(&items[i])
+	}
+	for name := range w.pods {
+		if !seen[name] {
+			w.remove(name)
+		}
+	}
+}
+
+// update records a pod's state when its condensed state changed.
+func (w *podWatch) update(pod *corev1.Pod) {
+	state := watchedPod(pod)
+	previous, known := w.pods[state.Name]
+	if known && previous == state {
+		return
+	}
+	w.pods[state.Name] = state
+	event := PodChanged
+	if !known {
+		event = PodAdded
+	}
+	w.record(PodChange{Event: event, WatchedPod: state})
+}
+
+// remove records the deletion of a known pod.
+func (w *podWatch) remove(name string) {
+	state, known := w.pods[name]
+	if !known {
+		return
+	}
+	delete(w.pods, name)
+	state.Healthy = false
+	w.record(PodChange{Event: PodDeleted, WatchedPod: state})
+	// Restarts of a deleted pod still happened during the watch.
+	w.result.Restarts += state.Restarts - w.initialRestarts[name]
+	delete(w.initialRestarts, name)
+}
+
+func (w *podWatch) record(change PodChange) {
+	change.At = time.Since(w.start).Round(time.Second).String()
+	w.changes++
+	if len(w.result.Changes) < maxWatchChanges {
+		w.result.Changes = append(w.result.Changes, change)
+	} else {
+		w.result.ChangesTruncated = true
+	}
+	if w.opts.Progress != nil {
+		w.opts.Progress(change)
+	}
+}
+
+// settled reports whether an UntilHealthy watch can end.
+func (w *podWatch) settled() bool {
+	if !w.opts.UntilHealthy || len(w.pods) == 0 {
+		return false
+	}
+	for _, pod := range w.pods {
+		if !pod.Healthy {
+			return false
+		}
+	}
+	w.result.Settled = true
+	return true
+}
+
+// finish fills in the final pod states and the summary.
+func (w *podWatch) finish() {
+	r := w.result
+	r.Duration = time.Since(w.start).Round(time.Second).String()
+	for _, pod := range w.pods {
+		r.Pods = append(r.Pods, pod)
+		if pod.Healthy {
+			r.Healthy++
+		}
+		r.Restarts += pod.Restarts - w.initialRestarts[pod.Name]
+	}
+	sort.Slice(r.Pods, func(i, j int) bool { return r.Pods[i].Name < r.Pods[j].Name })
+	switch {
+	case len(r.Pods) == 0:
+		r.Message = "no pods"
+	case r.Settled:
+		r.Message = fmt.Sprintf("all %d pod(s) healthy", len(r.Pods))
+	default:
+		r.Message = fmt.Sprintf("%d of %d pod(s) healthy", r.Healthy, len(r.Pods))
+	}
+	r.Message += fmt.Sprintf(", %d change(s) and %d restart(s) in %s", w.changes, r.Restarts, r.Duration)
+}
+
+// watchedPod condenses a pod to what a watch reports. A pod being deleted
+// is Terminating, as kubectl shows it.
+func watchedPod(pod *corev1.Pod) WatchedPod {
+	info := extractPodInfo(pod)
+	ready := 0
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Ready {
+			ready++
+		}
+	}
+	state := WatchedPod{
+		Name:     pod.Name,
+		Phase:    info.Status,
+		Ready:    fmt.Sprintf("%d/%d", ready, len(pod.Spec.Containers)),
+		Restarts: info.Restarts,
+		Reason:   info.Reason,
+		Healthy:  isPodHealthy(pod),
+	}
+	if pod.DeletionTimestamp != nil {
+		state.Reason = "Terminating"
+		state.Healthy = false
+	}
+	return state
+}
@@ -0,0 +1,136 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func watchTestPod(name string, phase corev1.PodPhase, ready bool, restarts int32) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", Labels: map[string]string{"app": "api"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "api"}}},
+		Status: corev1.PodStatus{Phase: phase, ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "api", Ready: ready, RestartCount: restarts},
+		}},
+	}
+}
+
+// fakePodWatch makes clientset's pod watches return one controllable watcher.
+func fakePodWatch(clientset *fake.Clientset) *watch.FakeWatcher {
+	watcher := watch.NewFake()
+	clientset.PrependWatchReactor("pods", func(k8stesting.Action) (bool, watch.Interface, error) {
+		return true, watcher, nil
+	})
+	return watcher
+}
+
+func TestNormalizeWatchKind(t *testing.T) {
+	for in, want := range map[string]string{"": KindPod, "po": KindPod, "Pods": KindPod, "deploy": KindDeployment, "ds": KindDaemonSet} {
+		if got, err := NormalizeWatchKind(in); err != nil || got != want {
+			t.Errorf("NormalizeWatchKind(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := NormalizeWatchKind("service"); err == nil {
+		t.Error("NormalizeWatchKind(service) succeeded, want an error")
+	}
+}
+
+func TestResolveWatchTarget(t *testing.T) {
+	clientset := fake.NewClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"},
+		Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}},
+	})
+	ctx := context.Background()
+	result, opts, err := resolveWatchTarget(ctx, clientset, "shop", "deploy", "api", "")
+	if err != nil || result.Kind != KindDeployment || opts.LabelSelector != "app=api" {
+		t.Errorf("deployment target = %+v, %+v, %v", result, opts, err)
+	}
+	if _, opts, err = resolveWatchTarget(ctx, clientset, "shop", "pod", "api-1", ""); err != nil || opts.FieldSelector != "metadata.name=api-1" {
+		t.Errorf("pod target options = %+v, %v", opts, err)
+	}
+	for want, args := range map[string][]string{
+		"a deployment name is required": {"deployment", "", ""},
+		"failed to get statefulset":     {"sts", "db", ""},
+		"invalid label selector":        {"pod", "", "app in ("},
+		"unsupported workload kind":     {"cronjob", "nightly", ""},
+	} {
+		if _, _, err := resolveWatchTarget(ctx, clientset, "shop", args[0], args[1], args[2]); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%v: error = %v, want %q", args, err, want)
+		}
+	}
+}
+
+func TestWatchPodsUntilHealthy(t *testing.T) {
+	clientset := fake.NewClientset(watchTestPod("api-1", corev1.PodPending, false, 0))
+	watcher := fakePodWatch(clientset)
+	result := &WatchResult{Namespace: "shop", Changes: []PodChange{}, Pods: []WatchedPod{}}
+	var progress []string
+	opts := WatchOptions{UntilHealthy: true, Progress: func(c PodChange) { progress = append(progress, c.Event+" "+c.Name) }}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- watchPods(ctx, clientset, metav1.ListOptions{LabelSelector: "app=api"}, opts, result) }()
+	// A status update that changes nothing condensed is not reported.
+	watcher.Modify(watchTestPod("api-1", corev1.PodPending, false, 0))
+	watcher.Modify(watchTestPod("api-1", corev1.PodRunning, false, 1))
+	watcher.Modify(watchTestPod("api-1", corev1.PodRunning, true, 1))
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("watchPods() failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watchPods() should end once every pod is healthy")
+	}
+	if !result.Settled || len(result.Changes) != 2 || result.Restarts != 1 || result.Healthy != 1 {
+		t.Errorf("result = %+v, want settled after 2 changes and 1 restart", result)
+	}
+	if len(progress) != 2 || progress[1] != "changed api-1" {
+		t.Errorf("progress = %v", progress)
+	}
+	if !strings.HasPrefix(result.Message, "all 1 pod(s) healthy, 2 change(s) and 1 restart(s)") {
+		t.Errorf("message = %q", result.Message)
+	}
+}
+
+func TestWatchPodsReportsReplacedPods(t *testing.T) {
+	clientset := fake.NewClientset(watchTestPod("api-old", corev1.PodRunning, true, 2))
+	watcher := fakePodWatch(clientset)
+	result := &WatchResult{Namespace: "shop", Changes: []PodChange{}, Pods: []WatchedPod{}}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() { done <- watchPods(ctx, clientset, metav1.ListOptions{}, WatchOptions{}, result) }()
+	terminating := watchTestPod("api-old", corev1.PodRunning, true, 3)
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	watcher.Modify(terminating)
+	watcher.Delete(terminating)
+	watcher.Add(watchTestPod("api-new", corev1.PodRunning, true, 0))
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("watchPods() failed: %v", err)
+	}
+	var events []string
+	for _, c := range result.Changes {
+		events = append(events, c.Event+" "+c.Name+" "+c.Reason)
+	}
+	want := []string{"changed api-old Terminating", "deleted api-old Terminating", "added api-new "}
+	if strings.Join(events, ",") != strings.Join(want, ",") {
+		t.Errorf("changes = %q, want %q", events, want)
+	}
+	if result.Settled || result.Restarts != 1 || len(result.Pods) != 1 || result.Pods[0].Name != "api-new" {
+		t.Errorf("result = %+v, want api-new left and the old pod's restart counted", result)
+	}
+}
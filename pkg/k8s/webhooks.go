@@ -0,0 +1,260 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the admission webhook health check.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// webhookFailureWindow is how far back "failed calling webhook" events are
+// counted.
+const webhookFailureWindow = time.Hour
+
+// failedWebhookCall extracts the webhook name from API server errors recorded
+// in events, e.g. `Internal error occurred: failed calling webhook
+// "validate.example.com": ... connection refused`.
+var failedWebhookCall = regexp.MustCompile(`failed calling webhook "([^"]+)"`)
+
+// WebhookFinding is an admission webhook whose backend is missing or failing.
+type WebhookFinding struct {
+	Kind          string `json:"kind"` // ValidatingWebhookConfiguration or MutatingWebhookConfiguration
+	Configuration string `json:"configuration"`
+	Webhook       string `json:"webhook"`
+	Backend       string `json:"backend"` // service/namespace/name:port or the URL
+	FailurePolicy string `json:"failure_policy"`
+	// Blocking is true when failurePolicy is Fail, so a broken backend
+	// rejects every matching API request.
+	Blocking bool   `json:"blocking"`
+	Problem  string `json:"problem"`
+}
+
+// WebhookReport is the outcome of CheckWebhooks.
+type WebhookReport struct {
+	Context  string           `json:"context"`
+	Webhooks int              `json:"webhooks"` // webhooks checked
+	Findings []WebhookFinding `json:"findings"`
+	Blocking int              `json:"blocking"` // findings with failurePolicy Fail
+	// Warnings note data that could not be read, e.g. for lack of RBAC.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// webhookClient is the admission webhook client configuration shared by
+// validating and mutating webhooks.
+type webhookClient struct {
+	kind, configuration, name string
+	config                    admissionregistrationv1.WebhookClientConfig
+	failurePolicy             *admissionregistrationv1.FailurePolicyType
+}
+
+// webhookBackends reads the backend state of the services webhooks call.
+type webhookBackends struct {
+	service   func(namespace, name string) (*corev1.Service, error)
+	endpoints func(namespace, name string) ([]discoveryv1.EndpointSlice, error)
+}
+
+// webhookBackend describes where a webhook sends its requests.
+func webhookBackend(config admissionregistrationv1.WebhookClientConfig) string {
+	if config.Service == nil {
+		if config.URL != nil {
+			return *config.URL
+		}
+		return ""
+	}
+	return fmt.Sprintf("service/%s/%s:%d", config.Service.Namespace, config.Service.Name, webhookServicePort(config.Service))
+}
+
+// webhookServicePort returns the service port a webhook calls; the API
+// server defaults it to 443.
+func webhookServicePort(ref *admissionregistrationv1.ServiceReference) int32 {
+	if ref.Port != nil {
+		return *ref.Port
+	}
+	return 443
+}
+
+// checkWebhookService returns the problems of the service behind a webhook.
+func checkWebhookService(ref *admissionregistrationv1.ServiceReference, backends webhookBackends) ([]string, error) {
+	svc, err := backends.service(ref.Namespace, ref.Name)
+	if apierrors.IsNotFound(err) {
+		return []string{fmt.Sprintf("service %s/%s not found", ref.Namespace, ref.Name)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	port := webhookServicePort(ref)
+	portFound := false
+	for _, p := range svc.Spec.Ports {
+		if p.Port == port {
+			portFound = true
+			break
+		}
+	}
+	if !portFound {
+		problems = append(problems, fmt.Sprintf("service %s/%s has no port %d", ref.Namespace, ref.Name, port))
+	}
+	if svc.Spec.Type == corev1.ServiceTypeExternalName {
+		return problems, nil
+	}
+	slices, err := backends.endpoints(ref.Namespace, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	if readyEndpointCounts(slices)[ref.Namespace+"/"+ref.Name] == 0 {
+		problems = append(problems, fmt.Sprintf("service %s/%s has no ready endpoints; is the webhook pod running?", ref.Namespace, ref.Name))
+	}
+	return problems, nil
+}
+
+// webhookFailures counts "failed calling webhook" events per webhook name
+// since since, keeping the latest message.
+func webhookFailures(events []corev1.Event, since time.Time) (map[string]int32, map[string]string) {
+	counts, messages := make(map[string]int32), make(map[string]string)
+	latest := make(map[string]time.Time)
+	for i := range events {
+		ev := &events[i]
+		m := failedWebhookCall.FindStringSubmatch(ev.Message)
+		if m == nil || eventLastSeen(ev).Before(since) {
+			continue
+		}
+		counts[m[1]] += eventCount(ev)
+		if seen := eventLastSeen(ev); seen.After(latest[m[1]]) {
+			latest[m[1]] = seen
+			messages[m[1]] = ev.Message
+		}
+	}
+	return counts, messages
+}
+
+// assessWebhooks checks the backend of every webhook and adds the failures
+// recorded in events.
+func assessWebhooks(webhooks []webhookClient, backends webhookBackends, events []corev1.Event, now time.Time) *WebhookReport {
+	report := &WebhookReport{Webhooks: len(webhooks), Findings: make([]WebhookFinding, 0)}
+	failures, messages := webhookFailures(events, now.Add(-webhookFailureWindow))
+	for _, w := range webhooks {
+		policy := string(admissionregistrationv1.Fail)
+		if w.failurePolicy != nil {
+			policy = string(*w.failurePolicy)
+		}
+		finding := func(problem string) WebhookFinding {
+			return WebhookFinding{
+				Kind: w.kind, Configuration: w.configuration, Webhook: w.name, Backend: webhookBackend(w.config),
+				FailurePolicy: policy, Blocking: policy == string(admissionregistrationv1.Fail), Problem: problem,
+			}
+		}
+
+		var problems []string
+		if ref := w.config.Service; ref != nil {
+			p, err := checkWebhookService(ref, backends)
+			if err != nil {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("could not check the service of webhook %s: %v", w.name, err))
+			}
+			problems = append(problems, p...)
+			if len(w.config.CABundle) == 0 {
+				problems = append(problems, "no caBundle, so the API server cannot verify the webhook certificate; did CA injection (e.g. cert-manager) fail?")
+			}
+		}
+		if n := failures[w.name]; n > 0 {
+			problems = append(problems, fmt.Sprintf("%d failed call(s) in the last %s: %s", n, webhookFailureWindow, messages[w.name]))
+		}
+		for _, p := range problems {
+			report.Findings = append(report.Findings, finding(p))
+		}
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.Blocking != b.Blocking {
+			return a.Blocking
+		}
+		if a.Configuration != b.Configuration {
+			return a.Configuration < b.Configuration
+		}
+		return a.Webhook < b.Webhook
+	})
+	for _, f := range report.Findings {
+		if f.Blocking {
+			report.Blocking++
+		}
+	}
+	return report
+}
+
+// CheckWebhooks reports validating and mutating admission webhooks whose
+// backing service is missing, has no ready endpoints or whose calls failed
+// recently.
+func (p *Provider) CheckWebhooks(ctx context.Context, contextName string) (*WebhookReport, error) {
+	clientset, _, err := p.createClientset(contextName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for context %q: %w", contextName, err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	report, err := checkWebhooks(queryCtx, clientset, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	report.Context = contextName
+	return report, nil
+}
+
+func checkWebhooks(ctx context.Context, clientset kubernetes.Interface, now time.Time) (*WebhookReport, error) {
+	validating, err := clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list validatingwebhookconfigurations: %w", err)
+	}
+	mutating, err := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mutatingwebhookconfigurations: %w", err)
+	}
+
+	var webhooks []webhookClient
+	for _, c := range validating.Items {
+		for _, w := range c.Webhooks {
+			webhooks = append(webhooks, webhookClient{kind: "ValidatingWebhookConfiguration", configuration: c.Name, name: w.Name, config: w.ClientConfig, failurePolicy: w.FailurePolicy})
+		}
+	}
+	for _, c := range mutating.Items {
+		for _, w := range c.Webhooks {
+			webhooks = append(webhooks, webhookClient{kind: "MutatingWebhookConfiguration", configuration: c.Name, name: w.Name, config: w.ClientConfig, failurePolicy: w.FailurePolicy})
+		}
+	}
+
+	var warnings []string
+	events, err := clientset.CoreV1().Events("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("could not list events, failed webhook calls are not detected: %v", err))
+		events = &corev1.EventList{}
+	}
+
+	backends := webhookBackends{
+		service: func(namespace, name string) (*corev1.Service, error) {
+			return clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+		},
+		endpoints: func(namespace, name string) ([]discoveryv1.EndpointSlice, error) {
+			list, err := clientset.DiscoveryV1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{
+				LabelSelector: discoveryv1.LabelServiceName + "=" + name,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return list.Items, nil
+		},
+	}
+	report := assessWebhooks(webhooks, backends, events.Items, now)
+	report.Warnings = append(warnings, report.Warnings...)
+	return report, nil
+}
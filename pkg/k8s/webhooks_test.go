@@ -0,0 +1,127 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+var webhooksNow = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+func serviceWebhookConfig(namespace, service string) admissionregistrationv1.WebhookClientConfig {
+	return admissionregistrationv1.WebhookClientConfig{
+		Service:  &admissionregistrationv1.ServiceReference{Namespace: namespace, Name: service},
+		CABundle: []byte("ca"),
+	}
+}
+
+func webhookService(namespace, name string, port int32) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: port}}},
+	}
+}
+
+func webhookEndpoints(namespace, service string, ready bool) *discoveryv1.EndpointSlice {
+	return &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{Name: service + "-abc", Namespace: namespace, Labels: map[string]string{discoveryv1.LabelServiceName: service}},
+		Endpoints:  []discoveryv1.Endpoint{{Addresses: []string{"10.0.0.1"}, Conditions: discoveryv1.EndpointConditions{Ready: ptr.To(ready)}}},
+	}
+}
+
+func TestCheckWebhooks(t *testing.T) {
+	ignore := admissionregistrationv1.Ignore
+	noCA := serviceWebhookConfig("policy", "gatekeeper")
+	noCA.CABundle = nil
+	clientset := fake.NewClientset(
+		&admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "gatekeeper"},
+			Webhooks: []admissionregistrationv1.ValidatingWebhook{
+				{Name: "validate.gatekeeper.sh", ClientConfig: serviceWebhookConfig("policy", "gatekeeper")},
+				{Name: "check-ignore.gatekeeper.sh", ClientConfig: noCA, FailurePolicy: &ignore},
+			},
+		},
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "injector"},
+			Webhooks: []admissionregistrationv1.MutatingWebhook{
+				{Name: "inject.mesh.io", ClientConfig: serviceWebhookConfig("mesh", "injector")},
+				{Name: "defaults.example.com", ClientConfig: serviceWebhookConfig("mesh", "defaults")},
+				{Name: "external.example.com", ClientConfig: admissionregistrationv1.WebhookClientConfig{URL: ptr.To("https://hooks.example.com/mutate")}},
+			},
+		},
+		webhookService("policy", "gatekeeper", 443),
+		webhookEndpoints("policy", "gatekeeper", true),
+		webhookService("mesh", "injector", 443),
+		webhookEndpoints("mesh", "injector", false),
+		&corev1.Event{
+			ObjectMeta:    metav1.ObjectMeta{Name: "web-rs.1", Namespace: "shop"},
+			Reason:        "FailedCreate",
+			Message:       `Error creating: Internal error occurred: failed calling webhook "inject.mesh.io": connection refused`,
+			Count:         7,
+			LastTimestamp: metav1.NewTime(webhooksNow.Add(-5 * time.Minute)),
+		},
+	)
+
+	report, err := checkWebhooks(context.Background(), clientset, webhooksNow)
+	if err != nil {
+		t.Fatalf("checkWebhooks: %v", err)
+	}
+	if report.Webhooks != 5 {
+		t.Errorf("webhooks = %d, want 5", report.Webhooks)
+	}
+	var got []string
+	for _, f := range report.Findings {
+		got = append(got, f.Webhook)
+	}
+	want := "defaults.example.com,inject.mesh.io,inject.mesh.io,check-ignore.gatekeeper.sh"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("findings = %v, want %s", got, want)
+	}
+	if report.Blocking != 3 {
+		t.Errorf("blocking = %d, want 3", report.Blocking)
+	}
+	checks := []struct {
+		i       int
+		problem string
+	}{
+		{0, "service mesh/defaults not found"},
+		{1, "no ready endpoints"},
+		{2, "7 failed call(s) in the last 1h0m0s: Error creating"},
+		{3, "no caBundle"},
+	}
+	for _, c := range checks {
+		if f := report.Findings[c.i]; !strings.Contains(f.Problem, c.problem) {
+			t.Errorf("finding %d problem = %q, want %q", c.i, f.Problem, c.problem)
+		}
+	}
+	if f := report.Findings[3]; f.Blocking || f.FailurePolicy != "Ignore" || f.Backend != "service/policy/gatekeeper:443" {
+		t.Errorf("ignore finding = %+v", f)
+	}
+}
+
+func TestCheckWebhookServicePort(t *testing.T) {
+	backends := webhookBackends{
+		service: func(namespace, name string) (*corev1.Service, error) {
+			return webhookService(namespace, name, 8443), nil
+		},
+		endpoints: func(namespace, name string) ([]discoveryv1.EndpointSlice, error) {
+			return []discoveryv1.EndpointSlice{*webhookEndpoints(namespace, name, true)}, nil
+		},
+	}
+	problems, err := checkWebhookService(&admissionregistrationv1.ServiceReference{Namespace: "ns", Name: "hook"}, backends)
+	if err != nil || len(problems) != 1 || problems[0] != "service ns/hook has no port 443" {
+		t.Errorf("problems = %v, err = %v", problems, err)
+	}
+	problems, err = checkWebhookService(&admissionregistrationv1.ServiceReference{Namespace: "ns", Name: "hook", Port: ptr.To(int32(8443))}, backends)
+	if err != nil || len(problems) != 0 {
+		t.Errorf("problems = %v, err = %v", problems, err)
+	}
+}
@@ -0,0 +1,277 @@
+// Package k8s provides Kubernetes cluster interaction capabilities.
+// This file contains the workload comparison between two namespaces or clusters.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Workload comparison states.
+const (
+	WorkloadIdentical = "identical"
+	WorkloadDifferent = "different"
+	WorkloadOnlyLeft  = "only_left"
+	WorkloadOnlyRight = "only_right"
+)
+
+// sensitiveEnvName matches env vars whose values are not shown in a diff.
+var sensitiveEnvName = regexp.MustCompile(`(?i)(password|passwd|secret|token|api_?key|private_?key|credential)`)
+
+// IsSensitiveName reports whether an env var or data key name suggests a
+// credential whose value must not be shown.
+func IsSensitiveName(name string) bool {
+	return sensitiveEnvName.MatchString(name)
+}
+
+// WorkloadLocation is one side of a comparison.
+type WorkloadLocation struct {
+	Context   string `json:"context"`
+	Namespace string `json:"namespace"`
+}
+
+func (l WorkloadLocation) String() string {
+	return l.Context + "/" + l.Namespace
+}
+
+// workloadSpec is the comparable part of a workload.
+type workloadSpec struct {
+	kind       string
+	name       string
+	replicas   *int32 // nil for DaemonSets and CronJobs
+	containers []corev1.Container
+}
+
+// WorkloadDifference is one field that differs between the two sides.
+type WorkloadDifference struct {
+	Field string `json:"field"` // e.g. replicas, container app image, container app env LOG_LEVEL
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+// WorkloadComparison compares one workload present on either side.
+type WorkloadComparison struct {
+	Kind        string               `json:"kind"`
+	Name        string               `json:"name"`
+	State       string               `json:"state"`
+	Differences []WorkloadDifference `json:"differences,omitempty"`
+}
+
+// WorkloadDiffReport compares the workloads of two namespaces.
+type WorkloadDiffReport struct {
+	Left      WorkloadLocation     `json:"left"`
+	Right     WorkloadLocation     `json:"right"`
+	Workloads []WorkloadComparison `json:"workloads"`
+	Counts    map[string]int       `json:"counts"`
+}
+
+// DiffWorkloads compares the Deployments, StatefulSets, DaemonSets and
+// CronJobs of two namespaces, in one cluster or two: presence, replicas,
+// container images and environment.
+func (p *Provider) DiffWorkloads(ctx context.Context, left, right WorkloadLocation) (*WorkloadDiffReport, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, p.APITimeout())
+	defer cancel()
+
+	specs := make([][]workloadSpec, 2)
+	for i, loc := range []WorkloadLocation{left, right} {
+		clientset, _, err := p.createClientset(loc.Context)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client for context %q: %w", loc.Context, err)
+		}
+		if specs[i], err = collectWorkloadSpecs(queryCtx, clientset, loc.Namespace); err != nil {
+			return nil, fmt.Errorf("%s: %w", loc, err)
+		}
+	}
+	report := compareWorkloads(specs[0], specs[1])
+	report.Left, report.Right = left, right
+	return report, nil
+}
+
+// collectWorkloadSpecs lists the workloads of namespace.
+func collectWorkloadSpecs(ctx context.Context, clientset kubernetes.Interface, namespace string) ([]workloadSpec, error) {
+	var specs []workloadSpec
+	deploys, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, d := range deploys.Items {
+		specs = append(specs, workloadSpec{"Deployment", d.Name, replicasOrOne(d.Spec.Replicas), d.Spec.Template.Spec.Containers})
+	}
+	statefulSets, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for _, s := range statefulSets.Items {
+		specs = append(specs, workloadSpec{"StatefulSet", s.Name, replicasOrOne(s.Spec.Replicas), s.Spec.Template.Spec.Containers})
+	}
+	daemonSets, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %w", err)
+	}
+	for _, d := range daemonSets.Items {
+		specs = append(specs, workloadSpec{"DaemonSet", d.Name, nil, d.Spec.Template.Spec.Containers})
+	}
+	cronJobs, err := clientset.BatchV1().CronJobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cronjobs: %w", err)
+	}
+	for _, c := range cronJobs.Items {
+		specs = append(specs, workloadSpec{"CronJob", c.Name, nil, c.Spec.JobTemplate.Spec.Template.Spec.Containers})
+	}
+	return specs, nil
+}
+
+// replicasOrOne applies the API default of one replica.
+func replicasOrOne(replicas *int32) *int32 {
+	if replicas == nil {
+		one := int32(1)
+		return &one
+	}
+	return replicas
+}
+
+// compareWorkloads matches workloads by kind and name.
+func compareWorkloads(left, right []workloadSpec) *WorkloadDiffReport {
+	key := func(w workloadSpec) string { return w.kind + "/" + w.name }
+	rightByKey := make(map[string]workloadSpec, len(right))
+	for _, w := range right {
+		rightByKey[key(w)] = w
+	}
+
+	report := &WorkloadDiffReport{Workloads: []WorkloadComparison{}, Counts: map[string]int{}}
+	seen := map[string]bool{}
+	for _, l := range left {
+		seen[key(l)] = true
+		c := WorkloadComparison{Kind: l.kind, Name: l.name, State: WorkloadOnlyLeft}
+		if r, ok := rightByKey[key(l)]; ok {
+			c.Differences = diffWorkloadSpecs(l, r)
+			c.State = WorkloadIdentical
+			if len(c.Differences) > 0 {
+				c.State = WorkloadDifferent
+			}
+		}
+		report.Workloads = append(report.Workloads, c)
+	}
+	for _, r := range right {
+		if !seen[key(r)] {
+			report.Workloads = append(report.Workloads, WorkloadComparison{Kind: r.kind, Name: r.name, State: WorkloadOnlyRight})
+		}
+	}
+
+	sort.Slice(report.Workloads, func(i, j int) bool {
+		a, b := report.Workloads[i], report.Workloads[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	for _, c := range report.Workloads {
+		report.Counts[c.State]++
+	}
+	return report
+}
+
+// diffWorkloadSpecs lists the replica, image and env differences of two
+// workloads, matching containers by name.
+func diffWorkloadSpecs(l, r workloadSpec) []WorkloadDifference {
+	var diffs []WorkloadDifference
+	if l.replicas != nil && r.replicas != nil && *l.replicas != *r.replicas {
+		diffs = append(diffs, WorkloadDifference{"replicas", strconv.Itoa(int(*l.replicas)), strconv.Itoa(int(*r.replicas))})
+	}
+
+	rightContainers := make(map[string]corev1.Container, len(r.containers))
+	for _, c := range r.containers {
+		rightContainers[c.Name] = c
+	}
+	for _, lc := range l.containers {
+		rc, ok := rightContainers[lc.Name]
+		if !ok {
+			diffs = append(diffs, WorkloadDifference{"container " + lc.Name, "present", "absent"})
+			continue
+		}
+		delete(rightContainers, lc.Name)
+		if lc.Image != rc.Image {
+			diffs = append(diffs, WorkloadDifference{"container " + lc.Name + " image", lc.Image, rc.Image})
+		}
+		diffs = append(diffs, diffEnv(lc, rc)...)
+	}
+	extra := make([]string, 0, len(rightContainers))
+	for name := range rightContainers {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		diffs = append(diffs, WorkloadDifference{"container " + name, "absent", "present"})
+	}
+	return diffs
+}
+
+// diffEnv compares the env vars of two containers. Literal values of
+// sensitive names are compared but not shown.
+func diffEnv(l, r corev1.Container) []WorkloadDifference {
+	lenv, renv := envValues(l.Env), envValues(r.Env)
+	names := make([]string, 0, len(lenv)+len(renv))
+	for name := range lenv {
+		names = append(names, name)
+	}
+	for name := range renv {
+		if _, ok := lenv[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	show := func(name string, v envValue, ok bool) string {
+		switch {
+		case !ok:
+			return "(unset)"
+		case v.literal && v.text != "" && IsSensitiveName(name):
+			return "(hidden)"
+		}
+		return v.text
+	}
+	var diffs []WorkloadDifference
+	for _, name := range names {
+		lv, lok := lenv[name]
+		rv, rok := renv[name]
+		if lok && rok && lv == rv {
+			continue
+		}
+		diffs = append(diffs, WorkloadDifference{"container " + l.Name + " env " + name, show(name, lv, lok), show(name, rv, rok)})
+	}
+	return diffs
+}
+
+// envValue is a literal env value or a description of its source.
+type envValue struct {
+	text    string
+	literal bool
+}
+
+func envValues(env []corev1.EnvVar) map[string]envValue {
+	values := make(map[string]envValue, len(env))
+	for _, e := range env {
+		switch from := e.ValueFrom; {
+		case from == nil:
+			values[e.Name] = envValue{e.Value, true}
+		case from.SecretKeyRef != nil:
+			values[e.Name] = envValue{text: "secret " + from.SecretKeyRef.Name + "/" + from.SecretKeyRef.Key}
+		case from.ConfigMapKeyRef != nil:
+			values[e.Name] = envValue{text: "configmap " + from.ConfigMapKeyRef.Name + "/" + from.ConfigMapKeyRef.Key}
+		case from.FieldRef != nil:
+			values[e.Name] = envValue{text: "field " + from.FieldRef.FieldPath}
+		case from.ResourceFieldRef != nil:
+			values[e.Name] = envValue{text: "resource " + from.ResourceFieldRef.Resource}
+		default:
+			values[e.Name] = envValue{text: "(from source)"}
+		}
+	}
+	return values
+}
@@ -0,0 +1,107 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func diffDeployment(namespace, name string, replicas int32, containers ...corev1.Container) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: containers}},
+		},
+	}
+}
+
+func TestCollectWorkloadSpecs(t *testing.T) {
+	clientset := fake.NewClientset(
+		diffDeployment("shop", "web", 2, corev1.Container{Name: "app", Image: "web:1"}),
+		diffDeployment("other", "api", 1),
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "shop"}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "shop"}},
+		&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "shop"}},
+	)
+	specs, err := collectWorkloadSpecs(context.Background(), clientset, "shop")
+	if err != nil {
+		t.Fatalf("collectWorkloadSpecs: %v", err)
+	}
+	got := map[string]workloadSpec{}
+	for _, s := range specs {
+		got[s.kind+"/"+s.name] = s
+	}
+	if len(got) != 4 || got["Deployment/web"].containers[0].Image != "web:1" || *got["StatefulSet/db"].replicas != 1 ||
+		got["DaemonSet/agent"].replicas != nil || got["CronJob/backup"].replicas != nil {
+		t.Errorf("unexpected specs: %+v", got)
+	}
+}
+
+func TestCompareWorkloads(t *testing.T) {
+	two, three := int32(2), int32(3)
+	left := []workloadSpec{
+		{kind: "Deployment", name: "web", replicas: &two, containers: []corev1.Container{{
+			Name: "app", Image: "web:1.4",
+			Env: []corev1.EnvVar{
+				{Name: "LOG_LEVEL", Value: "debug"},
+				{Name: "DB_PASSWORD", Value: "staging-pw"},
+				{Name: "ONLY_STAGING", Value: "x"},
+				{Name: "SAME", Value: "y"},
+			},
+		}, {Name: "sidecar", Image: "proxy:1"}}},
+		{kind: "Deployment", name: "same", replicas: &two, containers: []corev1.Container{{Name: "app", Image: "same:1"}}},
+		{kind: "CronJob", name: "cleanup"},
+	}
+	right := []workloadSpec{
+		{kind: "Deployment", name: "web", replicas: &three, containers: []corev1.Container{{
+			Name: "app", Image: "web:1.3",
+			Env: []corev1.EnvVar{
+				{Name: "LOG_LEVEL", Value: "info"},
+				{Name: "DB_PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "db"}, Key: "password"}}},
+				{Name: "SAME", Value: "y"},
+			},
+		}}},
+		{kind: "Deployment", name: "same", replicas: &two, containers: []corev1.Container{{Name: "app", Image: "same:1"}}},
+		{kind: "StatefulSet", name: "cache", replicas: &two},
+	}
+
+	report := compareWorkloads(left, right)
+	if report.Counts[WorkloadDifferent] != 1 || report.Counts[WorkloadIdentical] != 1 ||
+		report.Counts[WorkloadOnlyLeft] != 1 || report.Counts[WorkloadOnlyRight] != 1 {
+		t.Errorf("counts = %v", report.Counts)
+	}
+	if report.Workloads[0].Name != "cleanup" || report.Workloads[0].State != WorkloadOnlyLeft ||
+		report.Workloads[3].Name != "cache" || report.Workloads[3].State != WorkloadOnlyRight {
+		t.Errorf("unexpected order or states: %+v", report.Workloads)
+	}
+
+	var web WorkloadComparison
+	for _, w := range report.Workloads {
+		if w.Name == "web" {
+			web = w
+		}
+	}
+	want := []WorkloadDifference{
+		{"replicas", "2", "3"},
+		{"container app image", "web:1.4", "web:1.3"},
+		{"container app env DB_PASSWORD", "(hidden)", "secret db/password"},
+		{"container app env LOG_LEVEL", "debug", "info"},
+		{"container app env ONLY_STAGING", "x", "(unset)"},
+		{"container sidecar", "present", "absent"},
+	}
+	if len(web.Differences) != len(want) {
+		t.Fatalf("differences = %+v", web.Differences)
+	}
+	for i := range want {
+		if web.Differences[i] != want[i] {
+			t.Errorf("difference %d = %+v, want %+v", i, web.Differences[i], want[i])
+		}
+	}
+}
@@ -2,7 +2,9 @@ package copilot
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/e9169/kopilot/pkg/llm"
 	sdk "github.com/github/copilot-sdk/go"
@@ -30,6 +32,12 @@ func (p *Provider) Start(ctx context.Context) error {
 	if err := p.client.Start(ctx); err != nil {
 		return fmt.Errorf("failed to start copilot client: %w", err)
 	}
+	// An unauthenticated CLI starts but fails every prompt; report it
+	// here, so the caller can fall back. CLIs without the call pass.
+	if status, err := p.client.GetAuthStatus(ctx); err == nil && !status.IsAuthenticated {
+		_ = p.client.Stop()
+		return errors.New("copilot CLI is not authenticated; run copilot auth login")
+	}
 	return nil
 }
 
@@ -40,6 +48,25 @@ func (p *Provider) Stop() error {
 	return nil
 }
 
+// Ping checks that the Copilot CLI answers.
+func (p *Provider) Ping(ctx context.Context) error {
+	if p.client == nil {
+		return errors.New("copilot client not started")
+	}
+	if _, err := p.client.Ping(ctx, "kopilot"); err != nil {
+		return fmt.Errorf("copilot CLI not answering: %w", err)
+	}
+	return nil
+}
+
+// Restart kills the Copilot CLI, which may be hung, and starts a new one.
+func (p *Provider) Restart(ctx context.Context) error {
+	if p.client != nil {
+		p.client.ForceStop()
+	}
+	return p.Start(ctx)
+}
+
 func parseMCPServers(extra map[string]any) map[string]sdk.MCPServerConfig {
 	if extra == nil {
 		return nil
@@ -84,6 +111,12 @@ func parseMCPServerEntry(cfgAny any) (sdk.MCPServerConfig, bool) {
 }
 
 func (p *Provider) CreateSession(ctx context.Context, config *llm.SessionConfig) (llm.Session, error) {
+	if p.client == nil {
+		return nil, errors.New("copilot client not started")
+	}
+	// Tool calls arrive on SDK goroutines; they are traced under the prompt
+	// being answered, which SendPrompt records here.
+	s := &Session{}
 	sdkTools := make([]sdk.Tool, len(config.Tools))
 	for i, t := range config.Tools {
 		handler := t.Handler
@@ -97,16 +130,21 @@ func (p *Provider) CreateSession(ctx context.Context, config *llm.SessionConfig)
 					ID:        inv.ToolCallID,
 					Name:      inv.ToolName,
 					Arguments: argsStr,
+					Context:   s.promptContext(),
 				})
-				textResult := llm.ResultString(resAny)
 				if err != nil {
-					textResult = fmt.Sprintf("Error executing tool: %v", err)
+					// A handler error would reach the model as its bare
+					// text; a failure result carries the structured error.
+					return sdk.ToolResult{
+						TextResultForLLM: llm.ToolErrorString(err),
+						ResultType:       "failure",
+						Error:            err.Error(),
+					}, nil
 				}
-
 				return sdk.ToolResult{
-					TextResultForLLM: textResult,
+					TextResultForLLM: llm.ResultString(resAny),
 					ResultType:       "json",
-				}, err
+				}, nil
 			},
 		}
 	}
@@ -133,12 +171,23 @@ func (p *Provider) CreateSession(ctx context.Context, config *llm.SessionConfig)
 		return nil, fmt.Errorf("failed to create copilot session: %w", err)
 	}
 
-	return &Session{session: session}, nil
+	s.session = session
+	return s, nil
 }
 
 // Session implements llm.Session for Copilot.
 type Session struct {
 	session *sdk.Session
+
+	mu     sync.Mutex
+	prompt context.Context // context of the last prompt sent
+}
+
+// promptContext returns the context of the prompt being answered.
+func (s *Session) promptContext() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.prompt
 }
 
 func (s *Session) Disconnect() error {
@@ -146,6 +195,9 @@ func (s *Session) Disconnect() error {
 }
 
 func (s *Session) SendPrompt(ctx context.Context, prompt string) error {
+	s.mu.Lock()
+	s.prompt = ctx
+	s.mu.Unlock()
 	_, err := s.session.Send(ctx, sdk.MessageOptions{Prompt: prompt})
 	return err
 }
@@ -172,15 +224,8 @@ func convertSDKEvent(sdkEvent sdk.SessionEvent) (llm.Event, bool) {
 		event.Type = llm.EventIdle
 	case sdk.SessionEventTypeAssistantUsage:
 		event.Type = llm.EventUsage
-		if d, ok := sdkEvent.Data.(*rpc.AssistantUsageData); ok && d.QuotaSnapshots != nil {
-			if snapshot, exists := d.QuotaSnapshots["premium_interactions"]; exists {
-				event.Data = &llm.UsageData{
-					QuotaPercentage: snapshot.RemainingPercentage,
-					QuotaUnlimited:  snapshot.IsUnlimitedEntitlement,
-					QuotaUsed:       float64(snapshot.UsedRequests),
-					QuotaTotal:      float64(snapshot.EntitlementRequests),
-				}
-			}
+		if d, ok := sdkEvent.Data.(*rpc.AssistantUsageData); ok {
+			event.Data = convertUsage(d)
 		}
 	default:
 		return event, false
@@ -188,6 +233,35 @@ func convertSDKEvent(sdkEvent sdk.SessionEvent) (llm.Event, bool) {
 	return event, true
 }
 
+// convertUsage converts the usage of a model request, with the premium
+// quota snapshot when the event carries one.
+func convertUsage(d *rpc.AssistantUsageData) *llm.UsageData {
+	usage := &llm.UsageData{
+		QuotaPercentage: -1,
+		Model:           d.Model,
+		InputTokens:     valueOf(d.InputTokens),
+		OutputTokens:    valueOf(d.OutputTokens),
+		CachedTokens:    valueOf(d.CacheReadTokens),
+		Cost:            valueOf(d.Cost),
+	}
+	if snapshot, exists := d.QuotaSnapshots["premium_interactions"]; exists {
+		usage.QuotaPercentage = snapshot.RemainingPercentage
+		usage.QuotaUnlimited = snapshot.IsUnlimitedEntitlement
+		usage.QuotaUsed = float64(snapshot.UsedRequests)
+		usage.QuotaTotal = float64(snapshot.EntitlementRequests)
+	}
+	return usage
+}
+
+// valueOf returns *p, or the zero value when p is nil.
+func valueOf[T any](p *T) T {
+	var v T
+	if p != nil {
+		v = *p
+	}
+	return v
+}
+
 func (s *Session) On(handler func(llm.Event)) {
 	s.session.On(func(sdkEvent sdk.SessionEvent) {
 		if event, ok := convertSDKEvent(sdkEvent); ok {
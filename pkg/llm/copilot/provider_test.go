@@ -121,9 +121,49 @@ func TestConvertSDKEventKnownTypes(t *testing.T) {
 	}
 }
 
+func TestConvertSDKEventUsage(t *testing.T) {
+	in, out, cached, cost := int64(1200), int64(80), int64(1000), 1.0
+	got, ok := convertSDKEvent(sdk.SessionEvent{Data: &rpc.AssistantUsageData{
+		Model:           "gpt-4.1",
+		InputTokens:     &in,
+		OutputTokens:    &out,
+		CacheReadTokens: &cached,
+		Cost:            &cost,
+	}})
+	if !ok || got.Type != llm.EventUsage {
+		t.Fatalf("convertSDKEvent = %#v, %v", got, ok)
+	}
+	d, ok := got.Data.(*llm.UsageData)
+	if !ok {
+		t.Fatalf("data = %#v, want *llm.UsageData", got.Data)
+	}
+	want := llm.UsageData{QuotaPercentage: -1, Model: "gpt-4.1", InputTokens: 1200, OutputTokens: 80, CachedTokens: 1000, Cost: 1}
+	if *d != want {
+		t.Fatalf("usage = %+v, want %+v", *d, want)
+	}
+
+	got, _ = convertSDKEvent(sdk.SessionEvent{Data: &rpc.AssistantUsageData{
+		Model: "gpt-4.1",
+		QuotaSnapshots: map[string]rpc.AssistantUsageQuotaSnapshot{
+			"premium_interactions": {RemainingPercentage: 40, UsedRequests: 180, EntitlementRequests: 300},
+		},
+	}})
+	d = got.Data.(*llm.UsageData)
+	if d.QuotaPercentage != 40 || d.QuotaUsed != 180 || d.QuotaTotal != 300 || d.InputTokens != 0 {
+		t.Fatalf("usage with snapshot = %+v", *d)
+	}
+}
+
 func TestConvertSDKEventUnknownType(t *testing.T) {
 	got, ok := convertSDKEvent(sdk.SessionEvent{Data: rpc.RawSessionEventData{EventType: "unknown.type"}})
 	if ok {
 		t.Fatalf("unknown event should return ok=false, got %#v", got)
 	}
 }
+
+func TestPingBeforeStart(t *testing.T) {
+	var _ llm.HealthChecker = (*Provider)(nil)
+	if err := NewProvider().Ping(t.Context()); err == nil {
+		t.Error("Ping() of a provider that was not started succeeded")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/e9169/kopilot/pkg/llm"
+	"github.com/e9169/kopilot/pkg/telemetry"
 	"google.golang.org/genai"
 )
 
@@ -100,9 +101,35 @@ func convertJSONSchemaToType(schema map[string]any) *genai.Schema {
 	if required, ok := schema["required"].([]any); ok {
 		s.Required = parseRequired(required)
 	}
+	switch enum := schema["enum"].(type) {
+	case []string:
+		s.Enum = enum
+	case []any:
+		s.Enum = parseRequired(enum)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		s.Pattern = pattern
+	}
+	s.MaxItems = schemaLimit(schema["maxItems"])
+	s.MaxLength = schemaLimit(schema["maxLength"])
 	return s
 }
 
+// schemaLimit converts a numeric schema keyword, an int when set in code or
+// a float64 when decoded from JSON, nil when absent.
+func schemaLimit(v any) *int64 {
+	var n int64
+	switch v := v.(type) {
+	case int:
+		n = int64(v)
+	case float64:
+		n = int64(v)
+	default:
+		return nil
+	}
+	return &n
+}
+
 func (p *Provider) CreateSession(ctx context.Context, config *llm.SessionConfig) (llm.Session, error) {
 	var toolDeclarations []*genai.Tool
 	var funcDecls []*genai.FunctionDeclaration
@@ -229,37 +256,48 @@ func processCandidateParts(parts []*genai.Part, fullContent *strings.Builder, fu
 }
 
 func (s *Session) runStreamingStep(ctx context.Context, parts []genai.Part) ([]genai.Part, bool) {
+	_, span := telemetry.StartChat(ctx, "gemini", s.model)
 	stream := s.chat.SendMessageStream(ctx, parts...)
 	var fullContent strings.Builder
 	var functionCalls []*genai.FunctionCall
+	var usage *genai.GenerateContentResponseUsageMetadata
 	for resp, err := range stream {
 		if err != nil {
+			telemetry.End(span, err)
 			if !errors.Is(err, context.Canceled) {
 				s.emit(llm.Event{Type: llm.EventError, Data: &llm.ErrorData{Message: err.Error()}})
 			}
 			return nil, false
 		}
+		if resp.UsageMetadata != nil {
+			usage = resp.UsageMetadata
+		}
 		if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
 			processCandidateParts(resp.Candidates[0].Content.Parts, &fullContent, &functionCalls, s.emit)
 		}
 	}
+	telemetry.End(span, nil)
+	s.emitUsage(usage)
 	if fullContent.Len() > 0 {
 		s.emit(llm.Event{Type: llm.EventMessage, Data: &llm.MessageData{Content: fullContent.String()}})
 	}
 	if len(functionCalls) > 0 {
-		return s.dispatchToolCalls(functionCalls), true
+		return s.dispatchToolCalls(ctx, functionCalls), true
 	}
 	return nil, false
 }
 
 func (s *Session) runNonStreamingStep(ctx context.Context, parts []genai.Part) ([]genai.Part, bool) {
+	_, span := telemetry.StartChat(ctx, "gemini", s.model)
 	resp, err := s.chat.SendMessage(ctx, parts...)
+	telemetry.End(span, err)
 	if err != nil {
 		if !errors.Is(err, context.Canceled) {
 			s.emit(llm.Event{Type: llm.EventError, Data: &llm.ErrorData{Message: err.Error()}})
 		}
 		return nil, false
 	}
+	s.emitUsage(resp.UsageMetadata)
 	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
 		return nil, false
 	}
@@ -273,20 +311,35 @@ func (s *Session) runNonStreamingStep(ctx context.Context, parts []genai.Part) (
 		}
 	}
 	if len(functionCalls) > 0 {
-		return s.dispatchToolCalls(functionCalls), true
+		return s.dispatchToolCalls(ctx, functionCalls), true
 	}
 	return nil, false
 }
 
-func (s *Session) dispatchToolCalls(functionCalls []*genai.FunctionCall) []genai.Part {
+// emitUsage reports the token usage of a request; the metadata of a stream
+// is cumulative, so only its last chunk's is reported.
+func (s *Session) emitUsage(u *genai.GenerateContentResponseUsageMetadata) {
+	if u == nil {
+		return
+	}
+	s.emit(llm.Event{Type: llm.EventUsage, Data: &llm.UsageData{
+		QuotaPercentage: -1,
+		Model:           s.model,
+		InputTokens:     int64(u.PromptTokenCount),
+		OutputTokens:    int64(u.CandidatesTokenCount + u.ThoughtsTokenCount),
+		CachedTokens:    int64(u.CachedContentTokenCount),
+	}})
+}
+
+func (s *Session) dispatchToolCalls(ctx context.Context, functionCalls []*genai.FunctionCall) []genai.Part {
 	var parts []genai.Part
 	for _, fc := range functionCalls {
-		parts = append(parts, s.handleToolCall(fc))
+		parts = append(parts, s.handleToolCall(ctx, fc))
 	}
 	return parts
 }
 
-func (s *Session) handleToolCall(tc *genai.FunctionCall) genai.Part {
+func (s *Session) handleToolCall(ctx context.Context, tc *genai.FunctionCall) genai.Part {
 	var result map[string]any
 
 	params, argsStr := llm.NormalizeToolArguments(tc.Args)
@@ -294,21 +347,14 @@ func (s *Session) handleToolCall(tc *genai.FunctionCall) genai.Part {
 		ID:        tc.Name, // Gemini doesn't use unique call IDs natively in the same way.
 		Name:      tc.Name,
 		Arguments: argsStr,
+		Context:   ctx,
 	})
 
 	if err != nil {
-		if _, ok := s.toolMap[tc.Name]; !ok {
-			result = map[string]any{"error": fmt.Sprintf("Unknown tool %s", tc.Name)}
-		} else {
-			result = map[string]any{"error": fmt.Sprintf("Error executing tool: %v", err)}
-		}
+		result = llm.ToolErrorMap(err)
 	} else {
 		result = llm.ResultMap(resAny)
 	}
 
-	if result == nil {
-		result = map[string]any{"error": fmt.Sprintf("Unknown tool %s", tc.Name)}
-	}
-
 	return *genai.NewPartFromFunctionResponse(tc.Name, result)
 }
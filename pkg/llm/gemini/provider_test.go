@@ -1,6 +1,7 @@
 package gemini
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
@@ -104,6 +105,26 @@ func TestConvertJSONSchemaToType_ArrayWithItems(t *testing.T) {
 	}
 }
 
+func TestConvertJSONSchemaToType_Limits(t *testing.T) {
+	schema := map[string]any{
+		"type":     "array",
+		"maxItems": 2,
+		"items":    map[string]any{"type": "string", "enum": []string{"dev", "prod"}},
+	}
+	got := convertJSONSchemaToType(schema)
+	if got.MaxItems == nil || *got.MaxItems != 2 {
+		t.Errorf("MaxItems = %v, want 2", got.MaxItems)
+	}
+	if len(got.Items.Enum) != 2 || got.Items.Enum[1] != "prod" {
+		t.Errorf("Items.Enum = %v, want [dev prod]", got.Items.Enum)
+	}
+
+	decoded := convertJSONSchemaToType(map[string]any{"type": "string", "enum": []any{"a"}, "pattern": "^a$", "maxLength": float64(63)})
+	if len(decoded.Enum) != 1 || decoded.Pattern != "^a$" || decoded.MaxLength == nil || *decoded.MaxLength != 63 {
+		t.Errorf("decoded limits = %v %q %v", decoded.Enum, decoded.Pattern, decoded.MaxLength)
+	}
+}
+
 func TestConvertJSONSchemaToType_DefaultsToObject(t *testing.T) {
 	schema := map[string]any{}
 	got := convertJSONSchemaToType(schema)
@@ -164,7 +185,7 @@ func TestDispatchToolCalls(t *testing.T) {
 		},
 	}
 
-	parts := s.dispatchToolCalls([]*genai.FunctionCall{{Name: "echo", Args: map[string]any{"a": 1}}})
+	parts := s.dispatchToolCalls(context.Background(), []*genai.FunctionCall{{Name: "echo", Args: map[string]any{"a": 1}}})
 	if len(parts) != 1 {
 		t.Fatalf("dispatchToolCalls returned %d parts, want 1", len(parts))
 	}
@@ -185,13 +206,20 @@ func TestHandleToolCall_ErrorAndUnknown(t *testing.T) {
 		},
 	}
 
-	part := s.handleToolCall(&genai.FunctionCall{Name: "missing", Args: map[string]any{}})
+	part := s.handleToolCall(context.Background(), &genai.FunctionCall{Name: "missing", Args: map[string]any{}})
 	if part.FunctionResponse == nil {
 		t.Fatal("missing tool call should return a function response")
 	}
 
-	part = s.handleToolCall(&genai.FunctionCall{Name: "fail", Args: map[string]any{}})
+	if e, _ := part.FunctionResponse.Response["error"].(map[string]any); e["code"] != llm.ToolErrorUnknownTool {
+		t.Errorf("missing tool response = %v, want an unknown_tool error", part.FunctionResponse.Response)
+	}
+
+	part = s.handleToolCall(context.Background(), &genai.FunctionCall{Name: "fail", Args: map[string]any{}})
 	if part.FunctionResponse == nil {
 		t.Fatal("failing tool call should return a function response")
 	}
+	if e, _ := part.FunctionResponse.Response["error"].(map[string]any); e["code"] != llm.ToolErrorFailed || e["message"] != "boom" {
+		t.Errorf("failing tool response = %v, want a failed error with the message", part.FunctionResponse.Response)
+	}
 }
@@ -4,12 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"os"
 	"strings"
 
 	"github.com/e9169/kopilot/pkg/llm"
+	"github.com/e9169/kopilot/pkg/telemetry"
 	goopenai "github.com/sashabaranov/go-openai"
 	"github.com/sashabaranov/go-openai/jsonschema"
 )
@@ -152,6 +152,9 @@ func (s *Session) runCompletionLoop(ctx context.Context) {
 			Stream:   s.streaming,
 			Tools:    s.tools,
 		}
+		if s.streaming {
+			req.StreamOptions = &goopenai.StreamOptions{IncludeUsage: true}
+		}
 		var cont bool
 		if s.streaming {
 			cont = s.runStreamingStep(ctx, req)
@@ -179,8 +182,10 @@ func (s *Session) processStreamChunk(resp goopenai.ChatCompletionStreamResponse,
 }
 
 func (s *Session) runStreamingStep(ctx context.Context, req goopenai.ChatCompletionRequest) bool {
+	_, span := telemetry.StartChat(ctx, "openai", s.model)
 	stream, err := s.client.CreateChatCompletionStream(ctx, req)
 	if err != nil {
+		telemetry.End(span, err)
 		s.emit(llm.Event{Type: llm.EventError, Data: &llm.ErrorData{Message: err.Error()}})
 		return false
 	}
@@ -194,13 +199,18 @@ func (s *Session) runStreamingStep(ctx context.Context, req goopenai.ChatComplet
 		}
 		if err != nil {
 			_ = stream.Close()
+			telemetry.End(span, err)
 			if !errors.Is(err, context.Canceled) {
 				s.emit(llm.Event{Type: llm.EventError, Data: &llm.ErrorData{Message: err.Error()}})
 			}
 			return false
 		}
 		toolCalls = s.processStreamChunk(resp, &fullContent, toolCalls)
+		if resp.Usage != nil {
+			s.emitUsage(*resp.Usage)
+		}
 	}
+	telemetry.End(span, nil)
 	s.messages = append(s.messages, goopenai.ChatCompletionMessage{
 		Role:      goopenai.ChatMessageRoleAssistant,
 		Content:   fullContent.String(),
@@ -211,7 +221,7 @@ func (s *Session) runStreamingStep(ctx context.Context, req goopenai.ChatComplet
 	}
 	if len(toolCalls) > 0 {
 		for _, tc := range toolCalls {
-			s.handleToolCall(tc)
+			s.handleToolCall(ctx, tc)
 		}
 		return true
 	}
@@ -219,13 +229,16 @@ func (s *Session) runStreamingStep(ctx context.Context, req goopenai.ChatComplet
 }
 
 func (s *Session) runNonStreamingStep(ctx context.Context, req goopenai.ChatCompletionRequest) bool {
+	_, span := telemetry.StartChat(ctx, "openai", s.model)
 	resp, err := s.client.CreateChatCompletion(ctx, req)
+	telemetry.End(span, err)
 	if err != nil {
 		if !errors.Is(err, context.Canceled) {
 			s.emit(llm.Event{Type: llm.EventError, Data: &llm.ErrorData{Message: err.Error()}})
 		}
 		return false
 	}
+	s.emitUsage(resp.Usage)
 	if len(resp.Choices) == 0 {
 		return false
 	}
@@ -236,13 +249,27 @@ func (s *Session) runNonStreamingStep(ctx context.Context, req goopenai.ChatComp
 	}
 	if len(msg.ToolCalls) > 0 {
 		for _, tc := range msg.ToolCalls {
-			s.handleToolCall(tc)
+			s.handleToolCall(ctx, tc)
 		}
 		return true
 	}
 	return false
 }
 
+// emitUsage reports the token usage of a completion request.
+func (s *Session) emitUsage(u goopenai.Usage) {
+	usage := &llm.UsageData{
+		QuotaPercentage: -1,
+		Model:           s.model,
+		InputTokens:     int64(u.PromptTokens),
+		OutputTokens:    int64(u.CompletionTokens),
+	}
+	if u.PromptTokensDetails != nil {
+		usage.CachedTokens = int64(u.PromptTokensDetails.CachedTokens)
+	}
+	s.emit(llm.Event{Type: llm.EventUsage, Data: usage})
+}
+
 // mergeToolCallChunk merges a streamed tool-call delta chunk into the accumulator.
 // Chunks with a nil Index are malformed and are silently dropped.
 func mergeToolCallChunk(toolCalls []goopenai.ToolCall, tc goopenai.ToolCall) []goopenai.ToolCall {
@@ -268,7 +295,7 @@ func mergeToolCallChunk(toolCalls []goopenai.ToolCall, tc goopenai.ToolCall) []g
 	return toolCalls
 }
 
-func (s *Session) handleToolCall(tc goopenai.ToolCall) {
+func (s *Session) handleToolCall(ctx context.Context, tc goopenai.ToolCall) {
 	var result string
 
 	args := llm.ParseToolArgumentsString(tc.Function.Arguments)
@@ -276,22 +303,15 @@ func (s *Session) handleToolCall(tc goopenai.ToolCall) {
 		ID:        tc.ID,
 		Name:      tc.Function.Name,
 		Arguments: tc.Function.Arguments,
+		Context:   ctx,
 	})
 
 	if err != nil {
-		if _, ok := s.toolMap[tc.Function.Name]; !ok {
-			result = fmt.Sprintf("Error: Unknown tool %s", tc.Function.Name)
-		} else {
-			result = fmt.Sprintf("Error executing tool: %v", err)
-		}
+		result = llm.ToolErrorString(err)
 	} else {
 		result = llm.ResultString(resAny)
 	}
 
-	if result == "" {
-		result = fmt.Sprintf("Error: Unknown tool %s", tc.Function.Name)
-	}
-
 	s.messages = append(s.messages, goopenai.ChatCompletionMessage{
 		Role:       goopenai.ChatMessageRoleTool,
 		Content:    result,
@@ -167,6 +167,42 @@ func TestRunNonStreamingStep_TextContent(t *testing.T) {
 	}
 }
 
+func TestRunNonStreamingStep_EmitsUsage(t *testing.T) {
+	resp := map[string]any{
+		"id": "chatcmpl-test", "object": "chat.completion",
+		"choices": []map[string]any{
+			{"index": 0, "message": map[string]any{"role": "assistant", "content": "hi"}, "finish_reason": "stop"},
+		},
+		"usage": map[string]any{
+			"prompt_tokens": 50, "completion_tokens": 7, "total_tokens": 57,
+			"prompt_tokens_details": map[string]any{"cached_tokens": 20},
+		},
+	}
+	body, _ := json.Marshal(resp)
+
+	s, ts := newTestSession(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body) //nolint:errcheck
+	}, false)
+	defer ts.Close()
+
+	events := collectEvents(s)
+	req := goopenai.ChatCompletionRequest{Model: s.model, Messages: s.messages}
+	s.runNonStreamingStep(context.Background(), req)
+
+	for _, e := range *events {
+		if e.Type != llm.EventUsage {
+			continue
+		}
+		d := e.Data.(*llm.UsageData)
+		if d.Model != "test-model" || d.InputTokens != 50 || d.OutputTokens != 7 || d.CachedTokens != 20 || d.QuotaPercentage >= 0 {
+			t.Fatalf("usage = %+v", d)
+		}
+		return
+	}
+	t.Fatal("expected an EventUsage to be emitted")
+}
+
 func TestMergeToolCallChunk_NilIndexDropped(t *testing.T) {
 	chunk := goopenai.ToolCall{Index: nil, ID: "id1"}
 	got := mergeToolCallChunk(nil, chunk)
@@ -219,7 +255,7 @@ func TestHandleToolCall_KnownTool(t *testing.T) {
 		Type:     goopenai.ToolTypeFunction,
 		Function: goopenai.FunctionCall{Name: "echo", Arguments: `{"x":1}`},
 	}
-	s.handleToolCall(tc)
+	s.handleToolCall(context.Background(), tc)
 	if len(s.messages) != 1 {
 		t.Fatalf("expected 1 message, got %d", len(s.messages))
 	}
@@ -241,12 +277,12 @@ func TestHandleToolCall_UnknownTool(t *testing.T) {
 		Type:     goopenai.ToolTypeFunction,
 		Function: goopenai.FunctionCall{Name: "missing", Arguments: `{}`},
 	}
-	s.handleToolCall(tc)
+	s.handleToolCall(context.Background(), tc)
 	if len(s.messages) != 1 {
 		t.Fatalf("expected 1 message, got %d", len(s.messages))
 	}
-	if !strings.Contains(s.messages[0].Content, "Unknown tool") {
-		t.Errorf("expected 'Unknown tool' in content, got %q", s.messages[0].Content)
+	if !strings.Contains(s.messages[0].Content, `"code":"unknown_tool"`) {
+		t.Errorf("expected an unknown_tool error in content, got %q", s.messages[0].Content)
 	}
 }
 
@@ -268,7 +304,7 @@ func TestHandleToolCall_BrokenJSON(t *testing.T) {
 		ID:       "call-3",
 		Function: goopenai.FunctionCall{Name: "t", Arguments: `not-json`},
 	}
-	s.handleToolCall(tc)
+	s.handleToolCall(context.Background(), tc)
 	if !called {
 		t.Error("handler should be called even when arguments JSON is malformed")
 	}
@@ -4,12 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/e9169/kopilot/pkg/debug"
+	"github.com/e9169/kopilot/pkg/telemetry"
 	"github.com/invopop/jsonschema"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // DefineTool creates a typed llm.Tool from a struct type.
 // It reflects on T to generate a JSON Schema for the parameters.
+// Each call runs in a "tool <name>" trace span; inv.Context carries it.
 func DefineTool[T any](name string, description string, handler func(params T, inv ToolInvocation) (any, error)) Tool {
 	var zero T
 	reflector := jsonschema.Reflector{
@@ -38,7 +43,22 @@ func DefineTool[T any](name string, description string, handler func(params T, i
 					_ = json.Unmarshal(b, &typedParams)
 				}
 			}
-			return handler(typedParams, inv)
+			ctx, span := telemetry.Start(inv.Ctx(), "tool "+name,
+				attribute.String("tool.name", name), attribute.String("tool.call_id", inv.ID))
+			inv.Context = ctx
+			if debug.Enabled(debug.Tools) {
+				_, raw := NormalizeToolArguments(params)
+				debug.Logf(debug.Tools, "-> %s %s", name, debug.Truncate(raw))
+			}
+			start := time.Now()
+			result, err := handler(typedParams, inv)
+			telemetry.End(span, err)
+			if err != nil {
+				debug.Logf(debug.Tools, "<- %s failed after %s: %v", name, time.Since(start).Round(time.Millisecond), err)
+			} else {
+				debug.Logf(debug.Tools, "<- %s done in %s", name, time.Since(start).Round(time.Millisecond))
+			}
+			return result, err
 		},
 	}
 }
@@ -92,7 +112,8 @@ func ResultMap(result any) map[string]any {
 func InvokeTool(toolMap map[string]Tool, params map[string]any, inv ToolInvocation) (any, error) {
 	toolDef, ok := toolMap[inv.Name]
 	if !ok {
-		return nil, fmt.Errorf("unknown tool %s", inv.Name)
+		return nil, &ToolError{Code: ToolErrorUnknownTool, Message: fmt.Sprintf("unknown tool %s", inv.Name),
+			Hint: "call one of the tools declared in this session"}
 	}
 	return toolDef.Handler(params, inv)
 }
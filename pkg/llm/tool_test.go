@@ -1,7 +1,18 @@
 package llm
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
 	"testing"
+
+	"github.com/e9169/kopilot/pkg/debug"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestDefineTool(t *testing.T) {
@@ -54,6 +65,41 @@ func TestDefineTool_NilParams(t *testing.T) {
 	}
 }
 
+func TestDefineTool_Span(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	defer otel.SetTracerProvider(prev)
+
+	type Params struct{}
+	var handlerCtx context.Context
+	tool := DefineTool("noop", "does nothing", func(p Params, inv ToolInvocation) (any, error) {
+		handlerCtx = inv.Ctx()
+		return nil, nil
+	})
+	if _, err := tool.Handler(nil, ToolInvocation{ID: "call-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := rec.Ended()
+	if len(spans) != 1 || spans[0].Name() != "tool noop" {
+		t.Fatalf("spans = %v, want one \"tool noop\" span", spans)
+	}
+	if trace.SpanContextFromContext(handlerCtx).SpanID() != spans[0].SpanContext().SpanID() {
+		t.Error("handler context should carry the tool span")
+	}
+}
+
+func TestToolInvocationCtx(t *testing.T) {
+	if (ToolInvocation{}).Ctx() == nil {
+		t.Error("Ctx() of a zero invocation should not be nil")
+	}
+	ctx := context.WithValue(context.Background(), struct{}{}, "x")
+	if (ToolInvocation{Context: ctx}).Ctx() != ctx {
+		t.Error("Ctx() should return the invocation context")
+	}
+}
+
 func TestParseToolArgumentsString(t *testing.T) {
 	got := ParseToolArgumentsString(`{"name":"world"}`)
 	if got["name"] != "world" {
@@ -116,3 +162,27 @@ func TestInvokeTool(t *testing.T) {
 		t.Fatal("InvokeTool() should fail for unknown tools")
 	}
 }
+
+func TestDefineTool_Debug(t *testing.T) {
+	var buf bytes.Buffer
+	debug.SetOutput(&buf)
+	debug.Enable(debug.Tools)
+	defer func() {
+		debug.Enable()
+		debug.SetOutput(os.Stderr)
+	}()
+
+	type Params struct {
+		Name string `json:"name"`
+	}
+	tool := DefineTool("fail", "always fails", func(p Params, inv ToolInvocation) (any, error) {
+		return nil, errors.New("boom")
+	})
+	if _, err := tool.Handler(map[string]any{"name": "web"}, ToolInvocation{}); err == nil {
+		t.Fatal("expected the handler error")
+	}
+	out := buf.String()
+	if !strings.Contains(out, `[tools] -> fail {"name":"web"}`) || !strings.Contains(out, "<- fail failed after") || !strings.Contains(out, "boom") {
+		t.Errorf("debug output = %q", out)
+	}
+}
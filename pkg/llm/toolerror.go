@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Tool error codes, the machine-readable cause of a failed tool call.
+const (
+	ToolErrorUnknownTool      = "unknown_tool"
+	ToolErrorInvalidArguments = "invalid_arguments"
+	ToolErrorContextNotFound  = "context_not_found"
+	ToolErrorNotInstalled     = "not_installed"
+	ToolErrorNotFound         = "not_found"
+	ToolErrorForbidden        = "forbidden"
+	ToolErrorUnauthorized     = "unauthorized"
+	ToolErrorUnavailable      = "unavailable"
+	ToolErrorTimeout          = "timeout"
+	ToolErrorCancelled        = "cancelled"
+	ToolErrorFailed           = "failed"
+)
+
+// ToolError is a failed tool call as the model receives it: what went
+// wrong, how to recover, and whether the same call may succeed if repeated.
+type ToolError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Hint      string `json:"hint,omitempty"`
+	Retriable bool   `json:"retriable"`
+	// Err is the underlying error, if any.
+	Err error `json:"-"`
+}
+
+func (e *ToolError) Error() string { return e.Message }
+
+func (e *ToolError) Unwrap() error { return e.Err }
+
+// AsToolError returns err as a ToolError; an error that is not one is
+// reported as ToolErrorFailed with its text.
+func AsToolError(err error) *ToolError {
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) {
+		return toolErr
+	}
+	return &ToolError{Code: ToolErrorFailed, Message: err.Error(), Err: err}
+}
+
+// ToolErrorMap returns the result object the model receives for a failed
+// call: {"error": {"code", "message", "hint", "retriable"}}.
+func ToolErrorMap(err error) map[string]any {
+	e := AsToolError(err)
+	obj := map[string]any{"code": e.Code, "message": e.Message, "retriable": e.Retriable}
+	if e.Hint != "" {
+		obj["hint"] = e.Hint
+	}
+	return map[string]any{"error": obj}
+}
+
+// ToolErrorString is ToolErrorMap as JSON text.
+func ToolErrorString(err error) string {
+	b, jsonErr := json.Marshal(map[string]*ToolError{"error": AsToolError(err)})
+	if jsonErr != nil {
+		return fmt.Sprintf("Error executing tool: %v", err)
+	}
+	return string(b)
+}
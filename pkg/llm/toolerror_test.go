@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAsToolError(t *testing.T) {
+	plain := errors.New("boom")
+	if e := AsToolError(plain); e.Code != ToolErrorFailed || e.Message != "boom" || e.Retriable || !errors.Is(e, plain) {
+		t.Errorf("AsToolError(plain) = %+v", e)
+	}
+
+	structured := &ToolError{Code: ToolErrorTimeout, Message: "slow", Retriable: true, Err: context.DeadlineExceeded}
+	wrapped := fmt.Errorf("check failed: %w", structured)
+	if e := AsToolError(wrapped); e != structured {
+		t.Errorf("AsToolError(wrapped) = %+v, want the wrapped ToolError", e)
+	}
+	if !errors.Is(wrapped, context.DeadlineExceeded) {
+		t.Error("ToolError does not unwrap to its cause")
+	}
+}
+
+func TestToolErrorResult(t *testing.T) {
+	err := &ToolError{Code: ToolErrorContextNotFound, Message: `context "prod" not found`, Hint: "available contexts: dev, staging"}
+	want := `{"error":{"code":"context_not_found","message":"context \"prod\" not found","hint":"available contexts: dev, staging","retriable":false}}`
+	if got := ToolErrorString(err); got != want {
+		t.Errorf("ToolErrorString = %s, want %s", got, want)
+	}
+
+	m := ToolErrorMap(errors.New("boom"))
+	e, _ := m["error"].(map[string]any)
+	if e["code"] != ToolErrorFailed || e["message"] != "boom" || e["retriable"] != false {
+		t.Errorf("ToolErrorMap = %v", m)
+	}
+	if _, ok := e["hint"]; ok {
+		t.Errorf("ToolErrorMap has an empty hint: %v", m)
+	}
+}
@@ -16,6 +16,16 @@ type Provider interface {
 	CreateSession(ctx context.Context, config *SessionConfig) (Session, error)
 }
 
+// HealthChecker is implemented by providers whose backend is a separate
+// process that can crash or hang mid-session, such as the Copilot CLI.
+type HealthChecker interface {
+	// Ping returns an error when the backend does not answer.
+	Ping(ctx context.Context) error
+	// Restart kills the backend, without waiting on it, and starts a new
+	// one. Sessions of the old backend are gone; create new ones.
+	Restart(ctx context.Context) error
+}
+
 // Session represents an active conversation session with the AI.
 type Session interface {
 	// Disconnect closes the session.
@@ -51,6 +61,17 @@ type ToolInvocation struct {
 	ID        string
 	Name      string
 	Arguments string
+	// Context is the context of the prompt (inside the handler: of the tool
+	// call) carrying its trace span; nil when the caller has none.
+	Context context.Context
+}
+
+// Ctx returns inv.Context, or context.Background() when it is nil.
+func (inv ToolInvocation) Ctx() context.Context {
+	if inv.Context == nil {
+		return context.Background()
+	}
+	return inv.Context
 }
 
 // EventType represents the type of session event.
@@ -85,12 +106,23 @@ type ErrorData struct {
 	Message string
 }
 
-// UsageData contains token or quota usage metrics.
+// UsageData contains token or quota usage metrics of one model request.
+// QuotaPercentage is negative when the event carries no quota snapshot.
 type UsageData struct {
 	QuotaPercentage float64
 	QuotaUnlimited  bool
 	QuotaUsed       float64
 	QuotaTotal      float64
+
+	// Model is the model that served the request, when known.
+	Model        string
+	InputTokens  int64
+	OutputTokens int64
+	// CachedTokens is the part of InputTokens read from the prompt cache.
+	CachedTokens int64
+	// Cost is the provider's billing cost of the request, e.g. the premium
+	// request multiplier for Copilot; 0 when unknown.
+	Cost float64
 }
 
 // EventEmitter stores and dispatches session event handlers.
@@ -0,0 +1,156 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"health":       ClusterHealth,
+	"healthIcon":   healthIcon,
+	"severityIcon": severityIcon,
+	"lower":        strings.ToLower,
+	"cost":         monthlyCost,
+	"money":        money,
+	"distribution": distribution,
+	"join":         strings.Join,
+	"topCosts":     func(s *k8s.ClusterStatus) []k8s.NamespaceCost { return topNamespaceCosts(s, topCostNamespaces) },
+	"time":         func(t time.Time) string { return t.Format(time.RFC3339) },
+	"percent":      func(p float64) string { return fmt.Sprintf("%.0f%%", p) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Report.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 72em; color: #24292f; }
+h2 { border-bottom: 1px solid #d0d7de; padding-bottom: .3em; }
+table { border-collapse: collapse; margin: .5em 0 1em; }
+th, td { border: 1px solid #d0d7de; padding: 6px 12px; text-align: left; }
+th { background: #f6f8fa; }
+.healthy { color: #1a7f37; font-weight: bold; }
+.degraded { color: #9a6700; font-weight: bold; }
+.down, .critical { color: #cf222e; font-weight: bold; }
+.warning { color: #bc4c00; font-weight: bold; }
+.info { color: #0969da; }
+.meta { color: #57606a; }
+</style>
+</head>
+<body>
+{{- $r := .Report}}
+<h1>{{$r.Title}}</h1>
+<p class="meta">Generated {{time $r.GeneratedAt}}</p>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Clusters</th><th>Healthy</th><th>Degraded</th><th>Down</th><th>Nodes ready</th><th>Pods healthy</th><th>Critical</th><th>Warnings</th></tr>
+<tr><td>{{$r.Summary.Clusters}}</td><td>{{$r.Summary.Healthy}}</td><td>{{$r.Summary.Degraded}}</td><td>{{$r.Summary.Down}}</td><td>{{$r.Summary.HealthyNodes}}/{{$r.Summary.Nodes}}</td><td>{{$r.Summary.HealthyPods}}/{{$r.Summary.Pods}}</td><td>{{$r.Summary.Critical}}</td><td>{{$r.Summary.Warnings}}</td></tr>
+</table>
+<table>
+<tr><th>Cluster</th><th>Status</th><th>Nodes</th><th>Pods</th><th>Version</th><th>Cost/month</th></tr>
+{{- range $r.Clusters}}
+{{- $h := health .}}
+<tr><td><a href="#cluster-{{.Context}}">{{.Context}}</a></td><td class="{{lower $h}}">{{healthIcon $h}} {{$h}}</td>
+{{- if .IsReachable}}<td>{{.HealthyNodes}}/{{.NodeCount}}</td><td>{{.HealthyPods}}/{{.PodCount}}</td><td>{{.Version}}</td><td>{{cost .}}</td>
+{{- else}}<td>-</td><td>-</td><td>-</td><td>-</td>{{end}}</tr>
+{{- end}}
+</table>
+
+<h2>Issues</h2>
+{{- if $r.Issues}}
+<table>
+<tr><th>Severity</th><th>Cluster</th><th>Issue</th></tr>
+{{- range $r.Issues}}
+<tr><td class="{{.Severity}}">{{severityIcon .Severity}} {{.Severity}}</td><td>{{.Cluster}}</td><td>{{.Message}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No issues found.</p>
+{{- end}}
+{{- range .Sections}}
+{{- $s := .Status}}
+{{- $h := health $s}}
+
+<h2 id="cluster-{{$s.Context}}">{{healthIcon $h}} {{$s.Context}}</h2>
+<ul>
+<li>Status: <span class="{{lower $h}}">{{$h}}</span></li>
+<li>Server: {{$s.Server}}</li>
+{{- if not $s.IsReachable}}
+<li>Error: {{$s.Error}}</li>
+</ul>
+{{- else}}
+<li>Version: {{$s.Version}}</li>
+<li>Nodes: {{$s.HealthyNodes}}/{{$s.NodeCount}} ready ({{distribution $s.OSDistribution}}; {{distribution $s.ArchDistribution}})</li>
+<li>Pods: {{$s.HealthyPods}}/{{$s.PodCount}} healthy</li>
+<li>Namespaces: {{len $s.NamespaceList}}</li>
+{{- with $s.Cost}}
+<li>Estimated cost ({{.Source}}): {{money .HourlyCost .Currency}}/hour, ~{{money .MonthlyCost .Currency}}/month</li>
+{{- end}}
+</ul>
+{{- if $s.Nodes}}
+<h3>Nodes</h3>
+<table>
+<tr><th>Node</th><th>Status</th><th>Roles</th><th>Age</th><th>OS/Arch</th></tr>
+{{- range $s.Nodes}}
+<tr><td>{{.Name}}</td><td>{{.Status}}</td><td>{{join .Roles ", "}}</td><td>{{.Age}}</td><td>{{.OS}}/{{.Arch}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if $s.UnhealthyPods}}
+<h3>Unhealthy pods</h3>
+<table>
+<tr><th>Namespace</th><th>Pod</th><th>Status</th><th>Reason</th><th>Restarts</th></tr>
+{{- range $s.UnhealthyPods}}
+<tr><td>{{.Namespace}}</td><td>{{.Name}}</td><td>{{.Status}}</td><td>{{.Reason}}</td><td>{{.Restarts}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- with topCosts $s}}
+<h3>Most expensive namespaces</h3>
+<table>
+<tr><th>Namespace</th><th>Cost/month</th><th>Share</th></tr>
+{{- range .}}
+<tr><td>{{.Namespace}}</td><td>~{{money .MonthlyCost $s.Cost.Currency}}</td><td>{{percent .Percent}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- with .Issues}}
+<h3>Issues</h3>
+<ul>
+{{- range .}}
+<li><span class="{{.Severity}}">{{severityIcon .Severity}} {{.Severity}}</span>: {{.Message}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// htmlSection is the per-cluster data of the HTML template.
+type htmlSection struct {
+	Status *k8s.ClusterStatus
+	Issues []Issue
+}
+
+// HTML renders r as a self-contained HTML page.
+func HTML(r *Report) ([]byte, error) {
+	data := struct {
+		Report   *Report
+		Sections []htmlSection
+	}{Report: r}
+	for _, status := range r.Clusters {
+		data.Sections = append(data.Sections, htmlSection{Status: status, Issues: r.clusterIssues(status.Context)})
+	}
+	var b bytes.Buffer
+	if err := htmlTemplate.Execute(&b, data); err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+	return b.Bytes(), nil
+}
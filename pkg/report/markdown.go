@@ -0,0 +1,106 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+// topCostNamespaces is the number of namespaces listed per cluster cost section.
+const topCostNamespaces = 5
+
+// cell escapes a Markdown table cell.
+func cell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(orDash(s))
+}
+
+// Markdown renders r as a Markdown document.
+func Markdown(r *Report) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", r.Title)
+	fmt.Fprintf(&b, "Generated %s\n\n", r.GeneratedAt.Format(time.RFC3339))
+
+	b.WriteString("## Summary\n\n")
+	b.WriteString("| Clusters | Healthy | Degraded | Down | Nodes ready | Pods healthy | Critical | Warnings |\n")
+	b.WriteString("|---|---|---|---|---|---|---|---|\n")
+	fmt.Fprintf(&b, "| %d | %d | %d | %d | %d/%d | %d/%d | %d | %d |\n\n",
+		r.Summary.Clusters, r.Summary.Healthy, r.Summary.Degraded, r.Summary.Down,
+		r.Summary.HealthyNodes, r.Summary.Nodes, r.Summary.HealthyPods, r.Summary.Pods,
+		r.Summary.Critical, r.Summary.Warnings)
+
+	b.WriteString("| Cluster | Status | Nodes | Pods | Version | Cost/month |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, status := range r.Clusters {
+		health := ClusterHealth(status)
+		if !status.IsReachable {
+			fmt.Fprintf(&b, "| %s | %s %s | - | - | - | - |\n", cell(status.Context), healthIcon(health), health)
+			continue
+		}
+		fmt.Fprintf(&b, "| %s | %s %s | %d/%d | %d/%d | %s | %s |\n", cell(status.Context), healthIcon(health), health,
+			status.HealthyNodes, status.NodeCount, status.HealthyPods, status.PodCount, cell(status.Version), monthlyCost(status))
+	}
+
+	b.WriteString("\n## Issues\n\n")
+	if len(r.Issues) == 0 {
+		b.WriteString("No issues found.\n")
+	} else {
+		b.WriteString("| Severity | Cluster | Issue |\n|---|---|---|\n")
+		for _, issue := range r.Issues {
+			fmt.Fprintf(&b, "| %s %s | %s | %s |\n", severityIcon(issue.Severity), issue.Severity, cell(issue.Cluster), cell(issue.Message))
+		}
+	}
+
+	for _, status := range r.Clusters {
+		writeClusterMarkdown(&b, r, status)
+	}
+	return b.String()
+}
+
+// writeClusterMarkdown writes the section of one cluster.
+func writeClusterMarkdown(b *strings.Builder, r *Report, status *k8s.ClusterStatus) {
+	health := ClusterHealth(status)
+	fmt.Fprintf(b, "\n## %s %s\n\n", healthIcon(health), status.Context)
+	fmt.Fprintf(b, "- Status: %s\n", health)
+	fmt.Fprintf(b, "- Server: %s\n", orDash(status.Server))
+	if !status.IsReachable {
+		fmt.Fprintf(b, "- Error: %s\n", orDash(status.Error))
+		return
+	}
+	fmt.Fprintf(b, "- Version: %s\n", orDash(status.Version))
+	fmt.Fprintf(b, "- Nodes: %d/%d ready (%s; %s)\n", status.HealthyNodes, status.NodeCount,
+		distribution(status.OSDistribution), distribution(status.ArchDistribution))
+	fmt.Fprintf(b, "- Pods: %d/%d healthy\n", status.HealthyPods, status.PodCount)
+	fmt.Fprintf(b, "- Namespaces: %d\n", len(status.NamespaceList))
+	if status.Cost != nil {
+		fmt.Fprintf(b, "- Estimated cost (%s): %s/hour, ~%s/month\n", status.Cost.Source,
+			money(status.Cost.HourlyCost, status.Cost.Currency), money(status.Cost.MonthlyCost, status.Cost.Currency))
+	}
+
+	if len(status.Nodes) > 0 {
+		b.WriteString("\n### Nodes\n\n| Node | Status | Roles | Age | OS/Arch |\n|---|---|---|---|---|\n")
+		for _, node := range status.Nodes {
+			fmt.Fprintf(b, "| %s | %s | %s | %s | %s/%s |\n", cell(node.Name), cell(node.Status),
+				cell(strings.Join(node.Roles, ", ")), cell(node.Age), orDash(node.OS), orDash(node.Arch))
+		}
+	}
+	if len(status.UnhealthyPods) > 0 {
+		b.WriteString("\n### Unhealthy pods\n\n| Namespace | Pod | Status | Reason | Restarts |\n|---|---|---|---|---|\n")
+		for _, pod := range status.UnhealthyPods {
+			fmt.Fprintf(b, "| %s | %s | %s | %s | %d |\n", cell(pod.Namespace), cell(pod.Name), cell(pod.Status), cell(pod.Reason), pod.Restarts)
+		}
+	}
+	if costs := topNamespaceCosts(status, topCostNamespaces); len(costs) > 0 {
+		b.WriteString("\n### Most expensive namespaces\n\n| Namespace | Cost/month | Share |\n|---|---|---|\n")
+		for _, ns := range costs {
+			fmt.Fprintf(b, "| %s | ~%s | %.0f%% |\n", cell(ns.Namespace), money(ns.MonthlyCost, status.Cost.Currency), ns.Percent)
+		}
+	}
+	if issues := r.clusterIssues(status.Context); len(issues) > 0 {
+		b.WriteString("\n### Issues\n\n")
+		for _, issue := range issues {
+			fmt.Fprintf(b, "- %s **%s**: %s\n", severityIcon(issue.Severity), issue.Severity, issue.Message)
+		}
+	}
+}
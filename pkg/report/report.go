@@ -0,0 +1,292 @@
+// Package report renders cluster health data as Markdown or HTML reports: a
+// summary table, one section per cluster and an issue list ranked by severity.
+package report
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+// Output formats.
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Severity ranks an issue.
+type Severity string
+
+// Issue severities, most severe first.
+const (
+	SeverityCritical Severity = "critical"
+	SeverityWarning  Severity = "warning"
+	SeverityInfo     Severity = "info"
+)
+
+// severityRank orders severities for sorting.
+var severityRank = map[Severity]int{SeverityCritical: 0, SeverityWarning: 1, SeverityInfo: 2}
+
+// Cluster health labels.
+const (
+	HealthHealthy  = "HEALTHY"
+	HealthDegraded = "DEGRADED"
+	HealthDown     = "DOWN"
+)
+
+// maxPodIssues bounds the per-pod issues listed for one cluster.
+const maxPodIssues = 20
+
+// Issue is one problem found in a cluster.
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Cluster  string   `json:"cluster"`
+	Message  string   `json:"message"`
+}
+
+// Summary counts clusters and issues.
+type Summary struct {
+	Clusters     int `json:"clusters"`
+	Healthy      int `json:"healthy"`
+	Degraded     int `json:"degraded"`
+	Down         int `json:"down"`
+	Critical     int `json:"critical"`
+	Warnings     int `json:"warnings"`
+	Nodes        int `json:"nodes"`
+	HealthyNodes int `json:"healthy_nodes"`
+	Pods         int `json:"pods"`
+	HealthyPods  int `json:"healthy_pods"`
+}
+
+// Report is a rendered-ready health report of one or more clusters.
+type Report struct {
+	Title       string               `json:"title"`
+	GeneratedAt time.Time            `json:"generated_at"`
+	Summary     Summary              `json:"summary"`
+	Issues      []Issue              `json:"issues"`
+	Clusters    []*k8s.ClusterStatus `json:"clusters"`
+}
+
+// New builds a report of statuses; an empty title defaults to "Kopilot Cluster Report".
+func New(title string, statuses []*k8s.ClusterStatus, generatedAt time.Time) *Report {
+	if title == "" {
+		title = "Kopilot Cluster Report"
+	}
+	r := &Report{Title: title, GeneratedAt: generatedAt, Clusters: statuses}
+	r.Summary.Clusters = len(statuses)
+	for _, status := range statuses {
+		switch ClusterHealth(status) {
+		case HealthHealthy:
+			r.Summary.Healthy++
+		case HealthDegraded:
+			r.Summary.Degraded++
+		default:
+			r.Summary.Down++
+		}
+		r.Summary.Nodes += status.NodeCount
+		r.Summary.HealthyNodes += status.HealthyNodes
+		r.Summary.Pods += status.PodCount
+		r.Summary.HealthyPods += status.HealthyPods
+		r.Issues = append(r.Issues, ClusterIssues(status)...)
+	}
+	sort.SliceStable(r.Issues, func(i, j int) bool {
+		return severityRank[r.Issues[i].Severity] < severityRank[r.Issues[j].Severity]
+	})
+	for _, issue := range r.Issues {
+		switch issue.Severity {
+		case SeverityCritical:
+			r.Summary.Critical++
+		case SeverityWarning:
+			r.Summary.Warnings++
+		}
+	}
+	return r
+}
+
+// ClusterHealth classifies a cluster as HEALTHY, DEGRADED or DOWN.
+func ClusterHealth(status *k8s.ClusterStatus) string {
+	switch {
+	case !status.IsReachable:
+		return HealthDown
+	case status.HealthyNodes < status.NodeCount || status.HealthyPods < status.PodCount || len(status.ExposureIssues) > 0:
+		return HealthDegraded
+	}
+	return HealthHealthy
+}
+
+// ClusterIssues lists the problems of one cluster: unreachable clusters and
+// NotReady nodes are critical; unhealthy pods and broken exposed endpoints
+// are warnings; incomplete cost data is informational.
+func ClusterIssues(status *k8s.ClusterStatus) []Issue {
+	issue := func(severity Severity, format string, args ...any) Issue {
+		return Issue{Severity: severity, Cluster: status.Context, Message: fmt.Sprintf(format, args...)}
+	}
+	if !status.IsReachable {
+		return []Issue{issue(SeverityCritical, "Cluster unreachable: %s", orDash(status.Error))}
+	}
+
+	var issues []Issue
+	var notReady []string
+	for _, node := range status.Nodes {
+		if node.Status != "Ready" {
+			notReady = append(notReady, node.Name)
+		}
+	}
+	if status.HealthyNodes < status.NodeCount {
+		msg := fmt.Sprintf("%d of %d node(s) not ready", status.NodeCount-status.HealthyNodes, status.NodeCount)
+		if len(notReady) > 0 {
+			msg += ": " + strings.Join(notReady, ", ")
+		}
+		issues = append(issues, issue(SeverityCritical, "%s", msg))
+	}
+	if status.NodeCount == 0 {
+		issues = append(issues, issue(SeverityWarning, "No nodes reported"))
+	}
+
+	for i, pod := range status.UnhealthyPods {
+		if i == maxPodIssues {
+			issues = append(issues, issue(SeverityInfo, "%d more unhealthy pod(s) not listed", len(status.UnhealthyPods)-maxPodIssues))
+			break
+		}
+		msg := fmt.Sprintf("Pod %s/%s is %s", pod.Namespace, pod.Name, orDash(pod.Status))
+		if pod.Reason != "" && pod.Reason != pod.Status {
+			msg += " (" + pod.Reason + ")"
+		}
+		if pod.Restarts > 0 {
+			msg += fmt.Sprintf(", %d restart(s)", pod.Restarts)
+		}
+		issues = append(issues, issue(SeverityWarning, "%s", msg))
+	}
+	if len(status.UnhealthyPods) == 0 && status.HealthyPods < status.PodCount {
+		issues = append(issues, issue(SeverityWarning, "%d of %d pod(s) unhealthy", status.PodCount-status.HealthyPods, status.PodCount))
+	}
+
+	for _, e := range status.ExposureIssues {
+		issues = append(issues, issue(SeverityWarning, "%s %s/%s: %s", e.Kind, e.Namespace, e.Name, e.Problem))
+	}
+
+	if status.Cost != nil {
+		if status.Cost.OpenCostWarning != "" {
+			issues = append(issues, issue(SeverityInfo, "OpenCost: %s", status.Cost.OpenCostWarning))
+		}
+		if len(status.Cost.UnpricedNodes) > 0 {
+			issues = append(issues, issue(SeverityInfo, "%d node(s) without a price: %s", len(status.Cost.UnpricedNodes), strings.Join(status.Cost.UnpricedNodes, ", ")))
+		}
+	}
+	return issues
+}
+
+// ParseFormat normalizes a format name; md and htm are accepted as aliases.
+func ParseFormat(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", "md", FormatMarkdown:
+		return FormatMarkdown, nil
+	case "htm", FormatHTML:
+		return FormatHTML, nil
+	}
+	return "", fmt.Errorf("unsupported report format %q (use markdown or html)", format)
+}
+
+// FormatForPath picks the format from a file extension (.md or .html).
+func FormatForPath(path string) (string, error) {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	switch ext {
+	case "md", "markdown", "html", "htm":
+		return ParseFormat(ext)
+	}
+	return "", fmt.Errorf("unsupported report extension %q (use .md or .html)", filepath.Ext(path))
+}
+
+// Render renders r in format (markdown or html).
+func Render(r *Report, format string) ([]byte, error) {
+	switch format {
+	case FormatMarkdown:
+		return []byte(Markdown(r)), nil
+	case FormatHTML:
+		return HTML(r)
+	}
+	return nil, fmt.Errorf("unsupported report format %q", format)
+}
+
+// healthIcon decorates a health label.
+func healthIcon(health string) string {
+	switch health {
+	case HealthHealthy:
+		return "✅"
+	case HealthDegraded:
+		return "⚠️"
+	}
+	return "❌"
+}
+
+// severityIcon decorates a severity.
+func severityIcon(s Severity) string {
+	switch s {
+	case SeverityCritical:
+		return "🔴"
+	case SeverityWarning:
+		return "🟠"
+	}
+	return "🔵"
+}
+
+// money formats an amount like the agent's cost output.
+func money(amount float64, currency string) string {
+	return fmt.Sprintf("%.2f %s", amount, currency)
+}
+
+// monthlyCost returns the estimated monthly cost of a cluster, or "-".
+func monthlyCost(status *k8s.ClusterStatus) string {
+	if status.Cost == nil {
+		return "-"
+	}
+	return "~" + money(status.Cost.MonthlyCost, status.Cost.Currency)
+}
+
+// distribution formats a count map as "linux: 3, windows: 1".
+func distribution(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s: %d", k, counts[k])
+	}
+	return orDash(strings.Join(parts, ", "))
+}
+
+// topNamespaceCosts returns up to n of the most expensive namespaces.
+func topNamespaceCosts(status *k8s.ClusterStatus, n int) []k8s.NamespaceCost {
+	if status.Cost == nil {
+		return nil
+	}
+	costs := status.Cost.Namespaces
+	if len(costs) > n {
+		costs = costs[:n]
+	}
+	return costs
+}
+
+// clusterIssues returns the issues of r belonging to cluster.
+func (r *Report) clusterIssues(cluster string) []Issue {
+	var issues []Issue
+	for _, issue := range r.Issues {
+		if issue.Cluster == cluster {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,158 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/e9169/kopilot/pkg/k8s"
+)
+
+func sampleStatuses() []*k8s.ClusterStatus {
+	return []*k8s.ClusterStatus{
+		{
+			ClusterInfo: k8s.ClusterInfo{Context: "prod", Server: "https://prod.example.com", IsReachable: true},
+			Version:     "v1.31.0",
+			NodeCount:   2, HealthyNodes: 1,
+			Nodes: []k8s.NodeInfo{
+				{Name: "node-a", Status: "Ready", Roles: []string{"worker"}, Age: "10d", OS: "linux", Arch: "amd64"},
+				{Name: "node-b", Status: "NotReady", Roles: []string{"worker"}, Age: "3d", OS: "linux", Arch: "amd64"},
+			},
+			OSDistribution: map[string]int{"linux": 2},
+			PodCount:       4, HealthyPods: 3,
+			UnhealthyPods:  []k8s.PodInfo{{Name: "api-1", Namespace: "shop", Status: "Running", Reason: "CrashLoopBackOff", Restarts: 7}},
+			ExposureIssues: []k8s.ExposureIssue{{Namespace: "shop", Kind: "Ingress", Name: "web", Problem: "no ready endpoints"}},
+			Cost: &k8s.CostEstimate{
+				Currency: "USD", Source: "price-table", HourlyCost: 1, MonthlyCost: 730,
+				Namespaces:    []k8s.NamespaceCost{{Namespace: "shop", MonthlyCost: 500, Percent: 68}},
+				UnpricedNodes: []string{"node-b"},
+			},
+		},
+		{
+			ClusterInfo: k8s.ClusterInfo{Context: "dev<b>", Server: "https://dev.example.com"},
+			Error:       "connection refused",
+		},
+		{
+			ClusterInfo: k8s.ClusterInfo{Context: "staging", IsReachable: true},
+			NodeCount:   1, HealthyNodes: 1, PodCount: 2, HealthyPods: 2,
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	r := New("", sampleStatuses(), time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	if r.Title != "Kopilot Cluster Report" {
+		t.Errorf("Title = %q", r.Title)
+	}
+	want := Summary{Clusters: 3, Healthy: 1, Degraded: 1, Down: 1, Critical: 2, Warnings: 2, Nodes: 3, HealthyNodes: 2, Pods: 6, HealthyPods: 5}
+	if r.Summary != want {
+		t.Errorf("Summary = %+v, want %+v", r.Summary, want)
+	}
+	var order []Severity
+	for _, issue := range r.Issues {
+		order = append(order, issue.Severity)
+	}
+	wantOrder := []Severity{SeverityCritical, SeverityCritical, SeverityWarning, SeverityWarning, SeverityInfo}
+	if len(order) != len(wantOrder) {
+		t.Fatalf("issues = %+v", r.Issues)
+	}
+	for i := range order {
+		if order[i] != wantOrder[i] {
+			t.Errorf("issue severities = %v, want %v", order, wantOrder)
+			break
+		}
+	}
+}
+
+func TestClusterIssues(t *testing.T) {
+	issues := ClusterIssues(sampleStatuses()[0])
+	for _, want := range []string{
+		"1 of 2 node(s) not ready: node-b",
+		"Pod shop/api-1 is Running (CrashLoopBackOff), 7 restart(s)",
+		"Ingress shop/web: no ready endpoints",
+		"1 node(s) without a price: node-b",
+	} {
+		found := false
+		for _, issue := range issues {
+			found = found || issue.Message == want
+		}
+		if !found {
+			t.Errorf("ClusterIssues missing %q: %+v", want, issues)
+		}
+	}
+
+	many := &k8s.ClusterStatus{ClusterInfo: k8s.ClusterInfo{Context: "big", IsReachable: true}, NodeCount: 1, HealthyNodes: 1}
+	for i := 0; i < maxPodIssues+5; i++ {
+		many.UnhealthyPods = append(many.UnhealthyPods, k8s.PodInfo{Name: "p", Namespace: "ns", Status: "Pending"})
+	}
+	issues = ClusterIssues(many)
+	if len(issues) != maxPodIssues+1 || issues[maxPodIssues].Message != "5 more unhealthy pod(s) not listed" {
+		t.Errorf("pod issues should be capped at %d, got %d", maxPodIssues, len(issues))
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]string{"": FormatMarkdown, "md": FormatMarkdown, "Markdown": FormatMarkdown, "HTML": FormatHTML, "htm": FormatHTML} {
+		if got, err := ParseFormat(in); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Error("ParseFormat(pdf) should fail")
+	}
+	for path, want := range map[string]string{"out/status.md": FormatMarkdown, "status.HTML": FormatHTML} {
+		if got, err := FormatForPath(path); err != nil || got != want {
+			t.Errorf("FormatForPath(%q) = %q, %v; want %q", path, got, err, want)
+		}
+	}
+	if _, err := FormatForPath("status.json"); err == nil {
+		t.Error("FormatForPath(status.json) should fail")
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	out := Markdown(New("Weekly health", sampleStatuses(), time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)))
+	for _, want := range []string{
+		"# Weekly health\n",
+		"Generated 2026-01-02T03:04:05Z",
+		"| 3 | 1 | 1 | 1 | 2/3 | 5/6 | 2 | 2 |",
+		"| prod | ⚠️ DEGRADED | 1/2 | 3/4 | v1.31.0 | ~730.00 USD |",
+		"| dev<b> | ❌ DOWN | - | - | - | - |",
+		"| 🔴 critical | dev<b> | Cluster unreachable: connection refused |",
+		"## ⚠️ prod\n",
+		"- Nodes: 1/2 ready (linux: 2; -)",
+		"| node-b | NotReady | worker | 3d | linux/amd64 |",
+		"| shop | api-1 | Running | CrashLoopBackOff | 7 |",
+		"| shop | ~500.00 USD | 68% |",
+		"- 🟠 **warning**: Ingress shop/web: no ready endpoints",
+		"## ✅ staging\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestHTML(t *testing.T) {
+	data, err := Render(New("", sampleStatuses(), time.Now()), FormatHTML)
+	if err != nil {
+		t.Fatalf("Render(html) error = %v", err)
+	}
+	out := string(data)
+	for _, want := range []string{
+		"<title>Kopilot Cluster Report</title>",
+		`<td class="degraded">⚠️ DEGRADED</td>`,
+		`<td class="critical">🔴 critical</td><td>dev&lt;b&gt;</td><td>Cluster unreachable: connection refused</td>`,
+		`<h2 id="cluster-prod">⚠️ prod</h2>`,
+		"<tr><td>shop</td><td>api-1</td><td>Running</td><td>CrashLoopBackOff</td><td>7</td></tr>",
+		"<tr><td>shop</td><td>~500.00 USD</td><td>68%</td></tr>",
+		"<li>Error: connection refused</li>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("html missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "dev<b>") {
+		t.Error("html should escape cluster names")
+	}
+}
@@ -0,0 +1,153 @@
+// Package telemetry provides OpenTelemetry tracing for kopilot: one span per
+// prompt, model call, tool call, kubectl run and Kubernetes API request,
+// exported over OTLP/HTTP when an endpoint is configured.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation scope of every kopilot span.
+const TracerName = "github.com/e9169/kopilot"
+
+// Standard OTLP exporter environment variables; either enables tracing.
+const (
+	envOTLPEndpoint       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	envOTLPTracesEndpoint = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+)
+
+var (
+	activeMu sync.RWMutex
+	// active is the context of the prompt being answered. Spans started
+	// from a context without a span (tool handlers and kubectl runs that
+	// do not receive the caller's context yet) are parented to it.
+	active context.Context
+)
+
+// Enabled reports whether tracing should be exported: an explicit endpoint
+// or one of the standard OTEL_EXPORTER_OTLP_*ENDPOINT variables is set.
+func Enabled(endpoint string) bool {
+	return endpoint != "" || os.Getenv(envOTLPEndpoint) != "" || os.Getenv(envOTLPTracesEndpoint) != ""
+}
+
+// Setup installs a global tracer provider exporting spans over OTLP/HTTP to
+// endpoint (a URL such as http://localhost:4318), or to the endpoint from the
+// standard OTEL_EXPORTER_OTLP_* variables when endpoint is empty. Without
+// either, tracing stays a no-op. The returned function flushes and stops the
+// exporter and must be called before exit.
+func Setup(ctx context.Context, endpoint, version string) (func(context.Context) error, error) {
+	if !Enabled(endpoint) {
+		return func(context.Context) error { return nil }, nil
+	}
+	var opts []otlptracehttp.Option
+	if endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults.
+	res, err := resource.Merge(
+		resource.NewSchemaless(attribute.String("service.name", "kopilot"), attribute.String("service.version", version)),
+		resource.Environment(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// SetActive records ctx as the in-flight prompt context; pass nil to clear it.
+func SetActive(ctx context.Context) {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	active = ctx
+}
+
+// withParent returns ctx, or the active prompt context when ctx carries no span.
+func withParent(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	activeMu.RLock()
+	defer activeMu.RUnlock()
+	if active == nil {
+		return ctx
+	}
+	// Keep ctx's deadline and cancellation, borrow only the span.
+	return trace.ContextWithSpan(ctx, trace.SpanFromContext(active))
+}
+
+// Start starts a span named name as a child of the span in ctx, falling back
+// to the active prompt span.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(withParent(ctx), name, trace.WithAttributes(attrs...))
+}
+
+// StartChat starts the span of one model request, named and attributed after
+// the OpenTelemetry GenAI conventions ("chat <model>").
+func StartChat(ctx context.Context, provider, model string) (context.Context, trace.Span) {
+	return Start(ctx, "chat "+model,
+		attribute.String("gen_ai.operation.name", "chat"),
+		attribute.String("gen_ai.provider.name", provider),
+		attribute.String("gen_ai.request.model", model))
+}
+
+// End records err (if any) on span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// transport traces each HTTP request made through it.
+type transport struct {
+	next http.RoundTripper
+}
+
+// WrapTransport returns rt instrumented with one client span per request,
+// named "k8s.api <METHOD>". It matches rest.Config.WrapTransport.
+func WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return &transport{next: rt}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := otel.Tracer(TracerName).Start(withParent(req.Context()), "k8s.api "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Host),
+			attribute.String("url.path", req.URL.Path),
+		))
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err == nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+		if resp.StatusCode >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, resp.Status)
+		}
+	}
+	End(span, err)
+	return resp, err
+}
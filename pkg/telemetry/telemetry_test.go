@@ -0,0 +1,138 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a global tracer provider recording ended spans.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prev)
+		SetActive(nil)
+	})
+	return rec
+}
+
+func attr(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestEnabled(t *testing.T) {
+	t.Setenv(envOTLPEndpoint, "")
+	t.Setenv(envOTLPTracesEndpoint, "")
+	if Enabled("") {
+		t.Error("Enabled() without endpoint or env should be false")
+	}
+	if !Enabled("http://localhost:4318") {
+		t.Error("Enabled() with an explicit endpoint should be true")
+	}
+	t.Setenv(envOTLPTracesEndpoint, "http://collector:4318/v1/traces")
+	if !Enabled("") {
+		t.Error("Enabled() with OTEL_EXPORTER_OTLP_TRACES_ENDPOINT should be true")
+	}
+}
+
+func TestSetupDisabled(t *testing.T) {
+	t.Setenv(envOTLPEndpoint, "")
+	t.Setenv(envOTLPTracesEndpoint, "")
+	shutdown, err := Setup(context.Background(), "", "test")
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+}
+
+func TestStartFallsBackToActivePrompt(t *testing.T) {
+	rec := recordSpans(t)
+
+	promptCtx, prompt := Start(context.Background(), "kopilot.prompt")
+	SetActive(promptCtx)
+	_, tool := Start(context.Background(), "tool list_clusters")
+	End(tool, errors.New("boom"))
+	SetActive(nil)
+	_, orphan := Start(context.Background(), "kubectl")
+	End(orphan, nil)
+	End(prompt, nil)
+
+	spans := rec.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want 3", len(spans))
+	}
+	if spans[0].Parent().SpanID() != prompt.SpanContext().SpanID() {
+		t.Error("span started without a parent should use the active prompt span")
+	}
+	if spans[0].Status().Code != codes.Error || spans[0].Status().Description != "boom" {
+		t.Errorf("status = %+v, want error boom", spans[0].Status())
+	}
+	if spans[1].Parent().IsValid() {
+		t.Error("span started after SetActive(nil) should be a root span")
+	}
+}
+
+func TestWrapTransport(t *testing.T) {
+	rec := recordSpans(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/namespaces/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: WrapTransport(http.DefaultTransport)}
+	parentCtx, parent := Start(context.Background(), "tool get_cluster_status")
+	for _, path := range []string{"/api/v1/nodes", "/api/v1/namespaces/missing"} {
+		req, _ := http.NewRequestWithContext(parentCtx, http.MethodGet, server.URL+path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+	End(parent, nil)
+
+	spans := rec.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want 3", len(spans))
+	}
+	ok, notFound := spans[0], spans[1]
+	if ok.Name() != "k8s.api GET" || attr(ok, "url.path").AsString() != "/api/v1/nodes" || attr(ok, "http.response.status_code").AsInt64() != 200 {
+		t.Errorf("span = %s %v", ok.Name(), ok.Attributes())
+	}
+	if ok.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("API span should be a child of the span in the request context")
+	}
+	if notFound.Status().Code != codes.Error {
+		t.Errorf("404 status = %+v, want error", notFound.Status())
+	}
+}
+
+func TestStartChat(t *testing.T) {
+	rec := recordSpans(t)
+	_, span := StartChat(context.Background(), "openai", "gpt-4o")
+	End(span, nil)
+	got := rec.Ended()[0]
+	if got.Name() != "chat gpt-4o" || attr(got, "gen_ai.provider.name").AsString() != "openai" || attr(got, "gen_ai.request.model").AsString() != "gpt-4o" {
+		t.Errorf("span = %s %v", got.Name(), got.Attributes())
+	}
+}